@@ -2,15 +2,180 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/config"
+	"github.com/romariotrain/media-platform/internal/ingest"
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/quota"
 )
 
+var (
+	addr           = flag.String("addr", ":8082", "HTTP listen address")
+	mediaURL       = flag.String("media-url", "http://localhost:8081", "media service API")
+	maxUploadBytes = flag.Int64("max-upload-bytes", ingest.DefaultMaxUploadBytes, "largest accepted upload")
+	clamdAddr      = flag.String("clamd-addr", "", "clamd address for malware scanning (empty = disabled)")
+	asyncScanBytes = flag.Int64("async-scan-bytes", 0, "scan uploads larger than this in background (0 = always sync)")
+	scanTimeout    = flag.Duration("scan-timeout", 10*time.Minute, "timeout of one malware scan")
+	quotaURL       = flag.String("quota-url", "", "quota service API for upload rate limits (empty = unlimited)")
+	blobBackend    = flag.String("blob-store", "s3", "where sources are written: s3 | gcs | azure | local (file:// sources under -local-root)")
+	localRoot      = flag.String("local-root", "", "local: directory of file:// sources")
+	localSharded   = flag.Bool("local-sharded", false, "local: shard files into hash-prefix subdirectories (must match media -local-sharded)")
+	localMaxBytes  = flag.Int64("local-max-bytes", 0, "local: total size of stored sources; uploads beyond it get 503 (0 = unlimited)")
+	localRescan    = flag.Duration("local-rescan-interval", 10*time.Minute, "local: how often disk usage is recounted from the files (0 = only at start)")
+	dedup          = flag.String("dedup", string(ingest.DedupOff), "duplicate uploads of the same owner: off, reject (409) or reference (share the stored source)")
+)
+
+// httpServer — флаги -http-*. Загрузка большого исходника идёт дольше любого разумного
+// предела, а размер тела ограничивает -max-upload-bytes.
+var httpServer = config.HTTPServerFlags(flag.CommandLine, httpServerDefaults())
+
+func httpServerDefaults() config.HTTPServer {
+	c := config.DefaultHTTPServer()
+	c.ReadTimeout, c.WriteTimeout, c.MaxBodyBytes = 0, 0, 0
+	return c
+}
+
 func main() {
-	code := cli.Run("ingest", func(ctx context.Context) error {
-		<-ctx.Done()
-		return nil
-	})
+	flag.Parse()
+	code := cli.Run("ingest", run)
 	os.Exit(code)
 }
+
+func run(ctx context.Context, app *cli.App) error {
+	media, err := ingest.NewMediaClient(*mediaURL, nil)
+	if err != nil {
+		return err
+	}
+	store, err := sink(ctx, app)
+	if err != nil {
+		return fmt.Errorf("blob store: %w", err)
+	}
+	dedupMode, err := ingest.ParseDedupMode(*dedup)
+	if err != nil {
+		return err
+	}
+	cfg := ingest.HandlerConfig{
+		Media:          media,
+		Sink:           store,
+		MaxUploadBytes: *maxUploadBytes,
+		Logger:         app.Logger,
+		AsyncScanBytes: *asyncScanBytes,
+		ScanTimeout:    *scanTimeout,
+		Dedup:          dedupMode,
+	}
+	if *clamdAddr != "" {
+		if cfg.Scanner, err = ingest.NewClamAV(ingest.ClamAVConfig{Addr: *clamdAddr, Timeout: *scanTimeout}); err != nil {
+			return fmt.Errorf("clamav: %w", err)
+		}
+	}
+	if *quotaURL != "" {
+		if cfg.Limiter, err = quota.NewClient(*quotaURL, nil); err != nil {
+			return err
+		}
+	}
+	h, err := ingest.NewHandler(cfg)
+	if err != nil {
+		return err
+	}
+
+	srv, err := httpServer.NewServer(*addr, h)
+	if err != nil {
+		return err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe(srv)
+	}()
+	app.Register(cli.Component{Name: "http_server", Priority: cli.StopServers, Stop: srv.Shutdown})
+	// Фоновые проверки дописывают карантин в media, их нужно дождаться
+	app.Register(cli.Component{Name: "background_scans", Priority: cli.StopConsumers, Stop: h.Wait})
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("listen and serve: %w", err)
+	}
+}
+
+// sink — хранилище исходников из -blob-store
+func sink(ctx context.Context, app *cli.App) (ingest.Sink, error) {
+	switch *blobBackend {
+	case "s3":
+		// Credentials — те же переменные окружения, что у media с тем же -blob-store
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			// Загрузка может идти долго; предел задаёт контекст запроса
+			HTTPClient: &http.Client{},
+		})
+	case "gcs":
+		return blob.NewGCSStore(blob.GCSConfig{
+			Endpoint:   os.Getenv("GCS_ENDPOINT"),
+			AccessID:   os.Getenv("GCS_HMAC_ACCESS_ID"),
+			Secret:     os.Getenv("GCS_HMAC_SECRET"),
+			HTTPClient: &http.Client{},
+		})
+	case "azure":
+		return blob.NewAzureStore(blob.AzureConfig{
+			Account:    os.Getenv("AZURE_STORAGE_ACCOUNT"),
+			Key:        os.Getenv("AZURE_STORAGE_KEY"),
+			Endpoint:   os.Getenv("AZURE_BLOB_ENDPOINT"),
+			HTTPClient: &http.Client{},
+		})
+	case "local":
+		store, err := blob.NewLocalStore(blob.LocalConfig{
+			Root:     *localRoot,
+			Sharded:  *localSharded,
+			MaxBytes: *localMaxBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		app.Register(cli.Component{
+			Name:     "local_sources",
+			Priority: cli.StopStorage,
+			Stop:     func(context.Context) error { return store.Close() },
+		})
+		if *localRescan > 0 {
+			go rescan(ctx, app, store)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", *blobBackend)
+	}
+}
+
+// rescan сверяет учёт занятого места с диском: retention в media удаляет файлы в обход ingest
+func rescan(ctx context.Context, app *cli.App, store *blob.LocalStore) {
+	ticker := time.NewTicker(*localRescan)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		used, err := store.Rescan(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				app.Logger.Error().Err(err).Msg("local store rescan failed")
+			}
+			continue
+		}
+		app.Logger.Debug().Int64("used_bytes", used).Msg("local store rescanned")
+	}
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/replay"
+	"github.com/romariotrain/media-platform/internal/media/retention"
+	"github.com/romariotrain/media-platform/internal/media/seed"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/storage/mysql"
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// commands — команды бинаря media; первая (serve) запускается без аргументов
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{Name: "serve", Summary: "run HTTP API and outbox publisher (default)", Run: func(ctx context.Context, app *cli.App, _ []string) error {
+			return run(ctx, app)
+		}},
+		migrateCommand(),
+		outboxCommand(),
+		eventsCommand(),
+		projectionsCommand(),
+		mediaCommand(),
+		retentionCommand(),
+		scheduleCommand(),
+		seedCommand(),
+		healthcheckCommand(),
+	}
+}
+
+func migrateCommand() *cli.Command {
+	var yes bool
+	return &cli.Command{
+		Name:    "migrate",
+		Summary: "apply or roll back the postgres (or mysql, by DATABASE_URL scheme) schema",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "up",
+				Summary: "apply sql/script.sql (idempotent)",
+				Run: func(ctx context.Context, app *cli.App, _ []string) error {
+					if mysql.IsURL(os.Getenv("DATABASE_URL")) {
+						return migrateMySQL(ctx, app, mysql.MigrateUp)
+					}
+					_, pool, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					if err := pg.MigrateUp(ctx, pool); err != nil {
+						return err
+					}
+					app.Logger.Info().Msg("schema applied")
+					return nil
+				},
+			},
+			{
+				Name:    "down",
+				Summary: "drop all service tables with their data",
+				Flags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&yes, "yes", false, "confirm that all data will be deleted")
+				},
+				Run: func(ctx context.Context, app *cli.App, _ []string) error {
+					if !yes {
+						return fmt.Errorf("%w: migrate down deletes all data, pass -yes to confirm", cli.ErrUsage)
+					}
+					if mysql.IsURL(os.Getenv("DATABASE_URL")) {
+						return migrateMySQL(ctx, app, mysql.MigrateDown)
+					}
+					_, pool, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					if err := pg.MigrateDown(ctx, pool); err != nil {
+						return err
+					}
+					app.Logger.Warn().Msg("schema dropped")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// migrateMySQL применяет к MySQL из DATABASE_URL sql/mysql/script.sql (MigrateUp) или down.sql
+func migrateMySQL(ctx context.Context, app *cli.App, migrate func(context.Context, *sqlx.DB) error) error {
+	db, err := mysql.Connect(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := migrate(ctx, db); err != nil {
+		return err
+	}
+	app.Logger.Info().Msg("mysql schema migrated")
+	return nil
+}
+
+func outboxCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "outbox",
+		Summary: "manage outbox events",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "requeue",
+				Args:    "<id>...",
+				Summary: "return dead-lettered events to the publish queue",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if len(args) == 0 {
+						return fmt.Errorf("%w: at least one outbox id is required", cli.ErrUsage)
+					}
+					ids := make([]int64, len(args))
+					for i, arg := range args {
+						id, err := strconv.ParseInt(arg, 10, 64)
+						if err != nil || id <= 0 {
+							return fmt.Errorf("%w: invalid outbox id %q", cli.ErrUsage, arg)
+						}
+						ids[i] = id
+					}
+
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					repo, err := newOutboxRepo(db)
+					if err != nil {
+						return err
+					}
+					for _, id := range ids {
+						if err := repo.Requeue(ctx, id); err != nil {
+							return fmt.Errorf("requeue %d: %w", id, err)
+						}
+						app.Logger.Info().Int64("outbox_id", id).Msg("outbox event requeued")
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func eventsCommand() *cli.Command {
+	var aggregateID, eventType, from, to, mode, topic string
+	var limit int
+	return &cli.Command{
+		Name:    "events",
+		Summary: "manage published events",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "replay",
+				Summary: "replay published outbox events of an aggregate or a time range, as POST /admin/events/replay",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&aggregateID, "aggregate", "", "aggregate (media) id")
+					fs.StringVar(&eventType, "event-type", "", "only events of this type")
+					fs.StringVar(&from, "from", "", "events that occurred at or after this time (RFC 3339)")
+					fs.StringVar(&to, "to", "", "events that occurred before this time (RFC 3339)")
+					fs.StringVar(&mode, "mode", "", "outbox: publish again to their topics | topic: publish to -topic only")
+					fs.StringVar(&topic, "topic", "", "mode topic: target topic (default -replay-topic)")
+					fs.IntVar(&limit, "limit", 0, "replay at most this many events (0 = all)")
+				},
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					req := replay.Request{
+						Filter: pg.OutboxReplayFilter{AggregateID: aggregateID, EventType: eventType},
+						Mode:   mode,
+						Topic:  topic,
+						Limit:  limit,
+					}
+					for _, t := range []struct {
+						name, value string
+						dst         *time.Time
+					}{{"from", from, &req.Filter.From}, {"to", to, &req.Filter.To}} {
+						if t.value == "" {
+							continue
+						}
+						v, err := time.Parse(time.RFC3339, t.value)
+						if err != nil {
+							return fmt.Errorf("%w: invalid -%s %q, want RFC 3339", cli.ErrUsage, t.name, t.value)
+						}
+						*t.dst = v
+					}
+					if err := req.Validate(); err != nil {
+						return fmt.Errorf("%w: %w", cli.ErrUsage, err)
+					}
+
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					store, err := newOutboxRepo(db)
+					if err != nil {
+						return err
+					}
+					cfg := replay.Config{Store: store, Topic: *replayTopic, Logger: app.Logger}
+					if mode == replay.ModeTopic {
+						interceptors, err := producerInterceptors()
+						if err != nil {
+							return err
+						}
+						producer, err := kafka.NewProducer(kafka.ProducerConfig{
+							Brokers:      []string{"localhost:9092"},
+							Topic:        *replayTopic,
+							Format:       kafka.Format(*kafkaFormat),
+							Interceptors: interceptors,
+							SchemaRegistry: kafka.SchemaRegistryConfig{
+								URL:             os.Getenv("SCHEMA_REGISTRY_URL"),
+								Username:        os.Getenv("SCHEMA_REGISTRY_USERNAME"),
+								Password:        os.Getenv("SCHEMA_REGISTRY_PASSWORD"),
+								SubjectStrategy: kafka.SubjectStrategy(*subjectStrategy),
+							},
+							Logger: app.Logger,
+						})
+						if err != nil {
+							return fmt.Errorf("kafka producer: %w", err)
+						}
+						app.Register(cli.Component{Name: "kafka_producer", Priority: cli.StopProducers, Stop: producer.Shutdown})
+						cfg.Producer = producer
+					}
+					replayer, err := replay.New(cfg)
+					if err != nil {
+						return err
+					}
+					res, err := replayer.Replay(ctx, req)
+					if err != nil && res.Replayed > 0 {
+						app.Logger.Warn().Int64("replayed", res.Replayed).Msg("replay stopped partway")
+					}
+					return err
+				},
+			},
+			{
+				Name:    "state",
+				Summary: "print media state rebuilt from its event stream (-event-store), saving a snapshot every 50 events",
+				Args:    "<media-id>",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 1); err != nil {
+						return err
+					}
+					id, err := uuid.Parse(args[0])
+					if err != nil {
+						return fmt.Errorf("%w: invalid media id %q", cli.ErrUsage, args[0])
+					}
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					repo := pg.NewMediaEventsRepo(db)
+					m, version, err := eventstore.NewLoader(repo, repo, eventstore.DefaultSnapshotEvery).Load(ctx, id)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintf(w, "version\t%d\n", version)
+					fmt.Fprintf(w, "status\t%s\n", m.Status)
+					fmt.Fprintf(w, "owner\t%s\n", m.OwnerID)
+					fmt.Fprintf(w, "type\t%s\n", m.Type)
+					fmt.Fprintf(w, "source\t%s\n", m.Source)
+					fmt.Fprintf(w, "processing attempts\t%d\n", m.ProcessingAttempts)
+					fmt.Fprintf(w, "content\t%s %d bytes %s\n", m.ContentType, m.Size, m.Checksum)
+					fmt.Fprintf(w, "created\t%s\n", m.CreatedAt.Format(time.RFC3339))
+					fmt.Fprintf(w, "updated\t%s\n", m.UpdatedAt.Format(time.RFC3339))
+					return w.Flush()
+				},
+			},
+		},
+	}
+}
+
+func projectionsCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "projections",
+		Summary: "manage read model projections of the event store",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "rebuild",
+				Summary: "drop a projection and rebuild it from the whole event log",
+				Args:    "<name>",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 1); err != nil {
+						return err
+					}
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					runner, err := newProjectionRunner(db, app.Logger)
+					if err != nil {
+						return err
+					}
+					if !slices.Contains(runner.Names(), args[0]) {
+						return fmt.Errorf("%w: unknown projection %q, want one of %v", cli.ErrUsage, args[0], runner.Names())
+					}
+					n, err := runner.Rebuild(ctx, args[0])
+					if err != nil {
+						return err
+					}
+					app.Logger.Info().Str("projection", args[0]).Int("events", n).Msg("projection rebuilt")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func mediaCommand() *cli.Command {
+	var reason, actor string
+	return &cli.Command{
+		Name:    "media",
+		Summary: "manage media records",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "set-status",
+				Args:    "<id> <status>",
+				Summary: "change media status with history and outbox event, as PATCH /media/{id}/status",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&reason, "reason", "", "reason recorded in status history")
+					fs.StringVar(&actor, "actor", "cli", "actor recorded in status history")
+				},
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 2); err != nil {
+						return err
+					}
+					id, err := uuid.Parse(args[0])
+					if err != nil {
+						return fmt.Errorf("%w: invalid media id %q", cli.ErrUsage, args[0])
+					}
+
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					outboxRepo, err := newOutboxRepo(db)
+					if err != nil {
+						return err
+					}
+					svc := service.New(pg.NewMediaRepo(db), serviceOutbox(db, outboxRepo)).WithLogger(app.Logger)
+					_, err = svc.ChangeStatus(ctx, id, models.Status(args[1]), service.ChangeMeta{Actor: actor, Reason: reason})
+					return err
+				},
+			},
+		},
+	}
+}
+
+func seedCommand() *cli.Command {
+	var count, owners int
+	var seedValue uint64
+	return &cli.Command{
+		Name:    "seed",
+		Summary: "create fake media in all statuses and types with their outbox events, for local development",
+		Flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&count, "count", 100, "media to create")
+			fs.IntVar(&owners, "owners", 5, "owners the media are spread across")
+			fs.Uint64Var(&seedValue, "seed", 0, "generator seed, the same seed gives the same data (0 = random)")
+		},
+		Run: func(ctx context.Context, app *cli.App, args []string) error {
+			if err := cli.ExactArgs(args, 0); err != nil {
+				return err
+			}
+			if count <= 0 || owners <= 0 {
+				return fmt.Errorf("%w: -count and -owners must be positive", cli.ErrUsage)
+			}
+
+			db, _, err := openPrimaryFromEnv(ctx, app)
+			if err != nil {
+				return err
+			}
+			repo := pg.NewMediaRepo(db)
+			outboxRepo, err := newOutboxRepo(db)
+			if err != nil {
+				return err
+			}
+			seeder, err := seed.New(seed.Config{
+				Media:  service.New(repo, serviceOutbox(db, outboxRepo)).WithLogger(app.Logger),
+				Attrs:  repo,
+				Count:  count,
+				Owners: owners,
+				Seed:   seedValue,
+				Logger: app.Logger,
+			})
+			if err != nil {
+				return err
+			}
+			rep, err := seeder.Run(ctx)
+			if err != nil {
+				return fmt.Errorf("%w (created %d before the failure)", err, rep.Created)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "created\t%d\n", rep.Created)
+			for _, st := range slices.Sorted(maps.Keys(rep.ByStatus)) {
+				fmt.Fprintf(w, "status %s\t%d\n", st, rep.ByStatus[st])
+			}
+			for _, t := range slices.Sorted(maps.Keys(rep.ByType)) {
+				fmt.Fprintf(w, "type %s\t%d\n", t, rep.ByType[t])
+			}
+			for _, o := range rep.Owners {
+				fmt.Fprintf(w, "owner\t%s\n", o)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func retentionCommand() *cli.Command {
+	var mediaType, mediaID, action string
+	var after time.Duration
+	return &cli.Command{
+		Name:    "retention",
+		Summary: "manage retention policies of media sources",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "set",
+				Summary: "set the policy of a media type or of one media (replaces the existing one)",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&mediaType, "type", "", "media type the policy applies to: video | audio | file")
+					fs.StringVar(&mediaID, "media", "", "media id the policy applies to (overrides the type policy)")
+					fs.DurationVar(&after, "after", 0, "retention period counted from media creation, e.g. 720h")
+					fs.StringVar(&action, "action", string(retention.ActionArchive), "what to do with expired media: archive | delete")
+				},
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					p := retention.Policy{MediaType: models.MediaType(mediaType), RetainFor: after, Action: retention.Action(action)}
+					if mediaID != "" {
+						id, err := uuid.Parse(mediaID)
+						if err != nil {
+							return fmt.Errorf("%w: invalid media id %q", cli.ErrUsage, mediaID)
+						}
+						p.MediaID = id
+					}
+					if err := p.Validate(); err != nil {
+						return fmt.Errorf("%w: %w", cli.ErrUsage, err)
+					}
+
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					p, err = pg.NewRetentionRepo(db).SetPolicy(ctx, p)
+					if err != nil {
+						return err
+					}
+					app.Logger.Info().Int64("policy_id", p.ID).Msg("retention policy set")
+					return nil
+				},
+			},
+			{
+				Name:    "list",
+				Summary: "print retention policies",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					policies, err := pg.NewRetentionRepo(db).ListPolicies(ctx)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "ID\tTARGET\tAFTER\tACTION")
+					for _, p := range policies {
+						target := "type:" + string(p.MediaType)
+						if p.MediaID != uuid.Nil {
+							target = "media:" + p.MediaID.String()
+						}
+						fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", p.ID, target, p.RetainFor, p.Action)
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:    "delete",
+				Args:    "<id>",
+				Summary: "delete a retention policy",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 1); err != nil {
+						return err
+					}
+					id, err := strconv.ParseInt(args[0], 10, 64)
+					if err != nil || id <= 0 {
+						return fmt.Errorf("%w: invalid policy id %q", cli.ErrUsage, args[0])
+					}
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					if err := pg.NewRetentionRepo(db).DeletePolicy(ctx, id); err != nil {
+						return err
+					}
+					app.Logger.Info().Int64("policy_id", id).Msg("retention policy deleted")
+					return nil
+				},
+			},
+			{
+				Name:    "run",
+				Summary: "archive and delete expired media once, as the -retention-interval job does",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					outboxRepo, err := newOutboxRepo(db)
+					if err != nil {
+						return err
+					}
+					svc := service.New(pg.NewMediaRepo(db), serviceOutbox(db, outboxRepo)).WithLogger(app.Logger)
+					job, err := newRetentionJob(db, svc, 0, app.Logger)
+					if err != nil {
+						return err
+					}
+					_, err = job.RunOnce(ctx)
+					return err
+				},
+			},
+		},
+	}
+}
+
+func scheduleCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "schedule",
+		Summary: "publish and expire media by publish_at / expires_at",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "run",
+				Summary: "publish and expire due media once, as the -schedule-interval job does",
+				Run: func(ctx context.Context, app *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					db, _, err := openPrimaryFromEnv(ctx, app)
+					if err != nil {
+						return err
+					}
+					outboxRepo, err := newOutboxRepo(db)
+					if err != nil {
+						return err
+					}
+					svc := service.New(pg.NewMediaRepo(db), serviceOutbox(db, outboxRepo)).WithLogger(app.Logger)
+					job, err := newScheduleJob(db, svc, 0, app.Logger)
+					if err != nil {
+						return err
+					}
+					_, err = job.RunOnce(ctx)
+					return err
+				},
+			},
+		},
+	}
+}
+
+func healthcheckCommand() *cli.Command {
+	var url string
+	var timeout time.Duration
+	return &cli.Command{
+		Name:    "healthcheck",
+		Summary: "probe a running instance, exit code 0 if healthy (for container HEALTHCHECK)",
+		Flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&url, "url", "http://localhost:8081/health", "endpoint to probe; /readyz also checks dependencies")
+			fs.DurationVar(&timeout, "timeout", 3*time.Second, "request timeout")
+		},
+		Run: func(ctx context.Context, _ *cli.App, _ []string) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return fmt.Errorf("healthcheck: %w", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("healthcheck: %s returned %d", url, resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// eventCipher шифрует payload событий ключами из EVENT_ENCRYPTION_KEYS; nil — шифрование выключено
+var eventCipher = sync.OnceValues(encryption.FromEnv)
+
+// newOutboxRepo — OutboxRepo, который шифрует payload событий, если заданы ключи
+func newOutboxRepo(db *sqlx.DB) (*pg.OutboxRepo, error) {
+	c, err := eventCipher()
+	if err != nil {
+		return nil, err
+	}
+	return pg.NewOutboxRepo(db).WithEncryption(c), nil
+}
+
+// producerInterceptors — interceptor'ы producer'а событий: traceparent (-kafka-tracing)
+// и, если заданы ключи, шифрование value — последним, чтобы шифровалось готовое сообщение
+func producerInterceptors() ([]kafka.Interceptor, error) {
+	var interceptors []kafka.Interceptor
+	if *kafkaTracing {
+		interceptors = append(interceptors, kafka.TracingInterceptor())
+	}
+	c, err := eventCipher()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		interceptors = append(interceptors, kafka.EncryptionInterceptor(c))
+	}
+	return interceptors, nil
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"flag"
 	"os"
 
+	"github.com/joho/godotenv"
+
 	"github.com/romariotrain/media-platform/internal/cli"
 )
 
 func main() {
-	code := cli.Run("media", run)
+	_ = godotenv.Load()
+	flag.Parse()
+	code := cli.Run("media", cli.Dispatch(flag.Args(), commands()...))
 	os.Exit(code)
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/storage/mysql"
+)
+
+// runMySQL поднимает сервис поверх MySQL (DATABASE_URL mysql://...): media, outbox
+// и его publisher
+func runMySQL(ctx context.Context, app *cli.App) error {
+	db, err := mysql.Connect(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(*dbMaxConns)
+	app.Register(cli.Component{
+		Name:     "mysql",
+		Priority: cli.StopStorage,
+		Stop:     func(context.Context) error { return db.Close() },
+	})
+
+	c, err := eventCipher()
+	if err != nil {
+		return fmt.Errorf("event encryption: %w", err)
+	}
+	shard, err := outboxShard()
+	if err != nil {
+		return err
+	}
+	outboxRepo := mysql.NewOutboxRepo(db).WithEncryption(c)
+	return serveSQL(ctx, app, sqlStore{
+		name:      "mysql",
+		ping:      db.PingContext,
+		media:     mysql.NewMediaRepo(db),
+		outbox:    outboxRepo,
+		pending:   outboxRepo.Shard(shard),
+		transient: mysql.IsTransient,
+		publish:   true,
+	})
+}
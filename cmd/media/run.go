@@ -2,89 +2,1063 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/config"
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/audit"
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/cache"
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/download"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
 	httpapi "github.com/romariotrain/media-platform/internal/media/httpapi"
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
 	"github.com/romariotrain/media-platform/internal/media/kafka"
 	"github.com/romariotrain/media-platform/internal/media/outbox"
+	"github.com/romariotrain/media-platform/internal/media/projection"
+	"github.com/romariotrain/media-platform/internal/media/purge"
+	"github.com/romariotrain/media-platform/internal/media/replay"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/retention"
+	"github.com/romariotrain/media-platform/internal/media/schedule"
 	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/media/share"
+	"github.com/romariotrain/media-platform/internal/media/stream"
+	"github.com/romariotrain/media-platform/internal/processing/jobs"
+	"github.com/rs/zerolog"
 
+	"github.com/romariotrain/media-platform/internal/storage/mysql"
 	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
 	repos "github.com/romariotrain/media-platform/internal/storage/postgres"
 )
 
-func run(ctx context.Context) error {
-	_ = godotenv.Load()
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		return fmt.Errorf("DATABASE_URL is empty")
+var (
+	storageFlag      = flag.String("storage", "sql", "storage backend: sql (postgres or mysql by DATABASE_URL scheme) | postgres | sqlite | memory")
+	snapshotFile     = flag.String("snapshot-file", "", "memory storage: file for periodic snapshots (empty = disabled)")
+	snapshotInterval = flag.Duration("snapshot-interval", 30*time.Second, "memory storage: snapshot period")
+	snapshotMaxBytes = flag.Int64("snapshot-max-bytes", 64<<20, "memory storage: max snapshot size in bytes")
+	kafkaFormat      = flag.String("kafka-format", "json", "event serialization: json | avro | protobuf")
+	maxAttempts      = flag.Int("max-processing-attempts", domain.DefaultMaxProcessingAttempts, "processing attempts before media is marked failed")
+	subjectStrategy  = flag.String("schema-subject-strategy", "topic", "schema registry subject naming: topic | record | topic_record")
+	dbMaxConns       = flag.Int("db-max-conns", 25, "postgres: max pool connections")
+	dbMinConns       = flag.Int("db-min-conns", 0, "postgres: connections kept open by the pool")
+	dbQueryTimeout   = flag.Duration("db-query-timeout", 0, "postgres: server-side statement timeout (0 = none)")
+	dbReadTimeout    = flag.Duration("db-read-timeout", 5*time.Second, "postgres: client-side timeout of media/outbox reads unless the caller's deadline is shorter")
+	dbWriteTimeout   = flag.Duration("db-write-timeout", 10*time.Second, "postgres: client-side timeout of media/outbox writes unless the caller's deadline is shorter")
+	dbTxAttempts     = flag.Int("db-tx-attempts", 3, "postgres: attempts of a media change failing on serialization conflicts, deadlocks or dropped connections")
+	dbSlowQuery      = flag.Duration("db-slow-query", 500*time.Millisecond, "postgres: media/outbox operations slower than this are logged")
+	dbStartupWait    = flag.Duration("db-startup-wait", 0, "postgres: how long to wait for the database at start, retrying with backoff (0 = fail at once)")
+	dbHealthEvery    = flag.Duration("db-health-interval", 5*time.Second, "postgres: background ping period; failures reset the pool and fail /readyz")
+	dbStmtCache      = flag.Int("db-statement-cache", 512, "postgres: prepared statements cached per connection (-1 = disabled)")
+	cacheBackend     = flag.String("cache", "none", "GET /media/{id} cache: none | lru | redis")
+	cacheTTL         = flag.Duration("cache-ttl", 5*time.Minute, "cache entry TTL")
+	cacheSize        = flag.Int("cache-size", 10000, "lru cache: max entries")
+	outboxInterval   = flag.Duration("outbox-interval", time.Second, "outbox: poll interval after a partial batch (reloadable)")
+	outboxBatchSize  = flag.Int("outbox-batch-size", 100, "outbox: events read per batch (reloadable)")
+	outboxMaxIdle    = flag.Duration("outbox-max-interval", 30*time.Second, "outbox: max poll interval when outbox is empty")
+	outboxBacklogMax = flag.Int64("outbox-backlog-threshold", 0, "outbox: pending events above which /readyz fails (0 = disabled)")
+	outboxAttempts   = flag.Int("outbox-max-attempts", 20, "outbox: publish attempts before an event is moved to dead letter")
+	outboxShards     = flag.Int("outbox-shards", 1, "outbox: publishers splitting the outbox by aggregate id hash, per-aggregate order is kept (postgres, mysql)")
+	outboxShardIndex = flag.Int("outbox-shard", 0, "outbox: shard published by this instance, 0..outbox-shards-1; run every shard on exactly one instance")
+	kafkaAsync       = flag.Bool("kafka-async", false, "kafka: batch writes asynchronously; outbox marks events processed on delivery ack")
+	keyBatch         = flag.Int("kafka-key-batch", 0, "kafka: buffer up to this many events per media id into one write; outbox flushes after each batch (0 = disabled, sync only)")
+	keyBatchDelay    = flag.Duration("kafka-key-batch-delay", 10*time.Millisecond, "kafka: how long the first buffered event of a media id waits before its buffer is written")
+	kafkaTracing     = flag.Bool("kafka-tracing", true, "kafka: add a W3C traceparent header to published events")
+	breakerThreshold = flag.Int("kafka-breaker-threshold", 5, "kafka: consecutive write failures that open the circuit breaker (-1 = disabled)")
+	breakerCoolDown  = flag.Duration("kafka-breaker-cooldown", 30*time.Second, "kafka: how long the circuit breaker stays open before a probe")
+	kafkaCompression = flag.String("kafka-compression", "snappy", "kafka: batch compression codec: snappy | zstd | lz4 | none")
+	kafkaMaxMessage  = flag.Int("kafka-max-message-bytes", kafka.DefaultMaxMessageBytes, "kafka: max message size before compression, larger events go to dead letter (-1 = unchecked)")
+	createTopics     = flag.Bool("kafka-create-topics", false, "kafka: create missing topics at start (dev/staging; existing topics are not altered)")
+	topicPartitions  = flag.Int("kafka-topic-partitions", 3, "kafka: partitions for topics created by -kafka-create-topics")
+	topicReplication = flag.Int("kafka-topic-replication", 1, "kafka: replication factor for topics created by -kafka-create-topics")
+	outboxDrain      = flag.Duration("outbox-drain-timeout", 15*time.Second, "outbox: time to finish the in-flight batch and flush kafka on shutdown")
+	topicStrategy    = flag.String("kafka-topic-strategy", "static", "kafka: event topic naming: static | tenant | event_type")
+	tenantTopics     = flag.String("kafka-tenant-topics", "", "kafka: comma-separated owner ids with dedicated topics (tenant strategy)")
+	tenantPrefix     = flag.String("kafka-tenant-topic-prefix", "tenant.", "kafka: prefix of dedicated tenant topics: <prefix><owner_id>.events.media")
+	retentionEvery   = flag.Duration("retention-interval", 0, "postgres: how often expired media are archived or deleted by retention policies (0 = disabled)")
+	leaderElection   = flag.Bool("leader-election", true, "postgres: run the outbox archiver and schedule job on one replica at a time, elected by an advisory lock (false = on every replica)")
+	leaderLease      = flag.Duration("leader-lease", 5*time.Second, "postgres: how often the leader confirms its lock; a leader that cannot stops its singleton workers")
+	archiveEvery     = flag.Duration("outbox-archive-interval", 0, "postgres: how often published outbox events are moved to the day-partitioned outbox_archive (0 = disabled, outbox keeps every event)")
+	archiveAfter     = flag.Duration("outbox-archive-after", 24*time.Hour, "postgres: how long a published event stays in outbox (and in reach of replay) before it is archived")
+	archiveRetention = flag.Duration("outbox-archive-retention", 7*24*time.Hour, "postgres: how long published events are kept in outbox and archive; older archive partitions are dropped")
+	scheduleEvery    = flag.Duration("schedule-interval", 0, "postgres: how often media are published at publish_at and archived at expires_at (0 = disabled)")
+	blobBackend      = flag.String("blob-store", "none", "media sources on archive/delete: none (bucket lifecycle rules) | s3 | gcs | azure | local (files under -local-source-root)")
+	archiveBucket    = flag.String("s3-archive-bucket", "", "s3: cold storage bucket for archived sources (empty = change storage class in place)")
+	archiveClass     = flag.String("s3-storage-class", blob.DefaultArchiveStorageClass, "s3: storage class of archived sources")
+	s3PartSize       = flag.Int64("s3-part-size", blob.DefaultPartSize, "s3, gcs, azure: part size of multipart copies and ranged reads (objects larger than a part are copied in parts)")
+	s3Concurrency    = flag.Int("s3-concurrency", blob.DefaultConcurrency, "s3, gcs, azure: parts of one object transferred concurrently")
+	gcsArchiveBucket = flag.String("gcs-archive-bucket", "", "gcs: cold storage bucket for archived sources (empty = change storage class in place)")
+	gcsArchiveClass  = flag.String("gcs-storage-class", blob.DefaultGCSStorageClass, "gcs: storage class of archived sources")
+	azureArchive     = flag.String("azure-archive-container", "", "azure: cold storage container for archived sources (empty = change access tier in place)")
+	azureAccessTier  = flag.String("azure-access-tier", blob.DefaultAzureAccessTier, "azure: access tier of archived sources")
+	downloadBaseURL  = flag.String("download-base-url", "", "external media API address for proxy download links (empty = relative links)")
+	downloadTTL      = flag.Duration("download-ttl", 15*time.Minute, "default lifetime of download links")
+	downloadMaxTTL   = flag.Duration("download-max-ttl", 24*time.Hour, "longest download link lifetime a client may request")
+	shareTTL         = flag.Duration("share-ttl", 24*time.Hour, "default lifetime of anonymous share links (POST /media/{id}/share)")
+	shareMaxTTL      = flag.Duration("share-max-ttl", 7*24*time.Hour, "longest share link lifetime an owner may request; issued links cannot be revoked earlier")
+	trustedProxies   = flag.Int("trusted-proxies", httpapi.DefaultTrustedProxies, "proxies in front of the API appending to X-Forwarded-For; the client address is the entry added by the outermost one (0 = connection address)")
+	compressMinSize  = flag.Int("compress-min-bytes", httpapi.DefaultCompressMinSize, "gzip/deflate JSON responses of the public API from this size when the client accepts it (0 = disabled)")
+	corsOrigins      = flag.String("cors-origins", "", "comma-separated origins allowed to call the public API from browsers: https://app.example.com, https://*.example.com or * (empty = CORS disabled unless -cors-config)")
+	corsCredentials  = flag.Bool("cors-credentials", false, "CORS: let browsers send cookies and Authorization (not with -cors-origins *)")
+	corsMaxAge       = flag.Duration("cors-max-age", 10*time.Minute, "CORS: how long browsers cache preflight responses")
+	corsConfig       = flag.String("cors-config", "", "JSON file with the CORS default policy and per-route policies; replaces -cors-origins, -cors-credentials and -cors-max-age")
+	localSourceRoot  = flag.String("local-source-root", "", "directory of file:// sources served by the download proxy and managed by -blob-store local (empty = disabled)")
+	localSharded     = flag.Bool("local-sharded", false, "local: files are sharded into hash-prefix subdirectories of -local-source-root (must match ingest)")
+	localArchiveDir  = flag.String("local-archive-dir", "", "local: subdirectory of -local-source-root for archived sources (empty = archived in place)")
+	statusStream     = flag.Bool("status-stream", false, "postgres: serve GET /media/{id}/events (SSE) fed by a per-instance kafka consumer of media events")
+	replayTopic      = flag.String("replay-topic", replay.DefaultTopic, "kafka: topic of events replayed with mode topic (POST /admin/events/replay, media events replay)")
+	eventStore       = flag.Bool("event-store", false, "postgres: also keep the full event history of every media in media_events (GET /admin/events/streams/{id}, projections, GET /stats)")
+	projectionEvery  = flag.Duration("projection-interval", time.Second, "event store: projection poll interval once they caught up with the event log")
+	idempotencyTTL   = flag.Duration("idempotency-ttl", idempotency.DefaultTTL, "how long responses of POST/PATCH requests with Idempotency-Key are replayed (0 = the header is ignored)")
+	auditLog         = flag.Bool("audit-log", true, "postgres: record every mutating API request with the media field diff in audit_log (GET /admin/audit)")
+	ownerPurge       = flag.Bool("owner-purge", false, "postgres: serve POST /admin/owners/{id}/purge and run jobs deleting all data of an owner (GDPR erasure)")
+	purgeRenditions  = flag.String("purge-renditions", "", "owner purge: packaging output root whose {media_id}/ directories are deleted, e.g. s3://media-streaming/vod (needs -blob-store s3, gcs or azure; empty = renditions are kept)")
+)
+
+func run(ctx context.Context, app *cli.App) error {
+	switch *storageFlag {
+	case "sql":
+		if mysql.IsURL(os.Getenv("DATABASE_URL")) {
+			return runMySQL(ctx, app)
+		}
+		return runPostgres(ctx, app)
+	case "postgres":
+		return runPostgres(ctx, app)
+	case "sqlite":
+		return runSQLite(ctx, app)
+	case "memory":
+		return runMemory(ctx, app)
+	default:
+		return fmt.Errorf("unknown storage %q", *storageFlag)
 	}
+}
 
-	db, err := pg.Connect(ctx, dsn)
+func runPostgres(ctx context.Context, app *cli.App) error {
+	logger := app.Logger
+	poolCfg, err := primaryPoolConfig()
+	if err != nil {
+		return err
+	}
+	db, pool, err := openPrimary(ctx, app, poolCfg)
 	if err != nil {
-		return fmt.Errorf("db connect: %w", err)
+		return err
 	}
-	defer db.Close()
+	prometheus.MustRegister(pg.NewPoolCollector(pool, "primary"))
 
 	// Dependencies
-	mediaRepo := repos.NewMediaRepo(db)
-	outboxRepo := repos.NewOutboxRepo(db)
+	timeouts, err := pg.NewTimeouts(pg.TimeoutsConfig{
+		Read:   *dbReadTimeout,
+		Write:  *dbWriteTimeout,
+		Logger: logger,
+	})
+	if err != nil {
+		return fmt.Errorf("query timeouts: %w", err)
+	}
+	queryMetrics := pg.NewQueryMetrics()
+	if err := queryMetrics.Register(prometheus.DefaultRegisterer); err != nil {
+		return fmt.Errorf("register query metrics: %w", err)
+	}
+	instrumentCfg := pg.InstrumentConfig{Metrics: queryMetrics, SlowQuery: *dbSlowQuery, Logger: logger}
+	pgMediaRepo := repos.NewMediaRepo(db).WithTimeouts(timeouts)
+	mediaRepo, err := pg.InstrumentMediaRepo(pgMediaRepo, instrumentCfg)
+	if err != nil {
+		return fmt.Errorf("instrument media repo: %w", err)
+	}
+
+	// Реплика для чтения опциональна; недоступная на старте реплика не мешает запуску
+	if readDSN := os.Getenv("DATABASE_READ_URL"); readDSN != "" {
+		replicaCfg := poolCfg
+		replicaCfg.DSN = readDSN
+		replicaCfg.StartupWait = 0 // без реплики сервис работает, ждать её незачем
+		replicaPool, err := pg.NewPool(ctx, replicaCfg)
+		if err != nil {
+			logger.Warn().Err(err).Msg("read replica unavailable, all reads go to primary")
+		} else {
+			prometheus.MustRegister(pg.NewPoolCollector(replicaPool, "replica"))
 
-	svc := service.New(mediaRepo, outboxRepo)
-	h := httpapi.New(svc)
-	router := httpapi.NewRouter(h)
+			replicaDB := pg.OpenDB(replicaPool)
+			registerDB(app, "postgres_replica", replicaDB, replicaPool)
 
-	srv := &http.Server{
-		Addr:              ":8081",
-		Handler:           router,
-		ReadHeaderTimeout: 5 * time.Second,
+			replica, err := pg.NewReplica(pg.ReplicaConfig{DB: replicaDB, Logger: logger})
+			if err != nil {
+				return fmt.Errorf("read replica: %w", err)
+			}
+			pgMediaRepo.WithReplica(replica)
+		}
+	}
+	pgOutboxRepo, err := newOutboxRepo(db)
+	if err != nil {
+		return fmt.Errorf("event encryption: %w", err)
+	}
+	outboxRepo, err := pg.InstrumentOutboxRepo(pgOutboxRepo.WithTimeouts(timeouts), instrumentCfg)
+	if err != nil {
+		return fmt.Errorf("instrument outbox repo: %w", err)
 	}
 
-	kafkaProducer := kafka.NewProducer(
-		[]string{"localhost:9092"}, // брокеры из docker-compose
-		"events.media",             // topic
-	)
-	defer kafkaProducer.Close()
-
-	// Создаём outbox publisher
-	outboxPublisher := outbox.NewPublisher(
-		outboxRepo,
-		kafkaProducer,
-		5*time.Second, // каждые 5 секунд
-		100,           // до 100 событий за раз
+	naming, err := topicNaming()
+	if err != nil {
+		return err
+	}
+
+	var repo repository.MediaRepository = mediaRepo
+	if *cacheBackend != "none" {
+		cached, err := newCachedRepo(ctx, app, mediaRepo, naming)
+		if err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+		repo = cached
+	}
+	if *auditLog {
+		// Снаружи кэша: состояние до изменения читается из базы
+		repo = audit.NewRepository(repo)
+	}
+
+	svc := service.New(repo, serviceOutbox(db, outboxRepo)).
+		WithRetryPolicy(domain.RetryPolicy{MaxAttempts: *maxAttempts}).
+		WithTxRetry(service.TxRetryPolicy{MaxAttempts: *dbTxAttempts, Transient: pg.IsTransient}).
+		WithCollections(pg.NewCollectionRepo(db)).
+		WithGrants(pg.NewGrantRepo(db)).
+		WithLogger(logger)
+
+	if *createTopics {
+		if err := ensureTopics(ctx, naming, logger); err != nil {
+			return err
+		}
+	}
+
+	shard, err := outboxShard()
+	if err != nil {
+		return err
+	}
+	publisherRepo := outboxRepo
+	if shard.Sharded() {
+		if publisherRepo, err = pg.InstrumentOutboxRepo(pgOutboxRepo.Shard(shard), instrumentCfg); err != nil {
+			return fmt.Errorf("instrument outbox repo: %w", err)
+		}
+	}
+	kafkaProducer, outboxPublisher, err := startOutboxPublisher(ctx, app, publisherRepo, naming)
+	if err != nil {
+		return err
+	}
+
+	if *retentionEvery > 0 {
+		job, err := newRetentionJob(db, svc, *retentionEvery, logger)
+		if err != nil {
+			return fmt.Errorf("retention job: %w", err)
+		}
+		app.Go(ctx, cli.Worker{Name: "retention_job", Run: job.Start})
+	}
+	if *archiveEvery > 0 {
+		archiver, err := outbox.NewArchiver(outbox.ArchiverConfig{
+			Store:     pgOutboxRepo,
+			After:     *archiveAfter,
+			Retention: *archiveRetention,
+			Interval:  *archiveEvery,
+			Logger:    logger,
+		})
+		if err != nil {
+			return fmt.Errorf("outbox archiver: %w", err)
+		}
+		worker, err := singleton(db, cli.Worker{Name: "outbox_archiver", Run: archiver.Start}, logger)
+		if err != nil {
+			return err
+		}
+		app.Go(ctx, worker)
+	}
+	if *scheduleEvery > 0 {
+		job, err := newScheduleJob(db, svc, *scheduleEvery, logger)
+		if err != nil {
+			return fmt.Errorf("schedule job: %w", err)
+		}
+		worker, err := singleton(db, cli.Worker{Name: "schedule_job", Run: job.Start}, logger)
+		if err != nil {
+			return err
+		}
+		app.Go(ctx, worker)
+	}
+
+	dbHealth, err := pg.NewHealth(pg.HealthConfig{Pool: pool, Name: "primary", Interval: *dbHealthEvery, Logger: logger})
+	if err != nil {
+		return fmt.Errorf("db health: %w", err)
+	}
+	app.Go(ctx, cli.Worker{Name: "postgres_health", Run: dbHealth.Run})
+
+	h := httpapi.New(svc).
+		WithLogger(logger).
+		WithReadinessCheck("postgres", dbHealth.Check).
+		WithReadinessCheck("outbox_backlog", outboxPublisher.CheckBacklog).
+		WithOutboxBacklog(outboxRepo)
+	if *statusStream {
+		hub, err := newStatusStream(ctx, app, naming)
+		if err != nil {
+			return fmt.Errorf("status stream: %w", err)
+		}
+		h.WithStream(hub)
+	}
+	replayer, err := replay.New(replay.Config{Store: outboxRepo, Producer: kafkaProducer, Topic: *replayTopic, Logger: logger})
+	if err != nil {
+		return fmt.Errorf("event replay: %w", err)
+	}
+	admin := httpapi.NewAdmin(outboxRepo).WithReplay(replayer)
+	if *auditLog {
+		auditRepo := pg.NewAuditRepo(db)
+		h.WithAudit(auditRepo)
+		admin.WithAudit(auditRepo)
+	}
+	if *idempotencyTTL > 0 {
+		withIdempotency(ctx, app, h, pg.NewIdempotencyRepo(db))
+	}
+	if *eventStore {
+		admin.WithEventStore(repos.NewMediaEventsRepo(db))
+
+		runner, err := newProjectionRunner(db, logger)
+		if err != nil {
+			return err
+		}
+		app.Go(ctx, cli.Worker{Name: "projections", Run: runner.Start})
+		h.WithStats(repos.NewProjectionsRepo(db))
+	}
+	if *ownerPurge {
+		purger, err := startOwnerPurge(ctx, app, db, logger)
+		if err != nil {
+			return fmt.Errorf("owner purge: %w", err)
+		}
+		admin.WithPurge(purger)
+	}
+	return serve(ctx, app, h, httpapi.NewAdminRouter(admin))
+}
+
+// startOutboxPublisher создаёт producer событий и запускает publisher outbox'а store под
+// supervisor'ом. Сигнал publisher не отменяет: при остановке он дренируется через Stop
+// после HTTP сервера, иначе batch оборвётся на середине.
+// outboxShard — шард outbox, который публикует этот инстанс (-outbox-shard, -outbox-shards)
+func outboxShard() (pg.OutboxShard, error) {
+	s := pg.OutboxShard{Index: *outboxShardIndex, Count: *outboxShards}
+	if err := s.Validate(); err != nil {
+		return s, fmt.Errorf("outbox shard: %w", err)
+	}
+	return s, nil
+}
+
+func startOutboxPublisher(ctx context.Context, app *cli.App, store outbox.Store, naming kafka.TopicNaming) (*kafka.Producer, *outbox.Publisher, error) {
+	interceptors, err := producerInterceptors()
+	if err != nil {
+		return nil, nil, err
+	}
+	kafkaProducer, err := kafka.NewProducer(kafka.ProducerConfig{
+		Brokers:         []string{"localhost:9092"}, // брокеры из docker-compose
+		Topic:           naming.Base,
+		TopicRouter:     naming.Router(),
+		Format:          kafka.Format(*kafkaFormat),
+		Async:           *kafkaAsync,
+		KeyBatch:        kafka.KeyBatchConfig{MaxMessages: *keyBatch, MaxDelay: *keyBatchDelay},
+		Interceptors:    interceptors,
+		Compression:     kafka.Compression(*kafkaCompression),
+		MaxMessageBytes: *kafkaMaxMessage,
+		SchemaRegistry: kafka.SchemaRegistryConfig{
+			URL:             os.Getenv("SCHEMA_REGISTRY_URL"),
+			Username:        os.Getenv("SCHEMA_REGISTRY_USERNAME"),
+			Password:        os.Getenv("SCHEMA_REGISTRY_PASSWORD"),
+			SubjectStrategy: kafka.SubjectStrategy(*subjectStrategy),
+		},
+		Breaker: kafka.BreakerConfig{
+			FailureThreshold: *breakerThreshold,
+			CoolDown:         *breakerCoolDown,
+		},
+		Logger: app.Logger,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("kafka producer: %w", err)
+	}
+	// Publisher.Stop уже сбрасывает producer; здесь он закрывается, если drain не дошёл до flush
+	app.Register(cli.Component{
+		Name:     "kafka_producer",
+		Priority: cli.StopProducers,
+		Timeout:  30 * time.Second,
+		Stop: func(ctx context.Context) error {
+			if err := kafkaProducer.Shutdown(ctx); err != nil && !errors.Is(err, kafka.ErrProducerClosed) {
+				return err
+			}
+			return nil
+		},
+	})
+
+	outboxPublisher, err := outbox.NewPublisher(outbox.PublisherConfig{
+		OutboxRepo:       store,
+		Producer:         kafkaProducer,
+		Interval:         *outboxInterval, // пауза после неполного batch'а
+		MaxInterval:      *outboxMaxIdle,  // потолок backoff при пустом outbox
+		BatchSize:        *outboxBatchSize,
+		BacklogThreshold: *outboxBacklogMax,
+		MaxAttempts:      *outboxAttempts,
+		Logger:           app.Logger,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("outbox publisher: %w", err)
+	}
+	if err := outboxPublisher.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return nil, nil, fmt.Errorf("outbox metrics: %w", err)
+	}
+	for _, k := range []config.Knob{
+		config.Duration("outbox-interval", *outboxInterval, time.Millisecond, outboxPublisher.SetInterval),
+		config.Int("outbox-batch-size", *outboxBatchSize, 1, outboxPublisher.SetBatchSize),
+	} {
+		if err := app.Config.Subscribe(k); err != nil {
+			return nil, nil, fmt.Errorf("outbox config: %w", err)
+		}
+	}
+
+	app.Go(ctx, cli.Worker{
+		Name: "outbox_publisher",
+		Run: func(ctx context.Context) error {
+			return outboxPublisher.Start(context.WithoutCancel(ctx))
+		},
+	})
+	app.Register(cli.Component{
+		Name:     "outbox_publisher",
+		Priority: cli.StopConsumers,
+		Timeout:  *outboxDrain,
+		Stop:     outboxPublisher.Stop,
+	})
+	return kafkaProducer, outboxPublisher, nil
+}
+
+// startOwnerPurge собирает удаление данных владельцев и запускает worker его очереди jobs.
+// Задачи выполняются по одной: удаление не срочное, а нагрузка на базу и S3 заметная.
+func startOwnerPurge(ctx context.Context, app *cli.App, db *sqlx.DB, logger zerolog.Logger) (*purge.Purger, error) {
+	blobs, err := blobStore()
+	if err != nil {
+		return nil, err
+	}
+	queue := pg.NewJobsRepo(db)
+	purger, err := purge.New(purge.Config{
+		Store:      pg.NewPurgeRepo(db),
+		Jobs:       queue,
+		Blobs:      blobs,
+		Renditions: *purgeRenditions,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	worker, err := jobs.NewWorker(jobs.WorkerConfig{
+		Store:       queue,
+		Queue:       purge.Queue,
+		Handlers:    map[string]jobs.Handler{purge.Kind: purger.Handler()},
+		Concurrency: 1,
+		Logger:      logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := worker.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return nil, err
+	}
+	app.Go(ctx, cli.Worker{Name: "owner_purge", Run: worker.Start})
+	app.Register(cli.Component{Name: "owner_purge", Priority: cli.StopConsumers, Stop: worker.Wait})
+	return purger, nil
+}
+
+// newProjectionRunner — проекции журнала событий media_events с состоянием в Postgres
+func newProjectionRunner(db *sqlx.DB, logger zerolog.Logger) (*projection.Runner, error) {
+	state := repos.NewProjectionsRepo(db)
+	runner, err := projection.NewRunner(projection.Config{
+		Feed:        repos.NewMediaEventsRepo(db),
+		Checkpoints: state,
+		Tx:          repos.NewTxManager(db),
+		Projections: []projection.Projection{projection.NewMediaCounts(state), projection.NewOwnerUsage(state)},
+		Interval:    *projectionEvery,
+		Logger:      logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("projections: %w", err)
+	}
+	return runner, nil
+}
+
+// serviceOutbox — куда сервис пишет доменные события: outbox, а с -event-store ещё и
+// журнал событий media_events в той же транзакции
+func serviceOutbox(db *sqlx.DB, outboxRepo service.Outbox) service.Outbox {
+	if !*eventStore {
+		return outboxRepo
+	}
+	return eventstore.NewRecorder(outboxRepo, repos.NewMediaEventsRepo(db))
+}
+
+// newRetentionJob собирает retention job поверх Postgres и хранилища исходников из -blob-store
+func newRetentionJob(db *sqlx.DB, svc *service.Service, interval time.Duration, logger zerolog.Logger) (*retention.Job, error) {
+	blobs, err := blobStore()
+	if err != nil {
+		return nil, err
+	}
+	return retention.NewJob(retention.JobConfig{
+		Store:    pg.NewRetentionRepo(db),
+		Blobs:    blobs,
+		Media:    svc,
+		Interval: interval,
+		Logger:   logger,
+	})
+}
+
+// httpServer — флаги -http-*. Потоки SSE и прокси скачивания отвечают дольше любого
+// предела, поэтому ReadTimeout и WriteTimeout по умолчанию нет: истёкший ReadTimeout
+// отменяет контекст запроса и на ответе, который ещё пишется.
+var httpServer = config.HTTPServerFlags(flag.CommandLine, httpServerDefaults())
+
+func httpServerDefaults() config.HTTPServer {
+	c := config.DefaultHTTPServer()
+	c.ReadTimeout, c.WriteTimeout = 0, 0
+	c.MaxBodyBytes = 8 << 20
+	return c
+}
+
+// idempotencyCleanupInterval — период удаления истёкших ключей Idempotency-Key
+const idempotencyCleanupInterval = 10 * time.Minute
+
+// withIdempotency включает Idempotency-Key на публичном API и чистку истёкших ключей
+func withIdempotency(ctx context.Context, app *cli.App, h *httpapi.Handler, store idempotency.Store) {
+	h.WithIdempotency(store, *idempotencyTTL)
+	app.Go(ctx, cli.Worker{
+		Name: "idempotency_cleanup",
+		Run: func(ctx context.Context) error {
+			return idempotency.Cleanup(ctx, store, idempotencyCleanupInterval, app.Logger)
+		},
+	})
+}
+
+// singleton оставляет воркер, который должен работать на одной реплике, только лидеру выборов по
+// advisory lock Postgres с именем воркера (-leader-election)
+func singleton(db *sqlx.DB, w cli.Worker, logger zerolog.Logger) (cli.Worker, error) {
+	if !*leaderElection {
+		return w, nil
+	}
+	leader, err := cli.NewLeader(cli.LeaderConfig{
+		Name:   w.Name,
+		Lock:   pg.NewLeaderLock(db, w.Name),
+		Lease:  *leaderLease,
+		Logger: logger,
+	})
+	if err != nil {
+		return w, fmt.Errorf("%s leader election: %w", w.Name, err)
+	}
+	return leader.Guard(w), nil
+}
+
+// newScheduleJob собирает планировщик публикации поверх Postgres
+func newScheduleJob(db *sqlx.DB, svc *service.Service, interval time.Duration, logger zerolog.Logger) (*schedule.Job, error) {
+	return schedule.NewJob(schedule.JobConfig{
+		Store:    pg.NewScheduleRepo(db),
+		Media:    svc,
+		Interval: interval,
+		Logger:   logger,
+	})
+}
+
+// blobStore — хранилище исходников из -blob-store
+func blobStore() (blob.Store, error) {
+	switch *blobBackend {
+	case "none":
+		return blob.NopStore{}, nil
+	case "s3", "gcs", "azure":
+		store, _, err := objectStore(nil)
+		return store, err
+	case "local":
+		if *localSourceRoot == "" {
+			return nil, errors.New("-blob-store local needs -local-source-root")
+		}
+		return localStore()
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", *blobBackend)
+	}
+}
+
+// localStore — файлы под -local-source-root
+func localStore() (*blob.LocalStore, error) {
+	store, err := blob.NewLocalStore(blob.LocalConfig{
+		Root:       *localSourceRoot,
+		Sharded:    *localSharded,
+		ArchiveDir: *localArchiveDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("blob store: %w", err)
+	}
+	return store, nil
+}
+
+// cloudStore — объектное хранилище: переносит и удаляет исходники, читает их для proxy
+// и подписывает ссылки на скачивание
+type cloudStore interface {
+	blob.Store
+	blob.Reader
+	download.Presigner
+}
+
+// objectStore — объектное хранилище из -blob-store (s3, gcs, azure) и схема его source;
+// credentials берутся из окружения, client nil — клиент по умолчанию (timeout 30s)
+func objectStore(client *http.Client) (cloudStore, string, error) {
+	var (
+		store  cloudStore
+		scheme string
+		err    error
 	)
+	switch *blobBackend {
+	case "s3":
+		store, scheme, err = s3Store(client)
+	case "gcs":
+		scheme = "gs"
+		store, err = blob.NewGCSStore(blob.GCSConfig{
+			Endpoint:      os.Getenv("GCS_ENDPOINT"),
+			AccessID:      os.Getenv("GCS_HMAC_ACCESS_ID"),
+			Secret:        os.Getenv("GCS_HMAC_SECRET"),
+			ArchiveBucket: *gcsArchiveBucket,
+			StorageClass:  *gcsArchiveClass,
+			PartSize:      *s3PartSize,
+			Concurrency:   *s3Concurrency,
+			HTTPClient:    client,
+		})
+	case "azure":
+		scheme = "azure"
+		store, err = blob.NewAzureStore(blob.AzureConfig{
+			Account:          os.Getenv("AZURE_STORAGE_ACCOUNT"),
+			Key:              os.Getenv("AZURE_STORAGE_KEY"),
+			Endpoint:         os.Getenv("AZURE_BLOB_ENDPOINT"),
+			ArchiveContainer: *azureArchive,
+			AccessTier:       *azureAccessTier,
+			PartSize:         *s3PartSize,
+			Concurrency:      *s3Concurrency,
+			HTTPClient:       client,
+		})
+	default:
+		return nil, "", fmt.Errorf("blob store %q is not an object storage", *blobBackend)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("blob store: %w", err)
+	}
+	return store, scheme, nil
+}
 
-	// Запускаем publisher в отдельной горутине
-	go func() {
-		if err := outboxPublisher.Start(ctx); err != nil {
-			log.Printf("Outbox publisher error: %v", err)
+// s3Store — S3 из переменных окружения
+func s3Store(client *http.Client) (*blob.S3Store, string, error) {
+	store, err := blob.NewS3Store(blob.S3Config{
+		Endpoint:        os.Getenv("S3_ENDPOINT"),
+		Region:          os.Getenv("S3_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		ArchiveBucket:   *archiveBucket,
+		StorageClass:    *archiveClass,
+		PartSize:        *s3PartSize,
+		Concurrency:     *s3Concurrency,
+		HTTPClient:      client,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return store, "s3", nil
+}
+
+// shareLinks собирает выдачу анонимных ссылок; без SHARE_URL_SECRET ручки выключены.
+// Ссылки ведут на тот же внешний адрес, что и proxy скачивания (-download-base-url).
+func shareLinks() (*share.Links, error) {
+	secret := os.Getenv("SHARE_URL_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	return share.New(share.Config{
+		Secret:  []byte(secret),
+		BaseURL: *downloadBaseURL,
+		TTL:     *shareTTL,
+		MaxTTL:  *shareMaxTTL,
+	})
+}
+
+// corsPolicyFile — политика CORS в -cors-config
+type corsPolicyFile struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           string   `json:"max_age"` // длительность Go: 10m
+}
+
+func (p corsPolicyFile) policy() (httpapi.CORSPolicy, error) {
+	policy := httpapi.CORSPolicy{
+		AllowedOrigins:   p.AllowedOrigins,
+		AllowedMethods:   p.AllowedMethods,
+		AllowedHeaders:   p.AllowedHeaders,
+		ExposedHeaders:   p.ExposedHeaders,
+		AllowCredentials: p.AllowCredentials,
+	}
+	if p.MaxAge != "" {
+		d, err := time.ParseDuration(p.MaxAge)
+		if err != nil {
+			return httpapi.CORSPolicy{}, fmt.Errorf("max_age: %w", err)
 		}
-	}()
+		policy.MaxAge = d
+	}
+	return policy, nil
+}
+
+// corsRules собирает правила CORS из -cors-config или -cors-origins; без них CORS выключен.
+// Формат файла: {"default": {политика}, "routes": {"/media/{id}/status": {политика}}}.
+func corsRules() (*httpapi.CORSRules, error) {
+	if *corsConfig == "" {
+		if *corsOrigins == "" {
+			return nil, nil
+		}
+		return httpapi.NewCORSRules(httpapi.CORSConfig{Default: httpapi.CORSPolicy{
+			AllowedOrigins:   strings.Split(*corsOrigins, ","),
+			AllowCredentials: *corsCredentials,
+			MaxAge:           *corsMaxAge,
+		}})
+	}
+
+	f, err := os.Open(*corsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var file struct {
+		Default corsPolicyFile            `json:"default"`
+		Routes  map[string]corsPolicyFile `json:"routes"`
+	}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", *corsConfig, err)
+	}
+	cfg := httpapi.CORSConfig{Routes: make(map[string]httpapi.CORSPolicy, len(file.Routes))}
+	if cfg.Default, err = file.Default.policy(); err != nil {
+		return nil, fmt.Errorf("%s: default: %w", *corsConfig, err)
+	}
+	for pattern, p := range file.Routes {
+		if cfg.Routes[pattern], err = p.policy(); err != nil {
+			return nil, fmt.Errorf("%s: route %s: %w", *corsConfig, pattern, err)
+		}
+	}
+	return httpapi.NewCORSRules(cfg)
+}
+
+// downloadLinks собирает выдачу ссылок на скачивание; без DOWNLOAD_URL_SECRET ручки выключены.
+// Исходники объектного хранилища (-blob-store s3, gcs, azure) отдаются presigned URL,
+// file:// из -local-source-root — через proxy.
+func downloadLinks(app *cli.App) (*download.Links, error) {
+	secret := os.Getenv("DOWNLOAD_URL_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	cfg := download.Config{
+		Secret:  []byte(secret),
+		BaseURL: *downloadBaseURL,
+		TTL:     *downloadTTL,
+		MaxTTL:  *downloadMaxTTL,
+	}
+	sources := blob.Readers{}
+	switch *blobBackend {
+	case "s3", "gcs", "azure":
+		// Proxy читает объект столько, сколько идёт ответ клиенту: без общего timeout
+		store, scheme, err := objectStore(&http.Client{})
+		if err != nil {
+			return nil, err
+		}
+		cfg.Presigner = store
+		sources[scheme] = store
+	}
+	if *localSourceRoot != "" {
+		local, err := localStore()
+		if err != nil {
+			return nil, err
+		}
+		app.Register(cli.Component{
+			Name:     "local_sources",
+			Priority: cli.StopStorage,
+			Stop:     func(context.Context) error { return local.Close() },
+		})
+		sources["file"] = local
+	}
+	if len(sources) > 0 {
+		cfg.Sources = sources
+	}
+	return download.New(cfg)
+}
+
+// primaryPoolConfig — настройки пула primary из DATABASE_URL и флагов -db-*
+func primaryPoolConfig() (pg.PoolConfig, error) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		return pg.PoolConfig{}, fmt.Errorf("DATABASE_URL is empty")
+	}
+	return pg.PoolConfig{
+		DSN:                    dsn,
+		MaxConns:               int32(*dbMaxConns),
+		MinConns:               int32(*dbMinConns),
+		StatementCacheCapacity: *dbStmtCache,
+		QueryTimeout:           *dbQueryTimeout,
+		StartupWait:            *dbStartupWait,
+	}, nil
+}
+
+// openPrimary подключается к primary; пул закрывается при остановке App
+func openPrimary(ctx context.Context, app *cli.App, cfg pg.PoolConfig) (*sqlx.DB, *pgxpool.Pool, error) {
+	cfg.Logger = app.Logger
+	pool, err := pg.NewPool(ctx, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("db connect: %w", err)
+	}
+	db := pg.OpenDB(pool)
+	registerDB(app, "postgres_primary", db, pool)
+	return db, pool, nil
+}
+
+// openPrimaryFromEnv — openPrimary для служебных команд
+func openPrimaryFromEnv(ctx context.Context, app *cli.App) (*sqlx.DB, *pgxpool.Pool, error) {
+	cfg, err := primaryPoolConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	return openPrimary(ctx, app, cfg)
+}
+
+// registerDB закрывает sqlx обёртку и пул последними, когда все, кто пишет в базу, остановлены
+func registerDB(app *cli.App, name string, db io.Closer, pool *pgxpool.Pool) {
+	app.Register(cli.Component{
+		Name:     name,
+		Priority: cli.StopStorage,
+		Stop: func(context.Context) error {
+			err := db.Close()
+			pool.Close()
+			return err
+		},
+	})
+}
+
+// newCachedRepo оборачивает репозиторий кэшем и подписывает его на события media,
+// чтобы инвалидировать записи, изменённые другими инстансами.
+func newCachedRepo(ctx context.Context, app *cli.App, repo repository.MediaRepository, naming kafka.TopicNaming) (*cache.Repository, error) {
+	logger := app.Logger
+	var c cache.Cache
+	switch *cacheBackend {
+	case "lru":
+		lru, err := cache.NewLRU(*cacheSize, *cacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		c = lru
+	case "redis":
+		rc, err := cache.NewRedis(cache.RedisConfig{
+			Client: redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_ADDR")}),
+			TTL:    *cacheTTL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		c = rc
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", *cacheBackend)
+	}
+
+	cached, err := cache.NewRepository(cache.RepositoryConfig{Repo: repo, Cache: c, Logger: logger})
+	if err != nil {
+		return nil, err
+	}
+	if err := cached.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return nil, fmt.Errorf("register metrics: %w", err)
+	}
+
+	// Своя группа на инстанс: каждый инстанс должен увидеть все события
+	hostname, _ := os.Hostname()
+	decryption, err := eventCipher()
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := kafka.NewConsumer(kafka.ConsumerConfig{
+		Brokers:    []string{"localhost:9092"},
+		Topics:     naming.Topics(events.Default.Types()...),
+		GroupID:    "media-cache-" + hostname,
+		Decryption: decryption,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalidation consumer: %w", err)
+	}
+
+	// Run завершается по отмене ctx, до остановки компонентов; reader закрывается следом
+	app.Go(ctx, cli.Worker{
+		Name: "cache_invalidation_consumer",
+		Run: func(ctx context.Context) error {
+			return consumer.Run(ctx, func(ctx context.Context, msg kafka.Message) error {
+				return cached.InvalidateOnEvent(ctx, msg.Headers[kafka.HeaderEventType], msg.Headers[kafka.HeaderAggregateID])
+			})
+		},
+	})
+	app.Register(cli.Component{
+		Name:     "cache_invalidation_consumer",
+		Priority: cli.StopConsumers,
+		Stop:     func(context.Context) error { return consumer.Close() },
+	})
+
+	return cached, nil
+}
+
+// newStatusStream подписывает Hub потока изменений на события media. Как и инвалидация кэша,
+// каждый инстанс читает все события своей группой: клиент может быть подключён к любому.
+func newStatusStream(ctx context.Context, app *cli.App, naming kafka.TopicNaming) (*stream.Hub, error) {
+	hub := stream.NewHub()
+	hostname, _ := os.Hostname()
+	decryption, err := eventCipher()
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := kafka.NewConsumer(kafka.ConsumerConfig{
+		Brokers:    []string{"localhost:9092"},
+		Topics:     naming.Topics(events.Default.Types()...),
+		GroupID:    "media-stream-" + hostname,
+		Decryption: decryption,
+		Logger:     app.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	app.Go(ctx, cli.Worker{
+		Name: "status_stream_consumer",
+		Run: func(ctx context.Context) error {
+			return consumer.Run(ctx, func(_ context.Context, msg kafka.Message) error {
+				return hub.NotifyEvent(msg.Headers[kafka.HeaderEventType], msg.Headers[kafka.HeaderAggregateID])
+			})
+		},
+	})
+	app.Register(cli.Component{
+		Name:     "status_stream_consumer",
+		Priority: cli.StopConsumers,
+		Stop:     func(context.Context) error { return consumer.Close() },
+	})
+	return hub, nil
+}
+
+// runMemory поднимает сервис без Postgres и Kafka — для демо и локальной разработки.
+// Outbox в этом режиме нет, события не публикуются.
+func runMemory(ctx context.Context, app *cli.App) error {
+	logger := app.Logger
+	mediaRepo := repository.NewMemoryRepository()
+
+	if *snapshotFile != "" {
+		snapshotter, err := repository.NewSnapshotter(mediaRepo, repository.SnapshotConfig{
+			Path:     *snapshotFile,
+			Interval: *snapshotInterval,
+			MaxBytes: *snapshotMaxBytes,
+			Logger:   logger,
+		})
+		if err != nil {
+			return fmt.Errorf("snapshotter: %w", err)
+		}
+		if err := snapshotter.Load(); err != nil {
+			return fmt.Errorf("load snapshot: %w", err)
+		}
+
+		// Финальный снапшот пишется по отмене ctx и должен успеть записаться до выхода из процесса
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = snapshotter.Start(ctx)
+		}()
+		app.Register(cli.Component{
+			Name:     "snapshotter",
+			Priority: cli.StopStorage,
+			Stop: func(ctx context.Context) error {
+				select {
+				case <-done:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+	}
+
+	// Шины событий нет: поток изменений питается напрямую от сервиса
+	hub := stream.NewHub()
+	svc := service.New(mediaRepo, hub).
+		WithCollections(repository.NewMemoryCollectionRepository()).
+		WithGrants(repository.NewMemoryGrantRepository()).
+		WithLogger(logger)
+	h := httpapi.New(svc).WithLogger(logger).WithStream(hub)
+	if *idempotencyTTL > 0 {
+		withIdempotency(ctx, app, h, idempotency.NewMemoryStore())
+	}
+	return serve(ctx, app, h, nil)
+}
+
+// serve поднимает HTTP сервер и блокируется до отмены ctx или падения сервера;
+// admin может быть nil (in-memory режим без outbox). Сервер останавливается первым
+// из компонентов App, до drain'а outbox и закрытия хранилищ.
+func serve(ctx context.Context, app *cli.App, h *httpapi.Handler, admin http.Handler) error {
+	links, err := downloadLinks(app)
+	if err != nil {
+		return fmt.Errorf("download links: %w", err)
+	}
+	if links != nil {
+		h.WithDownloads(links)
+	}
+	shares, err := shareLinks()
+	if err != nil {
+		return fmt.Errorf("share links: %w", err)
+	}
+	if shares != nil {
+		h.WithShareLinks(shares)
+	}
+	h.WithTrustedProxies(*trustedProxies)
+	h.WithCompression(*compressMinSize)
+	cors, err := corsRules()
+	if err != nil {
+		return fmt.Errorf("cors: %w", err)
+	}
+	if cors != nil {
+		h.WithCORS(cors)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if admin != nil {
+		mux.Handle("/admin/", admin)
+	}
+	mux.Handle("/", httpapi.NewRouter(h))
+
+	srv, err := httpServer.NewServer(":8081", mux)
+	if err != nil {
+		return err
+	}
+	// Shutdown ждёт активные запросы: потоки SSE сами не заканчиваются
+	srv.RegisterOnShutdown(h.CloseStreams)
 
 	errCh := make(chan error, 1)
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
+		if err := httpServer.ListenAndServe(srv); err != nil {
 			errCh <- err
 		}
 	}()
+	app.Register(cli.Component{Name: "http_server", Priority: cli.StopServers, Stop: srv.Shutdown})
 
 	select {
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("shutdown: %w", err)
-		}
 		return nil
-
 	case err := <-errCh:
 		if errors.Is(err, http.ErrServerClosed) {
 			return nil
@@ -92,3 +1066,47 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("listen and serve: %w", err)
 	}
 }
+
+// topicNaming собирает схему топиков событий из флагов -kafka-topic-*
+func topicNaming() (kafka.TopicNaming, error) {
+	naming := kafka.TopicNaming{
+		Strategy:     kafka.TopicStrategy(*topicStrategy),
+		Base:         "events.media",
+		TenantPrefix: *tenantPrefix,
+	}
+	for tenant := range strings.SplitSeq(*tenantTopics, ",") {
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			naming.Tenants = append(naming.Tenants, tenant)
+		}
+	}
+	if err := naming.Validate(); err != nil {
+		return kafka.TopicNaming{}, fmt.Errorf("kafka topic naming: %w", err)
+	}
+	return naming, nil
+}
+
+// ensureTopics создаёт топики сервиса, если их нет (-kafka-create-topics)
+func ensureTopics(ctx context.Context, naming kafka.TopicNaming, logger zerolog.Logger) error {
+	admin, err := kafka.NewTopicAdmin(kafka.TopicAdminConfig{
+		Brokers: []string{"localhost:9092"},
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("kafka topic admin: %w", err)
+	}
+	topics := naming.Topics(events.Default.Types()...)
+	specs := make([]kafka.TopicSpec, len(topics))
+	for i, topic := range topics {
+		specs[i] = kafka.TopicSpec{
+			Name:              topic,
+			Partitions:        *topicPartitions,
+			ReplicationFactor: *topicReplication,
+			Retention:         7 * 24 * time.Hour,
+		}
+	}
+	err = admin.EnsureTopics(ctx, specs...)
+	if err != nil {
+		return fmt.Errorf("kafka topics: %w", err)
+	}
+	return nil
+}
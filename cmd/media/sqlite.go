@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/storage/sqlite"
+)
+
+var (
+	sqliteFile    = flag.String("sqlite-file", "media.db", "sqlite storage: database file, created with the schema if missing (:memory: = lost on exit)")
+	sqlitePublish = flag.Bool("sqlite-publish", false, "sqlite storage: publish outbox events to kafka (default: events stay in the outbox table, no external dependencies)")
+)
+
+// runSQLite поднимает сервис одним бинарём поверх файла SQLite: без Postgres, а без
+// -sqlite-publish и без Kafka
+func runSQLite(ctx context.Context, app *cli.App) error {
+	if *outboxShards > 1 {
+		return fmt.Errorf("-outbox-shards: sqlite storage runs a single instance, sharding needs postgres or mysql")
+	}
+	db, err := sqlite.Open(ctx, *sqliteFile)
+	if err != nil {
+		return err
+	}
+	app.Register(cli.Component{
+		Name:     "sqlite",
+		Priority: cli.StopStorage,
+		Stop:     func(context.Context) error { return db.Close() },
+	})
+
+	c, err := eventCipher()
+	if err != nil {
+		return fmt.Errorf("event encryption: %w", err)
+	}
+	outboxRepo := sqlite.NewOutboxRepo(db).WithEncryption(c)
+	return serveSQL(ctx, app, sqlStore{
+		name:      "sqlite",
+		ping:      db.PingContext,
+		media:     sqlite.NewMediaRepo(db),
+		outbox:    outboxRepo,
+		pending:   outboxRepo,
+		transient: sqlite.IsTransient,
+		publish:   *sqlitePublish,
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	httpapi "github.com/romariotrain/media-platform/internal/media/httpapi"
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
+	"github.com/romariotrain/media-platform/internal/media/outbox"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// sqlStore — хранилище без возможностей Postgres (MySQL, SQLite): только media и outbox
+type sqlStore struct {
+	name      string // имя проверки готовности
+	ping      func(ctx context.Context) error
+	media     repository.MediaRepository
+	outbox    sqlOutbox
+	pending   outbox.Store // outbox publisher'а: с -outbox-shards — только события своего шарда
+	transient func(err error) bool
+	// publish — запустить publisher outbox'а; без него события копятся в outbox
+	publish bool
+}
+
+type sqlOutbox interface {
+	outbox.Store
+	service.Outbox
+}
+
+// serveSQL поднимает сервис поверх store. Возможности, завязанные на Postgres (-db-* кроме
+// -db-max-conns и -db-tx-attempts, реплика, кэш, аудит, журнал событий, задачи retention,
+// schedule и purge, коллекции, гранты, админка), выключены. Ключи Idempotency-Key хранятся
+// в памяти инстанса.
+func serveSQL(ctx context.Context, app *cli.App, store sqlStore) error {
+	logger := app.Logger
+	svc := service.New(store.media, store.outbox).
+		WithRetryPolicy(domain.RetryPolicy{MaxAttempts: *maxAttempts}).
+		WithTxRetry(service.TxRetryPolicy{MaxAttempts: *dbTxAttempts, Transient: store.transient}).
+		WithLogger(logger)
+
+	h := httpapi.New(svc).
+		WithLogger(logger).
+		WithReadinessCheck(store.name, store.ping).
+		WithOutboxBacklog(store.outbox)
+	if store.publish {
+		naming, err := topicNaming()
+		if err != nil {
+			return err
+		}
+		if *createTopics {
+			if err := ensureTopics(ctx, naming, logger); err != nil {
+				return err
+			}
+		}
+		_, outboxPublisher, err := startOutboxPublisher(ctx, app, store.pending, naming)
+		if err != nil {
+			return err
+		}
+		h.WithReadinessCheck("outbox_backlog", outboxPublisher.CheckBacklog)
+		if *statusStream {
+			hub, err := newStatusStream(ctx, app, naming)
+			if err != nil {
+				return fmt.Errorf("status stream: %w", err)
+			}
+			h.WithStream(hub)
+		}
+	}
+	if *idempotencyTTL > 0 {
+		withIdempotency(ctx, app, h, idempotency.NewMemoryStore())
+	}
+	return serve(ctx, app, h, nil)
+}
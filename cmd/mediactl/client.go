@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// client вызывает HTTP API сервисов платформы с заголовками профиля
+type client struct {
+	profile Profile
+	http    *http.Client
+}
+
+// apiError — ответ сервиса с кодом ошибки (формат apierr: code, message, request_id)
+type apiError struct {
+	Status    int            `json:"-"`
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	msg := fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+	if fields, ok := e.Details["fields"]; ok {
+		if b, err := json.Marshal(fields); err == nil {
+			msg += " " + string(b)
+		}
+	}
+	if e.RequestID != "" {
+		msg += " (request_id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// do отправляет запрос к base+path и раскладывает JSON ответ в out (nil — ответ не нужен).
+// Ответ не 2xx возвращается как *apiError.
+func (c *client) do(ctx context.Context, method, base, path string, body, out any) error {
+	if base == "" {
+		return errors.New("service url is not set in the profile")
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.profile.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.profile.Token)
+	}
+	if c.profile.Scopes != "" {
+		req.Header.Set("X-Scopes", c.profile.Scopes)
+	}
+	if c.profile.Actor != "" {
+		req.Header.Set("X-Actor", c.profile.Actor)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &apiError{Status: resp.StatusCode}
+		if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Code == "" {
+			apiErr.Code, apiErr.Message = http.StatusText(resp.StatusCode), strings.TrimSpace(string(raw))
+		}
+		return apiErr
+	}
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
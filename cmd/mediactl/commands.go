@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/media/httpapi"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/publish"
+	"github.com/romariotrain/media-platform/internal/quota"
+)
+
+var (
+	configPath  = flag.String("profiles", "", "profiles file (default $MEDIACTL_CONFIG or <user config dir>/mediactl/config.json)")
+	profileName = flag.String("profile", "", "profile to use (default $MEDIACTL_PROFILE or current in the profiles file)")
+	output      = flag.String("o", "table", "output format: table | json")
+	timeout     = flag.Duration("timeout", 30*time.Second, "request timeout")
+)
+
+// maxDeadLetters — сколько dead letter событий outbox stats запрашивает за раз (maxAdminLimit media)
+const maxDeadLetters = 500
+
+// commands — команды mediactl
+func commands() []*cli.Command {
+	return []*cli.Command{
+		mediaCommand(),
+		outboxCommand(),
+		webhookCommand(),
+		quotaCommand(),
+	}
+}
+
+// call выполняет fn с клиентом выбранного профиля и таймаутом -timeout
+func call(ctx context.Context, fn func(ctx context.Context, c *client) error) error {
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("%w: unknown output format %q", cli.ErrUsage, *output)
+	}
+	p, err := loadProfile(*configPath, *profileName)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	return fn(ctx, &client{profile: p, http: http.DefaultClient})
+}
+
+// show печатает v таблицей table или, с -o json, как JSON
+func show(v any, table func(w *tabwriter.Writer)) error {
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+func parseUUID(what, s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid %s %q", cli.ErrUsage, what, s)
+	}
+	return id, nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func mediaCommand() *cli.Command {
+	var query, status, reason string
+	var tags stringList
+	var limit, offset int
+	return &cli.Command{
+		Name:    "media",
+		Summary: "read media and change their status",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "get",
+				Args:    "<id>",
+				Summary: "print a media, as GET /media/{id}",
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 1); err != nil {
+						return err
+					}
+					id, err := parseUUID("media id", args[0])
+					if err != nil {
+						return err
+					}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						var m httpapi.MediaResponse
+						if err := c.do(ctx, http.MethodGet, c.profile.MediaURL, "/media/"+id.String(), nil, &m); err != nil {
+							return err
+						}
+						return show(m, func(w *tabwriter.Writer) { printMedia(w, m) })
+					})
+				},
+			},
+			{
+				Name:    "list",
+				Summary: "list media matching a full-text query, tags and status, as GET /media/search",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&query, "q", "", "full-text query over title, tags and metadata")
+					fs.Var(&tags, "tag", "only media with this tag (repeatable)")
+					fs.StringVar(&status, "status", "", "only media in this status")
+					fs.IntVar(&limit, "limit", 20, "page size")
+					fs.IntVar(&offset, "offset", 0, "page offset")
+				},
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					q := url.Values{"limit": {strconv.Itoa(limit)}, "offset": {strconv.Itoa(offset)}}
+					if query != "" {
+						q.Set("q", query)
+					}
+					if status != "" {
+						q.Set("status", status)
+					}
+					for _, t := range tags {
+						q.Add("tag", t)
+					}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						var resp httpapi.SearchMediaResponse
+						if err := c.do(ctx, http.MethodGet, c.profile.MediaURL, "/media/search?"+q.Encode(), nil, &resp); err != nil {
+							return err
+						}
+						return show(resp, func(w *tabwriter.Writer) {
+							fmt.Fprintln(w, "ID\tSTATUS\tTYPE\tTITLE\tUPDATED")
+							for _, hit := range resp.Items {
+								m := hit.Media
+								fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.ID, m.Status, m.Type, m.Title, formatTime(m.UpdatedAt))
+							}
+						})
+					})
+				},
+			},
+			{
+				Name:    "set-status",
+				Args:    "<id> <status>",
+				Summary: "change media status, as PATCH /media/{id}/status (-reason is required for failed)",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&reason, "reason", "", "reason recorded in the status history")
+				},
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 2); err != nil {
+						return err
+					}
+					id, err := parseUUID("media id", args[0])
+					if err != nil {
+						return err
+					}
+					req := httpapi.ChangeStatusRequest{Status: models.Status(args[1]), Reason: reason}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						var m httpapi.MediaResponse
+						if err := c.do(ctx, http.MethodPatch, c.profile.MediaURL, "/media/"+id.String()+"/status", req, &m); err != nil {
+							return err
+						}
+						return show(m, func(w *tabwriter.Writer) { printMedia(w, m) })
+					})
+				},
+			},
+		},
+	}
+}
+
+func printMedia(w *tabwriter.Writer, m httpapi.MediaResponse) {
+	fmt.Fprintf(w, "id\t%s\n", m.ID)
+	fmt.Fprintf(w, "status\t%s\n", m.Status)
+	fmt.Fprintf(w, "type\t%s\n", m.Type)
+	fmt.Fprintf(w, "title\t%s\n", m.Title)
+	fmt.Fprintf(w, "source\t%s\n", m.Source)
+	if m.OwnerID != uuid.Nil {
+		fmt.Fprintf(w, "owner\t%s\n", m.OwnerID)
+	}
+	if len(m.Tags) > 0 {
+		fmt.Fprintf(w, "tags\t%s\n", strings.Join(m.Tags, ", "))
+	}
+	if m.Checksum != "" {
+		fmt.Fprintf(w, "content\t%s %d bytes %s\n", m.ContentType, m.Size, m.Checksum)
+	}
+	fmt.Fprintf(w, "processing attempts\t%d\n", m.ProcessingAttempts)
+	if m.LastError != "" {
+		fmt.Fprintf(w, "last error\t%s\n", m.LastError)
+	}
+	fmt.Fprintf(w, "created\t%s\n", formatTime(m.CreatedAt))
+	fmt.Fprintf(w, "updated\t%s\n", formatTime(m.UpdatedAt))
+}
+
+// outboxStats — вывод outbox stats
+type outboxStats struct {
+	Pending          *int64                        `json:"pending,omitempty"` // нет, если media запущен без outbox backlog
+	DeadLetters      int                           `json:"dead_letters"`
+	DeadLettersMore  bool                          `json:"dead_letters_more"` // dead letter событий больше, чем показано
+	OldestDeadLetter *httpapi.OutboxRecordResponse `json:"oldest_dead_letter,omitempty"`
+}
+
+func outboxCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "outbox",
+		Summary: "inspect and repair the media outbox (needs scope admin)",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "stats",
+				Summary: "print pending and dead-lettered event counts, from GET /stats and /admin/outbox/dead-letters",
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 0); err != nil {
+						return err
+					}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						var stats httpapi.StatsResponse
+						if err := c.do(ctx, http.MethodGet, c.profile.MediaURL, "/stats", nil, &stats); err != nil {
+							return err
+						}
+						var dead httpapi.OutboxRecordsResponse
+						path := "/admin/outbox/dead-letters?limit=" + strconv.Itoa(maxDeadLetters)
+						if err := c.do(ctx, http.MethodGet, c.profile.MediaURL, path, nil, &dead); err != nil {
+							return err
+						}
+
+						res := outboxStats{
+							Pending:         stats.OutboxBacklog,
+							DeadLetters:     len(dead.Items),
+							DeadLettersMore: len(dead.Items) == maxDeadLetters,
+						}
+						for i, rec := range dead.Items {
+							if res.OldestDeadLetter == nil || rec.OccurredAt.Before(res.OldestDeadLetter.OccurredAt) {
+								res.OldestDeadLetter = &dead.Items[i]
+							}
+						}
+						return show(res, func(w *tabwriter.Writer) {
+							if res.Pending != nil {
+								fmt.Fprintf(w, "pending\t%d\n", *res.Pending)
+							} else {
+								fmt.Fprintf(w, "pending\tunknown (outbox backlog is not exposed by /stats)\n")
+							}
+							more := ""
+							if res.DeadLettersMore {
+								more = "+"
+							}
+							fmt.Fprintf(w, "dead letters\t%d%s\n", res.DeadLetters, more)
+							if old := res.OldestDeadLetter; old != nil {
+								fmt.Fprintf(w, "oldest dead letter\t%d %s %s: %s\n", old.ID, old.EventType, formatTime(old.OccurredAt), old.LastError)
+							}
+						})
+					})
+				},
+			},
+			{
+				Name:    "requeue",
+				Args:    "<id>...",
+				Summary: "return dead-lettered events to the publish queue, as POST /admin/outbox/{id}/requeue",
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if len(args) == 0 {
+						return fmt.Errorf("%w: at least one outbox id is required", cli.ErrUsage)
+					}
+					ids := make([]int64, len(args))
+					for i, arg := range args {
+						id, err := strconv.ParseInt(arg, 10, 64)
+						if err != nil || id <= 0 {
+							return fmt.Errorf("%w: invalid outbox id %q", cli.ErrUsage, arg)
+						}
+						ids[i] = id
+					}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						for _, id := range ids {
+							path := fmt.Sprintf("/admin/outbox/%d/requeue", id)
+							if err := c.do(ctx, http.MethodPost, c.profile.MediaURL, path, nil, nil); err != nil {
+								return fmt.Errorf("requeue %d: %w", id, err)
+							}
+							fmt.Printf("requeued %d\n", id)
+						}
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+func webhookCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "webhook",
+		Summary: "check notification channels of the publish service",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "test",
+				Args:    "<channel>",
+				Summary: "send a test notification to a channel, as POST /webhooks/{id}/test",
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 1); err != nil {
+						return err
+					}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						var d publish.DeliveryResponse
+						path := "/webhooks/" + url.PathEscape(args[0]) + "/test"
+						if err := c.do(ctx, http.MethodPost, c.profile.PublishURL, path, nil, &d); err != nil {
+							return err
+						}
+						if err := show(d, func(w *tabwriter.Writer) {
+							fmt.Fprintf(w, "delivery\t%s\n", d.ID)
+							fmt.Fprintf(w, "success\t%t\n", d.Success)
+							if d.StatusCode != 0 {
+								fmt.Fprintf(w, "status code\t%d\n", d.StatusCode)
+							}
+							fmt.Fprintf(w, "latency\t%dms\n", d.LatencyMS)
+							if d.Response != "" {
+								fmt.Fprintf(w, "response\t%s\n", d.Response)
+							}
+							if d.Error != "" {
+								fmt.Fprintf(w, "error\t%s\n", d.Error)
+							}
+						}); err != nil {
+							return err
+						}
+						if !d.Success {
+							return fmt.Errorf("test notification to %q failed: %s", args[0], d.Error)
+						}
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+func quotaCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "quota",
+		Summary: "inspect owner quotas of the quota service",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "show",
+				Args:    "<owner-id>",
+				Summary: "print owner usage, upload limit and plan, as GET /quota/{owner}",
+				Run: func(ctx context.Context, _ *cli.App, args []string) error {
+					if err := cli.ExactArgs(args, 1); err != nil {
+						return err
+					}
+					owner, err := parseUUID("owner id", args[0])
+					if err != nil {
+						return err
+					}
+					return call(ctx, func(ctx context.Context, c *client) error {
+						var q quota.OwnerQuotaResponse
+						if err := c.do(ctx, http.MethodGet, c.profile.QuotaURL, "/quota/"+owner.String(), nil, &q); err != nil {
+							return err
+						}
+						return show(q, func(w *tabwriter.Writer) {
+							up := q.Limits.Uploads
+							fmt.Fprintf(w, "owner\t%s\n", q.OwnerID)
+							fmt.Fprintf(w, "usage\t%d objects, %d bytes\n", q.Usage.Objects, q.Usage.Bytes)
+							fmt.Fprintf(w, "uploads\t%d of %d left per %v, next at %s\n",
+								up.Remaining, up.Limit, time.Duration(up.WindowSeconds)*time.Second, formatTime(up.ResetAt))
+							if p := q.Plan; p != nil {
+								fmt.Fprintf(w, "plan\t%s: %d objects, %d bytes\n", p.Name, p.MaxObjects, p.MaxBytes)
+							}
+						})
+					})
+				},
+			},
+		},
+	}
+}
+
+// stringList — повторяемый строковый флаг
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,23 @@
+// mediactl — консольный клиент HTTP API платформы для операторов: медиа, outbox,
+// уведомления и квоты. Адреса сервисов и токен берутся из профиля (см. Profile).
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/romariotrain/media-platform/internal/cli"
+)
+
+func main() {
+	// Логи клиента мешают выводу команд: по умолчанию только предупреждения
+	_ = flag.Set("log-level", "warn")
+	_ = flag.Set("log-format", "console")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+	os.Exit(cli.Run("mediactl", cli.Dispatch(args, commands()...)))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Profile — куда и от чьего имени ходит mediactl
+type Profile struct {
+	MediaURL   string `json:"media_url"`
+	QuotaURL   string `json:"quota_url"`
+	PublishURL string `json:"publish_url"`
+	// Token уходит в Authorization: Bearer — его проверяет gateway. Вместо самого токена
+	// можно указать переменную окружения в token_env, как у каналов -notify-config.
+	Token    string `json:"token,omitempty"`
+	TokenEnv string `json:"token_env,omitempty"`
+	// Scopes — X-Scopes для запросов к сервисам напрямую, без gateway (локальная разработка)
+	Scopes string `json:"scopes,omitempty"`
+	Actor  string `json:"actor,omitempty"` // X-Actor в журнале статусов; default: $USER
+}
+
+// profilesFile — файл профилей: {"current": "local", "profiles": {"local": {...}}}
+type profilesFile struct {
+	Current  string             `json:"current"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// localProfile — профиль без файла: сервисы, запущенные локально на портах по умолчанию
+var localProfile = Profile{
+	MediaURL:   "http://localhost:8081",
+	QuotaURL:   "http://localhost:8083",
+	PublishURL: "http://localhost:8084",
+	Scopes:     "admin",
+}
+
+// defaultConfigPath — $MEDIACTL_CONFIG или <user config dir>/mediactl/config.json
+func defaultConfigPath() string {
+	if p := os.Getenv("MEDIACTL_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mediactl", "config.json")
+}
+
+// loadProfile читает профиль name из файла path. Пустой name — $MEDIACTL_PROFILE, затем current.
+// Если path не задан явно и файла нет, используется localProfile.
+func loadProfile(path, name string) (Profile, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if name == "" {
+		name = os.Getenv("MEDIACTL_PROFILE")
+	}
+
+	var f profilesFile
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+		f.Profiles = map[string]Profile{"local": localProfile}
+	case err != nil:
+		return Profile{}, fmt.Errorf("read profiles: %w", err)
+	default:
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return Profile{}, fmt.Errorf("parse profiles %s: %w", path, err)
+		}
+	}
+
+	if name == "" {
+		name = f.Current
+	}
+	if name == "" && len(f.Profiles) == 1 {
+		for n := range f.Profiles {
+			name = n
+		}
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found, known: %s", name, strings.Join(slices.Sorted(maps.Keys(f.Profiles)), ", "))
+	}
+
+	if p.Token == "" && p.TokenEnv != "" {
+		p.Token = os.Getenv(p.TokenEnv)
+	}
+	if p.Actor == "" {
+		p.Actor = os.Getenv("USER")
+	}
+	return p, nil
+}
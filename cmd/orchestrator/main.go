@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 
 	"github.com/romariotrain/media-platform/internal/cli"
 )
 
 func main() {
-	code := cli.Run("orchestrator", func(ctx context.Context) error {
+	flag.Parse()
+	code := cli.Run("orchestrator", func(ctx context.Context, _ *cli.App) error {
 		<-ctx.Done()
 		return nil
 	})
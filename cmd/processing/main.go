@@ -2,15 +2,429 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/config"
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/locks"
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/processing/jobs"
+	"github.com/romariotrain/media-platform/internal/processing/packaging"
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/pkg/client"
+)
+
+// Queue — очередь задач processing в таблице jobs
+const Queue = "processing"
+
+var (
+	jobsConcurrency = flag.Int("jobs-concurrency", 4, "jobs: tasks processed concurrently")
+	jobsPoll        = flag.Duration("jobs-poll-interval", time.Second, "jobs: pause between polls of an empty queue")
+	jobsVisibility  = flag.Duration("jobs-visibility", 5*time.Minute, "jobs: lease of a claimed task, extended while it runs")
+	sourceDir       = flag.String("source-dir", "", "directory the source is downloaded to before transcoding (empty = not downloaded)")
+	blobBackend     = flag.String("blob-store", "s3", "where sources are downloaded from with -source-dir: s3 | gcs | azure")
+	s3PartSize      = flag.Int64("s3-part-size", blob.DefaultPartSize, "s3, gcs, azure: part size of parallel ranged downloads")
+	s3Concurrency   = flag.Int("s3-concurrency", blob.DefaultConcurrency, "s3, gcs, azure: parts of one source downloaded concurrently")
+	transcodeLock   = flag.String("transcode-lock", "postgres", "one transcode per media across instances: postgres | redis (REDIS_ADDR) | none")
+	transcodeTTL    = flag.Duration("transcode-lock-ttl", 30*time.Second, "transcode lock: lease renewed while transcoding; busy media is rescheduled by it")
+	mediaURL        = flag.String("media-url", "http://localhost:8081", "media service API: failed transcodes are reported to POST /media/{id}/failures")
+	retryDelay      = flag.Duration("transcode-retry-delay", 30*time.Second, "pause before a transcode retry requested by media")
+	transcodeEvents = flag.Bool("transcode-events", true, "kafka: enqueue transcode jobs from MediaCreated and MediaContentRecorded events")
+	mediaTopics     = flag.String("media-topics", "events.media", "kafka: comma-separated topics with media events")
+	packageDest     = flag.String("package-destination", "", "where transcoded media is packaged for streaming, s3://bucket/prefix (empty = not packaged)")
 )
 
 func main() {
-	code := cli.Run("processing", func(ctx context.Context) error {
+	flag.Parse()
+	code := cli.Run("processing", run)
+	os.Exit(code)
+}
+
+// run запускает worker очереди jobs, если задан DATABASE_URL; без него сервис простаивает
+func run(ctx context.Context, app *cli.App) error {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		app.Logger.Warn().Msg("DATABASE_URL is empty, jobs worker disabled")
 		<-ctx.Done()
 		return nil
+	}
+
+	pool, err := pg.NewPool(ctx, pg.PoolConfig{DSN: dsn})
+	if err != nil {
+		return fmt.Errorf("db connect: %w", err)
+	}
+	db := pg.OpenDB(pool)
+	app.Register(cli.Component{
+		Name:     "postgres",
+		Priority: cli.StopStorage,
+		Stop: func(context.Context) error {
+			err := db.Close()
+			pool.Close()
+			return err
+		},
 	})
-	os.Exit(code)
+
+	var (
+		sources  blob.Downloader
+		packager *packaging.Packager
+	)
+	if *sourceDir != "" || *packageDest != "" {
+		store, err := objectStore()
+		if err != nil {
+			return fmt.Errorf("blob store: %w", err)
+		}
+		if *sourceDir != "" {
+			sources = store
+		}
+		if *packageDest != "" {
+			packager, err = packaging.NewPackager(packaging.Config{
+				Sink:        store,
+				Destination: *packageDest,
+				Registry:    pg.NewRenditionsRepo(db),
+				Logger:      app.Logger,
+			})
+			if err != nil {
+				return fmt.Errorf("packager: %w", err)
+			}
+		}
+	}
+
+	locker, err := newLocker(app, db)
+	if err != nil {
+		return fmt.Errorf("transcode lock: %w", err)
+	}
+	if *retryDelay <= 0 {
+		return fmt.Errorf("-transcode-retry-delay must be positive, got: %v", *retryDelay)
+	}
+	// Попытки обработки считает media: processing сообщает о неудаче, а повтор откладывает без траты попытки задачи
+	media, err := client.New(client.Config{
+		BaseURL: *mediaURL,
+		Auth:    client.Principal{Actor: "processing", Scopes: []string{"internal"}},
+	})
+	if err != nil {
+		return fmt.Errorf("media client: %w", err)
+	}
+
+	store := pg.NewJobsRepo(db)
+	if *transcodeEvents {
+		if err := enqueueTranscodes(ctx, app, store); err != nil {
+			return err
+		}
+	}
+
+	worker, err := jobs.NewWorker(jobs.WorkerConfig{
+		Store:        store,
+		Queue:        Queue,
+		Handlers:     map[string]jobs.Handler{jobs.KindTranscode: transcode(app, sources, packager, locker, media)},
+		Concurrency:  *jobsConcurrency,
+		PollInterval: *jobsPoll,
+		Visibility:   *jobsVisibility,
+		Logger:       app.Logger,
+	})
+	if err != nil {
+		return err
+	}
+	if err := worker.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return err
+	}
+	if err := app.Config.Subscribe(config.Int("jobs-concurrency", *jobsConcurrency, 1, worker.SetConcurrency)); err != nil {
+		return err
+	}
+	app.Go(ctx, cli.Worker{Name: "jobs_worker", Run: worker.Start})
+	// Worker возвращает задачи в очередь при отмене ctx; пул закрывается после этого
+	app.Register(cli.Component{
+		Name:     "jobs_worker",
+		Priority: cli.StopConsumers,
+		Stop:     worker.Wait,
+	})
+
+	<-ctx.Done()
+	return nil
+}
+
+// enqueueTranscodes ставит задачи transcode по событиям media из -media-topics. Разбираются
+// только JSON конверты (-kafka-format json у media), как и в quota. Повторную доставку
+// отбрасывает очередь: UniqueKey задачи — id медиа.
+func enqueueTranscodes(ctx context.Context, app *cli.App, store jobs.Store) error {
+	decryption, err := encryption.FromEnv()
+	if err != nil {
+		return err
+	}
+	consumer, err := kafka.NewConsumer(kafka.ConsumerConfig{
+		Brokers:    []string{"localhost:9092"},
+		Topics:     strings.Split(*mediaTopics, ","),
+		GroupID:    "processing-transcode",
+		Decryption: decryption,
+		Logger:     app.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("media events consumer: %w", err)
+	}
+	app.Go(ctx, cli.Worker{
+		Name: "transcode_enqueuer",
+		Run: func(ctx context.Context) error {
+			return consumer.Run(ctx, func(ctx context.Context, msg kafka.Message) error {
+				if f := msg.Headers[kafka.HeaderContentFormat]; f != "" && f != "json" {
+					app.Logger.Warn().Str("format", f).Str("event_type", msg.Headers[kafka.HeaderEventType]).Msg("transcode: event skipped, only json is supported")
+					return nil
+				}
+				env, err := events.UnmarshalEnvelope(msg.Value)
+				if err != nil {
+					return err
+				}
+				job, ok, err := jobs.TranscodeFromEvent(Queue, env.EventType, env.Payload)
+				if err != nil || !ok {
+					return err
+				}
+				queued, err := store.Enqueue(ctx, job)
+				if err != nil {
+					return fmt.Errorf("enqueue transcode: %w", err)
+				}
+				app.Logger.Info().Int64("job_id", queued.ID).Str("media_id", job.UniqueKey).Str("event_type", env.EventType).Msg("transcode enqueued")
+				return nil
+			})
+		},
+	})
+	app.Register(cli.Component{
+		Name:     "transcode_enqueuer",
+		Priority: cli.StopConsumers,
+		Stop:     func(context.Context) error { return consumer.Close() },
+	})
+	return nil
+}
+
+// newLocker — блокировки -transcode-lock; nil — транскодирования одного медиа не исключают друг друга
+func newLocker(app *cli.App, db *sqlx.DB) (locks.Locker, error) {
+	if *transcodeTTL <= 0 {
+		return nil, fmt.Errorf("-transcode-lock-ttl must be positive, got: %v", *transcodeTTL)
+	}
+	switch *transcodeLock {
+	case "none":
+		return nil, nil
+	case "postgres":
+		return locks.NewPostgres(locks.PostgresConfig{DB: db})
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_ADDR")})
+		app.Register(cli.Component{Name: "redis", Priority: cli.StopStorage, Stop: func(context.Context) error {
+			return client.Close()
+		}})
+		return locks.NewRedis(locks.RedisConfig{Client: client})
+	default:
+		return nil, fmt.Errorf("unknown -transcode-lock %q", *transcodeLock)
+	}
+}
+
+// transcode — обработчик задачи transcode (MVP: имитация транскодирования). С sources
+// исходник сначала скачивается в -source-dir параллельными ranged GET. С locker медиа
+// транскодируется одной задачей на весь флот: задача медиа, которое уже транскодируется,
+// откладывается без траты попытки, а потеря блокировки прерывает транскодирование.
+// С packager результат упаковывается в -package-destination, а его renditions регистрируются.
+// Неудачное транскодирование сообщается media (reportFailure): повтор решает её счётчик попыток.
+func transcode(app *cli.App, sources blob.Downloader, packager *packaging.Packager, locker locks.Locker, media *client.Client) jobs.Handler {
+	return func(ctx context.Context, job jobs.Job) error {
+		var p jobs.TranscodePayload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return fmt.Errorf("decode transcode payload: %w", err)
+		}
+		if p.MediaID == "" {
+			return fmt.Errorf("transcode payload: media_id is required")
+		}
+
+		work := func(ctx context.Context) error {
+			if sources != nil && p.Source != "" {
+				file, err := downloadSource(ctx, app, sources, p)
+				if err != nil {
+					return err
+				}
+				defer os.Remove(file)
+			}
+			app.Logger.Info().
+				Int64("job_id", job.ID).
+				Str("media_id", p.MediaID).
+				Int("attempt", job.Attempts).
+				Msg("transcoding")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			if packager == nil {
+				return nil
+			}
+			return packageOutput(ctx, app, packager, p)
+		}
+		if locker == nil {
+			return reportFailure(ctx, app, media, job, p, work(ctx))
+		}
+
+		err := locks.Hold(ctx, locker, "transcode:"+p.MediaID, *transcodeTTL, func(ctx context.Context) error {
+			return reportFailure(ctx, app, media, job, p, work(ctx))
+		})
+		if errors.Is(err, locks.ErrNotAcquired) {
+			return jobs.Reschedule(*transcodeTTL, "media "+p.MediaID+" is already being transcoded")
+		}
+		return err
+	}
+}
+
+// reportFailure сообщает media об ошибке транскодирования err. Запрошенный media повтор
+// откладывает задачу на -transcode-retry-delay без траты попытки, исчерпанные попытки
+// завершают её: медиа уже failed. Пока media недоступна, задача повторяется очередью.
+// Остановка и потеря блокировки — не неудача обработки и не сообщаются.
+func reportFailure(ctx context.Context, app *cli.App, media *client.Client, job jobs.Job, p jobs.TranscodePayload, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	id, parseErr := uuid.Parse(p.MediaID)
+	if parseErr != nil {
+		return fmt.Errorf("transcode payload: invalid media_id: %w", parseErr)
+	}
+	// Каждый повтор и отсрочка переносят scheduled_at: ключ меняется только с новой попыткой,
+	// а задача, вернувшаяся в очередь после падения worker'а, не потратит попытку медиа дважды
+	key := fmt.Sprintf("transcode-failure:%d:%d", job.ID, job.ScheduledAt.UnixMicro())
+	m, reportErr := media.ReportFailure(client.WithIdempotencyKey(ctx, key), id, err.Error())
+	var apiErr *client.APIError
+	switch {
+	case errors.As(reportErr, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError && apiErr.StatusCode != http.StatusTooManyRequests:
+		// Медиа удалено или уже не в processing — повторять нечего
+		app.Logger.Warn().Err(err).Str("media_id", p.MediaID).Str("code", apiErr.Code).Msg("transcode failed, media does not await it")
+		return nil
+	case reportErr != nil:
+		return errors.Join(err, fmt.Errorf("report failure: %w", reportErr))
+	case m.Status == client.StatusProcessing:
+		return jobs.Reschedule(*retryDelay, fmt.Sprintf("media requested attempt %d: %v", m.ProcessingAttempts, err))
+	default:
+		app.Logger.Error().Err(err).Str("media_id", p.MediaID).Int("attempts", m.ProcessingAttempts).Msg("transcode failed, media processing attempts exhausted")
+		return nil
+	}
+}
+
+// store — хранилище исходников и результатов упаковки
+type store interface {
+	blob.Downloader
+	packaging.Sink
+}
+
+// objectStore — хранилище из -blob-store; credentials — те же переменные окружения,
+// что у media и ingest
+func objectStore() (store, error) {
+	// Часть большого исходника может идти долго; предел задаёт контекст задачи
+	client := &http.Client{}
+	switch *blobBackend {
+	case "s3":
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			PartSize:        *s3PartSize,
+			Concurrency:     *s3Concurrency,
+			HTTPClient:      client,
+		})
+	case "gcs":
+		return blob.NewGCSStore(blob.GCSConfig{
+			Endpoint:    os.Getenv("GCS_ENDPOINT"),
+			AccessID:    os.Getenv("GCS_HMAC_ACCESS_ID"),
+			Secret:      os.Getenv("GCS_HMAC_SECRET"),
+			PartSize:    *s3PartSize,
+			Concurrency: *s3Concurrency,
+			HTTPClient:  client,
+		})
+	case "azure":
+		return blob.NewAzureStore(blob.AzureConfig{
+			Account:     os.Getenv("AZURE_STORAGE_ACCOUNT"),
+			Key:         os.Getenv("AZURE_STORAGE_KEY"),
+			Endpoint:    os.Getenv("AZURE_BLOB_ENDPOINT"),
+			PartSize:    *s3PartSize,
+			Concurrency: *s3Concurrency,
+			HTTPClient:  client,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", *blobBackend)
+	}
+}
+
+// downloadSource скачивает исходник во временный файл -source-dir и возвращает его путь
+func downloadSource(ctx context.Context, app *cli.App, sources blob.Downloader, p jobs.TranscodePayload) (string, error) {
+	f, err := os.CreateTemp(*sourceDir, "source-"+p.MediaID+"-*")
+	if err != nil {
+		return "", fmt.Errorf("download source: %w", err)
+	}
+	started := time.Now()
+	size, err := sources.Download(ctx, p.Source, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("download source %s: %w", p.Source, err)
+	}
+
+	elapsed := time.Since(started)
+	app.Logger.Info().
+		Str("media_id", p.MediaID).
+		Int64("size", size).
+		Dur("elapsed", elapsed).
+		Float64("mbps", float64(size)*8/1e6/max(elapsed.Seconds(), 1e-3)).
+		Msg("source downloaded")
+	return f.Name(), nil
+}
+
+// packageOutput упаковывает результат транскодирования медиа. MVP: транскодирование имитируется,
+// поэтому упаковывается одна rendition 720p из сегмента-заглушки MPEG-TS во временном каталоге
+func packageOutput(ctx context.Context, app *cli.App, packager *packaging.Packager, p jobs.TranscodePayload) error {
+	id, err := uuid.Parse(p.MediaID)
+	if err != nil {
+		return fmt.Errorf("transcode payload: invalid media_id: %w", err)
+	}
+	dir, err := os.MkdirTemp(*sourceDir, "package-"+p.MediaID+"-*")
+	if err != nil {
+		return fmt.Errorf("package: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	segment := filepath.Join(dir, "segment0.ts")
+	if err := os.WriteFile(segment, nullPackets(64), 0o600); err != nil {
+		return fmt.Errorf("package: %w", err)
+	}
+	result, err := packager.Package(ctx, id, []packaging.Rendition{{
+		Name:      "720p",
+		Bandwidth: 2_500_000,
+		Width:     1280,
+		Height:    720,
+		Codecs:    "avc1.64001f,mp4a.40.2",
+		Segments:  []packaging.Segment{{Path: segment, Duration: 6 * time.Second}},
+	}})
+	if err != nil {
+		return fmt.Errorf("package: %w", err)
+	}
+	app.Logger.Info().Str("media_id", p.MediaID).Str("master", result.Master).Msg("packaged")
+	return nil
+}
+
+// nullPackets — n пустых пакетов MPEG-TS (PID 0x1FFF)
+func nullPackets(n int) []byte {
+	const size = 188
+	b := make([]byte, n*size)
+	for i := 0; i < len(b); i += size {
+		b[i], b[i+1], b[i+2], b[i+3] = 0x47, 0x1F, 0xFF, 0x10
+	}
+	return b
 }
@@ -2,15 +2,336 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/config"
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/events/inbox"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/publish"
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+var (
+	addr            = flag.String("addr", ":8084", "HTTP listen address (/health, /metrics, notification deliveries API)")
+	cdnProvider     = flag.String("cdn", "none", "CDN cache invalidation: none | cloudfront (CLOUDFRONT_DISTRIBUTION_ID, AWS_*) | fastly (FASTLY_SERVICE_ID, FASTLY_API_TOKEN)")
+	cdnPathPrefix   = flag.String("cdn-path-prefix", "/vod", "cloudfront: media directory in the distribution, purged as {prefix}/{media_id}/*")
+	fastlyKeyPrefix = flag.String("fastly-key-prefix", "", "fastly: surrogate key of media is {prefix}{media_id}")
+	fastlySoftPurge = flag.Bool("fastly-soft-purge", false, "fastly: mark objects stale instead of evicting them")
+	purgeInterval   = flag.Duration("purge-interval", 5*time.Second, "how long media changes are batched before a purge")
+	purgeAttempts   = flag.Int("purge-attempts", 5, "attempts per purge batch before it is dropped")
+	mediaTopics     = flag.String("media-topics", "events.media", "kafka: comma-separated topics with media events")
+	notifyConfig    = flag.String("notify-config", "", "JSON file with notification channels and subscriptions; empty disables notifications")
+	lagInterval     = flag.Duration("lag-interval", 30*time.Second, "kafka: consumer group lag check period")
+	lagThreshold    = flag.Int64("lag-threshold", 0, "kafka: consumer group lag above which /readyz fails (0 = disabled)")
+	inboxTTL        = flag.Duration("inbox-ttl", 7*24*time.Hour, "how long ids of handled media events are kept to skip redeliveries (DATABASE_URL); must exceed the media topics retention")
 )
 
+// httpServer — флаги -http-*
+var httpServer = config.HTTPServerFlags(flag.CommandLine, config.DefaultHTTPServer())
+
 func main() {
-	code := cli.Run("publish", func(ctx context.Context) error {
-		<-ctx.Done()
+	flag.Parse()
+	code := cli.Run("publish", run)
+	os.Exit(code)
+}
+
+func run(ctx context.Context, app *cli.App) error {
+	db, err := openDB(ctx, app)
+	if err != nil {
+		return err
+	}
+	var processed *inbox.Store
+	if db != nil {
+		if processed, err = startInbox(ctx, app, db); err != nil {
+			return err
+		}
+	}
+	if err := purgeCDN(ctx, app, processed); err != nil {
+		return err
+	}
+	dispatcher, err := notify(ctx, app, db, processed)
+	if err != nil {
+		return err
+	}
+	lag, err := lagMonitor(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write([]byte(`{"status":"ok"}` + "\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status, body := http.StatusOK, `{"status":"ready"}`
+		if lag != nil {
+			if err := lag.CheckLag(r.Context()); err != nil {
+				status = http.StatusServiceUnavailable
+				b, _ := json.Marshal(map[string]any{"status": "not_ready", "checks": map[string]string{"kafka_lag": err.Error()}})
+				body = string(b)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body + "\n"))
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+	if dispatcher != nil {
+		h, err := publish.NewHandler(publish.HandlerConfig{Dispatcher: dispatcher, Logger: app.Logger})
+		if err != nil {
+			return err
+		}
+		mux.Handle("/webhooks/", h)
+		mux.Handle("/deliveries/", h)
+	}
+	srv, err := httpServer.NewServer(*addr, mux)
+	if err != nil {
+		return err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe(srv)
+	}()
+	app.Register(cli.Component{Name: "http_server", Priority: cli.StopServers, Stop: srv.Shutdown})
+
+	select {
+	case <-ctx.Done():
 		return nil
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("listen and serve: %w", err)
+	}
+}
+
+func newInvalidator() (publish.CDNInvalidator, error) {
+	switch *cdnProvider {
+	case "cloudfront":
+		return publish.NewCloudFront(publish.CloudFrontConfig{
+			DistributionID:  os.Getenv("CLOUDFRONT_DISTRIBUTION_ID"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			PathPrefix:      *cdnPathPrefix,
+		})
+	case "fastly":
+		return publish.NewFastly(publish.FastlyConfig{
+			ServiceID: os.Getenv("FASTLY_SERVICE_ID"),
+			APIToken:  os.Getenv("FASTLY_API_TOKEN"),
+			KeyPrefix: *fastlyKeyPrefix,
+			Soft:      *fastlySoftPurge,
+		})
+	default:
+		return nil, fmt.Errorf("unknown -cdn %q", *cdnProvider)
+	}
+}
+
+// purgeCDN сбрасывает кэш CDN по событиям media
+func purgeCDN(ctx context.Context, app *cli.App, processed *inbox.Store) error {
+	if *cdnProvider == "none" {
+		app.Logger.Warn().Msg("-cdn is none, CDN cache invalidation disabled")
+		return nil
+	}
+	cdn, err := newInvalidator()
+	if err != nil {
+		return err
+	}
+	purger, err := publish.NewPurger(publish.PurgerConfig{
+		CDN:           cdn,
+		Provider:      *cdnProvider,
+		FlushInterval: *purgeInterval,
+		MaxAttempts:   *purgeAttempts,
+		Logger:        app.Logger,
 	})
-	os.Exit(code)
+	if err != nil {
+		return err
+	}
+	if err := purger.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return err
+	}
+
+	app.Go(ctx, cli.Worker{Name: "cdn_purger", Run: purger.Start})
+	err = consumeMedia(ctx, app, "publish-cdn", processed, func(_ context.Context, env events.Envelope) error {
+		id, ok, err := publish.MediaFromEvent(env.EventType, env.Payload)
+		if err != nil || !ok {
+			return err
+		}
+		purger.Enqueue(id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Consumer останавливается раньше: последний Flush сбрасывает всё, что он успел поставить
+	app.Register(cli.Component{Name: "cdn_purger", Priority: cli.StopProducers, Stop: purger.Flush})
+	return nil
+}
+
+// notify рассылает уведомления о событиях media по подпискам из -notify-config.
+// Отдельная группа consumer: уведомления не ждут CDN и наоборот. Без конфигурации — nil.
+func notify(ctx context.Context, app *cli.App, db *sqlx.DB, processed *inbox.Store) (*publish.Dispatcher, error) {
+	if *notifyConfig == "" {
+		return nil, nil
+	}
+	f, err := os.Open(*notifyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("notify config: %w", err)
+	}
+	channels, subs, err := publish.LoadNotifyConfig(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	// Журнал доставок — в базе media (sql/script.sql); без неё — последние попытки в памяти, до рестарта
+	var deliveries publish.DeliveryStore = publish.NewMemoryDeliveryStore(0)
+	if db != nil {
+		deliveries = pg.NewPublishDeliveriesRepo(db)
+	}
+	dispatcher, err := publish.NewDispatcher(publish.DispatcherConfig{
+		Channels:      channels,
+		Subscriptions: subs,
+		Deliveries:    deliveries,
+		Logger:        app.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dispatcher.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return nil, err
+	}
+	app.Logger.Info().Int("channels", len(channels)).Int("subscriptions", len(subs)).Msg("notifications enabled")
+	if err := consumeMedia(ctx, app, "publish-notify", processed, dispatcher.Handle); err != nil {
+		return nil, err
+	}
+	return dispatcher, nil
+}
+
+// lagMonitor следит за лагом групп, которые читают события media; без consumer'ов — nil
+func lagMonitor(ctx context.Context, app *cli.App) (*kafka.LagMonitor, error) {
+	topics := strings.Split(*mediaTopics, ",")
+	var targets []kafka.LagTarget
+	if *cdnProvider != "none" {
+		targets = append(targets, kafka.LagTarget{Group: "publish-cdn", Topics: topics})
+	}
+	if *notifyConfig != "" {
+		targets = append(targets, kafka.LagTarget{Group: "publish-notify", Topics: topics})
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	lag, err := kafka.NewLagMonitor(kafka.LagMonitorConfig{
+		Brokers:   []string{"localhost:9092"},
+		Targets:   targets,
+		Interval:  *lagInterval,
+		Threshold: *lagThreshold,
+		Logger:    app.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lag monitor: %w", err)
+	}
+	if err := lag.Metrics().Register(prometheus.DefaultRegisterer); err != nil {
+		return nil, err
+	}
+	app.Go(ctx, cli.Worker{Name: "kafka_lag_monitor", Run: lag.Run})
+	return lag, nil
+}
+
+// openDB подключается к базе media по DATABASE_URL; без него — nil
+func openDB(ctx context.Context, app *cli.App) (*sqlx.DB, error) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		app.Logger.Warn().Msg("DATABASE_URL is empty, notification deliveries are kept in memory and redelivered events are handled again")
+		return nil, nil
+	}
+	pool, err := pg.NewPool(ctx, pg.PoolConfig{DSN: dsn})
+	if err != nil {
+		return nil, fmt.Errorf("db connect: %w", err)
+	}
+	db := pg.OpenDB(pool)
+	app.Register(cli.Component{
+		Name:     "postgres",
+		Priority: cli.StopStorage,
+		Stop: func(context.Context) error {
+			err := db.Close()
+			pool.Close()
+			return err
+		},
+	})
+	return db, nil
+}
+
+// startInbox — processed_events в базе media и их очистка по -inbox-ttl
+func startInbox(ctx context.Context, app *cli.App, db *sqlx.DB) (*inbox.Store, error) {
+	store := inbox.NewStore(db)
+	cleaner, err := inbox.NewCleaner(inbox.CleanerConfig{Store: store, TTL: *inboxTTL, Logger: app.Logger})
+	if err != nil {
+		return nil, err
+	}
+	app.Go(ctx, cli.Worker{Name: "inbox_cleaner", Run: cleaner.Start})
+	return store, nil
+}
+
+// consumeMedia передаёт handle события media из -media-topics. Разбираются только JSON
+// конверты (-kafka-format json у media), как и в quota. С processed повторная доставка
+// события, уже обработанного группой, пропускается (inbox).
+func consumeMedia(ctx context.Context, app *cli.App, group string, processed *inbox.Store, handle func(context.Context, events.Envelope) error) error {
+	if processed != nil {
+		next := handle
+		handle = inbox.WithIdempotency(processed, group, func(ctx context.Context, _ *sqlx.Tx, env events.Envelope) error {
+			return next(ctx, env)
+		})
+	}
+	decryption, err := encryption.FromEnv()
+	if err != nil {
+		return err
+	}
+	consumer, err := kafka.NewConsumer(kafka.ConsumerConfig{
+		Brokers:    []string{"localhost:9092"},
+		Topics:     strings.Split(*mediaTopics, ","),
+		GroupID:    group,
+		Decryption: decryption,
+		Logger:     app.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("media events consumer %s: %w", group, err)
+	}
+	name := strings.ReplaceAll(group, "-", "_") + "_consumer"
+	app.Go(ctx, cli.Worker{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			return consumer.Run(ctx, func(ctx context.Context, msg kafka.Message) error {
+				if f := msg.Headers[kafka.HeaderContentFormat]; f != "" && f != "json" {
+					app.Logger.Warn().Str("format", f).Str("group", group).Str("event_type", msg.Headers[kafka.HeaderEventType]).Msg("event skipped, only json is supported")
+					return nil
+				}
+				env, err := events.UnmarshalEnvelope(msg.Value)
+				if err != nil {
+					return err
+				}
+				return handle(ctx, env)
+			})
+		},
+	})
+	app.Register(cli.Component{
+		Name:     name,
+		Priority: cli.StopConsumers,
+		Stop:     func(context.Context) error { return consumer.Close() },
+	})
+	return nil
 }
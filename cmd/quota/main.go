@@ -2,15 +2,312 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/romariotrain/media-platform/internal/cli"
+	"github.com/romariotrain/media-platform/internal/config"
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/events/inbox"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/quota"
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+var (
+	addr              = flag.String("addr", ":8083", "HTTP listen address")
+	uploadLimit       = flag.Int("upload-limit", 100, "uploads per owner per window for plans without an upload limit")
+	uploadWindow      = flag.Duration("upload-window", time.Hour, "upload limit sliding window")
+	limitStore        = flag.String("limit-store", "memory", "upload counters: memory (single instance) | redis (REDIS_ADDR)")
+	usageEvents       = flag.Bool("usage-events", false, "kafka: count usage from media events")
+	mediaTopics       = flag.String("media-topics", "events.media", "kafka: comma-separated topics with media events")
+	usageTx           = flag.Bool("usage-transactional", false, "kafka: publish QuotaUsageChanged to events.quota and commit the media events offset in one transaction (exactly-once)")
+	transactionalID   = flag.String("transactional-id", "quota-usage-0", "kafka: transactional id of this instance for -usage-transactional; must be stable across restarts and unique per instance")
+	reconcileInterval = flag.Duration("reconcile-interval", 24*time.Hour, "usage reconciliation with the media table (DATABASE_URL) period")
+	reconcileAt       = flag.Duration("reconcile-at", 3*time.Hour, "time of day (UTC) reconciliation runs are aligned to (0 = from start)")
+	reconcileCorrect  = flag.Bool("reconcile-correct", true, "correct usage drift and publish QuotaReconciled, not only report it")
+	inboxTTL          = flag.Duration("inbox-ttl", 7*24*time.Hour, "how long ids of counted media events are kept to skip redeliveries (DATABASE_URL); must exceed the media topics retention")
 )
 
+// quotaTopic — топик событий quota
+const quotaTopic = "events.quota"
+
+// httpServer — флаги -http-*
+var httpServer = config.HTTPServerFlags(flag.CommandLine, config.DefaultHTTPServer())
+
 func main() {
-	code := cli.Run("quota", func(ctx context.Context) error {
-		<-ctx.Done()
+	flag.Parse()
+	code := cli.Run("quota", run)
+	os.Exit(code)
+}
+
+func run(ctx context.Context, app *cli.App) error {
+	db, err := openDB(ctx, app)
+	if err != nil {
+		return err
+	}
+	// Тарифы и их назначение — в базе media (sql/script.sql); без неё — в памяти, до рестарта
+	var plans quota.PlanStore = quota.NewMemoryPlanStore()
+	if db != nil {
+		plans = pg.NewQuotaPlansRepo(db)
+	}
+	// Usage — в базе media: переживает рестарт и общий у инстансов; без неё — в памяти инстанса
+	var usage quota.UsageStore = quota.NewMemoryUsageStore()
+	if db != nil {
+		usage = pg.NewQuotaUsageRepo(db)
+	}
+	limiter, err := newLimiter(app, plans, usage)
+	if err != nil {
+		return err
+	}
+	if *usageEvents {
+		if db != nil {
+			if err := cleanInbox(ctx, app, db); err != nil {
+				return err
+			}
+		}
+		if err := consumeUsage(ctx, app, usage); err != nil {
+			return err
+		}
+	}
+	if db != nil {
+		if err := reconcile(ctx, app, db, usage); err != nil {
+			return err
+		}
+	}
+
+	h, err := quota.NewHandler(quota.HandlerConfig{Limiter: limiter, Usage: usage, Plans: plans, Logger: app.Logger})
+	if err != nil {
+		return err
+	}
+	srv, err := httpServer.NewServer(*addr, h)
+	if err != nil {
+		return err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe(srv)
+	}()
+	app.Register(cli.Component{Name: "http_server", Priority: cli.StopServers, Stop: srv.Shutdown})
+
+	select {
+	case <-ctx.Done():
 		return nil
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("listen and serve: %w", err)
+	}
+}
+
+// openDB подключается к базе media по DATABASE_URL; без него — nil
+func openDB(ctx context.Context, app *cli.App) (*sqlx.DB, error) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		app.Logger.Warn().Msg("DATABASE_URL is empty, plans and usage are kept in memory and usage reconciliation is disabled")
+		return nil, nil
+	}
+	pool, err := pg.NewPool(ctx, pg.PoolConfig{DSN: dsn})
+	if err != nil {
+		return nil, fmt.Errorf("db connect: %w", err)
+	}
+	db := pg.OpenDB(pool)
+	app.Register(cli.Component{
+		Name:     "postgres",
+		Priority: cli.StopStorage,
+		Stop: func(context.Context) error {
+			err := db.Close()
+			pool.Close()
+			return err
+		},
 	})
-	os.Exit(code)
+	return db, nil
+}
+
+func newLimiter(app *cli.App, plans quota.PlanStore, usage quota.UsageStore) (*quota.Limiter, error) {
+	var store quota.WindowStore
+	switch *limitStore {
+	case "memory":
+		store = quota.NewMemoryWindowStore()
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: os.Getenv("REDIS_ADDR")})
+		app.Register(cli.Component{Name: "redis", Priority: cli.StopStorage, Stop: func(context.Context) error {
+			return client.Close()
+		}})
+		rs, err := quota.NewRedisWindowStore(client, "")
+		if err != nil {
+			return nil, err
+		}
+		store = rs
+	default:
+		return nil, fmt.Errorf("unknown -limit-store %q", *limitStore)
+	}
+	limiter, err := quota.NewLimiter(quota.LimiterConfig{
+		Store:  store,
+		Limit:  quota.Limit{Uploads: *uploadLimit, Window: *uploadWindow},
+		Plans:  plans,
+		Usage:  usage,
+		Logger: app.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Лимит по умолчанию меняется без перезапуска (-config-file, /debug/knobs)
+	for _, k := range []config.Knob{
+		config.Int("upload-limit", *uploadLimit, 1, func(n int) error {
+			return limiter.SetLimit(quota.Limit{Uploads: n, Window: limiter.Limit().Window})
+		}),
+		config.Duration("upload-window", *uploadWindow, time.Second, func(d time.Duration) error {
+			return limiter.SetLimit(quota.Limit{Uploads: limiter.Limit().Uploads, Window: d})
+		}),
+	} {
+		if err := app.Config.Subscribe(k); err != nil {
+			return nil, err
+		}
+	}
+	return limiter, nil
+}
+
+// cleanInbox удаляет отметки processed_events старше -inbox-ttl: по ним QuotaUsageRepo
+// не учитывает повторно доставленные события
+func cleanInbox(ctx context.Context, app *cli.App, db *sqlx.DB) error {
+	cleaner, err := inbox.NewCleaner(inbox.CleanerConfig{Store: inbox.NewStore(db), TTL: *inboxTTL, Logger: app.Logger})
+	if err != nil {
+		return err
+	}
+	app.Go(ctx, cli.Worker{Name: "inbox_cleaner", Run: cleaner.Start})
+	return nil
+}
+
+// consumeUsage применяет к usage события media. Разбираются только JSON конверты
+// (-kafka-format json у media): avro и protobuf quota пока не читает. Повторную доставку
+// уже учтённого события отбрасывает сам UsageStore (Apply по EventID). С -usage-transactional
+// каждое событие — цикл read-process-write: QuotaUsageChanged и offset фиксируются одной транзакцией.
+func consumeUsage(ctx context.Context, app *cli.App, usage quota.UsageStore) error {
+	decryption, err := encryption.FromEnv()
+	if err != nil {
+		return err
+	}
+	consumer, err := kafka.NewConsumer(kafka.ConsumerConfig{
+		Brokers:       []string{"localhost:9092"},
+		Topics:        strings.Split(*mediaTopics, ","),
+		GroupID:       "quota-usage",
+		ReadCommitted: *usageTx,
+		Decryption:    decryption,
+		Logger:        app.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("usage consumer: %w", err)
+	}
+
+	cfg := quota.UsageHandlerConfig{Store: usage, Logger: app.Logger}
+	var tx *kafka.TxProducer
+	if *usageTx {
+		tx, err = kafka.NewTxProducer(ctx, kafka.TxProducerConfig{
+			Brokers:         []string{"localhost:9092"},
+			TransactionalID: *transactionalID,
+			Topic:           quotaTopic,
+			Logger:          app.Logger,
+		})
+		if err != nil {
+			return errors.Join(fmt.Errorf("kafka tx producer: %w", err), consumer.Close())
+		}
+		app.Register(cli.Component{
+			Name:     "kafka_tx_producer",
+			Priority: cli.StopProducers,
+			Stop:     func(context.Context) error { return tx.Close() },
+		})
+		cfg.Events = tx
+	}
+	handler, err := quota.NewUsageHandler(cfg)
+	if err != nil {
+		return err
+	}
+
+	handle := func(ctx context.Context, msg kafka.Message) error {
+		if f := msg.Headers[kafka.HeaderContentFormat]; f != "" && f != "json" {
+			app.Logger.Warn().Str("format", f).Str("event_type", msg.Headers[kafka.HeaderEventType]).Msg("usage: event skipped, only json is supported")
+			return nil
+		}
+		env, err := events.UnmarshalEnvelope(msg.Value)
+		if err != nil {
+			return err
+		}
+		return handler.Handle(ctx, env)
+	}
+	app.Go(ctx, cli.Worker{
+		Name: "usage_consumer",
+		Run: func(ctx context.Context) error {
+			if tx != nil {
+				return consumer.RunTransactional(ctx, tx, handle)
+			}
+			return consumer.Run(ctx, handle)
+		},
+	})
+	app.Register(cli.Component{
+		Name:     "usage_consumer",
+		Priority: cli.StopConsumers,
+		Stop:     func(context.Context) error { return consumer.Close() },
+	})
+	return nil
+}
+
+// reconcile сверяет usage с таблицей media: при старте заполняет usage фактом, дальше —
+// по расписанию. Без базы (DATABASE_URL) usage считается только по событиям.
+func reconcile(ctx context.Context, app *cli.App, db *sqlx.DB, usage quota.UsageStore) error {
+	media := pg.NewMediaRepo(db)
+
+	// Стартуем с факта, а не с нуля и не с usage, накопленного до остановки: события,
+	// пропущенные за время простоя, сверка иначе исправила бы только ночью
+	actual, err := media.UsageByOwner(ctx)
+	if err != nil {
+		return fmt.Errorf("initial usage: %w", err)
+	}
+	for owner, u := range actual {
+		if err := usage.Set(ctx, owner, u); err != nil {
+			return err
+		}
+	}
+
+	cfg := quota.ReconcilerConfig{
+		Store:       usage,
+		Actual:      media,
+		Interval:    *reconcileInterval,
+		At:          *reconcileAt,
+		AutoCorrect: *reconcileCorrect,
+		Logger:      app.Logger,
+	}
+	if *reconcileCorrect {
+		producer, err := kafka.NewProducer(kafka.ProducerConfig{
+			Brokers: []string{"localhost:9092"},
+			Topic:   quotaTopic,
+			Format:  kafka.FormatJSON,
+			Logger:  app.Logger,
+		})
+		if err != nil {
+			return fmt.Errorf("kafka producer: %w", err)
+		}
+		app.Register(cli.Component{
+			Name:     "kafka_producer",
+			Priority: cli.StopProducers,
+			Stop:     producer.Shutdown,
+		})
+		cfg.Events = producer
+	}
+	reconciler, err := quota.NewReconciler(cfg)
+	if err != nil {
+		return err
+	}
+	app.Go(ctx, cli.Worker{Name: "usage_reconciler", Run: reconciler.Start})
+	return nil
 }
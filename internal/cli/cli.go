@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/config"
+)
+
+var configFile = flag.String("config-file", "", "file with name=value overrides of reloadable flags, re-read on SIGHUP (empty = changes only via ops /debug/knobs)")
+
+// App — окружение сервиса, которое Run передаёт в fn
+type App struct {
+	Name   string
+	Logger zerolog.Logger
+	// Config — перечитываемые настройки: компоненты подписываются на флаги, которые
+	// можно менять без перезапуска (см. config.Watcher)
+	Config     *config.Watcher
+	shutdown   *Shutdown
+	supervisor *Supervisor
+}
+
+// Register добавляет компонент в остановку сервиса (см. Shutdown)
+func (a *App) Register(c Component) {
+	a.shutdown.Register(c)
+}
+
+// Go запускает фоновый цикл под Supervisor: panic и ошибки перезапускают его,
+// исчерпанные перезапуски останавливают сервис с ошибкой
+func (a *App) Go(ctx context.Context, w Worker) {
+	a.supervisor.Go(ctx, w)
+}
+
+// Run запускает сервис name и блокируется до завершения fn.
+// Логгер собирается из флагов -log-level/-log-format (персональные данные вычищаются по
+// -log-redact/-log-hash/-log-allow) и передаётся в fn через App;
+// с -ops-addr рядом поднимается ops listener (pprof, expvar, /debug/config, /debug/knobs).
+// Перечитываемые настройки (App.Config) берутся из -config-file и перечитываются по SIGHUP.
+// Контекст fn отменяется по SIGINT/SIGTERM. fn собирает сервис, регистрирует компоненты
+// в App и возвращается, когда ctx отменён или сервис упал; после этого компоненты
+// останавливаются по приоритетам, а воркеры App.Go дожидаются. Воркер, исчерпавший
+// перезапуски, отменяет ctx так же, как сигнал. Возвращает exit code процесса.
+func Run(name string, fn func(ctx context.Context, app *App) error) int {
+	if err := SetLogLevel(*logLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 2
+	}
+	// Уровень задаёт SetLogLevel, чтобы его можно было менять на лету
+	logger, err := NewLogger(os.Stdout, LoggerConfig{
+		Service:   name,
+		Level:     zerolog.LevelTraceValue,
+		Format:    LogFormat(*logFormat),
+		Redaction: redactionFromFlags(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 2
+	}
+	knobs, err := config.NewWatcher(config.WatcherConfig{File: *configFile, Logger: logger})
+	if err == nil {
+		err = knobs.Subscribe(logLevelKnob())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	logger.Info().Msg("starting")
+
+	app := &App{
+		Name:       name,
+		Logger:     logger,
+		Config:     knobs,
+		shutdown:   NewShutdown(logger),
+		supervisor: NewSupervisor(logger, cancel),
+	}
+	if *opsAddr != "" {
+		app.Register(Component{Name: "ops_listener", Priority: StopServers, Stop: startOps(*opsAddr, knobs, logger)})
+	}
+	app.Go(ctx, Worker{Name: "config_reload", Run: knobs.Run})
+
+	runErr := fn(ctx, app)
+	if cause := context.Cause(ctx); errors.Is(cause, ErrWorkerFailed) {
+		runErr = errors.Join(runErr, cause)
+	}
+	cancel(nil) // fn мог вернуться из-за ошибки без отмены ctx; воркерам пора остановиться
+	if runErr != nil {
+		logger.Error().Err(runErr).Msg("service failed, shutting down")
+	} else {
+		logger.Info().Msg("shutting down")
+	}
+
+	stopErr := app.shutdown.Stop(context.Background())
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), DefaultStopTimeout)
+	defer waitCancel()
+	if err := app.supervisor.Wait(waitCtx); err != nil {
+		stopErr = errors.Join(stopErr, fmt.Errorf("wait workers: %w", err))
+	}
+
+	if err := errors.Join(runErr, stopErr); err != nil {
+		logger.Error().Err(err).Msg("stopped with error")
+		if errors.Is(err, ErrUsage) {
+			return 2
+		}
+		return 1
+	}
+
+	logger.Info().Msg("stopped")
+	return 0
+}
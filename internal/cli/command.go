@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrUsage — неверный вызов команды; Run печатает справку и завершается с кодом 2
+var ErrUsage = errors.New("usage")
+
+// Command — подкоманда бинаря сервиса (serve, migrate up, outbox requeue ...)
+type Command struct {
+	Name    string
+	Args    string // позиционные аргументы для справки, например "<id> <status>"
+	Summary string
+	// Flags объявляет флаги подкоманды; они идут после её имени: media outbox requeue -all
+	Flags       func(fs *flag.FlagSet)
+	Run         func(ctx context.Context, app *App, args []string) error
+	Subcommands []*Command
+}
+
+// Dispatch выбирает команду по args (обычно flag.Args()) и возвращает fn для Run.
+// Пустые args запускают первую команду — так бинарь без аргументов работает как раньше (serve).
+func Dispatch(args []string, cmds ...*Command) func(ctx context.Context, app *App) error {
+	return func(ctx context.Context, app *App) error {
+		root := &Command{Name: app.Name, Subcommands: cmds}
+		if len(args) == 0 && len(cmds) > 0 {
+			args = []string{cmds[0].Name}
+		}
+		return root.dispatch(ctx, app, app.Name, args)
+	}
+}
+
+func (c *Command) dispatch(ctx context.Context, app *App, path string, args []string) error {
+	if len(c.Subcommands) > 0 {
+		if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			c.usage(os.Stderr, path)
+			if len(args) == 0 {
+				return fmt.Errorf("%w: %s: missing command", ErrUsage, path)
+			}
+			return nil
+		}
+		for _, sub := range c.Subcommands {
+			if sub.Name == args[0] {
+				return sub.dispatch(ctx, app, path+" "+sub.Name, args[1:])
+			}
+		}
+		c.usage(os.Stderr, path)
+		return fmt.Errorf("%w: %s: unknown command %q", ErrUsage, path, args[0])
+	}
+
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		c.commandUsage(os.Stderr, path, fs)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s: %w", ErrUsage, path, err)
+	}
+	return c.Run(ctx, app, fs.Args())
+}
+
+func (c *Command) usage(w io.Writer, path string) {
+	fmt.Fprintf(w, "Usage: %s <command>\n\nCommands:\n", path)
+	for _, sub := range c.Subcommands {
+		name := sub.Name
+		if len(sub.Subcommands) > 0 {
+			name += " <command>"
+		} else if sub.Args != "" {
+			name += " " + sub.Args
+		}
+		fmt.Fprintf(w, "  %-28s %s\n", name, sub.Summary)
+	}
+}
+
+func (c *Command) commandUsage(w io.Writer, path string, fs *flag.FlagSet) {
+	line := []string{"Usage:", path}
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		line = append(line, "[flags]")
+	}
+	if c.Args != "" {
+		line = append(line, c.Args)
+	}
+	fmt.Fprintf(w, "%s\n\n%s\n", strings.Join(line, " "), c.Summary)
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+// ExactArgs проверяет число позиционных аргументов команды
+func ExactArgs(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%w: expected %d arguments, got %d", ErrUsage, n, len(args))
+	}
+	return nil
+}
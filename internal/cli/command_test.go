@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatch(t *testing.T) {
+	var got []string
+	var force bool
+	record := func(name string) func(context.Context, *App, []string) error {
+		return func(_ context.Context, _ *App, args []string) error {
+			got = append([]string{name}, args...)
+			return nil
+		}
+	}
+	cmds := []*Command{
+		{Name: "serve", Run: record("serve")},
+		{Name: "outbox", Subcommands: []*Command{
+			{Name: "requeue", Run: record("requeue"), Flags: func(fs *flag.FlagSet) {
+				fs.BoolVar(&force, "force", false, "")
+			}},
+		}},
+	}
+	app := &App{Name: "media", Logger: zerolog.Nop()}
+	run := func(args ...string) error {
+		got, force = nil, false
+		return Dispatch(args, cmds...)(context.Background(), app)
+	}
+
+	// без аргументов — первая команда
+	require.NoError(t, run())
+	require.Equal(t, []string{"serve"}, got)
+
+	require.NoError(t, run("outbox", "requeue", "-force", "1", "2"))
+	require.Equal(t, []string{"requeue", "1", "2"}, got)
+	require.True(t, force)
+
+	require.ErrorIs(t, run("outbox"), ErrUsage)
+	require.ErrorIs(t, run("outbox", "purge"), ErrUsage)
+	require.ErrorIs(t, run("outbox", "requeue", "-unknown"), ErrUsage)
+	require.Nil(t, got)
+}
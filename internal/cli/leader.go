@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LeaderLock — распределённая блокировка лидерства; реализуется *postgres.LeaderLock
+type LeaderLock interface {
+	// TryAcquire берёт блокировку без ожидания; false — её держит другой инстанс
+	TryAcquire(ctx context.Context) (bool, error)
+	// Renew подтверждает, что блокировка всё ещё у этого инстанса; ошибка — лидерство потеряно
+	Renew(ctx context.Context) error
+	// Release отдаёт блокировку
+	Release(ctx context.Context) error
+}
+
+// LeaderConfig содержит конфигурацию Leader
+type LeaderConfig struct {
+	Name string // имя в логах, обычно имя охраняемого воркера
+	Lock LeaderLock
+	// Lease — период продления блокировки лидером и срок, за который продление должно уложиться;
+	// не уложилось — работа останавливается (default: 5s)
+	Lease time.Duration
+	// RetryInterval — как часто инстанс без лидерства пытается взять блокировку (default: Lease)
+	RetryInterval time.Duration
+	OnElected     func()          // инстанс стал лидером, работа сейчас начнётся
+	OnLost        func(err error) // продление не удалось; работа уже остановлена, блокировка отдана
+	Logger        zerolog.Logger
+}
+
+// Leader выполняет работу только на одном инстансе из всех реплик: на том, который держит
+// блокировку. Остальные ждут и перехватывают лидерство, когда блокировка освобождается.
+type Leader struct {
+	name      string
+	lock      LeaderLock
+	lease     time.Duration
+	retry     time.Duration
+	onElected func()
+	onLost    func(err error)
+	logger    zerolog.Logger
+}
+
+func NewLeader(cfg LeaderConfig) (*Leader, error) {
+	if cfg.Lock == nil {
+		return nil, fmt.Errorf("lock is required")
+	}
+	if cfg.Lease < 0 {
+		return nil, fmt.Errorf("lease cannot be negative, got: %v", cfg.Lease)
+	}
+	if cfg.RetryInterval < 0 {
+		return nil, fmt.Errorf("retry interval cannot be negative, got: %v", cfg.RetryInterval)
+	}
+	if cfg.Lease == 0 {
+		cfg.Lease = 5 * time.Second
+	}
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = cfg.Lease
+	}
+
+	return &Leader{
+		name:      cfg.Name,
+		lock:      cfg.Lock,
+		lease:     cfg.Lease,
+		retry:     cfg.RetryInterval,
+		onElected: cfg.OnElected,
+		onLost:    cfg.OnLost,
+		logger:    cfg.Logger.With().Str("component", "leader").Str("name", cfg.Name).Logger(),
+	}, nil
+}
+
+// Guard оборачивает воркер: Run выполняется только на лидере
+func (l *Leader) Guard(w Worker) Worker {
+	run := w.Run
+	w.Run = func(ctx context.Context) error { return l.Run(ctx, run) }
+	return w
+}
+
+// Run ждёт лидерства и выполняет work, пока блокировка продлевается. Потеря лидерства отменяет
+// контекст work и возвращает инстанс к ожиданию. Run завершается при отмене ctx (ctx.Err())
+// или когда work завершился сам (его результат); блокировка при этом отдаётся.
+func (l *Leader) Run(ctx context.Context, work func(ctx context.Context) error) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		acquired, err := l.lock.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			l.logger.Warn().Err(err).Msg("leader lock acquire failed")
+		}
+		if !acquired {
+			timer.Reset(l.retry)
+			continue
+		}
+
+		l.logger.Info().Msg("elected leader")
+		if l.onElected != nil {
+			l.onElected()
+		}
+		if done, err := l.lead(ctx, work); done {
+			return err
+		}
+		timer.Reset(l.retry)
+	}
+}
+
+// lead выполняет work, продлевая блокировку каждые Lease. true — Run завершается с err,
+// false — лидерство потеряно.
+func (l *Leader) lead(ctx context.Context, work func(ctx context.Context) error) (bool, error) {
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() { result <- runRecovered(workCtx, work) }()
+
+	ticker := time.NewTicker(l.lease)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-result
+			l.release()
+			return true, ctx.Err()
+		case err := <-result:
+			l.release()
+			return true, err
+		case <-ticker.C:
+			renewCtx, cancelRenew := context.WithTimeout(ctx, l.lease)
+			err := l.lock.Renew(renewCtx)
+			cancelRenew()
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+
+			cancel()
+			<-result
+			l.release()
+			l.logger.Error().Err(err).Msg("leadership lost")
+			if l.onLost != nil {
+				l.onLost(err)
+			}
+			return false, nil
+		}
+	}
+}
+
+// release отдаёт блокировку; контекст свой — ctx Run к этому моменту может быть отменён
+func (l *Leader) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), l.lease)
+	defer cancel()
+	if err := l.lock.Release(ctx); err != nil {
+		l.logger.Warn().Err(err).Msg("leader lock release failed")
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLock — блокировка одного владельца на несколько инстансов; renewErr ломает продление
+type memoryLock struct {
+	mu     *sync.Mutex
+	holder *int
+	id     int
+
+	renewErr atomic.Pointer[error]
+}
+
+func newMemoryLocks(n int) []*memoryLock {
+	mu, holder := &sync.Mutex{}, new(int)
+	locks := make([]*memoryLock, n)
+	for i := range locks {
+		locks[i] = &memoryLock{mu: mu, holder: holder, id: i + 1}
+	}
+	return locks
+}
+
+func (l *memoryLock) TryAcquire(context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if *l.holder != 0 && *l.holder != l.id {
+		return false, nil
+	}
+	*l.holder = l.id
+	return true, nil
+}
+
+func (l *memoryLock) Renew(context.Context) error {
+	if err := l.renewErr.Swap(nil); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func (l *memoryLock) Release(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if *l.holder == l.id {
+		*l.holder = 0
+	}
+	return nil
+}
+
+func (l *memoryLock) held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return *l.holder == l.id
+}
+
+func newTestLeader(t *testing.T, lock LeaderLock, cfg LeaderConfig) *Leader {
+	t.Helper()
+	cfg.Lock, cfg.Lease, cfg.Logger = lock, 5*time.Millisecond, zerolog.Nop()
+	l, err := NewLeader(cfg)
+	require.NoError(t, err)
+	return l
+}
+
+func TestLeader_RunsWorkOnSingleInstance(t *testing.T) {
+	locks := newMemoryLocks(2)
+	var (
+		mu           sync.Mutex
+		active, peak int // инстансов с работой сейчас и за всё время одновременно
+	)
+	running := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return active
+	}
+	work := func(ctx context.Context) error {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		<-ctx.Done()
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return ctx.Err()
+	}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	doneA := make(chan error, 1)
+	go func() { doneA <- newTestLeader(t, locks[0], LeaderConfig{}).Run(ctxA, work) }()
+	require.Eventually(t, locks[0].held, time.Second, time.Millisecond)
+	go func() { _ = newTestLeader(t, locks[1], LeaderConfig{}).Run(ctxB, work) }()
+
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, 1, running())
+
+	// Лидер остановился — блокировка отдана, работу подхватывает второй инстанс
+	stopA()
+	require.ErrorIs(t, <-doneA, context.Canceled)
+	require.Eventually(t, locks[1].held, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return running() == 1 }, time.Second, time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, peak)
+}
+
+func TestLeader_LostLeadershipStopsWork(t *testing.T) {
+	lock := newMemoryLocks(1)[0]
+	var elected, stopped atomic.Int32
+	lost := make(chan error, 1)
+	l := newTestLeader(t, lock, LeaderConfig{
+		OnElected: func() { elected.Add(1) },
+		OnLost:    func(err error) { lost <- err },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = l.Run(ctx, func(ctx context.Context) error {
+			<-ctx.Done()
+			stopped.Add(1)
+			return ctx.Err()
+		})
+	}()
+	require.Eventually(t, func() bool { return elected.Load() == 1 }, time.Second, time.Millisecond)
+
+	renewErr := errors.New("connection reset")
+	lock.renewErr.Store(&renewErr)
+	require.ErrorIs(t, <-lost, renewErr)
+	require.EqualValues(t, 1, stopped.Load())
+
+	// Блокировка свободна — инстанс снова становится лидером
+	require.Eventually(t, func() bool { return elected.Load() == 2 }, time.Second, time.Millisecond)
+}
+
+func TestLeader_GuardReturnsWorkResult(t *testing.T) {
+	lock := newMemoryLocks(1)[0]
+	failure := errors.New("job failed")
+	w := newTestLeader(t, lock, LeaderConfig{}).Guard(Worker{
+		Name: "job",
+		Run:  func(context.Context) error { return failure },
+	})
+
+	require.ErrorIs(t, w.Run(context.Background()), failure)
+	require.False(t, lock.held())
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/config"
+)
+
+var (
+	logLevel  = flag.String("log-level", "info", "log level: trace | debug | info | warn | error")
+	logFormat = flag.String("log-format", "json", "log format: json | console")
+)
+
+// LogFormat — формат вывода логов
+type LogFormat string
+
+const (
+	LogFormatJSON    LogFormat = "json"
+	LogFormatConsole LogFormat = "console" // человекочитаемый, для локальной разработки
+)
+
+// LoggerConfig содержит конфигурацию общего логгера сервиса
+type LoggerConfig struct {
+	Service string    // поле service в каждой записи
+	Level   string    // default: info
+	Format  LogFormat // default: json
+	// Redaction вычищает персональные данные из записей (см. NewRedactingWriter); пустая — без вычистки
+	Redaction RedactionPolicy
+}
+
+// NewLogger создаёт логгер сервиса. Компоненты получают его при сборке
+// и добавляют своё поле component; request-scoped поля кладёт HTTP middleware.
+func NewLogger(w io.Writer, cfg LoggerConfig) (zerolog.Logger, error) {
+	if cfg.Level == "" {
+		cfg.Level = zerolog.LevelInfoValue
+	}
+	if cfg.Format == "" {
+		cfg.Format = LogFormatJSON
+	}
+
+	level, err := zerolog.ParseLevel(cfg.Level)
+	if err != nil {
+		return zerolog.Logger{}, fmt.Errorf("log level: %w", err)
+	}
+
+	switch cfg.Format {
+	case LogFormatJSON:
+	case LogFormatConsole:
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	default:
+		return zerolog.Logger{}, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	if !cfg.Redaction.empty() {
+		w = NewRedactingWriter(w, cfg.Redaction)
+	}
+
+	return zerolog.New(w).Level(level).With().Timestamp().Str("service", cfg.Service).Logger(), nil
+}
+
+// SetLogLevel меняет уровень логов всего процесса. Действует поверх уровня логгера:
+// поэтому Run собирает логгер сервиса с уровнем trace, а -log-level задаёт через SetLogLevel.
+func SetLogLevel(level string) error {
+	if level == "" {
+		return errors.New("log level is required")
+	}
+	l, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+	zerolog.SetGlobalLevel(l)
+	return nil
+}
+
+// logLevelKnob делает -log-level перечитываемым
+func logLevelKnob() config.Knob {
+	return config.Knob{
+		Name:  "log-level",
+		Value: zerolog.GlobalLevel().String(),
+		Parse: func(value string) (func() error, error) {
+			if _, err := zerolog.ParseLevel(value); err != nil || value == "" {
+				return nil, fmt.Errorf("unknown log level %q", value)
+			}
+			return func() error { return SetLogLevel(value) }, nil
+		},
+	}
+}
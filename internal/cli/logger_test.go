@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, LoggerConfig{Service: "media", Level: "warn"})
+	require.NoError(t, err)
+
+	logger.Info().Msg("skipped")
+	logger.Warn().Msg("kept")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "media", line["service"])
+	require.Equal(t, "kept", line["message"])
+	require.Contains(t, line, "time")
+}
+
+func TestNewLogger_InvalidConfig(t *testing.T) {
+	_, err := NewLogger(&bytes.Buffer{}, LoggerConfig{Level: "loud"})
+	require.Error(t, err)
+
+	_, err = NewLogger(&bytes.Buffer{}, LoggerConfig{Format: "xml"})
+	require.Error(t, err)
+}
+
+func TestSetLogLevel(t *testing.T) {
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.TraceLevel) })
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, LoggerConfig{Level: zerolog.LevelTraceValue})
+	require.NoError(t, err)
+
+	require.NoError(t, SetLogLevel("warn"))
+	logger.Info().Msg("skipped")
+	require.Empty(t, buf.String())
+
+	// Уже розданный логгер подхватывает новый уровень
+	apply, err := logLevelKnob().Parse("debug")
+	require.NoError(t, err)
+	require.NoError(t, apply())
+	logger.Debug().Msg("kept")
+	require.Contains(t, buf.String(), "kept")
+
+	require.Error(t, SetLogLevel("loud"))
+	_, err = logLevelKnob().Parse("")
+	require.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
+	"maps"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/config"
+)
+
+var opsAddr = flag.String("ops-addr", "", "ops listener with pprof, expvar, runtime stats, /debug/config and /debug/knobs, e.g. localhost:6060 (empty = disabled)")
+
+// redacted подставляется вместо значений секретных флагов в /debug/config
+const redacted = "[REDACTED]"
+
+// secretFlagMarkers — флаги, в имени которых есть эти слова, не показываются в /debug/config
+var secretFlagMarkers = []string{"password", "secret", "token", "dsn", "credential", "key"}
+
+// NewOpsHandler собирает служебные ручки: /debug/pprof/, /debug/vars (expvar),
+// /debug/runtime (горутины, память, GC), /debug/config (флаги с вырезанными секретами,
+// перечитываемые — с текущими значениями) и /debug/knobs (GET/PATCH перечитываемых настроек).
+// Ручки отдают внутренности процесса, поэтому слушаются на отдельном порту, закрытом снаружи.
+// knobs может быть nil — тогда /debug/knobs нет.
+func NewOpsHandler(flags *flag.FlagSet, knobs *config.Watcher) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		writeOpsJSON(w, runtimeStats())
+	})
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, r *http.Request) {
+		cfg := effectiveConfig(flags)
+		if knobs != nil {
+			maps.Copy(cfg, knobs.Values())
+		}
+		writeOpsJSON(w, cfg)
+	})
+	if knobs != nil {
+		mux.Handle("/debug/knobs", knobs)
+	}
+
+	return mux
+}
+
+// RuntimeStats — снимок рантайма для /debug/runtime
+type RuntimeStats struct {
+	GoVersion    string        `json:"go_version"`
+	Goroutines   int           `json:"goroutines"`
+	GOMAXPROCS   int           `json:"gomaxprocs"`
+	HeapAlloc    uint64        `json:"heap_alloc_bytes"`
+	HeapInuse    uint64        `json:"heap_inuse_bytes"`
+	Sys          uint64        `json:"sys_bytes"`
+	NumGC        int64         `json:"num_gc"`
+	LastGC       time.Time     `json:"last_gc,omitzero"`
+	PauseTotal   time.Duration `json:"pause_total_ns"`
+	NextGCTarget uint64        `json:"next_gc_bytes"`
+}
+
+func runtimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	return RuntimeStats{
+		GoVersion:    runtime.Version(),
+		Goroutines:   runtime.NumGoroutine(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		Sys:          mem.Sys,
+		NumGC:        gc.NumGC,
+		LastGC:       gc.LastGC,
+		PauseTotal:   gc.PauseTotal,
+		NextGCTarget: mem.NextGC,
+	}
+}
+
+// effectiveConfig возвращает значения всех флагов (заданных и по умолчанию); секреты вырезаются
+func effectiveConfig(flags *flag.FlagSet) map[string]string {
+	out := make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if isSecretFlag(f.Name) && value != "" {
+			value = redacted
+		}
+		out[f.Name] = value
+	})
+	return out
+}
+
+func isSecretFlag(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range secretFlagMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeOpsJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// startOps поднимает ops listener на addr и возвращает функцию остановки.
+// Ошибка listener'а не роняет сервис: без pprof он продолжает работать.
+func startOps(addr string, knobs *config.Watcher, logger zerolog.Logger) func(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           NewOpsHandler(flag.CommandLine, knobs),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error().Err(err).Str("addr", addr).Msg("ops listener stopped")
+		}
+	}()
+	logger.Info().Str("addr", addr).Msg("ops listener started")
+
+	return srv.Shutdown
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/config"
+)
+
+func TestOpsHandler_ConfigRedactsSecrets(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("storage", "postgres", "")
+	flags.String("db-password", "", "")
+	flags.String("api-token", "", "")
+	require.NoError(t, flags.Parse([]string{"-db-password=hunter2"}))
+
+	rec := httptest.NewRecorder()
+	NewOpsHandler(flags, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var cfg map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cfg))
+	require.Equal(t, map[string]string{
+		"storage":     "postgres",
+		"db-password": redacted,
+		"api-token":   "", // пустой секрет показываем как есть: видно, что он не задан
+	}, cfg)
+}
+
+func TestOpsHandler_Knobs(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Int("outbox-batch-size", 100, "")
+	knobs, err := config.NewWatcher(config.WatcherConfig{})
+	require.NoError(t, err)
+	require.NoError(t, knobs.Subscribe(config.Int("outbox-batch-size", 100, 1, func(int) error { return nil })))
+	h := NewOpsHandler(flags, knobs)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/debug/knobs", strings.NewReader(`{"outbox-batch-size":"250"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// /debug/config показывает действующее значение, а не флаг
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	var cfg map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cfg))
+	require.Equal(t, "250", cfg["outbox-batch-size"])
+}
+
+func TestOpsHandler_DebugEndpoints(t *testing.T) {
+	h := NewOpsHandler(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars", "/debug/runtime"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	var stats RuntimeStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Positive(t, stats.Goroutines)
+	require.NotEmpty(t, stats.GoVersion)
+}
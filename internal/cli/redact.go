@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	logRedact = flag.String("log-redact", strings.Join(DefaultRedaction.Deny, ","), "log fields replaced with "+redacted+" (comma separated)")
+	logHash   = flag.String("log-hash", strings.Join(DefaultRedaction.Hash, ","), "log fields with identifiers replaced by a salted hash (comma separated)")
+	logAllow  = flag.String("log-allow", "", "log fields never redacted or hashed, overrides -log-redact/-log-hash (comma separated)")
+)
+
+// EnvLogHashSalt — соль хэшей идентификаторов в логах. Без неё хэш короткого id
+// перебирается по словарю; одна соль на все сервисы сохраняет сквозную корреляцию.
+const EnvLogHashSalt = "LOG_HASH_SALT"
+
+// RedactionPolicy — какие поля записей лога считаются персональными данными.
+// Поля сравниваются по имени на верхнем уровне записи.
+type RedactionPolicy struct {
+	Deny  []string // значение заменяется на [REDACTED]
+	Hash  []string // идентификаторы: значение заменяется хэшем — записи одного владельца коррелируются, но id не читается
+	Allow []string // не трогаются, даже если есть в Deny или Hash (например, чтобы снять поле из DefaultRedaction)
+	Salt  string   // ключ HMAC для Hash
+}
+
+// DefaultRedaction — поля с персональными данными, которые пишут сервисы платформы:
+// владелец и автор действия, ключ сообщения Kafka (id медиа/агрегата владельца), контакты
+var DefaultRedaction = RedactionPolicy{
+	Deny: []string{"email", "phone", "ip", "remote_addr", "user_agent"},
+	Hash: []string{"owner_id", "actor", "user_id", "key"},
+}
+
+func (p RedactionPolicy) empty() bool {
+	return len(p.Deny) == 0 && len(p.Hash) == 0
+}
+
+// redactionFromFlags собирает политику из -log-redact/-log-hash/-log-allow и EnvLogHashSalt
+func redactionFromFlags() RedactionPolicy {
+	return RedactionPolicy{
+		Deny:  splitFields(*logRedact),
+		Hash:  splitFields(*logHash),
+		Allow: splitFields(*logAllow),
+		Salt:  os.Getenv(EnvLogHashSalt),
+	}
+}
+
+func splitFields(s string) []string {
+	var fields []string
+	for f := range strings.SplitSeq(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+type fieldAction int
+
+const (
+	keepField fieldAction = iota
+	denyField
+	hashField
+)
+
+// redactingWriter применяет RedactionPolicy к JSON-записям zerolog до того, как они
+// попадут в вывод. Стоит под логгером сервиса, поэтому покрывает все логи процесса:
+// access log, producer, outbox, хендлеры.
+type redactingWriter struct {
+	out     io.Writer
+	actions map[string]fieldAction
+	markers [][]byte // `"field":` — быстрая проверка, есть ли в записи что вычищать
+	salt    []byte
+}
+
+// NewRedactingWriter оборачивает w: поля записей из policy вычищаются или хэшируются,
+// порядок полей сохраняется. Записи без таких полей и не-JSON проходят как есть.
+func NewRedactingWriter(w io.Writer, policy RedactionPolicy) io.Writer {
+	r := &redactingWriter{out: w, actions: make(map[string]fieldAction), salt: []byte(policy.Salt)}
+	for _, f := range policy.Hash {
+		r.actions[f] = hashField
+	}
+	for _, f := range policy.Deny {
+		r.actions[f] = denyField
+	}
+	for _, f := range policy.Allow {
+		delete(r.actions, f)
+	}
+	for f := range r.actions {
+		marker, _ := json.Marshal(f)
+		r.markers = append(r.markers, append(marker, ':'))
+	}
+	return r
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if !slices.ContainsFunc(r.markers, func(m []byte) bool { return bytes.Contains(p, m) }) {
+		return r.out.Write(p)
+	}
+	line, err := r.redact(p)
+	if err != nil {
+		return r.out.Write(p)
+	}
+	if _, err := r.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redact переписывает поля верхнего уровня записи, не меняя их порядок
+func (r *redactingWriter) redact(p []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("log record is not a JSON object")
+	}
+	out := make([]byte, 0, len(p))
+	out = append(out, '{')
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		switch r.actions[name] {
+		case denyField:
+			value = json.RawMessage(`"` + redacted + `"`)
+		case hashField:
+			value = r.hash(value)
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		key, _ := json.Marshal(name)
+		out = append(out, key...)
+		out = append(out, ':')
+		out = append(out, value...)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return append(out, '}', '\n'), nil
+}
+
+// hash заменяет значение на "h:" + первые 16 hex-символов HMAC-SHA256. null и пустую строку не трогает.
+func (r *redactingWriter) hash(value json.RawMessage) json.RawMessage {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		s = string(value) // число или bool
+	}
+	if s == "" || string(value) == "null" {
+		return value
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(s))
+	return json.RawMessage(`"h:` + hex.EncodeToString(mac.Sum(nil))[:16] + `"`)
+}
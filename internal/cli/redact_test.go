@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, LoggerConfig{
+		Service:   "media",
+		Redaction: RedactionPolicy{Deny: []string{"email"}, Hash: []string{"owner_id", "key"}, Allow: []string{"key"}, Salt: "pepper"},
+	})
+	require.NoError(t, err)
+
+	logger.Info().Str("owner_id", "u-42").Str("email", "alice@example.com").Str("key", "m-1").Str("media_id", "m-1").Msg("media created")
+	logger.Info().Str("owner_id", "u-42").Msg("upload rejected")
+	logger.Info().Str("owner_id", "").Msg("no owner")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	var first, second, third map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	require.NoError(t, json.Unmarshal(lines[2], &third))
+
+	require.Equal(t, "[REDACTED]", first["email"])
+	require.Regexp(t, `^h:[0-9a-f]{16}$`, first["owner_id"])
+	require.NotContains(t, string(lines[0]), "u-42")
+	// Хэш стабилен: записи одного владельца коррелируются
+	require.Equal(t, first["owner_id"], second["owner_id"])
+	// Allow снимает поле из Hash
+	require.Equal(t, "m-1", first["key"])
+	require.Equal(t, "m-1", first["media_id"])
+	require.Equal(t, "media created", first["message"])
+	require.Equal(t, "", third["owner_id"])
+
+	// Порядок полей сохраняется
+	require.Less(t, bytes.Index(lines[0], []byte(`"owner_id"`)), bytes.Index(lines[0], []byte(`"email"`)))
+}
+
+func TestRedactingWriter_Salt(t *testing.T) {
+	hash := func(salt string) string {
+		var buf bytes.Buffer
+		w := NewRedactingWriter(&buf, RedactionPolicy{Hash: []string{"actor"}, Salt: salt})
+		_, err := w.Write([]byte(`{"actor":"u-42"}` + "\n"))
+		require.NoError(t, err)
+		return buf.String()
+	}
+	require.NotEqual(t, hash("a"), hash("b"))
+	require.Equal(t, hash("a"), hash("a"))
+}
+
+func TestRedactingWriter_PassesThrough(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewRedactingWriter(&buf, DefaultRedaction)
+
+	for _, line := range []string{
+		`{"level":"info","media_id":"m-1"}` + "\n", // нечего вычищать
+		`not json "email": x` + "\n",               // не JSON — как есть
+	} {
+		buf.Reset()
+		n, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		require.Equal(t, len(line), n)
+		require.Equal(t, line, buf.String())
+	}
+}
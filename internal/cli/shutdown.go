@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultStopTimeout — лимит на остановку компонента, если Component.Timeout не задан
+const DefaultStopTimeout = 10 * time.Second
+
+// Priority задаёт порядок остановки: компоненты с меньшим приоритетом останавливаются раньше
+type Priority int
+
+const (
+	StopServers   Priority = 10 // перестать принимать запросы (HTTP, gRPC)
+	StopConsumers Priority = 20 // дренировать consumers и outbox publisher
+	StopProducers Priority = 30 // сбросить и закрыть producers
+	StopStorage   Priority = 40 // закрыть пулы БД, кэши и прочие соединения
+)
+
+// Component — то, что нужно остановить при завершении сервиса
+type Component struct {
+	Name     string
+	Priority Priority
+	Timeout  time.Duration // default: DefaultStopTimeout
+	Stop     func(ctx context.Context) error
+}
+
+// Shutdown останавливает зарегистрированные компоненты по приоритетам.
+// Компоненты одного приоритета не зависят друг от друга и останавливаются параллельно;
+// следующий приоритет начинается, только когда закончился предыдущий.
+type Shutdown struct {
+	mu         sync.Mutex
+	components []Component
+	logger     zerolog.Logger
+}
+
+func NewShutdown(logger zerolog.Logger) *Shutdown {
+	return &Shutdown{logger: logger.With().Str("component", "shutdown").Logger()}
+}
+
+// Register добавляет компонент; вызывается при сборке сервиса, сразу после создания компонента
+func (s *Shutdown) Register(c Component) {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultStopTimeout
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.components = append(s.components, c)
+}
+
+// Stop останавливает все компоненты и возвращает объединённые ошибки. Ошибка или timeout
+// одного компонента не прерывает остановку остальных. Повторный вызов ничего не делает.
+func (s *Shutdown) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	components := s.components
+	s.components = nil
+	s.mu.Unlock()
+
+	// SortStableFunc: внутри приоритета сохраняется порядок регистрации (важно для логов)
+	slices.SortStableFunc(components, func(a, b Component) int { return int(a.Priority - b.Priority) })
+
+	var errs []error
+	for len(components) > 0 {
+		n := 1
+		for n < len(components) && components[n].Priority == components[0].Priority {
+			n++
+		}
+		errs = append(errs, s.stopGroup(ctx, components[:n])...)
+		components = components[n:]
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Shutdown) stopGroup(ctx context.Context, group []Component) []error {
+	errs := make([]error, len(group))
+	var wg sync.WaitGroup
+	for i, c := range group {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.stopComponent(ctx, c)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func (s *Shutdown) stopComponent(ctx context.Context, c Component) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.Stop(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Stop, не уважающий ctx, не должен задерживать остановку остальных компонентов
+		err = fmt.Errorf("timed out after %s: %w", c.Timeout, ctx.Err())
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("name", c.Name).Dur("duration", time.Since(start)).Msg("component stop failed")
+		return fmt.Errorf("stop %s: %w", c.Name, err)
+	}
+	s.logger.Info().Str("name", c.Name).Dur("duration", time.Since(start)).Msg("component stopped")
+	return nil
+}
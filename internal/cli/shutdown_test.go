@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown_StopsByPriority(t *testing.T) {
+	s := NewShutdown(zerolog.Nop())
+
+	var mu sync.Mutex
+	var order []string
+	stop := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	// регистрация в порядке сборки сервиса, остановка — в обратном по зависимостям
+	s.Register(Component{Name: "db", Priority: StopStorage, Stop: stop("db")})
+	s.Register(Component{Name: "producer", Priority: StopProducers, Stop: stop("producer")})
+	s.Register(Component{Name: "outbox", Priority: StopConsumers, Stop: stop("outbox")})
+	s.Register(Component{Name: "http", Priority: StopServers, Stop: stop("http")})
+
+	require.NoError(t, s.Stop(context.Background()))
+	require.Equal(t, []string{"http", "outbox", "producer", "db"}, order)
+
+	// повторный Stop ничего не делает
+	require.NoError(t, s.Stop(context.Background()))
+	require.Len(t, order, 4)
+}
+
+func TestShutdown_SamePriorityStopsConcurrently(t *testing.T) {
+	s := NewShutdown(zerolog.Nop())
+
+	// оба компонента ждут друг друга: последовательная остановка упёрлась бы в timeout
+	var wg sync.WaitGroup
+	wg.Add(2)
+	stop := func(context.Context) error {
+		wg.Done()
+		wg.Wait()
+		return nil
+	}
+	s.Register(Component{Name: "a", Priority: StopConsumers, Timeout: time.Second, Stop: stop})
+	s.Register(Component{Name: "b", Priority: StopConsumers, Timeout: time.Second, Stop: stop})
+
+	require.NoError(t, s.Stop(context.Background()))
+}
+
+func TestShutdown_TimeoutAndErrorsDoNotBlockOthers(t *testing.T) {
+	s := NewShutdown(zerolog.Nop())
+	errClose := errors.New("close failed")
+	dbClosed := false
+
+	s.Register(Component{Name: "stuck", Priority: StopServers, Timeout: 20 * time.Millisecond, Stop: func(context.Context) error {
+		select {} // игнорирует ctx
+	}})
+	s.Register(Component{Name: "producer", Priority: StopProducers, Stop: func(context.Context) error { return errClose }})
+	s.Register(Component{Name: "db", Priority: StopStorage, Stop: func(context.Context) error {
+		dbClosed = true
+		return nil
+	}})
+
+	err := s.Stop(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, errClose)
+	require.ErrorContains(t, err, "stop stuck")
+	require.ErrorContains(t, err, "stop producer")
+	require.True(t, dbClosed)
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrWorkerFailed — воркер исчерпал перезапуски; сервис после этого останавливается
+var ErrWorkerFailed = errors.New("worker failed")
+
+// Worker — фоновый цикл под присмотром Supervisor
+type Worker struct {
+	Name string
+	// Run блокируется, пока воркер работает. nil — штатное завершение, перезапуска нет;
+	// ошибка или panic — сбой, воркер перезапускается.
+	Run         func(ctx context.Context) error
+	MaxRestarts int           // подряд идущих сбоев до отказа сервиса (default: 5)
+	Backoff     time.Duration // пауза перед первым перезапуском, удваивается (default: 1s)
+	MaxBackoff  time.Duration // потолок паузы (default: 30s)
+}
+
+func (w *Worker) withDefaults() {
+	if w.MaxRestarts <= 0 {
+		w.MaxRestarts = 5
+	}
+	if w.Backoff <= 0 {
+		w.Backoff = time.Second
+	}
+	if w.MaxBackoff <= 0 {
+		w.MaxBackoff = 30 * time.Second
+	}
+}
+
+// Supervisor запускает воркеры, перехватывает их panic со стектрейсом в лог и перезапускает
+// с экспоненциальным backoff. Воркер, проработавший дольше MaxBackoff, считается восстановившимся —
+// счётчик сбоев сбрасывается. После MaxRestarts сбоев подряд вызывается onFailure.
+type Supervisor struct {
+	logger    zerolog.Logger
+	onFailure func(error)
+	wg        sync.WaitGroup
+}
+
+// NewSupervisor создаёт Supervisor; onFailure получает ErrWorkerFailed с причиной
+// и обычно отменяет контекст сервиса
+func NewSupervisor(logger zerolog.Logger, onFailure func(error)) *Supervisor {
+	return &Supervisor{
+		logger:    logger.With().Str("component", "supervisor").Logger(),
+		onFailure: onFailure,
+	}
+}
+
+// Go запускает воркер в отдельной горутине. Воркер останавливается отменой ctx.
+func (s *Supervisor) Go(ctx context.Context, w Worker) {
+	w.withDefaults()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.supervise(ctx, w); err != nil {
+			s.onFailure(err)
+		}
+	}()
+}
+
+// Wait дожидается завершения всех воркеров
+func (s *Supervisor) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Supervisor) supervise(ctx context.Context, w Worker) error {
+	logger := s.logger.With().Str("worker", w.Name).Logger()
+	failures := 0
+	backoff := w.Backoff
+
+	for {
+		start := time.Now()
+		err := runRecovered(ctx, w.Run)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		var pe *panicError
+		if errors.As(err, &pe) {
+			logger.Error().Interface("panic", pe.value).Str("stack", string(pe.stack)).Msg("worker panicked")
+		}
+
+		if time.Since(start) > w.MaxBackoff {
+			failures, backoff = 0, w.Backoff
+		}
+		failures++
+		if failures > w.MaxRestarts {
+			logger.Error().Err(err).Int("failures", failures).Msg("worker failed, restarts exhausted")
+			return fmt.Errorf("%w: %s: %w", ErrWorkerFailed, w.Name, err)
+		}
+
+		logger.Warn().Err(err).Int("failures", failures).Dur("backoff", backoff).Msg("worker failed, restarting")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, w.MaxBackoff)
+	}
+}
+
+// panicError — panic воркера, превращённый в ошибку; стектрейс уходит в лог
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+func runRecovered(ctx context.Context, run func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &panicError{value: v, stack: debug.Stack()}
+		}
+	}()
+	return run(ctx)
+}
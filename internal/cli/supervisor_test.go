@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisor_RestartsPanickingWorker(t *testing.T) {
+	var failed atomic.Value
+	s := NewSupervisor(zerolog.Nop(), func(err error) { failed.Store(err) })
+
+	var runs atomic.Int32
+	s.Go(context.Background(), Worker{
+		Name:    "flaky",
+		Backoff: time.Millisecond,
+		Run: func(context.Context) error {
+			switch runs.Add(1) {
+			case 1:
+				panic("boom")
+			case 2:
+				return errors.New("transient")
+			}
+			return nil // восстановился и штатно завершился
+		},
+	})
+
+	require.NoError(t, s.Wait(context.Background()))
+	require.EqualValues(t, 3, runs.Load())
+	require.Nil(t, failed.Load())
+}
+
+func TestSupervisor_FailsAfterMaxRestarts(t *testing.T) {
+	failed := make(chan error, 1)
+	s := NewSupervisor(zerolog.Nop(), func(err error) { failed <- err })
+
+	var runs atomic.Int32
+	s.Go(context.Background(), Worker{
+		Name:        "broken",
+		MaxRestarts: 2,
+		Backoff:     time.Millisecond,
+		Run: func(context.Context) error {
+			runs.Add(1)
+			panic("always")
+		},
+	})
+
+	require.NoError(t, s.Wait(context.Background()))
+	err := <-failed
+	require.ErrorIs(t, err, ErrWorkerFailed)
+	require.ErrorContains(t, err, "broken: panic: always")
+	require.EqualValues(t, 3, runs.Load()) // первый запуск + 2 перезапуска
+}
+
+func TestSupervisor_StopsOnContextCancel(t *testing.T) {
+	s := NewSupervisor(zerolog.Nop(), func(err error) { t.Errorf("unexpected failure: %v", err) })
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.Go(ctx, Worker{
+		Name:    "loop",
+		Backoff: time.Hour, // отмена должна прервать и паузу перед перезапуском
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	require.NoError(t, s.Wait(waitCtx))
+}
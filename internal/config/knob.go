@@ -0,0 +1,56 @@
+// Package config — настройки сервиса, которые меняются без перезапуска: по SIGHUP из файла
+// или через ops listener. Начальные значения задают флаги, имена настроек совпадают с ними.
+// HTTPServer — общие для сервисов флаги HTTP сервера; они применяются только при старте.
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Knob — настройка, которую компонент разрешает менять на лету
+type Knob struct {
+	Name  string // имя флага, задающего начальное значение
+	Value string // текущее значение
+	// Parse проверяет новое значение и возвращает функцию, применяющую его к компоненту.
+	// Все проверки — в Parse: Watcher применяет изменения, только если все значения корректны.
+	Parse func(value string) (apply func() error, err error)
+}
+
+// Int — целочисленная настройка не меньше min
+func Int(name string, value, min int, set func(int) error) Knob {
+	return Knob{
+		Name:  name,
+		Value: strconv.Itoa(value),
+		Parse: func(s string) (func() error, error) {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("not an integer: %q", s)
+			}
+			if n < min {
+				return nil, fmt.Errorf("must be at least %d, got: %d", min, n)
+			}
+			return func() error { return set(n) }, nil
+		},
+	}
+}
+
+// Duration — настройка-длительность не меньше min
+func Duration(name string, value, min time.Duration, set func(time.Duration) error) Knob {
+	return Knob{
+		Name:  name,
+		Value: value.String(),
+		Parse: func(s string) (func() error, error) {
+			d, err := time.ParseDuration(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("not a duration: %q", s)
+			}
+			if d < min {
+				return nil, fmt.Errorf("must be at least %v, got: %v", min, d)
+			}
+			return func() error { return set(d) }, nil
+		},
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPServer — настройки HTTP сервера сервиса. Флаги -http-* у всех сервисов одни,
+// умолчания у каждого свои. Меняются только перезапуском.
+type HTTPServer struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration // чтение запроса с телом; 0 — без предела
+	WriteTimeout      time.Duration // от конца заголовков до конца ответа; 0 — без предела
+	IdleTimeout       time.Duration // keep-alive соединения без запросов
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64 // предел тела запроса (413); 0 — без предела
+
+	// TLSCertFile и TLSKeyFile — PEM сертификат и ключ; пустые — сервер без TLS
+	TLSCertFile string
+	TLSKeyFile  string
+	// HTTP2 — HTTP/2 по TLS (ALPN h2)
+	HTTP2 bool
+	// H2C — HTTP/2 без TLS (prior knowledge), для сервиса за прокси с HTTP/2 до бэкенда
+	H2C bool
+	// HTTP2MaxConcurrentStreams — потоков на одно HTTP/2 соединение
+	HTTP2MaxConcurrentStreams int
+}
+
+// DefaultHTTPServer — умолчания для сервиса без долгих запросов и больших тел;
+// сервис с загрузками или потоками ответов ослабляет их перед HTTPServerFlags
+func DefaultHTTPServer() HTTPServer {
+	return HTTPServer{
+		ReadHeaderTimeout:         5 * time.Second,
+		ReadTimeout:               30 * time.Second,
+		WriteTimeout:              30 * time.Second,
+		IdleTimeout:               2 * time.Minute,
+		MaxHeaderBytes:            http.DefaultMaxHeaderBytes,
+		MaxBodyBytes:              1 << 20,
+		HTTP2:                     true,
+		HTTP2MaxConcurrentStreams: 250,
+	}
+}
+
+// HTTPServerFlags регистрирует флаги -http-* в fs с умолчаниями def.
+// Возвращённые настройки заполнены после fs.Parse.
+func HTTPServerFlags(fs *flag.FlagSet, def HTTPServer) *HTTPServer {
+	c := &HTTPServer{}
+	fs.DurationVar(&c.ReadHeaderTimeout, "http-read-header-timeout", def.ReadHeaderTimeout, "HTTP server: time to read request headers")
+	fs.DurationVar(&c.ReadTimeout, "http-read-timeout", def.ReadTimeout, "HTTP server: time to read the whole request including the body (0 = unlimited)")
+	fs.DurationVar(&c.WriteTimeout, "http-write-timeout", def.WriteTimeout, "HTTP server: time from the end of request headers to the end of the response (0 = unlimited)")
+	fs.DurationVar(&c.IdleTimeout, "http-idle-timeout", def.IdleTimeout, "HTTP server: how long an idle keep-alive connection is kept")
+	fs.IntVar(&c.MaxHeaderBytes, "http-max-header-bytes", def.MaxHeaderBytes, "HTTP server: largest accepted request headers")
+	fs.Int64Var(&c.MaxBodyBytes, "http-max-body-bytes", def.MaxBodyBytes, "HTTP server: largest accepted request body, larger get 413 (0 = unlimited)")
+	fs.StringVar(&c.TLSCertFile, "http-tls-cert", def.TLSCertFile, "HTTP server: PEM certificate file; with -http-tls-key serves HTTPS")
+	fs.StringVar(&c.TLSKeyFile, "http-tls-key", def.TLSKeyFile, "HTTP server: PEM private key file of -http-tls-cert")
+	fs.BoolVar(&c.HTTP2, "http2", def.HTTP2, "HTTP server: serve HTTP/2 over TLS")
+	fs.BoolVar(&c.H2C, "http-h2c", def.H2C, "HTTP server: serve unencrypted HTTP/2 (prior knowledge) next to HTTP/1.1, e.g. behind a proxy speaking HTTP/2 to backends")
+	fs.IntVar(&c.HTTP2MaxConcurrentStreams, "http2-max-concurrent-streams", def.HTTP2MaxConcurrentStreams, "HTTP server: concurrent streams per HTTP/2 connection")
+	return c
+}
+
+// Validate проверяет настройки
+func (c HTTPServer) Validate() error {
+	var errs []error
+	if c.ReadHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("read header timeout must be positive"))
+	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 {
+		errs = append(errs, errors.New("timeouts must not be negative"))
+	}
+	if c.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("max header bytes must be positive"))
+	}
+	if c.MaxBodyBytes < 0 {
+		errs = append(errs, errors.New("max body bytes must not be negative"))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls cert and key must be set together"))
+	}
+	if c.HTTP2MaxConcurrentStreams <= 0 {
+		errs = append(errs, errors.New("http2 max concurrent streams must be positive"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("http server: %w", err)
+	}
+	return nil
+}
+
+// TLS — сервер отдаёт HTTPS
+func (c HTTPServer) TLS() bool {
+	return c.TLSCertFile != ""
+}
+
+// NewServer собирает http.Server на addr с настройками c; handler получает предел тела MaxBodyBytes.
+// Запускать — через c.ListenAndServe.
+func (c HTTPServer) NewServer(addr string, handler http.Handler) (*http.Server, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.MaxBodyBytes > 0 {
+		handler = LimitBody(c.MaxBodyBytes, handler)
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(c.HTTP2 && c.TLS())
+	protocols.SetUnencryptedHTTP2(c.H2C)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		Protocols:         protocols,
+		HTTP2:             &http.HTTP2Config{MaxConcurrentStreams: c.HTTP2MaxConcurrentStreams},
+	}
+	if c.TLS() {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return srv, nil
+}
+
+// ListenAndServe запускает srv, собранный NewServer: с TLS, если он настроен
+func (c HTTPServer) ListenAndServe(srv *http.Server) error {
+	if c.TLS() {
+		return srv.ListenAndServeTLS(c.TLSCertFile, c.TLSKeyFile)
+	}
+	return srv.ListenAndServe()
+}
+
+// LimitBody отвечает 413 на запрос с Content-Length больше max, не читая тело. Тело без
+// Content-Length (chunked) обрывается на max байтах: чтение дальше — ошибка *http.MaxBytesError.
+func LimitBody(max int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Connection", "close")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_, _ = fmt.Fprintf(w, `{"code":"invalid_argument","message":"request body is larger than %d bytes"}`+"\n", max)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPServerFlags(t *testing.T) {
+	fs := flag.NewFlagSet("media", flag.ContinueOnError)
+	def := DefaultHTTPServer()
+	def.WriteTimeout = 0
+	c := HTTPServerFlags(fs, def)
+	require.NoError(t, fs.Parse([]string{"-http-read-timeout=1m", "-http-max-body-bytes=10", "-http-h2c"}))
+
+	require.Equal(t, time.Minute, c.ReadTimeout)
+	require.Zero(t, c.WriteTimeout)
+	require.Equal(t, int64(10), c.MaxBodyBytes)
+	require.True(t, c.H2C)
+
+	srv, err := c.NewServer(":0", http.NotFoundHandler())
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, srv.ReadTimeout)
+	require.Equal(t, 5*time.Second, srv.ReadHeaderTimeout)
+	require.Equal(t, http.DefaultMaxHeaderBytes, srv.MaxHeaderBytes)
+	require.True(t, srv.Protocols.UnencryptedHTTP2())
+	// HTTP/2 без сертификата возможен только как h2c
+	require.False(t, srv.Protocols.HTTP2())
+	require.Nil(t, srv.TLSConfig)
+}
+
+func TestHTTPServer_Validate(t *testing.T) {
+	c := DefaultHTTPServer()
+	require.NoError(t, c.Validate())
+
+	c.TLSCertFile = "cert.pem"
+	c.MaxBodyBytes = -1
+	c.ReadHeaderTimeout = 0
+	err := c.Validate()
+	require.ErrorContains(t, err, "tls cert and key")
+	require.ErrorContains(t, err, "max body bytes")
+	require.ErrorContains(t, err, "read header timeout")
+
+	_, err = c.NewServer(":0", http.NotFoundHandler())
+	require.Error(t, err)
+
+	c = DefaultHTTPServer()
+	c.TLSCertFile, c.TLSKeyFile = "cert.pem", "key.pem"
+	srv, err := c.NewServer(":0", http.NotFoundHandler())
+	require.NoError(t, err)
+	require.True(t, srv.Protocols.HTTP2())
+	require.NotNil(t, srv.TLSConfig)
+}
+
+func TestLimitBody(t *testing.T) {
+	var readErr error
+	h := LimitBody(4, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234")))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.NoError(t, readErr)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	require.Contains(t, rec.Body.String(), `"invalid_argument"`)
+
+	// Без Content-Length тело обрывается на пределе
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("12345")))
+	req.ContentLength = -1
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	var tooLarge *http.MaxBytesError
+	require.True(t, errors.As(readErr, &tooLarge))
+}
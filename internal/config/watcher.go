@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/rs/zerolog"
+)
+
+// WatcherConfig содержит конфигурацию Watcher
+type WatcherConfig struct {
+	// File — файл со строками name=value (# — комментарий), перечитывается по SIGHUP.
+	// Значения из файла заменяют флаги уже при подписке. Пустой — настройки меняются только через Set.
+	File   string
+	Logger zerolog.Logger
+}
+
+// Watcher хранит настройки, на которые подписались компоненты, и применяет их изменения:
+// по SIGHUP перечитывает File, через Set — из ops listener
+type Watcher struct {
+	file   string
+	logger zerolog.Logger
+
+	mu       sync.Mutex
+	knobs    map[string]*Knob
+	fromFile map[string]string // значения File на момент последнего чтения
+}
+
+func NewWatcher(cfg WatcherConfig) (*Watcher, error) {
+	w := &Watcher{
+		file:   cfg.File,
+		logger: cfg.Logger.With().Str("component", "config").Logger(),
+		knobs:  make(map[string]*Knob),
+	}
+	if cfg.File != "" {
+		values, err := readFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		w.fromFile = values
+	}
+	return w, nil
+}
+
+// Subscribe подписывает компонент на изменения настройки k. Если настройка есть в File,
+// её значение применяется сразу.
+func (w *Watcher) Subscribe(k Knob) error {
+	if k.Name == "" || k.Parse == nil {
+		return errors.New("knob name and parse are required")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.knobs[k.Name]; ok {
+		return fmt.Errorf("knob %q already subscribed", k.Name)
+	}
+	w.knobs[k.Name] = &k
+
+	if value, ok := w.fromFile[k.Name]; ok {
+		if err := w.set(map[string]string{k.Name: value}); err != nil {
+			delete(w.knobs, k.Name)
+			return fmt.Errorf("%s: %w", w.file, err)
+		}
+	}
+	return nil
+}
+
+// Set применяет новые значения настроек. Если хоть одно имя неизвестно или значение
+// некорректно, не меняется ничего.
+func (w *Watcher) Set(values map[string]string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.set(values)
+}
+
+func (w *Watcher) set(values map[string]string) error {
+	applies := make(map[string]func() error, len(values))
+	var errs []error
+	for name, value := range values {
+		k, ok := w.knobs[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: not reloadable", name))
+			continue
+		}
+		apply, err := k.Parse(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		applies[name] = apply
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(applies)) {
+		k, value := w.knobs[name], strings.TrimSpace(values[name])
+		if value == k.Value {
+			continue
+		}
+		if err := applies[name](); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		w.logger.Info().Str("knob", name).Str("old", k.Value).Str("new", value).Msg("config changed")
+		k.Value = value
+	}
+	return errors.Join(errs...)
+}
+
+// Reload перечитывает File и применяет его значения. Настройки, на которые никто
+// не подписан, пропускаются: один файл может обслуживать несколько сервисов.
+func (w *Watcher) Reload() error {
+	if w.file == "" {
+		return errors.New("config file is not set")
+	}
+	values, err := readFile(w.file)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fromFile = values
+	known := make(map[string]string, len(values))
+	for name, value := range values {
+		if _, ok := w.knobs[name]; ok {
+			known[name] = value
+		}
+	}
+	return w.set(known)
+}
+
+// Values возвращает текущие значения настроек
+func (w *Watcher) Values() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make(map[string]string, len(w.knobs))
+	for name, k := range w.knobs {
+		out[name] = k.Value
+	}
+	return out
+}
+
+// Run перечитывает File по SIGHUP до отмены ctx. Ошибка чтения не останавливает сервис:
+// остаются прежние значения.
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			if err := w.Reload(); err != nil {
+				w.logger.Error().Err(err).Msg("config reload failed")
+				continue
+			}
+			w.logger.Info().Msg("config reloaded")
+		}
+	}
+}
+
+// ServeHTTP отдаёт текущие значения настроек (GET) и меняет их (PATCH с JSON-объектом name→value)
+func (w *Watcher) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		var values map[string]string
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 64<<10)).Decode(&values); err != nil {
+			http.Error(rw, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := w.Set(values); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		rw.Header().Set("Allow", "GET, PATCH")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(w.Values())
+}
+
+// readFile читает файл строк name=value; пустые строки и строки с # пропускаются
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open config: %w", err)
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected name=value", path, n)
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestWatcher_SubscribeAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media.conf")
+	writeConfig(t, path, "# overrides\noutbox-batch-size = 200\nupload-limit=5\n")
+
+	w, err := NewWatcher(WatcherConfig{File: path, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	var batch int
+	var interval time.Duration
+	set := func(n int) error { batch = n; return nil }
+	require.NoError(t, w.Subscribe(Int("outbox-batch-size", 100, 1, set)))
+	require.NoError(t, w.Subscribe(Duration("outbox-interval", time.Second, time.Millisecond, func(d time.Duration) error {
+		interval = d
+		return nil
+	})))
+	require.Error(t, w.Subscribe(Int("outbox-batch-size", 1, 1, set)), "duplicate")
+
+	// Значение из файла заменяет флаг при подписке; upload-limit — чужая настройка
+	require.Equal(t, 200, batch)
+	require.Zero(t, interval)
+	require.Equal(t, map[string]string{"outbox-batch-size": "200", "outbox-interval": "1s"}, w.Values())
+
+	writeConfig(t, path, "outbox-batch-size=50\noutbox-interval=250ms\n")
+	require.NoError(t, w.Reload())
+	require.Equal(t, 50, batch)
+	require.Equal(t, 250*time.Millisecond, interval)
+
+	// Некорректное значение — не меняется ничего
+	writeConfig(t, path, "outbox-batch-size=10\noutbox-interval=soon\n")
+	require.Error(t, w.Reload())
+	require.Equal(t, 50, batch)
+
+	writeConfig(t, path, "outbox-batch-size\n")
+	require.Error(t, w.Reload())
+}
+
+func TestWatcher_Set(t *testing.T) {
+	w, err := NewWatcher(WatcherConfig{Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	var limit int
+	require.NoError(t, w.Subscribe(Int("upload-limit", 100, 1, func(n int) error { limit = n; return nil })))
+
+	require.Error(t, w.Set(map[string]string{"upload-limit": "0"}))
+	require.Error(t, w.Set(map[string]string{"upload-limit": "5", "db-dsn": "x"}), "not reloadable")
+	require.Zero(t, limit)
+
+	require.NoError(t, w.Set(map[string]string{"upload-limit": "5"}))
+	require.Equal(t, 5, limit)
+	require.Error(t, w.Reload(), "no file")
+
+	_, err = NewWatcher(WatcherConfig{File: filepath.Join(t.TempDir(), "missing.conf")})
+	require.Error(t, err)
+}
+
+func TestWatcher_ServeHTTP(t *testing.T) {
+	w, err := NewWatcher(WatcherConfig{Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	var batch int
+	require.NoError(t, w.Subscribe(Int("outbox-batch-size", 100, 1, func(n int) error { batch = n; return nil })))
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/debug/knobs", strings.NewReader(`{"outbox-batch-size":"300"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"outbox-batch-size":"300"}`, rec.Body.String())
+	require.Equal(t, 300, batch)
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/debug/knobs", strings.NewReader(`{"outbox-batch-size":"-1"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/debug/knobs", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payload схемы событий коллекций. Они пишутся тем же outbox, что и события media, поэтому
+// входят в Default; агрегат — коллекция.
+
+type CollectionItemAddedV1 struct {
+	EventID      uuid.UUID `json:"event_id"`
+	CollectionID uuid.UUID `json:"collection_id"`
+	OwnerID      uuid.UUID `json:"owner_id,omitzero"`
+	MediaID      uuid.UUID `json:"media_id"`
+	Position     int       `json:"position"`
+	Actor        string    `json:"actor,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+type CollectionItemRemovedV1 struct {
+	EventID      uuid.UUID `json:"event_id"`
+	CollectionID uuid.UUID `json:"collection_id"`
+	OwnerID      uuid.UUID `json:"owner_id,omitzero"`
+	MediaID      uuid.UUID `json:"media_id"`
+	Position     int       `json:"position"` // место, которое медиа занимало; следующие сдвинулись вперёд
+	Actor        string    `json:"actor,omitempty"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+type CollectionDeletedV1 struct {
+	EventID      uuid.UUID   `json:"event_id"`
+	CollectionID uuid.UUID   `json:"collection_id"`
+	OwnerID      uuid.UUID   `json:"owner_id,omitzero"`
+	MediaIDs     []uuid.UUID `json:"media_ids"` // медиа, которые были в коллекции, по порядку
+	Actor        string      `json:"actor,omitempty"`
+	OccurredAt   time.Time   `json:"occurred_at"`
+}
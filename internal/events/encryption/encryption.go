@@ -0,0 +1,194 @@
+// Package encryption — шифрование payload событий at rest: в outbox и в сообщениях Kafka.
+// AES-256-GCM, ключи по id выдаёт KeyProvider (KMS или статический Keyring); id ключа
+// хранится рядом с шифротекстом, поэтому старые ключи продолжают расшифровывать после ротации.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvKeys — переменная окружения с ключами шифрования событий (см. ParseKeyring)
+const EnvKeys = "EVENT_ENCRYPTION_KEYS"
+
+// ErrNoCipher — данные зашифрованы, а шифрование не настроено
+var ErrNoCipher = errors.New("payload is encrypted but no encryption keys are configured")
+
+// KeyProvider выдаёт 256-битные ключи AES. Реализация поверх KMS расшифровывает
+// data key'и мастер-ключом; Cipher кэширует ключи по id, поэтому Key вызывается
+// для каждого id один раз, а Current — на каждое шифрование и должен быть дешёвым.
+type KeyProvider interface {
+	// Current возвращает id ключа, которым шифруются новые данные
+	Current(ctx context.Context) (string, error)
+	// Key возвращает ключ по id
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// Keyring — статический набор ключей; первый — текущий, остальные только расшифровывают
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// ParseKeyring разбирает ключи вида "k2:<base64>,k1:<base64>": первый — текущий
+func ParseKeyring(spec string) (*Keyring, error) {
+	kr := &Keyring{keys: make(map[string][]byte)}
+	for _, part := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key %q: want id:base64", part)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %s: want 32 bytes, got %d", id, len(key))
+		}
+		if _, dup := kr.keys[id]; dup {
+			return nil, fmt.Errorf("key %s: duplicate id", id)
+		}
+		if kr.current == "" {
+			kr.current = id
+		}
+		kr.keys[id] = key
+	}
+	return kr, nil
+}
+
+func (k *Keyring) Current(context.Context) (string, error) { return k.current, nil }
+
+func (k *Keyring) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+// Cipher шифрует данные AES-256-GCM. nil *Cipher — шифрование выключено.
+type Cipher struct {
+	keys  KeyProvider
+	aeads sync.Map // id ключа → cipher.AEAD
+}
+
+func NewCipher(keys KeyProvider) (*Cipher, error) {
+	if keys == nil {
+		return nil, errors.New("key provider is required")
+	}
+	return &Cipher{keys: keys}, nil
+}
+
+// FromEnv — Cipher по ключам из EnvKeys; без них — nil
+func FromEnv() (*Cipher, error) {
+	spec := os.Getenv(EnvKeys)
+	if spec == "" {
+		return nil, nil
+	}
+	kr, err := ParseKeyring(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", EnvKeys, err)
+	}
+	return NewCipher(kr)
+}
+
+// Encrypt шифрует plaintext текущим ключом. aad не шифруется, но привязан к шифротексту:
+// расшифровать можно только с тем же aad (например event_id — payload нельзя подложить другому событию).
+// Возвращает id ключа и nonce||ciphertext.
+func (c *Cipher) Encrypt(ctx context.Context, plaintext, aad []byte) (string, []byte, error) {
+	id, err := c.keys.Current(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("current encryption key: %w", err)
+	}
+	aead, err := c.aead(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("nonce: %w", err)
+	}
+	return id, aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt расшифровывает результат Encrypt ключом id
+func (c *Cipher) Decrypt(ctx context.Context, id string, data, aad []byte) ([]byte, error) {
+	aead, err := c.aead(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("decrypt: ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt with key %s: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+func (c *Cipher) aead(ctx context.Context, id string) (cipher.AEAD, error) {
+	if a, ok := c.aeads.Load(id); ok {
+		return a.(cipher.AEAD), nil
+	}
+	key, err := c.keys.Key(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %s: %w", id, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %s: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c.aeads.Store(id, aead)
+	return aead, nil
+}
+
+// sealedJSON — зашифрованный JSON payload в хранилище; сам остаётся JSON (колонки jsonb)
+type sealedJSON struct {
+	Encrypted *sealedPayload `json:"$encrypted"`
+}
+
+type sealedPayload struct {
+	KeyID string `json:"key_id"`
+	Data  []byte `json:"data"` // nonce||ciphertext, base64
+}
+
+// SealJSON шифрует JSON payload в {"$encrypted":{"key_id":...,"data":...}}.
+// nil *Cipher возвращает payload как есть.
+func (c *Cipher) SealJSON(ctx context.Context, payload json.RawMessage, aad []byte) (json.RawMessage, error) {
+	if c == nil {
+		return payload, nil
+	}
+	id, data, err := c.Encrypt(ctx, payload, aad)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealedJSON{Encrypted: &sealedPayload{KeyID: id, Data: data}})
+}
+
+// OpenJSON расшифровывает результат SealJSON; незашифрованный payload (записанный до
+// включения шифрования) возвращается как есть. Зашифрованный при nil *Cipher — ErrNoCipher.
+func (c *Cipher) OpenJSON(ctx context.Context, payload json.RawMessage, aad []byte) (json.RawMessage, error) {
+	var sealed sealedJSON
+	if err := json.Unmarshal(payload, &sealed); err != nil || sealed.Encrypted == nil {
+		return payload, nil
+	}
+	if c == nil {
+		return nil, ErrNoCipher
+	}
+	return c.Decrypt(ctx, sealed.Encrypted.KeyID, sealed.Encrypted.Data, aad)
+}
@@ -0,0 +1,92 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestParseKeyring(t *testing.T) {
+	kr, err := ParseKeyring("k2:" + testKey('b') + ", k1:" + testKey('a'))
+	require.NoError(t, err)
+	current, err := kr.Current(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "k2", current)
+	_, err = kr.Key(context.Background(), "k1")
+	require.NoError(t, err)
+	_, err = kr.Key(context.Background(), "k3")
+	require.Error(t, err)
+
+	for _, spec := range []string{"", "k1", ":" + testKey('a'), "k1:not-base64!", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "k1:" + testKey('a') + ",k1:" + testKey('b')} {
+		_, err := ParseKeyring(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestCipher_RotationAndAAD(t *testing.T) {
+	ctx := context.Background()
+	old, err := ParseKeyring("k1:" + testKey('a'))
+	require.NoError(t, err)
+	c1, err := NewCipher(old)
+	require.NoError(t, err)
+
+	id, data, err := c1.Encrypt(ctx, []byte("user@example.com"), []byte("event-1"))
+	require.NoError(t, err)
+	require.Equal(t, "k1", id)
+	require.NotContains(t, string(data), "user@example.com")
+
+	// После ротации новый ключ шифрует, старый по-прежнему расшифровывает
+	rotated, err := ParseKeyring("k2:" + testKey('b') + ",k1:" + testKey('a'))
+	require.NoError(t, err)
+	c2, err := NewCipher(rotated)
+	require.NoError(t, err)
+	plaintext, err := c2.Decrypt(ctx, id, data, []byte("event-1"))
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", string(plaintext))
+	id, _, err = c2.Encrypt(ctx, []byte("x"), nil)
+	require.NoError(t, err)
+	require.Equal(t, "k2", id)
+
+	// Шифротекст привязан к aad
+	_, err = c2.Decrypt(ctx, "k1", data, []byte("event-2"))
+	require.Error(t, err)
+}
+
+func TestCipher_SealJSON(t *testing.T) {
+	ctx := context.Background()
+	kr, err := ParseKeyring("k1:" + testKey('a'))
+	require.NoError(t, err)
+	c, err := NewCipher(kr)
+	require.NoError(t, err)
+
+	payload := json.RawMessage(`{"owner_id":"u1","source":"s3://bucket/a.mp4"}`)
+	sealed, err := c.SealJSON(ctx, payload, []byte("event-1"))
+	require.NoError(t, err)
+	require.True(t, json.Valid(sealed))
+	require.Contains(t, string(sealed), `"$encrypted":{"key_id":"k1"`)
+
+	opened, err := c.OpenJSON(ctx, sealed, []byte("event-1"))
+	require.NoError(t, err)
+	require.JSONEq(t, string(payload), string(opened))
+
+	// Записанное до включения шифрования читается как есть
+	opened, err = c.OpenJSON(ctx, payload, []byte("event-1"))
+	require.NoError(t, err)
+	require.Equal(t, payload, opened)
+
+	// nil *Cipher: запись без шифрования, зашифрованное не читается
+	var none *Cipher
+	plain, err := none.SealJSON(ctx, payload, nil)
+	require.NoError(t, err)
+	require.Equal(t, payload, plain)
+	_, err = none.OpenJSON(ctx, sealed, []byte("event-1"))
+	require.ErrorIs(t, err, ErrNoCipher)
+}
@@ -0,0 +1,69 @@
+// Package events — общий контракт событий платформы: конверт со схемой версий
+// и реестр типов для marshal/unmarshal payload по (event_type, schema_version).
+// Используется outbox репозиторием при записи, producer'ом при публикации и consumer'ами при чтении.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Envelope — стандартный конверт события в outbox и Kafka. Ключ дедупликации — EventID
+// (повторная доставка несёт тот же id), порядок внутри агрегата — Sequence.
+type Envelope struct {
+	EventID       string    `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	SchemaVersion int       `json:"schema_version"`
+	AggregateID   string    `json:"aggregate_id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	// Sequence — номер события в потоке агрегата (1, 2, ...). Назначается outbox в транзакции
+	// изменения под блокировкой счётчика агрегата, поэтому порядок номеров совпадает с порядком
+	// коммитов. 0 — номер не назначен (события, записанные до его появления).
+	Sequence    int64           `json:"sequence,omitempty"`
+	PublishedAt time.Time       `json:"published_at,omitzero"` // проставляет outbox publisher
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// NewerThan сообщает, применять ли событие consumer'у, который уже применил событие этого
+// агрегата с номером last: повтор и устаревшее событие (Sequence <= last) пропускаются.
+// Событие без номера применяется — для него остаётся дедупликация по EventID.
+func (e Envelope) NewerThan(last int64) bool {
+	return e.Sequence == 0 || e.Sequence > last
+}
+
+// Marshal сериализует конверт в JSON
+func (e Envelope) Marshal() ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("marshal envelope: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalEnvelope разбирает конверт и проверяет обязательные поля
+func UnmarshalEnvelope(data []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Envelope{}, fmt.Errorf("unmarshal envelope: %w", err)
+	}
+	if env.EventID == "" || env.EventType == "" {
+		return Envelope{}, fmt.Errorf("unmarshal envelope: event_id and event_type are required")
+	}
+	if env.SchemaVersion <= 0 {
+		return Envelope{}, fmt.Errorf("unmarshal envelope: invalid schema_version %d", env.SchemaVersion)
+	}
+	return env, nil
+}
+
+// Tenant возвращает владельца события (payload.owner_id) — по нему события маршрутизируются
+// по тенантам. Пустая строка — у события нет владельца или payload не JSON объект.
+func (e Envelope) Tenant() string {
+	var body struct {
+		OwnerID string `json:"owner_id"`
+	}
+	if err := json.Unmarshal(e.Payload, &body); err != nil {
+		return ""
+	}
+	return body.OwnerID
+}
@@ -0,0 +1,93 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Pruner удаляет отметки об обработанных событиях старше cutoff
+type Pruner interface {
+	DeleteProcessedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// CleanerConfig содержит конфигурацию очистки processed_events
+type CleanerConfig struct {
+	Store    Pruner
+	TTL      time.Duration // Сколько хранить event_id (default: 7 дней); должно превышать retention топика
+	Interval time.Duration // Период очистки (default: 1h)
+	Logger   zerolog.Logger
+}
+
+// Cleaner периодически удаляет устаревшие записи processed_events,
+// чтобы таблица не росла бесконечно
+type Cleaner struct {
+	store    Pruner
+	ttl      time.Duration
+	interval time.Duration
+	clock    func() time.Time
+	logger   zerolog.Logger
+}
+
+func NewCleaner(cfg CleanerConfig) (*Cleaner, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if cfg.TTL < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative, got: %v", cfg.TTL)
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %v", cfg.Interval)
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 7 * 24 * time.Hour
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Hour
+	}
+
+	return &Cleaner{
+		store:    cfg.Store,
+		ttl:      cfg.TTL,
+		interval: cfg.Interval,
+		clock:    time.Now,
+		logger:   cfg.Logger.With().Str("component", "inbox_cleaner").Logger(),
+	}, nil
+}
+
+// RunOnce удаляет записи старше TTL
+func (c *Cleaner) RunOnce(ctx context.Context) (int64, error) {
+	cutoff := c.clock().Add(-c.ttl)
+
+	deleted, err := c.store.DeleteProcessedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	c.logger.Info().
+		Int64("deleted", deleted).
+		Time("cutoff", cutoff).
+		Msg("processed events cleaned up")
+
+	return deleted, nil
+}
+
+// Start запускает очистку каждые Interval до отмены контекста
+func (c *Cleaner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info().Msg("inbox cleaner stopped")
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := c.RunOnce(ctx); err != nil {
+				c.logger.Error().Err(err).Msg("inbox cleanup failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package inbox реализует паттерн idempotent consumer: outbox даёт at-least-once доставку,
+// поэтому каждый consumer фиксирует event_id в processed_events в той же транзакции,
+// в которой применяет эффект события. Повторная доставка того же события пропускается.
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// Handler применяет событие в рамках транзакции tx, в которой уже записан его event_id
+type Handler func(ctx context.Context, tx *sqlx.Tx, env events.Envelope) error
+
+// Store — хранилище обработанных событий (таблица processed_events)
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
+	return s.db.BeginTxx(ctx, nil)
+}
+
+// MarkProcessedTx записывает (consumer, event_id) в рамках tx.
+// Возвращает false, если событие этим consumer'ом уже обработано.
+func (s *Store) MarkProcessedTx(ctx context.Context, tx *sqlx.Tx, consumer, eventID string) (bool, error) {
+	const q = `
+        INSERT INTO processed_events (consumer, event_id, processed_at)
+        VALUES ($1, $2, now())
+        ON CONFLICT (consumer, event_id) DO NOTHING
+    `
+
+	res, err := tx.ExecContext(ctx, q, consumer, eventID)
+	if err != nil {
+		return false, fmt.Errorf("mark processed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("mark processed rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+// DeleteProcessedBefore удаляет записи старше cutoff и возвращает их количество
+func (s *Store) DeleteProcessedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	const q = `DELETE FROM processed_events WHERE processed_at < $1`
+
+	res, err := s.db.ExecContext(ctx, q, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("delete processed: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// WithIdempotency оборачивает handler: в одной транзакции сначала записывается event_id,
+// затем вызывается handler. Если handler вернул ошибку, откатывается и отметка —
+// событие обработается при следующей доставке. Дубликат возвращает nil, чтобы consumer
+// закоммитил offset. Конкурентная доставка того же события блокируется на уникальном
+// ключе до завершения первой транзакции, после чего пропускается.
+func WithIdempotency(store *Store, consumer string, h Handler) func(ctx context.Context, env events.Envelope) error {
+	return func(ctx context.Context, env events.Envelope) (err error) {
+		if env.EventID == "" {
+			return errors.New("inbox: event_id is required")
+		}
+
+		tx, err := store.BeginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() {
+			if err != nil {
+				_ = tx.Rollback()
+			}
+		}()
+
+		first, err := store.MarkProcessedTx(ctx, tx, consumer, env.EventID)
+		if err != nil {
+			return err
+		}
+		if !first {
+			return tx.Rollback()
+		}
+
+		if err = h(ctx, tx, env); err != nil {
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+type prunerFunc func(ctx context.Context, cutoff time.Time) (int64, error)
+
+func (f prunerFunc) DeleteProcessedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return f(ctx, cutoff)
+}
+
+func TestNewCleaner_Defaults(t *testing.T) {
+	c, err := NewCleaner(CleanerConfig{Store: prunerFunc(nil), Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	require.Equal(t, 7*24*time.Hour, c.ttl)
+	require.Equal(t, time.Hour, c.interval)
+}
+
+func TestNewCleaner_Validation(t *testing.T) {
+	_, err := NewCleaner(CleanerConfig{})
+	require.ErrorContains(t, err, "store is required")
+
+	_, err = NewCleaner(CleanerConfig{Store: prunerFunc(nil), TTL: -time.Second})
+	require.ErrorContains(t, err, "ttl cannot be negative")
+}
+
+func TestCleaner_RunOnce_UsesTTLCutoff(t *testing.T) {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	var gotCutoff time.Time
+	c, err := NewCleaner(CleanerConfig{
+		Store: prunerFunc(func(_ context.Context, cutoff time.Time) (int64, error) {
+			gotCutoff = cutoff
+			return 3, nil
+		}),
+		TTL:    48 * time.Hour,
+		Logger: zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	c.clock = func() time.Time { return now }
+
+	deleted, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), deleted)
+	require.Equal(t, now.Add(-48*time.Hour), gotCutoff)
+}
+
+func TestCleaner_RunOnce_Error(t *testing.T) {
+	boom := errors.New("boom")
+	c, err := NewCleaner(CleanerConfig{
+		Store: prunerFunc(func(context.Context, time.Time) (int64, error) { return 0, boom }),
+	})
+	require.NoError(t, err)
+
+	_, err = c.RunOnce(context.Background())
+	require.ErrorIs(t, err, boom)
+}
+
+func TestWithIdempotency_RequiresEventID(t *testing.T) {
+	h := WithIdempotency(&Store{}, "quota", nil)
+	require.ErrorContains(t, h(context.Background(), events.Envelope{}), "event_id is required")
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Payload схемы событий media. Поля и json-теги должны совпадать с MarshalJSON
+// соответствующих событий в models — это проверяется в тестах.
+
+type MediaCreatedV1 struct {
+	EventID    uuid.UUID        `json:"event_id"`
+	MediaID    uuid.UUID        `json:"media_id"`
+	OwnerID    uuid.UUID        `json:"owner_id,omitzero"` // добавлено без смены версии: поле опциональное
+	Type       models.MediaType `json:"type"`
+	Source     string           `json:"source"`
+	Status     models.Status    `json:"status"`
+	OccurredAt time.Time        `json:"occurred_at"`
+}
+
+type MediaStatusChangedV1 struct {
+	EventID uuid.UUID     `json:"event_id"`
+	MediaID uuid.UUID     `json:"media_id"`
+	OwnerID uuid.UUID     `json:"owner_id,omitzero"` // добавлено без смены версии: поле опциональное
+	From    models.Status `json:"from"`
+	To      models.Status `json:"to"`
+	Actor   string        `json:"actor,omitempty"`  // добавлено без смены версии: поле опциональное
+	Reason  string        `json:"reason,omitempty"` // добавлено без смены версии: поле опциональное
+	// Visibility — видимость медиа после перехода (с учётом статуса); добавлено без смены версии
+	Visibility models.Visibility `json:"visibility,omitempty"`
+	// Sequence — номер события в потоке media, как Envelope.Sequence; добавлено без смены версии
+	Sequence   int64     `json:"sequence,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type MediaDeletedV1 struct {
+	EventID    uuid.UUID           `json:"event_id"`
+	MediaID    uuid.UUID           `json:"media_id"`
+	OwnerID    uuid.UUID           `json:"owner_id,omitzero"` // добавлено без смены версии: поле опциональное
+	Type       models.MediaType    `json:"type"`
+	Size       int64               `json:"size_bytes,omitempty"` // добавлено без смены версии: поле опциональное
+	Reason     models.DeleteReason `json:"reason"`
+	OccurredAt time.Time           `json:"occurred_at"`
+}
+
+type MediaContentRecordedV1 struct {
+	EventID      uuid.UUID `json:"event_id"`
+	MediaID      uuid.UUID `json:"media_id"`
+	OwnerID      uuid.UUID `json:"owner_id,omitzero"`
+	Checksum     string    `json:"checksum_sha256"`
+	Size         int64     `json:"size_bytes"`
+	PreviousSize int64     `json:"previous_size_bytes,omitempty"` // размер прежнего исходника при повторной загрузке
+	ContentType  string    `json:"content_type"`
+	Source       string    `json:"source,omitempty"` // исходник другого медиа при дедупликации
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+type MediaArchivedV1 struct {
+	EventID    uuid.UUID        `json:"event_id"`
+	MediaID    uuid.UUID        `json:"media_id"`
+	OwnerID    uuid.UUID        `json:"owner_id,omitzero"`
+	Type       models.MediaType `json:"type"`
+	Source     string           `json:"source"`   // расположение до архивации
+	Location   string           `json:"location"` // расположение в холодном хранилище
+	OccurredAt time.Time        `json:"occurred_at"`
+}
+
+type MediaQuarantinedV1 struct {
+	EventID    uuid.UUID        `json:"event_id"`
+	MediaID    uuid.UUID        `json:"media_id"`
+	OwnerID    uuid.UUID        `json:"owner_id,omitzero"`
+	Type       models.MediaType `json:"type"`
+	Source     string           `json:"source"`
+	Checksum   string           `json:"checksum_sha256,omitempty"`
+	Threat     string           `json:"threat"` // сигнатура, которую нашёл сканер
+	Scanner    string           `json:"scanner"`
+	OccurredAt time.Time        `json:"occurred_at"`
+}
+
+type MediaScheduledV1 struct {
+	EventID    uuid.UUID  `json:"event_id"`
+	MediaID    uuid.UUID  `json:"media_id"`
+	OwnerID    uuid.UUID  `json:"owner_id,omitzero"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"` // nil — публикация без эмбарго
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil — без срока
+	Actor      string     `json:"actor,omitempty"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+type MediaVisibilityChangedV1 struct {
+	EventID    uuid.UUID         `json:"event_id"`
+	MediaID    uuid.UUID         `json:"media_id"`
+	OwnerID    uuid.UUID         `json:"owner_id,omitzero"`
+	From       models.Visibility `json:"from"`
+	To         models.Visibility `json:"to"`
+	Effective  models.Visibility `json:"effective"` // с учётом статуса: unlisted и public действуют только для ready
+	Actor      string            `json:"actor,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+type MediaAccessGrantedV1 struct {
+	EventID    uuid.UUID         `json:"event_id"`
+	MediaID    uuid.UUID         `json:"media_id"`
+	OwnerID    uuid.UUID         `json:"owner_id,omitzero"`
+	Principal  uuid.UUID         `json:"principal"`
+	Permission models.Permission `json:"permission"`
+	Previous   models.Permission `json:"previous,omitempty"` // пустое — доступа не было
+	Actor      string            `json:"actor,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+type MediaAccessRevokedV1 struct {
+	EventID    uuid.UUID         `json:"event_id"`
+	MediaID    uuid.UUID         `json:"media_id"`
+	OwnerID    uuid.UUID         `json:"owner_id,omitzero"`
+	Principal  uuid.UUID         `json:"principal"`
+	Permission models.Permission `json:"permission"`
+	Actor      string            `json:"actor,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// Default — реестр со всеми событиями media (и коллекций, см. collection.go)
+var Default = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("MediaCreated", 1, func() any { return new(MediaCreatedV1) })
+	r.Register("MediaStatusChanged", 1, func() any { return new(MediaStatusChangedV1) })
+	r.Register("MediaDeleted", 1, func() any { return new(MediaDeletedV1) })
+	r.Register("MediaContentRecorded", 1, func() any { return new(MediaContentRecordedV1) })
+	r.Register("MediaArchived", 1, func() any { return new(MediaArchivedV1) })
+	r.Register("MediaQuarantined", 1, func() any { return new(MediaQuarantinedV1) })
+	r.Register("MediaScheduled", 1, func() any { return new(MediaScheduledV1) })
+	r.Register("MediaVisibilityChanged", 1, func() any { return new(MediaVisibilityChangedV1) })
+	r.Register("MediaAccessGranted", 1, func() any { return new(MediaAccessGrantedV1) })
+	r.Register("MediaAccessRevoked", 1, func() any { return new(MediaAccessRevokedV1) })
+	r.Register("CollectionItemAdded", 1, func() any { return new(CollectionItemAddedV1) })
+	r.Register("CollectionItemRemoved", 1, func() any { return new(CollectionItemRemovedV1) })
+	r.Register("CollectionDeleted", 1, func() any { return new(CollectionDeletedV1) })
+	return r
+}
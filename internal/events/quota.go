@@ -0,0 +1,43 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payload схемы событий quota. Поля и json-теги должны совпадать с MarshalJSON
+// событий в пакете quota — это проверяется в его тестах.
+
+type QuotaUsageV1 struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+type QuotaReconciledV1 struct {
+	EventID    uuid.UUID    `json:"event_id"`
+	OwnerID    string       `json:"owner_id,omitempty"` // пустой — общий пул медиа без владельца
+	Previous   QuotaUsageV1 `json:"previous"`           // usage по событиям до исправления
+	Actual     QuotaUsageV1 `json:"actual"`             // фактический usage по таблице media
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// QuotaUsageChangedV1 — событие media учтено в usage; Delta — его корректировка
+type QuotaUsageChangedV1 struct {
+	EventID       uuid.UUID    `json:"event_id"`
+	SourceEventID string       `json:"source_event_id"` // event_id события media
+	OwnerID       string       `json:"owner_id,omitempty"`
+	Delta         QuotaUsageV1 `json:"delta"`
+	OccurredAt    time.Time    `json:"occurred_at"`
+}
+
+// Quota — реестр событий quota. Они публикуются в свой топик (events.quota), поэтому не входят
+// в Default, по которому media строит топики и подписки
+var Quota = newQuotaRegistry()
+
+func newQuotaRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("QuotaReconciled", 1, func() any { return new(QuotaReconciledV1) })
+	r.Register("QuotaUsageChanged", 1, func() any { return new(QuotaUsageChangedV1) })
+	return r
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+var (
+	ErrUnknownEventType = errors.New("unknown event type")
+	ErrUnknownSchema    = errors.New("unknown schema version")
+)
+
+// Upcaster переводит payload из версии N в N+1
+type Upcaster func(payload json.RawMessage) (json.RawMessage, error)
+
+type typeKey struct {
+	name    string
+	version int
+}
+
+// Registry хранит известные типы событий и их версии схем
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[typeKey]func() any
+	upcasters map[typeKey]Upcaster // ключ — исходная версия
+	latest    map[string]int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[typeKey]func() any),
+		upcasters: make(map[typeKey]Upcaster),
+		latest:    make(map[string]int),
+	}
+}
+
+// Register регистрирует версию схемы: newPayload возвращает указатель на структуру,
+// в которую декодируется payload этой версии. Самая старшая версия становится текущей.
+func (r *Registry) Register(eventType string, version int, newPayload func() any) {
+	if version <= 0 {
+		panic(fmt.Sprintf("events: invalid schema version %d for %s", version, eventType))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := typeKey{eventType, version}
+	if _, dup := r.factories[k]; dup {
+		panic(fmt.Sprintf("events: %s v%d registered twice", eventType, version))
+	}
+	r.factories[k] = newPayload
+	if version > r.latest[eventType] {
+		r.latest[eventType] = version
+	}
+}
+
+// RegisterUpcaster регистрирует преобразование payload из from в from+1
+func (r *Registry) RegisterUpcaster(eventType string, from int, fn Upcaster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.upcasters[typeKey{eventType, from}] = fn
+}
+
+// Latest возвращает текущую версию схемы типа
+func (r *Registry) Latest(eventType string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.latest[eventType]
+	return v, ok
+}
+
+// Types возвращает зарегистрированные типы событий в алфавитном порядке
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.latest))
+	for t := range r.latest {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Wrap заворачивает доменное событие в конверт текущей версии схемы.
+// Незарегистрированные типы отклоняются — так ad-hoc события не уходят в outbox.
+func (r *Registry) Wrap(ev models.DomainEvent) (Envelope, error) {
+	version, ok := r.Latest(ev.EventType())
+	if !ok {
+		return Envelope{}, fmt.Errorf("%w: %s", ErrUnknownEventType, ev.EventType())
+	}
+
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("marshal %s payload: %w", ev.EventType(), err)
+	}
+
+	return Envelope{
+		EventID:       ev.EventID().String(),
+		EventType:     ev.EventType(),
+		SchemaVersion: version,
+		AggregateID:   ev.AggregateID().String(),
+		OccurredAt:    ev.OccurredAt(),
+		Payload:       payload,
+	}, nil
+}
+
+// Decode декодирует payload в структуру, зарегистрированную для версии конверта
+func (r *Registry) Decode(env Envelope) (any, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[typeKey{env.EventType, env.SchemaVersion}]
+	_, known := r.latest[env.EventType]
+	r.mu.RUnlock()
+
+	if !known {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, env.EventType)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s v%d", ErrUnknownSchema, env.EventType, env.SchemaVersion)
+	}
+
+	v := factory()
+	if err := json.Unmarshal(env.Payload, v); err != nil {
+		return nil, fmt.Errorf("decode %s v%d payload: %w", env.EventType, env.SchemaVersion, err)
+	}
+	return v, nil
+}
+
+// DecodeLatest поднимает payload до текущей версии через upcaster'ы и декодирует его.
+// Consumer, написанный под текущую схему, продолжает читать старые события.
+func (r *Registry) DecodeLatest(env Envelope) (any, error) {
+	latest, ok := r.Latest(env.EventType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, env.EventType)
+	}
+	if env.SchemaVersion > latest {
+		return nil, fmt.Errorf("%w: %s v%d is newer than v%d", ErrUnknownSchema, env.EventType, env.SchemaVersion, latest)
+	}
+
+	for env.SchemaVersion < latest {
+		r.mu.RLock()
+		up, ok := r.upcasters[typeKey{env.EventType, env.SchemaVersion}]
+		r.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: no upcaster for %s v%d", ErrUnknownSchema, env.EventType, env.SchemaVersion)
+		}
+
+		payload, err := up(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("upcast %s v%d: %w", env.EventType, env.SchemaVersion, err)
+		}
+		env.Payload = payload
+		env.SchemaVersion++
+	}
+
+	return r.Decode(env)
+}
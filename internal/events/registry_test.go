@@ -0,0 +1,166 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func testMedia() *models.Media {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	return &models.Media{
+		ID:        uuid.New(),
+		Status:    models.UploadedStatus,
+		Type:      models.Video,
+		Source:    "s3://bucket/file.mp4",
+		CreatedAt: now,
+		UpdatedAt: now,
+		OwnerID:   uuid.New(),
+	}
+}
+
+// Каждое доменное событие должно быть зарегистрировано, а его JSON — без лишних
+// и недостающих полей ложиться в payload-структуру текущей версии.
+func TestDefault_PayloadsMatchDomainEvents(t *testing.T) {
+	m := testMedia()
+	changed := models.NewMediaStatusChanged(m.ID, m.OwnerID, models.ProcessingStatus, models.FailedStatus, "transcoder", "codec not supported").
+		WithVisibility(models.PrivateVisibility)
+	changed.SetSequence(2)
+	scheduled := *m
+	publishAt, expiresAt := m.CreatedAt.Add(time.Hour), m.CreatedAt.Add(48*time.Hour)
+	scheduled.PublishAt, scheduled.ExpiresAt = &publishAt, &expiresAt
+	grant := models.Grant{MediaID: m.ID, Principal: uuid.New(), Permission: models.DownloadPermission, UpdatedAt: m.CreatedAt}
+	domainEvents := []models.DomainEvent{
+		models.NewMediaCreated(m),
+		changed,
+		models.NewMediaDeleted(m, models.DeleteReasonExpired, m.CreatedAt),
+		models.NewMediaContentRecorded(m, models.Content{Checksum: strings.Repeat("a", 64), Size: 42, ContentType: "video/mp4"}, m.CreatedAt),
+		models.NewMediaArchived(m, "s3://cold/file.mp4", m.CreatedAt),
+		models.NewMediaQuarantined(m, "Eicar-Test-Signature", "clamav", m.CreatedAt),
+		models.NewMediaScheduled(&scheduled, "editor", m.CreatedAt),
+		models.NewMediaVisibilityChanged(m, models.DraftVisibility, "editor", m.CreatedAt),
+		models.NewMediaAccessGranted(m, grant, models.ReadPermission, "editor"),
+		models.NewMediaAccessRevoked(m, grant, "editor", m.CreatedAt),
+	}
+
+	for _, ev := range domainEvents {
+		t.Run(ev.EventType(), func(t *testing.T) {
+			env, err := Default.Wrap(ev)
+			require.NoError(t, err)
+			require.Equal(t, ev.EventID().String(), env.EventID)
+			require.Equal(t, m.ID.String(), env.AggregateID)
+			require.Equal(t, 1, env.SchemaVersion)
+
+			decoded, err := Default.Decode(env)
+			require.NoError(t, err)
+
+			dec := json.NewDecoder(bytes.NewReader(env.Payload))
+			dec.DisallowUnknownFields()
+			require.NoError(t, dec.Decode(decoded), "payload schema drifted from %T", ev)
+		})
+	}
+}
+
+// События коллекций — в потоке коллекции, а не медиа
+func TestDefault_PayloadsMatchCollectionEvents(t *testing.T) {
+	m := testMedia()
+	c := &models.Collection{ID: uuid.New(), OwnerID: m.OwnerID, Title: "Trip"}
+	item := models.CollectionItem{CollectionID: c.ID, MediaID: m.ID, Position: 2, AddedAt: m.CreatedAt}
+	domainEvents := []models.DomainEvent{
+		models.NewCollectionItemAdded(c, item, "editor"),
+		models.NewCollectionItemRemoved(c, item, "editor", m.CreatedAt),
+		models.NewCollectionDeleted(c, []uuid.UUID{m.ID}, "editor", m.CreatedAt),
+		models.NewCollectionDeleted(c, nil, "", m.CreatedAt),
+	}
+
+	for _, ev := range domainEvents {
+		t.Run(ev.EventType(), func(t *testing.T) {
+			env, err := Default.Wrap(ev)
+			require.NoError(t, err)
+			require.Equal(t, c.ID.String(), env.AggregateID)
+
+			decoded, err := Default.Decode(env)
+			require.NoError(t, err)
+
+			dec := json.NewDecoder(bytes.NewReader(env.Payload))
+			dec.DisallowUnknownFields()
+			require.NoError(t, dec.Decode(decoded), "payload schema drifted from %T", ev)
+		})
+	}
+}
+
+func TestRegistry_WrapRejectsUnknownType(t *testing.T) {
+	r := NewRegistry()
+	_, err := r.Wrap(models.NewMediaCreated(testMedia()))
+	require.ErrorIs(t, err, ErrUnknownEventType)
+}
+
+func TestRegistry_DecodeUnknownVersion(t *testing.T) {
+	_, err := Default.Decode(Envelope{EventType: "MediaCreated", SchemaVersion: 99, Payload: []byte(`{}`)})
+	require.ErrorIs(t, err, ErrUnknownSchema)
+
+	_, err = Default.Decode(Envelope{EventType: "Nope", SchemaVersion: 1, Payload: []byte(`{}`)})
+	require.ErrorIs(t, err, ErrUnknownEventType)
+}
+
+func TestRegistry_DecodeLatestUpcasts(t *testing.T) {
+	type v1 struct {
+		Name string `json:"name"`
+	}
+	type v2 struct {
+		FullName string `json:"full_name"`
+	}
+
+	r := NewRegistry()
+	r.Register("Renamed", 1, func() any { return new(v1) })
+	r.Register("Renamed", 2, func() any { return new(v2) })
+	r.RegisterUpcaster("Renamed", 1, func(p json.RawMessage) (json.RawMessage, error) {
+		var old v1
+		if err := json.Unmarshal(p, &old); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v2{FullName: old.Name})
+	})
+
+	latest, ok := r.Latest("Renamed")
+	require.True(t, ok)
+	require.Equal(t, 2, latest)
+
+	got, err := r.DecodeLatest(Envelope{EventType: "Renamed", SchemaVersion: 1, Payload: []byte(`{"name":"x"}`)})
+	require.NoError(t, err)
+	require.Equal(t, &v2{FullName: "x"}, got)
+
+	_, err = r.DecodeLatest(Envelope{EventType: "Renamed", SchemaVersion: 3, Payload: []byte(`{}`)})
+	require.ErrorIs(t, err, ErrUnknownSchema)
+}
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	env, err := Default.Wrap(models.NewMediaCreated(testMedia()))
+	require.NoError(t, err)
+
+	data, err := env.Marshal()
+	require.NoError(t, err)
+
+	got, err := UnmarshalEnvelope(data)
+	require.NoError(t, err)
+	require.Equal(t, env.EventID, got.EventID)
+	require.JSONEq(t, string(env.Payload), string(got.Payload))
+
+	_, err = UnmarshalEnvelope([]byte(`{"event_id":"x","event_type":"MediaCreated","schema_version":0}`))
+	require.Error(t, err)
+}
+
+func TestEnvelope_NewerThan(t *testing.T) {
+	env := Envelope{Sequence: 5}
+	require.True(t, env.NewerThan(4))
+	require.False(t, env.NewerThan(5), "duplicate")
+	require.False(t, env.NewerThan(7), "stale")
+	require.True(t, Envelope{}.NewerThan(7), "events without sequence are always applied")
+}
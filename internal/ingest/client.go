@@ -0,0 +1,190 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// forwardedHeaders — заголовки входящего запроса, которые ingest передаёт в media:
+// media проверяет владельца медиа так же, как при прямом обращении клиента
+var forwardedHeaders = []string{"X-Request-ID", "X-Owner-ID", "X-Scopes", "X-Actor"}
+
+type forwardKey struct{}
+
+// WithForwardedHeaders сохраняет в ctx заголовки запроса, которые MediaClient передаст в media
+func WithForwardedHeaders(ctx context.Context, h http.Header) context.Context {
+	fwd := make(http.Header)
+	for _, name := range forwardedHeaders {
+		if v := h.Get(name); v != "" {
+			fwd.Set(name, v)
+		}
+	}
+	return context.WithValue(ctx, forwardKey{}, fwd)
+}
+
+// MediaClient — HTTP клиент media API для ingest
+type MediaClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewMediaClient создаёт клиент media API по адресу baseURL (http://media:8081);
+// client может быть nil — тогда используется клиент с timeout 10s
+func NewMediaClient(baseURL string, client *http.Client) (*MediaClient, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid media url %q", baseURL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &MediaClient{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}, nil
+}
+
+// mediaResponse — поля MediaResponse, нужные ingest
+type mediaResponse struct {
+	ID          uuid.UUID        `json:"id"`
+	OwnerID     uuid.UUID        `json:"owner_id"`
+	Status      models.Status    `json:"status"`
+	Type        models.MediaType `json:"type"`
+	Source      string           `json:"source"`
+	Checksum    string           `json:"checksum_sha256"`
+	Size        int64            `json:"size_bytes"`
+	ContentType string           `json:"content_type"`
+}
+
+func (r mediaResponse) media() *models.Media {
+	return &models.Media{
+		ID:          r.ID,
+		OwnerID:     r.OwnerID,
+		Status:      r.Status,
+		Type:        r.Type,
+		Source:      r.Source,
+		Checksum:    r.Checksum,
+		Size:        r.Size,
+		ContentType: r.ContentType,
+	}
+}
+
+// GetMedia — GET /media/{id}
+func (c *MediaClient) GetMedia(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	var resp mediaResponse
+	if err := c.do(ctx, http.MethodGet, "/media/"+id.String(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.media(), nil
+}
+
+// RecordContent — PUT /media/{id}/content
+func (c *MediaClient) RecordContent(ctx context.Context, id uuid.UUID, content models.Content) (*models.Media, error) {
+	req := map[string]any{
+		"checksum_sha256": content.Checksum,
+		"size_bytes":      content.Size,
+		"content_type":    content.ContentType,
+	}
+	if content.Source != "" {
+		req["source"] = content.Source
+	}
+	var resp mediaResponse
+	if err := c.do(ctx, http.MethodPut, "/media/"+id.String()+"/content", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.media(), nil
+}
+
+// Duplicates — GET /media/{id}/duplicates
+func (c *MediaClient) Duplicates(ctx context.Context, id uuid.UUID, checksum string) ([]*models.Media, error) {
+	var resp struct {
+		Items []mediaResponse `json:"items"`
+	}
+	path := "/media/" + id.String() + "/duplicates?checksum=" + url.QueryEscape(checksum)
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]*models.Media, len(resp.Items))
+	for i, r := range resp.Items {
+		out[i] = r.media()
+	}
+	return out, nil
+}
+
+// QuarantineMedia — POST /media/{id}/quarantine
+func (c *MediaClient) QuarantineMedia(ctx context.Context, id uuid.UUID, threat, scanner string) error {
+	req := map[string]string{"threat": threat, "scanner": scanner}
+	var resp mediaResponse
+	return c.do(ctx, http.MethodPost, "/media/"+id.String()+"/quarantine", req, &resp)
+}
+
+func (c *MediaClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if fwd, ok := ctx.Value(forwardKey{}).(http.Header); ok {
+		for name := range fwd {
+			req.Header.Set(name, fwd.Get(name))
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("media %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return responseError(method, path, resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("media %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// responseError переводит ошибку media API обратно в доменную по коду ответа,
+// чтобы ingest отдал клиенту тот же статус
+func responseError(method, path string, resp *http.Response) error {
+	var e struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+
+	var kind error
+	switch e.Code {
+	case "not_found":
+		kind = models.ErrNotFound
+	case "conflict", "invalid_transition":
+		kind = models.ErrConflict
+	case "invalid_argument", "validation_failed", "invalid_json":
+		kind = models.ErrInvalidArgument
+	}
+	if kind != nil {
+		return fmt.Errorf("media %s %s: %w: %s", method, path, kind, e.Message)
+	}
+	return errors.New("media " + method + " " + path + ": " + resp.Status)
+}
@@ -0,0 +1,125 @@
+package ingest
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DedupHeader — режим дедупликации одной загрузки; переопределяет HandlerConfig.Dedup
+const DedupHeader = "X-Dedup"
+
+// DedupMode — что делать с загрузкой, содержимое которой уже сохранено у другого медиа владельца.
+// Дубликат ищется по X-Checksum-SHA256 до чтения тела: без заявленного checksum загрузка идёт как обычно.
+type DedupMode string
+
+const (
+	DedupOff DedupMode = "off"
+	// DedupReject — 409 conflict, в details.existing_media_id — медиа с тем же содержимым
+	DedupReject DedupMode = "reject"
+	// DedupReference — тело не сохраняется: медиа ссылается на исходник медиа с тем же
+	// содержимым (копия при записи — повторная загрузка в такое медиа отклоняется)
+	DedupReference DedupMode = "reference"
+)
+
+// ParseDedupMode разбирает значение флага или DedupHeader
+func ParseDedupMode(s string) (DedupMode, error) {
+	switch m := DedupMode(s); m {
+	case DedupOff, DedupReject, DedupReference:
+		return m, nil
+	}
+	return "", fmt.Errorf("dedup mode must be %s, %s or %s, got: %q", DedupOff, DedupReject, DedupReference, s)
+}
+
+// dedupMode — режим загрузки: из DedupHeader, иначе из конфигурации
+func (h *Handler) dedupMode(header http.Header) (DedupMode, error) {
+	v := header.Get(DedupHeader)
+	if v == "" {
+		return h.dedup, nil
+	}
+	return ParseDedupMode(v)
+}
+
+// reusable — исходник медиа в этих статусах сохранён и проверен; на него можно сослаться
+func reusable(s models.Status) bool {
+	return s == models.UploadedStatus || s == models.ProcessingStatus || s == models.ReadyStatus
+}
+
+// findDuplicate ищет медиа владельца с исходником, совпадающим с заявленным клиентом:
+// тот же sha256, размер и тип медиа. nil — дубликата нет или дедупликация выключена.
+func (h *Handler) findDuplicate(ctx context.Context, m *models.Media, expected Expected, size int64, mode DedupMode) (*models.Media, error) {
+	if mode == DedupOff || expected.SHA256 == nil {
+		return nil, nil
+	}
+	list, err := h.media.Duplicates(ctx, m.ID, hex.EncodeToString(expected.SHA256))
+	if err != nil {
+		return nil, fmt.Errorf("find duplicates: %w", err)
+	}
+	for _, d := range list {
+		if d.Type == m.Type && d.Size == size && reusable(d.Status) {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkNotShared не даёт перезаписать исходник, на который ссылается другое медиа
+// (DedupReference): при повторной загрузке содержимое изменилось бы у обоих
+func (h *Handler) checkNotShared(ctx context.Context, m *models.Media) error {
+	if m.Checksum == "" {
+		return nil
+	}
+	list, err := h.media.Duplicates(ctx, m.ID, m.Checksum)
+	if err != nil {
+		return fmt.Errorf("find duplicates: %w", err)
+	}
+	for _, d := range list {
+		if d.Source == m.Source {
+			return fmt.Errorf("%w: source is shared with media %s, create a new media to upload other content", models.ErrConflict, d.ID)
+		}
+	}
+	return nil
+}
+
+// reference записывает в медиа исходник дубликата вместо загрузки тела
+func (h *Handler) reference(ctx context.Context, w http.ResponseWriter, r *http.Request, m, dup *models.Media) {
+	if err := h.consumeUpload(ctx, w, m, dup.Size); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	content := models.Content{Checksum: dup.Checksum, Size: dup.Size, ContentType: dup.ContentType, Source: dup.Source}
+	if _, err := h.media.RecordContent(ctx, m.ID, content); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("media_id", m.ID.String()).
+		Str("duplicate_of", dup.ID.String()).
+		Str("checksum", content.Checksum).
+		Int64("size", content.Size).
+		Msg("media source deduplicated")
+	// Исходник уже проверен антивирусом при загрузке оригинала
+	writeJSON(w, http.StatusOK, UploadResponse{
+		MediaID:     m.ID,
+		Source:      dup.Source,
+		Checksum:    content.Checksum,
+		Size:        content.Size,
+		ContentType: content.ContentType,
+		DuplicateOf: dup.ID,
+	})
+}
+
+// writeDuplicate — ответ DedupReject
+func writeDuplicate(w http.ResponseWriter, r *http.Request, dup *models.Media) {
+	writeJSON(w, http.StatusConflict, ErrorResponse{
+		Code:      apierr.CodeConflict,
+		Message:   "content is already uploaded as media " + dup.ID.String(),
+		RequestID: r.Header.Get("X-Request-ID"),
+		Details:   map[string]any{"existing_media_id": dup.ID},
+	})
+}
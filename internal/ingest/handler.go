@@ -0,0 +1,489 @@
+package ingest
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/quota"
+)
+
+const (
+	// ChecksumSHA256Header — sha256 содержимого в hex, заявленный клиентом
+	ChecksumSHA256Header = "X-Checksum-SHA256"
+	// ContentMD5Header — md5 содержимого в base64 (RFC 1864)
+	ContentMD5Header = "Content-MD5"
+
+	// DefaultMaxUploadBytes — предел одного PUT в S3
+	DefaultMaxUploadBytes = 5 << 30
+
+	// CodeScanUnavailable — антивирус недоступен, загрузку нужно повторить
+	CodeScanUnavailable = "scan_unavailable"
+
+	// ScanClean и ScanPending — значения UploadResponse.Scan
+	ScanClean   = "clean"
+	ScanPending = "pending"
+)
+
+// Media — операции media сервиса, нужные ingest; реализуется *MediaClient
+type Media interface {
+	GetMedia(ctx context.Context, id uuid.UUID) (*models.Media, error)
+	RecordContent(ctx context.Context, id uuid.UUID, c models.Content) (*models.Media, error)
+	// Duplicates — другие медиа владельца медиа id с исходником checksum
+	Duplicates(ctx context.Context, id uuid.UUID, checksum string) ([]*models.Media, error)
+	QuarantineMedia(ctx context.Context, id uuid.UUID, threat, scanner string) error
+}
+
+// UploadLimiter — лимиты загрузок и хранения владельца; реализуется *quota.Client
+type UploadLimiter interface {
+	CheckAndConsume(ctx context.Context, owner string, n int, bytes int64) (quota.Decision, error)
+}
+
+// Sink — хранилище исходников; реализуется *blob.S3Store и *blob.LocalStore
+type Sink interface {
+	// Put пишет body длиной size в source; ошибка чтения body не должна оставлять объект
+	Put(ctx context.Context, source string, body io.Reader, size int64, contentType string) error
+	// Open читает сохранённый исходник — для антивирусной проверки
+	Open(ctx context.Context, source string) (io.ReadCloser, error)
+}
+
+// HandlerConfig содержит конфигурацию Handler
+type HandlerConfig struct {
+	Media          Media
+	Sink           Sink
+	MaxUploadBytes int64 // default: DefaultMaxUploadBytes
+	Logger         zerolog.Logger
+
+	// Scanner проверяет исходник после загрузки; nil — проверка выключена
+	Scanner Scanner
+	// AsyncScanBytes — исходники больше этого размера проверяются в фоне, клиент получает 202;
+	// 0 — всегда синхронно
+	AsyncScanBytes     int64
+	ScanTimeout        time.Duration // на одну проверку (default: 10m)
+	MaxConcurrentScans int           // default: 4
+
+	// Limiter ограничивает число загрузок владельца; nil — без лимита
+	Limiter UploadLimiter
+
+	// Dedup — режим дедупликации по умолчанию (default: DedupOff); клиент выбирает свой в DedupHeader
+	Dedup DedupMode
+}
+
+// Handler — HTTP API ingest: PUT /uploads/{media_id} загружает исходник медиа
+type Handler struct {
+	media   Media
+	sink    Sink
+	maxSize int64
+	logger  zerolog.Logger
+
+	scanner     Scanner
+	asyncBytes  int64
+	scanTimeout time.Duration
+	scans       chan struct{} // семафор одновременных проверок
+	background  sync.WaitGroup
+
+	limiter UploadLimiter
+	dedup   DedupMode
+}
+
+func NewHandler(cfg HandlerConfig) (*Handler, error) {
+	if cfg.Media == nil {
+		return nil, fmt.Errorf("media client is required")
+	}
+	if cfg.Sink == nil {
+		return nil, fmt.Errorf("sink is required")
+	}
+	if cfg.MaxUploadBytes < 0 {
+		return nil, fmt.Errorf("max upload bytes cannot be negative, got: %d", cfg.MaxUploadBytes)
+	}
+	if cfg.AsyncScanBytes < 0 {
+		return nil, fmt.Errorf("async scan bytes cannot be negative, got: %d", cfg.AsyncScanBytes)
+	}
+	if cfg.ScanTimeout < 0 {
+		return nil, fmt.Errorf("scan timeout cannot be negative, got: %v", cfg.ScanTimeout)
+	}
+	if cfg.MaxConcurrentScans < 0 {
+		return nil, fmt.Errorf("max concurrent scans cannot be negative, got: %d", cfg.MaxConcurrentScans)
+	}
+	if cfg.Dedup != "" {
+		if _, err := ParseDedupMode(string(cfg.Dedup)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MaxUploadBytes == 0 {
+		cfg.MaxUploadBytes = DefaultMaxUploadBytes
+	}
+	if cfg.Dedup == "" {
+		cfg.Dedup = DedupOff
+	}
+	if cfg.ScanTimeout == 0 {
+		cfg.ScanTimeout = 10 * time.Minute
+	}
+	if cfg.MaxConcurrentScans == 0 {
+		cfg.MaxConcurrentScans = 4
+	}
+	return &Handler{
+		media:       cfg.Media,
+		sink:        cfg.Sink,
+		maxSize:     cfg.MaxUploadBytes,
+		logger:      cfg.Logger.With().Str("component", "ingest_http").Logger(),
+		scanner:     cfg.Scanner,
+		asyncBytes:  cfg.AsyncScanBytes,
+		scanTimeout: cfg.ScanTimeout,
+		scans:       make(chan struct{}, cfg.MaxConcurrentScans),
+		limiter:     cfg.Limiter,
+		dedup:       cfg.Dedup,
+	}, nil
+}
+
+// Wait ждёт завершения фоновых проверок — при остановке сервиса, после http.Server.Shutdown
+func (h *Handler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background scans: %w", ctx.Err())
+	}
+}
+
+// UploadResponse — результат загрузки: характеристики, записанные в media
+type UploadResponse struct {
+	MediaID     uuid.UUID `json:"media_id"`
+	Source      string    `json:"source"`
+	Checksum    string    `json:"checksum_sha256"`
+	Size        int64     `json:"size_bytes"`
+	ContentType string    `json:"content_type"`
+	Scan        string    `json:"scan,omitempty"` // clean, pending; пусто — проверка выключена
+	// DuplicateOf — медиа, на исходник которого сослалась загрузка (DedupReference); тело не сохранялось
+	DuplicateOf uuid.UUID `json:"duplicate_of,omitzero"`
+}
+
+// ErrorResponse — формат ошибки, общий с media API
+type ErrorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	RequestID string         `json:"request_id,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/health":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case strings.HasPrefix(r.URL.Path, "/uploads/"):
+		if r.Method != http.MethodPut {
+			writeError(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		h.Upload(w, r)
+	default:
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "not found")
+	}
+}
+
+// Upload — PUT /uploads/{media_id}. Тело — содержимое исходника; Content-Length обязателен.
+// Заявленный checksum передаётся в X-Checksum-SHA256 (hex) и/или Content-MD5 (base64).
+// Несовпадение checksum или MIME типа с типом медиа — 422, исходник не сохраняется.
+// Если настроен Scanner, сохранённый исходник проверяется антивирусом: при угрозе медиа
+// уходит в карантин и ответ — 422 malware_detected. Большие исходники (AsyncScanBytes)
+// проверяются в фоне, ответ — 202 со scan=pending.
+// Если содержимое с заявленным X-Checksum-SHA256 уже есть у другого медиа владельца, загрузка
+// дедуплицируется по DedupMode: 409 с id этого медиа или ссылка на его исходник без чтения тела.
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/uploads/"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid media id")
+		return
+	}
+	if r.ContentLength < 0 {
+		writeError(w, r, http.StatusLengthRequired, apierr.CodeInvalidArgument, "Content-Length is required")
+		return
+	}
+	if r.ContentLength > h.maxSize {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apierr.CodeInvalidArgument,
+			fmt.Sprintf("upload exceeds %d bytes", h.maxSize))
+		return
+	}
+	expected, err := expectedChecksums(r.Header)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, err.Error())
+		return
+	}
+	mode, err := h.dedupMode(r.Header)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, err.Error())
+		return
+	}
+
+	ctx := WithForwardedHeaders(r.Context(), r.Header)
+	m, err := h.media.GetMedia(ctx, id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	// media тоже это проверит, но после того, как исходник уже перезаписан
+	if m.Status != models.UploadedStatus && m.Status != models.FailedStatus {
+		h.writeServiceError(w, r, fmt.Errorf("%w: content of %s media cannot change", models.ErrConflict, m.Status))
+		return
+	}
+	if err := h.checkNotShared(ctx, m); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	dup, err := h.findDuplicate(ctx, m, expected, r.ContentLength, mode)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	switch {
+	case dup != nil && mode == DedupReject:
+		writeDuplicate(w, r, dup)
+		return
+	case dup != nil:
+		h.reference(ctx, w, r, m, dup)
+		return
+	}
+
+	if err := h.consumeUpload(ctx, w, m, r.ContentLength); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	expected.Type = m.Type
+	v, err := NewVerifier(r.Body, expected)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	if err := h.sink.Put(ctx, m.Source, v, r.ContentLength, v.ContentType()); err != nil {
+		switch {
+		case v.err != nil:
+			// Поток оборвала проверка: клиенту важна она, а не ошибка хранилища
+			err = v.err
+		case errors.Is(err, blob.ErrUnsupportedSource):
+			err = fmt.Errorf("%w: %w", models.ErrInvalidArgument, err)
+		case errors.Is(err, blob.ErrStorageFull):
+			// Место освободит retention или оператор: клиент может повторить позже
+			err = fmt.Errorf("%w: %w", models.ErrUnavailable, err)
+		}
+		h.writeServiceError(w, r, err)
+		return
+	}
+	content, err := v.Content()
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	if _, err := h.media.RecordContent(ctx, id, content); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("media_id", id.String()).
+		Str("checksum", content.Checksum).
+		Int64("size", content.Size).
+		Str("content_type", content.ContentType).
+		Msg("media source uploaded")
+	resp := UploadResponse{
+		MediaID:     id,
+		Source:      m.Source,
+		Checksum:    content.Checksum,
+		Size:        content.Size,
+		ContentType: content.ContentType,
+	}
+	if h.scanner == nil {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	if h.asyncBytes > 0 && content.Size > h.asyncBytes {
+		// Проверка переживает запрос, но заголовки владельца нужны для карантина
+		h.background.Add(1)
+		go func() {
+			defer h.background.Done()
+			if err := h.scan(context.WithoutCancel(ctx), id, m.Source); err != nil {
+				h.logger.Error().Err(err).Str("media_id", id.String()).Msg("background scan failed")
+			}
+		}()
+		resp.Scan = ScanPending
+		writeJSON(w, http.StatusAccepted, resp)
+		return
+	}
+
+	if err := h.scan(ctx, id, m.Source); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	resp.Scan = ScanClean
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// consumeUpload засчитывает загрузку size байт владельцу медиа. Лимит исчерпан — *quota.LimitError,
+// заголовки X-RateLimit-* (и Retry-After для лимита загрузок) уже в ответе.
+// Недоступность quota не блокирует загрузки.
+func (h *Handler) consumeUpload(ctx context.Context, w http.ResponseWriter, m *models.Media, size int64) error {
+	if h.limiter == nil {
+		return nil
+	}
+	owner := ""
+	if m.OwnerID != uuid.Nil {
+		owner = m.OwnerID.String()
+	}
+	// Повторная загрузка заменяет прежний исходник: для лимита хранения важен только прирост
+	d, err := h.limiter.CheckAndConsume(ctx, owner, 1, max(0, size-m.Size))
+	if err != nil {
+		h.logger.Warn().Err(err).Str("media_id", m.ID.String()).Msg("upload rate limit unavailable, upload allowed")
+		return nil
+	}
+	quota.SetRateLimitHeaders(w.Header(), d, time.Now())
+	return d.Err()
+}
+
+// limitDetails — details ответа на отказ quota: по ним клиент показывает, какой лимит
+// исчерпан, и предлагает тариф выше, если он есть
+func limitDetails(d quota.Decision) map[string]any {
+	details := map[string]any{"exceeded": d.Exceeded}
+	if d.Exceeded == quota.ExceededUploads {
+		details["reset_at"] = d.ResetAt.UTC()
+	}
+	if d.Plan != nil {
+		details["plan"] = d.Plan.Name
+		details["upgrade_available"] = d.Plan.Upgradable
+	}
+	return details
+}
+
+// scanError — проверка не состоялась (антивирус или хранилище недоступны)
+type scanError struct{ err error }
+
+func (e *scanError) Error() string { return "scan: " + e.err.Error() }
+func (e *scanError) Unwrap() error { return e.err }
+
+// scan проверяет сохранённый исходник; при угрозе переводит медиа в карантин и возвращает
+// domain.ErrMalwareDetected
+func (h *Handler) scan(ctx context.Context, id uuid.UUID, source string) error {
+	select {
+	case h.scans <- struct{}{}:
+		defer func() { <-h.scans }()
+	case <-ctx.Done():
+		return &scanError{ctx.Err()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.scanTimeout)
+	defer cancel()
+
+	body, err := h.sink.Open(ctx, source)
+	if err != nil {
+		return &scanError{err}
+	}
+	defer body.Close()
+
+	result, err := h.scanner.Scan(ctx, body)
+	if err != nil {
+		return &scanError{err}
+	}
+	if !result.Infected() {
+		return nil
+	}
+
+	h.logger.Warn().
+		Str("media_id", id.String()).
+		Str("threat", result.Threat).
+		Str("scanner", h.scanner.Name()).
+		Msg("malware detected")
+	if err := h.media.QuarantineMedia(ctx, id, result.Threat, h.scanner.Name()); err != nil {
+		return fmt.Errorf("quarantine media %s: %w", id, err)
+	}
+	return fmt.Errorf("%w: %s", domain.ErrMalwareDetected, result.Threat)
+}
+
+// expectedChecksums разбирает заявленные клиентом checksum из заголовков
+func expectedChecksums(h http.Header) (Expected, error) {
+	var e Expected
+	if v := h.Get(ChecksumSHA256Header); v != "" {
+		sum, err := hex.DecodeString(v)
+		if err != nil || len(sum) != sha256.Size {
+			return Expected{}, fmt.Errorf("%s must be a hex sha256", ChecksumSHA256Header)
+		}
+		e.SHA256 = sum
+	}
+	if v := h.Get(ContentMD5Header); v != "" {
+		sum, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(sum) != md5.Size {
+			return Expected{}, fmt.Errorf("%s must be a base64 md5", ContentMD5Header)
+		}
+		e.MD5 = sum
+	}
+	return e, nil
+}
+
+// writeServiceError маппит ошибку через apierr, как media API
+func (h *Handler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var se *scanError
+	if errors.As(err, &se) {
+		h.logger.Error().Err(err).Str("path", r.URL.Path).Msg("upload scan failed")
+		writeError(w, r, http.StatusServiceUnavailable, CodeScanUnavailable, "malware scan is unavailable, retry later")
+		return
+	}
+
+	var le *quota.LimitError
+	if errors.As(err, &le) {
+		m := apierr.Lookup(err)
+		writeJSON(w, m.HTTPStatus, ErrorResponse{
+			Code:      m.Code,
+			Message:   err.Error(),
+			RequestID: r.Header.Get("X-Request-ID"),
+			Details:   limitDetails(le.Decision),
+		})
+		return
+	}
+
+	m := apierr.Lookup(err)
+	message := m.Message
+	switch {
+	case m.HTTPStatus >= http.StatusInternalServerError:
+		h.logger.Error().Err(err).Str("path", r.URL.Path).Msg("upload failed")
+	case errors.Is(err, domain.ErrChecksumMismatch), errors.Is(err, domain.ErrContentTypeMismatch),
+		errors.Is(err, domain.ErrMalwareDetected):
+		// Клиенту нужна причина отказа: какой checksum или тип не совпал, какая угроза найдена
+		message = err.Error()
+	}
+	writeError(w, r, m.HTTPStatus, m.Code, message)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Code: code, Message: message, RequestID: r.Header.Get("X-Request-ID")})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
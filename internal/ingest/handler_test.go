@@ -0,0 +1,368 @@
+package ingest
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/httpapi"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/quota"
+)
+
+// memorySink — хранилище, которое, как S3, не сохраняет объект при ошибке чтения тела
+type memorySink struct {
+	objects map[string]string
+}
+
+func (s *memorySink) Put(_ context.Context, source string, body io.Reader, size int64, _ string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return io.ErrUnexpectedEOF
+	}
+	s.objects[source] = string(data)
+	return nil
+}
+
+func (s *memorySink) Open(_ context.Context, source string) (io.ReadCloser, error) {
+	data, ok := s.objects[source]
+	if !ok {
+		return nil, errors.New("no such object")
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+// fakeScanner находит EICAR в содержимом; err — антивирус недоступен
+type fakeScanner struct {
+	err error
+}
+
+func (s *fakeScanner) Name() string { return "fake" }
+
+func (s *fakeScanner) Scan(_ context.Context, r io.Reader) (ScanResult, error) {
+	if s.err != nil {
+		return ScanResult{}, s.err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	if strings.Contains(string(data), "EICAR") {
+		return ScanResult{Threat: "Eicar-Test-Signature"}, nil
+	}
+	return ScanResult{}, nil
+}
+
+// newIngest поднимает media API на memory репозитории и ingest поверх него через MediaClient;
+// configure дополняет конфигурацию Handler
+func newIngest(t *testing.T, configure ...func(*HandlerConfig)) (*service.Service, *memorySink, *Handler) {
+	t.Helper()
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	media := httptest.NewServer(httpapi.NewRouter(httpapi.New(svc)))
+	t.Cleanup(media.Close)
+
+	client, err := NewMediaClient(media.URL, nil)
+	require.NoError(t, err)
+	sink := &memorySink{objects: map[string]string{}}
+	cfg := HandlerConfig{Media: client, Sink: sink, MaxUploadBytes: 1 << 20, Logger: zerolog.Nop()}
+	for _, f := range configure {
+		f(&cfg)
+	}
+	h, err := NewHandler(cfg)
+	require.NoError(t, err)
+	return svc, sink, h
+}
+
+func upload(h http.Handler, id uuid.UUID, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/uploads/"+id.String(), strings.NewReader(body))
+	// Без владельца загружает внутренний клиент: медиа тестов создаются без principal
+	req.Header.Set("X-Scopes", "internal")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if req.Header.Get("X-Owner-ID") != "" {
+		req.Header.Del("X-Scopes")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpload_StoresSourceAndRecordsContent(t *testing.T) {
+	svc, sink, h := newIngest(t)
+	ctx := context.Background()
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://media/a.mp4")
+	require.NoError(t, err)
+
+	sha := sha256.Sum256([]byte(mp4))
+	sum := md5.Sum([]byte(mp4))
+	rec := upload(h, m.ID, mp4, map[string]string{
+		ChecksumSHA256Header: hex.EncodeToString(sha[:]),
+		ContentMD5Header:     base64.StdEncoding.EncodeToString(sum[:]),
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp UploadResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, hex.EncodeToString(sha[:]), resp.Checksum)
+	require.Equal(t, "video/mp4", resp.ContentType)
+	require.Equal(t, mp4, sink.objects["s3://media/a.mp4"])
+
+	stored, err := svc.GetMedia(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(sha[:]), stored.Checksum)
+	require.Equal(t, int64(len(mp4)), stored.Size)
+	require.Equal(t, "video/mp4", stored.ContentType)
+}
+
+func TestUpload_MismatchIs422AndNothingIsStored(t *testing.T) {
+	svc, sink, h := newIngest(t)
+	ctx := context.Background()
+	video, err := svc.CreateMedia(ctx, models.Video, "s3://media/a.mp4")
+	require.NoError(t, err)
+
+	wrong := sha256.Sum256([]byte("other"))
+	rec := upload(h, video.ID, mp4, map[string]string{ChecksumSHA256Header: hex.EncodeToString(wrong[:])})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), apierr.CodeChecksumMismatch)
+
+	rec = upload(h, video.ID, "MZ\x90\x00 not a video", nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), apierr.CodeContentTypeMismatch)
+
+	require.Empty(t, sink.objects)
+	stored, err := svc.GetMedia(ctx, video.ID)
+	require.NoError(t, err)
+	require.Empty(t, stored.Checksum)
+}
+
+func TestUpload_Errors(t *testing.T) {
+	svc, _, h := newIngest(t)
+	ctx := context.Background()
+
+	require.Equal(t, http.StatusNotFound, upload(h, uuid.New(), mp4, nil).Code)
+	require.Equal(t, http.StatusBadRequest, upload(h, uuid.New(), mp4, map[string]string{ChecksumSHA256Header: "xyz"}).Code)
+
+	// Исходник медиа в обработке не перезаписывается
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://media/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, upload(h, m.ID, mp4, nil).Code)
+
+	big := upload(h, m.ID, strings.Repeat("x", 1<<20+1), nil)
+	require.Equal(t, http.StatusRequestEntityTooLarge, big.Code)
+}
+
+func TestUpload_Dedup(t *testing.T) {
+	svc, sink, h := newIngest(t, func(cfg *HandlerConfig) { cfg.Dedup = DedupReject })
+	owner := uuid.New()
+	ctx := service.WithPrincipal(context.Background(), service.Principal{OwnerID: owner})
+	sha := sha256.Sum256([]byte(mp4))
+	headers := map[string]string{"X-Owner-ID": owner.String(), ChecksumSHA256Header: hex.EncodeToString(sha[:])}
+
+	original, err := svc.CreateMedia(ctx, models.Video, "s3://media/original.mp4")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, upload(h, original.ID, mp4, headers).Code)
+
+	// reject: 409 с id оригинала, ничего не сохраняется
+	second, err := svc.CreateMedia(ctx, models.Video, "s3://media/second.mp4")
+	require.NoError(t, err)
+	rec := upload(h, second.ID, mp4, headers)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, original.ID.String(), body.Details["existing_media_id"])
+	require.NotContains(t, sink.objects, "s3://media/second.mp4")
+
+	// Без заявленного checksum дубликат неизвестен до загрузки
+	rec = upload(h, second.ID, mp4, map[string]string{"X-Owner-ID": owner.String()})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, sink.objects, "s3://media/second.mp4")
+
+	// reference из заголовка: медиа ссылается на исходник оригинала
+	third, err := svc.CreateMedia(ctx, models.Video, "s3://media/third.mp4")
+	require.NoError(t, err)
+	headers[DedupHeader] = string(DedupReference)
+	rec = upload(h, third.ID, mp4, headers)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp UploadResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, second.ID, resp.DuplicateOf) // новые первыми
+	require.Equal(t, "s3://media/second.mp4", resp.Source)
+	require.NotContains(t, sink.objects, "s3://media/third.mp4")
+	stored, err := svc.GetMedia(ctx, third.ID)
+	require.NoError(t, err)
+	require.Equal(t, "s3://media/second.mp4", stored.Source)
+	require.Equal(t, int64(len(mp4)), stored.Size)
+
+	// Общий исходник не перезаписывается ни через копию, ни через оригинал
+	delete(headers, DedupHeader)
+	headers[ChecksumSHA256Header] = ""
+	require.Equal(t, http.StatusConflict, upload(h, third.ID, mp4+"x", headers).Code)
+	require.Equal(t, http.StatusConflict, upload(h, second.ID, mp4+"x", headers).Code)
+	require.Equal(t, mp4, sink.objects["s3://media/second.mp4"])
+
+	headers[DedupHeader] = "always"
+	require.Equal(t, http.StatusBadRequest, upload(h, third.ID, mp4, headers).Code)
+}
+
+func TestUpload_Scan(t *testing.T) {
+	scanner := &fakeScanner{}
+	svc, _, h := newIngest(t, func(cfg *HandlerConfig) { cfg.Scanner = scanner })
+	ctx := context.Background()
+
+	clean, err := svc.CreateMedia(ctx, models.Video, "s3://media/clean.mp4")
+	require.NoError(t, err)
+	rec := upload(h, clean.ID, mp4, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp UploadResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, ScanClean, resp.Scan)
+
+	infected, err := svc.CreateMedia(ctx, models.Video, "s3://media/infected.mp4")
+	require.NoError(t, err)
+	rec = upload(h, infected.ID, mp4+"EICAR", nil)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), apierr.CodeMalwareDetected)
+	require.Contains(t, rec.Body.String(), "Eicar-Test-Signature")
+
+	stored, err := svc.GetMedia(ctx, infected.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.QuarantinedStatus, stored.Status)
+
+	// Недоступный антивирус — 503, медиа остаётся uploaded и загрузку можно повторить
+	scanner.err = errors.New("connection refused")
+	rec = upload(h, clean.ID, mp4, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Contains(t, rec.Body.String(), CodeScanUnavailable)
+	require.NotContains(t, rec.Body.String(), "connection refused")
+}
+
+func TestUpload_AsyncScan(t *testing.T) {
+	svc, _, h := newIngest(t, func(cfg *HandlerConfig) {
+		cfg.Scanner = &fakeScanner{}
+		cfg.AsyncScanBytes = int64(len(mp4))
+	})
+	ctx := context.Background()
+
+	// Не больше AsyncScanBytes — синхронно
+	small, err := svc.CreateMedia(ctx, models.Video, "s3://media/small.mp4")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, upload(h, small.ID, mp4, nil).Code)
+
+	large, err := svc.CreateMedia(ctx, models.Video, "s3://media/large.mp4")
+	require.NoError(t, err)
+	rec := upload(h, large.ID, mp4+"EICAR", nil)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var resp UploadResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, ScanPending, resp.Scan)
+
+	require.NoError(t, h.Wait(ctx))
+	stored, err := svc.GetMedia(ctx, large.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.QuarantinedStatus, stored.Status)
+}
+
+// fakeLimiter пропускает allow загрузок на владельца и, если maxBytes > 0, не больше maxBytes
+// байт на тарифе free; err — quota недоступна
+type fakeLimiter struct {
+	allow    int
+	maxBytes int64
+	used     map[string]int
+	err      error
+}
+
+func (l *fakeLimiter) CheckAndConsume(_ context.Context, owner string, n int, bytes int64) (quota.Decision, error) {
+	if l.err != nil {
+		return quota.Decision{}, l.err
+	}
+	d := quota.Decision{Limit: l.allow, ResetAt: time.Now().Add(time.Minute).Truncate(time.Second)}
+	if l.maxBytes > 0 {
+		d.Plan = &quota.PlanStatus{Name: "free", Limits: quota.Limits{MaxBytes: l.maxBytes}, Upgradable: true}
+	}
+	switch {
+	case l.maxBytes > 0 && bytes > l.maxBytes:
+		d.Exceeded = quota.ExceededBytes
+	case l.used[owner]+n <= l.allow:
+		l.used[owner] += n
+		d.Allowed = true
+	default:
+		d.Exceeded = quota.ExceededUploads
+	}
+	d.Remaining = l.allow - l.used[owner]
+	return d, nil
+}
+
+func TestUpload_RateLimited(t *testing.T) {
+	limiter := &fakeLimiter{allow: 1, used: map[string]int{}}
+	svc, _, h := newIngest(t, func(cfg *HandlerConfig) { cfg.Limiter = limiter })
+	owner := uuid.New()
+	ctx := service.WithPrincipal(context.Background(), service.Principal{OwnerID: owner})
+	headers := map[string]string{"X-Owner-ID": owner.String()}
+
+	first, err := svc.CreateMedia(ctx, models.Video, "s3://media/first.mp4")
+	require.NoError(t, err)
+	rec := upload(h, first.ID, mp4, headers)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "0", rec.Header().Get(quota.HeaderRateLimitRemaining))
+	require.Equal(t, 1, limiter.used[owner.String()])
+
+	// Лимит владельца исчерпан: 429 с моментом, когда можно повторить; исходник не сохраняется
+	second, err := svc.CreateMedia(ctx, models.Video, "s3://media/second.mp4")
+	require.NoError(t, err)
+	rec = upload(h, second.ID, mp4, headers)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Contains(t, rec.Body.String(), apierr.CodeRateLimited)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+	require.NotEmpty(t, rec.Header().Get(quota.HeaderRateLimitReset))
+	stored, err := svc.GetMedia(ctx, second.ID)
+	require.NoError(t, err)
+	require.Empty(t, stored.Checksum)
+
+	// Недоступная quota не останавливает загрузки
+	limiter.err = errors.New("connection refused")
+	require.Equal(t, http.StatusOK, upload(h, second.ID, mp4, headers).Code)
+}
+
+func TestUpload_QuotaExceeded(t *testing.T) {
+	limiter := &fakeLimiter{allow: 10, maxBytes: int64(len(mp4)) - 1, used: map[string]int{}}
+	svc, _, h := newIngest(t, func(cfg *HandlerConfig) { cfg.Limiter = limiter })
+	owner := uuid.New()
+	ctx := service.WithPrincipal(context.Background(), service.Principal{OwnerID: owner})
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://media/big.mp4")
+	require.NoError(t, err)
+	rec := upload(h, m.ID, mp4, map[string]string{"X-Owner-ID": owner.String()})
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+
+	// Отказ по тарифу: клиенту нужно, что исчерпано и можно ли перейти на тариф выше
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, apierr.CodeQuotaExceeded, body.Code)
+	require.Contains(t, body.Message, "upgrade the plan")
+	require.Equal(t, map[string]any{"exceeded": quota.ExceededBytes, "plan": "free", "upgrade_available": true}, body.Details)
+	require.Zero(t, limiter.used[owner.String()])
+}
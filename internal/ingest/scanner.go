@@ -0,0 +1,161 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Scanner проверяет содержимое исходника на вредоносное ПО
+type Scanner interface {
+	// Name — имя сканера, попадает в MediaQuarantined
+	Name() string
+	// Scan читает r до конца; ошибка означает, что проверка не состоялась, а не что файл заражён
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// ScanResult — заключение сканера
+type ScanResult struct {
+	Threat string // найденная сигнатура; пустая — угроз не найдено
+}
+
+func (r ScanResult) Infected() bool { return r.Threat != "" }
+
+// ClamAVConfig содержит конфигурацию ClamAV
+type ClamAVConfig struct {
+	Addr      string        // адрес clamd, host:3310
+	Timeout   time.Duration // на одну проверку, если у ctx нет своего дедлайна (default: 5m)
+	ChunkSize int           // размер блока INSTREAM (default: 64KiB); не больше StreamMaxLength clamd
+}
+
+// ClamAV — Scanner поверх clamd по TCP (команда INSTREAM)
+type ClamAV struct {
+	addr      string
+	timeout   time.Duration
+	chunkSize int
+	dialer    net.Dialer
+}
+
+func NewClamAV(cfg ClamAVConfig) (*ClamAV, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("clamd address is required")
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("timeout cannot be negative, got: %v", cfg.Timeout)
+	}
+	if cfg.ChunkSize < 0 {
+		return nil, fmt.Errorf("chunk size cannot be negative, got: %d", cfg.ChunkSize)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = 64 << 10
+	}
+	return &ClamAV{addr: cfg.Addr, timeout: cfg.Timeout, chunkSize: cfg.ChunkSize}, nil
+}
+
+func (c *ClamAV) Name() string { return "clamav" }
+
+// Scan отправляет r в clamd блоками INSTREAM: 4 байта длины (big endian) и данные,
+// поток завершается блоком нулевой длины. clamd отвечает "stream: OK",
+// "stream: <сигнатура> FOUND" или "<причина> ERROR".
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamd connect: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return ScanResult{}, fmt.Errorf("clamd: %w", err)
+	}
+	// Отмена ctx прерывает блокирующие чтение и запись
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := c.stream(conn, r); err != nil {
+		// clamd закрывает соединение, если поток больше StreamMaxLength, и пишет причину
+		if reply, rerr := readReply(conn); rerr == nil && reply != "" {
+			return parseReply(reply)
+		}
+		if ctx.Err() != nil {
+			return ScanResult{}, fmt.Errorf("clamd scan: %w", ctx.Err())
+		}
+		return ScanResult{}, fmt.Errorf("clamd scan: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamd reply: %w", err)
+	}
+	return parseReply(reply)
+}
+
+func (c *ClamAV) stream(conn net.Conn, r io.Reader) error {
+	w := bufio.NewWriterSize(conn, c.chunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+
+	buf := make([]byte, c.chunkSize)
+	var size [4]byte
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, werr := w.Write(size[:]); werr != nil {
+				return werr
+			}
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read content: %w", err)
+		}
+	}
+
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// readReply читает ответ clamd до нулевого байта (z-команды) или закрытия соединения
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
+
+func parseReply(reply string) (ScanResult, error) {
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		threat := strings.TrimSuffix(reply, " FOUND")
+		threat = strings.TrimPrefix(threat, "stream: ")
+		return ScanResult{Threat: threat}, nil
+	case strings.HasSuffix(reply, ": OK"):
+		return ScanResult{}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd: %s", reply)
+	}
+}
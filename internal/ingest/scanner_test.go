@@ -0,0 +1,101 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd принимает INSTREAM и отвечает reply(содержимое)
+func fakeClamd(t *testing.T, reply func(content string) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var content strings.Builder
+				for {
+					var size [4]byte
+					if _, err := io.ReadFull(r, size[:]); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size[:])
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, r, int64(n)); err != nil {
+						return
+					}
+				}
+				_, _ = conn.Write([]byte(reply(content.String()) + "\x00"))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	addr := fakeClamd(t, func(content string) string {
+		switch {
+		case strings.Contains(content, "EICAR"):
+			return "stream: Eicar-Test-Signature FOUND"
+		case content == "":
+			return "INSTREAM size limit exceeded. ERROR"
+		default:
+			return "stream: OK"
+		}
+	})
+	// Маленький блок, чтобы содержимое ушло несколькими блоками
+	clam, err := NewClamAV(ClamAVConfig{Addr: addr, ChunkSize: 4})
+	require.NoError(t, err)
+	require.Equal(t, "clamav", clam.Name())
+	ctx := context.Background()
+
+	res, err := clam.Scan(ctx, strings.NewReader(mp4))
+	require.NoError(t, err)
+	require.False(t, res.Infected())
+
+	res, err = clam.Scan(ctx, strings.NewReader("prefix EICAR suffix"))
+	require.NoError(t, err)
+	require.True(t, res.Infected())
+	require.Equal(t, "Eicar-Test-Signature", res.Threat)
+
+	_, err = clam.Scan(ctx, strings.NewReader(""))
+	require.ErrorContains(t, err, "size limit exceeded")
+}
+
+func TestClamAV_Unavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	clam, err := NewClamAV(ClamAVConfig{Addr: addr, Timeout: time.Second})
+	require.NoError(t, err)
+	_, err = clam.Scan(context.Background(), strings.NewReader(mp4))
+	require.ErrorContains(t, err, "clamd connect")
+
+	_, err = NewClamAV(ClamAVConfig{})
+	require.Error(t, err)
+}
@@ -0,0 +1,176 @@
+// Package ingest — приём исходников медиа. Тело загрузки проверяется на лету: MIME тип
+// определяется по первым байтам и сверяется с типом медиа, checksum считается по потоку
+// и сверяется с заявленным клиентом. Проверенный исходник пишется в объектное хранилище,
+// а checksum, размер и MIME тип записываются в media. Если настроен Scanner, сохранённый
+// исходник проверяется антивирусом, заражённое медиа уходит в карантин.
+package ingest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// sniffLen — сколько первых байт нужно http.DetectContentType
+const sniffLen = 512
+
+// Expected — что клиент заявил о загрузке. Пустой checksum не проверяется.
+type Expected struct {
+	Type   models.MediaType
+	SHA256 []byte
+	MD5    []byte
+}
+
+// Verifier читает тело загрузки и проверяет его. MIME тип проверяется в NewVerifier,
+// до того как первый байт уйдёт в хранилище; checksum — в конце потока: вместо io.EOF
+// Read возвращает ошибку, и хранилище не принимает объект целиком.
+type Verifier struct {
+	r           io.Reader
+	expected    Expected
+	sha256      hash.Hash
+	md5         hash.Hash
+	size        int64
+	contentType string
+	done        bool
+	err         error
+}
+
+// NewVerifier читает начало тела и определяет MIME тип; domain.ErrContentTypeMismatch,
+// если содержимое не подходит для expected.Type (например, исполняемый файл под видом видео)
+func NewVerifier(r io.Reader, expected Expected) (*Verifier, error) {
+	if len(expected.SHA256) != 0 && len(expected.SHA256) != sha256.Size {
+		return nil, fmt.Errorf("%w: sha256 must be %d bytes", models.ErrInvalidArgument, sha256.Size)
+	}
+	if len(expected.MD5) != 0 && len(expected.MD5) != md5.Size {
+		return nil, fmt.Errorf("%w: md5 must be %d bytes", models.ErrInvalidArgument, md5.Size)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("read upload: %w", err)
+	}
+	head = head[:n]
+
+	contentType := Sniff(head)
+	if !Allowed(expected.Type, contentType) {
+		return nil, fmt.Errorf("%w: %s content cannot be stored as %s", domain.ErrContentTypeMismatch, contentType, expected.Type)
+	}
+
+	v := &Verifier{
+		expected:    expected,
+		sha256:      sha256.New(),
+		md5:         md5.New(),
+		contentType: contentType,
+	}
+	v.r = io.TeeReader(io.MultiReader(bytes.NewReader(head), r), io.MultiWriter(v.sha256, v.md5))
+	return v, nil
+}
+
+func (v *Verifier) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err := v.r.Read(p)
+	v.size += int64(n)
+	if errors.Is(err, io.EOF) {
+		v.done = true
+		if mismatch := v.verify(); mismatch != nil {
+			v.err = mismatch
+			return n, mismatch
+		}
+	}
+	return n, err
+}
+
+// verify сверяет checksum прочитанного потока с заявленными
+func (v *Verifier) verify() error {
+	if len(v.expected.SHA256) != 0 && !bytes.Equal(v.sha256.Sum(nil), v.expected.SHA256) {
+		return fmt.Errorf("%w: sha256 of content is %x, declared %x", domain.ErrChecksumMismatch, v.sha256.Sum(nil), v.expected.SHA256)
+	}
+	if len(v.expected.MD5) != 0 && !bytes.Equal(v.md5.Sum(nil), v.expected.MD5) {
+		return fmt.Errorf("%w: md5 of content is %x, declared %x", domain.ErrChecksumMismatch, v.md5.Sum(nil), v.expected.MD5)
+	}
+	return nil
+}
+
+// ContentType — MIME тип, определённый по содержимому
+func (v *Verifier) ContentType() string { return v.contentType }
+
+// Content возвращает характеристики исходника; доступен только после успешного чтения до конца
+func (v *Verifier) Content() (models.Content, error) {
+	if v.err != nil {
+		return models.Content{}, v.err
+	}
+	if !v.done {
+		return models.Content{}, errors.New("upload is not read to the end")
+	}
+	return models.Content{
+		Checksum:    hex.EncodeToString(v.sha256.Sum(nil)),
+		Size:        v.size,
+		ContentType: v.contentType,
+	}, nil
+}
+
+// executables — сигнатуры исполняемых файлов, которые http.DetectContentType не различает
+var executables = []struct {
+	magic       string
+	contentType string
+}{
+	{"MZ", "application/vnd.microsoft.portable-executable"},
+	{"\x7fELF", "application/x-elf"},
+	{"\xfe\xed\xfa\xce", "application/x-mach-binary"},
+	{"\xfe\xed\xfa\xcf", "application/x-mach-binary"},
+	{"\xce\xfa\xed\xfe", "application/x-mach-binary"},
+	{"\xcf\xfa\xed\xfe", "application/x-mach-binary"},
+	{"#!", "text/x-shellscript"},
+}
+
+// Sniff определяет MIME тип по первым байтам содержимого (без параметров вроде charset)
+func Sniff(head []byte) string {
+	for _, e := range executables {
+		if bytes.HasPrefix(head, []byte(e.magic)) {
+			return e.contentType
+		}
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return contentType
+}
+
+// Allowed сообщает, можно ли хранить содержимое contentType как медиа типа t.
+// Видео и аудио должны распознаваться как таковые (ogg — контейнер для обоих);
+// file принимает любое содержимое, кроме исполняемого.
+func Allowed(t models.MediaType, contentType string) bool {
+	if isExecutable(contentType) {
+		return false
+	}
+	switch t {
+	case models.Video:
+		return strings.HasPrefix(contentType, "video/") || contentType == "application/ogg"
+	case models.Audio:
+		return strings.HasPrefix(contentType, "audio/") || contentType == "application/ogg"
+	case models.File:
+		return true
+	default:
+		return false
+	}
+}
+
+func isExecutable(contentType string) bool {
+	for _, e := range executables {
+		if e.contentType == contentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package ingest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// mp4 — начало mp4 файла (ftyp box) и немного данных
+var mp4 = "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom" + strings.Repeat("\x00frame", 200)
+
+func TestVerifier_ComputesContent(t *testing.T) {
+	sha := sha256.Sum256([]byte(mp4))
+	sum := md5.Sum([]byte(mp4))
+
+	v, err := NewVerifier(strings.NewReader(mp4), Expected{Type: models.Video, SHA256: sha[:], MD5: sum[:]})
+	require.NoError(t, err)
+	require.Equal(t, "video/mp4", v.ContentType())
+
+	_, err = v.Content()
+	require.Error(t, err, "content is unknown before the stream is read")
+
+	data, err := io.ReadAll(v)
+	require.NoError(t, err)
+	require.Equal(t, mp4, string(data))
+
+	content, err := v.Content()
+	require.NoError(t, err)
+	require.Equal(t, models.Content{Checksum: hex.EncodeToString(sha[:]), Size: int64(len(mp4)), ContentType: "video/mp4"}, content)
+}
+
+func TestVerifier_ChecksumMismatchFailsStream(t *testing.T) {
+	wrong := sha256.Sum256([]byte("something else"))
+	v, err := NewVerifier(strings.NewReader(mp4), Expected{Type: models.Video, SHA256: wrong[:]})
+	require.NoError(t, err)
+
+	// Вместо io.EOF поток заканчивается ошибкой — хранилище не примет объект
+	_, err = io.ReadAll(v)
+	require.ErrorIs(t, err, domain.ErrChecksumMismatch)
+	_, err = v.Content()
+	require.ErrorIs(t, err, domain.ErrChecksumMismatch)
+
+	badMD5 := md5.Sum([]byte("x"))
+	v, err = NewVerifier(strings.NewReader(mp4), Expected{Type: models.Video, MD5: badMD5[:]})
+	require.NoError(t, err)
+	_, err = io.ReadAll(v)
+	require.ErrorIs(t, err, domain.ErrChecksumMismatch)
+}
+
+func TestVerifier_RejectsContentOfWrongType(t *testing.T) {
+	cases := []struct {
+		name    string
+		typ     models.MediaType
+		content string
+	}{
+		{"exe as video", models.Video, "MZ\x90\x00\x03\x00\x00\x00This program cannot be run in DOS mode"},
+		{"elf as file", models.File, "\x7fELF\x02\x01\x01" + strings.Repeat("\x00", 64)},
+		{"script as file", models.File, "#!/bin/sh\nrm -rf /\n"},
+		{"text as video", models.Video, "just some text"},
+		{"mp4 as audio", models.Audio, mp4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewVerifier(strings.NewReader(tc.content), Expected{Type: tc.typ})
+			require.ErrorIs(t, err, domain.ErrContentTypeMismatch)
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	require.True(t, Allowed(models.Video, "video/webm"))
+	require.True(t, Allowed(models.Audio, "audio/mpeg"))
+	require.True(t, Allowed(models.Audio, "application/ogg"))
+	require.True(t, Allowed(models.File, "application/pdf"))
+	require.True(t, Allowed(models.File, "application/octet-stream"))
+	require.False(t, Allowed(models.File, "application/vnd.microsoft.portable-executable"))
+	require.False(t, Allowed("image", "image/png"))
+}
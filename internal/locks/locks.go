@@ -0,0 +1,101 @@
+// Package locks — распределённые блокировки с TTL для координации между инстансами и сервисами:
+// пока блокировка ключа у одного владельца, остальные её не получат. Владелец продлевает
+// блокировку раньше, чем истечёт TTL; не продлил (упал, завис, потерял связь) — блокировка
+// освобождается сама и достаётся другому.
+//
+// Реализации: Postgres (session advisory lock), Redis (SET NX PX) и Memory для тестов
+// и одного инстанса.
+package locks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotAcquired — блокировку ключа держит другой владелец
+	ErrNotAcquired = errors.New("lock is held by another owner")
+	// ErrLost — блокировка истекла или отдана: владелец больше не может на неё полагаться
+	ErrLost = errors.New("lock lost")
+)
+
+// Lease — взятая блокировка. Token отличает владельца: продлить и отдать блокировку
+// можно только с ним, даже если ключ уже взял кто-то другой.
+type Lease struct {
+	Key   string
+	Token string
+	TTL   time.Duration
+}
+
+// Locker — распределённые блокировки
+type Locker interface {
+	// Acquire берёт блокировку key на ttl без ожидания; ErrNotAcquired — ключ занят
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lease, error)
+	// Renew продлевает блокировку ещё на lease.TTL; ErrLost — блокировка уже не этого владельца
+	Renew(ctx context.Context, lease Lease) error
+	// Release отдаёт блокировку; ErrLost — она истекла раньше
+	Release(ctx context.Context, lease Lease) error
+}
+
+func validate(key string, ttl time.Duration) error {
+	if key == "" {
+		return fmt.Errorf("lock key is required")
+	}
+	if ttl < time.Millisecond {
+		return fmt.Errorf("lock ttl must be at least 1ms, got: %v", ttl)
+	}
+	return nil
+}
+
+// Hold берёт блокировку key и выполняет fn, продлевая блокировку каждую треть ttl.
+// Занятый ключ — ErrNotAcquired, fn не вызывается. Продление не удалось — контекст fn
+// отменяется, и Hold возвращает ошибку с ErrLost. После fn блокировка отдаётся.
+func Hold(ctx context.Context, l Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lease, err := l.Acquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() { result <- fn(workCtx) }()
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	var lost error
+	for lost == nil {
+		select {
+		case err := <-result:
+			return errors.Join(err, release(l, lease))
+		case <-ticker.C:
+			renewCtx, cancelRenew := context.WithTimeout(ctx, ttl/3)
+			err := l.Renew(renewCtx, lease)
+			cancelRenew()
+			if err != nil && ctx.Err() == nil {
+				lost = err
+			}
+		}
+	}
+
+	cancel()
+	<-result
+	_ = release(l, lease)
+	if !errors.Is(lost, ErrLost) {
+		lost = fmt.Errorf("%w: %w", ErrLost, lost)
+	}
+	return fmt.Errorf("lock %s: %w", key, lost)
+}
+
+// release отдаёт блокировку; контекст свой — ctx Hold к этому моменту может быть отменён
+func release(l Locker, lease Lease) error {
+	ctx, cancel := context.WithTimeout(context.Background(), lease.TTL)
+	defer cancel()
+	if err := l.Release(ctx, lease); err != nil {
+		return fmt.Errorf("release lock %s: %w", lease.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build integration
+
+package locks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestPostgres(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.StartPostgres(t).DB
+
+	l, err := NewPostgres(PostgresConfig{DB: db})
+	require.NoError(t, err)
+	runLockerTests(t, l)
+
+	// Другой инстанс с тем же namespace конкурирует за те же ключи, с другим — нет
+	lease, err := l.Acquire(ctx, "transcode:m1", time.Minute)
+	require.NoError(t, err)
+
+	peer, err := NewPostgres(PostgresConfig{DB: db})
+	require.NoError(t, err)
+	_, err = peer.Acquire(ctx, "transcode:m1", time.Minute)
+	require.ErrorIs(t, err, ErrNotAcquired)
+
+	other, err := NewPostgres(PostgresConfig{DB: db, Namespace: "other"})
+	require.NoError(t, err)
+	otherLease, err := other.Acquire(ctx, "transcode:m1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx, otherLease))
+
+	// Соединение блокировки возвращается в пул без неё
+	require.NoError(t, l.Release(ctx, lease))
+	lease, err = peer.Acquire(ctx, "transcode:m1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, peer.Release(ctx, lease))
+}
+
+func TestRedis(t *testing.T) {
+	client := testutil.StartRedis(t)
+
+	l, err := NewRedis(RedisConfig{Client: client})
+	require.NoError(t, err)
+	runLockerTests(t, l)
+
+	ttl, err := client.PTTL(context.Background(), "lock:transcode:m1").Result()
+	require.NoError(t, err)
+	require.Negative(t, ttl, "released lock must not leave a key")
+}
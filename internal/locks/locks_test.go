@@ -0,0 +1,142 @@
+package locks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runLockerTests — общий контракт Locker; прогоняется против Memory здесь и против Postgres
+// и Redis в интеграционных тестах
+func runLockerTests(t *testing.T, l Locker) {
+	ctx := context.Background()
+
+	t.Run("exclusive", func(t *testing.T) {
+		lease, err := l.Acquire(ctx, "transcode:m1", time.Minute)
+		require.NoError(t, err)
+		require.NotEmpty(t, lease.Token)
+
+		_, err = l.Acquire(ctx, "transcode:m1", time.Minute)
+		require.ErrorIs(t, err, ErrNotAcquired)
+
+		other, err := l.Acquire(ctx, "transcode:m2", time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, l.Renew(ctx, lease))
+		require.NoError(t, l.Release(ctx, lease))
+		require.NoError(t, l.Release(ctx, other))
+
+		again, err := l.Acquire(ctx, "transcode:m1", time.Minute)
+		require.NoError(t, err)
+		require.NotEqual(t, lease.Token, again.Token)
+		require.NoError(t, l.Release(ctx, again))
+	})
+
+	t.Run("stale lease", func(t *testing.T) {
+		lease, err := l.Acquire(ctx, "transcode:stale", time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, l.Release(ctx, lease))
+
+		current, err := l.Acquire(ctx, "transcode:stale", time.Minute)
+		require.NoError(t, err)
+
+		// Отданная блокировка не продлевает и не снимает блокировку нового владельца
+		require.ErrorIs(t, l.Renew(ctx, lease), ErrLost)
+		require.ErrorIs(t, l.Release(ctx, lease), ErrLost)
+		_, err = l.Acquire(ctx, "transcode:stale", time.Minute)
+		require.ErrorIs(t, err, ErrNotAcquired)
+		require.NoError(t, l.Release(ctx, current))
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		lease, err := l.Acquire(ctx, "transcode:expires", 200*time.Millisecond)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			next, err := l.Acquire(ctx, "transcode:expires", time.Minute)
+			if err != nil {
+				return false
+			}
+			require.NoError(t, l.Release(ctx, next))
+			return true
+		}, 5*time.Second, 50*time.Millisecond)
+		require.ErrorIs(t, l.Renew(ctx, lease), ErrLost)
+	})
+
+	t.Run("renew extends", func(t *testing.T) {
+		lease, err := l.Acquire(ctx, "transcode:renew", 300*time.Millisecond)
+		require.NoError(t, err)
+		for range 4 {
+			time.Sleep(150 * time.Millisecond)
+			require.NoError(t, l.Renew(ctx, lease))
+		}
+		_, err = l.Acquire(ctx, "transcode:renew", time.Minute)
+		require.ErrorIs(t, err, ErrNotAcquired)
+		require.NoError(t, l.Release(ctx, lease))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := l.Acquire(ctx, "", time.Minute)
+		require.Error(t, err)
+		_, err = l.Acquire(ctx, "transcode:m1", 0)
+		require.Error(t, err)
+	})
+}
+
+func TestMemory(t *testing.T) {
+	runLockerTests(t, NewMemory())
+}
+
+func TestHold(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemory()
+
+	var ran bool
+	err := Hold(ctx, l, "transcode:m1", time.Minute, func(ctx context.Context) error {
+		ran = true
+		_, err := l.Acquire(ctx, "transcode:m1", time.Minute)
+		require.ErrorIs(t, err, ErrNotAcquired)
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, ran)
+
+	// После fn блокировка отдана
+	lease, err := l.Acquire(ctx, "transcode:m1", time.Minute)
+	require.NoError(t, err)
+
+	// Занятый ключ: fn не вызывается
+	err = Hold(ctx, l, "transcode:m1", time.Minute, func(context.Context) error {
+		t.Fatal("fn must not run")
+		return nil
+	})
+	require.ErrorIs(t, err, ErrNotAcquired)
+	require.NoError(t, l.Release(ctx, lease))
+
+	// Ошибка fn возвращается как есть
+	boom := errors.New("boom")
+	require.ErrorIs(t, Hold(ctx, l, "transcode:m1", time.Minute, func(context.Context) error { return boom }), boom)
+}
+
+func TestHold_Lost(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemory()
+
+	err := Hold(ctx, l, "transcode:m1", 300*time.Millisecond, func(ctx context.Context) error {
+		// Блокировку забрали: следующее продление не удастся
+		l.mu.Lock()
+		delete(l.held, "transcode:m1")
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("work was not cancelled")
+		}
+	})
+	require.ErrorIs(t, err, ErrLost)
+}
@@ -0,0 +1,70 @@
+package locks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Memory — блокировки в памяти процесса: для тестов и одного инстанса
+type Memory struct {
+	mu    sync.Mutex
+	held  map[string]memoryLock
+	clock func() time.Time
+}
+
+type memoryLock struct {
+	token     string
+	expiresAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{held: make(map[string]memoryLock), clock: time.Now}
+}
+
+func (m *Memory) Acquire(_ context.Context, key string, ttl time.Duration) (Lease, error) {
+	if err := validate(key, ttl); err != nil {
+		return Lease{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+	if h, ok := m.held[key]; ok && now.Before(h.expiresAt) {
+		return Lease{}, ErrNotAcquired
+	}
+	lease := Lease{Key: key, Token: uuid.NewString(), TTL: ttl}
+	m.held[key] = memoryLock{token: lease.Token, expiresAt: now.Add(ttl)}
+	return lease, nil
+}
+
+func (m *Memory) Renew(_ context.Context, lease Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+	h, ok := m.held[lease.Key]
+	if !ok || h.token != lease.Token || !now.Before(h.expiresAt) {
+		return ErrLost
+	}
+	h.expiresAt = now.Add(lease.TTL)
+	m.held[lease.Key] = h
+	return nil
+}
+
+func (m *Memory) Release(_ context.Context, lease Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.held[lease.Key]
+	if !ok || h.token != lease.Token {
+		return ErrLost
+	}
+	delete(m.held, lease.Key)
+	if !m.clock().Before(h.expiresAt) {
+		return ErrLost
+	}
+	return nil
+}
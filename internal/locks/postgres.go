@@ -0,0 +1,162 @@
+package locks
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresConfig содержит настройки блокировок Postgres
+type PostgresConfig struct {
+	DB        *sqlx.DB
+	Namespace string // Первая половина ключа advisory lock: блокировки разных подсистем не пересекаются (default: "locks")
+}
+
+// Postgres — блокировки на session advisory lock с ключом (hashtext(Namespace), hashtext(key)).
+// Каждую блокировку держит своё соединение пула. У advisory lock нет TTL: его заменяет таймер
+// в процессе, который по истечении закрывает соединение, и Postgres снимает блокировку вместе
+// с сессией. Упал процесс — сессия обрывается, и блокировка тоже снимается. Продление —
+// ping соединения. Через pgbouncer в режиме transaction не работает.
+type Postgres struct {
+	db        *sqlx.DB
+	namespace string
+
+	mu   sync.Mutex
+	held map[string]*postgresLock // по Token
+}
+
+type postgresLock struct {
+	mu    sync.Mutex
+	conn  *sqlx.Conn
+	timer *time.Timer
+	done  bool // соединение закрыто или возвращено в пул
+}
+
+func NewPostgres(cfg PostgresConfig) (*Postgres, error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("db is required")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = "locks"
+	}
+
+	return &Postgres{db: cfg.DB, namespace: cfg.Namespace, held: make(map[string]*postgresLock)}, nil
+}
+
+func (p *Postgres) Acquire(ctx context.Context, key string, ttl time.Duration) (Lease, error) {
+	if err := validate(key, ttl); err != nil {
+		return Lease{}, err
+	}
+
+	conn, err := p.db.Connx(ctx)
+	if err != nil {
+		return Lease{}, fmt.Errorf("acquire lock %s: %w", key, err)
+	}
+	var acquired bool
+	err = conn.GetContext(ctx, &acquired, `SELECT pg_try_advisory_lock(hashtext($1), hashtext($2))`, p.namespace, key)
+	if err != nil {
+		discardConn(conn)
+		return Lease{}, fmt.Errorf("acquire lock %s: %w", key, err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return Lease{}, ErrNotAcquired
+	}
+
+	lease := Lease{Key: key, Token: uuid.NewString(), TTL: ttl}
+	h := &postgresLock{conn: conn}
+	p.mu.Lock()
+	p.held[lease.Token] = h
+	p.mu.Unlock()
+	h.timer = time.AfterFunc(ttl, func() { p.expire(lease.Token, h) })
+	return lease, nil
+}
+
+func (p *Postgres) Renew(ctx context.Context, lease Lease) error {
+	h := p.lookup(lease.Token)
+	if h == nil {
+		return ErrLost
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Таймер уже сработал — блокировка истекла, даже если expire ещё ждёт h.mu
+	if h.done || !h.timer.Stop() {
+		p.drop(lease.Token, h)
+		return ErrLost
+	}
+	if err := h.conn.PingContext(ctx); err != nil {
+		p.drop(lease.Token, h)
+		return fmt.Errorf("%w: %w", ErrLost, err)
+	}
+	h.timer.Reset(lease.TTL)
+	return nil
+}
+
+// Release — см. Locker. Если снять блокировку не удалось, соединение закрывается, а не
+// возвращается в пул: иначе блокировка осталась бы за соединением пула.
+func (p *Postgres) Release(ctx context.Context, lease Lease) error {
+	h := p.lookup(lease.Token)
+	if h == nil {
+		return ErrLost
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done || !h.timer.Stop() {
+		p.drop(lease.Token, h)
+		return ErrLost
+	}
+
+	p.forget(lease.Token)
+	h.done = true
+	var released bool
+	err := h.conn.GetContext(ctx, &released, `SELECT pg_advisory_unlock(hashtext($1), hashtext($2))`, p.namespace, lease.Key)
+	if err != nil || !released {
+		discardConn(h.conn)
+		if err == nil {
+			return ErrLost
+		}
+		return fmt.Errorf("release lock %s: %w", lease.Key, err)
+	}
+	return h.conn.Close()
+}
+
+// expire срабатывает по таймеру: блокировку не продлили за TTL
+func (p *Postgres) expire(token string, h *postgresLock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p.drop(token, h)
+}
+
+// drop закрывает соединение вместе с блокировкой; вызывается под h.mu
+func (p *Postgres) drop(token string, h *postgresLock) {
+	p.forget(token)
+	if h.done {
+		return
+	}
+	h.done = true
+	h.timer.Stop()
+	discardConn(h.conn)
+}
+
+func (p *Postgres) lookup(token string) *postgresLock {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.held[token]
+}
+
+func (p *Postgres) forget(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.held, token)
+}
+
+// discardConn закрывает соединение вместе с сессией вместо возврата в пул
+func discardConn(conn *sqlx.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
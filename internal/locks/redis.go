@@ -0,0 +1,90 @@
+package locks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig содержит настройки блокировок Redis
+type RedisConfig struct {
+	Client    redis.UniversalClient
+	KeyPrefix string // Префикс ключей (default: "lock:")
+}
+
+// Redis — блокировки на ключах Redis: SET NX PX со случайным токеном владельца. Продление
+// и снятие — Lua-скрипты, которые трогают ключ, только если в нём токен этого владельца:
+// истёкшая и взятая другим блокировка не продлевается и не снимается чужим владельцем.
+// Один инстанс Redis (или primary с репликами): при failover недореплицированная блокировка
+// может достаться второму владельцу.
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "lock:"
+	}
+
+	return &Redis{client: cfg.Client, prefix: cfg.KeyPrefix}, nil
+}
+
+// renewScript: KEYS[1] — ключ, ARGV[1] — токен, ARGV[2] — TTL в мс
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript: KEYS[1] — ключ, ARGV[1] — токен
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (r *Redis) Acquire(ctx context.Context, key string, ttl time.Duration) (Lease, error) {
+	if err := validate(key, ttl); err != nil {
+		return Lease{}, err
+	}
+	lease := Lease{Key: key, Token: uuid.NewString(), TTL: ttl}
+	ok, err := r.client.SetNX(ctx, r.prefix+key, lease.Token, ttl).Result()
+	if err != nil {
+		return Lease{}, fmt.Errorf("redis acquire lock %s: %w", key, err)
+	}
+	if !ok {
+		return Lease{}, ErrNotAcquired
+	}
+	return lease, nil
+}
+
+func (r *Redis) Renew(ctx context.Context, lease Lease) error {
+	n, err := renewScript.Run(ctx, r.client, []string{r.prefix + lease.Key}, lease.Token, lease.TTL.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("redis renew lock %s: %w", lease.Key, err)
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+func (r *Redis) Release(ctx context.Context, lease Lease) error {
+	n, err := releaseScript.Run(ctx, r.client, []string{r.prefix + lease.Key}, lease.Token).Int()
+	if err != nil {
+		return fmt.Errorf("redis release lock %s: %w", lease.Key, err)
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
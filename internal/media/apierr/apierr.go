@@ -0,0 +1,83 @@
+// Package apierr — единый реестр соответствия доменных ошибок транспортным кодам.
+// HTTP и gRPC берут статус отсюда, поэтому одна и та же ошибка не может
+// превратиться в 404 в одном транспорте и в Internal в другом.
+package apierr
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Коды ошибок API. Стабильная часть контракта: клиенты ветвятся по code, а не по message.
+const (
+	CodeInvalidArgument     = "invalid_argument"
+	CodeNotFound            = "not_found"
+	CodeConflict            = "conflict"
+	CodePreconditionFailed  = "precondition_failed"
+	CodeForbidden           = "forbidden"
+	CodeInvalidTransition   = "invalid_transition"
+	CodeQuotaExceeded       = "quota_exceeded"
+	CodeRateLimited         = "rate_limited"
+	CodeChecksumMismatch    = "checksum_mismatch"
+	CodeContentTypeMismatch = "content_type_mismatch"
+	CodeMalwareDetected     = "malware_detected"
+	CodeUnavailable         = "unavailable"
+	CodeInternal            = "internal"
+)
+
+// Mapping описывает, как доменная ошибка выглядит в каждом транспорте
+type Mapping struct {
+	Err        error
+	Code       string
+	Message    string
+	HTTPStatus int
+	GRPCCode   codes.Code
+}
+
+// registry проверяется по порядку, первое совпадение по errors.Is побеждает
+var registry = []Mapping{
+	{models.ErrInvalidArgument, CodeInvalidArgument, "invalid argument", http.StatusBadRequest, codes.InvalidArgument},
+	{models.ErrNotFound, CodeNotFound, "not found", http.StatusNotFound, codes.NotFound},
+	{domain.ErrNotFound, CodeNotFound, "not found", http.StatusNotFound, codes.NotFound},
+	{domain.ErrInvalidTransition, CodeInvalidTransition, "invalid status transition", http.StatusConflict, codes.FailedPrecondition},
+	{models.ErrConflict, CodeConflict, "conflict", http.StatusConflict, codes.AlreadyExists},
+	{models.ErrPreconditionFailed, CodePreconditionFailed, "media was modified, re-read it and retry", http.StatusPreconditionFailed, codes.FailedPrecondition},
+	{models.ErrForbidden, CodeForbidden, "permission denied", http.StatusForbidden, codes.PermissionDenied},
+	{domain.ErrConflict, CodeConflict, "conflict", http.StatusConflict, codes.Aborted},
+	{domain.ErrQuotaExceeded, CodeQuotaExceeded, "quota exceeded", http.StatusTooManyRequests, codes.ResourceExhausted},
+	{domain.ErrRateLimited, CodeRateLimited, "upload rate limit exceeded", http.StatusTooManyRequests, codes.ResourceExhausted},
+	{domain.ErrChecksumMismatch, CodeChecksumMismatch, "checksum mismatch", http.StatusUnprocessableEntity, codes.InvalidArgument},
+	{domain.ErrMalwareDetected, CodeMalwareDetected, "malware detected, media is quarantined", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{domain.ErrContentTypeMismatch, CodeContentTypeMismatch, "content does not match media type", http.StatusUnprocessableEntity, codes.InvalidArgument},
+	{models.ErrUnavailable, CodeUnavailable, "temporarily unavailable, retry later", http.StatusServiceUnavailable, codes.Unavailable},
+}
+
+// internal — ответ для всего, что не описано в реестре. Текст исходной ошибки наружу не отдаётся.
+var internal = Mapping{
+	Code:       CodeInternal,
+	Message:    "internal error",
+	HTTPStatus: http.StatusInternalServerError,
+	GRPCCode:   codes.Internal,
+}
+
+// Lookup возвращает маппинг для err; для неизвестных ошибок — Internal
+func Lookup(err error) Mapping {
+	for _, m := range registry {
+		if errors.Is(err, m.Err) {
+			return m
+		}
+	}
+	return internal
+}
+
+// All возвращает копию реестра (для тестов и документации)
+func All() []Mapping {
+	out := make([]Mapping, len(registry))
+	copy(out, registry)
+	return out
+}
@@ -0,0 +1,144 @@
+package apierr
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// knownErrors — все доменные ошибки по именам. Список сверяется с исходниками
+// пакетов ниже, так что новая ErrXxx без маппинга роняет тест.
+var knownErrors = map[string]error{
+	"models.ErrNotFound":            models.ErrNotFound,
+	"models.ErrConflict":            models.ErrConflict,
+	"models.ErrInvalidArgument":     models.ErrInvalidArgument,
+	"models.ErrPreconditionFailed":  models.ErrPreconditionFailed,
+	"models.ErrForbidden":           models.ErrForbidden,
+	"models.ErrUnavailable":         models.ErrUnavailable,
+	"domain.ErrNotFound":            domain.ErrNotFound,
+	"domain.ErrInvalidTransition":   domain.ErrInvalidTransition,
+	"domain.ErrConflict":            domain.ErrConflict,
+	"domain.ErrQuotaExceeded":       domain.ErrQuotaExceeded,
+	"domain.ErrRateLimited":         domain.ErrRateLimited,
+	"domain.ErrChecksumMismatch":    domain.ErrChecksumMismatch,
+	"domain.ErrContentTypeMismatch": domain.ErrContentTypeMismatch,
+	"domain.ErrMalwareDetected":     domain.ErrMalwareDetected,
+}
+
+// declaredErrors парсит пакет и возвращает имена экспортированных переменных Err*
+func declaredErrors(t *testing.T, dir, pkg string) []string {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+
+	var out []string
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						if strings.HasPrefix(name.Name, "Err") && name.IsExported() {
+							out = append(out, pkg+"."+name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestRegistry_CoversEveryDomainError(t *testing.T) {
+	var declared []string
+	declared = append(declared, declaredErrors(t, filepath.Join("..", "models"), "models")...)
+	declared = append(declared, declaredErrors(t, filepath.Join("..", "domain"), "domain")...)
+	sort.Strings(declared)
+
+	var known []string
+	for name := range knownErrors {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+	require.Equal(t, declared, known, "knownErrors is out of date with domain/models packages")
+
+	for name, err := range knownErrors {
+		t.Run(name, func(t *testing.T) {
+			m := Lookup(err)
+			require.NotEqual(t, CodeInternal, m.Code, "%s is not mapped and would surface as 500", name)
+			require.NotEqual(t, http.StatusInternalServerError, m.HTTPStatus)
+			require.NotEqual(t, codes.Internal, m.GRPCCode)
+			require.NotEmpty(t, m.Message)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		grpc   codes.Code
+		code   string
+	}{
+		{"invalid argument", models.ErrInvalidArgument, http.StatusBadRequest, codes.InvalidArgument, CodeInvalidArgument},
+		{"not found", models.ErrNotFound, http.StatusNotFound, codes.NotFound, CodeNotFound},
+		{"invalid transition", domain.ValidateTransition(domain.Ready, domain.Uploaded), http.StatusConflict, codes.FailedPrecondition, CodeInvalidTransition},
+		{"precondition", fmt.Errorf("change status: %w", models.ErrPreconditionFailed), http.StatusPreconditionFailed, codes.FailedPrecondition, CodePreconditionFailed},
+		{"forbidden", fmt.Errorf("download: %w", models.ErrForbidden), http.StatusForbidden, codes.PermissionDenied, CodeForbidden},
+		{"quota", domain.ErrQuotaExceeded, http.StatusTooManyRequests, codes.ResourceExhausted, CodeQuotaExceeded},
+		{"rate limited", fmt.Errorf("ingest: %w", domain.ErrRateLimited), http.StatusTooManyRequests, codes.ResourceExhausted, CodeRateLimited},
+		{"checksum", fmt.Errorf("upload: %w", domain.ErrChecksumMismatch), http.StatusUnprocessableEntity, codes.InvalidArgument, CodeChecksumMismatch},
+		{"content type", domain.ErrContentTypeMismatch, http.StatusUnprocessableEntity, codes.InvalidArgument, CodeContentTypeMismatch},
+		{"unavailable", fmt.Errorf("change status: %w: deadlock detected", models.ErrUnavailable), http.StatusServiceUnavailable, codes.Unavailable, CodeUnavailable},
+		{"wrapped", fmt.Errorf("repo: %w", models.ErrNotFound), http.StatusNotFound, codes.NotFound, CodeNotFound},
+		{"unknown", errors.New("pq: connection refused"), http.StatusInternalServerError, codes.Internal, CodeInternal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := Lookup(tc.err)
+			require.Equal(t, tc.status, m.HTTPStatus)
+			require.Equal(t, tc.grpc, m.GRPCCode)
+			require.Equal(t, tc.code, m.Code)
+		})
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	require.NoError(t, GRPCError(nil))
+
+	st, ok := status.FromError(GRPCError(fmt.Errorf("get: %w", models.ErrNotFound)))
+	require.True(t, ok)
+	require.Equal(t, codes.NotFound, st.Code())
+
+	// Внутренний текст не утекает клиенту
+	st, _ = status.FromError(GRPCError(errors.New("pq: password authentication failed")))
+	require.Equal(t, codes.Internal, st.Code())
+	require.NotContains(t, st.Message(), "password")
+
+	// Готовые status error пробрасываются как есть
+	orig := status.Error(codes.Unauthenticated, "no token")
+	require.Equal(t, orig, GRPCError(orig))
+}
@@ -0,0 +1,28 @@
+package apierr
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCError конвертирует доменную ошибку в gRPC status error
+func GRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err // уже status error
+	}
+	m := Lookup(err)
+	return status.Error(m.GRPCCode, m.Message)
+}
+
+// UnaryServerInterceptor маппит ошибки хендлеров через общий реестр
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, GRPCError(err)
+	}
+}
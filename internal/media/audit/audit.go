@@ -0,0 +1,193 @@
+// Package audit — журнал аудита изменяющих запросов API: кто (actor, владелец), что
+// (метод и ручка), над каким ресурсом, с каким результатом и что именно поменялось.
+// Записи пишет middleware HTTP после ответа; diff полей медиа собирает декоратор
+// репозитория (Repository) по состоянию до и после транзакции запроса. У остальных
+// ресурсов (коллекции, доступ, служебные ручки) в записи только факт запроса.
+// Журнал не чистится удалением данных владельца: он и есть след такого удаления.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultListLimit — размер страницы List по умолчанию
+	DefaultListLimit = 50
+
+	// ResourceMedia — тип ресурса медиа
+	ResourceMedia = "media"
+)
+
+// Entry — запись журнала: один изменяющий запрос над одним ресурсом. Пакетный
+// запрос даёт по записи на ресурс с общим RequestID.
+type Entry struct {
+	ID           int64 // присваивает Store
+	At           time.Time
+	RequestID    string
+	Actor        string    // X-Actor; пустой — инициатор неизвестен
+	OwnerID      uuid.UUID // владелец вызывающего (X-Owner-ID); uuid.Nil — внутренний вызов
+	Admin        bool      // запрос со scope admin
+	Method       string
+	Endpoint     string // путь с {id} вместо идентификаторов: /media/{id}/status
+	ResourceType string // media, collection, owner...; пустой — ресурс не определён
+	ResourceID   string
+	Status       int // код ответа
+	Diff         map[string]FieldChange
+}
+
+// FieldChange — значение поля до и после запроса; null — поля не было (создание, удаление)
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Filter — выборка записей; пустые поля не ограничивают. Записи идут от новых к старым.
+type Filter struct {
+	Actor        string
+	OwnerID      uuid.UUID
+	ResourceType string
+	ResourceID   string
+	From, To     time.Time // [From, To)
+	Limit        int       // <= 0 — DefaultListLimit
+	Offset       int
+}
+
+// Store хранит журнал
+type Store interface {
+	Record(ctx context.Context, entries []Entry) error
+	List(ctx context.Context, f Filter) ([]Entry, error)
+}
+
+// Diff — поля, которые отличаются у before и after. Поля берутся по тегу db (имена как в
+// таблицах и API), значения сравниваются в JSON. nil before или after — ресурса не было.
+func Diff(before, after any) map[string]FieldChange {
+	return diff(snapshot(before), snapshot(after))
+}
+
+// fields — значения полей ресурса в JSON по тегу db; nil — ресурса нет
+type fields map[string]json.RawMessage
+
+var null = json.RawMessage("null")
+
+func snapshot(v any) fields {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	out := make(fields, rv.NumField())
+	for i := range rv.NumField() {
+		f := rv.Type().Field(i)
+		name := f.Tag.Get("db")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		raw, err := json.Marshal(rv.Field(i).Interface())
+		if err != nil {
+			continue
+		}
+		out[name] = raw
+	}
+	return out
+}
+
+func diff(before, after fields) map[string]FieldChange {
+	out := make(map[string]FieldChange)
+	for name, v := range after {
+		was, ok := before[name]
+		if !ok {
+			was = null
+		}
+		if !bytes.Equal(was, v) {
+			out[name] = FieldChange{Before: was, After: v}
+		}
+	}
+	for name, v := range before {
+		if _, ok := after[name]; !ok {
+			out[name] = FieldChange{Before: v, After: null}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// MemoryStore — Store в памяти для in-memory режима и тестов
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Record(ctx context.Context, entries []Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range entries {
+		e.ID = int64(len(s.entries) + 1)
+		s.entries = append(s.entries, e)
+	}
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, f Filter) ([]Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultListLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []Entry
+	for _, e := range slices.Backward(s.entries) {
+		if !f.matches(e) {
+			continue
+		}
+		if f.Offset > 0 {
+			f.Offset--
+			continue
+		}
+		out = append(out, e)
+		if len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (f Filter) matches(e Entry) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor,
+		f.OwnerID != uuid.Nil && e.OwnerID != f.OwnerID,
+		f.ResourceType != "" && e.ResourceType != f.ResourceType,
+		f.ResourceID != "" && e.ResourceID != f.ResourceID,
+		!f.From.IsZero() && e.At.Before(f.From),
+		!f.To.IsZero() && !e.At.Before(f.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+func newMedia() *models.Media {
+	now := time.Now().UTC()
+	return &models.Media{
+		ID:        uuid.New(),
+		Status:    models.UploadedStatus,
+		Type:      models.Video,
+		Source:    "s3://bucket/a.mp4",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := newMedia()
+	after := *before
+	after.Status = models.ReadyStatus
+	after.Title = "Intro"
+
+	d := Diff(before, &after)
+	require.Len(t, d, 2)
+	require.JSONEq(t, `"uploaded"`, string(d["status"].Before))
+	require.JSONEq(t, `"ready"`, string(d["status"].After))
+	require.JSONEq(t, `""`, string(d["title"].Before))
+
+	require.Nil(t, Diff(before, before))
+
+	// Удаление: все поля уходят в null
+	d = Diff(before, nil)
+	require.JSONEq(t, `"s3://bucket/a.mp4"`, string(d["source"].Before))
+	require.JSONEq(t, `null`, string(d["source"].After))
+}
+
+func TestRepository_TracksChanges(t *testing.T) {
+	inner := repository.NewMemoryRepository()
+	repo := NewRepository(inner)
+	ctx, tracker := Track(context.Background())
+
+	m := newMedia()
+	require.NoError(t, repo.Create(ctx, m))
+	other := newMedia()
+	require.NoError(t, inner.Create(ctx, other))
+
+	// Запись без GetForUpdate: состояние до читает декоратор
+	_, err := repo.UpdateStatus(ctx, other.ID, models.ProcessingStatus)
+	require.NoError(t, err)
+	require.NoError(t, repo.SetLastError(ctx, other.ID, "boom"))
+
+	// Откаченная транзакция в журнал не попадает
+	errRollback := errors.New("rollback")
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.GetForUpdate(ctx, m.ID); err != nil {
+			return err
+		}
+		if _, err := repo.Delete(ctx, m.ID); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	changes := tracker.Changes()
+	require.Len(t, changes, 2)
+	require.Equal(t, m.ID.String(), changes[0].ResourceID)
+	require.JSONEq(t, `null`, string(changes[0].Diff["status"].Before))
+	require.JSONEq(t, `"uploaded"`, string(changes[0].Diff["status"].After))
+
+	require.Equal(t, other.ID.String(), changes[1].ResourceID)
+	require.JSONEq(t, `"uploaded"`, string(changes[1].Diff["status"].Before))
+	require.JSONEq(t, `"processing"`, string(changes[1].Diff["status"].After))
+	require.JSONEq(t, `"boom"`, string(changes[1].Diff["last_error"].After))
+
+	// Без Tracker декоратор ничего не собирает
+	_, err = repo.UpdateStatus(context.Background(), other.ID, models.ReadyStatus)
+	require.NoError(t, err)
+	require.Len(t, tracker.Changes(), 2)
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	start := time.Now()
+	var entries []Entry
+	for i := range 5 {
+		actor := "alice"
+		if i%2 == 1 {
+			actor = "bob"
+		}
+		entries = append(entries, Entry{At: start.Add(time.Duration(i) * time.Minute), Actor: actor, Method: "PATCH", Status: 200})
+	}
+	require.NoError(t, s.Record(ctx, entries))
+
+	got, err := s.List(ctx, Filter{Actor: "alice"})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	require.Equal(t, []int64{5, 3, 1}, []int64{got[0].ID, got[1].ID, got[2].ID})
+
+	got, err = s.List(ctx, Filter{Actor: "alice", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.EqualValues(t, 3, got[0].ID)
+
+	got, err = s.List(ctx, Filter{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+}
@@ -0,0 +1,228 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// Change — изменение ресурса за запрос
+type Change struct {
+	ResourceType string
+	ResourceID   string
+	Diff         map[string]FieldChange
+}
+
+// Tracker собирает состояние ресурсов, изменённых за один запрос: первое прочитанное
+// до изменения и последнее после. Изменения откаченной транзакции забываются.
+type Tracker struct {
+	mu      sync.Mutex
+	changes []tracked // в порядке первого обращения
+}
+
+type tracked struct {
+	resourceType string
+	id           string
+	before       fields
+	after        fields
+	changed      bool // была запись; только прочитанное в журнал не попадает
+}
+
+type trackerKey struct{}
+
+// Track кладёт в контекст новый Tracker; декоратор Repository пишет в него изменения медиа
+func Track(ctx context.Context) (context.Context, *Tracker) {
+	t := &Tracker{}
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+func trackerFrom(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+// Changes — изменённые ресурсы с diff; ресурс, вернувшийся к исходному состоянию, не входит
+func (t *Tracker) Changes() []Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []Change
+	for _, c := range t.changes {
+		if !c.changed {
+			continue
+		}
+		if d := diff(c.before, c.after); d != nil {
+			out = append(out, Change{ResourceType: c.resourceType, ResourceID: c.id, Diff: d})
+		}
+	}
+	return out
+}
+
+// find возвращает запись ресурса; вызывается под t.mu
+func (t *Tracker) find(resourceType, id string) *tracked {
+	for i := range t.changes {
+		if c := &t.changes[i]; c.resourceType == resourceType && c.id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// seen запоминает состояние до изменения, если ресурс ещё не встречался
+func (t *Tracker) seen(resourceType, id string, before fields) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.find(resourceType, id) == nil {
+		t.changes = append(t.changes, tracked{resourceType: resourceType, id: id, before: before})
+	}
+}
+
+func (t *Tracker) known(resourceType, id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.find(resourceType, id) != nil
+}
+
+// wrote запоминает состояние после записи; update получает предыдущее известное состояние
+func (t *Tracker) wrote(resourceType, id string, update func(last fields) fields) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.find(resourceType, id)
+	if c == nil {
+		t.changes = append(t.changes, tracked{resourceType: resourceType, id: id})
+		c = &t.changes[len(t.changes)-1]
+	}
+	last := c.after
+	if !c.changed {
+		last = c.before
+	}
+	c.after, c.changed = update(last), true
+}
+
+func (t *Tracker) save() []tracked {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// fields не меняются после создания: копии среза достаточно
+	return append([]tracked(nil), t.changes...)
+}
+
+func (t *Tracker) restore(saved []tracked) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changes = saved
+}
+
+// Repository — декоратор MediaRepository, который пишет изменения медиа в Tracker из
+// контекста. Без Tracker (фоновые задачи, consumers) только передаёт вызовы дальше.
+// Состояние до изменения — то, что прочитал GetForUpdate; если сервис пишет без него,
+// декоратор сам читает медиа перед записью.
+type Repository struct {
+	repository.MediaRepository
+}
+
+func NewRepository(repo repository.MediaRepository) *Repository {
+	return &Repository{MediaRepository: repo}
+}
+
+type txKey struct{}
+
+// WithinTransaction забывает изменения, если транзакция откатилась (в том числе перед повтором)
+func (r *Repository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t := trackerFrom(ctx)
+	if t == nil || ctx.Value(txKey{}) != nil {
+		return r.MediaRepository.WithinTransaction(ctx, fn)
+	}
+
+	saved := t.save()
+	err := r.MediaRepository.WithinTransaction(context.WithValue(ctx, txKey{}, true), fn)
+	if err != nil {
+		t.restore(saved)
+	}
+	return err
+}
+
+func (r *Repository) Create(ctx context.Context, m *models.Media) error {
+	if err := r.MediaRepository.Create(ctx, m); err != nil {
+		return err
+	}
+	if t := trackerFrom(ctx); t != nil {
+		t.wrote(ResourceMedia, m.ID.String(), func(fields) fields { return snapshot(m) })
+	}
+	return nil
+}
+
+func (r *Repository) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	m, err := r.MediaRepository.GetForUpdate(ctx, id)
+	if err == nil {
+		if t := trackerFrom(ctx); t != nil {
+			t.seen(ResourceMedia, id.String(), snapshot(m))
+		}
+	}
+	return m, err
+}
+
+func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	r.before(ctx, id)
+	m, err := r.MediaRepository.UpdateStatus(ctx, id, status)
+	r.after(ctx, id, m, err)
+	return m, err
+}
+
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	r.before(ctx, id)
+	m, err := r.MediaRepository.Update(ctx, id, patch)
+	r.after(ctx, id, m, err)
+	return m, err
+}
+
+// Delete — после удаления медиа нет: все поля уходят в null
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	r.before(ctx, id)
+	m, err := r.MediaRepository.Delete(ctx, id)
+	r.after(ctx, id, nil, err)
+	return m, err
+}
+
+func (r *Repository) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	r.before(ctx, id)
+	err := r.MediaRepository.SetLastError(ctx, id, lastError)
+	if t := trackerFrom(ctx); t != nil && err == nil {
+		raw, _ := json.Marshal(lastError)
+		t.wrote(ResourceMedia, id.String(), func(last fields) fields {
+			next := make(fields, len(last)+1)
+			for k, v := range last {
+				next[k] = v
+			}
+			next["last_error"] = raw
+			return next
+		})
+	}
+	return err
+}
+
+// before читает медиа до первой записи, если сервис не прочитал его через GetForUpdate.
+// Ошибку чтения отдаст и сама запись, поэтому здесь она не важна.
+func (r *Repository) before(ctx context.Context, id uuid.UUID) {
+	t := trackerFrom(ctx)
+	if t == nil || t.known(ResourceMedia, id.String()) {
+		return
+	}
+	if m, err := r.MediaRepository.GetForUpdate(ctx, id); err == nil {
+		t.seen(ResourceMedia, id.String(), snapshot(m))
+	}
+}
+
+func (r *Repository) after(ctx context.Context, id uuid.UUID, m *models.Media, err error) {
+	t := trackerFrom(ctx)
+	if t == nil || err != nil {
+		return
+	}
+	t.wrote(ResourceMedia, id.String(), func(fields) fields { return snapshot(m) })
+}
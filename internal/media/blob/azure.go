@@ -0,0 +1,698 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAzureAccessTier — уровень доступа архивных blob по умолчанию
+const DefaultAzureAccessTier = "Archive"
+
+// azureVersion — версия REST API Blob service в запросах и SAS
+const azureVersion = "2021-08-06"
+
+// maxAzureBlocks — предел блоков одного blob
+const maxAzureBlocks = 50000
+
+// AzureConfig содержит конфигурацию AzureStore
+type AzureConfig struct {
+	Account string // имя storage account
+	Key     string // ключ доступа account (base64), авторизация Shared Key
+	// Endpoint — адрес Blob service (default: https://{account}.blob.core.windows.net);
+	// для Azurite — http://127.0.0.1:10000/devstoreaccount1
+	Endpoint string
+	// ArchiveContainer — контейнер холодного хранилища: Archive копирует blob туда с тем же
+	// именем. Пустой — blob остаётся на месте и только меняет уровень доступа.
+	ArchiveContainer string
+	// AccessTier — уровень доступа архивного blob: Cool, Cold, Archive (default: Archive)
+	AccessTier string
+	// PartSize — блок Put Block для blob больше него и часть Download (default: DefaultPartSize)
+	PartSize    int64
+	Concurrency int // блоков или частей одного blob одновременно (default: DefaultConcurrency)
+	// CopyPollInterval — как часто проверяется асинхронное копирование в ArchiveContainer (default: 2s)
+	CopyPollInterval time.Duration
+	HTTPClient       *http.Client // default: timeout 30s; ограничивает и один запрос блока
+}
+
+// AzureStore — Store поверх REST API Azure Blob Storage (авторизация Shared Key)
+type AzureStore struct {
+	endpoint *url.URL
+	config   AzureConfig
+	key      []byte
+	client   *http.Client
+	clock    func() time.Time
+}
+
+func NewAzureStore(cfg AzureConfig) (*AzureStore, error) {
+	if cfg.Account == "" {
+		return nil, errors.New("azure storage account is required")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("azure storage key must be a non-empty base64 string")
+	}
+	if cfg.PartSize != 0 && cfg.PartSize < MinPartSize {
+		return nil, fmt.Errorf("azure part size must be at least %d bytes, got: %d", MinPartSize, cfg.PartSize)
+	}
+	if cfg.Concurrency < 0 {
+		return nil, fmt.Errorf("azure concurrency cannot be negative, got: %d", cfg.Concurrency)
+	}
+	if cfg.CopyPollInterval < 0 {
+		return nil, fmt.Errorf("azure copy poll interval cannot be negative, got: %v", cfg.CopyPollInterval)
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://" + cfg.Account + ".blob.core.windows.net"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid azure blob endpoint %q", cfg.Endpoint)
+	}
+	if cfg.AccessTier == "" {
+		cfg.AccessTier = DefaultAzureAccessTier
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	if cfg.CopyPollInterval == 0 {
+		cfg.CopyPollInterval = 2 * time.Second
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	return &AzureStore{endpoint: endpoint, config: cfg, key: key, client: client, clock: time.Now}, nil
+}
+
+// AzureObject — blob, разобранный из source
+type AzureObject struct {
+	Container string
+	Blob      string
+}
+
+func (o AzureObject) String() string { return "azure://" + o.Container + "/" + o.Blob }
+
+// ParseAzure разбирает source вида azure://container/blob; account задаёт конфигурация
+func ParseAzure(source string) (AzureObject, error) {
+	rest, ok := strings.CutPrefix(source, "azure://")
+	if !ok {
+		return AzureObject{}, fmt.Errorf("%w: %q", ErrUnsupportedSource, source)
+	}
+	container, name, ok := strings.Cut(rest, "/")
+	if !ok || container == "" || name == "" {
+		return AzureObject{}, fmt.Errorf("%w: %q has no container or blob", ErrUnsupportedSource, source)
+	}
+	return AzureObject{Container: container, Blob: name}, nil
+}
+
+// Archive переводит blob в AccessTier: на месте (ArchiveContainer пустой) или копированием
+// в ArchiveContainer с удалением оригинала. Уже перенесённый blob повторно не копируется.
+func (s *AzureStore) Archive(ctx context.Context, source string) (string, error) {
+	src, err := ParseAzure(source)
+	if err != nil {
+		return "", err
+	}
+	info, srcFound, err := s.head(ctx, src)
+	if err != nil {
+		return "", err
+	}
+
+	if s.config.ArchiveContainer == "" || s.config.ArchiveContainer == src.Container {
+		if !srcFound {
+			return "", fmt.Errorf("archive %s: blob not found", src)
+		}
+		if info.class != s.config.AccessTier {
+			if err := s.setTier(ctx, src, s.config.AccessTier); err != nil {
+				return "", err
+			}
+		}
+		return src.String(), nil
+	}
+
+	dst := AzureObject{Container: s.config.ArchiveContainer, Blob: src.Blob}
+	if !srcFound {
+		// Предыдущий запуск успел перенести blob и удалить оригинал
+		_, dstFound, err := s.head(ctx, dst)
+		if err != nil {
+			return "", err
+		}
+		if !dstFound {
+			return "", fmt.Errorf("archive %s: blob not found", src)
+		}
+		return dst.String(), nil
+	}
+	if err := s.copy(ctx, src, dst, s.config.AccessTier); err != nil {
+		return "", err
+	}
+	if err := s.Delete(ctx, source); err != nil {
+		return "", err
+	}
+	return dst.String(), nil
+}
+
+// Copy копирует blob src в dst внутри account и ждёт конца асинхронного копирования
+func (s *AzureStore) Copy(ctx context.Context, src, dst string) error {
+	from, err := ParseAzure(src)
+	if err != nil {
+		return err
+	}
+	to, err := ParseAzure(dst)
+	if err != nil {
+		return err
+	}
+	return s.copy(ctx, from, to, "")
+}
+
+// Delete удаляет blob вместе со snapshot'ами; отсутствующий blob не ошибка
+func (s *AzureStore) Delete(ctx context.Context, source string) error {
+	obj, err := ParseAzure(source)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, obj, nil, map[string]string{"x-ms-delete-snapshots": "include"}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && resp.StatusCode/100 != 2 {
+		return azureError("delete", obj, resp)
+	}
+	return nil
+}
+
+// azureListPage — страница ответа List Blobs
+type azureListPage struct {
+	Names      []string `xml:"Blobs>Blob>Name"`
+	NextMarker string   `xml:"NextMarker"`
+}
+
+// DeletePrefix удаляет blob под префиксом azure://container/prefix/ (см. S3Store.DeletePrefix)
+func (s *AzureStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	rest, ok := strings.CutPrefix(prefix, "azure://")
+	container, name, _ := strings.Cut(rest, "/")
+	if !ok || container == "" || name == "" || !strings.HasSuffix(name, "/") {
+		return 0, fmt.Errorf("%w: prefix %q must be azure://container/path/", ErrUnsupportedSource, prefix)
+	}
+
+	deleted := 0
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {name}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		obj := AzureObject{Container: container}
+		resp, err := s.do(ctx, http.MethodGet, obj, query, nil, nil)
+		if err != nil {
+			return deleted, err
+		}
+		var page azureListPage
+		if resp.StatusCode/100 != 2 {
+			err = azureError("list", obj, resp)
+		} else if decodeErr := decodeXML(resp.Body, &page); decodeErr != nil {
+			err = fmt.Errorf("azure list %s: decode response: %w", obj, decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, n := range page.Names {
+			if err := s.Delete(ctx, AzureObject{Container: container, Blob: n}.String()); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if page.NextMarker == "" {
+			return deleted, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// Put загружает blob потоком из body; size — точная длина тела. Blob до PartSize
+// загружается одним Put Blob, больший — блоками (Put Block, Concurrency одновременно),
+// которые фиксируются Put Block List. Незафиксированные блоки Azure удаляет сам,
+// поэтому оборванная загрузка не оставляет blob.
+func (s *AzureStore) Put(ctx context.Context, source string, body io.Reader, size int64, contentType string) error {
+	obj, err := ParseAzure(source)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("azure put %s: size is required", obj)
+	}
+	if size > s.config.PartSize {
+		return s.putBlocks(ctx, obj, body, size, contentType)
+	}
+
+	headers := map[string]string{"x-ms-blob-type": "BlockBlob"}
+	if contentType != "" {
+		headers["x-ms-blob-content-type"] = contentType
+	}
+	req, err := s.request(ctx, http.MethodPut, obj, nil, headers, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	s.sign(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("azure put %s: %w", obj, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return azureError("put", obj, resp)
+	}
+	return nil
+}
+
+// putBlocks читает body блоками и загружает их параллельно, затем фиксирует список блоков.
+// Блок в памяти, поэтому оборванный запрос повторяется; в памяти не больше Concurrency блоков.
+func (s *AzureStore) putBlocks(ctx context.Context, obj AzureObject, body io.Reader, size int64, contentType string) error {
+	parts := splitParts(size, max(s.config.PartSize, (size+maxAzureBlocks-1)/maxAzureBlocks))
+	ids := make([]string, len(parts))
+	for i := range parts {
+		// Все id блоков одного blob одной длины
+		ids[i] = base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "block-%06d", i))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+	buffers := make(chan []byte, s.config.Concurrency)
+	for range s.config.Concurrency {
+		buffers <- nil
+	}
+	for i, p := range parts {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if int64(cap(buf)) < p.size {
+			buf = make([]byte, p.size)
+		}
+		buf = buf[:p.size]
+		if _, err := io.ReadFull(body, buf); err != nil {
+			fail(fmt.Errorf("azure put %s: read body: %w", obj, err))
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { buffers <- buf }()
+			if err := s.putBlock(ctx, obj, ids[i], buf); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.putBlockList(ctx, obj, ids, contentType)
+}
+
+// putBlock загружает блок id, повторяя запрос, оборванный сетью или ошибкой 5xx
+func (s *AzureStore) putBlock(ctx context.Context, obj AzureObject, id string, data []byte) error {
+	query := url.Values{"comp": {"block"}, "blockid": {id}}
+	var err error
+	for range maxPartAttempts {
+		var resp *http.Response
+		resp, err = s.do(ctx, http.MethodPut, obj, query, nil, data)
+		if err == nil {
+			if resp.StatusCode/100 == 2 {
+				resp.Body.Close()
+				return nil
+			}
+			err = azureError("put block", obj, resp)
+			resp.Body.Close()
+			if resp.StatusCode < http.StatusInternalServerError {
+				return err
+			}
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// putBlockList собирает blob из блоков ids по порядку
+func (s *AzureStore) putBlockList(ctx context.Context, obj AzureObject, ids []string, contentType string) error {
+	list := struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string `xml:"Latest"`
+	}{Latest: ids}
+	data, err := xml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/xml"}
+	if contentType != "" {
+		headers["x-ms-blob-content-type"] = contentType
+	}
+	req, err := s.request(ctx, http.MethodPut, obj, url.Values{"comp": {"blocklist"}}, headers, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	s.sign(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("azure put block list %s: %w", obj, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return azureError("put block list", obj, resp)
+	}
+	return nil
+}
+
+// Open открывает blob на чтение; вызывающий закрывает тело
+func (s *AzureStore) Open(ctx context.Context, source string) (io.ReadCloser, error) {
+	obj, err := ParseAzure(source)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, obj, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		err := azureError("get", obj, resp)
+		if resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Download скачивает blob source в w частями по PartSize, Concurrency частей одновременно.
+// Части читаются с If-Match на ETag: перезаписанный во время скачивания blob даёт ошибку.
+func (s *AzureStore) Download(ctx context.Context, source string, w io.WriterAt) (int64, error) {
+	obj, err := ParseAzure(source)
+	if err != nil {
+		return 0, err
+	}
+	info, found, err := s.head(ctx, obj)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: azure download %s", ErrNotFound, obj)
+	}
+	if info.size == 0 {
+		return 0, nil
+	}
+
+	err = forEachPart(ctx, splitParts(info.size, s.config.PartSize), s.config.Concurrency, func(ctx context.Context, p part) error {
+		headers := map[string]string{"x-ms-range": byteRange(p)}
+		if info.etag != "" {
+			headers["If-Match"] = info.etag
+		}
+		resp, err := s.do(ctx, http.MethodGet, obj, nil, headers, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent {
+			return azureError("get part "+strconv.Itoa(p.number), obj, resp)
+		}
+		if err := writePart(w, p, resp.Body); err != nil {
+			return fmt.Errorf("azure get part %d %s: %w", p.number, obj, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return info.size, nil
+}
+
+// Presign возвращает SAS URL на чтение blob, действующий ttl (service SAS, подписанный
+// ключом account). Запросов к Azure не делает.
+func (s *AzureStore) Presign(source string, ttl time.Duration) (string, error) {
+	obj, err := ParseAzure(source)
+	if err != nil {
+		return "", err
+	}
+	if ttl < time.Second || ttl > MaxPresignTTL {
+		return "", fmt.Errorf("presign ttl must be between 1s and %v, got: %v", MaxPresignTTL, ttl)
+	}
+	expiry := s.clock().UTC().Add(ttl).Format(time.RFC3339)
+	protocol := ""
+	if s.endpoint.Scheme == "https" {
+		protocol = "https"
+	}
+	// Поля подписи service SAS версии 2020-12-06 и новее; пустые — не заданы
+	stringToSign := strings.Join([]string{
+		"r", // signedPermissions
+		"",  // signedStart
+		expiry,
+		"/blob/" + s.config.Account + "/" + obj.Container + "/" + obj.Blob,
+		"", // signedIdentifier
+		"", // signedIP
+		protocol,
+		azureVersion,
+		"b", // signedResource
+		"",  // signedSnapshotTime
+		"",  // signedEncryptionScope
+		"",  // rscc
+		"",  // rscd
+		"",  // rsce
+		"",  // rscl
+		"",  // rsct
+	}, "\n")
+
+	query := url.Values{
+		"sv":  {azureVersion},
+		"sr":  {"b"},
+		"sp":  {"r"},
+		"se":  {expiry},
+		"sig": {s.hmac(stringToSign)},
+	}
+	if protocol != "" {
+		query.Set("spr", protocol)
+	}
+	u := s.url(obj)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// head возвращает сведения о blob; class — его уровень доступа
+func (s *AzureStore) head(ctx context.Context, obj AzureObject) (objectInfo, bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, obj, nil, nil, nil)
+	if err != nil {
+		return objectInfo{}, false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return objectInfo{}, false, nil
+	case resp.StatusCode/100 != 2:
+		return objectInfo{}, false, azureError("head", obj, resp)
+	}
+	return objectInfo{
+		class:       resp.Header.Get("x-ms-access-tier"),
+		size:        resp.ContentLength,
+		contentType: resp.Header.Get("Content-Type"),
+		etag:        resp.Header.Get("ETag"),
+	}, true, nil
+}
+
+// setTier меняет уровень доступа blob на месте
+func (s *AzureStore) setTier(ctx context.Context, obj AzureObject, tier string) error {
+	resp, err := s.do(ctx, http.MethodPut, obj, url.Values{"comp": {"tier"}}, map[string]string{"x-ms-access-tier": tier}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return azureError("set tier", obj, resp)
+	}
+	return nil
+}
+
+// copy копирует src в dst (Copy Blob) с уровнем доступа tier (пустой — по умолчанию account)
+// и ждёт, пока асинхронное копирование завершится
+func (s *AzureStore) copy(ctx context.Context, src, dst AzureObject, tier string) error {
+	headers := map[string]string{"x-ms-copy-source": s.url(src).String()}
+	if tier != "" {
+		headers["x-ms-access-tier"] = tier
+	}
+	resp, err := s.do(ctx, http.MethodPut, dst, nil, headers, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return azureError("copy", src, resp)
+	}
+
+	status := resp.Header.Get("x-ms-copy-status")
+	for status == "pending" {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("azure copy %s: %w", src, ctx.Err())
+		case <-time.After(s.config.CopyPollInterval):
+		}
+		resp, err := s.do(ctx, http.MethodHead, dst, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return azureError("copy status", dst, resp)
+		}
+		status = resp.Header.Get("x-ms-copy-status")
+	}
+	if status != "success" {
+		return fmt.Errorf("azure copy %s: copy status %q", src, status)
+	}
+	return nil
+}
+
+// do выполняет подписанный запрос
+func (s *AzureStore) do(ctx context.Context, method string, obj AzureObject, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	req, err := s.request(ctx, method, obj, query, headers, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		req.Body, req.ContentLength = http.NoBody, 0
+	}
+	s.sign(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("azure %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	return resp, nil
+}
+
+// request собирает запрос к blob (к контейнеру, если Blob пустой)
+func (s *AzureStore) request(ctx context.Context, method string, obj AzureObject, query url.Values, headers map[string]string, body io.Reader) (*http.Request, error) {
+	u := s.url(obj)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("azure %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// url — адрес blob (контейнера, если Blob пустой)
+func (s *AzureStore) url(obj AzureObject) *url.URL {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + obj.Container
+	u.RawPath = s.endpoint.Path + "/" + awsEscape(obj.Container)
+	if obj.Blob != "" {
+		u.Path += "/" + obj.Blob
+		u.RawPath += "/" + awsEscape(obj.Blob)
+	}
+	return &u
+}
+
+// sign подписывает запрос Shared Key: подписываются стандартные заголовки, все x-ms-*
+// и путь с параметрами запроса
+func (s *AzureStore) sign(req *http.Request) {
+	req.Header.Set("x-ms-date", s.clock().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureVersion)
+
+	var msHeaders []string
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k+":"+strings.TrimSpace(strings.Join(v, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + s.config.Account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for k := range query {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		values := query[k]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(values, ",")
+	}
+
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	h := req.Header
+	stringToSign := strings.Join([]string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		length,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // Date: задан x-ms-date
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+	}, "\n") + "\n" + strings.Join(msHeaders, "\n") + "\n" + resource
+
+	req.Header.Set("Authorization", "SharedKey "+s.config.Account+":"+s.hmac(stringToSign))
+}
+
+// hmac — подпись data ключом account (base64)
+func (s *AzureStore) hmac(data string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func azureError(op string, obj AzureObject, resp *http.Response) error {
+	return storageError("azure", op, obj, resp)
+}
@@ -0,0 +1,361 @@
+package blob
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testAzureAccount = "devstoreaccount1"
+
+// azureBlob — blob fakeAzure
+type azureBlob struct {
+	tier, contentType, data string
+	etag                    int
+	copyPending             bool // Copy Blob ещё идёт: следующий HEAD сообщит success
+}
+
+// fakeAzure — Blob service в стиле Azurite (account в пути): Put Blob, Put Block и Put Block List,
+// GET (с x-ms-range), HEAD, Set Blob Tier, асинхронный Copy Blob, DELETE и List Blobs
+type fakeAzure struct {
+	mu        sync.Mutex
+	blobs     map[string]azureBlob         // /container/blob
+	blocks    map[string]map[string]string // /container/blob → id блока → данные
+	failBlock int                          // первая попытка блока с этим номером отвечает 500
+	failed    bool
+	requests  []string
+}
+
+func newFakeAzure(t *testing.T) (*fakeAzure, *AzureStore) {
+	t.Helper()
+	f := &fakeAzure{blobs: map[string]azureBlob{}, blocks: map[string]map[string]string{}, failBlock: -1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	store, err := NewAzureStore(AzureConfig{
+		Account:          testAzureAccount,
+		Key:              base64.StdEncoding.EncodeToString([]byte("secret")),
+		Endpoint:         srv.URL + "/" + testAzureAccount,
+		CopyPollInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	return f, store
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+" "+q.Get("comp"))
+
+	sas := q.Get("sig") != "" && q.Get("sp") == "r"
+	if !sas && (!strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey "+testAzureAccount+":") ||
+		r.Header.Get("X-Ms-Date") == "" || r.Header.Get("X-Ms-Version") != azureVersion) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/"+testAzureAccount)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	b, found := f.blobs[path]
+	switch {
+	case r.Method == http.MethodGet && q.Get("comp") == "list":
+		f.list(w, strings.Trim(path, "/"), q.Get("prefix"), q.Get("marker"))
+	case r.Method == http.MethodGet:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><Error><Code>BlobNotFound</Code></Error>`))
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && m != strconv.Itoa(b.etag) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		r.Header.Del("If-Match")
+		if rng := r.Header.Get("X-Ms-Range"); rng != "" {
+			r.Header.Set("Range", rng)
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(b.data))
+	case r.Method == http.MethodHead:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("x-ms-access-tier", b.tier)
+		w.Header().Set("ETag", strconv.Itoa(b.etag))
+		w.Header().Set("Content-Type", b.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(b.data)))
+		w.Header().Set("x-ms-copy-status", "success")
+		if b.copyPending {
+			w.Header().Set("x-ms-copy-status", "pending")
+			b.copyPending = false
+			f.blobs[path] = b
+		}
+	case r.Method == http.MethodPut && q.Get("comp") == "block":
+		number, _ := strconv.Atoi(strings.TrimPrefix(decodeBlockID(q.Get("blockid")), "block-"))
+		if number == f.failBlock && !f.failed {
+			f.failed = true
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if f.blocks[path] == nil {
+			f.blocks[path] = map[string]string{}
+		}
+		f.blocks[path][q.Get("blockid")] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && q.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		_ = xml.NewDecoder(r.Body).Decode(&list)
+		var data strings.Builder
+		for _, id := range list.Latest {
+			block, ok := f.blocks[path][id]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data.WriteString(block)
+		}
+		delete(f.blocks, path)
+		f.put(path, azureBlob{tier: "Hot", contentType: r.Header.Get("X-Ms-Blob-Content-Type"), data: data.String()})
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && q.Get("comp") == "tier":
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b.tier = r.Header.Get("X-Ms-Access-Tier")
+		f.blobs[path] = b
+	case r.Method == http.MethodPut && r.Header.Get("X-Ms-Copy-Source") != "":
+		u, err := url.Parse(r.Header.Get("X-Ms-Copy-Source"))
+		from, ok := f.blobs[strings.TrimPrefix(u.Path, "/"+testAzureAccount)]
+		if err != nil || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><Error><Code>CannotVerifyCopySource</Code></Error>`))
+			return
+		}
+		from.tier, from.copyPending = r.Header.Get("X-Ms-Access-Tier"), true
+		f.put(path, from)
+		w.Header().Set("x-ms-copy-status", "pending")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && r.Header.Get("X-Ms-Blob-Type") == "BlockBlob":
+		body, _ := io.ReadAll(r.Body)
+		f.put(path, azureBlob{tier: "Hot", contentType: r.Header.Get("X-Ms-Blob-Content-Type"), data: string(body)})
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, path)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeAzure) put(path string, b azureBlob) {
+	b.etag = f.blobs[path].etag + 1
+	f.blobs[path] = b
+}
+
+func decodeBlockID(id string) string {
+	data, _ := base64.StdEncoding.DecodeString(id)
+	return string(data)
+}
+
+// list отвечает List Blobs страницами по два имени; marker — последнее отданное имя
+func (f *fakeAzure) list(w http.ResponseWriter, container, prefix, after string) {
+	var names []string
+	for path := range f.blobs {
+		if name, ok := strings.CutPrefix(path, "/"+container+"/"); ok && strings.HasPrefix(name, prefix) && name > after {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	next := ""
+	if len(names) > 2 {
+		names, next = names[:2], names[1]
+	}
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+	for _, n := range names {
+		body.WriteString("<Blob><Name>" + n + "</Name></Blob>")
+	}
+	body.WriteString("</Blobs><NextMarker>" + next + "</NextMarker></EnumerationResults>")
+	_, _ = w.Write([]byte(body.String()))
+}
+
+func TestParseAzure(t *testing.T) {
+	obj, err := ParseAzure("azure://media/videos/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, AzureObject{Container: "media", Blob: "videos/a.mp4"}, obj)
+
+	for _, source := range []string{"gs://media/a.mp4", "azure://media", "azure:///a.mp4"} {
+		_, err := ParseAzure(source)
+		require.ErrorIs(t, err, ErrUnsupportedSource, source)
+	}
+}
+
+func TestAzureStore_PutAndOpen(t *testing.T) {
+	fake, store := newFakeAzure(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "azure://media/a b.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+	require.Equal(t, azureBlob{tier: "Hot", contentType: "video/mp4", data: "frames", etag: 1}, fake.blobs["/media/a b.mp4"])
+
+	body, err := store.Open(ctx, "azure://media/a b.mp4")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "frames", string(data))
+
+	_, err = store.Open(ctx, "azure://media/missing.mp4")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAzureStore_PutBlocks(t *testing.T) {
+	fake, store := newFakeAzure(t)
+	store.config.PartSize, store.config.Concurrency = 4, 2
+	ctx := context.Background()
+
+	// Сбой блока повторяется, блоки собираются по порядку
+	fake.failBlock = 1
+	require.NoError(t, store.Put(ctx, "azure://media/master.mov", strings.NewReader("0123456789"), 10, "video/quicktime"))
+	require.True(t, fake.failed)
+	require.Equal(t, azureBlob{tier: "Hot", contentType: "video/quicktime", data: "0123456789", etag: 1}, fake.blobs["/media/master.mov"])
+	require.Empty(t, fake.blocks)
+
+	// Тело короче заявленного: список блоков не фиксируется, blob не создаётся
+	err := store.Put(ctx, "azure://media/short.mov", strings.NewReader("01234"), 10, "video/quicktime")
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.NotContains(t, fake.blobs, "/media/short.mov")
+}
+
+func TestAzureStore_Archive(t *testing.T) {
+	fake, store := newFakeAzure(t)
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "azure://media/a.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+
+	// На месте — смена уровня доступа
+	location, err := store.Archive(ctx, "azure://media/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, "azure://media/a.mp4", location)
+	require.Equal(t, DefaultAzureAccessTier, fake.blobs["/media/a.mp4"].tier)
+
+	// В архивный контейнер — асинхронная копия, затем удаление оригинала; повтор находит перенесённый blob
+	store.config.ArchiveContainer = "media-cold"
+	require.NoError(t, store.Put(ctx, "azure://media/b.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+	for range 2 {
+		location, err = store.Archive(ctx, "azure://media/b.mp4")
+		require.NoError(t, err)
+		require.Equal(t, "azure://media-cold/b.mp4", location)
+	}
+	require.NotContains(t, fake.blobs, "/media/b.mp4")
+	require.Equal(t, azureBlob{tier: DefaultAzureAccessTier, contentType: "video/mp4", data: "frames", etag: 1}, fake.blobs["/media-cold/b.mp4"])
+
+	_, err = store.Archive(ctx, "azure://media/missing.mp4")
+	require.Error(t, err)
+}
+
+func TestAzureStore_DeleteAndDeletePrefix(t *testing.T) {
+	fake, store := newFakeAzure(t)
+	ctx := context.Background()
+	for _, name := range []string{"vod/1/a.ts", "vod/1/b.ts", "vod/1/master.m3u8", "vod/10/a.ts"} {
+		require.NoError(t, store.Put(ctx, "azure://stream/"+name, strings.NewReader("x"), 1, ""))
+	}
+
+	n, err := store.DeletePrefix(ctx, "azure://stream/vod/1/")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Len(t, fake.blobs, 1)
+	require.Contains(t, fake.blobs, "/stream/vod/10/a.ts")
+
+	require.NoError(t, store.Delete(ctx, "azure://stream/vod/10/a.ts"))
+	require.NoError(t, store.Delete(ctx, "azure://stream/vod/10/a.ts"))
+	require.Empty(t, fake.blobs)
+
+	_, err = store.DeletePrefix(ctx, "azure://stream/vod/1")
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func TestAzureStore_DownloadInParts(t *testing.T) {
+	fake, store := newFakeAzure(t)
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "azure://media/master.mov", strings.NewReader("0123456789"), 10, "video/quicktime"))
+	store.config.PartSize, store.config.Concurrency = 4, 2
+
+	var buf offsetBuffer
+	n, err := store.Download(ctx, "azure://media/master.mov", &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), n)
+	require.Equal(t, "0123456789", string(buf.data))
+	gets := slices.DeleteFunc(slices.Clone(fake.requests), func(r string) bool { return !strings.HasPrefix(r, "GET ") })
+	require.Len(t, gets, 3, "three ranged parts")
+
+	_, err = store.Download(ctx, "azure://media/missing.mov", &buf)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAzureStore_Presign(t *testing.T) {
+	_, store := newFakeAzure(t)
+	store.clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "azure://media/videos/a b.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+
+	link, err := store.Presign("azure://media/videos/a b.mp4", time.Hour)
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, "/"+testAzureAccount+"/media/videos/a%20b.mp4", u.EscapedPath())
+	q := u.Query()
+	require.Equal(t, "r", q.Get("sp"))
+	require.Equal(t, "b", q.Get("sr"))
+	require.Equal(t, "2024-01-02T04:04:05Z", q.Get("se"))
+	require.Empty(t, q.Get("spr"), "http endpoint")
+	require.NotEmpty(t, q.Get("sig"))
+
+	// Ссылка открывается без заголовков авторизации
+	resp, err := http.Get(link)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "frames", string(data))
+
+	_, err = store.Presign("s3://media/a.mp4", time.Hour)
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func TestNewAzureStore_Validation(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("secret"))
+	for name, cfg := range map[string]AzureConfig{
+		"no account":       {Key: key},
+		"bad key":          {Account: "a", Key: "not base64!"},
+		"bad endpoint":     {Account: "a", Key: key, Endpoint: "localhost:10000"},
+		"small part":       {Account: "a", Key: key, PartSize: 1 << 20},
+		"negative workers": {Account: "a", Key: key, Concurrency: -1},
+		"negative poll":    {Account: "a", Key: key, CopyPollInterval: -time.Second},
+	} {
+		_, err := NewAzureStore(cfg)
+		require.Error(t, err, name)
+	}
+}
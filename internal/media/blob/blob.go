@@ -0,0 +1,94 @@
+// Package blob — операции над исходниками медиа в объектном хранилище, которые нужны
+// жизненному циклу: перенос в холодное хранилище, удаление и чтение для скачивания.
+// Загрузку исходников делает ingest.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedSource — хранилище не умеет работать с таким source (другая схема URL)
+	ErrUnsupportedSource = errors.New("unsupported blob source")
+	// ErrNotFound — объекта по source нет
+	ErrNotFound = errors.New("blob not found")
+)
+
+// Store переносит и удаляет исходники по их source (s3://bucket/key, gs://bucket/key,
+// azure://container/blob, file://path).
+// Обе операции идемпотентны: повтор после сбоя не должен падать.
+type Store interface {
+	// Archive переносит объект в холодное хранилище и возвращает его новое расположение
+	// (тот же source, если объект только сменил класс хранения)
+	Archive(ctx context.Context, source string) (string, error)
+	// Delete удаляет объект; отсутствующий объект не ошибка
+	Delete(ctx context.Context, source string) error
+}
+
+// PrefixDeleter удаляет все объекты под префиксом (renditions и манифесты медиа).
+// Идемпотентен: пустой префикс — не ошибка, возвращается 0.
+type PrefixDeleter interface {
+	// DeletePrefix удаляет объекты, source которых начинается с prefix, и возвращает их число
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// Downloader скачивает объект целиком частями параллельно — для больших исходников
+// (мастер-файлы в десятки ГБ), которые одним потоком не выбирают полосу сети
+type Downloader interface {
+	// Download пишет объект source в w и возвращает его размер
+	Download(ctx context.Context, source string, w io.WriterAt) (int64, error)
+}
+
+// Copier копирует объект внутри хранилища, не пропуская данные через сервис
+type Copier interface {
+	Copy(ctx context.Context, src, dst string) error
+}
+
+// Reader открывает исходник на чтение; вызывающий закрывает тело
+type Reader interface {
+	Open(ctx context.Context, source string) (io.ReadCloser, error)
+}
+
+// Readers выбирает Reader по схеме source: {"s3": s3Store, "file": localStore}
+type Readers map[string]Reader
+
+func (r Readers) Open(ctx context.Context, source string) (io.ReadCloser, error) {
+	scheme, _, ok := strings.Cut(source, "://")
+	reader, found := r[scheme]
+	if !ok || !found {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedSource, source)
+	}
+	return reader.Open(ctx, source)
+}
+
+// NopStore ничего не делает с объектами: ими управляют правила lifecycle самого бакета,
+// а сервис только меняет состояние медиа. Archive возвращает source без изменений.
+type NopStore struct{}
+
+func (NopStore) Archive(ctx context.Context, source string) (string, error) { return source, ctx.Err() }
+func (NopStore) Delete(ctx context.Context, source string) error            { return ctx.Err() }
+
+// Object — объект S3, разобранный из source
+type Object struct {
+	Bucket string
+	Key    string
+}
+
+func (o Object) String() string { return "s3://" + o.Bucket + "/" + o.Key }
+
+// ParseS3 разбирает source вида s3://bucket/key
+func ParseS3(source string) (Object, error) {
+	rest, ok := strings.CutPrefix(source, "s3://")
+	if !ok {
+		return Object{}, fmt.Errorf("%w: %q", ErrUnsupportedSource, source)
+	}
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return Object{}, fmt.Errorf("%w: %q has no bucket or key", ErrUnsupportedSource, source)
+	}
+	return Object{Bucket: bucket, Key: key}, nil
+}
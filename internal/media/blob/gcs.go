@@ -0,0 +1,607 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultGCSStorageClass — класс хранения архивных объектов GCS по умолчанию
+const DefaultGCSStorageClass = "ARCHIVE"
+
+// gcsChunkAlign — кусок resumable upload, кроме последнего, должен быть кратен 256 КиБ
+const gcsChunkAlign = 256 << 10
+
+// GCSConfig содержит конфигурацию GCSStore
+type GCSConfig struct {
+	// Endpoint — адрес XML API (default: https://storage.googleapis.com); запросы path-style
+	Endpoint string
+	// AccessID и Secret — HMAC ключ сервисного аккаунта (Cloud Storage → Settings → Interoperability)
+	AccessID string
+	Secret   string
+	Region   string // регион в подписи (default: auto)
+	// ArchiveBucket — бакет холодного хранилища: Archive переносит объект туда с тем же ключом.
+	// Пустой — объект остаётся на месте и только меняет класс хранения.
+	ArchiveBucket string
+	// StorageClass — класс хранения архивного объекта (default: ARCHIVE)
+	StorageClass string
+	// PartSize — часть объекта для Download (ranged GET) и кусок resumable upload в Put
+	// (default: DefaultPartSize; не меньше MinPartSize и кратна 256 КиБ). Объект больше
+	// части загружается resumable upload: оборванный кусок дозагружается с того места,
+	// которое GCS успел сохранить.
+	PartSize    int64
+	Concurrency int          // частей одного объекта, скачиваемых одновременно (default: DefaultConcurrency)
+	HTTPClient  *http.Client // default: timeout 30s; ограничивает и один запрос части
+}
+
+// GCSStore — Store поверх XML API Google Cloud Storage (подпись V4 по HMAC ключу)
+type GCSStore struct {
+	endpoint *url.URL
+	config   GCSConfig
+	client   *http.Client
+	clock    func() time.Time
+}
+
+func NewGCSStore(cfg GCSConfig) (*GCSStore, error) {
+	if cfg.AccessID == "" || cfg.Secret == "" {
+		return nil, errors.New("gcs hmac credentials are required")
+	}
+	if cfg.PartSize != 0 && (cfg.PartSize < MinPartSize || cfg.PartSize%gcsChunkAlign != 0) {
+		return nil, fmt.Errorf("gcs part size must be at least %d bytes and a multiple of %d, got: %d", MinPartSize, gcsChunkAlign, cfg.PartSize)
+	}
+	if cfg.Concurrency < 0 {
+		return nil, fmt.Errorf("gcs concurrency cannot be negative, got: %d", cfg.Concurrency)
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://storage.googleapis.com"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid gcs endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "auto"
+	}
+	if cfg.StorageClass == "" {
+		cfg.StorageClass = DefaultGCSStorageClass
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	return &GCSStore{
+		endpoint: endpoint,
+		config:   cfg,
+		client:   client,
+		clock:    time.Now,
+	}, nil
+}
+
+// GCSObject — объект GCS, разобранный из source
+type GCSObject struct {
+	Bucket string
+	Key    string
+}
+
+func (o GCSObject) String() string { return "gs://" + o.Bucket + "/" + o.Key }
+
+// ParseGCS разбирает source вида gs://bucket/key
+func ParseGCS(source string) (GCSObject, error) {
+	rest, ok := strings.CutPrefix(source, "gs://")
+	if !ok {
+		return GCSObject{}, fmt.Errorf("%w: %q", ErrUnsupportedSource, source)
+	}
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return GCSObject{}, fmt.Errorf("%w: %q has no bucket or key", ErrUnsupportedSource, source)
+	}
+	return GCSObject{Bucket: bucket, Key: key}, nil
+}
+
+// Archive переносит объект в холодное хранилище копированием с новым классом хранения:
+// на место (ArchiveBucket пустой) или в ArchiveBucket с удалением оригинала.
+// Уже перенесённый объект повторно не копируется.
+func (s *GCSStore) Archive(ctx context.Context, source string) (string, error) {
+	src, err := ParseGCS(source)
+	if err != nil {
+		return "", err
+	}
+	dst := src
+	if s.config.ArchiveBucket != "" {
+		dst.Bucket = s.config.ArchiveBucket
+	}
+
+	info, srcFound, err := s.head(ctx, src)
+	if err != nil {
+		return "", err
+	}
+	if dst == src {
+		if !srcFound {
+			return "", fmt.Errorf("archive %s: object not found", src)
+		}
+		if info.class == s.config.StorageClass {
+			return src.String(), nil
+		}
+		if err := s.copy(ctx, src, dst, s.config.StorageClass); err != nil {
+			return "", err
+		}
+		return dst.String(), nil
+	}
+
+	if !srcFound {
+		// Предыдущий запуск успел перенести объект и удалить оригинал
+		_, dstFound, err := s.head(ctx, dst)
+		if err != nil {
+			return "", err
+		}
+		if !dstFound {
+			return "", fmt.Errorf("archive %s: object not found", src)
+		}
+		return dst.String(), nil
+	}
+	if err := s.copy(ctx, src, dst, s.config.StorageClass); err != nil {
+		return "", err
+	}
+	if err := s.Delete(ctx, source); err != nil {
+		return "", err
+	}
+	return dst.String(), nil
+}
+
+// Copy копирует объект src в dst внутри GCS, не пропуская данные через сервис
+func (s *GCSStore) Copy(ctx context.Context, src, dst string) error {
+	from, err := ParseGCS(src)
+	if err != nil {
+		return err
+	}
+	to, err := ParseGCS(dst)
+	if err != nil {
+		return err
+	}
+	return s.copy(ctx, from, to, "")
+}
+
+// Delete удаляет объект; отсутствующий объект не ошибка
+func (s *GCSStore) Delete(ctx context.Context, source string) error {
+	obj, err := ParseGCS(source)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, obj, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && resp.StatusCode/100 != 2 {
+		return gcsError("delete", obj, resp)
+	}
+	return nil
+}
+
+// DeletePrefix удаляет объекты под префиксом gs://bucket/prefix/ (см. S3Store.DeletePrefix)
+func (s *GCSStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	rest, ok := strings.CutPrefix(prefix, "gs://")
+	bucket, key, _ := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" || !strings.HasSuffix(key, "/") {
+		return 0, fmt.Errorf("%w: prefix %q must be gs://bucket/path/", ErrUnsupportedSource, prefix)
+	}
+
+	deleted := 0
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {key}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		var page listPage
+		if err := s.list(ctx, GCSObject{Bucket: bucket}, query, &page); err != nil {
+			return deleted, err
+		}
+		for _, k := range page.Keys {
+			if err := s.Delete(ctx, GCSObject{Bucket: bucket, Key: k}.String()); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if !page.Truncated {
+			return deleted, nil
+		}
+		token = page.NextToken
+	}
+}
+
+// Put загружает объект потоком из body; size — точная длина тела. Объект до PartSize
+// загружается одним PUT, больший — resumable upload кусками по PartSize. Ошибка чтения
+// body отменяет загрузку, и объект не создаётся.
+func (s *GCSStore) Put(ctx context.Context, source string, body io.Reader, size int64, contentType string) error {
+	obj, err := ParseGCS(source)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("gcs put %s: size is required", obj)
+	}
+	if size > s.config.PartSize {
+		return s.resumableUpload(ctx, obj, body, size, contentType)
+	}
+
+	req, err := s.request(ctx, http.MethodPut, obj, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer().sign(req, s.clock(), unsignedPayload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gcs put %s: %w", obj, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return gcsError("put", obj, resp)
+	}
+	return nil
+}
+
+// Open открывает объект на чтение; вызывающий закрывает тело
+func (s *GCSStore) Open(ctx context.Context, source string) (io.ReadCloser, error) {
+	obj, err := ParseGCS(source)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, obj, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		err := gcsError("get", obj, resp)
+		if resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Download скачивает объект source в w частями по PartSize, Concurrency частей одновременно.
+// Части читаются с условием на generation из HEAD: перезаписанный во время скачивания
+// объект даёт ошибку, а не смесь версий.
+func (s *GCSStore) Download(ctx context.Context, source string, w io.WriterAt) (int64, error) {
+	obj, err := ParseGCS(source)
+	if err != nil {
+		return 0, err
+	}
+	info, found, err := s.head(ctx, obj)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: gcs download %s", ErrNotFound, obj)
+	}
+	if info.size == 0 {
+		return 0, nil
+	}
+
+	err = forEachPart(ctx, splitParts(info.size, s.config.PartSize), s.config.Concurrency, func(ctx context.Context, p part) error {
+		headers := map[string]string{"Range": byteRange(p)}
+		if info.etag != "" {
+			headers["x-goog-if-generation-match"] = info.etag
+		}
+		resp, err := s.do(ctx, http.MethodGet, obj, nil, headers)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent {
+			return gcsError("get part "+strconv.Itoa(p.number), obj, resp)
+		}
+		if err := writePart(w, p, resp.Body); err != nil {
+			return fmt.Errorf("gcs get part %d %s: %w", p.number, obj, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return info.size, nil
+}
+
+// Presign возвращает подписанный URL на GET объекта, действующий ttl (V4 в query string).
+// Запросов к GCS не делает.
+func (s *GCSStore) Presign(source string, ttl time.Duration) (string, error) {
+	obj, err := ParseGCS(source)
+	if err != nil {
+		return "", err
+	}
+	if ttl < time.Second || ttl > MaxPresignTTL {
+		return "", fmt.Errorf("presign ttl must be between 1s and %v, got: %v", MaxPresignTTL, ttl)
+	}
+	req, err := s.request(context.Background(), http.MethodGet, obj, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	s.signer().presign(req, s.clock(), ttl)
+	return req.URL.String(), nil
+}
+
+// resumableUpload загружает body кусками по PartSize в одну resumable сессию. Оборванный
+// кусок (сеть, 5xx) повторяется с позиции, которую сообщает сессия; при отказе сессия отменяется.
+func (s *GCSStore) resumableUpload(ctx context.Context, obj GCSObject, body io.Reader, size int64, contentType string) error {
+	session, err := s.startResumable(ctx, obj, contentType)
+	if err != nil {
+		return err
+	}
+	if err := s.uploadChunks(ctx, obj, session, body, size); err != nil {
+		if cancelErr := s.cancelResumable(context.WithoutCancel(ctx), session); cancelErr != nil {
+			return fmt.Errorf("%w (cancel: %v)", err, cancelErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// startResumable открывает сессию и возвращает её URI; запросы к сессии не подписываются —
+// URI сам служит ключом
+func (s *GCSStore) startResumable(ctx context.Context, obj GCSObject, contentType string) (string, error) {
+	req, err := s.request(ctx, http.MethodPost, obj, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-goog-resumable", "start")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer().sign(req, s.clock(), emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gcs start resumable upload %s: %w", obj, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", gcsError("start resumable upload", obj, resp)
+	}
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return "", fmt.Errorf("gcs start resumable upload %s: no session uri in response", obj)
+	}
+	return session, nil
+}
+
+// uploadChunks читает body кусками и отправляет их в сессию
+func (s *GCSStore) uploadChunks(ctx context.Context, obj GCSObject, session string, body io.Reader, size int64) error {
+	buf := make([]byte, s.config.PartSize)
+	for offset := int64(0); offset < size; {
+		n, err := io.ReadFull(body, buf[:min(s.config.PartSize, size-offset)])
+		if err != nil {
+			return fmt.Errorf("gcs put %s: read body: %w", obj, err)
+		}
+		if err := s.uploadChunk(ctx, obj, session, buf[:n], offset, size); err != nil {
+			return err
+		}
+		offset += int64(n)
+	}
+	return nil
+}
+
+// uploadChunk отправляет кусок chunk, начинающийся с offset, повторяя недошедший остаток
+func (s *GCSStore) uploadChunk(ctx context.Context, obj GCSObject, session string, chunk []byte, offset, size int64) error {
+	end := offset + int64(len(chunk))
+	sent := offset
+	var lastErr error
+	for range maxPartAttempts {
+		done, persisted, err := s.putChunk(ctx, session, chunk[sent-offset:], sent, size)
+		if err == nil && (persisted == end || done) {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("session persisted %d of %d bytes", persisted, end)
+		}
+		lastErr = fmt.Errorf("gcs put %s: chunk at %d: %w", obj, offset, err)
+		if ctx.Err() != nil {
+			return lastErr
+		}
+		// Сколько GCS успел сохранить, знает только сессия
+		done, persisted, err = s.putChunk(ctx, session, nil, -1, size)
+		if err != nil {
+			return fmt.Errorf("%w (status: %v)", lastErr, err)
+		}
+		if done || persisted == end {
+			return nil
+		}
+		if persisted < offset || persisted > end {
+			return fmt.Errorf("%w (session persisted %d bytes, chunk is %d-%d)", lastErr, persisted, offset, end)
+		}
+		sent = persisted
+	}
+	return lastErr
+}
+
+// putChunk отправляет data с позиции start (-1 — запрос статуса без данных). Возвращает
+// признак завершения загрузки и сколько байт сохранено в сессии.
+func (s *GCSStore) putChunk(ctx context.Context, session string, data []byte, start, size int64) (bool, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(data))
+	if err != nil {
+		return false, 0, err
+	}
+	if start < 0 {
+		req.Header.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+	} else {
+		req.Header.Set("Content-Range", "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(start+int64(len(data))-1, 10)+"/"+strconv.FormatInt(size, 10))
+	}
+	req.ContentLength = int64(len(data))
+	if len(data) == 0 {
+		req.Body = http.NoBody
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated:
+		return true, size, nil
+	case resp.StatusCode == http.StatusPermanentRedirect: // 308 Resume Incomplete
+		persisted, err := persistedBytes(resp.Header.Get("Range"))
+		return false, persisted, err
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if code := errorCode(body); code != "" {
+			return false, 0, fmt.Errorf("%s: %s", resp.Status, code)
+		}
+		return false, 0, errors.New(resp.Status)
+	}
+}
+
+// persistedBytes разбирает заголовок Range ответа 308 (bytes=0-N); без него не сохранено ничего
+func persistedBytes(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+	last, ok := strings.CutPrefix(header, "bytes=0-")
+	n, err := strconv.ParseInt(last, 10, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid resumable range %q", header)
+	}
+	return n + 1, nil
+}
+
+// cancelResumable отменяет сессию, чтобы сохранённые куски не занимали место
+func (s *GCSStore) cancelResumable(ctx context.Context, session string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, session, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Отменённая сессия отвечает 499
+	if resp.StatusCode != 499 && resp.StatusCode != http.StatusNotFound && resp.StatusCode/100 != 2 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// head возвращает сведения об объекте; etag — его generation
+func (s *GCSStore) head(ctx context.Context, obj GCSObject) (objectInfo, bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, obj, nil, nil)
+	if err != nil {
+		return objectInfo{}, false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return objectInfo{}, false, nil
+	case resp.StatusCode/100 != 2:
+		return objectInfo{}, false, gcsError("head", obj, resp)
+	}
+	return objectInfo{
+		class:       resp.Header.Get("x-goog-storage-class"),
+		size:        resp.ContentLength,
+		contentType: resp.Header.Get("Content-Type"),
+		etag:        resp.Header.Get("x-goog-generation"),
+	}, true, nil
+}
+
+// copy копирует src в dst с классом хранения class (пустой — класс бакета), сохраняя метаданные.
+// Смена класса переписывает объект целиком: для больших объектов нужен HTTPClient без
+// короткого timeout.
+func (s *GCSStore) copy(ctx context.Context, src, dst GCSObject, class string) error {
+	headers := map[string]string{
+		"x-goog-copy-source":        "/" + src.Bucket + "/" + awsEscape(src.Key),
+		"x-goog-metadata-directive": "COPY",
+	}
+	if class != "" {
+		headers["x-goog-storage-class"] = class
+	}
+	resp, err := s.do(ctx, http.MethodPut, dst, nil, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return gcsError("copy", src, resp)
+	}
+	return nil
+}
+
+// list запрашивает страницу списка объектов бакета и разбирает её в page
+func (s *GCSStore) list(ctx context.Context, bucket GCSObject, query url.Values, page any) error {
+	resp, err := s.do(ctx, http.MethodGet, bucket, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return gcsError("list", bucket, resp)
+	}
+	if err := decodeXML(resp.Body, page); err != nil {
+		return fmt.Errorf("gcs list %s: decode response: %w", bucket, err)
+	}
+	return nil
+}
+
+func (s *GCSStore) signer() sigV4 {
+	return googSigner(s.config.Region, s.config.AccessID, s.config.Secret)
+}
+
+// do выполняет подписанный запрос без тела
+func (s *GCSStore) do(ctx context.Context, method string, obj GCSObject, query url.Values, headers map[string]string) (*http.Response, error) {
+	req, err := s.request(ctx, method, obj, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.signer().sign(req, s.clock(), emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gcs %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	return resp, nil
+}
+
+// request собирает path-style запрос к объекту (к бакету, если Key пустой)
+func (s *GCSStore) request(ctx context.Context, method string, obj GCSObject, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + obj.Bucket + "/" + obj.Key
+	u.RawPath = s.endpoint.Path + "/" + awsEscape(obj.Bucket) + "/" + awsEscape(obj.Key)
+	u.RawQuery = canonicalQueryString(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("gcs %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	return req, nil
+}
+
+func gcsError(op string, obj GCSObject, resp *http.Response) error {
+	return storageError("gcs", op, obj, resp)
+}
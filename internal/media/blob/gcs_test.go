@@ -0,0 +1,365 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// gcsObject — объект fakeGCS
+type gcsObject struct {
+	class, contentType, data string
+	generation               int
+}
+
+// fakeGCS — XML API GCS: HEAD, GET (с Range), PUT (upload и copy), DELETE, список
+// и resumable upload; объекты — путь /bucket/key
+type fakeGCS struct {
+	mu       sync.Mutex
+	url      string
+	objects  map[string]gcsObject
+	sessions map[string]*gcsSession
+	// breakChunk — кусок resumable upload с этим смещением один раз сохраняется наполовину и отвечает 503
+	breakChunk int64
+	broken     bool
+	requests   []string
+}
+
+type gcsSession struct {
+	path, contentType string
+	data              []byte
+}
+
+func newFakeGCS(t *testing.T) (*fakeGCS, *GCSStore) {
+	t.Helper()
+	f := &fakeGCS{objects: map[string]gcsObject{}, sessions: map[string]*gcsSession{}, breakChunk: -1}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+
+	store, err := NewGCSStore(GCSConfig{Endpoint: srv.URL, AccessID: "GOOG1ID", Secret: "secret"})
+	require.NoError(t, err)
+	return f, store
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if id, ok := strings.CutPrefix(r.URL.Path, "/upload/"); ok {
+		f.serveSession(w, r, id)
+		return
+	}
+	presigned := strings.HasPrefix(r.URL.Query().Get("X-Goog-Credential"), "GOOG1ID/") && r.URL.Query().Get("X-Goog-Signature") != ""
+	if !presigned && (!strings.HasPrefix(r.Header.Get("Authorization"), "GOOG4-HMAC-SHA256 Credential=GOOG1ID/") ||
+		r.Header.Get("X-Goog-Date") == "") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	path := r.URL.Path
+	obj, found := f.objects[path]
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r)
+			return
+		}
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		if g := r.Header.Get("X-Goog-If-Generation-Match"); g != "" && g != strconv.Itoa(obj.generation) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(obj.data))
+	case http.MethodHead:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("x-goog-storage-class", obj.class)
+		w.Header().Set("x-goog-generation", strconv.Itoa(obj.generation))
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	case http.MethodPost:
+		if r.Header.Get("X-Goog-Resumable") != "start" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id := strconv.Itoa(len(f.sessions) + 1)
+		f.sessions[id] = &gcsSession{path: path, contentType: r.Header.Get("Content-Type")}
+		w.Header().Set("Location", f.url+"/upload/"+id)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPut:
+		source := r.Header.Get("X-Goog-Copy-Source")
+		if source == "" {
+			body, _ := io.ReadAll(r.Body)
+			f.put(path, gcsObject{class: "STANDARD", contentType: r.Header.Get("Content-Type"), data: string(body)})
+			return
+		}
+		src, _ := url.PathUnescape(source)
+		from, ok := f.objects[src]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		from.class = orDefault(r.Header.Get("X-Goog-Storage-Class"), "STANDARD")
+		f.put(path, from)
+	case http.MethodDelete:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeGCS) put(path string, obj gcsObject) {
+	obj.generation = f.objects[path].generation + 1
+	f.objects[path] = obj
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// serveSession — куски resumable upload (Content-Range: bytes a-b/total), запрос статуса
+// (bytes */total) и отмена сессии
+func (f *fakeGCS) serveSession(w http.ResponseWriter, r *http.Request, id string) {
+	s, ok := f.sessions[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		delete(f.sessions, id)
+		w.WriteHeader(499)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var start, end, total int64
+	if _, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err == nil {
+		if start != int64(len(s.data)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if start == f.breakChunk && !f.broken {
+			f.broken = true
+			s.data = append(s.data, body[:len(body)/2]...)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.data = append(s.data, body...)
+	} else {
+		_, _ = fmt.Sscanf(r.Header.Get("Content-Range"), "bytes */%d", &total)
+	}
+	if int64(len(s.data)) == total {
+		delete(f.sessions, id)
+		f.put(s.path, gcsObject{class: "STANDARD", contentType: s.contentType, data: string(s.data)})
+		return
+	}
+	if len(s.data) > 0 {
+		w.Header().Set("Range", "bytes=0-"+strconv.Itoa(len(s.data)-1))
+	}
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+// list отвечает ListObjectsV2 страницами по два ключа; continuation-token — последний отданный ключ
+func (f *fakeGCS) list(w http.ResponseWriter, r *http.Request) {
+	bucket := strings.Trim(r.URL.Path, "/")
+	prefix, after := r.URL.Query().Get("prefix"), r.URL.Query().Get("continuation-token")
+	var keys []string
+	for path := range f.objects {
+		if key, ok := strings.CutPrefix(path, "/"+bucket+"/"); ok && strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	truncated := len(keys) > 2
+	if truncated {
+		keys = keys[:2]
+	}
+	var body strings.Builder
+	body.WriteString("<ListBucketResult>")
+	for _, k := range keys {
+		body.WriteString("<Contents><Key>" + k + "</Key></Contents>")
+	}
+	if truncated {
+		body.WriteString("<IsTruncated>true</IsTruncated><NextContinuationToken>" + keys[1] + "</NextContinuationToken>")
+	}
+	body.WriteString("</ListBucketResult>")
+	_, _ = w.Write([]byte(body.String()))
+}
+
+func TestParseGCS(t *testing.T) {
+	obj, err := ParseGCS("gs://media/videos/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, GCSObject{Bucket: "media", Key: "videos/a.mp4"}, obj)
+
+	for _, source := range []string{"s3://media/a.mp4", "gs://media", "gs:///a.mp4"} {
+		_, err := ParseGCS(source)
+		require.ErrorIs(t, err, ErrUnsupportedSource, source)
+	}
+}
+
+func TestGCSStore_PutAndOpen(t *testing.T) {
+	fake, store := newFakeGCS(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "gs://media/a b.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+	require.Equal(t, "frames", fake.objects["/media/a b.mp4"].data)
+	require.Equal(t, "video/mp4", fake.objects["/media/a b.mp4"].contentType)
+
+	body, err := store.Open(ctx, "gs://media/a b.mp4")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "frames", string(data))
+
+	_, err = store.Open(ctx, "gs://media/missing.mp4")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGCSStore_PutResumable(t *testing.T) {
+	fake, store := newFakeGCS(t)
+	store.config.PartSize = 4
+	ctx := context.Background()
+
+	// Второй кусок обрывается на середине: загрузка продолжается с сохранённой позиции
+	fake.breakChunk = 4
+	require.NoError(t, store.Put(ctx, "gs://media/master.mov", strings.NewReader("0123456789"), 10, "video/quicktime"))
+	require.True(t, fake.broken)
+	require.Equal(t, gcsObject{class: "STANDARD", contentType: "video/quicktime", data: "0123456789", generation: 1}, fake.objects["/media/master.mov"])
+	require.Empty(t, fake.sessions)
+
+	// Тело короче заявленного отменяет сессию, объект не создаётся
+	err := store.Put(ctx, "gs://media/short.mov", strings.NewReader("01234"), 10, "video/quicktime")
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Empty(t, fake.sessions)
+	require.NotContains(t, fake.objects, "/media/short.mov")
+}
+
+func TestGCSStore_Archive(t *testing.T) {
+	fake, store := newFakeGCS(t)
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "gs://media/a.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+
+	// На месте — смена класса хранения; повтор не копирует заново
+	location, err := store.Archive(ctx, "gs://media/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, "gs://media/a.mp4", location)
+	require.Equal(t, DefaultGCSStorageClass, fake.objects["/media/a.mp4"].class)
+	require.Equal(t, 2, fake.objects["/media/a.mp4"].generation)
+	_, err = store.Archive(ctx, "gs://media/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, 2, fake.objects["/media/a.mp4"].generation)
+
+	// В архивный бакет — копия с удалением оригинала; повтор находит перенесённый объект
+	store.config.ArchiveBucket = "media-cold"
+	require.NoError(t, store.Put(ctx, "gs://media/b.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+	for range 2 {
+		location, err = store.Archive(ctx, "gs://media/b.mp4")
+		require.NoError(t, err)
+		require.Equal(t, "gs://media-cold/b.mp4", location)
+	}
+	require.NotContains(t, fake.objects, "/media/b.mp4")
+	require.Equal(t, "frames", fake.objects["/media-cold/b.mp4"].data)
+
+	_, err = store.Archive(ctx, "gs://media/missing.mp4")
+	require.Error(t, err)
+}
+
+func TestGCSStore_DeleteAndDeletePrefix(t *testing.T) {
+	fake, store := newFakeGCS(t)
+	ctx := context.Background()
+	for _, key := range []string{"vod/1/a.ts", "vod/1/b.ts", "vod/1/master.m3u8", "vod/10/a.ts"} {
+		require.NoError(t, store.Put(ctx, "gs://stream/"+key, strings.NewReader("x"), 1, ""))
+	}
+
+	n, err := store.DeletePrefix(ctx, "gs://stream/vod/1/")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Len(t, fake.objects, 1)
+	require.Contains(t, fake.objects, "/stream/vod/10/a.ts")
+
+	require.NoError(t, store.Delete(ctx, "gs://stream/vod/10/a.ts"))
+	require.NoError(t, store.Delete(ctx, "gs://stream/vod/10/a.ts"))
+	require.Empty(t, fake.objects)
+
+	_, err = store.DeletePrefix(ctx, "gs://stream/vod/1")
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func TestGCSStore_DownloadInParts(t *testing.T) {
+	fake, store := newFakeGCS(t)
+	store.config.Concurrency = 2
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "gs://media/master.mov", strings.NewReader("0123456789"), 10, "video/quicktime"))
+	store.config.PartSize = 4
+
+	var buf offsetBuffer
+	n, err := store.Download(ctx, "gs://media/master.mov", &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), n)
+	require.Equal(t, "0123456789", string(buf.data))
+	gets := slices.DeleteFunc(slices.Clone(fake.requests), func(r string) bool { return !strings.HasPrefix(r, "GET ") })
+	require.Len(t, gets, 3, "three ranged parts")
+
+	_, err = store.Download(ctx, "gs://media/missing.mov", &buf)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGCSStore_Presign(t *testing.T) {
+	_, store := newFakeGCS(t)
+	store.clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	link, err := store.Presign("gs://media/videos/a b.mp4", time.Hour)
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, "/media/videos/a%20b.mp4", u.EscapedPath())
+	q := u.Query()
+	require.Equal(t, "GOOG4-HMAC-SHA256", q.Get("X-Goog-Algorithm"))
+	require.Equal(t, "GOOG1ID/20240102/auto/storage/goog4_request", q.Get("X-Goog-Credential"))
+	require.Equal(t, "3600", q.Get("X-Goog-Expires"))
+	require.Len(t, q.Get("X-Goog-Signature"), 64)
+
+	_, err = store.Presign("s3://media/a.mp4", time.Hour)
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+	_, err = store.Presign("gs://media/a.mp4", MaxPresignTTL+time.Second)
+	require.Error(t, err)
+}
+
+func TestNewGCSStore_Validation(t *testing.T) {
+	for name, cfg := range map[string]GCSConfig{
+		"no keys":          {},
+		"bad endpoint":     {Endpoint: "localhost:4443", AccessID: "a", Secret: "s"},
+		"small part":       {AccessID: "a", Secret: "s", PartSize: 1 << 20},
+		"unaligned part":   {AccessID: "a", Secret: "s", PartSize: MinPartSize + 1},
+		"negative workers": {AccessID: "a", Secret: "s", Concurrency: -1},
+	} {
+		_, err := NewGCSStore(cfg)
+		require.Error(t, err, name)
+	}
+}
@@ -0,0 +1,374 @@
+package blob
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStorageFull — запись превысила бы LocalConfig.MaxBytes
+var ErrStorageFull = errors.New("blob storage is full")
+
+// tempMarker — часть имени временного файла незавершённой записи; такие файлы не видны
+// по source, а брошенные после падения процесса удаляет Rescan
+const tempMarker = ".tmp-"
+
+// staleTemp — временный файл старше этого брошен: столько не длится ни одна загрузка
+const staleTemp = 24 * time.Hour
+
+// LocalConfig содержит конфигурацию LocalStore
+type LocalConfig struct {
+	// Root — каталог исходников; source — file:// URL файла внутри него
+	Root string
+	// Sharded раскладывает файлы по подкаталогам из префикса sha256 пути (Root/ab/cd/videos/a.mp4),
+	// чтобы в одном каталоге не копились сотни тысяч файлов. Source от этого не меняется, но файлы,
+	// положенные в Root в обход хранилища, по source уже не найдутся.
+	Sharded bool
+	// ArchiveDir — подкаталог Root, куда Archive переносит файлы; пустой — файл остаётся на месте
+	ArchiveDir string
+	// MaxBytes — предел суммарного размера файлов; Put сверх него — ErrStorageFull. 0 — без предела.
+	MaxBytes int64
+	FileMode fs.FileMode // default: 0640
+}
+
+// LocalStore — исходники на локальном диске (source вида file:///data/media/a.mp4), хранилище
+// для развёртывания на одном узле. Доступны только файлы внутри Root: source задаёт клиент,
+// и file:///etc/passwd не должен читаться. Выход за Root через симлинки тоже запрещён (os.Root).
+//
+// Запись атомарна: Put пишет во временный файл рядом, делает fsync и переименовывает его,
+// затем fsync каталога — после сбоя по source лежит либо прежний файл, либо новый целиком.
+// Занятое место учитывается на лету и пересчитывается обходом Root (Rescan).
+type LocalStore struct {
+	root     *os.Root
+	dir      string
+	sharded  bool
+	archive  string
+	maxBytes int64
+	mode     fs.FileMode
+	used     atomic.Int64
+	clock    func() time.Time
+}
+
+func NewLocalStore(cfg LocalConfig) (*LocalStore, error) {
+	if cfg.Root == "" {
+		return nil, errors.New("local store root is required")
+	}
+	if cfg.MaxBytes < 0 {
+		return nil, fmt.Errorf("local store max bytes cannot be negative, got: %d", cfg.MaxBytes)
+	}
+	archive := filepath.Clean(cfg.ArchiveDir)
+	if cfg.ArchiveDir != "" && (filepath.IsAbs(archive) || archive == "." || archive == ".." || strings.HasPrefix(archive, ".."+string(filepath.Separator))) {
+		return nil, fmt.Errorf("local store archive dir must be a subdirectory of the root, got: %q", cfg.ArchiveDir)
+	}
+	if cfg.ArchiveDir == "" {
+		archive = ""
+	}
+	if cfg.FileMode == 0 {
+		cfg.FileMode = 0o640
+	}
+	abs, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return nil, fmt.Errorf("local store root: %w", err)
+	}
+	root, err := os.OpenRoot(abs)
+	if err != nil {
+		return nil, fmt.Errorf("local store root: %w", err)
+	}
+
+	s := &LocalStore{
+		root:     root,
+		dir:      abs,
+		sharded:  cfg.Sharded,
+		archive:  archive,
+		maxBytes: cfg.MaxBytes,
+		mode:     cfg.FileMode,
+		clock:    time.Now,
+	}
+	if _, err := s.Rescan(context.Background()); err != nil {
+		_ = root.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open открывает файл source на чтение
+func (s *LocalStore) Open(ctx context.Context, source string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name, err := s.path(source)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.root.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", source, err)
+	}
+	return f, nil
+}
+
+// Put атомарно записывает body длиной size в source. Место под size резервируется до записи:
+// параллельные загрузки не превысят MaxBytes вместе. Тело короче или длиннее size, ошибка
+// чтения или отмена ctx оставляют прежний файл нетронутым.
+func (s *LocalStore) Put(ctx context.Context, source string, body io.Reader, size int64, contentType string) error {
+	name, err := s.path(source)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("local put %s: size is required", source)
+	}
+	if err := s.reserve(size); err != nil {
+		return fmt.Errorf("local put %s: %w", source, err)
+	}
+	replaced, err := s.write(ctx, name, body, size)
+	if err != nil {
+		s.used.Add(-size)
+		return fmt.Errorf("local put %s: %w", source, err)
+	}
+	s.used.Add(-replaced)
+	return nil
+}
+
+// write пишет временный файл и переименовывает его в name; возвращает размер замещённого файла
+func (s *LocalStore) write(ctx context.Context, name string, body io.Reader, size int64) (int64, error) {
+	dir := filepath.Dir(name)
+	if err := s.root.MkdirAll(dir, 0o750); err != nil {
+		return 0, err
+	}
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	tmp := name + tempMarker + hex.EncodeToString(suffix)
+	f, err := s.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, s.mode)
+	if err != nil {
+		return 0, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = f.Close()
+			_ = s.root.Remove(tmp)
+		}
+	}()
+
+	n, err := io.Copy(f, io.LimitReader(contextReader{ctx: ctx, r: body}, size+1))
+	if err != nil {
+		return 0, err
+	}
+	if n != size {
+		return 0, fmt.Errorf("body is %d bytes, expected %d", n, size)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	var replaced int64
+	if info, err := s.root.Lstat(name); err == nil && info.Mode().IsRegular() {
+		replaced = info.Size()
+	}
+	if err := s.root.Rename(tmp, name); err != nil {
+		return 0, err
+	}
+	committed = true
+	// Без fsync каталога переименование может не пережить сбой питания
+	return replaced, s.syncDir(dir)
+}
+
+// Delete удаляет файл source; отсутствующий файл не ошибка
+func (s *LocalStore) Delete(ctx context.Context, source string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name, err := s.path(source)
+	if err != nil {
+		return err
+	}
+	info, err := s.root.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("local delete %s: %w", source, err)
+	}
+	if err := s.root.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("local delete %s: %w", source, err)
+	}
+	if info.Mode().IsRegular() {
+		s.used.Add(-info.Size())
+	}
+	if err := s.syncDir(filepath.Dir(name)); err != nil {
+		return fmt.Errorf("local delete %s: %w", source, err)
+	}
+	return nil
+}
+
+// Archive переносит файл в ArchiveDir и возвращает его новый source; без ArchiveDir
+// source возвращается как есть. Повтор после переноса находит файл уже в архиве.
+func (s *LocalStore) Archive(ctx context.Context, source string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	rel, err := s.relative(source)
+	if err != nil {
+		return "", err
+	}
+	if s.archive == "" || rel == s.archive || strings.HasPrefix(rel, s.archive+string(filepath.Separator)) {
+		return source, nil
+	}
+	archived := filepath.Join(s.archive, rel)
+	target := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(s.dir, archived))}).String()
+
+	from, to := s.physical(rel), s.physical(archived)
+	if _, err := s.root.Lstat(from); errors.Is(err, fs.ErrNotExist) {
+		if _, err := s.root.Lstat(to); err == nil {
+			return target, nil
+		}
+		return "", fmt.Errorf("%w: %s", ErrNotFound, source)
+	}
+	if err := s.root.MkdirAll(filepath.Dir(to), 0o750); err != nil {
+		return "", fmt.Errorf("local archive %s: %w", source, err)
+	}
+	if err := s.root.Rename(from, to); err != nil {
+		return "", fmt.Errorf("local archive %s: %w", source, err)
+	}
+	if err := errors.Join(s.syncDir(filepath.Dir(to)), s.syncDir(filepath.Dir(from))); err != nil {
+		return "", fmt.Errorf("local archive %s: %w", source, err)
+	}
+	return target, nil
+}
+
+// Usage возвращает занятое файлами место и предел MaxBytes (0 — без предела)
+func (s *LocalStore) Usage() (used, limit int64) {
+	return s.used.Load(), s.maxBytes
+}
+
+// Rescan пересчитывает занятое место обходом Root и удаляет брошенные временные файлы.
+// Учёт на лету не видит файлов, удалённых в обход хранилища или другим процессом
+// (retention в media), поэтому его стоит периодически сверять с диском. Идущие загрузки
+// после пересчёта учтены записанной частью, а не резервом.
+func (s *LocalStore) Rescan(ctx context.Context) (int64, error) {
+	var total int64
+	err := fs.WalkDir(s.root.FS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.Contains(d.Name(), tempMarker) && s.clock().Sub(info.ModTime()) > staleTemp {
+			if err := s.root.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("local store usage: %w", err)
+	}
+	s.used.Store(total)
+	return total, nil
+}
+
+// Close закрывает корневой каталог
+func (s *LocalStore) Close() error { return s.root.Close() }
+
+// reserve учитывает size байт, если они помещаются в MaxBytes
+func (s *LocalStore) reserve(size int64) error {
+	for {
+		used := s.used.Load()
+		if s.maxBytes > 0 && used+size > s.maxBytes {
+			return fmt.Errorf("%w: %d of %d bytes used, %d more requested", ErrStorageFull, used, s.maxBytes, size)
+		}
+		if s.used.CompareAndSwap(used, used+size) {
+			return nil
+		}
+	}
+}
+
+// syncDir сбрасывает на диск записи каталога dir (созданные, переименованные, удалённые файлы)
+func (s *LocalStore) syncDir(dir string) error {
+	d, err := s.root.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// path — путь файла source относительно Root с учётом Sharded
+func (s *LocalStore) path(source string) (string, error) {
+	rel, err := s.relative(source)
+	if err != nil {
+		return "", err
+	}
+	return s.physical(rel), nil
+}
+
+// physical — путь файла rel на диске: с Sharded он лежит под префиксом sha256 пути
+func (s *LocalStore) physical(rel string) string {
+	if !s.sharded {
+		return rel
+	}
+	sum := sha256.Sum256([]byte(filepath.ToSlash(rel)))
+	h := hex.EncodeToString(sum[:2])
+	return filepath.Join(h[:2], h[2:], rel)
+}
+
+// relative — путь source относительно Root
+func (s *LocalStore) relative(source string) (string, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") || u.Path == "" {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedSource, source)
+	}
+	rel, err := filepath.Rel(s.dir, filepath.Clean(u.Path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q is outside %s", ErrUnsupportedSource, source, s.dir)
+	}
+	if strings.Contains(filepath.Base(rel), tempMarker) {
+		return "", fmt.Errorf("%w: %q is a temporary file name", ErrUnsupportedSource, source)
+	}
+	return rel, nil
+}
+
+// contextReader прекращает чтение после отмены ctx: загрузка, брошенная клиентом,
+// не дописывается до конца
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
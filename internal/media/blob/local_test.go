@@ -0,0 +1,206 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore_Open(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "videos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "videos", "a.mp4"), []byte("frames"), 0o644))
+	// Симлинк наружу не даёт выйти за root
+	require.NoError(t, os.Symlink("/etc/hostname", filepath.Join(dir, "escape")))
+
+	store, err := NewLocalStore(LocalConfig{Root: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	body, err := store.Open(ctx, "file://"+filepath.Join(dir, "videos", "a.mp4"))
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "frames", string(data))
+
+	_, err = store.Open(ctx, "file://"+filepath.Join(dir, "videos", "missing.mp4"))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	for _, source := range []string{
+		"file:///etc/passwd",
+		"file://" + dir + "/../etc/passwd",
+		"file://" + dir,
+		"file://remote-host" + dir + "/videos/a.mp4",
+		"s3://media/a.mp4",
+	} {
+		_, err := store.Open(ctx, source)
+		require.ErrorIs(t, err, ErrUnsupportedSource, source)
+	}
+	_, err = store.Open(ctx, "file://"+filepath.Join(dir, "escape"))
+	require.Error(t, err)
+}
+
+func TestLocalStore_Put(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(LocalConfig{Root: dir, MaxBytes: 10})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	source := "file://" + filepath.Join(dir, "videos", "a.mp4")
+
+	require.NoError(t, store.Put(ctx, source, strings.NewReader("frames"), 6, "video/mp4"))
+	data, err := os.ReadFile(filepath.Join(dir, "videos", "a.mp4"))
+	require.NoError(t, err)
+	require.Equal(t, "frames", string(data))
+	used, limit := store.Usage()
+	require.Equal(t, int64(6), used)
+	require.Equal(t, int64(10), limit)
+
+	// Оборванное или не того размера тело не трогает прежний файл и не оставляет временных
+	require.Error(t, store.Put(ctx, source, strings.NewReader("new"), 4, "video/mp4"))
+	require.Error(t, store.Put(ctx, source, io.MultiReader(strings.NewReader("ne"), errReader{}), 3, "video/mp4"))
+	data, err = os.ReadFile(filepath.Join(dir, "videos", "a.mp4"))
+	require.NoError(t, err)
+	require.Equal(t, "frames", string(data))
+	entries, err := os.ReadDir(filepath.Join(dir, "videos"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	used, _ = store.Usage()
+	require.Equal(t, int64(6), used)
+
+	// Перезапись учитывает освобождённое место
+	require.NoError(t, store.Put(ctx, source, strings.NewReader("new"), 3, "video/mp4"))
+	used, _ = store.Usage()
+	require.Equal(t, int64(3), used)
+
+	err = store.Put(ctx, "file://"+filepath.Join(dir, "b.mp4"), strings.NewReader("12345678"), 8, "video/mp4")
+	require.ErrorIs(t, err, ErrStorageFull)
+	require.NoFileExists(t, filepath.Join(dir, "b.mp4"))
+
+	require.NoError(t, store.Delete(ctx, source))
+	require.NoError(t, store.Delete(ctx, source))
+	require.NoFileExists(t, filepath.Join(dir, "videos", "a.mp4"))
+	used, _ = store.Usage()
+	require.Zero(t, used)
+
+	err = store.Put(ctx, "file:///etc/passwd", strings.NewReader("x"), 1, "text/plain")
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func TestLocalStore_ShardedArchive(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(LocalConfig{Root: dir, Sharded: true, ArchiveDir: "archive"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	source := "file://" + filepath.Join(dir, "videos", "a.mp4")
+
+	require.NoError(t, store.Put(ctx, source, strings.NewReader("frames"), 6, "video/mp4"))
+	// Файл лежит под префиксом хэша, но читается по тому же source
+	require.NoFileExists(t, filepath.Join(dir, "videos", "a.mp4"))
+	matches, err := filepath.Glob(filepath.Join(dir, "*", "*", "videos", "a.mp4"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	body, err := store.Open(ctx, source)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	archived, err := store.Archive(ctx, source)
+	require.NoError(t, err)
+	require.Equal(t, "file://"+filepath.Join(dir, "archive", "videos", "a.mp4"), archived)
+	_, err = store.Open(ctx, source)
+	require.ErrorIs(t, err, ErrNotFound)
+	body, err = store.Open(ctx, archived)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "frames", string(data))
+
+	// Повтор после переноса и архивирование уже архивного файла — не ошибка
+	again, err := store.Archive(ctx, source)
+	require.NoError(t, err)
+	require.Equal(t, archived, again)
+	again, err = store.Archive(ctx, archived)
+	require.NoError(t, err)
+	require.Equal(t, archived, again)
+
+	_, err = store.Archive(ctx, "file://"+filepath.Join(dir, "missing.mp4"))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStore_Rescan(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "videos"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "videos", "a.mp4"), []byte("frames"), 0o644))
+	stale := filepath.Join(dir, "videos", "b.mp4"+tempMarker+"1")
+	require.NoError(t, os.WriteFile(stale, []byte("partial"), 0o644))
+	old := time.Now().Add(-2 * staleTemp)
+	require.NoError(t, os.Chtimes(stale, old, old))
+	fresh := filepath.Join(dir, "videos", "c.mp4"+tempMarker+"2")
+	require.NoError(t, os.WriteFile(fresh, []byte("uploading"), 0o644))
+
+	store, err := NewLocalStore(LocalConfig{Root: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	// Брошенная запись удалена, идущая — учтена как занятое место
+	require.NoFileExists(t, stale)
+	require.FileExists(t, fresh)
+	used, _ := store.Usage()
+	require.Equal(t, int64(len("frames")+len("uploading")), used)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "videos", "a.mp4")))
+	total, err := store.Rescan(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(len("uploading")), total)
+
+	_, err = store.Open(context.Background(), "file://"+fresh)
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func TestNewLocalStore_Validation(t *testing.T) {
+	dir := t.TempDir()
+	for _, cfg := range []LocalConfig{
+		{},
+		{Root: dir, MaxBytes: -1},
+		{Root: dir, ArchiveDir: "/archive"},
+		{Root: dir, ArchiveDir: "../archive"},
+		{Root: dir, ArchiveDir: "."},
+		{Root: filepath.Join(dir, "missing")},
+	} {
+		_, err := NewLocalStore(cfg)
+		require.Error(t, err, cfg)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestReaders_DispatchByScheme(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.mp4"), []byte("frames"), 0o644))
+	local, err := NewLocalStore(LocalConfig{Root: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = local.Close() })
+
+	readers := Readers{"file": local}
+	body, err := readers.Open(context.Background(), "file://"+filepath.Join(dir, "a.mp4"))
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	for _, source := range []string{"s3://media/a.mp4", "https://example.com/a.mp4", "no-scheme"} {
+		_, err := readers.Open(context.Background(), source)
+		require.ErrorIs(t, err, ErrUnsupportedSource, source)
+	}
+}
@@ -0,0 +1,107 @@
+package blob
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DefaultPartSize — часть объекта при параллельном скачивании и копировании
+	DefaultPartSize = 64 << 20
+	// MinPartSize — меньше S3 не принимает для частей multipart, кроме последней
+	MinPartSize = 5 << 20
+	// DefaultConcurrency — частей объекта в работе одновременно
+	DefaultConcurrency = 8
+
+	// maxParts — предел частей multipart upload в S3; для больших объектов часть растёт
+	maxParts = 10000
+	// maxPartAttempts — попыток отправить часть загрузки: часть в памяти, её можно повторить
+	maxPartAttempts = 3
+)
+
+// part — диапазон [offset, offset+size) объекта; number — номер части multipart, с 1
+type part struct {
+	number int
+	offset int64
+	size   int64
+}
+
+// splitParts делит объект размера size на части по partSize, увеличивая часть,
+// если их получается больше maxParts. Пустой объект — одна пустая часть.
+func splitParts(size, partSize int64) []part {
+	if minSize := (size + maxParts - 1) / maxParts; partSize < minSize {
+		partSize = minSize
+	}
+	if size == 0 {
+		return []part{{number: 1}}
+	}
+	parts := make([]part, 0, (size+partSize-1)/partSize)
+	for offset := int64(0); offset < size; offset += partSize {
+		parts = append(parts, part{number: len(parts) + 1, offset: offset, size: min(partSize, size-offset)})
+	}
+	return parts
+}
+
+// forEachPart выполняет fn для частей, не больше concurrency одновременно. Первая ошибка
+// отменяет ctx остальных частей и возвращается; новые части после неё не начинаются.
+func forEachPart(ctx context.Context, parts []part, concurrency int, fn func(context.Context, part) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, p := range parts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, p); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// byteRange — значение заголовка Range для части p
+func byteRange(p part) string {
+	return "bytes=" + strconv.FormatInt(p.offset, 10) + "-" + strconv.FormatInt(p.offset+p.size-1, 10)
+}
+
+// writePart пишет часть p из body в w по её смещению; короткое тело — io.ErrUnexpectedEOF
+func writePart(w io.WriterAt, p part, body io.Reader) error {
+	n, err := io.Copy(io.NewOffsetWriter(w, p.offset), io.LimitReader(body, p.size))
+	if err != nil {
+		return err
+	}
+	if n != p.size {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// decodeXML разбирает XML ответ хранилища в v
+func decodeXML(body io.Reader, v any) error {
+	return xml.NewDecoder(io.LimitReader(body, 16<<20)).Decode(v)
+}
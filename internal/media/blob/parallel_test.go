@@ -0,0 +1,34 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitParts(t *testing.T) {
+	require.Equal(t, []part{{1, 0, 4}, {2, 4, 4}, {3, 8, 2}}, splitParts(10, 4))
+	require.Equal(t, []part{{number: 1}}, splitParts(0, 4))
+
+	// Больше maxParts частей не бывает: часть растёт
+	parts := splitParts(maxParts*10+1, 1)
+	require.LessOrEqual(t, len(parts), maxParts)
+	require.Equal(t, int64(11), parts[0].size)
+}
+
+func TestForEachPart_FirstErrorStopsTheRest(t *testing.T) {
+	var started atomic.Int32
+	boom := errors.New("boom")
+	err := forEachPart(context.Background(), splitParts(100, 1), 1, func(ctx context.Context, p part) error {
+		started.Add(1)
+		if p.number == 3 {
+			return boom
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, boom)
+	require.Less(t, started.Load(), int32(10))
+}
@@ -0,0 +1,492 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultArchiveStorageClass — класс хранения архивных объектов по умолчанию
+const DefaultArchiveStorageClass = "GLACIER"
+
+// emptyPayloadHash — sha256 пустого тела для запросов без тела
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// unsignedPayload — тело Put не подписывается: оно потоковое и его hash заранее неизвестен
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config содержит конфигурацию S3Store
+type S3Config struct {
+	// Endpoint — адрес S3 API (https://s3.eu-central-1.amazonaws.com, MinIO); запросы path-style
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // временные credentials (STS); пустой — не передаётся
+	// ArchiveBucket — бакет холодного хранилища: Archive переносит объект туда с тем же ключом.
+	// Пустой — объект остаётся на месте и только меняет класс хранения.
+	ArchiveBucket string
+	// StorageClass — класс хранения архивного объекта (default: GLACIER)
+	StorageClass string
+	// PartSize — часть объекта для Download (ranged GET) и копирования multipart'ом
+	// (default: DefaultPartSize, не меньше MinPartSize). Объект больше части копируется по частям.
+	PartSize int64
+	// Concurrency — частей одного объекта, которые скачиваются или копируются одновременно
+	// (default: DefaultConcurrency)
+	Concurrency int
+	HTTPClient  *http.Client // default: timeout 30s; ограничивает и один запрос части
+}
+
+// S3Store — Store поверх S3 REST API (подпись AWS Signature V4)
+type S3Store struct {
+	endpoint *url.URL
+	config   S3Config
+	client   *http.Client
+	clock    func() time.Time
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("s3 endpoint is required")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("s3 region is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+	if cfg.PartSize != 0 && cfg.PartSize < MinPartSize {
+		return nil, fmt.Errorf("s3 part size must be at least %d bytes, got: %d", MinPartSize, cfg.PartSize)
+	}
+	if cfg.Concurrency < 0 {
+		return nil, fmt.Errorf("s3 concurrency cannot be negative, got: %d", cfg.Concurrency)
+	}
+	if cfg.StorageClass == "" {
+		cfg.StorageClass = DefaultArchiveStorageClass
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	return &S3Store{
+		endpoint: endpoint,
+		config:   cfg,
+		client:   client,
+		clock:    time.Now,
+	}, nil
+}
+
+// Archive переносит объект в холодное хранилище копированием с новым классом хранения:
+// на место (ArchiveBucket пустой) или в ArchiveBucket с удалением оригинала.
+// Уже перенесённый объект повторно не копируется.
+func (s *S3Store) Archive(ctx context.Context, source string) (string, error) {
+	src, err := ParseS3(source)
+	if err != nil {
+		return "", err
+	}
+	dst := src
+	if s.config.ArchiveBucket != "" {
+		dst.Bucket = s.config.ArchiveBucket
+	}
+
+	if dst == src {
+		info, found, err := s.head(ctx, src)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "", fmt.Errorf("archive %s: object not found", src)
+		}
+		if info.class == s.config.StorageClass {
+			return src.String(), nil
+		}
+		if err := s.copy(ctx, src, dst, info, s.config.StorageClass); err != nil {
+			return "", err
+		}
+		return dst.String(), nil
+	}
+
+	info, srcFound, err := s.head(ctx, src)
+	if err != nil {
+		return "", err
+	}
+	if !srcFound {
+		// Предыдущий запуск успел перенести объект и удалить оригинал
+		_, dstFound, err := s.head(ctx, dst)
+		if err != nil {
+			return "", err
+		}
+		if !dstFound {
+			return "", fmt.Errorf("archive %s: object not found", src)
+		}
+		return dst.String(), nil
+	}
+	if err := s.copy(ctx, src, dst, info, s.config.StorageClass); err != nil {
+		return "", err
+	}
+	if err := s.Delete(ctx, source); err != nil {
+		return "", err
+	}
+	return dst.String(), nil
+}
+
+// Delete удаляет объект (S3 отвечает успехом и для отсутствующего)
+func (s *S3Store) Delete(ctx context.Context, source string) error {
+	obj, err := ParseS3(source)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, obj, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && resp.StatusCode/100 != 2 {
+		return responseError("delete", obj, resp)
+	}
+	return nil
+}
+
+// DeletePrefix удаляет объекты под префиксом s3://bucket/prefix: листает их ListObjectsV2
+// и удаляет по одному. Prefix должен заканчиваться на "/", иначе заденет соседние ключи
+// (s3://media/vod/1 и s3://media/vod/10).
+func (s *S3Store) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	rest, ok := strings.CutPrefix(prefix, "s3://")
+	bucket, key, _ := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" || !strings.HasSuffix(key, "/") {
+		return 0, fmt.Errorf("%w: prefix %q must be s3://bucket/path/", ErrUnsupportedSource, prefix)
+	}
+
+	deleted := 0
+	token := ""
+	for {
+		page, err := s.list(ctx, bucket, key, token)
+		if err != nil {
+			return deleted, err
+		}
+		for _, k := range page.Keys {
+			if err := s.Delete(ctx, Object{Bucket: bucket, Key: k}.String()); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if !page.Truncated {
+			return deleted, nil
+		}
+		token = page.NextToken
+	}
+}
+
+// listPage — страница ответа ListObjectsV2
+type listPage struct {
+	Keys      []string `xml:"Contents>Key"`
+	Truncated bool     `xml:"IsTruncated"`
+	NextToken string   `xml:"NextContinuationToken"`
+}
+
+// list запрашивает страницу ключей бакета под prefix
+func (s *S3Store) list(ctx context.Context, bucket, prefix, token string) (listPage, error) {
+	obj := Object{Bucket: bucket, Key: prefix}
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	req, err := s.request(ctx, http.MethodGet, Object{Bucket: bucket}, nil)
+	if err != nil {
+		return listPage{}, err
+	}
+	req.URL.RawQuery = canonicalQueryString(query)
+	s.signer().sign(req, s.clock(), emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return listPage{}, fmt.Errorf("s3 list %s: %w", obj, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return listPage{}, responseError("list", obj, resp)
+	}
+	var page listPage
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&page); err != nil {
+		return listPage{}, fmt.Errorf("s3 list %s: decode response: %w", obj, err)
+	}
+	return page, nil
+}
+
+// Put загружает объект потоком из body; size — точная длина тела (S3 не принимает
+// PUT без Content-Length). Ошибка чтения body обрывает запрос, и объект не создаётся.
+func (s *S3Store) Put(ctx context.Context, source string, body io.Reader, size int64, contentType string) error {
+	obj, err := ParseS3(source)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("s3 put %s: size is required", obj)
+	}
+
+	req, err := s.request(ctx, http.MethodPut, obj, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer().sign(req, s.clock(), unsignedPayload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 put %s: %w", obj, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("put", obj, resp)
+	}
+	return nil
+}
+
+// Open открывает объект на чтение; вызывающий закрывает тело
+func (s *S3Store) Open(ctx context.Context, source string) (io.ReadCloser, error) {
+	obj, err := ParseS3(source)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, obj, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		err := responseError("get", obj, resp)
+		if resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// MaxPresignTTL — предел срока presigned URL в S3 (SigV4)
+const MaxPresignTTL = 7 * 24 * time.Hour
+
+// Presign возвращает presigned URL на GET объекта, действующий ttl (SigV4 в query string).
+// Запросов к S3 не делает; подписан только host, так что ссылку можно открыть откуда угодно.
+func (s *S3Store) Presign(source string, ttl time.Duration) (string, error) {
+	obj, err := ParseS3(source)
+	if err != nil {
+		return "", err
+	}
+	if ttl < time.Second || ttl > MaxPresignTTL {
+		return "", fmt.Errorf("presign ttl must be between 1s and %v, got: %v", MaxPresignTTL, ttl)
+	}
+	req, err := s.request(context.Background(), http.MethodGet, obj, nil)
+	if err != nil {
+		return "", err
+	}
+	s.signer().presign(req, s.clock(), ttl)
+	return req.URL.String(), nil
+}
+
+// objectInfo — то, что HEAD сообщает об объекте
+type objectInfo struct {
+	class       string // класс хранения; пустой — STANDARD
+	size        int64
+	contentType string
+	etag        string
+}
+
+// head возвращает сведения об объекте и признак его наличия
+func (s *S3Store) head(ctx context.Context, obj Object) (objectInfo, bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, obj, nil)
+	if err != nil {
+		return objectInfo{}, false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return objectInfo{}, false, nil
+	case resp.StatusCode/100 != 2:
+		return objectInfo{}, false, responseError("head", obj, resp)
+	}
+	return objectInfo{
+		class:       resp.Header.Get("X-Amz-Storage-Class"),
+		size:        resp.ContentLength,
+		contentType: resp.Header.Get("Content-Type"),
+		etag:        resp.Header.Get("ETag"),
+	}, true, nil
+}
+
+// copy копирует src в dst с классом хранения class (пустой — STANDARD), сохраняя метаданные.
+// Объект больше PartSize копируется multipart'ом: CopyObject не принимает объекты больше 5 ГБ.
+func (s *S3Store) copy(ctx context.Context, src, dst Object, info objectInfo, class string) error {
+	if info.size > s.config.PartSize {
+		return s.multipartCopy(ctx, src, dst, info, class)
+	}
+	headers := map[string]string{
+		"x-amz-copy-source":        copySource(src),
+		"x-amz-metadata-directive": "COPY",
+	}
+	if class != "" {
+		headers["x-amz-storage-class"] = class
+	}
+	resp, err := s.do(ctx, http.MethodPut, dst, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("copy", src, resp)
+	}
+
+	// CopyObject может ответить 200 с ошибкой в теле, если копирование сорвалось по ходу
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("s3 copy %s: read response: %w", src, err)
+	}
+	if code := errorCode(body); code != "" {
+		return fmt.Errorf("s3 copy %s: %s", src, code)
+	}
+	return nil
+}
+
+// signer — подпись запросов текущими credentials
+func (s *S3Store) signer() sigV4 {
+	return awsSigner(s.config.Region, s.config.AccessKeyID, s.config.SecretAccessKey, s.config.SessionToken)
+}
+
+// do выполняет подписанный запрос без тела
+func (s *S3Store) do(ctx context.Context, method string, obj Object, headers map[string]string) (*http.Response, error) {
+	req, err := s.request(ctx, method, obj, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.signer().sign(req, s.clock(), emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	return resp, nil
+}
+
+// request собирает path-style запрос к объекту
+func (s *S3Store) request(ctx context.Context, method string, obj Object, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + obj.Bucket + "/" + obj.Key
+	u.RawPath = s.endpoint.Path + "/" + awsEscape(obj.Bucket) + "/" + awsEscape(obj.Key)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	return req, nil
+}
+
+// canonicalQueryString — параметры, отсортированные по имени и закодированные по правилам SigV4
+func canonicalQueryString(q url.Values) string {
+	names := make([]string, 0, len(q))
+	for k := range q {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, k := range names {
+		for _, v := range q[k] {
+			parts = append(parts, awsQueryEscape(k)+"="+awsQueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// responseError собирает ошибку из ответа S3 (код из XML тела, если он есть)
+func responseError(op string, obj Object, resp *http.Response) error {
+	return storageError("s3", op, obj, resp)
+}
+
+// storageError — ошибка ответа хранилища service; S3, GCS и Azure присылают её одинаково:
+// XML <Error><Code>
+func storageError(service, op string, obj fmt.Stringer, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if code := errorCode(body); code != "" {
+		return fmt.Errorf("%s %s %s: %s: %s", service, op, obj, resp.Status, code)
+	}
+	return fmt.Errorf("%s %s %s: %s", service, op, obj, resp.Status)
+}
+
+func errorCode(body []byte) string {
+	if !bytes.Contains(body, []byte("<Error>")) {
+		return ""
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.Unmarshal(body, &e); err != nil || e.Code == "" {
+		return "unknown error"
+	}
+	if e.Message != "" {
+		return e.Code + " (" + e.Message + ")"
+	}
+	return e.Code
+}
+
+// awsEscape кодирует путь по правилам SigV4: всё, кроме A-Z a-z 0-9 - _ . ~ и '/'
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsQueryEscape — awsEscape для параметров query string, где '/' тоже кодируется
+func awsQueryEscape(s string) string {
+	return strings.ReplaceAll(awsEscape(s), "/", "%2F")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,244 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Download скачивает объект source в w частями по PartSize, Concurrency частей одновременно
+// (ranged GET): большой исходник не упирается в полосу одного TCP потока. Части читаются
+// с If-Match на ETag из HEAD — объект, перезаписанный во время скачивания, даёт ошибку,
+// а не смесь версий. Возвращает размер объекта.
+func (s *S3Store) Download(ctx context.Context, source string, w io.WriterAt) (int64, error) {
+	obj, err := ParseS3(source)
+	if err != nil {
+		return 0, err
+	}
+	info, found, err := s.head(ctx, obj)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: s3 download %s", ErrNotFound, obj)
+	}
+	if info.size == 0 {
+		return 0, nil
+	}
+
+	err = forEachPart(ctx, splitParts(info.size, s.config.PartSize), s.config.Concurrency, func(ctx context.Context, p part) error {
+		return s.downloadPart(ctx, obj, info.etag, p, w)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return info.size, nil
+}
+
+// downloadPart пишет часть p объекта в w по её смещению
+func (s *S3Store) downloadPart(ctx context.Context, obj Object, etag string, p part, w io.WriterAt) error {
+	headers := map[string]string{"Range": byteRange(p)}
+	if etag != "" {
+		headers["If-Match"] = etag
+	}
+	resp, err := s.do(ctx, http.MethodGet, obj, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return responseError("get part "+strconv.Itoa(p.number), obj, resp)
+	}
+	if err := writePart(w, p, resp.Body); err != nil {
+		return fmt.Errorf("s3 get part %d %s: %w", p.number, obj, err)
+	}
+	return nil
+}
+
+// Copy копирует объект src в dst внутри S3, не пропуская данные через сервис. Объект больше
+// PartSize копируется multipart'ом, Concurrency частей одновременно.
+func (s *S3Store) Copy(ctx context.Context, src, dst string) error {
+	from, err := ParseS3(src)
+	if err != nil {
+		return err
+	}
+	to, err := ParseS3(dst)
+	if err != nil {
+		return err
+	}
+	info, found, err := s.head(ctx, from)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: s3 copy %s", ErrNotFound, from)
+	}
+	return s.copy(ctx, from, to, info, "")
+}
+
+// multipartCopy копирует объект частями через UploadPartCopy; при ошибке незавершённая
+// загрузка отменяется, чтобы её части не занимали место
+func (s *S3Store) multipartCopy(ctx context.Context, src, dst Object, info objectInfo, class string) error {
+	uploadID, err := s.createMultipart(ctx, dst, info, class)
+	if err != nil {
+		return err
+	}
+
+	parts := splitParts(info.size, s.config.PartSize)
+	etags := make([]string, len(parts))
+	err = forEachPart(ctx, parts, s.config.Concurrency, func(ctx context.Context, p part) error {
+		etag, err := s.copyPart(ctx, src, dst, uploadID, p)
+		etags[p.number-1] = etag
+		return err
+	})
+	if err == nil {
+		err = s.completeMultipart(ctx, dst, uploadID, etags)
+	}
+	if err != nil {
+		if abortErr := s.abortMultipart(context.WithoutCancel(ctx), dst, uploadID); abortErr != nil {
+			return fmt.Errorf("%w (abort: %v)", err, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// createMultipart начинает multipart upload в dst; метаданные (Content-Type) multipart copy
+// не переносит, поэтому они задаются здесь
+func (s *S3Store) createMultipart(ctx context.Context, dst Object, info objectInfo, class string) (string, error) {
+	headers := map[string]string{}
+	if info.contentType != "" {
+		headers["Content-Type"] = info.contentType
+	}
+	if class != "" {
+		headers["x-amz-storage-class"] = class
+	}
+	resp, err := s.send(ctx, http.MethodPost, dst, url.Values{"uploads": {""}}, headers, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", responseError("create multipart upload", dst, resp)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("s3 create multipart upload %s: no upload id in response", dst)
+	}
+	return result.UploadID, nil
+}
+
+// copyPart копирует диапазон p объекта src в часть загрузки uploadID и возвращает ETag части
+func (s *S3Store) copyPart(ctx context.Context, src, dst Object, uploadID string, p part) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(p.number)}, "uploadId": {uploadID}}
+	headers := map[string]string{
+		"x-amz-copy-source":       copySource(src),
+		"x-amz-copy-source-range": "bytes=" + strconv.FormatInt(p.offset, 10) + "-" + strconv.FormatInt(p.offset+p.size-1, 10),
+	}
+	resp, err := s.send(ctx, http.MethodPut, dst, query, headers, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	op := "copy part " + strconv.Itoa(p.number)
+	if resp.StatusCode/100 != 2 {
+		return "", responseError(op, src, resp)
+	}
+	// Как CopyObject, UploadPartCopy может ответить 200 с ошибкой в теле
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", fmt.Errorf("s3 %s %s: read response: %w", op, src, err)
+	}
+	if code := errorCode(body); code != "" {
+		return "", fmt.Errorf("s3 %s %s: %s", op, src, code)
+	}
+	var result struct {
+		ETag string `xml:"ETag"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.ETag == "" {
+		return "", fmt.Errorf("s3 %s %s: no etag in response", op, src)
+	}
+	return result.ETag, nil
+}
+
+// completeMultipart собирает объект из частей с ETag etags (по порядку номеров)
+func (s *S3Store) completeMultipart(ctx context.Context, dst Object, uploadID string, etags []string) error {
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	request := struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{}
+	for i, etag := range etags {
+		request.Parts = append(request.Parts, completedPart{PartNumber: i + 1, ETag: etag})
+	}
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.send(ctx, http.MethodPost, dst, url.Values{"uploadId": {uploadID}}, map[string]string{"Content-Type": "application/xml"}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("complete multipart upload", dst, resp)
+	}
+	// Сборка долгая: S3 отвечает 200 сразу, а ошибку может прислать в теле
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("s3 complete multipart upload %s: read response: %w", dst, err)
+	}
+	if code := errorCode(data); code != "" {
+		return fmt.Errorf("s3 complete multipart upload %s: %s", dst, code)
+	}
+	return nil
+}
+
+// abortMultipart отменяет загрузку и удаляет уже скопированные части
+func (s *S3Store) abortMultipart(ctx context.Context, dst Object, uploadID string) error {
+	resp, err := s.send(ctx, http.MethodDelete, dst, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && resp.StatusCode/100 != 2 {
+		return responseError("abort multipart upload", dst, resp)
+	}
+	return nil
+}
+
+// send выполняет подписанный запрос с query и телом body (подписывается его sha256)
+func (s *S3Store) send(ctx context.Context, method string, obj Object, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	req, err := s.request(ctx, method, obj, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = canonicalQueryString(query)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.signer().sign(req, s.clock(), sha256Hex(body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s: %w", strings.ToLower(method), obj, err)
+	}
+	return resp, nil
+}
+
+// copySource — значение x-amz-copy-source
+func copySource(obj Object) string {
+	return "/" + obj.Bucket + "/" + awsEscape(obj.Key)
+}
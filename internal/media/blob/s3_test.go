@@ -0,0 +1,425 @@
+package blob
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 — path-style S3 с HEAD, GET (с Range), PUT (upload и copy), DELETE и multipart copy;
+// объекты — ключ /bucket/key → класс хранения
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string]string
+	uploads   map[string]string         // тела загруженных через Put объектов: "content-type:data"
+	multipart map[string]map[int]string // незавершённые multipart upload: id → номер части → данные
+	failPart  int                       // UploadPartCopy этой части отвечает 500
+	requests  []string
+}
+
+func newFakeS3(t *testing.T, objects map[string]string) (*fakeS3, *S3Store) {
+	t.Helper()
+	f := &fakeS3{objects: objects, uploads: map[string]string{}, multipart: map[string]map[int]string{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        srv.URL,
+		Region:          "eu-central-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	return f, store
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.EscapedPath())
+
+	presigned := strings.HasPrefix(r.URL.Query().Get("X-Amz-Credential"), "AKID/") &&
+		r.URL.Query().Get("X-Amz-Signature") != ""
+	if !presigned && (!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		r.Header.Get("X-Amz-Date") == "") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	path := r.URL.Path
+	if r.URL.Query().Has("uploads") || r.URL.Query().Has("uploadId") {
+		f.serveMultipart(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r)
+			return
+		}
+		body, ok := f.uploads[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		_, data, _ := strings.Cut(body, ":")
+		w.Header().Set("ETag", etagOf(data))
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(data))
+	case http.MethodHead:
+		class, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if class != "" {
+			w.Header().Set("X-Amz-Storage-Class", class)
+		}
+		if body, ok := f.uploads[path]; ok {
+			contentType, data, _ := strings.Cut(body, ":")
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Header().Set("ETag", etagOf(data))
+		}
+	case http.MethodPut:
+		if r.Header.Get("X-Amz-Copy-Source") == "" {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.objects[path] = ""
+			f.uploads[path] = r.Header.Get("Content-Type") + ":" + string(body)
+			return
+		}
+		src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		if _, ok := f.objects[src]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		f.objects[path] = r.Header.Get("X-Amz-Storage-Class")
+		if body, ok := f.uploads[src]; ok {
+			f.uploads[path] = body
+		}
+		_, _ = w.Write([]byte(`<CopyObjectResult><ETag>"x"</ETag></CopyObjectResult>`))
+	case http.MethodDelete:
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func etagOf(data string) string { return `"` + strconv.Itoa(len(data)) + `"` }
+
+// serveMultipart — CreateMultipartUpload, UploadPartCopy, CompleteMultipartUpload и AbortMultipartUpload
+func (f *fakeS3) serveMultipart(w http.ResponseWriter, r *http.Request) {
+	path, id := r.URL.Path, r.URL.Query().Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+		id = "upload-" + strconv.Itoa(len(f.multipart)+1)
+		f.multipart[id] = map[int]string{}
+		f.objects[path+"#"+id] = r.Header.Get("X-Amz-Storage-Class") + "|" + r.Header.Get("Content-Type")
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>` + id + `</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut:
+		number, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if number == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		_, data, _ := strings.Cut(f.uploads[src], ":")
+		var from, to int
+		_, _ = fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &from, &to)
+		f.multipart[id][number] = data[from : to+1]
+		_, _ = w.Write([]byte(`<CopyPartResult><ETag>"part-` + strconv.Itoa(number) + `"</ETag></CopyPartResult>`))
+	case r.Method == http.MethodPost:
+		var req struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		_ = xml.NewDecoder(r.Body).Decode(&req)
+		var data strings.Builder
+		for i, p := range req.Parts {
+			if p.PartNumber != i+1 || p.ETag != `"part-`+strconv.Itoa(p.PartNumber)+`"` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data.WriteString(f.multipart[id][p.PartNumber])
+		}
+		class, contentType, _ := strings.Cut(f.objects[path+"#"+id], "|")
+		delete(f.objects, path+"#"+id)
+		delete(f.multipart, id)
+		f.objects[path] = class
+		f.uploads[path] = contentType + ":" + data.String()
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><ETag>"x-3"</ETag></CompleteMultipartUploadResult>`))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, path+"#"+id)
+		delete(f.multipart, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list отвечает ListObjectsV2 страницами по два ключа; continuation-token — последний отданный ключ
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	bucket := strings.Trim(r.URL.Path, "/")
+	prefix, after := r.URL.Query().Get("prefix"), r.URL.Query().Get("continuation-token")
+	var keys []string
+	for path := range f.objects {
+		if key, ok := strings.CutPrefix(path, "/"+bucket+"/"); ok && strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	truncated := len(keys) > 2
+	if truncated {
+		keys = keys[:2]
+	}
+	var body strings.Builder
+	body.WriteString("<ListBucketResult>")
+	for _, k := range keys {
+		body.WriteString("<Contents><Key>" + k + "</Key></Contents>")
+	}
+	if truncated {
+		body.WriteString("<IsTruncated>true</IsTruncated><NextContinuationToken>" + keys[1] + "</NextContinuationToken>")
+	}
+	body.WriteString("</ListBucketResult>")
+	_, _ = w.Write([]byte(body.String()))
+}
+
+func TestParseS3(t *testing.T) {
+	obj, err := ParseS3("s3://media/videos/a b.mp4")
+	require.NoError(t, err)
+	require.Equal(t, Object{Bucket: "media", Key: "videos/a b.mp4"}, obj)
+	require.Equal(t, "s3://media/videos/a b.mp4", obj.String())
+
+	for _, bad := range []string{"https://media/a.mp4", "s3://media", "s3:///a.mp4", "s3://media/"} {
+		_, err := ParseS3(bad)
+		require.ErrorIs(t, err, ErrUnsupportedSource, bad)
+	}
+}
+
+func TestS3Store_ArchiveInPlaceChangesStorageClass(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{"/media/videos/a b.mp4": ""})
+	ctx := context.Background()
+
+	location, err := store.Archive(ctx, "s3://media/videos/a b.mp4")
+	require.NoError(t, err)
+	require.Equal(t, "s3://media/videos/a b.mp4", location)
+	require.Equal(t, DefaultArchiveStorageClass, fake.objects["/media/videos/a b.mp4"])
+	require.Equal(t, []string{"HEAD /media/videos/a%20b.mp4", "PUT /media/videos/a%20b.mp4"}, fake.requests)
+
+	// Объект уже в холодном классе — повторного копирования нет
+	_, err = store.Archive(ctx, "s3://media/videos/a b.mp4")
+	require.NoError(t, err)
+	require.Len(t, fake.requests, 3)
+}
+
+func TestS3Store_ArchiveToColdBucket(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{"/media/a.mp4": ""})
+	store.config.ArchiveBucket = "media-cold"
+	ctx := context.Background()
+
+	location, err := store.Archive(ctx, "s3://media/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, "s3://media-cold/a.mp4", location)
+	require.Equal(t, map[string]string{"/media-cold/a.mp4": DefaultArchiveStorageClass}, fake.objects)
+
+	// Повтор после успешного переноса находит объект в холодном бакете
+	location, err = store.Archive(ctx, "s3://media/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, "s3://media-cold/a.mp4", location)
+
+	_, err = store.Archive(ctx, "s3://media/missing.mp4")
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestS3Store_Delete(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{"/media/a.mp4": ""})
+	ctx := context.Background()
+
+	require.NoError(t, store.Delete(ctx, "s3://media/a.mp4"))
+	require.Empty(t, fake.objects)
+	require.NoError(t, store.Delete(ctx, "s3://media/a.mp4"), "missing object is not an error")
+	require.ErrorIs(t, store.Delete(ctx, "file:///tmp/a.mp4"), ErrUnsupportedSource)
+}
+
+func TestS3Store_DeletePrefix(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{
+		"/vod/m1/master.m3u8":     "",
+		"/vod/m1/720p/index.m3u8": "",
+		"/vod/m1/720p/seg_1.m4s":  "",
+		"/vod/m10/master.m3u8":    "",
+	})
+	ctx := context.Background()
+
+	n, err := store.DeletePrefix(ctx, "s3://vod/m1/")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, map[string]string{"/vod/m10/master.m3u8": ""}, fake.objects)
+
+	// Повтор ничего не находит
+	n, err = store.DeletePrefix(ctx, "s3://vod/m1/")
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// Префикс без "/" на конце задел бы m10
+	_, err = store.DeletePrefix(ctx, "s3://vod/m1")
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func TestS3Store_PutAndOpen(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{})
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "s3://media/a.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+	require.Equal(t, "video/mp4:frames", fake.uploads["/media/a.mp4"])
+
+	body, err := store.Open(ctx, "s3://media/a.mp4")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "frames", string(data))
+	_, err = store.Open(ctx, "s3://media/missing.mp4")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorContains(t, err, "NoSuchKey")
+
+	// Ошибка чтения тела обрывает запрос: объект не появляется
+	broken := io.MultiReader(strings.NewReader("fra"), iotest.ErrReader(errors.New("checksum mismatch")))
+	require.Error(t, store.Put(ctx, "s3://media/b.mp4", broken, 6, "video/mp4"))
+	require.NotContains(t, fake.objects, "/media/b.mp4")
+}
+
+func TestS3Store_Presign(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{})
+	store.config.SessionToken = "token/1"
+	store.clock = func() time.Time { return time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "s3://media/videos/a b.mp4", strings.NewReader("frames"), 6, "video/mp4"))
+
+	link, err := store.Presign("s3://media/videos/a b.mp4", time.Hour)
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, "/media/videos/a%20b.mp4", u.EscapedPath())
+	q := u.Query()
+	require.Equal(t, "AKID/20130524/eu-central-1/s3/aws4_request", q.Get("X-Amz-Credential"))
+	require.Equal(t, "20130524T000000Z", q.Get("X-Amz-Date"))
+	require.Equal(t, "3600", q.Get("X-Amz-Expires"))
+	require.Equal(t, "host", q.Get("X-Amz-SignedHeaders"))
+	require.Equal(t, "token/1", q.Get("X-Amz-Security-Token"))
+	require.Len(t, q.Get("X-Amz-Signature"), 64)
+	// Параметры кодируются по SigV4: '/' тоже
+	require.Contains(t, u.RawQuery, "X-Amz-Credential=AKID%2F20130524")
+
+	// Ссылка открывается без заголовков подписи
+	resp, err := http.Get(link)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "frames", string(data))
+	require.Equal(t, "GET /media/videos/a%20b.mp4", fake.requests[len(fake.requests)-1])
+
+	for _, ttl := range []time.Duration{0, MaxPresignTTL + time.Second} {
+		_, err := store.Presign("s3://media/a.mp4", ttl)
+		require.Error(t, err, ttl)
+	}
+	_, err = store.Presign("file:///tmp/a.mp4", time.Hour)
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+// offsetBuffer — io.WriterAt в памяти
+type offsetBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (b *offsetBuffer) WriteAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if end := int(off) + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	return copy(b.data[off:], p), nil
+}
+
+func TestS3Store_DownloadInParts(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{})
+	store.config.PartSize, store.config.Concurrency = 4, 2
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "s3://media/master.mov", strings.NewReader("0123456789"), 10, "video/quicktime"))
+
+	var buf offsetBuffer
+	n, err := store.Download(ctx, "s3://media/master.mov", &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), n)
+	require.Equal(t, "0123456789", string(buf.data))
+	gets := slices.DeleteFunc(slices.Clone(fake.requests), func(r string) bool { return !strings.HasPrefix(r, "GET ") })
+	require.Len(t, gets, 3, "three ranged parts")
+
+	_, err = store.Download(ctx, "s3://media/missing.mov", &buf)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestS3Store_CopyMultipart(t *testing.T) {
+	fake, store := newFakeS3(t, map[string]string{})
+	store.config.PartSize, store.config.Concurrency = 4, 3
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "s3://media/master.mov", strings.NewReader("0123456789"), 10, "video/quicktime"))
+
+	// Объект больше части: multipart copy с переносом Content-Type
+	require.NoError(t, store.Copy(ctx, "s3://media/master.mov", "s3://work/master.mov"))
+	require.Equal(t, "video/quicktime:0123456789", fake.uploads["/work/master.mov"])
+	require.Empty(t, fake.multipart)
+
+	// Archive больших объектов тоже идёт multipart'ом, с классом хранения
+	store.config.ArchiveBucket = "media-cold"
+	location, err := store.Archive(ctx, "s3://media/master.mov")
+	require.NoError(t, err)
+	require.Equal(t, "s3://media-cold/master.mov", location)
+	require.Equal(t, DefaultArchiveStorageClass, fake.objects["/media-cold/master.mov"])
+	require.Equal(t, "video/quicktime:0123456789", fake.uploads["/media-cold/master.mov"])
+
+	// Сбой части отменяет загрузку: частей и объекта не остаётся
+	fake.failPart = 2
+	require.Error(t, store.Copy(ctx, "s3://work/master.mov", "s3://work/broken.mov"))
+	require.Empty(t, fake.multipart)
+	require.NotContains(t, fake.objects, "/work/broken.mov")
+
+	// Объект не больше части копируется одним CopyObject
+	require.NoError(t, store.Put(ctx, "s3://media/small.mp4", strings.NewReader("abc"), 3, "video/mp4"))
+	require.NoError(t, store.Copy(ctx, "s3://media/small.mp4", "s3://work/small.mp4"))
+	require.Equal(t, "video/mp4:abc", fake.uploads["/work/small.mp4"])
+	require.ErrorIs(t, store.Copy(ctx, "s3://media/missing.mp4", "s3://work/x.mp4"), ErrNotFound)
+}
+
+func TestNewS3Store_Validation(t *testing.T) {
+	for name, cfg := range map[string]S3Config{
+		"no endpoint":      {Region: "r", AccessKeyID: "a", SecretAccessKey: "s"},
+		"bad endpoint":     {Endpoint: "localhost:9000", Region: "r", AccessKeyID: "a", SecretAccessKey: "s"},
+		"no region":        {Endpoint: "http://localhost:9000", AccessKeyID: "a", SecretAccessKey: "s"},
+		"no keys":          {Endpoint: "http://localhost:9000", Region: "r"},
+		"small part":       {Endpoint: "http://localhost:9000", Region: "r", AccessKeyID: "a", SecretAccessKey: "s", PartSize: 1 << 20},
+		"negative workers": {Endpoint: "http://localhost:9000", Region: "r", AccessKeyID: "a", SecretAccessKey: "s", Concurrency: -1},
+	} {
+		_, err := NewS3Store(cfg)
+		require.Error(t, err, name)
+	}
+}
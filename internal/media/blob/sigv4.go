@@ -0,0 +1,148 @@
+package blob
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sigV4 подписывает запросы по AWS Signature V4. GCS с HMAC ключами (interoperability)
+// принимает ту же схему, отличаются только имена: алгоритм, сервис, префикс заголовков.
+type sigV4 struct {
+	algorithm  string // AWS4-HMAC-SHA256, GOOG4-HMAC-SHA256
+	keyPrefix  string // AWS4, GOOG4
+	service    string // s3, storage
+	terminator string // aws4_request, goog4_request
+	header     string // префикс подписываемых заголовков: x-amz-, x-goog-
+	query      string // префикс параметров presigned URL: X-Amz-, X-Goog-
+
+	region    string
+	accessKey string
+	secret    string
+	token     string // временные credentials (STS); пустой — не передаётся
+}
+
+// awsSigner — подпись запросов к S3
+func awsSigner(region, accessKey, secret, token string) sigV4 {
+	return sigV4{
+		algorithm:  "AWS4-HMAC-SHA256",
+		keyPrefix:  "AWS4",
+		service:    "s3",
+		terminator: "aws4_request",
+		header:     "x-amz-",
+		query:      "X-Amz-",
+		region:     region,
+		accessKey:  accessKey,
+		secret:     secret,
+		token:      token,
+	}
+}
+
+// googSigner — подпись запросов к XML API GCS по HMAC ключу
+func googSigner(region, accessKey, secret string) sigV4 {
+	return sigV4{
+		algorithm:  "GOOG4-HMAC-SHA256",
+		keyPrefix:  "GOOG4",
+		service:    "storage",
+		terminator: "goog4_request",
+		header:     "x-goog-",
+		query:      "X-Goog-",
+		region:     region,
+		accessKey:  accessKey,
+		secret:     secret,
+	}
+}
+
+// sign подписывает запрос заголовком Authorization: подписываются host и все заголовки
+// с префиксом header, payloadHash — sha256 тела (hex) или UNSIGNED-PAYLOAD
+func (v sigV4) sign(req *http.Request, now time.Time, payloadHash string) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set(v.header+"date", amzDate)
+	req.Header.Set(v.header+"content-sha256", payloadHash)
+	if v.token != "" {
+		req.Header.Set(v.header+"security-token", v.token)
+	}
+
+	canonical := map[string]string{"host": req.URL.Host}
+	for k, vals := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, v.header) {
+			canonical[k] = strings.TrimSpace(strings.Join(vals, ","))
+		}
+	}
+	names := make([]string, 0, len(canonical))
+	for k := range canonical {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, k := range names {
+		headers.WriteString(k + ":" + canonical[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := v.scope(date)
+	signature := v.signature(date, amzDate, scope, canonicalRequest)
+	req.Header.Set("Authorization", v.algorithm+" Credential="+v.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// presign дописывает в query запроса подпись на ttl; подписан только host
+func (v sigV4) presign(req *http.Request, now time.Time, ttl time.Duration) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	scope := v.scope(date)
+	query := url.Values{
+		v.query + "Algorithm":     {v.algorithm},
+		v.query + "Credential":    {v.accessKey + "/" + scope},
+		v.query + "Date":          {amzDate},
+		v.query + "Expires":       {strconv.Itoa(int(ttl / time.Second))},
+		v.query + "SignedHeaders": {"host"},
+	}
+	if v.token != "" {
+		query.Set(v.query+"Security-Token", v.token)
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery,
+		"host:" + req.URL.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	req.URL.RawQuery = canonicalQuery + "&" + v.query + "Signature=" + v.signature(date, amzDate, scope, canonicalRequest)
+}
+
+func (v sigV4) scope(date string) string {
+	return date + "/" + v.region + "/" + v.service + "/" + v.terminator
+}
+
+func (v sigV4) signature(date, amzDate, scope, canonicalRequest string) string {
+	stringToSign := v.algorithm + "\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	return hex.EncodeToString(hmacSHA256(v.signingKey(date), stringToSign))
+}
+
+// signingKey — ключ подписи на дату date (YYYYMMDD)
+func (v sigV4) signingKey(date string) []byte {
+	key := hmacSHA256([]byte(v.keyPrefix+v.secret), date)
+	key = hmacSHA256(key, v.region)
+	key = hmacSHA256(key, v.service)
+	return hmacSHA256(key, v.terminator)
+}
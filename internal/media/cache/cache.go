@@ -0,0 +1,55 @@
+// Package cache — cache-aside слой перед MediaRepository для GET /media/{id}.
+// Бэкенды: Redis (общий для всех инстансов) и in-process LRU.
+// Инвалидация — после коммита записи и по событиям media из Kafka.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// ErrMiss — записи нет в кэше (или истёк TTL)
+var ErrMiss = errors.New("cache miss")
+
+// Cache — хранилище медиа по id. Реализации сами отвечают за TTL.
+type Cache interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Media, error)
+	Set(ctx context.Context, m *models.Media) error
+	Delete(ctx context.Context, ids ...uuid.UUID) error
+}
+
+// Metrics содержит метрики кэша
+type Metrics struct {
+	Hits          atomic.Int64
+	Misses        atomic.Int64
+	Errors        atomic.Int64 // ошибки бэкенда; запрос при этом обслуживается из репозитория
+	Invalidations atomic.Int64
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	counter := func(name, help string, v *atomic.Int64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "media_cache_" + name,
+			Help: help,
+		}, func() float64 { return float64(v.Load()) })
+	}
+
+	for _, c := range []prometheus.Collector{
+		counter("hits_total", "Чтения, обслуженные из кэша", &m.Hits),
+		counter("misses_total", "Чтения, ушедшие в репозиторий", &m.Misses),
+		counter("errors_total", "Ошибки бэкенда кэша", &m.Errors),
+		counter("invalidations_total", "Удалённые из кэша записи", &m.Invalidations),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+func newMedia() *models.Media {
+	now := time.Now().UTC()
+	return &models.Media{
+		ID:        uuid.New(),
+		Status:    models.UploadedStatus,
+		Type:      models.Video,
+		Source:    "s3://bucket/a.mp4",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewLRU(2, time.Minute)
+	require.NoError(t, err)
+
+	a, b, d := newMedia(), newMedia(), newMedia()
+	require.NoError(t, c.Set(ctx, a))
+	require.NoError(t, c.Set(ctx, b))
+
+	// a становится свежее b
+	_, err = c.Get(ctx, a.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, d))
+	require.Equal(t, 2, c.Len())
+
+	_, err = c.Get(ctx, b.ID)
+	require.ErrorIs(t, err, ErrMiss)
+	_, err = c.Get(ctx, a.ID)
+	require.NoError(t, err)
+}
+
+func TestLRU_TTL(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewLRU(10, time.Second)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	m := newMedia()
+	require.NoError(t, c.Set(ctx, m))
+
+	got, err := c.Get(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, got.ID)
+
+	now = now.Add(time.Second)
+	_, err = c.Get(ctx, m.ID)
+	require.ErrorIs(t, err, ErrMiss)
+	require.Equal(t, 0, c.Len())
+}
+
+func newCachedRepo(t *testing.T) (*Repository, *repository.MemoryRepository) {
+	t.Helper()
+	lru, err := NewLRU(100, time.Minute)
+	require.NoError(t, err)
+
+	mem := repository.NewMemoryRepository()
+	r, err := NewRepository(RepositoryConfig{Repo: mem, Cache: lru})
+	require.NoError(t, err)
+	return r, mem
+}
+
+func TestRepository_CacheAside(t *testing.T) {
+	ctx := context.Background()
+	r, mem := newCachedRepo(t)
+
+	m := newMedia()
+	require.NoError(t, mem.Create(ctx, m))
+
+	_, err := r.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	_, err = r.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1), r.Metrics().Misses.Load())
+	require.Equal(t, int64(1), r.Metrics().Hits.Load())
+}
+
+func TestRepository_InvalidatesAfterTransaction(t *testing.T) {
+	ctx := context.Background()
+	r, mem := newCachedRepo(t)
+
+	m := newMedia()
+	require.NoError(t, mem.Create(ctx, m))
+	_, err := r.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+
+	err = r.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := r.UpdateStatus(ctx, m.ID, models.ProcessingStatus)
+		require.NoError(t, err)
+
+		// До конца транзакции запись в кэше остаётся
+		_, err = r.cache.Get(ctx, m.ID)
+		require.NoError(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err := r.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingStatus, got.Status)
+	require.Equal(t, int64(1), r.Metrics().Invalidations.Load())
+}
+
+func TestRepository_InvalidatesOnRollback(t *testing.T) {
+	ctx := context.Background()
+	r, mem := newCachedRepo(t)
+
+	m := newMedia()
+	require.NoError(t, mem.Create(ctx, m))
+	_, err := r.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+
+	boom := errors.New("boom")
+	err = r.WithinTransaction(ctx, func(ctx context.Context) error {
+		_ = r.SetLastError(ctx, m.ID, "decode failed")
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+
+	_, err = r.cache.Get(ctx, m.ID)
+	require.ErrorIs(t, err, ErrMiss)
+}
+
+func TestRepository_InvalidateOnEvent(t *testing.T) {
+	ctx := context.Background()
+	r, mem := newCachedRepo(t)
+
+	m := newMedia()
+	require.NoError(t, mem.Create(ctx, m))
+	_, err := r.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, r.InvalidateOnEvent(ctx, "MediaCreated", m.ID.String()))
+	_, err = r.cache.Get(ctx, m.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, r.InvalidateOnEvent(ctx, "MediaStatusChanged", m.ID.String()))
+	_, err = r.cache.Get(ctx, m.ID)
+	require.ErrorIs(t, err, ErrMiss)
+
+	require.Error(t, r.InvalidateOnEvent(ctx, "MediaDeleted", "not-a-uuid"))
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+type lruEntry struct {
+	media     models.Media
+	expiresAt time.Time
+}
+
+// LRU — in-process кэш с ограничением по числу записей и TTL.
+// Подходит для одного инстанса; при нескольких инстансах инвалидация по событиям обязательна.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // front — самая свежая запись
+	items    map[uuid.UUID]*list.Element
+	now      func() time.Time
+}
+
+func NewLRU(capacity int, ttl time.Duration) (*LRU, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive, got: %d", capacity)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive, got: %v", ttl)
+	}
+
+	return &LRU{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[uuid.UUID]*list.Element, capacity),
+		now:      time.Now,
+	}, nil
+}
+
+func (c *LRU) Get(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[id]
+	if !ok {
+		return nil, ErrMiss
+	}
+	e := el.Value.(*lruEntry)
+	if !c.now().Before(e.expiresAt) {
+		c.remove(el)
+		return nil, ErrMiss
+	}
+
+	c.order.MoveToFront(el)
+	cp := e.media
+	return &cp, nil
+}
+
+func (c *LRU) Set(ctx context.Context, m *models.Media) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{media: *m, expiresAt: c.now().Add(c.ttl)}
+	if el, ok := c.items[m.ID]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.items[m.ID] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, ids ...uuid.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		if el, ok := c.items[id]; ok {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len возвращает число записей (включая ещё не вытесненные просроченные)
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*lruEntry).media.ID)
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// RedisConfig содержит настройки Redis-кэша
+type RedisConfig struct {
+	Client    redis.UniversalClient
+	TTL       time.Duration // Время жизни записи (default: 5m)
+	KeyPrefix string        // Префикс ключей (default: "media:")
+}
+
+// Redis — кэш, общий для всех инстансов сервиса. Значения хранятся как JSON.
+type Redis struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	prefix string
+}
+
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if cfg.TTL < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative, got: %v", cfg.TTL)
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "media:"
+	}
+
+	return &Redis{client: cfg.Client, ttl: cfg.TTL, prefix: cfg.KeyPrefix}, nil
+}
+
+func (c *Redis) key(id uuid.UUID) string {
+	return c.prefix + id.String()
+}
+
+func (c *Redis) Get(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	data, err := c.client.Get(ctx, c.key(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get: %w", err)
+	}
+
+	var m models.Media
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("redis decode: %w", err)
+	}
+	return &m, nil
+}
+
+func (c *Redis) Set(ctx context.Context, m *models.Media) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("redis encode: %w", err)
+	}
+	if err := c.client.Set(ctx, c.key(m.ID), data, c.ttl).Err(); err != nil {
+		return fmt.Errorf("redis set: %w", err)
+	}
+	return nil
+}
+
+func (c *Redis) Delete(ctx context.Context, ids ...uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.key(id)
+	}
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis del: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// RepositoryConfig содержит настройки кэширующего репозитория
+type RepositoryConfig struct {
+	Repo   repository.MediaRepository
+	Cache  Cache
+	Logger zerolog.Logger
+}
+
+// Repository — cache-aside декоратор MediaRepository.
+// GetByID читает из кэша и заполняет его при промахе; изменения медиа удаляют запись
+// после завершения транзакции. Окно, когда параллельное чтение успело положить
+// старую версию, закрывается инвалидацией по событию из Kafka и TTL.
+type Repository struct {
+	repository.MediaRepository
+
+	cache   Cache
+	logger  zerolog.Logger
+	metrics *Metrics
+}
+
+func NewRepository(cfg RepositoryConfig) (*Repository, error) {
+	if cfg.Repo == nil {
+		return nil, fmt.Errorf("repository is required")
+	}
+	if cfg.Cache == nil {
+		return nil, fmt.Errorf("cache is required")
+	}
+
+	return &Repository{
+		MediaRepository: cfg.Repo,
+		cache:           cfg.Cache,
+		logger:          cfg.Logger.With().Str("component", "media_cache").Logger(),
+		metrics:         &Metrics{},
+	}, nil
+}
+
+// Metrics возвращает метрики кэша
+func (r *Repository) Metrics() *Metrics { return r.metrics }
+
+type pendingKey struct{}
+
+// pending — id, изменённые внутри транзакции; инвалидируются после её завершения
+type pending struct {
+	ids []uuid.UUID
+}
+
+// WithinTransaction откладывает инвалидацию до конца транзакции, иначе параллельное
+// чтение до коммита вернуло бы в кэш старую версию.
+func (r *Repository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(pendingKey{}).(*pending); ok {
+		return r.MediaRepository.WithinTransaction(ctx, fn)
+	}
+
+	p := &pending{}
+	err := r.MediaRepository.WithinTransaction(context.WithValue(ctx, pendingKey{}, p), fn)
+	// И после rollback: лишний промах дешевле устаревшей записи
+	r.invalidate(ctx, p.ids...)
+	return err
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	// Внутри транзакции и при read-your-writes кэш не используется
+	if _, inTx := ctx.Value(pendingKey{}).(*pending); inTx || repository.ReadPrimary(ctx) {
+		return r.MediaRepository.GetByID(ctx, id)
+	}
+
+	m, err := r.cache.Get(ctx, id)
+	switch {
+	case err == nil:
+		r.metrics.Hits.Add(1)
+		return m, nil
+	case errors.Is(err, ErrMiss):
+		r.metrics.Misses.Add(1)
+	default:
+		r.metrics.Errors.Add(1)
+		r.metrics.Misses.Add(1)
+		r.logger.Warn().Err(err).Str("media_id", id.String()).Msg("cache get failed")
+	}
+
+	m, err = r.MediaRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.cache.Set(ctx, m); err != nil {
+		r.metrics.Errors.Add(1)
+		r.logger.Warn().Err(err).Str("media_id", id.String()).Msg("cache set failed")
+	}
+	return m, nil
+}
+
+func (r *Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	m, err := r.MediaRepository.UpdateStatus(ctx, id, status)
+	r.changed(ctx, id)
+	return m, err
+}
+
+func (r *Repository) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	m, err := r.MediaRepository.Update(ctx, id, patch)
+	r.changed(ctx, id)
+	return m, err
+}
+
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	m, err := r.MediaRepository.Delete(ctx, id)
+	r.changed(ctx, id)
+	return m, err
+}
+
+func (r *Repository) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	err := r.MediaRepository.SetLastError(ctx, id, lastError)
+	r.changed(ctx, id)
+	return err
+}
+
+// InvalidateOnEvent удаляет запись по событию media из Kafka.
+// Так узнают об изменениях инстансы с in-process кэшем, которые сами запись не делали.
+func (r *Repository) InvalidateOnEvent(ctx context.Context, eventType, aggregateID string) error {
+	switch eventType {
+	case "MediaStatusChanged", "MediaContentRecorded", "MediaDeleted", "MediaArchived", "MediaQuarantined", "MediaScheduled", "MediaVisibilityChanged":
+	default:
+		return nil
+	}
+
+	id, err := uuid.Parse(aggregateID)
+	if err != nil {
+		return fmt.Errorf("invalid aggregate id %q: %w", aggregateID, err)
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+// changed инвалидирует сразу или, внутри транзакции, после её завершения
+func (r *Repository) changed(ctx context.Context, id uuid.UUID) {
+	if p, ok := ctx.Value(pendingKey{}).(*pending); ok {
+		p.ids = append(p.ids, id)
+		return
+	}
+	r.invalidate(ctx, id)
+}
+
+func (r *Repository) invalidate(ctx context.Context, ids ...uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	// Инвалидация не должна теряться из-за отменённого запроса
+	if err := r.cache.Delete(context.WithoutCancel(ctx), ids...); err != nil {
+		r.metrics.Errors.Add(1)
+		r.logger.Warn().Err(err).Int("count", len(ids)).Msg("cache invalidation failed")
+		return
+	}
+	r.metrics.Invalidations.Add(int64(len(ids)))
+}
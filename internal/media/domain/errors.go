@@ -6,4 +6,12 @@ var (
 	ErrNotFound          = errors.New("not found")
 	ErrInvalidTransition = errors.New("invalid transition")
 	ErrConflict          = errors.New("conflict") // под optimistic lock / version mismatch
+	ErrQuotaExceeded     = errors.New("quota exceeded")
+	ErrRateLimited       = errors.New("rate limited") // слишком много загрузок за окно
+
+	// Загруженное содержимое не совпало с тем, что заявил клиент
+	ErrChecksumMismatch    = errors.New("checksum mismatch")
+	ErrContentTypeMismatch = errors.New("content type mismatch")
+	// Антивирус нашёл угрозу, медиа отправлено в карантин
+	ErrMalwareDetected = errors.New("malware detected")
 )
@@ -0,0 +1,22 @@
+package domain
+
+// DefaultMaxProcessingAttempts — сколько раз медиа может войти в processing,
+// прежде чем ошибка обработки станет окончательной
+const DefaultMaxProcessingAttempts = 3
+
+// RetryPolicy решает, повторять ли обработку после неудачной попытки
+type RetryPolicy struct {
+	MaxAttempts int // <= 0 — DefaultMaxProcessingAttempts
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultMaxProcessingAttempts
+	}
+	return p.MaxAttempts
+}
+
+// ShouldRetry — attempts уже сделанных попыток меньше лимита
+func (p RetryPolicy) ShouldRetry(attempts int) bool {
+	return attempts < p.maxAttempts()
+}
@@ -1,37 +1,115 @@
 package domain
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 type Status string
 
 const (
-	Uploaded   Status = "uploaded"
-	Processing Status = "processing"
-	Ready      Status = "ready"
-	Failed     Status = "failed"
+	Uploaded    Status = "uploaded"
+	Processing  Status = "processing"
+	Ready       Status = "ready"
+	Failed      Status = "failed"
+	Deleted     Status = "deleted"
+	Archived    Status = "archived"
+	Quarantined Status = "quarantined"
+	Scheduled   Status = "scheduled"
 )
 
-func CanTransition(from, to Status) bool {
-	switch from {
-	case Uploaded:
-		return to == Processing || to == Failed
-	case Processing:
-		return to == Ready || to == Failed
-	case Ready:
-		return false
-	case Failed:
-		return false
-	default:
-		return false
+// Transitions — таблица допустимых переходов: из статуса-ключа в любой из статусов-значений.
+// Статус без исходящих переходов — терминальный.
+type Transitions map[Status][]Status
+
+// DefaultTransitions — жизненный цикл медиа по умолчанию:
+//   - failed → processing: повторная обработка после ошибки
+//   - ready → processing: перекодирование готового медиа
+//   - ready/failed → archived: исходник ушёл в холодное хранилище по retention
+//   - uploaded/processing/ready/failed → quarantined: антивирус ingest нашёл угрозу в исходнике
+//     (асинхронная проверка может закончиться, когда обработка уже идёт)
+//   - processing → scheduled: обработка закончилась до publish_at, медиа ждёт публикации;
+//     scheduled → ready в publish_at, ready → scheduled — publish_at перенесли в будущее
+//   - scheduled → archived: expires_at наступил раньше публикации
+//   - archived и quarantined — терминальные
+//
+// deleted в таблице нет: это не переход, а удаление медиа (DeleteMedia) вместе с MediaDeleted.
+var DefaultTransitions = Transitions{
+	Uploaded:    {Processing, Failed, Quarantined},
+	Processing:  {Ready, Scheduled, Failed, Quarantined},
+	Ready:       {Processing, Scheduled, Archived, Quarantined},
+	Scheduled:   {Ready, Processing, Archived, Quarantined},
+	Failed:      {Processing, Archived, Quarantined},
+	Archived:    {},
+	Quarantined: {},
+}
+
+// With возвращает копию таблицы с добавленными переходами from → to.
+// Исходная таблица не меняется, поэтому DefaultTransitions безопасно расширять.
+func (t Transitions) With(from Status, to ...Status) Transitions {
+	out := make(Transitions, len(t)+1)
+	for k, v := range t {
+		out[k] = slices.Clone(v)
+	}
+	for _, s := range to {
+		if !slices.Contains(out[from], s) {
+			out[from] = append(out[from], s)
+		}
+		if _, ok := out[s]; !ok {
+			out[s] = nil // статус должен быть известен машине, даже если он терминальный
+		}
 	}
+	return out
 }
 
-func ValidateTransition(from, to Status) error {
+// StateMachine проверяет переходы по таблице Transitions
+type StateMachine struct {
+	allowed map[Status]map[Status]bool
+}
+
+func NewStateMachine(t Transitions) *StateMachine {
+	allowed := make(map[Status]map[Status]bool, len(t))
+	for from, targets := range t {
+		allowed[from] = make(map[Status]bool, len(targets))
+		for _, to := range targets {
+			allowed[from][to] = true
+		}
+	}
+	return &StateMachine{allowed: allowed}
+}
+
+// Known сообщает, описан ли статус в таблице переходов
+func (m *StateMachine) Known(s Status) bool {
+	_, ok := m.allowed[s]
+	return ok
+}
+
+func (m *StateMachine) CanTransition(from, to Status) bool {
+	return m.allowed[from][to]
+}
+
+// IsTerminal — из статуса нет ни одного перехода
+func (m *StateMachine) IsTerminal(s Status) bool {
+	return m.Known(s) && len(m.allowed[s]) == 0
+}
+
+func (m *StateMachine) ValidateTransition(from, to Status) error {
 	if from == to {
 		return nil
 	}
-	if !CanTransition(from, to) {
-		return fmt.Errorf("invalid transition: %s -> %s", from, to)
+	if !m.CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
 	}
 	return nil
 }
+
+// DefaultStateMachine построена по DefaultTransitions
+var DefaultStateMachine = NewStateMachine(DefaultTransitions)
+
+func CanTransition(from, to Status) bool {
+	return DefaultStateMachine.CanTransition(from, to)
+}
+
+func ValidateTransition(from, to Status) error {
+	return DefaultStateMachine.ValidateTransition(from, to)
+}
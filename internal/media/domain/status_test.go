@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTransitions(t *testing.T) {
+	cases := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{Uploaded, Processing, true},
+		{Uploaded, Ready, false},
+		{Processing, Ready, true},
+		{Processing, Failed, true},
+		{Failed, Processing, true}, // retry
+		{Ready, Processing, true},  // re-transcoding
+		{Ready, Failed, false},
+		{Ready, Deleted, false}, // удаление — DeleteMedia, не переход
+		{Deleted, Processing, false},
+		{Deleted, Uploaded, false},
+		{Ready, Archived, true},
+		{Failed, Archived, true},
+		{Processing, Archived, false},
+		{Archived, Processing, false},
+		{Archived, Deleted, false},
+		{Uploaded, Quarantined, true},
+		{Failed, Quarantined, true},
+		{Ready, Quarantined, true},
+		{Archived, Quarantined, false},
+		{Quarantined, Processing, false},
+		{Quarantined, Deleted, false},
+		{Processing, Scheduled, true},
+		{Uploaded, Scheduled, false},
+		{Scheduled, Ready, true},
+		{Ready, Scheduled, true},
+		{Scheduled, Archived, true},
+		{Scheduled, Failed, false},
+		{Archived, Scheduled, false},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.ok, CanTransition(tc.from, tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
+
+func TestValidateTransition(t *testing.T) {
+	require.NoError(t, ValidateTransition(Ready, Ready))
+	require.NoError(t, ValidateTransition(Failed, Processing))
+	require.ErrorIs(t, ValidateTransition(Deleted, Ready), ErrInvalidTransition)
+}
+
+func TestStateMachine_Terminal(t *testing.T) {
+	require.True(t, DefaultStateMachine.IsTerminal(Archived))
+	require.True(t, DefaultStateMachine.IsTerminal(Quarantined))
+	require.False(t, DefaultStateMachine.Known(Deleted))
+	require.False(t, DefaultStateMachine.IsTerminal(Failed))
+	require.False(t, DefaultStateMachine.IsTerminal(Status("unknown")))
+}
+
+func TestTransitions_WithDoesNotMutateBase(t *testing.T) {
+	const onHold Status = "on_hold"
+
+	custom := NewStateMachine(DefaultTransitions.With(Ready, onHold))
+
+	require.True(t, custom.CanTransition(Ready, onHold))
+	require.True(t, custom.IsTerminal(onHold))
+	require.False(t, DefaultStateMachine.Known(onHold))
+	require.NotContains(t, DefaultTransitions[Ready], onHold)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var def RetryPolicy
+	require.True(t, def.ShouldRetry(DefaultMaxProcessingAttempts-1))
+	require.False(t, def.ShouldRetry(DefaultMaxProcessingAttempts))
+
+	p := RetryPolicy{MaxAttempts: 1}
+	require.False(t, p.ShouldRetry(1))
+}
+
+func TestValidateVisibility(t *testing.T) {
+	require.NoError(t, ValidateVisibility(Draft, Public))
+	require.NoError(t, ValidateVisibility(Public, Unlisted))
+	require.NoError(t, ValidateVisibility(Private, Private))
+	require.ErrorIs(t, ValidateVisibility(Private, Draft), ErrInvalidTransition)
+	require.ErrorIs(t, ValidateVisibility("hidden", Public), ErrInvalidTransition)
+}
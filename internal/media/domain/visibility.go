@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Visibility string
+
+const (
+	Draft    Visibility = "draft"
+	Private  Visibility = "private"
+	Unlisted Visibility = "unlisted"
+	Public   Visibility = "public"
+)
+
+// VisibilityTransitions — допустимые смены видимости. Из draft медиа выходит один раз:
+// показанное хоть кому-то медиа черновиком уже не станет, дальше — любая из остальных.
+var VisibilityTransitions = map[Visibility][]Visibility{
+	Draft:    {Private, Unlisted, Public},
+	Private:  {Unlisted, Public},
+	Unlisted: {Private, Public},
+	Public:   {Private, Unlisted},
+}
+
+// ValidateVisibility проверяет смену видимости from → to; повтор той же видимости допустим
+func ValidateVisibility(from, to Visibility) error {
+	targets, ok := VisibilityTransitions[from]
+	if !ok {
+		return fmt.Errorf("%w: unknown visibility %q", ErrInvalidTransition, from)
+	}
+	if from == to {
+		return nil
+	}
+	if !slices.Contains(targets, to) {
+		return fmt.Errorf("%w: visibility %s -> %s", ErrInvalidTransition, from, to)
+	}
+	return nil
+}
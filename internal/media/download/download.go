@@ -0,0 +1,227 @@
+// Package download — ссылки на скачивание исходников медиа. Клиент не видит source
+// (внутренний путь бакета или диска): S3 объекты отдаются presigned URL хранилища,
+// остальные — через proxy media сервиса по ссылке, подписанной HMAC. Ссылка с привязкой
+// к IP всегда идёт через proxy: presigned URL S3 к адресу не привязать.
+package download
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// ErrInvalidLink — подпись proxy ссылки не сошлась, ссылка истекла или открыта с другого IP
+var ErrInvalidLink = errors.New("invalid download link")
+
+// minSecretLength — HMAC ключ короче 256 бит подбирается слишком легко
+const minSecretLength = 32
+
+// Способы выдачи исходника
+const (
+	MethodPresigned = "presigned"
+	MethodProxy     = "proxy"
+)
+
+// Presigner выдаёт presigned URL хранилища; реализуется *blob.S3Store, *blob.GCSStore
+// и *blob.AzureStore (SAS).
+// blob.ErrUnsupportedSource — source не из этого хранилища, ссылка пойдёт через proxy.
+type Presigner interface {
+	Presign(source string, ttl time.Duration) (string, error)
+}
+
+// Config содержит конфигурацию Links
+type Config struct {
+	Secret []byte // ключ HMAC proxy ссылок, не короче 32 байт
+	// BaseURL — внешний адрес media API для proxy ссылок (https://media.example.com);
+	// пустой — ссылка относительная (/media/{id}/download/content?...)
+	BaseURL   string
+	TTL       time.Duration // срок ссылки по умолчанию (default: 15m)
+	MaxTTL    time.Duration // предел срока, который может запросить клиент (default: 24h)
+	Presigner Presigner     // nil — все ссылки через proxy
+	Sources   blob.Reader   // откуда proxy читает исходники; nil — proxy недоступен
+}
+
+// Options — параметры одной ссылки
+type Options struct {
+	TTL      time.Duration // 0 — Config.TTL
+	ClientIP string        // непустой — ссылка действует только для запросов с этого адреса
+}
+
+// Link — выданная ссылка
+type Link struct {
+	URL       string
+	ExpiresAt time.Time
+	Method    string // presigned, proxy
+}
+
+// Links выдаёт и проверяет ссылки на скачивание
+type Links struct {
+	secret    []byte
+	baseURL   string
+	ttl       time.Duration
+	maxTTL    time.Duration
+	presigner Presigner
+	sources   blob.Reader
+	clock     func() time.Time
+}
+
+func New(cfg Config) (*Links, error) {
+	if len(cfg.Secret) < minSecretLength {
+		return nil, fmt.Errorf("download secret must be at least %d bytes, got: %d", minSecretLength, len(cfg.Secret))
+	}
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid download base url %q", cfg.BaseURL)
+		}
+	}
+	if cfg.TTL < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative, got: %v", cfg.TTL)
+	}
+	if cfg.MaxTTL < 0 {
+		return nil, fmt.Errorf("max ttl cannot be negative, got: %v", cfg.MaxTTL)
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 15 * time.Minute
+	}
+	if cfg.MaxTTL == 0 {
+		cfg.MaxTTL = 24 * time.Hour
+	}
+	if cfg.TTL > cfg.MaxTTL {
+		return nil, fmt.Errorf("ttl %v exceeds max ttl %v", cfg.TTL, cfg.MaxTTL)
+	}
+	return &Links{
+		secret:    cfg.Secret,
+		baseURL:   strings.TrimSuffix(cfg.BaseURL, "/"),
+		ttl:       cfg.TTL,
+		maxTTL:    cfg.MaxTTL,
+		presigner: cfg.Presigner,
+		sources:   cfg.Sources,
+		clock:     time.Now,
+	}, nil
+}
+
+// TTL — срок ссылки по умолчанию
+func (l *Links) TTL() time.Duration { return l.ttl }
+
+// MaxTTL — предел срока ссылки
+func (l *Links) MaxTTL() time.Duration { return l.maxTTL }
+
+// Downloadable проверяет, что исходник медиа можно отдать клиенту
+func Downloadable(m *models.Media) error {
+	switch m.Status {
+	case models.QuarantinedStatus:
+		return fmt.Errorf("%w: quarantined media cannot be downloaded", models.ErrConflict)
+	case models.ArchivedStatus:
+		return fmt.Errorf("%w: archived media source is in cold storage", models.ErrConflict)
+	case models.ScheduledStatus:
+		return fmt.Errorf("%w: media is embargoed until publish_at", models.ErrConflict)
+	case models.DeletedStatus:
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// Link выдаёт ссылку на исходник m: presigned URL, если хранилище его умеет и нет привязки
+// к IP, иначе подписанную ссылку на proxy
+func (l *Links) Link(m *models.Media, opts Options) (Link, error) {
+	if err := Downloadable(m); err != nil {
+		return Link{}, err
+	}
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = l.ttl
+	}
+	if ttl < time.Second || ttl > l.maxTTL {
+		return Link{}, fmt.Errorf("%w: ttl must be between 1s and %v", models.ErrInvalidArgument, l.maxTTL)
+	}
+	expires := l.clock().Add(ttl).Truncate(time.Second)
+
+	if l.presigner != nil && opts.ClientIP == "" {
+		u, err := l.presigner.Presign(m.Source, ttl)
+		switch {
+		case err == nil:
+			return Link{URL: u, ExpiresAt: expires, Method: MethodPresigned}, nil
+		case !errors.Is(err, blob.ErrUnsupportedSource):
+			return Link{}, fmt.Errorf("presign %s: %w", m.ID, err)
+		}
+	}
+
+	if l.sources == nil {
+		return Link{}, fmt.Errorf("%w: source of media %s cannot be downloaded", models.ErrConflict, m.ID)
+	}
+	q := url.Values{"expires": {strconv.FormatInt(expires.Unix(), 10)}}
+	if opts.ClientIP != "" {
+		q.Set("ip", opts.ClientIP)
+	}
+	q.Set("sig", l.sign(m.ID, q.Get("expires"), opts.ClientIP))
+	return Link{
+		URL:       l.baseURL + "/media/" + m.ID.String() + "/download/content?" + q.Encode(),
+		ExpiresAt: expires,
+		Method:    MethodProxy,
+	}, nil
+}
+
+// Verify проверяет параметры proxy ссылки на медиа id, открытой с адреса clientIP
+func (l *Links) Verify(id uuid.UUID, q url.Values, clientIP string) error {
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrInvalidLink
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(q.Get("sig"))
+	if err != nil {
+		return ErrInvalidLink
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(l.sign(id, q.Get("expires"), q.Get("ip")))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalidLink
+	}
+	if l.clock().Unix() >= expires {
+		return fmt.Errorf("%w: link expired", ErrInvalidLink)
+	}
+	if ip := q.Get("ip"); ip != "" && ip != clientIP {
+		return fmt.Errorf("%w: link is bound to another address", ErrInvalidLink)
+	}
+	return nil
+}
+
+// Open открывает исходник m для proxy
+func (l *Links) Open(ctx context.Context, m *models.Media) (io.ReadCloser, error) {
+	if err := Downloadable(m); err != nil {
+		return nil, err
+	}
+	if l.sources == nil {
+		return nil, fmt.Errorf("%w: source of media %s cannot be downloaded", models.ErrConflict, m.ID)
+	}
+	body, err := l.sources.Open(ctx, m.Source)
+	switch {
+	case errors.Is(err, blob.ErrNotFound):
+		return nil, fmt.Errorf("%w: source of media %s", models.ErrNotFound, m.ID)
+	case errors.Is(err, blob.ErrUnsupportedSource):
+		return nil, fmt.Errorf("%w: source of media %s cannot be downloaded", models.ErrConflict, m.ID)
+	case err != nil:
+		return nil, err
+	}
+	return body, nil
+}
+
+// sign — HMAC-SHA256 от id, срока и адреса; адрес подписывается и пустым,
+// чтобы ссылку без привязки нельзя было перепривязать и наоборот
+func (l *Links) sign(id uuid.UUID, expires, ip string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(id.String() + "\n" + expires + "\n" + ip))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
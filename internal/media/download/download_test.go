@@ -0,0 +1,150 @@
+package download
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// fakePresigner подписывает только s3:// source
+type fakePresigner struct{}
+
+func (fakePresigner) Presign(source string, ttl time.Duration) (string, error) {
+	if !strings.HasPrefix(source, "s3://") {
+		return "", blob.ErrUnsupportedSource
+	}
+	return "https://s3.example.com/signed?ttl=" + ttl.String(), nil
+}
+
+// memoryReader отдаёт содержимое по source
+type memoryReader map[string]string
+
+func (r memoryReader) Open(_ context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "file://") {
+		return nil, blob.ErrUnsupportedSource
+	}
+	data, ok := r[source]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func newLinks(t *testing.T, now time.Time) *Links {
+	t.Helper()
+	l, err := New(Config{
+		Secret:    secret,
+		BaseURL:   "https://media.example.com/",
+		Presigner: fakePresigner{},
+		Sources:   memoryReader{"file:///data/a.mp4": "frames"},
+	})
+	require.NoError(t, err)
+	l.clock = func() time.Time { return now }
+	return l
+}
+
+func TestLinks_PresignedForS3(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLinks(t, now)
+	m := &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Source: "s3://media/a.mp4"}
+
+	link, err := l.Link(m, Options{TTL: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, MethodPresigned, link.Method)
+	require.Equal(t, "https://s3.example.com/signed?ttl=1h0m0s", link.URL)
+	require.Equal(t, now.Add(time.Hour), link.ExpiresAt)
+
+	// Привязка к IP — только через proxy
+	link, err = l.Link(m, Options{ClientIP: "203.0.113.7"})
+	require.NoError(t, err)
+	require.Equal(t, MethodProxy, link.Method)
+	require.NotContains(t, link.URL, "s3://")
+}
+
+func TestLinks_ProxyRoundTrip(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLinks(t, now)
+	m := &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Source: "file:///data/a.mp4"}
+
+	link, err := l.Link(m, Options{})
+	require.NoError(t, err)
+	require.Equal(t, MethodProxy, link.Method)
+	require.Equal(t, now.Add(15*time.Minute), link.ExpiresAt)
+	require.True(t, strings.HasPrefix(link.URL, "https://media.example.com/media/"+m.ID.String()+"/download/content?"))
+	require.NotContains(t, link.URL, "/data/a.mp4")
+
+	u, err := url.Parse(link.URL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.NoError(t, l.Verify(m.ID, q, "198.51.100.1"), "unbound link works from any address")
+	require.ErrorIs(t, l.Verify(uuid.New(), q, ""), ErrInvalidLink)
+
+	tampered := url.Values{"expires": {"9999999999"}, "sig": q["sig"]}
+	require.ErrorIs(t, l.Verify(m.ID, tampered, ""), ErrInvalidLink)
+	rebound := url.Values{"expires": q["expires"], "sig": q["sig"], "ip": {"198.51.100.1"}}
+	require.ErrorIs(t, l.Verify(m.ID, rebound, "198.51.100.1"), ErrInvalidLink)
+
+	l.clock = func() time.Time { return now.Add(16 * time.Minute) }
+	require.ErrorIs(t, l.Verify(m.ID, q, ""), ErrInvalidLink)
+
+	body, err := l.Open(context.Background(), m)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "frames", string(data))
+}
+
+func TestLinks_BoundToIP(t *testing.T) {
+	l := newLinks(t, time.Now())
+	m := &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Source: "file:///data/a.mp4"}
+
+	link, err := l.Link(m, Options{ClientIP: "203.0.113.7"})
+	require.NoError(t, err)
+	u, err := url.Parse(link.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Verify(m.ID, u.Query(), "203.0.113.7"))
+	require.ErrorIs(t, l.Verify(m.ID, u.Query(), "198.51.100.1"), ErrInvalidLink)
+}
+
+func TestLinks_Errors(t *testing.T) {
+	l := newLinks(t, time.Now())
+	ctx := context.Background()
+
+	for status, want := range map[models.Status]error{
+		models.QuarantinedStatus: models.ErrConflict,
+		models.ArchivedStatus:    models.ErrConflict,
+		models.DeletedStatus:     models.ErrNotFound,
+	} {
+		m := &models.Media{ID: uuid.New(), Status: status, Source: "s3://media/a.mp4"}
+		_, err := l.Link(m, Options{})
+		require.ErrorIs(t, err, want, status)
+		_, err = l.Open(ctx, m)
+		require.ErrorIs(t, err, want, status)
+	}
+
+	m := &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Source: "s3://media/a.mp4"}
+	_, err := l.Link(m, Options{TTL: 25 * time.Hour})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	_, err = l.Open(ctx, &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Source: "file:///data/missing.mp4"})
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = l.Open(ctx, m)
+	require.ErrorIs(t, err, models.ErrConflict)
+
+	_, err = New(Config{Secret: []byte("short")})
+	require.Error(t, err)
+	_, err = New(Config{Secret: secret, TTL: 2 * time.Hour, MaxTTL: time.Hour})
+	require.Error(t, err)
+}
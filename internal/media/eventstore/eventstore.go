@@ -0,0 +1,113 @@
+// Package eventstore — журнал доменных событий media: полная история каждого агрегата
+// с номерами в потоке. В отличие от outbox события не удаляются после публикации,
+// по ним восстанавливается состояние media (Rebuild) для аудита и отладки.
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// AnyVersion — expected для Append без проверки версии потока
+const AnyVersion int64 = -1
+
+// ErrVersionConflict — поток изменился после того, как вызывающий прочитал его версию
+var ErrVersionConflict = fmt.Errorf("%w: event stream version conflict", models.ErrConflict)
+
+// Event — событие в потоке агрегата
+type Event struct {
+	Sequence   int64 // номер в потоке: с 1, без пропусков
+	Position   int64 // сквозной номер в журнале всех агрегатов, возрастает; задаёт порядок для проекций
+	RecordedAt time.Time
+	events.Envelope
+}
+
+// Store — потоки событий агрегатов
+type Store interface {
+	// Append дописывает события в конец потока aggregateID и возвращает новую версию
+	// (номер последнего события). expected — версия, которую видел вызывающий (0 — пустой
+	// поток): если поток успел измениться, ничего не пишется и возвращается ErrVersionConflict.
+	Append(ctx context.Context, aggregateID uuid.UUID, expected int64, envs ...events.Envelope) (int64, error)
+	// Load возвращает события потока с номерами больше after по порядку
+	Load(ctx context.Context, aggregateID uuid.UUID, after int64) ([]Event, error)
+}
+
+// Feed — журнал событий всех агрегатов по порядку записи; его читают проекции
+type Feed interface {
+	// ReadAll возвращает не больше limit событий с Position больше after по возрастанию
+	ReadAll(ctx context.Context, after int64, limit int) ([]Event, error)
+}
+
+// Outbox — запись событий сервиса; совпадает с service.Outbox
+type Outbox interface {
+	Add(ctx context.Context, event models.DomainEvent) error
+}
+
+// BatchOutbox — Outbox с записью пачки событий; совпадает с service.BatchOutbox
+type BatchOutbox interface {
+	AddBatch(ctx context.Context, batch []models.DomainEvent) error
+}
+
+// Recorder — Outbox, который вдобавок пишет каждое событие в Store. Сервис вызывает Add
+// внутри транзакции изменения состояния, поэтому и событие outbox, и запись в потоке
+// фиксируются вместе с ним. Порядок в потоке задаёт транзакция: изменения одного media
+// сериализуются блокировкой его строки, поэтому Append идёт с AnyVersion.
+type Recorder struct {
+	next     Outbox
+	store    Store
+	registry *events.Registry
+}
+
+// NewRecorder оборачивает next; next может быть nil — тогда события пишутся только в store
+func NewRecorder(next Outbox, store Store) *Recorder {
+	return &Recorder{next: next, store: store, registry: events.Default}
+}
+
+// AddBatch — Add для пачки событий: в next пишет одним вызовом, если next — BatchOutbox,
+// в store — по потоку каждого события
+func (r *Recorder) AddBatch(ctx context.Context, batch []models.DomainEvent) error {
+	if next, ok := r.next.(BatchOutbox); ok {
+		if err := next.AddBatch(ctx, batch); err != nil {
+			return err
+		}
+	} else if r.next != nil {
+		for _, event := range batch {
+			if err := r.next.Add(ctx, event); err != nil {
+				return err
+			}
+		}
+	}
+	for _, event := range batch {
+		if err := r.append(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) Add(ctx context.Context, event models.DomainEvent) error {
+	if r.next != nil {
+		if err := r.next.Add(ctx, event); err != nil {
+			return err
+		}
+	}
+	return r.append(ctx, event)
+}
+
+// append дописывает событие в поток его агрегата
+func (r *Recorder) append(ctx context.Context, event models.DomainEvent) error {
+	env, err := r.registry.Wrap(event)
+	if err != nil {
+		return fmt.Errorf("wrap event: %w", err)
+	}
+	if _, err := r.store.Append(ctx, event.AggregateID(), AnyVersion, env); err != nil {
+		return fmt.Errorf("append event stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+type recordingOutbox struct {
+	events []models.DomainEvent
+	err    error
+}
+
+func (o *recordingOutbox) Add(_ context.Context, event models.DomainEvent) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+func wrap(t *testing.T, ev models.DomainEvent) events.Envelope {
+	t.Helper()
+	env, err := events.Default.Wrap(ev)
+	require.NoError(t, err)
+	return env
+}
+
+func TestMemory_Append(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	id := uuid.New()
+	m := &models.Media{ID: id, Type: models.Video, Source: "s3://a", Status: models.UploadedStatus}
+
+	version, err := s.Append(ctx, id, 0, wrap(t, models.NewMediaCreated(m)))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	// Второй писатель прочитал поток до первого
+	_, err = s.Append(ctx, id, 0, wrap(t, models.NewMediaStatusChanged(id, uuid.Nil, models.UploadedStatus, models.ProcessingStatus, "", "")))
+	require.ErrorIs(t, err, ErrVersionConflict)
+	require.ErrorIs(t, err, models.ErrConflict)
+
+	version, err = s.Append(ctx, id, 1,
+		wrap(t, models.NewMediaStatusChanged(id, uuid.Nil, models.UploadedStatus, models.ProcessingStatus, "", "")),
+		wrap(t, models.NewMediaStatusChanged(id, uuid.Nil, models.ProcessingStatus, models.ReadyStatus, "", "")),
+	)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), version)
+	version, err = s.Append(ctx, id, AnyVersion)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), version)
+
+	stream, err := s.Load(ctx, id, 1)
+	require.NoError(t, err)
+	require.Len(t, stream, 2)
+	require.Equal(t, int64(2), stream[0].Sequence)
+	require.Equal(t, "MediaStatusChanged", stream[0].EventType)
+
+	stream, err = s.Load(ctx, uuid.New(), 0)
+	require.NoError(t, err)
+	require.Empty(t, stream)
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	outbox, store := &recordingOutbox{}, NewMemory()
+	r := NewRecorder(outbox, store)
+	m := &models.Media{ID: uuid.New(), Type: models.Audio, Source: "s3://a", Status: models.UploadedStatus}
+
+	created := models.NewMediaCreated(m)
+	require.NoError(t, r.Add(ctx, created))
+	require.Len(t, outbox.events, 1)
+	stream, err := store.Load(ctx, m.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, stream, 1)
+	require.Equal(t, created.EventID().String(), stream[0].EventID, "same event id as in outbox")
+
+	// Ошибка outbox — в поток ничего не пишется, транзакция сервиса откатится
+	outbox.err = errors.New("db is down")
+	require.Error(t, r.Add(ctx, models.NewMediaDeleted(m, models.DeleteReasonDeleted, time.Now())))
+	stream, err = store.Load(ctx, m.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, stream, 1)
+}
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	owner := uuid.New()
+	m := &models.Media{ID: uuid.New(), OwnerID: owner, Type: models.Video, Source: "s3://hot/a.mp4", Status: models.UploadedStatus, CreatedAt: time.Now()}
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	public := *m
+	public.Visibility = models.PublicVisibility
+
+	for _, ev := range []models.DomainEvent{
+		models.NewMediaCreated(m),
+		models.NewMediaContentRecorded(m, models.Content{Checksum: "ab", Size: 42, ContentType: "video/mp4"}, at),
+		models.NewMediaStatusChanged(m.ID, owner, models.UploadedStatus, models.ProcessingStatus, "", ""),
+		models.NewMediaStatusChanged(m.ID, owner, models.ProcessingStatus, models.FailedStatus, "", "crashed"),
+		models.NewMediaStatusChanged(m.ID, owner, models.FailedStatus, models.ProcessingStatus, "", ""),
+		models.NewMediaVisibilityChanged(&public, models.DraftVisibility, "", at),
+		models.NewMediaAccessGranted(m, models.Grant{MediaID: m.ID, Principal: uuid.New(), Permission: models.ReadPermission, UpdatedAt: at}, "", ""),
+		models.NewMediaArchived(m, "s3://cold/a.mp4", at),
+	} {
+		_, err := s.Append(ctx, m.ID, AnyVersion, wrap(t, ev))
+		require.NoError(t, err)
+	}
+	stream, err := s.Load(ctx, m.ID, 0)
+	require.NoError(t, err)
+
+	got, err := Rebuild(stream)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, got.ID)
+	require.Equal(t, owner, got.OwnerID)
+	require.Equal(t, models.ArchivedStatus, got.Status)
+	require.Equal(t, "s3://cold/a.mp4", got.Source)
+	require.Equal(t, 2, got.ProcessingAttempts)
+	require.Equal(t, int64(42), got.Size)
+	require.Equal(t, "video/mp4", got.ContentType)
+	require.Equal(t, models.PublicVisibility, got.Visibility)
+	require.Equal(t, at, got.UpdatedAt)
+
+	// Состояние на любой момент истории — по префиксу потока
+	got, err = Rebuild(stream[:4])
+	require.NoError(t, err)
+	require.Equal(t, models.FailedStatus, got.Status)
+
+	_, err = Rebuild(nil)
+	require.ErrorIs(t, err, ErrEmptyStream)
+	_, err = Rebuild(stream[1:])
+	require.ErrorContains(t, err, "does not start with MediaCreated")
+}
+
+func TestRebuild_CreateMedia(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	svc := service.New(repository.NewMemoryRepository(), NewRecorder(&recordingOutbox{}, store))
+
+	// Поток медиа из POST /media начинается с MediaCreated, как и из пачки
+	want, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	stream, err := store.Load(ctx, want.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, stream, 1)
+	require.Equal(t, "MediaCreated", stream[0].EventType)
+
+	got, err := Rebuild(stream)
+	require.NoError(t, err)
+	require.Equal(t, want.ID, got.ID)
+	require.Equal(t, want.Status, got.Status)
+	require.Equal(t, want.Type, got.Type)
+	require.Equal(t, want.Source, got.Source)
+	require.Equal(t, want.Visibility, got.Visibility)
+
+	want, err = svc.ChangeStatus(ctx, want.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	stream, err = store.Load(ctx, want.ID, 0)
+	require.NoError(t, err)
+	got, err = Rebuild(stream)
+	require.NoError(t, err)
+	require.Equal(t, want.Status, got.Status)
+	require.Equal(t, want.ProcessingAttempts, got.ProcessingAttempts)
+}
+
+func TestLoader_Snapshots(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	m := &models.Media{ID: uuid.New(), Type: models.Video, Source: "s3://a", Status: models.UploadedStatus}
+	evs := []models.DomainEvent{models.NewMediaCreated(m)}
+	for range 2 {
+		evs = append(evs,
+			models.NewMediaStatusChanged(m.ID, uuid.Nil, models.FailedStatus, models.ProcessingStatus, "", ""),
+			models.NewMediaStatusChanged(m.ID, uuid.Nil, models.ProcessingStatus, models.FailedStatus, "", ""),
+		)
+	}
+	for _, ev := range evs {
+		_, err := s.Append(ctx, m.ID, AnyVersion, wrap(t, ev))
+		require.NoError(t, err)
+	}
+	l := NewLoader(s, s, 3)
+
+	got, version, err := l.Load(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), version)
+	require.Equal(t, models.FailedStatus, got.Status)
+	require.Equal(t, 2, got.ProcessingAttempts)
+	snap, ok, err := s.LatestSnapshot(ctx, m.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(5), snap.Version)
+
+	// После снапшота читается только хвост потока
+	_, err = s.Append(ctx, m.ID, 5, wrap(t, models.NewMediaStatusChanged(m.ID, uuid.Nil, models.FailedStatus, models.ProcessingStatus, "", "")))
+	require.NoError(t, err)
+	got, version, err = l.Load(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), version)
+	require.Equal(t, models.ProcessingStatus, got.Status)
+	require.Equal(t, 3, got.ProcessingAttempts)
+	require.Equal(t, models.FailedStatus, snap.State.Status, "snapshot state is not modified")
+
+	_, _, err = l.Load(ctx, uuid.New())
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
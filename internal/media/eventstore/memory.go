@@ -0,0 +1,90 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// Memory — Store, Feed и SnapshotStore в памяти процесса: для тестов и in-memory режима, до рестарта
+type Memory struct {
+	mu        sync.Mutex
+	streams   map[uuid.UUID][]Event
+	all       []Event // все события по Position
+	snapshots map[uuid.UUID]Snapshot
+	clock     func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		streams:   make(map[uuid.UUID][]Event),
+		snapshots: make(map[uuid.UUID]Snapshot),
+		clock:     time.Now,
+	}
+}
+
+func (m *Memory) Append(_ context.Context, aggregateID uuid.UUID, expected int64, envs ...events.Envelope) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream := m.streams[aggregateID]
+	version := int64(len(stream))
+	if expected != AnyVersion && expected != version {
+		return version, fmt.Errorf("%w: stream %s is at version %d, expected %d", ErrVersionConflict, aggregateID, version, expected)
+	}
+	now := m.clock().UTC()
+	for _, env := range envs {
+		version++
+		ev := Event{Sequence: version, Position: int64(len(m.all)) + 1, RecordedAt: now, Envelope: env}
+		stream = append(stream, ev)
+		m.all = append(m.all, ev)
+	}
+	m.streams[aggregateID] = stream
+	return version, nil
+}
+
+func (m *Memory) Load(_ context.Context, aggregateID uuid.UUID, after int64) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return tail(m.streams[aggregateID], after, 0), nil
+}
+
+func (m *Memory) ReadAll(_ context.Context, after int64, limit int) ([]Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return tail(m.all, after, limit), nil
+}
+
+// tail — события после номера after (номера идут с 1 без пропусков), не больше limit; 0 — все
+func tail(evs []Event, after int64, limit int) []Event {
+	after = max(after, 0)
+	if after >= int64(len(evs)) {
+		return nil
+	}
+	evs = evs[after:]
+	if limit > 0 && len(evs) > limit {
+		evs = evs[:limit]
+	}
+	return slices.Clone(evs)
+}
+
+func (m *Memory) SaveSnapshot(_ context.Context, s Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.snapshots[s.AggregateID]; !ok || cur.Version < s.Version {
+		m.snapshots[s.AggregateID] = s
+	}
+	return nil
+}
+
+func (m *Memory) LatestSnapshot(_ context.Context, aggregateID uuid.UUID) (Snapshot, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.snapshots[aggregateID]
+	return s, ok, nil
+}
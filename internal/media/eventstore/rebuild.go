@@ -0,0 +1,120 @@
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// ErrEmptyStream — у агрегата нет событий
+var ErrEmptyStream = errors.New("event stream is empty")
+
+// Rebuild восстанавливает media из его потока событий. Восстанавливается то, что несут
+// события: статус, владелец, тип, расположение, содержимое и счётчик попыток обработки;
+// title, теги, metadata и last_error в события не попадают и остаются пустыми.
+// Поток должен начинаться с MediaCreated.
+func Rebuild(stream []Event) (*models.Media, error) {
+	return RebuildWith(events.Default, stream)
+}
+
+// RebuildWith — Rebuild с заданным реестром событий
+func RebuildWith(registry *events.Registry, stream []Event) (*models.Media, error) {
+	if len(stream) == 0 {
+		return nil, ErrEmptyStream
+	}
+	return rebuild(registry, nil, stream)
+}
+
+// RebuildFrom применяет к состоянию base (снапшоту) события, записанные после него.
+// base не меняется.
+func RebuildFrom(base *models.Media, stream []Event) (*models.Media, error) {
+	if base == nil {
+		return Rebuild(stream)
+	}
+	m := *base
+	return rebuild(events.Default, &m, stream)
+}
+
+func rebuild(registry *events.Registry, m *models.Media, stream []Event) (*models.Media, error) {
+	for _, ev := range stream {
+		payload, err := registry.DecodeLatest(ev.Envelope)
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s): %w", ev.Sequence, ev.EventType, err)
+		}
+		if created, ok := payload.(*events.MediaCreatedV1); ok {
+			if m != nil {
+				return nil, fmt.Errorf("event %d: media %s created twice", ev.Sequence, created.MediaID)
+			}
+			m = &models.Media{
+				ID:         created.MediaID,
+				OwnerID:    created.OwnerID,
+				Type:       created.Type,
+				Source:     created.Source,
+				Status:     created.Status,
+				CreatedAt:  created.OccurredAt,
+				UpdatedAt:  created.OccurredAt,
+				Visibility: models.DraftVisibility,
+			}
+			continue
+		}
+		if m == nil {
+			return nil, fmt.Errorf("event %d (%s): stream does not start with MediaCreated", ev.Sequence, ev.EventType)
+		}
+		if err := apply(m, payload); err != nil {
+			return nil, fmt.Errorf("event %d (%s): %w", ev.Sequence, ev.EventType, err)
+		}
+		m.UpdatedAt = ev.OccurredAt
+	}
+	return m, nil
+}
+
+// apply применяет событие к состоянию media так же, как его применил MediaRepo
+func apply(m *models.Media, payload any) error {
+	var mediaID uuid.UUID
+	switch p := payload.(type) {
+	case *events.MediaStatusChangedV1:
+		mediaID = p.MediaID
+		m.Status = p.To
+		switch p.To {
+		case models.ProcessingStatus:
+			m.ProcessingAttempts++
+		case models.ReadyStatus, models.ScheduledStatus:
+			m.ProcessingAttempts = 0
+		}
+	case *events.MediaContentRecordedV1:
+		mediaID = p.MediaID
+		m.Checksum, m.Size, m.ContentType = p.Checksum, p.Size, p.ContentType
+		if p.Source != "" {
+			m.Source = p.Source
+		}
+	case *events.MediaArchivedV1:
+		mediaID = p.MediaID
+		m.Status, m.Source = models.ArchivedStatus, p.Location
+	case *events.MediaQuarantinedV1:
+		mediaID = p.MediaID
+		m.Status = models.QuarantinedStatus
+	case *events.MediaScheduledV1:
+		mediaID = p.MediaID
+		m.PublishAt, m.ExpiresAt = p.PublishAt, p.ExpiresAt
+	case *events.MediaVisibilityChangedV1:
+		mediaID = p.MediaID
+		m.Visibility = p.To
+	case *events.MediaAccessGrantedV1: // ACL хранится отдельно от медиа
+		mediaID = p.MediaID
+	case *events.MediaAccessRevokedV1:
+		mediaID = p.MediaID
+	case *events.MediaDeletedV1:
+		mediaID = p.MediaID
+		m.Status = models.DeletedStatus
+	default:
+		return fmt.Errorf("unsupported payload %T", payload)
+	}
+	if mediaID != m.ID {
+		return fmt.Errorf("event of media %s in stream of %s", mediaID, m.ID)
+	}
+	return nil
+}
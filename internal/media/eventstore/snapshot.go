@@ -0,0 +1,84 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DefaultSnapshotEvery — через сколько событий после снапшота Loader сохраняет новый
+const DefaultSnapshotEvery = 50
+
+// Snapshot — состояние media на версии потока Version
+type Snapshot struct {
+	AggregateID uuid.UUID
+	Version     int64
+	State       models.Media
+	TakenAt     time.Time
+}
+
+// SnapshotStore — снапшоты потоков
+type SnapshotStore interface {
+	// SaveSnapshot сохраняет снапшот; снапшот не новее сохранённого ничего не меняет
+	SaveSnapshot(ctx context.Context, s Snapshot) error
+	// LatestSnapshot возвращает самый новый снапшот; ok=false — снапшотов нет
+	LatestSnapshot(ctx context.Context, aggregateID uuid.UUID) (s Snapshot, ok bool, err error)
+}
+
+// Loader восстанавливает media по снапшоту и событиям после него, не перечитывая
+// весь поток. Снапшоты сохраняются лениво: при загрузке, если после последнего
+// набралось every событий.
+type Loader struct {
+	store     Store
+	snapshots SnapshotStore
+	every     int64
+	clock     func() time.Time
+}
+
+// NewLoader создаёт Loader; every <= 0 — DefaultSnapshotEvery
+func NewLoader(store Store, snapshots SnapshotStore, every int64) *Loader {
+	if every <= 0 {
+		every = DefaultSnapshotEvery
+	}
+	return &Loader{store: store, snapshots: snapshots, every: every, clock: time.Now}
+}
+
+// Load возвращает состояние media и версию потока, на которой оно построено.
+// Нет событий — models.ErrNotFound.
+func (l *Loader) Load(ctx context.Context, id uuid.UUID) (*models.Media, int64, error) {
+	snap, ok, err := l.snapshots.LatestSnapshot(ctx, id)
+	if err != nil {
+		return nil, 0, fmt.Errorf("load snapshot: %w", err)
+	}
+	var base *models.Media
+	if ok {
+		base = &snap.State
+	}
+	stream, err := l.store.Load(ctx, id, snap.Version)
+	if err != nil {
+		return nil, 0, err
+	}
+	if base == nil && len(stream) == 0 {
+		return nil, 0, fmt.Errorf("%w: event stream %s", models.ErrNotFound, id)
+	}
+	m, err := RebuildFrom(base, stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	version := snap.Version
+	if len(stream) > 0 {
+		version = stream[len(stream)-1].Sequence
+	}
+
+	if int64(len(stream)) >= l.every {
+		s := Snapshot{AggregateID: id, Version: version, State: *m, TakenAt: l.clock().UTC()}
+		if err := l.snapshots.SaveSnapshot(ctx, s); err != nil {
+			return nil, 0, fmt.Errorf("save snapshot: %w", err)
+		}
+	}
+	return m, version, nil
+}
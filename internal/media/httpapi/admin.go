@@ -0,0 +1,514 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/audit"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/purge"
+	"github.com/romariotrain/media-platform/internal/media/replay"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+const (
+	defaultAdminLimit = 50
+	maxAdminLimit     = 500
+)
+
+// OutboxAdmin — операции над outbox для операторов; реализуется *postgres.OutboxRepo
+type OutboxAdmin interface {
+	ListOutbox(ctx context.Context, f postgres.OutboxFilter) ([]postgres.OutboxRecord, error)
+	GetOutbox(ctx context.Context, id int64) (*postgres.OutboxRecord, error)
+	Requeue(ctx context.Context, id int64) error
+	DeleteOutbox(ctx context.Context, id int64) error
+}
+
+// EventReplayer переигрывает опубликованные события; реализуется *replay.Replayer
+type EventReplayer interface {
+	Replay(ctx context.Context, req replay.Request) (replay.Result, error)
+}
+
+// OwnerPurger удаляет все данные владельца фоновой задачей; реализуется *purge.Purger
+type OwnerPurger interface {
+	Request(ctx context.Context, ownerID uuid.UUID, requestedBy string) (purge.Purge, error)
+	Get(ctx context.Context, id int64) (purge.Purge, error)
+}
+
+// AdminHandler — служебные ручки для операторов, отдельно от публичного API
+type AdminHandler struct {
+	outbox OutboxAdmin
+	replay EventReplayer
+	events eventstore.Store
+	purger OwnerPurger
+	audit  audit.Store
+}
+
+func NewAdmin(outbox OutboxAdmin) *AdminHandler {
+	return &AdminHandler{outbox: outbox}
+}
+
+// WithReplay включает POST /admin/events/replay
+func (a *AdminHandler) WithReplay(r EventReplayer) *AdminHandler {
+	a.replay = r
+	return a
+}
+
+// WithEventStore включает GET /admin/events/streams/{id}
+func (a *AdminHandler) WithEventStore(s eventstore.Store) *AdminHandler {
+	a.events = s
+	return a
+}
+
+// WithPurge включает POST /admin/owners/{id}/purge и GET /admin/purges/{id}
+func (a *AdminHandler) WithPurge(p OwnerPurger) *AdminHandler {
+	a.purger = p
+	return a
+}
+
+// NewAdminRouter монтирует ручки под /admin/
+func NewAdminRouter(a *AdminHandler) http.Handler {
+	mux := http.NewServeMux()
+
+	// GET /admin/outbox?state=&event_type=&aggregate_id=
+	mux.HandleFunc("/admin/outbox", a.ListOutbox)
+
+	// GET /admin/outbox/dead-letters
+	mux.HandleFunc("/admin/outbox/dead-letters", a.ListDeadLetters)
+
+	// GET/DELETE /admin/outbox/{id}, POST /admin/outbox/{id}/requeue
+	mux.HandleFunc("/admin/outbox/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/requeue") {
+			a.RequeueOutbox(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			a.GetOutbox(w, r)
+		case http.MethodDelete:
+			a.DeleteOutbox(w, r)
+		default:
+			writeMethodNotAllowed(w, r)
+		}
+	})
+
+	// POST /admin/events/replay
+	mux.HandleFunc("/admin/events/replay", a.ReplayEvents)
+
+	// GET /admin/events/streams/{id}?after=
+	mux.HandleFunc("/admin/events/streams/", a.GetEventStream)
+
+	// POST /admin/owners/{id}/purge
+	mux.HandleFunc("/admin/owners/", a.PurgeOwner)
+
+	// GET /admin/purges/{id}
+	mux.HandleFunc("/admin/purges/", a.GetPurge)
+
+	// GET /admin/audit?actor=&owner_id=&resource_type=&resource_id=&from=&to=
+	mux.HandleFunc("/admin/audit", a.ListAudit)
+
+	if a.audit == nil {
+		return RequestID(RequireScope(AdminScope, mux))
+	}
+	// В журнал попадают и запросы без scope admin
+	return RequestID(Actor(Audit(a.audit, RequireScope(AdminScope, mux))))
+}
+
+type OutboxRecordResponse struct {
+	ID             int64           `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	SchemaVersion  int             `json:"schema_version"`
+	AggregateID    string          `json:"aggregate_id"`
+	Payload        json.RawMessage `json:"payload"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextRetryAt    *time.Time      `json:"next_retry_at,omitempty"`
+	DeadLetteredAt *time.Time      `json:"dead_lettered_at,omitempty"`
+	ProcessedAt    *time.Time      `json:"processed_at,omitempty"`
+}
+
+type OutboxRecordsResponse struct {
+	Items []OutboxRecordResponse `json:"items"`
+}
+
+// ListOutbox — GET /admin/outbox?state=pending|dead_letter&event_type=&aggregate_id=&limit=&offset=
+func (a *AdminHandler) ListOutbox(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	state := q.Get("state")
+	if state != "" && state != postgres.OutboxStatePending && state != postgres.OutboxStateDeadLetter {
+		writeValidationError(w, r, []FieldError{{
+			Field:   "state",
+			Message: fmt.Sprintf("must be one of %s, %s", postgres.OutboxStatePending, postgres.OutboxStateDeadLetter),
+		}})
+		return
+	}
+	a.listOutbox(w, r, postgres.OutboxFilter{
+		State:       state,
+		EventType:   q.Get("event_type"),
+		AggregateID: q.Get("aggregate_id"),
+	})
+}
+
+// ListDeadLetters — GET /admin/outbox/dead-letters?limit=&offset=
+func (a *AdminHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	a.listOutbox(w, r, postgres.OutboxFilter{State: postgres.OutboxStateDeadLetter})
+}
+
+func (a *AdminHandler) listOutbox(w http.ResponseWriter, r *http.Request, f postgres.OutboxFilter) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	var errs []FieldError
+	f.Limit, f.Offset, errs = parsePage(r, defaultAdminLimit, maxAdminLimit)
+	if len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	records, err := a.outbox.ListOutbox(r.Context(), f)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := OutboxRecordsResponse{Items: make([]OutboxRecordResponse, 0, len(records))}
+	for _, rec := range records {
+		resp.Items = append(resp.Items, toOutboxRecordResponse(rec))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetOutbox — GET /admin/outbox/{id}
+func (a *AdminHandler) GetOutbox(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseOutboxID(w, r, "")
+	if !ok {
+		return
+	}
+
+	rec, err := a.outbox.GetOutbox(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toOutboxRecordResponse(*rec))
+}
+
+// RequeueOutbox — POST /admin/outbox/{id}/requeue: возвращает припаркованное событие в очередь
+func (a *AdminHandler) RequeueOutbox(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	id, ok := parseOutboxID(w, r, "/requeue")
+	if !ok {
+		return
+	}
+
+	if err := a.outbox.Requeue(r.Context(), id); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteOutbox — DELETE /admin/outbox/{id}: событие не будет опубликовано
+func (a *AdminHandler) DeleteOutbox(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseOutboxID(w, r, "")
+	if !ok {
+		return
+	}
+
+	if err := a.outbox.DeleteOutbox(r.Context(), id); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReplayRequest — тело POST /admin/events/replay. Нужен aggregate_id или from/to;
+// mode: outbox — снова поставить события в outbox, topic — опубликовать в топик replay.
+type ReplayRequest struct {
+	AggregateID string     `json:"aggregate_id"`
+	EventType   string     `json:"event_type"`
+	From        *time.Time `json:"from"`
+	To          *time.Time `json:"to"`
+	Mode        string     `json:"mode"`
+	Topic       string     `json:"topic"` // mode topic: пустой — топик replay сервиса
+	Limit       int        `json:"limit"` // 0 — все подходящие события
+}
+
+// ReplayResponse — ответ POST /admin/events/replay
+type ReplayResponse struct {
+	Mode     string `json:"mode"`
+	Topic    string `json:"topic,omitempty"`
+	Replayed int64  `json:"replayed"`
+}
+
+// ReplayEvents — POST /admin/events/replay: переигрывает опубликованные события агрегата
+// или интервала времени. Выполняется синхронно; большие интервалы лучше делить или
+// переигрывать командой media events replay.
+func (a *AdminHandler) ReplayEvents(w http.ResponseWriter, r *http.Request) {
+	if a.replay == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "event replay is not configured", nil)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	var req ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+
+	rr := replay.Request{
+		Filter: postgres.OutboxReplayFilter{AggregateID: req.AggregateID, EventType: req.EventType},
+		Mode:   req.Mode,
+		Topic:  req.Topic,
+		Limit:  req.Limit,
+	}
+	if req.From != nil {
+		rr.Filter.From = *req.From
+	}
+	if req.To != nil {
+		rr.Filter.To = *req.To
+	}
+	if err := rr.Validate(); err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, err.Error(), nil)
+		return
+	}
+	res, err := a.replay.Replay(r.Context(), rr)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ReplayResponse{Mode: res.Mode, Topic: res.Topic, Replayed: res.Replayed})
+}
+
+// StreamEventResponse — событие потока агрегата
+type StreamEventResponse struct {
+	Sequence      int64           `json:"sequence"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	SchemaVersion int             `json:"schema_version"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	RecordedAt    time.Time       `json:"recorded_at"`
+}
+
+// EventStreamResponse — ответ GET /admin/events/streams/{id}. state — media, восстановленное
+// по всему потоку (eventstore.Rebuild); если поток не восстанавливается — rebuild_error.
+type EventStreamResponse struct {
+	AggregateID  string                `json:"aggregate_id"`
+	Version      int64                 `json:"version"`
+	Events       []StreamEventResponse `json:"events"`
+	State        *MediaResponse        `json:"state,omitempty"`
+	RebuildError string                `json:"rebuild_error,omitempty"`
+}
+
+// GetEventStream — GET /admin/events/streams/{id}?after=: история событий media из журнала
+// событий и состояние, восстановленное по ней. С after отдаются только события после него,
+// состояние при этом всё равно строится по всему потоку.
+func (a *AdminHandler) GetEventStream(w http.ResponseWriter, r *http.Request) {
+	if a.events == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "event store is not configured", nil)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	id, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/admin/events/streams/"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+	var after int64
+	if s := r.URL.Query().Get("after"); s != "" {
+		if after, err = strconv.ParseInt(s, 10, 64); err != nil || after < 0 {
+			writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "after must be a non-negative integer", nil)
+			return
+		}
+	}
+
+	stream, err := a.events.Load(r.Context(), id, 0)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if len(stream) == 0 {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "event stream not found", nil)
+		return
+	}
+	resp := EventStreamResponse{
+		AggregateID: id.String(),
+		Version:     stream[len(stream)-1].Sequence,
+		Events:      make([]StreamEventResponse, 0, len(stream)),
+	}
+	for _, ev := range stream {
+		if ev.Sequence <= after {
+			continue
+		}
+		resp.Events = append(resp.Events, StreamEventResponse{
+			Sequence:      ev.Sequence,
+			EventID:       ev.EventID,
+			EventType:     ev.EventType,
+			SchemaVersion: ev.SchemaVersion,
+			Payload:       ev.Payload,
+			OccurredAt:    ev.OccurredAt,
+			RecordedAt:    ev.RecordedAt,
+		})
+	}
+	if m, err := eventstore.Rebuild(stream); err != nil {
+		resp.RebuildError = err.Error()
+	} else {
+		state := toMediaResponse(m)
+		resp.State = &state
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PurgeResponse — запрос на удаление данных владельца и отчёт о том, что уже удалено
+type PurgeResponse struct {
+	ID          int64        `json:"id"`
+	OwnerID     string       `json:"owner_id"`
+	RequestedBy string       `json:"requested_by,omitempty"`
+	Status      purge.Status `json:"status"`
+	Report      purge.Report `json:"report"`
+	LastError   string       `json:"last_error,omitempty"`
+	RequestedAt time.Time    `json:"requested_at"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+// PurgeOwner — POST /admin/owners/{id}/purge: ставит удаление всех данных владельца (GDPR).
+// Удаление необратимо и выполняется в фоне; 202 с запросом, за ходом — GET /admin/purges/{id}.
+// Инициатор берётся из X-Actor.
+func (a *AdminHandler) PurgeOwner(w http.ResponseWriter, r *http.Request) {
+	if a.purger == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "owner purge is not configured", nil)
+		return
+	}
+	raw, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/admin/owners/"), "/purge")
+	if !ok {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "not found", nil)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	owner, err := uuid.Parse(raw)
+	if err != nil || owner == uuid.Nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid owner id", nil)
+		return
+	}
+
+	p, err := a.purger.Request(r.Context(), owner, r.Header.Get(ActorHeader))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, toPurgeResponse(p))
+}
+
+// GetPurge — GET /admin/purges/{id}: состояние удаления и отчёт
+func (a *AdminHandler) GetPurge(w http.ResponseWriter, r *http.Request) {
+	if a.purger == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "owner purge is not configured", nil)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/admin/purges/"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	p, err := a.purger.Get(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPurgeResponse(p))
+}
+
+func toPurgeResponse(p purge.Purge) PurgeResponse {
+	return PurgeResponse{
+		ID:          p.ID,
+		OwnerID:     p.OwnerID.String(),
+		RequestedBy: p.RequestedBy,
+		Status:      p.Status,
+		Report:      p.Report,
+		LastError:   p.LastError,
+		RequestedAt: p.RequestedAt,
+		CompletedAt: p.CompletedAt,
+	}
+}
+
+// parseOutboxID достаёт id из /admin/outbox/{id}{suffix}; при ошибке сам пишет ответ
+func parseOutboxID(w http.ResponseWriter, r *http.Request, suffix string) (int64, bool) {
+	s := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/admin/outbox/"), suffix)
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return 0, false
+	}
+	return id, true
+}
+
+func toOutboxRecordResponse(rec postgres.OutboxRecord) OutboxRecordResponse {
+	return OutboxRecordResponse{
+		ID:             rec.ID,
+		EventID:        rec.EventID,
+		EventType:      rec.EventType,
+		SchemaVersion:  rec.SchemaVersion,
+		AggregateID:    rec.AggregateID,
+		Payload:        rec.Payload,
+		OccurredAt:     rec.OccurredAt,
+		Attempts:       rec.Attempts,
+		LastError:      rec.LastError,
+		NextRetryAt:    rec.NextRetryAt,
+		DeadLetteredAt: rec.DeadLetteredAt,
+		ProcessedAt:    rec.ProcessedAt,
+	}
+}
+
+// parsePage читает limit/offset из query
+func parsePage(r *http.Request, defaultLimit, maxLimit int) (int, int, []FieldError) {
+	var v validator
+	limit, offset := defaultLimit, 0
+
+	q := r.URL.Query()
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxLimit {
+			v.add("limit", "must be an integer between 1 and %d", maxLimit)
+		}
+		limit = n
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			v.add("offset", "must be a non-negative integer")
+		}
+		offset = n
+	}
+	return limit, offset, v.errs
+}
@@ -0,0 +1,326 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/purge"
+	"github.com/romariotrain/media-platform/internal/media/replay"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+type fakeOutboxAdmin struct {
+	records   []postgres.OutboxRecord
+	gotFilter postgres.OutboxFilter
+	requeued  []int64
+	deleted   []int64
+}
+
+func (f *fakeOutboxAdmin) ListOutbox(ctx context.Context, filter postgres.OutboxFilter) ([]postgres.OutboxRecord, error) {
+	f.gotFilter = filter
+	return f.records, nil
+}
+
+func (f *fakeOutboxAdmin) find(id int64) *postgres.OutboxRecord {
+	for i := range f.records {
+		if f.records[i].ID == id {
+			return &f.records[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeOutboxAdmin) GetOutbox(ctx context.Context, id int64) (*postgres.OutboxRecord, error) {
+	if rec := f.find(id); rec != nil {
+		return rec, nil
+	}
+	return nil, models.ErrNotFound
+}
+
+func (f *fakeOutboxAdmin) Requeue(ctx context.Context, id int64) error {
+	rec := f.find(id)
+	if rec == nil {
+		return models.ErrNotFound
+	}
+	if rec.DeadLetteredAt == nil {
+		return models.ErrConflict
+	}
+	f.requeued = append(f.requeued, id)
+	return nil
+}
+
+func (f *fakeOutboxAdmin) DeleteOutbox(ctx context.Context, id int64) error {
+	if f.find(id) == nil {
+		return models.ErrNotFound
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func newFakeOutboxAdmin() *fakeOutboxAdmin {
+	parked := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeOutboxAdmin{records: []postgres.OutboxRecord{
+		{
+			ID:             7,
+			EventID:        "e-7",
+			EventType:      "MediaCreated",
+			SchemaVersion:  1,
+			AggregateID:    "a-7",
+			Payload:        json.RawMessage(`{"type":"video"}`),
+			Attempts:       20,
+			LastError:      "message too large",
+			DeadLetteredAt: &parked,
+		},
+		{ID: 8, EventID: "e-8", EventType: "MediaDeleted", AggregateID: "a-8", Payload: json.RawMessage(`{}`)},
+	}}
+}
+
+func adminRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set(ScopesHeader, "media:read "+AdminScope)
+	return req
+}
+
+func TestAdmin_ListDeadLetters(t *testing.T) {
+	store := newFakeOutboxAdmin()
+	store.records = store.records[:1]
+	router := NewAdminRouter(NewAdmin(store))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/outbox/dead-letters?limit=10&offset=5"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, postgres.OutboxFilter{State: postgres.OutboxStateDeadLetter, Limit: 10, Offset: 5}, store.gotFilter)
+
+	var resp OutboxRecordsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	require.Equal(t, "message too large", resp.Items[0].LastError)
+	require.Equal(t, 20, resp.Items[0].Attempts)
+	require.JSONEq(t, `{"type":"video"}`, string(resp.Items[0].Payload))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/outbox/dead-letters?limit=0"))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestAdmin_ListOutboxFilters(t *testing.T) {
+	store := newFakeOutboxAdmin()
+	router := NewAdminRouter(NewAdmin(store))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/outbox?state=pending&event_type=MediaDeleted&aggregate_id=a-8"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, postgres.OutboxFilter{
+		State:       postgres.OutboxStatePending,
+		EventType:   "MediaDeleted",
+		AggregateID: "a-8",
+		Limit:       defaultAdminLimit,
+	}, store.gotFilter)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/outbox?state=processed"))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestAdmin_GetRequeueDelete(t *testing.T) {
+	store := newFakeOutboxAdmin()
+	router := NewAdminRouter(NewAdmin(store))
+
+	cases := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/admin/outbox/7", http.StatusOK},
+		{http.MethodGet, "/admin/outbox/99", http.StatusNotFound},
+		{http.MethodGet, "/admin/outbox/abc", http.StatusBadRequest},
+		{http.MethodPost, "/admin/outbox/7/requeue", http.StatusNoContent},
+		{http.MethodPost, "/admin/outbox/8/requeue", http.StatusConflict},
+		{http.MethodGet, "/admin/outbox/7/requeue", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/admin/outbox/8", http.StatusNoContent},
+		{http.MethodDelete, "/admin/outbox/99", http.StatusNotFound},
+		{http.MethodPut, "/admin/outbox/8", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, adminRequest(tc.method, tc.target))
+		require.Equal(t, tc.want, rec.Code, "%s %s", tc.method, tc.target)
+	}
+
+	require.Equal(t, []int64{7}, store.requeued)
+	require.Equal(t, []int64{8}, store.deleted)
+}
+
+func TestAdmin_RequiresAdminScope(t *testing.T) {
+	router := NewAdminRouter(NewAdmin(newFakeOutboxAdmin()))
+
+	for _, scopes := range []string{"", "media:read", "administrator"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/outbox", nil)
+		req.Header.Set(ScopesHeader, scopes)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusForbidden, rec.Code, "scopes %q", scopes)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, CodeForbidden, resp.Code)
+		require.NotEmpty(t, resp.RequestID)
+	}
+}
+
+type fakeReplayer struct {
+	got replay.Request
+}
+
+func (f *fakeReplayer) Replay(_ context.Context, req replay.Request) (replay.Result, error) {
+	f.got = req
+	return replay.Result{Mode: req.Mode, Topic: req.Topic, Replayed: 3}, nil
+}
+
+func TestAdmin_ReplayEvents(t *testing.T) {
+	replayer := &fakeReplayer{}
+	router := NewAdminRouter(NewAdmin(newFakeOutboxAdmin()).WithReplay(replayer))
+	post := func(body string) *httptest.ResponseRecorder {
+		req := adminRequest(http.MethodPost, "/admin/events/replay")
+		req.Body = io.NopCloser(strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"from":"2026-03-01T00:00:00Z","to":"2026-03-02T00:00:00Z","event_type":"MediaDeleted","mode":"topic","topic":"backfill","limit":10}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, replay.Request{
+		Filter: postgres.OutboxReplayFilter{
+			EventType: "MediaDeleted",
+			From:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			To:        time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		Mode:  replay.ModeTopic,
+		Topic: "backfill",
+		Limit: 10,
+	}, replayer.got)
+	var resp ReplayResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, ReplayResponse{Mode: replay.ModeTopic, Topic: "backfill", Replayed: 3}, resp)
+
+	rec = post(`{"mode":"outbox"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "aggregate id or time range is required")
+	rec = post(`{"aggregate_id":"a-1"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, adminRequest(http.MethodGet, "/admin/events/replay"))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	// Без replayer ручки нет
+	rec = httptest.NewRecorder()
+	NewAdminRouter(NewAdmin(newFakeOutboxAdmin())).ServeHTTP(rec, adminRequest(http.MethodPost, "/admin/events/replay"))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdmin_EventStream(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.NewMemory()
+	m := &models.Media{ID: uuid.New(), Type: models.Video, Source: "s3://a", Status: models.UploadedStatus}
+	for _, ev := range []models.DomainEvent{
+		models.NewMediaCreated(m),
+		models.NewMediaStatusChanged(m.ID, uuid.Nil, models.UploadedStatus, models.ProcessingStatus, "", ""),
+	} {
+		env, err := events.Default.Wrap(ev)
+		require.NoError(t, err)
+		_, err = store.Append(ctx, m.ID, eventstore.AnyVersion, env)
+		require.NoError(t, err)
+	}
+	router := NewAdminRouter(NewAdmin(newFakeOutboxAdmin()).WithEventStore(store))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, adminRequest(http.MethodGet, path))
+		return rec
+	}
+
+	rec := get("/admin/events/streams/" + m.ID.String() + "?after=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp EventStreamResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, int64(2), resp.Version)
+	require.Len(t, resp.Events, 1)
+	require.Equal(t, "MediaStatusChanged", resp.Events[0].EventType)
+	require.NotNil(t, resp.State, resp.RebuildError)
+	require.Equal(t, string(models.ProcessingStatus), resp.State.Status)
+	require.Equal(t, 1, resp.State.ProcessingAttempts)
+
+	require.Equal(t, http.StatusNotFound, get("/admin/events/streams/"+uuid.NewString()).Code)
+	require.Equal(t, http.StatusBadRequest, get("/admin/events/streams/42").Code)
+}
+
+type fakePurger struct {
+	purges map[int64]purge.Purge
+}
+
+func (f *fakePurger) Request(_ context.Context, ownerID uuid.UUID, requestedBy string) (purge.Purge, error) {
+	p := purge.Purge{ID: int64(len(f.purges) + 1), OwnerID: ownerID, RequestedBy: requestedBy, Status: purge.StatusPending}
+	f.purges[p.ID] = p
+	return p, nil
+}
+
+func (f *fakePurger) Get(_ context.Context, id int64) (purge.Purge, error) {
+	p, ok := f.purges[id]
+	if !ok {
+		return purge.Purge{}, purge.ErrNotFound
+	}
+	return p, nil
+}
+
+func TestAdmin_PurgeOwner(t *testing.T) {
+	purger := &fakePurger{purges: map[int64]purge.Purge{}}
+	router := NewAdminRouter(NewAdmin(newFakeOutboxAdmin()).WithPurge(purger))
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := adminRequest(method, path)
+		req.Header.Set(ActorHeader, "dpo@example.com")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	owner := uuid.New()
+
+	rec := do(http.MethodPost, "/admin/owners/"+owner.String()+"/purge")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var resp PurgeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, owner.String(), resp.OwnerID)
+	require.Equal(t, "dpo@example.com", resp.RequestedBy)
+	require.Equal(t, purge.StatusPending, resp.Status)
+
+	p := purger.purges[resp.ID]
+	p.Status, p.Report = purge.StatusRunning, purge.Report{Media: 5, Blobs: 5}
+	purger.purges[p.ID] = p
+	rec = do(http.MethodGet, "/admin/purges/1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, purge.StatusRunning, resp.Status)
+	require.Equal(t, int64(5), resp.Report.Media)
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/purges/2").Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/admin/purges/x").Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/owners/42/purge").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/admin/owners/"+owner.String()+"/purge").Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/owners/"+owner.String()).Code)
+
+	// Без purger ручек нет
+	rec = httptest.NewRecorder()
+	NewAdminRouter(NewAdmin(newFakeOutboxAdmin())).ServeHTTP(rec, adminRequest(http.MethodPost, "/admin/owners/"+owner.String()+"/purge"))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
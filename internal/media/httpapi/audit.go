@@ -0,0 +1,220 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/audit"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// WithAudit пишет каждый изменяющий запрос публичного API в журнал аудита
+func (h *Handler) WithAudit(store audit.Store) *Handler {
+	h.audit = store
+	return h
+}
+
+// WithAudit включает GET /admin/audit и пишет в журнал изменяющие запросы к /admin/
+func (a *AdminHandler) WithAudit(store audit.Store) *AdminHandler {
+	a.audit = store
+	return a
+}
+
+// Audit пишет в журнал каждый POST, PUT, PATCH и DELETE — и успешный, и отклонённый — после
+// ответа хендлера. Изменения медиа за запрос приходят из audit.Tracker (сервис должен работать
+// через audit.Repository): по записи на медиа с diff полей. Без них — одна запись с ресурсом
+// из пути. Журнал пишется вне транзакции запроса: ошибка записи только логируется, ответ
+// клиенту уже определён. Должен стоять после RequestID, Actor и Principal.
+func Audit(store audit.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ctx, tracker := audit.Track(r.Context())
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		base := audit.Entry{
+			At:        start,
+			RequestID: RequestIDFromContext(ctx),
+			Actor:     service.ActorFromContext(ctx),
+			Admin:     slices.Contains(strings.Fields(r.Header.Get(ScopesHeader)), AdminScope),
+			Method:    r.Method,
+			Endpoint:  auditEndpoint(r.URL.Path),
+			Status:    rec.status,
+		}
+		if p, ok := service.PrincipalFromContext(ctx); ok {
+			base.OwnerID = p.OwnerID
+		}
+
+		var entries []audit.Entry
+		for _, c := range tracker.Changes() {
+			e := base
+			e.ResourceType, e.ResourceID, e.Diff = c.ResourceType, c.ResourceID, c.Diff
+			entries = append(entries, e)
+		}
+		if len(entries) == 0 {
+			base.ResourceType, base.ResourceID = auditResource(r.URL.Path)
+			entries = append(entries, base)
+		}
+
+		// Запись журнала не должна теряться из-за закрытого клиентом соединения
+		if err := store.Record(context.WithoutCancel(ctx), entries); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("audit record failed")
+		}
+	})
+}
+
+// isAuditID — сегмент пути, который является идентификатором: uuid или номер
+func isAuditID(segment string) bool {
+	if _, err := uuid.Parse(segment); err == nil {
+		return true
+	}
+	n, err := strconv.ParseInt(segment, 10, 64)
+	return err == nil && n > 0
+}
+
+// auditEndpoint — путь с {id} вместо идентификаторов: записи одной ручки группируются
+func auditEndpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if isAuditID(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// auditResource — ресурс запроса по пути: тип — первый сегмент (после admin) в единственном
+// числе, id — первый идентификатор. /media/{id}/grants/{principal} — это медиа.
+func auditResource(path string) (resourceType, id string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && segments[0] == "admin" {
+		segments = segments[1:]
+	}
+	resourceType = segments[0]
+	if resourceType != audit.ResourceMedia {
+		resourceType = strings.TrimSuffix(resourceType, "s")
+	}
+	for _, s := range segments[1:] {
+		if isAuditID(s) {
+			return resourceType, s
+		}
+	}
+	return resourceType, ""
+}
+
+// AuditEntryResponse — запись журнала аудита
+type AuditEntryResponse struct {
+	ID           int64                        `json:"id"`
+	At           time.Time                    `json:"at"`
+	RequestID    string                       `json:"request_id,omitempty"`
+	Actor        string                       `json:"actor,omitempty"`
+	OwnerID      string                       `json:"owner_id,omitempty"`
+	Admin        bool                         `json:"admin"`
+	Method       string                       `json:"method"`
+	Endpoint     string                       `json:"endpoint"`
+	ResourceType string                       `json:"resource_type,omitempty"`
+	ResourceID   string                       `json:"resource_id,omitempty"`
+	Status       int                          `json:"status"`
+	Diff         map[string]audit.FieldChange `json:"diff,omitempty"`
+}
+
+type AuditEntriesResponse struct {
+	Items []AuditEntryResponse `json:"items"`
+}
+
+// ListAudit — GET /admin/audit?actor=&owner_id=&resource_type=&resource_id=&from=&to=&limit=&offset=:
+// записи журнала от новых к старым; from и to — RFC 3339, интервал [from, to)
+func (a *AdminHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if a.audit == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "audit log is not configured", nil)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	f := audit.Filter{
+		Actor:        q.Get("actor"),
+		ResourceType: q.Get("resource_type"),
+		ResourceID:   q.Get("resource_id"),
+	}
+	var errs []FieldError
+	f.Limit, f.Offset, errs = parsePage(r, defaultAdminLimit, maxAdminLimit)
+	v := validator{errs: errs}
+	if raw := q.Get("owner_id"); raw != "" {
+		owner, err := uuid.Parse(raw)
+		if err != nil {
+			v.add("owner_id", "must be a uuid")
+		}
+		f.OwnerID = owner
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		raw := q.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			v.add(p.name, "must be an RFC 3339 timestamp")
+		}
+		*p.dst = t
+	}
+	if len(v.errs) > 0 {
+		writeValidationError(w, r, v.errs)
+		return
+	}
+
+	entries, err := a.audit.List(r.Context(), f)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	resp := AuditEntriesResponse{Items: make([]AuditEntryResponse, 0, len(entries))}
+	for _, e := range entries {
+		resp.Items = append(resp.Items, toAuditEntryResponse(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func toAuditEntryResponse(e audit.Entry) AuditEntryResponse {
+	resp := AuditEntryResponse{
+		ID:           e.ID,
+		At:           e.At,
+		RequestID:    e.RequestID,
+		Actor:        e.Actor,
+		Admin:        e.Admin,
+		Method:       e.Method,
+		Endpoint:     e.Endpoint,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Status:       e.Status,
+		Diff:         e.Diff,
+	}
+	if e.OwnerID != uuid.Nil {
+		resp.OwnerID = e.OwnerID.String()
+	}
+	return resp
+}
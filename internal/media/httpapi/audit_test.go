@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/audit"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestAudit_RecordsWrites(t *testing.T) {
+	store := audit.NewMemoryStore()
+	svc := service.New(audit.NewRepository(repository.NewMemoryRepository()), nil)
+	router := NewRouter(New(svc).WithAudit(store))
+	owner := uuid.New()
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(OwnerHeader, owner.String())
+		req.Header.Set(ActorHeader, "alice")
+		req.Header.Set(RequestIDHeader, "req-"+method)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/media", `{"type":"video","source":"s3://media/a.mp4","title":"clip"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created MediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+	// Чтение в журнал не попадает
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/media/"+created.ID.String(), "").Code)
+	rec = do(http.MethodPatch, "/media/"+created.ID.String()+"/status", `{"status":"processing"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	// Отклонённый запрос — запись без diff
+	rec = do(http.MethodDelete, "/collections/"+uuid.NewString(), "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	entries, err := store.List(t.Context(), audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	rejected, changed, createdEntry := entries[0], entries[1], entries[2]
+	require.Equal(t, "/collections/{id}", rejected.Endpoint)
+	require.Equal(t, "collection", rejected.ResourceType)
+	require.Equal(t, http.StatusNotFound, rejected.Status)
+	require.Nil(t, rejected.Diff)
+
+	require.Equal(t, "PATCH", changed.Method)
+	require.Equal(t, "/media/{id}/status", changed.Endpoint)
+	require.Equal(t, audit.ResourceMedia, changed.ResourceType)
+	require.Equal(t, created.ID.String(), changed.ResourceID)
+	require.Equal(t, "alice", changed.Actor)
+	require.Equal(t, owner, changed.OwnerID)
+	require.Equal(t, "req-PATCH", changed.RequestID)
+	require.JSONEq(t, `"uploaded"`, string(changed.Diff["status"].Before))
+	require.JSONEq(t, `"processing"`, string(changed.Diff["status"].After))
+
+	require.Equal(t, created.ID.String(), createdEntry.ResourceID)
+	require.Equal(t, http.StatusCreated, createdEntry.Status)
+	require.JSONEq(t, `null`, string(createdEntry.Diff["source"].Before))
+}
+
+func TestAdminAudit(t *testing.T) {
+	store := audit.NewMemoryStore()
+	router := NewAdminRouter(NewAdmin(newFakeOutboxAdmin()).WithAudit(store))
+	do := func(method, target, scopes string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(ActorHeader, "ops")
+		if scopes != "" {
+			req.Header.Set(ScopesHeader, scopes)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/admin/outbox/7/requeue", AdminScope).Code)
+	// Запрос без scope отклоняется, но попадает в журнал
+	require.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/admin/outbox/8", "").Code)
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin/audit", "").Code)
+
+	rec := do(http.MethodGet, "/admin/audit?resource_type=outbox&actor=ops", AdminScope)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp AuditEntriesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 2)
+	require.Equal(t, "/admin/outbox/{id}", resp.Items[0].Endpoint)
+	require.Equal(t, "8", resp.Items[0].ResourceID)
+	require.False(t, resp.Items[0].Admin)
+	require.Equal(t, http.StatusForbidden, resp.Items[0].Status)
+	require.Equal(t, "/admin/outbox/{id}/requeue", resp.Items[1].Endpoint)
+	require.True(t, resp.Items[1].Admin)
+
+	rec = do(http.MethodGet, "/admin/audit?from=yesterday&owner_id=x", AdminScope)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), `"from"`)
+	require.Contains(t, rec.Body.String(), `"owner_id"`)
+}
@@ -0,0 +1,149 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// CreateMediaBatch — POST /media/batch.
+// Отвечает 200 с результатом по каждому элементу (created / conflict / invalid),
+// даже если часть элементов не создана.
+func (h *Handler) CreateMediaBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	var req CreateMediaBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > service.MaxBatchSize {
+		writeValidationError(w, r, []FieldError{{
+			Field:   "items",
+			Message: fmt.Sprintf("must contain between 1 and %d items", service.MaxBatchSize),
+		}})
+		return
+	}
+
+	results := make([]BatchItemResult, len(req.Items))
+	items := make([]service.BatchItem, 0, len(req.Items))
+	indexes := make([]int, 0, len(req.Items)) // позиция в items -> позиция в запросе
+
+	for i, it := range req.Items {
+		results[i] = BatchItemResult{Index: i}
+		if errs := it.Validate(); len(errs) > 0 {
+			results[i].Status = string(service.BatchItemInvalid)
+			results[i].Errors = errs
+			continue
+		}
+
+		if it.ID != nil && *it.ID == uuid.Nil {
+			results[i].Status = string(service.BatchItemInvalid)
+			results[i].Errors = []FieldError{{Field: "id", Message: "must not be nil uuid"}}
+			continue
+		}
+
+		item := service.BatchItem{Type: it.Type, Source: it.Source}
+		if it.ID != nil {
+			item.ID = *it.ID
+		}
+		items = append(items, item)
+		indexes = append(indexes, i)
+	}
+
+	if len(items) > 0 {
+		created, err := h.svc.CreateMediaBatch(r.Context(), items)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		for _, res := range created {
+			out := &results[indexes[res.Index]]
+			out.Status = string(res.Status)
+			if res.Media != nil {
+				m := toMediaResponse(res.Media)
+				out.Media = &m
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, CreateMediaBatchResponse{Results: results})
+}
+
+// statusBatchInvalid — элемент не прошёл валидацию полей и в сервис не попал
+const statusBatchInvalid = "invalid"
+
+// ChangeStatusBatch — PATCH /media/status/batch: переходы пачки медиа одной транзакцией,
+// например все rendition'ы завершённой группы задач обработки.
+// Отвечает 200 с результатом по каждому элементу (changed / unchanged / invalid / rejected),
+// даже если часть переходов не выполнена.
+func (h *Handler) ChangeStatusBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	var req ChangeStatusBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > service.MaxBatchSize {
+		writeValidationError(w, r, []FieldError{{
+			Field:   "items",
+			Message: fmt.Sprintf("must contain between 1 and %d items", service.MaxBatchSize),
+		}})
+		return
+	}
+
+	results := make([]StatusBatchItemResult, len(req.Items))
+	items := make([]service.StatusBatchItem, 0, len(req.Items))
+	indexes := make([]int, 0, len(req.Items)) // позиция в items -> позиция в запросе
+
+	for i, it := range req.Items {
+		results[i] = StatusBatchItemResult{Index: i, ID: it.ID}
+		errs := (ChangeStatusRequest{Status: it.Status, Reason: it.Reason}).Validate()
+		if it.ID == uuid.Nil {
+			errs = append(errs, FieldError{Field: "id", Message: "is required"})
+		}
+		if len(errs) > 0 {
+			results[i].Status = statusBatchInvalid
+			results[i].Errors = errs
+			continue
+		}
+		items = append(items, service.StatusBatchItem{ID: it.ID, Status: it.Status, Reason: it.Reason})
+		indexes = append(indexes, i)
+	}
+
+	if len(items) > 0 {
+		changed, err := h.svc.ChangeStatusBatch(r.Context(), items)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		for _, res := range changed {
+			out := &results[indexes[res.Index]]
+			out.Status = string(res.Outcome)
+			if res.Media != nil {
+				m := toMediaResponse(res.Media)
+				out.Media = &m
+			}
+			if res.Err != nil {
+				out.Code = apierr.Lookup(res.Err).Code
+				out.Message = res.Err.Error()
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, ChangeStatusBatchResponse{Results: results})
+}
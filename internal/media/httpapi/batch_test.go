@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestCreateMediaBatch_SizeLimits(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	tooMany := `{"items":[` + strings.TrimSuffix(strings.Repeat(`{"type":"video","source":"s3://b/k"},`, service.MaxBatchSize+1), ",") + `]}`
+
+	for name, body := range map[string]string{
+		"empty":    `{"items":[]}`,
+		"too many": tooMany,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/media/batch", strings.NewReader(body)))
+			require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		})
+	}
+}
+
+func TestCreateMediaBatch_InvalidItemsReportedPerIndex(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	// Все элементы невалидны — сервис не вызывается, результаты по каждому индексу
+	body := fmt.Sprintf(`{"items":[%s,%s,%s]}`,
+		`{"type":"image","source":"s3://b/k"}`,
+		`{"type":"video","source":"relative"}`,
+		`{"id":"00000000-0000-0000-0000-000000000000","type":"video","source":"s3://b/k"}`,
+	)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/media/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp CreateMediaBatchResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+
+	wantFields := []string{"type", "source", "id"}
+	for i, res := range resp.Results {
+		require.Equal(t, i, res.Index)
+		require.Equal(t, "invalid", res.Status)
+		require.Equal(t, []string{wantFields[i]}, fieldsOf(res.Errors))
+	}
+}
+
+func TestChangeStatusBatch_PerItemResults(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	var ids []uuid.UUID
+	for range 2 {
+		m := &models.Media{ID: uuid.New(), Status: models.ProcessingStatus, Type: models.Video, Source: "s3://b/k"}
+		require.NoError(t, repo.Create(ctx, m))
+		ids = append(ids, m.ID)
+	}
+	router := NewRouter(New(service.New(repo, nil)))
+
+	body := fmt.Sprintf(`{"items":[%s,%s,%s,%s,%s]}`,
+		`{"id":"`+ids[0].String()+`","status":"ready"}`,
+		`{"id":"`+ids[1].String()+`","status":"failed"}`,
+		`{"id":"`+uuid.NewString()+`","status":"ready"}`,
+		`{"id":"`+ids[1].String()+`","status":"uploaded"}`,
+		`{"id":"`+ids[1].String()+`","status":"failed","reason":"codec not supported"}`,
+	)
+	req := httptest.NewRequest(http.MethodPatch, "/media/status/batch", strings.NewReader(body))
+	req.Header.Set(ScopesHeader, InternalScope)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp ChangeStatusBatchResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 5)
+
+	require.Equal(t, "changed", resp.Results[0].Status)
+	require.Equal(t, string(models.ReadyStatus), resp.Results[0].Media.Status)
+	// failed без причины не доходит до сервиса
+	require.Equal(t, "invalid", resp.Results[1].Status)
+	require.Equal(t, []string{"reason"}, fieldsOf(resp.Results[1].Errors))
+	require.Equal(t, "rejected", resp.Results[2].Status)
+	require.Equal(t, apierr.CodeNotFound, resp.Results[2].Code)
+	require.Equal(t, "rejected", resp.Results[3].Status)
+	require.Equal(t, apierr.CodeInvalidTransition, resp.Results[3].Code)
+	// Повтор id среди дошедших до сервиса элементов (3-й) отклоняется; 1-й отсеян валидацией
+	require.Equal(t, "rejected", resp.Results[4].Status)
+	require.Equal(t, apierr.CodeInvalidArgument, resp.Results[4].Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/media/status/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
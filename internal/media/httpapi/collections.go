@@ -0,0 +1,226 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// listCollectionsSpec — параметры GET /collections
+var listCollectionsSpec = listSpec{
+	sorts:       []string{string(repository.SortCreatedAt)},
+	defaultSort: "-" + string(repository.SortCreatedAt),
+	filters:     []string{"owner_id"},
+}
+
+// Collections — POST /collections (создание), GET /collections?limit=&cursor=&sort=-created_at&filter[owner_id]=
+// (свои коллекции; filter[owner_id] — только со scope admin)
+func (h *Handler) Collections(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.createCollection(w, r)
+	case http.MethodGet:
+		h.listCollections(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *Handler) createCollection(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req CreateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	c, err := h.svc.CreateCollection(r.Context(), req.Title, req.Description)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toCollectionResponse(c))
+}
+
+func (h *Handler) listCollections(w http.ResponseWriter, r *http.Request) {
+	params, errs := parseListParams(r.URL.Query(), listCollectionsSpec)
+	// Лишняя запись показывает, есть ли следующая страница
+	filter := repository.CollectionFilter{Ascending: !params.Desc, Limit: params.Limit + 1}
+	var v validator
+	if raw := params.Filter["owner_id"]; raw != "" {
+		owner, err := uuid.Parse(raw)
+		if err != nil {
+			v.add("filter[owner_id]", "must be a uuid")
+		}
+		filter.OwnerID = owner
+	}
+	if params.Cursor != "" {
+		cursor, err := decodeCursor(params.Cursor, params.sortParam())
+		switch {
+		case errors.Is(err, errForeignCursor):
+			v.add("cursor", "%v", err)
+		case err != nil:
+			v.add("cursor", "is malformed")
+		}
+		filter.After = &cursor
+	}
+	if errs = append(errs, v.errs...); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	items, err := h.svc.ListCollections(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := ListCollectionsResponse{Items: make([]CollectionResponse, 0, min(len(items), params.Limit))}
+	for _, c := range items[:min(len(items), params.Limit)] {
+		resp.Items = append(resp.Items, toCollectionResponse(c))
+	}
+	if len(items) > params.Limit {
+		resp.NextCursor = encodeCursor(params.sortParam(), repository.CollectionCursor(items[params.Limit-1]))
+		setNextPage(w, r, resp.NextCursor)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Collection — GET/PATCH/DELETE /collections/{id}, POST /collections/{id}/items,
+// DELETE /collections/{id}/items/{media_id}
+func (h *Handler) Collection(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/collections/"), "/")
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			h.getCollection(w, r, id)
+		case http.MethodPatch:
+			h.updateCollection(w, r, id)
+		case http.MethodDelete:
+			h.deleteCollection(w, r, id)
+		default:
+			writeMethodNotAllowed(w, r)
+		}
+	case len(parts) == 2 && parts[1] == "items":
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		h.addCollectionItem(w, r, id)
+	case len(parts) == 3 && parts[1] == "items":
+		if r.Method != http.MethodDelete {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		mediaID, err := uuid.Parse(parts[2])
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid media id", nil)
+			return
+		}
+		if err := h.svc.RemoveCollectionItem(r.Context(), id, mediaID); err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "not found", nil)
+	}
+}
+
+func (h *Handler) getCollection(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	c, items, err := h.svc.GetCollection(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	resp := CollectionDetailsResponse{CollectionResponse: toCollectionResponse(c), Items: make([]CollectionItemResponse, 0, len(items))}
+	for _, it := range items {
+		resp.Items = append(resp.Items, toCollectionItemResponse(it))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) updateCollection(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	defer r.Body.Close()
+
+	var req UpdateCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	c, err := h.svc.UpdateCollection(r.Context(), id, models.CollectionPatch{Title: req.Title, Description: req.Description})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCollectionResponse(c))
+}
+
+func (h *Handler) deleteCollection(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	if err := h.svc.DeleteCollection(r.Context(), id); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) addCollectionItem(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	defer r.Body.Close()
+
+	var req AddCollectionItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	item, err := h.svc.AddCollectionItem(r.Context(), id, req.MediaID, req.Position)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toCollectionItemResponse(item))
+}
+
+func toCollectionResponse(c *models.Collection) CollectionResponse {
+	return CollectionResponse{
+		ID:          c.ID,
+		OwnerID:     c.OwnerID,
+		Title:       c.Title,
+		Description: c.Description,
+		ItemCount:   c.ItemCount,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+func toCollectionItemResponse(it models.CollectionItem) CollectionItemResponse {
+	return CollectionItemResponse{MediaID: it.MediaID, Position: it.Position, AddedAt: it.AddedAt}
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestCollections_Lifecycle(t *testing.T) {
+	svc := service.New(repository.NewMemoryRepository(), nil).WithCollections(repository.NewMemoryCollectionRepository())
+	router := NewRouter(New(svc))
+	owner, other := uuid.New(), uuid.New()
+
+	do := func(method, target, body string, as uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(OwnerHeader, as.String())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	ctx := service.WithPrincipal(context.Background(), service.Principal{OwnerID: owner})
+	first, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/1.mp4")
+	require.NoError(t, err)
+	second, err := svc.CreateMedia(ctx, models.Audio, "s3://bucket/2.mp3")
+	require.NoError(t, err)
+
+	rec := do(http.MethodPost, "/collections", `{"title":""}`, owner)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	rec = do(http.MethodPost, "/collections", `{"title":"Trip","description":"summer"}`, owner)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, owner, created.OwnerID)
+	base := "/collections/" + created.ID.String()
+
+	rec = do(http.MethodPost, base+"/items", `{"media_id":"`+first.ID.String()+`"}`, owner)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = do(http.MethodPost, base+"/items", `{"media_id":"`+second.ID.String()+`","position":0}`, owner)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = do(http.MethodPost, base+"/items", `{"media_id":"`+second.ID.String()+`"}`, owner)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	rec = do(http.MethodPost, base+"/items", `{"media_id":"`+first.ID.String()+`","position":-1}`, owner)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	rec = do(http.MethodGet, base, "", owner)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var details CollectionDetailsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+	require.Equal(t, 2, details.ItemCount)
+	require.Len(t, details.Items, 2)
+	require.Equal(t, second.ID, details.Items[0].MediaID)
+	require.Equal(t, 1, details.Items[1].Position)
+
+	// Чужая коллекция не видна
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, base, "", other).Code)
+	rec = do(http.MethodGet, "/collections", "", other)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"items":[]}`, rec.Body.String())
+
+	rec = do(http.MethodPatch, base, `{"title":"Trip 2026"}`, owner)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"title":"Trip 2026"`)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, base+"/items/"+second.ID.String(), "", owner).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, base+"/items/"+second.ID.String(), "", owner).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, base+"/items", "", owner).Code)
+
+	rec = do(http.MethodGet, "/collections?limit=1", "", owner)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var list ListCollectionsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Items, 1)
+	require.Equal(t, 1, list.Items[0].ItemCount)
+	require.Empty(t, list.NextCursor)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, base, "", owner).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, base, "", owner).Code)
+}
+
+func TestCollections_NotConfigured(t *testing.T) {
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)))
+	req := httptest.NewRequest(http.MethodPost, "/collections", strings.NewReader(`{"title":"Trip"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
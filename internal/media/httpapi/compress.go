@@ -0,0 +1,239 @@
+package httpapi
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCompressMinSize — ответы короче не сжимаются: выигрыш меньше накладных расходов
+const DefaultCompressMinSize = 1024
+
+// WithCompression задаёт порог сжатия ответов (по умолчанию DefaultCompressMinSize); 0 — ответы не сжимаются
+func (h *Handler) WithCompression(minSize int) *Handler {
+	h.compressMinSize = minSize
+	return h
+}
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.BestSpeed)
+		return w
+	}}
+	zlibWriters = sync.Pool{New: func() any {
+		w, _ := zlib.NewWriterLevel(io.Discard, flate.BestSpeed)
+		return w
+	}}
+)
+
+// Compress сжимает JSON и текстовые ответы от minSize байт в gzip или deflate (zlib) — что
+// клиент принимает в Accept-Encoding, gzip при равных q. Решение принимается по первым
+// minSize байтам тела: короткий ответ уходит как есть. Не сжимаются потоки SSE и ответы,
+// которые хендлер сбрасывает (Flush) до порога, а также HEAD, 204, 304, ответы с
+// Content-Encoding или Content-Range. Сжатый ответ — другие байты, поэтому к его ETag
+// добавляется суффикс кодирования ("v" → "v-gzip"); предусловия принимают обе формы.
+func Compress(minSize int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Values("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, status: http.StatusOK}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding выбирает gzip или deflate по Accept-Encoding; "" — сжатие не принимается
+func negotiateEncoding(values []string) string {
+	best, bestQ := "", 0.0
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				parsed, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					continue
+				}
+				q = parsed
+			}
+			if name == "*" {
+				name = encodingGzip
+			}
+			if (name != encodingGzip && name != encodingDeflate) || q <= 0 {
+				continue
+			}
+			if q > bestQ || (q == bestQ && name == encodingGzip) {
+				best, bestQ = name, q
+			}
+		}
+	}
+	return best
+}
+
+// compressible — тип ответа, который имеет смысл сжимать
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"), strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	return false
+}
+
+// compressWriter копит начало тела, пока не ясно, сжимать ли ответ
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+
+	buf       []byte
+	decided   bool
+	committed bool // хендлер задал код ответа
+	enc       io.WriteCloser
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.committed || c.decided {
+		c.ResponseWriter.WriteHeader(status)
+		return
+	}
+	// Информационные ответы (103) уходят сразу и не завершают заголовки
+	if status >= 100 && status < 200 {
+		c.ResponseWriter.WriteHeader(status)
+		return
+	}
+	c.status = status
+	c.committed = true
+	if !c.eligible() {
+		_ = c.decide(false)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.committed {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.decided {
+		if c.enc != nil {
+			return c.enc.Write(p)
+		}
+		return c.ResponseWriter.Write(p)
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) < c.minSize {
+		return len(p), nil
+	}
+	if err := c.decide(true); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush отдаёт накопленное: ответ, сброшенный до порога, уходит без сжатия
+func (c *compressWriter) Flush() {
+	if !c.decided {
+		if !c.committed {
+			c.WriteHeader(http.StatusOK)
+		}
+		_ = c.decide(false)
+	}
+	if f, ok := c.enc.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	_ = http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Unwrap даёт http.ResponseController доступ к исходному writer'у
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// eligible — ответ по статусу и заголовкам можно сжать
+func (c *compressWriter) eligible() bool {
+	h := c.Header()
+	switch {
+	case c.status < http.StatusOK, c.status == http.StatusNoContent, c.status == http.StatusNotModified:
+		return false
+	case h.Get("Content-Encoding") != "", h.Get("Content-Range") != "":
+		return false
+	}
+	return compressible(h.Get("Content-Type"))
+}
+
+// decide передаёт заголовки и накопленное тело дальше — сжатым, если compress и ответ подходит
+func (c *compressWriter) decide(compress bool) error {
+	c.decided = true
+	if compress && c.eligible() {
+		h := c.Header()
+		h.Set("Content-Encoding", c.encoding)
+		h.Del("Content-Length")
+		if tag := h.Get("ETag"); tag != "" {
+			h.Set("ETag", encodedETag(tag, c.encoding))
+		}
+		switch c.encoding {
+		case encodingGzip:
+			gw := gzipWriters.Get().(*gzip.Writer)
+			gw.Reset(c.ResponseWriter)
+			c.enc = gw
+		case encodingDeflate:
+			zw := zlibWriters.Get().(*zlib.Writer)
+			zw.Reset(c.ResponseWriter)
+			c.enc = zw
+		}
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+	if len(c.buf) == 0 {
+		return nil
+	}
+	var err error
+	if c.enc != nil {
+		_, err = c.enc.Write(c.buf)
+	} else {
+		_, err = c.ResponseWriter.Write(c.buf)
+	}
+	c.buf = nil
+	return err
+}
+
+// close дописывает ответ: короткий — как есть, сжатый — с концом потока
+func (c *compressWriter) close() {
+	if !c.decided {
+		if !c.committed {
+			// Хендлер ничего не написал: net/http сам ответит 200
+			return
+		}
+		_ = c.decide(false)
+	}
+	switch enc := c.enc.(type) {
+	case *gzip.Writer:
+		_ = enc.Close()
+		gzipWriters.Put(enc)
+	case *zlib.Writer:
+		_ = enc.Close()
+		zlibWriters.Put(enc)
+	}
+	c.enc = nil
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                         "",
+		"identity":                 "",
+		"gzip":                     "gzip",
+		"deflate, gzip":            "gzip",
+		"gzip;q=0.5, deflate":      "deflate",
+		"gzip;q=0, deflate;q=0":    "",
+		"br, *":                    "gzip",
+		"GZIP;q=0.8, br;q=1":       "gzip",
+		"deflate;q=bad, gzip;q=.1": "gzip",
+	} {
+		require.Equal(t, want, negotiateEncoding([]string{header}), header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":[` + strings.Repeat(`{"id":"x"},`, 200) + `{}]}`
+	handler := Compress(DefaultCompressMinSize, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			// Тело по частям: решение принимается, когда набран порог
+			for i := 0; i < len(large); i += 100 {
+				_, _ = io.WriteString(w, large[i:min(i+100, len(large))])
+			}
+		case "/small":
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		case "/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, strings.Repeat("data: x\n\n", 500))
+		case "/flushed":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, "{")
+			_ = http.NewResponseController(w).Flush()
+			_, _ = io.WriteString(w, strings.Repeat(" ", 2000)+"}")
+		}
+	}))
+	do := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/large", "gzip, deflate")
+	require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	require.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+	// Сжатые байты — другое представление: ETag с суффиксом кодирования
+	require.Equal(t, `"v1-gzip"`, rec.Header().Get("ETag"))
+	require.Less(t, rec.Body.Len(), len(large))
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, large, string(body))
+
+	rec = do("/large", "deflate")
+	require.Equal(t, "deflate", rec.Header().Get("Content-Encoding"))
+	require.Equal(t, `"v1-deflate"`, rec.Header().Get("ETag"))
+	dr, err := zlib.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(dr)
+	require.NoError(t, err)
+	require.Equal(t, large, string(body))
+
+	rec = do("/large", "")
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+	require.Equal(t, large, rec.Body.String())
+
+	for _, path := range []string{"/small", "/stream", "/flushed"} {
+		rec = do(path, "gzip")
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		require.Empty(t, rec.Header().Get("Content-Encoding"), path)
+	}
+	require.True(t, do("/flushed", "gzip").Flushed)
+	require.JSONEq(t, `{"status":"ok"}`, do("/small", "gzip").Body.String())
+}
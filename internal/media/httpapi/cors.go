@@ -0,0 +1,290 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy — правила CORS для запросов к группе путей
+type CORSPolicy struct {
+	// AllowedOrigins — "https://app.example.com", "https://*.example.com" (любой поддомен)
+	// или "*" (любой origin). Пустой — cross-origin запросы к путям политики запрещены.
+	AllowedOrigins []string
+	// AllowedMethods — default: DefaultCORSMethods
+	AllowedMethods []string
+	// AllowedHeaders — заголовки запроса сверх CORS-safelisted; default: DefaultCORSHeaders
+	AllowedHeaders []string
+	// ExposedHeaders — заголовки ответа, доступные скрипту; default: DefaultCORSExposedHeaders
+	ExposedHeaders []string
+	// AllowCredentials — браузер шлёт cookie и Authorization; с "*" в AllowedOrigins запрещено
+	AllowCredentials bool
+	// MaxAge — сколько браузер кэширует ответ на preflight; 0 — не кэширует
+	MaxAge time.Duration
+}
+
+// CORSConfig — политика по умолчанию и политики отдельных путей
+type CORSConfig struct {
+	Default CORSPolicy
+	// Routes — политики по шаблону пути: сегмент {name} совпадает с любым сегментом,
+	// шаблон с / на конце — префикс (/shared/ — всё под /shared). Из подходящих шаблонов
+	// берётся самый длинный. Политика маршрута заменяет Default целиком.
+	Routes map[string]CORSPolicy
+}
+
+var (
+	DefaultCORSMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	}
+	DefaultCORSHeaders = []string{
+		"Authorization", "Content-Type", "If-Match", "If-None-Match",
+		IdempotencyKeyHeader, RequestIDHeader, OwnerHeader, ActorHeader,
+	}
+	DefaultCORSExposedHeaders = []string{
+		"ETag", "Location", "Link", "Retry-After", RequestIDHeader, NextCursorHeader, IdempotentReplayedHeader,
+	}
+)
+
+// CORSRules — проверенная CORSConfig
+type CORSRules struct {
+	fallback *corsPolicy
+	routes   []corsRoute // от длинных шаблонов к коротким
+}
+
+type corsRoute struct {
+	segments []string
+	prefix   bool
+	policy   *corsPolicy
+}
+
+type corsPolicy struct {
+	anyOrigin    bool
+	origins      []string // в нижнем регистре
+	suffixes     []string // ".example.com" со схемой: https://*.example.com → "https://", ".example.com"
+	schemes      []string
+	methods      []string
+	headers      []string // в нижнем регистре
+	allowMethods string
+	exposed      string
+	credentials  bool
+	maxAge       string
+}
+
+func NewCORSRules(cfg CORSConfig) (*CORSRules, error) {
+	fallback, err := newCORSPolicy(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("cors default: %w", err)
+	}
+	c := &CORSRules{fallback: fallback}
+	for pattern, p := range cfg.Routes {
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("cors route %q: pattern must start with /", pattern)
+		}
+		policy, err := newCORSPolicy(p)
+		if err != nil {
+			return nil, fmt.Errorf("cors route %q: %w", pattern, err)
+		}
+		c.routes = append(c.routes, corsRoute{
+			segments: pathSegments(pattern),
+			prefix:   strings.HasSuffix(pattern, "/"),
+			policy:   policy,
+		})
+	}
+	// Длинный шаблон точнее; при равной длине точный путь точнее префикса
+	slices.SortFunc(c.routes, func(a, b corsRoute) int {
+		if d := len(b.segments) - len(a.segments); d != 0 {
+			return d
+		}
+		switch {
+		case a.prefix == b.prefix:
+			return 0
+		case a.prefix:
+			return 1
+		default:
+			return -1
+		}
+	})
+	return c, nil
+}
+
+func newCORSPolicy(p CORSPolicy) (*corsPolicy, error) {
+	c := &corsPolicy{credentials: p.AllowCredentials}
+	for _, o := range p.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		switch {
+		case o == "*":
+			if p.AllowCredentials {
+				return nil, errors.New(`allow credentials is not allowed with origin "*"`)
+			}
+			c.anyOrigin = true
+		case strings.Contains(o, "://*."):
+			scheme, host, _ := strings.Cut(o, "*")
+			c.schemes = append(c.schemes, scheme)
+			c.suffixes = append(c.suffixes, host)
+		case strings.Contains(o, "://") && !strings.HasSuffix(o, "/"):
+			c.origins = append(c.origins, o)
+		default:
+			return nil, fmt.Errorf("origin %q must be scheme://host[:port], scheme://*.domain or *", o)
+		}
+	}
+	if p.MaxAge < 0 {
+		return nil, errors.New("max age must not be negative")
+	}
+
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	for _, m := range methods {
+		c.methods = append(c.methods, strings.ToUpper(m))
+	}
+	c.allowMethods = strings.Join(c.methods, ", ")
+	headers := p.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	for _, h := range headers {
+		c.headers = append(c.headers, strings.ToLower(h))
+	}
+	exposed := p.ExposedHeaders
+	if len(exposed) == 0 {
+		exposed = DefaultCORSExposedHeaders
+	}
+	c.exposed = strings.Join(exposed, ", ")
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return c, nil
+}
+
+// WithCORS разрешает браузерам cross-origin запросы к публичному API по правилам c
+func (h *Handler) WithCORS(c *CORSRules) *Handler {
+	h.cors = c
+	return h
+}
+
+// CORS отвечает на preflight (OPTIONS с Access-Control-Request-Method) сам, не пуская
+// его дальше: 204 с разрешёнными методами и заголовками или 403, если origin, метод или
+// заголовки не разрешены политикой пути. К остальным запросам с разрешённым Origin добавляет
+// Access-Control-Allow-Origin; с неразрешённым — пропускает без CORS заголовков, и браузер
+// не отдаёт ответ скрипту. Запросы без Origin не меняются.
+func CORS(c *CORSRules, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		// Ответ зависит от Origin: кэш не должен отдавать его запросу с другим Origin
+		h.Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p := c.policy(r.URL.Path)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if !p.allowOrigin(origin) {
+			if preflight {
+				writeError(w, r, http.StatusForbidden, CodeForbidden, "origin is not allowed", map[string]any{"origin": origin})
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", p.exposed)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		if !slices.Contains(p.methods, method) {
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "method is not allowed", map[string]any{"method": method})
+			return
+		}
+		var requested []string
+		for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+			for name := range strings.SplitSeq(value, ",") {
+				if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+					requested = append(requested, name)
+				}
+			}
+		}
+		for _, name := range requested {
+			if !slices.Contains(p.headers, name) {
+				writeError(w, r, http.StatusForbidden, CodeForbidden, "header is not allowed", map[string]any{"header": name})
+				return
+			}
+		}
+		h.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if len(requested) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+		if p.maxAge != "" {
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// policy — политика пути: самый длинный подходящий маршрут или Default
+func (c *CORSRules) policy(path string) *corsPolicy {
+	segments := pathSegments(path)
+	for _, route := range c.routes {
+		if route.match(segments) {
+			return route.policy
+		}
+	}
+	return c.fallback
+}
+
+func (r corsRoute) match(segments []string) bool {
+	if len(segments) < len(r.segments) || (!r.prefix && len(segments) != len(r.segments)) {
+		return false
+	}
+	for i, s := range r.segments {
+		if !strings.HasPrefix(s, "{") && s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.origins, origin) {
+		return true
+	}
+	for i, suffix := range p.suffixes {
+		host, ok := strings.CutPrefix(origin, p.schemes[i])
+		if ok && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestCORS_Preflight(t *testing.T) {
+	rules, err := NewCORSRules(CORSConfig{
+		Default: CORSPolicy{
+			AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+		Routes: map[string]CORSPolicy{
+			"/shared/":           {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+			"/media/{id}/status": {AllowedOrigins: []string{"https://ops.example.com"}, AllowedMethods: []string{"PATCH"}},
+		},
+	})
+	require.NoError(t, err)
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	router := NewRouter(New(svc).WithCORS(rules))
+	preflight := func(path, origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// PATCH статуса с If-Match — только с origin маршрута
+	status := "/media/" + uuid.NewString() + "/status"
+	rec := preflight(status, "https://ops.example.com", "PATCH", "Content-Type, If-Match, X-Owner-ID")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "https://ops.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "PATCH", rec.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "content-type, if-match, x-owner-id", rec.Header().Get("Access-Control-Allow-Headers"))
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+	require.Contains(t, rec.Header().Values("Vary"), "Origin")
+	require.Equal(t, http.StatusForbidden, preflight(status, "https://app.example.com", "PATCH", "").Code)
+	require.Equal(t, http.StatusForbidden, preflight(status, "https://ops.example.com", "PATCH", "X-Debug").Code)
+
+	// Остальные пути — политика по умолчанию
+	rec = preflight("/media", "https://pr-1.preview.example.com", "POST", "content-type")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "https://pr-1.preview.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+	require.Equal(t, http.StatusForbidden, preflight("/media", "https://preview.example.com", "POST", "").Code)
+	require.Equal(t, http.StatusForbidden, preflight("/media", "https://app.example.com", "TRACE", "").Code)
+
+	rec = preflight("/shared/token", "https://anywhere.test", "GET", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	rules, err := NewCORSRules(CORSConfig{Default: CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}})
+	require.NoError(t, err)
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)).WithCORS(rules))
+	do := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/media", strings.NewReader(`{"type":"video","source":"s3://media/a.mp4","title":"clip"}`))
+		req.Header.Set(OwnerHeader, uuid.NewString())
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("https://app.example.com")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, rec.Header().Get("Access-Control-Expose-Headers"), "ETag")
+
+	// Чужой origin и запрос без Origin выполняются, но без CORS заголовков
+	rec = do("https://evil.test")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, do("").Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestNewCORSRules_Validation(t *testing.T) {
+	_, err := NewCORSRules(CORSConfig{Default: CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}})
+	require.Error(t, err)
+	_, err = NewCORSRules(CORSConfig{Default: CORSPolicy{AllowedOrigins: []string{"app.example.com"}}})
+	require.Error(t, err)
+	_, err = NewCORSRules(CORSConfig{Routes: map[string]CORSPolicy{"media/": {}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,200 @@
+package httpapi
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/download"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// ForwardedForHeader — цепочка адресов, которую дописывает каждый proxy; без доверенных proxy берётся адрес соединения
+const ForwardedForHeader = "X-Forwarded-For"
+
+// DefaultTrustedProxies — перед сервисом один gateway, он дописывает адрес клиента последним
+const DefaultTrustedProxies = 1
+
+// WithTrustedProxies задаёт число доверенных proxy перед сервисом (по умолчанию DefaultTrustedProxies).
+// Адрес клиента — n-й справа в X-Forwarded-For: левее стоит то, что прислал сам клиент; 0 — заголовок не читается
+func (h *Handler) WithTrustedProxies(n int) *Handler {
+	h.trustedProxies = n
+	return h
+}
+
+// WithDownloads включает GET /media/{id}/download и proxy скачивания (по умолчанию ручки отвечают 404)
+func (h *Handler) WithDownloads(links *download.Links) *Handler {
+	h.downloads = links
+	return h
+}
+
+// Download — GET /media/{id}/download?ttl=10m&bind_ip=true: ссылка на исходник вместо source
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if h.downloads == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "downloads are not configured", nil)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/download")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+	req, errs := parseDownloadRequest(r.URL.Query(), h.downloads.MaxTTL())
+	if len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+	opts := download.Options{TTL: req.TTL}
+	if req.BindIP {
+		opts.ClientIP = h.clientIP(r)
+	}
+
+	m, err := h.svc.GetMediaForDownload(r.Context(), mediaID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	link, err := h.downloads.Link(m, opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, DownloadResponse{URL: link.URL, ExpiresAt: link.ExpiresAt, Method: link.Method})
+}
+
+// parseDownloadRequest разбирает ttl (duration, не больше maxTTL) и bind_ip
+func parseDownloadRequest(q url.Values, maxTTL time.Duration) (DownloadRequest, []FieldError) {
+	var (
+		v   validator
+		req DownloadRequest
+	)
+	if s := q.Get("ttl"); s != "" {
+		ttl, err := time.ParseDuration(s)
+		switch {
+		case err != nil:
+			v.add("ttl", "must be a duration like 10m")
+		case ttl < time.Second || ttl > maxTTL:
+			v.add("ttl", "must be between 1s and %v", maxTTL)
+		}
+		req.TTL = ttl
+	}
+	if s := q.Get("bind_ip"); s != "" {
+		bind, err := strconv.ParseBool(s)
+		if err != nil {
+			v.add("bind_ip", "must be a boolean")
+		}
+		req.BindIP = bind
+	}
+	return req, v.errs
+}
+
+// DownloadContent — GET /media/{id}/download/content?expires=...&sig=...: proxy исходника по
+// подписанной ссылке. Заголовки владельца не нужны: доступ подтверждает подпись.
+func (h *Handler) DownloadContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if h.downloads == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "downloads are not configured", nil)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/download/content")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+	if err := h.downloads.Verify(mediaID, r.URL.Query(), h.clientIP(r)); err != nil {
+		// Причину (истекла, чужой IP) не раскрываем — она в логе
+		zerolog.Ctx(r.Context()).Info().Err(err).Str("media_id", mediaID.String()).Msg("download link rejected")
+		writeError(w, r, http.StatusForbidden, CodeForbidden, "invalid or expired download link", nil)
+		return
+	}
+
+	ctx := service.WithPrincipal(r.Context(), service.Principal{Admin: true})
+	m, err := h.svc.GetMedia(ctx, mediaID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	body, err := h.downloads.Open(ctx, m)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if m.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(m.Size, 10))
+	}
+	// Имя файла — id медиа: путь source клиенту не показываем
+	w.Header().Set("Content-Disposition", `attachment; filename="`+m.ID.String()+extension(m.Source)+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, body); err != nil && r.Context().Err() == nil {
+		zerolog.Ctx(r.Context()).Warn().Err(err).Str("media_id", m.ID.String()).Msg("download interrupted")
+	}
+}
+
+// extension — расширение файла source, если оно безопасно для заголовка (.mp4), иначе пустое
+func extension(source string) string {
+	ext := path.Ext(source)
+	if len(ext) < 2 || len(ext) > 10 {
+		return ""
+	}
+	for _, c := range ext[1:] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// clientIP — адрес клиента: запись X-Forwarded-For, добавленная самым дальним доверенным proxy,
+// или адрес соединения. Записи левее подделываются клиентом и не читаются
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.trustedProxies > 0 {
+		var hops []string
+		for _, v := range r.Header.Values(ForwardedForHeader) {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+		if len(hops) >= h.trustedProxies {
+			if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-h.trustedProxies])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,135 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/download"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestDownload_ProxyFromLocalDisk(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.mp4"), []byte("frames"), 0o644))
+	local, err := blob.NewLocalStore(blob.LocalConfig{Root: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = local.Close() })
+
+	links, err := download.New(download.Config{
+		Secret:  []byte("0123456789abcdef0123456789abcdef"),
+		Sources: blob.Readers{"file": local},
+	})
+	require.NoError(t, err)
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	router := NewRouter(New(svc).WithDownloads(links))
+
+	owner := uuid.New()
+	m, err := svc.CreateMedia(service.WithPrincipal(ctx, service.Principal{OwnerID: owner}), models.Video, "file://"+filepath.Join(dir, "a.mp4"))
+	require.NoError(t, err)
+	_, err = svc.RecordContent(ctx, m.ID, models.Content{Checksum: strings.Repeat("a", 64), Size: 6, ContentType: "video/mp4"})
+	require.NoError(t, err)
+
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	link := func(query string, headers map[string]string) DownloadResponse {
+		rec := get("/media/"+m.ID.String()+"/download"+query, headers)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp DownloadResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// Чужой владелец не получает ссылку
+	require.Equal(t, http.StatusNotFound, get("/media/"+m.ID.String()+"/download", map[string]string{OwnerHeader: uuid.NewString()}).Code)
+
+	resp := link("?ttl=5m", map[string]string{OwnerHeader: owner.String()})
+	require.Equal(t, download.MethodProxy, resp.Method)
+	require.NotContains(t, resp.URL, dir)
+
+	rec := get(resp.URL, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "frames", rec.Body.String())
+	require.Equal(t, "video/mp4", rec.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="`+m.ID.String()+`.mp4"`, rec.Header().Get("Content-Disposition"))
+
+	// Подделанная подпись
+	u, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	q := u.Query()
+	q.Set("expires", "9999999999")
+	require.Equal(t, http.StatusForbidden, get(u.Path+"?"+q.Encode(), nil).Code)
+
+	// Ссылка с привязкой к IP работает только с этого адреса
+	bound := link("?bind_ip=true", map[string]string{OwnerHeader: owner.String(), ForwardedForHeader: "203.0.113.7"})
+	require.Equal(t, http.StatusOK, get(bound.URL, map[string]string{ForwardedForHeader: "203.0.113.7"}).Code)
+	require.Equal(t, http.StatusForbidden, get(bound.URL, map[string]string{ForwardedForHeader: "198.51.100.1"}).Code)
+	// Клиент с другого адреса подставил адрес владельца ссылки перед записью gateway
+	spoofed := map[string]string{ForwardedForHeader: "203.0.113.7, 198.51.100.1"}
+	require.Equal(t, http.StatusForbidden, get(bound.URL, spoofed).Code)
+
+	rec = get("/media/"+m.ID.String()+"/download?ttl=48h", map[string]string{OwnerHeader: owner.String()})
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), "ttl")
+
+	// Медиа в карантине не скачивается
+	_, err = svc.QuarantineMedia(ctx, m.ID, service.Verdict{Threat: "Eicar-Test-Signature"}, service.ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, get("/media/"+m.ID.String()+"/download", map[string]string{OwnerHeader: owner.String()}).Code)
+	require.Equal(t, http.StatusConflict, get(resp.URL, nil).Code)
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	cases := []struct {
+		name    string
+		proxies int
+		fwd     []string
+		want    string
+	}{
+		{name: "no header", proxies: 1, want: "192.0.2.10"},
+		{name: "gateway entry", proxies: 1, fwd: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed prefix", proxies: 1, fwd: []string{"198.51.100.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "two proxies", proxies: 2, fwd: []string{"198.51.100.1, 203.0.113.7", "10.0.0.1"}, want: "203.0.113.7"},
+		{name: "fewer entries than proxies", proxies: 2, fwd: []string{"203.0.113.7"}, want: "192.0.2.10"},
+		{name: "header not trusted", proxies: 0, fwd: []string{"203.0.113.7"}, want: "192.0.2.10"},
+		{name: "garbage", proxies: 1, fwd: []string{"unknown"}, want: "192.0.2.10"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(nil).WithTrustedProxies(tc.proxies)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.10:51234"
+			for _, v := range tc.fwd {
+				r.Header.Add(ForwardedForHeader, v)
+			}
+			require.Equal(t, tc.want, h.clientIP(r))
+		})
+	}
+}
+
+func TestDownload_NotConfigured(t *testing.T) {
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/"+uuid.NewString()+"/download", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -10,6 +10,15 @@ import (
 type CreateMediaRequest struct {
 	Type   models.MediaType `json:"type"`
 	Source string           `json:"source"`
+
+	Title    string            `json:"title"`
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type ChangeStatusRequest struct {
+	Status models.Status `json:"status"`
+	Reason string        `json:"reason,omitempty"` // обязателен для failed
 }
 
 type MediaResponse struct {
@@ -19,4 +28,263 @@ type MediaResponse struct {
 	Source    string           `json:"source"`
 	CreatedAt time.Time        `json:"created_at"`
 	UpdatedAt time.Time        `json:"updated_at"`
+
+	Title    string            `json:"title,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	ProcessingAttempts int    `json:"processing_attempts"`
+	LastError          string `json:"last_error,omitempty"`
+
+	OwnerID uuid.UUID `json:"owner_id,omitzero"`
+
+	Checksum    string `json:"checksum_sha256,omitempty"`
+	Size        int64  `json:"size_bytes,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	Visibility models.Visibility `json:"visibility"`
+	// EffectiveVisibility — видимость с учётом статуса: unlisted и public действуют только для ready
+	EffectiveVisibility models.Visibility `json:"effective_visibility"`
+}
+
+// ListMediaResponse — страница GET /media; next_cursor (он же в X-Next-Cursor и Link) —
+// параметр cursor следующей страницы, пустой на последней
+type ListMediaResponse struct {
+	Items      []MediaResponse `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	// Total — с count=exact|estimated: медиа под фильтром на всех страницах
+	Total          *int64      `json:"total,omitempty"`
+	TotalEstimated bool        `json:"total_estimated,omitempty"`
+	Facets         *ListFacets `json:"facets,omitempty"`
+}
+
+// ListFacets — с facets=true: медиа под фильтром на всех страницах по статусам и типам
+type ListFacets struct {
+	Status map[models.Status]int64    `json:"status"`
+	Type   map[models.MediaType]int64 `json:"type"`
+}
+
+// DownloadRequest — query параметры GET /media/{id}/download
+type DownloadRequest struct {
+	TTL    time.Duration // срок ссылки; 0 — по умолчанию сервиса
+	BindIP bool          // ссылка действует только с адреса запросившего
+}
+
+// DownloadResponse — ссылка на скачивание исходника; source клиенту не отдаётся
+type DownloadResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Method    string    `json:"method"` // presigned — ссылка хранилища, proxy — через media
+}
+
+// QuarantineRequest — ingest сообщает, что антивирус нашёл угрозу в исходнике
+type QuarantineRequest struct {
+	Threat  string `json:"threat"`
+	Scanner string `json:"scanner,omitempty"`
+}
+
+// ScheduleRequest — тело PUT /media/{id}/schedule; поле без значения снимает ограничение
+type ScheduleRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// VisibilityRequest — тело PATCH /media/{id}/visibility
+type VisibilityRequest struct {
+	Visibility models.Visibility `json:"visibility"`
+}
+
+// RecordContentRequest — ingest сообщает характеристики загруженного и проверенного исходника
+type RecordContentRequest struct {
+	Checksum    string `json:"checksum_sha256"`
+	Size        int64  `json:"size_bytes"`
+	ContentType string `json:"content_type"`
+	// Source — исходник другого медиа владельца с тем же содержимым (дедупликация):
+	// медиа переходит на него, ingest содержимое не сохранял
+	Source string `json:"source,omitempty"`
+}
+
+// ReportFailureRequest — processing сервис сообщает о неудачной попытке обработки
+type ReportFailureRequest struct {
+	Error string `json:"error"`
+}
+
+type CreateMediaBatchRequest struct {
+	Items []CreateMediaBatchItem `json:"items"`
+}
+
+type CreateMediaBatchItem struct {
+	ID     *uuid.UUID       `json:"id,omitempty"`
+	Type   models.MediaType `json:"type"`
+	Source string           `json:"source"`
+}
+
+type CreateMediaBatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+type BatchItemResult struct {
+	Index  int            `json:"index"`
+	Status string         `json:"status"`
+	Media  *MediaResponse `json:"media,omitempty"`
+	Errors []FieldError   `json:"errors,omitempty"`
+}
+
+// ChangeStatusBatchRequest — тело PATCH /media/status/batch
+type ChangeStatusBatchRequest struct {
+	Items []ChangeStatusBatchItem `json:"items"`
+}
+
+type ChangeStatusBatchItem struct {
+	ID     uuid.UUID     `json:"id"`
+	Status models.Status `json:"status"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+type ChangeStatusBatchResponse struct {
+	Results []StatusBatchItemResult `json:"results"`
+}
+
+// StatusBatchItemResult — результат элемента: changed, unchanged, invalid (ошибки полей
+// в errors) или rejected (code и message: not_found, invalid_transition, invalid_argument)
+type StatusBatchItemResult struct {
+	Index   int            `json:"index"`
+	ID      uuid.UUID      `json:"id"`
+	Status  string         `json:"status"`
+	Media   *MediaResponse `json:"media,omitempty"`
+	Errors  []FieldError   `json:"errors,omitempty"`
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message,omitempty"`
+}
+
+// DuplicatesResponse — другие медиа владельца с тем же исходником, новые первыми
+type DuplicatesResponse struct {
+	Items []MediaResponse `json:"items"`
+}
+
+type StatusHistoryResponse struct {
+	Items []StatusChangeResponse `json:"items"`
+}
+
+type StatusChangeResponse struct {
+	From      models.Status `json:"from"`
+	To        models.Status `json:"to"`
+	Actor     string        `json:"actor,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+	ChangedAt time.Time     `json:"changed_at"`
+}
+
+// SearchMediaRequest — query-параметры GET /media/search
+type SearchMediaRequest struct {
+	Query  string
+	Tags   []string
+	Status models.Status
+	Limit  int
+	Offset int
+}
+
+type SearchMediaResponse struct {
+	Items  []SearchHitResponse `json:"items"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+type SearchHitResponse struct {
+	Media MediaResponse `json:"media"`
+	Rank  float64       `json:"rank"`
+}
+
+// CreateCollectionRequest — тело POST /collections
+type CreateCollectionRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// UpdateCollectionRequest — тело PATCH /collections/{id}; поле без значения не меняется
+type UpdateCollectionRequest struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// AddCollectionItemRequest — тело POST /collections/{id}/items; без position — в конец
+type AddCollectionItemRequest struct {
+	MediaID  uuid.UUID `json:"media_id"`
+	Position *int      `json:"position,omitempty"`
+}
+
+type CollectionResponse struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id,omitzero"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CollectionDetailsResponse — GET /collections/{id}: коллекция и её состав по порядку
+type CollectionDetailsResponse struct {
+	CollectionResponse
+	Items []CollectionItemResponse `json:"items"`
+}
+
+type CollectionItemResponse struct {
+	MediaID  uuid.UUID `json:"media_id"`
+	Position int       `json:"position"` // место с нуля
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// ListCollectionsResponse — страница GET /collections, курсор — как у ListMediaResponse
+type ListCollectionsResponse struct {
+	Items      []CollectionResponse `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// GrantRequest — тело PUT /media/{id}/grants/{principal}
+type GrantRequest struct {
+	Permission models.Permission `json:"permission"`
+}
+
+// GrantResponse — запись ACL медиа
+type GrantResponse struct {
+	Principal  uuid.UUID         `json:"principal"`
+	Permission models.Permission `json:"permission"`
+	GrantedBy  string            `json:"granted_by,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// ListGrantsResponse — GET /media/{id}/grants, в порядке выдачи
+type ListGrantsResponse struct {
+	Items []GrantResponse `json:"items"`
+}
+
+// ShareLinkRequest — тело POST /media/{id}/share; без полей — ссылка на чтение на срок по умолчанию
+type ShareLinkRequest struct {
+	Permission models.Permission `json:"permission,omitempty"`
+	TTL        string            `json:"ttl,omitempty"` // duration: 24h, 30m
+}
+
+// ShareLinkResponse — анонимная ссылка на медиа; отозвать её до expires_at нельзя
+type ShareLinkResponse struct {
+	URL        string            `json:"url"`
+	Token      string            `json:"token"`
+	Permission models.Permission `json:"permission"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+// SharedMediaResponse — медиа по анонимной ссылке: без source, владельца и служебных полей
+type SharedMediaResponse struct {
+	ID          uuid.UUID         `json:"id"`
+	Type        models.MediaType  `json:"type"`
+	Status      string            `json:"status"`
+	Title       string            `json:"title,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Size        int64             `json:"size_bytes,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Permission  models.Permission `json:"permission"`
+	ExpiresAt   time.Time         `json:"expires_at"` // срок ссылки
 }
@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+)
+
+// Коды ошибок, специфичные для HTTP-транспорта. Коды доменных ошибок живут в apierr.
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeValidationFailed = "validation_failed"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeForbidden        = apierr.CodeForbidden // нет scope или подписи; тот же код у models.ErrForbidden
+	// Idempotency-Key уже использован другим запросом
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	// запрос с тем же Idempotency-Key ещё выполняется
+	CodeRequestInProgress = "request_in_progress"
+)
+
+// ErrorResponse — единый формат ошибки для всех ручек
+type ErrorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// writeError пишет ошибку в едином формате, подставляя request_id из контекста запроса
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]any) {
+	writeJSON(w, status, ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: RequestIDFromContext(r.Context()),
+	})
+}
+
+// writeServiceError маппит ошибку сервиса через общий реестр apierr и пишет её клиенту
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	m := apierr.Lookup(err)
+	if m.HTTPStatus >= http.StatusInternalServerError {
+		// Клиент получает обобщённое сообщение, причина остаётся в логе с request_id
+		zerolog.Ctx(r.Context()).Error().Err(err).Int("status", m.HTTPStatus).Msg("request failed")
+	}
+	writeError(w, r, m.HTTPStatus, m.Code, m.Message, nil)
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed", nil)
+}
@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestWriteServiceError_DoesNotLeakInternals(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	writeServiceError(rec, req, errors.New("pq: password authentication failed"))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NotContains(t, rec.Body.String(), "password")
+}
+
+func TestErrorResponse_CarriesRequestID(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/media/not-a-uuid", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "req-42", rec.Header().Get(RequestIDHeader))
+
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, apierr.CodeInvalidArgument, body.Code)
+	require.Equal(t, "req-42", body.RequestID)
+}
+
+func TestRequestID_GeneratedWhenMissing(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/media/"+uuid.NewString()+"/status", nil))
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	_, err := uuid.Parse(rec.Header().Get(RequestIDHeader))
+	require.NoError(t, err)
+}
+
+func TestChangeStatus_ErrorMapping(t *testing.T) {
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	m, err := svc.CreateMedia(context.Background(), models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	router := NewRouter(New(svc))
+
+	patch := func(id, body string) (int, ErrorResponse) {
+		req := httptest.NewRequest(http.MethodPatch, "/media/"+id+"/status", strings.NewReader(body))
+		req.Header.Set(ScopesHeader, InternalScope)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp ErrorResponse
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+		}
+		return rec.Code, resp
+	}
+
+	code, resp := patch(uuid.NewString(), `{"status":"processing"}`)
+	require.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, apierr.CodeNotFound, resp.Code)
+
+	code, resp = patch(m.ID.String(), `{"status":"ready"}`)
+	require.Equal(t, http.StatusConflict, code)
+	require.Equal(t, apierr.CodeInvalidTransition, resp.Code)
+
+	code, resp = patch("not-a-uuid", `{"status":"processing"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, apierr.CodeInvalidArgument, resp.Code)
+
+	code, resp = patch(m.ID.String(), `{"status":`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, CodeInvalidJSON, resp.Code)
+
+	// Повтор того же перехода — 200 без новой записи в истории
+	for range 2 {
+		code, _ = patch(m.ID.String(), `{"status":"processing","reason":"picked up"}`)
+		require.Equal(t, http.StatusOK, code)
+	}
+	history, err := svc.GetStatusHistory(context.Background(), m.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "picked up", history[0].Reason)
+}
+
+func TestActor_PutsHeaderIntoServiceContext(t *testing.T) {
+	var got string
+	h := Actor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = service.ActorFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPatch, "/media/x/status", nil)
+	req.Header.Set(ActorHeader, "transcoder")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "transcoder", got)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/media/x/status", nil))
+	require.Empty(t, got)
+}
+
+func TestReadPrimary_FlagsContext(t *testing.T) {
+	var got bool
+	h := ReadPrimary(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = repository.ReadPrimary(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/media/x", nil)
+	req.Header.Set(ReadPrimaryHeader, "true")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.True(t, got)
+
+	req = httptest.NewRequest(http.MethodGet, "/media/x", nil)
+	req.Header.Set(ReadPrimaryHeader, "nope")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.False(t, got)
+}
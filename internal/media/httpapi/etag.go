@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// etag — сильный ETag медиа из его версии (updated_at)
+func etag(m *models.Media) string {
+	return `"` + strconv.FormatInt(m.Version(), 36) + `"`
+}
+
+// setETag отдаёт ETag медиа в заголовке ответа
+func setETag(w http.ResponseWriter, m *models.Media) {
+	w.Header().Set("ETag", etag(m))
+}
+
+// encodedETag — ETag сжатого представления: суффикс кодирования внутри кавычек, "v" → "v-gzip"
+func encodedETag(tag, encoding string) string {
+	if !strings.HasSuffix(tag, `"`) {
+		return tag
+	}
+	return tag[:len(tag)-1] + "-" + encoding + `"`
+}
+
+// decodedETag снимает суффикс кодирования, добавленный Compress: предусловия сравнивают
+// ETag сжатого и несжатого представления с одной версией
+func decodedETag(tag string) string {
+	for _, encoding := range []string{encodingGzip, encodingDeflate} {
+		if base, ok := strings.CutSuffix(tag, "-"+encoding+`"`); ok {
+			return base + `"`
+		}
+	}
+	return tag
+}
+
+// parseETags разбирает список ETag из If-Match/If-None-Match в версии медиа.
+// wildcard — заголовок равен "*"; ETag, выданные не нами, пропускаются. weak — слабое
+// сравнение (If-None-Match): W/ ETag тоже учитываются; If-Match сравнивает только сильные.
+func parseETags(header string, weak bool) (versions []int64, wildcard bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = decodedETag(strings.TrimSpace(tag))
+		if tag == "*" {
+			return nil, true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[len("W/"):]
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		v, err := strconv.ParseInt(tag[1:len(tag)-1], 36, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, false
+}
+
+// notModified — If-None-Match совпал с текущей версией медиа
+func notModified(r *http.Request, m *models.Media) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	versions, wildcard := parseETags(header, true)
+	return wildcard || slices.Contains(versions, m.Version())
+}
+
+// bodyETag — слабый ETag ответа, не привязанного к версии одного медиа: хэш тела. Страница
+// списка меняется и без записи в её медиа — когда медиа удаляют или в выборку попадает новое.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// writeConditionalJSON отдаёт v как writeJSON с кодом 200 и bodyETag; если ETag уже есть
+// в If-None-Match — 304 без тела
+func writeConditionalJSON(w http.ResponseWriter, r *http.Request, v any) {
+	body, _ := json.Marshal(v)
+	body = append(body, '\n')
+	tag := bodyETag(body)
+	w.Header().Set("ETag", tag)
+	if noneMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// noneMatch — заголовок If-None-Match содержит tag или "*" (слабое сравнение)
+func noneMatch(header, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = decodedETag(strings.TrimSpace(candidate))
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
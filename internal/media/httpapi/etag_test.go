@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	m, err := svc.CreateMedia(context.Background(), models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	router := NewRouter(New(svc))
+
+	do := func(method, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/media/"+m.ID.String(), strings.NewReader(body))
+		if method == http.MethodPatch {
+			req.URL.Path += "/status"
+		}
+		req.Header.Set(ScopesHeader, InternalScope)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	tag := rec.Header().Get("ETag")
+	require.NotEmpty(t, tag)
+
+	// Не изменилось — 304 без тела; слабое сравнение и список тоже подходят
+	for _, v := range []string{tag, "W/" + tag, `"other", ` + tag, "*"} {
+		rec = do(http.MethodGet, "If-None-Match", v, "")
+		require.Equal(t, http.StatusNotModified, rec.Code, v)
+		require.Empty(t, rec.Body.String())
+		require.Equal(t, tag, rec.Header().Get("ETag"))
+	}
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "If-None-Match", `"other"`, "").Code)
+
+	// ETag сжатого представления сравнивается с той же версией
+	gzipTag := encodedETag(tag, encodingGzip)
+	require.Equal(t, http.StatusNotModified, do(http.MethodGet, "If-None-Match", gzipTag, "").Code)
+
+	// If-Match с актуальной версией (здесь — в форме сжатого представления) меняет статус и отдаёт новый ETag
+	rec = do(http.MethodPatch, "If-Match", gzipTag, `{"status":"processing"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	newTag := rec.Header().Get("ETag")
+	require.NotEqual(t, tag, newTag)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "If-None-Match", tag, "").Code)
+
+	// Устаревшая, слабая или чужая версия — 412, статус не меняется
+	for _, v := range []string{tag, gzipTag, "W/" + newTag, `"not-ours"`} {
+		rec = do(http.MethodPatch, "If-Match", v, `{"status":"ready"}`)
+		require.Equal(t, http.StatusPreconditionFailed, rec.Code, v)
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, apierr.CodePreconditionFailed, resp.Code)
+	}
+	got, err := svc.GetMedia(context.Background(), m.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingStatus, got.Status)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPatch, "If-Match", "*", `{"status":"ready"}`).Code)
+}
@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/stream"
+)
+
+// События потока GET /media/{id}/events
+const (
+	StreamEventMedia   = "media"   // текущее состояние медиа (MediaResponse)
+	StreamEventDeleted = "deleted" // медиа удалено, поток закрывается
+)
+
+// streamKeepAlive — период комментариев-пингов: прокси не закрывают простаивающее соединение
+const streamKeepAlive = 15 * time.Second
+
+// WithStream включает GET /media/{id}/events (по умолчанию ручка отвечает 404)
+func (h *Handler) WithStream(hub *stream.Hub) *Handler {
+	h.stream = hub
+	return h
+}
+
+// MediaEvents — GET /media/{id}/events: Server-Sent Events с состоянием медиа. Первое событие —
+// текущее состояние, дальше — каждое изменение (статус, содержимое), пока клиент не отключится
+// или медиа не удалят. Изменения, случившиеся подряд, могут прийти одним событием.
+func (h *Handler) MediaEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if h.stream == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "event stream is not configured", nil)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/events")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	// Подписка до чтения состояния: изменение между чтением и подпиской не потеряется
+	changes, unsubscribe := h.stream.Subscribe(mediaID)
+	defer unsubscribe()
+
+	ctx := r.Context()
+	m, err := h.svc.GetMedia(ctx, mediaID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// Поток живёт дольше WriteTimeout сервера; без поддержки дедлайнов (тесты) — как есть
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data []byte) error {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	var last []byte
+	keepAlive := time.NewTicker(h.keepAlive())
+	defer keepAlive.Stop()
+	for {
+		if m == nil || m.Status == models.DeletedStatus {
+			_ = send(StreamEventDeleted, fmt.Appendf(nil, `{"id":%q}`, mediaID))
+			return
+		}
+		data, err := json.Marshal(toMediaResponse(m))
+		if err != nil {
+			h.logger.Error().Err(err).Msg("marshal media event")
+			return
+		}
+		if !bytes.Equal(data, last) {
+			if err := send(StreamEventMedia, data); err != nil {
+				return
+			}
+			last = data
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.stream.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+			continue
+		case <-changes:
+		}
+
+		// Сигнал пришёл после коммита: реплика и кэш могут ещё не знать об изменении
+		m, err = h.svc.GetMedia(repository.WithReadPrimary(ctx), mediaID)
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			m = nil
+		case err != nil:
+			if ctx.Err() == nil {
+				h.logger.Warn().Err(err).Str("media_id", mediaID.String()).Msg("media event stream: reload failed")
+			}
+			return
+		}
+	}
+}
+
+// CloseStreams завершает открытые потоки событий; вызывается при остановке сервера
+func (h *Handler) CloseStreams() {
+	if h.stream != nil {
+		h.stream.Close()
+	}
+}
+
+func (h *Handler) keepAlive() time.Duration {
+	if h.streamKeepAlive > 0 {
+		return h.streamKeepAlive
+	}
+	return streamKeepAlive
+}
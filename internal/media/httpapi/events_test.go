@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/media/stream"
+)
+
+// sseEvent — одно событие потока
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents читает события потока в канал, пропуская комментарии
+func readEvents(t *testing.T, resp *http.Response) <-chan sseEvent {
+	t.Helper()
+	out := make(chan sseEvent, 16)
+	go func() {
+		defer close(out)
+		sc := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev.name != "" {
+					out <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, "event: "):
+				ev.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return out
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event")
+		return sseEvent{}
+	}
+}
+
+func TestMediaEvents_StreamsStatusChanges(t *testing.T) {
+	ctx := context.Background()
+	hub := stream.NewHub()
+	svc := service.New(repository.NewMemoryRepository(), hub)
+	h := New(svc).WithStream(hub)
+	h.streamKeepAlive = 10 * time.Millisecond
+	srv := httptest.NewServer(NewRouter(h))
+	t.Cleanup(srv.Close)
+
+	owner := uuid.New()
+	m, err := svc.CreateMedia(service.WithPrincipal(ctx, service.Principal{OwnerID: owner}), models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+
+	open := func(id uuid.UUID, owner uuid.UUID) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/media/"+id.String()+"/events", nil)
+		require.NoError(t, err)
+		req.Header.Set(OwnerHeader, owner.String())
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	// Чужое медиа не отдаётся
+	require.Equal(t, http.StatusNotFound, open(m.ID, uuid.New()).StatusCode)
+
+	resp := open(m.ID, owner)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := readEvents(t, resp)
+
+	status := func(ev sseEvent) models.Status {
+		require.Equal(t, StreamEventMedia, ev.name)
+		var body MediaResponse
+		require.NoError(t, json.Unmarshal([]byte(ev.data), &body))
+		require.Equal(t, m.ID.String(), body.ID.String())
+		return models.Status(body.Status)
+	}
+	require.Equal(t, models.UploadedStatus, status(nextEvent(t, events)))
+
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingStatus, status(nextEvent(t, events)))
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.ReadyStatus, status(nextEvent(t, events)))
+
+	// Удаление закрывает поток
+	require.NoError(t, svc.DeleteMedia(ctx, m.ID, models.DeleteReasonDeleted))
+	ev := nextEvent(t, events)
+	require.Equal(t, StreamEventDeleted, ev.name)
+	require.JSONEq(t, `{"id":"`+m.ID.String()+`"}`, ev.data)
+	_, ok := <-events
+	require.False(t, ok)
+	require.Eventually(t, func() bool { return hub.Subscribers() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestMediaEvents_ClosedOnShutdown(t *testing.T) {
+	hub := stream.NewHub()
+	svc := service.New(repository.NewMemoryRepository(), hub)
+	h := New(svc).WithStream(hub)
+	srv := httptest.NewServer(NewRouter(h))
+	t.Cleanup(srv.Close)
+
+	m, err := svc.CreateMedia(context.Background(), models.Audio, "s3://bucket/a.mp3")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/media/"+m.ID.String()+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set(ScopesHeader, InternalScope)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	events := readEvents(t, resp)
+	require.Equal(t, StreamEventMedia, nextEvent(t, events).name)
+
+	h.CloseStreams()
+	select {
+	case _, ok := <-events:
+		require.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream not closed")
+	}
+}
+
+func TestMediaEvents_NotConfigured(t *testing.T) {
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/"+uuid.NewString()+"/events", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
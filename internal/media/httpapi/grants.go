@@ -0,0 +1,227 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/download"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/share"
+)
+
+// WithShareLinks включает POST /media/{id}/share и GET /shared/{token} (по умолчанию ручки отвечают 404)
+func (h *Handler) WithShareLinks(links *share.Links) *Handler {
+	h.shares = links
+	return h
+}
+
+// Grants — GET /media/{id}/grants (ACL медиа), PUT /media/{id}/grants/{principal} (выдать
+// или сменить право), DELETE /media/{id}/grants/{principal} (закрыть доступ)
+func (h *Handler) Grants(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/media/")
+	idStr, tail, _ := strings.Cut(rest, "/grants")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	if tail == "" {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		grants, err := h.svc.ListGrants(r.Context(), mediaID)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		resp := ListGrantsResponse{Items: make([]GrantResponse, 0, len(grants))}
+		for _, g := range grants {
+			resp.Items = append(resp.Items, toGrantResponse(g))
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	principal, err := uuid.Parse(strings.TrimPrefix(tail, "/"))
+	if err != nil || principal == uuid.Nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid principal", nil)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		h.grantAccess(w, r, mediaID, principal)
+	case http.MethodDelete:
+		if err := h.svc.RevokeAccess(r.Context(), mediaID, principal); err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *Handler) grantAccess(w http.ResponseWriter, r *http.Request, mediaID, principal uuid.UUID) {
+	defer r.Body.Close()
+
+	var req GrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	g, err := h.svc.GrantAccess(r.Context(), mediaID, principal, req.Permission)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toGrantResponse(g))
+}
+
+// Share — POST /media/{id}/share: анонимная ссылка на медиа с правом read или download
+func (h *Handler) Share(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if h.shares == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "share links are not configured", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/share")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	var req ShareLinkRequest
+	// Пустое тело — ссылка по умолчанию
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	ttl, errs := req.Validate(h.shares.MaxTTL())
+	if len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+	if req.Permission == "" {
+		req.Permission = models.ReadPermission
+	}
+
+	m, err := h.svc.ShareMedia(r.Context(), mediaID, req.Permission)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	link, err := h.shares.Issue(m.ID, req.Permission, ttl)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, ShareLinkResponse{
+		URL:        link.URL,
+		Token:      link.Token,
+		Permission: link.Permission,
+		ExpiresAt:  link.ExpiresAt,
+	})
+}
+
+// Shared — GET /shared/{token} (медиа по анонимной ссылке) и GET /shared/{token}/download
+// (ссылка на исходник, если ссылка выдана с правом download). Заголовки владельца не нужны:
+// доступ подтверждает подпись токена.
+func (h *Handler) Shared(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	if h.shares == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "share links are not configured", nil)
+		return
+	}
+
+	token, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/shared/"), "/")
+	if sub != "" && sub != "download" {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "not found", nil)
+		return
+	}
+	claims, err := h.shares.Verify(token)
+	if err != nil {
+		// Причину (подпись, срок) не раскрываем — она в логе
+		zerolog.Ctx(r.Context()).Info().Err(err).Msg("share link rejected")
+		writeError(w, r, http.StatusForbidden, CodeForbidden, "invalid or expired share link", nil)
+		return
+	}
+
+	m, err := h.svc.GetSharedMedia(r.Context(), claims.MediaID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if sub == "" {
+		writeJSON(w, http.StatusOK, SharedMediaResponse{
+			ID:          m.ID,
+			Type:        m.Type,
+			Status:      string(m.Status),
+			Title:       m.Title,
+			ContentType: m.ContentType,
+			Size:        m.Size,
+			CreatedAt:   m.CreatedAt,
+			Permission:  claims.Permission,
+			ExpiresAt:   claims.ExpiresAt,
+		})
+		return
+	}
+
+	if !claims.Permission.Allows(models.DownloadPermission) {
+		writeError(w, r, http.StatusForbidden, CodeForbidden, "share link does not allow download", nil)
+		return
+	}
+	if h.downloads == nil {
+		writeError(w, r, http.StatusNotFound, apierr.CodeNotFound, "downloads are not configured", nil)
+		return
+	}
+	// Ссылка на исходник не переживает ссылку, по которой выдана
+	ttl := min(h.downloads.TTL(), time.Until(claims.ExpiresAt).Truncate(time.Second))
+	if ttl < time.Second {
+		writeError(w, r, http.StatusForbidden, CodeForbidden, "invalid or expired share link", nil)
+		return
+	}
+	link, err := h.downloads.Link(m, download.Options{TTL: ttl})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DownloadResponse{URL: link.URL, ExpiresAt: link.ExpiresAt, Method: link.Method})
+}
+
+func toGrantResponse(g models.Grant) GrantResponse {
+	return GrantResponse{
+		Principal:  g.Principal,
+		Permission: g.Permission,
+		GrantedBy:  g.GrantedBy,
+		CreatedAt:  g.CreatedAt,
+		UpdatedAt:  g.UpdatedAt,
+	}
+}
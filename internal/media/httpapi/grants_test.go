@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/download"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/media/share"
+)
+
+// sharingRouter — роутер с ACL, анонимными ссылками и скачиванием с локального диска;
+// у владельца owner одно медиа с исходником
+func sharingRouter(t *testing.T, owner uuid.UUID) (http.Handler, *models.Media) {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.mp4"), []byte("frames"), 0o644))
+	local, err := blob.NewLocalStore(blob.LocalConfig{Root: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = local.Close() })
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	downloads, err := download.New(download.Config{Secret: secret, Sources: blob.Readers{"file": local}})
+	require.NoError(t, err)
+	shares, err := share.New(share.Config{Secret: secret})
+	require.NoError(t, err)
+	svc := service.New(repository.NewMemoryRepository(), nil).WithGrants(repository.NewMemoryGrantRepository())
+
+	m, err := svc.CreateMedia(service.WithPrincipal(ctx, service.Principal{OwnerID: owner}), models.Video, "file://"+filepath.Join(dir, "a.mp4"))
+	require.NoError(t, err)
+	m, err = svc.RecordContent(ctx, m.ID, models.Content{Checksum: strings.Repeat("a", 64), Size: 6, ContentType: "video/mp4"})
+	require.NoError(t, err)
+	return NewRouter(New(svc).WithDownloads(downloads).WithShareLinks(shares)), m
+}
+
+func TestGrants_Endpoints(t *testing.T) {
+	owner, reader := uuid.New(), uuid.New()
+	router, m := sharingRouter(t, owner)
+	do := func(method, target, body string, as uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(OwnerHeader, as.String())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	base := "/media/" + m.ID.String()
+	grant := base + "/grants/" + reader.String()
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, base, "", reader).Code)
+	// Выдать право может только владелец
+	require.Equal(t, http.StatusNotFound, do(http.MethodPut, grant, `{"permission":"read"}`, reader).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPut, grant, `{"permission":"write"}`, owner).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPut, base+"/grants/nope", `{"permission":"read"}`, owner).Code)
+
+	rec := do(http.MethodPut, grant, `{"permission":"read"}`, owner)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var g GrantResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &g))
+	require.Equal(t, reader, g.Principal)
+	require.Equal(t, models.ReadPermission, g.Permission)
+
+	require.Equal(t, http.StatusOK, do(http.MethodGet, base, "", reader).Code)
+	rec = do(http.MethodGet, base+"/download", "", reader)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"forbidden"`)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPut, grant, `{"permission":"download"}`, owner).Code)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, base+"/download", "", reader).Code)
+
+	rec = do(http.MethodGet, base+"/grants", "", owner)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list ListGrantsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Items, 1)
+	require.Equal(t, models.DownloadPermission, list.Items[0].Permission)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, base+"/grants", "", reader).Code)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, grant, "", owner).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, grant, "", owner).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, base, "", reader).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, base+"/grants", "", owner).Code)
+}
+
+func TestShareLinks(t *testing.T) {
+	owner := uuid.New()
+	router, m := sharingRouter(t, owner)
+	do := func(method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	asOwner := map[string]string{OwnerHeader: owner.String()}
+	issue := func(body string) ShareLinkResponse {
+		rec := do(http.MethodPost, "/media/"+m.ID.String()+"/share", body, asOwner)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp ShareLinkResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodPost, "/media/"+m.ID.String()+"/share", "", map[string]string{OwnerHeader: uuid.NewString()}).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPost, "/media/"+m.ID.String()+"/share", `{"ttl":"30d"}`, asOwner).Code)
+
+	read := issue("")
+	require.Equal(t, models.ReadPermission, read.Permission)
+	require.Equal(t, "/shared/"+read.Token, read.URL)
+
+	// Анонимно: без заголовков владельца
+	rec := do(http.MethodGet, read.URL, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NotContains(t, rec.Body.String(), "file://")
+	var shared SharedMediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shared))
+	require.Equal(t, m.ID, shared.ID)
+	require.Equal(t, int64(6), shared.Size)
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, read.URL+"/download", "", nil).Code)
+
+	dl := issue(`{"permission":"download","ttl":"1h"}`)
+	rec = do(http.MethodGet, dl.URL+"/download", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var link DownloadResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &link))
+	require.False(t, link.ExpiresAt.After(dl.ExpiresAt))
+	rec = do(http.MethodGet, link.URL, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "frames", rec.Body.String())
+
+	tampered := dl.Token[:len(dl.Token)-2] + "AA"
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/shared/"+tampered, "", nil).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, dl.URL+"/other", "", nil).Code)
+}
+
+func TestShareLinks_NotConfigured(t *testing.T) {
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shared/token", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
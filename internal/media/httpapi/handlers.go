@@ -2,27 +2,56 @@ package httpapi
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/audit"
+	"github.com/romariotrain/media-platform/internal/media/download"
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
 	"github.com/romariotrain/media-platform/internal/media/models"
 	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/media/share"
+	"github.com/romariotrain/media-platform/internal/media/stream"
 )
 
 type Handler struct {
-	svc *service.Service
+	svc       *service.Service
+	readiness []namedCheck
+	audit     audit.Store
+	downloads *download.Links
+	shares    *share.Links
+	stream    *stream.Hub
+	stats     StatsReader
+	outbox    OutboxBacklog
+	logger    zerolog.Logger
+
+	cors            *CORSRules
+	compressMinSize int
+	idempotency     idempotency.Store
+	idempotencyTTL  time.Duration
+	trustedProxies  int
+
+	streamKeepAlive time.Duration // тесты; 0 — streamKeepAlive
 }
 
 func New(svc *service.Service) *Handler {
-	return &Handler{svc: svc}
+	return &Handler{svc: svc, logger: zerolog.Nop(), compressMinSize: DefaultCompressMinSize, trustedProxies: DefaultTrustedProxies}
+}
+
+// WithLogger задаёт логгер HTTP слоя: access log и необработанные ошибки (по умолчанию логи не пишутся)
+func (h *Handler) WithLogger(logger zerolog.Logger) *Handler {
+	h.logger = logger.With().Str("component", "http").Logger()
+	return h
 }
 
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
@@ -30,27 +59,28 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) CreateMedia(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 	defer r.Body.Close()
 
 	var req CreateMediaRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid json body")
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
 		return
 	}
 
-	m, err := h.svc.CreateMedia(r.Context(), req.Type, req.Source)
+	m, err := h.svc.CreateMediaWithAttrs(r.Context(), req.Type, req.Source, models.MediaAttrs{
+		Title:    req.Title,
+		Tags:     req.Tags,
+		Metadata: req.Metadata,
+	})
 	if err != nil {
-		switch {
-		case errors.Is(err, models.ErrInvalidArgument):
-			writeErrorJSON(w, http.StatusBadRequest, "invalid argument")
-		case errors.Is(err, models.ErrConflict):
-			writeErrorJSON(w, http.StatusConflict, "conflict")
-		default:
-			writeErrorJSON(w, http.StatusInternalServerError, "internal error")
-		}
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -59,41 +89,56 @@ func (h *Handler) CreateMedia(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetMedia(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
 	// ожидаем path вида /media/{id}
 	idStr := strings.TrimPrefix(r.URL.Path, "/media/")
 	if idStr == "" || idStr == r.URL.Path {
-		writeErrorJSON(w, http.StatusBadRequest, "missing id")
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "missing id", nil)
 		return
 	}
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		panic(err)
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
 	}
 
+	m, err := h.svc.GetMedia(r.Context(), id)
 	if err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid id")
+		writeServiceError(w, r, err)
 		return
 	}
 
-	m, err := h.svc.GetMedia(r.Context(), id)
+	setETag(w, m)
+	if notModified(r, m) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, toMediaResponse(m))
+}
+
+// DeleteMedia — DELETE /media/{id}
+func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	id, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/media/"))
 	if err != nil {
-		switch {
-		case errors.Is(err, models.ErrNotFound):
-			writeErrorJSON(w, http.StatusNotFound, "not found")
-		case errors.Is(err, models.ErrInvalidArgument):
-			writeErrorJSON(w, http.StatusBadRequest, "invalid argument")
-		default:
-			writeErrorJSON(w, http.StatusInternalServerError, "internal error: "+err.Error())
-		}
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, toMediaResponse(m))
+	if err := h.svc.DeleteMedia(r.Context(), id, models.DeleteReasonDeleted); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -102,10 +147,6 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func writeErrorJSON(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, map[string]string{"error": message})
-}
-
 func toMediaResponse(m *models.Media) MediaResponse {
 	return MediaResponse{
 		ID:        m.ID,
@@ -114,14 +155,34 @@ func toMediaResponse(m *models.Media) MediaResponse {
 		Source:    m.Source,
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
+
+		Title:    m.Title,
+		Tags:     m.Tags,
+		Metadata: m.Metadata,
+
+		ProcessingAttempts: m.ProcessingAttempts,
+		LastError:          m.LastError,
+
+		OwnerID: m.OwnerID,
+
+		Checksum:    m.Checksum,
+		Size:        m.Size,
+		ContentType: m.ContentType,
+
+		PublishAt: m.PublishAt,
+		ExpiresAt: m.ExpiresAt,
+
+		Visibility:          m.Visibility,
+		EffectiveVisibility: m.EffectiveVisibility(),
 	}
 }
 
 func (h *Handler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 		return
 	}
+	defer r.Body.Close()
 
 	// Парсим ID из URL: /media/{id}/status
 	path := strings.TrimPrefix(r.URL.Path, "/media/")
@@ -129,28 +190,288 @@ func (h *Handler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
 
 	mediaID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid id", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
 		return
 	}
 
 	// Парсим body
-	var req struct {
-		Status models.Status `json:"status"`
-	}
+	var req ChangeStatusRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
 		return
 	}
 
+	// If-Match: статус меняется, только если медиа не изменилось с чтения клиентом
+	meta := service.ChangeMeta{Reason: req.Reason}
+	if header := r.Header.Get("If-Match"); header != "" {
+		versions, wildcard := parseETags(header, false)
+		if !wildcard && len(versions) == 0 {
+			writeServiceError(w, r, models.ErrPreconditionFailed)
+			return
+		}
+		meta.IfMatch = versions
+	}
+
 	// Вызываем сервис
-	media, err := h.svc.ChangeStatus(r.Context(), mediaID, req.Status)
+	media, err := h.svc.ChangeStatus(r.Context(), mediaID, req.Status, meta)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	// Возвращаем результат в том же формате, что и GET /media/{id}
+	setETag(w, media)
+	writeJSON(w, http.StatusOK, toMediaResponse(media))
+}
+
+// StatusHistory — GET /media/{id}/history
+func (h *Handler) StatusHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/history")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	history, err := h.svc.GetStatusHistory(r.Context(), mediaID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := StatusHistoryResponse{Items: make([]StatusChangeResponse, 0, len(history))}
+	for _, c := range history {
+		resp.Items = append(resp.Items, StatusChangeResponse{
+			From:      c.From,
+			To:        c.To,
+			Actor:     c.Actor,
+			Reason:    c.Reason,
+			ChangedAt: c.ChangedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ReportFailure — POST /media/{id}/failures.
+// Ответ — медиа после обработки ошибки: processing, если запрошен повтор, иначе failed.
+func (h *Handler) ReportFailure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/failures")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	var req ReportFailureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	media, _, err := h.svc.ReportProcessingFailure(r.Context(), mediaID, req.Error, service.ChangeMeta{})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMediaResponse(media))
+}
+
+// RecordContent — PUT /media/{id}/content
+func (h *Handler) RecordContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/content")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	var req RecordContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	media, err := h.svc.RecordContent(r.Context(), mediaID, models.Content{
+		Checksum:    req.Checksum,
+		Size:        req.Size,
+		ContentType: req.ContentType,
+		Source:      req.Source,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMediaResponse(media))
+}
+
+// Duplicates — GET /media/{id}/duplicates?checksum={sha256}
+func (h *Handler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/duplicates")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	list, err := h.svc.Duplicates(r.Context(), mediaID, r.URL.Query().Get("checksum"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := DuplicatesResponse{Items: make([]MediaResponse, 0, len(list))}
+	for _, m := range list {
+		resp.Items = append(resp.Items, toMediaResponse(m))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Quarantine — POST /media/{id}/quarantine
+func (h *Handler) Quarantine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/quarantine")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	var req QuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	media, err := h.svc.QuarantineMedia(r.Context(), mediaID, service.Verdict{Threat: req.Threat, Scanner: req.Scanner}, service.ChangeMeta{})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMediaResponse(media))
+}
+
+// Visibility — PATCH /media/{id}/visibility; If-Match — как у ChangeStatus
+func (h *Handler) Visibility(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/visibility")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	var req VisibilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	var meta service.ChangeMeta
+	if header := r.Header.Get("If-Match"); header != "" {
+		versions, wildcard := parseETags(header, false)
+		if !wildcard && len(versions) == 0 {
+			writeServiceError(w, r, models.ErrPreconditionFailed)
+			return
+		}
+		meta.IfMatch = versions
+	}
+
+	media, err := h.svc.SetVisibility(r.Context(), mediaID, req.Visibility, meta)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, media)
+	writeJSON(w, http.StatusOK, toMediaResponse(media))
+}
+
+// Schedule — PUT /media/{id}/schedule
+func (h *Handler) Schedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/media/"), "/schedule")
+	mediaID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid id", nil)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "invalid json body", nil)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	media, err := h.svc.SetSchedule(r.Context(), mediaID, models.Schedule{PublishAt: req.PublishAt, ExpiresAt: req.ExpiresAt}, service.ChangeMeta{})
 	if err != nil {
-		// TODO: обработка разных ошибок (404, validation)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
-	// Возвращаем результат
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(media)
+	writeJSON(w, http.StatusOK, toMediaResponse(media))
 }
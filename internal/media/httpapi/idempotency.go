@@ -0,0 +1,165 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader — ответ повторён из сохранённого, запрос не выполнялся
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody — предел тела запроса с Idempotency-Key: тело читается целиком для хэша
+	maxIdempotentBody = 8 << 20
+)
+
+// WithIdempotency включает Idempotency-Key для POST и PATCH публичного API; ответы хранятся ttl
+func (h *Handler) WithIdempotency(store idempotency.Store, ttl time.Duration) *Handler {
+	h.idempotency = store
+	h.idempotencyTTL = ttl
+	return h
+}
+
+// Idempotency повторяет ответ на POST или PATCH с тем же Idempotency-Key, не выполняя запрос
+// снова (заголовок Idempotent-Replayed: true). Ключ привязан к методу, пути и телу: другой
+// запрос с тем же ключом — 422 idempotency_key_reused, повтор, пока первый выполняется, —
+// 409 request_in_progress с Retry-After. Сохраняются ответы 2xx и 4xx; после 5xx и паники
+// ключ освобождается и повтор выполнится заново. Ключи владельца (X-Owner-ID) не пересекаются
+// с чужими. Запросы без заголовка проходят как есть. Должен стоять после Principal.
+func Idempotency(store idempotency.Store, ttl time.Duration, next http.Handler) http.Handler {
+	if ttl <= 0 {
+		ttl = idempotency.DefaultTTL
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "Idempotency-Key is too long", nil)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, r, http.StatusRequestEntityTooLarge, apierr.CodeInvalidArgument, "request body is too large", nil)
+				return
+			}
+			writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "cannot read request body", nil)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		now := time.Now()
+		rec := idempotency.Record{
+			Key:         key,
+			Route:       r.Method + " " + r.URL.Path,
+			Fingerprint: idempotency.Fingerprint(r.Method, r.URL.RequestURI(), body),
+			Token:       uuid.New(),
+			CreatedAt:   now,
+			LockedUntil: now.Add(idempotency.DefaultLockTimeout),
+			ExpiresAt:   now.Add(ttl),
+		}
+		if p, ok := service.PrincipalFromContext(ctx); ok && p.OwnerID != uuid.Nil {
+			rec.Scope = p.OwnerID.String()
+		}
+
+		cur, claimed, err := store.Claim(ctx, rec)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		if !claimed {
+			switch {
+			case cur.Fingerprint != rec.Fingerprint:
+				writeError(w, r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused,
+					"idempotency key was already used for another request", map[string]any{"route": cur.Route})
+			case cur.Response == nil:
+				w.Header().Set("Retry-After", "1")
+				writeError(w, r, http.StatusConflict, CodeRequestInProgress,
+					"a request with this idempotency key is in progress", nil)
+			default:
+				writeReplayed(w, *cur.Response)
+			}
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Release(context.WithoutCancel(ctx), rec.Scope, rec.Key, rec.Token); err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Msg("idempotency key release failed")
+			}
+		}()
+		next.ServeHTTP(capture, r)
+		if capture.status >= http.StatusInternalServerError {
+			return
+		}
+
+		completed = true
+		resp := idempotency.Response{Status: capture.status, Header: http.Header{}, Body: capture.body.Bytes()}
+		for _, name := range idempotency.ReplayedHeaders {
+			if v := w.Header().Values(name); len(v) > 0 {
+				resp.Header[name] = v
+			}
+		}
+		// Ответ клиенту уже записан: без сохранения повтор просто выполнится заново через
+		// LockTimeout, поэтому ошибка только логируется
+		if err := store.Complete(context.WithoutCancel(ctx), rec.Scope, rec.Key, rec.Token, resp); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("idempotency response save failed")
+		}
+	})
+}
+
+func writeReplayed(w http.ResponseWriter, resp idempotency.Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// responseCapture запоминает код и тело ответа, передавая их дальше
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// Unwrap даёт http.ResponseController доступ к исходному writer'у
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
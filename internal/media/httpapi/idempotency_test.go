@@ -0,0 +1,114 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	repo := repository.NewMemoryRepository()
+	router := NewRouter(New(service.New(repo, nil)).WithIdempotency(store, time.Hour))
+	owner := uuid.New()
+	do := func(owner uuid.UUID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/media", strings.NewReader(body))
+		req.Header.Set(OwnerHeader, owner.String())
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	const body = `{"type":"video","source":"s3://media/a.mp4","title":"clip"}`
+
+	first := do(owner, "k1", body)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	again := do(owner, "k1", body)
+	require.Equal(t, http.StatusCreated, again.Code)
+	require.Equal(t, "true", again.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, first.Header().Get("ETag"), again.Header().Get("ETag"))
+	require.Equal(t, first.Header().Get("Content-Type"), again.Header().Get("Content-Type"))
+	require.Equal(t, first.Body.String(), again.Body.String())
+
+	// Тот же ключ другого владельца — новый запрос; без ключа — тоже
+	require.Empty(t, do(uuid.New(), "k1", body).Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, http.StatusCreated, do(owner, "", body).Code)
+	list, err := repo.List(t.Context(), repository.ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	reused := do(owner, "k1", `{"type":"image","source":"s3://media/a.png","title":"clip"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(reused.Body.Bytes(), &errResp))
+	require.Equal(t, CodeIdempotencyKeyReused, errResp.Code)
+
+	require.Equal(t, http.StatusBadRequest, do(owner, strings.Repeat("k", 256), body).Code)
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	calls := 0
+	handler := Idempotency(store, time.Hour, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/media/x", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusServiceUnavailable, do().Code)
+	require.Equal(t, http.StatusNoContent, do().Code)
+	replayed := do()
+	require.Equal(t, http.StatusNoContent, replayed.Code)
+	require.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	now := time.Now()
+	req := httptest.NewRequest(http.MethodPost, "/collections", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "k")
+	_, claimed, err := store.Claim(t.Context(), idempotency.Record{
+		Key:         "k",
+		Fingerprint: idempotency.Fingerprint(http.MethodPost, "/collections", []byte(`{}`)),
+		Token:       uuid.New(),
+		CreatedAt:   now,
+		LockedUntil: now.Add(time.Minute),
+		ExpiresAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	handler := Idempotency(store, time.Hour, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request with a key in progress must not run")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), CodeRequestInProgress)
+}
@@ -0,0 +1,248 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+const (
+	// NextCursorHeader — курсор следующей страницы списка; нет заголовка — страница последняя
+	NextCursorHeader = "X-Next-Cursor"
+
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// ListParams — стандартные query параметры списков:
+// limit, cursor, sort (поле; "-" впереди — по убыванию) и filter[поле]=значение
+type ListParams struct {
+	Limit  int
+	Cursor string
+	Sort   string // поле сортировки без "-"
+	Desc   bool
+	Filter map[string]string
+}
+
+// listSpec — что принимает конкретный список
+type listSpec struct {
+	sorts       []string // поля сортировки
+	defaultSort string   // с "-", если по убыванию
+	filters     []string // поля filter[...]
+}
+
+// parseListParams разбирает параметры списка по spec; ошибки отдаются как ошибки полей
+func parseListParams(q url.Values, spec listSpec) (ListParams, []FieldError) {
+	var v validator
+	p := ListParams{Limit: defaultListLimit, Cursor: q.Get("cursor"), Filter: map[string]string{}}
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxListLimit {
+			v.add("limit", "must be an integer between 1 and %d", maxListLimit)
+		}
+		p.Limit = n
+	}
+
+	sort := q.Get("sort")
+	if sort == "" {
+		sort = spec.defaultSort
+	}
+	p.Sort, p.Desc = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+	if !slices.Contains(spec.sorts, p.Sort) {
+		v.add("sort", "must be one of: %s (prefix - for descending order)", strings.Join(spec.sorts, ", "))
+	}
+
+	for key, values := range q {
+		field, ok := strings.CutPrefix(key, "filter[")
+		if !ok {
+			continue
+		}
+		field, ok = strings.CutSuffix(field, "]")
+		if !ok || !slices.Contains(spec.filters, field) {
+			v.add(key, "unknown filter, supported: %s", strings.Join(spec.filters, ", "))
+			continue
+		}
+		p.Filter[field] = values[0]
+	}
+	return p, v.errs
+}
+
+// sortParam — значение sort, под которое выдан курсор
+func (p ListParams) sortParam() string {
+	if p.Desc {
+		return "-" + p.Sort
+	}
+	return p.Sort
+}
+
+// pageCursor — содержимое курсора: позиция keyset пагинации и сортировка, для которой она
+// посчитана. Клиенту курсор непрозрачен (base64url JSON).
+type pageCursor struct {
+	Sort string    `json:"s"`
+	At   time.Time `json:"t"`
+	ID   uuid.UUID `json:"id"`
+}
+
+var errForeignCursor = errors.New("cursor was issued for another sort")
+
+func encodeCursor(sort string, c repository.ListCursor) string {
+	data, _ := json.Marshal(pageCursor{Sort: sort, At: c.At, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw, sort string) (repository.ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return repository.ListCursor{}, err
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return repository.ListCursor{}, err
+	}
+	if c.Sort != sort {
+		return repository.ListCursor{}, errForeignCursor
+	}
+	return repository.ListCursor{At: c.At, ID: c.ID}, nil
+}
+
+// setNextPage отдаёт курсор следующей страницы в X-Next-Cursor и ссылку на неё в Link
+// (rel="next", относительно адреса запроса: остальные параметры сохраняются)
+func setNextPage(w http.ResponseWriter, r *http.Request, cursor string) {
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Set(NextCursorHeader, cursor)
+	w.Header().Set("Link", "<"+next.String()+`>; rel="next"`)
+}
+
+// listMediaSpec — параметры GET /media
+var listMediaSpec = listSpec{
+	sorts:       []string{string(repository.SortCreatedAt), string(repository.SortUpdatedAt)},
+	defaultSort: "-" + string(repository.SortCreatedAt),
+	filters:     []string{"status", "type", "owner_id", "visibility"},
+}
+
+// listStatuses — статусы, по которым фильтрует список (все, в том числе служебные)
+var listStatuses = []models.Status{
+	models.UploadedStatus, models.ProcessingStatus, models.ReadyStatus, models.ScheduledStatus, models.FailedStatus,
+	models.DeletedStatus, models.ArchivedStatus, models.QuarantinedStatus,
+}
+
+// ListMedia — GET /media?limit=&cursor=&sort=-created_at&filter[status]=&filter[type]=&filter[owner_id]=&filter[visibility]=&count=&facets=.
+// Страницы по курсору (keyset): медиа, созданные во время обхода, не сдвигают следующие страницы.
+// Без scope admin видно своё медиа, а с чужим owner_id — опубликованное public медиа владельца.
+// count=exact|estimated добавляет total, facets=true — число медиа по статусам и типам; оба
+// считаются по фильтру без курсора. ETag — хэш тела: с If-None-Match неизменная страница — 304.
+func (h *Handler) ListMedia(w http.ResponseWriter, r *http.Request) {
+	params, errs := parseListParams(r.URL.Query(), listMediaSpec)
+	filter, filterErrs := params.mediaFilter()
+	totals, totalsErrs := parseTotalsQuery(r.URL.Query())
+	if errs = append(append(errs, filterErrs...), totalsErrs...); len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	// Лишняя запись показывает, есть ли следующая страница
+	filter.Limit = params.Limit + 1
+	items, err := h.svc.ListMedia(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := ListMediaResponse{Items: make([]MediaResponse, 0, min(len(items), params.Limit))}
+	for _, m := range items[:min(len(items), params.Limit)] {
+		resp.Items = append(resp.Items, toMediaResponse(m))
+	}
+	if len(items) > params.Limit {
+		resp.NextCursor = encodeCursor(params.sortParam(), repository.CursorOf(items[params.Limit-1], filter.Sort))
+		setNextPage(w, r, resp.NextCursor)
+	}
+	if totals.Count != repository.CountNone || totals.Facets {
+		t, err := h.svc.ListTotals(r.Context(), filter, totals)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		if totals.Count != repository.CountNone {
+			resp.Total, resp.TotalEstimated = &t.Total, t.Estimated
+		}
+		if totals.Facets {
+			resp.Facets = &ListFacets{Status: t.ByStatus, Type: t.ByType}
+		}
+	}
+	writeConditionalJSON(w, r, resp)
+}
+
+// parseTotalsQuery разбирает count (exact или estimated) и facets (boolean) списка
+func parseTotalsQuery(q url.Values) (repository.TotalsQuery, []FieldError) {
+	var (
+		v  validator
+		tq repository.TotalsQuery
+	)
+	if s := q.Get("count"); s != "" {
+		tq.Count = repository.CountMode(s)
+		if !tq.Count.Valid() {
+			v.add("count", "must be one of: %s, %s", repository.CountExact, repository.CountEstimated)
+		}
+	}
+	if s := q.Get("facets"); s != "" {
+		facets, err := strconv.ParseBool(s)
+		if err != nil {
+			v.add("facets", "must be a boolean")
+		}
+		tq.Facets = facets
+	}
+	return tq, v.errs
+}
+
+// mediaFilter переводит параметры GET /media в фильтр репозитория
+func (p ListParams) mediaFilter() (repository.ListFilter, []FieldError) {
+	var v validator
+	filter := repository.ListFilter{
+		Status:     models.Status(p.Filter["status"]),
+		Type:       models.MediaType(p.Filter["type"]),
+		Visibility: models.Visibility(p.Filter["visibility"]),
+		Sort:       repository.ListSort(p.Sort),
+		Ascending:  !p.Desc,
+	}
+	if filter.Status != "" && !slices.Contains(listStatuses, filter.Status) {
+		v.add("filter[status]", "unknown status %q", filter.Status)
+	}
+	if filter.Type != "" {
+		v.mediaType("filter[type]", filter.Type)
+	}
+	if filter.Visibility != "" {
+		v.visibility("filter[visibility]", filter.Visibility)
+	}
+	if raw := p.Filter["owner_id"]; raw != "" {
+		owner, err := uuid.Parse(raw)
+		if err != nil {
+			v.add("filter[owner_id]", "must be a uuid")
+		}
+		filter.OwnerID = owner
+	}
+	if p.Cursor != "" {
+		cursor, err := decodeCursor(p.Cursor, p.sortParam())
+		switch {
+		case errors.Is(err, errForeignCursor):
+			v.add("cursor", "%v", err)
+		case err != nil:
+			v.add("cursor", "is malformed")
+		}
+		filter.After = &cursor
+	}
+	return filter, v.errs
+}
@@ -0,0 +1,178 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestListMedia_Pages(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	base := time.Now().UTC().Add(-time.Hour)
+	owner, other := uuid.New(), uuid.New()
+
+	var created []uuid.UUID // старые первыми
+	for i := range 5 {
+		m := &models.Media{ID: uuid.New(), Status: models.UploadedStatus, Type: models.Video, Source: "s3://b/k", OwnerID: owner}
+		if i == 3 {
+			m.Type = models.Audio
+		}
+		m.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		m.UpdatedAt = m.CreatedAt
+		require.NoError(t, repo.Create(ctx, m))
+		created = append(created, m.ID)
+	}
+	foreign := &models.Media{ID: uuid.New(), Status: models.UploadedStatus, Type: models.Video, Source: "s3://b/x", OwnerID: other, CreatedAt: base, UpdatedAt: base}
+	require.NoError(t, repo.Create(ctx, foreign))
+
+	router := NewRouter(New(service.New(repo, nil)))
+	get := func(target string) (*httptest.ResponseRecorder, ListMediaResponse) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(OwnerHeader, owner.String())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp ListMediaResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp
+	}
+
+	// Обход по Link: новые первыми, чужое медиа не видно
+	var seen []uuid.UUID
+	target := "/media?limit=2"
+	for pages := 0; target != ""; pages++ {
+		require.Less(t, pages, 5)
+		rec, resp := get(target)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		for _, m := range resp.Items {
+			seen = append(seen, m.ID)
+		}
+		require.Equal(t, resp.NextCursor, rec.Header().Get(NextCursorHeader))
+		target = ""
+		if link := rec.Header().Get("Link"); link != "" {
+			require.True(t, strings.HasSuffix(link, `>; rel="next"`), link)
+			next, err := url.Parse(strings.TrimPrefix(strings.TrimSuffix(link, `>; rel="next"`), "<"))
+			require.NoError(t, err)
+			require.Equal(t, "2", next.Query().Get("limit"))
+			require.Equal(t, resp.NextCursor, next.Query().Get("cursor"))
+			target = next.String()
+		}
+	}
+	require.Equal(t, []uuid.UUID{created[4], created[3], created[2], created[1], created[0]}, seen)
+
+	rec, resp := get("/media?sort=created_at&filter[type]=video&limit=3")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, []uuid.UUID{created[0], created[1], created[2]}, responseIDs(resp.Items))
+	rec, resp = get("/media?sort=created_at&filter[type]=video&limit=3&cursor=" + resp.NextCursor)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, []uuid.UUID{created[4]}, responseIDs(resp.Items))
+	require.Empty(t, rec.Header().Get(NextCursorHeader))
+	require.Empty(t, rec.Header().Get("Link"))
+
+	// Курсор другой сортировки и испорченный курсор — ошибки полей
+	_, first := get("/media?limit=1")
+	for query, field := range map[string]string{
+		"sort=title":           "sort",
+		"filter[name]=x":       "filter[name]",
+		"filter[status]=gone":  "filter[status]",
+		"filter[visibility]=x": "filter[visibility]",
+		"limit=1000":           "limit",
+		"sort=updated_at&cursor=" + first.NextCursor: "cursor",
+		"cursor=%21%21": "cursor",
+	} {
+		rec, _ := get("/media?" + query)
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code, query)
+		require.Contains(t, rec.Body.String(), `"field":"`+field+`"`, query)
+	}
+}
+
+func responseIDs(items []MediaResponse) []uuid.UUID {
+	out := make([]uuid.UUID, len(items))
+	for i, m := range items {
+		out[i] = m.ID
+	}
+	return out
+}
+
+func TestListMedia_TotalsAndConditionalGet(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	owner := uuid.New()
+	for i := range 3 {
+		m := &models.Media{ID: uuid.New(), Status: models.UploadedStatus, Type: models.Video, Source: "s3://b/k", OwnerID: owner}
+		if i == 0 {
+			m.Status, m.Type = models.ReadyStatus, models.File
+		}
+		require.NoError(t, repo.Create(ctx, m))
+	}
+	// Чужое медиа не попадает ни в total, ни в фасеты
+	require.NoError(t, repo.Create(ctx, &models.Media{ID: uuid.New(), Status: models.UploadedStatus, Type: models.Video, Source: "s3://b/x", OwnerID: uuid.New()}))
+
+	router := NewRouter(New(service.New(repo, nil)))
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(OwnerHeader, owner.String())
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/media?limit=1&count=exact&facets=true", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp ListMediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	require.NotNil(t, resp.Total)
+	require.Equal(t, int64(3), *resp.Total)
+	require.False(t, resp.TotalEstimated)
+	require.Equal(t, map[models.Status]int64{models.UploadedStatus: 2, models.ReadyStatus: 1}, resp.Facets.Status)
+	require.Equal(t, map[models.MediaType]int64{models.Video: 2, models.File: 1}, resp.Facets.Type)
+
+	// Итоги — по фильтру на всех страницах, не по текущей
+	rec = get("/media?limit=1&filter[status]=uploaded&count=estimated&cursor="+resp.NextCursor, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	resp = ListMediaResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, int64(2), *resp.Total)
+	require.Nil(t, resp.Facets)
+
+	// Без параметров итогов в ответе нет
+	rec = get("/media", "")
+	require.NotContains(t, rec.Body.String(), `"total"`)
+	require.NotContains(t, rec.Body.String(), `"facets"`)
+
+	// Неизменная страница — 304 по ETag, изменившаяся — снова 200
+	tag := rec.Header().Get("ETag")
+	require.True(t, strings.HasPrefix(tag, `W/"`), tag)
+	rec = get("/media", `"other", `+tag)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Equal(t, tag, rec.Header().Get("ETag"))
+	require.NoError(t, repo.Create(ctx, &models.Media{ID: uuid.New(), Status: models.UploadedStatus, Type: models.Audio, Source: "s3://b/n", OwnerID: owner}))
+	rec = get("/media", tag)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEqual(t, tag, rec.Header().Get("ETag"))
+
+	for query, field := range map[string]string{"count=all": "count", "facets=maybe": "facets"} {
+		rec := get("/media?"+query, "")
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code, query)
+		require.Contains(t, rec.Body.String(), `"field":"`+field+`"`, query)
+	}
+}
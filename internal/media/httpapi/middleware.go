@@ -0,0 +1,153 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+const (
+	RequestIDHeader   = "X-Request-ID"
+	ActorHeader       = "X-Actor"
+	OwnerHeader       = "X-Owner-ID"
+	ReadPrimaryHeader = "X-Read-Primary"
+	ScopesHeader      = "X-Scopes"
+)
+
+// AdminScope открывает служебные ручки /admin/
+const AdminScope = "admin"
+
+// InternalScope — вызовы других сервисов платформы без владельца: как и admin, снимает
+// ограничение по владельцу, но не открывает /admin/
+const InternalScope = "internal"
+
+const maxActorLength = 128
+
+type requestIDKey struct{}
+
+// RequestID берёт X-Request-ID из запроса (или генерирует новый),
+// кладёт его в контекст и возвращает клиенту в заголовке ответа.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext возвращает request id, проставленный middleware RequestID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Actor берёт инициатора запроса из X-Actor (имя сервиса или пользователь,
+// проставленный gateway после аутентификации) и кладёт его в контекст сервиса.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := r.Header.Get(ActorHeader)
+		if actor == "" || len(actor) > maxActorLength {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(service.WithActor(r.Context(), actor)))
+	})
+}
+
+// Principal берёт владельца запроса из X-Owner-ID (пользователь или тенант, проставленный
+// gateway после аутентификации) и scope admin или internal из X-Scopes и кладёт их в контекст
+// сервиса. Доступ без ограничения по владельцу — только со scope. Запрос без обоих заголовков
+// анонимный: ему видно только опубликованное медиа, открытое всем.
+func Principal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p service.Principal
+		if raw := r.Header.Get(OwnerHeader); raw != "" {
+			owner, err := uuid.Parse(raw)
+			if err != nil || owner == uuid.Nil {
+				writeError(w, r, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid "+OwnerHeader, nil)
+				return
+			}
+			p.OwnerID = owner
+		}
+		scopes := strings.Fields(r.Header.Get(ScopesHeader))
+		p.Admin = slices.Contains(scopes, AdminScope) || slices.Contains(scopes, InternalScope)
+		next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// ReadPrimary включает чтение из primary, если клиент передал X-Read-Primary: true.
+// Клиент ставит заголовок сразу после собственной записи, чтобы не увидеть отстающую реплику.
+func ReadPrimary(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, err := strconv.ParseBool(r.Header.Get(ReadPrimaryHeader)); err == nil && v {
+			r = r.WithContext(repository.WithReadPrimary(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope пропускает запрос, только если в X-Scopes (через пробел, проставляет gateway
+// после аутентификации, как и X-Actor) есть scope; иначе 403.
+func RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(strings.Fields(r.Header.Get(ScopesHeader)), scope) {
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "missing scope "+scope, nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AccessLog кладёт в контекст request-scoped логгер с request_id (его берут zerolog.Ctx
+// в хендлерах и сервис) и пишет строку access log по завершении запроса.
+// Должен стоять после RequestID.
+func AccessLog(logger zerolog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		reqLogger := logger.With().Str("request_id", RequestIDFromContext(r.Context())).Logger()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r.WithContext(reqLogger.WithContext(r.Context())))
+
+		event := reqLogger.Info()
+		if rec.status >= http.StatusInternalServerError {
+			event = reqLogger.Warn()
+		}
+		event.
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", rec.status).
+			Dur("duration", time.Since(start)).
+			Msg("http request")
+	})
+}
+
+// statusRecorder запоминает код ответа для access log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap даёт http.ResponseController доступ к исходному writer'у (Flush и т.п.)
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
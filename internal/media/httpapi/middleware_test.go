@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestAccessLog_RequestScopedFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	svc := service.New(repository.NewMemoryRepository(), nil).WithLogger(logger)
+	router := NewRouter(New(svc).WithLogger(logger))
+
+	req := httptest.NewRequest(http.MethodPost, "/media", strings.NewReader(`{"type":"video","source":"s3://b/k","title":"clip"}`))
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set(OwnerHeader, uuid.NewString())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var created MediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+	lines := decodeLogLines(t, &buf)
+	require.Len(t, lines, 2)
+
+	// сервис пишет через request-scoped логгер: request_id из middleware + media_id
+	require.Equal(t, "media created", lines[0]["message"])
+	require.Equal(t, "req-1", lines[0]["request_id"])
+	require.Equal(t, created.ID.String(), lines[0]["media_id"])
+
+	require.Equal(t, "http request", lines[1]["message"])
+	require.Equal(t, "req-1", lines[1]["request_id"])
+	require.Equal(t, "/media", lines[1]["path"])
+	require.EqualValues(t, http.StatusCreated, lines[1]["status"])
+}
+
+func TestPrincipal_ScopesMediaByOwner(t *testing.T) {
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)))
+	owner := uuid.New()
+
+	do := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/media", `{"type":"video","source":"s3://b/k","title":"clip"}`, map[string]string{OwnerHeader: owner.String()})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created MediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, owner, created.OwnerID)
+
+	path := "/media/" + created.ID.String()
+	require.Equal(t, http.StatusOK, do(http.MethodGet, path, "", map[string]string{OwnerHeader: owner.String()}).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, path, "", map[string]string{OwnerHeader: uuid.NewString()}).Code)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, path, "", map[string]string{OwnerHeader: uuid.NewString(), ScopesHeader: AdminScope}).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, path, "", map[string]string{OwnerHeader: "not-a-uuid"}).Code)
+}
+
+func TestPrincipal_AnonymousSeesOnlyPublic(t *testing.T) {
+	ctx := context.Background()
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	router := NewRouter(New(svc))
+
+	do := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	list := func(headers map[string]string) []uuid.UUID {
+		rec := do(http.MethodGet, "/media", "", headers)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp ListMediaResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return responseIDs(resp.Items)
+	}
+
+	// Медиа без владельца (создано внутри процесса) — не «ничьё»: анонимному оно не видно
+	private, err := svc.CreateMedia(ctx, models.Video, "s3://b/private.mp4")
+	require.NoError(t, err)
+	path := "/media/" + private.ID.String()
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, path, "", nil).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodPatch, path+"/status", `{"status":"processing"}`, nil).Code)
+	require.Empty(t, list(nil))
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/media/search?q=private", "", nil).Code)
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/media", `{"type":"video","source":"s3://b/k","title":"clip"}`, nil).Code)
+
+	// Без ограничения по владельцу — только со scope
+	require.Equal(t, http.StatusOK, do(http.MethodGet, path, "", map[string]string{ScopesHeader: InternalScope}).Code)
+	require.Equal(t, []uuid.UUID{private.ID}, list(map[string]string{ScopesHeader: InternalScope}))
+
+	// Опубликованное public медиа видно всем
+	owner := service.WithPrincipal(ctx, service.Principal{OwnerID: uuid.New()})
+	public, err := svc.CreateMedia(owner, models.Video, "s3://b/public.mp4")
+	require.NoError(t, err)
+	for _, st := range []models.Status{models.ProcessingStatus, models.ReadyStatus} {
+		_, err = svc.ChangeStatus(owner, public.ID, st, service.ChangeMeta{})
+		require.NoError(t, err)
+	}
+	_, err = svc.SetVisibility(owner, public.ID, models.PublicVisibility, service.ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/media/"+public.ID.String(), "", nil).Code)
+	require.Equal(t, []uuid.UUID{public.ID}, list(nil))
+}
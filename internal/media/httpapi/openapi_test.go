@@ -0,0 +1,282 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+type openAPIDoc struct {
+	OpenAPI    string                    `json:"openapi"`
+	Paths      map[string]map[string]any `json:"paths"`
+	Components struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPISchema struct {
+	Type       string                     `json:"type"`
+	Required   []string                   `json:"required"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Enum       []string                   `json:"enum"`
+}
+
+func loadSpec(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(openAPISpec, &doc))
+	require.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "expected OpenAPI 3.x, got %q", doc.OpenAPI)
+	return doc
+}
+
+// jsonFields возвращает имена json-полей структуры DTO (с полями встроенных структур).
+func jsonFields(typ reflect.Type) []string {
+	var out []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			out = append(out, jsonFields(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func schemaFields(s openAPISchema) []string {
+	out := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// assertMatchesSchema проверяет, что JSON-объект содержит все required поля
+// схемы и не содержит недокументированных.
+func assertMatchesSchema(t *testing.T, schema openAPISchema, body []byte) {
+	t.Helper()
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal(body, &obj))
+
+	for _, name := range schema.Required {
+		require.Contains(t, obj, name, "required field %q is missing", name)
+	}
+	for name := range obj {
+		require.Contains(t, schema.Properties, name, "field %q is not documented", name)
+	}
+}
+
+func TestOpenAPI_DTOsInSync(t *testing.T) {
+	doc := loadSpec(t)
+
+	dtos := map[string]reflect.Type{
+		"CreateMediaRequest":        reflect.TypeOf(CreateMediaRequest{}),
+		"ChangeStatusRequest":       reflect.TypeOf(ChangeStatusRequest{}),
+		"MediaResponse":             reflect.TypeOf(MediaResponse{}),
+		"ErrorResponse":             reflect.TypeOf(ErrorResponse{}),
+		"FieldError":                reflect.TypeOf(FieldError{}),
+		"CreateMediaBatchRequest":   reflect.TypeOf(CreateMediaBatchRequest{}),
+		"CreateMediaBatchItem":      reflect.TypeOf(CreateMediaBatchItem{}),
+		"CreateMediaBatchResponse":  reflect.TypeOf(CreateMediaBatchResponse{}),
+		"BatchItemResult":           reflect.TypeOf(BatchItemResult{}),
+		"ChangeStatusBatchRequest":  reflect.TypeOf(ChangeStatusBatchRequest{}),
+		"ChangeStatusBatchItem":     reflect.TypeOf(ChangeStatusBatchItem{}),
+		"ChangeStatusBatchResponse": reflect.TypeOf(ChangeStatusBatchResponse{}),
+		"StatusBatchItemResult":     reflect.TypeOf(StatusBatchItemResult{}),
+		"StatusHistoryResponse":     reflect.TypeOf(StatusHistoryResponse{}),
+		"StatusChange":              reflect.TypeOf(StatusChangeResponse{}),
+		"ReportFailureRequest":      reflect.TypeOf(ReportFailureRequest{}),
+		"RecordContentRequest":      reflect.TypeOf(RecordContentRequest{}),
+		"DuplicatesResponse":        reflect.TypeOf(DuplicatesResponse{}),
+		"QuarantineRequest":         reflect.TypeOf(QuarantineRequest{}),
+		"ScheduleRequest":           reflect.TypeOf(ScheduleRequest{}),
+		"VisibilityRequest":         reflect.TypeOf(VisibilityRequest{}),
+		"DownloadResponse":          reflect.TypeOf(DownloadResponse{}),
+		"SearchMediaResponse":       reflect.TypeOf(SearchMediaResponse{}),
+		"ListMediaResponse":         reflect.TypeOf(ListMediaResponse{}),
+		"ListFacets":                reflect.TypeOf(ListFacets{}),
+		"SearchHit":                 reflect.TypeOf(SearchHitResponse{}),
+		"ReadinessResponse":         reflect.TypeOf(ReadinessResponse{}),
+		"StatsResponse":             reflect.TypeOf(StatsResponse{}),
+		"OwnerUsage":                reflect.TypeOf(OwnerUsageResponse{}),
+		"IngestStats":               reflect.TypeOf(IngestStatsResponse{}),
+		"ProcessingStats":           reflect.TypeOf(ProcessingStatsResponse{}),
+		"CreateCollectionRequest":   reflect.TypeOf(CreateCollectionRequest{}),
+		"UpdateCollectionRequest":   reflect.TypeOf(UpdateCollectionRequest{}),
+		"AddCollectionItemRequest":  reflect.TypeOf(AddCollectionItemRequest{}),
+		"CollectionResponse":        reflect.TypeOf(CollectionResponse{}),
+		"CollectionDetailsResponse": reflect.TypeOf(CollectionDetailsResponse{}),
+		"CollectionItemResponse":    reflect.TypeOf(CollectionItemResponse{}),
+		"ListCollectionsResponse":   reflect.TypeOf(ListCollectionsResponse{}),
+		"GrantRequest":              reflect.TypeOf(GrantRequest{}),
+		"GrantResponse":             reflect.TypeOf(GrantResponse{}),
+		"ListGrantsResponse":        reflect.TypeOf(ListGrantsResponse{}),
+		"ShareLinkRequest":          reflect.TypeOf(ShareLinkRequest{}),
+		"ShareLinkResponse":         reflect.TypeOf(ShareLinkResponse{}),
+		"SharedMediaResponse":       reflect.TypeOf(SharedMediaResponse{}),
+	}
+
+	for name, typ := range dtos {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := doc.Components.Schemas[name]
+			require.True(t, ok, "schema %s is missing in openapi.json", name)
+			require.Equal(t, jsonFields(typ), schemaFields(schema))
+		})
+	}
+}
+
+func TestOpenAPI_EnumsInSync(t *testing.T) {
+	doc := loadSpec(t)
+
+	require.ElementsMatch(t,
+		[]string{string(models.Video), string(models.Audio), string(models.File)},
+		doc.Components.Schemas["MediaType"].Enum,
+	)
+	require.ElementsMatch(t,
+		[]string{
+			string(models.UploadedStatus),
+			string(models.ProcessingStatus),
+			string(models.ReadyStatus),
+			string(models.ScheduledStatus),
+			string(models.FailedStatus),
+			string(models.DeletedStatus),
+			string(models.ArchivedStatus),
+			string(models.QuarantinedStatus),
+		},
+		doc.Components.Schemas["Status"].Enum,
+	)
+	require.ElementsMatch(t,
+		[]string{
+			string(models.DraftVisibility),
+			string(models.PrivateVisibility),
+			string(models.UnlistedVisibility),
+			string(models.PublicVisibility),
+		},
+		doc.Components.Schemas["Visibility"].Enum,
+	)
+	require.ElementsMatch(t,
+		[]string{string(models.ReadPermission), string(models.DownloadPermission)},
+		doc.Components.Schemas["Permission"].Enum,
+	)
+}
+
+func TestOpenAPI_PathsDocumented(t *testing.T) {
+	doc := loadSpec(t)
+
+	want := map[string][]string{
+		"/health":                            {"get"},
+		"/readyz":                            {"get"},
+		"/media":                             {"get", "post"},
+		"/media/batch":                       {"post"},
+		"/media/status/batch":                {"patch"},
+		"/media/search":                      {"get"},
+		"/media/{id}":                        {"get", "delete"},
+		"/media/{id}/status":                 {"patch"},
+		"/media/{id}/history":                {"get"},
+		"/media/{id}/failures":               {"post"},
+		"/media/{id}/content":                {"put"},
+		"/media/{id}/quarantine":             {"post"},
+		"/media/{id}/schedule":               {"put"},
+		"/media/{id}/visibility":             {"patch"},
+		"/media/{id}/download":               {"get"},
+		"/media/{id}/download/content":       {"get"},
+		"/media/{id}/events":                 {"get"},
+		"/media/{id}/duplicates":             {"get"},
+		"/media/{id}/grants":                 {"get"},
+		"/media/{id}/grants/{principal}":     {"put", "delete"},
+		"/media/{id}/share":                  {"post"},
+		"/shared/{token}":                    {"get"},
+		"/shared/{token}/download":           {"get"},
+		"/stats":                             {"get"},
+		"/collections":                       {"get", "post"},
+		"/collections/{id}":                  {"get", "patch", "delete"},
+		"/collections/{id}/items":            {"post"},
+		"/collections/{id}/items/{media_id}": {"delete"},
+	}
+
+	for path, methods := range want {
+		ops, ok := doc.Paths[path]
+		require.True(t, ok, "path %s is not documented", path)
+		for _, m := range methods {
+			require.Contains(t, ops, m, "%s %s is not documented", strings.ToUpper(m), path)
+		}
+	}
+}
+
+func TestOpenAPI_WritesAcceptIdempotencyKey(t *testing.T) {
+	doc := loadSpec(t)
+
+	for path, ops := range doc.Paths {
+		for _, method := range []string{"post", "patch"} {
+			op, ok := ops[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			params, _ := op["parameters"].([]any)
+			require.Contains(t, params, map[string]any{"$ref": "#/components/parameters/IdempotencyKey"},
+				"%s %s: Idempotency-Key is not documented", method, path)
+		}
+	}
+}
+
+func TestOpenAPI_ResponsesMatchSchema(t *testing.T) {
+	doc := loadSpec(t)
+	router := NewRouter(New(nil))
+
+	// GET /health
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assertMatchesSchema(t, doc.Components.Schemas["HealthResponse"], rec.Body.Bytes())
+
+	// Ошибка метода отдаётся в формате ErrorResponse
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/health", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assertMatchesSchema(t, doc.Components.Schemas["ErrorResponse"], rec.Body.Bytes())
+
+	// MediaResponse
+	now := time.Now()
+	body, err := json.Marshal(toMediaResponse(&models.Media{
+		ID:        uuid.New(),
+		Status:    models.UploadedStatus,
+		Type:      models.Video,
+		Source:    "s3://bucket/file.mp4",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}))
+	require.NoError(t, err)
+	assertMatchesSchema(t, doc.Components.Schemas["MediaResponse"], body)
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+	require.JSONEq(t, string(openAPISpec), rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rec.Body.String(), "/openapi.json")
+}
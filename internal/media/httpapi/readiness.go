@@ -0,0 +1,59 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout — общий лимит на все проверки одного /readyz
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck возвращает ошибку, если компонент не готов принимать нагрузку
+type ReadinessCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// ReadinessResponse — ответ /readyz; checks содержит только непрошедшие проверки
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// WithReadinessCheck добавляет проверку в /readyz
+func (h *Handler) WithReadinessCheck(name string, check ReadinessCheck) *Handler {
+	h.readiness = append(h.readiness, namedCheck{name: name, check: check})
+	return h
+}
+
+// Readyz — readiness probe: 200, если все проверки прошли, иначе 503 с причинами.
+// В отличие от /health, не готовый инстанс жив и не должен перезапускаться.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	resp := ReadinessResponse{Status: "ready"}
+	for _, c := range h.readiness {
+		if err := c.check(ctx); err != nil {
+			if resp.Checks == nil {
+				resp.Checks = make(map[string]string)
+			}
+			resp.Checks[c.name] = err.Error()
+		}
+	}
+
+	if len(resp.Checks) > 0 {
+		resp.Status = "not_ready"
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,34 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyz(t *testing.T) {
+	backlogErr := error(nil)
+	h := New(nil).
+		WithReadinessCheck("outbox_backlog", func(context.Context) error { return backlogErr }).
+		WithReadinessCheck("noop", func(context.Context) error { return nil })
+	router := NewRouter(h)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	backlogErr = errors.New("outbox backlog 5000 exceeds threshold 1000")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var resp ReadinessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "not_ready", resp.Status)
+	require.Equal(t, map[string]string{"outbox_backlog": backlogErr.Error()}, resp.Checks)
+}
@@ -9,32 +9,160 @@ func NewRouter(h *Handler) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health", h.Health)
+	mux.HandleFunc("/readyz", h.Readyz)
 
-	// POST /media (создание)
+	// Документация API
+	mux.HandleFunc("/openapi.json", h.OpenAPI)
+	mux.HandleFunc("/docs", h.SwaggerUI)
+
+	// POST /media (создание), GET /media (список по курсору)
 	mux.HandleFunc("/media", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
+		switch r.Method {
+		case http.MethodPost:
 			h.CreateMedia(w, r)
-			return
+		case http.MethodGet:
+			h.ListMedia(w, r)
+		default:
+			writeMethodNotAllowed(w, r)
 		}
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	})
 
-	// GET /media/{id} и PATCH /media/{id}/status
+	// POST /media/batch (пакетное создание)
+	mux.HandleFunc("/media/batch", h.CreateMediaBatch)
+
+	// PATCH /media/status/batch (пакетная смена статуса)
+	mux.HandleFunc("/media/status/batch", h.ChangeStatusBatch)
+
+	// GET /media/search (полнотекстовый поиск)
+	mux.HandleFunc("/media/search", h.SearchMedia)
+
+	// POST /collections, GET /collections (список по курсору)
+	mux.HandleFunc("/collections", h.Collections)
+
+	// GET/PATCH/DELETE /collections/{id}, POST /collections/{id}/items,
+	// DELETE /collections/{id}/items/{media_id}
+	mux.HandleFunc("/collections/", h.Collection)
+
+	// GET /shared/{token}, GET /shared/{token}/download (анонимные ссылки)
+	mux.HandleFunc("/shared/", h.Shared)
+
+	// GET /stats (сводка для дашбордов)
+	mux.HandleFunc("/stats", h.Stats)
+
+	// GET/DELETE /media/{id}, PATCH /media/{id}/status, GET /media/{id}/history, POST /media/{id}/failures,
+	// PUT /media/{id}/content, POST /media/{id}/quarantine, GET /media/{id}/download, GET /media/{id}/download/content,
+	// GET /media/{id}/events, GET /media/{id}/duplicates, PUT /media/{id}/schedule,
+	// PATCH /media/{id}/visibility, GET /media/{id}/grants, PUT/DELETE /media/{id}/grants/{principal},
+	// POST /media/{id}/share
 	mux.HandleFunc("/media/", func(w http.ResponseWriter, r *http.Request) {
+		// GET /media/{id}/events (SSE)
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			h.MediaEvents(w, r)
+			return
+		}
+
+		// GET /media/{id}/download/content — до /content, у которого тот же суффикс
+		if strings.HasSuffix(r.URL.Path, "/download/content") {
+			h.DownloadContent(w, r)
+			return
+		}
+
+		// GET /media/{id}/download
+		if strings.HasSuffix(r.URL.Path, "/download") {
+			h.Download(w, r)
+			return
+		}
+
 		// PATCH /media/{id}/status
 		if r.Method == http.MethodPatch && strings.HasSuffix(r.URL.Path, "/status") {
 			h.ChangeStatus(w, r)
 			return
 		}
 
+		// POST /media/{id}/failures
+		if strings.HasSuffix(r.URL.Path, "/failures") {
+			h.ReportFailure(w, r)
+			return
+		}
+
+		// PUT /media/{id}/content
+		if strings.HasSuffix(r.URL.Path, "/content") {
+			h.RecordContent(w, r)
+			return
+		}
+
+		// POST /media/{id}/quarantine
+		if strings.HasSuffix(r.URL.Path, "/quarantine") {
+			h.Quarantine(w, r)
+			return
+		}
+
+		// PATCH /media/{id}/visibility
+		if strings.HasSuffix(r.URL.Path, "/visibility") {
+			h.Visibility(w, r)
+			return
+		}
+
+		// GET /media/{id}/grants, PUT/DELETE /media/{id}/grants/{principal}
+		if strings.Contains(r.URL.Path, "/grants") {
+			h.Grants(w, r)
+			return
+		}
+
+		// POST /media/{id}/share
+		if strings.HasSuffix(r.URL.Path, "/share") {
+			h.Share(w, r)
+			return
+		}
+
+		// PUT /media/{id}/schedule
+		if strings.HasSuffix(r.URL.Path, "/schedule") {
+			h.Schedule(w, r)
+			return
+		}
+
+		// GET /media/{id}/duplicates
+		if strings.HasSuffix(r.URL.Path, "/duplicates") {
+			h.Duplicates(w, r)
+			return
+		}
+
+		// GET /media/{id}/history
+		if strings.HasSuffix(r.URL.Path, "/history") {
+			h.StatusHistory(w, r)
+			return
+		}
+
 		// GET /media/{id}
 		if r.Method == http.MethodGet {
 			h.GetMedia(w, r)
 			return
 		}
 
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		// DELETE /media/{id}
+		if r.Method == http.MethodDelete {
+			h.DeleteMedia(w, r)
+			return
+		}
+
+		writeMethodNotAllowed(w, r)
 	})
 
-	return mux
+	var api http.Handler = ReadPrimary(mux)
+	if h.audit != nil {
+		api = Audit(h.audit, api)
+	}
+	// Снаружи аудита: повтор сохранённого ответа ничего не меняет и в журнал не пишется
+	if h.idempotency != nil {
+		api = Idempotency(h.idempotency, h.idempotencyTTL, api)
+	}
+	api = Actor(Principal(api))
+	// Preflight отвечается до Principal и роутера: у OPTIONS нет ни заголовков владельца, ни ручек
+	if h.cors != nil {
+		api = CORS(h.cors, api)
+	}
+	if h.compressMinSize > 0 {
+		api = Compress(h.compressMinSize, api)
+	}
+	return RequestID(AccessLog(h.logger, api))
 }
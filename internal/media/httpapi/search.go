@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+const defaultSearchLimit = 20
+
+// SearchMedia — GET /media/search?q=...&tag=...&status=...&limit=...&offset=...
+func (h *Handler) SearchMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	req, errs := parseSearchRequest(r.URL.Query())
+	if len(errs) == 0 {
+		errs = req.Validate()
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, r, errs)
+		return
+	}
+
+	hits, err := h.svc.SearchMedia(r.Context(), repository.SearchQuery{
+		Text:   req.Query,
+		Tags:   req.Tags,
+		Status: req.Status,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	resp := SearchMediaResponse{
+		Items:  make([]SearchHitResponse, 0, len(hits)),
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}
+	for i := range hits {
+		resp.Items = append(resp.Items, SearchHitResponse{
+			Media: toMediaResponse(&hits[i].Media),
+			Rank:  hits[i].Rank,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseSearchRequest разбирает query-параметры; ошибки формата чисел отдаются как ошибки полей
+func parseSearchRequest(q url.Values) (SearchMediaRequest, []FieldError) {
+	var v validator
+	req := SearchMediaRequest{
+		Query:  q.Get("q"),
+		Tags:   q["tag"],
+		Status: models.Status(q.Get("status")),
+		Limit:  defaultSearchLimit,
+	}
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.add("limit", "must be an integer")
+		}
+		req.Limit = n
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.add("offset", "must be an integer")
+		}
+		req.Offset = n
+	}
+	return req, v.errs
+}
@@ -0,0 +1,78 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func TestSearchMedia(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	now := time.Now().UTC()
+
+	for _, m := range []*models.Media{
+		{Title: "Cat video", Tags: models.Tags{"pets"}},
+		{Title: "Dog video", Tags: models.Tags{"pets", "dogs"}},
+		{Title: "Lecture", Metadata: models.Metadata{"description": "about a cat"}},
+	} {
+		m.ID, m.Status, m.Type, m.Source = uuid.New(), models.UploadedStatus, models.Video, "s3://b/k"
+		m.CreatedAt, m.UpdatedAt = now, now
+		require.NoError(t, repo.Create(ctx, m))
+	}
+
+	router := NewRouter(New(service.New(repo, nil)))
+	search := func(query string) SearchMediaResponse {
+		req := httptest.NewRequest(http.MethodGet, "/media/search?"+query, nil)
+		req.Header.Set(ScopesHeader, InternalScope)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp SearchMediaResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// Совпадение в title релевантнее совпадения в описании
+	resp := search("q=cat")
+	require.Len(t, resp.Items, 2)
+	require.Equal(t, "Cat video", resp.Items[0].Media.Title)
+	require.Equal(t, "Lecture", resp.Items[1].Media.Title)
+	require.Greater(t, resp.Items[0].Rank, resp.Items[1].Rank)
+
+	resp = search("tag=pets&tag=dogs")
+	require.Len(t, resp.Items, 1)
+	require.Equal(t, "Dog video", resp.Items[0].Media.Title)
+
+	resp = search("q=video&limit=1&offset=1")
+	require.Len(t, resp.Items, 1)
+	require.Equal(t, 1, resp.Limit)
+	require.Equal(t, 1, resp.Offset)
+}
+
+func TestSearchMedia_Validation(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	for _, query := range []string{"limit=0", "limit=101", "limit=x", "offset=-1", "status=unknown"} {
+		t.Run(query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/search?"+query, nil))
+			require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/media/search", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
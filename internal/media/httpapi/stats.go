@@ -0,0 +1,169 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/projection"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+const (
+	maxStatsOwners = 100
+	// statsWindow — окно приёма и обработки в GET /stats
+	statsWindow = 24 * time.Hour
+)
+
+// StatsReader — сводка проекций журнала событий; реализуется *postgres.ProjectionsRepo
+type StatsReader interface {
+	Stats(ctx context.Context, f projection.StatsFilter) (projection.Stats, error)
+}
+
+// OutboxBacklog — число неотправленных событий outbox; реализуется *postgres.OutboxRepo
+type OutboxBacklog interface {
+	CountPending(ctx context.Context) (int64, error)
+}
+
+// WithStats добавляет в GET /stats использование хранилища владельцами из проекций
+func (h *Handler) WithStats(s StatsReader) *Handler {
+	h.stats = s
+	return h
+}
+
+// WithOutboxBacklog добавляет в GET /stats backlog outbox (только для scope admin)
+func (h *Handler) WithOutboxBacklog(b OutboxBacklog) *Handler {
+	h.outbox = b
+	return h
+}
+
+// StatsResponse — ответ GET /stats
+type StatsResponse struct {
+	ByStatus      map[string]int64        `json:"by_status"`
+	ByType        map[string]int64        `json:"by_type"`
+	Total         int64                   `json:"total"`
+	WindowHours   int                     `json:"window_hours"`
+	Ingest        IngestStatsResponse     `json:"ingest"`
+	Processing    ProcessingStatsResponse `json:"processing"`
+	OutboxBacklog *int64                  `json:"outbox_backlog,omitempty"`
+	Owners        []OwnerUsageResponse    `json:"owners,omitempty"`
+	GeneratedAt   time.Time               `json:"generated_at"`
+}
+
+// IngestStatsResponse — приём media за окно
+type IngestStatsResponse struct {
+	Created int64   `json:"created"`
+	PerHour float64 `json:"per_hour"`
+}
+
+// ProcessingStatsResponse — обработки, завершённые за окно
+type ProcessingStatsResponse struct {
+	Completed          int64   `json:"completed"`
+	Failed             int64   `json:"failed"`
+	FailureRate        float64 `json:"failure_rate"`
+	AvgDurationSeconds float64 `json:"avg_duration_seconds"`
+}
+
+type OwnerUsageResponse struct {
+	OwnerID uuid.UUID `json:"owner_id"`
+	Objects int64     `json:"objects"`
+	Bytes   int64     `json:"bytes"`
+}
+
+// Stats — GET /stats?owner_id=&limit=: сводка для дашбордов и smoke-проверок. Счётчики
+// по статусам и типам, приём и обработка за последние 24 часа считаются агрегатными
+// запросами; owners — из проекции owner_storage_usage (если включена, отстаёт на несколько
+// секунд), outbox_backlog — только для scope admin. Вызывающий без scope admin видит
+// только свои media; owner_id сужает выборку админу.
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	var (
+		v validator
+		f projection.StatsFilter
+	)
+	q := r.URL.Query()
+	if s := q.Get("owner_id"); s != "" {
+		owner, err := uuid.Parse(s)
+		if err != nil {
+			v.add("owner_id", "must be a uuid")
+		}
+		f.Owner = &owner
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxStatsOwners {
+			v.add("limit", "must be an integer between 1 and %d", maxStatsOwners)
+		}
+		f.Limit = n
+	}
+	if len(v.errs) > 0 {
+		writeValidationError(w, r, v.errs)
+		return
+	}
+	p, ok := service.PrincipalFromContext(r.Context())
+	restricted := ok && !p.Admin
+	if restricted {
+		f.Owner = &p.OwnerID
+	}
+
+	var owner uuid.UUID
+	if f.Owner != nil {
+		owner = *f.Owner
+	}
+	d, err := h.svc.Dashboard(r.Context(), owner, statsWindow)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	resp := StatsResponse{
+		ByStatus:    make(map[string]int64, len(d.ByStatus)),
+		ByType:      make(map[string]int64, len(d.ByType)),
+		Total:       d.Total,
+		WindowHours: int(statsWindow / time.Hour),
+		Ingest: IngestStatsResponse{
+			Created: d.Created,
+			PerHour: float64(d.Created) / statsWindow.Hours(),
+		},
+		Processing: ProcessingStatsResponse{
+			Completed:          d.Completed,
+			Failed:             d.Failed,
+			FailureRate:        d.FailureRate(),
+			AvgDurationSeconds: d.AvgProcessing.Seconds(),
+		},
+		GeneratedAt: time.Now().UTC(),
+	}
+	for status, n := range d.ByStatus {
+		resp.ByStatus[string(status)] = n
+	}
+	for typ, n := range d.ByType {
+		resp.ByType[string(typ)] = n
+	}
+
+	if h.outbox != nil && !restricted {
+		n, err := h.outbox.CountPending(r.Context())
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		resp.OutboxBacklog = &n
+	}
+	if h.stats != nil {
+		st, err := h.stats.Stats(r.Context(), f)
+		if err != nil {
+			writeServiceError(w, r, err)
+			return
+		}
+		resp.Owners = make([]OwnerUsageResponse, 0, len(st.Owners))
+		for _, o := range st.Owners {
+			resp.Owners = append(resp.Owners, OwnerUsageResponse{OwnerID: o.OwnerID, Objects: o.Objects, Bytes: o.Bytes})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/projection"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+type fakeStats struct {
+	got projection.StatsFilter
+}
+
+func (f *fakeStats) Stats(_ context.Context, filter projection.StatsFilter) (projection.Stats, error) {
+	f.got = filter
+	return projection.Stats{
+		ByStatus: map[models.Status]int64{models.ReadyStatus: 2},
+		Total:    2,
+		Owners:   []projection.OwnerStats{{OwnerID: uuid.Nil, Objects: 2, Bytes: 10}},
+	}, nil
+}
+
+type fakeBacklog int64
+
+func (f fakeBacklog) CountPending(context.Context) (int64, error) { return int64(f), nil }
+
+func TestStats(t *testing.T) {
+	doc := loadSpec(t)
+	stats := &fakeStats{}
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	owner, other := uuid.New(), uuid.New()
+	_, err := svc.CreateMedia(service.WithPrincipal(context.Background(), service.Principal{OwnerID: owner}), models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.CreateMedia(context.Background(), models.Audio, "s3://bucket/b.mp3")
+	require.NoError(t, err)
+
+	router := NewRouter(New(svc).WithStats(stats).WithOutboxBacklog(fakeBacklog(7)))
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	internal := http.Header{"X-Scopes": {InternalScope}}
+	rec := get("/stats?limit=5", internal)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assertMatchesSchema(t, doc.Components.Schemas["StatsResponse"], rec.Body.Bytes())
+	var resp StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, map[string]int64{"uploaded": 2}, resp.ByStatus)
+	require.Equal(t, map[string]int64{"video": 1, "audio": 1}, resp.ByType)
+	require.Equal(t, int64(2), resp.Total)
+	require.Equal(t, 24, resp.WindowHours)
+	require.Equal(t, int64(2), resp.Ingest.Created)
+	require.Zero(t, resp.Processing.FailureRate)
+	require.Equal(t, int64(7), *resp.OutboxBacklog)
+	require.Len(t, resp.Owners, 1)
+	require.Equal(t, projection.StatsFilter{Limit: 5}, stats.got)
+
+	// Владелец без scope admin видит только себя, даже если просит чужого, и не видит outbox
+	rec = get("/stats?owner_id="+other.String(), http.Header{"X-Owner-Id": {owner.String()}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, owner, *stats.got.Owner)
+	resp = StatsResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, int64(1), resp.Total)
+	require.Nil(t, resp.OutboxBacklog)
+
+	rec = get("/stats?owner_id="+other.String(), http.Header{"X-Owner-Id": {owner.String()}, "X-Scopes": {AdminScope}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, other, *stats.got.Owner)
+	resp = StatsResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Zero(t, resp.Total)
+
+	require.Equal(t, http.StatusUnprocessableEntity, get("/stats?limit=0", nil).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, get("/stats?owner_id=x", nil).Code)
+
+	// Анонимному вызывающему сводка не отдаётся
+	require.Equal(t, http.StatusForbidden, get("/stats", nil).Code)
+
+	// Без проекций owners отсутствует
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	req.Header.Set(ScopesHeader, InternalScope)
+	NewRouter(New(svc)).ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assertMatchesSchema(t, doc.Components.Schemas["StatsResponse"], rec.Body.Bytes())
+	require.NotContains(t, rec.Body.String(), `"owners"`)
+}
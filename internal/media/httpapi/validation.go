@@ -0,0 +1,336 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+const (
+	maxSourceLength = 2048
+	maxReasonLength = 1024
+	maxContentType  = 255
+	maxThreatLength = 256
+	maxScannerName  = 64
+
+	maxMediaTitle    = 200
+	maxMediaTags     = 20
+	maxTagLength     = 64
+	maxMetadataKeys  = 50
+	maxMetadataKey   = 64
+	maxMetadataValue = 1024
+
+	maxCollectionTitle       = 200
+	maxCollectionDescription = 2000
+
+	maxSearchQueryLength = 256
+	maxSearchTags        = 20
+	maxSearchLimit       = 100
+)
+
+// allowedSourceSchemes — откуда сервис в принципе умеет забирать медиа
+var allowedSourceSchemes = map[string]bool{
+	"s3":    true,
+	"gs":    true,
+	"http":  true,
+	"https": true,
+	"file":  true,
+}
+
+// FieldError — ошибка валидации конкретного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validator накапливает ошибки по полям, чтобы клиент увидел их все за один запрос
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) maxLen(field, value string, max int) {
+	if len(value) > max {
+		v.add(field, "must be at most %d characters", max)
+	}
+}
+
+func (v *validator) uri(field, value string) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		v.add(field, "must be an absolute URI")
+		return
+	}
+	if !allowedSourceSchemes[strings.ToLower(u.Scheme)] {
+		v.add(field, "unsupported scheme %q", u.Scheme)
+		return
+	}
+	if u.Host == "" && u.Path == "" {
+		v.add(field, "must point to an object")
+	}
+}
+
+func (v *validator) mediaType(field string, t models.MediaType) {
+	switch t {
+	case models.Video, models.Audio, models.File:
+	default:
+		v.add(field, "must be one of: video, audio, file")
+	}
+}
+
+func (v *validator) status(field string, s models.Status) {
+	switch s {
+	case models.UploadedStatus, models.ProcessingStatus, models.ReadyStatus, models.FailedStatus:
+	default:
+		v.add(field, "must be one of: uploaded, processing, ready, failed")
+	}
+}
+
+func (v *validator) visibility(field string, vis models.Visibility) {
+	switch vis {
+	case models.DraftVisibility, models.PrivateVisibility, models.UnlistedVisibility, models.PublicVisibility:
+	default:
+		v.add(field, "must be one of: draft, private, unlisted, public")
+	}
+}
+
+func (v *validator) permission(field string, p models.Permission) {
+	if !p.Valid() {
+		v.add(field, "must be one of: read, download")
+	}
+}
+
+// tags — не больше maxMediaTags непустых меток до maxTagLength символов
+func (v *validator) tags(field string, tags []string) {
+	if len(tags) > maxMediaTags {
+		v.add(field, "must have at most %d values", maxMediaTags)
+		return
+	}
+	for i, tag := range tags {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		if v.required(name, tag) {
+			v.maxLen(name, tag, maxTagLength)
+		}
+	}
+}
+
+// metadata — не больше maxMetadataKeys пар с непустыми ключами; порядок ошибок не зависит от обхода map
+func (v *validator) metadata(field string, meta map[string]string) {
+	if len(meta) > maxMetadataKeys {
+		v.add(field, "must have at most %d keys", maxMetadataKeys)
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(meta)) {
+		if strings.TrimSpace(key) == "" {
+			v.add(field, "keys must not be empty")
+			continue
+		}
+		if len(key) > maxMetadataKey {
+			v.add(field, "keys must be at most %d characters", maxMetadataKey)
+			continue
+		}
+		v.maxLen(field+"."+key, meta[key], maxMetadataValue)
+	}
+}
+
+// mediaSource — общие проверки type и source для POST /media и элементов batch
+func (v *validator) mediaSource(t models.MediaType, source string) {
+	if v.required("type", string(t)) {
+		v.mediaType("type", t)
+	}
+	if v.required("source", source) {
+		v.maxLen("source", source, maxSourceLength)
+		v.uri("source", source)
+	}
+}
+
+func (r CreateMediaRequest) Validate() []FieldError {
+	var v validator
+	v.mediaSource(r.Type, r.Source)
+	if v.required("title", r.Title) {
+		v.maxLen("title", r.Title, maxMediaTitle)
+	}
+	v.tags("tags", r.Tags)
+	v.metadata("metadata", r.Metadata)
+	return v.errs
+}
+
+// Validate — элемент batch проверяется как POST /media, но без описательных полей
+func (r CreateMediaBatchItem) Validate() []FieldError {
+	var v validator
+	v.mediaSource(r.Type, r.Source)
+	return v.errs
+}
+
+func (r ChangeStatusRequest) Validate() []FieldError {
+	var v validator
+	if v.required("status", string(r.Status)) {
+		v.status("status", r.Status)
+	}
+	// Для failed причина обязательна: по ней оператор поймёт, почему упала обработка
+	if r.Status == models.FailedStatus && strings.TrimSpace(r.Reason) == "" {
+		v.add("reason", "is required when status is failed")
+	}
+	v.maxLen("reason", r.Reason, maxReasonLength)
+	return v.errs
+}
+
+func (r ReportFailureRequest) Validate() []FieldError {
+	var v validator
+	if v.required("error", r.Error) {
+		v.maxLen("error", r.Error, maxReasonLength)
+	}
+	return v.errs
+}
+
+func (r RecordContentRequest) Validate() []FieldError {
+	var v validator
+	if v.required("checksum_sha256", r.Checksum) {
+		if sum, err := hex.DecodeString(r.Checksum); err != nil || len(sum) != sha256.Size || r.Checksum != strings.ToLower(r.Checksum) {
+			v.add("checksum_sha256", "must be a lowercase hex sha256")
+		}
+	}
+	if r.Size < 0 {
+		v.add("size_bytes", "must not be negative")
+	}
+	if v.required("content_type", r.ContentType) {
+		v.maxLen("content_type", r.ContentType, maxContentType)
+	}
+	if r.Source != "" {
+		v.maxLen("source", r.Source, maxSourceLength)
+		v.uri("source", r.Source)
+	}
+	return v.errs
+}
+
+func (r QuarantineRequest) Validate() []FieldError {
+	var v validator
+	if v.required("threat", r.Threat) {
+		v.maxLen("threat", r.Threat, maxThreatLength)
+	}
+	v.maxLen("scanner", r.Scanner, maxScannerName)
+	return v.errs
+}
+
+func (r VisibilityRequest) Validate() []FieldError {
+	var v validator
+	if v.required("visibility", string(r.Visibility)) {
+		v.visibility("visibility", r.Visibility)
+	}
+	return v.errs
+}
+
+func (r GrantRequest) Validate() []FieldError {
+	var v validator
+	if v.required("permission", string(r.Permission)) {
+		v.permission("permission", r.Permission)
+	}
+	return v.errs
+}
+
+// Validate проверяет право и срок ссылки (не больше maxTTL) и возвращает срок; 0 — по умолчанию
+func (r ShareLinkRequest) Validate(maxTTL time.Duration) (time.Duration, []FieldError) {
+	var v validator
+	if r.Permission != "" {
+		v.permission("permission", r.Permission)
+	}
+	var ttl time.Duration
+	if r.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(r.TTL)
+		switch {
+		case err != nil:
+			v.add("ttl", "must be a duration like 24h")
+		case ttl < time.Second || ttl > maxTTL:
+			v.add("ttl", "must be between 1s and %v", maxTTL)
+		}
+	}
+	return ttl, v.errs
+}
+
+func (r ScheduleRequest) Validate() []FieldError {
+	var v validator
+	if r.PublishAt != nil && r.ExpiresAt != nil && !r.ExpiresAt.After(*r.PublishAt) {
+		v.add("expires_at", "must be after publish_at")
+	}
+	return v.errs
+}
+
+func (r CreateCollectionRequest) Validate() []FieldError {
+	var v validator
+	if v.required("title", r.Title) {
+		v.maxLen("title", r.Title, maxCollectionTitle)
+	}
+	v.maxLen("description", r.Description, maxCollectionDescription)
+	return v.errs
+}
+
+func (r UpdateCollectionRequest) Validate() []FieldError {
+	var v validator
+	if r.Title == nil && r.Description == nil {
+		v.add("title", "title or description is required")
+	}
+	if r.Title != nil && v.required("title", *r.Title) {
+		v.maxLen("title", *r.Title, maxCollectionTitle)
+	}
+	if r.Description != nil {
+		v.maxLen("description", *r.Description, maxCollectionDescription)
+	}
+	return v.errs
+}
+
+func (r AddCollectionItemRequest) Validate() []FieldError {
+	var v validator
+	if r.MediaID == uuid.Nil {
+		v.add("media_id", "is required")
+	}
+	if r.Position != nil && *r.Position < 0 {
+		v.add("position", "must not be negative")
+	}
+	return v.errs
+}
+
+func (r SearchMediaRequest) Validate() []FieldError {
+	var v validator
+	v.maxLen("q", r.Query, maxSearchQueryLength)
+	if len(r.Tags) > maxSearchTags {
+		v.add("tag", "must have at most %d values", maxSearchTags)
+	}
+	if r.Status != "" {
+		v.status("status", r.Status)
+	}
+	if r.Limit < 1 || r.Limit > maxSearchLimit {
+		v.add("limit", "must be between 1 and %d", maxSearchLimit)
+	}
+	if r.Offset < 0 {
+		v.add("offset", "must not be negative")
+	}
+	return v.errs
+}
+
+// writeValidationError отвечает 422 со списком ошибок по полям
+func writeValidationError(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	writeError(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, "request validation failed",
+		map[string]any{"fields": errs})
+}
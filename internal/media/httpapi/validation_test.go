@@ -0,0 +1,218 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+func fieldsOf(errs []FieldError) []string {
+	out := make([]string, 0, len(errs))
+	for _, e := range errs {
+		out = append(out, e.Field)
+	}
+	return out
+}
+
+func TestCreateMediaRequest_Validate(t *testing.T) {
+	cases := []struct {
+		name   string
+		req    CreateMediaRequest
+		fields []string
+	}{
+		{name: "valid s3", req: CreateMediaRequest{Type: models.Video, Source: "s3://bucket/file.mp4", Title: "clip"}},
+		{name: "valid https", req: CreateMediaRequest{Type: models.Audio, Source: "https://cdn.example.com/a.mp3", Title: "clip"}},
+		{name: "all missing", req: CreateMediaRequest{}, fields: []string{"type", "source", "title"}},
+		{name: "unknown type", req: CreateMediaRequest{Type: "image", Source: "s3://b/k", Title: "clip"}, fields: []string{"type"}},
+		{name: "relative source", req: CreateMediaRequest{Type: models.File, Source: "file.mp4", Title: "clip"}, fields: []string{"source"}},
+		{name: "bad scheme", req: CreateMediaRequest{Type: models.File, Source: "ftp://host/file", Title: "clip"}, fields: []string{"source"}},
+		{name: "no object", req: CreateMediaRequest{Type: models.File, Source: "s3://", Title: "clip"}, fields: []string{"source"}},
+		{
+			name:   "too long",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://bucket/" + strings.Repeat("a", maxSourceLength), Title: "clip"},
+			fields: []string{"source"},
+		},
+		{name: "blank title", req: CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "  "}, fields: []string{"title"}},
+		{
+			name:   "title too long",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: strings.Repeat("a", maxMediaTitle+1)},
+			fields: []string{"title"},
+		},
+		{
+			name: "valid tags and metadata",
+			req: CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip",
+				Tags: []string{"promo", "4k"}, Metadata: map[string]string{"description": "teaser"}},
+		},
+		{
+			name:   "too many tags",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip", Tags: make([]string, maxMediaTags+1)},
+			fields: []string{"tags"},
+		},
+		{
+			name:   "empty tag",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip", Tags: []string{"promo", ""}},
+			fields: []string{"tags[1]"},
+		},
+		{
+			name:   "tag too long",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip", Tags: []string{strings.Repeat("t", maxTagLength+1)}},
+			fields: []string{"tags[0]"},
+		},
+		{
+			name:   "too many metadata keys",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip", Metadata: manyKeys(maxMetadataKeys + 1)},
+			fields: []string{"metadata"},
+		},
+		{
+			name:   "empty metadata key",
+			req:    CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip", Metadata: map[string]string{"": "x"}},
+			fields: []string{"metadata"},
+		},
+		{
+			name: "metadata key too long",
+			req: CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip",
+				Metadata: map[string]string{strings.Repeat("k", maxMetadataKey+1): "x"}},
+			fields: []string{"metadata"},
+		},
+		{
+			name: "metadata value too long",
+			req: CreateMediaRequest{Type: models.Video, Source: "s3://b/k", Title: "clip",
+				Metadata: map[string]string{"description": strings.Repeat("v", maxMetadataValue+1)}},
+			fields: []string{"metadata.description"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ElementsMatch(t, tc.fields, fieldsOf(tc.req.Validate()))
+		})
+	}
+}
+
+func manyKeys(n int) map[string]string {
+	out := make(map[string]string, n)
+	for i := range n {
+		out[fmt.Sprintf("k%d", i)] = "v"
+	}
+	return out
+}
+
+func TestCreateMediaBatchItem_Validate(t *testing.T) {
+	// у элемента batch нет title: проверяются только type и source
+	require.Empty(t, CreateMediaBatchItem{Type: models.Video, Source: "s3://b/k"}.Validate())
+	require.ElementsMatch(t, []string{"type", "source"}, fieldsOf(CreateMediaBatchItem{}.Validate()))
+}
+
+func TestChangeStatusRequest_Validate(t *testing.T) {
+	require.Empty(t, ChangeStatusRequest{Status: models.ReadyStatus}.Validate())
+	require.Equal(t, []string{"status"}, fieldsOf(ChangeStatusRequest{}.Validate()))
+	require.Equal(t, []string{"status"}, fieldsOf(ChangeStatusRequest{Status: "archived"}.Validate()))
+	require.Equal(t, []string{"status"}, fieldsOf(ChangeStatusRequest{Status: models.QuarantinedStatus}.Validate()))
+	require.Equal(t, []string{"status"}, fieldsOf(ChangeStatusRequest{Status: models.DeletedStatus}.Validate()))
+
+	// failed без причины не принимаем
+	require.Equal(t, []string{"reason"}, fieldsOf(ChangeStatusRequest{Status: models.FailedStatus}.Validate()))
+	require.Empty(t, ChangeStatusRequest{Status: models.FailedStatus, Reason: "codec not supported"}.Validate())
+	require.Equal(t, []string{"reason"}, fieldsOf(ChangeStatusRequest{
+		Status: models.ReadyStatus,
+		Reason: strings.Repeat("x", maxReasonLength+1),
+	}.Validate()))
+}
+
+func TestRecordContentRequest_Validate(t *testing.T) {
+	sum := strings.Repeat("a1", 32)
+	require.Empty(t, RecordContentRequest{Checksum: sum, Size: 10, ContentType: "video/mp4"}.Validate())
+	require.Empty(t, RecordContentRequest{Checksum: sum, ContentType: "application/octet-stream"}.Validate(), "empty file is valid")
+	require.ElementsMatch(t, []string{"checksum_sha256", "content_type"}, fieldsOf(RecordContentRequest{}.Validate()))
+	require.Equal(t, []string{"checksum_sha256"}, fieldsOf(RecordContentRequest{Checksum: strings.ToUpper(sum), ContentType: "video/mp4"}.Validate()))
+	require.Equal(t, []string{"checksum_sha256"}, fieldsOf(RecordContentRequest{Checksum: "abc", ContentType: "video/mp4"}.Validate()))
+	require.Equal(t, []string{"size_bytes"}, fieldsOf(RecordContentRequest{Checksum: sum, Size: -1, ContentType: "video/mp4"}.Validate()))
+}
+
+func TestQuarantineRequest_Validate(t *testing.T) {
+	require.Empty(t, QuarantineRequest{Threat: "Eicar-Test-Signature", Scanner: "clamav"}.Validate())
+	require.Equal(t, []string{"threat"}, fieldsOf(QuarantineRequest{Scanner: "clamav"}.Validate()))
+	require.Equal(t, []string{"threat"}, fieldsOf(QuarantineRequest{Threat: strings.Repeat("x", maxThreatLength+1)}.Validate()))
+}
+
+func TestVisibilityRequest_Validate(t *testing.T) {
+	require.Empty(t, VisibilityRequest{Visibility: models.UnlistedVisibility}.Validate())
+	require.Equal(t, []string{"visibility"}, fieldsOf(VisibilityRequest{}.Validate()))
+	require.Equal(t, []string{"visibility"}, fieldsOf(VisibilityRequest{Visibility: "hidden"}.Validate()))
+}
+
+func TestScheduleRequest_Validate(t *testing.T) {
+	publishAt := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := publishAt.Add(time.Hour)
+	require.Empty(t, ScheduleRequest{}.Validate())
+	require.Empty(t, ScheduleRequest{PublishAt: &publishAt, ExpiresAt: &expiresAt}.Validate())
+	require.Empty(t, ScheduleRequest{ExpiresAt: &publishAt}.Validate())
+	require.Equal(t, []string{"expires_at"}, fieldsOf(ScheduleRequest{PublishAt: &publishAt, ExpiresAt: &publishAt}.Validate()))
+}
+
+func TestValidation_Returns422WithFieldDetails(t *testing.T) {
+	router := NewRouter(New(nil))
+
+	cases := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/media", `{"type":"image","source":""}`},
+		{http.MethodPatch, "/media/" + uuid.NewString() + "/status", `{"status":"bogus"}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+			require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+			var body struct {
+				Code    string `json:"code"`
+				Details struct {
+					Fields []FieldError `json:"fields"`
+				} `json:"details"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			require.Equal(t, CodeValidationFailed, body.Code)
+			require.NotEmpty(t, body.Details.Fields)
+		})
+	}
+}
+
+func TestCreateMedia_StoresAttrs(t *testing.T) {
+	router := NewRouter(New(service.New(repository.NewMemoryRepository(), nil)))
+	owner := uuid.NewString()
+
+	body := `{"type":"video","source":"s3://b/k","title":"launch","tags":["promo"],"metadata":{"description":"teaser"}}`
+	req := httptest.NewRequest(http.MethodPost, "/media", strings.NewReader(body))
+	req.Header.Set(OwnerHeader, owner)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var created MediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, "launch", created.Title)
+	require.Equal(t, []string{"promo"}, created.Tags)
+	require.Equal(t, map[string]string{"description": "teaser"}, created.Metadata)
+
+	req = httptest.NewRequest(http.MethodGet, "/media/"+created.ID.String(), nil)
+	req.Header.Set(OwnerHeader, owner)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got MediaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "launch", got.Title)
+}
@@ -0,0 +1,30 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Cleanup раз в interval удаляет истёкшие ключи; работает до отмены ctx
+func Cleanup(ctx context.Context, store Store, interval time.Duration, logger zerolog.Logger) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			n, err := store.DeleteExpired(ctx, now)
+			if err != nil && ctx.Err() == nil {
+				logger.Error().Err(err).Msg("idempotency cleanup failed")
+				continue
+			}
+			if n > 0 {
+				logger.Debug().Int64("deleted", n).Msg("expired idempotency keys deleted")
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package idempotency — повтор ответа на запрос с тем же Idempotency-Key. Первый запрос
+// занимает ключ (Claim), выполняется и сохраняет ответ (Complete); повтор с тем же ключом
+// получает сохранённый ответ, не выполняясь. Ключ привязан к запросу: тот же ключ с другим
+// методом, путём или телом — ошибка клиента, а не повтор. Ключи живут TTL, ключи владельца
+// не пересекаются с ключами других владельцев (Scope).
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTTL — сколько хранится ответ
+	DefaultTTL = 24 * time.Hour
+	// DefaultLockTimeout — через сколько ключ запроса без ответа (упал инстанс) можно занять снова
+	DefaultLockTimeout = 5 * time.Minute
+)
+
+// Response — сохранённый ответ
+type Response struct {
+	Status int
+	Header http.Header // только заголовки из ReplayedHeaders
+	Body   []byte
+}
+
+// ReplayedHeaders — заголовки ответа, которые сохраняются и отдаются при повторе
+var ReplayedHeaders = []string{"Content-Type", "ETag", "Location", "Cache-Control", "X-Next-Cursor"}
+
+// Record — ключ и запрос, под который он занят
+type Record struct {
+	Scope       string // владелец вызывающего; пустой — внутренние вызовы
+	Key         string
+	Route       string // метод и путь: PATCH /media/{uuid}/status
+	Fingerprint string // см. Fingerprint
+	Token       uuid.UUID
+	Response    *Response // nil — запрос ещё выполняется
+	CreatedAt   time.Time
+	LockedUntil time.Time // до этого времени ключ без ответа занят
+	ExpiresAt   time.Time
+}
+
+// Fingerprint — хэш метода, пути с query и тела: совпадает только у повтора того же запроса
+func Fingerprint(method, requestURI string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + requestURI + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Store хранит ключи
+type Store interface {
+	// Claim занимает ключ под rec (claimed=true), если ключа нет, он истёк (ExpiresAt) или
+	// брошен без ответа (LockedUntil); время — rec.CreatedAt. Иначе возвращает запись ключа.
+	Claim(ctx context.Context, rec Record) (existing Record, claimed bool, err error)
+	// Complete сохраняет ответ; ключ, занятый уже другим запросом (другой Token), не меняется
+	Complete(ctx context.Context, scope, key string, token uuid.UUID, resp Response) error
+	// Release освобождает ключ без ответа: повтор выполнится заново
+	Release(ctx context.Context, scope, key string, token uuid.UUID) error
+	// DeleteExpired удаляет ключи, истёкшие к now
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type memoryKey struct{ scope, key string }
+
+// MemoryStore — Store в памяти для in-memory режима и тестов
+type MemoryStore struct {
+	mu   sync.Mutex
+	data map[memoryKey]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[memoryKey]Record)}
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, rec Record) (Record, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Record{}, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := memoryKey{rec.Scope, rec.Key}
+	if cur, ok := s.data[k]; ok && !claimable(cur, rec.CreatedAt) {
+		return cur, false, nil
+	}
+	rec.Response = nil
+	s.data[k] = rec
+	return rec, true, nil
+}
+
+// claimable — ключ cur можно занять в момент now
+func claimable(cur Record, now time.Time) bool {
+	return !cur.ExpiresAt.After(now) || (cur.Response == nil && !cur.LockedUntil.After(now))
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, scope, key string, token uuid.UUID, resp Response) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := memoryKey{scope, key}
+	if cur, ok := s.data[k]; ok && cur.Token == token {
+		cur.Response = &resp
+		s.data[k] = cur
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, scope, key string, token uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := memoryKey{scope, key}
+	if cur, ok := s.data[k]; ok && cur.Token == token && cur.Response == nil {
+		delete(s.data, k)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for k, rec := range s.data {
+		if !rec.ExpiresAt.After(now) {
+			delete(s.data, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("POST", "/media", []byte(`{"type":"video"}`))
+	require.Equal(t, base, Fingerprint("POST", "/media", []byte(`{"type":"video"}`)))
+	require.NotEqual(t, base, Fingerprint("PATCH", "/media", []byte(`{"type":"video"}`)))
+	require.NotEqual(t, base, Fingerprint("POST", "/media?x=1", []byte(`{"type":"video"}`)))
+	require.NotEqual(t, base, Fingerprint("POST", "/media", []byte(`{"type":"image"}`)))
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemoryStore()
+	now := time.Now()
+	newRecord := func(scope string, at time.Time) Record {
+		return Record{
+			Scope: scope, Key: "k", Fingerprint: "f", Token: uuid.New(),
+			CreatedAt: at, LockedUntil: at.Add(time.Minute), ExpiresAt: at.Add(time.Hour),
+		}
+	}
+
+	first := newRecord("a", now)
+	_, claimed, err := store.Claim(ctx, first)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	cur, claimed, err := store.Claim(ctx, newRecord("a", now))
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.Nil(t, cur.Response)
+
+	// Ключи разных scope не пересекаются
+	_, claimed, err = store.Claim(ctx, newRecord("b", now))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// Release чужим токеном ключ не освобождает, своим — освобождает
+	require.NoError(t, store.Release(ctx, "a", "k", uuid.New()))
+	_, claimed, _ = store.Claim(ctx, newRecord("a", now))
+	require.False(t, claimed)
+	require.NoError(t, store.Release(ctx, "a", "k", first.Token))
+	second := newRecord("a", now)
+	_, claimed, _ = store.Claim(ctx, second)
+	require.True(t, claimed)
+
+	require.NoError(t, store.Complete(ctx, "a", "k", second.Token, Response{Status: 201, Body: []byte("ok")}))
+	// Ответ хранится до ExpiresAt, даже когда LockedUntil прошёл
+	cur, claimed, _ = store.Claim(ctx, newRecord("a", now.Add(30*time.Minute)))
+	require.False(t, claimed)
+	require.Equal(t, 201, cur.Response.Status)
+	_, claimed, _ = store.Claim(ctx, newRecord("a", now.Add(2*time.Hour)))
+	require.True(t, claimed)
+
+	// Ключ b брошен без ответа: занимается снова после LockedUntil
+	_, claimed, _ = store.Claim(ctx, newRecord("b", now.Add(2*time.Minute)))
+	require.True(t, claimed)
+
+	n, err := store.DeleteExpired(ctx, now.Add(90*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
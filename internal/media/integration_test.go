@@ -0,0 +1,129 @@
+//go:build integration
+
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/replay"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func doJSON(t *testing.T, method, url string, body any, out any) int {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Scopes", "internal")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+// ChangeStatus → outbox → Kafka: событие смены статуса доходит до топика, outbox пустеет
+func TestChangeStatusPublishesEvent(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	broker := testutil.StartKafka(t, testutil.MediaTopic)
+	media := testutil.StartMedia(t, testutil.MediaConfig{Postgres: db, Kafka: broker})
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	status := doJSON(t, http.MethodPost, media.URL+"/media", map[string]string{
+		"type":   string(models.Video),
+		"source": "s3://bucket/a.mp4",
+	}, &created)
+	require.Equal(t, http.StatusCreated, status)
+
+	status = doJSON(t, http.MethodPatch, media.URL+"/media/"+created.ID+"/status", map[string]string{
+		"status": string(models.ProcessingStatus),
+		"reason": "picked up",
+	}, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	// Первое событие агрегата — MediaCreated, за ним смена статуса
+	msgs := broker.ReadMessages(t, testutil.MediaTopic, 2, 30*time.Second)
+	msg := msgs[1]
+
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	require.Equal(t, "MediaStatusChanged", headers[kafka.HeaderEventType])
+	require.Equal(t, created.ID, headers[kafka.HeaderAggregateID])
+
+	env, err := events.UnmarshalEnvelope(msg.Value)
+	require.NoError(t, err)
+	require.Equal(t, env.EventID, string(msg.Key))
+	require.Equal(t, created.ID, env.AggregateID)
+	payload, err := events.Default.Decode(env)
+	require.NoError(t, err)
+	changed := payload.(*events.MediaStatusChangedV1)
+	require.Equal(t, models.UploadedStatus, changed.From)
+	require.Equal(t, models.ProcessingStatus, changed.To)
+	require.Equal(t, "picked up", changed.Reason)
+
+	// Событие помечается processed после публикации
+	require.Eventually(t, func() bool {
+		pending, err := media.Outbox.ListOutbox(context.Background(), postgres.OutboxFilter{State: postgres.OutboxStatePending})
+		return err == nil && len(pending) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+// Опубликованные события агрегата переигрываются в топик replay с прежними event_id,
+// а в режиме outbox снова встают в очередь publisher'а
+func TestReplayEvents(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	broker := testutil.StartKafka(t, testutil.MediaTopic, replay.DefaultTopic)
+	media := testutil.StartMedia(t, testutil.MediaConfig{Postgres: db, Kafka: broker})
+	ctx := context.Background()
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	status := doJSON(t, http.MethodPost, media.URL+"/media", map[string]string{
+		"type":   string(models.Video),
+		"source": "s3://bucket/a.mp4",
+	}, &created)
+	require.Equal(t, http.StatusCreated, status)
+	original := broker.ReadMessages(t, testutil.MediaTopic, 1, 30*time.Second)
+	require.Eventually(t, func() bool {
+		pending, err := media.Outbox.ListOutbox(ctx, postgres.OutboxFilter{State: postgres.OutboxStatePending})
+		return err == nil && len(pending) == 0
+	}, 10*time.Second, 100*time.Millisecond)
+
+	replayer, err := replay.New(replay.Config{Store: media.Outbox, Producer: media.Producer})
+	require.NoError(t, err)
+	filter := postgres.OutboxReplayFilter{AggregateID: created.ID}
+	res, err := replayer.Replay(ctx, replay.Request{Filter: filter, Mode: replay.ModeTopic})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.Replayed)
+	replayed := broker.ReadMessages(t, replay.DefaultTopic, 1, 30*time.Second)
+	require.Equal(t, original[0].Key, replayed[0].Key)
+
+	res, err = replayer.Replay(ctx, replay.Request{Filter: filter, Mode: replay.ModeOutbox})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.Replayed)
+	again := broker.ReadMessages(t, testutil.MediaTopic, 2, 30*time.Second)
+	require.Equal(t, original[0].Key, again[1].Key)
+}
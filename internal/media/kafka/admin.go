@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// TopicSpec описывает топик, который нужен сервису
+type TopicSpec struct {
+	Name              string
+	Partitions        int               // default: 1
+	ReplicationFactor int               // default: 1
+	Retention         time.Duration     // retention.ms; 0 — настройка брокера, -1 — хранить бессрочно
+	Configs           map[string]string // Прочие настройки топика (cleanup.policy и т.п.)
+}
+
+// TopicAdminConfig содержит конфигурацию TopicAdmin
+type TopicAdminConfig struct {
+	Brokers []string
+	Timeout time.Duration // Timeout запроса к брокеру (default: 10s)
+	Logger  zerolog.Logger
+}
+
+// topicCreator — запрос создания топиков; реализуется *kafkago.Client
+type topicCreator interface {
+	CreateTopics(ctx context.Context, req *kafkago.CreateTopicsRequest) (*kafkago.CreateTopicsResponse, error)
+}
+
+// TopicAdmin создаёт топики сервиса при старте — для dev/staging, где их некому завести руками.
+// В production топики заводятся отдельно: TopicAdmin не меняет настройки существующих топиков.
+type TopicAdmin struct {
+	client topicCreator
+	logger zerolog.Logger
+}
+
+func NewTopicAdmin(cfg TopicAdminConfig) (*TopicAdmin, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("brokers list is empty")
+	}
+	if cfg.Timeout < 0 {
+		return nil, errors.New("timeout cannot be negative")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &TopicAdmin{
+		client: &kafkago.Client{
+			Addr:    kafkago.TCP(cfg.Brokers...),
+			Timeout: cfg.Timeout,
+		},
+		logger: cfg.Logger.With().Str("component", "kafka_topic_admin").Logger(),
+	}, nil
+}
+
+// EnsureTopics создаёт недостающие топики. Уже существующие не трогает,
+// даже если их партиции или настройки отличаются от specs.
+func (a *TopicAdmin) EnsureTopics(ctx context.Context, specs ...TopicSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	topics := make([]kafkago.TopicConfig, 0, len(specs))
+	for _, spec := range specs {
+		topic, err := spec.topicConfig()
+		if err != nil {
+			return err
+		}
+		topics = append(topics, topic)
+	}
+
+	res, err := a.client.CreateTopics(ctx, &kafkago.CreateTopicsRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("create topics: %w", err)
+	}
+
+	var errs []error
+	for _, topic := range topics {
+		err := res.Errors[topic.Topic]
+		switch {
+		case err == nil:
+			a.logger.Info().
+				Str("topic", topic.Topic).
+				Int("partitions", topic.NumPartitions).
+				Int("replication_factor", topic.ReplicationFactor).
+				Msg("kafka topic created")
+		case errors.Is(err, kafkago.TopicAlreadyExists):
+			a.logger.Debug().Str("topic", topic.Topic).Msg("kafka topic already exists")
+		default:
+			errs = append(errs, fmt.Errorf("create topic %s: %w", topic.Topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s TopicSpec) topicConfig() (kafkago.TopicConfig, error) {
+	if s.Name == "" {
+		return kafkago.TopicConfig{}, errors.New("topic name is empty")
+	}
+	if s.Partitions < 0 {
+		return kafkago.TopicConfig{}, fmt.Errorf("topic %s: partitions cannot be negative", s.Name)
+	}
+	if s.ReplicationFactor < 0 {
+		return kafkago.TopicConfig{}, fmt.Errorf("topic %s: replication_factor cannot be negative", s.Name)
+	}
+	if s.Retention < -1 {
+		return kafkago.TopicConfig{}, fmt.Errorf("topic %s: retention must be positive or -1 for unlimited", s.Name)
+	}
+
+	topic := kafkago.TopicConfig{
+		Topic:             s.Name,
+		NumPartitions:     max(s.Partitions, 1),
+		ReplicationFactor: max(s.ReplicationFactor, 1),
+	}
+	for name, value := range s.Configs {
+		topic.ConfigEntries = append(topic.ConfigEntries, kafkago.ConfigEntry{ConfigName: name, ConfigValue: value})
+	}
+	switch {
+	case s.Retention == -1:
+		topic.ConfigEntries = append(topic.ConfigEntries, kafkago.ConfigEntry{ConfigName: "retention.ms", ConfigValue: "-1"})
+	case s.Retention > 0:
+		topic.ConfigEntries = append(topic.ConfigEntries, kafkago.ConfigEntry{
+			ConfigName:  "retention.ms",
+			ConfigValue: strconv.FormatInt(s.Retention.Milliseconds(), 10),
+		})
+	}
+	return topic, nil
+}
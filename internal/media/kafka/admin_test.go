@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTopicCreator struct {
+	req    *kafkago.CreateTopicsRequest
+	errors map[string]error
+}
+
+func (f *fakeTopicCreator) CreateTopics(_ context.Context, req *kafkago.CreateTopicsRequest) (*kafkago.CreateTopicsResponse, error) {
+	f.req = req
+	return &kafkago.CreateTopicsResponse{Errors: f.errors}, nil
+}
+
+func TestTopicAdmin_EnsureTopics(t *testing.T) {
+	client := &fakeTopicCreator{errors: map[string]error{"events.media": kafkago.TopicAlreadyExists}}
+	admin := &TopicAdmin{client: client, logger: zerolog.Nop()}
+
+	err := admin.EnsureTopics(context.Background(),
+		TopicSpec{Name: "events.media"},
+		TopicSpec{
+			Name:              "events.quota",
+			Partitions:        6,
+			ReplicationFactor: 3,
+			Retention:         7 * 24 * time.Hour,
+			Configs:           map[string]string{"cleanup.policy": "delete"},
+		},
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, []kafkago.TopicConfig{
+		{Topic: "events.media", NumPartitions: 1, ReplicationFactor: 1},
+		{
+			Topic:             "events.quota",
+			NumPartitions:     6,
+			ReplicationFactor: 3,
+			ConfigEntries: []kafkago.ConfigEntry{
+				{ConfigName: "cleanup.policy", ConfigValue: "delete"},
+				{ConfigName: "retention.ms", ConfigValue: "604800000"},
+			},
+		},
+	}, client.req.Topics)
+}
+
+func TestTopicAdmin_EnsureTopicsErrors(t *testing.T) {
+	client := &fakeTopicCreator{errors: map[string]error{"events.quota": kafkago.InvalidReplicationFactor}}
+	admin := &TopicAdmin{client: client, logger: zerolog.Nop()}
+
+	err := admin.EnsureTopics(context.Background(), TopicSpec{Name: "events.media"}, TopicSpec{Name: "events.quota"})
+	require.ErrorIs(t, err, kafkago.InvalidReplicationFactor)
+	require.ErrorContains(t, err, "events.quota")
+
+	// Некорректный spec отклоняется до запроса
+	client.req = nil
+	require.Error(t, admin.EnsureTopics(context.Background(), TopicSpec{Name: "x", Retention: -2}))
+	require.Nil(t, client.req)
+}
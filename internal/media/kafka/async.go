@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// DeliveryError — сообщение, которое не удалось доставить в async режиме
+type DeliveryError struct {
+	Message Message
+	Err     error
+}
+
+func (e DeliveryError) Error() string {
+	return fmt.Sprintf("deliver message %q: %v", e.Message.Key, e.Err)
+}
+
+func (e DeliveryError) Unwrap() error { return e.Err }
+
+// Errors возвращает канал ошибок доставки async режима. Если читатель не успевает
+// и буфер (ErrorBuffer) полон, ошибка отбрасывается и учитывается в ErrorsDropped.
+// Канал закрывается после Shutdown. В sync режиме — nil: ошибки возвращает Publish.
+func (p *Producer) Errors() <-chan DeliveryError { return p.errors }
+
+// PublishMessageAsync ставит сообщение в очередь и сообщает результат доставки в done.
+// Если сообщение не принято (producer закрыт, breaker открыт), возвращается ошибка
+// и done не вызывается. С KeyBatch сообщение ждёт в буфере своего ключа (см. Flush),
+// иначе в sync режиме публикуется сразу и done вызывается до возврата.
+func (p *Producer) PublishMessageAsync(ctx context.Context, msg Message, done func(err error)) error {
+	if p.batcher != nil {
+		if p.closed.Load() {
+			return ErrProducerClosed
+		}
+		msg, err := p.prepare(ctx, msg)
+		if err != nil {
+			return err
+		}
+		if err := p.checkSize(msg); err != nil {
+			return err
+		}
+		p.batcher.add(ctx, msg, done)
+		return nil
+	}
+	if !p.config.Async {
+		if err := p.PublishMessage(ctx, msg); err != nil {
+			return err
+		}
+		done(nil)
+		return nil
+	}
+
+	msg, err := p.prepare(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if err := p.checkSize(msg); err != nil {
+		return err
+	}
+	km := msg.toKafka()
+	km.WriterData = done
+	return p.enqueue(ctx, km)
+}
+
+// PublishEnvelopeAsync — PublishEnvelope с результатом доставки в done (см. PublishMessageAsync)
+func (p *Producer) PublishEnvelopeAsync(ctx context.Context, env events.Envelope, ts time.Time, done func(err error)) error {
+	msg, err := p.envelopeMessage(ctx, env, ts)
+	if err != nil {
+		return err
+	}
+	return p.PublishMessageAsync(ctx, msg, done)
+}
+
+// enqueue передаёт сообщения writer'у в async режиме. Retry делает сам writer,
+// результат приходит в onCompletion.
+func (p *Producer) enqueue(ctx context.Context, msgs ...kafkago.Message) error {
+	if p.closed.Load() {
+		return errors.New("producer is closed")
+	}
+	if err := p.breaker.allow(); err != nil {
+		return p.rejected(len(msgs), nil)
+	}
+	// Результат записи придёт позже: слот пробы не держим, исход учтёт onCompletion
+	p.breaker.cancel()
+
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		p.metrics.MessagesFailed.Add(int64(len(msgs)))
+		return fmt.Errorf("kafka enqueue: %w", err)
+	}
+	return nil
+}
+
+// onCompletion — Completion writer'а в async режиме: метрики, breaker и уведомления по каждому сообщению
+func (p *Producer) onCompletion(msgs []kafkago.Message, err error) {
+	p.recordBrokerResult(err)
+
+	if err != nil {
+		p.metrics.MessagesFailed.Add(int64(len(msgs)))
+		p.logger.Error().Err(err).Int("count", len(msgs)).Msg("async delivery failed")
+	} else {
+		p.metrics.MessagesPublished.Add(int64(len(msgs)))
+	}
+
+	for _, km := range msgs {
+		if done, ok := km.WriterData.(func(error)); ok {
+			done(err)
+		}
+		if p.config.Completion == nil && err == nil {
+			continue
+		}
+
+		msg := fromKafka(km)
+		if p.config.Completion != nil {
+			p.config.Completion(msg, err)
+		}
+		if err != nil {
+			select {
+			case p.errors <- DeliveryError{Message: msg, Err: err}:
+			default:
+				p.metrics.ErrorsDropped.Add(1)
+			}
+		}
+	}
+}
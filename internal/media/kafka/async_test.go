@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func newAsyncProducer(t *testing.T, completion func(Message, error)) *Producer {
+	t.Helper()
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:     []string{"localhost:9092"},
+		Topic:       "test",
+		Async:       true,
+		Completion:  completion,
+		ErrorBuffer: 1,
+		Logger:      zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	return producer
+}
+
+func TestProducer_AsyncCompletion(t *testing.T) {
+	var completed []string
+	producer := newAsyncProducer(t, func(msg Message, err error) {
+		completed = append(completed, msg.Key)
+	})
+	defer producer.Close()
+
+	var delivered []error
+	done := func(err error) { delivered = append(delivered, err) }
+
+	producer.onCompletion([]kafkago.Message{
+		{Key: []byte("a"), WriterData: done},
+		{Key: []byte("b")},
+	}, nil)
+
+	boom := kafkago.NotEnoughReplicas
+	producer.onCompletion([]kafkago.Message{
+		{Key: []byte("c"), WriterData: done},
+		{Key: []byte("d")},
+	}, boom)
+
+	require.Equal(t, []error{nil, boom}, delivered)
+	require.Equal(t, []string{"a", "b", "c", "d"}, completed)
+	require.Equal(t, int64(2), producer.metrics.MessagesPublished.Load())
+	require.Equal(t, int64(2), producer.metrics.MessagesFailed.Load())
+
+	// Буфер на одну ошибку: вторая отброшена, но учтена
+	derr := <-producer.Errors()
+	require.Equal(t, "c", derr.Message.Key)
+	require.ErrorIs(t, derr, boom)
+	require.Equal(t, int64(1), producer.metrics.ErrorsDropped.Load())
+}
+
+func TestProducer_AsyncFailuresOpenCircuit(t *testing.T) {
+	producer := newAsyncProducer(t, nil)
+	defer producer.Close()
+
+	for range 5 {
+		producer.onCompletion([]kafkago.Message{{Key: []byte("k")}}, kafkago.LeaderNotAvailable)
+	}
+	require.Equal(t, BreakerOpen, producer.BreakerState())
+
+	// Открытый breaker отклоняет сообщение до постановки в очередь, done не вызывается
+	err := producer.PublishMessageAsync(context.Background(), Message{Key: "k"}, func(error) {
+		t.Fatal("done must not be called for rejected message")
+	})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestProducer_SyncPublishAsyncRejects(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{Brokers: []string{"localhost:9092"}, Topic: "test", Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	require.Nil(t, producer.Errors())
+	require.NoError(t, producer.Close())
+
+	err = producer.PublishMessageAsync(context.Background(), Message{Key: "k"}, func(error) {
+		t.Fatal("done must not be called for rejected message")
+	})
+	require.Error(t, err)
+}
+
+func TestProducer_ShutdownClosesErrors(t *testing.T) {
+	producer := newAsyncProducer(t, nil)
+	require.NoError(t, producer.Shutdown(context.Background()))
+
+	_, ok := <-producer.Errors()
+	require.False(t, ok)
+	require.Error(t, producer.PublishMessage(context.Background(), Message{}))
+}
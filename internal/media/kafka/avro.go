@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// envelopeRecordName — полное имя записи/сообщения конверта (для RecordName стратегий)
+const envelopeRecordName = "media.platform.events.Envelope"
+
+// envelopeAvroSchema — Avro схема конверта. Payload остаётся JSON строкой:
+// его схема версионируется отдельно через event_type + schema_version (см. events.Registry).
+// Новые поля добавляются в конец с default — так схема остаётся обратно совместимой.
+const envelopeAvroSchema = `{
+  "type": "record",
+  "name": "Envelope",
+  "namespace": "media.platform.events",
+  "fields": [
+    {"name": "event_id", "type": "string"},
+    {"name": "event_type", "type": "string"},
+    {"name": "schema_version", "type": "int"},
+    {"name": "aggregate_id", "type": "string"},
+    {"name": "occurred_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "published_at", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}], "default": null},
+    {"name": "payload", "type": "string"},
+    {"name": "sequence", "type": "long", "default": 0}
+  ]
+}`
+
+// AvroSerializer кодирует конверт в Avro binary и обрамляет его в wire format Schema Registry
+type AvroSerializer struct {
+	registry *SchemaRegistryClient
+	strategy SubjectStrategy
+}
+
+func NewAvroSerializer(registry *SchemaRegistryClient, strategy SubjectStrategy) *AvroSerializer {
+	return &AvroSerializer{registry: registry, strategy: strategy}
+}
+
+func (s *AvroSerializer) Format() Format { return FormatAvro }
+
+func (s *AvroSerializer) Serialize(ctx context.Context, topic string, env events.Envelope) ([]byte, error) {
+	id, err := s.registry.Register(ctx, s.strategy.Subject(topic, envelopeRecordName), "AVRO", envelopeAvroSchema)
+	if err != nil {
+		return nil, err
+	}
+	return frame(id, encodeEnvelopeAvro(env)), nil
+}
+
+// encodeEnvelopeAvro пишет поля в порядке схемы (Avro binary encoding)
+func encodeEnvelopeAvro(env events.Envelope) []byte {
+	var buf []byte
+	buf = avroString(buf, env.EventID)
+	buf = avroString(buf, env.EventType)
+	buf = avroLong(buf, int64(env.SchemaVersion))
+	buf = avroString(buf, env.AggregateID)
+	buf = avroLong(buf, env.OccurredAt.UnixMicro())
+	if env.PublishedAt.IsZero() {
+		buf = avroLong(buf, 0) // union branch "null"
+	} else {
+		buf = avroLong(buf, 1)
+		buf = avroLong(buf, env.PublishedAt.UnixMicro())
+	}
+	buf = avroString(buf, string(env.Payload))
+	buf = avroLong(buf, env.Sequence)
+	return buf
+}
+
+// avroLong кодирует int/long как zigzag varint
+func avroLong(buf []byte, v int64) []byte {
+	return binary.AppendVarint(buf, v)
+}
+
+func avroString(buf []byte, s string) []byte {
+	buf = avroLong(buf, int64(len(s)))
+	return append(buf, s...)
+}
@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen — брокер признан недоступным, запись отклонена без попытки
+var ErrCircuitOpen = errors.New("kafka circuit breaker is open")
+
+// BreakerConfig содержит настройки circuit breaker вокруг записи в Kafka
+type BreakerConfig struct {
+	// FailureThreshold — число подряд неудачных записей, после которого breaker открывается
+	// (default: 5, -1 — breaker выключен)
+	FailureThreshold int
+	CoolDown         time.Duration // Сколько breaker открыт до пробной записи (default: 30s)
+	HalfOpenProbes   int           // Одновременных пробных записей в half-open (default: 1)
+}
+
+// BreakerState — состояние circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // записи идут в брокер
+	BreakerOpen                         // записи отклоняются до истечения CoolDown
+	BreakerHalfOpen                     // пропускаются только пробные записи
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker — circuit breaker: открывается после FailureThreshold неудач подряд,
+// через CoolDown пропускает пробные записи; успешная проба закрывает его, неудачная — открывает снова.
+type breaker struct {
+	threshold int
+	coolDown  time.Duration
+	probes    int
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // неудач подряд в closed
+	openedAt time.Time // когда breaker открылся
+	inFlight int       // пробных записей в half-open
+}
+
+func newBreaker(cfg BreakerConfig) *breaker {
+	return &breaker{
+		threshold: cfg.FailureThreshold,
+		coolDown:  cfg.CoolDown,
+		probes:    cfg.HalfOpenProbes,
+		now:       time.Now,
+	}
+}
+
+// allow решает, можно ли писать в брокер. Разрешённая запись обязана закончиться вызовом done.
+func (b *breaker) allow() error {
+	if b.threshold < 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.coolDown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.inFlight = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.inFlight >= b.probes {
+			return ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+	return nil
+}
+
+// done учитывает результат записи, разрешённой allow.
+// brokerFailed — брокер недоступен; ошибки самого сообщения здоровью брокера не вредят.
+// Возвращает true, если breaker только что открылся.
+func (b *breaker) done(brokerFailed bool) bool {
+	if b.threshold < 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.release()
+		if brokerFailed {
+			b.open()
+			return true
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+	case BreakerClosed:
+		if !brokerFailed {
+			b.failures = 0
+			return false
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+			return true
+		}
+	}
+	return false
+}
+
+// cancel освобождает разрешение allow без учёта результата: запись отменил вызывающий,
+// о брокере она ничего не сказала
+func (b *breaker) cancel() {
+	if b.threshold < 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.release()
+	}
+}
+
+// release освобождает слот пробной записи
+func (b *breaker) release() {
+	if b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+func (b *breaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.inFlight = 0
+}
+
+// State возвращает текущее состояние; открытый breaker с истёкшим CoolDown считается half-open
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.coolDown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
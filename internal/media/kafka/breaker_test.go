@@ -0,0 +1,127 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBreaker(now *time.Time) *breaker {
+	b := newBreaker(BreakerConfig{FailureThreshold: 3, CoolDown: 10 * time.Second, HalfOpenProbes: 1})
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker(&now)
+
+	// Успех сбрасывает счётчик
+	for _, failed := range []bool{true, true, false, true, true} {
+		require.NoError(t, b.allow())
+		require.False(t, b.done(failed))
+	}
+	require.Equal(t, BreakerClosed, b.State())
+
+	require.NoError(t, b.allow())
+	require.True(t, b.done(true))
+	require.Equal(t, BreakerOpen, b.State())
+	require.ErrorIs(t, b.allow(), ErrCircuitOpen)
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker(&now)
+	for range 3 {
+		require.NoError(t, b.allow())
+		b.done(true)
+	}
+
+	now = now.Add(10 * time.Second)
+	require.Equal(t, BreakerHalfOpen, b.State())
+
+	// Одна проба за раз
+	require.NoError(t, b.allow())
+	require.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	// Неудачная проба снова открывает breaker на CoolDown
+	require.True(t, b.done(true))
+	require.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	now = now.Add(10 * time.Second)
+	require.NoError(t, b.allow())
+	require.False(t, b.done(false))
+	require.Equal(t, BreakerClosed, b.State())
+	require.NoError(t, b.allow())
+}
+
+func TestBreaker_CancelledProbeFreesSlot(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTestBreaker(&now)
+	for range 3 {
+		require.NoError(t, b.allow())
+		b.done(true)
+	}
+	now = now.Add(10 * time.Second)
+
+	require.NoError(t, b.allow())
+	b.cancel()
+	require.Equal(t, BreakerHalfOpen, b.State())
+	require.NoError(t, b.allow())
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := newBreaker(BreakerConfig{FailureThreshold: -1})
+	for range 100 {
+		require.NoError(t, b.allow())
+		require.False(t, b.done(true))
+	}
+}
+
+func TestProducer_CircuitOpenFailsFast(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "test",
+		Breaker: BreakerConfig{FailureThreshold: 1, CoolDown: time.Minute},
+		Logger:  zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	// Открываем breaker так, как это сделала бы неудачная запись
+	require.NoError(t, producer.breaker.allow())
+	require.True(t, producer.recordBrokerResult(fmt.Errorf("kafka write: %w", syscall.ECONNREFUSED)))
+
+	start := time.Now()
+	err = producer.Publish(context.Background(), "k", []byte("v"))
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	require.ErrorIs(t, producer.PublishBatch(context.Background(), []Message{{Key: "a"}, {Key: "b"}}), ErrCircuitOpen)
+	require.Equal(t, int64(3), producer.metrics.CircuitRejected.Load())
+	require.Equal(t, int64(1), producer.metrics.CircuitOpens.Load())
+	require.ErrorIs(t, producer.HealthCheck(context.Background()), ErrCircuitOpen)
+}
+
+func TestProducer_MessageErrorsDoNotOpenCircuit(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "test",
+		Breaker: BreakerConfig{FailureThreshold: 1},
+		Logger:  zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	require.NoError(t, producer.breaker.allow())
+	require.False(t, producer.recordBrokerResult(kafkago.MessageSizeTooLarge))
+	require.NoError(t, producer.breaker.allow())
+	require.False(t, producer.recordBrokerResult(context.Canceled))
+	require.Equal(t, BreakerClosed, producer.BreakerState())
+}
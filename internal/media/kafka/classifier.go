@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ErrorClassifier решает, имеет ли смысл повторять запись после ошибки.
+// Тот же ответ определяет, считается ли ошибка отказом брокера для circuit breaker.
+type ErrorClassifier interface {
+	Retriable(err error) bool
+}
+
+// ErrorClassifierFunc — функция как ErrorClassifier
+type ErrorClassifierFunc func(err error) bool
+
+func (f ErrorClassifierFunc) Retriable(err error) bool { return f(err) }
+
+// DefaultErrorClassifier — классификация по типам ошибок kafka-go, net и syscall
+var DefaultErrorClassifier ErrorClassifier = ErrorClassifierFunc(isRetriableError)
+
+// isRetriableError определяет, можно ли retry эту ошибку:
+//   - отмена и дедлайн вызывающего — нет, решение за ним
+//   - коды протокола Kafka — по списку retriable кодов из документации (kafkago.Error.Temporary)
+//   - WriteErrors — только если все ошибки по сообщениям retriable, иначе повтор упадёт снова
+//   - сетевые ошибки, разорванные и отклонённые соединения — да
+//   - остальное (конфигурация writer'а, закрытый producer) — нет; событие повторит outbox
+func isRetriableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var writeErrs kafkago.WriteErrors
+	if errors.As(err, &writeErrs) {
+		for _, e := range writeErrs {
+			if e != nil && !isRetriableError(e) {
+				return false
+			}
+		}
+		return writeErrs.Count() > 0
+	}
+
+	var kerr kafkago.Error
+	if errors.As(err, &kerr) {
+		return kerr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return true
+	}
+
+	for _, target := range []error{
+		syscall.ECONNREFUSED,
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.EPIPE,
+		syscall.ETIMEDOUT,
+		syscall.EHOSTUNREACH,
+		syscall.ENETUNREACH,
+		io.EOF,
+		io.ErrUnexpectedEOF,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetriableError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	tests := []struct {
+		name      string
+		err       error
+		retriable bool
+	}{
+		{name: "nil error", err: nil, retriable: false},
+		{name: "context canceled", err: context.Canceled, retriable: false},
+		{name: "context deadline exceeded", err: fmt.Errorf("kafka write: %w", context.DeadlineExceeded), retriable: false},
+
+		// Коды протокола Kafka
+		{name: "leader not available", err: kafkago.LeaderNotAvailable, retriable: true},
+		{name: "not leader for partition", err: fmt.Errorf("kafka write: %w", kafkago.NotLeaderForPartition), retriable: true},
+		{name: "request timed out", err: kafkago.RequestTimedOut, retriable: true},
+		{name: "not enough replicas", err: kafkago.NotEnoughReplicas, retriable: true},
+		{name: "message size too large", err: kafkago.MessageSizeTooLarge, retriable: false},
+		{name: "message too large (writer)", err: kafkago.MessageTooLargeError{}, retriable: false},
+		{name: "topic authorization failed", err: kafkago.TopicAuthorizationFailed, retriable: false},
+		{name: "sasl authentication failed", err: kafkago.SASLAuthenticationFailed, retriable: false},
+
+		// Ошибки по сообщениям из синхронного WriteMessages
+		{name: "write errors all retriable", err: kafkago.WriteErrors{nil, kafkago.LeaderNotAvailable}, retriable: true},
+		{name: "write errors with poison message", err: kafkago.WriteErrors{kafkago.LeaderNotAvailable, kafkago.MessageSizeTooLarge}, retriable: false},
+		{name: "write errors empty", err: kafkago.WriteErrors{nil, nil}, retriable: false},
+
+		// Сеть
+		{name: "dial refused", err: fmt.Errorf("kafka write: %w", dialErr), retriable: true},
+		{name: "connection reset", err: os.NewSyscallError("read", syscall.ECONNRESET), retriable: true},
+		{name: "broken pipe", err: syscall.EPIPE, retriable: true},
+		{name: "dns", err: &net.DNSError{Err: "no such host", Name: "kafka"}, retriable: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, retriable: true},
+
+		{name: "writer closed", err: io.ErrClosedPipe, retriable: false},
+		// Строки больше не влияют на решение
+		{name: "text mentioning timeout", err: errors.New("config: timeout must be positive"), retriable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.retriable, isRetriableError(tt.err))
+		})
+	}
+}
+
+func TestProducer_CustomClassifier(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "test",
+		Breaker: BreakerConfig{FailureThreshold: 1},
+		// Политика сервиса: ни одна ошибка не повторяется и не открывает breaker
+		Classifier: ErrorClassifierFunc(func(err error) bool { return false }),
+		Logger:     zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	require.NoError(t, producer.breaker.allow())
+	require.False(t, producer.recordBrokerResult(kafkago.LeaderNotAvailable))
+	require.Equal(t, BreakerClosed, producer.BreakerState())
+}
+
+func TestSetDefaults_Classifier(t *testing.T) {
+	cfg := ProducerConfig{}
+	setDefaults(&cfg)
+	require.NotNil(t, cfg.Classifier)
+	require.True(t, cfg.Classifier.Retriable(kafkago.LeaderNotAvailable))
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Compression — кодек сжатия batch'ей writer'а
+type Compression string
+
+const (
+	CompressionSnappy Compression = "snappy" // default
+	CompressionZstd   Compression = "zstd"
+	CompressionLZ4    Compression = "lz4"
+	CompressionNone   Compression = "none"
+)
+
+// codec возвращает кодек kafka-go; 0 — без сжатия
+func (c Compression) codec() (kafkago.Compression, error) {
+	switch c {
+	case "", CompressionSnappy:
+		return kafkago.Snappy, nil
+	case CompressionZstd:
+		return kafkago.Zstd, nil
+	case CompressionLZ4:
+		return kafkago.Lz4, nil
+	case CompressionNone:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unknown compression %q", c)
+	}
+}
+
+// DefaultMaxMessageBytes — message.max.bytes брокера по умолчанию
+const DefaultMaxMessageBytes = 1 << 20
+
+// ErrMessageTooLarge — сообщение больше ProducerConfig.MaxMessageBytes; повтор не поможет
+var ErrMessageTooLarge = errors.New("kafka message too large")
+
+// messageSize — размер сообщения до сжатия: key, value и заголовки
+func messageSize(msg Message) int {
+	size := len(msg.Key) + len(msg.Value)
+	for k, v := range msg.Headers {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// checkSize отклоняет сообщения больше MaxMessageBytes до обращения к брокеру:
+// иначе брокер ответит MESSAGE_TOO_LARGE уже после сериализации и сжатия batch'а
+func (p *Producer) checkSize(msgs ...Message) error {
+	limit := p.config.MaxMessageBytes
+	if limit < 0 {
+		return nil
+	}
+	for _, msg := range msgs {
+		size := messageSize(msg)
+		if size <= limit {
+			continue
+		}
+
+		p.metrics.MessagesFailed.Add(int64(len(msgs)))
+		p.metrics.OversizedRejected.Add(1)
+		p.logger.Error().
+			Str("topic", msg.Topic).
+			Str("key", msg.Key).
+			Int("size", size).
+			Int("limit", limit).
+			Msg("message exceeds max size, rejected")
+		return fmt.Errorf("%w: %q is %d bytes, limit %d", ErrMessageTooLarge, msg.Key, size, limit)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+)
+
+// MessageHandler обрабатывает одно сообщение. Ошибка логируется, offset всё равно коммитится:
+// consumer не должен вставать на одном сообщении.
+type MessageHandler func(ctx context.Context, msg Message) error
+
+// ConsumerConfig содержит конфигурацию Consumer
+type ConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	// Topics — подписка на несколько топиков одной группой (например TopicNaming.Topics);
+	// взаимоисключающе с Topic
+	Topics         []string
+	GroupID        string
+	CommitInterval time.Duration // Период коммита offset'ов (default: 1s)
+	// ReadCommitted — читать только зафиксированные транзакции; нужен для топиков,
+	// в которые пишет TxProducer
+	ReadCommitted bool
+	// Decryption расшифровывает сообщения с заголовком encryption_key_id (EncryptionInterceptor);
+	// без него такие сообщения не доходят до обработчика
+	Decryption *encryption.Cipher
+	Logger     zerolog.Logger
+}
+
+// ConsumerMetrics содержит метрики consumer
+type ConsumerMetrics struct {
+	MessagesConsumed atomic.Int64
+	HandlerErrors    atomic.Int64
+}
+
+// Consumer читает топик в составе consumer group и передаёт сообщения обработчику
+type Consumer struct {
+	reader     *kafkago.Reader
+	groupID    string
+	decryption *encryption.Cipher
+	logger     zerolog.Logger
+	metrics    *ConsumerMetrics
+}
+
+func NewConsumer(cfg ConsumerConfig) (*Consumer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("brokers list is empty")
+	}
+	if cfg.Topic == "" && len(cfg.Topics) == 0 {
+		return nil, errors.New("topic is empty")
+	}
+	if cfg.Topic != "" && len(cfg.Topics) > 0 {
+		return nil, errors.New("topic and topics are mutually exclusive")
+	}
+	if cfg.GroupID == "" {
+		return nil, errors.New("group id is empty")
+	}
+	if cfg.CommitInterval < 0 {
+		return nil, errors.New("commit_interval cannot be negative")
+	}
+	if cfg.CommitInterval == 0 {
+		cfg.CommitInterval = time.Second
+	}
+
+	isolation := kafkago.ReadUncommitted
+	if cfg.ReadCommitted {
+		isolation = kafkago.ReadCommitted
+	}
+
+	topics := cfg.Topics
+	if cfg.Topic != "" {
+		topics = []string{cfg.Topic}
+	}
+
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:        cfg.Brokers,
+		Topic:          cfg.Topic,
+		GroupTopics:    cfg.Topics,
+		GroupID:        cfg.GroupID,
+		CommitInterval: cfg.CommitInterval,
+		IsolationLevel: isolation,
+	})
+
+	return &Consumer{
+		reader:     reader,
+		groupID:    cfg.GroupID,
+		decryption: cfg.Decryption,
+		logger: cfg.Logger.With().
+			Str("component", "kafka_consumer").
+			Strs("topics", topics).
+			Str("group_id", cfg.GroupID).
+			Logger(),
+		metrics: &ConsumerMetrics{},
+	}, nil
+}
+
+// Run читает сообщения до отмены контекста
+func (c *Consumer) Run(ctx context.Context, h MessageHandler) error {
+	c.logger.Info().Msg("kafka consumer started")
+
+	for {
+		km, err := c.reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info().Msg("kafka consumer stopped")
+				return nil
+			}
+			return fmt.Errorf("read message: %w", err)
+		}
+
+		c.metrics.MessagesConsumed.Add(1)
+		if err := c.handle(ctx, km, h); err != nil {
+			c.metrics.HandlerErrors.Add(1)
+			c.logger.Error().
+				Err(err).
+				Int("partition", km.Partition).
+				Int64("offset", km.Offset).
+				Msg("message handler failed")
+		}
+	}
+}
+
+// RunTransactional — exactly-once цикл read-process-write: на каждое сообщение открывается
+// транзакция tx, обработчик пишет результат через tx.Produce, offset сообщения коммитится
+// в той же транзакции. Ошибка обработчика откатывает его записи, а offset фиксируется
+// отдельной транзакцией — как и в Run, consumer не встаёт на одном сообщении.
+//
+// Если транзакцию не удалось зафиксировать или откатить, Run возвращает ошибку:
+// reader уже ушёл вперёд, consumer нужно пересоздать, чтобы перечитать с закоммиченного offset'а.
+func (c *Consumer) RunTransactional(ctx context.Context, tx *TxProducer, h MessageHandler) error {
+	c.logger.Info().Msg("kafka transactional consumer started")
+
+	for {
+		km, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info().Msg("kafka transactional consumer stopped")
+				return nil
+			}
+			return fmt.Errorf("fetch message: %w", err)
+		}
+
+		c.metrics.MessagesConsumed.Add(1)
+		if err := c.processTransactional(ctx, tx, km, h); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Consumer) processTransactional(ctx context.Context, tx *TxProducer, km kafkago.Message, h MessageHandler) error {
+	offsets := map[string]map[int]int64{km.Topic: {km.Partition: km.Offset + 1}}
+
+	if err := tx.BeginTxn(); err != nil {
+		return fmt.Errorf("begin txn: %w", err)
+	}
+
+	if err := c.handle(ctx, km, h); err != nil {
+		c.metrics.HandlerErrors.Add(1)
+		c.logger.Error().
+			Err(err).
+			Int("partition", km.Partition).
+			Int64("offset", km.Offset).
+			Msg("message handler failed, transaction aborted")
+
+		// Записи обработчика откатываются, offset фиксируется отдельно
+		if err := tx.AbortTxn(ctx); err != nil {
+			return fmt.Errorf("abort txn: %w", err)
+		}
+		if err := tx.BeginTxn(); err != nil {
+			return fmt.Errorf("begin txn: %w", err)
+		}
+	}
+
+	if err := tx.SendOffsets(ctx, c.groupID, offsets); err != nil {
+		return errors.Join(fmt.Errorf("send offsets: %w", err), tx.AbortTxn(ctx))
+	}
+	if err := tx.CommitTxn(ctx); err != nil {
+		return errors.Join(fmt.Errorf("commit txn: %w", err), tx.AbortTxn(ctx))
+	}
+	return nil
+}
+
+// handle расшифровывает сообщение и передаёт его обработчику
+func (c *Consumer) handle(ctx context.Context, km kafkago.Message, h MessageHandler) error {
+	msg, err := decrypt(ctx, c.decryption, km)
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+	return h(messageContext(ctx, km), msg)
+}
+
+// Metrics возвращает метрики consumer
+func (c *Consumer) Metrics() *ConsumerMetrics { return c.metrics }
+
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+func fromKafka(km kafkago.Message) Message {
+	headers := make(map[string]string, len(km.Headers))
+	for _, h := range km.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	return Message{
+		Topic:   km.Topic,
+		Key:     string(km.Key),
+		Value:   km.Value,
+		Time:    km.Time,
+		Headers: headers,
+	}
+}
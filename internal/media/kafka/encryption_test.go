@@ -0,0 +1,43 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+)
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kr, err := encryption.ParseKeyring("k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	c, err := encryption.NewCipher(kr)
+	require.NoError(t, err)
+
+	msg := Message{Key: "event-1", Value: []byte(`{"source":"s3://bucket/a.mp4"}`), Headers: map[string]string{HeaderEventType: "MediaCreated"}}
+	require.NoError(t, EncryptionInterceptor(c)(ctx, &msg))
+	require.Equal(t, "k1", msg.Headers[HeaderEncryptionKeyID])
+	require.NotContains(t, string(msg.Value), "s3://")
+
+	km := msg.toKafka()
+	got, err := decrypt(ctx, c, km)
+	require.NoError(t, err)
+	require.Equal(t, `{"source":"s3://bucket/a.mp4"}`, string(got.Value))
+	require.Equal(t, map[string]string{HeaderEventType: "MediaCreated"}, got.Headers)
+
+	// Без ключей зашифрованное сообщение не читается, открытое — читается
+	_, err = decrypt(ctx, nil, km)
+	require.ErrorIs(t, err, encryption.ErrNoCipher)
+	plain, err := decrypt(ctx, nil, kafkago.Message{Key: []byte("k"), Value: []byte("v")})
+	require.NoError(t, err)
+	require.Equal(t, "v", string(plain.Value))
+
+	// Value под чужим ключом сообщения не расшифровывается
+	km.Key = []byte("event-2")
+	_, err = decrypt(ctx, c, km)
+	require.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// Заголовки, которые дублируют поля конверта: consumer может отфильтровать
+// или смаршрутизировать сообщение, не разбирая payload.
+const (
+	HeaderEventType     = "event_type"
+	HeaderSchemaVersion = "schema_version"
+	HeaderContentFormat = "content_format" // json | avro | protobuf
+	HeaderAggregateID   = "aggregate_id"
+	HeaderTenantID      = "tenant_id" // владелец события; отсутствует у событий без владельца
+)
+
+// EnvelopeMessage собирает Kafka сообщение из конверта события.
+// Ключ — event_id, ts — timestamp сообщения (zero — время публикации).
+func EnvelopeMessage(ctx context.Context, s Serializer, topic string, env events.Envelope, ts time.Time) (Message, error) {
+	value, err := s.Serialize(ctx, topic, env)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		Key:   env.EventID,
+		Value: value,
+		Time:  ts,
+		Headers: map[string]string{
+			HeaderEventType:     env.EventType,
+			HeaderSchemaVersion: strconv.Itoa(env.SchemaVersion),
+			HeaderContentFormat: string(s.Format()),
+			HeaderAggregateID:   env.AggregateID,
+		},
+	}
+	if tenant := env.Tenant(); tenant != "" {
+		msg.Headers[HeaderTenantID] = tenant
+	}
+	return msg, nil
+}
+
+// PublishEnvelope сериализует конверт форматом из конфига producer и публикует его
+func (p *Producer) PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error {
+	msg, err := p.envelopeMessage(ctx, env, ts)
+	if err != nil {
+		return err
+	}
+	return p.PublishMessage(ctx, msg)
+}
+
+// PublishEnvelopeTo публикует конверт в topic мимо TopicRouter — так события переигрываются
+// в отдельный топик
+func (p *Producer) PublishEnvelopeTo(ctx context.Context, topic string, env events.Envelope, ts time.Time) error {
+	msg, err := EnvelopeMessage(ctx, p.serializer, topic, env, ts)
+	if err != nil {
+		return fmt.Errorf("serialize envelope: %w", err)
+	}
+	msg.Topic = topic
+	return p.PublishMessage(ctx, msg)
+}
+
+// envelopeMessage выбирает топик до сериализации: от него зависит subject в schema registry
+func (p *Producer) envelopeMessage(ctx context.Context, env events.Envelope, ts time.Time) (Message, error) {
+	headers := map[string]string{HeaderEventType: env.EventType, HeaderAggregateID: env.AggregateID}
+	if p.config.TopicRouter != nil {
+		if tenant := env.Tenant(); tenant != "" {
+			headers[HeaderTenantID] = tenant
+		}
+	}
+	topic := p.route(Message{Key: env.EventID, Headers: headers}).Topic
+
+	msg, err := EnvelopeMessage(ctx, p.serializer, topic, env, ts)
+	if err != nil {
+		return Message{}, fmt.Errorf("serialize envelope: %w", err)
+	}
+	msg.Topic = topic
+	return msg, nil
+}
@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"strings"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Interceptor меняет сообщение перед записью: шифрование, сжатие payload'а, заголовки,
+// вычистка персональных данных. Вызывается после выбора топика и до проверки размера.
+// Ошибка отклоняет публикацию (в PublishBatch — весь batch).
+// Headers можно менять на месте — это копия; Value нужно заменять, а не править: он общий с вызывающим.
+type Interceptor func(ctx context.Context, msg *Message) error
+
+// intercept прогоняет сообщение через ProducerConfig.Interceptors по порядку
+func (p *Producer) intercept(ctx context.Context, msg Message) (Message, error) {
+	if len(p.config.Interceptors) == 0 {
+		return msg, nil
+	}
+	msg.Headers = maps.Clone(msg.Headers)
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	for _, ic := range p.config.Interceptors {
+		if err := ic(ctx, &msg); err != nil {
+			return Message{}, fmt.Errorf("interceptor: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+// prepare выбирает топик сообщения и прогоняет его через interceptor'ы
+func (p *Producer) prepare(ctx context.Context, msg Message) (Message, error) {
+	msg, err := p.intercept(ctx, p.route(msg))
+	if err != nil {
+		p.metrics.MessagesFailed.Add(1)
+	}
+	return msg, err
+}
+
+// HeaderTraceParent — контекст трассировки сообщения в формате W3C Trace Context
+const HeaderTraceParent = "traceparent"
+
+type traceParentKey struct{}
+
+// ContextWithTraceParent кладёт в ctx traceparent, который TracingInterceptor продолжит
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+// TraceParentFromContext возвращает traceparent из ctx или пустую строку
+func TraceParentFromContext(ctx context.Context) string {
+	tp, _ := ctx.Value(traceParentKey{}).(string)
+	return tp
+}
+
+// TracingInterceptor проставляет сообщению заголовок traceparent (W3C Trace Context):
+// trace id берётся из ctx (ContextWithTraceParent, его кладёт Consumer из прочитанного
+// сообщения), без него начинается новый трейс. Каждое сообщение получает свой span id.
+// Уже проставленный заголовок не трогает.
+func TracingInterceptor() Interceptor {
+	return func(ctx context.Context, msg *Message) error {
+		if msg.Headers[HeaderTraceParent] != "" {
+			return nil
+		}
+		traceID, flags := "", "01"
+		if parts := strings.Split(TraceParentFromContext(ctx), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+			traceID, flags = parts[1], parts[3]
+		}
+		if traceID == "" {
+			traceID = randomHex(16)
+		}
+		msg.Headers[HeaderTraceParent] = "00-" + traceID + "-" + randomHex(8) + "-" + flags
+		return nil
+	}
+}
+
+// messageContext — ctx обработчика сообщения: с traceparent сообщения, если он есть
+func messageContext(ctx context.Context, km kafkago.Message) context.Context {
+	for _, h := range km.Headers {
+		if h.Key == HeaderTraceParent {
+			return ContextWithTraceParent(ctx, string(h.Value))
+		}
+	}
+	return ctx
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProducer_Interceptors(t *testing.T) {
+	var order []string
+	scrub := func(_ context.Context, msg *Message) error {
+		order = append(order, "scrub:"+msg.Topic)
+		msg.Value = []byte(strings.ReplaceAll(string(msg.Value), "alice@example.com", "***"))
+		return nil
+	}
+	tag := func(_ context.Context, msg *Message) error {
+		order = append(order, "tag")
+		msg.Headers["x-service"] = "media"
+		return nil
+	}
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:      []string{"localhost:9092"},
+		Topic:        "events.media",
+		Interceptors: []Interceptor{scrub, tag},
+		Logger:       zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	headers := map[string]string{HeaderEventType: "MediaCreated"}
+	msg, err := producer.prepare(context.Background(), Message{Key: "k", Value: []byte(`{"email":"alice@example.com"}`), Headers: headers})
+	require.NoError(t, err)
+
+	// Interceptor'ы видят выбранный топик и идут по порядку
+	require.Equal(t, []string{"scrub:events.media", "tag"}, order)
+	require.Equal(t, `{"email":"***"}`, string(msg.Value))
+	require.Equal(t, "media", msg.Headers["x-service"])
+	// Заголовки вызывающего не тронуты
+	require.Equal(t, map[string]string{HeaderEventType: "MediaCreated"}, headers)
+}
+
+func TestProducer_InterceptorErrorRejectsPublish(t *testing.T) {
+	boom := errors.New("encryption key unavailable")
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:      []string{"localhost:9092"},
+		Topic:        "events.media",
+		Interceptors: []Interceptor{func(context.Context, *Message) error { return boom }},
+		Logger:       zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	require.ErrorIs(t, producer.Publish(context.Background(), "k", []byte("v")), boom)
+	require.ErrorIs(t, producer.PublishBatch(context.Background(), []Message{{Key: "a"}, {Key: "b"}}), boom)
+	require.Equal(t, int64(3), producer.metrics.MessagesFailed.Load())
+}
+
+func TestTracingInterceptor(t *testing.T) {
+	trace := TracingInterceptor()
+
+	// Без контекста трассировки — новый трейс
+	msg := Message{Headers: map[string]string{}}
+	require.NoError(t, trace(context.Background(), &msg))
+	tp := msg.Headers[HeaderTraceParent]
+	require.Regexp(t, `^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`, tp)
+
+	// Traceparent прочитанного сообщения продолжается: тот же trace id, свой span id
+	ctx := messageContext(context.Background(), kafkago.Message{Headers: []kafkago.Header{{Key: HeaderTraceParent, Value: []byte(tp)}}})
+	next := Message{Headers: map[string]string{}}
+	require.NoError(t, trace(ctx, &next))
+	require.Equal(t, tp[:36], next.Headers[HeaderTraceParent][:36])
+	require.NotEqual(t, tp, next.Headers[HeaderTraceParent])
+
+	// Проставленный вызывающим заголовок не перезаписывается
+	own := Message{Headers: map[string]string{HeaderTraceParent: "00-own"}}
+	require.NoError(t, trace(ctx, &own))
+	require.Equal(t, "00-own", own.Headers[HeaderTraceParent])
+}
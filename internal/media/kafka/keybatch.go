@@ -0,0 +1,165 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// KeyBatchConfig — накопление сообщений по ключу перед записью (см. ProducerConfig.KeyBatch)
+type KeyBatchConfig struct {
+	// MaxMessages — сообщений ключа, после которых буфер пишется сразу (0 — накопление выключено)
+	MaxMessages int
+	// MaxDelay — сколько ждёт первое сообщение буфера, прежде чем он будет записан (default: 10ms)
+	MaxDelay time.Duration
+}
+
+func (c KeyBatchConfig) validate() error {
+	if c.MaxMessages < 0 {
+		return errors.New("key_batch max_messages cannot be negative")
+	}
+	if c.MaxDelay < 0 {
+		return errors.New("key_batch max_delay cannot be negative")
+	}
+	return nil
+}
+
+// keyBuffer — накопленные сообщения одного ключа и их колбэки доставки
+type keyBuffer struct {
+	msgs  []Message
+	done  []func(err error)
+	timer *time.Timer
+}
+
+// keyBatcher копит сообщения по ключу: буфер ключа пишется одним WriteMessages, когда
+// наберёт maxMessages, через maxDelay после первого сообщения или по Flush.
+// Буферы пишутся по одному, в том порядке, в котором забраны из buffers, поэтому сообщения
+// ключа уходят в брокер в порядке поступления.
+type keyBatcher struct {
+	maxMessages  int
+	maxDelay     time.Duration
+	writeTimeout time.Duration
+	write        func(ctx context.Context, msgs []Message) error
+	logger       zerolog.Logger
+
+	mu      sync.Mutex
+	buffers map[string]*keyBuffer
+	next    uint64 // очередь следующего забранного буфера
+
+	writeMu sync.Mutex
+	turn    *sync.Cond // ждёт своей очереди на запись; под writeMu
+	serving uint64     // очередь, которая пишется сейчас; под writeMu
+}
+
+func newKeyBatcher(cfg KeyBatchConfig, writeTimeout time.Duration, write func(context.Context, []Message) error, logger zerolog.Logger) *keyBatcher {
+	b := &keyBatcher{
+		maxMessages:  cfg.MaxMessages,
+		maxDelay:     cfg.MaxDelay,
+		writeTimeout: writeTimeout,
+		write:        write,
+		logger:       logger,
+		buffers:      make(map[string]*keyBuffer),
+	}
+	b.turn = sync.NewCond(&b.writeMu)
+	return b
+}
+
+// add ставит сообщение в буфер его ключа. Заполненный буфер пишется сразу, в ctx вызывающего.
+func (b *keyBatcher) add(ctx context.Context, msg Message, done func(err error)) {
+	b.mu.Lock()
+	buf, ok := b.buffers[msg.Key]
+	if !ok {
+		buf = &keyBuffer{}
+		b.buffers[msg.Key] = buf
+		key := msg.Key
+		buf.timer = time.AfterFunc(b.maxDelay, func() { b.expire(key, buf) })
+	}
+	buf.msgs = append(buf.msgs, msg)
+	buf.done = append(buf.done, done)
+	full := len(buf.msgs) >= b.maxMessages
+	var ticket uint64
+	if full {
+		buf.timer.Stop()
+		ticket = b.take(msg.Key)
+	}
+	b.mu.Unlock()
+
+	if full {
+		_ = b.flush(ctx, buf, ticket)
+	}
+}
+
+// take убирает буфер key из buffers и выдаёт ему очередь на запись; под mu
+func (b *keyBatcher) take(key string) uint64 {
+	delete(b.buffers, key)
+	ticket := b.next
+	b.next++
+	return ticket
+}
+
+// expire пишет буфер, дождавшийся maxDelay, если его ещё не забрали add или Flush
+func (b *keyBatcher) expire(key string, buf *keyBuffer) {
+	b.mu.Lock()
+	if b.buffers[key] != buf {
+		b.mu.Unlock()
+		return
+	}
+	ticket := b.take(key)
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.writeTimeout)
+	defer cancel()
+	if err := b.flush(ctx, buf, ticket); err != nil {
+		b.logger.Warn().Err(err).Str("key", key).Int("messages", len(buf.msgs)).Msg("key batch write failed")
+	}
+}
+
+// Flush пишет все накопленные буферы одним WriteMessages
+func (b *keyBatcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	if len(b.buffers) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	all := &keyBuffer{}
+	for key, buf := range b.buffers {
+		buf.timer.Stop()
+		all.msgs = append(all.msgs, buf.msgs...)
+		all.done = append(all.done, buf.done...)
+		delete(b.buffers, key)
+	}
+	ticket := b.next
+	b.next++
+	b.mu.Unlock()
+
+	return b.flush(ctx, all, ticket)
+}
+
+// flush пишет буфер в свою очередь и сообщает результат его колбэкам
+func (b *keyBatcher) flush(ctx context.Context, buf *keyBuffer, ticket uint64) error {
+	b.writeMu.Lock()
+	for b.serving != ticket {
+		b.turn.Wait()
+	}
+	err := b.write(ctx, buf.msgs)
+	b.serving++
+	b.turn.Broadcast()
+	b.writeMu.Unlock()
+
+	for _, done := range buf.done {
+		done(err)
+	}
+	return err
+}
+
+// Flush записывает сообщения, накопленные по ключам (KeyBatch), не дожидаясь MaxDelay.
+// Ошибка записи возвращается и передаётся колбэкам доставки. Без KeyBatch ничего не делает.
+func (p *Producer) Flush(ctx context.Context) error {
+	if p.batcher == nil {
+		return nil
+	}
+	return p.batcher.Flush(ctx)
+}
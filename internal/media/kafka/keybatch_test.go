@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWriter запоминает ключи каждой записи
+type recordingWriter struct {
+	mu     sync.Mutex
+	writes [][]string
+	err    error
+}
+
+func (w *recordingWriter) write(_ context.Context, msgs []Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	keys := make([]string, len(msgs))
+	for i, m := range msgs {
+		keys[i] = m.Key
+	}
+	w.writes = append(w.writes, keys)
+	return w.err
+}
+
+func (w *recordingWriter) Writes() [][]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([][]string(nil), w.writes...)
+}
+
+func TestKeyBatcher_FlushesFullBuffer(t *testing.T) {
+	w := &recordingWriter{}
+	b := newKeyBatcher(KeyBatchConfig{MaxMessages: 3, MaxDelay: time.Hour}, time.Second, w.write, zerolog.Nop())
+
+	var results []error
+	done := func(err error) { results = append(results, err) }
+	for _, key := range []string{"m1", "m2", "m1", "m1", "m1"} {
+		b.add(context.Background(), Message{Key: key}, done)
+	}
+
+	// Третье сообщение m1 заполнило буфер — записан одним запросом; m1 после него копится заново
+	require.Equal(t, [][]string{{"m1", "m1", "m1"}}, w.Writes())
+	require.Equal(t, []error{nil, nil, nil}, results)
+
+	w.err = errors.New("not enough replicas")
+	require.ErrorIs(t, b.Flush(context.Background()), w.err)
+	require.Len(t, w.Writes(), 2)
+	require.ElementsMatch(t, []string{"m1", "m2"}, w.Writes()[1])
+	require.Len(t, results, 5)
+	require.ErrorIs(t, results[4], w.err)
+
+	// Пустой Flush ничего не пишет
+	require.NoError(t, b.Flush(context.Background()))
+	require.Len(t, w.Writes(), 2)
+}
+
+func TestKeyBatcher_FlushesAfterMaxDelay(t *testing.T) {
+	w := &recordingWriter{}
+	b := newKeyBatcher(KeyBatchConfig{MaxMessages: 100, MaxDelay: 10 * time.Millisecond}, time.Second, w.write, zerolog.Nop())
+
+	delivered := make(chan error, 2)
+	done := func(err error) { delivered <- err }
+	b.add(context.Background(), Message{Key: "m1"}, done)
+	b.add(context.Background(), Message{Key: "m1"}, done)
+
+	for range 2 {
+		select {
+		case err := <-delivered:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("buffer was not flushed after max delay")
+		}
+	}
+	require.Equal(t, [][]string{{"m1", "m1"}}, w.Writes())
+}
+
+func TestProducer_KeyBatchConfig(t *testing.T) {
+	_, err := NewProducer(ProducerConfig{
+		Brokers:  []string{"localhost:9092"},
+		Topic:    "test",
+		Async:    true,
+		KeyBatch: KeyBatchConfig{MaxMessages: 10},
+		Logger:   zerolog.Nop(),
+	})
+	require.ErrorContains(t, err, "mutually exclusive")
+
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:  []string{"localhost:9092"},
+		Topic:    "test",
+		KeyBatch: KeyBatchConfig{MaxMessages: 10},
+		Logger:   zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Millisecond, producer.batcher.maxDelay)
+	require.NoError(t, producer.Close())
+
+	err = producer.PublishMessageAsync(context.Background(), Message{Key: "m1"}, func(error) {})
+	require.ErrorIs(t, err, ErrProducerClosed)
+}
@@ -0,0 +1,265 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// LagTarget — consumer group и топики, лаг которых отслеживается
+type LagTarget struct {
+	Group  string
+	Topics []string
+}
+
+// LagMonitorConfig содержит конфигурацию LagMonitor
+type LagMonitorConfig struct {
+	Brokers  []string
+	Targets  []LagTarget
+	Interval time.Duration // Период замера (default: 30s)
+	// Threshold — при большем суммарном лаге группы CheckLag возвращает ошибку (0 = не проверять)
+	Threshold int64
+	Timeout   time.Duration // Timeout запроса к брокеру (default: 10s)
+	Logger    zerolog.Logger
+}
+
+// LagMetrics содержит метрики LagMonitor
+type LagMetrics struct {
+	// Lag — сообщения партиции, ещё не закоммиченные группой
+	Lag      *prometheus.GaugeVec
+	Failures atomic.Int64 // Неудачные замеры
+}
+
+func newLagMetrics() *LagMetrics {
+	return &LagMetrics{
+		Lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kafka_consumer_group_lag",
+			Help: "Сообщения партиции, ещё не обработанные consumer group (high watermark − committed offset)",
+		}, []string{"group", "topic", "partition"}),
+	}
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *LagMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.Lag,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "kafka_consumer_lag_check_errors_total",
+			Help: "Неудачные замеры лага consumer group",
+		}, func() float64 { return float64(m.Failures.Load()) }),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offsetClient — запросы метаданных и offset'ов; реализуется *kafkago.Client
+type offsetClient interface {
+	Metadata(ctx context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error)
+	ListOffsets(ctx context.Context, req *kafkago.ListOffsetsRequest) (*kafkago.ListOffsetsResponse, error)
+	OffsetFetch(ctx context.Context, req *kafkago.OffsetFetchRequest) (*kafkago.OffsetFetchResponse, error)
+}
+
+// LagMonitor периодически сравнивает закоммиченные offset'ы групп с high watermark
+// партиций: лаг уходит в метрики и в /readyz (CheckLag), чтобы отставание обработки
+// было видно раньше, чем его заметят пользователи.
+type LagMonitor struct {
+	client    offsetClient
+	targets   []LagTarget
+	interval  time.Duration
+	threshold int64
+	metrics   *LagMetrics
+	logger    zerolog.Logger
+
+	mu  sync.Mutex
+	lag map[string]int64 // суммарный лаг группы на момент последнего удачного замера
+}
+
+func NewLagMonitor(cfg LagMonitorConfig) (*LagMonitor, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("brokers list is empty")
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("lag targets are empty")
+	}
+	for _, t := range cfg.Targets {
+		if t.Group == "" {
+			return nil, errors.New("lag target group id is empty")
+		}
+		if len(t.Topics) == 0 {
+			return nil, fmt.Errorf("lag target %s: topics are empty", t.Group)
+		}
+	}
+	if cfg.Interval < 0 {
+		return nil, errors.New("interval cannot be negative")
+	}
+	if cfg.Threshold < 0 {
+		return nil, errors.New("threshold cannot be negative")
+	}
+	if cfg.Timeout < 0 {
+		return nil, errors.New("timeout cannot be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &LagMonitor{
+		client: &kafkago.Client{
+			Addr:    kafkago.TCP(cfg.Brokers...),
+			Timeout: cfg.Timeout,
+		},
+		targets:   cfg.Targets,
+		interval:  cfg.Interval,
+		threshold: cfg.Threshold,
+		metrics:   newLagMetrics(),
+		logger:    cfg.Logger.With().Str("component", "kafka_lag_monitor").Logger(),
+		lag:       make(map[string]int64),
+	}, nil
+}
+
+// Metrics возвращает метрики монитора
+func (m *LagMonitor) Metrics() *LagMetrics {
+	return m.metrics
+}
+
+// Run замеряет лаг сразу и дальше каждые Interval до отмены контекста.
+// Неудачный замер логируется, последние значения остаются в силе.
+func (m *LagMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(ctx); err != nil && ctx.Err() == nil {
+			m.metrics.Failures.Add(1)
+			m.logger.Warn().Err(err).Msg("consumer lag check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll замеряет лаг всех групп один раз
+func (m *LagMonitor) Poll(ctx context.Context) error {
+	var errs []error
+	for _, t := range m.targets {
+		total, err := m.pollGroup(ctx, t)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", t.Group, err))
+			continue
+		}
+		m.mu.Lock()
+		m.lag[t.Group] = total
+		m.mu.Unlock()
+
+		ev := m.logger.Debug()
+		if m.threshold > 0 && total > m.threshold {
+			ev = m.logger.Warn()
+		}
+		ev.Str("group", t.Group).Int64("lag", total).Msg("consumer lag")
+	}
+	return errors.Join(errs...)
+}
+
+// Lag возвращает суммарный лаг групп на момент последнего удачного замера
+func (m *LagMonitor) Lag() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lag := make(map[string]int64, len(m.lag))
+	for g, n := range m.lag {
+		lag[g] = n
+	}
+	return lag
+}
+
+// CheckLag — проверка готовности для /readyz: ошибка, если лаг какой-либо группы превысил Threshold.
+// Значения берутся из последнего замера, брокер не опрашивается.
+func (m *LagMonitor) CheckLag(ctx context.Context) error {
+	if m.threshold == 0 {
+		return nil
+	}
+	var errs []error
+	for _, t := range m.targets {
+		m.mu.Lock()
+		n := m.lag[t.Group]
+		m.mu.Unlock()
+		if n > m.threshold {
+			errs = append(errs, fmt.Errorf("consumer group %s lag %d exceeds threshold %d", t.Group, n, m.threshold))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pollGroup считает лаг партиций группы и возвращает их сумму. Партиция без закоммиченного
+// offset'а читается группой с начала, её лаг — все сообщения партиции.
+func (m *LagMonitor) pollGroup(ctx context.Context, t LagTarget) (int64, error) {
+	meta, err := m.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: t.Topics})
+	if err != nil {
+		return 0, fmt.Errorf("metadata: %w", err)
+	}
+	partitions := make(map[string][]int, len(meta.Topics))
+	requests := make(map[string][]kafkago.OffsetRequest, len(meta.Topics))
+	for _, topic := range meta.Topics {
+		if topic.Error != nil {
+			return 0, fmt.Errorf("metadata %s: %w", topic.Name, topic.Error)
+		}
+		for _, p := range topic.Partitions {
+			partitions[topic.Name] = append(partitions[topic.Name], p.ID)
+			requests[topic.Name] = append(requests[topic.Name], kafkago.FirstOffsetOf(p.ID), kafkago.LastOffsetOf(p.ID))
+		}
+	}
+
+	offsets, err := m.client.ListOffsets(ctx, &kafkago.ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return 0, fmt.Errorf("list offsets: %w", err)
+	}
+	committed, err := m.client.OffsetFetch(ctx, &kafkago.OffsetFetchRequest{GroupID: t.Group, Topics: partitions})
+	if err != nil {
+		return 0, fmt.Errorf("offset fetch: %w", err)
+	}
+	if committed.Error != nil {
+		return 0, fmt.Errorf("offset fetch: %w", committed.Error)
+	}
+
+	var total int64
+	for topic, parts := range offsets.Topics {
+		for _, p := range parts {
+			if p.Error != nil {
+				return 0, fmt.Errorf("list offsets %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			lag := p.LastOffset - p.FirstOffset
+			i := slices.IndexFunc(committed.Topics[topic], func(c kafkago.OffsetFetchPartition) bool {
+				return c.Partition == p.Partition
+			})
+			if i >= 0 {
+				c := committed.Topics[topic][i]
+				if c.Error != nil {
+					return 0, fmt.Errorf("offset fetch %s/%d: %w", topic, p.Partition, c.Error)
+				}
+				if c.CommittedOffset >= 0 {
+					lag = p.LastOffset - c.CommittedOffset
+				}
+			}
+			lag = max(lag, 0)
+			m.metrics.Lag.WithLabelValues(t.Group, topic, strconv.Itoa(p.Partition)).Set(float64(lag))
+			total += lag
+		}
+	}
+	return total, nil
+}
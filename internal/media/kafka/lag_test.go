@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOffsetClient struct {
+	partitions map[string]int                            // число партиций топика
+	offsets    map[string][]kafkago.PartitionOffsets     // first/last offset партиций
+	committed  map[string][]kafkago.OffsetFetchPartition // закоммиченные offset'ы группы
+	fetchErr   error
+}
+
+func (f *fakeOffsetClient) Metadata(_ context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error) {
+	res := &kafkago.MetadataResponse{}
+	for _, name := range req.Topics {
+		topic := kafkago.Topic{Name: name}
+		for id := range f.partitions[name] {
+			topic.Partitions = append(topic.Partitions, kafkago.Partition{Topic: name, ID: id})
+		}
+		res.Topics = append(res.Topics, topic)
+	}
+	return res, nil
+}
+
+func (f *fakeOffsetClient) ListOffsets(_ context.Context, req *kafkago.ListOffsetsRequest) (*kafkago.ListOffsetsResponse, error) {
+	res := &kafkago.ListOffsetsResponse{Topics: make(map[string][]kafkago.PartitionOffsets)}
+	for topic := range req.Topics {
+		res.Topics[topic] = f.offsets[topic]
+	}
+	return res, nil
+}
+
+func (f *fakeOffsetClient) OffsetFetch(_ context.Context, _ *kafkago.OffsetFetchRequest) (*kafkago.OffsetFetchResponse, error) {
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+	return &kafkago.OffsetFetchResponse{Topics: f.committed}, nil
+}
+
+func TestLagMonitor_Poll(t *testing.T) {
+	client := &fakeOffsetClient{
+		partitions: map[string]int{"events.media": 2},
+		offsets: map[string][]kafkago.PartitionOffsets{"events.media": {
+			{Partition: 0, FirstOffset: 0, LastOffset: 120},
+			{Partition: 1, FirstOffset: 10, LastOffset: 50},
+		}},
+		committed: map[string][]kafkago.OffsetFetchPartition{"events.media": {
+			{Partition: 0, CommittedOffset: 100},
+			{Partition: 1, CommittedOffset: -1}, // группа ещё ничего не коммитила
+		}},
+	}
+	m := &LagMonitor{
+		client:    client,
+		targets:   []LagTarget{{Group: "publish-cdn", Topics: []string{"events.media"}}},
+		threshold: 50,
+		metrics:   newLagMetrics(),
+		logger:    zerolog.Nop(),
+		lag:       make(map[string]int64),
+	}
+
+	// До первого замера лаг неизвестен — сервис готов
+	require.NoError(t, m.CheckLag(context.Background()))
+
+	require.NoError(t, m.Poll(context.Background()))
+	require.Equal(t, map[string]int64{"publish-cdn": 60}, m.Lag())
+	require.Equal(t, float64(20), testutil.ToFloat64(m.metrics.Lag.WithLabelValues("publish-cdn", "events.media", "0")))
+	require.Equal(t, float64(40), testutil.ToFloat64(m.metrics.Lag.WithLabelValues("publish-cdn", "events.media", "1")))
+	require.ErrorContains(t, m.CheckLag(context.Background()), "publish-cdn lag 60 exceeds threshold 50")
+
+	// Неудачный замер не сбрасывает последнее значение
+	client.fetchErr = errors.New("broker unavailable")
+	require.ErrorContains(t, m.Poll(context.Background()), "publish-cdn")
+	require.Equal(t, map[string]int64{"publish-cdn": 60}, m.Lag())
+
+	client.fetchErr = nil
+	client.committed["events.media"][1].CommittedOffset = 50
+	require.NoError(t, m.Poll(context.Background()))
+	require.NoError(t, m.CheckLag(context.Background()))
+}
+
+func TestNewLagMonitor_Validation(t *testing.T) {
+	target := []LagTarget{{Group: "g", Topics: []string{"t"}}}
+	for _, cfg := range []LagMonitorConfig{
+		{Targets: target},
+		{Brokers: []string{"localhost:9092"}},
+		{Brokers: []string{"localhost:9092"}, Targets: []LagTarget{{Topics: []string{"t"}}}},
+		{Brokers: []string{"localhost:9092"}, Targets: []LagTarget{{Group: "g"}}},
+		{Brokers: []string{"localhost:9092"}, Targets: target, Threshold: -1},
+	} {
+		_, err := NewLagMonitor(cfg)
+		require.Error(t, err)
+	}
+
+	m, err := NewLagMonitor(LagMonitorConfig{Brokers: []string{"localhost:9092"}, Targets: target})
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, m.interval)
+}
@@ -11,6 +11,9 @@ import (
 	kafkago "github.com/segmentio/kafka-go"
 )
 
+// ErrProducerClosed — producer уже закрыт через Close/Shutdown
+var ErrProducerClosed = errors.New("producer is closed")
+
 // Producer реализует надёжную публикацию сообщений в Kafka с retry, metrics и логированием
 type Producer struct {
 	writer  *kafkago.Writer
@@ -18,18 +21,48 @@ type Producer struct {
 	config  ProducerConfig
 	metrics *ProducerMetrics
 	closed  atomic.Bool
+	breaker *breaker
+	errors  chan DeliveryError // ошибки доставки async режима; nil в sync режиме
+	batcher *keyBatcher        // накопление по ключу (KeyBatch); nil — выключено
+
+	serializer Serializer
 }
 
 // ProducerConfig содержит конфигурацию для создания Producer
 type ProducerConfig struct {
 	Brokers      []string
-	Topic        string
+	Topic        string        // Топик по умолчанию
 	MaxRetries   int           // Максимальное количество retry (default: 3)
 	RetryBackoff time.Duration // Задержка между retry (default: 100ms)
 	WriteTimeout time.Duration // Timeout для записи (default: 10s)
 	BatchSize    int           // Размер batch для producer (default: 100)
 	Async        bool          // Асинхронная публикация (default: false)
-	Logger       zerolog.Logger
+	Format       Format        // Формат конверта в PublishEnvelope (default: json)
+	Compression  Compression   // Кодек сжатия batch'ей (default: snappy)
+	// MaxMessageBytes — предел размера сообщения до сжатия (key + value + заголовки);
+	// большие отклоняются ErrMessageTooLarge без обращения к брокеру (default: 1MiB, -1 — без проверки)
+	MaxMessageBytes int
+	// TopicRouter выбирает топик для сообщений без Message.Topic (default: всё в Topic)
+	TopicRouter TopicRouter
+	// Interceptors меняют каждое сообщение перед записью, по порядку (см. Interceptor)
+	Interceptors []Interceptor
+
+	// Completion вызывается на каждое сообщение после доставки в async режиме (err == nil — доставлено).
+	// Вызывается из горутины writer'а: долгая работа в нём задерживает следующие batch'и.
+	Completion func(msg Message, err error)
+	// ErrorBuffer — размер канала Errors() в async режиме (default: 100)
+	ErrorBuffer int
+	// KeyBatch копит сообщения PublishMessageAsync/PublishEnvelopeAsync по ключу и пишет буфер
+	// ключа одним запросом; только в sync режиме (async writer группирует сообщения сам)
+	KeyBatch KeyBatchConfig
+
+	// SchemaRegistry обязателен для FormatAvro и FormatProtobuf
+	SchemaRegistry SchemaRegistryConfig
+	// Breaker отклоняет запись без попыток, пока брокер недоступен
+	Breaker BreakerConfig
+	// Classifier решает, какие ошибки повторять (default: DefaultErrorClassifier)
+	Classifier ErrorClassifier
+	Logger     zerolog.Logger
 }
 
 // ProducerMetrics содержит метрики для мониторинга
@@ -38,6 +71,10 @@ type ProducerMetrics struct {
 	MessagesFailed    atomic.Int64 // Проваленные сообщения
 	RetriesTotal      atomic.Int64 // Общее количество retry
 	PublishDuration   atomic.Int64 // Суммарное время публикации (наносекунды)
+	CircuitOpens      atomic.Int64 // Сколько раз открывался circuit breaker
+	CircuitRejected   atomic.Int64 // Публикации, отклонённые открытым breaker'ом
+	ErrorsDropped     atomic.Int64 // Ошибки доставки, не поместившиеся в канал Errors()
+	OversizedRejected atomic.Int64 // Сообщения, отклонённые по MaxMessageBytes
 }
 
 // NewProducer создаёт новый экземпляр Producer с заданной конфигурацией
@@ -49,24 +86,47 @@ func NewProducer(cfg ProducerConfig) (*Producer, error) {
 	// Устанавливаем defaults
 	setDefaults(&cfg)
 
+	serializer, err := newSerializer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("serializer: %w", err)
+	}
+	compression, err := cfg.Compression.codec()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Топик не фиксируется на writer'е: его несёт каждое сообщение,
+	// поэтому один writer и его соединения обслуживают все топики
 	writer := &kafkago.Writer{
 		Addr:         kafkago.TCP(cfg.Brokers...),
-		Topic:        cfg.Topic,
 		Balancer:     &kafkago.LeastBytes{},
 		BatchSize:    cfg.BatchSize,
 		BatchTimeout: 10 * time.Millisecond,
 		WriteTimeout: cfg.WriteTimeout,
-		// Compression
-		Compression: kafkago.Snappy,
+		Compression:  compression,
 		// Async mode
 		Async: cfg.Async,
 	}
+	if cfg.MaxMessageBytes > 0 {
+		// Иначе writer отклонит сообщение больше своего default в 1MiB раньше нашей проверки
+		writer.BatchBytes = int64(cfg.MaxMessageBytes)
+	}
 
 	p := &Producer{
-		writer:  writer,
-		logger:  cfg.Logger.With().Str("component", "kafka_producer").Str("topic", cfg.Topic).Logger(),
-		config:  cfg,
-		metrics: &ProducerMetrics{},
+		writer:     writer,
+		logger:     cfg.Logger.With().Str("component", "kafka_producer").Logger(),
+		config:     cfg,
+		metrics:    &ProducerMetrics{},
+		breaker:    newBreaker(cfg.Breaker),
+		serializer: serializer,
+	}
+	if cfg.Async {
+		// Без Completion kafka-go молча теряет ошибки async записи
+		writer.Completion = p.onCompletion
+		p.errors = make(chan DeliveryError, cfg.ErrorBuffer)
+	}
+	if cfg.KeyBatch.MaxMessages > 0 {
+		p.batcher = newKeyBatcher(cfg.KeyBatch, cfg.WriteTimeout, p.PublishBatch, p.logger)
 	}
 
 	p.logger.Info().
@@ -76,6 +136,13 @@ func NewProducer(cfg ProducerConfig) (*Producer, error) {
 		Dur("retry_backoff", cfg.RetryBackoff).
 		Dur("write_timeout", cfg.WriteTimeout).
 		Bool("async", cfg.Async).
+		Str("format", string(cfg.Format)).
+		Str("compression", string(cfg.Compression)).
+		Int("max_message_bytes", cfg.MaxMessageBytes).
+		Int("key_batch_max_messages", cfg.KeyBatch.MaxMessages).
+		Dur("key_batch_max_delay", cfg.KeyBatch.MaxDelay).
+		Int("breaker_threshold", cfg.Breaker.FailureThreshold).
+		Dur("breaker_cool_down", cfg.Breaker.CoolDown).
 		Msg("kafka producer created")
 
 	return p, nil
@@ -98,6 +165,39 @@ func validateConfig(cfg *ProducerConfig) error {
 	if cfg.WriteTimeout < 0 {
 		return errors.New("write_timeout cannot be negative")
 	}
+	if cfg.ErrorBuffer < 0 {
+		return errors.New("error_buffer cannot be negative")
+	}
+	if cfg.MaxMessageBytes < -1 {
+		return errors.New("max_message_bytes must be positive or -1 to disable")
+	}
+	if _, err := cfg.Compression.codec(); err != nil {
+		return err
+	}
+	if err := cfg.KeyBatch.validate(); err != nil {
+		return err
+	}
+	if cfg.KeyBatch.MaxMessages > 0 && cfg.Async {
+		return errors.New("key_batch and async are mutually exclusive")
+	}
+	if cfg.Breaker.FailureThreshold < -1 {
+		return errors.New("breaker failure_threshold must be positive or -1 to disable")
+	}
+	if cfg.Breaker.CoolDown < 0 {
+		return errors.New("breaker cool_down cannot be negative")
+	}
+	if cfg.Breaker.HalfOpenProbes < 0 {
+		return errors.New("breaker half_open_probes cannot be negative")
+	}
+	switch cfg.Format {
+	case "", FormatJSON:
+	case FormatAvro, FormatProtobuf:
+		if cfg.SchemaRegistry.URL == "" {
+			return fmt.Errorf("schema registry url is required for format %q", cfg.Format)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", cfg.Format)
+	}
 	return nil
 }
 
@@ -115,6 +215,33 @@ func setDefaults(cfg *ProducerConfig) {
 	if cfg.BatchSize == 0 {
 		cfg.BatchSize = 100
 	}
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = CompressionSnappy
+	}
+	if cfg.MaxMessageBytes == 0 {
+		cfg.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+	if cfg.Breaker.FailureThreshold == 0 {
+		cfg.Breaker.FailureThreshold = 5
+	}
+	if cfg.Breaker.CoolDown == 0 {
+		cfg.Breaker.CoolDown = 30 * time.Second
+	}
+	if cfg.Breaker.HalfOpenProbes == 0 {
+		cfg.Breaker.HalfOpenProbes = 1
+	}
+	if cfg.ErrorBuffer == 0 {
+		cfg.ErrorBuffer = 100
+	}
+	if cfg.KeyBatch.MaxMessages > 0 && cfg.KeyBatch.MaxDelay == 0 {
+		cfg.KeyBatch.MaxDelay = 10 * time.Millisecond
+	}
+	if cfg.Classifier == nil {
+		cfg.Classifier = DefaultErrorClassifier
+	}
 }
 
 // Publish публикует сообщение в Kafka с retry логикой
@@ -125,14 +252,38 @@ func setDefaults(cfg *ProducerConfig) {
 // - Structured logging для всех операций
 // - Метрики для мониторинга
 func (p *Producer) Publish(ctx context.Context, key string, value []byte) error {
+	return p.PublishMessage(ctx, Message{Key: key, Value: value})
+}
+
+// PublishTo публикует сообщение в указанный топик (см. Publish)
+func (p *Producer) PublishTo(ctx context.Context, topic, key string, value []byte) error {
+	return p.PublishMessage(ctx, Message{Topic: topic, Key: key, Value: value})
+}
+
+// PublishMessage публикует одно сообщение с retry логикой (см. Publish).
+// Если msg.Time задан, он используется как timestamp сообщения в Kafka,
+// иначе сообщение штампуется временем публикации. Пустой msg.Topic выбирается TopicRouter'ом.
+func (p *Producer) PublishMessage(ctx context.Context, msg Message) error {
 	if p.closed.Load() {
-		return errors.New("producer is closed")
+		return ErrProducerClosed
+	}
+	msg, err := p.prepare(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if err := p.checkSize(msg); err != nil {
+		return err
+	}
+	if p.config.Async {
+		// Результат доставки — в Completion и Errors()
+		return p.enqueue(ctx, msg.toKafka())
 	}
 
 	start := time.Now()
 	logger := p.logger.With().
-		Str("key", key).
-		Int("value_size", len(value)).
+		Str("topic", msg.Topic).
+		Str("key", msg.Key).
+		Int("value_size", len(msg.Value)).
 		Logger()
 
 	logger.Debug().Msg("publishing message")
@@ -163,8 +314,14 @@ func (p *Producer) Publish(ctx context.Context, key string, value []byte) error
 			}
 		}
 
+		// Открытый breaker — брокер недоступен, не тратим retry бюджет
+		if err := p.breaker.allow(); err != nil {
+			return p.rejected(1, lastErr)
+		}
+
 		// Attempt to publish
-		err := p.publishAttempt(ctx, key, value)
+		err := p.publishAttempt(ctx, msg)
+		opened := p.recordBrokerResult(err)
 		if err == nil {
 			duration := time.Since(start)
 			p.metrics.MessagesPublished.Add(1)
@@ -179,9 +336,12 @@ func (p *Producer) Publish(ctx context.Context, key string, value []byte) error
 		}
 
 		lastErr = err
+		if opened {
+			return p.rejected(1, lastErr)
+		}
 
 		// Проверяем, является ли ошибка retriable
-		if !isRetriableError(err) {
+		if !p.config.Classifier.Retriable(err) {
 			logger.Error().
 				Err(err).
 				Int("attempt", attempt+1).
@@ -208,14 +368,8 @@ func (p *Producer) Publish(ctx context.Context, key string, value []byte) error
 }
 
 // publishAttempt выполняет одну попытку публикации
-func (p *Producer) publishAttempt(ctx context.Context, key string, value []byte) error {
-	msg := kafkago.Message{
-		Key:   []byte(key),
-		Value: value,
-		Time:  time.Now(),
-	}
-
-	err := p.writer.WriteMessages(ctx, msg)
+func (p *Producer) publishAttempt(ctx context.Context, msg Message) error {
+	err := p.writer.WriteMessages(ctx, msg.toKafka())
 	if err != nil {
 		return fmt.Errorf("kafka write: %w", err)
 	}
@@ -223,76 +377,71 @@ func (p *Producer) publishAttempt(ctx context.Context, key string, value []byte)
 	return nil
 }
 
-// isRetriableError определяет, можно ли retry эту ошибку
-func isRetriableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// Context errors не retry
+// recordBrokerResult сообщает breaker'у исход записи и возвращает true, если он открылся.
+// Здоровью брокера вредят только retriable ошибки: отказ в конкретном сообщении
+// (message too large) значит, что брокер отвечает.
+func (p *Producer) recordBrokerResult(err error) bool {
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		p.breaker.cancel()
 		return false
 	}
-
-	// Kafka-специфичные ошибки
-	// Retriable: сетевые ошибки, temporary failures
-	// Non-retriable: invalid message, authorization errors
-
-	errStr := err.Error()
-
-	// Retriable errors
-	retriable := []string{
-		"connection refused",
-		"connection reset",
-		"broken pipe",
-		"timeout",
-		"temporary failure",
-		"leader not available",
-		"not controller",
-	}
-
-	for _, pattern := range retriable {
-		if contains(errStr, pattern) {
-			return true
-		}
-	}
-
-	// Non-retriable errors
-	nonRetriable := []string{
-		"invalid message",
-		"message too large",
-		"authorization failed",
-		"topic authorization failed",
-	}
-
-	for _, pattern := range nonRetriable {
-		if contains(errStr, pattern) {
-			return false
-		}
+	if !p.breaker.done(err != nil && p.config.Classifier.Retriable(err)) {
+		return false
 	}
 
-	// По умолчанию считаем ошибку retriable
+	p.metrics.CircuitOpens.Add(1)
+	p.logger.Warn().
+		Err(err).
+		Dur("cool_down", p.config.Breaker.CoolDown).
+		Msg("kafka circuit breaker opened")
 	return true
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) &&
-		(s[:len(substr)] == substr || s[len(s)-len(substr):] == substr ||
-			len(s) > len(substr)*2))
+// rejected завершает публикацию n сообщений, остановленную breaker'ом
+func (p *Producer) rejected(n int, lastErr error) error {
+	p.metrics.MessagesFailed.Add(int64(n))
+	p.metrics.CircuitRejected.Add(int64(n))
+	if lastErr != nil {
+		return fmt.Errorf("%w: %w", ErrCircuitOpen, lastErr)
+	}
+	return ErrCircuitOpen
 }
 
+// BreakerState возвращает состояние circuit breaker
+func (p *Producer) BreakerState() BreakerState { return p.breaker.State() }
+
 // PublishBatch публикует batch сообщений атомарно
 //
 // Если хотя бы одно сообщение не удалось опубликовать, вся операция считается неуспешной.
 // Retry применяется ко всему batch.
 func (p *Producer) PublishBatch(ctx context.Context, messages []Message) error {
 	if p.closed.Load() {
-		return errors.New("producer is closed")
+		return ErrProducerClosed
 	}
 
 	if len(messages) == 0 {
 		return nil
 	}
+	routed := make([]Message, len(messages))
+	for i, msg := range messages {
+		m, err := p.intercept(ctx, p.route(msg))
+		if err != nil {
+			p.metrics.MessagesFailed.Add(int64(len(messages)))
+			return err
+		}
+		routed[i] = m
+	}
+	// Batch атомарен: одно большое сообщение отклоняет весь batch
+	if err := p.checkSize(routed...); err != nil {
+		return err
+	}
+	if p.config.Async {
+		kafkaMessages := make([]kafkago.Message, len(routed))
+		for i, msg := range routed {
+			kafkaMessages[i] = msg.toKafka()
+		}
+		return p.enqueue(ctx, kafkaMessages...)
+	}
 
 	start := time.Now()
 	logger := p.logger.With().
@@ -326,17 +475,18 @@ func (p *Producer) PublishBatch(ctx context.Context, messages []Message) error {
 		}
 
 		// Convert to kafka messages
-		kafkaMessages := make([]kafkago.Message, len(messages))
-		for i, msg := range messages {
-			kafkaMessages[i] = kafkago.Message{
-				Key:   []byte(msg.Key),
-				Value: msg.Value,
-				Time:  time.Now(),
-			}
+		kafkaMessages := make([]kafkago.Message, len(routed))
+		for i, msg := range routed {
+			kafkaMessages[i] = msg.toKafka()
+		}
+
+		if err := p.breaker.allow(); err != nil {
+			return p.rejected(len(messages), lastErr)
 		}
 
 		// Attempt to publish batch
 		err := p.writer.WriteMessages(ctx, kafkaMessages...)
+		opened := p.recordBrokerResult(err)
 		if err == nil {
 			duration := time.Since(start)
 			p.metrics.MessagesPublished.Add(int64(len(messages)))
@@ -351,8 +501,11 @@ func (p *Producer) PublishBatch(ctx context.Context, messages []Message) error {
 		}
 
 		lastErr = err
+		if opened {
+			return p.rejected(len(messages), lastErr)
+		}
 
-		if !isRetriableError(err) {
+		if !p.config.Classifier.Retriable(err) {
 			logger.Error().
 				Err(err).
 				Int("attempt", attempt+1).
@@ -374,8 +527,34 @@ func (p *Producer) PublishBatch(ctx context.Context, messages []Message) error {
 
 // Message представляет сообщение для публикации
 type Message struct {
-	Key   string
-	Value []byte
+	Topic   string // Пустой — по TopicRouter или ProducerConfig.Topic
+	Key     string
+	Value   []byte
+	Time    time.Time // Timestamp сообщения в Kafka; zero — время публикации
+	Headers map[string]string
+}
+
+func (m Message) toKafka() kafkago.Message {
+	ts := m.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	var headers []kafkago.Header
+	if len(m.Headers) > 0 {
+		headers = make([]kafkago.Header, 0, len(m.Headers))
+		for k, v := range m.Headers {
+			headers = append(headers, kafkago.Header{Key: k, Value: []byte(v)})
+		}
+	}
+
+	return kafkago.Message{
+		Topic:   m.Topic,
+		Key:     []byte(m.Key),
+		Value:   m.Value,
+		Time:    ts,
+		Headers: headers,
+	}
 }
 
 // GetMetrics возвращает текущие метрики producer
@@ -410,20 +589,45 @@ func (p *Producer) calculateAvgPublishTime() time.Duration {
 // После вызова Close дальнейшие вызовы Publish будут возвращать ошибку.
 // Метод блокируется до завершения всех pending операций или до истечения 30 секунд.
 func (p *Producer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return p.Shutdown(ctx)
+}
+
+// Shutdown закрывает producer, дожидаясь отправки буферизованных сообщений (async режим),
+// но не дольше ctx. Если ctx истёк раньше, возвращает его ошибку: неотправленные сообщения теряются.
+func (p *Producer) Shutdown(ctx context.Context) error {
+	if p.batcher != nil && !p.closed.Load() {
+		if err := p.batcher.Flush(ctx); err != nil {
+			p.logger.Warn().Err(err).Msg("key batches flush failed")
+		}
+	}
 	if !p.closed.CompareAndSwap(false, true) {
-		return errors.New("producer already closed")
+		return fmt.Errorf("%w: already closed", ErrProducerClosed)
 	}
 
 	p.logger.Info().Msg("closing kafka producer")
 
-	// Даём время на flush pending messages
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Закрываем writer
-	if err := p.writer.Close(); err != nil {
-		p.logger.Error().Err(err).Msg("error closing kafka writer")
-		return fmt.Errorf("close writer: %w", err)
+	// writer.Close сбрасывает буфер и не принимает контекст, поэтому ждём его отдельно
+	done := make(chan error, 1)
+	go func() {
+		err := p.writer.Close()
+		// Close дожидается всех Completion, после него писать в канал некому
+		if p.errors != nil {
+			close(p.errors)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			p.logger.Error().Err(err).Msg("error closing kafka writer")
+			return fmt.Errorf("close writer: %w", err)
+		}
+	case <-ctx.Done():
+		p.logger.Warn().Err(ctx.Err()).Msg("kafka producer flush interrupted")
+		return fmt.Errorf("flush: %w", ctx.Err())
 	}
 
 	// Логируем финальные метрики
@@ -435,14 +639,17 @@ func (p *Producer) Close() error {
 		Dur("avg_publish_time", metrics.AvgPublishTime).
 		Msg("kafka producer closed")
 
-	<-ctx.Done()
 	return nil
 }
 
 // HealthCheck проверяет здоровье producer
 func (p *Producer) HealthCheck(ctx context.Context) error {
 	if p.closed.Load() {
-		return errors.New("producer is closed")
+		return ErrProducerClosed
+	}
+
+	if p.breaker.State() == BreakerOpen {
+		return ErrCircuitOpen
 	}
 
 	// Проверяем connectivity через stats
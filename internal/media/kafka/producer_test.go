@@ -2,11 +2,11 @@ package kafka
 
 import (
 	"context"
-	"errors"
 	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -81,6 +81,46 @@ func TestNewProducer_Validation(t *testing.T) {
 			},
 			wantErr: "write_timeout cannot be negative",
 		},
+		{
+			name: "avro without schema registry",
+			config: ProducerConfig{
+				Brokers: []string{"localhost:9092"},
+				Topic:   "test",
+				Format:  FormatAvro,
+				Logger:  zerolog.Nop(),
+			},
+			wantErr: "schema registry url is required",
+		},
+		{
+			name: "unknown format",
+			config: ProducerConfig{
+				Brokers: []string{"localhost:9092"},
+				Topic:   "test",
+				Format:  Format("xml"),
+				Logger:  zerolog.Nop(),
+			},
+			wantErr: "unknown format",
+		},
+		{
+			name: "unknown compression",
+			config: ProducerConfig{
+				Brokers:     []string{"localhost:9092"},
+				Topic:       "test",
+				Compression: Compression("brotli"),
+				Logger:      zerolog.Nop(),
+			},
+			wantErr: "unknown compression",
+		},
+		{
+			name: "invalid max message bytes",
+			config: ProducerConfig{
+				Brokers:         []string{"localhost:9092"},
+				Topic:           "test",
+				MaxMessageBytes: -2,
+				Logger:          zerolog.Nop(),
+			},
+			wantErr: "max_message_bytes",
+		},
 	}
 
 	for _, tt := range tests {
@@ -133,77 +173,6 @@ func TestProducer_CustomConfig(t *testing.T) {
 	assert.True(t, producer.config.Async)
 }
 
-func TestIsRetriableError(t *testing.T) {
-	tests := []struct {
-		name      string
-		err       error
-		retriable bool
-	}{
-		{
-			name:      "nil error",
-			err:       nil,
-			retriable: false,
-		},
-		{
-			name:      "context canceled",
-			err:       context.Canceled,
-			retriable: false,
-		},
-		{
-			name:      "context deadline exceeded",
-			err:       context.DeadlineExceeded,
-			retriable: false,
-		},
-		{
-			name:      "connection refused",
-			err:       errors.New("connection refused"),
-			retriable: true,
-		},
-		{
-			name:      "connection reset",
-			err:       errors.New("connection reset by peer"),
-			retriable: true,
-		},
-		{
-			name:      "timeout",
-			err:       errors.New("i/o timeout"),
-			retriable: true,
-		},
-		{
-			name:      "leader not available",
-			err:       errors.New("leader not available"),
-			retriable: true,
-		},
-		{
-			name:      "invalid message",
-			err:       errors.New("invalid message format"),
-			retriable: false,
-		},
-		{
-			name:      "message too large",
-			err:       errors.New("message too large"),
-			retriable: false,
-		},
-		{
-			name:      "authorization failed",
-			err:       errors.New("authorization failed"),
-			retriable: false,
-		},
-		{
-			name:      "unknown error (default retriable)",
-			err:       errors.New("some random error"),
-			retriable: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isRetriableError(tt.err)
-			assert.Equal(t, tt.retriable, result)
-		})
-	}
-}
-
 func TestProducer_GetMetrics(t *testing.T) {
 	cfg := ProducerConfig{
 		Brokers: []string{"localhost:9092"},
@@ -309,6 +278,36 @@ func TestProducer_PublishBatchAfterClose(t *testing.T) {
 	assert.Contains(t, err.Error(), "producer is closed")
 }
 
+func TestProducer_RejectsOversizedMessages(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:         []string{"localhost:9092"},
+		Topic:           "test",
+		Compression:     CompressionZstd,
+		MaxMessageBytes: 16,
+		Logger:          zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	assert.Equal(t, kafkago.Zstd, producer.writer.Compression)
+	assert.Equal(t, int64(16), producer.writer.BatchBytes)
+
+	// До брокера не доходит: ошибка сразу и без retry
+	err = producer.Publish(context.Background(), "key", make([]byte, 32))
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+	assert.False(t, DefaultErrorClassifier.Retriable(err))
+
+	err = producer.PublishBatch(context.Background(), []Message{
+		{Key: "small", Value: []byte("v")},
+		{Key: "big", Value: make([]byte, 32)},
+	})
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+
+	assert.Equal(t, int64(2), producer.metrics.OversizedRejected.Load())
+	assert.Equal(t, int64(3), producer.metrics.MessagesFailed.Load())
+	assert.Equal(t, int64(0), producer.metrics.RetriesTotal.Load())
+}
+
 func TestProducer_PublishBatch_EmptyMessages(t *testing.T) {
 	cfg := ProducerConfig{
 		Brokers: []string{"localhost:9092"},
@@ -392,6 +391,9 @@ func TestSetDefaults(t *testing.T) {
 	assert.Equal(t, 100*time.Millisecond, cfg.RetryBackoff)
 	assert.Equal(t, 10*time.Second, cfg.WriteTimeout)
 	assert.Equal(t, 100, cfg.BatchSize)
+	assert.Equal(t, FormatJSON, cfg.Format)
+	assert.Equal(t, CompressionSnappy, cfg.Compression)
+	assert.Equal(t, DefaultMaxMessageBytes, cfg.MaxMessageBytes)
 }
 
 func TestSetDefaults_DoesNotOverrideExisting(t *testing.T) {
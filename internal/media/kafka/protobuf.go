@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// envelopeProtoSchema — Protobuf схема конверта. Времена — unix микросекунды,
+// чтобы не тянуть google/protobuf/timestamp.proto как reference в Schema Registry.
+const envelopeProtoSchema = `syntax = "proto3";
+package media.platform.events;
+
+message Envelope {
+  string event_id = 1;
+  string event_type = 2;
+  int32 schema_version = 3;
+  string aggregate_id = 4;
+  int64 occurred_at_micros = 5;
+  int64 published_at_micros = 6;
+  string payload = 7;
+  int64 sequence = 8;
+}
+`
+
+// Номера полей envelopeProtoSchema
+const (
+	protoFieldEventID       protowire.Number = 1
+	protoFieldEventType     protowire.Number = 2
+	protoFieldSchemaVersion protowire.Number = 3
+	protoFieldAggregateID   protowire.Number = 4
+	protoFieldOccurredAt    protowire.Number = 5
+	protoFieldPublishedAt   protowire.Number = 6
+	protoFieldPayload       protowire.Number = 7
+	protoFieldSequence      protowire.Number = 8
+)
+
+// ProtobufSerializer кодирует конверт в Protobuf и обрамляет его в wire format Schema Registry
+type ProtobufSerializer struct {
+	registry *SchemaRegistryClient
+	strategy SubjectStrategy
+}
+
+func NewProtobufSerializer(registry *SchemaRegistryClient, strategy SubjectStrategy) *ProtobufSerializer {
+	return &ProtobufSerializer{registry: registry, strategy: strategy}
+}
+
+func (s *ProtobufSerializer) Format() Format { return FormatProtobuf }
+
+func (s *ProtobufSerializer) Serialize(ctx context.Context, topic string, env events.Envelope) ([]byte, error) {
+	id, err := s.registry.Register(ctx, s.strategy.Subject(topic, envelopeRecordName), "PROTOBUF", envelopeProtoSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	// После schema id Confluent ожидает индексы сообщения в файле схемы;
+	// Envelope — первое сообщение, для него пишется сокращённая форма [0].
+	data := append([]byte{0}, encodeEnvelopeProto(env)...)
+	return frame(id, data), nil
+}
+
+// encodeEnvelopeProto кодирует конверт; поля с zero value опускаются, как в proto3
+func encodeEnvelopeProto(env events.Envelope) []byte {
+	var buf []byte
+	buf = protoString(buf, protoFieldEventID, env.EventID)
+	buf = protoString(buf, protoFieldEventType, env.EventType)
+	buf = protoVarint(buf, protoFieldSchemaVersion, int64(env.SchemaVersion))
+	buf = protoString(buf, protoFieldAggregateID, env.AggregateID)
+	if !env.OccurredAt.IsZero() {
+		buf = protoVarint(buf, protoFieldOccurredAt, env.OccurredAt.UnixMicro())
+	}
+	if !env.PublishedAt.IsZero() {
+		buf = protoVarint(buf, protoFieldPublishedAt, env.PublishedAt.UnixMicro())
+	}
+	buf = protoString(buf, protoFieldPayload, string(env.Payload))
+	buf = protoVarint(buf, protoFieldSequence, env.Sequence)
+	return buf
+}
+
+func protoString(buf []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+	return protowire.AppendString(buf, s)
+}
+
+func protoVarint(buf []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = protowire.AppendTag(buf, num, protowire.VarintType)
+	return protowire.AppendVarint(buf, uint64(v))
+}
@@ -0,0 +1,93 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"time"
+)
+
+// attrTransactional — бит transactional в атрибутах record batch v2
+const attrTransactional = 1 << 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// producerBatch — заголовок идемпотентного batch'а: брокер по (producerID, epoch, sequence)
+// отбрасывает дубли повторов и связывает записи с транзакцией
+type producerBatch struct {
+	producerID    int64
+	producerEpoch int16
+	baseSequence  int32
+	transactional bool
+}
+
+// encodeRecordBatch кодирует сообщения в record batch v2 без сжатия — с префиксом размера,
+// как record set идёт в Produce запросе. kafka-go пишет producer id = -1 и не умеет транзакционные batch'и, поэтому формат собираем сами:
+// https://kafka.apache.org/documentation/#recordbatch
+func encodeRecordBatch(b producerBatch, msgs []Message, now time.Time) []byte {
+	first := timestampMillis(msgs[0].Time, now)
+	maxTS := first
+	var records []byte
+	for i, m := range msgs {
+		ts := timestampMillis(m.Time, now)
+		maxTS = max(maxTS, ts)
+		records = appendRecord(records, m, ts-first, int64(i))
+	}
+
+	var attributes int16
+	if b.transactional {
+		attributes |= attrTransactional
+	}
+
+	// Всё после crc — то, что crc покрывает
+	body := make([]byte, 0, 40+len(records))
+	body = binary.BigEndian.AppendUint16(body, uint16(attributes))
+	body = binary.BigEndian.AppendUint32(body, uint32(len(msgs)-1)) // last offset delta
+	body = binary.BigEndian.AppendUint64(body, uint64(first))
+	body = binary.BigEndian.AppendUint64(body, uint64(maxTS))
+	body = binary.BigEndian.AppendUint64(body, uint64(b.producerID))
+	body = binary.BigEndian.AppendUint16(body, uint16(b.producerEpoch))
+	body = binary.BigEndian.AppendUint32(body, uint32(b.baseSequence))
+	body = binary.BigEndian.AppendUint32(body, uint32(len(msgs)))
+	body = append(body, records...)
+
+	out := make([]byte, 0, 25+len(body))
+	out = binary.BigEndian.AppendUint32(out, uint32(8+4+4+1+4+len(body))) // размер record set
+	out = binary.BigEndian.AppendUint64(out, 0)                           // base offset, назначает брокер
+	out = binary.BigEndian.AppendUint32(out, uint32(4+1+4+len(body)))     // batch length
+	out = binary.BigEndian.AppendUint32(out, 0xFFFFFFFF)                  // partition leader epoch = -1
+	out = append(out, 2)                                                  // magic
+	out = binary.BigEndian.AppendUint32(out, crc32.Checksum(body, castagnoli))
+	return append(out, body...)
+}
+
+func appendRecord(dst []byte, m Message, timestampDelta, offsetDelta int64) []byte {
+	var rec []byte
+	rec = append(rec, 0) // attributes
+	rec = binary.AppendVarint(rec, timestampDelta)
+	rec = binary.AppendVarint(rec, offsetDelta)
+	rec = appendVarBytes(rec, []byte(m.Key), m.Key == "")
+	rec = appendVarBytes(rec, m.Value, m.Value == nil)
+	rec = binary.AppendVarint(rec, int64(len(m.Headers)))
+	for k, v := range m.Headers {
+		rec = appendVarBytes(rec, []byte(k), false)
+		rec = appendVarBytes(rec, []byte(v), false)
+	}
+
+	dst = binary.AppendVarint(dst, int64(len(rec)))
+	return append(dst, rec...)
+}
+
+func appendVarBytes(dst, b []byte, null bool) []byte {
+	if null {
+		return binary.AppendVarint(dst, -1)
+	}
+	dst = binary.AppendVarint(dst, int64(len(b)))
+	return append(dst, b...)
+}
+
+func timestampMillis(ts, now time.Time) int64 {
+	if ts.IsZero() {
+		ts = now
+	}
+	return ts.UnixMilli()
+}
@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRecordBatch_DecodesWithKafkaGo(t *testing.T) {
+	ts := time.UnixMilli(1_700_000_000_000)
+	data := encodeRecordBatch(producerBatch{
+		producerID:    42,
+		producerEpoch: 3,
+		baseSequence:  7,
+		transactional: true,
+	}, []Message{
+		{Key: "a", Value: []byte("1"), Time: ts, Headers: map[string]string{HeaderEventType: "QuotaReserved"}},
+		{Value: []byte("2"), Time: ts.Add(5 * time.Millisecond)},
+	}, time.Now())
+
+	// ReadFrom проверяет crc32c
+	var rs protocol.RecordSet
+	_, err := rs.ReadFrom(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, int8(2), rs.Version)
+	require.True(t, rs.Attributes.Transactional())
+
+	stream, ok := rs.Records.(*protocol.RecordStream)
+	require.True(t, ok)
+	require.Len(t, stream.Records, 1)
+	batch, ok := stream.Records[0].(*protocol.RecordBatch)
+	require.True(t, ok)
+	require.Equal(t, int64(42), batch.ProducerID)
+	require.Equal(t, int16(3), batch.ProducerEpoch)
+	require.Equal(t, int32(7), batch.BaseSequence)
+
+	first, err := rs.Records.ReadRecord()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), first.Offset)
+	require.Equal(t, ts, first.Time)
+	require.Equal(t, []protocol.Header{{Key: HeaderEventType, Value: []byte("QuotaReserved")}}, first.Headers)
+	key, err := protocol.ReadAll(first.Key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), key)
+
+	second, err := rs.Records.ReadRecord()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), second.Offset)
+	require.Equal(t, ts.Add(5*time.Millisecond), second.Time)
+	require.Nil(t, second.Key)
+	value, err := protocol.ReadAll(second.Value)
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), value)
+
+	_, err = rs.Records.ReadRecord()
+	require.ErrorIs(t, err, io.EOF)
+}
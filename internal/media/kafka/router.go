@@ -0,0 +1,31 @@
+package kafka
+
+// TopicRouter выбирает топик для сообщения без явного Message.Topic.
+// Пустой результат — топик по умолчанию (ProducerConfig.Topic).
+type TopicRouter func(msg Message) string
+
+// RouteByEventType маршрутизирует по заголовку event_type (его проставляет EnvelopeMessage).
+// Типы, которых нет в routes, идут в топик по умолчанию.
+func RouteByEventType(routes map[string]string) TopicRouter {
+	return func(msg Message) string {
+		return routes[msg.Headers[HeaderEventType]]
+	}
+}
+
+// route проставляет сообщению топик: явный, от роутера или по умолчанию
+func (p *Producer) route(msg Message) Message {
+	return routeMessage(msg, p.config.TopicRouter, p.config.Topic)
+}
+
+func routeMessage(msg Message, router TopicRouter, defaultTopic string) Message {
+	if msg.Topic != "" {
+		return msg
+	}
+	if router != nil {
+		msg.Topic = router(msg)
+	}
+	if msg.Topic == "" {
+		msg.Topic = defaultTopic
+	}
+	return msg
+}
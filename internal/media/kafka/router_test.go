@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+func TestProducer_Route(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "events.media",
+		TopicRouter: RouteByEventType(map[string]string{
+			"QuotaReserved": "events.quota",
+		}),
+		Logger: zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	quota := Message{Headers: map[string]string{HeaderEventType: "QuotaReserved"}}
+	require.Equal(t, "events.quota", producer.route(quota).Topic)
+
+	// Явный топик важнее роутера, неизвестный тип — в топик по умолчанию
+	quota.Topic = "events.dlq"
+	require.Equal(t, "events.dlq", producer.route(quota).Topic)
+	require.Equal(t, "events.media", producer.route(Message{Key: "k"}).Topic)
+
+	require.Equal(t, "events.dlq", quota.toKafka().Topic)
+}
+
+func TestProducer_EnvelopeMessageRouted(t *testing.T) {
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:     []string{"localhost:9092"},
+		Topic:       "events.media",
+		TopicRouter: RouteByEventType(map[string]string{"ProcessingStarted": "events.processing"}),
+		Logger:      zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	msg, err := producer.envelopeMessage(context.Background(), events.Envelope{
+		EventID:       "e-1",
+		EventType:     "ProcessingStarted",
+		SchemaVersion: 1,
+		AggregateID:   "a-1",
+		Payload:       []byte(`{}`),
+	}, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, "events.processing", msg.Topic)
+	require.Equal(t, "e-1", msg.Key)
+}
@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SubjectStrategy — стратегия именования subject в Schema Registry
+type SubjectStrategy string
+
+const (
+	TopicNameStrategy       SubjectStrategy = "topic"        // <topic>-value (default)
+	RecordNameStrategy      SubjectStrategy = "record"       // <record fullname>
+	TopicRecordNameStrategy SubjectStrategy = "topic_record" // <topic>-<record fullname>
+)
+
+// Subject возвращает имя subject для topic и полного имени записи/сообщения
+func (s SubjectStrategy) Subject(topic, recordName string) string {
+	switch s {
+	case RecordNameStrategy:
+		return recordName
+	case TopicRecordNameStrategy:
+		return topic + "-" + recordName
+	default:
+		return topic + "-value"
+	}
+}
+
+// SchemaRegistryConfig содержит настройки подключения к Confluent Schema Registry
+type SchemaRegistryConfig struct {
+	URL             string
+	Username        string
+	Password        string
+	SubjectStrategy SubjectStrategy // default: TopicNameStrategy
+	Timeout         time.Duration   // default: 5s
+	HTTPClient      *http.Client
+}
+
+// SchemaRegistryClient регистрирует схемы и кэширует их id
+type SchemaRegistryClient struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+
+	mu    sync.RWMutex
+	cache map[string]int // subject + "\x00" + schema -> id
+}
+
+func NewSchemaRegistryClient(cfg SchemaRegistryConfig) (*SchemaRegistryClient, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("schema registry url is empty")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	return &SchemaRegistryClient{
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		http:     client,
+		cache:    make(map[string]int),
+	}, nil
+}
+
+// Register регистрирует схему в subject (идемпотентно на стороне registry) и возвращает её id.
+// schemaType: "AVRO" или "PROTOBUF".
+func (c *SchemaRegistryClient) Register(ctx context.Context, subject, schemaType, schema string) (int, error) {
+	key := subject + "\x00" + schema
+
+	c.mu.RLock()
+	id, ok := c.cache[key]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"schema": schema, "schemaType": schemaType})
+	if err != nil {
+		return 0, fmt.Errorf("marshal schema: %w", err)
+	}
+
+	url := c.baseURL + "/subjects/" + subject + "/versions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, fmt.Errorf("schema registry: register %s: status %d: %s", subject, resp.StatusCode, msg)
+	}
+
+	var out struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("schema registry: decode response: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cache[key] = out.ID
+	c.mu.Unlock()
+
+	return out.ID, nil
+}
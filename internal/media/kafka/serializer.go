@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// Format — формат сериализации конверта события в value сообщения
+type Format string
+
+const (
+	FormatJSON     Format = "json" // default
+	FormatAvro     Format = "avro"
+	FormatProtobuf Format = "protobuf"
+)
+
+// Serializer превращает конверт события в value Kafka сообщения
+type Serializer interface {
+	Format() Format
+	Serialize(ctx context.Context, topic string, env events.Envelope) ([]byte, error)
+}
+
+// JSONSerializer — формат по умолчанию, без Schema Registry
+type JSONSerializer struct{}
+
+func (JSONSerializer) Format() Format { return FormatJSON }
+
+func (JSONSerializer) Serialize(_ context.Context, _ string, env events.Envelope) ([]byte, error) {
+	return env.Marshal()
+}
+
+// newSerializer выбирает сериализатор по конфигу producer
+func newSerializer(cfg ProducerConfig) (Serializer, error) {
+	switch cfg.Format {
+	case "", FormatJSON:
+		return JSONSerializer{}, nil
+	case FormatAvro, FormatProtobuf:
+		registry, err := NewSchemaRegistryClient(cfg.SchemaRegistry)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Format == FormatAvro {
+			return NewAvroSerializer(registry, cfg.SchemaRegistry.SubjectStrategy), nil
+		}
+		return NewProtobufSerializer(registry, cfg.SchemaRegistry.SubjectStrategy), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", cfg.Format)
+	}
+}
+
+// confluentMagicByte — первый байт wire format Confluent Schema Registry
+const confluentMagicByte = 0
+
+// frame добавляет к данным заголовок Confluent: magic byte + 4 байта schema id (big endian)
+func frame(schemaID int, data []byte) []byte {
+	out := make([]byte, 5, 5+len(data))
+	out[0] = confluentMagicByte
+	binary.BigEndian.PutUint32(out[1:], uint32(schemaID))
+	return append(out, data...)
+}
+
+// Unframe разбирает wire format Confluent: возвращает schema id и данные после заголовка
+func Unframe(value []byte) (schemaID int, data []byte, err error) {
+	if len(value) < 5 || value[0] != confluentMagicByte {
+		return 0, nil, fmt.Errorf("not a schema registry framed message")
+	}
+	return int(binary.BigEndian.Uint32(value[1:5])), value[5:], nil
+}
@@ -0,0 +1,196 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+func testEnvelope() events.Envelope {
+	return events.Envelope{
+		EventID:       "11111111-1111-1111-1111-111111111111",
+		EventType:     "MediaCreated",
+		SchemaVersion: 1,
+		AggregateID:   "22222222-2222-2222-2222-222222222222",
+		OccurredAt:    time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC),
+		Sequence:      3,
+		Payload:       json.RawMessage(`{"type":"video"}`),
+	}
+}
+
+// fakeRegistry отвечает фиксированным id и считает запросы
+func fakeRegistry(t *testing.T, id int, calls *atomic.Int32, subjects chan<- string) *SchemaRegistryClient {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			Schema     string `json:"schema"`
+			SchemaType string `json:"schemaType"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotEmpty(t, req.Schema)
+		if subjects != nil {
+			subjects <- r.URL.Path
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": id})
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewSchemaRegistryClient(SchemaRegistryConfig{URL: srv.URL})
+	require.NoError(t, err)
+	return client
+}
+
+func TestSubjectStrategy(t *testing.T) {
+	assert.Equal(t, "events.media-value", TopicNameStrategy.Subject("events.media", envelopeRecordName))
+	assert.Equal(t, "events.media-value", SubjectStrategy("").Subject("events.media", envelopeRecordName))
+	assert.Equal(t, envelopeRecordName, RecordNameStrategy.Subject("events.media", envelopeRecordName))
+	assert.Equal(t, "events.media-"+envelopeRecordName, TopicRecordNameStrategy.Subject("events.media", envelopeRecordName))
+}
+
+func TestSchemaRegistryClient_CachesIDs(t *testing.T) {
+	var calls atomic.Int32
+	subjects := make(chan string, 1)
+	client := fakeRegistry(t, 7, &calls, subjects)
+
+	for range 3 {
+		id, err := client.Register(context.Background(), "events.media-value", "AVRO", envelopeAvroSchema)
+		require.NoError(t, err)
+		assert.Equal(t, 7, id)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, "/subjects/events.media-value/versions", <-subjects)
+}
+
+func TestSchemaRegistryClient_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error_code":409}`, http.StatusConflict)
+	}))
+	defer srv.Close()
+
+	client, err := NewSchemaRegistryClient(SchemaRegistryConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	_, err = client.Register(context.Background(), "s", "AVRO", envelopeAvroSchema)
+	require.ErrorContains(t, err, "status 409")
+}
+
+func TestAvroSerializer(t *testing.T) {
+	var calls atomic.Int32
+	s := NewAvroSerializer(fakeRegistry(t, 42, &calls, nil), TopicNameStrategy)
+
+	env := testEnvelope()
+	value, err := s.Serialize(context.Background(), "events.media", env)
+	require.NoError(t, err)
+
+	id, data, err := Unframe(value)
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+
+	// Разбираем поля в порядке схемы
+	readString := func() string {
+		n, k := binary.Varint(data)
+		require.Positive(t, k)
+		s := string(data[k : k+int(n)])
+		data = data[k+int(n):]
+		return s
+	}
+	readLong := func() int64 {
+		v, k := binary.Varint(data)
+		require.Positive(t, k)
+		data = data[k:]
+		return v
+	}
+
+	assert.Equal(t, env.EventID, readString())
+	assert.Equal(t, env.EventType, readString())
+	assert.Equal(t, int64(1), readLong())
+	assert.Equal(t, env.AggregateID, readString())
+	assert.Equal(t, env.OccurredAt.UnixMicro(), readLong())
+	assert.Equal(t, int64(0), readLong()) // published_at = null
+	assert.Equal(t, `{"type":"video"}`, readString())
+	assert.Equal(t, env.Sequence, readLong())
+	assert.Empty(t, data)
+}
+
+func TestProtobufSerializer(t *testing.T) {
+	var calls atomic.Int32
+	s := NewProtobufSerializer(fakeRegistry(t, 5, &calls, nil), TopicNameStrategy)
+
+	env := testEnvelope()
+	env.PublishedAt = env.OccurredAt.Add(time.Second)
+	value, err := s.Serialize(context.Background(), "events.media", env)
+	require.NoError(t, err)
+
+	id, data, err := Unframe(value)
+	require.NoError(t, err)
+	assert.Equal(t, 5, id)
+	require.Equal(t, byte(0), data[0]) // message indexes [0]
+	data = data[1:]
+
+	got := map[protowire.Number]any{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		require.Positive(t, n)
+		data = data[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeString(data)
+			require.Positive(t, n)
+			got[num] = v
+			data = data[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			require.Positive(t, n)
+			got[num] = int64(v)
+			data = data[n:]
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+	}
+
+	assert.Equal(t, env.EventID, got[protoFieldEventID])
+	assert.Equal(t, env.EventType, got[protoFieldEventType])
+	assert.Equal(t, int64(1), got[protoFieldSchemaVersion])
+	assert.Equal(t, env.AggregateID, got[protoFieldAggregateID])
+	assert.Equal(t, env.OccurredAt.UnixMicro(), got[protoFieldOccurredAt])
+	assert.Equal(t, env.PublishedAt.UnixMicro(), got[protoFieldPublishedAt])
+	assert.Equal(t, `{"type":"video"}`, got[protoFieldPayload])
+	assert.Equal(t, env.Sequence, got[protoFieldSequence])
+}
+
+func TestEnvelopeMessage_JSONDefault(t *testing.T) {
+	env := testEnvelope()
+	ts := env.OccurredAt
+
+	msg, err := EnvelopeMessage(context.Background(), JSONSerializer{}, "events.media", env, ts)
+	require.NoError(t, err)
+
+	assert.Equal(t, env.EventID, msg.Key)
+	assert.Equal(t, ts, msg.Time)
+	assert.Equal(t, "MediaCreated", msg.Headers[HeaderEventType])
+	assert.Equal(t, "1", msg.Headers[HeaderSchemaVersion])
+	assert.Equal(t, "json", msg.Headers[HeaderContentFormat])
+	assert.Equal(t, env.AggregateID, msg.Headers[HeaderAggregateID])
+
+	decoded, err := events.UnmarshalEnvelope(msg.Value)
+	require.NoError(t, err)
+	assert.Equal(t, env.EventID, decoded.EventID)
+}
+
+func TestUnframe_RejectsPlainJSON(t *testing.T) {
+	_, _, err := Unframe([]byte(`{"event_id":"x"}`))
+	require.Error(t, err)
+}
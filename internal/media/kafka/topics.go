@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// TopicStrategy — схема именования топиков событий
+type TopicStrategy string
+
+const (
+	// TopicStatic — все события в базовый топик
+	TopicStatic TopicStrategy = "static"
+	// TopicPerTenant — события выделенных тенантов в <TenantPrefix><tenant>.<base>,
+	// остальных — в базовый топик
+	TopicPerTenant TopicStrategy = "tenant"
+	// TopicPerEventType — каждый тип события в свой топик <base>.<event_type>
+	// (MediaStatusChanged → events.media.media_status_changed)
+	TopicPerEventType TopicStrategy = "event_type"
+)
+
+// TopicNaming описывает, в какие топики producer раскладывает события. Одна конфигурация
+// задаёт маршрутизацию producer'а (Router) и список топиков для TopicAdmin и consumers (Topics),
+// поэтому вынос крупного тенанта на свой топик не требует изменений в коде.
+type TopicNaming struct {
+	Strategy TopicStrategy // default: TopicStatic
+	Base     string        // базовый топик, например events.media
+	// TenantPrefix — префикс топиков выделенных тенантов (default: "tenant.")
+	TenantPrefix string
+	// Tenants — выделенные тенанты (owner_id) для TopicPerTenant
+	Tenants []string
+}
+
+// Validate проверяет конфигурацию и подставляет значения по умолчанию
+func (n *TopicNaming) Validate() error {
+	if n.Base == "" {
+		return errors.New("base topic is empty")
+	}
+	if n.Strategy == "" {
+		n.Strategy = TopicStatic
+	}
+	switch n.Strategy {
+	case TopicStatic, TopicPerEventType:
+	case TopicPerTenant:
+		if len(n.Tenants) == 0 {
+			return errors.New("tenant strategy requires at least one dedicated tenant")
+		}
+		if n.TenantPrefix == "" {
+			n.TenantPrefix = "tenant."
+		}
+		for _, t := range n.Tenants {
+			if !validTopicPart(t) {
+				return fmt.Errorf("tenant %q cannot be used in a topic name", t)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown topic strategy %q", n.Strategy)
+	}
+	return nil
+}
+
+// Topic возвращает топик события типа eventType владельца tenant (пустой — без владельца)
+func (n TopicNaming) Topic(eventType, tenant string) string {
+	switch n.Strategy {
+	case TopicPerTenant:
+		if tenant != "" && slices.Contains(n.Tenants, tenant) {
+			return n.TenantPrefix + tenant + "." + n.Base
+		}
+	case TopicPerEventType:
+		if eventType != "" {
+			return n.Base + "." + snakeCase(eventType)
+		}
+	}
+	return n.Base
+}
+
+// Router — TopicRouter producer'а по заголовкам event_type и tenant_id
+// (их проставляет EnvelopeMessage)
+func (n TopicNaming) Router() TopicRouter {
+	return func(msg Message) string {
+		return n.Topic(msg.Headers[HeaderEventType], msg.Headers[HeaderTenantID])
+	}
+}
+
+// Topics возвращает все топики, в которые может писать producer с этой схемой:
+// их создаёт TopicAdmin и на них подписываются consumers. eventTypes нужны для TopicPerEventType.
+func (n TopicNaming) Topics(eventTypes ...string) []string {
+	topics := []string{n.Base}
+	switch n.Strategy {
+	case TopicPerTenant:
+		for _, t := range n.Tenants {
+			topics = append(topics, n.Topic("", t))
+		}
+	case TopicPerEventType:
+		for _, et := range eventTypes {
+			topics = append(topics, n.Topic(et, ""))
+		}
+	}
+	slices.Sort(topics)
+	return slices.Compact(topics)
+}
+
+// validTopicPart — допустимые в имени топика символы: [a-zA-Z0-9._-]
+func validTopicPart(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// snakeCase переводит имя типа события в часть имени топика: MediaStatusChanged → media_status_changed
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+func TestTopicNaming_Validate(t *testing.T) {
+	n := TopicNaming{Base: "events.media"}
+	require.NoError(t, n.Validate())
+	require.Equal(t, TopicStatic, n.Strategy)
+
+	n = TopicNaming{Base: "events.media", Strategy: TopicPerTenant, Tenants: []string{"acme"}}
+	require.NoError(t, n.Validate())
+	require.Equal(t, "tenant.", n.TenantPrefix)
+
+	for name, bad := range map[string]TopicNaming{
+		"no base":        {Strategy: TopicStatic},
+		"unknown":        {Base: "events.media", Strategy: "round_robin"},
+		"no tenants":     {Base: "events.media", Strategy: TopicPerTenant},
+		"invalid tenant": {Base: "events.media", Strategy: TopicPerTenant, Tenants: []string{"a/b"}},
+	} {
+		require.Error(t, bad.Validate(), name)
+	}
+}
+
+func TestTopicNaming_Topic(t *testing.T) {
+	static := TopicNaming{Base: "events.media", Strategy: TopicStatic}
+	require.Equal(t, "events.media", static.Topic("MediaCreated", "acme"))
+
+	tenant := TopicNaming{Base: "events.media", Strategy: TopicPerTenant, TenantPrefix: "tenant.", Tenants: []string{"acme"}}
+	require.Equal(t, "tenant.acme.events.media", tenant.Topic("MediaCreated", "acme"))
+	require.Equal(t, "events.media", tenant.Topic("MediaCreated", "other"))
+	require.Equal(t, "events.media", tenant.Topic("MediaCreated", ""))
+	require.Equal(t, []string{"events.media", "tenant.acme.events.media"}, tenant.Topics())
+
+	byType := TopicNaming{Base: "events.media", Strategy: TopicPerEventType}
+	require.Equal(t, "events.media.media_status_changed", byType.Topic("MediaStatusChanged", "acme"))
+	require.Equal(t, []string{"events.media", "events.media.media_created", "events.media.media_deleted"},
+		byType.Topics("MediaDeleted", "MediaCreated"))
+}
+
+func TestProducer_EnvelopeRoutedByTenant(t *testing.T) {
+	naming := TopicNaming{Base: "events.media", Strategy: TopicPerTenant, Tenants: []string{"acme"}}
+	require.NoError(t, naming.Validate())
+
+	producer, err := NewProducer(ProducerConfig{
+		Brokers:     []string{"localhost:9092"},
+		Topic:       naming.Base,
+		TopicRouter: naming.Router(),
+		Logger:      zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	defer producer.Close()
+
+	env := events.Envelope{
+		EventID:       "e-1",
+		EventType:     "MediaCreated",
+		SchemaVersion: 1,
+		AggregateID:   "a-1",
+		Payload:       []byte(`{"owner_id":"acme"}`),
+	}
+	msg, err := producer.envelopeMessage(context.Background(), env, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, "tenant.acme.events.media", msg.Topic)
+	require.Equal(t, "acme", msg.Headers[HeaderTenantID])
+
+	env.Payload = []byte(`{"media_id":"m"}`)
+	msg, err = producer.envelopeMessage(context.Background(), env, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, "events.media", msg.Topic)
+	require.NotContains(t, msg.Headers, HeaderTenantID)
+}
@@ -0,0 +1,590 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+var (
+	// ErrProducerFenced — transactional id захватил более новый экземпляр; этот TxProducer
+	// больше не может писать, его нужно закрыть
+	ErrProducerFenced = errors.New("kafka transactional producer is fenced")
+	// ErrTxnState — вызов не подходит к состоянию транзакции (Produce без BeginTxn и т.п.)
+	ErrTxnState = errors.New("invalid kafka transaction state")
+	// ErrTxnAbortRequired — операция в транзакции не удалась, зафиксировать её нельзя: нужен AbortTxn
+	ErrTxnAbortRequired = errors.New("kafka transaction must be aborted")
+)
+
+// TxProducerConfig содержит конфигурацию транзакционного producer
+type TxProducerConfig struct {
+	Brokers []string
+	// TransactionalID — стабильный id экземпляра (например, имя сервиса + номер партиции/реплики).
+	// Новый producer с тем же id отсекает старый и откатывает его незавершённую транзакцию.
+	TransactionalID    string
+	Topic              string        // Топик по умолчанию
+	TransactionTimeout time.Duration // Через сколько брокер откатит незавершённую транзакцию (default: 1m)
+	RequestTimeout     time.Duration // Timeout одного запроса к брокеру (default: 10s)
+	MaxRetries         int           // Повторы retriable ошибок на запрос (default: 3)
+	RetryBackoff       time.Duration // Задержка между повторами (default: 100ms)
+	// TopicRouter выбирает топик для сообщений без Message.Topic (default: всё в Topic)
+	TopicRouter TopicRouter
+	Logger      zerolog.Logger
+}
+
+// TxProducerMetrics содержит метрики транзакционного producer
+type TxProducerMetrics struct {
+	MessagesProduced atomic.Int64 // Записано в транзакциях (включая откаченные)
+	TxnCommitted     atomic.Int64
+	TxnAborted       atomic.Int64
+	RetriesTotal     atomic.Int64
+}
+
+// txClient — запросы к брокеру, которые нужны транзакциям; реализуется *kafkago.Client
+type txClient interface {
+	InitProducerID(ctx context.Context, req *kafkago.InitProducerIDRequest) (*kafkago.InitProducerIDResponse, error)
+	Metadata(ctx context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error)
+	AddPartitionsToTxn(ctx context.Context, req *kafkago.AddPartitionsToTxnRequest) (*kafkago.AddPartitionsToTxnResponse, error)
+	RawProduce(ctx context.Context, req *kafkago.RawProduceRequest) (*kafkago.ProduceResponse, error)
+	AddOffsetsToTxn(ctx context.Context, req *kafkago.AddOffsetsToTxnRequest) (*kafkago.AddOffsetsToTxnResponse, error)
+	TxnOffsetCommit(ctx context.Context, req *kafkago.TxnOffsetCommitRequest) (*kafkago.TxnOffsetCommitResponse, error)
+	EndTxn(ctx context.Context, req *kafkago.EndTxnRequest) (*kafkago.EndTxnResponse, error)
+}
+
+type txState int
+
+const (
+	txReady     txState = iota // транзакции нет, можно BeginTxn
+	txActive                   // транзакция открыта
+	txAbortOnly                // операция в транзакции не удалась, допустим только AbortTxn
+	txFenced                   // producer отсечён, допустим только Close
+	txClosed
+)
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// TxProducer — идемпотентный транзакционный producer для exactly-once циклов read-process-write:
+// сообщения и offset'ы входных сообщений фиксируются одной транзакцией (BeginTxn → Produce /
+// SendOffsets → CommitTxn) либо откатываются вместе (AbortTxn). Consumer'ы выходных топиков
+// должны читать с ReadCommitted, иначе увидят и откаченные сообщения.
+//
+// Writer kafka-go транзакций не поддерживает, поэтому producer сам ведёт producer id,
+// sequence номера партиций и протокол транзакций поверх kafkago.Client.
+// Методы безопасны для конкурентного вызова, но транзакция одна на экземпляр.
+type TxProducer struct {
+	client  txClient
+	logger  zerolog.Logger
+	config  TxProducerConfig
+	metrics *TxProducerMetrics
+	now     func() time.Time
+
+	mu         sync.Mutex
+	state      txState
+	producerID int
+	epoch      int
+	dirty      bool // в транзакции была неудачная запись: sequence номера могли разойтись с брокером
+
+	sequences  map[topicPartition]int32 // следующий sequence по партиции
+	inTxn      map[topicPartition]bool  // партиции, добавленные в текущую транзакцию
+	partitions map[string][]int         // партиции топиков из metadata
+	balancers  map[string]*kafkago.Hash // партиция по хэшу ключа
+}
+
+// NewTxProducer регистрирует TransactionalID у координатора транзакций (InitProducerID):
+// незавершённая транзакция прошлого экземпляра с тем же id откатывается, сам он отсекается.
+func NewTxProducer(ctx context.Context, cfg TxProducerConfig) (*TxProducer, error) {
+	if err := validateTxConfig(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	setTxDefaults(&cfg)
+
+	client := &kafkago.Client{
+		Addr:    kafkago.TCP(cfg.Brokers...),
+		Timeout: cfg.RequestTimeout,
+	}
+	return newTxProducer(ctx, client, cfg)
+}
+
+func newTxProducer(ctx context.Context, client txClient, cfg TxProducerConfig) (*TxProducer, error) {
+	p := &TxProducer{
+		client: client,
+		logger: cfg.Logger.With().
+			Str("component", "kafka_tx_producer").
+			Str("transactional_id", cfg.TransactionalID).
+			Logger(),
+		config:     cfg,
+		metrics:    &TxProducerMetrics{},
+		now:        time.Now,
+		partitions: make(map[string][]int),
+		balancers:  make(map[string]*kafkago.Hash),
+	}
+	if err := p.initProducerID(ctx); err != nil {
+		return nil, err
+	}
+
+	p.logger.Info().
+		Strs("brokers", cfg.Brokers).
+		Str("topic", cfg.Topic).
+		Dur("transaction_timeout", cfg.TransactionTimeout).
+		Int("producer_id", p.producerID).
+		Int("producer_epoch", p.epoch).
+		Msg("kafka transactional producer created")
+
+	return p, nil
+}
+
+func validateTxConfig(cfg *TxProducerConfig) error {
+	if len(cfg.Brokers) == 0 {
+		return errors.New("brokers list is empty")
+	}
+	if cfg.TransactionalID == "" {
+		return errors.New("transactional_id is empty")
+	}
+	if cfg.Topic == "" && cfg.TopicRouter == nil {
+		return errors.New("topic is empty")
+	}
+	if cfg.TransactionTimeout < 0 {
+		return errors.New("transaction_timeout cannot be negative")
+	}
+	if cfg.RequestTimeout < 0 {
+		return errors.New("request_timeout cannot be negative")
+	}
+	if cfg.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if cfg.RetryBackoff < 0 {
+		return errors.New("retry_backoff cannot be negative")
+	}
+	return nil
+}
+
+func setTxDefaults(cfg *TxProducerConfig) {
+	if cfg.TransactionTimeout == 0 {
+		cfg.TransactionTimeout = time.Minute
+	}
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = 10 * time.Second
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 100 * time.Millisecond
+	}
+}
+
+// initProducerID получает producer id и новый epoch; sequence номера начинаются заново.
+// Без текущего id координатор сам поднимает epoch для TransactionalID — так работает и со старыми брокерами.
+func (p *TxProducer) initProducerID(ctx context.Context) error {
+	var res *kafkago.InitProducerIDResponse
+	err := p.retry(ctx, func() error {
+		var err error
+		res, err = p.client.InitProducerID(ctx, &kafkago.InitProducerIDRequest{
+			TransactionalID:      p.config.TransactionalID,
+			TransactionTimeoutMs: int(p.config.TransactionTimeout.Milliseconds()),
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		})
+		if err != nil {
+			return err
+		}
+		return res.Error
+	})
+	if err != nil {
+		return p.fail(fmt.Errorf("init producer id: %w", err))
+	}
+
+	p.producerID = res.Producer.ProducerID
+	p.epoch = res.Producer.ProducerEpoch
+	p.sequences = make(map[topicPartition]int32)
+	p.dirty = false
+	return nil
+}
+
+// BeginTxn открывает транзакцию. Брокеру о ней станет известно при первой записи.
+func (p *TxProducer) BeginTxn() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkState(txReady); err != nil {
+		return err
+	}
+	p.state = txActive
+	p.inTxn = make(map[topicPartition]bool)
+	return nil
+}
+
+// Produce пишет сообщения в текущую транзакцию и ждёт подтверждения всех реплик.
+// Сообщения с одним ключом попадают в одну партицию. Ошибка переводит транзакцию
+// в состояние, когда её можно только откатить (ErrTxnAbortRequired на остальные вызовы).
+func (p *TxProducer) Produce(ctx context.Context, msgs ...Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkState(txActive); err != nil {
+		return err
+	}
+
+	batches := make(map[topicPartition][]Message)
+	var order []topicPartition
+	for _, msg := range msgs {
+		msg = routeMessage(msg, p.config.TopicRouter, p.config.Topic)
+		tp, err := p.partitionFor(ctx, msg)
+		if err != nil {
+			return p.fail(err)
+		}
+		if _, ok := batches[tp]; !ok {
+			order = append(order, tp)
+		}
+		batches[tp] = append(batches[tp], msg)
+	}
+
+	if err := p.addPartitions(ctx, order); err != nil {
+		return p.fail(err)
+	}
+	for _, tp := range order {
+		if err := p.produceBatch(ctx, tp, batches[tp]); err != nil {
+			p.dirty = true
+			return p.fail(err)
+		}
+	}
+	return nil
+}
+
+// PublishEnvelope пишет конверт JSON'ом в текущую транзакцию — как Producer.PublishEnvelope,
+// но запись станет видна только после CommitTxn
+func (p *TxProducer) PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error {
+	msg, err := EnvelopeMessage(ctx, JSONSerializer{}, p.config.Topic, env, ts)
+	if err != nil {
+		return fmt.Errorf("serialize envelope: %w", err)
+	}
+	return p.Produce(ctx, msg)
+}
+
+// SendOffsets добавляет в транзакцию offset'ы consumer group: они зафиксируются
+// только вместе с записанными сообщениями. offsets — следующий offset для чтения
+// (offset обработанного сообщения + 1) по топику и партиции.
+func (p *TxProducer) SendOffsets(ctx context.Context, groupID string, offsets map[string]map[int]int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkState(txActive); err != nil {
+		return err
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	err := p.retry(ctx, func() error {
+		res, err := p.client.AddOffsetsToTxn(ctx, &kafkago.AddOffsetsToTxnRequest{
+			TransactionalID: p.config.TransactionalID,
+			ProducerID:      p.producerID,
+			ProducerEpoch:   p.epoch,
+			GroupID:         groupID,
+		})
+		if err != nil {
+			return err
+		}
+		return res.Error
+	})
+	if err != nil {
+		return p.fail(fmt.Errorf("add offsets to txn: %w", err))
+	}
+
+	topics := make(map[string][]kafkago.TxnOffsetCommit, len(offsets))
+	for topic, parts := range offsets {
+		for partition, offset := range parts {
+			topics[topic] = append(topics[topic], kafkago.TxnOffsetCommit{Partition: partition, Offset: offset})
+		}
+	}
+
+	err = p.retry(ctx, func() error {
+		// Без generation и member id брокер не сверяет членство в группе:
+		// reader kafka-go их не раскрывает, а от зомби защищает fencing по epoch
+		res, err := p.client.TxnOffsetCommit(ctx, &kafkago.TxnOffsetCommitRequest{
+			TransactionalID: p.config.TransactionalID,
+			GroupID:         groupID,
+			ProducerID:      p.producerID,
+			ProducerEpoch:   p.epoch,
+			GenerationID:    -1,
+			Topics:          topics,
+		})
+		if err != nil {
+			return err
+		}
+		for topic, parts := range res.Topics {
+			for _, part := range parts {
+				if part.Error != nil {
+					return fmt.Errorf("%s[%d]: %w", topic, part.Partition, part.Error)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return p.fail(fmt.Errorf("txn offset commit: %w", err))
+	}
+	return nil
+}
+
+// CommitTxn фиксирует транзакцию: сообщения становятся видны ReadCommitted consumer'ам,
+// offset'ы из SendOffsets — закоммичены. При ошибке транзакцию нужно откатить AbortTxn.
+func (p *TxProducer) CommitTxn(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.checkState(txActive); err != nil {
+		return err
+	}
+	if err := p.endTxn(ctx, true); err != nil {
+		return p.fail(fmt.Errorf("commit txn: %w", err))
+	}
+
+	p.state = txReady
+	p.metrics.TxnCommitted.Add(1)
+	return nil
+}
+
+// AbortTxn откатывает транзакцию. После неудачной записи producer заново получает epoch,
+// чтобы сбросить sequence номера.
+func (p *TxProducer) AbortTxn(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state != txActive && p.state != txAbortOnly {
+		return p.checkState(txActive)
+	}
+
+	// Пустая транзакция до брокера не доходила — откатывать нечего
+	if len(p.inTxn) > 0 {
+		if err := p.endTxn(ctx, false); err != nil {
+			return p.fail(fmt.Errorf("abort txn: %w", err))
+		}
+	}
+	if p.dirty {
+		if err := p.initProducerID(ctx); err != nil {
+			return err
+		}
+	}
+
+	p.state = txReady
+	p.metrics.TxnAborted.Add(1)
+	return nil
+}
+
+func (p *TxProducer) endTxn(ctx context.Context, commit bool) error {
+	return p.retry(ctx, func() error {
+		res, err := p.client.EndTxn(ctx, &kafkago.EndTxnRequest{
+			TransactionalID: p.config.TransactionalID,
+			ProducerID:      p.producerID,
+			ProducerEpoch:   p.epoch,
+			Committed:       commit,
+		})
+		if err != nil {
+			return err
+		}
+		return res.Error
+	})
+}
+
+// addPartitions регистрирует новые для транзакции партиции у координатора
+func (p *TxProducer) addPartitions(ctx context.Context, tps []topicPartition) error {
+	topics := make(map[string][]kafkago.AddPartitionToTxn)
+	for _, tp := range tps {
+		if !p.inTxn[tp] {
+			topics[tp.topic] = append(topics[tp.topic], kafkago.AddPartitionToTxn{Partition: tp.partition})
+		}
+	}
+	if len(topics) == 0 {
+		return nil
+	}
+
+	err := p.retry(ctx, func() error {
+		res, err := p.client.AddPartitionsToTxn(ctx, &kafkago.AddPartitionsToTxnRequest{
+			TransactionalID: p.config.TransactionalID,
+			ProducerID:      p.producerID,
+			ProducerEpoch:   p.epoch,
+			Topics:          topics,
+		})
+		if err != nil {
+			return err
+		}
+		for topic, parts := range res.Topics {
+			for _, part := range parts {
+				if part.Error != nil {
+					return fmt.Errorf("%s[%d]: %w", topic, part.Partition, part.Error)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("add partitions to txn: %w", err)
+	}
+
+	for topic, parts := range topics {
+		for _, part := range parts {
+			p.inTxn[topicPartition{topic: topic, partition: part.Partition}] = true
+		}
+	}
+	return nil
+}
+
+// produceBatch пишет batch в партицию. Повтор идёт с тем же sequence:
+// если первая попытка дошла, брокер отбросит дубль.
+func (p *TxProducer) produceBatch(ctx context.Context, tp topicPartition, msgs []Message) error {
+	seq := p.sequences[tp]
+	data := encodeRecordBatch(producerBatch{
+		producerID:    int64(p.producerID),
+		producerEpoch: int16(p.epoch),
+		baseSequence:  seq,
+		transactional: true,
+	}, msgs, p.now())
+
+	err := p.retry(ctx, func() error {
+		res, err := p.client.RawProduce(ctx, &kafkago.RawProduceRequest{
+			Topic:           tp.topic,
+			Partition:       tp.partition,
+			RequiredAcks:    kafkago.RequireAll,
+			TransactionalID: p.config.TransactionalID,
+			RawRecords:      protocol.RawRecordSet{Reader: bytes.NewReader(data)},
+		})
+		if err != nil {
+			return err
+		}
+		if errors.Is(res.Error, kafkago.DuplicateSequenceNumber) {
+			return nil
+		}
+		return res.Error
+	})
+	if err != nil {
+		return fmt.Errorf("produce %s[%d]: %w", tp.topic, tp.partition, err)
+	}
+
+	p.sequences[tp] = seq + int32(len(msgs))
+	p.metrics.MessagesProduced.Add(int64(len(msgs)))
+	return nil
+}
+
+// partitionFor выбирает партицию по ключу сообщения
+func (p *TxProducer) partitionFor(ctx context.Context, msg Message) (topicPartition, error) {
+	parts, ok := p.partitions[msg.Topic]
+	if !ok {
+		res, err := p.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: []string{msg.Topic}})
+		if err != nil {
+			return topicPartition{}, fmt.Errorf("metadata %s: %w", msg.Topic, err)
+		}
+		for _, t := range res.Topics {
+			if t.Name != msg.Topic {
+				continue
+			}
+			if t.Error != nil {
+				return topicPartition{}, fmt.Errorf("metadata %s: %w", msg.Topic, t.Error)
+			}
+			for _, part := range t.Partitions {
+				parts = append(parts, part.ID)
+			}
+		}
+		if len(parts) == 0 {
+			return topicPartition{}, fmt.Errorf("metadata %s: no partitions", msg.Topic)
+		}
+		p.partitions[msg.Topic] = parts
+		p.balancers[msg.Topic] = &kafkago.Hash{}
+	}
+
+	partition := p.balancers[msg.Topic].Balance(kafkago.Message{Key: []byte(msg.Key)}, parts...)
+	return topicPartition{topic: msg.Topic, partition: partition}, nil
+}
+
+// retry повторяет запрос на retriable ошибках. Отсечение producer'а не повторяется.
+func (p *TxProducer) retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			p.metrics.RetriesTotal.Add(1)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			case <-time.After(p.config.RetryBackoff):
+			}
+		}
+
+		err = fn()
+		if err == nil || isFencedError(err) || !isRetriableError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// fail переводит producer в состояние после ошибки: отсечённый — навсегда,
+// открытая транзакция — только на откат
+func (p *TxProducer) fail(err error) error {
+	switch {
+	case isFencedError(err):
+		p.state = txFenced
+		p.logger.Error().Err(err).Msg("kafka transactional producer fenced")
+		return fmt.Errorf("%w: %v", ErrProducerFenced, err)
+	case p.state == txActive:
+		p.state = txAbortOnly
+	}
+	return err
+}
+
+func (p *TxProducer) checkState(want txState) error {
+	switch p.state {
+	case want:
+		return nil
+	case txFenced:
+		return ErrProducerFenced
+	case txAbortOnly:
+		return ErrTxnAbortRequired
+	case txClosed:
+		return errors.New("producer is closed")
+	case txActive:
+		return fmt.Errorf("%w: transaction already started", ErrTxnState)
+	default:
+		return fmt.Errorf("%w: no transaction in progress", ErrTxnState)
+	}
+}
+
+func isFencedError(err error) bool {
+	return errors.Is(err, kafkago.ProducerFenced) || errors.Is(err, kafkago.InvalidProducerEpoch)
+}
+
+// Metrics возвращает метрики producer
+func (p *TxProducer) Metrics() *TxProducerMetrics { return p.metrics }
+
+// Close закрывает producer. Открытую транзакцию брокер откатит по TransactionTimeout
+// или при старте нового экземпляра с тем же TransactionalID.
+func (p *TxProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state == txActive || p.state == txAbortOnly {
+		p.logger.Warn().Msg("kafka transactional producer closed with open transaction")
+	}
+	p.state = txClosed
+
+	p.logger.Info().
+		Int64("messages_produced", p.metrics.MessagesProduced.Load()).
+		Int64("txn_committed", p.metrics.TxnCommitted.Load()).
+		Int64("txn_aborted", p.metrics.TxnAborted.Load()).
+		Msg("kafka transactional producer closed")
+	return nil
+}
+
+var _ txClient = (*kafkago.Client)(nil)
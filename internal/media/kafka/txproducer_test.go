@@ -0,0 +1,245 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+type producedBatch struct {
+	topic     string
+	partition int
+	batch     *protocol.RecordBatch
+}
+
+// fakeTxClient — координатор транзакций и брокер в памяти
+type fakeTxClient struct {
+	t *testing.T
+
+	epoch      int
+	added      []topicPartition
+	produced   []producedBatch
+	offsets    map[string][]kafkago.TxnOffsetCommit
+	ended      []bool // Committed по каждому EndTxn
+	produceErr []error
+}
+
+func newFakeTxClient(t *testing.T) *fakeTxClient {
+	return &fakeTxClient{t: t, offsets: make(map[string][]kafkago.TxnOffsetCommit)}
+}
+
+func (f *fakeTxClient) InitProducerID(_ context.Context, req *kafkago.InitProducerIDRequest) (*kafkago.InitProducerIDResponse, error) {
+	require.Equal(f.t, "quota-0", req.TransactionalID)
+	f.epoch++
+	return &kafkago.InitProducerIDResponse{
+		Producer: &kafkago.ProducerSession{ProducerID: 100, ProducerEpoch: f.epoch},
+	}, nil
+}
+
+func (f *fakeTxClient) Metadata(_ context.Context, req *kafkago.MetadataRequest) (*kafkago.MetadataResponse, error) {
+	return &kafkago.MetadataResponse{Topics: []kafkago.Topic{{
+		Name:       req.Topics[0],
+		Partitions: []kafkago.Partition{{ID: 0}, {ID: 1}, {ID: 2}},
+	}}}, nil
+}
+
+func (f *fakeTxClient) AddPartitionsToTxn(_ context.Context, req *kafkago.AddPartitionsToTxnRequest) (*kafkago.AddPartitionsToTxnResponse, error) {
+	for topic, parts := range req.Topics {
+		for _, p := range parts {
+			f.added = append(f.added, topicPartition{topic: topic, partition: p.Partition})
+		}
+	}
+	return &kafkago.AddPartitionsToTxnResponse{}, nil
+}
+
+func (f *fakeTxClient) RawProduce(_ context.Context, req *kafkago.RawProduceRequest) (*kafkago.ProduceResponse, error) {
+	require.Equal(f.t, "quota-0", req.TransactionalID)
+	require.Equal(f.t, kafkago.RequireAll, req.RequiredAcks)
+
+	if len(f.produceErr) > 0 {
+		err := f.produceErr[0]
+		f.produceErr = f.produceErr[1:]
+		if err != nil {
+			return &kafkago.ProduceResponse{Error: err}, nil
+		}
+	}
+
+	var rs protocol.RecordSet
+	_, err := rs.ReadFrom(req.RawRecords.Reader)
+	require.NoError(f.t, err)
+	batch := rs.Records.(*protocol.RecordStream).Records[0].(*protocol.RecordBatch)
+	f.produced = append(f.produced, producedBatch{topic: req.Topic, partition: req.Partition, batch: batch})
+	return &kafkago.ProduceResponse{}, nil
+}
+
+func (f *fakeTxClient) AddOffsetsToTxn(context.Context, *kafkago.AddOffsetsToTxnRequest) (*kafkago.AddOffsetsToTxnResponse, error) {
+	return &kafkago.AddOffsetsToTxnResponse{}, nil
+}
+
+func (f *fakeTxClient) TxnOffsetCommit(_ context.Context, req *kafkago.TxnOffsetCommitRequest) (*kafkago.TxnOffsetCommitResponse, error) {
+	f.offsets[req.GroupID] = append(f.offsets[req.GroupID], req.Topics["in"]...)
+	return &kafkago.TxnOffsetCommitResponse{}, nil
+}
+
+func (f *fakeTxClient) EndTxn(_ context.Context, req *kafkago.EndTxnRequest) (*kafkago.EndTxnResponse, error) {
+	if req.ProducerEpoch != f.epoch {
+		return &kafkago.EndTxnResponse{Error: kafkago.ProducerFenced}, nil
+	}
+	f.ended = append(f.ended, req.Committed)
+	return &kafkago.EndTxnResponse{}, nil
+}
+
+func newTestTxProducer(t *testing.T, client *fakeTxClient) *TxProducer {
+	t.Helper()
+	cfg := TxProducerConfig{
+		Brokers:         []string{"localhost:9092"},
+		TransactionalID: "quota-0",
+		Topic:           "out",
+		RetryBackoff:    1,
+		Logger:          zerolog.Nop(),
+	}
+	require.NoError(t, validateTxConfig(&cfg))
+	setTxDefaults(&cfg)
+
+	p, err := newTxProducer(context.Background(), client, cfg)
+	require.NoError(t, err)
+	return p
+}
+
+func TestTxProducer_CommitFlow(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeTxClient(t)
+	p := newTestTxProducer(t, client)
+
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.Produce(ctx, Message{Key: "user-1", Value: []byte("a")}))
+	require.NoError(t, p.Produce(ctx, Message{Key: "user-1", Value: []byte("b")}, Message{Key: "user-1", Value: []byte("c")}))
+	require.NoError(t, p.SendOffsets(ctx, "quota", map[string]map[int]int64{"in": {0: 11}}))
+	require.NoError(t, p.CommitTxn(ctx))
+
+	// Партиция регистрируется в транзакции один раз, sequence растёт на размер batch'а
+	require.Len(t, client.added, 1)
+	require.Len(t, client.produced, 2)
+	require.Equal(t, client.added[0].partition, client.produced[0].partition)
+	for i, want := range []int32{0, 1} {
+		b := client.produced[i].batch
+		require.Equal(t, "out", client.produced[i].topic)
+		require.Equal(t, int64(100), b.ProducerID)
+		require.Equal(t, int16(1), b.ProducerEpoch)
+		require.Equal(t, want, b.BaseSequence)
+		require.True(t, b.Attributes.Transactional())
+	}
+
+	require.Equal(t, []kafkago.TxnOffsetCommit{{Partition: 0, Offset: 11}}, client.offsets["quota"])
+	require.Equal(t, []bool{true}, client.ended)
+	require.Equal(t, int64(3), p.Metrics().MessagesProduced.Load())
+	require.Equal(t, int64(1), p.Metrics().TxnCommitted.Load())
+
+	// В следующей транзакции партицию снова нужно добавить
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.Produce(ctx, Message{Key: "user-1", Value: []byte("d")}))
+	require.Len(t, client.added, 2)
+	require.Equal(t, int32(3), client.produced[2].batch.BaseSequence)
+}
+
+func TestTxProducer_StateErrors(t *testing.T) {
+	ctx := context.Background()
+	p := newTestTxProducer(t, newFakeTxClient(t))
+
+	require.ErrorIs(t, p.Produce(ctx, Message{Key: "k"}), ErrTxnState)
+	require.ErrorIs(t, p.CommitTxn(ctx), ErrTxnState)
+	require.ErrorIs(t, p.AbortTxn(ctx), ErrTxnState)
+
+	require.NoError(t, p.BeginTxn())
+	require.ErrorIs(t, p.BeginTxn(), ErrTxnState)
+
+	// Пустая транзакция откатывается без запросов к брокеру
+	require.NoError(t, p.AbortTxn(ctx))
+	require.NoError(t, p.Close())
+	require.Error(t, p.BeginTxn())
+}
+
+func TestTxProducer_RetriesProduceWithSameSequence(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeTxClient(t)
+	client.produceErr = []error{kafkago.NotLeaderForPartition}
+	p := newTestTxProducer(t, client)
+
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.Produce(ctx, Message{Key: "k", Value: []byte("v")}))
+	require.Len(t, client.produced, 1)
+	require.Equal(t, int32(0), client.produced[0].batch.BaseSequence)
+	require.Equal(t, int64(1), p.Metrics().RetriesTotal.Load())
+}
+
+func TestTxProducer_FailedProduceRequiresAbort(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeTxClient(t)
+	p := newTestTxProducer(t, client)
+
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.Produce(ctx, Message{Key: "k", Value: []byte("v")}))
+
+	client.produceErr = []error{kafkago.MessageSizeTooLarge}
+	require.ErrorIs(t, p.Produce(ctx, Message{Key: "k", Value: []byte("big")}), kafkago.MessageSizeTooLarge)
+	require.ErrorIs(t, p.CommitTxn(ctx), ErrTxnAbortRequired)
+
+	// Откат после неудачной записи берёт новый epoch и сбрасывает sequence
+	require.NoError(t, p.AbortTxn(ctx))
+	require.Equal(t, []bool{false}, client.ended)
+	require.Equal(t, 2, client.epoch)
+
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.Produce(ctx, Message{Key: "k", Value: []byte("v")}))
+	last := client.produced[len(client.produced)-1].batch
+	require.Equal(t, int16(2), last.ProducerEpoch)
+	require.Equal(t, int32(0), last.BaseSequence)
+	require.NoError(t, p.CommitTxn(ctx))
+}
+
+func TestTxProducer_Fenced(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeTxClient(t)
+	p := newTestTxProducer(t, client)
+
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.Produce(ctx, Message{Key: "k", Value: []byte("v")}))
+
+	// Новый экземпляр с тем же transactional id
+	_ = newTestTxProducer(t, client)
+
+	require.ErrorIs(t, p.CommitTxn(ctx), ErrProducerFenced)
+	require.ErrorIs(t, p.AbortTxn(ctx), ErrProducerFenced)
+	require.ErrorIs(t, p.BeginTxn(), ErrProducerFenced)
+}
+
+func TestTxProducer_PublishEnvelope(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeTxClient(t)
+	p := newTestTxProducer(t, client)
+
+	env := events.Envelope{EventID: "e-1", EventType: "QuotaUsageChanged", SchemaVersion: 1, Payload: []byte(`{}`)}
+	require.ErrorIs(t, p.PublishEnvelope(ctx, env, time.Time{}), ErrTxnState)
+
+	require.NoError(t, p.BeginTxn())
+	require.NoError(t, p.PublishEnvelope(ctx, env, time.Time{}))
+	require.NoError(t, p.CommitTxn(ctx))
+
+	require.Len(t, client.produced, 1)
+	require.Equal(t, "out", client.produced[0].topic)
+	rec, err := client.produced[0].batch.ReadRecord()
+	require.NoError(t, err)
+	headers := make(map[string]string)
+	for _, h := range rec.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	require.Equal(t, "QuotaUsageChanged", headers[HeaderEventType])
+	require.Equal(t, string(FormatJSON), headers[HeaderContentFormat])
+}
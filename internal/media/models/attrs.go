@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Tags — метки медиа; в Postgres хранятся как jsonb массив
+type Tags []string
+
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		t = Tags{}
+	}
+	return json.Marshal(t)
+}
+
+func (t *Tags) Scan(src any) error {
+	return scanJSON(src, t)
+}
+
+// Metadata — произвольные строковые атрибуты медиа; в Postgres хранятся как jsonb объект
+type Metadata map[string]string
+
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		m = Metadata{}
+	}
+	return json.Marshal(m)
+}
+
+func (m *Metadata) Scan(src any) error {
+	return scanJSON(src, m)
+}
+
+func scanJSON(src, dst any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("unsupported type %T for json column", src)
+	}
+}
+
+// MediaAttrs — описательные поля, которые клиент задаёт при создании медиа
+type MediaAttrs struct {
+	Title    string
+	Tags     Tags
+	Metadata Metadata
+}
+
+// MediaPatch — частичное обновление медиа: nil поле не меняется
+type MediaPatch struct {
+	Source   *string
+	Title    *string
+	Tags     *Tags
+	Metadata *Metadata
+	Content  *Content
+	// Schedule заменяет расписание целиком: nil поле внутри снимает ограничение
+	Schedule   *Schedule
+	Visibility *Visibility
+}
+
+// IsEmpty — в патче нет ни одного поля
+func (p MediaPatch) IsEmpty() bool {
+	return p.Source == nil && p.Title == nil && p.Tags == nil && p.Metadata == nil && p.Content == nil &&
+		p.Schedule == nil && p.Visibility == nil
+}
+
+// Apply применяет патч к m (для in-memory хранилищ)
+func (p MediaPatch) Apply(m *Media) {
+	if p.Source != nil {
+		m.Source = *p.Source
+	}
+	if p.Title != nil {
+		m.Title = *p.Title
+	}
+	if p.Tags != nil {
+		m.Tags = append(Tags(nil), (*p.Tags)...)
+	}
+	if p.Metadata != nil {
+		md := make(Metadata, len(*p.Metadata))
+		for k, v := range *p.Metadata {
+			md[k] = v
+		}
+		m.Metadata = md
+	}
+	if p.Content != nil {
+		m.Checksum = p.Content.Checksum
+		m.Size = p.Content.Size
+		m.ContentType = p.Content.ContentType
+	}
+	if p.Schedule != nil {
+		m.PublishAt = cloneTime(p.Schedule.PublishAt)
+		m.ExpiresAt = cloneTime(p.Schedule.ExpiresAt)
+	}
+	if p.Visibility != nil {
+		m.Visibility = *p.Visibility
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxCollectionItems — сколько медиа помещается в одну коллекцию
+const MaxCollectionItems = 1000
+
+// Collection — упорядоченная подборка медиа владельца (плейлист, альбом).
+// Коллекция видна только владельцу; медиа в ней могут быть и чужими, если их видно вызывающему.
+type Collection struct {
+	ID          uuid.UUID `db:"id"`
+	OwnerID     uuid.UUID `db:"owner_id"`
+	Title       string    `db:"title"`
+	Description string    `db:"description"`
+	ItemCount   int       `db:"item_count"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// CollectionItem — медиа в коллекции. Position — место с нуля, без пропусков.
+type CollectionItem struct {
+	CollectionID uuid.UUID `db:"collection_id"`
+	MediaID      uuid.UUID `db:"media_id"`
+	Position     int       `db:"position"`
+	AddedAt      time.Time `db:"added_at"`
+}
+
+// CollectionPatch — изменение атрибутов коллекции; nil поля не меняются
+type CollectionPatch struct {
+	Title       *string
+	Description *string
+}
+
+// IsEmpty — патч ничего не меняет
+func (p CollectionPatch) IsEmpty() bool {
+	return p.Title == nil && p.Description == nil
+}
+
+// Apply применяет патч к c
+func (p CollectionPatch) Apply(c *Collection) {
+	if p.Title != nil {
+		c.Title = *p.Title
+	}
+	if p.Description != nil {
+		c.Description = *p.Description
+	}
+}
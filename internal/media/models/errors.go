@@ -6,4 +6,10 @@ var (
 	ErrNotFound        = errors.New("not found")
 	ErrConflict        = errors.New("conflict")
 	ErrInvalidArgument = errors.New("invalid arguments")
+	// ErrPreconditionFailed — медиа изменилось после того, как клиент его прочитал (If-Match)
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrForbidden — вызывающий видит медиа, но права на операцию у него нет (ACL)
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnavailable — хранилище не справилось с временной ошибкой за все повторы
+	ErrUnavailable = errors.New("storage temporarily unavailable")
 )
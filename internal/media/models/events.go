@@ -14,20 +14,34 @@ type DomainEvent interface {
 	OccurredAt() time.Time
 }
 
+// Sequenced — событие, которое несёт в payload свой номер в потоке агрегата.
+// Номер назначает outbox при записи (см. events.Envelope.Sequence).
+type Sequenced interface {
+	SetSequence(seq int64)
+}
+
 type MediaStatusChanged struct {
 	eventID    uuid.UUID
 	mediaID    uuid.UUID
+	ownerID    uuid.UUID
 	from       Status
 	to         Status
+	actor      string
+	reason     string
+	visibility Visibility
+	sequence   int64
 	occurredAt time.Time
 }
 
-func NewMediaStatusChanged(mediaID uuid.UUID, from, to Status) *MediaStatusChanged {
+func NewMediaStatusChanged(mediaID, ownerID uuid.UUID, from, to Status, actor, reason string) *MediaStatusChanged {
 	return &MediaStatusChanged{
 		eventID:    uuid.New(),
 		mediaID:    mediaID,
+		ownerID:    ownerID,
 		from:       from,
 		to:         to,
+		actor:      actor,
+		reason:     reason,
 		occurredAt: time.Now(),
 	}
 }
@@ -38,23 +52,715 @@ func (e *MediaStatusChanged) EventType() string      { return "MediaStatusChange
 func (e *MediaStatusChanged) AggregateID() uuid.UUID { return e.mediaID }
 func (e *MediaStatusChanged) OccurredAt() time.Time  { return e.occurredAt }
 
+// OwnerID — владелец медиа, чтобы потребители могли атрибутировать событие
+func (e *MediaStatusChanged) OwnerID() uuid.UUID { return e.ownerID }
+
 // Геттеры для payload
 func (e *MediaStatusChanged) From() Status { return e.from }
 func (e *MediaStatusChanged) To() Status   { return e.to }
 
+// Actor и Reason — аудит: кто и почему сменил статус
+func (e *MediaStatusChanged) Actor() string  { return e.actor }
+func (e *MediaStatusChanged) Reason() string { return e.reason }
+
+// WithVisibility дописывает в событие видимость медиа после перехода (EffectiveVisibility):
+// publish и CDN по ней решают, отдавать ли медиа
+func (e *MediaStatusChanged) WithVisibility(v Visibility) *MediaStatusChanged {
+	e.visibility = v
+	return e
+}
+
+// Visibility — видимость медиа после перехода; пустая, если не известна
+func (e *MediaStatusChanged) Visibility() Visibility { return e.visibility }
+
+// Sequence — номер события в потоке медиа; 0, пока событие не записано в outbox
+func (e *MediaStatusChanged) Sequence() int64 { return e.sequence }
+
+// SetSequence — см. Sequenced
+func (e *MediaStatusChanged) SetSequence(seq int64) { e.sequence = seq }
+
 // Кастомная JSON сериализация
 func (e *MediaStatusChanged) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID  `json:"event_id"`
+		MediaID    uuid.UUID  `json:"media_id"`
+		OwnerID    uuid.UUID  `json:"owner_id,omitzero"`
+		From       Status     `json:"from"`
+		To         Status     `json:"to"`
+		Actor      string     `json:"actor,omitempty"`
+		Reason     string     `json:"reason,omitempty"`
+		Visibility Visibility `json:"visibility,omitempty"`
+		Sequence   int64      `json:"sequence,omitempty"`
+		OccurredAt time.Time  `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		From:       e.from,
+		To:         e.to,
+		Actor:      e.actor,
+		Reason:     e.reason,
+		Visibility: e.visibility,
+		Sequence:   e.sequence,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+type MediaCreated struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	mediaType  MediaType
+	source     string
+	status     Status
+	occurredAt time.Time
+}
+
+func NewMediaCreated(m *Media) *MediaCreated {
+	return &MediaCreated{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		mediaType:  m.Type,
+		source:     m.Source,
+		status:     m.Status,
+		occurredAt: m.CreatedAt,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaCreated) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaCreated) EventType() string      { return "MediaCreated" }
+func (e *MediaCreated) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaCreated) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaCreated) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID `json:"event_id"`
+		MediaID    uuid.UUID `json:"media_id"`
+		OwnerID    uuid.UUID `json:"owner_id,omitzero"`
+		Type       MediaType `json:"type"`
+		Source     string    `json:"source"`
+		Status     Status    `json:"status"`
+		OccurredAt time.Time `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		Type:       e.mediaType,
+		Source:     e.source,
+		Status:     e.status,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// DeleteReason — почему медиа перестало существовать. Quota сервис корректирует
+// usage одинаково, но причина нужна для аудита и сверки.
+type DeleteReason string
+
+const (
+	DeleteReasonDeleted DeleteReason = "deleted"
+	DeleteReasonExpired DeleteReason = "expired"
+)
+
+type MediaDeleted struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	mediaType  MediaType
+	size       int64
+	reason     DeleteReason
+	occurredAt time.Time
+}
+
+func NewMediaDeleted(m *Media, reason DeleteReason, at time.Time) *MediaDeleted {
+	return &MediaDeleted{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		mediaType:  m.Type,
+		size:       m.Size,
+		reason:     reason,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaDeleted) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaDeleted) EventType() string      { return "MediaDeleted" }
+func (e *MediaDeleted) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaDeleted) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaDeleted) Reason() DeleteReason { return e.reason }
+
+func (e *MediaDeleted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID    `json:"event_id"`
+		MediaID    uuid.UUID    `json:"media_id"`
+		OwnerID    uuid.UUID    `json:"owner_id,omitzero"`
+		Type       MediaType    `json:"type"`
+		Size       int64        `json:"size_bytes,omitempty"`
+		Reason     DeleteReason `json:"reason"`
+		OccurredAt time.Time    `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		Type:       e.mediaType,
+		Size:       e.size,
+		Reason:     e.reason,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// MediaContentRecorded — ingest сохранил исходник медиа: checksum, размер и MIME тип.
+// PreviousSize — размер прежнего исходника (повторная загрузка), чтобы quota учла разницу.
+// Source — есть, если исходник переиспользован у другого медиа (дедупликация).
+type MediaContentRecorded struct {
+	eventID      uuid.UUID
+	mediaID      uuid.UUID
+	ownerID      uuid.UUID
+	checksum     string
+	size         int64
+	previousSize int64
+	contentType  string
+	source       string
+	occurredAt   time.Time
+}
+
+// NewMediaContentRecorded собирает событие по медиа до записи содержимого и новому содержимому
+func NewMediaContentRecorded(m *Media, c Content, at time.Time) *MediaContentRecorded {
+	return &MediaContentRecorded{
+		eventID:      uuid.New(),
+		mediaID:      m.ID,
+		ownerID:      m.OwnerID,
+		checksum:     c.Checksum,
+		size:         c.Size,
+		previousSize: m.Size,
+		contentType:  c.ContentType,
+		source:       c.Source,
+		occurredAt:   at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaContentRecorded) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaContentRecorded) EventType() string      { return "MediaContentRecorded" }
+func (e *MediaContentRecorded) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaContentRecorded) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaContentRecorded) Size() int64 { return e.size }
+
+func (e *MediaContentRecorded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID      uuid.UUID `json:"event_id"`
+		MediaID      uuid.UUID `json:"media_id"`
+		OwnerID      uuid.UUID `json:"owner_id,omitzero"`
+		Checksum     string    `json:"checksum_sha256"`
+		Size         int64     `json:"size_bytes"`
+		PreviousSize int64     `json:"previous_size_bytes,omitempty"`
+		ContentType  string    `json:"content_type"`
+		Source       string    `json:"source,omitempty"`
+		OccurredAt   time.Time `json:"occurred_at"`
+	}{
+		EventID:      e.eventID,
+		MediaID:      e.mediaID,
+		OwnerID:      e.ownerID,
+		Checksum:     e.checksum,
+		Size:         e.size,
+		PreviousSize: e.previousSize,
+		ContentType:  e.contentType,
+		Source:       e.source,
+		OccurredAt:   e.occurredAt,
+	})
+}
+
+// MediaArchived — исходник медиа перенесён в холодное хранилище по политике retention.
+// Location — новое расположение исходника (совпадает с Source, если объект сменил только класс хранения).
+type MediaArchived struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	mediaType  MediaType
+	source     string
+	location   string
+	occurredAt time.Time
+}
+
+// NewMediaArchived собирает событие по медиа до архивации: source — прежнее расположение
+func NewMediaArchived(m *Media, location string, at time.Time) *MediaArchived {
+	return &MediaArchived{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		mediaType:  m.Type,
+		source:     m.Source,
+		location:   location,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaArchived) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaArchived) EventType() string      { return "MediaArchived" }
+func (e *MediaArchived) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaArchived) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaArchived) Location() string { return e.location }
+
+func (e *MediaArchived) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		EventID    uuid.UUID `json:"event_id"`
 		MediaID    uuid.UUID `json:"media_id"`
-		From       Status    `json:"from"`
-		To         Status    `json:"to"`
+		OwnerID    uuid.UUID `json:"owner_id,omitzero"`
+		Type       MediaType `json:"type"`
+		Source     string    `json:"source"`
+		Location   string    `json:"location"`
 		OccurredAt time.Time `json:"occurred_at"`
 	}{
 		EventID:    e.eventID,
 		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		Type:       e.mediaType,
+		Source:     e.source,
+		Location:   e.location,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// MediaQuarantined — антивирус нашёл угрозу в исходнике медиа, медиа переведено в quarantined
+type MediaQuarantined struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	mediaType  MediaType
+	source     string
+	checksum   string
+	threat     string
+	scanner    string
+	occurredAt time.Time
+}
+
+func NewMediaQuarantined(m *Media, threat, scanner string, at time.Time) *MediaQuarantined {
+	return &MediaQuarantined{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		mediaType:  m.Type,
+		source:     m.Source,
+		checksum:   m.Checksum,
+		threat:     threat,
+		scanner:    scanner,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaQuarantined) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaQuarantined) EventType() string      { return "MediaQuarantined" }
+func (e *MediaQuarantined) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaQuarantined) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaQuarantined) Threat() string { return e.threat }
+
+func (e *MediaQuarantined) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID `json:"event_id"`
+		MediaID    uuid.UUID `json:"media_id"`
+		OwnerID    uuid.UUID `json:"owner_id,omitzero"`
+		Type       MediaType `json:"type"`
+		Source     string    `json:"source"`
+		Checksum   string    `json:"checksum_sha256,omitempty"`
+		Threat     string    `json:"threat"`
+		Scanner    string    `json:"scanner"`
+		OccurredAt time.Time `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		Type:       e.mediaType,
+		Source:     e.source,
+		Checksum:   e.checksum,
+		Threat:     e.threat,
+		Scanner:    e.scanner,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// MediaScheduled — изменилось расписание публикации медиа (PUT /media/{id}/schedule).
+// Nil время — ограничение снято.
+type MediaScheduled struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	publishAt  *time.Time
+	expiresAt  *time.Time
+	actor      string
+	occurredAt time.Time
+}
+
+func NewMediaScheduled(m *Media, actor string, at time.Time) *MediaScheduled {
+	return &MediaScheduled{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		publishAt:  cloneTime(m.PublishAt),
+		expiresAt:  cloneTime(m.ExpiresAt),
+		actor:      actor,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaScheduled) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaScheduled) EventType() string      { return "MediaScheduled" }
+func (e *MediaScheduled) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaScheduled) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaScheduled) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID  `json:"event_id"`
+		MediaID    uuid.UUID  `json:"media_id"`
+		OwnerID    uuid.UUID  `json:"owner_id,omitzero"`
+		PublishAt  *time.Time `json:"publish_at,omitempty"`
+		ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+		Actor      string     `json:"actor,omitempty"`
+		OccurredAt time.Time  `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		PublishAt:  e.publishAt,
+		ExpiresAt:  e.expiresAt,
+		Actor:      e.actor,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// MediaVisibilityChanged — владелец сменил видимость медиа (PATCH /media/{id}/visibility).
+// Effective — видимость с учётом статуса: по ней publish и CDN решают, отдавать ли медиа.
+type MediaVisibilityChanged struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	from       Visibility
+	to         Visibility
+	effective  Visibility
+	actor      string
+	occurredAt time.Time
+}
+
+// NewMediaVisibilityChanged — событие для медиа m, видимость которого сменилась с from
+func NewMediaVisibilityChanged(m *Media, from Visibility, actor string, at time.Time) *MediaVisibilityChanged {
+	return &MediaVisibilityChanged{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		from:       from,
+		to:         m.Visibility,
+		effective:  m.EffectiveVisibility(),
+		actor:      actor,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaVisibilityChanged) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaVisibilityChanged) EventType() string      { return "MediaVisibilityChanged" }
+func (e *MediaVisibilityChanged) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaVisibilityChanged) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaVisibilityChanged) From() Visibility      { return e.from }
+func (e *MediaVisibilityChanged) To() Visibility        { return e.to }
+func (e *MediaVisibilityChanged) Effective() Visibility { return e.effective }
+
+func (e *MediaVisibilityChanged) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID  `json:"event_id"`
+		MediaID    uuid.UUID  `json:"media_id"`
+		OwnerID    uuid.UUID  `json:"owner_id,omitzero"`
+		From       Visibility `json:"from"`
+		To         Visibility `json:"to"`
+		Effective  Visibility `json:"effective"`
+		Actor      string     `json:"actor,omitempty"`
+		OccurredAt time.Time  `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
 		From:       e.from,
 		To:         e.to,
+		Effective:  e.effective,
+		Actor:      e.actor,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// MediaAccessGranted — владелец открыл медиа principal (PUT /media/{id}/grants/{principal})
+// или сменил его право. Previous — прежнее право, пустое — доступа не было.
+type MediaAccessGranted struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	principal  uuid.UUID
+	permission Permission
+	previous   Permission
+	actor      string
+	occurredAt time.Time
+}
+
+// NewMediaAccessGranted — событие для записи g ACL медиа m
+func NewMediaAccessGranted(m *Media, g Grant, previous Permission, actor string) *MediaAccessGranted {
+	return &MediaAccessGranted{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		principal:  g.Principal,
+		permission: g.Permission,
+		previous:   previous,
+		actor:      actor,
+		occurredAt: g.UpdatedAt,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaAccessGranted) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaAccessGranted) EventType() string      { return "MediaAccessGranted" }
+func (e *MediaAccessGranted) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaAccessGranted) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaAccessGranted) Principal() uuid.UUID   { return e.principal }
+func (e *MediaAccessGranted) Permission() Permission { return e.permission }
+
+func (e *MediaAccessGranted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID  `json:"event_id"`
+		MediaID    uuid.UUID  `json:"media_id"`
+		OwnerID    uuid.UUID  `json:"owner_id,omitzero"`
+		Principal  uuid.UUID  `json:"principal"`
+		Permission Permission `json:"permission"`
+		Previous   Permission `json:"previous,omitempty"`
+		Actor      string     `json:"actor,omitempty"`
+		OccurredAt time.Time  `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		Principal:  e.principal,
+		Permission: e.permission,
+		Previous:   e.previous,
+		Actor:      e.actor,
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// MediaAccessRevoked — владелец закрыл principal доступ к медиа (DELETE /media/{id}/grants/{principal})
+type MediaAccessRevoked struct {
+	eventID    uuid.UUID
+	mediaID    uuid.UUID
+	ownerID    uuid.UUID
+	principal  uuid.UUID
+	permission Permission
+	actor      string
+	occurredAt time.Time
+}
+
+// NewMediaAccessRevoked — событие для удалённой записи g ACL медиа m
+func NewMediaAccessRevoked(m *Media, g Grant, actor string, at time.Time) *MediaAccessRevoked {
+	return &MediaAccessRevoked{
+		eventID:    uuid.New(),
+		mediaID:    m.ID,
+		ownerID:    m.OwnerID,
+		principal:  g.Principal,
+		permission: g.Permission,
+		actor:      actor,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *MediaAccessRevoked) EventID() uuid.UUID     { return e.eventID }
+func (e *MediaAccessRevoked) EventType() string      { return "MediaAccessRevoked" }
+func (e *MediaAccessRevoked) AggregateID() uuid.UUID { return e.mediaID }
+func (e *MediaAccessRevoked) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *MediaAccessRevoked) Principal() uuid.UUID { return e.principal }
+
+func (e *MediaAccessRevoked) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID    uuid.UUID  `json:"event_id"`
+		MediaID    uuid.UUID  `json:"media_id"`
+		OwnerID    uuid.UUID  `json:"owner_id,omitzero"`
+		Principal  uuid.UUID  `json:"principal"`
+		Permission Permission `json:"permission"` // право, которое было у principal
+		Actor      string     `json:"actor,omitempty"`
+		OccurredAt time.Time  `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		MediaID:    e.mediaID,
+		OwnerID:    e.ownerID,
+		Principal:  e.principal,
+		Permission: e.permission,
+		Actor:      e.actor,
 		OccurredAt: e.occurredAt,
 	})
 }
+
+// CollectionItemAdded — медиа добавлено в коллекцию (POST /collections/{id}/items).
+// Агрегат — коллекция: события одной коллекции идут в одном порядке.
+type CollectionItemAdded struct {
+	eventID      uuid.UUID
+	collectionID uuid.UUID
+	ownerID      uuid.UUID
+	mediaID      uuid.UUID
+	position     int
+	actor        string
+	occurredAt   time.Time
+}
+
+// NewCollectionItemAdded — событие для медиа item, добавленного в коллекцию c
+func NewCollectionItemAdded(c *Collection, item CollectionItem, actor string) *CollectionItemAdded {
+	return &CollectionItemAdded{
+		eventID:      uuid.New(),
+		collectionID: c.ID,
+		ownerID:      c.OwnerID,
+		mediaID:      item.MediaID,
+		position:     item.Position,
+		actor:        actor,
+		occurredAt:   item.AddedAt,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *CollectionItemAdded) EventID() uuid.UUID     { return e.eventID }
+func (e *CollectionItemAdded) EventType() string      { return "CollectionItemAdded" }
+func (e *CollectionItemAdded) AggregateID() uuid.UUID { return e.collectionID }
+func (e *CollectionItemAdded) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *CollectionItemAdded) MediaID() uuid.UUID { return e.mediaID }
+func (e *CollectionItemAdded) Position() int      { return e.position }
+
+func (e *CollectionItemAdded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID      uuid.UUID `json:"event_id"`
+		CollectionID uuid.UUID `json:"collection_id"`
+		OwnerID      uuid.UUID `json:"owner_id,omitzero"`
+		MediaID      uuid.UUID `json:"media_id"`
+		Position     int       `json:"position"`
+		Actor        string    `json:"actor,omitempty"`
+		OccurredAt   time.Time `json:"occurred_at"`
+	}{
+		EventID:      e.eventID,
+		CollectionID: e.collectionID,
+		OwnerID:      e.ownerID,
+		MediaID:      e.mediaID,
+		Position:     e.position,
+		Actor:        e.actor,
+		OccurredAt:   e.occurredAt,
+	})
+}
+
+// CollectionItemRemoved — медиа убрано из коллекции; медиа после него сдвигаются на место вперёд
+type CollectionItemRemoved struct {
+	eventID      uuid.UUID
+	collectionID uuid.UUID
+	ownerID      uuid.UUID
+	mediaID      uuid.UUID
+	position     int
+	actor        string
+	occurredAt   time.Time
+}
+
+// NewCollectionItemRemoved — событие для медиа item, убранного из коллекции c
+func NewCollectionItemRemoved(c *Collection, item CollectionItem, actor string, at time.Time) *CollectionItemRemoved {
+	return &CollectionItemRemoved{
+		eventID:      uuid.New(),
+		collectionID: c.ID,
+		ownerID:      c.OwnerID,
+		mediaID:      item.MediaID,
+		position:     item.Position,
+		actor:        actor,
+		occurredAt:   at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *CollectionItemRemoved) EventID() uuid.UUID     { return e.eventID }
+func (e *CollectionItemRemoved) EventType() string      { return "CollectionItemRemoved" }
+func (e *CollectionItemRemoved) AggregateID() uuid.UUID { return e.collectionID }
+func (e *CollectionItemRemoved) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *CollectionItemRemoved) MediaID() uuid.UUID { return e.mediaID }
+
+func (e *CollectionItemRemoved) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EventID      uuid.UUID `json:"event_id"`
+		CollectionID uuid.UUID `json:"collection_id"`
+		OwnerID      uuid.UUID `json:"owner_id,omitzero"`
+		MediaID      uuid.UUID `json:"media_id"`
+		Position     int       `json:"position"` // место, которое медиа занимало
+		Actor        string    `json:"actor,omitempty"`
+		OccurredAt   time.Time `json:"occurred_at"`
+	}{
+		EventID:      e.eventID,
+		CollectionID: e.collectionID,
+		OwnerID:      e.ownerID,
+		MediaID:      e.mediaID,
+		Position:     e.position,
+		Actor:        e.actor,
+		OccurredAt:   e.occurredAt,
+	})
+}
+
+// CollectionDeleted — коллекция удалена вместе со всеми её элементами; отдельных
+// CollectionItemRemoved по ним нет
+type CollectionDeleted struct {
+	eventID      uuid.UUID
+	collectionID uuid.UUID
+	ownerID      uuid.UUID
+	mediaIDs     []uuid.UUID
+	actor        string
+	occurredAt   time.Time
+}
+
+// NewCollectionDeleted — событие для коллекции c, в которой были медиа mediaIDs (по порядку)
+func NewCollectionDeleted(c *Collection, mediaIDs []uuid.UUID, actor string, at time.Time) *CollectionDeleted {
+	return &CollectionDeleted{
+		eventID:      uuid.New(),
+		collectionID: c.ID,
+		ownerID:      c.OwnerID,
+		mediaIDs:     mediaIDs,
+		actor:        actor,
+		occurredAt:   at,
+	}
+}
+
+// Реализация интерфейса DomainEvent
+func (e *CollectionDeleted) EventID() uuid.UUID     { return e.eventID }
+func (e *CollectionDeleted) EventType() string      { return "CollectionDeleted" }
+func (e *CollectionDeleted) AggregateID() uuid.UUID { return e.collectionID }
+func (e *CollectionDeleted) OccurredAt() time.Time  { return e.occurredAt }
+
+func (e *CollectionDeleted) MediaIDs() []uuid.UUID { return e.mediaIDs }
+
+func (e *CollectionDeleted) MarshalJSON() ([]byte, error) {
+	mediaIDs := e.mediaIDs
+	if mediaIDs == nil {
+		mediaIDs = []uuid.UUID{}
+	}
+	return json.Marshal(struct {
+		EventID      uuid.UUID   `json:"event_id"`
+		CollectionID uuid.UUID   `json:"collection_id"`
+		OwnerID      uuid.UUID   `json:"owner_id,omitzero"`
+		MediaIDs     []uuid.UUID `json:"media_ids"`
+		Actor        string      `json:"actor,omitempty"`
+		OccurredAt   time.Time   `json:"occurred_at"`
+	}{
+		EventID:      e.eventID,
+		CollectionID: e.collectionID,
+		OwnerID:      e.ownerID,
+		MediaIDs:     mediaIDs,
+		Actor:        e.actor,
+		OccurredAt:   e.occurredAt,
+	})
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission — право на чужое медиа, которое выдаёт владелец
+type Permission string
+
+const (
+	ReadPermission     Permission = "read"     // видеть медиа по id
+	DownloadPermission Permission = "download" // видеть и скачивать исходник
+)
+
+// Valid — известное право
+func (p Permission) Valid() bool {
+	return p == ReadPermission || p == DownloadPermission
+}
+
+// Allows — право p включает need: download включает read
+func (p Permission) Allows(need Permission) bool {
+	return p == need || (p == DownloadPermission && need == ReadPermission)
+}
+
+// Grant — запись ACL медиа: владелец открыл медиа principal независимо от видимости
+type Grant struct {
+	MediaID    uuid.UUID  `db:"media_id"`
+	Principal  uuid.UUID  `db:"principal"` // владелец (X-Owner-ID), которому открыт доступ
+	Permission Permission `db:"permission"`
+	GrantedBy  string     `db:"granted_by"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusChange — запись аудита о переходе статуса медиа
+type StatusChange struct {
+	ID        int64     `db:"id"`
+	MediaID   uuid.UUID `db:"media_id"`
+	From      Status    `db:"from_status"`
+	To        Status    `db:"to_status"`
+	Actor     string    `db:"actor"`  // кто инициировал переход (сервис или пользователь)
+	Reason    string    `db:"reason"` // свободный текст; для failed — причина падения обработки
+	ChangedAt time.Time `db:"changed_at"`
+}
@@ -9,10 +9,14 @@ import (
 type Status string
 
 const (
-	UploadedStatus   Status = "uploaded"
-	ProcessingStatus Status = "processing"
-	ReadyStatus      Status = "ready"
-	FailedStatus     Status = "failed"
+	UploadedStatus    Status = "uploaded"
+	ProcessingStatus  Status = "processing"
+	ReadyStatus       Status = "ready"
+	FailedStatus      Status = "failed"
+	DeletedStatus     Status = "deleted"
+	ArchivedStatus    Status = "archived"    // исходник перенесён в холодное хранилище по политике retention
+	QuarantinedStatus Status = "quarantined" // антивирус нашёл угрозу в исходнике; медиа не обрабатывается
+	ScheduledStatus   Status = "scheduled"   // обработано, но до publish_at не публикуется (эмбарго)
 )
 
 type MediaType string
@@ -30,4 +34,58 @@ type Media struct {
 	Source    string    `db:"source"`
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
+
+	Title    string   `db:"title"`
+	Tags     Tags     `db:"tags"`
+	Metadata Metadata `db:"metadata"`
+
+	ProcessingAttempts int    `db:"processing_attempts"` // сколько раз медиа входило в processing с последнего ready
+	LastError          string `db:"last_error"`          // ошибка последней неудачной обработки
+
+	OwnerID uuid.UUID `db:"owner_id"` // пользователь или тенант, создавший медиа; uuid.Nil — общий пул
+
+	// Характеристики исходника, проверенные ingest'ом при загрузке; пустые, пока загрузки не было
+	Checksum    string `db:"checksum_sha256"` // sha256 содержимого, hex
+	Size        int64  `db:"size_bytes"`
+	ContentType string `db:"content_type"` // MIME тип, определённый по содержимому
+
+	// Расписание публикации; nil — без ограничения
+	PublishAt *time.Time `db:"publish_at"` // до этого времени обработанное медиа в scheduled, не в ready
+	ExpiresAt *time.Time `db:"expires_at"` // после этого времени медиа архивируется
+
+	Visibility Visibility `db:"visibility"` // кому видно; см. EffectiveVisibility
+}
+
+// Version — версия медиа для условных запросов (ETag, If-Match): updated_at меняется при каждой записи
+func (m *Media) Version() int64 {
+	return m.UpdatedAt.UnixNano()
+}
+
+// Schedule — время публикации и снятия медиа; nil поле — без ограничения
+type Schedule struct {
+	PublishAt *time.Time
+	ExpiresAt *time.Time
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// Embargoed — публикация медиа ещё не наступила на момент now
+func (m *Media) Embargoed(now time.Time) bool {
+	return m.PublishAt != nil && m.PublishAt.After(now)
+}
+
+// Content — характеристики загруженного исходника, которые ingest записывает в медиа
+type Content struct {
+	Checksum    string // sha256, hex
+	Size        int64
+	ContentType string
+	// Source — непустой, если ingest не сохранял исходник, а сослался на объект другого медиа
+	// владельца с тем же checksum (дедупликация): медиа переходит на этот Source
+	Source string
 }
@@ -0,0 +1,30 @@
+package models
+
+// Visibility — кому видно медиа. Ставится владельцем независимо от статуса обработки
+type Visibility string
+
+const (
+	DraftVisibility    Visibility = "draft"    // новое медиа: видит только владелец, ещё ни разу не показывалось
+	PrivateVisibility  Visibility = "private"  // только владелец
+	UnlistedVisibility Visibility = "unlisted" // всем, кто знает id; в списки чужого медиа не попадает
+	PublicVisibility   Visibility = "public"   // всем, в том числе в списках
+)
+
+// EffectiveVisibility — видимость с учётом статуса: unlisted и public действуют только для
+// опубликованного (ready) медиа, иначе медиа видно как private. Пустая видимость — draft.
+func (m *Media) EffectiveVisibility() Visibility {
+	switch v := m.Visibility; {
+	case v == "":
+		return DraftVisibility
+	case (v == UnlistedVisibility || v == PublicVisibility) && m.Status != ReadyStatus:
+		return PrivateVisibility
+	default:
+		return v
+	}
+}
+
+// Visible — медиа видно не только владельцу (по id)
+func (m *Media) Visible() bool {
+	v := m.EffectiveVisibility()
+	return v == UnlistedVisibility || v == PublicVisibility
+}
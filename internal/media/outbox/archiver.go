@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ArchiveStore — архив опубликованных событий; реализуется *postgres.OutboxRepo
+type ArchiveStore interface {
+	// ArchiveProcessed переносит в архив не больше limit событий, опубликованных раньше before;
+	// опубликованные раньше dropBefore удаляются без архива. Возвращает число строк, ушедших из outbox.
+	ArchiveProcessed(ctx context.Context, before, dropBefore time.Time, limit int) (int64, error)
+	// DropArchive удаляет части архива, целиком лежащие раньше before, и возвращает их число
+	DropArchive(ctx context.Context, before time.Time) (int, error)
+}
+
+// ArchiverConfig содержит конфигурацию Archiver
+type ArchiverConfig struct {
+	Store ArchiveStore
+	// After — сколько опубликованное событие остаётся в outbox (и доступно replay) (default: 24h)
+	After time.Duration
+	// Retention — сколько опубликованное событие хранится всего, в outbox и архиве (default: 7 дней)
+	Retention time.Duration
+	Interval  time.Duration // Период запуска (default: 10m)
+	BatchSize int           // Событий за одну транзакцию переноса (default: 1000)
+	Logger    zerolog.Logger
+}
+
+// ArchiveReport — результат одного запуска
+type ArchiveReport struct {
+	Moved   int64 // строк ушло из outbox (в архив или удалено за сроком хранения)
+	Dropped int   // удалено частей архива
+}
+
+// Archiver держит outbox маленьким: опубликованные события переносятся в архив пачками,
+// старые части архива удаляются целиком. Без него outbox растёт с каждым событием, и
+// вставка, vacuum и выборки по опубликованным событиям (replay) дорожают вместе с таблицей.
+type Archiver struct {
+	store     ArchiveStore
+	after     time.Duration
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+	clock     func() time.Time
+	logger    zerolog.Logger
+}
+
+func NewArchiver(cfg ArchiverConfig) (*Archiver, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if cfg.After < 0 {
+		return nil, fmt.Errorf("archive after cannot be negative, got: %v", cfg.After)
+	}
+	if cfg.Retention < 0 {
+		return nil, fmt.Errorf("retention cannot be negative, got: %v", cfg.Retention)
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %v", cfg.Interval)
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("batch size cannot be negative, got: %d", cfg.BatchSize)
+	}
+	if cfg.After == 0 {
+		cfg.After = 24 * time.Hour
+	}
+	if cfg.Retention == 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.Retention < cfg.After {
+		return nil, fmt.Errorf("retention %v is shorter than archive after %v", cfg.Retention, cfg.After)
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+
+	return &Archiver{
+		store:     cfg.Store,
+		after:     cfg.After,
+		retention: cfg.Retention,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+		clock:     time.Now,
+		logger:    cfg.Logger.With().Str("component", "outbox_archiver").Logger(),
+	}, nil
+}
+
+// RunOnce переносит все события, опубликованные раньше After назад, пачками по BatchSize,
+// затем удаляет части архива старше Retention. Пачки — отдельные транзакции: блокировки
+// outbox короткие, а прерванный запуск продолжит следующий.
+func (a *Archiver) RunOnce(ctx context.Context) (ArchiveReport, error) {
+	now := a.clock()
+	before, dropBefore := now.Add(-a.after), now.Add(-a.retention)
+
+	var report ArchiveReport
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		n, err := a.store.ArchiveProcessed(ctx, before, dropBefore, a.batchSize)
+		if err != nil {
+			return report, err
+		}
+		report.Moved += n
+		if n < int64(a.batchSize) {
+			break
+		}
+	}
+
+	dropped, err := a.store.DropArchive(ctx, dropBefore)
+	if err != nil {
+		return report, err
+	}
+	report.Dropped = dropped
+
+	a.logger.Info().
+		Int64("moved", report.Moved).
+		Int("dropped_partitions", report.Dropped).
+		Time("before", before).
+		Msg("outbox archived")
+	return report, nil
+}
+
+// Start запускает архивацию сразу и далее каждые Interval до отмены контекста
+func (a *Archiver) Start(ctx context.Context) error {
+	a.logger.Info().Dur("interval", a.interval).Dur("after", a.after).Dur("retention", a.retention).Msg("outbox archiver started")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.logger.Info().Msg("outbox archiver stopped")
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := a.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				a.logger.Error().Err(err).Msg("outbox archive failed")
+			}
+			timer.Reset(a.interval)
+		}
+	}
+}
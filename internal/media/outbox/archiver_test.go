@@ -0,0 +1,68 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeArchive — опубликованные события с временем публикации; архив — те, что перенесены
+type fakeArchive struct {
+	processed []time.Time
+	archived  []time.Time
+	calls     int
+	dropped   time.Time
+}
+
+func (s *fakeArchive) ArchiveProcessed(ctx context.Context, before, dropBefore time.Time, limit int) (int64, error) {
+	s.calls++
+	var n int64
+	kept := s.processed[:0]
+	for _, at := range s.processed {
+		if !at.Before(before) || n == int64(limit) {
+			kept = append(kept, at)
+			continue
+		}
+		n++
+		if !at.Before(dropBefore) {
+			s.archived = append(s.archived, at)
+		}
+	}
+	s.processed = kept
+	return n, nil
+}
+
+func (s *fakeArchive) DropArchive(ctx context.Context, before time.Time) (int, error) {
+	s.dropped = before
+	return 1, nil
+}
+
+func TestArchiver_RunOnce(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := &fakeArchive{}
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 30 * time.Hour, 40 * time.Hour, 50 * time.Hour, 10 * 24 * time.Hour} {
+		store.processed = append(store.processed, now.Add(-age))
+	}
+
+	a, err := NewArchiver(ArchiverConfig{Store: store, After: 24 * time.Hour, Retention: 7 * 24 * time.Hour, BatchSize: 2, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	a.clock = func() time.Time { return now }
+
+	report, err := a.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, ArchiveReport{Moved: 4, Dropped: 1}, report)
+	// Пачки идут, пока пачка полная: 2 + 2 + пустая
+	require.Equal(t, 3, store.calls)
+	// Свежие события остаются в outbox, событие старше хранения удалено без архива
+	require.Equal(t, []time.Time{now.Add(-time.Hour), now.Add(-2 * time.Hour)}, store.processed)
+	require.Len(t, store.archived, 3)
+	require.Equal(t, now.Add(-7*24*time.Hour), store.dropped)
+}
+
+func TestNewArchiver_RetentionShorterThanAfter(t *testing.T) {
+	_, err := NewArchiver(ArchiverConfig{Store: &fakeArchive{}, After: 48 * time.Hour, Retention: 24 * time.Hour})
+	require.Error(t, err)
+}
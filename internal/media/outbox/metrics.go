@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PublisherMetrics содержит метрики outbox publisher
+type PublisherMetrics struct {
+	Published atomic.Int64 // Опубликованные события
+	Failed    atomic.Int64 // Неудачные попытки публикации
+	Backlog   atomic.Int64 // Pending события на момент последнего замера
+
+	DeadLettered atomic.Int64 // События, припаркованные после MaxAttempts
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *PublisherMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "Опубликованные в Kafka события outbox",
+		}, func() float64 { return float64(m.Published.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "outbox_publish_errors_total",
+			Help: "Неудачные попытки публикации событий outbox",
+		}, func() float64 { return float64(m.Failed.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "outbox_events_dead_lettered_total",
+			Help: "События outbox, припаркованные после исчерпания попыток",
+		}, func() float64 { return float64(m.DeadLettered.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "outbox_pending_events",
+			Help: "Необработанные события в outbox",
+		}, func() float64 { return float64(m.Backlog.Load()) }),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
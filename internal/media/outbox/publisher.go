@@ -2,31 +2,104 @@ package outbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/romariotrain/media-platform/internal/events"
 	"github.com/romariotrain/media-platform/internal/media/kafka"
 	"github.com/romariotrain/media-platform/internal/storage/postgres"
 	"github.com/rs/zerolog"
 )
 
+// errHeldBack — запись не отправлялась: более раннее событие агрегата в этом batch'е не опубликовано
+var errHeldBack = errors.New("held back behind failed aggregate event")
+
+// Store — outbox таблица; реализуется *postgres.OutboxRepo
+type Store interface {
+	GetPending(ctx context.Context, limit int) ([]postgres.OutboxRecord, error)
+	MarkProcessed(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error
+	MarkDeadLetter(ctx context.Context, id int64, lastError string) error
+	CountPending(ctx context.Context) (int64, error)
+}
+
+// EnvelopePublisher публикует конверт события; реализуется *kafka.Producer
+type EnvelopePublisher interface {
+	PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error
+}
+
+// AsyncEnvelopePublisher — producer, подтверждающий доставку асинхронно; реализуется *kafka.Producer.
+// Если PublishEnvelopeAsync вернул ошибку, done не вызывается.
+type AsyncEnvelopePublisher interface {
+	PublishEnvelopeAsync(ctx context.Context, env events.Envelope, ts time.Time, done func(err error)) error
+}
+
+// Flusher — producer с буфером, который нужно сбросить при остановке; реализуется *kafka.Producer
+type Flusher interface {
+	Shutdown(ctx context.Context) error
+}
+
+// BatchFlusher — producer, который копит принятые сообщения до записи; реализуется *kafka.Producer
+// (KeyBatch). Publisher сбрасывает его после каждого batch'а, не дожидаясь таймера.
+type BatchFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// TimestampSource определяет, каким временем штампуется сообщение в Kafka
+type TimestampSource int
+
+const (
+	// EventTime — время возникновения события (outbox.occurred_at).
+	// Сохраняет исходное время при отложенной публикации. Используется по умолчанию.
+	EventTime TimestampSource = iota
+	// ProcessingTime — время публикации в Kafka
+	ProcessingTime
+)
+
 // Publisher реализует Outbox паттерн для надёжной публикации событий в Kafka.
 // Гарантирует at-least-once delivery семантику.
 type Publisher struct {
-	outboxRepo *postgres.OutboxRepo
-	producer   *kafka.Producer
-	interval   time.Duration
-	batchSize  int
-	logger     zerolog.Logger
+	outboxRepo       Store
+	producer         EnvelopePublisher
+	interval         time.Duration // interval, maxInterval и batchSize — под mu, меняются на лету
+	maxInterval      time.Duration
+	batchSize        int
+	backlogThreshold int64
+	maxAttempts      int
+	retryBackoff     time.Duration
+	maxRetryBackoff  time.Duration
+	timestamps       TimestampSource
+	clock            func() time.Time
+	logger           zerolog.Logger
+	metrics          *PublisherMetrics
+
+	mu      sync.Mutex
+	stopCh  chan struct{}      // закрывается Stop
+	stopped bool               // Stop уже вызван
+	running chan struct{}      // закрывается при выходе из Start; nil — Start не запущен
+	abort   context.CancelFunc // прерывает текущий batch, если drain не уложился в срок
 }
 
 // PublisherConfig содержит конфигурацию для создания Publisher
 type PublisherConfig struct {
-	OutboxRepo *postgres.OutboxRepo
-	Producer   *kafka.Producer
-	Interval   time.Duration
-	BatchSize  int
-	Logger     zerolog.Logger
+	OutboxRepo Store
+	Producer   EnvelopePublisher
+	Interval   time.Duration // Пауза после неполного batch'а и начальная пауза backoff
+	// MaxInterval — потолок экспоненциального backoff при пустом outbox (default: 10 × Interval)
+	MaxInterval time.Duration
+	BatchSize   int
+	// BacklogThreshold — при большем числе pending событий CheckBacklog возвращает ошибку (0 = не проверять)
+	BacklogThreshold int64
+	// MaxAttempts — после стольких неудачных публикаций событие паркуется в dead letter (default: 20)
+	MaxAttempts int
+	// RetryBackoff — задержка повтора после первой неудачи, дальше удваивается (default: 1s)
+	RetryBackoff time.Duration
+	// MaxRetryBackoff — потолок задержки повтора события (default: 10m)
+	MaxRetryBackoff time.Duration
+	Timestamps      TimestampSource // Источник timestamp сообщений (default: EventTime)
+	Logger          zerolog.Logger
 }
 
 // NewPublisher создаёт новый экземпляр Publisher с заданной конфигурацией
@@ -43,68 +116,261 @@ func NewPublisher(cfg PublisherConfig) (*Publisher, error) {
 	if cfg.BatchSize <= 0 {
 		return nil, fmt.Errorf("batch size must be positive, got: %d", cfg.BatchSize)
 	}
+	if cfg.MaxInterval < 0 {
+		return nil, fmt.Errorf("max interval cannot be negative, got: %v", cfg.MaxInterval)
+	}
+	if cfg.MaxInterval == 0 {
+		cfg.MaxInterval = 10 * cfg.Interval
+	}
+	if cfg.MaxInterval < cfg.Interval {
+		return nil, fmt.Errorf("max interval (%v) cannot be less than interval (%v)", cfg.MaxInterval, cfg.Interval)
+	}
+	if cfg.BacklogThreshold < 0 {
+		return nil, fmt.Errorf("backlog threshold cannot be negative, got: %d", cfg.BacklogThreshold)
+	}
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("max attempts cannot be negative, got: %d", cfg.MaxAttempts)
+	}
+	if cfg.RetryBackoff < 0 || cfg.MaxRetryBackoff < 0 {
+		return nil, fmt.Errorf("retry backoff cannot be negative")
+	}
+	if cfg.MaxAttempts == 0 {
+		// При backoff до 10m это ~2 часа повторов: переживаем недоступность Kafka, не паркуя всё подряд
+		cfg.MaxAttempts = 20
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.MaxRetryBackoff == 0 {
+		cfg.MaxRetryBackoff = 10 * time.Minute
+	}
+	if cfg.Timestamps != EventTime && cfg.Timestamps != ProcessingTime {
+		return nil, fmt.Errorf("unknown timestamp source: %d", cfg.Timestamps)
+	}
 
 	return &Publisher{
-		outboxRepo: cfg.OutboxRepo,
-		producer:   cfg.Producer,
-		interval:   cfg.Interval,
-		batchSize:  cfg.BatchSize,
-		logger:     cfg.Logger.With().Str("component", "outbox_publisher").Logger(),
+		outboxRepo:       cfg.OutboxRepo,
+		producer:         cfg.Producer,
+		interval:         cfg.Interval,
+		maxInterval:      cfg.MaxInterval,
+		batchSize:        cfg.BatchSize,
+		backlogThreshold: cfg.BacklogThreshold,
+		maxAttempts:      cfg.MaxAttempts,
+		retryBackoff:     cfg.RetryBackoff,
+		maxRetryBackoff:  cfg.MaxRetryBackoff,
+		timestamps:       cfg.Timestamps,
+		clock:            time.Now,
+		logger:           cfg.Logger.With().Str("component", "outbox_publisher").Logger(),
+		metrics:          &PublisherMetrics{},
+		stopCh:           make(chan struct{}),
 	}, nil
 }
 
-// Start запускает polling механизм для обработки событий из outbox таблицы.
-// Блокирует до тех пор, пока не будет отменён контекст.
+// Metrics возвращает метрики publisher
+func (p *Publisher) Metrics() *PublisherMetrics { return p.metrics }
+
+// SetInterval меняет паузу опроса на лету; MaxInterval поднимается до неё, если стал меньше.
+// Действует со следующей паузы.
+func (p *Publisher) SetInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got: %v", interval)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interval = interval
+	p.maxInterval = max(p.maxInterval, interval)
+	return nil
+}
+
+// SetBatchSize меняет размер batch'а на лету; действует со следующего batch'а
+func (p *Publisher) SetBatchSize(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("batch size must be positive, got: %d", n)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batchSize = n
+	return nil
+}
+
+// polling возвращает текущие параметры опроса
+func (p *Publisher) polling() (interval, maxInterval time.Duration, batchSize int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.interval, p.maxInterval, p.batchSize
+}
+
+// Start запускает адаптивный polling outbox таблицы.
+// Блокирует до отмены контекста или вызова Stop.
 //
 // Процесс работы:
-// 1. Каждые interval времени проверяет наличие необработанных событий
-// 2. Читает batch событий из БД
-// 3. Публикует каждое событие в Kafka
-// 4. Помечает успешно опубликованные события как processed
+// 1. Читает batch событий из БД, публикует их в Kafka и помечает processed
+// 2. Полный batch — за ним есть ещё события, следующий читается сразу (drain)
+// 3. Неполный batch — пауза Interval
+// 4. Пустой outbox или ошибка чтения — пауза растёт экспоненциально до MaxInterval
+// 5. Перед каждой паузой обновляется размер backlog (pending событий)
 //
 // Гарантии:
 // - At-least-once delivery: события могут быть доставлены повторно
-// - Graceful shutdown при отмене контекста
+// - Graceful shutdown через Stop: текущий batch дорабатывается до конца
+// - Отмена ctx прерывает batch сразу: опубликованные, но не помеченные события уйдут повторно
 // - Продолжает работу даже при ошибках публикации отдельных событий
 func (p *Publisher) Start(ctx context.Context) error {
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
+	p.mu.Lock()
+	if p.running != nil {
+		p.mu.Unlock()
+		return errors.New("publisher already started")
+	}
+	running := make(chan struct{})
+	p.running, p.abort = running, cancel
+	p.mu.Unlock()
+	// После выхода (в том числе по panic) Start можно вызвать снова — так его перезапускает supervisor
+	defer func() {
+		p.mu.Lock()
+		p.running, p.abort = nil, nil
+		p.mu.Unlock()
+		close(running)
+	}()
+
+	interval, maxInterval, batchSize := p.polling()
 	p.logger.Info().
-		Dur("interval", p.interval).
-		Int("batch_size", p.batchSize).
+		Dur("interval", interval).
+		Dur("max_interval", maxInterval).
+		Int("batch_size", batchSize).
 		Msg("outbox publisher started")
 
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	idle := time.Duration(0) // текущая пауза backoff; 0 — outbox не пустой
 	for {
+		// Stop важнее готового таймера: после него новый batch не начинается
 		select {
+		case <-p.stopCh:
+			p.logger.Info().Msg("outbox publisher drained")
+			return nil
+		default:
+		}
+
+		select {
+		case <-p.stopCh:
+			p.logger.Info().Msg("outbox publisher drained")
+			return nil
+
 		case <-ctx.Done():
 			p.logger.Info().
 				Err(ctx.Err()).
 				Msg("outbox publisher stopped")
 			return ctx.Err()
 
-		case <-ticker.C:
-			if err := p.publishBatch(ctx); err != nil {
-				p.logger.Error().
-					Err(err).
-					Msg("failed to publish batch")
-				// Продолжаем работать, не падаем
-			}
+		case <-timer.C:
 		}
+
+		n, err := p.publishBatch(ctx)
+		if err != nil {
+			p.logger.Error().
+				Err(err).
+				Msg("failed to publish batch")
+			// Продолжаем работать, не падаем
+		}
+
+		var delay time.Duration
+		idle, delay = p.nextDelay(idle, n, err)
+		if delay > 0 {
+			p.refreshBacklog(ctx)
+		}
+		timer.Reset(delay)
 	}
 }
 
-// publishBatch обрабатывает один batch событий из outbox таблицы
-func (p *Publisher) publishBatch(ctx context.Context) error {
+// Stop останавливает polling: дожидается завершения текущего batch'а и сбрасывает буфер
+// producer'а (если он реализует Flusher). Если ctx истекает раньше, batch прерывается
+// и возвращается ошибка ctx. Повторный вызов только дожидается остановки.
+func (p *Publisher) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.stopCh)
+	}
+	running, abort := p.running, p.abort
+	p.mu.Unlock()
+
+	var drainErr error
+	if running != nil {
+		select {
+		case <-running:
+		case <-ctx.Done():
+			abort()
+			<-running
+			drainErr = fmt.Errorf("drain outbox publisher: %w", ctx.Err())
+		}
+	}
+
+	if f, ok := p.producer.(Flusher); ok {
+		if err := f.Shutdown(ctx); err != nil {
+			return errors.Join(drainErr, fmt.Errorf("flush producer: %w", err))
+		}
+	}
+	return drainErr
+}
+
+// nextDelay выбирает паузу до следующего опроса по результату batch'а.
+// idle — текущая пауза backoff, возвращается обновлённой.
+func (p *Publisher) nextDelay(idle time.Duration, fetched int, err error) (time.Duration, time.Duration) {
+	interval, maxInterval, batchSize := p.polling()
+	switch {
+	case err == nil && fetched >= batchSize:
+		return 0, 0
+	case err == nil && fetched > 0:
+		return 0, interval
+	}
+
+	if idle == 0 {
+		idle = interval
+	} else {
+		idle = min(2*idle, maxInterval)
+	}
+	return idle, idle
+}
+
+// refreshBacklog обновляет метрику числа pending событий
+func (p *Publisher) refreshBacklog(ctx context.Context) {
+	n, err := p.outboxRepo.CountPending(ctx)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("failed to count pending events")
+		return
+	}
+	p.metrics.Backlog.Store(n)
+}
+
+// CheckBacklog — проверка готовности для /readyz: ошибка, если backlog превысил BacklogThreshold.
+// Значение берётся из последнего замера publisher'а, БД не опрашивается.
+func (p *Publisher) CheckBacklog(ctx context.Context) error {
+	if p.backlogThreshold == 0 {
+		return nil
+	}
+	if n := p.metrics.Backlog.Load(); n > p.backlogThreshold {
+		return fmt.Errorf("outbox backlog %d exceeds threshold %d", n, p.backlogThreshold)
+	}
+	return nil
+}
+
+// publishBatch обрабатывает один batch событий из outbox таблицы.
+// Возвращает число прочитанных записей — по нему Start решает, дренировать ли дальше.
+func (p *Publisher) publishBatch(ctx context.Context) (int, error) {
 	// 1. Читаем pending события
-	records, err := p.outboxRepo.GetPending(ctx, p.batchSize)
+	_, _, batchSize := p.polling()
+	records, err := p.outboxRepo.GetPending(ctx, batchSize)
 	if err != nil {
-		return fmt.Errorf("get pending records: %w", err)
+		return 0, fmt.Errorf("get pending records: %w", err)
 	}
 
 	if len(records) == 0 {
 		p.logger.Debug().Msg("no pending events to publish")
-		return nil
+		return 0, nil
 	}
 
 	p.logger.Info().
@@ -113,13 +379,18 @@ func (p *Publisher) publishBatch(ctx context.Context) error {
 
 	// Метрики для tracking
 	var (
-		published int
-		failed    int
-		marked    int
+		published   int
+		failed      int
+		marked      int
+		heldBack    int
+		breakerOpen bool
 	)
 
-	// 2. Публикуем каждое событие
-	for _, record := range records {
+	// 2. Публикуем события; в async режиме дожидаемся подтверждения всех
+	results := p.publishAll(ctx, records)
+
+	// 3. Помечаем результат каждого события
+	for i, record := range records {
 		eventLogger := p.logger.With().
 			Str("event_id", record.EventID).
 			Str("event_type", record.EventType).
@@ -127,15 +398,27 @@ func (p *Publisher) publishBatch(ctx context.Context) error {
 			Int64("outbox_id", record.ID).
 			Logger()
 
-		eventLogger.Debug().Msg("publishing event")
-
-		// Публикуем в Kafka
-		if err := p.producer.Publish(ctx, record.EventID, record.Payload); err != nil {
+		err := results[i]
+		if errors.Is(err, errHeldBack) {
+			// Остаётся pending: GetPending не отдаст его, пока предыдущее событие ждёт повтора
+			eventLogger.Debug().Msg("event held back behind failed aggregate event")
+			heldBack++
+			continue
+		}
+		if errors.Is(err, kafka.ErrCircuitOpen) {
+			// Брокер недоступен — событие не виновато: попытку не засчитываем,
+			// publisher уходит в backoff
+			breakerOpen = true
+			continue
+		}
+		if err != nil {
 			eventLogger.Error().
 				Err(err).
+				Int("attempt", record.Attempts+1).
 				Msg("failed to publish event to kafka")
 			failed++
-			continue // пропускаем, попробуем в следующий раз
+			p.recordFailure(ctx, record, err, eventLogger)
+			continue // пропускаем, попробуем после next_retry_at
 		}
 
 		published++
@@ -161,7 +444,129 @@ func (p *Publisher) publishBatch(ctx context.Context) error {
 		Int("published", published).
 		Int("failed", failed).
 		Int("marked", marked).
+		Int("held_back", heldBack).
 		Msg("batch processing completed")
 
-	return nil
+	p.metrics.Published.Add(int64(published))
+	p.metrics.Failed.Add(int64(failed))
+
+	if breakerOpen {
+		return len(records), fmt.Errorf("publish batch: %w", kafka.ErrCircuitOpen)
+	}
+	// Все события провалились — дренировать дальше бессмысленно, producer недоступен
+	if failed > 0 && failed+heldBack == len(records) {
+		return len(records), fmt.Errorf("all %d events failed to publish", failed)
+	}
+	return len(records), nil
+}
+
+// publishAll публикует batch и возвращает результат по каждой записи.
+// Async producer получает все события сразу и группирует их в свои batch'и; processed
+// помечается только после подтверждения доставки, поэтому async режим не теряет события.
+// После открытия breaker'а остальные записи не отправляются и получают ErrCircuitOpen.
+// В sync режиме после неудачи события агрегата его следующие события не отправляются
+// и получают errHeldBack, чтобы не обогнать упавшее.
+func (p *Publisher) publishAll(ctx context.Context, records []postgres.OutboxRecord) []error {
+	results := make([]error, len(records))
+	async, isAsync := p.producer.(AsyncEnvelopePublisher)
+
+	var wg sync.WaitGroup
+	failedAggregates := make(map[string]bool)
+	for i, record := range records {
+		if failedAggregates[record.AggregateID] {
+			results[i] = errHeldBack
+			continue
+		}
+		env, ts := p.buildEnvelope(record)
+
+		// Публикуем в Kafka (формат value определяется конфигом producer)
+		var err error
+		if isAsync {
+			wg.Add(1)
+			err = async.PublishEnvelopeAsync(ctx, env, ts, func(err error) {
+				results[i] = err
+				wg.Done()
+			})
+			if err != nil {
+				wg.Done()
+			}
+		} else {
+			err = p.producer.PublishEnvelope(ctx, env, ts)
+		}
+
+		if errors.Is(err, kafka.ErrCircuitOpen) {
+			p.logger.Warn().Err(err).Int64("outbox_id", record.ID).Msg("kafka circuit open, batch interrupted")
+			for j := i; j < len(records); j++ {
+				results[j] = kafka.ErrCircuitOpen
+			}
+			break
+		}
+		if err != nil {
+			results[i] = err
+			if record.AggregateID != "" {
+				failedAggregates[record.AggregateID] = true
+			}
+		}
+	}
+
+	// Ошибка записи приходит каждому событию в done
+	if f, ok := p.producer.(BatchFlusher); ok {
+		_ = f.Flush(ctx)
+	}
+	wg.Wait()
+	return results
+}
+
+// recordFailure учитывает неудачную попытку: откладывает повтор с экспоненциальной задержкой
+// или, если попытки исчерпаны, паркует событие, чтобы оно не публиковалось в каждом batch'е.
+// Слишком большое сообщение паркуется сразу: повторы его не уменьшат.
+func (p *Publisher) recordFailure(ctx context.Context, record postgres.OutboxRecord, publishErr error, logger zerolog.Logger) {
+	attempt := record.Attempts + 1
+
+	if attempt >= p.maxAttempts || errors.Is(publishErr, kafka.ErrMessageTooLarge) {
+		if err := p.outboxRepo.MarkDeadLetter(ctx, record.ID, publishErr.Error()); err != nil {
+			logger.Warn().Err(err).Msg("failed to move event to dead letter")
+			return
+		}
+		p.metrics.DeadLettered.Add(1)
+		logger.Warn().
+			Int("attempts", attempt).
+			Msg("event moved to dead letter")
+		return
+	}
+
+	if err := p.outboxRepo.MarkFailed(ctx, record.ID, publishErr.Error(), p.retryDelay(attempt)); err != nil {
+		logger.Warn().Err(err).Msg("failed to record publish attempt")
+	}
+}
+
+// retryDelay — задержка перед попыткой attempt+1: RetryBackoff * 2^(attempt-1), не больше MaxRetryBackoff
+func (p *Publisher) retryDelay(attempt int) time.Duration {
+	d := p.retryBackoff
+	for i := 1; i < attempt && d < p.maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.maxRetryBackoff)
+}
+
+// buildEnvelope заворачивает outbox запись в events.Envelope и выбирает timestamp сообщения.
+// Конверт несёт и occurred_at, и published_at, чтобы consumer мог посчитать задержку пайплайна.
+func (p *Publisher) buildEnvelope(record postgres.OutboxRecord) (events.Envelope, time.Time) {
+	now := p.clock()
+
+	ts := now
+	if p.timestamps == EventTime && !record.OccurredAt.IsZero() {
+		ts = record.OccurredAt
+	}
+
+	return events.Envelope{
+		EventID:       record.EventID,
+		EventType:     record.EventType,
+		SchemaVersion: record.SchemaVersion,
+		AggregateID:   record.AggregateID,
+		OccurredAt:    record.OccurredAt,
+		Sequence:      record.Sequence,
+		PublishedAt:   now,
+		Payload:       record.Payload,
+	}, ts
 }
@@ -0,0 +1,517 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+func testRecord(occurredAt time.Time) postgres.OutboxRecord {
+	return postgres.OutboxRecord{
+		ID:            1,
+		EventID:       "11111111-1111-1111-1111-111111111111",
+		EventType:     "MediaStatusChanged",
+		SchemaVersion: 1,
+		AggregateID:   "22222222-2222-2222-2222-222222222222",
+		Payload:       json.RawMessage(`{"from":"uploaded","to":"processing"}`),
+		OccurredAt:    occurredAt,
+	}
+}
+
+func TestBuildEnvelope_Timestamps(t *testing.T) {
+	occurred := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	now := occurred.Add(90 * time.Second) // отложенная публикация
+
+	cases := []struct {
+		name   string
+		source TimestampSource
+		want   time.Time
+	}{
+		{name: "event time", source: EventTime, want: occurred},
+		{name: "processing time", source: ProcessingTime, want: now},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Publisher{timestamps: tc.source, clock: func() time.Time { return now }}
+
+			env, ts := p.buildEnvelope(testRecord(occurred))
+			require.Equal(t, tc.want, ts)
+			require.Equal(t, "11111111-1111-1111-1111-111111111111", env.EventID)
+
+			// Конверт всегда несёт оба времени
+			require.Equal(t, 1, env.SchemaVersion)
+			require.True(t, occurred.Equal(env.OccurredAt))
+			require.True(t, now.Equal(env.PublishedAt))
+			require.Equal(t, "MediaStatusChanged", env.EventType)
+			require.JSONEq(t, `{"from":"uploaded","to":"processing"}`, string(env.Payload))
+		})
+	}
+}
+
+func TestBuildEnvelope_EventTimeFallsBackWhenMissing(t *testing.T) {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	p := &Publisher{timestamps: EventTime, clock: func() time.Time { return now }}
+
+	_, ts := p.buildEnvelope(testRecord(time.Time{}))
+	require.Equal(t, now, ts)
+}
+
+func TestNewPublisher_UnknownTimestampSource(t *testing.T) {
+	producer, err := kafka.NewProducer(kafka.ProducerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "test",
+		Logger:  zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	_, err = NewPublisher(PublisherConfig{
+		OutboxRepo: &postgres.OutboxRepo{},
+		Producer:   producer,
+		Interval:   time.Second,
+		BatchSize:  1,
+		Timestamps: TimestampSource(42),
+	})
+	require.ErrorContains(t, err, "unknown timestamp source")
+}
+
+// fakeStore — outbox в памяти: pending записи отдаются по порядку
+type fakeStore struct {
+	mu      sync.Mutex
+	pending []postgres.OutboxRecord
+	polls   int
+
+	failed       map[int64]time.Duration // id -> retryIn последней неудачи
+	deadLettered []int64
+}
+
+func (s *fakeStore) GetPending(ctx context.Context, limit int) ([]postgres.OutboxRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.polls++
+	n := min(limit, len(s.pending))
+	return append([]postgres.OutboxRecord(nil), s.pending[:n]...), nil
+}
+
+func (s *fakeStore) MarkProcessed(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.pending {
+		if r.ID == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed == nil {
+		s.failed = make(map[int64]time.Duration)
+	}
+	s.failed[id] = retryIn
+	return nil
+}
+
+func (s *fakeStore) MarkDeadLetter(ctx context.Context, id int64, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLettered = append(s.deadLettered, id)
+	return nil
+}
+
+func (s *fakeStore) CountPending(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.pending)), nil
+}
+
+type fakeProducer struct{ err error }
+
+func (p fakeProducer) PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error {
+	return p.err
+}
+
+func newTestPublisher(t *testing.T, store Store, producer EnvelopePublisher, threshold int64) *Publisher {
+	t.Helper()
+	p, err := NewPublisher(PublisherConfig{
+		OutboxRepo:       store,
+		Producer:         producer,
+		Interval:         time.Second,
+		MaxInterval:      8 * time.Second,
+		BatchSize:        2,
+		BacklogThreshold: threshold,
+		Logger:           zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestPublisher_NextDelay(t *testing.T) {
+	p := newTestPublisher(t, &fakeStore{}, fakeProducer{}, 0)
+
+	// Полный batch — дренируем без паузы
+	idle, delay := p.nextDelay(0, 2, nil)
+	require.Zero(t, idle)
+	require.Zero(t, delay)
+
+	// Неполный batch — обычный интервал, backoff сбрасывается
+	idle, delay = p.nextDelay(4*time.Second, 1, nil)
+	require.Zero(t, idle)
+	require.Equal(t, time.Second, delay)
+
+	// Пусто — экспоненциальный рост до MaxInterval
+	var got []time.Duration
+	idle = 0
+	for range 5 {
+		idle, delay = p.nextDelay(idle, 0, nil)
+		got = append(got, delay)
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}, got)
+
+	// Ошибка при полном batch'е — тоже backoff
+	_, delay = p.nextDelay(0, 2, errors.New("kafka down"))
+	require.Equal(t, time.Second, delay)
+}
+
+func TestPublisher_SetPolling(t *testing.T) {
+	p := newTestPublisher(t, &fakeStore{}, fakeProducer{}, 0)
+
+	require.Error(t, p.SetInterval(0))
+	require.Error(t, p.SetBatchSize(0))
+
+	require.NoError(t, p.SetBatchSize(5))
+	idle, delay := p.nextDelay(0, 2, nil)
+	require.Zero(t, idle)
+	require.Equal(t, time.Second, delay, "2 of 5 is a partial batch now")
+
+	// Интервал больше MaxInterval поднимает и потолок backoff
+	require.NoError(t, p.SetInterval(20*time.Second))
+	idle, _ = p.nextDelay(0, 0, nil)
+	_, delay = p.nextDelay(idle, 0, nil)
+	require.Equal(t, 20*time.Second, delay)
+}
+
+func TestPublisher_DrainsBacklogWithoutWaiting(t *testing.T) {
+	store := &fakeStore{}
+	for i := range 5 {
+		store.pending = append(store.pending, postgres.OutboxRecord{ID: int64(i + 1), EventID: "e", SchemaVersion: 1})
+	}
+	p := newTestPublisher(t, store, fakeProducer{}, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, p.Start(ctx), context.DeadlineExceeded)
+
+	// 2 + 2 + 1 — три опроса подряд, дальше пауза Interval (1s) не даёт опросить снова
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Empty(t, store.pending)
+	require.Equal(t, 3, store.polls)
+	require.Equal(t, int64(5), p.Metrics().Published.Load())
+	require.Zero(t, p.Metrics().Backlog.Load())
+}
+
+func TestPublisher_CheckBacklog(t *testing.T) {
+	store := &fakeStore{pending: make([]postgres.OutboxRecord, 3)}
+	p := newTestPublisher(t, store, fakeProducer{}, 2)
+	require.NoError(t, p.CheckBacklog(context.Background()))
+
+	p.refreshBacklog(context.Background())
+	require.Equal(t, int64(3), p.Metrics().Backlog.Load())
+	require.ErrorContains(t, p.CheckBacklog(context.Background()), "exceeds threshold")
+
+	// Порог 0 — проверка выключена
+	p.backlogThreshold = 0
+	require.NoError(t, p.CheckBacklog(context.Background()))
+}
+
+func TestPublisher_RetryDelay(t *testing.T) {
+	p := newTestPublisher(t, &fakeStore{}, fakeProducer{}, 0)
+
+	require.Equal(t, time.Second, p.retryDelay(1))
+	require.Equal(t, 2*time.Second, p.retryDelay(2))
+	require.Equal(t, 8*time.Second, p.retryDelay(4))
+	require.Equal(t, 10*time.Minute, p.retryDelay(15))
+	require.Equal(t, 10*time.Minute, p.retryDelay(1000))
+}
+
+func TestPublisher_ParksPoisonEvents(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{
+		{ID: 1, EventID: "a", SchemaVersion: 1, Attempts: 0},
+		{ID: 2, EventID: "b", SchemaVersion: 1, Attempts: 19}, // последняя попытка при MaxAttempts=20
+	}}
+	p := newTestPublisher(t, store, fakeProducer{err: errors.New("message too large")}, 0)
+
+	n, err := p.publishBatch(context.Background())
+	require.Equal(t, 2, n)
+	require.Error(t, err)
+
+	require.Equal(t, map[int64]time.Duration{1: time.Second}, store.failed)
+	require.Equal(t, []int64{2}, store.deadLettered)
+	require.Equal(t, int64(1), p.Metrics().DeadLettered.Load())
+}
+
+func TestPublisher_ParksOversizedEventsImmediately(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{{ID: 1, EventID: "a", SchemaVersion: 1}}}
+	p := newTestPublisher(t, store, fakeProducer{err: kafka.ErrMessageTooLarge}, 0)
+
+	_, err := p.publishBatch(context.Background())
+	require.Error(t, err)
+	require.Empty(t, store.failed)
+	require.Equal(t, []int64{1}, store.deadLettered)
+}
+
+// failingProducer роняет публикацию выбранных событий и запоминает отправленные
+type failingProducer struct {
+	failIDs map[string]bool
+	sent    []string
+}
+
+func (p *failingProducer) PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error {
+	if p.failIDs[env.EventID] {
+		return errors.New("not enough replicas")
+	}
+	p.sent = append(p.sent, env.EventID)
+	return nil
+}
+
+func TestPublisher_HoldsBackAggregateAfterFailure(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{
+		{ID: 1, EventID: "a1", AggregateID: "a", SchemaVersion: 1},
+		{ID: 2, EventID: "b1", AggregateID: "b", SchemaVersion: 1},
+		{ID: 3, EventID: "a2", AggregateID: "a", SchemaVersion: 1},
+	}}
+	producer := &failingProducer{failIDs: map[string]bool{"a1": true}}
+	p := newTestPublisher(t, store, producer, 0)
+	require.NoError(t, p.SetBatchSize(3))
+
+	_, err := p.publishBatch(context.Background())
+	require.NoError(t, err)
+
+	// Следующее событие агрегата не обгоняет упавшее и остаётся pending без попытки
+	require.Equal(t, []string{"b1"}, producer.sent)
+	require.Equal(t, map[int64]time.Duration{1: time.Second}, store.failed)
+	require.Len(t, store.pending, 2)
+	require.Equal(t, []int64{1, 3}, []int64{store.pending[0].ID, store.pending[1].ID})
+}
+
+// blockingProducer держит публикацию до release и запоминает сброс буфера
+type blockingProducer struct {
+	started chan struct{}
+	release chan struct{}
+	flushed chan struct{}
+}
+
+func newBlockingProducer() *blockingProducer {
+	return &blockingProducer{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+		flushed: make(chan struct{}),
+	}
+}
+
+func (p *blockingProducer) PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error {
+	p.started <- struct{}{}
+	select {
+	case <-p.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *blockingProducer) Shutdown(ctx context.Context) error {
+	close(p.flushed)
+	return nil
+}
+
+func TestPublisher_StopFinishesInFlightBatch(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{
+		{ID: 1, EventID: "a", SchemaVersion: 1},
+		{ID: 2, EventID: "b", SchemaVersion: 1},
+	}}
+	producer := newBlockingProducer()
+	p := newTestPublisher(t, store, producer, 0)
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- p.Start(context.Background()) }()
+	<-producer.started
+
+	stopErr := make(chan error, 1)
+	go func() { stopErr <- p.Stop(context.Background()) }()
+
+	// Stop ждёт batch, а не обрывает его
+	select {
+	case <-stopErr:
+		t.Fatal("Stop returned before in-flight batch completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(producer.release)
+
+	require.NoError(t, <-stopErr)
+	require.NoError(t, <-startErr)
+	<-producer.flushed
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Empty(t, store.pending)
+	require.Equal(t, 1, store.polls)
+}
+
+func TestPublisher_StopDrainTimeoutAbortsBatch(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{{ID: 1, EventID: "a", SchemaVersion: 1}}}
+	producer := newBlockingProducer()
+	p := newTestPublisher(t, store, producer, 0)
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- p.Start(context.Background()) }()
+	<-producer.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, p.Stop(ctx), context.DeadlineExceeded)
+	require.NoError(t, <-startErr)
+
+	// Неопубликованное событие осталось в outbox и уйдёт после рестарта
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Len(t, store.pending, 1)
+}
+
+func TestPublisher_StopBeforeStart(t *testing.T) {
+	p := newTestPublisher(t, &fakeStore{}, fakeProducer{}, 0)
+	require.NoError(t, p.Stop(context.Background()))
+	require.NoError(t, p.Start(context.Background()))
+}
+
+func TestPublisher_RestartAfterExit(t *testing.T) {
+	p := newTestPublisher(t, &fakeStore{}, fakeProducer{}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, p.Start(ctx), context.Canceled)
+
+	// supervisor перезапускает упавший Start; повторный запуск работает до Stop
+	startErr := make(chan error, 1)
+	go func() { startErr <- p.Start(context.Background()) }()
+	require.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.running != nil
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, p.Stop(context.Background()))
+	require.NoError(t, <-startErr)
+}
+
+func TestPublisher_CircuitOpenInterruptsBatch(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{
+		{ID: 1, EventID: "a", SchemaVersion: 1},
+		{ID: 2, EventID: "b", SchemaVersion: 1},
+	}}
+	p := newTestPublisher(t, store, fakeProducer{err: kafka.ErrCircuitOpen}, 0)
+
+	n, err := p.publishBatch(context.Background())
+	require.Equal(t, 2, n)
+	require.ErrorIs(t, err, kafka.ErrCircuitOpen)
+
+	// Недоступность брокера не расходует попытки событий
+	require.Empty(t, store.failed)
+	require.Empty(t, store.deadLettered)
+	require.Len(t, store.pending, 2)
+}
+
+// asyncProducer подтверждает доставку из другой горутины, как kafka-go writer
+type asyncProducer struct {
+	fakeProducer
+	failIDs map[string]error
+}
+
+func (p asyncProducer) PublishEnvelopeAsync(ctx context.Context, env events.Envelope, ts time.Time, done func(err error)) error {
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done(p.failIDs[env.EventID])
+	}()
+	return nil
+}
+
+func TestPublisher_AsyncMarksAfterDelivery(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{
+		{ID: 1, EventID: "a", SchemaVersion: 1},
+		{ID: 2, EventID: "b", SchemaVersion: 1},
+	}}
+	producer := asyncProducer{
+		// sync путь не должен использоваться
+		fakeProducer: fakeProducer{err: errors.New("sync path used")},
+		failIDs:      map[string]error{"b": errors.New("not enough replicas")},
+	}
+	p := newTestPublisher(t, store, producer, 0)
+
+	n, err := p.publishBatch(context.Background())
+	require.Equal(t, 2, n)
+	require.NoError(t, err)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Len(t, store.pending, 1)
+	require.Equal(t, int64(2), store.pending[0].ID)
+	require.Equal(t, map[int64]time.Duration{2: time.Second}, store.failed)
+	require.Equal(t, int64(1), p.Metrics().Published.Load())
+	require.Equal(t, int64(1), p.Metrics().Failed.Load())
+}
+
+// bufferingProducer подтверждает доставку только по Flush, как producer с KeyBatch
+type bufferingProducer struct {
+	fakeProducer
+	mu      sync.Mutex
+	pending []func(err error)
+	flushes int
+}
+
+func (p *bufferingProducer) PublishEnvelopeAsync(_ context.Context, _ events.Envelope, _ time.Time, done func(err error)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, done)
+	return nil
+}
+
+func (p *bufferingProducer) Flush(context.Context) error {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.flushes++
+	p.mu.Unlock()
+	for _, done := range pending {
+		done(nil)
+	}
+	return nil
+}
+
+func TestPublisher_FlushesBufferedProducer(t *testing.T) {
+	store := &fakeStore{pending: []postgres.OutboxRecord{
+		{ID: 1, EventID: "a", SchemaVersion: 1},
+		{ID: 2, EventID: "b", SchemaVersion: 1},
+	}}
+	producer := &bufferingProducer{}
+	p := newTestPublisher(t, store, producer, 0)
+
+	n, err := p.publishBatch(context.Background())
+	require.Equal(t, 2, n)
+	require.NoError(t, err)
+	require.Equal(t, 1, producer.flushes)
+	require.Empty(t, store.pending)
+	require.Equal(t, int64(2), p.Metrics().Published.Load())
+}
@@ -0,0 +1,125 @@
+package projection
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Имена встроенных проекций; совпадают с именами checkpoint'ов
+const (
+	MediaCountsName = "media_counts_by_status"
+	OwnerUsageName  = "owner_storage_usage"
+)
+
+// MediaStatusStore — состояние проекции media_counts_by_status: текущий статус каждого media
+type MediaStatusStore interface {
+	AddMedia(ctx context.Context, id, owner uuid.UUID, status models.Status) error
+	SetMediaStatus(ctx context.Context, id uuid.UUID, status models.Status) error
+	RemoveMedia(ctx context.Context, id uuid.UUID) error
+	ResetMediaStatuses(ctx context.Context) error
+}
+
+// OwnerUsageStore — состояние проекции owner_storage_usage
+type OwnerUsageStore interface {
+	// AddOwnerUsage прибавляет к объектам и байтам владельца (дельты бывают отрицательными)
+	AddOwnerUsage(ctx context.Context, owner uuid.UUID, objects, bytes int64) error
+	ResetOwnerUsage(ctx context.Context) error
+}
+
+// MediaCounts — проекция media_counts_by_status: сколько media в каждом статусе
+type MediaCounts struct {
+	store    MediaStatusStore
+	registry *events.Registry
+}
+
+func NewMediaCounts(store MediaStatusStore) *MediaCounts {
+	return &MediaCounts{store: store, registry: events.Default}
+}
+
+func (p *MediaCounts) Name() string { return MediaCountsName }
+
+func (p *MediaCounts) Apply(ctx context.Context, ev eventstore.Event) error {
+	payload, err := p.registry.DecodeLatest(ev.Envelope)
+	if err != nil {
+		return err
+	}
+	// MediaArchived и MediaQuarantined сопровождаются MediaStatusChanged
+	switch e := payload.(type) {
+	case *events.MediaCreatedV1:
+		return p.store.AddMedia(ctx, e.MediaID, e.OwnerID, e.Status)
+	case *events.MediaStatusChangedV1:
+		return p.store.SetMediaStatus(ctx, e.MediaID, e.To)
+	case *events.MediaDeletedV1:
+		return p.store.RemoveMedia(ctx, e.MediaID)
+	}
+	return nil
+}
+
+func (p *MediaCounts) Reset(ctx context.Context) error { return p.store.ResetMediaStatuses(ctx) }
+
+// OwnerUsage — проекция owner_storage_usage: число media и байты исходников владельца
+type OwnerUsage struct {
+	store    OwnerUsageStore
+	registry *events.Registry
+}
+
+func NewOwnerUsage(store OwnerUsageStore) *OwnerUsage {
+	return &OwnerUsage{store: store, registry: events.Default}
+}
+
+func (p *OwnerUsage) Name() string { return OwnerUsageName }
+
+func (p *OwnerUsage) Apply(ctx context.Context, ev eventstore.Event) error {
+	payload, err := p.registry.DecodeLatest(ev.Envelope)
+	if err != nil {
+		return err
+	}
+	switch e := payload.(type) {
+	case *events.MediaCreatedV1:
+		return p.store.AddOwnerUsage(ctx, e.OwnerID, 1, 0)
+	case *events.MediaContentRecordedV1:
+		// Повторная загрузка заменяет прежний исходник
+		return p.store.AddOwnerUsage(ctx, e.OwnerID, 0, e.Size-e.PreviousSize)
+	case *events.MediaDeletedV1:
+		return p.store.AddOwnerUsage(ctx, e.OwnerID, -1, -e.Size)
+	}
+	return nil
+}
+
+func (p *OwnerUsage) Reset(ctx context.Context) error { return p.store.ResetOwnerUsage(ctx) }
+
+// Stats — сводка read model'ей для GET /stats
+type Stats struct {
+	ByStatus map[models.Status]int64
+	Total    int64
+	Owners   []OwnerStats // по убыванию байт
+}
+
+// OwnerStats — использование хранилища владельцем
+type OwnerStats struct {
+	OwnerID uuid.UUID
+	Objects int64
+	Bytes   int64
+}
+
+// StatsFilter — выборка Stats
+type StatsFilter struct {
+	Owner *uuid.UUID // nil — все владельцы
+	Limit int        // владельцев в Owners (default: 20)
+}
+
+// DefaultStatsOwners — сколько владельцев Stats отдаёт без явного лимита
+const DefaultStatsOwners = 20
+
+// OwnersLimit — Limit с учётом значения по умолчанию
+func (f StatsFilter) OwnersLimit() int {
+	if f.Limit <= 0 {
+		return DefaultStatsOwners
+	}
+	return f.Limit
+}
@@ -0,0 +1,120 @@
+package projection
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+type mediaState struct {
+	owner  uuid.UUID
+	status models.Status
+}
+
+// Memory — checkpoint'ы и состояние встроенных проекций в памяти процесса: для тестов
+// и in-memory режима, до рестарта
+type Memory struct {
+	mu          sync.Mutex
+	checkpoints map[string]int64
+	media       map[uuid.UUID]mediaState
+	usage       map[uuid.UUID]OwnerStats
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		checkpoints: make(map[string]int64),
+		media:       make(map[uuid.UUID]mediaState),
+		usage:       make(map[uuid.UUID]OwnerStats),
+	}
+}
+
+func (m *Memory) Checkpoint(_ context.Context, name string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkpoints[name], nil
+}
+
+func (m *Memory) SaveCheckpoint(_ context.Context, name string, position int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[name] = position
+	return nil
+}
+
+func (m *Memory) AddMedia(_ context.Context, id, owner uuid.UUID, status models.Status) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.media[id] = mediaState{owner: owner, status: status}
+	return nil
+}
+
+func (m *Memory) SetMediaStatus(_ context.Context, id uuid.UUID, status models.Status) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.media[id]; ok {
+		s.status = status
+		m.media[id] = s
+	}
+	return nil
+}
+
+func (m *Memory) RemoveMedia(_ context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.media, id)
+	return nil
+}
+
+func (m *Memory) ResetMediaStatuses(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.media)
+	return nil
+}
+
+func (m *Memory) AddOwnerUsage(_ context.Context, owner uuid.UUID, objects, bytes int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.usage[owner]
+	u.OwnerID = owner
+	u.Objects += objects
+	u.Bytes += bytes
+	m.usage[owner] = u
+	return nil
+}
+
+func (m *Memory) ResetOwnerUsage(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.usage)
+	return nil
+}
+
+func (m *Memory) Stats(_ context.Context, f StatsFilter) (Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := Stats{ByStatus: make(map[models.Status]int64)}
+	for _, s := range m.media {
+		if f.Owner == nil || s.owner == *f.Owner {
+			st.ByStatus[s.status]++
+			st.Total++
+		}
+	}
+	for _, u := range m.usage {
+		if f.Owner == nil || u.OwnerID == *f.Owner {
+			st.Owners = append(st.Owners, u)
+		}
+	}
+	slices.SortFunc(st.Owners, func(a, b OwnerStats) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.OwnerID.String(), b.OwnerID.String()))
+	})
+	if len(st.Owners) > f.OwnersLimit() {
+		st.Owners = st.Owners[:f.OwnersLimit()]
+	}
+	return st, nil
+}
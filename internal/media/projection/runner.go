@@ -0,0 +1,223 @@
+// Package projection строит read model'и по журналу событий media (eventstore): каждая
+// проекция — обработчик событий со своим checkpoint'ом, её можно перестроить с нуля.
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Projection — read model, который строится по журналу событий
+type Projection interface {
+	Name() string
+	// Apply применяет событие. Вызывается в одной транзакции с сохранением checkpoint'а:
+	// проекция в той же БД применяет каждое событие ровно один раз.
+	Apply(ctx context.Context, ev eventstore.Event) error
+	// Reset удаляет состояние проекции перед перестроением
+	Reset(ctx context.Context) error
+}
+
+// Checkpoints — позиции проекций в журнале событий
+type Checkpoints interface {
+	// Checkpoint возвращает position последнего применённого события; 0 — проекция пуста.
+	// Внутри транзакции блокирует checkpoint до её конца — так одну проекцию не применяют
+	// параллельно несколько реплик.
+	Checkpoint(ctx context.Context, name string) (int64, error)
+	SaveCheckpoint(ctx context.Context, name string, position int64) error
+}
+
+// Transactor выполняет fn в транзакции; реализуется *postgres.TxManager
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Config содержит конфигурацию Runner
+type Config struct {
+	Feed        eventstore.Feed
+	Checkpoints Checkpoints
+	Tx          Transactor // nil — без транзакций (хранилища в памяти): после сбоя начало пачки применится повторно
+	Projections []Projection
+	BatchSize   int           // событий за одну транзакцию (default: 100)
+	Interval    time.Duration // пауза, когда проекции догнали журнал (default: 1s)
+	Logger      zerolog.Logger
+}
+
+// Runner применяет новые события журнала к проекциям
+type Runner struct {
+	feed        eventstore.Feed
+	checkpoints Checkpoints
+	tx          Transactor
+	projections []Projection
+	batchSize   int
+	interval    time.Duration
+	logger      zerolog.Logger
+}
+
+func NewRunner(cfg Config) (*Runner, error) {
+	if cfg.Feed == nil {
+		return nil, errors.New("feed is required")
+	}
+	if cfg.Checkpoints == nil {
+		return nil, errors.New("checkpoints are required")
+	}
+	if len(cfg.Projections) == 0 {
+		return nil, errors.New("at least one projection is required")
+	}
+	seen := make(map[string]bool, len(cfg.Projections))
+	for _, p := range cfg.Projections {
+		if p.Name() == "" || seen[p.Name()] {
+			return nil, fmt.Errorf("projection name %q is empty or duplicated", p.Name())
+		}
+		seen[p.Name()] = true
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("batch size cannot be negative, got: %d", cfg.BatchSize)
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %s", cfg.Interval)
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Second
+	}
+	return &Runner{
+		feed:        cfg.Feed,
+		checkpoints: cfg.Checkpoints,
+		tx:          cfg.Tx,
+		projections: cfg.Projections,
+		batchSize:   cfg.BatchSize,
+		interval:    cfg.Interval,
+		logger:      cfg.Logger.With().Str("component", "projections").Logger(),
+	}, nil
+}
+
+// Names возвращает имена проекций в порядке конфигурации
+func (r *Runner) Names() []string {
+	names := make([]string, 0, len(r.projections))
+	for _, p := range r.projections {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// Start догоняет журнал всеми проекциями, пока не отменят ctx. Ошибка проекции логируется,
+// проекция повторяет с того же checkpoint'а на следующем круге.
+func (r *Runner) Start(ctx context.Context) error {
+	r.logger.Info().Strs("projections", r.Names()).Dur("interval", r.interval).Msg("projections started")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info().Msg("projections stopped")
+			return ctx.Err()
+		case <-timer.C:
+			for _, p := range r.projections {
+				if _, err := r.catchUp(ctx, p); err != nil && ctx.Err() == nil {
+					r.logger.Error().Err(err).Str("projection", p.Name()).Msg("projection failed")
+				}
+			}
+			timer.Reset(r.interval)
+		}
+	}
+}
+
+// CatchUp применяет к проекции все новые события и возвращает их число
+func (r *Runner) CatchUp(ctx context.Context, name string) (int, error) {
+	p, err := r.projection(name)
+	if err != nil {
+		return 0, err
+	}
+	return r.catchUp(ctx, p)
+}
+
+// Rebuild удаляет состояние проекции и строит её заново по всему журналу
+func (r *Runner) Rebuild(ctx context.Context, name string) (int, error) {
+	p, err := r.projection(name)
+	if err != nil {
+		return 0, err
+	}
+	err = r.within(ctx, func(ctx context.Context) error {
+		// Блокирует checkpoint: Start других реплик ждёт конца сброса
+		if _, err := r.checkpoints.Checkpoint(ctx, p.Name()); err != nil {
+			return err
+		}
+		if err := p.Reset(ctx); err != nil {
+			return fmt.Errorf("reset: %w", err)
+		}
+		return r.checkpoints.SaveCheckpoint(ctx, p.Name(), 0)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("rebuild projection %s: %w", p.Name(), err)
+	}
+	r.logger.Info().Str("projection", p.Name()).Msg("projection reset, rebuilding")
+	return r.catchUp(ctx, p)
+}
+
+func (r *Runner) projection(name string) (Projection, error) {
+	for _, p := range r.projections {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown projection %q", models.ErrNotFound, name)
+}
+
+func (r *Runner) catchUp(ctx context.Context, p Projection) (int, error) {
+	total := 0
+	for {
+		n, err := r.step(ctx, p)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("projection %s: %w", p.Name(), err)
+		}
+		if n < r.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// step применяет одну пачку событий и сдвигает checkpoint в той же транзакции
+func (r *Runner) step(ctx context.Context, p Projection) (int, error) {
+	applied := 0
+	err := r.within(ctx, func(ctx context.Context) error {
+		applied = 0
+		pos, err := r.checkpoints.Checkpoint(ctx, p.Name())
+		if err != nil {
+			return err
+		}
+		batch, err := r.feed.ReadAll(ctx, pos, r.batchSize)
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+		for _, ev := range batch {
+			if err := p.Apply(ctx, ev); err != nil {
+				return fmt.Errorf("apply event %d (%s %s): %w", ev.Position, ev.EventType, ev.EventID, err)
+			}
+			applied++
+		}
+		return r.checkpoints.SaveCheckpoint(ctx, p.Name(), batch[len(batch)-1].Position)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+func (r *Runner) within(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.tx == nil {
+		return fn(ctx)
+	}
+	return r.tx.WithinTransaction(ctx, fn)
+}
@@ -0,0 +1,137 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func record(t *testing.T, s *eventstore.Memory, evs ...models.DomainEvent) {
+	t.Helper()
+	for _, ev := range evs {
+		env, err := events.Default.Wrap(ev)
+		require.NoError(t, err)
+		_, err = s.Append(context.Background(), ev.AggregateID(), eventstore.AnyVersion, env)
+		require.NoError(t, err)
+	}
+}
+
+// failingProjection падает на событиях, пока fail не сброшен
+type failingProjection struct {
+	fail    bool
+	applied []int64
+}
+
+func (p *failingProjection) Name() string { return "failing" }
+
+func (p *failingProjection) Apply(_ context.Context, ev eventstore.Event) error {
+	if p.fail {
+		return errors.New("boom")
+	}
+	p.applied = append(p.applied, ev.Position)
+	return nil
+}
+
+func (p *failingProjection) Reset(context.Context) error {
+	p.applied = nil
+	return nil
+}
+
+func TestRunner_MediaProjections(t *testing.T) {
+	ctx := context.Background()
+	log, state := eventstore.NewMemory(), NewMemory()
+	runner, err := NewRunner(Config{
+		Feed:        log,
+		Checkpoints: state,
+		Projections: []Projection{NewMediaCounts(state), NewOwnerUsage(state)},
+		BatchSize:   2,
+		Logger:      zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	alice, bob := uuid.New(), uuid.New()
+	a := &models.Media{ID: uuid.New(), OwnerID: alice, Type: models.Video, Status: models.UploadedStatus}
+	b := &models.Media{ID: uuid.New(), OwnerID: alice, Type: models.Audio, Status: models.UploadedStatus}
+	c := &models.Media{ID: uuid.New(), OwnerID: bob, Type: models.File, Status: models.UploadedStatus}
+	now := time.Now()
+	record(t, log,
+		models.NewMediaCreated(a), models.NewMediaCreated(b), models.NewMediaCreated(c),
+		models.NewMediaContentRecorded(a, models.Content{Checksum: "x", Size: 100, ContentType: "video/mp4"}, now),
+		models.NewMediaContentRecorded(c, models.Content{Checksum: "y", Size: 40, ContentType: "text/plain"}, now),
+		models.NewMediaStatusChanged(a.ID, alice, models.UploadedStatus, models.ProcessingStatus, "", ""),
+		models.NewMediaStatusChanged(a.ID, alice, models.ProcessingStatus, models.ReadyStatus, "", ""),
+	)
+
+	n, err := runner.CatchUp(ctx, MediaCountsName)
+	require.NoError(t, err)
+	require.Equal(t, 7, n)
+	_, err = runner.CatchUp(ctx, OwnerUsageName)
+	require.NoError(t, err)
+
+	st, err := state.Stats(ctx, StatsFilter{})
+	require.NoError(t, err)
+	require.Equal(t, map[models.Status]int64{models.ReadyStatus: 1, models.UploadedStatus: 2}, st.ByStatus)
+	require.Equal(t, int64(3), st.Total)
+	require.Equal(t, []OwnerStats{{OwnerID: alice, Objects: 2, Bytes: 100}, {OwnerID: bob, Objects: 1, Bytes: 40}}, st.Owners)
+
+	// Следующие события применяются с checkpoint'а
+	cDeleted := *c
+	cDeleted.Size = 40
+	record(t, log, models.NewMediaDeleted(&cDeleted, models.DeleteReasonDeleted, now))
+	n, err = runner.CatchUp(ctx, MediaCountsName)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = runner.CatchUp(ctx, OwnerUsageName)
+	require.NoError(t, err)
+
+	st, err = state.Stats(ctx, StatsFilter{Owner: &bob})
+	require.NoError(t, err)
+	require.Zero(t, st.Total)
+	require.Equal(t, []OwnerStats{{OwnerID: bob}}, st.Owners)
+
+	// Перестроение с нуля даёт то же состояние
+	n, err = runner.Rebuild(ctx, OwnerUsageName)
+	require.NoError(t, err)
+	require.Equal(t, 8, n)
+	st, err = state.Stats(ctx, StatsFilter{Owner: &alice})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), st.Total)
+	require.Equal(t, []OwnerStats{{OwnerID: alice, Objects: 2, Bytes: 100}}, st.Owners)
+
+	_, err = runner.Rebuild(ctx, "unknown")
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestRunner_FailedBatchIsRetried(t *testing.T) {
+	ctx := context.Background()
+	log, state := eventstore.NewMemory(), NewMemory()
+	p := &failingProjection{fail: true}
+	runner, err := NewRunner(Config{Feed: log, Checkpoints: state, Projections: []Projection{p}, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	m := &models.Media{ID: uuid.New(), Type: models.Video, Status: models.UploadedStatus}
+	record(t, log, models.NewMediaCreated(m))
+
+	_, err = runner.CatchUp(ctx, "failing")
+	require.ErrorContains(t, err, "boom")
+	pos, err := state.Checkpoint(ctx, "failing")
+	require.NoError(t, err)
+	require.Zero(t, pos, "checkpoint is not moved past a failed event")
+
+	p.fail = false
+	_, err = runner.CatchUp(ctx, "failing")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, p.applied)
+
+	_, err = NewRunner(Config{Feed: log, Checkpoints: state, Projections: []Projection{p, p}})
+	require.ErrorContains(t, err, "duplicated")
+}
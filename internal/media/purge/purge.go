@@ -0,0 +1,298 @@
+// Package purge — удаление всех данных владельца по запросу (GDPR, право на забвение).
+// Оператор ставит запрос (Purger.Request), выполняет его задача очереди jobs: пачками удаляются
+// исходники и renditions медиа владельца, затем строки медиа вместе с историей (журнал событий,
+// outbox, история статусов, журнал доставок уведомлений), в конце — записи самого владельца.
+// Каждый шаг идемпотентен, поэтому прерванная задача при повторе продолжает с того места,
+// где остановилась; отчёт (Report) накапливается в запросе по мере удаления.
+package purge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/processing/jobs"
+)
+
+const (
+	// Queue — очередь jobs, в которой выполняются удаления
+	Queue = "media-purge"
+	// Kind — тип задачи удаления данных владельца
+	Kind = "purge_owner"
+)
+
+// ErrNotFound — запроса на удаление с таким id нет
+var ErrNotFound = fmt.Errorf("purge %w", models.ErrNotFound)
+
+// Status — состояние запроса на удаление
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running" // с last_error — попытка упала и будет повторена
+	StatusCompleted Status = "completed"
+)
+
+// Report — сколько записей и объектов удалено
+type Report struct {
+	Media         int64 `json:"media"`
+	Blobs         int64 `json:"blobs"`      // исходники
+	Renditions    int64 `json:"renditions"` // объекты renditions и манифесты
+	Events        int64 `json:"events"`     // журнал событий и снапшоты
+	Outbox        int64 `json:"outbox"`
+	StatusHistory int64 `json:"status_history"`
+	Deliveries    int64 `json:"deliveries"`    // журнал доставок уведомлений
+	OwnerRecords  int64 `json:"owner_records"` // тариф, лимиты, учёт хранилища, коллекции владельца, выданный ему доступ, ключи идемпотентности
+}
+
+// Add прибавляет счётчики o
+func (r *Report) Add(o Report) {
+	r.Media += o.Media
+	r.Blobs += o.Blobs
+	r.Renditions += o.Renditions
+	r.Events += o.Events
+	r.Outbox += o.Outbox
+	r.StatusHistory += o.StatusHistory
+	r.Deliveries += o.Deliveries
+	r.OwnerRecords += o.OwnerRecords
+}
+
+// Purge — запрос на удаление данных владельца и его отчёт
+type Purge struct {
+	ID          int64
+	OwnerID     uuid.UUID
+	RequestedBy string
+	Status      Status
+	Report      Report
+	LastError   string
+	RequestedAt time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// Store хранит запросы и удаляет данные; реализуется *postgres.PurgeRepo
+type Store interface {
+	// CreatePurge создаёт запрос; незавершённый запрос того же владельца возвращается как есть
+	CreatePurge(ctx context.Context, ownerID uuid.UUID, requestedBy string) (Purge, error)
+	// GetPurge возвращает запрос; ErrNotFound, если его нет
+	GetPurge(ctx context.Context, id int64) (Purge, error)
+	// UpdatePurge сохраняет статус, отчёт и ошибку запроса
+	UpdatePurge(ctx context.Context, p Purge) error
+	// OwnerMedia возвращает до limit медиа владельца
+	OwnerMedia(ctx context.Context, ownerID uuid.UUID, limit int) ([]models.Media, error)
+	// DeleteMedia в одной транзакции удаляет медиа ids и всё, что к ним относится
+	DeleteMedia(ctx context.Context, ids []uuid.UUID) (Report, error)
+	// DeleteOwner удаляет записи самого владельца и оставшиеся доставки уведомлений о нём
+	DeleteOwner(ctx context.Context, ownerID uuid.UUID) (Report, error)
+}
+
+// Enqueuer ставит задачи; реализуется *postgres.JobsRepo
+type Enqueuer interface {
+	Enqueue(ctx context.Context, job jobs.Job) (jobs.Job, error)
+}
+
+// Config содержит конфигурацию Purger
+type Config struct {
+	Store Store
+	Jobs  Enqueuer
+	Blobs blob.Store
+	// Renditions — корень результатов packaging (s3://media-streaming/vod): renditions медиа
+	// лежат в Renditions/{media_id}/. Пустой — renditions не удаляются; иначе Blobs должен
+	// реализовывать blob.PrefixDeleter.
+	Renditions string
+	BatchSize  int // Медиа за один шаг (default: 100)
+	Logger     zerolog.Logger
+}
+
+// Purger принимает запросы на удаление и выполняет их задачами очереди Queue
+type Purger struct {
+	store      Store
+	jobs       Enqueuer
+	blobs      blob.Store
+	prefixes   blob.PrefixDeleter
+	renditions string
+	batchSize  int
+	clock      func() time.Time
+	logger     zerolog.Logger
+}
+
+func New(cfg Config) (*Purger, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("store is required")
+	}
+	if cfg.Jobs == nil {
+		return nil, errors.New("job queue is required")
+	}
+	if cfg.Blobs == nil {
+		return nil, errors.New("blob store is required")
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("batch size cannot be negative, got: %d", cfg.BatchSize)
+	}
+	prefixes, ok := cfg.Blobs.(blob.PrefixDeleter)
+	if cfg.Renditions != "" && !ok {
+		return nil, errors.New("renditions root is set but the blob store cannot delete by prefix")
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Purger{
+		store:      cfg.Store,
+		jobs:       cfg.Jobs,
+		blobs:      cfg.Blobs,
+		prefixes:   prefixes,
+		renditions: strings.TrimSuffix(cfg.Renditions, "/"),
+		batchSize:  cfg.BatchSize,
+		clock:      time.Now,
+		logger:     cfg.Logger.With().Str("component", "purge").Logger(),
+	}, nil
+}
+
+// payload — payload задачи Kind
+type payload struct {
+	PurgeID int64 `json:"purge_id"`
+}
+
+// Request ставит удаление всех данных владельца. Повторный запрос, пока удаление не
+// завершено, возвращает тот же запрос и заново ставит задачу, если прежняя исчерпала попытки.
+func (p *Purger) Request(ctx context.Context, ownerID uuid.UUID, requestedBy string) (Purge, error) {
+	if ownerID == uuid.Nil {
+		return Purge{}, fmt.Errorf("%w: owner_id is required", models.ErrInvalidArgument)
+	}
+	pr, err := p.store.CreatePurge(ctx, ownerID, requestedBy)
+	if err != nil {
+		return Purge{}, err
+	}
+	data, err := json.Marshal(payload{PurgeID: pr.ID})
+	if err != nil {
+		return Purge{}, err
+	}
+	_, err = p.jobs.Enqueue(ctx, jobs.Job{
+		Queue:     Queue,
+		Kind:      Kind,
+		Payload:   data,
+		UniqueKey: "purge:" + strconv.FormatInt(pr.ID, 10),
+	})
+	if err != nil {
+		return Purge{}, fmt.Errorf("enqueue purge: %w", err)
+	}
+	p.logger.Info().Int64("purge_id", pr.ID).Str("owner_id", ownerID.String()).Str("requested_by", requestedBy).Msg("owner purge requested")
+	return pr, nil
+}
+
+// Get возвращает запрос с отчётом
+func (p *Purger) Get(ctx context.Context, id int64) (Purge, error) {
+	return p.store.GetPurge(ctx, id)
+}
+
+// Handler — обработчик задач Kind для jobs.Worker
+func (p *Purger) Handler() jobs.Handler {
+	return p.run
+}
+
+func (p *Purger) run(ctx context.Context, job jobs.Job) error {
+	var pl payload
+	if err := json.Unmarshal(job.Payload, &pl); err != nil {
+		return fmt.Errorf("decode purge payload: %w", err)
+	}
+	pr, err := p.store.GetPurge(ctx, pl.PurgeID)
+	if err != nil {
+		return err
+	}
+	if pr.Status == StatusCompleted {
+		return nil
+	}
+
+	pr.Status = StatusRunning
+	if err := p.purge(ctx, &pr); err != nil {
+		pr.LastError = err.Error()
+		// Отчёт о том, что успело удалиться, сохраняется и при остановке worker'а
+		if saveErr := p.store.UpdatePurge(context.WithoutCancel(ctx), pr); saveErr != nil {
+			err = errors.Join(err, saveErr)
+		}
+		return err
+	}
+
+	now := p.clock()
+	pr.Status, pr.LastError, pr.CompletedAt = StatusCompleted, "", &now
+	if err := p.store.UpdatePurge(ctx, pr); err != nil {
+		return err
+	}
+	p.logger.Info().
+		Int64("purge_id", pr.ID).
+		Str("owner_id", pr.OwnerID.String()).
+		Int64("media", pr.Report.Media).
+		Int64("blobs", pr.Report.Blobs).
+		Int64("renditions", pr.Report.Renditions).
+		Int64("events", pr.Report.Events).
+		Int64("deliveries", pr.Report.Deliveries).
+		Msg("owner purge completed")
+	return nil
+}
+
+// purge удаляет медиа владельца пачками, затем записи владельца. Объекты хранилища
+// удаляются раньше строк: сбой между ними оставляет медиа в выборке, и повтор удалит их снова.
+func (p *Purger) purge(ctx context.Context, pr *Purge) error {
+	for {
+		batch, err := p.store.OwnerMedia(ctx, pr.OwnerID, p.batchSize)
+		if err != nil {
+			return fmt.Errorf("list owner media: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var step Report
+		ids := make([]uuid.UUID, len(batch))
+		for i, m := range batch {
+			ids[i] = m.ID
+			if err := p.deleteObjects(ctx, m, &step); err != nil {
+				return fmt.Errorf("media %s: %w", m.ID, err)
+			}
+		}
+		deleted, err := p.store.DeleteMedia(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("delete media: %w", err)
+		}
+		step.Add(deleted)
+		pr.Report.Add(step)
+		if err := p.store.UpdatePurge(ctx, *pr); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := p.store.DeleteOwner(ctx, pr.OwnerID)
+	if err != nil {
+		return fmt.Errorf("delete owner records: %w", err)
+	}
+	pr.Report.Add(deleted)
+	return nil
+}
+
+// deleteObjects удаляет исходник и renditions медиа
+func (p *Purger) deleteObjects(ctx context.Context, m models.Media, report *Report) error {
+	if m.Source != "" {
+		if err := p.blobs.Delete(ctx, m.Source); err != nil {
+			return fmt.Errorf("delete blob: %w", err)
+		}
+		report.Blobs++
+	}
+	if p.renditions != "" {
+		n, err := p.prefixes.DeletePrefix(ctx, p.renditions+"/"+m.ID.String()+"/")
+		if err != nil {
+			return fmt.Errorf("delete renditions: %w", err)
+		}
+		report.Renditions += int64(n)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/processing/jobs"
+)
+
+// fakeStore хранит медиа владельцев в памяти; у каждого медиа одно событие и одна запись истории
+type fakeStore struct {
+	purges  map[int64]Purge
+	media   []models.Media
+	owners  map[uuid.UUID]bool // владельцы с тарифом
+	failAt  int                // DeleteMedia с этим номером вызова падает
+	deletes int
+}
+
+func (s *fakeStore) CreatePurge(_ context.Context, ownerID uuid.UUID, requestedBy string) (Purge, error) {
+	for _, p := range s.purges {
+		if p.OwnerID == ownerID && p.Status != StatusCompleted {
+			return p, nil
+		}
+	}
+	p := Purge{ID: int64(len(s.purges) + 1), OwnerID: ownerID, RequestedBy: requestedBy, Status: StatusPending}
+	s.purges[p.ID] = p
+	return p, nil
+}
+
+func (s *fakeStore) GetPurge(_ context.Context, id int64) (Purge, error) {
+	p, ok := s.purges[id]
+	if !ok {
+		return Purge{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (s *fakeStore) UpdatePurge(_ context.Context, p Purge) error {
+	s.purges[p.ID] = p
+	return nil
+}
+
+func (s *fakeStore) OwnerMedia(_ context.Context, ownerID uuid.UUID, limit int) ([]models.Media, error) {
+	var out []models.Media
+	for _, m := range s.media {
+		if m.OwnerID == ownerID && len(out) < limit {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) DeleteMedia(_ context.Context, ids []uuid.UUID) (Report, error) {
+	s.deletes++
+	if s.deletes == s.failAt {
+		return Report{}, errors.New("connection reset")
+	}
+	n := len(s.media)
+	s.media = slices.DeleteFunc(s.media, func(m models.Media) bool { return slices.Contains(ids, m.ID) })
+	deleted := int64(n - len(s.media))
+	return Report{Media: deleted, Events: deleted, StatusHistory: deleted}, nil
+}
+
+func (s *fakeStore) DeleteOwner(_ context.Context, ownerID uuid.UUID) (Report, error) {
+	if !s.owners[ownerID] {
+		return Report{}, nil
+	}
+	delete(s.owners, ownerID)
+	return Report{OwnerRecords: 1}, nil
+}
+
+// fakeBlobs запоминает удалённые исходники; под каждым медиа два объекта renditions
+type fakeBlobs struct {
+	deleted  []string
+	prefixes []string
+}
+
+func (b *fakeBlobs) Archive(_ context.Context, source string) (string, error) { return source, nil }
+
+func (b *fakeBlobs) Delete(_ context.Context, source string) error {
+	b.deleted = append(b.deleted, source)
+	return nil
+}
+
+func (b *fakeBlobs) DeletePrefix(_ context.Context, prefix string) (int, error) {
+	b.prefixes = append(b.prefixes, prefix)
+	return 2, nil
+}
+
+type fakeQueue struct{ queued []jobs.Job }
+
+func (q *fakeQueue) Enqueue(_ context.Context, job jobs.Job) (jobs.Job, error) {
+	job.ID = int64(len(q.queued) + 1)
+	q.queued = append(q.queued, job)
+	return job, nil
+}
+
+func newTestPurger(t *testing.T, store *fakeStore, blobs *fakeBlobs, queue *fakeQueue) *Purger {
+	t.Helper()
+	p, err := New(Config{
+		Store:      store,
+		Jobs:       queue,
+		Blobs:      blobs,
+		Renditions: "s3://vod/",
+		BatchSize:  2,
+		Logger:     zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestPurger_PurgesOwnerAndResumes(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	store := &fakeStore{purges: map[int64]Purge{}, owners: map[uuid.UUID]bool{owner: true, other: true}, failAt: 2}
+	for range 3 {
+		store.media = append(store.media, models.Media{ID: uuid.New(), OwnerID: owner, Source: "s3://media/" + uuid.NewString()})
+	}
+	kept := models.Media{ID: uuid.New(), OwnerID: other, Source: "s3://media/kept.mp4"}
+	store.media = append(store.media, kept)
+	blobs, queue := &fakeBlobs{}, &fakeQueue{}
+	p := newTestPurger(t, store, blobs, queue)
+	ctx := context.Background()
+
+	pr, err := p.Request(ctx, owner, "admin@ops")
+	require.NoError(t, err)
+	require.Len(t, queue.queued, 1)
+	job := queue.queued[0]
+	require.Equal(t, Queue, job.Queue)
+	require.Equal(t, Kind, job.Kind)
+
+	// Вторая пачка падает: первая уже удалена и учтена в отчёте
+	require.Error(t, p.Handler()(ctx, job))
+	got, err := p.Get(ctx, pr.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusRunning, got.Status)
+	require.Contains(t, got.LastError, "connection reset")
+	require.Equal(t, int64(2), got.Report.Media)
+
+	// Повтор задачи продолжает с оставшегося медиа
+	require.NoError(t, p.Handler()(ctx, job))
+	got, err = p.Get(ctx, pr.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Empty(t, got.LastError)
+	require.NotNil(t, got.CompletedAt)
+	// Объекты упавшей пачки удалены дважды, но в отчёт попали один раз
+	require.Len(t, blobs.deleted, 4)
+	require.Equal(t, Report{Media: 3, Blobs: 3, Renditions: 6, Events: 3, StatusHistory: 3, OwnerRecords: 1}, got.Report)
+	require.Equal(t, []models.Media{kept}, store.media)
+	require.NotContains(t, blobs.deleted, kept.Source)
+	for _, prefix := range blobs.prefixes {
+		require.True(t, strings.HasPrefix(prefix, "s3://vod/") && strings.HasSuffix(prefix, "/"), prefix)
+		require.NotContains(t, prefix, kept.ID.String())
+	}
+	require.True(t, store.owners[other])
+
+	// Завершённый запрос повторно не выполняется
+	require.NoError(t, p.Handler()(ctx, job))
+	require.Equal(t, 3, store.deletes)
+}
+
+func TestPurger_Request(t *testing.T) {
+	store, queue := &fakeStore{purges: map[int64]Purge{}}, &fakeQueue{}
+	p := newTestPurger(t, store, &fakeBlobs{}, queue)
+	ctx := context.Background()
+
+	_, err := p.Request(ctx, uuid.Nil, "admin")
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	// Незавершённый запрос того же владельца переиспользуется
+	owner := uuid.New()
+	first, err := p.Request(ctx, owner, "admin")
+	require.NoError(t, err)
+	second, err := p.Request(ctx, owner, "admin")
+	require.NoError(t, err)
+	require.Equal(t, first.ID, second.ID)
+	require.Equal(t, queue.queued[0].UniqueKey, queue.queued[1].UniqueKey)
+
+	_, err = p.Get(ctx, 42)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestNew_RenditionsNeedPrefixDeleter(t *testing.T) {
+	_, err := New(Config{Store: &fakeStore{}, Jobs: &fakeQueue{}, Blobs: sourceOnly{}, Renditions: "s3://vod"})
+	require.ErrorContains(t, err, "prefix")
+
+	_, err = New(Config{Store: &fakeStore{}, Jobs: &fakeQueue{}, Blobs: sourceOnly{}})
+	require.NoError(t, err)
+}
+
+type sourceOnly struct{}
+
+func (sourceOnly) Archive(_ context.Context, source string) (string, error) { return source, nil }
+func (sourceOnly) Delete(context.Context, string) error                     { return nil }
@@ -0,0 +1,181 @@
+// Package replay переигрывает опубликованные события media из outbox и его архива — например, чтобы
+// наполнить историей нового consumer'а без ручного SQL.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// Режимы переигрывания
+const (
+	// ModeOutbox снова ставит события в outbox: publisher опубликует их в их обычные топики
+	// с прежними event_id, consumer'ы с inbox пропустят уже обработанное
+	ModeOutbox = "outbox"
+	// ModeTopic публикует события напрямую в отдельный топик; обычные consumer'ы их не видят
+	ModeTopic = "topic"
+)
+
+// DefaultTopic — топик ModeTopic по умолчанию
+const DefaultTopic = "events.media.replay"
+
+// Store — опубликованные события outbox и outbox_archive; реализуется *postgres.OutboxRepo
+type Store interface {
+	ListProcessed(ctx context.Context, f postgres.OutboxReplayFilter, afterID int64, limit int) ([]postgres.OutboxRecord, error)
+	RequeueProcessed(ctx context.Context, f postgres.OutboxReplayFilter, limit int) (int64, error)
+}
+
+// TopicPublisher публикует конверт в заданный топик; реализуется *kafka.Producer
+type TopicPublisher interface {
+	PublishEnvelopeTo(ctx context.Context, topic string, env events.Envelope, ts time.Time) error
+}
+
+// Request — что и как переиграть
+type Request struct {
+	Filter postgres.OutboxReplayFilter
+	Mode   string
+	Topic  string // ModeTopic: пустой — топик из Config
+	Limit  int    // не больше стольких первых событий; 0 — все подходящие
+}
+
+// Validate проверяет запрос: нужен агрегат или интервал времени — переиграть всю историю
+// одним запросом слишком легко по ошибке
+func (r Request) Validate() error {
+	f := r.Filter
+	if f.AggregateID == "" && f.From.IsZero() && f.To.IsZero() {
+		return fmt.Errorf("%w: aggregate id or time range is required", models.ErrInvalidArgument)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("%w: from must be before to", models.ErrInvalidArgument)
+	}
+	if r.Mode != ModeOutbox && r.Mode != ModeTopic {
+		return fmt.Errorf("%w: unknown replay mode %q, want %s or %s", models.ErrInvalidArgument, r.Mode, ModeOutbox, ModeTopic)
+	}
+	if r.Limit < 0 {
+		return fmt.Errorf("%w: limit cannot be negative", models.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Result — итог переигрывания
+type Result struct {
+	Mode     string
+	Topic    string // ModeTopic
+	Replayed int64  // ModeOutbox — поставлено в очередь, ModeTopic — опубликовано
+}
+
+// Config содержит конфигурацию Replayer
+type Config struct {
+	Store     Store
+	Producer  TopicPublisher // nil — ModeTopic недоступен
+	Topic     string         // default: DefaultTopic
+	BatchSize int            // событий за один запрос к Store в ModeTopic (default: 100)
+	Logger    zerolog.Logger
+}
+
+// Replayer переигрывает опубликованные события outbox
+type Replayer struct {
+	store     Store
+	producer  TopicPublisher
+	topic     string
+	batchSize int
+	clock     func() time.Time
+	logger    zerolog.Logger
+}
+
+func New(cfg Config) (*Replayer, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("batch size cannot be negative, got: %d", cfg.BatchSize)
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = DefaultTopic
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	return &Replayer{
+		store:     cfg.Store,
+		producer:  cfg.Producer,
+		topic:     cfg.Topic,
+		batchSize: cfg.BatchSize,
+		clock:     time.Now,
+		logger:    cfg.Logger.With().Str("component", "event_replay").Logger(),
+	}, nil
+}
+
+// Replay переигрывает события по запросу. В ModeTopic события публикуются по порядку
+// со временем возникновения в timestamp; при ошибке Result содержит уже опубликованные.
+func (r *Replayer) Replay(ctx context.Context, req Request) (Result, error) {
+	if err := req.Validate(); err != nil {
+		return Result{}, err
+	}
+	res := Result{Mode: req.Mode}
+	logger := r.logger.With().
+		Str("mode", req.Mode).
+		Str("aggregate_id", req.Filter.AggregateID).
+		Str("event_type", req.Filter.EventType).
+		Time("from", req.Filter.From).
+		Time("to", req.Filter.To).
+		Logger()
+
+	if req.Mode == ModeOutbox {
+		n, err := r.store.RequeueProcessed(ctx, req.Filter, req.Limit)
+		if err != nil {
+			return res, err
+		}
+		res.Replayed = n
+		logger.Info().Int64("replayed", n).Msg("events requeued to outbox")
+		return res, nil
+	}
+
+	if r.producer == nil {
+		return res, fmt.Errorf("%w: replay to topic is not configured", models.ErrInvalidArgument)
+	}
+	res.Topic = req.Topic
+	if res.Topic == "" {
+		res.Topic = r.topic
+	}
+	var afterID int64
+	for req.Limit == 0 || res.Replayed < int64(req.Limit) {
+		limit := r.batchSize
+		if req.Limit > 0 {
+			limit = min(limit, req.Limit-int(res.Replayed))
+		}
+		records, err := r.store.ListProcessed(ctx, req.Filter, afterID, limit)
+		if err != nil {
+			return res, err
+		}
+		for _, rec := range records {
+			env := events.Envelope{
+				EventID:       rec.EventID,
+				EventType:     rec.EventType,
+				SchemaVersion: rec.SchemaVersion,
+				AggregateID:   rec.AggregateID,
+				OccurredAt:    rec.OccurredAt,
+				Sequence:      rec.Sequence,
+				PublishedAt:   r.clock(),
+				Payload:       rec.Payload,
+			}
+			if err := r.producer.PublishEnvelopeTo(ctx, res.Topic, env, rec.OccurredAt); err != nil {
+				return res, fmt.Errorf("replay outbox %d: %w", rec.ID, err)
+			}
+			res.Replayed++
+			afterID = rec.ID
+		}
+		if len(records) < limit {
+			break
+		}
+	}
+	logger.Info().Str("topic", res.Topic).Int64("replayed", res.Replayed).Msg("events replayed to topic")
+	return res, nil
+}
@@ -0,0 +1,133 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+type fakeStore struct {
+	records   []postgres.OutboxRecord
+	requeued  int64
+	gotFilter postgres.OutboxReplayFilter
+	gotLimit  int
+	pages     int
+}
+
+func (s *fakeStore) ListProcessed(_ context.Context, f postgres.OutboxReplayFilter, afterID int64, limit int) ([]postgres.OutboxRecord, error) {
+	s.gotFilter = f
+	s.pages++
+	var out []postgres.OutboxRecord
+	for _, rec := range s.records {
+		if rec.ID > afterID && len(out) < limit {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) RequeueProcessed(_ context.Context, f postgres.OutboxReplayFilter, limit int) (int64, error) {
+	s.gotFilter, s.gotLimit = f, limit
+	return s.requeued, nil
+}
+
+type published struct {
+	topic string
+	env   events.Envelope
+	ts    time.Time
+}
+
+type fakeProducer struct {
+	msgs   []published
+	failAt int // 1-based; 0 — без ошибок
+}
+
+func (p *fakeProducer) PublishEnvelopeTo(_ context.Context, topic string, env events.Envelope, ts time.Time) error {
+	if p.failAt > 0 && len(p.msgs)+1 == p.failAt {
+		return errors.New("broker down")
+	}
+	p.msgs = append(p.msgs, published{topic, env, ts})
+	return nil
+}
+
+func newStore(n int) *fakeStore {
+	s := &fakeStore{}
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		s.records = append(s.records, postgres.OutboxRecord{
+			ID: int64(i), EventID: fmt.Sprintf("e-%d", i), EventType: "MediaCreated", SchemaVersion: 1,
+			AggregateID: "a-1", Payload: json.RawMessage(`{}`), OccurredAt: at.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return s
+}
+
+func TestReplayer_Topic(t *testing.T) {
+	store, producer := newStore(5), &fakeProducer{}
+	r, err := New(Config{Store: store, Producer: producer, BatchSize: 2, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	res, err := r.Replay(ctx, Request{Filter: postgres.OutboxReplayFilter{AggregateID: "a-1"}, Mode: ModeTopic})
+	require.NoError(t, err)
+	require.Equal(t, Result{Mode: ModeTopic, Topic: DefaultTopic, Replayed: 5}, res)
+	require.Len(t, producer.msgs, 5)
+	require.Equal(t, "e-1", producer.msgs[0].env.EventID)
+	require.Equal(t, store.records[0].OccurredAt, producer.msgs[0].ts)
+	require.Equal(t, 3, store.pages)
+
+	// Limit и топик из запроса
+	producer.msgs = nil
+	res, err = r.Replay(ctx, Request{Filter: postgres.OutboxReplayFilter{AggregateID: "a-1"}, Mode: ModeTopic, Topic: "backfill.search", Limit: 3})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), res.Replayed)
+	require.Equal(t, "backfill.search", producer.msgs[2].topic)
+
+	// Ошибка публикации: в результате — уже опубликованные
+	producer.msgs, producer.failAt = nil, 2
+	res, err = r.Replay(ctx, Request{Filter: postgres.OutboxReplayFilter{AggregateID: "a-1"}, Mode: ModeTopic})
+	require.ErrorContains(t, err, "replay outbox 2")
+	require.Equal(t, int64(1), res.Replayed)
+}
+
+func TestReplayer_Outbox(t *testing.T) {
+	store := newStore(0)
+	store.requeued = 7
+	r, err := New(Config{Store: store, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	f := postgres.OutboxReplayFilter{EventType: "MediaDeleted", From: from, To: from.Add(24 * time.Hour)}
+	res, err := r.Replay(context.Background(), Request{Filter: f, Mode: ModeOutbox, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, Result{Mode: ModeOutbox, Replayed: 7}, res)
+	require.Equal(t, f, store.gotFilter)
+	require.Equal(t, 10, store.gotLimit)
+
+	// Без producer топик недоступен
+	_, err = r.Replay(context.Background(), Request{Filter: f, Mode: ModeTopic})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+}
+
+func TestRequest_Validate(t *testing.T) {
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for name, req := range map[string]Request{
+		"no filter":    {Mode: ModeOutbox},
+		"empty range":  {Filter: postgres.OutboxReplayFilter{From: at, To: at}, Mode: ModeOutbox},
+		"unknown mode": {Filter: postgres.OutboxReplayFilter{AggregateID: "a"}, Mode: "kafka"},
+		"bad limit":    {Filter: postgres.OutboxReplayFilter{AggregateID: "a"}, Mode: ModeTopic, Limit: -1},
+	} {
+		require.ErrorIs(t, req.Validate(), models.ErrInvalidArgument, name)
+	}
+	require.NoError(t, Request{Filter: postgres.OutboxReplayFilter{From: at}, Mode: ModeTopic}.Validate())
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// CollectionRepository хранит коллекции и их элементы. Транзакцию методы берут из ctx,
+// как и MediaRepository: сервис открывает её через MediaRepository.WithinTransaction.
+// Position элементов, которые отдаёт репозиторий, — место в коллекции с нуля, без пропусков.
+type CollectionRepository interface {
+	Create(ctx context.Context, c *models.Collection) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Collection, error)
+	// GetForUpdate внутри транзакции блокирует коллекцию до её конца: изменения состава
+	// одной коллекции идут по очереди
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Collection, error)
+	List(ctx context.Context, filter CollectionFilter) ([]*models.Collection, error)
+	Update(ctx context.Context, id uuid.UUID, patch models.CollectionPatch) (*models.Collection, error)
+	// Delete удаляет коллекцию вместе с элементами
+	Delete(ctx context.Context, id uuid.UUID) (*models.Collection, error)
+
+	// Items — элементы коллекции по порядку; существование коллекции не проверяет
+	Items(ctx context.Context, collectionID uuid.UUID) ([]models.CollectionItem, error)
+	// AddItem вставляет медиа на место item.Position (от 0 до числа элементов), сдвигая
+	// следующие. Медиа уже в коллекции — models.ErrConflict.
+	AddItem(ctx context.Context, item models.CollectionItem) error
+	// RemoveItem убирает медиа из коллекции и возвращает элемент с местом, которое он занимал
+	RemoveItem(ctx context.Context, collectionID, mediaID uuid.UUID) (models.CollectionItem, error)
+	// RemoveMedia убирает медиа из всех коллекций (медиа удаляется)
+	RemoveMedia(ctx context.Context, mediaID uuid.UUID) ([]models.CollectionItem, error)
+}
+
+// CollectionFilter — фильтр и пагинация списка коллекций; порядок — по created_at, при равных по id
+type CollectionFilter struct {
+	OwnerID   uuid.UUID   // uuid.Nil — любой владелец
+	Ascending bool        // false — новые первыми
+	After     *ListCursor // страница начинается сразу за этой позицией (created_at, id)
+	Limit     int         // <= 0 — DefaultListLimit
+}
+
+// CollectionCursor — позиция коллекции c в списке
+func CollectionCursor(c *models.Collection) ListCursor {
+	return ListCursor{At: c.CreatedAt, ID: c.ID}
+}
+
+// MemoryCollectionRepository — CollectionRepository в памяти для in-memory режима и тестов
+type MemoryCollectionRepository struct {
+	mu    sync.RWMutex
+	data  map[uuid.UUID]*models.Collection
+	items map[uuid.UUID][]models.CollectionItem // по порядку
+}
+
+func NewMemoryCollectionRepository() *MemoryCollectionRepository {
+	return &MemoryCollectionRepository{
+		data:  make(map[uuid.UUID]*models.Collection),
+		items: make(map[uuid.UUID][]models.CollectionItem),
+	}
+}
+
+func (r *MemoryCollectionRepository) Create(ctx context.Context, c *models.Collection) error {
+	if c == nil || c.ID == uuid.Nil {
+		return models.ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.data[c.ID]; exists {
+		return models.ErrConflict
+	}
+	cp := *c
+	cp.ItemCount = 0
+	r.data[c.ID] = &cp
+	return nil
+}
+
+func (r *MemoryCollectionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.data[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return r.copyOf(c), nil
+}
+
+// GetForUpdate — см. CollectionRepository; транзакций в памяти нет, поэтому без блокировки
+func (r *MemoryCollectionRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	return r.GetByID(ctx, id)
+}
+
+// List возвращает страницу коллекций в порядке filter (как Postgres репозиторий)
+func (r *MemoryCollectionRepository) List(ctx context.Context, filter CollectionFilter) ([]*models.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	order := ListFilter{Sort: SortCreatedAt, Ascending: filter.Ascending}
+
+	r.mu.RLock()
+	list := make([]*models.Collection, 0, len(r.data))
+	for _, c := range r.data {
+		if filter.OwnerID != uuid.Nil && c.OwnerID != filter.OwnerID {
+			continue
+		}
+		if filter.After != nil && !order.before(*filter.After, CollectionCursor(c)) {
+			continue
+		}
+		list = append(list, r.copyOf(c))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return order.before(CollectionCursor(list[i]), CollectionCursor(list[j]))
+	})
+	if len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+func (r *MemoryCollectionRepository) Update(ctx context.Context, id uuid.UUID, patch models.CollectionPatch) (*models.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.data[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if !patch.IsEmpty() {
+		patch.Apply(c)
+		c.UpdatedAt = time.Now()
+	}
+	return r.copyOf(c), nil
+}
+
+func (r *MemoryCollectionRepository) Delete(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.data[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	cp := r.copyOf(c)
+	delete(r.data, id)
+	delete(r.items, id)
+	return cp, nil
+}
+
+func (r *MemoryCollectionRepository) Items(ctx context.Context, collectionID uuid.UUID) ([]models.CollectionItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.items[collectionID]), nil
+}
+
+func (r *MemoryCollectionRepository) AddItem(ctx context.Context, item models.CollectionItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.data[item.CollectionID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	items := r.items[item.CollectionID]
+	if item.Position < 0 || item.Position > len(items) {
+		return fmt.Errorf("%w: position must be between 0 and %d", models.ErrInvalidArgument, len(items))
+	}
+	if slices.ContainsFunc(items, func(it models.CollectionItem) bool { return it.MediaID == item.MediaID }) {
+		return fmt.Errorf("%w: media %s is already in the collection", models.ErrConflict, item.MediaID)
+	}
+	r.items[item.CollectionID] = renumber(slices.Insert(items, item.Position, item))
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *MemoryCollectionRepository) RemoveItem(ctx context.Context, collectionID, mediaID uuid.UUID) (models.CollectionItem, error) {
+	if err := ctx.Err(); err != nil {
+		return models.CollectionItem{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.data[collectionID]
+	if !ok {
+		return models.CollectionItem{}, models.ErrNotFound
+	}
+	items := r.items[collectionID]
+	i := slices.IndexFunc(items, func(it models.CollectionItem) bool { return it.MediaID == mediaID })
+	if i < 0 {
+		return models.CollectionItem{}, models.ErrNotFound
+	}
+	removed := items[i]
+	r.items[collectionID] = renumber(slices.Delete(items, i, i+1))
+	c.UpdatedAt = time.Now()
+	return removed, nil
+}
+
+func (r *MemoryCollectionRepository) RemoveMedia(ctx context.Context, mediaID uuid.UUID) ([]models.CollectionItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed []models.CollectionItem
+	for id, items := range r.items {
+		i := slices.IndexFunc(items, func(it models.CollectionItem) bool { return it.MediaID == mediaID })
+		if i < 0 {
+			continue
+		}
+		removed = append(removed, items[i])
+		r.items[id] = renumber(slices.Delete(items, i, i+1))
+		r.data[id].UpdatedAt = time.Now()
+	}
+	// Порядок — как в Postgres репозитории
+	slices.SortFunc(removed, func(a, b models.CollectionItem) int {
+		return strings.Compare(a.CollectionID.String(), b.CollectionID.String())
+	})
+	return removed, nil
+}
+
+// copyOf — копия коллекции с актуальным числом элементов; вызывается под r.mu
+func (r *MemoryCollectionRepository) copyOf(c *models.Collection) *models.Collection {
+	cp := *c
+	cp.ItemCount = len(r.items[c.ID])
+	return &cp
+}
+
+// renumber проставляет элементам места по порядку
+func renumber(items []models.CollectionItem) []models.CollectionItem {
+	for i := range items {
+		items[i].Position = i
+	}
+	return items
+}
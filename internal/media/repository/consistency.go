@@ -0,0 +1,17 @@
+package repository
+
+import "context"
+
+type readPrimaryKey struct{}
+
+// WithReadPrimary помечает контекст: чтения идут в primary, минуя реплики.
+// Нужен для read-your-writes — сразу после записи реплика может ещё не догнать primary.
+func WithReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// ReadPrimary сообщает, требуется ли чтение из primary
+func ReadPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(readPrimaryKey{}).(bool)
+	return v
+}
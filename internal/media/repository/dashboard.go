@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DashboardQuery — выборка сводки для дашбордов
+type DashboardQuery struct {
+	OwnerID uuid.UUID // uuid.Nil — любой владелец
+	Since   time.Time // начало окна для приёма и обработки
+}
+
+// Dashboard — агрегаты по media: текущие счётчики и активность за окно [Since, now)
+type Dashboard struct {
+	ByStatus map[models.Status]int64
+	ByType   map[models.MediaType]int64
+	Total    int64
+
+	Created   int64 // media создано за окно
+	Completed int64 // переходов processing → ready за окно
+	Failed    int64 // переходов processing → failed за окно
+
+	// AvgProcessing — среднее время от входа в processing до ready/failed
+	// по завершённым за окно обработкам; 0, если их не было
+	AvgProcessing time.Duration
+}
+
+// FailureRate — доля неудачных обработок среди завершённых за окно
+func (d Dashboard) FailureRate() float64 {
+	if d.Completed+d.Failed == 0 {
+		return 0
+	}
+	return float64(d.Failed) / float64(d.Completed+d.Failed)
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// GrantRepository хранит ACL медиа: кому из не-владельцев медиа открыто и с каким правом.
+// Транзакцию методы берут из ctx, как и MediaRepository.
+type GrantRepository interface {
+	// Get — право principal на медиа; нет записи — models.ErrNotFound
+	Get(ctx context.Context, mediaID, principal uuid.UUID) (models.Grant, error)
+	// Put выдаёт право или меняет уже выданное и возвращает запись; CreatedAt
+	// существующей записи сохраняется
+	Put(ctx context.Context, g models.Grant) (models.Grant, error)
+	// Revoke удаляет запись и возвращает её; нет записи — models.ErrNotFound
+	Revoke(ctx context.Context, mediaID, principal uuid.UUID) (models.Grant, error)
+	// List — записи медиа в порядке выдачи
+	List(ctx context.Context, mediaID uuid.UUID) ([]models.Grant, error)
+}
+
+type grantKey struct {
+	mediaID   uuid.UUID
+	principal uuid.UUID
+}
+
+// MemoryGrantRepository — GrantRepository в памяти для in-memory режима и тестов.
+// Записи удалённого медиа остаются, но не действуют: медиа уже не найти.
+type MemoryGrantRepository struct {
+	mu   sync.RWMutex
+	data map[grantKey]models.Grant
+}
+
+func NewMemoryGrantRepository() *MemoryGrantRepository {
+	return &MemoryGrantRepository{data: make(map[grantKey]models.Grant)}
+}
+
+func (r *MemoryGrantRepository) Get(ctx context.Context, mediaID, principal uuid.UUID) (models.Grant, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Grant{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, ok := r.data[grantKey{mediaID, principal}]
+	if !ok {
+		return models.Grant{}, models.ErrNotFound
+	}
+	return g, nil
+}
+
+func (r *MemoryGrantRepository) Put(ctx context.Context, g models.Grant) (models.Grant, error) {
+	if g.MediaID == uuid.Nil || g.Principal == uuid.Nil || !g.Permission.Valid() {
+		return models.Grant{}, models.ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Grant{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := grantKey{g.MediaID, g.Principal}
+	if prev, ok := r.data[key]; ok {
+		g.CreatedAt = prev.CreatedAt
+	}
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = time.Now()
+	}
+	if g.UpdatedAt.IsZero() {
+		g.UpdatedAt = g.CreatedAt
+	}
+	r.data[key] = g
+	return g, nil
+}
+
+func (r *MemoryGrantRepository) Revoke(ctx context.Context, mediaID, principal uuid.UUID) (models.Grant, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Grant{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := grantKey{mediaID, principal}
+	g, ok := r.data[key]
+	if !ok {
+		return models.Grant{}, models.ErrNotFound
+	}
+	delete(r.data, key)
+	return g, nil
+}
+
+// List — см. GrantRepository; при одинаковом CreatedAt порядок по principal, как в Postgres
+func (r *MemoryGrantRepository) List(ctx context.Context, mediaID uuid.UUID) ([]models.Grant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var out []models.Grant
+	for key, g := range r.data {
+		if key.mediaID == mediaID {
+			out = append(out, g)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(out, func(a, b models.Grant) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Principal.String(), b.Principal.String())
+	})
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DefaultListLimit — размер страницы List, если Limit не задан
+const DefaultListLimit = 100
+
+// ListSort — поле сортировки List; при равных значениях порядок по id
+type ListSort string
+
+const (
+	SortCreatedAt ListSort = "created_at"
+	SortUpdatedAt ListSort = "updated_at"
+)
+
+// Valid сообщает, что по полю можно сортировать
+func (s ListSort) Valid() bool {
+	return s == SortCreatedAt || s == SortUpdatedAt
+}
+
+// ListCursor — позиция keyset пагинации: значение поля сортировки и id последнего медиа страницы
+type ListCursor struct {
+	At time.Time
+	ID uuid.UUID
+}
+
+// CursorOf — позиция медиа m в выдаче, отсортированной по sort
+func CursorOf(m *models.Media, sort ListSort) ListCursor {
+	if sort == SortUpdatedAt {
+		return ListCursor{At: m.UpdatedAt, ID: m.ID}
+	}
+	return ListCursor{At: m.CreatedAt, ID: m.ID}
+}
+
+// ListFilter — фильтр и пагинация для List
+type ListFilter struct {
+	Status   models.Status    // пустой — любой статус
+	Type     models.MediaType // пустой — любой тип
+	OwnerID  uuid.UUID        // uuid.Nil — любой владелец
+	Checksum string           // sha256 исходника; пустой — любой
+	// Visibility — заданная владельцем видимость (models.Media.Visibility); пустая — любая
+	Visibility models.Visibility
+
+	Sort      ListSort // пустой — SortCreatedAt
+	Ascending bool     // false — новые первыми
+	// After — страница начинается сразу за этой позицией; в отличие от Offset не пропускает
+	// и не повторяет медиа, созданные между запросами страниц
+	After *ListCursor
+
+	Limit  int // <= 0 — DefaultListLimit
+	Offset int
+}
+
+// WithDefaults возвращает фильтр с подставленными значениями по умолчанию
+func (f ListFilter) WithDefaults() ListFilter {
+	if f.Limit <= 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	if f.Sort == "" {
+		f.Sort = SortCreatedAt
+	}
+	return f
+}
+
+// matches сообщает, что медиа подходит под фильтр (без учёта пагинации)
+func (f ListFilter) matches(m *models.Media) bool {
+	return (f.Status == "" || m.Status == f.Status) &&
+		(f.OwnerID == uuid.Nil || m.OwnerID == f.OwnerID) &&
+		(f.Checksum == "" || m.Checksum == f.Checksum) &&
+		(f.Type == "" || m.Type == f.Type) &&
+		(f.Visibility == "" || m.Visibility == f.Visibility)
+}
+
+// before сообщает, что a идёт в выдаче раньше b
+func (f ListFilter) before(a, b ListCursor) bool {
+	if !a.At.Equal(b.At) {
+		return a.At.Before(b.At) == f.Ascending
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// CountMode — считать ли общее число медиа под фильтром List
+type CountMode string
+
+const (
+	CountNone  CountMode = ""
+	CountExact CountMode = "exact"
+	// CountEstimated — на больших таблицах оценка по статистике планировщика вместо COUNT(*)
+	CountEstimated CountMode = "estimated"
+)
+
+// Valid сообщает, что режим подсчёта известен
+func (c CountMode) Valid() bool {
+	return c == CountNone || c == CountExact || c == CountEstimated
+}
+
+// EstimateThreshold — с CountEstimated таблица меньше этого числа строк (по pg_class.reltuples)
+// всё равно считается точно: COUNT(*) по ней дешёвый, а оценка на малых числах грубая
+const EstimateThreshold = 100_000
+
+// TotalsQuery — что посчитать по фильтру List
+type TotalsQuery struct {
+	Count  CountMode
+	Facets bool // число медиа по статусам и типам
+}
+
+// ListTotals — итоги по фильтру List без пагинации: After, Limit и Offset не учитываются
+type ListTotals struct {
+	Total     int64 // 0 с CountNone
+	Estimated bool  // Total — оценка
+	// ByStatus и ByType — nil без Facets
+	ByStatus map[models.Status]int64
+	ByType   map[models.MediaType]int64
+}
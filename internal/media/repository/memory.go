@@ -2,7 +2,12 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -10,13 +15,19 @@ import (
 )
 
 type MemoryRepository struct {
-	mu   sync.RWMutex
-	data map[uuid.UUID]*models.Media
+	NoopTxManager
+
+	mu      sync.RWMutex
+	data    map[uuid.UUID]*models.Media
+	history map[uuid.UUID][]models.StatusChange
+
+	lastChangeID int64
 }
 
 func NewMemoryRepository() *MemoryRepository {
 	return &MemoryRepository{
-		data: make(map[uuid.UUID]*models.Media),
+		data:    make(map[uuid.UUID]*models.Media),
+		history: make(map[uuid.UUID][]models.StatusChange),
 	}
 }
 
@@ -42,6 +53,11 @@ func (r *MemoryRepository) Create(ctx context.Context, m *models.Media) error {
 
 	// Защитная копия, чтобы внешняя сторона не могла мутировать хранимый объект
 	cp := *m
+	cp.Tags = slices.Clone(m.Tags)
+	cp.Metadata = maps.Clone(m.Metadata)
+	if cp.Visibility == "" {
+		cp.Visibility = models.DraftVisibility // как DEFAULT колонки в Postgres
+	}
 	r.data[m.ID] = &cp
 
 	return nil
@@ -67,3 +83,344 @@ func (r *MemoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.M
 	cp := *m
 	return &cp, nil
 }
+
+// GetForUpdate — см. MediaRepository; транзакций в памяти нет, поэтому без блокировки
+func (r *MemoryRepository) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *MemoryRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.data[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	applyStatus(m, status)
+	m.UpdatedAt = time.Now()
+
+	cp := *m
+	return &cp, nil
+}
+
+// Update частично обновляет медиа; nil поля патча не меняются
+func (r *MemoryRepository) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.data[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if !patch.IsEmpty() {
+		patch.Apply(m)
+		m.UpdatedAt = time.Now()
+	}
+
+	cp := *m
+	return &cp, nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.data[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	delete(r.data, id)
+
+	return m, nil
+}
+
+func (r *MemoryRepository) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.data[id]
+	if !ok {
+		return models.ErrNotFound
+	}
+	m.LastError = lastError
+	m.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// List возвращает страницу медиа в порядке filter.Sort (как Postgres репозиторий)
+func (r *MemoryRepository) List(ctx context.Context, filter ListFilter) ([]*models.Media, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filter = filter.WithDefaults()
+	if !filter.Sort.Valid() {
+		return nil, fmt.Errorf("%w: unknown sort %q", models.ErrInvalidArgument, filter.Sort)
+	}
+
+	r.mu.RLock()
+	items := make([]*models.Media, 0, len(r.data))
+	for _, m := range r.data {
+		if !filter.matches(m) {
+			continue
+		}
+		if filter.After != nil && !filter.before(*filter.After, CursorOf(m, filter.Sort)) {
+			continue
+		}
+		cp := *m
+		items = append(items, &cp)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return filter.before(CursorOf(items[i], filter.Sort), CursorOf(items[j], filter.Sort))
+	})
+
+	if filter.Offset >= len(items) {
+		return []*models.Media{}, nil
+	}
+	items = items[filter.Offset:]
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+	}
+	return items, nil
+}
+
+// Totals считает всегда точно: в памяти оценивать нечего
+func (r *MemoryRepository) Totals(ctx context.Context, filter ListFilter, q TotalsQuery) (ListTotals, error) {
+	if err := ctx.Err(); err != nil {
+		return ListTotals{}, err
+	}
+	if !q.Count.Valid() {
+		return ListTotals{}, fmt.Errorf("%w: unknown count mode %q", models.ErrInvalidArgument, q.Count)
+	}
+
+	var t ListTotals
+	if q.Facets {
+		t.ByStatus = make(map[models.Status]int64)
+		t.ByType = make(map[models.MediaType]int64)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.data {
+		if !filter.matches(m) {
+			continue
+		}
+		if q.Count != CountNone {
+			t.Total++
+		}
+		if q.Facets {
+			t.ByStatus[m.Status]++
+			t.ByType[m.Type]++
+		}
+	}
+	return t, nil
+}
+
+// Search — упрощённый аналог полнотекстового поиска Postgres: все слова запроса должны
+// встретиться в title или описании; rank — доля совпавших слов с весом title выше описания.
+func (r *MemoryRepository) Search(ctx context.Context, q SearchQuery) ([]SearchHit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	q = q.WithDefaults()
+	terms := searchTerms(q.Text)
+
+	r.mu.RLock()
+	hits := make([]SearchHit, 0)
+	for _, m := range r.data {
+		if q.Status != "" && m.Status != q.Status {
+			continue
+		}
+		if q.OwnerID != uuid.Nil && m.OwnerID != q.OwnerID {
+			continue
+		}
+		if !hasAllTags(m.Tags, q.Tags) {
+			continue
+		}
+		rank, ok := matchTerms(m, terms)
+		if !ok {
+			continue
+		}
+		hits = append(hits, SearchHit{Media: *m, Rank: rank})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		if !hits[i].CreatedAt.Equal(hits[j].CreatedAt) {
+			return hits[i].CreatedAt.After(hits[j].CreatedAt)
+		}
+		return hits[i].ID.String() < hits[j].ID.String()
+	})
+
+	if q.Offset >= len(hits) {
+		return []SearchHit{}, nil
+	}
+	hits = hits[q.Offset:]
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+// Dashboard — см. DashboardQuery. История удалённых media учитывается только без фильтра по владельцу.
+func (r *MemoryRepository) Dashboard(ctx context.Context, q DashboardQuery) (Dashboard, error) {
+	if err := ctx.Err(); err != nil {
+		return Dashboard{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d := Dashboard{
+		ByStatus: make(map[models.Status]int64),
+		ByType:   make(map[models.MediaType]int64),
+	}
+	for _, m := range r.data {
+		if q.OwnerID != uuid.Nil && m.OwnerID != q.OwnerID {
+			continue
+		}
+		d.ByStatus[m.Status]++
+		d.ByType[m.Type]++
+		d.Total++
+		if !m.CreatedAt.Before(q.Since) {
+			d.Created++
+		}
+	}
+
+	var (
+		took     time.Duration
+		measured int64
+	)
+	for id, changes := range r.history {
+		if q.OwnerID != uuid.Nil {
+			if m, ok := r.data[id]; !ok || m.OwnerID != q.OwnerID {
+				continue
+			}
+		}
+		var started time.Time
+		for _, c := range changes {
+			if c.To == models.ProcessingStatus {
+				started = c.ChangedAt
+				continue
+			}
+			if c.From != models.ProcessingStatus || c.ChangedAt.Before(q.Since) {
+				continue
+			}
+			switch c.To {
+			case models.ReadyStatus, models.ScheduledStatus:
+				d.Completed++
+			case models.FailedStatus:
+				d.Failed++
+			default:
+				continue
+			}
+			if !started.IsZero() {
+				took += c.ChangedAt.Sub(started)
+				measured++
+			}
+		}
+	}
+	if measured > 0 {
+		d.AvgProcessing = took / time.Duration(measured)
+	}
+	return d, nil
+}
+
+func hasAllTags(have models.Tags, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchTerms(m *models.Media, terms []string) (float64, bool) {
+	if len(terms) == 0 {
+		return 0, true
+	}
+	title := searchTerms(m.Title)
+	description := searchTerms(m.Metadata[DescriptionKey])
+
+	var rank float64
+	for _, t := range terms {
+		switch {
+		case slices.Contains(title, t):
+			rank += 1
+		case slices.Contains(description, t):
+			rank += 0.4
+		default:
+			return 0, false
+		}
+	}
+	return rank / float64(len(terms)), true
+}
+
+// applyStatus меняет статус так же, как Postgres репозиторий: вход в processing —
+// новая попытка обработки, ready обнуляет счётчик попыток и последнюю ошибку.
+func applyStatus(m *models.Media, status models.Status) {
+	switch status {
+	case models.ProcessingStatus:
+		m.ProcessingAttempts++
+	case models.ReadyStatus, models.ScheduledStatus:
+		m.ProcessingAttempts = 0
+		m.LastError = ""
+	}
+	m.Status = status
+}
+
+func (r *MemoryRepository) AddStatusChange(ctx context.Context, c *models.StatusChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastChangeID++
+	c.ID = r.lastChangeID
+	r.history[c.MediaID] = append(r.history[c.MediaID], *c)
+
+	return nil
+}
+
+func (r *MemoryRepository) ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]models.StatusChange(nil), r.history[mediaID]...), nil
+}
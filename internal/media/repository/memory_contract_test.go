@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/repository/repotest"
+)
+
+func TestMemoryRepository_Contract(t *testing.T) {
+	repotest.RunRepositoryTests(t, func(*testing.T) repository.MediaRepository {
+		return repository.NewMemoryRepository()
+	}, repotest.WithoutRollback())
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func TestMemoryRepository_UpdatePartial(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	m := newMedia("s3://bucket/a.mp4")
+	m.Title = "original"
+	require.NoError(t, repo.Create(ctx, m))
+
+	title := "renamed"
+	tags := models.Tags{"promo", "4k"}
+	got, err := repo.Update(ctx, m.ID, models.MediaPatch{Title: &title, Tags: &tags})
+	require.NoError(t, err)
+
+	require.Equal(t, "renamed", got.Title)
+	require.Equal(t, models.Tags{"promo", "4k"}, got.Tags)
+	require.Equal(t, "s3://bucket/a.mp4", got.Source) // не было в патче
+	require.Nil(t, got.Metadata)
+	require.True(t, got.UpdatedAt.After(m.UpdatedAt) || got.UpdatedAt.Equal(m.UpdatedAt))
+
+	// патч не держит ссылку на хранимые данные
+	tags[0] = "mutated"
+	stored, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, "promo", stored.Tags[0])
+}
+
+func TestMemoryRepository_UpdateEmptyPatchIsNoop(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	m := newMedia("s3://bucket/a.mp4")
+	require.NoError(t, repo.Create(ctx, m))
+
+	got, err := repo.Update(ctx, m.ID, models.MediaPatch{})
+	require.NoError(t, err)
+	require.Equal(t, m.UpdatedAt, got.UpdatedAt)
+}
+
+func TestMemoryRepository_UpdateNotFound(t *testing.T) {
+	_, err := NewMemoryRepository().Update(context.Background(), uuid.New(), models.MediaPatch{})
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestMemoryRepository_ListFiltersAndPaginates(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	var ids []uuid.UUID
+	for i := range 5 {
+		m := newMedia("s3://bucket/file.mp4")
+		m.CreatedAt = m.CreatedAt.Add(time.Duration(i) * time.Second)
+		if i%2 == 0 {
+			m.Status = models.ReadyStatus
+		}
+		require.NoError(t, repo.Create(ctx, m))
+		ids = append(ids, m.ID)
+	}
+
+	all, err := repo.List(ctx, ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	require.Equal(t, ids[4], all[0].ID) // новые первыми
+
+	ready, err := repo.List(ctx, ListFilter{Status: models.ReadyStatus})
+	require.NoError(t, err)
+	require.Len(t, ready, 3)
+
+	page, err := repo.List(ctx, ListFilter{Limit: 2, Offset: 4})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, ids[0], page[0].ID)
+}
+
+func TestMemoryRepository_WithinTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	m := newMedia("s3://bucket/a.mp4")
+	err := repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, repo.Create(ctx, m))
+		require.ErrorIs(t, repo.Create(ctx, m), models.ErrConflict)
+
+		updated, err := repo.UpdateStatus(ctx, m.ID, models.ProcessingStatus)
+		require.NoError(t, err)
+		require.Equal(t, 1, updated.ProcessingAttempts)
+
+		return repo.AddStatusChange(ctx, &models.StatusChange{
+			MediaID: m.ID, From: models.UploadedStatus, To: models.ProcessingStatus,
+		})
+	})
+	require.NoError(t, err)
+
+	history, err := repo.ListStatusChanges(ctx, m.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	deleted, err := repo.Delete(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, deleted.ID)
+	_, err = repo.Delete(ctx, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, repo.WithinTransaction(cancelled, func(context.Context) error { return nil }), context.Canceled)
+}
+
+func TestMemoryRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	a := newMedia("s3://bucket/a.mp4")
+	a.Title = "Cat compilation"
+	a.Tags = models.Tags{"pets"}
+	b := newMedia("s3://bucket/b.mp4")
+	b.Title = "Lecture"
+	b.Metadata = models.Metadata{DescriptionKey: "Cat behaviour explained"}
+	b.Status = models.ReadyStatus
+	for _, m := range []*models.Media{a, b} {
+		require.NoError(t, repo.Create(ctx, m))
+	}
+
+	hits, err := repo.Search(ctx, SearchQuery{Text: "cat"})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	require.Equal(t, a.ID, hits[0].ID)
+	require.Greater(t, hits[0].Rank, hits[1].Rank)
+
+	hits, err = repo.Search(ctx, SearchQuery{Text: "cat", Status: models.ReadyStatus})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Equal(t, b.ID, hits[0].ID)
+
+	hits, err = repo.Search(ctx, SearchQuery{Tags: []string{"pets"}})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Zero(t, hits[0].Rank)
+
+	hits, err = repo.Search(ctx, SearchQuery{Text: "cat dog"})
+	require.NoError(t, err)
+	require.Empty(t, hits)
+}
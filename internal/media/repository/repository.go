@@ -4,16 +4,28 @@ import (
 	"context"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
+
 	"github.com/romariotrain/media-platform/internal/media/models"
 )
 
 type MediaRepository interface {
+	TxManager
+
 	Create(ctx context.Context, m *models.Media) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Media, error)
+	// GetForUpdate читает медиа из primary и внутри транзакции блокирует его до её конца
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error)
 	UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error)
+	Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error)
+	List(ctx context.Context, filter ListFilter) ([]*models.Media, error)
+	// Totals — общее число и фасеты медиа под фильтром List, см. TotalsQuery
+	Totals(ctx context.Context, filter ListFilter, q TotalsQuery) (ListTotals, error)
+	Search(ctx context.Context, q SearchQuery) ([]SearchHit, error)
+	Dashboard(ctx context.Context, q DashboardQuery) (Dashboard, error)
+	Delete(ctx context.Context, id uuid.UUID) (*models.Media, error)
+	SetLastError(ctx context.Context, id uuid.UUID, lastError string) error
 
-	// Новые методы для транзакций:
-	BeginTx(ctx context.Context) (*sqlx.Tx, error)
-	UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status models.Status) (*models.Media, error)
+	// История статусов (аудит переходов)
+	AddStatusChange(ctx context.Context, c *models.StatusChange) error
+	ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error)
 }
@@ -0,0 +1,590 @@
+// Package repotest — контрактные тесты repository.MediaRepository. Любая реализация
+// (memory, Postgres, будущие) прогоняется через RunRepositoryTests и должна вести себя одинаково.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// Factory возвращает пустой репозиторий; вызывается для каждого подтеста
+type Factory func(t *testing.T) repository.MediaRepository
+
+type options struct {
+	noRollback bool
+}
+
+// Option меняет набор проверок под возможности реализации
+type Option func(*options)
+
+// WithoutRollback — реализация не откатывает транзакции (repository.NoopTxManager):
+// проверки отката пропускаются
+func WithoutRollback() Option {
+	return func(o *options) { o.noRollback = true }
+}
+
+// RunRepositoryTests прогоняет контракт MediaRepository против репозиториев из factory
+func RunRepositoryTests(t *testing.T, factory Factory, opts ...Option) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.MediaRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateWithAttrs", testCreateWithAttrs},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"NotFound", testNotFound},
+		{"UpdateStatus", testUpdateStatus},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"List", testList},
+		{"ListKeyset", testListKeyset},
+		{"StatusHistory", testStatusHistory},
+		{"Totals", testTotals},
+		{"Dashboard", testDashboard},
+		{"GetForUpdate", testGetForUpdate},
+		{"TransactionCommit", testTransactionCommit},
+		{"TransactionRollback", func(t *testing.T, repo repository.MediaRepository) {
+			if o.noRollback {
+				t.Skip("repository does not support rollback")
+			}
+			testTransactionRollback(t, repo)
+		}},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, factory(t))
+		})
+	}
+}
+
+// newMedia — медиа только с полями, которые сохраняет Create любой реализации.
+// Время усечено до микросекунд — точность timestamp в Postgres.
+func newMedia(source string, createdAt time.Time) *models.Media {
+	createdAt = createdAt.UTC().Truncate(time.Microsecond)
+	return &models.Media{
+		ID:        uuid.New(),
+		Status:    models.UploadedStatus,
+		Type:      models.Video,
+		Source:    source,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+func create(t *testing.T, repo repository.MediaRepository, m *models.Media) {
+	t.Helper()
+	require.NoError(t, repo.Create(context.Background(), m))
+}
+
+func requireSameMedia(t *testing.T, want, got *models.Media) {
+	t.Helper()
+	require.Equal(t, want.ID, got.ID)
+	require.Equal(t, want.Status, got.Status)
+	require.Equal(t, want.Type, got.Type)
+	require.Equal(t, want.Source, got.Source)
+	require.True(t, want.CreatedAt.Equal(got.CreatedAt), "created_at: want %v, got %v", want.CreatedAt, got.CreatedAt)
+}
+
+func ids(items []*models.Media) []uuid.UUID {
+	out := make([]uuid.UUID, len(items))
+	for i, m := range items {
+		out[i] = m.ID
+	}
+	return out
+}
+
+func testCreateAndGet(t *testing.T, repo repository.MediaRepository) {
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	got, err := repo.GetByID(context.Background(), m.ID)
+	require.NoError(t, err)
+	requireSameMedia(t, m, got)
+	require.Zero(t, got.ProcessingAttempts)
+	require.Empty(t, got.LastError)
+}
+
+func testCreateDuplicate(t *testing.T, repo repository.MediaRepository) {
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	dup := *m
+	dup.Source = "s3://bucket/other.mp4"
+	require.ErrorIs(t, repo.Create(context.Background(), &dup), models.ErrConflict)
+
+	// первая запись не перезаписана
+	got, err := repo.GetByID(context.Background(), m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.Source, got.Source)
+}
+
+func testNotFound(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	id := uuid.New()
+	title := "x"
+
+	_, err := repo.GetByID(ctx, id)
+	require.ErrorIs(t, err, models.ErrNotFound, "GetByID")
+	_, err = repo.UpdateStatus(ctx, id, models.ProcessingStatus)
+	require.ErrorIs(t, err, models.ErrNotFound, "UpdateStatus")
+	_, err = repo.Update(ctx, id, models.MediaPatch{Title: &title})
+	require.ErrorIs(t, err, models.ErrNotFound, "Update")
+	_, err = repo.Update(ctx, id, models.MediaPatch{})
+	require.ErrorIs(t, err, models.ErrNotFound, "Update with empty patch")
+	_, err = repo.Delete(ctx, id)
+	require.ErrorIs(t, err, models.ErrNotFound, "Delete")
+	require.ErrorIs(t, repo.SetLastError(ctx, id, "boom"), models.ErrNotFound, "SetLastError")
+}
+
+func testUpdateStatus(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	// каждый вход в processing — новая попытка
+	for attempt := 1; attempt <= 2; attempt++ {
+		got, err := repo.UpdateStatus(ctx, m.ID, models.ProcessingStatus)
+		require.NoError(t, err)
+		require.Equal(t, models.ProcessingStatus, got.Status)
+		require.Equal(t, attempt, got.ProcessingAttempts)
+	}
+
+	require.NoError(t, repo.SetLastError(ctx, m.ID, "transcode failed"))
+	got, err := repo.UpdateStatus(ctx, m.ID, models.FailedStatus)
+	require.NoError(t, err)
+	require.Equal(t, 2, got.ProcessingAttempts, "failed keeps attempts")
+	require.Equal(t, "transcode failed", got.LastError)
+
+	// ready обнуляет попытки и последнюю ошибку
+	got, err = repo.UpdateStatus(ctx, m.ID, models.ReadyStatus)
+	require.NoError(t, err)
+	require.Equal(t, models.ReadyStatus, got.Status)
+	require.Zero(t, got.ProcessingAttempts)
+	require.Empty(t, got.LastError)
+
+	stored, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ReadyStatus, stored.Status)
+}
+
+func testCreateWithAttrs(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/attrs.mp4", time.Now())
+	m.Title = "launch"
+	m.Tags = models.Tags{"promo"}
+	m.Metadata = models.Metadata{"description": "teaser"}
+	create(t, repo, m)
+
+	stored, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, "launch", stored.Title)
+	require.Equal(t, models.Tags{"promo"}, stored.Tags)
+	require.Equal(t, models.Metadata{"description": "teaser"}, stored.Metadata)
+}
+
+func testUpdate(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	title := "renamed"
+	tags := models.Tags{"promo", "4k"}
+	got, err := repo.Update(ctx, m.ID, models.MediaPatch{Title: &title, Tags: &tags})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", got.Title)
+	require.Equal(t, models.Tags{"promo", "4k"}, got.Tags)
+	require.Equal(t, m.Source, got.Source, "field outside patch is unchanged")
+
+	stored, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", stored.Title)
+	require.Equal(t, models.Tags{"promo", "4k"}, stored.Tags)
+
+	content := models.Content{Checksum: strings.Repeat("ab", 32), Size: 1024, ContentType: "video/mp4"}
+	got, err = repo.Update(ctx, m.ID, models.MediaPatch{Content: &content})
+	require.NoError(t, err)
+	require.Equal(t, content, models.Content{Checksum: got.Checksum, Size: got.Size, ContentType: got.ContentType})
+	require.Equal(t, "renamed", got.Title)
+	stored, err = repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), stored.Size)
+
+	// пустой патч возвращает текущую запись без изменений
+	same, err := repo.Update(ctx, m.ID, models.MediaPatch{})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", same.Title)
+	require.True(t, stored.UpdatedAt.Equal(same.UpdatedAt), "empty patch must not touch updated_at")
+}
+
+func testDelete(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	deleted, err := repo.Delete(ctx, m.ID)
+	require.NoError(t, err)
+	requireSameMedia(t, m, deleted)
+
+	_, err = repo.GetByID(ctx, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = repo.Delete(ctx, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func testList(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	// created_at по возрастанию: items[3] — самый новый
+	owner := uuid.New()
+	items := make([]*models.Media, 4)
+	for i := range items {
+		items[i] = newMedia(fmt.Sprintf("s3://bucket/%d.mp4", i), base.Add(time.Duration(i)*time.Minute))
+		if i%2 == 0 {
+			items[i].OwnerID = owner
+		}
+		create(t, repo, items[i])
+	}
+	_, err := repo.UpdateStatus(ctx, items[1].ID, models.ProcessingStatus)
+	require.NoError(t, err)
+	_, err = repo.UpdateStatus(ctx, items[3].ID, models.ProcessingStatus)
+	require.NoError(t, err)
+
+	all, err := repo.List(ctx, repository.ListFilter{})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[3].ID, items[2].ID, items[1].ID, items[0].ID}, ids(all), "newest first")
+
+	processing, err := repo.List(ctx, repository.ListFilter{Status: models.ProcessingStatus})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[3].ID, items[1].ID}, ids(processing))
+
+	owned, err := repo.List(ctx, repository.ListFilter{OwnerID: owner})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[2].ID, items[0].ID}, ids(owned))
+	require.Equal(t, owner, owned[0].OwnerID)
+
+	checksum := strings.Repeat("0f", 32)
+	content := models.Content{Checksum: checksum, Size: 10, ContentType: "video/mp4"}
+	_, err = repo.Update(ctx, items[0].ID, models.MediaPatch{Content: &content})
+	require.NoError(t, err)
+	same, err := repo.List(ctx, repository.ListFilter{OwnerID: owner, Checksum: checksum})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[0].ID}, ids(same))
+
+	page, err := repo.List(ctx, repository.ListFilter{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[2].ID, items[1].ID}, ids(page))
+
+	empty, err := repo.List(ctx, repository.ListFilter{Offset: 10})
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	// одинаковый created_at — порядок по id
+	at := base.Add(time.Hour)
+	a, b := newMedia("s3://bucket/a.mp4", at), newMedia("s3://bucket/b.mp4", at)
+	create(t, repo, a)
+	create(t, repo, b)
+	top, err := repo.List(ctx, repository.ListFilter{Limit: 2})
+	require.NoError(t, err)
+	want := []uuid.UUID{a.ID, b.ID}
+	if b.ID.String() < a.ID.String() {
+		want = []uuid.UUID{b.ID, a.ID}
+	}
+	require.Equal(t, want, ids(top))
+}
+
+func testListKeyset(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	// items[1] и items[2] созданы одновременно: граница страницы проходит между ними
+	items := make([]*models.Media, 5)
+	for i, minute := range []int{0, 1, 1, 2, 3} {
+		items[i] = newMedia(fmt.Sprintf("s3://bucket/%d.mp4", i), base.Add(time.Duration(minute)*time.Minute))
+	}
+	items[4].Type = models.Audio
+	for _, m := range items {
+		create(t, repo, m)
+	}
+
+	walk := func(filter repository.ListFilter) []uuid.UUID {
+		filter.Limit = 2
+		var out []uuid.UUID
+		for {
+			page, err := repo.List(ctx, filter)
+			require.NoError(t, err)
+			out = append(out, ids(page)...)
+			if len(page) < filter.Limit {
+				return out
+			}
+			cursor := repository.CursorOf(page[len(page)-1], filter.Sort)
+			filter.After = &cursor
+		}
+	}
+
+	desc, err := repo.List(ctx, repository.ListFilter{})
+	require.NoError(t, err)
+	require.Equal(t, ids(desc), walk(repository.ListFilter{}))
+	require.Len(t, desc, 5)
+	require.Equal(t, items[4].ID, desc[0].ID)
+
+	asc, err := repo.List(ctx, repository.ListFilter{Ascending: true})
+	require.NoError(t, err)
+	require.Equal(t, items[0].ID, asc[0].ID)
+	require.Equal(t, ids(asc), walk(repository.ListFilter{Ascending: true}))
+
+	// Медиа, созданное после начала обхода, не сдвигает следующие страницы
+	first, err := repo.List(ctx, repository.ListFilter{Limit: 2})
+	require.NoError(t, err)
+	create(t, repo, newMedia("s3://bucket/new.mp4", time.Now()))
+	cursor := repository.CursorOf(first[1], repository.SortCreatedAt)
+	rest, err := repo.List(ctx, repository.ListFilter{After: &cursor})
+	require.NoError(t, err)
+	require.Equal(t, ids(desc[2:]), ids(rest))
+
+	audio, err := repo.List(ctx, repository.ListFilter{Type: models.Audio})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[4].ID}, ids(audio))
+
+	_, err = repo.UpdateStatus(ctx, items[0].ID, models.ProcessingStatus)
+	require.NoError(t, err)
+	updated, err := repo.List(ctx, repository.ListFilter{Sort: repository.SortUpdatedAt, Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{items[0].ID}, ids(updated))
+
+	_, err = repo.List(ctx, repository.ListFilter{Sort: "title"})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+}
+
+func testStatusHistory(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	changes := []*models.StatusChange{
+		{MediaID: m.ID, From: models.UploadedStatus, To: models.ProcessingStatus, Actor: "worker", ChangedAt: at},
+		{MediaID: m.ID, From: models.ProcessingStatus, To: models.FailedStatus, Actor: "worker", Reason: "timeout", ChangedAt: at.Add(time.Second)},
+	}
+	for _, c := range changes {
+		require.NoError(t, repo.AddStatusChange(ctx, c))
+		require.NotZero(t, c.ID, "id is assigned")
+	}
+
+	got, err := repo.ListStatusChanges(ctx, m.ID)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for i, c := range changes {
+		require.Equal(t, c.ID, got[i].ID)
+		require.Equal(t, c.From, got[i].From)
+		require.Equal(t, c.To, got[i].To)
+		require.Equal(t, c.Actor, got[i].Actor)
+		require.Equal(t, c.Reason, got[i].Reason)
+		require.True(t, c.ChangedAt.Equal(got[i].ChangedAt), "changed_at: want %v, got %v", c.ChangedAt, got[i].ChangedAt)
+	}
+
+	other, err := repo.ListStatusChanges(ctx, uuid.New())
+	require.NoError(t, err)
+	require.Empty(t, other)
+}
+
+func testDashboard(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	owner := uuid.New()
+
+	old := newMedia("s3://bucket/old.mp4", now.Add(-48*time.Hour))
+	fresh := newMedia("s3://bucket/fresh.mp3", now.Add(-time.Hour))
+	fresh.Type, fresh.OwnerID = models.Audio, owner
+	failed := newMedia("s3://bucket/failed.mp4", now.Add(-time.Hour))
+	failed.OwnerID = owner
+	for _, m := range []*models.Media{old, fresh, failed} {
+		create(t, repo, m)
+	}
+	_, err := repo.UpdateStatus(ctx, fresh.ID, models.ReadyStatus)
+	require.NoError(t, err)
+	_, err = repo.UpdateStatus(ctx, failed.ID, models.FailedStatus)
+	require.NoError(t, err)
+
+	start := now.Add(-30 * time.Minute)
+	changes := []*models.StatusChange{
+		// обработка завершилась до окна — не считается
+		{MediaID: old.ID, From: models.UploadedStatus, To: models.ProcessingStatus, ChangedAt: now.Add(-47 * time.Hour)},
+		{MediaID: old.ID, From: models.ProcessingStatus, To: models.ReadyStatus, ChangedAt: now.Add(-46 * time.Hour)},
+		{MediaID: fresh.ID, From: models.UploadedStatus, To: models.ProcessingStatus, ChangedAt: start},
+		{MediaID: fresh.ID, From: models.ProcessingStatus, To: models.ReadyStatus, ChangedAt: start.Add(10 * time.Second)},
+		{MediaID: failed.ID, From: models.UploadedStatus, To: models.ProcessingStatus, ChangedAt: start},
+		{MediaID: failed.ID, From: models.ProcessingStatus, To: models.FailedStatus, ChangedAt: start.Add(30 * time.Second)},
+	}
+	for _, c := range changes {
+		require.NoError(t, repo.AddStatusChange(ctx, c))
+	}
+
+	d, err := repo.Dashboard(ctx, repository.DashboardQuery{Since: now.Add(-24 * time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), d.Total)
+	require.Equal(t, map[models.Status]int64{models.UploadedStatus: 1, models.ReadyStatus: 1, models.FailedStatus: 1}, d.ByStatus)
+	require.Equal(t, map[models.MediaType]int64{models.Video: 2, models.Audio: 1}, d.ByType)
+	require.Equal(t, int64(2), d.Created)
+	require.Equal(t, int64(1), d.Completed)
+	require.Equal(t, int64(1), d.Failed)
+	require.Equal(t, 20*time.Second, d.AvgProcessing)
+	require.InDelta(t, 0.5, d.FailureRate(), 1e-9)
+
+	d, err = repo.Dashboard(ctx, repository.DashboardQuery{OwnerID: owner, Since: now.Add(-24 * time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), d.Total)
+	require.Equal(t, int64(2), d.Created)
+
+	d, err = repo.Dashboard(ctx, repository.DashboardQuery{OwnerID: uuid.New(), Since: now.Add(-24 * time.Hour)})
+	require.NoError(t, err)
+	require.Zero(t, d.Total)
+	require.Zero(t, d.Completed+d.Failed)
+	require.Zero(t, d.AvgProcessing)
+}
+
+func testTotals(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	owner := uuid.New()
+
+	items := make([]*models.Media, 4)
+	for i := range items {
+		items[i] = newMedia(fmt.Sprintf("s3://bucket/%d.mp4", i), base.Add(time.Duration(i)*time.Minute))
+		items[i].OwnerID = owner
+		if i == 3 {
+			items[i].Type, items[i].OwnerID = models.Audio, uuid.New()
+		}
+		create(t, repo, items[i])
+	}
+	_, err := repo.UpdateStatus(ctx, items[0].ID, models.ReadyStatus)
+	require.NoError(t, err)
+
+	all := repository.TotalsQuery{Count: repository.CountExact, Facets: true}
+	totals, err := repo.Totals(ctx, repository.ListFilter{}, all)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), totals.Total)
+	require.False(t, totals.Estimated)
+	require.Equal(t, map[models.Status]int64{models.UploadedStatus: 3, models.ReadyStatus: 1}, totals.ByStatus)
+	require.Equal(t, map[models.MediaType]int64{models.Video: 3, models.Audio: 1}, totals.ByType)
+
+	// Пагинация не влияет на итоги; на малой таблице estimated считает точно
+	after := repository.CursorOf(items[2], repository.SortCreatedAt)
+	owned := repository.ListFilter{OwnerID: owner, Status: models.UploadedStatus, Limit: 1, After: &after}
+	totals, err = repo.Totals(ctx, owned, repository.TotalsQuery{Count: repository.CountEstimated})
+	require.NoError(t, err)
+	require.Equal(t, repository.ListTotals{Total: 2}, totals)
+
+	totals, err = repo.Totals(ctx, repository.ListFilter{Type: models.Audio}, repository.TotalsQuery{Facets: true})
+	require.NoError(t, err)
+	require.Zero(t, totals.Total)
+	require.Equal(t, map[models.Status]int64{models.UploadedStatus: 1}, totals.ByStatus)
+
+	_, err = repo.Totals(ctx, repository.ListFilter{}, repository.TotalsQuery{Count: "approximate"})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+}
+
+func testGetForUpdate(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	err := repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		got, err := repo.GetForUpdate(ctx, m.ID)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, m.Version(), got.Version())
+		_, err = repo.GetForUpdate(ctx, uuid.New())
+		require.ErrorIs(t, err, models.ErrNotFound)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testTransactionCommit(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+
+	err := repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, m); err != nil {
+			return err
+		}
+		// запись видна внутри той же транзакции
+		if _, err := repo.UpdateStatus(ctx, m.ID, models.ProcessingStatus); err != nil {
+			return err
+		}
+		return repo.AddStatusChange(ctx, &models.StatusChange{
+			MediaID: m.ID, From: models.UploadedStatus, To: models.ProcessingStatus, ChangedAt: time.Now(),
+		})
+	})
+	require.NoError(t, err)
+
+	got, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingStatus, got.Status)
+	history, err := repo.ListStatusChanges(ctx, m.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
+
+func testTransactionRollback(t *testing.T, repo repository.MediaRepository) {
+	ctx := context.Background()
+	existing := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, existing)
+	created := newMedia("s3://bucket/b.mp4", time.Now())
+	errBoom := errors.New("boom")
+
+	err := repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, created); err != nil {
+			return err
+		}
+		if _, err := repo.UpdateStatus(ctx, existing.ID, models.ProcessingStatus); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	require.ErrorIs(t, err, errBoom)
+
+	_, err = repo.GetByID(ctx, created.ID)
+	require.ErrorIs(t, err, models.ErrNotFound, "create is rolled back")
+	got, err := repo.GetByID(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.UploadedStatus, got.Status, "status change is rolled back")
+	require.Zero(t, got.ProcessingAttempts)
+}
+
+func testCanceledContext(t *testing.T, repo repository.MediaRepository) {
+	m := newMedia("s3://bucket/a.mp4", time.Now())
+	create(t, repo, m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.Error(t, repo.Create(ctx, newMedia("s3://bucket/b.mp4", time.Now())))
+	_, err := repo.GetByID(ctx, m.ID)
+	require.Error(t, err)
+	_, err = repo.UpdateStatus(ctx, m.ID, models.ProcessingStatus)
+	require.Error(t, err)
+	require.Error(t, repo.WithinTransaction(ctx, func(context.Context) error { return nil }))
+
+	got, err := repo.GetByID(context.Background(), m.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.UploadedStatus, got.Status, "canceled update is not applied")
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DescriptionKey — ключ metadata, по которому вместе с title идёт полнотекстовый поиск
+const DescriptionKey = "description"
+
+// SearchQuery — полнотекстовый поиск с фильтрами и пагинацией
+type SearchQuery struct {
+	Text    string        // поисковая строка (websearch синтаксис в Postgres); пустая — без полнотекста
+	Tags    []string      // медиа должно содержать все перечисленные метки
+	Status  models.Status // пустой — любой статус
+	OwnerID uuid.UUID     // uuid.Nil — любой владелец
+	Limit   int           // <= 0 — DefaultListLimit
+	Offset  int
+}
+
+// WithDefaults возвращает запрос с подставленными значениями по умолчанию
+func (q SearchQuery) WithDefaults() SearchQuery {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Limit <= 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return q
+}
+
+// SearchHit — найденное медиа с релевантностью (0, если Text пустой)
+type SearchHit struct {
+	models.Media
+	Rank float64 `db:"rank"`
+}
+
+// searchTerms разбивает строку на слова в нижнем регистре — упрощённый аналог to_tsvector('simple')
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
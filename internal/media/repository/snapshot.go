@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// snapshotVersion — текущая версия формата; версия 1 (без истории статусов) читается
+// с пустой историей
+const snapshotVersion = 2
+
+var (
+	ErrSnapshotTooLarge  = errors.New("snapshot exceeds size limit")
+	ErrSnapshotCorrupted = errors.New("snapshot is corrupted")
+)
+
+// snapshotFile — формат файла снапшота на диске
+type snapshotFile struct {
+	Version      int                   `json:"version"`
+	SavedAt      time.Time             `json:"saved_at"`
+	Media        []models.Media        `json:"media"`
+	History      []models.StatusChange `json:"history,omitempty"`
+	LastChangeID int64                 `json:"last_change_id,omitempty"` // id следующего перехода — больше
+}
+
+// Snapshot сериализует текущее содержимое репозитория в w (JSON).
+// Если maxBytes > 0 и снапшот больше лимита — возвращает ErrSnapshotTooLarge и ничего не пишет.
+func (r *MemoryRepository) Snapshot(w io.Writer, maxBytes int64) error {
+	r.mu.RLock()
+	items := make([]models.Media, 0, len(r.data))
+	for _, m := range r.data {
+		items = append(items, *m)
+	}
+	var history []models.StatusChange
+	for _, changes := range r.history {
+		history = append(history, changes...)
+	}
+	lastChangeID := r.lastChangeID
+	r.mu.RUnlock()
+
+	// Детерминированный порядок — удобно диффать снапшоты
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].ID < history[j].ID
+	})
+
+	data, err := json.Marshal(snapshotFile{
+		Version:      snapshotVersion,
+		SavedAt:      time.Now().UTC(),
+		Media:        items,
+		History:      history,
+		LastChangeID: lastChangeID,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return fmt.Errorf("%w: %d > %d bytes", ErrSnapshotTooLarge, len(data), maxBytes)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore заменяет содержимое репозитория, включая историю статусов, данными из снапшота.
+// Читает не больше maxBytes (если maxBytes > 0).
+func (r *MemoryRepository) Restore(rd io.Reader, maxBytes int64) error {
+	if maxBytes > 0 {
+		// +1 байт, чтобы отличить "ровно лимит" от "больше лимита"
+		rd = io.LimitReader(rd, maxBytes+1)
+	}
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrSnapshotTooLarge, maxBytes)
+	}
+
+	var snap snapshotFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&snap); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupted, err)
+	}
+	if snap.Version != 1 && snap.Version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrSnapshotCorrupted, snap.Version)
+	}
+
+	loaded := make(map[uuid.UUID]*models.Media, len(snap.Media))
+	for i := range snap.Media {
+		m := snap.Media[i]
+		if m.ID == uuid.Nil {
+			return fmt.Errorf("%w: media without id", ErrSnapshotCorrupted)
+		}
+		loaded[m.ID] = &m
+	}
+
+	// Счётчик не откатывается ниже сохранённых переходов: новые id не совпадут со старыми
+	history := make(map[uuid.UUID][]models.StatusChange)
+	lastChangeID := snap.LastChangeID
+	for _, c := range snap.History {
+		if c.MediaID == uuid.Nil || c.ID <= 0 {
+			return fmt.Errorf("%w: status change without id", ErrSnapshotCorrupted)
+		}
+		history[c.MediaID] = append(history[c.MediaID], c)
+		lastChangeID = max(lastChangeID, c.ID)
+	}
+
+	r.mu.Lock()
+	r.data = loaded
+	r.history = history
+	r.lastChangeID = lastChangeID
+	r.mu.Unlock()
+
+	return nil
+}
+
+// SnapshotConfig содержит настройки периодического снапшота in-memory репозитория
+type SnapshotConfig struct {
+	Path     string        // Файл снапшота
+	Interval time.Duration // Период сохранения (default: 30s)
+	MaxBytes int64         // Максимальный размер снапшота (default: 64MB)
+	Logger   zerolog.Logger
+}
+
+// Snapshotter периодически сохраняет MemoryRepository на диск и восстанавливает его на старте,
+// чтобы демо и локальная разработка без Postgres переживали рестарты.
+type Snapshotter struct {
+	repo   *MemoryRepository
+	cfg    SnapshotConfig
+	logger zerolog.Logger
+}
+
+func NewSnapshotter(repo *MemoryRepository, cfg SnapshotConfig) (*Snapshotter, error) {
+	if repo == nil {
+		return nil, fmt.Errorf("memory repository is required")
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("snapshot path is required")
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %v", cfg.Interval)
+	}
+	if cfg.MaxBytes < 0 {
+		return nil, fmt.Errorf("max bytes cannot be negative, got: %d", cfg.MaxBytes)
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = 64 << 20
+	}
+
+	return &Snapshotter{
+		repo:   repo,
+		cfg:    cfg,
+		logger: cfg.Logger.With().Str("component", "memory_snapshotter").Str("path", cfg.Path).Logger(),
+	}, nil
+}
+
+// Load восстанавливает репозиторий из файла.
+// Отсутствующий файл — не ошибка (первый запуск). Повреждённый файл переименовывается
+// в <path>.corrupt-<unix>, чтобы не потерять его для разбора, и сервис стартует с пустым состоянием.
+func (s *Snapshotter) Load() error {
+	f, err := os.Open(s.cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.logger.Info().Msg("snapshot not found, starting empty")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+
+	err = s.repo.Restore(f, s.cfg.MaxBytes)
+	_ = f.Close()
+
+	if errors.Is(err, ErrSnapshotCorrupted) || errors.Is(err, ErrSnapshotTooLarge) {
+		aside := fmt.Sprintf("%s.corrupt-%d", s.cfg.Path, time.Now().Unix())
+		if renameErr := os.Rename(s.cfg.Path, aside); renameErr != nil {
+			return fmt.Errorf("move aside bad snapshot: %w", renameErr)
+		}
+		s.logger.Warn().
+			Err(err).
+			Str("moved_to", aside).
+			Msg("bad snapshot moved aside, starting empty")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info().Msg("snapshot loaded")
+	return nil
+}
+
+// Save атомарно записывает снапшот: временный файл + fsync + rename.
+// Так частично записанный файл никогда не заменит последний валидный снапшот.
+func (s *Snapshotter) Save() error {
+	var buf bytes.Buffer
+	if err := s.repo.Snapshot(&buf, s.cfg.MaxBytes); err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.cfg.Path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.cfg.Path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op после успешного rename
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write temp snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync temp snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.cfg.Path); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	return nil
+}
+
+// Start сохраняет снапшот каждые Interval до отмены контекста,
+// после чего делает финальное сохранение.
+func (s *Snapshotter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Save(); err != nil {
+				s.logger.Error().Err(err).Msg("final snapshot failed")
+				return err
+			}
+			s.logger.Info().Msg("final snapshot saved")
+			return nil
+
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				s.logger.Error().Err(err).Msg("snapshot failed")
+				// Продолжаем работать, попробуем на следующем тике
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func newMedia(source string) *models.Media {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &models.Media{
+		ID:        uuid.New(),
+		Status:    models.UploadedStatus,
+		Type:      models.Video,
+		Source:    source,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryRepository()
+	m1, m2 := newMedia("a"), newMedia("b")
+	require.NoError(t, src.Create(ctx, m1))
+	require.NoError(t, src.Create(ctx, m2))
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf, 0))
+
+	dst := NewMemoryRepository()
+	require.NoError(t, dst.Restore(&buf, 0))
+
+	got, err := dst.GetByID(ctx, m1.ID)
+	require.NoError(t, err)
+	require.True(t, m1.CreatedAt.Equal(got.CreatedAt))
+	require.Equal(t, m1.Source, got.Source)
+
+	_, err = dst.GetByID(ctx, m2.ID)
+	require.NoError(t, err)
+}
+
+func TestSnapshot_RoundTripHistory(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryRepository()
+	m := newMedia("a")
+	require.NoError(t, src.Create(ctx, m))
+	changes := []models.StatusChange{
+		{MediaID: m.ID, From: models.UploadedStatus, To: models.ProcessingStatus, Actor: "processing"},
+		{MediaID: m.ID, From: models.ProcessingStatus, To: models.ReadyStatus, Actor: "processing", Reason: "transcoded"},
+	}
+	for i := range changes {
+		require.NoError(t, src.AddStatusChange(ctx, &changes[i]))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf, 0))
+
+	// Restore заменяет и историю, а не дописывает к ней
+	dst := NewMemoryRepository()
+	require.NoError(t, dst.AddStatusChange(ctx, &models.StatusChange{MediaID: uuid.New()}))
+	require.NoError(t, dst.Restore(&buf, 0))
+
+	history, err := dst.ListStatusChanges(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, changes, history)
+
+	// id новых переходов продолжают сохранённые
+	next := models.StatusChange{MediaID: m.ID, From: models.ReadyStatus, To: models.FailedStatus}
+	require.NoError(t, dst.AddStatusChange(ctx, &next))
+	require.Equal(t, int64(3), next.ID)
+}
+
+func TestSnapshot_RestoresVersion1(t *testing.T) {
+	ctx := context.Background()
+	m := newMedia("a")
+	data := `{"version":1,"saved_at":"2026-01-01T00:00:00Z","media":[{"id":"` + m.ID.String() + `","status":"uploaded","type":"video","source":"a"}]}`
+
+	repo := NewMemoryRepository()
+	require.NoError(t, repo.Restore(bytes.NewBufferString(data), 0))
+	_, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	history, err := repo.ListStatusChanges(ctx, m.ID)
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
+func TestSnapshot_SizeLimit(t *testing.T) {
+	repo := NewMemoryRepository()
+	require.NoError(t, repo.Create(context.Background(), newMedia("s3://bucket/file.mp4")))
+
+	var buf bytes.Buffer
+	err := repo.Snapshot(&buf, 10)
+	require.ErrorIs(t, err, ErrSnapshotTooLarge)
+	require.Zero(t, buf.Len())
+
+	err = NewMemoryRepository().Restore(bytes.NewReader(bytes.Repeat([]byte("x"), 11)), 10)
+	require.ErrorIs(t, err, ErrSnapshotTooLarge)
+}
+
+func TestSnapshot_Corrupted(t *testing.T) {
+	cases := map[string]string{
+		"garbage":         "{not json",
+		"unknown version": `{"version":42,"media":[]}`,
+		"missing id":      `{"version":1,"media":[{"Source":"x"}]}`,
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := NewMemoryRepository().Restore(bytes.NewReader([]byte(data)), 0)
+			require.ErrorIs(t, err, ErrSnapshotCorrupted)
+		})
+	}
+}
+
+func TestSnapshotter_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "media.json")
+
+	src := NewMemoryRepository()
+	m := newMedia("a")
+	require.NoError(t, src.Create(ctx, m))
+
+	s, err := NewSnapshotter(src, SnapshotConfig{Path: path, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	require.NoError(t, s.Save())
+
+	dst := NewMemoryRepository()
+	s, err = NewSnapshotter(dst, SnapshotConfig{Path: path, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	require.NoError(t, s.Load())
+
+	_, err = dst.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+}
+
+func TestSnapshotter_LoadMissingFile(t *testing.T) {
+	s, err := NewSnapshotter(NewMemoryRepository(), SnapshotConfig{
+		Path:   filepath.Join(t.TempDir(), "absent.json"),
+		Logger: zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Load())
+}
+
+func TestSnapshotter_LoadCorruptedMovesAside(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "media.json")
+	require.NoError(t, os.WriteFile(path, []byte("{broken"), 0o600))
+
+	s, err := NewSnapshotter(NewMemoryRepository(), SnapshotConfig{Path: path, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	// Повреждённый снапшот не должен мешать старту
+	require.NoError(t, s.Load())
+
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	matches, err := filepath.Glob(path + ".corrupt-*")
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+}
+
+func TestNewSnapshotter_Validation(t *testing.T) {
+	_, err := NewSnapshotter(nil, SnapshotConfig{Path: "x"})
+	require.Error(t, err)
+
+	_, err = NewSnapshotter(NewMemoryRepository(), SnapshotConfig{})
+	require.Error(t, err)
+
+	_, err = NewSnapshotter(NewMemoryRepository(), SnapshotConfig{Path: "x", Interval: -time.Second})
+	require.Error(t, err)
+}
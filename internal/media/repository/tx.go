@@ -0,0 +1,20 @@
+package repository
+
+import "context"
+
+// TxManager выполняет fn в транзакции хранилища. Транзакция передаётся через ctx:
+// методы репозитория, вызванные с этим ctx, работают в ней. Ошибка fn — откат.
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// NoopTxManager — транзакции in-memory репозитория: fn выполняется как есть,
+// операции применяются сразу, откатить их нельзя. Для демо и тестов без Postgres этого достаточно.
+type NoopTxManager struct{}
+
+func (NoopTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
@@ -0,0 +1,222 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/blob"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// Actor — инициатор переходов статуса, сделанных job'ом (история статусов, события)
+const Actor = "retention"
+
+// Lifecycle меняет состояние медиа; реализуется *service.Service
+type Lifecycle interface {
+	ArchiveMedia(ctx context.Context, id uuid.UUID, location string, meta service.ChangeMeta) (*models.Media, error)
+	DeleteMedia(ctx context.Context, id uuid.UUID, reason models.DeleteReason) error
+}
+
+// JobConfig содержит конфигурацию Job
+type JobConfig struct {
+	Store     DueStore
+	Blobs     blob.Store
+	Media     Lifecycle
+	Interval  time.Duration // Период запуска (default: 1h)
+	BatchSize int           // Медиа за один запрос к Store (default: 100)
+	Logger    zerolog.Logger
+}
+
+// JobMetrics содержит метрики retention job
+type JobMetrics struct {
+	Runs     atomic.Int64
+	Archived atomic.Int64
+	Deleted  atomic.Int64
+	Failed   atomic.Int64 // Медиа, которые не удалось обработать (повторятся в следующем запуске)
+}
+
+// Report — результат одного запуска
+type Report struct {
+	Archived int
+	Deleted  int
+	Failed   int
+}
+
+// Job периодически применяет политики retention: сначала переносит или удаляет исходник,
+// потом меняет состояние медиа. Обе операции идемпотентны, поэтому сбой между ними
+// исправляет следующий запуск, а параллельные запуски на нескольких инстансах безопасны.
+type Job struct {
+	store     DueStore
+	blobs     blob.Store
+	media     Lifecycle
+	interval  time.Duration
+	batchSize int
+	clock     func() time.Time
+	logger    zerolog.Logger
+	metrics   *JobMetrics
+}
+
+func NewJob(cfg JobConfig) (*Job, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if cfg.Blobs == nil {
+		return nil, fmt.Errorf("blob store is required")
+	}
+	if cfg.Media == nil {
+		return nil, fmt.Errorf("media lifecycle is required")
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %v", cfg.Interval)
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("batch size cannot be negative, got: %d", cfg.BatchSize)
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Job{
+		store:     cfg.Store,
+		blobs:     cfg.Blobs,
+		media:     cfg.Media,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+		clock:     time.Now,
+		logger:    cfg.Logger.With().Str("component", "retention_job").Logger(),
+		metrics:   &JobMetrics{},
+	}, nil
+}
+
+// Metrics возвращает метрики job
+func (j *Job) Metrics() *JobMetrics { return j.metrics }
+
+// RunOnce обрабатывает все медиа с истёкшим сроком. Ошибка отдельного медиа не прерывает
+// запуск, но повторно в этом запуске медиа не берётся. Запуск заканчивается, когда batch
+// неполный или в нём не удалось ни одно медиа: неудавшиеся повторятся в следующий раз.
+func (j *Job) RunOnce(ctx context.Context) (Report, error) {
+	j.metrics.Runs.Add(1)
+	ctx = service.WithActor(ctx, Actor)
+	now := j.clock()
+
+	var report Report
+	failed := make(map[uuid.UUID]bool)
+	for {
+		due, err := j.store.Due(ctx, now, j.batchSize)
+		if err != nil {
+			return report, fmt.Errorf("find expired media: %w", err)
+		}
+
+		progress := 0
+		for _, c := range due {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			if failed[c.Media.ID] {
+				continue
+			}
+			if err := j.apply(ctx, c); err != nil {
+				failed[c.Media.ID] = true
+				report.Failed++
+				j.metrics.Failed.Add(1)
+				j.logger.Error().
+					Err(err).
+					Str("media_id", c.Media.ID.String()).
+					Int64("policy_id", c.Policy.ID).
+					Str("action", string(c.Policy.Action)).
+					Msg("retention failed")
+				continue
+			}
+			progress++
+			if c.Policy.Action == ActionArchive {
+				report.Archived++
+				j.metrics.Archived.Add(1)
+			} else {
+				report.Deleted++
+				j.metrics.Deleted.Add(1)
+			}
+		}
+
+		if len(due) < j.batchSize || progress == 0 {
+			break
+		}
+	}
+
+	j.logger.Info().
+		Int("archived", report.Archived).
+		Int("deleted", report.Deleted).
+		Int("failed", report.Failed).
+		Msg("retention run completed")
+	return report, nil
+}
+
+// apply выполняет действие политики над одним медиа. Исходник, на который ссылаются
+// другие медиа, остаётся на месте: его перенесёт или удалит политика последнего из них.
+func (j *Job) apply(ctx context.Context, c Candidate) error {
+	meta := service.ChangeMeta{Actor: Actor, Reason: "retention policy " + strconv.FormatInt(c.Policy.ID, 10)}
+	shared, err := j.store.SourceShared(ctx, c.Media)
+	if err != nil {
+		return fmt.Errorf("check shared source: %w", err)
+	}
+
+	switch c.Policy.Action {
+	case ActionArchive:
+		location := c.Media.Source
+		if !shared {
+			if location, err = j.blobs.Archive(ctx, c.Media.Source); err != nil {
+				return fmt.Errorf("archive blob: %w", err)
+			}
+		}
+		_, err = j.media.ArchiveMedia(ctx, c.Media.ID, location, meta)
+		return ignoreGone(err)
+	case ActionDelete:
+		if !shared {
+			if err := j.blobs.Delete(ctx, c.Media.Source); err != nil {
+				return fmt.Errorf("delete blob: %w", err)
+			}
+		}
+		return ignoreGone(j.media.DeleteMedia(ctx, c.Media.ID, models.DeleteReasonExpired))
+	default:
+		return fmt.Errorf("unknown action %q", c.Policy.Action)
+	}
+}
+
+// ignoreGone — медиа удалили между выборкой и действием (пользователь или другой инстанс job'а)
+func ignoreGone(err error) error {
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Start запускает job сразу и далее каждые Interval до отмены контекста
+func (j *Job) Start(ctx context.Context) error {
+	j.logger.Info().Dur("interval", j.interval).Msg("retention job started")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info().Msg("retention job stopped")
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				j.logger.Error().Err(err).Msg("retention run failed")
+			}
+			timer.Reset(j.interval)
+		}
+	}
+}
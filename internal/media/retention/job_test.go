@@ -0,0 +1,213 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// fakeDue отдаёт медиа из репозитория, пока они в подходящем статусе — как SQL выборка Due
+type fakeDue struct {
+	repo     repository.MediaRepository
+	policies map[uuid.UUID]Policy
+}
+
+func (f *fakeDue) Due(ctx context.Context, now time.Time, limit int) ([]Candidate, error) {
+	var out []Candidate
+	for id, p := range f.policies {
+		m, err := f.repo.GetByID(ctx, id)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		eligible := false
+		for _, s := range p.Action.EligibleStatuses() {
+			eligible = eligible || m.Status == s
+		}
+		if eligible && !m.CreatedAt.Add(p.RetainFor).After(now) && len(out) < limit {
+			out = append(out, Candidate{Media: *m, Policy: p})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDue) SourceShared(ctx context.Context, m models.Media) (bool, error) {
+	all, err := f.repo.List(ctx, repository.ListFilter{})
+	if err != nil {
+		return false, err
+	}
+	for _, other := range all {
+		if other.ID != m.ID && other.Source == m.Source {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeBlobs struct {
+	archived []string
+	deleted  []string
+	fail     map[string]bool
+}
+
+func (b *fakeBlobs) Archive(ctx context.Context, source string) (string, error) {
+	if b.fail[source] {
+		return "", errors.New("s3 unavailable")
+	}
+	b.archived = append(b.archived, source)
+	return source + ".cold", nil
+}
+
+func (b *fakeBlobs) Delete(ctx context.Context, source string) error {
+	if b.fail[source] {
+		return errors.New("s3 unavailable")
+	}
+	b.deleted = append(b.deleted, source)
+	return nil
+}
+
+func readyMedia(t *testing.T, svc *service.Service, source string) *models.Media {
+	t.Helper()
+	ctx := context.Background()
+	m, err := svc.CreateMedia(ctx, models.Video, source)
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	m, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	return m
+}
+
+func TestJob_ArchivesAndDeletesExpiredMedia(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := service.New(repo, nil)
+
+	toArchive := readyMedia(t, svc, "s3://media/a.mp4")
+	toDelete := readyMedia(t, svc, "s3://media/b.mp4")
+	broken := readyMedia(t, svc, "s3://media/c.mp4")
+	fresh := readyMedia(t, svc, "s3://media/d.mp4")
+
+	due := &fakeDue{repo: repo, policies: map[uuid.UUID]Policy{
+		toArchive.ID: {ID: 1, RetainFor: time.Nanosecond, Action: ActionArchive},
+		toDelete.ID:  {ID: 2, RetainFor: time.Nanosecond, Action: ActionDelete},
+		broken.ID:    {ID: 3, RetainFor: time.Nanosecond, Action: ActionArchive},
+		fresh.ID:     {ID: 4, RetainFor: time.Hour, Action: ActionDelete},
+	}}
+	blobs := &fakeBlobs{fail: map[string]bool{"s3://media/c.mp4": true}}
+
+	job, err := NewJob(JobConfig{Store: due, Blobs: blobs, Media: svc, BatchSize: 2, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	job.clock = func() time.Time { return time.Now().Add(time.Minute) }
+
+	report, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Report{Archived: 1, Deleted: 1, Failed: 1}, report)
+
+	archived, err := repo.GetByID(ctx, toArchive.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedStatus, archived.Status)
+	require.Equal(t, "s3://media/a.mp4.cold", archived.Source)
+
+	history, err := svc.GetStatusHistory(ctx, toArchive.ID)
+	require.NoError(t, err)
+	require.Equal(t, Actor, history[len(history)-1].Actor)
+	require.Equal(t, "retention policy 1", history[len(history)-1].Reason)
+
+	_, err = repo.GetByID(ctx, toDelete.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	require.Equal(t, []string{"s3://media/b.mp4"}, blobs.deleted)
+
+	// Неудавшееся медиа остаётся и повторится в следующем запуске; свежее не тронуто
+	stillReady, err := repo.GetByID(ctx, broken.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ReadyStatus, stillReady.Status)
+	_, err = repo.GetByID(ctx, fresh.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, job.Metrics().Failed.Load())
+}
+
+func TestJob_KeepsSharedSource(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := service.New(repo, nil)
+
+	// copy сослалось на исходник original при загрузке (дедупликация)
+	original := readyMedia(t, svc, "s3://media/a.mp4")
+	copied := readyMedia(t, svc, "s3://media/a.mp4")
+	due := &fakeDue{repo: repo, policies: map[uuid.UUID]Policy{
+		original.ID: {ID: 1, RetainFor: time.Nanosecond, Action: ActionArchive},
+	}}
+	blobs := &fakeBlobs{}
+	job, err := NewJob(JobConfig{Store: due, Blobs: blobs, Media: svc, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	job.clock = func() time.Time { return time.Now().Add(time.Minute) }
+
+	// Пока исходник общий, объект не переносится и не удаляется
+	_, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	archived, err := repo.GetByID(ctx, original.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedStatus, archived.Status)
+	require.Equal(t, "s3://media/a.mp4", archived.Source)
+	require.Empty(t, blobs.archived)
+
+	due.policies = map[uuid.UUID]Policy{copied.ID: {ID: 2, RetainFor: time.Nanosecond, Action: ActionDelete}}
+	_, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	_, err = repo.GetByID(ctx, copied.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	require.Empty(t, blobs.deleted)
+}
+
+func TestJob_MediaGoneIsNotAFailure(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := service.New(repo, nil)
+	m := readyMedia(t, svc, "s3://media/a.mp4")
+
+	// Due отдал медиа, которое удалили до действия
+	gone := &staticDue{candidates: []Candidate{{Media: *m, Policy: Policy{ID: 1, Action: ActionDelete}}}}
+	require.NoError(t, svc.DeleteMedia(context.Background(), m.ID, models.DeleteReasonDeleted))
+
+	job, err := NewJob(JobConfig{Store: gone, Blobs: &fakeBlobs{}, Media: svc, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	report, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Report{Deleted: 1}, report)
+}
+
+type staticDue struct {
+	candidates []Candidate
+}
+
+func (s *staticDue) Due(context.Context, time.Time, int) ([]Candidate, error) {
+	return s.candidates, nil
+}
+
+func (s *staticDue) SourceShared(context.Context, models.Media) (bool, error) { return false, nil }
+
+func TestPolicy_Validate(t *testing.T) {
+	require.NoError(t, Policy{MediaType: models.Video, RetainFor: time.Hour, Action: ActionArchive}.Validate())
+	require.NoError(t, Policy{MediaID: uuid.New(), RetainFor: time.Hour, Action: ActionDelete}.Validate())
+
+	for name, p := range map[string]Policy{
+		"no target":    {RetainFor: time.Hour, Action: ActionDelete},
+		"both targets": {MediaID: uuid.New(), MediaType: models.Video, RetainFor: time.Hour, Action: ActionDelete},
+		"bad type":     {MediaType: "image", RetainFor: time.Hour, Action: ActionDelete},
+		"no duration":  {MediaType: models.Video, Action: ActionDelete},
+		"bad action":   {MediaType: models.Video, RetainFor: time.Hour, Action: "shred"},
+	} {
+		require.ErrorIs(t, p.Validate(), models.ErrInvalidArgument, name)
+	}
+}
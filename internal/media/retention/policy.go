@@ -0,0 +1,90 @@
+// Package retention — сроки хранения медиа. Политика задаётся на тип медиа или на конкретное
+// медиа (она важнее политики типа); Job периодически находит медиа с истёкшим сроком,
+// переносит или удаляет исходник через blob.Store и меняет состояние медиа через сервис.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// ErrPolicyNotFound — политики с таким id нет
+var ErrPolicyNotFound = errors.New("retention policy not found")
+
+// Action — что делать с медиа по истечении срока
+type Action string
+
+const (
+	// ActionArchive переносит исходник в холодное хранилище, медиа переходит в archived
+	ActionArchive Action = "archive"
+	// ActionDelete удаляет исходник и медиа (MediaDeleted с reason=expired)
+	ActionDelete Action = "delete"
+)
+
+// Policy — срок хранения медиа одного типа (MediaType) или одного медиа (MediaID).
+// Срок отсчитывается от created_at медиа.
+type Policy struct {
+	ID        int64            `db:"id"`
+	MediaID   uuid.UUID        `db:"media_id"`   // uuid.Nil — политика типа
+	MediaType models.MediaType `db:"media_type"` // пустой — политика конкретного медиа
+	RetainFor time.Duration    `db:"retain_for"`
+	Action    Action           `db:"action"`
+	CreatedAt time.Time        `db:"created_at"`
+}
+
+// Validate проверяет, что политика задана ровно для типа или для медиа
+func (p Policy) Validate() error {
+	if (p.MediaID == uuid.Nil) == (p.MediaType == "") {
+		return fmt.Errorf("%w: policy must target either a media type or a media id", models.ErrInvalidArgument)
+	}
+	switch p.MediaType {
+	case "", models.Video, models.Audio, models.File:
+	default:
+		return fmt.Errorf("%w: unknown media type %q", models.ErrInvalidArgument, p.MediaType)
+	}
+	if p.RetainFor <= 0 {
+		return fmt.Errorf("%w: retain_for must be positive", models.ErrInvalidArgument)
+	}
+	if p.Action != ActionArchive && p.Action != ActionDelete {
+		return fmt.Errorf("%w: unknown action %q", models.ErrInvalidArgument, p.Action)
+	}
+	return nil
+}
+
+// EligibleStatuses — статусы, из которых действие применимо. Медиа в обработке не трогаем;
+// архивируется только обработанное медиа (в том числе под эмбарго), архивное и в карантине можно удалить.
+func (a Action) EligibleStatuses() []models.Status {
+	if a == ActionArchive {
+		return []models.Status{models.ReadyStatus, models.ScheduledStatus, models.FailedStatus}
+	}
+	return []models.Status{models.UploadedStatus, models.ReadyStatus, models.ScheduledStatus, models.FailedStatus, models.ArchivedStatus, models.QuarantinedStatus}
+}
+
+// Candidate — медиа с истёкшим сроком и применённая к нему политика
+type Candidate struct {
+	Media  models.Media
+	Policy Policy
+}
+
+// PolicyStore хранит политики; реализуется *postgres.RetentionRepo
+type PolicyStore interface {
+	// SetPolicy создаёт политику или заменяет существующую для того же типа/медиа
+	SetPolicy(ctx context.Context, p Policy) (Policy, error)
+	ListPolicies(ctx context.Context) ([]Policy, error)
+	DeletePolicy(ctx context.Context, id int64) error
+}
+
+// DueStore находит медиа с истёкшим сроком; реализуется *postgres.RetentionRepo
+type DueStore interface {
+	// Due возвращает до limit медиа, срок которых истёк к now, в порядке created_at.
+	// Политика медиа важнее политики его типа; медиа в неподходящем для действия статусе пропускаются.
+	Due(ctx context.Context, now time.Time, limit int) ([]Candidate, error)
+	// SourceShared — на исходник медиа ссылаются и другие медиа (дедупликация загрузок)
+	SourceShared(ctx context.Context, m models.Media) (bool, error)
+}
@@ -0,0 +1,226 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// Actor — инициатор переходов статуса, сделанных планировщиком (история статусов, события)
+const Actor = "scheduler"
+
+// Lifecycle меняет состояние медиа; реализуется *service.Service
+type Lifecycle interface {
+	ChangeStatus(ctx context.Context, id uuid.UUID, to models.Status, meta service.ChangeMeta) (*models.Media, error)
+	ExpireMedia(ctx context.Context, id uuid.UUID, meta service.ChangeMeta) (*models.Media, error)
+}
+
+// DueStore находит медиа, расписание которых наступило; реализуется *postgres.ScheduleRepo
+type DueStore interface {
+	// DuePublish возвращает до limit медиа в статусе scheduled, у которых наступил publish_at
+	// и ещё не наступил expires_at, в порядке publish_at
+	DuePublish(ctx context.Context, now time.Time, limit int) ([]models.Media, error)
+	// DueExpiry возвращает до limit обработанных медиа (ready, scheduled, failed),
+	// у которых наступил expires_at, в порядке expires_at
+	DueExpiry(ctx context.Context, now time.Time, limit int) ([]models.Media, error)
+}
+
+// JobConfig содержит конфигурацию Job
+type JobConfig struct {
+	Store     DueStore
+	Media     Lifecycle
+	Interval  time.Duration // Период запуска (default: 1m)
+	BatchSize int           // Медиа за один запрос к Store (default: 100)
+	Logger    zerolog.Logger
+}
+
+// JobMetrics содержит метрики планировщика
+type JobMetrics struct {
+	Runs      atomic.Int64
+	Published atomic.Int64
+	Expired   atomic.Int64
+	Failed    atomic.Int64 // Медиа, которые не удалось обработать (повторятся в следующем запуске)
+}
+
+// Report — результат одного запуска
+type Report struct {
+	Published int
+	Expired   int
+	Failed    int
+}
+
+// Job периодически применяет расписание медиа: архивирует медиа с наступившим expires_at
+// и публикует (scheduled → ready) медиа с наступившим publish_at. Переходы идемпотентны и
+// перепроверяются сервисом в транзакции, поэтому параллельные запуски на нескольких
+// инстансах безопасны.
+type Job struct {
+	store     DueStore
+	media     Lifecycle
+	interval  time.Duration
+	batchSize int
+	clock     func() time.Time
+	logger    zerolog.Logger
+	metrics   *JobMetrics
+}
+
+func NewJob(cfg JobConfig) (*Job, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if cfg.Media == nil {
+		return nil, fmt.Errorf("media lifecycle is required")
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %v", cfg.Interval)
+	}
+	if cfg.BatchSize < 0 {
+		return nil, fmt.Errorf("batch size cannot be negative, got: %d", cfg.BatchSize)
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Job{
+		store:     cfg.Store,
+		media:     cfg.Media,
+		interval:  cfg.Interval,
+		batchSize: cfg.BatchSize,
+		clock:     time.Now,
+		logger:    cfg.Logger.With().Str("component", "schedule_job").Logger(),
+		metrics:   &JobMetrics{},
+	}, nil
+}
+
+// Metrics возвращает метрики job
+func (j *Job) Metrics() *JobMetrics { return j.metrics }
+
+// RunOnce сначала архивирует медиа с истёкшим сроком, потом публикует медиа, эмбарго которых
+// закончилось: медиа, у которого наступили оба срока, не публикуется перед архивацией.
+// Ошибка отдельного медиа не прерывает запуск, но повторно в этом запуске медиа не берётся.
+func (j *Job) RunOnce(ctx context.Context) (Report, error) {
+	j.metrics.Runs.Add(1)
+	ctx = service.WithActor(ctx, Actor)
+	now := j.clock()
+
+	var report Report
+	skip := make(map[uuid.UUID]bool)
+
+	expired, err := j.drain(ctx, skip, &report, func(ctx context.Context) ([]models.Media, error) {
+		return j.store.DueExpiry(ctx, now, j.batchSize)
+	}, func(ctx context.Context, m models.Media) (bool, error) {
+		_, err := j.media.ExpireMedia(ctx, m.ID, service.ChangeMeta{Actor: Actor, Reason: "expires_at reached"})
+		return err == nil, err
+	})
+	report.Expired = expired
+	j.metrics.Expired.Add(int64(expired))
+	if err != nil {
+		return report, fmt.Errorf("expire media: %w", err)
+	}
+
+	published, err := j.drain(ctx, skip, &report, func(ctx context.Context) ([]models.Media, error) {
+		return j.store.DuePublish(ctx, now, j.batchSize)
+	}, func(ctx context.Context, m models.Media) (bool, error) {
+		got, err := j.media.ChangeStatus(ctx, m.ID, models.ReadyStatus, service.ChangeMeta{Actor: Actor, Reason: "publish_at reached"})
+		// publish_at успели перенести — медиа осталось scheduled
+		return err == nil && got.Status == models.ReadyStatus, err
+	})
+	report.Published = published
+	j.metrics.Published.Add(int64(published))
+	if err != nil {
+		return report, fmt.Errorf("publish media: %w", err)
+	}
+
+	j.logger.Info().
+		Int("published", report.Published).
+		Int("expired", report.Expired).
+		Int("failed", report.Failed).
+		Msg("schedule run completed")
+	return report, nil
+}
+
+// drain применяет apply ко всем медиа, которые отдаёт due, пока batch полный и в нём что-то
+// изменилось; возвращает число переходов. Медиа, которое не удалось или не изменилось
+// (гонка), попадает в skip и больше в этом запуске не берётся.
+func (j *Job) drain(
+	ctx context.Context,
+	skip map[uuid.UUID]bool,
+	report *Report,
+	due func(context.Context) ([]models.Media, error),
+	apply func(context.Context, models.Media) (bool, error),
+) (int, error) {
+	done := 0
+	for {
+		batch, err := due(ctx)
+		if err != nil {
+			return done, err
+		}
+
+		progress := 0
+		for _, m := range batch {
+			if err := ctx.Err(); err != nil {
+				return done, err
+			}
+			if skip[m.ID] {
+				continue
+			}
+			changed, err := apply(ctx, m)
+			if err = ignoreRaced(err); err != nil {
+				report.Failed++
+				j.metrics.Failed.Add(1)
+				j.logger.Error().Err(err).Str("media_id", m.ID.String()).Msg("schedule failed")
+			}
+			if !changed {
+				skip[m.ID] = true
+				continue
+			}
+			progress++
+		}
+		done += progress
+
+		if len(batch) < j.batchSize || progress == 0 {
+			return done, nil
+		}
+	}
+}
+
+// ignoreRaced — медиа изменили между выборкой и переходом: удалили, перенесли срок или
+// перевели в другой статус (пользователь или другой инстанс планировщика)
+func ignoreRaced(err error) error {
+	if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrConflict) || errors.Is(err, domain.ErrInvalidTransition) {
+		return nil
+	}
+	return err
+}
+
+// Start запускает job сразу и далее каждые Interval до отмены контекста
+func (j *Job) Start(ctx context.Context) error {
+	j.logger.Info().Dur("interval", j.interval).Msg("schedule job started")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			j.logger.Info().Msg("schedule job stopped")
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				j.logger.Error().Err(err).Msg("schedule run failed")
+			}
+			timer.Reset(j.interval)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// fakeDue выбирает медиа из репозитория теми же условиями, что SQL выборки ScheduleRepo
+type fakeDue struct {
+	repo repository.MediaRepository
+	err  error
+}
+
+func (f *fakeDue) DuePublish(ctx context.Context, now time.Time, limit int) ([]models.Media, error) {
+	return f.due(ctx, limit, func(m *models.Media) bool {
+		return m.Status == models.ScheduledStatus && !m.Embargoed(now) && (m.ExpiresAt == nil || m.ExpiresAt.After(now))
+	})
+}
+
+func (f *fakeDue) DueExpiry(ctx context.Context, now time.Time, limit int) ([]models.Media, error) {
+	return f.due(ctx, limit, func(m *models.Media) bool {
+		switch m.Status {
+		case models.ReadyStatus, models.ScheduledStatus, models.FailedStatus:
+			return m.ExpiresAt != nil && !m.ExpiresAt.After(now)
+		}
+		return false
+	})
+}
+
+func (f *fakeDue) due(ctx context.Context, limit int, match func(*models.Media) bool) ([]models.Media, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	all, err := f.repo.List(ctx, repository.ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+	var out []models.Media
+	for _, m := range all {
+		if match(m) && len(out) < limit {
+			out = append(out, *m)
+		}
+	}
+	return out, nil
+}
+
+// scheduledMedia создаёт обработанное медиа под эмбарго и сдвигает его расписание в прошлое
+// в обход сервиса — как будто publish_at и expires_at наступили
+func scheduledMedia(t *testing.T, repo repository.MediaRepository, svc *service.Service, publishAgo, expiresAgo time.Duration) *models.Media {
+	t.Helper()
+	ctx := context.Background()
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://media/"+uuid.NewString()+".mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	future := time.Now().Add(time.Hour)
+	_, err = svc.SetSchedule(ctx, m.ID, models.Schedule{PublishAt: &future}, service.ChangeMeta{})
+	require.NoError(t, err)
+	m, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.ScheduledStatus, m.Status)
+
+	var sched models.Schedule
+	if publishAgo != 0 {
+		at := time.Now().Add(-publishAgo)
+		sched.PublishAt = &at
+	}
+	if expiresAgo != 0 {
+		at := time.Now().Add(-expiresAgo)
+		sched.ExpiresAt = &at
+	}
+	m, err = repo.Update(ctx, m.ID, models.MediaPatch{Schedule: &sched})
+	require.NoError(t, err)
+	return m
+}
+
+func TestJob_PublishesAndExpires(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := service.New(repo, nil)
+
+	var toPublish []*models.Media
+	for range 3 {
+		toPublish = append(toPublish, scheduledMedia(t, repo, svc, time.Minute, -time.Hour))
+	}
+	// Оба срока наступили — медиа архивируется, не публикуясь
+	toExpire := scheduledMedia(t, repo, svc, 2*time.Minute, time.Minute)
+	embargoed := scheduledMedia(t, repo, svc, -time.Hour, 0)
+
+	job, err := NewJob(JobConfig{Store: &fakeDue{repo: repo}, Media: svc, BatchSize: 2, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	report, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Report{Published: 3, Expired: 1}, report)
+
+	for _, m := range toPublish {
+		got, err := repo.GetByID(ctx, m.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.ReadyStatus, got.Status)
+	}
+	history, err := svc.GetStatusHistory(ctx, toPublish[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, Actor, history[len(history)-1].Actor)
+	require.Equal(t, "publish_at reached", history[len(history)-1].Reason)
+
+	got, err := repo.GetByID(ctx, toExpire.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedStatus, got.Status)
+	require.Equal(t, toExpire.Source, got.Source)
+
+	got, err = repo.GetByID(ctx, embargoed.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ScheduledStatus, got.Status)
+
+	// Повтор ничего не находит
+	report, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Report{}, report)
+	require.EqualValues(t, 2, job.Metrics().Runs.Load())
+	require.EqualValues(t, 3, job.Metrics().Published.Load())
+}
+
+func TestJob_RacedMediaIsNotAFailure(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := service.New(repo, nil)
+	m := scheduledMedia(t, repo, svc, time.Minute, time.Minute)
+
+	// Выборка отдала медиа, срок которого успели продлить
+	stale := &staticDue{expiry: []models.Media{*m}}
+	later := time.Now().Add(time.Hour)
+	_, err := repo.Update(ctx, m.ID, models.MediaPatch{Schedule: &models.Schedule{ExpiresAt: &later}})
+	require.NoError(t, err)
+
+	job, err := NewJob(JobConfig{Store: stale, Media: svc, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	report, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Report{}, report)
+}
+
+func TestJob_StoreError(t *testing.T) {
+	job, err := NewJob(JobConfig{Store: &fakeDue{err: errors.New("db down")}, Media: service.New(repository.NewMemoryRepository(), nil), Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	_, err = job.RunOnce(context.Background())
+	require.ErrorContains(t, err, "db down")
+}
+
+type staticDue struct {
+	publish, expiry []models.Media
+}
+
+func (s *staticDue) DuePublish(context.Context, time.Time, int) ([]models.Media, error) {
+	return s.publish, nil
+}
+
+func (s *staticDue) DueExpiry(context.Context, time.Time, int) ([]models.Media, error) {
+	return s.expiry, nil
+}
+
+func TestNewJob_Validation(t *testing.T) {
+	svc := service.New(repository.NewMemoryRepository(), nil)
+	_, err := NewJob(JobConfig{Media: svc})
+	require.Error(t, err)
+	_, err = NewJob(JobConfig{Store: &staticDue{}})
+	require.Error(t, err)
+	_, err = NewJob(JobConfig{Store: &staticDue{}, Media: svc, Interval: -time.Second})
+	require.Error(t, err)
+
+	job, err := NewJob(JobConfig{Store: &staticDue{}, Media: svc})
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, job.interval)
+	require.Equal(t, 100, job.batchSize)
+}
@@ -0,0 +1,328 @@
+// Package seed наполняет базу правдоподобными медиа для локальной разработки: фронтенду и
+// consumer'ам событий нужны данные во всех статусах, а не пустая таблица.
+package seed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// Actor — инициатор переходов статуса, сделанных seed (история статусов, события)
+const Actor = "seed"
+
+// Media — операции сервиса, которыми seed проводит медиа по жизненному циклу, чтобы
+// история статусов и события outbox были такими же, как у настоящих; реализуется *service.Service
+type Media interface {
+	CreateMediaBatch(ctx context.Context, items []service.BatchItem) ([]service.BatchItemResult, error)
+	RecordContent(ctx context.Context, id uuid.UUID, c models.Content) (*models.Media, error)
+	ChangeStatus(ctx context.Context, id uuid.UUID, to models.Status, meta service.ChangeMeta) (*models.Media, error)
+	ArchiveMedia(ctx context.Context, id uuid.UUID, location string, meta service.ChangeMeta) (*models.Media, error)
+	QuarantineMedia(ctx context.Context, id uuid.UUID, v service.Verdict, meta service.ChangeMeta) (*models.Media, error)
+	DeleteMedia(ctx context.Context, id uuid.UUID, reason models.DeleteReason) error
+}
+
+// Attributes записывает title, tags и metadata: у сервиса для них операции нет, поэтому
+// они пишутся в репозиторий напрямую; реализуется репозиторием медиа
+type Attributes interface {
+	Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error)
+}
+
+// Config содержит конфигурацию Seeder
+type Config struct {
+	Media  Media
+	Attrs  Attributes
+	Count  int    // сколько медиа создать (default: 100)
+	Owners int    // между сколькими владельцами их поделить (default: 5)
+	Seed   uint64 // зерно генератора: один и тот же Seed даёт те же данные; 0 — случайное
+	Logger zerolog.Logger
+}
+
+// Report — что создал Seeder
+type Report struct {
+	Created  int
+	ByStatus map[models.Status]int
+	ByType   map[models.MediaType]int
+	Owners   []uuid.UUID
+}
+
+// Seeder создаёт медиа всех типов и статусов
+type Seeder struct {
+	media  Media
+	attrs  Attributes
+	count  int
+	owners int
+	rnd    *rand.Rand
+	logger zerolog.Logger
+}
+
+func New(cfg Config) (*Seeder, error) {
+	if cfg.Media == nil {
+		return nil, errors.New("media service is required")
+	}
+	if cfg.Attrs == nil {
+		return nil, errors.New("attributes store is required")
+	}
+	if cfg.Count < 0 {
+		return nil, fmt.Errorf("count cannot be negative, got: %d", cfg.Count)
+	}
+	if cfg.Owners < 0 {
+		return nil, fmt.Errorf("owners cannot be negative, got: %d", cfg.Owners)
+	}
+	if cfg.Count == 0 {
+		cfg.Count = 100
+	}
+	if cfg.Owners == 0 {
+		cfg.Owners = 5
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = rand.Uint64()
+	}
+
+	return &Seeder{
+		media:  cfg.Media,
+		attrs:  cfg.Attrs,
+		count:  cfg.Count,
+		owners: cfg.Owners,
+		rnd:    rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		logger: cfg.Logger.With().Str("component", "seed").Uint64("seed", cfg.Seed).Logger(),
+	}, nil
+}
+
+// weighted — значение и его доля в выборке
+type weighted[T any] struct {
+	value  T
+	weight int
+}
+
+// statuses — распределение итоговых статусов: больше всего готовых, как в живой базе
+var statuses = []weighted[models.Status]{
+	{models.UploadedStatus, 15},
+	{models.ProcessingStatus, 10},
+	{models.ReadyStatus, 40},
+	{models.FailedStatus, 10},
+	{models.ArchivedStatus, 10},
+	{models.QuarantinedStatus, 5},
+	{models.DeletedStatus, 10},
+}
+
+var types = []weighted[models.MediaType]{
+	{models.Video, 50},
+	{models.Audio, 30},
+	{models.File, 20},
+}
+
+var (
+	adjectives = []string{"Morning", "Quiet", "Golden", "Winter", "Urban", "Lost", "Electric", "Northern", "Hidden", "Summer"}
+	nouns      = []string{"Harbor", "Forest", "Session", "Lecture", "Interview", "Highlights", "Podcast", "Walkthrough", "Concert", "Report"}
+	tagPool    = []string{"travel", "music", "education", "news", "sports", "nature", "tech", "kids", "interview", "archive", "4k", "draft"}
+	failures   = []string{
+		"ffmpeg: Invalid data found when processing input",
+		"transcode: timed out after 30m0s",
+		"probe: unsupported codec hevc_10bit",
+		"storage: source object not found",
+	}
+)
+
+func pick[T any](r *rand.Rand, items []weighted[T]) T {
+	total := 0
+	for _, it := range items {
+		total += it.weight
+	}
+	n := r.IntN(total)
+	for _, it := range items {
+		if n < it.weight {
+			return it.value
+		}
+		n -= it.weight
+	}
+	return items[len(items)-1].value
+}
+
+// plan — медиа, которое предстоит создать
+type plan struct {
+	owner  uuid.UUID
+	typ    models.MediaType
+	status models.Status
+	title  string
+	tags   models.Tags
+	meta   models.Metadata
+	item   service.BatchItem
+	ext    string
+	mime   string
+}
+
+// Run создаёт медиа: пачками на владельца через CreateMediaBatch (события MediaCreated),
+// затем записывает исходник и проводит каждое по переходам до выбранного статуса.
+// Ошибка останавливает seed; уже созданное остаётся и попадает в Report.
+func (s *Seeder) Run(ctx context.Context) (Report, error) {
+	rep := Report{ByStatus: make(map[models.Status]int), ByType: make(map[models.MediaType]int)}
+	for range s.owners {
+		rep.Owners = append(rep.Owners, s.uuid())
+	}
+
+	byOwner := make(map[uuid.UUID][]plan)
+	for i := range s.count {
+		p := s.plan(rep.Owners[i%len(rep.Owners)], i)
+		byOwner[p.owner] = append(byOwner[p.owner], p)
+	}
+
+	for _, owner := range rep.Owners {
+		octx := service.WithActor(service.WithPrincipal(ctx, service.Principal{OwnerID: owner}), Actor)
+		plans := byOwner[owner]
+		for start := 0; start < len(plans); start += service.MaxBatchSize {
+			chunk := plans[start:min(start+service.MaxBatchSize, len(plans))]
+			items := make([]service.BatchItem, len(chunk))
+			for i, p := range chunk {
+				items[i] = p.item
+			}
+			if _, err := s.media.CreateMediaBatch(octx, items); err != nil {
+				return rep, fmt.Errorf("create media: %w", err)
+			}
+			for _, p := range chunk {
+				if err := s.advance(octx, p); err != nil {
+					return rep, fmt.Errorf("seed media %s: %w", p.item.ID, err)
+				}
+				rep.Created++
+				rep.ByStatus[p.status]++
+				rep.ByType[p.typ]++
+			}
+		}
+	}
+
+	s.logger.Info().Int("created", rep.Created).Int("owners", len(rep.Owners)).Msg("media seeded")
+	return rep, nil
+}
+
+// uuid — UUID v4 из генератора seed, чтобы один Seed давал те же id
+func (s *Seeder) uuid() uuid.UUID {
+	var id uuid.UUID
+	for i := 0; i < len(id); i += 8 {
+		v := s.rnd.Uint64()
+		for j := range 8 {
+			id[i+j] = byte(v >> (8 * j))
+		}
+	}
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
+
+func (s *Seeder) plan(owner uuid.UUID, n int) plan {
+	r := s.rnd
+	p := plan{
+		owner:  owner,
+		typ:    pick(r, types),
+		status: pick(r, statuses),
+		title:  fmt.Sprintf("%s %s #%d", adjectives[r.IntN(len(adjectives))], nouns[r.IntN(len(nouns))], n+1),
+	}
+	for _, i := range r.Perm(len(tagPool))[:1+r.IntN(3)] {
+		p.tags = append(p.tags, tagPool[i])
+	}
+
+	switch p.typ {
+	case models.Video:
+		res := []string{"1280x720", "1920x1080", "3840x2160"}[r.IntN(3)]
+		p.ext, p.mime = "mp4", "video/mp4"
+		p.meta = models.Metadata{"duration_seconds": strconv.Itoa(30 + r.IntN(3600)), "resolution": res, "codec": "h264"}
+	case models.Audio:
+		p.ext, p.mime = "mp3", "audio/mpeg"
+		p.meta = models.Metadata{"duration_seconds": strconv.Itoa(60 + r.IntN(5400)), "bitrate_kbps": "192", "codec": "mp3"}
+	default:
+		p.ext, p.mime = "pdf", "application/pdf"
+		p.meta = models.Metadata{"pages": strconv.Itoa(1 + r.IntN(300))}
+	}
+	p.meta["language"] = []string{"en", "ru", "de", "es"}[r.IntN(4)]
+
+	id := s.uuid()
+	slug := strings.ToLower(strings.ReplaceAll(p.title, " ", "-"))
+	slug = strings.ReplaceAll(slug, "#", "")
+	p.item = service.BatchItem{
+		ID:     id,
+		Type:   p.typ,
+		Source: fmt.Sprintf("s3://media-uploads/%s/%s/%s.%s", owner, id, slug, p.ext),
+	}
+	return p
+}
+
+// advance дописывает атрибуты и исходник медиа и проводит его до p.status
+func (s *Seeder) advance(ctx context.Context, p plan) error {
+	id := p.item.ID
+	if _, err := s.attrs.Update(ctx, id, models.MediaPatch{Title: &p.title, Tags: &p.tags, Metadata: &p.meta}); err != nil {
+		return fmt.Errorf("attributes: %w", err)
+	}
+	sum := sha256.Sum256([]byte(p.item.Source))
+	content := models.Content{
+		Checksum:    hex.EncodeToString(sum[:]),
+		Size:        s.size(p.typ),
+		ContentType: p.mime,
+	}
+	if _, err := s.media.RecordContent(ctx, id, content); err != nil {
+		return fmt.Errorf("content: %w", err)
+	}
+
+	meta := service.ChangeMeta{Actor: Actor}
+	change := func(to ...models.Status) error {
+		for _, st := range to {
+			m := meta
+			if st == models.FailedStatus {
+				m.Reason = failures[s.rnd.IntN(len(failures))]
+			}
+			if _, err := s.media.ChangeStatus(ctx, id, st, m); err != nil {
+				return fmt.Errorf("status %s: %w", st, err)
+			}
+		}
+		return nil
+	}
+
+	switch p.status {
+	case models.UploadedStatus:
+		return nil
+	case models.ProcessingStatus:
+		return change(models.ProcessingStatus)
+	case models.ReadyStatus:
+		return change(models.ProcessingStatus, models.ReadyStatus)
+	case models.FailedStatus:
+		return change(models.ProcessingStatus, models.FailedStatus)
+	case models.ArchivedStatus:
+		if err := change(models.ProcessingStatus, models.ReadyStatus); err != nil {
+			return err
+		}
+		location := strings.Replace(p.item.Source, "s3://media-uploads/", "s3://media-archive/", 1)
+		_, err := s.media.ArchiveMedia(ctx, id, location, meta)
+		return err
+	case models.QuarantinedStatus:
+		_, err := s.media.QuarantineMedia(ctx, id, service.Verdict{Threat: "Eicar-Test-Signature", Scanner: "clamav"}, meta)
+		return err
+	case models.DeletedStatus:
+		if err := change(models.ProcessingStatus, models.ReadyStatus); err != nil {
+			return err
+		}
+		return s.media.DeleteMedia(ctx, id, models.DeleteReasonDeleted)
+	default:
+		return fmt.Errorf("unknown status %q", p.status)
+	}
+}
+
+// size — правдоподобный размер исходника типа t
+func (s *Seeder) size(t models.MediaType) int64 {
+	switch t {
+	case models.Video:
+		return 20<<20 + s.rnd.Int64N(2<<30)
+	case models.Audio:
+		return 2<<20 + s.rnd.Int64N(150<<20)
+	default:
+		return 50<<10 + s.rnd.Int64N(30<<20)
+	}
+}
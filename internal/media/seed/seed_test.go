@@ -0,0 +1,88 @@
+package seed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+)
+
+// recordingOutbox считает события, положенные в outbox, по типам
+type recordingOutbox struct {
+	types map[string]int
+}
+
+func (o *recordingOutbox) Add(_ context.Context, event models.DomainEvent) error {
+	o.types[event.EventType()]++
+	return nil
+}
+
+func runSeed(t *testing.T, cfg Config) (Report, *repository.MemoryRepository, *recordingOutbox) {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+	outbox := &recordingOutbox{types: make(map[string]int)}
+	cfg.Media = service.New(repo, outbox).WithLogger(zerolog.Nop())
+	cfg.Attrs = repo
+	cfg.Logger = zerolog.Nop()
+	s, err := New(cfg)
+	require.NoError(t, err)
+	rep, err := s.Run(context.Background())
+	require.NoError(t, err)
+	return rep, repo, outbox
+}
+
+func TestSeeder_Run(t *testing.T) {
+	rep, repo, outbox := runSeed(t, Config{Count: 300, Owners: 3, Seed: 42})
+
+	require.Equal(t, 300, rep.Created)
+	require.Len(t, rep.Owners, 3)
+	for _, st := range statuses {
+		require.Positive(t, rep.ByStatus[st.value], st.value)
+	}
+	for _, typ := range types {
+		require.Positive(t, rep.ByType[typ.value], typ.value)
+	}
+	require.Equal(t, 300, outbox.types["MediaCreated"])
+	require.Positive(t, outbox.types["MediaStatusChanged"])
+
+	// Итоговый статус и атрибуты — как в плане
+	ready, err := repo.List(context.Background(), repository.ListFilter{Status: models.ReadyStatus, Limit: 500})
+	require.NoError(t, err)
+	require.Len(t, ready, rep.ByStatus[models.ReadyStatus])
+	m := ready[0]
+	require.NotEmpty(t, m.Title)
+	require.NotEmpty(t, m.Tags)
+	require.NotEmpty(t, m.Metadata["language"])
+	require.Len(t, m.Checksum, 64)
+	require.Contains(t, rep.Owners, m.OwnerID)
+}
+
+func TestSeeder_Deterministic(t *testing.T) {
+	a, _, _ := runSeed(t, Config{Count: 20, Seed: 7})
+	b, _, _ := runSeed(t, Config{Count: 20, Seed: 7})
+	require.Equal(t, a, b)
+}
+
+func TestNew_Validation(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := service.New(repo, nil)
+	for name, cfg := range map[string]Config{
+		"no media":       {Attrs: repo},
+		"no attrs":       {Media: svc},
+		"negative count": {Media: svc, Attrs: repo, Count: -1},
+		"negative owner": {Media: svc, Attrs: repo, Owners: -1},
+	} {
+		_, err := New(cfg)
+		require.Error(t, err, name)
+	}
+
+	s, err := New(Config{Media: svc, Attrs: repo})
+	require.NoError(t, err)
+	require.Equal(t, 100, s.count)
+	require.Equal(t, 5, s.owners)
+}
@@ -0,0 +1,17 @@
+package service
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor кладёт в контекст инициатора операции: имя сервиса или пользователя из auth.
+// Транспорт вызывает его один раз на запрос, сервис читает через ActorFromContext.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает инициатора, положенного WithActor, или "" если он неизвестен
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// ArchiveMedia переводит медиа в archived после того, как исходник перенесён в холодное хранилище.
+// location — новое расположение исходника; пустое или равное Source — объект остался на месте
+// (например, сменил класс хранения). Новый source, переход статуса, запись в историю,
+// MediaStatusChanged и MediaArchived пишутся одной транзакцией. Повторный вызов для уже
+// архивированного медиа ничего не меняет.
+func (s *Service) ArchiveMedia(ctx context.Context, id uuid.UUID, location string, meta ChangeMeta) (*models.Media, error) {
+	return s.archiveMedia(ctx, id, location, meta, nil)
+}
+
+// archiveMedia — ArchiveMedia с проверкой check медиа, прочитанного в транзакции
+// (nil — без проверки): ошибка check отменяет архивацию
+func (s *Service) archiveMedia(ctx context.Context, id uuid.UUID, location string, meta ChangeMeta, check func(*models.Media) error) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if meta.Actor == "" {
+		meta.Actor = ActorFromContext(ctx)
+	}
+
+	var (
+		before   *models.Media
+		archived *models.Media
+	)
+	err := s.withinTransaction(ctx, "archive media", func(ctx context.Context) error {
+		read := func(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+			return s.repo.GetByID(repository.WithReadPrimary(ctx), id)
+		}
+		if check != nil {
+			// Проверенное check не должно измениться до перехода
+			read = s.repo.GetForUpdate
+		}
+		m, err := read(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if m.Status == models.ArchivedStatus {
+			archived = m
+			return nil
+		}
+		if check != nil {
+			if err := check(m); err != nil {
+				return err
+			}
+		}
+
+		from, err := toDomainStatus(m.Status)
+		if err != nil {
+			return err
+		}
+		if err := s.states.ValidateTransition(from, domain.Archived); err != nil {
+			return err
+		}
+
+		if location != "" && location != m.Source {
+			if _, err := s.repo.Update(ctx, id, models.MediaPatch{Source: &location}); err != nil {
+				return err
+			}
+		}
+		archived, err = s.transition(ctx, m.Status, id, models.ArchivedStatus, meta)
+		if err != nil {
+			return err
+		}
+		before = m
+		return s.addEvent(ctx, models.NewMediaArchived(m, archived.Source, s.clock()))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if before != nil {
+		s.log(ctx, id).Info().
+			Str("from", string(before.Status)).
+			Str("location", archived.Source).
+			Str("actor", meta.Actor).
+			Msg("media archived")
+	}
+	return archived, nil
+}
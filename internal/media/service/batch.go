@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// MaxBatchSize — максимальное количество элементов в одном CreateMediaBatch и ChangeStatusBatch
+const MaxBatchSize = 500
+
+// BatchItem — один элемент пакетного создания.
+// ID опционален: клиент может задать его сам, чтобы безопасно повторять частично успешный batch.
+type BatchItem struct {
+	ID     uuid.UUID
+	Type   models.MediaType
+	Source string
+}
+
+type BatchItemStatus string
+
+const (
+	BatchItemCreated  BatchItemStatus = "created"
+	BatchItemConflict BatchItemStatus = "conflict"
+	BatchItemInvalid  BatchItemStatus = "invalid"
+)
+
+// BatchItemResult — результат по элементу; Index совпадает с позицией во входном срезе
+type BatchItemResult struct {
+	Index  int
+	Status BatchItemStatus
+	Media  *models.Media
+}
+
+// CreateMediaBatch создаёт пачку медиа в одной транзакции, добавляя в outbox
+// по событию MediaCreated на каждый созданный элемент.
+// Невалидные элементы и конфликты по ID не прерывают batch — они попадают в результаты,
+// чтобы вызывающая сторона могла повторить только нужные. Любая другая ошибка
+// откатывает транзакцию целиком.
+func (s *Service) CreateMediaBatch(ctx context.Context, items []BatchItem) ([]BatchItemResult, error) {
+	if len(items) == 0 || len(items) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch size must be between 1 and %d", models.ErrInvalidArgument, MaxBatchSize)
+	}
+	if anonymous(ctx) {
+		return nil, errAnonymous
+	}
+
+	results := make([]BatchItemResult, len(items))
+	valid := make([]*models.Media, len(items))
+	now := s.clock()
+	owner := newOwner(ctx)
+	pending := 0
+
+	for i, it := range items {
+		results[i] = BatchItemResult{Index: i, Status: BatchItemInvalid}
+		if it.Type == "" || it.Source == "" {
+			continue
+		}
+
+		id := it.ID
+		if id == uuid.Nil {
+			id = s.idGen()
+		}
+		valid[i] = &models.Media{
+			ID:         id,
+			Status:     models.UploadedStatus,
+			Type:       it.Type,
+			Source:     it.Source,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			OwnerID:    owner,
+			Visibility: models.DraftVisibility,
+		}
+		pending++
+	}
+
+	// Нечего вставлять — транзакция не нужна
+	if pending == 0 {
+		return results, nil
+	}
+
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i, m := range valid {
+			if m == nil {
+				continue
+			}
+
+			err := s.repo.Create(ctx, m)
+			if errors.Is(err, models.ErrConflict) {
+				results[i].Status = BatchItemConflict
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("create item %d: %w", i, err)
+			}
+
+			if err := s.addEvent(ctx, models.NewMediaCreated(m)); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+
+			results[i].Status = BatchItemCreated
+			results[i].Media = m
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[BatchItemStatus]int, 3)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	s.ctxLogger(ctx).Info().
+		Int("created", counts[BatchItemCreated]).
+		Int("conflicts", counts[BatchItemConflict]).
+		Int("invalid", counts[BatchItemInvalid]).
+		Msg("media batch created")
+	return results, nil
+}
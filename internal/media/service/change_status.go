@@ -1 +1,143 @@
 package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// ChangeMeta — кто и почему меняет статус
+type ChangeMeta struct {
+	Actor  string // инициатор; пустой — берётся из контекста (ActorFromContext)
+	Reason string // свободный текст; для failed — почему упала обработка
+	// IfMatch — менять, только если версия медиа (models.Media.Version) одна из перечисленных;
+	// пустой — без проверки. Иначе models.ErrPreconditionFailed.
+	IfMatch []int64
+}
+
+// ChangeStatus переводит медиа в статус to. Переход, запись в историю статусов
+// и событие в outbox пишутся одной транзакцией; actor и reason попадают и в историю, и в событие.
+func (s *Service) ChangeStatus(ctx context.Context, id uuid.UUID, to models.Status, meta ChangeMeta) (*models.Media, error) {
+	if meta.Actor == "" {
+		meta.Actor = ActorFromContext(ctx)
+	}
+	// archived и quarantined означают, что с исходником уже что-то сделано:
+	// их ставят только ArchiveMedia и QuarantineMedia вместе со своими событиями,
+	// deleted — только DeleteMedia: с MediaDeleted и удалением строки
+	switch to {
+	case models.DeletedStatus:
+		return nil, fmt.Errorf("%w: status %q is set by deletion, use DeleteMedia", models.ErrInvalidArgument, to)
+	case models.ArchivedStatus:
+		return nil, fmt.Errorf("%w: status %q is set by retention, use ArchiveMedia", models.ErrInvalidArgument, to)
+	case models.QuarantinedStatus:
+		return nil, fmt.Errorf("%w: status %q is set by malware scan, use QuarantineMedia", models.ErrInvalidArgument, to)
+	}
+	if err := scheduledOnly(to); err != nil {
+		return nil, err
+	}
+
+	// Конфликт сериализации или deadlock повторяет переход с блокирующего чтения
+	var updated *models.Media
+	err := s.retryTransient(ctx, "change status", func() (err error) {
+		updated, err = s.changeStatus(ctx, id, to, meta)
+		return err
+	})
+	return updated, err
+}
+
+func (s *Service) changeStatus(ctx context.Context, id uuid.UUID, to models.Status, meta ChangeMeta) (*models.Media, error) {
+	// Чтение, проверки и переход — в одной транзакции по заблокированной строке:
+	// параллельный переход ждёт блокировку и валидируется уже по новому статусу
+	var (
+		from    models.Status
+		updated *models.Media
+	)
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		m, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if len(meta.IfMatch) > 0 && !slices.Contains(meta.IfMatch, m.Version()) {
+			return fmt.Errorf("%w: media %s is at another version", models.ErrPreconditionFailed, id)
+		}
+		// ready до publish_at — это scheduled: медиа опубликует планировщик
+		to = s.publishStatus(m, to)
+
+		fromDom, err := toDomainStatus(m.Status)
+		if err != nil {
+			return err
+		}
+		toDom, err := toDomainStatus(to)
+		if err != nil {
+			return err
+		}
+		if err := s.states.ValidateTransition(fromDom, toDom); err != nil {
+			return err
+		}
+
+		from = m.Status
+		// Если статус уже такой — ничего не делаем
+		if m.Status == to {
+			updated = m
+			return nil
+		}
+		updated, err = s.transition(ctx, m.Status, id, to, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if from != to {
+		s.log(ctx, id).Info().
+			Str("from", string(from)).
+			Str("to", string(to)).
+			Str("actor", meta.Actor).
+			Msg("media status changed")
+	}
+	return updated, nil
+}
+
+// transition меняет статус, пишет запись в историю и событие в outbox.
+// Вызывается внутри WithinTransaction; валидация перехода — на вызывающем.
+func (s *Service) transition(ctx context.Context, from models.Status, id uuid.UUID, to models.Status, meta ChangeMeta) (*models.Media, error) {
+	updated, event, err := s.applyTransition(ctx, from, id, to, meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// applyTransition — transition без записи в outbox: событие возвращается вызывающему
+// (ChangeStatusBatch пишет события пачки одним вызовом)
+func (s *Service) applyTransition(ctx context.Context, from models.Status, id uuid.UUID, to models.Status, meta ChangeMeta) (*models.Media, models.DomainEvent, error) {
+	updated, err := s.repo.UpdateStatus(ctx, id, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.AddStatusChange(ctx, &models.StatusChange{
+		MediaID:   id,
+		From:      from,
+		To:        to,
+		Actor:     meta.Actor,
+		Reason:    meta.Reason,
+		ChangedAt: s.clock(),
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	event := models.NewMediaStatusChanged(id, updated.OwnerID, from, to, meta.Actor, meta.Reason).
+		WithVisibility(updated.EffectiveVisibility())
+	return updated, event, nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// WithCollections включает коллекции. Репозиторий должен работать в транзакциях repo
+// (Postgres — та же база): состав коллекции, медиа и outbox меняются вместе.
+func (s *Service) WithCollections(repo repository.CollectionRepository) *Service {
+	s.collections = repo
+	return s
+}
+
+// errNoCollections — коллекции не включены (WithCollections)
+var errNoCollections = fmt.Errorf("%w: collections are not configured", models.ErrNotFound)
+
+// authorizeCollection — коллекции видны только владельцу (или админу), чужая неотличима от несуществующей
+func authorizeCollection(ctx context.Context, c *models.Collection) error {
+	if owner, restricted := ownerScope(ctx); restricted && (owner == uuid.Nil || c.OwnerID != owner) {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// CreateCollection создаёт пустую коллекцию вызывающего
+func (s *Service) CreateCollection(ctx context.Context, title, description string) (*models.Collection, error) {
+	if s.collections == nil {
+		return nil, errNoCollections
+	}
+	if anonymous(ctx) {
+		return nil, errAnonymous
+	}
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("%w: title is required", models.ErrInvalidArgument)
+	}
+
+	now := s.clock()
+	c := &models.Collection{
+		ID:          s.idGen(),
+		OwnerID:     newOwner(ctx),
+		Title:       title,
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.collections.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	s.ctxLogger(ctx).Info().Str("collection_id", c.ID.String()).Msg("collection created")
+	return c, nil
+}
+
+// GetCollection возвращает коллекцию и её элементы по порядку
+func (s *Service) GetCollection(ctx context.Context, id uuid.UUID) (*models.Collection, []models.CollectionItem, error) {
+	if s.collections == nil {
+		return nil, nil, errNoCollections
+	}
+	c, err := s.collections.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := authorizeCollection(ctx, c); err != nil {
+		return nil, nil, err
+	}
+	items, err := s.collections.Items(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, items, nil
+}
+
+// ListCollections — страница коллекций, новые первыми. Без scope admin — только свои.
+func (s *Service) ListCollections(ctx context.Context, filter repository.CollectionFilter) ([]*models.Collection, error) {
+	if s.collections == nil {
+		return nil, errNoCollections
+	}
+	if owner, restricted := ownerScope(ctx); restricted {
+		if owner == uuid.Nil {
+			return nil, errAnonymous
+		}
+		filter.OwnerID = owner
+	}
+	return s.collections.List(ctx, filter)
+}
+
+// UpdateCollection меняет название и описание коллекции
+func (s *Service) UpdateCollection(ctx context.Context, id uuid.UUID, patch models.CollectionPatch) (*models.Collection, error) {
+	if s.collections == nil {
+		return nil, errNoCollections
+	}
+	if patch.Title != nil && strings.TrimSpace(*patch.Title) == "" {
+		return nil, fmt.Errorf("%w: title is required", models.ErrInvalidArgument)
+	}
+
+	var updated *models.Collection
+	err := s.withinTransaction(ctx, "update collection", func(ctx context.Context) error {
+		c, err := s.collections.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorizeCollection(ctx, c); err != nil {
+			return err
+		}
+		updated, err = s.collections.Update(ctx, id, patch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteCollection удаляет коллекцию; медиа в ней не трогает. Удаление и событие
+// CollectionDeleted со списком медиа пишутся одной транзакцией.
+func (s *Service) DeleteCollection(ctx context.Context, id uuid.UUID) error {
+	if s.collections == nil {
+		return errNoCollections
+	}
+	actor := ActorFromContext(ctx)
+
+	var mediaIDs []uuid.UUID
+	err := s.withinTransaction(ctx, "delete collection", func(ctx context.Context) error {
+		c, err := s.collections.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorizeCollection(ctx, c); err != nil {
+			return err
+		}
+		items, err := s.collections.Items(ctx, id)
+		if err != nil {
+			return err
+		}
+		mediaIDs = make([]uuid.UUID, len(items))
+		for i, it := range items {
+			mediaIDs[i] = it.MediaID
+		}
+		if _, err := s.collections.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewCollectionDeleted(c, mediaIDs, actor, s.clock()))
+	})
+	if err != nil {
+		return err
+	}
+	s.ctxLogger(ctx).Info().
+		Str("collection_id", id.String()).
+		Int("items", len(mediaIDs)).
+		Str("actor", actor).
+		Msg("collection deleted")
+	return nil
+}
+
+// AddCollectionItem добавляет медиа в коллекцию на место position (с нуля; nil — в конец).
+// Добавить можно медиа, которое видно вызывающему: своё, чужое unlisted/public или открытое ему.
+// Медиа уже в коллекции — models.ErrConflict; элемент и событие CollectionItemAdded
+// пишутся одной транзакцией.
+func (s *Service) AddCollectionItem(ctx context.Context, id, mediaID uuid.UUID, position *int) (models.CollectionItem, error) {
+	if s.collections == nil {
+		return models.CollectionItem{}, errNoCollections
+	}
+	if mediaID == uuid.Nil {
+		return models.CollectionItem{}, models.ErrInvalidArgument
+	}
+	actor := ActorFromContext(ctx)
+
+	var item models.CollectionItem
+	err := s.withinTransaction(ctx, "add collection item", func(ctx context.Context) error {
+		c, err := s.collections.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorizeCollection(ctx, c); err != nil {
+			return err
+		}
+		m, err := s.repo.GetByID(ctx, mediaID)
+		if err != nil {
+			return err
+		}
+		if err := s.authorizeAccess(ctx, m, models.ReadPermission); err != nil {
+			return err
+		}
+		if c.ItemCount >= models.MaxCollectionItems {
+			return fmt.Errorf("%w: collection already holds %d media", models.ErrConflict, models.MaxCollectionItems)
+		}
+
+		item = models.CollectionItem{CollectionID: id, MediaID: mediaID, Position: c.ItemCount, AddedAt: s.clock()}
+		if position != nil {
+			item.Position = *position
+		}
+		if err := s.collections.AddItem(ctx, item); err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewCollectionItemAdded(c, item, actor))
+	})
+	if err != nil {
+		return models.CollectionItem{}, err
+	}
+	s.log(ctx, mediaID).Info().
+		Str("collection_id", id.String()).
+		Int("position", item.Position).
+		Str("actor", actor).
+		Msg("media added to collection")
+	return item, nil
+}
+
+// RemoveCollectionItem убирает медиа из коллекции; медиа после него сдвигаются на место вперёд.
+// Медиа нет в коллекции — models.ErrNotFound.
+func (s *Service) RemoveCollectionItem(ctx context.Context, id, mediaID uuid.UUID) error {
+	if s.collections == nil {
+		return errNoCollections
+	}
+	actor := ActorFromContext(ctx)
+
+	err := s.withinTransaction(ctx, "remove collection item", func(ctx context.Context) error {
+		c, err := s.collections.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorizeCollection(ctx, c); err != nil {
+			return err
+		}
+		item, err := s.collections.RemoveItem(ctx, id, mediaID)
+		if err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewCollectionItemRemoved(c, item, actor, s.clock()))
+	})
+	if err != nil {
+		return err
+	}
+	s.log(ctx, mediaID).Info().
+		Str("collection_id", id.String()).
+		Str("actor", actor).
+		Msg("media removed from collection")
+	return nil
+}
+
+// removeFromCollections убирает удаляемое медиа из всех коллекций внутри транзакции удаления
+// и публикует CollectionItemRemoved по каждой
+func (s *Service) removeFromCollections(ctx context.Context, mediaID uuid.UUID) error {
+	if s.collections == nil {
+		return nil
+	}
+	removed, err := s.collections.RemoveMedia(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	actor, now := ActorFromContext(ctx), s.clock()
+	for _, item := range removed {
+		c, err := s.collections.GetByID(ctx, item.CollectionID)
+		if err != nil {
+			return err
+		}
+		if err := s.addEvent(ctx, models.NewCollectionItemRemoved(c, item, actor, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
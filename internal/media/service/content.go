@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// RecordContent сохраняет checksum, размер и MIME тип исходника, проверенные ingest'ом.
+// Исходник можно (пере)загрузить, пока медиа не взято в обработку: в uploaded или failed.
+func (s *Service) RecordContent(ctx context.Context, id uuid.UUID, c models.Content) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if sum, err := hex.DecodeString(c.Checksum); err != nil || len(sum) != 32 {
+		return nil, fmt.Errorf("%w: checksum must be a hex sha256", models.ErrInvalidArgument)
+	}
+	if c.Size < 0 {
+		return nil, fmt.Errorf("%w: size cannot be negative", models.ErrInvalidArgument)
+	}
+	if c.ContentType == "" {
+		return nil, fmt.Errorf("%w: content type is required", models.ErrInvalidArgument)
+	}
+
+	var updated *models.Media
+	err := s.withinTransaction(ctx, "record content", func(ctx context.Context) error {
+		m, err := s.repo.GetByID(repository.WithReadPrimary(ctx), id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if m.Status != models.UploadedStatus && m.Status != models.FailedStatus {
+			return fmt.Errorf("%w: content of %s media cannot change", models.ErrConflict, m.Status)
+		}
+		patch := models.MediaPatch{Content: &c}
+		if c.Source != "" {
+			if err := s.checkSharedSource(ctx, m, c); err != nil {
+				return err
+			}
+			patch.Source = &c.Source
+		}
+		if updated, err = s.repo.Update(ctx, id, patch); err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewMediaContentRecorded(m, c, s.clock()))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx, id).Info().
+		Str("checksum", c.Checksum).
+		Int64("size", c.Size).
+		Str("content_type", c.ContentType).
+		Msg("media content recorded")
+	return updated, nil
+}
+
+// maxDuplicates — сколько медиа с тем же исходником возвращает Duplicates
+const maxDuplicates = 20
+
+// Duplicates возвращает другие медиа владельца медиа id, исходник которых имеет sha256 checksum,
+// новые первыми: по ним ingest дедуплицирует загрузку, не сохраняя содержимое повторно
+func (s *Service) Duplicates(ctx context.Context, id uuid.UUID, checksum string) ([]*models.Media, error) {
+	if sum, err := hex.DecodeString(checksum); err != nil || len(sum) != 32 {
+		return nil, fmt.Errorf("%w: checksum must be a hex sha256", models.ErrInvalidArgument)
+	}
+	m, err := s.getOwned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.duplicates(ctx, m, checksum)
+}
+
+func (s *Service) duplicates(ctx context.Context, m *models.Media, checksum string) ([]*models.Media, error) {
+	list, err := s.repo.List(ctx, repository.ListFilter{OwnerID: m.OwnerID, Checksum: checksum, Limit: maxDuplicates})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*models.Media, 0, len(list))
+	for _, d := range list {
+		// uuid.Nil в фильтре — любой владелец, а медиа без владельца делят только между собой
+		if d.ID != m.ID && d.OwnerID == m.OwnerID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// checkSharedSource проверяет, что c.Source — исходник другого медиа того же владельца
+// с тем же содержимым: сослаться можно только на уже проверенный ingest'ом объект
+func (s *Service) checkSharedSource(ctx context.Context, m *models.Media, c models.Content) error {
+	list, err := s.duplicates(repository.WithReadPrimary(ctx), m, c.Checksum)
+	if err != nil {
+		return err
+	}
+	for _, d := range list {
+		if d.Source == c.Source && d.Size == c.Size && d.Status != models.QuarantinedStatus {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: source %q is not a stored copy of this content", models.ErrInvalidArgument, c.Source)
+}
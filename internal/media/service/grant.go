@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// WithGrants включает ACL медиа. Репозиторий должен работать в транзакциях repo
+// (Postgres — та же база): запись ACL и событие outbox пишутся вместе.
+func (s *Service) WithGrants(repo repository.GrantRepository) *Service {
+	s.grants = repo
+	return s
+}
+
+// errNoGrants — ACL не включены (WithGrants)
+var errNoGrants = fmt.Errorf("%w: grants are not configured", models.ErrNotFound)
+
+// ListGrants — ACL медиа в порядке выдачи; видит только владелец
+func (s *Service) ListGrants(ctx context.Context, id uuid.UUID) ([]models.Grant, error) {
+	if s.grants == nil {
+		return nil, errNoGrants
+	}
+	if _, err := s.getOwned(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.grants.List(ctx, id)
+}
+
+// GrantAccess открывает медиа principal с правом permission независимо от видимости или
+// меняет уже выданное право. Выдаёт только владелец (или админ); запись и событие
+// MediaAccessGranted пишутся одной транзакцией, повтор того же права событий не пишет.
+// Медиа в карантине открыть нельзя — models.ErrConflict.
+func (s *Service) GrantAccess(ctx context.Context, id, principal uuid.UUID, permission models.Permission) (models.Grant, error) {
+	if s.grants == nil {
+		return models.Grant{}, errNoGrants
+	}
+	if id == uuid.Nil || principal == uuid.Nil {
+		return models.Grant{}, models.ErrInvalidArgument
+	}
+	if !permission.Valid() {
+		return models.Grant{}, fmt.Errorf("%w: unknown permission %q", models.ErrInvalidArgument, permission)
+	}
+	actor := ActorFromContext(ctx)
+
+	var (
+		grant    models.Grant
+		previous models.Permission
+	)
+	err := s.withinTransaction(ctx, "grant access", func(ctx context.Context) error {
+		m, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if principal == m.OwnerID {
+			return fmt.Errorf("%w: owner already has access", models.ErrInvalidArgument)
+		}
+		if m.Status == models.QuarantinedStatus {
+			return fmt.Errorf("%w: quarantined media cannot be shared", models.ErrConflict)
+		}
+
+		current, err := s.grants.Get(ctx, id, principal)
+		switch {
+		case err == nil && current.Permission == permission:
+			grant, previous = current, current.Permission
+			return nil
+		case err == nil:
+			previous = current.Permission
+		case !errors.Is(err, models.ErrNotFound):
+			return err
+		}
+
+		now := s.clock()
+		grant, err = s.grants.Put(ctx, models.Grant{
+			MediaID:    id,
+			Principal:  principal,
+			Permission: permission,
+			GrantedBy:  actor,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewMediaAccessGranted(m, grant, previous, actor))
+	})
+	if err != nil {
+		return models.Grant{}, err
+	}
+
+	if previous != permission {
+		s.log(ctx, id).Info().
+			Str("principal", principal.String()).
+			Str("permission", string(permission)).
+			Str("previous", string(previous)).
+			Str("actor", actor).
+			Msg("media access granted")
+	}
+	return grant, nil
+}
+
+// RevokeAccess закрывает principal доступ к медиа; права нет — models.ErrNotFound.
+// Запись удаляется вместе с событием MediaAccessRevoked одной транзакцией.
+func (s *Service) RevokeAccess(ctx context.Context, id, principal uuid.UUID) error {
+	if s.grants == nil {
+		return errNoGrants
+	}
+	if id == uuid.Nil || principal == uuid.Nil {
+		return models.ErrInvalidArgument
+	}
+	actor := ActorFromContext(ctx)
+
+	err := s.withinTransaction(ctx, "revoke access", func(ctx context.Context) error {
+		m, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		g, err := s.grants.Revoke(ctx, id, principal)
+		if err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewMediaAccessRevoked(m, g, actor, s.clock()))
+	})
+	if err != nil {
+		return err
+	}
+	s.log(ctx, id).Info().
+		Str("principal", principal.String()).
+		Str("actor", actor).
+		Msg("media access revoked")
+	return nil
+}
+
+// ShareMedia проверяет, что вызывающий может выдать анонимную ссылку на медиа с правом
+// permission: только владелец (или админ) и не для медиа в карантине. Саму ссылку
+// подписывает транспорт (share.Links) — сервис её не хранит.
+func (s *Service) ShareMedia(ctx context.Context, id uuid.UUID, permission models.Permission) (*models.Media, error) {
+	if !permission.Valid() {
+		return nil, fmt.Errorf("%w: unknown permission %q", models.ErrInvalidArgument, permission)
+	}
+	m, err := s.getOwned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == models.QuarantinedStatus {
+		return nil, fmt.Errorf("%w: quarantined media cannot be shared", models.ErrConflict)
+	}
+	s.log(ctx, id).Info().
+		Str("permission", string(permission)).
+		Str("actor", ActorFromContext(ctx)).
+		Msg("share link issued")
+	return m, nil
+}
+
+// GetSharedMedia — медиа по анонимной ссылке, подпись которой транспорт уже проверил.
+// Медиа, попавшее в карантин после выдачи ссылки, по ней не отдаётся.
+func (s *Service) GetSharedMedia(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	m, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == models.QuarantinedStatus {
+		return nil, models.ErrNotFound
+	}
+	return m, nil
+}
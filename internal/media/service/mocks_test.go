@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
 )
 
 type StoreMock struct {
@@ -26,6 +27,14 @@ func (m *StoreMock) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, e
 	return nil, args.Error(1)
 }
 
+func (m *StoreMock) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	args := m.Called(ctx, id)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Media), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *StoreMock) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
 	args := m.Called(ctx, id, status)
 	if v := args.Get(0); v != nil {
@@ -33,3 +42,102 @@ func (m *StoreMock) UpdateStatus(ctx context.Context, id uuid.UUID, status model
 	}
 	return nil, args.Error(1)
 }
+
+// WithinTransaction фиксирует вызов и, если не задана ошибка, выполняет fn с тем же ctx.
+func (m *StoreMock) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+func (m *StoreMock) AddStatusChange(ctx context.Context, c *models.StatusChange) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *StoreMock) ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error) {
+	args := m.Called(ctx, mediaID)
+	if v := args.Get(0); v != nil {
+		return v.([]models.StatusChange), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *StoreMock) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	args := m.Called(ctx, id, lastError)
+	return args.Error(0)
+}
+
+func (m *StoreMock) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	args := m.Called(ctx, id, patch)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Media), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *StoreMock) List(ctx context.Context, filter repository.ListFilter) ([]*models.Media, error) {
+	args := m.Called(ctx, filter)
+	if v := args.Get(0); v != nil {
+		return v.([]*models.Media), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *StoreMock) Totals(ctx context.Context, filter repository.ListFilter, q repository.TotalsQuery) (repository.ListTotals, error) {
+	args := m.Called(ctx, filter, q)
+	return args.Get(0).(repository.ListTotals), args.Error(1)
+}
+
+func (m *StoreMock) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	args := m.Called(ctx, id)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Media), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *StoreMock) Search(ctx context.Context, q repository.SearchQuery) ([]repository.SearchHit, error) {
+	args := m.Called(ctx, q)
+	if v := args.Get(0); v != nil {
+		return v.([]repository.SearchHit), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// recordingOutbox запоминает типы событий, положенных в outbox
+type recordingOutbox struct {
+	events []models.DomainEvent
+}
+
+func (o *recordingOutbox) Add(ctx context.Context, event models.DomainEvent) error {
+	o.events = append(o.events, event)
+	return nil
+}
+
+// batchOutbox — recordingOutbox с AddBatch; calls считает вызовы AddBatch
+type batchOutbox struct {
+	recordingOutbox
+	calls int
+}
+
+func (o *batchOutbox) AddBatch(ctx context.Context, events []models.DomainEvent) error {
+	o.calls++
+	o.events = append(o.events, events...)
+	return nil
+}
+
+func (o *recordingOutbox) types() []string {
+	out := make([]string, len(o.events))
+	for i, ev := range o.events {
+		out[i] = ev.EventType()
+	}
+	return out
+}
+
+func (m *StoreMock) Dashboard(ctx context.Context, q repository.DashboardQuery) (repository.Dashboard, error) {
+	args := m.Called(ctx, q)
+	return args.Get(0).(repository.Dashboard), args.Error(1)
+}
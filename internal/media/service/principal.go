@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Principal — вызывающий: владелец, от имени которого идёт запрос, и признак админского
+// (или внутреннего) scope, снимающего ограничение по владельцу. Principal без владельца
+// и scope — анонимный вызывающий: ему доступно только опубликованное медиа, открытое всем.
+type Principal struct {
+	OwnerID uuid.UUID
+	Admin   bool
+}
+
+type principalKey struct{}
+
+// WithPrincipal кладёт в контекст вызывающего. Транспорт вызывает его на каждый запрос.
+// Без principal (вызовы внутри процесса: фоновые задачи, CLI) сервис не ограничивает доступ по владельцу.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext возвращает вызывающего, положенного WithPrincipal
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// ownerScope — владелец, которым ограничен доступ вызывающего; restricted=false —
+// без ограничения (нет principal или админ). restricted с uuid.Nil — анонимный вызывающий.
+func ownerScope(ctx context.Context) (owner uuid.UUID, restricted bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || p.Admin {
+		return uuid.Nil, false
+	}
+	return p.OwnerID, true
+}
+
+// anonymous — вызывающий без владельца и без scope
+func anonymous(ctx context.Context) bool {
+	owner, restricted := ownerScope(ctx)
+	return restricted && owner == uuid.Nil
+}
+
+// errAnonymous — операция требует владельца или scope
+var errAnonymous = fmt.Errorf("%w: owner or scope is required", models.ErrForbidden)
+
+// authorize проверяет, что вызывающий владеет медиа. Чужое медиа неотличимо
+// от несуществующего (ErrNotFound), чтобы не раскрывать чужие id; анонимному не принадлежит
+// ничего, в том числе медиа без владельца.
+func authorize(ctx context.Context, m *models.Media) error {
+	if owner, restricted := ownerScope(ctx); restricted && (owner == uuid.Nil || m.OwnerID != owner) {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// authorizeAccess — authorize для чтения и скачивания: чужое медиа доступно, если владелец
+// открыл его всем (unlisted или public, см. models.Media.EffectiveVisibility) и оно опубликовано
+// либо выдал вызывающему право need (ACL). Право read там, где нужно download, —
+// models.ErrForbidden: медиа вызывающему видно, скрывать его незачем.
+func (s *Service) authorizeAccess(ctx context.Context, m *models.Media, need models.Permission) error {
+	if m.Visible() {
+		return nil
+	}
+	err := authorize(ctx, m)
+	if err == nil || s.grants == nil || anonymous(ctx) {
+		return err
+	}
+	owner, _ := ownerScope(ctx)
+	g, gerr := s.grants.Get(ctx, m.ID, owner)
+	switch {
+	case errors.Is(gerr, models.ErrNotFound):
+		return err
+	case gerr != nil:
+		return gerr
+	case !g.Permission.Allows(need):
+		return fmt.Errorf("%w: %s permission is required", models.ErrForbidden, need)
+	}
+	return nil
+}
+
+// newOwner — владелец создаваемого медиа: сам вызывающий, uuid.Nil без principal
+func newOwner(ctx context.Context) uuid.UUID {
+	p, _ := PrincipalFromContext(ctx)
+	return p.OwnerID
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// maxLastErrorLength — last_error хранится для оператора, стектрейсы целиком не нужны
+const maxLastErrorLength = 1024
+
+// ReportProcessingFailure фиксирует неудачную попытку обработки медиа.
+// Медиа переводится processing → failed с сохранением lastError; если политика повторов
+// разрешает ещё попытку, в той же транзакции выполняется переход failed → processing,
+// и processing сервис получит MediaStatusChanged(to=processing) как запрос на повтор.
+// Возвращает итоговое медиа и признак, был ли запрошен повтор.
+func (s *Service) ReportProcessingFailure(ctx context.Context, id uuid.UUID, lastError string, meta ChangeMeta) (*models.Media, bool, error) {
+	if id == uuid.Nil || lastError == "" {
+		return nil, false, models.ErrInvalidArgument
+	}
+	if len(lastError) > maxLastErrorLength {
+		lastError = lastError[:maxLastErrorLength]
+	}
+	if meta.Actor == "" {
+		meta.Actor = ActorFromContext(ctx)
+	}
+
+	// Счётчик попыток решает судьбу повтора — читаем его из строки, заблокированной в транзакции:
+	// два параллельных отчёта не решат повтор по одному и тому же значению
+	var (
+		attempts int
+		retry    bool
+		updated  *models.Media
+	)
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		m, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if m.Status != models.ProcessingStatus {
+			return fmt.Errorf("%w: failure reported for media in status %s", domain.ErrInvalidTransition, m.Status)
+		}
+
+		attempts = m.ProcessingAttempts
+		retry = s.retry.ShouldRetry(attempts)
+
+		failMeta := meta
+		if failMeta.Reason == "" {
+			failMeta.Reason = lastError
+		}
+		if !retry {
+			failMeta.Reason = fmt.Sprintf("max processing attempts (%d) exceeded: %s", attempts, failMeta.Reason)
+		}
+
+		if err := s.repo.SetLastError(ctx, id, lastError); err != nil {
+			return err
+		}
+
+		updated, err = s.transition(ctx, models.ProcessingStatus, id, models.FailedStatus, failMeta)
+		if err != nil || !retry {
+			return err
+		}
+
+		retryMeta := ChangeMeta{
+			Actor:  meta.Actor,
+			Reason: fmt.Sprintf("retry attempt %d", attempts+1),
+		}
+		updated, err = s.transition(ctx, models.FailedStatus, id, models.ProcessingStatus, retryMeta)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.log(ctx, id).Warn().
+		Int("attempts", attempts).
+		Bool("retry", retry).
+		Str("actor", meta.Actor).
+		Str("last_error", lastError).
+		Msg("media processing failed")
+	return updated, retry, nil
+}
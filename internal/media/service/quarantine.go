@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// Verdict — заключение антивируса об исходнике медиа
+type Verdict struct {
+	Threat  string // найденная сигнатура, например Eicar-Test-Signature
+	Scanner string // кто проверял: clamav, ...
+}
+
+// QuarantineMedia переводит медиа в quarantined: в исходнике найдена угроза. Переход статуса,
+// запись в историю, MediaStatusChanged и MediaQuarantined пишутся одной транзакцией.
+// Повторный вызов для медиа в карантине ничего не меняет.
+func (s *Service) QuarantineMedia(ctx context.Context, id uuid.UUID, v Verdict, meta ChangeMeta) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if v.Threat == "" {
+		return nil, fmt.Errorf("%w: threat is required", models.ErrInvalidArgument)
+	}
+	if meta.Actor == "" {
+		meta.Actor = ActorFromContext(ctx)
+	}
+	if meta.Reason == "" {
+		meta.Reason = v.Threat
+	}
+
+	var (
+		before      *models.Media
+		quarantined *models.Media
+	)
+	err := s.withinTransaction(ctx, "quarantine media", func(ctx context.Context) error {
+		m, err := s.repo.GetByID(repository.WithReadPrimary(ctx), id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if m.Status == models.QuarantinedStatus {
+			quarantined = m
+			return nil
+		}
+
+		from, err := toDomainStatus(m.Status)
+		if err != nil {
+			return err
+		}
+		if err := s.states.ValidateTransition(from, domain.Quarantined); err != nil {
+			return err
+		}
+
+		quarantined, err = s.transition(ctx, m.Status, id, models.QuarantinedStatus, meta)
+		if err != nil {
+			return err
+		}
+		before = m
+		return s.addEvent(ctx, models.NewMediaQuarantined(m, v.Threat, v.Scanner, s.clock()))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if before != nil {
+		s.log(ctx, id).Warn().
+			Str("from", string(before.Status)).
+			Str("threat", v.Threat).
+			Str("scanner", v.Scanner).
+			Str("actor", meta.Actor).
+			Msg("media quarantined")
+	}
+	return quarantined, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// TxRetryPolicy — повтор операций, упавших на временной ошибке хранилища
+// (конфликт сериализации, взаимоблокировка, обрыв соединения)
+type TxRetryPolicy struct {
+	MaxAttempts int           // попыток всего, включая первую (default: 3)
+	BaseDelay   time.Duration // задержка перед первым повтором, дальше удваивается (default: 20ms)
+	MaxDelay    time.Duration // потолок задержки (default: 500ms)
+	// Transient отличает временные ошибки хранилища; nil — без повторов
+	Transient func(error) bool
+}
+
+func (p TxRetryPolicy) withDefaults() TxRetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 20 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 500 * time.Millisecond
+	}
+	return p
+}
+
+// delay — задержка перед попыткой attempt+1: случайная в [d/2, d], d = BaseDelay * 2^(attempt-1)
+// не больше MaxDelay. Разброс не даёт столкнувшимся транзакциям повториться одновременно.
+func (p TxRetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	return d/2 + rand.N(d/2+1)
+}
+
+// WithTxRetry включает повтор изменений media при временных ошибках хранилища
+func (s *Service) WithTxRetry(p TxRetryPolicy) *Service {
+	s.txRetry = p.withDefaults()
+	return s
+}
+
+// retryTransient выполняет fn, повторяя её при временной ошибке хранилища. fn должна
+// выполняться целиком заново: перечитывать состояние и открывать свою транзакцию.
+// Исчерпав попытки, возвращает models.ErrUnavailable вместо исходной ошибки.
+func (s *Service) retryTransient(ctx context.Context, op string, fn func() error) error {
+	p := s.txRetry
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || p.Transient == nil || !p.Transient(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return fmt.Errorf("%s: %w after %d attempts: %v", op, models.ErrUnavailable, attempt, err)
+		}
+
+		delay := p.delay(attempt)
+		s.ctxLogger(ctx).Warn().Err(err).
+			Str("op", op).
+			Int("attempt", attempt).
+			Dur("retry_in", delay).
+			Msg("transient storage error, retrying")
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// withinTransaction — repo.WithinTransaction с повтором при временной ошибке.
+// fn должна сама читать всё, от чего зависит, внутри транзакции.
+func (s *Service) withinTransaction(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	return s.retryTransient(ctx, op, func() error {
+		return s.repo.WithinTransaction(ctx, fn)
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// SetSchedule заменяет расписание публикации медиа. Обработанное медиа сразу приводится
+// к расписанию: ready с publish_at в будущем уходит под эмбарго (scheduled), scheduled
+// без эмбарго публикуется (ready). Новое расписание, переход, история и события
+// MediaScheduled и MediaStatusChanged пишутся одной транзакцией.
+func (s *Service) SetSchedule(ctx context.Context, id uuid.UUID, sched models.Schedule, meta ChangeMeta) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if sched.PublishAt != nil && sched.ExpiresAt != nil && !sched.ExpiresAt.After(*sched.PublishAt) {
+		return nil, fmt.Errorf("%w: expires_at must be after publish_at", models.ErrInvalidArgument)
+	}
+	if meta.Actor == "" {
+		meta.Actor = ActorFromContext(ctx)
+	}
+
+	var (
+		before  *models.Media
+		updated *models.Media
+	)
+	err := s.withinTransaction(ctx, "set schedule", func(ctx context.Context) error {
+		m, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		switch m.Status {
+		case models.DeletedStatus, models.ArchivedStatus, models.QuarantinedStatus:
+			return fmt.Errorf("%w: media in status %s cannot be scheduled", models.ErrConflict, m.Status)
+		}
+
+		updated, err = s.repo.Update(ctx, id, models.MediaPatch{Schedule: &sched})
+		if err != nil {
+			return err
+		}
+		if err := s.addEvent(ctx, models.NewMediaScheduled(updated, meta.Actor, s.clock())); err != nil {
+			return err
+		}
+
+		var to models.Status
+		switch now := s.clock(); {
+		case m.Status == models.ReadyStatus && updated.Embargoed(now):
+			to = models.ScheduledStatus
+		case m.Status == models.ScheduledStatus && !updated.Embargoed(now):
+			to = models.ReadyStatus
+		default:
+			return nil
+		}
+		if meta.Reason == "" {
+			meta.Reason = "publish_at changed"
+		}
+		before = m
+		updated, err = s.transition(ctx, m.Status, id, to, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log := s.log(ctx, id).Info().
+		Time("publish_at", timeOrZero(updated.PublishAt)).
+		Time("expires_at", timeOrZero(updated.ExpiresAt)).
+		Str("actor", meta.Actor)
+	if before != nil {
+		log = log.Str("from", string(before.Status)).Str("to", string(updated.Status))
+	}
+	log.Msg("media schedule set")
+	return updated, nil
+}
+
+// ExpireMedia архивирует медиа, у которого наступил expires_at; исходник остаётся на месте.
+// Если срок успели перенести или снять — models.ErrConflict, медиа не меняется.
+func (s *Service) ExpireMedia(ctx context.Context, id uuid.UUID, meta ChangeMeta) (*models.Media, error) {
+	now := s.clock()
+	return s.archiveMedia(ctx, id, "", meta, func(m *models.Media) error {
+		if m.ExpiresAt == nil || m.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: media %s has not expired", models.ErrConflict, id)
+		}
+		return nil
+	})
+}
+
+// publishStatus — статус, в который на самом деле переходит m при запросе перехода в to:
+// ready до publish_at становится scheduled
+func (s *Service) publishStatus(m *models.Media, to models.Status) models.Status {
+	if to == models.ReadyStatus && m.Embargoed(s.clock()) {
+		return models.ScheduledStatus
+	}
+	return to
+}
+
+// scheduledOnly — scheduled ставит только расписание: SetSchedule и переход в ready под эмбарго
+func scheduledOnly(to models.Status) error {
+	if to == models.ScheduledStatus {
+		return fmt.Errorf("%w: status %q is set by publish_at, use SetSchedule", models.ErrInvalidArgument, to)
+	}
+	return nil
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -6,59 +6,250 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
 	"github.com/romariotrain/media-platform/internal/media/domain"
-	"github.com/romariotrain/media-platform/internal/storage/postgres"
 
 	"github.com/romariotrain/media-platform/internal/media/models"
 	"github.com/romariotrain/media-platform/internal/media/repository"
 )
 
+// Outbox — запись доменных событий в outbox. Add вызывается внутри
+// repo.WithinTransaction и пишет в ту же транзакцию, что и изменение состояния.
+type Outbox interface {
+	Add(ctx context.Context, event models.DomainEvent) error
+}
+
+// BatchOutbox — Outbox, который пишет пачку событий одним запросом. Если outbox сервиса его
+// реализует, пакетные операции пишут события через AddBatch, иначе — по одному через Add.
+type BatchOutbox interface {
+	AddBatch(ctx context.Context, events []models.DomainEvent) error
+}
+
 type Service struct {
-	repo       repository.MediaRepository
+	repo repository.MediaRepository
+	// collections — коллекции (WithCollections); nil — выключены
+	collections repository.CollectionRepository
+	// grants — ACL медиа (WithGrants); nil — доступ только по видимости
+	grants     repository.GrantRepository
 	clock      func() time.Time
 	idGen      func() uuid.UUID
-	outboxRepo *postgres.OutboxRepo
+	outboxRepo Outbox
+	retry      domain.RetryPolicy
+	states     *domain.StateMachine
+	txRetry    TxRetryPolicy
+	logger     zerolog.Logger
 }
 
-func New(repo repository.MediaRepository, outboxRepo *postgres.OutboxRepo) *Service {
+// New создаёт сервис. outboxRepo может быть nil (in-memory режим) — тогда события не пишутся.
+func New(repo repository.MediaRepository, outboxRepo Outbox) *Service {
 	return &Service{
 		repo:       repo,
-		outboxRepo: outboxRepo, // добавь это
+		outboxRepo: outboxRepo,
 		clock:      time.Now,
 		idGen:      uuid.New,
+		states:     domain.DefaultStateMachine,
+		logger:     zerolog.Nop(),
 	}
 }
 
+// WithLogger задаёт логгер сервиса (по умолчанию логи не пишутся)
+func (s *Service) WithLogger(logger zerolog.Logger) *Service {
+	s.logger = logger.With().Str("component", "media_service").Logger()
+	return s
+}
+
+// ctxLogger возвращает request-scoped логгер из ctx (request_id от HTTP middleware),
+// если он есть, иначе логгер сервиса
+func (s *Service) ctxLogger(ctx context.Context) *zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return l
+	}
+	return &s.logger
+}
+
+// log — ctxLogger с полем media_id для операций над одним медиа
+func (s *Service) log(ctx context.Context, id uuid.UUID) *zerolog.Logger {
+	l := s.ctxLogger(ctx).With().Str("media_id", id.String()).Logger()
+	return &l
+}
+
+// WithRetryPolicy задаёт политику повторов обработки (по умолчанию domain.DefaultMaxProcessingAttempts)
+func (s *Service) WithRetryPolicy(p domain.RetryPolicy) *Service {
+	s.retry = p
+	return s
+}
+
+// WithStateMachine задаёт таблицу переходов статусов (по умолчанию domain.DefaultStateMachine):
+// по ней проверяют переходы ChangeStatus, ChangeStatusBatch, архивирование и карантин
+func (s *Service) WithStateMachine(m *domain.StateMachine) *Service {
+	s.states = m
+	return s
+}
+
 // GetMedia returns Media by id. It simply delegates to repository and passes through
 // domain errors (e.g. models.ErrNotFound) so the transport layer can map them to HTTP.
+// Чужое медиа видно, только если оно unlisted или public и опубликовано либо владелец
+// выдал вызывающему право (ACL).
 func (s *Service) GetMedia(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	return s.getAccessible(ctx, id, models.ReadPermission)
+}
+
+// GetMediaForDownload — GetMedia для выдачи ссылки на исходник: чужое медиа, открытое
+// только на чтение (ACL read), — models.ErrForbidden
+func (s *Service) GetMediaForDownload(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	return s.getAccessible(ctx, id, models.DownloadPermission)
+}
+
+func (s *Service) getAccessible(ctx context.Context, id uuid.UUID, need models.Permission) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	m, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeAccess(ctx, m, need); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// getOwned — GetMedia только для владельца: видимость медиа не даёт прав на его историю,
+// дубликаты и удаление
+func (s *Service) getOwned(ctx context.Context, id uuid.UUID) (*models.Media, error) {
 	if id == uuid.Nil {
 		return nil, models.ErrInvalidArgument
 	}
-	return s.repo.GetByID(ctx, id)
+	m, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SearchMedia ищет медиа по тексту в title/описании с фильтрами по меткам и статусу.
+// Вызывающий без админского scope видит только своё медиа.
+func (s *Service) SearchMedia(ctx context.Context, q repository.SearchQuery) ([]repository.SearchHit, error) {
+	if owner, restricted := ownerScope(ctx); restricted {
+		q.OwnerID = owner
+		if owner == uuid.Nil {
+			return nil, errAnonymous
+		}
+	}
+	return s.repo.Search(ctx, q)
+}
+
+// ListMedia возвращает страницу медиа по фильтру; вызывающий без админского scope видит
+// своё медиа, а с filter.OwnerID другого владельца — его опубликованное public медиа
+func (s *Service) ListMedia(ctx context.Context, filter repository.ListFilter) ([]*models.Media, error) {
+	filter, empty, err := listScope(ctx, filter)
+	switch {
+	case err != nil:
+		return nil, err
+	case empty:
+		return []*models.Media{}, nil
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// ListTotals — общее число и фасеты медиа под фильтром ListMedia с теми же правилами видимости
+func (s *Service) ListTotals(ctx context.Context, filter repository.ListFilter, q repository.TotalsQuery) (repository.ListTotals, error) {
+	filter, empty, err := listScope(ctx, filter)
+	if err != nil {
+		return repository.ListTotals{}, err
+	}
+	if empty {
+		var t repository.ListTotals
+		if q.Facets {
+			t.ByStatus, t.ByType = map[models.Status]int64{}, map[models.MediaType]int64{}
+		}
+		return t, nil
+	}
+	return s.repo.Totals(ctx, filter, q)
+}
+
+// listScope сужает фильтр списка до видимого вызывающему; empty — под фильтр заведомо ничего не попадёт.
+// Анонимный вызывающий видит только опубликованное public медиа.
+func listScope(ctx context.Context, filter repository.ListFilter) (_ repository.ListFilter, empty bool, _ error) {
+	owner, restricted := ownerScope(ctx)
+	if !restricted {
+		return filter, false, nil
+	}
+	if owner == uuid.Nil || (filter.OwnerID != uuid.Nil && filter.OwnerID != owner) {
+		if (filter.Status != "" && filter.Status != models.ReadyStatus) ||
+			(filter.Visibility != "" && filter.Visibility != models.PublicVisibility) {
+			return filter, true, nil
+		}
+		filter.Status, filter.Visibility = models.ReadyStatus, models.PublicVisibility
+		return filter, false, nil
+	}
+	filter.OwnerID = owner
+	return filter, false, nil
+}
+
+// Dashboard возвращает сводку по media владельца owner (uuid.Nil — всех): счётчики
+// и активность за последний window. Вызывающий без админского scope видит только своё медиа.
+func (s *Service) Dashboard(ctx context.Context, owner uuid.UUID, window time.Duration) (repository.Dashboard, error) {
+	if window <= 0 {
+		return repository.Dashboard{}, fmt.Errorf("%w: window must be positive", models.ErrInvalidArgument)
+	}
+	q := repository.DashboardQuery{OwnerID: owner, Since: s.clock().Add(-window)}
+	if owner, restricted := ownerScope(ctx); restricted {
+		q.OwnerID = owner
+		if owner == uuid.Nil {
+			return repository.Dashboard{}, errAnonymous
+		}
+	}
+	return s.repo.Dashboard(ctx, q)
 }
 
 // CreateMedia creates a new Media entity and persists it via repository.
 // Service owns invariants: id, initial status, timestamps, basic validation.
+// MediaCreated пишется в outbox в той же транзакции, что и медиа, — как в CreateMediaBatch.
 func (s *Service) CreateMedia(ctx context.Context, mediaType models.MediaType, source string) (*models.Media, error) {
+	return s.CreateMediaWithAttrs(ctx, mediaType, source, models.MediaAttrs{})
+}
+
+// CreateMediaWithAttrs — CreateMedia с описательными полями (title, tags, metadata),
+// которые сохраняются той же вставкой. Лимиты на них проверяет HTTP слой.
+func (s *Service) CreateMediaWithAttrs(ctx context.Context, mediaType models.MediaType, source string, attrs models.MediaAttrs) (*models.Media, error) {
 	if mediaType == "" || source == "" {
 		return nil, models.ErrInvalidArgument
 	}
+	if anonymous(ctx) {
+		return nil, errAnonymous
+	}
 
 	now := s.clock()
 
 	m := &models.Media{
-		ID:        s.idGen(),
-		Status:    models.UploadedStatus,
-		Type:      mediaType,
-		Source:    source,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:         s.idGen(),
+		Status:     models.UploadedStatus,
+		Type:       mediaType,
+		Source:     source,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		OwnerID:    newOwner(ctx),
+		Visibility: models.DraftVisibility,
+		Title:      attrs.Title,
+		Tags:       attrs.Tags,
+		Metadata:   attrs.Metadata,
 	}
 
-	if err := s.repo.Create(ctx, m); err != nil {
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, m); err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewMediaCreated(m))
+	})
+	if err != nil {
 		return nil, err
 	}
+	s.log(ctx, m.ID).Info().Str("type", string(m.Type)).Msg("media created")
 
 	return m, nil
 }
@@ -73,61 +264,108 @@ func toDomainStatus(s models.Status) (domain.Status, error) {
 		return domain.Ready, nil
 	case models.FailedStatus:
 		return domain.Failed, nil
+	case models.DeletedStatus:
+		return domain.Deleted, nil
+	case models.ArchivedStatus:
+		return domain.Archived, nil
+	case models.QuarantinedStatus:
+		return domain.Quarantined, nil
+	case models.ScheduledStatus:
+		return domain.Scheduled, nil
 	default:
-		return "", fmt.Errorf("unknown status: %s", s)
+		return "", fmt.Errorf("%w: unknown status %q", models.ErrInvalidArgument, s)
 	}
 }
 
-func (s *Service) ChangeStatus(ctx context.Context, id uuid.UUID, to models.Status) (*models.Media, error) {
-	// 1. Получаем текущую медиа (чтобы узнать старый статус)
-	m, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
+// addEvent кладёт событие в outbox в рамках транзакции из ctx.
+// Без outbox (in-memory режим) события не публикуются.
+func (s *Service) addEvent(ctx context.Context, event models.DomainEvent) error {
+	if s.outboxRepo == nil {
+		return nil
 	}
+	if err := s.outboxRepo.Add(ctx, event); err != nil {
+		return fmt.Errorf("add outbox: %w", err)
+	}
+	return nil
+}
 
-	// 2. Валидация перехода (твоя логика)
-	fromDom, err := toDomainStatus(m.Status)
-	if err != nil {
-		return nil, err
+// addEvents кладёт события пачки в outbox в рамках транзакции из ctx: через
+// BatchOutbox.AddBatch, если outbox его поддерживает
+func (s *Service) addEvents(ctx context.Context, events []models.DomainEvent) error {
+	if s.outboxRepo == nil || len(events) == 0 {
+		return nil
 	}
-	toDom, err := toDomainStatus(to)
-	if err != nil {
-		return nil, err
+	batch, ok := s.outboxRepo.(BatchOutbox)
+	if !ok {
+		for _, event := range events {
+			if err := s.addEvent(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	if err := domain.ValidateTransition(fromDom, toDom); err != nil {
-		return nil, err
+	if err := batch.AddBatch(ctx, events); err != nil {
+		return fmt.Errorf("add outbox: %w", err)
 	}
+	return nil
+}
 
-	// Если статус уже такой — ничего не делаем
-	if m.Status == to {
-		return m, nil
+// GetStatusHistory возвращает историю переходов статуса медиа.
+// История переживает удаление медиа, поэтому 404 отдаётся, только если нет ни медиа, ни истории.
+func (s *Service) GetStatusHistory(ctx context.Context, id uuid.UUID) ([]models.StatusChange, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
 	}
 
-	// 3. НАЧИНАЕМ ТРАНЗАКЦИЮ
-	tx, err := s.repo.BeginTx(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("begin tx: %w", err)
+	// Владельцу история видна, пока медиа существует: после удаления принадлежность не проверить
+	if _, restricted := ownerScope(ctx); restricted {
+		if _, err := s.getOwned(ctx, id); err != nil {
+			return nil, err
+		}
 	}
-	defer tx.Rollback() // откатится если не сделаем Commit
 
-	// 4. Обновляем статус (В ТРАНЗАКЦИИ)
-	updated, err := s.repo.UpdateStatusTx(ctx, tx, id, to)
+	history, err := s.repo.ListStatusChanges(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-
-	// 5. Создаём событие
-	event := models.NewMediaStatusChanged(id, m.Status, to)
-
-	// 6. Добавляем в outbox (В ТОЙ ЖЕ ТРАНЗАКЦИИ)
-	if err := s.outboxRepo.Add(ctx, tx, event); err != nil {
-		return nil, fmt.Errorf("add outbox: %w", err)
+	if len(history) == 0 {
+		if _, err := s.repo.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
 	}
+	return history, nil
+}
 
-	// 7. КОММИТИМ (атомарно!)
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit tx: %w", err)
+// DeleteMedia удаляет медиа и в той же транзакции кладёт в outbox MediaDeleted.
+// Событие гарантированно дойдёт до quota сервиса, поэтому usage не разъедется
+// с реальным количеством объектов. reason различает удаление и истечение срока.
+func (s *Service) DeleteMedia(ctx context.Context, id uuid.UUID, reason models.DeleteReason) error {
+	if id == uuid.Nil {
+		return models.ErrInvalidArgument
+	}
+	if reason != models.DeleteReasonDeleted && reason != models.DeleteReasonExpired {
+		return fmt.Errorf("%w: unknown delete reason %q", models.ErrInvalidArgument, reason)
 	}
 
-	return updated, nil
+	err := s.withinTransaction(ctx, "delete media", func(ctx context.Context) error {
+		if _, restricted := ownerScope(ctx); restricted {
+			if _, err := s.getOwned(repository.WithReadPrimary(ctx), id); err != nil {
+				return err
+			}
+		}
+		// До удаления строки media: иначе членство в коллекциях снимет каскад, без событий
+		if err := s.removeFromCollections(ctx, id); err != nil {
+			return err
+		}
+		deleted, err := s.repo.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewMediaDeleted(deleted, reason, s.clock()))
+	})
+	if err != nil {
+		return err
+	}
+	s.log(ctx, id).Info().Str("reason", string(reason)).Msg("media deleted")
+	return nil
 }
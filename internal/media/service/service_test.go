@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,13 +12,15 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/romariotrain/media-platform/internal/media/domain"
 	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
 )
 
 func TestGetMedia_InvalidID(t *testing.T) {
 	ctx := context.Background()
 	st := new(StoreMock)
-	svc := New(st)
+	svc := New(st, nil)
 
 	// Invalid input should be rejected before calling the repository.
 	got, err := svc.GetMedia(ctx, uuid.Nil)
@@ -27,7 +32,7 @@ func TestGetMedia_InvalidID(t *testing.T) {
 func TestGetMedia_Found(t *testing.T) {
 	ctx := context.Background()
 	st := new(StoreMock)
-	svc := New(st)
+	svc := New(st, nil)
 
 	id := uuid.New()
 	want := &models.Media{
@@ -59,7 +64,7 @@ func TestCreateMedia_InvalidArguments(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			st := new(StoreMock)
-			svc := New(st)
+			svc := New(st, nil)
 
 			// Invalid arguments should short-circuit without persisting anything.
 			got, err := svc.CreateMedia(ctx, tc.mediaType, tc.source)
@@ -73,7 +78,7 @@ func TestCreateMedia_InvalidArguments(t *testing.T) {
 func TestCreateMedia_SetsFieldsAndPersists(t *testing.T) {
 	ctx := context.Background()
 	st := new(StoreMock)
-	svc := New(st)
+	svc := New(st, nil)
 
 	fixedID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
 	fixedTime := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
@@ -81,6 +86,7 @@ func TestCreateMedia_SetsFieldsAndPersists(t *testing.T) {
 	svc.clock = func() time.Time { return fixedTime }
 
 	var persisted *models.Media
+	st.On("WithinTransaction", mock.Anything).Return(nil).Once()
 	st.On("Create", mock.Anything, mock.Anything).
 		Run(func(args mock.Arguments) {
 			persisted = args.Get(1).(*models.Media)
@@ -106,9 +112,10 @@ func TestCreateMedia_SetsFieldsAndPersists(t *testing.T) {
 func TestCreateMedia_RepoErrorPropagated(t *testing.T) {
 	ctx := context.Background()
 	st := new(StoreMock)
-	svc := New(st)
+	svc := New(st, nil)
 
 	// Service should pass through repository errors to the caller.
+	st.On("WithinTransaction", mock.Anything).Return(nil).Once()
 	st.On("Create", mock.Anything, mock.Anything).Return(models.ErrConflict).Once()
 
 	got, err := svc.CreateMedia(ctx, models.Video, "src")
@@ -116,3 +123,799 @@ func TestCreateMedia_RepoErrorPropagated(t *testing.T) {
 	require.Nil(t, got)
 	st.AssertExpectations(t)
 }
+
+func TestCreateMedia_AddsMediaCreatedToOutbox(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+
+	// Одиночное создание пишет то же событие, что и пачка: по нему считается usage квоты
+	require.Equal(t, []string{"MediaCreated"}, outbox.types())
+	require.Equal(t, m.ID, outbox.events[0].AggregateID())
+
+	// Медиа не создано — события нет
+	svc.idGen = func() uuid.UUID { return m.ID }
+	_, err = svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.ErrorIs(t, err, models.ErrConflict)
+	require.Len(t, outbox.events, 1)
+}
+
+func TestCreateMediaBatch_SizeLimits(t *testing.T) {
+	ctx := context.Background()
+	st := new(StoreMock)
+	svc := New(st, nil)
+
+	_, err := svc.CreateMediaBatch(ctx, nil)
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	_, err = svc.CreateMediaBatch(ctx, make([]BatchItem, MaxBatchSize+1))
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	st.AssertNotCalled(t, "WithinTransaction", mock.Anything)
+}
+
+func TestCreateMediaBatch_AllInvalidSkipsTransaction(t *testing.T) {
+	ctx := context.Background()
+	st := new(StoreMock)
+	svc := New(st, nil)
+
+	// Невалидные элементы возвращаются в результатах, транзакция не открывается.
+	got, err := svc.CreateMediaBatch(ctx, []BatchItem{
+		{Type: "", Source: "s3://b/k"},
+		{Type: models.Video, Source: ""},
+	})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	for i, res := range got {
+		require.Equal(t, i, res.Index)
+		require.Equal(t, BatchItemInvalid, res.Status)
+		require.Nil(t, res.Media)
+	}
+	st.AssertNotCalled(t, "WithinTransaction", mock.Anything)
+}
+
+func TestGetStatusHistory_ReturnsEntries(t *testing.T) {
+	ctx := context.Background()
+	st := new(StoreMock)
+	svc := New(st, nil)
+
+	id := uuid.New()
+	history := []models.StatusChange{
+		{MediaID: id, From: models.UploadedStatus, To: models.ProcessingStatus},
+		{MediaID: id, From: models.ProcessingStatus, To: models.FailedStatus, Reason: "codec not supported"},
+	}
+	st.On("ListStatusChanges", mock.Anything, id).Return(history, nil).Once()
+
+	got, err := svc.GetStatusHistory(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, history, got)
+	// История есть — наличие медиа не проверяем (она переживает удаление)
+	st.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestGetStatusHistory_UnknownMedia(t *testing.T) {
+	ctx := context.Background()
+	st := new(StoreMock)
+	svc := New(st, nil)
+
+	id := uuid.New()
+	st.On("ListStatusChanges", mock.Anything, id).Return([]models.StatusChange(nil), nil).Once()
+	st.On("GetByID", mock.Anything, id).Return(nil, models.ErrNotFound).Once()
+
+	_, err := svc.GetStatusHistory(ctx, id)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	st.AssertExpectations(t)
+}
+
+func TestReportProcessingFailure_RequiresProcessingStatus(t *testing.T) {
+	ctx := context.Background()
+	st := new(StoreMock)
+	svc := New(st, nil)
+
+	id := uuid.New()
+	st.On("WithinTransaction", mock.Anything).Return(nil).Once()
+	st.On("GetForUpdate", mock.Anything, id).Return(&models.Media{ID: id, Status: models.ReadyStatus}, nil).Once()
+
+	_, _, err := svc.ReportProcessingFailure(ctx, id, "decoder crashed", ChangeMeta{})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+	st.AssertNotCalled(t, "SetLastError", mock.Anything, mock.Anything, mock.Anything)
+	st.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReportProcessingFailure_InvalidArguments(t *testing.T) {
+	svc := New(new(StoreMock), nil)
+
+	_, _, err := svc.ReportProcessingFailure(context.Background(), uuid.New(), "", ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+}
+
+func TestChangeStatus_MemoryRepository(t *testing.T) {
+	ctx := WithActor(context.Background(), "transcoder")
+	repo := repository.NewMemoryRepository()
+	svc := New(repo, nil)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	got, err := svc.ChangeStatus(ctx, m.ID, models.FailedStatus, ChangeMeta{Reason: "codec not supported"})
+	require.NoError(t, err)
+	require.Equal(t, models.FailedStatus, got.Status)
+
+	history, err := svc.GetStatusHistory(ctx, m.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, "transcoder", history[1].Actor)
+	require.Equal(t, "codec not supported", history[1].Reason)
+
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, ChangeMeta{})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+}
+
+func TestChangeStatus_DeletedOnlyByDeleteMedia(t *testing.T) {
+	ctx := context.Background()
+	outbox := &recordingOutbox{}
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+
+	// Статус deleted без MediaDeleted и удаления строки оставил бы медиа в квоте и в CDN
+	_, err = svc.ChangeStatus(ctx, m.ID, models.DeletedStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	results, err := svc.ChangeStatusBatch(ctx, []StatusBatchItem{{ID: m.ID, Status: models.DeletedStatus}})
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, models.ErrInvalidArgument)
+
+	got, err := svc.GetMedia(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.UploadedStatus, got.Status)
+	require.Equal(t, []string{"MediaCreated"}, outbox.types())
+}
+
+func TestChangeStatus_UsesInjectedStateMachine(t *testing.T) {
+	ctx := context.Background()
+	// Таблица без перекодирования готового медиа, зато с uploaded → ready
+	states := domain.NewStateMachine(domain.Transitions{
+		domain.Uploaded:   {domain.Ready},
+		domain.Processing: {domain.Ready},
+		domain.Ready:      {},
+	})
+	svc := New(repository.NewMemoryRepository(), nil).WithStateMachine(states)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	other, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+
+	got, err := svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.ReadyStatus, got.Status)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+
+	results, err := svc.ChangeStatusBatch(ctx, []StatusBatchItem{
+		{ID: other.ID, Status: models.ProcessingStatus},
+		{ID: m.ID, Status: models.ProcessingStatus},
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, domain.ErrInvalidTransition)
+	require.ErrorIs(t, results[1].Err, domain.ErrInvalidTransition)
+}
+
+func TestChangeStatusBatch_MemoryRepository(t *testing.T) {
+	ctx := WithActor(context.Background(), "transcoder")
+	outbox := &batchOutbox{}
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	var ids []uuid.UUID
+	for range 4 {
+		m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+		require.NoError(t, err)
+		ids = append(ids, m.ID)
+	}
+	outbox.events = nil
+
+	results, err := svc.ChangeStatusBatch(ctx, []StatusBatchItem{
+		{ID: ids[0], Status: models.ProcessingStatus},
+		{ID: ids[1], Status: models.ReadyStatus},
+		{ID: uuid.New(), Status: models.ProcessingStatus},
+		{ID: ids[3], Status: models.UploadedStatus},
+		{ID: ids[0], Status: models.FailedStatus, Reason: "x"},
+		{ID: ids[2], Status: models.FailedStatus, Reason: "codec not supported"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+
+	require.Equal(t, StatusBatchChanged, results[0].Outcome)
+	require.Equal(t, models.ProcessingStatus, results[0].Media.Status)
+	require.ErrorIs(t, results[1].Err, domain.ErrInvalidTransition)
+	require.ErrorIs(t, results[2].Err, models.ErrNotFound)
+	require.Equal(t, StatusBatchUnchanged, results[3].Outcome)
+	require.ErrorIs(t, results[4].Err, models.ErrInvalidArgument) // id повторяется
+	require.Equal(t, StatusBatchChanged, results[5].Outcome)
+	for i, r := range results {
+		require.Equal(t, i, r.Index)
+	}
+
+	// Все события пачки — одним вызовом, в порядке элементов
+	require.Equal(t, 1, outbox.calls)
+	require.Len(t, outbox.events, 2)
+	require.Equal(t, ids[0], outbox.events[0].AggregateID())
+	require.Equal(t, ids[2], outbox.events[1].AggregateID())
+
+	history, err := svc.GetStatusHistory(ctx, ids[2])
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "transcoder", history[0].Actor)
+	require.Equal(t, "codec not supported", history[0].Reason)
+
+	_, err = svc.ChangeStatusBatch(ctx, nil)
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+}
+
+func TestChangeStatus_ValidatesLockedRow(t *testing.T) {
+	ctx := context.Background()
+	st := new(StoreMock)
+	svc := New(st, nil)
+
+	id := uuid.New()
+	// Клиент видел uploaded, но до блокировки медиа успели перевести в processing
+	seen := &models.Media{ID: id, Status: models.UploadedStatus, UpdatedAt: time.Unix(100, 0)}
+	locked := &models.Media{ID: id, Status: models.ProcessingStatus, UpdatedAt: time.Unix(101, 0)}
+	st.On("WithinTransaction", mock.Anything).Return(nil)
+	st.On("GetForUpdate", mock.Anything, id).Return(locked, nil)
+
+	_, err := svc.ChangeStatus(ctx, id, models.ProcessingStatus, ChangeMeta{IfMatch: []int64{seen.Version()}})
+	require.ErrorIs(t, err, models.ErrPreconditionFailed)
+
+	// processing → uploaded запрещён: проверяется статус заблокированной строки
+	_, err = svc.ChangeStatus(ctx, id, models.UploadedStatus, ChangeMeta{})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+
+	st.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	st.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChangeStatus_RetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	errDeadlock := errors.New("deadlock detected")
+	policy := TxRetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		Transient:   func(err error) bool { return errors.Is(err, errDeadlock) },
+	}
+
+	st := new(StoreMock)
+	svc := New(st, nil).WithTxRetry(policy)
+	id := uuid.New()
+	m := &models.Media{ID: id, Status: models.UploadedStatus}
+	st.On("WithinTransaction", mock.Anything).Return(nil)
+	st.On("GetForUpdate", mock.Anything, id).Return(m, nil)
+	st.On("UpdateStatus", mock.Anything, id, models.ProcessingStatus).Return(nil, errDeadlock).Twice()
+	st.On("UpdateStatus", mock.Anything, id, models.ProcessingStatus).Return(&models.Media{ID: id, Status: models.ProcessingStatus}, nil).Once()
+	st.On("AddStatusChange", mock.Anything, mock.Anything).Return(nil)
+
+	got, err := svc.ChangeStatus(ctx, id, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingStatus, got.Status)
+	// Каждая попытка заново блокирует и перечитывает медиа
+	st.AssertNumberOfCalls(t, "GetForUpdate", 3)
+
+	// Попытки кончились — ErrUnavailable, а не исходная ошибка драйвера
+	st = new(StoreMock)
+	svc = New(st, nil).WithTxRetry(policy)
+	st.On("WithinTransaction", mock.Anything).Return(errDeadlock)
+	_, err = svc.ChangeStatus(ctx, id, models.ProcessingStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrUnavailable)
+	st.AssertNumberOfCalls(t, "WithinTransaction", 3)
+
+	// Не временные ошибки не повторяются
+	st = new(StoreMock)
+	svc = New(st, nil).WithTxRetry(policy)
+	st.On("WithinTransaction", mock.Anything).Return(nil)
+	st.On("GetForUpdate", mock.Anything, id).Return(nil, models.ErrNotFound)
+	_, err = svc.ChangeStatus(ctx, id, models.ProcessingStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrNotFound)
+	st.AssertNumberOfCalls(t, "GetForUpdate", 1)
+}
+
+func TestTxRetryPolicy_Delay(t *testing.T) {
+	p := TxRetryPolicy{}.withDefaults()
+	for attempt, want := range map[int]time.Duration{1: 20 * time.Millisecond, 2: 40 * time.Millisecond, 10: 500 * time.Millisecond} {
+		d := p.delay(attempt)
+		require.GreaterOrEqual(t, d, want/2)
+		require.LessOrEqual(t, d, want)
+	}
+}
+
+func TestReportProcessingFailure_RetriesThenFails(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := New(repo, nil).WithRetryPolicy(domain.RetryPolicy{MaxAttempts: 2})
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+
+	got, retried, err := svc.ReportProcessingFailure(ctx, m.ID, "decoder crashed", ChangeMeta{})
+	require.NoError(t, err)
+	require.True(t, retried)
+	require.Equal(t, models.ProcessingStatus, got.Status)
+	require.Equal(t, 2, got.ProcessingAttempts)
+
+	got, retried, err = svc.ReportProcessingFailure(ctx, m.ID, "decoder crashed again", ChangeMeta{})
+	require.NoError(t, err)
+	require.False(t, retried)
+	require.Equal(t, models.FailedStatus, got.Status)
+	require.Equal(t, "decoder crashed again", got.LastError)
+}
+
+func TestOwnerScope_MemoryRepository(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := New(repo, nil)
+
+	alice := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+	bob := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+	admin := WithPrincipal(context.Background(), Principal{Admin: true})
+
+	m, err := svc.CreateMedia(alice, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+	owner, _ := PrincipalFromContext(alice)
+	require.Equal(t, owner.OwnerID, m.OwnerID)
+
+	// Чужое медиа неотличимо от несуществующего
+	_, err = svc.GetMedia(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = svc.ChangeStatus(bob, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = svc.GetStatusHistory(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	require.ErrorIs(t, svc.DeleteMedia(bob, m.ID, models.DeleteReasonDeleted), models.ErrNotFound)
+
+	hits, err := svc.SearchMedia(bob, repository.SearchQuery{})
+	require.NoError(t, err)
+	require.Empty(t, hits)
+
+	dash, err := svc.Dashboard(bob, uuid.Nil, time.Hour)
+	require.NoError(t, err)
+	require.Zero(t, dash.Total)
+	_, err = svc.Dashboard(WithPrincipal(context.Background(), Principal{}), uuid.Nil, time.Hour)
+	require.ErrorIs(t, err, models.ErrForbidden)
+
+	// Владелец и админ видят медиа
+	_, err = svc.GetMedia(alice, m.ID)
+	require.NoError(t, err)
+	hits, err = svc.SearchMedia(alice, repository.SearchQuery{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	dash, err = svc.Dashboard(alice, uuid.Nil, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), dash.Created)
+	got, err := svc.ChangeStatus(admin, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, owner.OwnerID, got.OwnerID)
+}
+
+func TestArchiveMedia_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+
+	// Из uploaded архивировать нельзя: исходник ещё не обработан
+	_, err = svc.ArchiveMedia(ctx, m.ID, "s3://cold/file.mp4", ChangeMeta{Actor: "retention"})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ArchivedStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, ChangeMeta{})
+	require.NoError(t, err)
+
+	got, err := svc.ArchiveMedia(ctx, m.ID, "s3://cold/file.mp4", ChangeMeta{Actor: "retention", Reason: "policy 1"})
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedStatus, got.Status)
+	require.Equal(t, "s3://cold/file.mp4", got.Source)
+
+	archived := outbox.events[len(outbox.events)-1].(*models.MediaArchived)
+	require.Equal(t, "s3://cold/file.mp4", archived.Location())
+	require.Equal(t, []string{"MediaCreated", "MediaStatusChanged", "MediaStatusChanged", "MediaStatusChanged", "MediaArchived"}, outbox.types())
+
+	// Повтор ничего не меняет и событий не добавляет
+	_, err = svc.ArchiveMedia(ctx, m.ID, "s3://cold/file.mp4", ChangeMeta{})
+	require.NoError(t, err)
+	require.Len(t, outbox.events, 5)
+
+	history, err := svc.GetStatusHistory(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, "retention", history[len(history)-1].Actor)
+}
+
+func TestRecordContent_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+
+	content := models.Content{Checksum: strings.Repeat("0f", 32), Size: 2048, ContentType: "video/mp4"}
+	got, err := svc.RecordContent(ctx, m.ID, content)
+	require.NoError(t, err)
+	require.Equal(t, content.Checksum, got.Checksum)
+	require.Equal(t, int64(2048), got.Size)
+	require.Equal(t, "video/mp4", got.ContentType)
+	recorded := outbox.events[len(outbox.events)-1].(*models.MediaContentRecorded)
+	require.Equal(t, int64(2048), recorded.Size())
+
+	for name, bad := range map[string]models.Content{
+		"short checksum": {Checksum: "abc", Size: 1, ContentType: "video/mp4"},
+		"negative size":  {Checksum: content.Checksum, Size: -1, ContentType: "video/mp4"},
+		"no type":        {Checksum: content.Checksum, Size: 1},
+	} {
+		_, err := svc.RecordContent(ctx, m.ID, bad)
+		require.ErrorIs(t, err, models.ErrInvalidArgument, name)
+	}
+
+	// После начала обработки исходник зафиксирован
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	_, err = svc.RecordContent(ctx, m.ID, content)
+	require.ErrorIs(t, err, models.ErrConflict)
+}
+
+func TestRecordContent_SharedSource(t *testing.T) {
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+	alice := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+	bob := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+
+	content := models.Content{Checksum: strings.Repeat("0f", 32), Size: 2048, ContentType: "video/mp4"}
+	original, err := svc.CreateMedia(alice, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.RecordContent(alice, original.ID, content)
+	require.NoError(t, err)
+	copied, err := svc.CreateMedia(alice, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+	foreign, err := svc.CreateMedia(bob, models.Video, "s3://bucket/c.mp4")
+	require.NoError(t, err)
+	_, err = svc.RecordContent(bob, foreign.ID, content)
+	require.NoError(t, err)
+
+	// Дубликаты ищутся только среди медиа владельца, само медиа не входит
+	dups, err := svc.Duplicates(alice, copied.ID, content.Checksum)
+	require.NoError(t, err)
+	require.Len(t, dups, 1)
+	require.Equal(t, original.ID, dups[0].ID)
+	dups, err = svc.Duplicates(alice, original.ID, content.Checksum)
+	require.NoError(t, err)
+	require.Empty(t, dups)
+	_, err = svc.Duplicates(alice, copied.ID, "abc")
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	// Сослаться можно только на исходник медиа с тем же содержимым
+	for name, source := range map[string]string{
+		"unknown object": "s3://bucket/other.mp4",
+		"foreign media":  "s3://bucket/c.mp4",
+	} {
+		bad := content
+		bad.Source = source
+		_, err := svc.RecordContent(alice, copied.ID, bad)
+		require.ErrorIs(t, err, models.ErrInvalidArgument, name)
+	}
+
+	shared := content
+	shared.Source = original.Source
+	got, err := svc.RecordContent(alice, copied.ID, shared)
+	require.NoError(t, err)
+	require.Equal(t, "s3://bucket/a.mp4", got.Source)
+	recorded, err := json.Marshal(outbox.events[len(outbox.events)-1])
+	require.NoError(t, err)
+	require.Contains(t, string(recorded), `"source":"s3://bucket/a.mp4"`)
+}
+
+func TestQuarantineMedia_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+
+	_, err = svc.ChangeStatus(ctx, m.ID, models.QuarantinedStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	_, err = svc.QuarantineMedia(ctx, m.ID, Verdict{}, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	got, err := svc.QuarantineMedia(ctx, m.ID, Verdict{Threat: "Eicar-Test-Signature", Scanner: "clamav"}, ChangeMeta{Actor: "ingest"})
+	require.NoError(t, err)
+	require.Equal(t, models.QuarantinedStatus, got.Status)
+	require.Equal(t, []string{"MediaCreated", "MediaStatusChanged", "MediaQuarantined"}, outbox.types())
+	require.Equal(t, "Eicar-Test-Signature", outbox.events[2].(*models.MediaQuarantined).Threat())
+
+	history, err := svc.GetStatusHistory(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Eicar-Test-Signature", history[len(history)-1].Reason)
+
+	// Повтор ничего не добавляет; из карантина в обработку нельзя
+	_, err = svc.QuarantineMedia(ctx, m.ID, Verdict{Threat: "Eicar-Test-Signature"}, ChangeMeta{})
+	require.NoError(t, err)
+	require.Len(t, outbox.events, 3)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+}
+
+func TestSetSchedule_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.clock = func() time.Time { return now }
+	publishAt, expiresAt := now.Add(time.Hour), now.Add(48*time.Hour)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+	_, err = svc.SetSchedule(ctx, m.ID, models.Schedule{PublishAt: &expiresAt, ExpiresAt: &publishAt}, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	// До обработки меняется только расписание
+	got, err := svc.SetSchedule(ctx, m.ID, models.Schedule{PublishAt: &publishAt, ExpiresAt: &expiresAt}, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.UploadedStatus, got.Status)
+	require.Equal(t, publishAt, *got.PublishAt)
+
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ScheduledStatus, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	// ready до publish_at — scheduled
+	got, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.ScheduledStatus, got.Status)
+	_, err = svc.ExpireMedia(ctx, m.ID, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrConflict)
+
+	// Снятое эмбарго публикует медиа сразу
+	got, err = svc.SetSchedule(ctx, m.ID, models.Schedule{ExpiresAt: &expiresAt}, ChangeMeta{Actor: "editor"})
+	require.NoError(t, err)
+	require.Equal(t, models.ReadyStatus, got.Status)
+	require.Nil(t, got.PublishAt)
+	require.Equal(t, []string{
+		"MediaCreated", "MediaScheduled", "MediaStatusChanged", "MediaStatusChanged", "MediaScheduled", "MediaStatusChanged",
+	}, outbox.types())
+
+	now = expiresAt
+	got, err = svc.ExpireMedia(ctx, m.ID, ChangeMeta{Actor: "scheduler"})
+	require.NoError(t, err)
+	require.Equal(t, models.ArchivedStatus, got.Status)
+	require.Equal(t, "s3://bucket/file.mp4", got.Source)
+	require.Equal(t, "MediaArchived", outbox.types()[len(outbox.events)-1])
+
+	_, err = svc.SetSchedule(ctx, m.ID, models.Schedule{}, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrConflict)
+}
+
+func TestSetVisibility_MemoryRepository(t *testing.T) {
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox)
+
+	aliceID := uuid.New()
+	alice := WithPrincipal(context.Background(), Principal{OwnerID: aliceID})
+	bob := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+
+	m, err := svc.CreateMedia(alice, models.Video, "s3://bucket/file.mp4")
+	require.NoError(t, err)
+	require.Equal(t, models.DraftVisibility, m.Visibility)
+
+	_, err = svc.SetVisibility(alice, m.ID, "hidden", ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	// Менять видимость может только владелец, даже у видимого ему медиа
+	_, err = svc.SetVisibility(bob, m.ID, models.PublicVisibility, ChangeMeta{})
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	got, err := svc.SetVisibility(alice, m.ID, models.PublicVisibility, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.PublicVisibility, got.Visibility)
+	// public действует только для опубликованного медиа
+	require.Equal(t, models.PrivateVisibility, got.EffectiveVisibility())
+	_, err = svc.GetMedia(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = svc.ChangeStatus(alice, m.ID, models.ProcessingStatus, ChangeMeta{})
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(alice, m.ID, models.ReadyStatus, ChangeMeta{})
+	require.NoError(t, err)
+	require.Equal(t, models.PublicVisibility, outbox.events[len(outbox.events)-1].(*models.MediaStatusChanged).Visibility())
+
+	// Опубликованное public медиа видно и в списке медиа владельца
+	_, err = svc.GetMedia(bob, m.ID)
+	require.NoError(t, err)
+	list, err := svc.ListMedia(bob, repository.ListFilter{OwnerID: aliceID})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	// но не история и не удаление
+	_, err = svc.GetStatusHistory(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	require.ErrorIs(t, svc.DeleteMedia(bob, m.ID, models.DeleteReasonDeleted), models.ErrNotFound)
+
+	// unlisted — по id, но не в списке
+	_, err = svc.SetVisibility(alice, m.ID, models.UnlistedVisibility, ChangeMeta{Actor: "alice"})
+	require.NoError(t, err)
+	_, err = svc.GetMedia(bob, m.ID)
+	require.NoError(t, err)
+	list, err = svc.ListMedia(bob, repository.ListFilter{OwnerID: aliceID})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	changed := outbox.events[len(outbox.events)-1].(*models.MediaVisibilityChanged)
+	require.Equal(t, models.PublicVisibility, changed.From())
+	require.Equal(t, models.UnlistedVisibility, changed.Effective())
+
+	// В черновик не возвращаются
+	_, err = svc.SetVisibility(alice, m.ID, models.DraftVisibility, ChangeMeta{})
+	require.ErrorIs(t, err, domain.ErrInvalidTransition)
+}
+
+func TestCollections_MemoryRepository(t *testing.T) {
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox).WithCollections(repository.NewMemoryCollectionRepository())
+
+	alice := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+	bob := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+
+	_, err := svc.CreateCollection(alice, " ", "")
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	c, err := svc.CreateCollection(alice, "Trip", "summer 2026")
+	require.NoError(t, err)
+
+	first, err := svc.CreateMedia(alice, models.Video, "s3://bucket/1.mp4")
+	require.NoError(t, err)
+	second, err := svc.CreateMedia(alice, models.Video, "s3://bucket/2.mp4")
+	require.NoError(t, err)
+	foreign, err := svc.CreateMedia(bob, models.Video, "s3://bucket/3.mp4")
+	require.NoError(t, err)
+
+	_, err = svc.AddCollectionItem(alice, c.ID, first.ID, nil)
+	require.NoError(t, err)
+	// В начало, перед first
+	item, err := svc.AddCollectionItem(alice, c.ID, second.ID, new(int))
+	require.NoError(t, err)
+	require.Equal(t, 0, item.Position)
+	_, err = svc.AddCollectionItem(alice, c.ID, first.ID, nil)
+	require.ErrorIs(t, err, models.ErrConflict)
+	// Чужое медиа, которое вызывающему не видно, не добавляется
+	_, err = svc.AddCollectionItem(alice, c.ID, foreign.ID, nil)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	// Чужая коллекция неотличима от несуществующей
+	_, err = svc.AddCollectionItem(bob, c.ID, foreign.ID, nil)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, _, err = svc.GetCollection(bob, c.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	got, items, err := svc.GetCollection(alice, c.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, got.ItemCount)
+	require.Equal(t, []uuid.UUID{second.ID, first.ID}, []uuid.UUID{items[0].MediaID, items[1].MediaID})
+
+	added := outbox.events[len(outbox.events)-1].(*models.CollectionItemAdded)
+	require.Equal(t, c.ID, added.AggregateID())
+	require.Equal(t, second.ID, added.MediaID())
+
+	// Удалённое медиа уходит из коллекции с событием
+	require.NoError(t, svc.DeleteMedia(alice, second.ID, models.DeleteReasonDeleted))
+	_, items, err = svc.GetCollection(alice, c.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, 0, items[0].Position)
+	var removed *models.CollectionItemRemoved
+	for _, ev := range outbox.events {
+		if e, ok := ev.(*models.CollectionItemRemoved); ok {
+			removed = e
+		}
+	}
+	require.NotNil(t, removed)
+	require.Equal(t, second.ID, removed.MediaID())
+
+	list, err := svc.ListCollections(bob, repository.CollectionFilter{})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	require.ErrorIs(t, svc.RemoveCollectionItem(alice, c.ID, second.ID), models.ErrNotFound)
+	require.NoError(t, svc.DeleteCollection(alice, c.ID))
+	deleted := outbox.events[len(outbox.events)-1].(*models.CollectionDeleted)
+	require.Equal(t, []uuid.UUID{first.ID}, deleted.MediaIDs())
+	_, _, err = svc.GetCollection(alice, c.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestGrants_AccessControl(t *testing.T) {
+	outbox := new(recordingOutbox)
+	svc := New(repository.NewMemoryRepository(), outbox).WithGrants(repository.NewMemoryGrantRepository())
+
+	aliceID, bobID := uuid.New(), uuid.New()
+	alice := WithPrincipal(context.Background(), Principal{OwnerID: aliceID})
+	bob := WithPrincipal(context.Background(), Principal{OwnerID: bobID})
+
+	m, err := svc.CreateMedia(alice, models.Video, "s3://bucket/1.mp4")
+	require.NoError(t, err)
+	_, err = svc.GetMedia(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	// Выдаёт только владелец, себе — нельзя
+	_, err = svc.GrantAccess(bob, m.ID, bobID, models.ReadPermission)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = svc.GrantAccess(alice, m.ID, aliceID, models.ReadPermission)
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	_, err = svc.GrantAccess(alice, m.ID, bobID, "write")
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+
+	g, err := svc.GrantAccess(alice, m.ID, bobID, models.ReadPermission)
+	require.NoError(t, err)
+	require.Equal(t, models.ReadPermission, g.Permission)
+	got, err := svc.GetMedia(bob, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, got.ID)
+	_, err = svc.GetMediaForDownload(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrForbidden)
+	// Право на чтение не даёт прав владельца
+	require.ErrorIs(t, svc.DeleteMedia(bob, m.ID, models.DeleteReasonDeleted), models.ErrNotFound)
+	_, err = svc.ListGrants(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	// Повтор того же права событий не пишет
+	_, err = svc.GrantAccess(alice, m.ID, bobID, models.ReadPermission)
+	require.NoError(t, err)
+	_, err = svc.GrantAccess(alice, m.ID, bobID, models.DownloadPermission)
+	require.NoError(t, err)
+	_, err = svc.GetMediaForDownload(bob, m.ID)
+	require.NoError(t, err)
+
+	grants, err := svc.ListGrants(alice, m.ID)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, models.DownloadPermission, grants[0].Permission)
+
+	require.NoError(t, svc.RevokeAccess(alice, m.ID, bobID))
+	require.ErrorIs(t, svc.RevokeAccess(alice, m.ID, bobID), models.ErrNotFound)
+	_, err = svc.GetMedia(bob, m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	require.Equal(t, []string{"MediaCreated", "MediaAccessGranted", "MediaAccessGranted", "MediaAccessRevoked"}, outbox.types())
+	upgraded := outbox.events[2].(*models.MediaAccessGranted)
+	require.Equal(t, bobID, upgraded.Principal())
+	require.Equal(t, models.DownloadPermission, upgraded.Permission())
+}
+
+func TestShareMedia(t *testing.T) {
+	svc := New(repository.NewMemoryRepository(), nil)
+	alice := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+	bob := WithPrincipal(context.Background(), Principal{OwnerID: uuid.New()})
+
+	m, err := svc.CreateMedia(alice, models.Video, "s3://bucket/1.mp4")
+	require.NoError(t, err)
+	_, err = svc.ShareMedia(bob, m.ID, models.ReadPermission)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = svc.ShareMedia(alice, m.ID, "write")
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	_, err = svc.ShareMedia(alice, m.ID, models.DownloadPermission)
+	require.NoError(t, err)
+
+	// По ссылке медиа отдаётся без principal, кроме попавшего в карантин
+	got, err := svc.GetSharedMedia(context.Background(), m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, got.ID)
+	_, err = svc.QuarantineMedia(context.Background(), m.ID, Verdict{Threat: "Eicar-Test-Signature", Scanner: "clamav"}, ChangeMeta{})
+	require.NoError(t, err)
+	_, err = svc.GetSharedMedia(context.Background(), m.ID)
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = svc.ShareMedia(alice, m.ID, models.ReadPermission)
+	require.ErrorIs(t, err, models.ErrConflict)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// StatusBatchItem — один переход пакетной смены статуса
+type StatusBatchItem struct {
+	ID     uuid.UUID
+	Status models.Status
+	Reason string
+}
+
+type StatusBatchOutcome string
+
+const (
+	StatusBatchChanged   StatusBatchOutcome = "changed"
+	StatusBatchUnchanged StatusBatchOutcome = "unchanged" // медиа уже в этом статусе
+	StatusBatchRejected  StatusBatchOutcome = "rejected"  // причина — в Err
+)
+
+// StatusBatchResult — результат по элементу; Index совпадает с позицией во входном срезе
+type StatusBatchResult struct {
+	Index   int
+	Outcome StatusBatchOutcome
+	Media   *models.Media
+	// Err — почему элемент отклонён: models.ErrNotFound, models.ErrInvalidArgument,
+	// domain.ErrInvalidTransition
+	Err error
+}
+
+// ChangeStatusBatch переводит пачку медиа в новые статусы одной транзакцией: переходы, история
+// статусов и события MediaStatusChanged (одним вызовом outbox, см. BatchOutbox) фиксируются вместе.
+// Отсутствующее медиа, недопустимый переход или повтор id не прерывают пачку — элемент
+// отклоняется с причиной в результате. Любая другая ошибка откатывает транзакцию целиком.
+func (s *Service) ChangeStatusBatch(ctx context.Context, items []StatusBatchItem) ([]StatusBatchResult, error) {
+	if len(items) == 0 || len(items) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch size must be between 1 and %d", models.ErrInvalidArgument, MaxBatchSize)
+	}
+	actor := ActorFromContext(ctx)
+
+	// Элементы, прошедшие проверку без чтения медиа, в порядке id: пачки с общими медиа
+	// блокируют строки в одном порядке и не ловят deadlock друг на друге
+	pending := make([]int, 0, len(items))
+	seen := make(map[uuid.UUID]bool, len(items))
+	rejected := make([]error, len(items))
+	for i, it := range items {
+		switch {
+		case seen[it.ID]:
+			rejected[i] = fmt.Errorf("%w: media %s appears more than once in the batch", models.ErrInvalidArgument, it.ID)
+		case it.Status == models.ArchivedStatus || it.Status == models.QuarantinedStatus || it.Status == models.DeletedStatus:
+			rejected[i] = fmt.Errorf("%w: status %q cannot be set by a batch", models.ErrInvalidArgument, it.Status)
+		case it.Status == models.ScheduledStatus:
+			rejected[i] = scheduledOnly(it.Status)
+		default:
+			if _, err := toDomainStatus(it.Status); err != nil {
+				rejected[i] = err
+			}
+		}
+		seen[it.ID] = true
+		if rejected[i] == nil {
+			pending = append(pending, i)
+		}
+	}
+	slices.SortFunc(pending, func(a, b int) int { return slices.Compare(items[a].ID[:], items[b].ID[:]) })
+
+	var results []StatusBatchResult
+	err := s.retryTransient(ctx, "change status batch", func() error {
+		// Повтор после конфликта сериализации начинает пачку заново
+		results = make([]StatusBatchResult, len(items))
+		for i, err := range rejected {
+			results[i] = StatusBatchResult{Index: i, Outcome: StatusBatchRejected, Err: err}
+		}
+		return s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+			events := make([]models.DomainEvent, len(items))
+			for _, i := range pending {
+				it := items[i]
+				m, event, err := s.batchTransition(ctx, it, ChangeMeta{Actor: actor, Reason: it.Reason})
+				switch {
+				case errors.Is(err, models.ErrNotFound), errors.Is(err, domain.ErrInvalidTransition):
+					results[i] = StatusBatchResult{Index: i, Outcome: StatusBatchRejected, Err: err}
+				case err != nil:
+					return fmt.Errorf("item %d: %w", i, err)
+				case event == nil:
+					results[i] = StatusBatchResult{Index: i, Outcome: StatusBatchUnchanged, Media: m}
+				default:
+					results[i] = StatusBatchResult{Index: i, Outcome: StatusBatchChanged, Media: m}
+					events[i] = event
+				}
+			}
+			// События — в порядке элементов запроса
+			return s.addEvents(ctx, slices.DeleteFunc(events, func(e models.DomainEvent) bool { return e == nil }))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[StatusBatchOutcome]int, 3)
+	for _, r := range results {
+		counts[r.Outcome]++
+	}
+	s.ctxLogger(ctx).Info().
+		Int("changed", counts[StatusBatchChanged]).
+		Int("unchanged", counts[StatusBatchUnchanged]).
+		Int("rejected", counts[StatusBatchRejected]).
+		Str("actor", actor).
+		Msg("media status batch applied")
+	return results, nil
+}
+
+// batchTransition блокирует медиа и выполняет переход элемента внутри транзакции пачки.
+// Медиа уже в статусе it.Status возвращается без события.
+func (s *Service) batchTransition(ctx context.Context, it StatusBatchItem, meta ChangeMeta) (*models.Media, models.DomainEvent, error) {
+	m, err := s.repo.GetForUpdate(ctx, it.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := authorize(ctx, m); err != nil {
+		return nil, nil, err
+	}
+	fromDom, err := toDomainStatus(m.Status)
+	if err != nil {
+		return nil, nil, err
+	}
+	// ready до publish_at — это scheduled, как и в ChangeStatus
+	to := s.publishStatus(m, it.Status)
+	toDom, err := toDomainStatus(to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.states.ValidateTransition(fromDom, toDom); err != nil {
+		return nil, nil, err
+	}
+	if m.Status == to {
+		return m, nil, nil
+	}
+	return s.applyTransition(ctx, m.Status, it.ID, to, meta)
+}
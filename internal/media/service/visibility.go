@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// SetVisibility меняет видимость медиа по правилам domain.VisibilityTransitions. Меняет только
+// владелец (или админ); новая видимость и событие MediaVisibilityChanged пишутся одной транзакцией.
+// Медиа в карантине открыть нельзя — models.ErrConflict.
+func (s *Service) SetVisibility(ctx context.Context, id uuid.UUID, to models.Visibility, meta ChangeMeta) (*models.Media, error) {
+	if id == uuid.Nil {
+		return nil, models.ErrInvalidArgument
+	}
+	if _, ok := domain.VisibilityTransitions[domain.Visibility(to)]; !ok {
+		return nil, fmt.Errorf("%w: unknown visibility %q", models.ErrInvalidArgument, to)
+	}
+	if meta.Actor == "" {
+		meta.Actor = ActorFromContext(ctx)
+	}
+
+	var (
+		from    models.Visibility
+		updated *models.Media
+	)
+	err := s.withinTransaction(ctx, "set visibility", func(ctx context.Context) error {
+		m, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorize(ctx, m); err != nil {
+			return err
+		}
+		if len(meta.IfMatch) > 0 && !slices.Contains(meta.IfMatch, m.Version()) {
+			return fmt.Errorf("%w: media %s is at another version", models.ErrPreconditionFailed, id)
+		}
+		if from = m.Visibility; from == "" {
+			from = models.DraftVisibility
+		}
+		if err := domain.ValidateVisibility(domain.Visibility(from), domain.Visibility(to)); err != nil {
+			return err
+		}
+		if from == to {
+			updated = m
+			return nil
+		}
+		if m.Status == models.QuarantinedStatus && to != models.PrivateVisibility {
+			return fmt.Errorf("%w: quarantined media cannot be shared", models.ErrConflict)
+		}
+
+		updated, err = s.repo.Update(ctx, id, models.MediaPatch{Visibility: &to})
+		if err != nil {
+			return err
+		}
+		return s.addEvent(ctx, models.NewMediaVisibilityChanged(updated, from, meta.Actor, s.clock()))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx, id).Info().
+		Str("from", string(from)).
+		Str("to", string(to)).
+		Str("effective", string(updated.EffectiveVisibility())).
+		Str("actor", meta.Actor).
+		Msg("media visibility changed")
+	return updated, nil
+}
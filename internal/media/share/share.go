@@ -0,0 +1,174 @@
+// Package share — ссылки для анонимного доступа к медиа. Токен в ссылке подписан HMAC и сам
+// несёт id медиа, право (read или download) и срок: ссылки нигде не хранятся, поэтому и
+// отозвать выданную ссылку раньше срока нельзя — срок ограничен MaxTTL.
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// ErrInvalidToken — токен повреждён, подпись не сошлась или срок истёк
+var ErrInvalidToken = errors.New("invalid share token")
+
+// minSecretLength — HMAC ключ короче 256 бит подбирается слишком легко
+const minSecretLength = 32
+
+// payloadLength — id медиа (16 байт), право (1) и срок в unix секундах (8)
+const payloadLength = 16 + 1 + 8
+
+// Config содержит конфигурацию Links
+type Config struct {
+	Secret []byte // ключ HMAC токенов, не короче 32 байт
+	// BaseURL — внешний адрес media API (https://media.example.com); пустой — ссылка
+	// относительная (/shared/{token})
+	BaseURL string
+	TTL     time.Duration // срок ссылки по умолчанию (default: 24h)
+	MaxTTL  time.Duration // предел срока, который может запросить владелец (default: 7 дней)
+}
+
+// Link — выданная ссылка
+type Link struct {
+	Token      string
+	URL        string
+	Permission models.Permission
+	ExpiresAt  time.Time
+}
+
+// Claims — то, что подтверждает проверенный токен
+type Claims struct {
+	MediaID    uuid.UUID
+	Permission models.Permission
+	ExpiresAt  time.Time
+}
+
+// Links выдаёт и проверяет ссылки
+type Links struct {
+	secret  []byte
+	baseURL string
+	ttl     time.Duration
+	maxTTL  time.Duration
+	clock   func() time.Time
+}
+
+func New(cfg Config) (*Links, error) {
+	if len(cfg.Secret) < minSecretLength {
+		return nil, fmt.Errorf("share secret must be at least %d bytes, got: %d", minSecretLength, len(cfg.Secret))
+	}
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid share base url %q", cfg.BaseURL)
+		}
+	}
+	if cfg.TTL < 0 {
+		return nil, fmt.Errorf("ttl cannot be negative, got: %v", cfg.TTL)
+	}
+	if cfg.MaxTTL < 0 {
+		return nil, fmt.Errorf("max ttl cannot be negative, got: %v", cfg.MaxTTL)
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.MaxTTL == 0 {
+		cfg.MaxTTL = 7 * 24 * time.Hour
+	}
+	if cfg.TTL > cfg.MaxTTL {
+		return nil, fmt.Errorf("ttl %v exceeds max ttl %v", cfg.TTL, cfg.MaxTTL)
+	}
+	return &Links{
+		secret:  cfg.Secret,
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		ttl:     cfg.TTL,
+		maxTTL:  cfg.MaxTTL,
+		clock:   time.Now,
+	}, nil
+}
+
+// MaxTTL — предел срока ссылки
+func (l *Links) MaxTTL() time.Duration { return l.maxTTL }
+
+// Issue выдаёт ссылку на медиа mediaID с правом permission на ttl (0 — по умолчанию)
+func (l *Links) Issue(mediaID uuid.UUID, permission models.Permission, ttl time.Duration) (Link, error) {
+	code, ok := permissionCodes[permission]
+	if !ok {
+		return Link{}, fmt.Errorf("%w: unknown permission %q", models.ErrInvalidArgument, permission)
+	}
+	if ttl == 0 {
+		ttl = l.ttl
+	}
+	if ttl < time.Second || ttl > l.maxTTL {
+		return Link{}, fmt.Errorf("%w: ttl must be between 1s and %v", models.ErrInvalidArgument, l.maxTTL)
+	}
+	expires := l.clock().Add(ttl).Truncate(time.Second)
+
+	payload := make([]byte, payloadLength)
+	copy(payload, mediaID[:])
+	payload[16] = code
+	binary.BigEndian.PutUint64(payload[17:], uint64(expires.Unix()))
+	token := base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(l.sign(payload))
+	return Link{
+		Token:      token,
+		URL:        l.baseURL + "/shared/" + token,
+		Permission: permission,
+		ExpiresAt:  expires,
+	}, nil
+}
+
+// Verify проверяет подпись и срок токена
+func (l *Links) Verify(token string) (Claims, error) {
+	rawPayload, rawSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(rawPayload)
+	if err != nil || len(payload) != payloadLength {
+		return Claims{}, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(rawSig)
+	if err != nil || !hmac.Equal(sig, l.sign(payload)) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	copy(claims.MediaID[:], payload[:16])
+	for p, code := range permissionCodes {
+		if code == payload[16] {
+			claims.Permission = p
+		}
+	}
+	if claims.Permission == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	claims.ExpiresAt = time.Unix(int64(binary.BigEndian.Uint64(payload[17:])), 0).UTC()
+	if !l.clock().Before(claims.ExpiresAt) {
+		return Claims{}, fmt.Errorf("%w: link expired", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// permissionCodes — право в токене одним байтом
+var permissionCodes = map[models.Permission]byte{
+	models.ReadPermission:     'r',
+	models.DownloadPermission: 'd',
+}
+
+// sign — HMAC-SHA256 от payload с префиксом назначения: тот же ключ в другой подписи
+// (например, ссылок на скачивание) не даст валидный токен
+func (l *Links) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte("share\n"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
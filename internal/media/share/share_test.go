@@ -0,0 +1,88 @@
+package share
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func newLinks(t *testing.T, now *time.Time) *Links {
+	t.Helper()
+	l, err := New(Config{Secret: secret, BaseURL: "https://media.example.com/"})
+	require.NoError(t, err)
+	l.clock = func() time.Time { return *now }
+	return l
+}
+
+func TestLinks_IssueVerify(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLinks(t, &now)
+	id := uuid.New()
+
+	link, err := l.Issue(id, models.DownloadPermission, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, "https://media.example.com/shared/"+link.Token, link.URL)
+	require.Equal(t, now.Add(time.Hour), link.ExpiresAt)
+
+	claims, err := l.Verify(link.Token)
+	require.NoError(t, err)
+	require.Equal(t, Claims{MediaID: id, Permission: models.DownloadPermission, ExpiresAt: link.ExpiresAt}, claims)
+
+	def, err := l.Issue(id, models.ReadPermission, 0)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(24*time.Hour), def.ExpiresAt)
+
+	now = now.Add(time.Hour)
+	_, err = l.Verify(link.Token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLinks_RejectsTampering(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLinks(t, &now)
+	read, err := l.Issue(uuid.New(), models.ReadPermission, time.Hour)
+	require.NoError(t, err)
+	download, err := l.Issue(uuid.New(), models.DownloadPermission, time.Hour)
+	require.NoError(t, err)
+
+	readPayload, _, _ := strings.Cut(read.Token, ".")
+	_, downloadSig, _ := strings.Cut(download.Token, ".")
+	for _, token := range []string{
+		"",
+		"garbage",
+		readPayload + "." + downloadSig, // подпись чужого токена
+		readPayload + ".",
+		"AAAA." + downloadSig,
+	} {
+		_, err := l.Verify(token)
+		require.ErrorIs(t, err, ErrInvalidToken, token)
+	}
+
+	other, err := New(Config{Secret: []byte(strings.Repeat("x", 32))})
+	require.NoError(t, err)
+	_, err = other.Verify(read.Token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLinks_Validation(t *testing.T) {
+	_, err := New(Config{Secret: []byte("short")})
+	require.Error(t, err)
+	_, err = New(Config{Secret: secret, BaseURL: "ftp://x"})
+	require.Error(t, err)
+	_, err = New(Config{Secret: secret, TTL: 48 * time.Hour, MaxTTL: time.Hour})
+	require.Error(t, err)
+
+	now := time.Now()
+	l := newLinks(t, &now)
+	_, err = l.Issue(uuid.New(), models.DownloadPermission, 8*24*time.Hour)
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+	_, err = l.Issue(uuid.New(), "write", time.Hour)
+	require.ErrorIs(t, err, models.ErrInvalidArgument)
+}
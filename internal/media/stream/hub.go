@@ -0,0 +1,114 @@
+// Package stream — подписки на изменения медиа для потоковой отдачи клиентам (SSE).
+// Hub не передаёт содержимое событий: подписчик получает сигнал «медиа изменилось» и сам
+// перечитывает текущее состояние. Поэтому сигналы можно склеивать и терять без потери
+// состояния, а формат сериализации событий в Kafka не важен — достаточно заголовков.
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Hub раздаёт сигналы об изменениях медиа подписчикам этого инстанса
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[uuid.UUID]map[*subscription]struct{}
+	done   chan struct{}
+	closed bool
+}
+
+type subscription struct {
+	ch chan struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[uuid.UUID]map[*subscription]struct{}), done: make(chan struct{})}
+}
+
+// Done закрывается вызовом Close: подписчикам пора завершаться
+func (h *Hub) Done() <-chan struct{} { return h.done }
+
+// Close просит подписчиков завершиться (остановка сервера); повторный вызов ничего не делает
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+}
+
+// Subscribe подписывается на изменения медиа id. Канал буферизован на один сигнал: пока
+// подписчик занят, новые сигналы склеиваются. cancel отписывает; канал не закрывается.
+func (h *Hub) Subscribe(id uuid.UUID) (changes <-chan struct{}, cancel func()) {
+	s := &subscription{ch: make(chan struct{}, 1)}
+
+	h.mu.Lock()
+	if h.subs[id] == nil {
+		h.subs[id] = make(map[*subscription]struct{})
+	}
+	h.subs[id][s] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return s.ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs[id], s)
+			if len(h.subs[id]) == 0 {
+				delete(h.subs, id)
+			}
+		})
+	}
+}
+
+// Subscribers возвращает число активных подписок
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, subs := range h.subs {
+		n += len(subs)
+	}
+	return n
+}
+
+// Notify сообщает подписчикам медиа id об изменении; не блокируется
+func (h *Hub) Notify(id uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[id] {
+		select {
+		case s.ch <- struct{}{}:
+		default: // сигнал уже ждёт подписчика
+		}
+	}
+}
+
+// NotifyEvent — Notify по заголовкам события из шины (event_type, aggregate_id).
+// События не о медиа и без aggregate_id пропускаются.
+func (h *Hub) NotifyEvent(eventType, aggregateID string) error {
+	if eventType == "" || aggregateID == "" {
+		return nil
+	}
+	id, err := uuid.Parse(aggregateID)
+	if err != nil {
+		return fmt.Errorf("event %s: invalid aggregate id %q: %w", eventType, aggregateID, err)
+	}
+	h.Notify(id)
+	return nil
+}
+
+// Add реализует service.Outbox для in-memory режима, где шины событий нет: подписчики
+// получают сигнал сразу после изменения. С настоящим outbox сигнал до коммита транзакции
+// преждевременен — там Hub питается событиями из Kafka.
+func (h *Hub) Add(_ context.Context, event models.DomainEvent) error {
+	h.Notify(event.AggregateID())
+	return nil
+}
@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_NotifyCoalescesAndUnsubscribes(t *testing.T) {
+	hub := NewHub()
+	id, other := uuid.New(), uuid.New()
+
+	a, cancelA := hub.Subscribe(id)
+	b, cancelB := hub.Subscribe(id)
+	require.Equal(t, 2, hub.Subscribers())
+
+	// Сигналы, пришедшие, пока подписчик занят, склеиваются в один
+	hub.Notify(id)
+	hub.Notify(id)
+	require.NoError(t, hub.NotifyEvent("MediaStatusChanged", id.String()))
+	hub.Notify(other)
+	require.Len(t, a, 1)
+	<-a
+	require.Empty(t, a)
+	require.Len(t, b, 1)
+
+	cancelA()
+	cancelA()
+	hub.Notify(id)
+	require.Empty(t, a)
+	require.Equal(t, 1, hub.Subscribers())
+	cancelB()
+	require.Equal(t, 0, hub.Subscribers())
+
+	require.Error(t, hub.NotifyEvent("MediaStatusChanged", "not-a-uuid"))
+	require.NoError(t, hub.NotifyEvent("QuotaReserved", ""))
+}
@@ -0,0 +1,96 @@
+// Package jobs — очередь фоновых задач processing поверх Postgres. В отличие от реакции
+// на события Kafka, задачи имеют приоритет и время запуска: тяжёлое транскодирование можно
+// поднять в очереди или отложить, не держа offset консьюмера. Worker забирает задачи с
+// арендой (visibility timeout): задача упавшего worker'а возвращается в очередь, когда аренда
+// истекает. Каждый захват увеличивает attempts; номер попытки — токен аренды, так что worker,
+// потерявший аренду, не может завершить задачу, которую уже взял другой.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrLeaseLost — аренда задачи истекла и задачу взял другой worker (или она уже завершена)
+	ErrLeaseLost = errors.New("job lease lost")
+	// ErrNotFound — задачи с таким id нет
+	ErrNotFound = errors.New("job not found")
+)
+
+// DefaultMaxAttempts — попыток по умолчанию, после которых задача считается проваленной
+const DefaultMaxAttempts = 5
+
+// Job — задача в очереди
+type Job struct {
+	ID          int64           `db:"id"`
+	Queue       string          `db:"queue"`
+	Kind        string          `db:"kind"`     // выбирает обработчик: transcode, package, ...
+	Payload     json.RawMessage `db:"payload"`  // пустой — {}
+	Priority    int             `db:"priority"` // больше — раньше
+	Attempts    int             `db:"attempts"` // захваты, включая текущий
+	MaxAttempts int             `db:"max_attempts"`
+	// UniqueKey — пока задача с этим ключом не завершена, повторная постановка возвращает её
+	// (processing получает события at least once). Пустой — без дедупликации.
+	UniqueKey   string     `db:"unique_key"`
+	ScheduledAt time.Time  `db:"scheduled_at"` // раньше этого времени задача не берётся
+	LockedUntil *time.Time `db:"locked_until"` // аренда текущего захвата
+	LastError   string     `db:"last_error"`
+	CreatedAt   time.Time  `db:"created_at"`
+	CompletedAt *time.Time `db:"completed_at"`
+	FailedAt    *time.Time `db:"failed_at"` // исчерпала попытки
+}
+
+// Validate проверяет задачу перед постановкой
+func (j Job) Validate() error {
+	switch {
+	case j.Queue == "":
+		return errors.New("job queue is required")
+	case j.Kind == "":
+		return errors.New("job kind is required")
+	case j.MaxAttempts < 0:
+		return fmt.Errorf("max attempts cannot be negative, got: %d", j.MaxAttempts)
+	case len(j.Payload) > 0 && !json.Valid(j.Payload):
+		return errors.New("job payload must be valid json")
+	}
+	return nil
+}
+
+// Store — хранилище очереди. Все операции над захваченной задачей принимают номер попытки
+// и возвращают ErrLeaseLost, если задача уже не принадлежит этой попытке.
+type Store interface {
+	// Enqueue ставит задачу; с UniqueKey возвращает уже стоящую незавершённую задачу
+	Enqueue(ctx context.Context, job Job) (Job, error)
+	// Claim захватывает до limit готовых задач очереди: по убыванию приоритета, затем по
+	// времени запуска. Захват увеличивает attempts и выдаёт аренду на visibility.
+	Claim(ctx context.Context, queue string, limit int, visibility time.Duration) ([]Job, error)
+	// Extend продлевает аренду длинной задачи
+	Extend(ctx context.Context, id int64, attempt int, visibility time.Duration) error
+	// Complete завершает задачу
+	Complete(ctx context.Context, id int64, attempt int) error
+	// Retry снимает аренду после неудачной попытки и откладывает задачу до runAt
+	Retry(ctx context.Context, id int64, attempt int, runAt time.Time, lastError string) error
+	// Release снимает аренду без траты попытки: задача отложена обработчиком или прервана остановкой
+	Release(ctx context.Context, id int64, attempt int, runAt time.Time) error
+	// Fail помечает задачу проваленной: больше она не берётся
+	Fail(ctx context.Context, id int64, attempt int, lastError string) error
+}
+
+// RescheduleError — обработчик откладывает задачу, не считая это неудачей (например, транскодер
+// занят более срочными задачами). Попытка не тратится.
+type RescheduleError struct {
+	After  time.Duration
+	Reason string
+}
+
+func (e *RescheduleError) Error() string {
+	return fmt.Sprintf("rescheduled in %v: %s", e.After, e.Reason)
+}
+
+// Reschedule возвращается обработчиком, чтобы отложить задачу на after
+func Reschedule(after time.Duration, reason string) error {
+	return &RescheduleError{After: after, Reason: reason}
+}
@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore — Store в памяти процесса для тестов и локального запуска
+type MemoryStore struct {
+	mu     sync.Mutex
+	jobs   map[int64]*Job
+	lastID int64
+	now    func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[int64]*Job), now: time.Now}
+}
+
+// Get возвращает копию задачи
+func (s *MemoryStore) Get(_ context.Context, id int64) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *j, nil
+}
+
+func (s *MemoryStore) Enqueue(ctx context.Context, job Job) (Job, error) {
+	if err := job.Validate(); err != nil {
+		return Job{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Job{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.UniqueKey != "" {
+		for _, j := range s.jobs {
+			if j.Queue == job.Queue && j.UniqueKey == job.UniqueKey && j.CompletedAt == nil && j.FailedAt == nil {
+				return *j, nil
+			}
+		}
+	}
+	now := s.now()
+	s.lastID++
+	j := Job{
+		ID:          s.lastID,
+		Queue:       job.Queue,
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		Priority:    job.Priority,
+		MaxAttempts: job.MaxAttempts,
+		UniqueKey:   job.UniqueKey,
+		ScheduledAt: job.ScheduledAt,
+		CreatedAt:   now,
+	}
+	if j.MaxAttempts == 0 {
+		j.MaxAttempts = DefaultMaxAttempts
+	}
+	if j.ScheduledAt.IsZero() {
+		j.ScheduledAt = now
+	}
+	if len(j.Payload) == 0 {
+		j.Payload = json.RawMessage(`{}`)
+	}
+	s.jobs[j.ID] = &j
+	return j, nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, queue string, limit int, visibility time.Duration) ([]Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var ready []*Job
+	for _, j := range s.jobs {
+		if j.Queue == queue && j.CompletedAt == nil && j.FailedAt == nil &&
+			!j.ScheduledAt.After(now) && (j.LockedUntil == nil || !j.LockedUntil.After(now)) {
+			ready = append(ready, j)
+		}
+	}
+	slices.SortFunc(ready, compareJobs)
+	if len(ready) > limit {
+		ready = ready[:limit]
+	}
+
+	claimed := make([]Job, 0, len(ready))
+	lockedUntil := now.Add(visibility)
+	for _, j := range ready {
+		j.Attempts++
+		j.LockedUntil = &lockedUntil
+		claimed = append(claimed, *j)
+	}
+	return claimed, nil
+}
+
+// compareJobs — порядок выдачи: приоритет по убыванию, затем время запуска и id
+func compareJobs(a, b *Job) int {
+	switch {
+	case a.Priority != b.Priority:
+		return b.Priority - a.Priority
+	case !a.ScheduledAt.Equal(b.ScheduledAt):
+		return a.ScheduledAt.Compare(b.ScheduledAt)
+	default:
+		return int(a.ID - b.ID)
+	}
+}
+
+// leased возвращает задачу, если она захвачена попыткой attempt и аренда не истекла
+func (s *MemoryStore) leased(id int64, attempt int) (*Job, error) {
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if j.Attempts != attempt || j.LockedUntil == nil || !j.LockedUntil.After(s.now()) ||
+		j.CompletedAt != nil || j.FailedAt != nil {
+		return nil, ErrLeaseLost
+	}
+	return j, nil
+}
+
+func (s *MemoryStore) Extend(_ context.Context, id int64, attempt int, visibility time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(id, attempt)
+	if err != nil {
+		return err
+	}
+	lockedUntil := s.now().Add(visibility)
+	j.LockedUntil = &lockedUntil
+	return nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, id int64, attempt int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(id, attempt)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	j.CompletedAt = &now
+	j.LockedUntil = nil
+	return nil
+}
+
+func (s *MemoryStore) Retry(_ context.Context, id int64, attempt int, runAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(id, attempt)
+	if err != nil {
+		return err
+	}
+	j.ScheduledAt = runAt
+	j.LockedUntil = nil
+	j.LastError = lastError
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, id int64, attempt int, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(id, attempt)
+	if err != nil {
+		return err
+	}
+	j.Attempts--
+	j.ScheduledAt = runAt
+	j.LockedUntil = nil
+	return nil
+}
+
+func (s *MemoryStore) Fail(_ context.Context, id int64, attempt int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.leased(id, attempt)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	j.FailedAt = &now
+	j.LockedUntil = nil
+	j.LastError = lastError
+	return nil
+}
@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock — управляемое время MemoryStore
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time      { return c.now }
+func (c *fakeClock) Add(d time.Duration) { c.now = c.now.Add(d) }
+
+func newClockedStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.Now
+	return s, clock
+}
+
+func TestMemoryStore_ClaimOrder(t *testing.T) {
+	ctx := context.Background()
+	s, clock := newClockedStore()
+
+	low, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode"})
+	require.NoError(t, err)
+	require.Equal(t, DefaultMaxAttempts, low.MaxAttempts)
+	high, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode", Priority: 10})
+	require.NoError(t, err)
+	delayed, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode", Priority: 100, ScheduledAt: clock.now.Add(time.Minute)})
+	require.NoError(t, err)
+	_, err = s.Enqueue(ctx, Job{Queue: "other", Kind: "transcode", Priority: 100})
+	require.NoError(t, err)
+
+	claimed, err := s.Claim(ctx, "processing", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.Equal(t, high.ID, claimed[0].ID)
+	require.Equal(t, low.ID, claimed[1].ID)
+	require.Equal(t, 1, claimed[0].Attempts)
+
+	// Захваченные задачи не выдаются повторно, отложенная становится готовой в своё время
+	claimed, err = s.Claim(ctx, "processing", 10, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	clock.Add(time.Minute)
+	claimed, err = s.Claim(ctx, "processing", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, delayed.ID, claimed[0].ID)
+}
+
+func TestMemoryStore_Lease(t *testing.T) {
+	ctx := context.Background()
+	s, clock := newClockedStore()
+	job, err := s.Enqueue(ctx, Job{Queue: "q", Kind: "k"})
+	require.NoError(t, err)
+
+	first, err := s.Claim(ctx, "q", 1, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Extend(ctx, job.ID, first[0].Attempts, time.Minute))
+
+	// Аренда истекла — задачу берёт другой worker, первый уже не может её завершить
+	clock.Add(2 * time.Minute)
+	second, err := s.Claim(ctx, "q", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	require.Equal(t, 2, second[0].Attempts)
+	require.ErrorIs(t, s.Complete(ctx, job.ID, first[0].Attempts), ErrLeaseLost)
+	require.ErrorIs(t, s.Extend(ctx, job.ID, first[0].Attempts, time.Minute), ErrLeaseLost)
+
+	// Release не тратит попытку
+	require.NoError(t, s.Release(ctx, job.ID, 2, clock.now))
+	third, err := s.Claim(ctx, "q", 1, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 2, third[0].Attempts)
+	require.NoError(t, s.Complete(ctx, job.ID, 2))
+	require.ErrorIs(t, s.Complete(ctx, job.ID, 2), ErrLeaseLost)
+	require.ErrorIs(t, s.Complete(ctx, 999, 1), ErrNotFound)
+}
+
+func TestMemoryStore_EnqueueUnique(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newClockedStore()
+	payload := json.RawMessage(`{"media_id":"a"}`)
+
+	first, err := s.Enqueue(ctx, Job{Queue: "q", Kind: "k", UniqueKey: "transcode:a", Payload: payload})
+	require.NoError(t, err)
+	dup, err := s.Enqueue(ctx, Job{Queue: "q", Kind: "k", UniqueKey: "transcode:a", Priority: 5})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, dup.ID)
+	require.Equal(t, 0, dup.Priority)
+
+	// После завершения ключ свободен
+	claimed, err := s.Claim(ctx, "q", 1, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Complete(ctx, first.ID, claimed[0].Attempts))
+	again, err := s.Enqueue(ctx, Job{Queue: "q", Kind: "k", UniqueKey: "transcode:a"})
+	require.NoError(t, err)
+	require.NotEqual(t, first.ID, again.ID)
+
+	for _, bad := range []Job{
+		{Kind: "k"},
+		{Queue: "q"},
+		{Queue: "q", Kind: "k", MaxAttempts: -1},
+		{Queue: "q", Kind: "k", Payload: json.RawMessage(`{`)},
+	} {
+		_, err := s.Enqueue(ctx, bad)
+		require.Error(t, err)
+	}
+}
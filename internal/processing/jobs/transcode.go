@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// KindTranscode — задача транскодирования медиа
+const KindTranscode = "transcode"
+
+// TranscodePayload — payload задачи transcode
+type TranscodePayload struct {
+	MediaID string `json:"media_id"`
+	Source  string `json:"source"`
+}
+
+// TranscodeFromEvent переводит событие media в задачу transcode очереди queue: новое медиа
+// (MediaCreated) и загруженный исходник (MediaContentRecorded) нужно транскодировать.
+// UniqueKey — id медиа: пока задача медиа не завершена, повторная доставка события и
+// загрузка исходника возвращают её, а не ставят вторую. ok=false — событие задач не порождает.
+func TranscodeFromEvent(queue, eventType string, payload json.RawMessage) (job Job, ok bool, err error) {
+	switch eventType {
+	case "MediaCreated", "MediaContentRecorded":
+	default:
+		return Job{}, false, nil
+	}
+
+	var body struct {
+		MediaID uuid.UUID `json:"media_id"`
+		Source  string    `json:"source"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return Job{}, false, fmt.Errorf("decode %s payload: %w", eventType, err)
+	}
+	if body.MediaID == uuid.Nil {
+		return Job{}, false, fmt.Errorf("%s payload: media_id is required", eventType)
+	}
+
+	p, err := json.Marshal(TranscodePayload{MediaID: body.MediaID.String(), Source: body.Source})
+	if err != nil {
+		return Job{}, false, err
+	}
+	return Job{
+		Queue:     queue,
+		Kind:      KindTranscode,
+		Payload:   p,
+		UniqueKey: body.MediaID.String(),
+	}, true, nil
+}
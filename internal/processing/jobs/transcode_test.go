@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func TestTranscodeFromEvent(t *testing.T) {
+	m := &models.Media{ID: uuid.New(), Type: models.Video, Source: "s3://media/in/1.mp4", Status: models.UploadedStatus}
+	env, err := events.Default.Wrap(models.NewMediaCreated(m))
+	require.NoError(t, err)
+
+	job, ok, err := TranscodeFromEvent("processing", env.EventType, env.Payload)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "processing", job.Queue)
+	require.Equal(t, KindTranscode, job.Kind)
+	require.Equal(t, m.ID.String(), job.UniqueKey)
+	var p TranscodePayload
+	require.NoError(t, json.Unmarshal(job.Payload, &p))
+	require.Equal(t, TranscodePayload{MediaID: m.ID.String(), Source: "s3://media/in/1.mp4"}, p)
+
+	// Загруженный исходник — задача того же медиа
+	content := models.Content{Checksum: "ab", Size: 10, ContentType: "video/mp4"}
+	env, err = events.Default.Wrap(models.NewMediaContentRecorded(m, content, time.Now()))
+	require.NoError(t, err)
+	job, ok, err = TranscodeFromEvent("processing", env.EventType, env.Payload)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, m.ID.String(), job.UniqueKey)
+
+	_, ok, err = TranscodeFromEvent("processing", "MediaStatusChanged", json.RawMessage(`{}`))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = TranscodeFromEvent("processing", "MediaCreated", json.RawMessage(`{"source":"s3://b/k"}`))
+	require.Error(t, err)
+}
+
+func TestTranscodeFromEvent_EnqueuedAndHandled(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	handled := make(chan TranscodePayload, 2)
+	startWorker(t, WorkerConfig{
+		Store: s,
+		Handlers: map[string]Handler{
+			KindTranscode: func(_ context.Context, j Job) error {
+				var p TranscodePayload
+				if err := json.Unmarshal(j.Payload, &p); err != nil {
+					return err
+				}
+				handled <- p
+				return nil
+			},
+		},
+	})
+
+	m := &models.Media{ID: uuid.New(), Type: models.Video, Source: "s3://media/in/1.mp4", Status: models.UploadedStatus}
+	env, err := events.Default.Wrap(models.NewMediaCreated(m))
+	require.NoError(t, err)
+	job, ok, err := TranscodeFromEvent("processing", env.EventType, env.Payload)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	enqueued, err := s.Enqueue(ctx, job)
+	require.NoError(t, err)
+	done := waitJob(t, s, enqueued.ID, func(j Job) bool { return j.CompletedAt != nil })
+	require.Equal(t, m.ID.String(), done.UniqueKey)
+	require.Equal(t, TranscodePayload{MediaID: m.ID.String(), Source: m.Source}, <-handled)
+	require.Empty(t, handled)
+}
+
+func TestTranscodeFromEvent_RedeliveryReturnsPendingJob(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	payload := json.RawMessage(`{"media_id":"` + uuid.NewString() + `","source":"s3://b/k"}`)
+
+	job, _, err := TranscodeFromEvent("processing", "MediaCreated", payload)
+	require.NoError(t, err)
+	first, err := s.Enqueue(ctx, job)
+	require.NoError(t, err)
+	again, err := s.Enqueue(ctx, job)
+	require.NoError(t, err)
+	require.Equal(t, first.ID, again.ID)
+}
@@ -0,0 +1,363 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// Handler выполняет задачу. Ошибка — попытка неудачна, задача повторится с backoff;
+// Reschedule — задача откладывается без траты попытки. Контекст отменяется при остановке
+// worker'а и при потере аренды: результат такой попытки уже не будет записан.
+type Handler func(ctx context.Context, job Job) error
+
+// WorkerConfig содержит конфигурацию Worker
+type WorkerConfig struct {
+	Store    Store
+	Queue    string
+	Handlers map[string]Handler // обработчики по Job.Kind
+	// Concurrency — задач, выполняемых одновременно (default: 4)
+	Concurrency int
+	// PollInterval — пауза между опросами пустой очереди (default: 1s)
+	PollInterval time.Duration
+	// Visibility — аренда захваченной задачи; пока обработчик работает, она продлевается
+	// каждые Visibility/2 (default: 5m)
+	Visibility time.Duration
+	// RetryBackoff — задержка повтора после первой неудачи, дальше удваивается (default: 10s)
+	RetryBackoff time.Duration
+	// MaxRetryBackoff — потолок задержки повтора (default: 30m)
+	MaxRetryBackoff time.Duration
+	Logger          zerolog.Logger
+}
+
+// WorkerMetrics содержит метрики worker'а
+type WorkerMetrics struct {
+	Completed   atomic.Int64
+	Retried     atomic.Int64 // Неудачные попытки, после которых задача повторится
+	Failed      atomic.Int64 // Задачи, исчерпавшие попытки
+	Rescheduled atomic.Int64
+	LeasesLost  atomic.Int64
+	Running     atomic.Int64
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *WorkerMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "jobs_completed_total",
+			Help: "Успешно выполненные задачи",
+		}, func() float64 { return float64(m.Completed.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "jobs_retried_total",
+			Help: "Неудачные попытки задач, отложенные для повтора",
+		}, func() float64 { return float64(m.Retried.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "jobs_failed_total",
+			Help: "Задачи, исчерпавшие попытки",
+		}, func() float64 { return float64(m.Failed.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "jobs_rescheduled_total",
+			Help: "Задачи, отложенные обработчиком без траты попытки",
+		}, func() float64 { return float64(m.Rescheduled.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "jobs_leases_lost_total",
+			Help: "Попытки, у которых истекла аренда до завершения",
+		}, func() float64 { return float64(m.LeasesLost.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "jobs_running",
+			Help: "Задачи, выполняемые сейчас",
+		}, func() float64 { return float64(m.Running.Load()) }),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Worker забирает задачи очереди и выполняет их обработчиками
+type Worker struct {
+	store           Store
+	queue           string
+	handlers        map[string]Handler
+	concurrency     atomic.Int64 // меняется на лету SetConcurrency
+	pollInterval    time.Duration
+	visibility      time.Duration
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	clock           func() time.Time
+	logger          zerolog.Logger
+	metrics         *WorkerMetrics
+
+	running sync.WaitGroup // Start и его задачи
+}
+
+func NewWorker(cfg WorkerConfig) (*Worker, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("store is required")
+	}
+	if cfg.Queue == "" {
+		return nil, errors.New("queue is required")
+	}
+	if len(cfg.Handlers) == 0 {
+		return nil, errors.New("at least one handler is required")
+	}
+	if cfg.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency cannot be negative, got: %d", cfg.Concurrency)
+	}
+	if cfg.PollInterval < 0 || cfg.Visibility < 0 || cfg.RetryBackoff < 0 || cfg.MaxRetryBackoff < 0 {
+		return nil, errors.New("worker durations cannot be negative")
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Visibility == 0 {
+		cfg.Visibility = 5 * time.Minute
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 10 * time.Second
+	}
+	if cfg.MaxRetryBackoff == 0 {
+		cfg.MaxRetryBackoff = 30 * time.Minute
+	}
+
+	w := &Worker{
+		store:           cfg.Store,
+		queue:           cfg.Queue,
+		handlers:        cfg.Handlers,
+		pollInterval:    cfg.PollInterval,
+		visibility:      cfg.Visibility,
+		retryBackoff:    cfg.RetryBackoff,
+		maxRetryBackoff: cfg.MaxRetryBackoff,
+		clock:           time.Now,
+		logger:          cfg.Logger.With().Str("component", "jobs_worker").Str("queue", cfg.Queue).Logger(),
+		metrics:         &WorkerMetrics{},
+	}
+	w.concurrency.Store(int64(cfg.Concurrency))
+	return w, nil
+}
+
+// Metrics возвращает метрики worker'а
+func (w *Worker) Metrics() *WorkerMetrics { return w.metrics }
+
+// SetConcurrency меняет число одновременно выполняемых задач на лету. При уменьшении
+// выполняемые задачи дорабатывают, новые не забираются, пока их не станет меньше n;
+// при увеличении новые слоты занимаются в пределах PollInterval.
+func (w *Worker) SetConcurrency(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("concurrency must be positive, got: %d", n)
+	}
+	w.concurrency.Store(int64(n))
+	return nil
+}
+
+// releaseTimeout — сколько даётся на запись результата попытки, в том числе при остановке
+const releaseTimeout = 5 * time.Second
+
+// Start забирает задачи, пока есть свободные слоты, до отмены контекста. При остановке
+// обработчики отменяются, а их задачи возвращаются в очередь без траты попытки; задачи
+// worker'а, упавшего без остановки, вернутся по истечении аренды.
+func (w *Worker) Start(ctx context.Context) error {
+	w.running.Add(1)
+	defer w.running.Done()
+	w.logger.Info().Int64("concurrency", w.concurrency.Load()).Dur("visibility", w.visibility).Msg("jobs worker started")
+
+	var busy atomic.Int64              // выполняемые задачи
+	released := make(chan struct{}, 1) // задача завершилась, слот освободился
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		free := int(w.concurrency.Load() - busy.Load())
+		if free <= 0 {
+			select {
+			case <-ctx.Done():
+				w.logger.Info().Msg("jobs worker stopped")
+				return ctx.Err()
+			case <-released:
+			case <-time.After(w.pollInterval): // SetConcurrency мог добавить слоты
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("jobs worker stopped")
+			return ctx.Err()
+		default:
+		}
+
+		claimed, err := w.store.Claim(ctx, w.queue, free, w.visibility)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error().Err(err).Msg("claim jobs failed")
+		}
+		for _, job := range claimed {
+			busy.Add(1)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					busy.Add(-1)
+					select {
+					case released <- struct{}{}:
+					default:
+					}
+				}()
+				w.run(ctx, job)
+			}()
+		}
+		// Очередь отдала меньше, чем просили — готовых задач больше нет
+		if len(claimed) < free {
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.pollInterval):
+			}
+		}
+	}
+}
+
+// Wait дожидается, пока остановленный worker запишет результаты задач, или отмены ctx.
+// Хранилище можно закрывать после Wait.
+func (w *Worker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run выполняет одну задачу и записывает результат попытки
+func (w *Worker) run(ctx context.Context, job Job) {
+	w.metrics.Running.Add(1)
+	defer w.metrics.Running.Add(-1)
+	logger := w.logger.With().Int64("job_id", job.ID).Str("kind", job.Kind).Int("attempt", job.Attempts).Logger()
+
+	handler, ok := w.handlers[job.Kind]
+	if !ok {
+		storeCtx, storeCancel := storeContext(ctx)
+		defer storeCancel()
+		w.record(logger, w.store.Fail(storeCtx, job.ID, job.Attempts, "no handler for kind "+job.Kind))
+		w.metrics.Failed.Add(1)
+		logger.Error().Msg("no handler for job kind")
+		return
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stopHeartbeat := w.heartbeat(jobCtx, cancel, job)
+	err := invoke(jobCtx, handler, job)
+	stopHeartbeat()
+
+	if errors.Is(context.Cause(jobCtx), ErrLeaseLost) {
+		w.metrics.LeasesLost.Add(1)
+		logger.Warn().Err(err).Msg("job lease lost, result discarded")
+		return
+	}
+	storeCtx, storeCancel := storeContext(ctx)
+	defer storeCancel()
+
+	var reschedule *RescheduleError
+	switch {
+	case err == nil:
+		w.record(logger, w.store.Complete(storeCtx, job.ID, job.Attempts))
+		w.metrics.Completed.Add(1)
+	case ctx.Err() != nil:
+		w.record(logger, w.store.Release(storeCtx, job.ID, job.Attempts, w.clock()))
+		logger.Info().Msg("job released on shutdown")
+	case errors.As(err, &reschedule):
+		w.record(logger, w.store.Release(storeCtx, job.ID, job.Attempts, w.clock().Add(reschedule.After)))
+		w.metrics.Rescheduled.Add(1)
+		logger.Info().Dur("after", reschedule.After).Str("reason", reschedule.Reason).Msg("job rescheduled")
+	case job.Attempts >= job.MaxAttempts:
+		w.record(logger, w.store.Fail(storeCtx, job.ID, job.Attempts, err.Error()))
+		w.metrics.Failed.Add(1)
+		logger.Error().Err(err).Msg("job failed, attempts exhausted")
+	default:
+		delay := w.retryDelay(job.Attempts)
+		w.record(logger, w.store.Retry(storeCtx, job.ID, job.Attempts, w.clock().Add(delay), err.Error()))
+		w.metrics.Retried.Add(1)
+		logger.Warn().Err(err).Dur("retry_in", delay).Msg("job attempt failed")
+	}
+}
+
+// storeContext — контекст записи результата попытки. Запись не должна прерываться остановкой:
+// иначе выполненная задача повторится.
+func storeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+}
+
+// record логирует ошибку записи результата: задача вернётся в очередь по истечении аренды
+func (w *Worker) record(logger zerolog.Logger, err error) {
+	if errors.Is(err, ErrLeaseLost) {
+		w.metrics.LeasesLost.Add(1)
+		logger.Warn().Msg("job lease lost before result was recorded")
+	} else if err != nil {
+		logger.Error().Err(err).Msg("failed to record job result")
+	}
+}
+
+// heartbeat продлевает аренду, пока работает обработчик; потеряв аренду, отменяет его
+func (w *Worker) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, job Job) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(w.visibility / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := w.store.Extend(ctx, job.ID, job.Attempts, w.visibility)
+				if errors.Is(err, ErrLeaseLost) || errors.Is(err, ErrNotFound) {
+					cancel(ErrLeaseLost)
+					return
+				}
+				if err != nil && ctx.Err() == nil {
+					w.logger.Warn().Err(err).Int64("job_id", job.ID).Msg("extend job lease failed")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// invoke вызывает обработчик; panic считается неудачной попыткой
+func invoke(ctx context.Context, handler Handler, job Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("handler panic: %v\n%s", v, debug.Stack())
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// retryDelay — задержка перед попыткой attempt+1: RetryBackoff * 2^(attempt-1), не больше MaxRetryBackoff
+func (w *Worker) retryDelay(attempt int) time.Duration {
+	d := w.retryBackoff
+	for i := 1; i < attempt && d < w.maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, w.maxRetryBackoff)
+}
@@ -0,0 +1,237 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// startWorker запускает worker до конца теста
+func startWorker(t *testing.T, cfg WorkerConfig) *Worker {
+	t.Helper()
+	cfg.Queue = "processing"
+	cfg.PollInterval = 5 * time.Millisecond
+	cfg.Logger = zerolog.Nop()
+	w, err := NewWorker(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+	return w
+}
+
+// waitJob ждёт, пока задача не придёт в нужное состояние
+func waitJob(t *testing.T, s *MemoryStore, id int64, cond func(Job) bool) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = s.Get(context.Background(), id)
+		require.NoError(t, err)
+		return cond(job)
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestWorker_RetryThenFail(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	var calls atomic.Int32
+	w := startWorker(t, WorkerConfig{
+		Store:        s,
+		RetryBackoff: time.Millisecond,
+		Handlers: map[string]Handler{
+			"ok": func(context.Context, Job) error { return nil },
+			"flaky": func(_ context.Context, j Job) error {
+				calls.Add(1)
+				if j.Attempts == 2 {
+					panic("ffmpeg crashed")
+				}
+				return errors.New("transcoder unavailable")
+			},
+		},
+	})
+
+	ok, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "ok"})
+	require.NoError(t, err)
+	flaky, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "flaky", MaxAttempts: 3})
+	require.NoError(t, err)
+	unknown, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "thumbnail"})
+	require.NoError(t, err)
+
+	waitJob(t, s, ok.ID, func(j Job) bool { return j.CompletedAt != nil })
+	failed := waitJob(t, s, flaky.ID, func(j Job) bool { return j.FailedAt != nil })
+	require.Equal(t, 3, failed.Attempts)
+	require.Equal(t, int32(3), calls.Load())
+	require.Equal(t, "transcoder unavailable", failed.LastError)
+	gone := waitJob(t, s, unknown.ID, func(j Job) bool { return j.FailedAt != nil })
+	require.Contains(t, gone.LastError, "no handler")
+
+	require.Equal(t, int64(1), w.Metrics().Completed.Load())
+	require.Equal(t, int64(2), w.Metrics().Retried.Load())
+	require.Equal(t, int64(2), w.Metrics().Failed.Load())
+}
+
+func TestWorker_Reschedule(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	var calls atomic.Int32
+	w := startWorker(t, WorkerConfig{
+		Store: s,
+		Handlers: map[string]Handler{
+			"transcode": func(context.Context, Job) error {
+				if calls.Add(1) < 3 {
+					return Reschedule(time.Millisecond, "encoder busy")
+				}
+				return nil
+			},
+		},
+	})
+
+	job, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode", MaxAttempts: 1})
+	require.NoError(t, err)
+	done := waitJob(t, s, job.ID, func(j Job) bool { return j.CompletedAt != nil })
+	// Отложенные попытки не тратят MaxAttempts
+	require.Equal(t, 1, done.Attempts)
+	require.Equal(t, int64(2), w.Metrics().Rescheduled.Load())
+}
+
+func TestWorker_HeartbeatAndLeaseLoss(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	release := make(chan struct{})
+	started := make(chan Job, 1)
+	var cancelled atomic.Bool
+	w := startWorker(t, WorkerConfig{
+		Store:      s,
+		Visibility: 40 * time.Millisecond,
+		Handlers: map[string]Handler{
+			"transcode": func(ctx context.Context, j Job) error {
+				started <- j
+				select {
+				case <-release:
+					return nil
+				case <-ctx.Done():
+					cancelled.Store(true)
+					return ctx.Err()
+				}
+			},
+		},
+	})
+
+	job, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode"})
+	require.NoError(t, err)
+	first := <-started
+
+	// Аренда продлевается, пока обработчик работает: задача не выдаётся повторно
+	time.Sleep(150 * time.Millisecond)
+	claimed, err := s.Claim(ctx, "processing", 1, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	// Забираем аренду себе (как будто она истекла во время паузы): worker отменяет обработчик
+	s.mu.Lock()
+	expired := time.Now().Add(-time.Second)
+	s.jobs[job.ID].LockedUntil = &expired
+	s.mu.Unlock()
+	claimed, err = s.Claim(ctx, "processing", 1, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Eventually(t, cancelled.Load, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return w.Metrics().LeasesLost.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	// Результат чужой попытки не записан
+	got, err := s.Get(ctx, job.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.CompletedAt)
+	require.Equal(t, first.Attempts+1, got.Attempts)
+	close(release)
+}
+
+func TestWorker_ReleaseOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	started := make(chan struct{})
+	w, err := NewWorker(WorkerConfig{
+		Store:  s,
+		Queue:  "processing",
+		Logger: zerolog.Nop(),
+		Handlers: map[string]Handler{
+			"transcode": func(ctx context.Context, _ Job) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	})
+	require.NoError(t, err)
+	job, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode"})
+	require.NoError(t, err)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- w.Start(runCtx) }()
+	<-started
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// Задача сразу доступна другим worker'ам, попытка не потрачена
+	got, err := s.Get(ctx, job.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.LockedUntil)
+	require.Equal(t, 0, got.Attempts)
+}
+
+func TestWorker_SetConcurrency(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	var running atomic.Int32
+	release := make(chan struct{})
+	w := startWorker(t, WorkerConfig{
+		Store:       s,
+		Concurrency: 1,
+		Handlers: map[string]Handler{
+			"transcode": func(context.Context, Job) error {
+				running.Add(1)
+				<-release
+				return nil
+			},
+		},
+	})
+	defer close(release)
+
+	for range 3 {
+		_, err := s.Enqueue(ctx, Job{Queue: "processing", Kind: "transcode"})
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return running.Load() == 1 }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return running.Load() > 1 }, 30*time.Millisecond, time.Millisecond)
+
+	require.Error(t, w.SetConcurrency(0))
+	require.NoError(t, w.SetConcurrency(3))
+	require.Eventually(t, func() bool { return running.Load() == 3 }, time.Second, time.Millisecond)
+}
+
+func TestNewWorker_Validation(t *testing.T) {
+	handlers := map[string]Handler{"k": func(context.Context, Job) error { return nil }}
+	for name, cfg := range map[string]WorkerConfig{
+		"no store":         {Queue: "q", Handlers: handlers},
+		"no queue":         {Store: NewMemoryStore(), Handlers: handlers},
+		"no handlers":      {Store: NewMemoryStore(), Queue: "q"},
+		"negative workers": {Store: NewMemoryStore(), Queue: "q", Handlers: handlers, Concurrency: -1},
+		"negative lease":   {Store: NewMemoryStore(), Queue: "q", Handlers: handlers, Visibility: -time.Second},
+	} {
+		_, err := NewWorker(cfg)
+		require.Error(t, err, name)
+	}
+}
@@ -0,0 +1,132 @@
+package packaging
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Элементы MPD (ISO/IEC 23009-1), которые пишет DASH; сегменты — отдельные файлы (профиль live)
+type mpd struct {
+	XMLName                   xml.Name `xml:"urn:mpeg:dash:schema:mpd:2011 MPD"`
+	Profiles                  string   `xml:"profiles,attr"`
+	Type                      string   `xml:"type,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             string   `xml:"minBufferTime,attr"`
+	Period                    period   `xml:"Period"`
+}
+
+type period struct {
+	AdaptationSets []adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	MimeType         string           `xml:"mimeType,attr"`
+	ContentType      string           `xml:"contentType,attr"`
+	SegmentAlignment bool             `xml:"segmentAlignment,attr"`
+	Representations  []representation `xml:"Representation"`
+}
+
+type representation struct {
+	ID          string      `xml:"id,attr"`
+	Bandwidth   int         `xml:"bandwidth,attr"`
+	Width       int         `xml:"width,attr,omitempty"`
+	Height      int         `xml:"height,attr,omitempty"`
+	FrameRate   string      `xml:"frameRate,attr,omitempty"`
+	Codecs      string      `xml:"codecs,attr,omitempty"`
+	BaseURL     string      `xml:"BaseURL"`
+	SegmentList segmentList `xml:"SegmentList"`
+}
+
+type segmentList struct {
+	Timescale       int             `xml:"timescale,attr"`
+	Initialization  initialization  `xml:"Initialization"`
+	SegmentTimeline segmentTimeline `xml:"SegmentTimeline"`
+	SegmentURLs     []segmentURL    `xml:"SegmentURL"`
+}
+
+type initialization struct {
+	SourceURL string `xml:"sourceURL,attr"`
+}
+
+type segmentTimeline struct {
+	S []timelineEntry `xml:"S"`
+}
+
+type timelineEntry struct {
+	D int64 `xml:"d,attr"`
+}
+
+type segmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// dashTimescale — единицы SegmentTimeline: миллисекунды
+const dashTimescale = 1000
+
+// DASH — статический MPD: видео и аудио renditions в отдельных AdaptationSet,
+// сегменты перечислены явно (SegmentList), так что длительности могут различаться
+func DASH(renditions []Rendition) ([]byte, error) {
+	var (
+		video    = adaptationSet{MimeType: "video/mp4", ContentType: "video", SegmentAlignment: true}
+		audio    = adaptationSet{MimeType: "audio/mp4", ContentType: "audio", SegmentAlignment: true}
+		duration time.Duration
+	)
+	for _, r := range renditions {
+		if r.Init == "" {
+			return nil, fmt.Errorf("rendition %s: DASH requires fMP4 segments with an init segment", r.Name)
+		}
+		rep := representation{
+			ID:        r.Name,
+			Bandwidth: r.Bandwidth,
+			Width:     r.Width,
+			Height:    r.Height,
+			Codecs:    r.Codecs,
+			BaseURL:   r.Name + "/",
+			SegmentList: segmentList{
+				Timescale:      dashTimescale,
+				Initialization: initialization{SourceURL: filepath.Base(r.Init)},
+			},
+		}
+		if r.FrameRate > 0 {
+			rep.FrameRate = fmt.Sprintf("%g", r.FrameRate)
+		}
+		var total time.Duration
+		for _, s := range r.Segments {
+			rep.SegmentList.SegmentTimeline.S = append(rep.SegmentList.SegmentTimeline.S, timelineEntry{D: s.Duration.Milliseconds()})
+			rep.SegmentList.SegmentURLs = append(rep.SegmentList.SegmentURLs, segmentURL{Media: filepath.Base(s.Path)})
+			total += s.Duration
+		}
+		duration = max(duration, total)
+
+		if r.Audio() {
+			audio.Representations = append(audio.Representations, rep)
+		} else {
+			video.Representations = append(video.Representations, rep)
+		}
+	}
+
+	doc := mpd{
+		Profiles:                  "urn:mpeg:dash:profile:isoff-live:2011",
+		Type:                      "static",
+		MediaPresentationDuration: isoDuration(duration),
+		MinBufferTime:             "PT2S",
+	}
+	for _, set := range []adaptationSet{video, audio} {
+		if len(set.Representations) > 0 {
+			doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, set)
+		}
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal mpd: %w", err)
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// isoDuration — длительность в формате xs:duration: PT1M5.250S
+func isoDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%.3fS", d.Seconds())
+}
@@ -0,0 +1,378 @@
+// Package packaging — упаковка результатов транскодирования для адаптивного стриминга.
+// Транскодер нарезает каждую rendition на сегменты (fMP4/CMAF — общие для HLS и DASH,
+// или MPEG-TS только для HLS) в локальный каталог; Packager выгружает сегменты в объектное
+// хранилище и пишет рядом манифесты: HLS master и media плейлисты и, опционально, DASH MPD.
+// Publish/CDN отдают их напрямую, без участия media.
+package packaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Имена манифестов в каталоге медиа
+const (
+	MasterPlaylist = "master.m3u8"
+	MediaPlaylist  = "index.m3u8"
+	DASHManifest   = "manifest.mpd"
+)
+
+// Sink — объектное хранилище результатов; реализуется *blob.S3Store
+type Sink interface {
+	Put(ctx context.Context, source string, body io.Reader, size int64, contentType string) error
+}
+
+// Segment — сегмент rendition, нарезанный транскодером
+type Segment struct {
+	Path     string        // локальный файл
+	Duration time.Duration // длительность сегмента
+}
+
+// Rendition — один вариант качества после транскодирования
+type Rendition struct {
+	Name             string  // каталог rendition в хранилище: 1080p, audio_128k
+	Bandwidth        int     // пиковый битрейт, бит/с
+	AverageBandwidth int     // средний битрейт, бит/с; 0 — не указывается
+	Width, Height    int     // 0 — аудио rendition
+	FrameRate        float64 // 0 — не указывается
+	Codecs           string  // RFC 6381: avc1.640028,mp4a.40.2
+	Init             string  // локальный init сегмент fMP4; пустой — сегменты MPEG-TS
+	Segments         []Segment
+}
+
+// Audio сообщает, что rendition без видео
+func (r Rendition) Audio() bool { return r.Width == 0 && r.Height == 0 }
+
+// Имена, под которыми манифесты регистрируются среди renditions медиа
+const (
+	MasterRendition = "master"
+	DASHRendition   = "dash"
+)
+
+// Result — где лежат манифесты упакованного медиа
+type Result struct {
+	Master   string        // HLS master плейлист: s3://bucket/prefix/{media_id}/master.m3u8
+	DASH     string        // DASH MPD; пустой — DASH выключен
+	Duration time.Duration // длительность самой длинной rendition
+	// Renditions — записи для Registry: master плейлист, MPD и media плейлист каждой rendition
+	Renditions []Stored
+}
+
+// Stored — выгруженная rendition или манифест медиа. У манифестов (MasterRendition,
+// DASHRendition) Bandwidth — максимальный среди renditions, размеры — пустые.
+type Stored struct {
+	MediaID   uuid.UUID `db:"media_id"`
+	Name      string    `db:"name"`
+	URI       string    `db:"uri"` // плейлист или манифест в хранилище
+	Bandwidth int       `db:"bandwidth"`
+	Width     int       `db:"width"`
+	Height    int       `db:"height"`
+	Codecs    string    `db:"codecs"`
+}
+
+// Registry хранит renditions медиа; реализуется *postgres.RenditionsRepo
+type Registry interface {
+	// ReplaceRenditions заменяет renditions медиа: после повторной упаковки не остаётся старых
+	ReplaceRenditions(ctx context.Context, mediaID uuid.UUID, renditions []Stored) error
+}
+
+// Config содержит конфигурацию Packager
+type Config struct {
+	Sink Sink
+	// Destination — корень результатов (s3://media-streaming/vod); медиа пишется
+	// в Destination/{media_id}/
+	Destination string
+	DASH        bool // кроме HLS писать DASH MPD; требует fMP4 сегментов
+	// Registry получает renditions упакованного медиа после записи манифестов; nil — не регистрировать
+	Registry Registry
+	Logger   zerolog.Logger
+}
+
+// Packager выгружает сегменты и пишет манифесты
+type Packager struct {
+	sink        Sink
+	destination string
+	dash        bool
+	registry    Registry
+	logger      zerolog.Logger
+}
+
+func NewPackager(cfg Config) (*Packager, error) {
+	if cfg.Sink == nil {
+		return nil, errors.New("sink is required")
+	}
+	if !strings.Contains(cfg.Destination, "://") {
+		return nil, fmt.Errorf("destination must be a storage URL, got: %q", cfg.Destination)
+	}
+	return &Packager{
+		sink:        cfg.Sink,
+		destination: strings.TrimSuffix(cfg.Destination, "/"),
+		dash:        cfg.DASH,
+		registry:    cfg.Registry,
+		logger:      cfg.Logger.With().Str("component", "packaging").Logger(),
+	}, nil
+}
+
+var (
+	// renditionName — имя rendition становится каталогом в хранилище и URI в манифестах
+	renditionName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+	// fileName — имя файла сегмента попадает в манифест как есть, без экранирования
+	fileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+)
+
+// Package выгружает сегменты всех renditions и пишет манифесты. Media плейлисты и сегменты
+// пишутся до master плейлиста и MPD: клиент, увидевший манифест, найдёт всё, на что он ссылается.
+// Повтор после сбоя перезаписывает те же объекты. Последними renditions регистрируются
+// в Registry: зарегистрированный master плейлист уже доступен в хранилище.
+func (p *Packager) Package(ctx context.Context, mediaID uuid.UUID, renditions []Rendition) (Result, error) {
+	if err := p.validate(renditions); err != nil {
+		return Result{}, err
+	}
+	root := p.destination + "/" + mediaID.String()
+
+	var duration time.Duration
+	for _, r := range renditions {
+		d, err := p.uploadRendition(ctx, root, r)
+		if err != nil {
+			return Result{}, fmt.Errorf("rendition %s: %w", r.Name, err)
+		}
+		duration = max(duration, d)
+	}
+
+	result := Result{Master: root + "/" + MasterPlaylist, Duration: duration}
+	if p.dash {
+		mpd, err := DASH(renditions)
+		if err != nil {
+			return Result{}, err
+		}
+		result.DASH = root + "/" + DASHManifest
+		if err := p.put(ctx, result.DASH, mpd); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := p.put(ctx, result.Master, HLSMaster(renditions)); err != nil {
+		return Result{}, err
+	}
+
+	result.Renditions = stored(mediaID, root, result, renditions)
+	if p.registry != nil {
+		if err := p.registry.ReplaceRenditions(ctx, mediaID, result.Renditions); err != nil {
+			return Result{}, fmt.Errorf("register renditions: %w", err)
+		}
+	}
+
+	p.logger.Info().
+		Str("media_id", mediaID.String()).
+		Int("renditions", len(renditions)).
+		Dur("duration", duration).
+		Str("master", result.Master).
+		Msg("media packaged")
+	return result, nil
+}
+
+// stored — записи Registry: сначала манифесты, затем renditions в порядке master плейлиста
+func stored(mediaID uuid.UUID, root string, result Result, renditions []Rendition) []Stored {
+	var bandwidth int
+	for _, r := range renditions {
+		bandwidth = max(bandwidth, r.Bandwidth)
+	}
+	out := []Stored{{MediaID: mediaID, Name: MasterRendition, URI: result.Master, Bandwidth: bandwidth}}
+	if result.DASH != "" {
+		out = append(out, Stored{MediaID: mediaID, Name: DASHRendition, URI: result.DASH, Bandwidth: bandwidth})
+	}
+	for _, r := range renditions {
+		out = append(out, Stored{
+			MediaID:   mediaID,
+			Name:      r.Name,
+			URI:       root + "/" + r.Name + "/" + MediaPlaylist,
+			Bandwidth: r.Bandwidth,
+			Width:     r.Width,
+			Height:    r.Height,
+			Codecs:    r.Codecs,
+		})
+	}
+	return out
+}
+
+func (p *Packager) validate(renditions []Rendition) error {
+	if len(renditions) == 0 {
+		return errors.New("no renditions to package")
+	}
+	seen := make(map[string]bool, len(renditions))
+	for _, r := range renditions {
+		switch {
+		case !renditionName.MatchString(r.Name):
+			return fmt.Errorf("invalid rendition name %q", r.Name)
+		case r.Name == MasterRendition || r.Name == DASHRendition:
+			return fmt.Errorf("rendition name %q is reserved for manifests", r.Name)
+		case seen[r.Name]:
+			return fmt.Errorf("duplicate rendition %q", r.Name)
+		case r.Bandwidth <= 0:
+			return fmt.Errorf("rendition %s: bandwidth is required", r.Name)
+		case len(r.Segments) == 0:
+			return fmt.Errorf("rendition %s: no segments", r.Name)
+		case p.dash && r.Init == "":
+			return fmt.Errorf("rendition %s: DASH requires fMP4 segments with an init segment", r.Name)
+		}
+		files := make(map[string]bool, len(r.Segments)+1)
+		for _, file := range append([]string{r.Init}, segmentPaths(r.Segments)...) {
+			if file == "" {
+				continue
+			}
+			name := filepath.Base(file)
+			if !fileName.MatchString(name) || files[name] {
+				return fmt.Errorf("rendition %s: invalid or duplicate segment file name %q", r.Name, name)
+			}
+			files[name] = true
+		}
+		for _, s := range r.Segments {
+			if s.Duration <= 0 {
+				return fmt.Errorf("rendition %s: segment %s has no duration", r.Name, s.Path)
+			}
+		}
+		seen[r.Name] = true
+	}
+	return nil
+}
+
+func segmentPaths(segments []Segment) []string {
+	paths := make([]string, len(segments))
+	for i, s := range segments {
+		paths[i] = s.Path
+	}
+	return paths
+}
+
+// uploadRendition выгружает init и сегменты, затем media плейлист; возвращает длительность
+func (p *Packager) uploadRendition(ctx context.Context, root string, r Rendition) (time.Duration, error) {
+	dir := root + "/" + r.Name
+	if r.Init != "" {
+		if err := p.upload(ctx, dir, r.Init); err != nil {
+			return 0, err
+		}
+	}
+	var duration time.Duration
+	for _, s := range r.Segments {
+		if err := p.upload(ctx, dir, s.Path); err != nil {
+			return 0, err
+		}
+		duration += s.Duration
+	}
+	return duration, p.put(ctx, dir+"/"+MediaPlaylist, HLSMedia(r))
+}
+
+// upload выгружает локальный файл в dir под его именем
+func (p *Packager) upload(ctx context.Context, dir, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(file)
+	return p.sink.Put(ctx, dir+"/"+name, f, info.Size(), contentType(name))
+}
+
+func (p *Packager) put(ctx context.Context, target string, data []byte) error {
+	return p.sink.Put(ctx, target, bytes.NewReader(data), int64(len(data)), contentType(target))
+}
+
+// contentType — MIME тип объекта по расширению; CDN отдаёт его клиенту как есть
+func contentType(name string) string {
+	switch path.Ext(name) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".mpd":
+		return "application/dash+xml"
+	case ".m4s":
+		return "video/iso.segment"
+	case ".ts":
+		return "video/mp2t"
+	case ".mp4":
+		return "video/mp4"
+	case ".m4a":
+		return "audio/mp4"
+	case ".aac":
+		return "audio/aac"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// HLSMaster — master плейлист: по EXT-X-STREAM-INF на rendition, в порядке renditions
+// (первая — стартовая для плеера)
+func HLSMaster(renditions []Rendition) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", hlsVersion(renditions))
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, r := range renditions {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d", r.Bandwidth)
+		if r.AverageBandwidth > 0 {
+			fmt.Fprintf(&b, ",AVERAGE-BANDWIDTH=%d", r.AverageBandwidth)
+		}
+		if !r.Audio() {
+			fmt.Fprintf(&b, ",RESOLUTION=%dx%d", r.Width, r.Height)
+		}
+		if r.FrameRate > 0 {
+			fmt.Fprintf(&b, ",FRAME-RATE=%.3f", r.FrameRate)
+		}
+		if r.Codecs != "" {
+			fmt.Fprintf(&b, ",CODECS=%q", r.Codecs)
+		}
+		b.WriteString("\n" + r.Name + "/" + MediaPlaylist + "\n")
+	}
+	return b.Bytes()
+}
+
+// HLSMedia — VOD плейлист одной rendition; URI сегментов относительные
+func HLSMedia(r Rendition) []byte {
+	var target time.Duration
+	for _, s := range r.Segments {
+		target = max(target, s.Duration)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", hlsVersion([]Rendition{r}))
+	// TARGETDURATION — округлённая вверх длительность самого длинного сегмента (RFC 8216 4.3.3.1)
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target.Seconds())))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	if r.Init != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=%q\n", filepath.Base(r.Init))
+	}
+	for _, s := range r.Segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", s.Duration.Seconds(), filepath.Base(s.Path))
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.Bytes()
+}
+
+// hlsVersion — минимальная версия протокола (RFC 8216, 7): EXT-X-MAP в обычных плейлистах
+// требует 6, дробные EXTINF — 3
+func hlsVersion(renditions []Rendition) int {
+	for _, r := range renditions {
+		if r.Init != "" {
+			return 6
+		}
+	}
+	return 3
+}
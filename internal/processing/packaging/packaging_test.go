@@ -0,0 +1,252 @@
+package packaging
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink запоминает объекты и порядок записи
+type memorySink struct {
+	objects map[string]string
+	types   map[string]string
+	order   []string
+	fail    string // объект, запись которого падает
+}
+
+func newMemorySink() *memorySink {
+	return &memorySink{objects: map[string]string{}, types: map[string]string{}}
+}
+
+func (s *memorySink) Put(_ context.Context, target string, body io.Reader, size int64, contentType string) error {
+	if target == s.fail {
+		return errors.New("storage unavailable")
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return io.ErrUnexpectedEOF
+	}
+	s.objects[target] = string(data)
+	s.types[target] = contentType
+	s.order = append(s.order, target)
+	return nil
+}
+
+// transcoded раскладывает файлы rendition в dir, как их оставил бы транскодер
+func transcoded(t *testing.T, dir, name string, width, height, bandwidth int, durations ...time.Duration) Rendition {
+	t.Helper()
+	sub := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+	r := Rendition{
+		Name:      name,
+		Bandwidth: bandwidth,
+		Width:     width,
+		Height:    height,
+		Codecs:    "avc1.640028,mp4a.40.2",
+		Init:      filepath.Join(sub, "init.mp4"),
+	}
+	if width == 0 {
+		r.Codecs = "mp4a.40.2"
+	}
+	require.NoError(t, os.WriteFile(r.Init, []byte("init-"+name), 0o644))
+	for i, d := range durations {
+		file := filepath.Join(sub, "seg_"+string(rune('1'+i))+".m4s")
+		require.NoError(t, os.WriteFile(file, []byte("segment"), 0o644))
+		r.Segments = append(r.Segments, Segment{Path: file, Duration: d})
+	}
+	return r
+}
+
+func TestPackager_HLSAndDASH(t *testing.T) {
+	dir := t.TempDir()
+	renditions := []Rendition{
+		transcoded(t, dir, "1080p", 1920, 1080, 5_000_000, 6*time.Second, 6*time.Second, 2500*time.Millisecond),
+		transcoded(t, dir, "480p", 854, 480, 1_200_000, 6*time.Second, 6*time.Second, 2500*time.Millisecond),
+		transcoded(t, dir, "audio_128k", 0, 0, 128_000, 6*time.Second, 6*time.Second, 2500*time.Millisecond),
+	}
+	renditions[0].AverageBandwidth = 4_500_000
+	renditions[0].FrameRate = 30
+
+	sink := newMemorySink()
+	p, err := NewPackager(Config{Sink: sink, Destination: "s3://streaming/vod/", DASH: true, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	id := uuid.New()
+	root := "s3://streaming/vod/" + id.String()
+
+	res, err := p.Package(context.Background(), id, renditions)
+	require.NoError(t, err)
+	require.Equal(t, root+"/master.m3u8", res.Master)
+	require.Equal(t, root+"/manifest.mpd", res.DASH)
+	require.Equal(t, 14500*time.Millisecond, res.Duration)
+
+	require.Equal(t, "init-1080p", sink.objects[root+"/1080p/init.mp4"])
+	require.Equal(t, "segment", sink.objects[root+"/480p/seg_3.m4s"])
+	require.Equal(t, "video/iso.segment", sink.types[root+"/480p/seg_3.m4s"])
+	require.Equal(t, "application/vnd.apple.mpegurl", sink.types[res.Master])
+	require.Equal(t, "application/dash+xml", sink.types[res.DASH])
+	// Master плейлист пишется последним: всё, на что он ссылается, уже в хранилище
+	require.Equal(t, res.Master, sink.order[len(sink.order)-1])
+
+	require.Equal(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,AVERAGE-BANDWIDTH=4500000,RESOLUTION=1920x1080,FRAME-RATE=30.000,CODECS="avc1.640028,mp4a.40.2"
+1080p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1200000,RESOLUTION=854x480,CODECS="avc1.640028,mp4a.40.2"
+480p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=128000,CODECS="mp4a.40.2"
+audio_128k/index.m3u8
+`, sink.objects[res.Master])
+
+	require.Equal(t, `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:6.000,
+seg_1.m4s
+#EXTINF:6.000,
+seg_2.m4s
+#EXTINF:2.500,
+seg_3.m4s
+#EXT-X-ENDLIST
+`, sink.objects[root+"/480p/index.m3u8"])
+
+	var doc mpd
+	require.NoError(t, xml.Unmarshal([]byte(sink.objects[res.DASH]), &doc))
+	require.Equal(t, "static", doc.Type)
+	require.Equal(t, "PT14.500S", doc.MediaPresentationDuration)
+	require.Len(t, doc.Period.AdaptationSets, 2)
+	video, audio := doc.Period.AdaptationSets[0], doc.Period.AdaptationSets[1]
+	require.Equal(t, "video/mp4", video.MimeType)
+	require.Len(t, video.Representations, 2)
+	require.Equal(t, "1080p/", video.Representations[0].BaseURL)
+	require.Equal(t, "30", video.Representations[0].FrameRate)
+	require.Equal(t, "init.mp4", video.Representations[0].SegmentList.Initialization.SourceURL)
+	require.Equal(t, []timelineEntry{{6000}, {6000}, {2500}}, video.Representations[0].SegmentList.SegmentTimeline.S)
+	require.Equal(t, "seg_3.m4s", video.Representations[0].SegmentList.SegmentURLs[2].Media)
+	require.Equal(t, "audio/mp4", audio.MimeType)
+	require.Equal(t, "audio_128k", audio.Representations[0].ID)
+}
+
+func TestPackager_TSSegmentsHLSOnly(t *testing.T) {
+	dir := t.TempDir()
+	r := transcoded(t, dir, "720p", 1280, 720, 3_000_000, 4*time.Second, 3*time.Second)
+	r.Init = ""
+
+	sink := newMemorySink()
+	p, err := NewPackager(Config{Sink: sink, Destination: "s3://streaming/vod"})
+	require.NoError(t, err)
+	res, err := p.Package(context.Background(), uuid.New(), []Rendition{r})
+	require.NoError(t, err)
+	require.Empty(t, res.DASH)
+
+	media := sink.objects[strings.TrimSuffix(res.Master, MasterPlaylist)+"720p/index.m3u8"]
+	require.Contains(t, media, "#EXT-X-VERSION:3\n")
+	require.Contains(t, media, "#EXT-X-TARGETDURATION:4\n")
+	require.NotContains(t, media, "EXT-X-MAP")
+
+	// DASH без init сегмента невозможен
+	p, err = NewPackager(Config{Sink: sink, Destination: "s3://streaming/vod", DASH: true})
+	require.NoError(t, err)
+	_, err = p.Package(context.Background(), uuid.New(), []Rendition{r})
+	require.ErrorContains(t, err, "DASH requires fMP4")
+}
+
+// memoryRegistry запоминает зарегистрированные renditions
+type memoryRegistry struct {
+	renditions map[uuid.UUID][]Stored
+	fail       error
+}
+
+func (r *memoryRegistry) ReplaceRenditions(_ context.Context, mediaID uuid.UUID, renditions []Stored) error {
+	if r.fail != nil {
+		return r.fail
+	}
+	r.renditions[mediaID] = renditions
+	return nil
+}
+
+func TestPackager_RegistersRenditions(t *testing.T) {
+	dir := t.TempDir()
+	renditions := []Rendition{
+		transcoded(t, dir, "1080p", 1920, 1080, 5_000_000, 6*time.Second),
+		transcoded(t, dir, "audio_128k", 0, 0, 128_000, 6*time.Second),
+	}
+	registry := &memoryRegistry{renditions: map[uuid.UUID][]Stored{}}
+	p, err := NewPackager(Config{Sink: newMemorySink(), Destination: "s3://streaming/vod", DASH: true, Registry: registry})
+	require.NoError(t, err)
+	id := uuid.New()
+	root := "s3://streaming/vod/" + id.String()
+
+	res, err := p.Package(context.Background(), id, renditions)
+	require.NoError(t, err)
+	// Master плейлист — тоже rendition: по нему плеер находит остальные
+	want := []Stored{
+		{MediaID: id, Name: MasterRendition, URI: root + "/master.m3u8", Bandwidth: 5_000_000},
+		{MediaID: id, Name: DASHRendition, URI: root + "/manifest.mpd", Bandwidth: 5_000_000},
+		{MediaID: id, Name: "1080p", URI: root + "/1080p/index.m3u8", Bandwidth: 5_000_000, Width: 1920, Height: 1080, Codecs: "avc1.640028,mp4a.40.2"},
+		{MediaID: id, Name: "audio_128k", URI: root + "/audio_128k/index.m3u8", Bandwidth: 128_000, Codecs: "mp4a.40.2"},
+	}
+	require.Equal(t, want, res.Renditions)
+	require.Equal(t, want, registry.renditions[id])
+
+	// Не удалось зарегистрировать — упаковка не завершена, задача повторится
+	registry.fail = errors.New("db unavailable")
+	_, err = p.Package(context.Background(), uuid.New(), renditions)
+	require.ErrorContains(t, err, "register renditions")
+}
+
+func TestPackager_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ok := transcoded(t, dir, "720p", 1280, 720, 3_000_000, 4*time.Second)
+	sink := newMemorySink()
+	p, err := NewPackager(Config{Sink: sink, Destination: "s3://streaming/vod"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	bad := map[string][]Rendition{
+		"no renditions":  nil,
+		"path in name":   {{Name: "../x", Bandwidth: 1, Segments: ok.Segments}},
+		"duplicate":      {ok, ok},
+		"no bandwidth":   {{Name: "a", Segments: ok.Segments}},
+		"no segments":    {{Name: "a", Bandwidth: 1}},
+		"zero duration":  {{Name: "a", Bandwidth: 1, Segments: []Segment{{Path: ok.Segments[0].Path}}}},
+		"quoted segment": {{Name: "a", Bandwidth: 1, Segments: []Segment{{Path: `/tmp/x".m4s`, Duration: time.Second}}}},
+		"reserved name":  {{Name: MasterRendition, Bandwidth: 1, Segments: ok.Segments}},
+	}
+	for name, renditions := range bad {
+		_, err := p.Package(ctx, uuid.New(), renditions)
+		require.Error(t, err, name)
+	}
+	require.Empty(t, sink.objects)
+
+	// Сбой записи master плейлиста — ошибка, повтор перезаписывает те же объекты
+	id := uuid.New()
+	sink.fail = "s3://streaming/vod/" + id.String() + "/" + MasterPlaylist
+	_, err = p.Package(ctx, id, []Rendition{ok})
+	require.ErrorContains(t, err, "storage unavailable")
+	sink.fail = ""
+	_, err = p.Package(ctx, id, []Rendition{ok})
+	require.NoError(t, err)
+
+	_, err = NewPackager(Config{Sink: sink, Destination: "/local/path"})
+	require.Error(t, err)
+	_, err = NewPackager(Config{Destination: "s3://streaming/vod"})
+	require.Error(t, err)
+}
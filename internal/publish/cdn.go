@@ -0,0 +1,89 @@
+// Package publish — раздача готовых медиа через CDN. Медиа отдаются из объектного хранилища
+// (манифесты и сегменты packaging) через CDN напрямую; publish следит, чтобы CDN не отдавал
+// устаревшее: по событиям media сбрасывает кэш изменённых и удалённых медиа.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// CDNInvalidator сбрасывает кэш CDN для объектов медиа; реализуется *CloudFront и *Fastly
+type CDNInvalidator interface {
+	// Invalidate сбрасывает кэш всех объектов медиа ids: манифестов и сегментов renditions
+	Invalidate(ctx context.Context, ids []uuid.UUID) error
+	// MaxBatch — сколько медиа принимает один вызов Invalidate
+	MaxBatch() int
+}
+
+// APIError — API CDN или канала уведомлений ответил ошибкой
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Code       string // код ошибки из тела ответа, если есть
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s api: %d %s: %s", e.Provider, e.StatusCode, http.StatusText(e.StatusCode), e.Code)
+	}
+	return fmt.Sprintf("%s api: %d %s", e.Provider, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Temporary — повтор может помочь: throttling или ошибка на стороне провайдера
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// retryable — стоит ли повторять запрос к CDN или каналу уведомлений после err. Ответы 4xx
+// (кроме 429) и постоянные ошибки SMTP (5xx) не изменятся от повтора: неверные credentials,
+// distribution или адрес.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+	return true
+}
+
+// MediaFromEvent возвращает медиа, кэш которого надо сбросить после события eventType.
+// ok=false — событие не меняет то, что отдаёт CDN. Готовность медиа (MediaStatusChanged → ready)
+// тоже сбрасывает кэш: после повторной обработки renditions и манифесты перезаписаны,
+// а до первой — CDN мог закэшировать 404. Уход из ready (в том числе под эмбарго, → scheduled)
+// и смена видимости (MediaVisibilityChanged) меняют то, кому медиа можно отдавать.
+func MediaFromEvent(eventType string, payload json.RawMessage) (id uuid.UUID, ok bool, err error) {
+	switch eventType {
+	case "MediaStatusChanged", "MediaContentRecorded", "MediaDeleted", "MediaArchived", "MediaQuarantined",
+		"MediaVisibilityChanged":
+	default:
+		return uuid.Nil, false, nil
+	}
+
+	var body struct {
+		MediaID uuid.UUID     `json:"media_id"`
+		From    models.Status `json:"from"`
+		To      models.Status `json:"to"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return uuid.Nil, false, fmt.Errorf("decode %s payload: %w", eventType, err)
+	}
+	if body.MediaID == uuid.Nil {
+		return uuid.Nil, false, fmt.Errorf("%s payload: media_id is required", eventType)
+	}
+	if eventType == "MediaStatusChanged" && body.From != models.ReadyStatus && body.To != models.ReadyStatus && body.To != models.ScheduledStatus {
+		return uuid.Nil, false, nil
+	}
+	return body.MediaID, true, nil
+}
@@ -0,0 +1,201 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMediaFromEvent(t *testing.T) {
+	id := uuid.New()
+	for _, tc := range []struct {
+		eventType string
+		payload   string
+		ok        bool
+	}{
+		{"MediaDeleted", `{"media_id":"` + id.String() + `","reason":"deleted"}`, true},
+		{"MediaContentRecorded", `{"media_id":"` + id.String() + `"}`, true},
+		{"MediaArchived", `{"media_id":"` + id.String() + `"}`, true},
+		{"MediaQuarantined", `{"media_id":"` + id.String() + `"}`, true},
+		{"MediaStatusChanged", `{"media_id":"` + id.String() + `","from":"processing","to":"ready"}`, true},
+		{"MediaStatusChanged", `{"media_id":"` + id.String() + `","from":"uploaded","to":"processing"}`, false},
+		{"MediaStatusChanged", `{"media_id":"` + id.String() + `","from":"ready","to":"processing","visibility":"private"}`, true},
+		{"MediaVisibilityChanged", `{"media_id":"` + id.String() + `","from":"public","to":"private","effective":"private"}`, true},
+		{"MediaCreated", `{"media_id":"` + id.String() + `"}`, false},
+	} {
+		got, ok, err := MediaFromEvent(tc.eventType, json.RawMessage(tc.payload))
+		require.NoError(t, err, tc.eventType)
+		require.Equal(t, tc.ok, ok, tc.eventType+" "+tc.payload)
+		if ok {
+			require.Equal(t, id, got)
+		}
+	}
+
+	_, _, err := MediaFromEvent("MediaDeleted", json.RawMessage(`{}`))
+	require.Error(t, err)
+}
+
+func TestCloudFront_Invalidate(t *testing.T) {
+	var (
+		gotPath string
+		gotAuth string
+		batch   invalidationBatch
+		status  = http.StatusCreated
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, xml.Unmarshal(body, &batch))
+		w.WriteHeader(status)
+		if status != http.StatusCreated {
+			_, _ = io.WriteString(w, `<ErrorResponse><Error><Code>NoSuchDistribution</Code></Error></ErrorResponse>`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cf, err := NewCloudFront(CloudFrontConfig{
+		DistributionID: "E123", AccessKeyID: "AKID", SecretAccessKey: "secret",
+		PathPrefix: "vod/", Endpoint: srv.URL,
+	})
+	require.NoError(t, err)
+	require.Equal(t, DefaultCloudFrontBatch, cf.MaxBatch())
+
+	a, b := uuid.New(), uuid.New()
+	require.NoError(t, cf.Invalidate(context.Background(), []uuid.UUID{a, b}))
+	require.Equal(t, "/2020-05-31/distribution/E123/invalidation", gotPath)
+	require.True(t, strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/"), gotAuth)
+	require.Contains(t, gotAuth, "/us-east-1/cloudfront/aws4_request")
+	require.Equal(t, 2, batch.Quantity)
+	require.Equal(t, []string{"/vod/" + a.String() + "/*", "/vod/" + b.String() + "/*"}, batch.Paths)
+	require.NotEmpty(t, batch.CallerReference)
+
+	status = http.StatusNotFound
+	err = cf.Invalidate(context.Background(), []uuid.UUID{a})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "NoSuchDistribution", apiErr.Code)
+	require.False(t, retryable(err))
+}
+
+func TestFastly_Invalidate(t *testing.T) {
+	var (
+		req  *http.Request
+		body map[string][]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	f, err := NewFastly(FastlyConfig{ServiceID: "svc", APIToken: "token", KeyPrefix: "media-", Soft: true, Endpoint: srv.URL})
+	require.NoError(t, err)
+	id := uuid.New()
+	require.NoError(t, f.Invalidate(context.Background(), []uuid.UUID{id}))
+	require.Equal(t, "/service/svc/purge", req.URL.Path)
+	require.Equal(t, "token", req.Header.Get("Fastly-Key"))
+	require.Equal(t, "1", req.Header.Get("Fastly-Soft-Purge"))
+	require.Equal(t, []string{"media-" + id.String()}, body["surrogate_keys"])
+
+	_, err = NewFastly(FastlyConfig{ServiceID: "svc", APIToken: "token", MaxBatch: 1000})
+	require.Error(t, err)
+}
+
+// fakeCDN записывает батчи; errs — ошибки следующих вызовов по порядку
+type fakeCDN struct {
+	mu      sync.Mutex
+	batch   int
+	batches [][]uuid.UUID
+	errs    []error
+}
+
+func (c *fakeCDN) MaxBatch() int { return c.batch }
+
+func (c *fakeCDN) Invalidate(_ context.Context, ids []uuid.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	c.batches = append(c.batches, append([]uuid.UUID(nil), ids...))
+	return nil
+}
+
+func newTestPurger(t *testing.T, cdn *fakeCDN) *Purger {
+	t.Helper()
+	p, err := NewPurger(PurgerConfig{CDN: cdn, Provider: "fake", MaxAttempts: 3, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	p.sleep = func(context.Context, time.Duration) error { return nil }
+	return p
+}
+
+func TestPurger_BatchesAndRetries(t *testing.T) {
+	unavailable := &APIError{Provider: "fake", StatusCode: http.StatusServiceUnavailable}
+	cdn := &fakeCDN{batch: 2, errs: []error{unavailable, nil}}
+	p := newTestPurger(t, cdn)
+
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	p.Enqueue(a, b)
+	p.Enqueue(a, c) // a уже в очереди
+	require.Equal(t, int64(3), p.Metrics().Pending.Load())
+
+	require.NoError(t, p.Flush(context.Background()))
+	require.Equal(t, [][]uuid.UUID{{a, b}, {c}}, cdn.batches)
+	require.Equal(t, int64(3), p.Metrics().Purged.Load())
+	require.Equal(t, int64(1), p.Metrics().Failures.Load())
+	require.Zero(t, p.Metrics().Pending.Load())
+
+	// Постоянная ошибка не повторяется, временная — до MaxAttempts
+	cdn.errs = []error{&APIError{Provider: "fake", StatusCode: http.StatusForbidden}, unavailable, unavailable, unavailable}
+	p.Enqueue(a)
+	p.Enqueue(b)
+	p.Enqueue(c)
+	err := p.Flush(context.Background())
+	require.Error(t, err)
+	require.Equal(t, int64(3), p.Metrics().Dropped.Load())
+	require.Empty(t, cdn.errs)
+}
+
+func TestPurger_FullBatchFlushedEarly(t *testing.T) {
+	cdn := &fakeCDN{batch: 2}
+	p := newTestPurger(t, cdn)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Start(ctx) }()
+
+	p.Enqueue(uuid.New(), uuid.New())
+	require.Eventually(t, func() bool {
+		cdn.mu.Lock()
+		defer cdn.mu.Unlock()
+		return len(cdn.batches) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// Отмена посреди сброса возвращает медиа в очередь для Flush при остановке
+	cdn.errs = []error{errors.New("connection reset")}
+	id := uuid.New()
+	p.Enqueue(id)
+	require.ErrorIs(t, p.Flush(ctx), context.Canceled)
+	require.Equal(t, int64(1), p.Metrics().Pending.Load())
+	require.NoError(t, p.Flush(context.Background()))
+	require.Equal(t, []uuid.UUID{id}, cdn.batches[1])
+}
@@ -0,0 +1,128 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloudFront API: регион подписи у него один, us-east-1
+const (
+	cloudFrontEndpoint   = "https://cloudfront.amazonaws.com"
+	cloudFrontAPIVersion = "2020-05-31"
+	cloudFrontRegion     = "us-east-1"
+)
+
+// DefaultCloudFrontBatch — одновременно в работе может быть не больше 15 путей с wildcard,
+// а каждое медиа — один путь /{prefix}/{media_id}/*
+const DefaultCloudFrontBatch = 15
+
+// CloudFrontConfig содержит конфигурацию CloudFront
+type CloudFrontConfig struct {
+	DistributionID  string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // временные credentials (STS); пустой — не передаётся
+	// PathPrefix — каталог медиа в distribution: /vod → /vod/{media_id}/*
+	PathPrefix string
+	Endpoint   string       // default: https://cloudfront.amazonaws.com
+	MaxBatch   int          // default: DefaultCloudFrontBatch
+	HTTPClient *http.Client // default: timeout 10s
+}
+
+// CloudFront — CDNInvalidator поверх CloudFront CreateInvalidation (подпись AWS Signature V4)
+type CloudFront struct {
+	endpoint string
+	config   CloudFrontConfig
+	client   *http.Client
+	clock    func() time.Time
+}
+
+func NewCloudFront(cfg CloudFrontConfig) (*CloudFront, error) {
+	if cfg.DistributionID == "" {
+		return nil, errors.New("cloudfront distribution id is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("cloudfront credentials are required")
+	}
+	if cfg.MaxBatch < 0 {
+		return nil, fmt.Errorf("cloudfront max batch cannot be negative, got: %d", cfg.MaxBatch)
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = cloudFrontEndpoint
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid cloudfront endpoint %q", cfg.Endpoint)
+	}
+	if cfg.MaxBatch == 0 {
+		cfg.MaxBatch = DefaultCloudFrontBatch
+	}
+	cfg.PathPrefix = "/" + strings.Trim(cfg.PathPrefix, "/")
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &CloudFront{endpoint: strings.TrimSuffix(cfg.Endpoint, "/"), config: cfg, client: client, clock: time.Now}, nil
+}
+
+func (c *CloudFront) MaxBatch() int { return c.config.MaxBatch }
+
+// invalidationBatch — тело CreateInvalidation
+type invalidationBatch struct {
+	XMLName         xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Quantity        int      `xml:"Paths>Quantity"`
+	Paths           []string `xml:"Paths>Items>Path"`
+	CallerReference string   `xml:"CallerReference"`
+}
+
+// Invalidate создаёт invalidation путей /{prefix}/{media_id}/*. CallerReference уникален
+// для каждого вызова: с тем же reference CloudFront вернул бы прежнюю invalidation вместо новой.
+func (c *CloudFront) Invalidate(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	batch := invalidationBatch{Quantity: len(ids), CallerReference: uuid.NewString()}
+	for _, id := range ids {
+		batch.Paths = append(batch.Paths, path.Join(c.config.PathPrefix, id.String())+"/*")
+	}
+	body, err := xml.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	u := c.endpoint + "/" + cloudFrontAPIVersion + "/distribution/" + url.PathEscape(c.config.DistributionID) + "/invalidation"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	creds := awsCredentials{AccessKeyID: c.config.AccessKeyID, SecretAccessKey: c.config.SecretAccessKey, SessionToken: c.config.SessionToken}
+	signV4(req, body, creds, cloudFrontRegion, "cloudfront", c.clock())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudfront purge: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var e struct {
+			Code string `xml:"Error>Code"`
+		}
+		_ = xml.Unmarshal(data, &e)
+		return &APIError{Provider: "cloudfront", StatusCode: resp.StatusCode, Code: e.Code}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,156 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// responseSnippetLimit — сколько байт ответа канала сохраняется в журнале доставок
+const responseSnippetLimit = 1024
+
+// Response — ответ канала на доставку: HTTP статус и начало тела; у SMTP — код и текст ответа
+type Response struct {
+	StatusCode int
+	Body       string
+}
+
+// Deliverer — Notifier, который сообщает ответ канала для журнала доставок. Встроенные
+// HTTP каналы его реализуют; у остальных в журнал попадает только ошибка.
+type Deliverer interface {
+	Deliver(ctx context.Context, msg Message) (Response, error)
+}
+
+// deliver отправляет msg в n и возвращает ответ канала: от Deliverer, а при ошибке — из неё
+func deliver(ctx context.Context, n Notifier, msg Message) (Response, error) {
+	if d, ok := n.(Deliverer); ok {
+		return d.Deliver(ctx, msg)
+	}
+	err := n.Notify(ctx, msg)
+	return responseFromError(err), err
+}
+
+func responseFromError(err error) Response {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return Response{StatusCode: apiErr.StatusCode, Body: apiErr.Code}
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return Response{StatusCode: smtpErr.Code, Body: smtpErr.Msg}
+	}
+	return Response{}
+}
+
+// snippet обрезает ответ канала до responseSnippetLimit байт, не разрезая символы
+func snippet(s string) string {
+	if len(s) <= responseSnippetLimit {
+		return s
+	}
+	cut := responseSnippetLimit
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut]
+}
+
+// Delivery — одна попытка доставки уведомления в канал. Message сохраняется целиком:
+// по нему доставку можно повторить (Dispatcher.Redeliver).
+type Delivery struct {
+	ID           uuid.UUID
+	Channel      string
+	Subscription string
+	EventID      string
+	EventType    string
+	Attempt      int        // номер попытки в доставке события: 1..MaxAttempts; у повтора — 1
+	RedeliveryOf *uuid.UUID // попытка, которую повторили через Redeliver
+	Message      Message
+	StatusCode   int    // 0 — канал не ответил (ошибка сети) или не сообщает статус
+	Response     string // начало ответа канала, не длиннее responseSnippetLimit
+	Error        string // пустая — доставлено
+	Latency      time.Duration
+	DeliveredAt  time.Time
+}
+
+func (d Delivery) Succeeded() bool { return d.Error == "" }
+
+// DeliveryFilter — выборка журнала доставок канала
+type DeliveryFilter struct {
+	Failed bool // только неуспешные попытки
+	Limit  int  // default: 50
+}
+
+// DefaultDeliveriesLimit — сколько доставок отдаётся без явного лимита
+const DefaultDeliveriesLimit = 50
+
+// DeliveryStore — журнал попыток доставки уведомлений
+type DeliveryStore interface {
+	Record(ctx context.Context, d Delivery) error
+	// Delivery возвращает попытку по id; нет такой — models.ErrNotFound
+	Delivery(ctx context.Context, id uuid.UUID) (Delivery, error)
+	// Deliveries возвращает попытки канала, новые первыми
+	Deliveries(ctx context.Context, channel string, f DeliveryFilter) ([]Delivery, error)
+}
+
+// DefaultMemoryDeliveries — сколько попыток хранит MemoryDeliveryStore по умолчанию
+const DefaultMemoryDeliveries = 10000
+
+// MemoryDeliveryStore — журнал доставок в памяти процесса: последние max попыток, до рестарта
+type MemoryDeliveryStore struct {
+	mu         sync.Mutex
+	max        int
+	deliveries []Delivery // по времени записи
+}
+
+// NewMemoryDeliveryStore создаёт журнал на max попыток; max <= 0 — DefaultMemoryDeliveries
+func NewMemoryDeliveryStore(max int) *MemoryDeliveryStore {
+	if max <= 0 {
+		max = DefaultMemoryDeliveries
+	}
+	return &MemoryDeliveryStore{max: max}
+}
+
+func (s *MemoryDeliveryStore) Record(_ context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.deliveries) >= s.max {
+		s.deliveries = slices.Delete(s.deliveries, 0, len(s.deliveries)-s.max+1)
+	}
+	s.deliveries = append(s.deliveries, d)
+	return nil
+}
+
+func (s *MemoryDeliveryStore) Delivery(_ context.Context, id uuid.UUID) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return Delivery{}, fmt.Errorf("%w: delivery %s", models.ErrNotFound, id)
+}
+
+func (s *MemoryDeliveryStore) Deliveries(_ context.Context, channel string, f DeliveryFilter) ([]Delivery, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultDeliveriesLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Delivery
+	for i := len(s.deliveries) - 1; i >= 0 && len(out) < f.Limit; i-- {
+		d := s.deliveries[i]
+		if d.Channel == channel && (!f.Failed || !d.Succeeded()) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
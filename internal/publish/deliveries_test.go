@@ -0,0 +1,156 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func TestDispatcher_DeliveryLog(t *testing.T) {
+	var fail bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fail {
+			http.Error(w, strings.Repeat("x", 2*responseSnippetLimit), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	hook, err := NewWebhookChannel(srv.URL, nil, nil)
+	require.NoError(t, err)
+
+	store := NewMemoryDeliveryStore(0)
+	d, err := NewDispatcher(DispatcherConfig{
+		Channels:      map[string]Notifier{"hook": hook, "plain": &recordingNotifier{}},
+		Subscriptions: []Subscription{{Name: "all", Events: []string{"MediaStatusChanged"}, Channels: []string{"hook", "plain"}}},
+		Deliveries:    store,
+		Logger:        zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	fail = true
+	require.Error(t, d.Handle(ctx, statusChanged(t, uuid.New(), models.FailedStatus, "boom")))
+	list, err := d.Deliveries(ctx, "hook", DeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 1, "4xx is not retried")
+	failed := list[0]
+	require.False(t, failed.Succeeded())
+	require.Equal(t, http.StatusBadRequest, failed.StatusCode)
+	require.Len(t, failed.Response, responseSnippetLimit)
+	require.Equal(t, "all", failed.Subscription)
+	require.Equal(t, 1, failed.Attempt)
+
+	// Канал без Deliverer: попытка в журнале без ответа
+	list, err = d.Deliveries(ctx, "plain", DeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.True(t, list[0].Succeeded())
+	require.Zero(t, list[0].StatusCode)
+
+	fail = false
+	re, err := d.Redeliver(ctx, failed.ID)
+	require.NoError(t, err)
+	require.True(t, re.Succeeded())
+	require.Equal(t, "ok", re.Response)
+	require.Equal(t, failed.ID, *re.RedeliveryOf)
+	require.Equal(t, failed.Message, re.Message)
+
+	list, err = d.Deliveries(ctx, "hook", DeliveryFilter{Failed: true})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	_, err = d.Deliveries(ctx, "pager", DeliveryFilter{})
+	require.ErrorIs(t, err, models.ErrNotFound)
+	_, err = d.Redeliver(ctx, uuid.New())
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestMemoryDeliveryStore_Bounded(t *testing.T) {
+	s := NewMemoryDeliveryStore(2)
+	ctx := context.Background()
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range ids {
+		require.NoError(t, s.Record(ctx, Delivery{ID: id, Channel: "c", DeliveredAt: time.Now()}))
+	}
+	_, err := s.Delivery(ctx, ids[0])
+	require.ErrorIs(t, err, models.ErrNotFound)
+	list, err := s.Deliveries(ctx, "c", DeliveryFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, ids[2], list[0].ID)
+}
+
+func TestHandler_Deliveries(t *testing.T) {
+	ch := &recordingNotifier{errs: []error{&APIError{Provider: "slack", StatusCode: http.StatusNotFound, Code: "no_service"}}}
+	d, err := NewDispatcher(DispatcherConfig{
+		Channels:      map[string]Notifier{"ops": ch},
+		Subscriptions: []Subscription{{Name: "all", Events: []string{"MediaStatusChanged"}, Channels: []string{"ops"}}},
+		Deliveries:    NewMemoryDeliveryStore(0),
+		Logger:        zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	require.Error(t, d.Handle(context.Background(), statusChanged(t, uuid.New(), models.ReadyStatus, "")))
+	h, err := NewHandler(HandlerConfig{Dispatcher: d, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	do := func(method, target string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec, body
+	}
+
+	rec, body := do(http.MethodGet, "/webhooks/ops/deliveries?failed=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	list := body["deliveries"].([]any)
+	require.Len(t, list, 1)
+	first := list[0].(map[string]any)
+	require.Equal(t, false, first["success"])
+	require.Equal(t, float64(http.StatusNotFound), first["status_code"])
+	require.Equal(t, "no_service", first["response"])
+	require.Nil(t, first["request"])
+	id := first["id"].(string)
+
+	rec, body = do(http.MethodGet, "/deliveries/"+id)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, body["request"].(map[string]any)["subject"], "MediaStatusChanged")
+
+	rec, body = do(http.MethodPost, "/deliveries/"+id+"/redeliver")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, true, body["success"])
+	require.Equal(t, id, body["redelivery_of"])
+	require.Len(t, ch.msgs, 1)
+
+	rec, body = do(http.MethodPost, "/webhooks/ops/test")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, true, body["success"])
+	require.Equal(t, TestEventType, body["event_type"])
+	require.Len(t, ch.msgs, 2)
+	rec, _ = do(http.MethodGet, "/webhooks/ops/test")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	rec, _ = do(http.MethodPost, "/webhooks/pager/test")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec, _ = do(http.MethodGet, "/webhooks/pager/deliveries")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = do(http.MethodGet, "/webhooks/ops/deliveries?limit=1000")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = do(http.MethodGet, "/deliveries/"+id+"/redeliver")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	rec, _ = do(http.MethodPost, "/deliveries/"+uuid.NewString()+"/redeliver")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	_, err = NewHandler(HandlerConfig{Dispatcher: &Dispatcher{}})
+	require.ErrorIs(t, err, ErrDeliveryLogDisabled)
+}
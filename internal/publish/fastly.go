@@ -0,0 +1,115 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const fastlyEndpoint = "https://api.fastly.com"
+
+// DefaultFastlyBatch — больше 256 surrogate keys в одном запросе Fastly не принимает
+const DefaultFastlyBatch = 256
+
+// FastlyConfig содержит конфигурацию Fastly
+type FastlyConfig struct {
+	ServiceID string
+	APIToken  string
+	// KeyPrefix — surrogate key медиа: KeyPrefix + media_id. Ключ проставляет сервис Fastly
+	// (VCL) объектам каталога медиа — по пути запроса к хранилищу.
+	KeyPrefix string
+	// Soft — soft purge: объекты помечаются устаревшими и могут отдаваться, пока CDN
+	// перезапрашивает их у хранилища. Для удалённых медиа это значит ещё несколько секунд отдачи.
+	Soft       bool
+	Endpoint   string       // default: https://api.fastly.com
+	MaxBatch   int          // default: DefaultFastlyBatch
+	HTTPClient *http.Client // default: timeout 10s
+}
+
+// Fastly — CDNInvalidator поверх batch purge по surrogate keys
+type Fastly struct {
+	endpoint string
+	config   FastlyConfig
+	client   *http.Client
+}
+
+func NewFastly(cfg FastlyConfig) (*Fastly, error) {
+	if cfg.ServiceID == "" {
+		return nil, errors.New("fastly service id is required")
+	}
+	if cfg.APIToken == "" {
+		return nil, errors.New("fastly api token is required")
+	}
+	if cfg.MaxBatch < 0 || cfg.MaxBatch > DefaultFastlyBatch {
+		return nil, fmt.Errorf("fastly max batch must be in [0, %d], got: %d", DefaultFastlyBatch, cfg.MaxBatch)
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fastlyEndpoint
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid fastly endpoint %q", cfg.Endpoint)
+	}
+	if cfg.MaxBatch == 0 {
+		cfg.MaxBatch = DefaultFastlyBatch
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Fastly{endpoint: strings.TrimSuffix(cfg.Endpoint, "/"), config: cfg, client: client}, nil
+}
+
+func (f *Fastly) MaxBatch() int { return f.config.MaxBatch }
+
+// Invalidate — POST /service/{id}/purge с surrogate keys медиа
+func (f *Fastly) Invalidate(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = f.config.KeyPrefix + id.String()
+	}
+	body, err := json.Marshal(map[string][]string{"surrogate_keys": keys})
+	if err != nil {
+		return err
+	}
+
+	u := f.endpoint + "/service/" + url.PathEscape(f.config.ServiceID) + "/purge"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Fastly-Key", f.config.APIToken)
+	if f.config.Soft {
+		req.Header.Set("Fastly-Soft-Purge", "1")
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fastly purge: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var e struct {
+			Msg string `json:"msg"`
+		}
+		_ = json.Unmarshal(data, &e)
+		return &APIError{Provider: "fastly", StatusCode: resp.StatusCode, Code: e.Msg}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
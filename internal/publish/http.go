@@ -0,0 +1,230 @@
+package publish
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+)
+
+// MaxDeliveriesLimit — наибольший limit GET /webhooks/{id}/deliveries
+const MaxDeliveriesLimit = 500
+
+// DeliveryResponse — попытка доставки в ответах API. webhook — имя канала из -notify-config;
+// request (subject, body, event) — только в GET /deliveries/{id} и ответе redeliver.
+type DeliveryResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Webhook      string     `json:"webhook"`
+	Subscription string     `json:"subscription"`
+	EventID      string     `json:"event_id"`
+	EventType    string     `json:"event_type"`
+	Attempt      int        `json:"attempt"`
+	RedeliveryOf *uuid.UUID `json:"redelivery_of,omitempty"`
+	Success      bool       `json:"success"`
+	StatusCode   int        `json:"status_code,omitempty"`
+	LatencyMS    int64      `json:"latency_ms"`
+	Response     string     `json:"response,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	DeliveredAt  time.Time  `json:"delivered_at"`
+	Request      *Message   `json:"request,omitempty"`
+}
+
+func deliveryResponse(d Delivery, full bool) DeliveryResponse {
+	resp := DeliveryResponse{
+		ID:           d.ID,
+		Webhook:      d.Channel,
+		Subscription: d.Subscription,
+		EventID:      d.EventID,
+		EventType:    d.EventType,
+		Attempt:      d.Attempt,
+		RedeliveryOf: d.RedeliveryOf,
+		Success:      d.Succeeded(),
+		StatusCode:   d.StatusCode,
+		LatencyMS:    d.Latency.Milliseconds(),
+		Response:     d.Response,
+		Error:        d.Error,
+		DeliveredAt:  d.DeliveredAt,
+	}
+	if full {
+		resp.Request = &d.Message
+	}
+	return resp
+}
+
+// DeliveriesResponse — ответ GET /webhooks/{id}/deliveries
+type DeliveriesResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"` // новые первыми
+}
+
+// HandlerConfig содержит конфигурацию Handler
+type HandlerConfig struct {
+	Dispatcher *Dispatcher // с журналом доставок
+	Logger     zerolog.Logger
+}
+
+// Handler — HTTP API журнала доставок уведомлений: GET /webhooks/{id}/deliveries
+// (?failed=true, ?limit=), POST /webhooks/{id}/test, GET /deliveries/{id} и
+// POST /deliveries/{id}/redeliver.
+// Webhook здесь — любой канал уведомлений, id — его имя в конфигурации.
+type Handler struct {
+	dispatcher *Dispatcher
+	logger     zerolog.Logger
+}
+
+func NewHandler(cfg HandlerConfig) (*Handler, error) {
+	if cfg.Dispatcher == nil {
+		return nil, errors.New("dispatcher is required")
+	}
+	if cfg.Dispatcher.log == nil {
+		return nil, ErrDeliveryLogDisabled
+	}
+	return &Handler{
+		dispatcher: cfg.Dispatcher,
+		logger:     cfg.Logger.With().Str("component", "publish_http").Logger(),
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/webhooks/"); ok {
+		channel, sub, _ := strings.Cut(rest, "/")
+		var handle func(http.ResponseWriter, *http.Request, string)
+		method := http.MethodGet
+		switch {
+		case channel != "" && sub == "deliveries":
+			handle = h.Deliveries
+		case channel != "" && sub == "test":
+			handle, method = h.Test, http.MethodPost
+		default:
+			writeError(w, http.StatusNotFound, apierr.CodeNotFound, "not found")
+			return
+		}
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		handle(w, r, channel)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/deliveries/")
+	if !ok {
+		writeError(w, http.StatusNotFound, apierr.CodeNotFound, "not found")
+		return
+	}
+	raw, sub, _ := strings.Cut(rest, "/")
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid delivery id")
+		return
+	}
+	handle, method := h.Delivery, http.MethodGet
+	switch sub {
+	case "":
+	case "redeliver":
+		handle, method = h.Redeliver, http.MethodPost
+	default:
+		writeError(w, http.StatusNotFound, apierr.CodeNotFound, "not found")
+		return
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	handle(w, r, id)
+}
+
+// Deliveries — GET /webhooks/{id}/deliveries: последние попытки доставки в канал
+func (h *Handler) Deliveries(w http.ResponseWriter, r *http.Request, channel string) {
+	f := DeliveryFilter{Limit: DefaultDeliveriesLimit}
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxDeliveriesLimit {
+			writeError(w, http.StatusBadRequest, apierr.CodeInvalidArgument, "limit must be between 1 and "+strconv.Itoa(MaxDeliveriesLimit))
+			return
+		}
+		f.Limit = n
+	}
+	if v := q.Get("failed"); v != "" {
+		failed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, apierr.CodeInvalidArgument, "failed must be a boolean")
+			return
+		}
+		f.Failed = failed
+	}
+
+	deliveries, err := h.dispatcher.Deliveries(r.Context(), channel, f)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	resp := DeliveriesResponse{Deliveries: make([]DeliveryResponse, 0, len(deliveries))}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, deliveryResponse(d, false))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Test — POST /webhooks/{id}/test: отправляет в канал пробное уведомление и отдаёт попытку.
+// 200 и при неуспехе: результат в success, status_code и error.
+func (h *Handler) Test(w http.ResponseWriter, r *http.Request, channel string) {
+	d, err := h.dispatcher.Test(r.Context(), channel)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.logger.Info().Str("webhook", channel).Str("delivery_id", d.ID.String()).Bool("success", d.Succeeded()).Msg("test notification sent")
+	writeJSON(w, http.StatusOK, deliveryResponse(d, true))
+}
+
+// Delivery — GET /deliveries/{id}: попытка вместе с отправленным сообщением
+func (h *Handler) Delivery(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	d, err := h.dispatcher.Delivery(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveryResponse(d, true))
+}
+
+// Redeliver — POST /deliveries/{id}/redeliver: повторяет доставку и отдаёт новую попытку.
+// 200 и при неуспехе повтора: результат в success, status_code и error.
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	d, err := h.dispatcher.Redeliver(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.logger.Info().Str("delivery_id", id.String()).Str("redelivery_id", d.ID.String()).Bool("success", d.Succeeded()).Msg("notification redelivered")
+	writeJSON(w, http.StatusOK, deliveryResponse(d, true))
+}
+
+// writeServiceError отвечает ошибкой по реестру apierr: клиентские — с текстом ошибки
+func (h *Handler) writeServiceError(w http.ResponseWriter, err error) {
+	m := apierr.Lookup(err)
+	message := m.Message
+	if m.HTTPStatus >= http.StatusInternalServerError {
+		h.logger.Error().Err(err).Msg("deliveries request failed")
+	} else {
+		message = err.Error()
+	}
+	writeError(w, m.HTTPStatus, m.Code, message)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
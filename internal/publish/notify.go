@@ -0,0 +1,475 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Message — уведомление, готовое к отправке в канал
+type Message struct {
+	Subject string       `json:"subject"`
+	Body    string       `json:"body"`
+	Event   Notification `json:"event"` // из чего собрано: каналы со структурированным payload отдают его целиком
+}
+
+// Notifier доставляет уведомления в один канал: почту, Slack, SNS, webhook
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// ChannelFactory создаёт Notifier по настройкам канала — JSON объекту из конфигурации
+// со всеми полями канала, включая type
+type ChannelFactory func(settings json.RawMessage) (Notifier, error)
+
+var (
+	channelsMu   sync.RWMutex
+	channelTypes = map[string]ChannelFactory{
+		"email":   newEmailChannel,
+		"slack":   newSlackChannel,
+		"sns":     newSNSChannel,
+		"webhook": newWebhookChannel,
+	}
+)
+
+// RegisterChannel добавляет тип канала уведомлений — так подключаются каналы помимо встроенных
+// email, slack, sns и webhook. Повторная регистрация типа заменяет прежнюю.
+func RegisterChannel(typ string, f ChannelFactory) {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	channelTypes[typ] = f
+}
+
+// ChannelTypes возвращает зарегистрированные типы каналов
+func ChannelTypes() []string {
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	return slices.Sorted(maps.Keys(channelTypes))
+}
+
+// Notification — данные шаблонов уведомления: событие media и его основные поля
+type Notification struct {
+	Subscription string         `json:"subscription"`
+	EventID      string         `json:"event_id"`
+	EventType    string         `json:"event_type"`
+	OccurredAt   time.Time      `json:"occurred_at"`
+	MediaID      string         `json:"media_id"`
+	OwnerID      string         `json:"owner_id,omitempty"`
+	Status       string         `json:"status,omitempty"` // новый статус для MediaStatusChanged
+	Reason       string         `json:"reason,omitempty"`
+	Payload      map[string]any `json:"payload"`
+}
+
+// Шаблоны по умолчанию
+const (
+	DefaultSubjectTemplate = `{{.EventType}} {{.MediaID}}`
+	DefaultBodyTemplate    = `{{.EventType}}: media {{.MediaID}}{{with .Status}} is {{.}}{{end}}{{with .Reason}} ({{.}}){{end}} at {{.OccurredAt.UTC.Format "2006-01-02 15:04:05Z"}}`
+)
+
+// Subscription — какие события и в какие каналы отправлять
+type Subscription struct {
+	Name   string   `json:"name"`
+	Events []string `json:"events"` // event_type событий media
+	// Statuses — для MediaStatusChanged только переходы в эти статусы; пустой — все
+	Statuses []models.Status `json:"statuses,omitempty"`
+	Channels []string        `json:"channels"`           // имена каналов из NotifyConfig.Channels
+	Subject  string          `json:"subject,omitempty"`  // text/template по Notification; default: DefaultSubjectTemplate
+	Template string          `json:"template,omitempty"` // text/template тела; default: DefaultBodyTemplate
+}
+
+// NotifyConfig — файл конфигурации уведомлений (JSON). Секреты каналов можно не писать
+// в файл, а передать через переменные окружения: поля *_env содержат имя переменной.
+//
+//	{
+//	  "channels": {
+//	    "ops-slack": {"type": "slack", "webhook_url_env": "SLACK_WEBHOOK_URL"},
+//	    "ops-email": {"type": "email", "addr": "smtp.example.com:587", "from": "media@example.com",
+//	                  "to": ["ops@example.com"], "username": "media", "password_env": "SMTP_PASSWORD"}
+//	  },
+//	  "subscriptions": [
+//	    {"name": "processing-failed", "events": ["MediaStatusChanged"], "statuses": ["failed"],
+//	     "channels": ["ops-slack", "ops-email"], "template": "Media {{.MediaID}} failed: {{.Reason}}"}
+//	  ]
+//	}
+type NotifyConfig struct {
+	Channels      map[string]json.RawMessage `json:"channels"`
+	Subscriptions []Subscription             `json:"subscriptions"`
+}
+
+// LoadNotifyConfig читает конфигурацию и создаёт её каналы
+func LoadNotifyConfig(r io.Reader) (map[string]Notifier, []Subscription, error) {
+	var cfg NotifyConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("decode notify config: %w", err)
+	}
+
+	channels := make(map[string]Notifier, len(cfg.Channels))
+	for name, settings := range cfg.Channels {
+		var head struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(settings, &head); err != nil {
+			return nil, nil, fmt.Errorf("channel %q: %w", name, err)
+		}
+		channelsMu.RLock()
+		factory, ok := channelTypes[head.Type]
+		channelsMu.RUnlock()
+		if !ok {
+			return nil, nil, fmt.Errorf("channel %q: unknown type %q", name, head.Type)
+		}
+		n, err := factory(settings)
+		if err != nil {
+			return nil, nil, fmt.Errorf("channel %q: %w", name, err)
+		}
+		channels[name] = n
+	}
+	return channels, cfg.Subscriptions, nil
+}
+
+// decodeSettings разбирает настройки канала в v; неизвестные поля — ошибка, чтобы опечатка
+// в имени поля не превращалась в молча пустую настройку
+func decodeSettings(settings json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(settings))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("decode settings: %w", err)
+	}
+	return nil
+}
+
+// secret — значение, а если оно пустое — переменная окружения env
+func secret(value, env string) string {
+	if value == "" && env != "" {
+		return os.Getenv(env)
+	}
+	return value
+}
+
+// NotifyMetrics содержит метрики Dispatcher
+type NotifyMetrics struct {
+	Sent   atomic.Int64 // Уведомления, доставленные в канал
+	Failed atomic.Int64 // Уведомления, не доставленные за все попытки
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *NotifyMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "publish_notifications_sent_total",
+			Help: "Уведомления, доставленные в каналы",
+		}, func() float64 { return float64(m.Sent.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "publish_notifications_failed_total",
+			Help: "Уведомления, не доставленные за все попытки",
+		}, func() float64 { return float64(m.Failed.Load()) }),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DispatcherConfig содержит конфигурацию Dispatcher
+type DispatcherConfig struct {
+	Channels      map[string]Notifier
+	Subscriptions []Subscription
+	MaxAttempts   int           // попыток доставки в канал; default: 3
+	RetryBackoff  time.Duration // пауза перед повтором, удваивается; default: 1s
+	Deliveries    DeliveryStore // журнал попыток доставки; nil — не ведётся, повтор доставки недоступен
+	Logger        zerolog.Logger
+}
+
+// Dispatcher рассылает уведомления о событиях media по подпискам
+type Dispatcher struct {
+	channels map[string]Notifier
+	subs     []subscription
+	attempts int
+	backoff  time.Duration
+	sleep    func(ctx context.Context, d time.Duration) error
+	clock    func() time.Time
+	log      DeliveryStore
+	metrics  *NotifyMetrics
+	logger   zerolog.Logger
+}
+
+// subscription — Subscription с разобранными шаблонами
+type subscription struct {
+	Subscription
+	subject *template.Template
+	body    *template.Template
+}
+
+func NewDispatcher(cfg DispatcherConfig) (*Dispatcher, error) {
+	if cfg.MaxAttempts < 0 || cfg.RetryBackoff < 0 {
+		return nil, errors.New("notify attempts and backoff cannot be negative")
+	}
+	subs := make([]subscription, 0, len(cfg.Subscriptions))
+	for i, s := range cfg.Subscriptions {
+		if s.Name == "" {
+			return nil, fmt.Errorf("subscription #%d: name is required", i)
+		}
+		if len(s.Events) == 0 || len(s.Channels) == 0 {
+			return nil, fmt.Errorf("subscription %q: events and channels are required", s.Name)
+		}
+		for _, ch := range s.Channels {
+			if _, ok := cfg.Channels[ch]; !ok {
+				return nil, fmt.Errorf("subscription %q: unknown channel %q", s.Name, ch)
+			}
+		}
+		if s.Subject == "" {
+			s.Subject = DefaultSubjectTemplate
+		}
+		if s.Template == "" {
+			s.Template = DefaultBodyTemplate
+		}
+		subject, err := template.New("subject").Option("missingkey=zero").Parse(s.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %q: subject: %w", s.Name, err)
+		}
+		body, err := template.New("body").Option("missingkey=zero").Parse(s.Template)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %q: template: %w", s.Name, err)
+		}
+		subs = append(subs, subscription{Subscription: s, subject: subject, body: body})
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	return &Dispatcher{
+		channels: cfg.Channels,
+		subs:     subs,
+		attempts: cfg.MaxAttempts,
+		backoff:  cfg.RetryBackoff,
+		sleep:    sleepContext,
+		clock:    time.Now,
+		log:      cfg.Deliveries,
+		metrics:  &NotifyMetrics{},
+		logger:   cfg.Logger.With().Str("component", "notify_dispatcher").Logger(),
+	}, nil
+}
+
+// Metrics возвращает метрики Dispatcher
+func (d *Dispatcher) Metrics() *NotifyMetrics { return d.metrics }
+
+// Handle отправляет уведомления всех подписок, под которые подходит событие. Каналы
+// независимы: недоставленное в один канал не мешает остальным и не повторяется
+// повторной доставкой события — ошибка только логируется и возвращается.
+func (d *Dispatcher) Handle(ctx context.Context, env events.Envelope) error {
+	var errs []error
+	var n *Notification
+	for _, s := range d.subs {
+		if !slices.Contains(s.Events, env.EventType) {
+			continue
+		}
+		if n == nil {
+			parsed, err := notification(env)
+			if err != nil {
+				return err
+			}
+			n = &parsed
+		}
+		if env.EventType == "MediaStatusChanged" && len(s.Statuses) > 0 && !slices.Contains(s.Statuses, models.Status(n.Status)) {
+			continue
+		}
+
+		data := *n
+		data.Subscription = s.Name
+		msg, err := s.render(data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, ch := range s.Channels {
+			if err := d.send(ctx, ch, msg); err != nil {
+				d.metrics.Failed.Add(1)
+				d.logger.Error().Err(err).Str("subscription", s.Name).Str("channel", ch).Str("event_id", env.EventID).Msg("notification failed")
+				errs = append(errs, fmt.Errorf("subscription %q channel %q: %w", s.Name, ch, err))
+				continue
+			}
+			d.metrics.Sent.Add(1)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send доставляет msg в канал с повторами временных ошибок
+func (d *Dispatcher) send(ctx context.Context, channel string, msg Message) error {
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		_, err := d.attempt(ctx, channel, msg, attempt, nil)
+		if err == nil || ctx.Err() != nil || !retryable(err) || attempt >= d.attempts {
+			return err
+		}
+		d.logger.Warn().Err(err).Str("channel", channel).Int("attempt", attempt).Msg("notification failed, retrying")
+		if err := d.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// attempt доставляет msg в канал один раз и записывает попытку в журнал. Ошибка записи
+// только логируется: журнал нужен для отладки и не должен мешать доставке.
+func (d *Dispatcher) attempt(ctx context.Context, channel string, msg Message, n int, redeliveryOf *uuid.UUID) (Delivery, error) {
+	start := d.clock()
+	resp, err := deliver(ctx, d.channels[channel], msg)
+	rec := Delivery{
+		ID:           uuid.New(),
+		Channel:      channel,
+		Subscription: msg.Event.Subscription,
+		EventID:      msg.Event.EventID,
+		EventType:    msg.Event.EventType,
+		Attempt:      n,
+		RedeliveryOf: redeliveryOf,
+		Message:      msg,
+		StatusCode:   resp.StatusCode,
+		Response:     snippet(resp.Body),
+		Latency:      d.clock().Sub(start),
+		DeliveredAt:  start,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if d.log != nil {
+		if rerr := d.log.Record(context.WithoutCancel(ctx), rec); rerr != nil {
+			d.logger.Warn().Err(rerr).Str("channel", channel).Str("event_id", rec.EventID).Msg("record delivery failed")
+		}
+	}
+	return rec, err
+}
+
+// Deliveries возвращает журнал доставок канала; канала нет в конфигурации — models.ErrNotFound
+func (d *Dispatcher) Deliveries(ctx context.Context, channel string, f DeliveryFilter) ([]Delivery, error) {
+	if err := d.logEnabled(channel); err != nil {
+		return nil, err
+	}
+	return d.log.Deliveries(ctx, channel, f)
+}
+
+// Delivery возвращает попытку доставки по id
+func (d *Dispatcher) Delivery(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	if d.log == nil {
+		return Delivery{}, ErrDeliveryLogDisabled
+	}
+	return d.log.Delivery(ctx, id)
+}
+
+// Redeliver повторяет доставку id в тот же канал одной попыткой — с тем же сообщением, без
+// повторного рендера шаблонов — и возвращает новую попытку. Её неуспех не ошибка Redeliver:
+// он, как и у остальных попыток, в Delivery.Error.
+func (d *Dispatcher) Redeliver(ctx context.Context, id uuid.UUID) (Delivery, error) {
+	orig, err := d.Delivery(ctx, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if err := d.logEnabled(orig.Channel); err != nil {
+		return Delivery{}, err
+	}
+	rec, err := d.attempt(ctx, orig.Channel, orig.Message, 1, &orig.ID)
+	if err != nil {
+		d.metrics.Failed.Add(1)
+		d.logger.Warn().Err(err).Str("channel", orig.Channel).Str("delivery_id", id.String()).Msg("redelivery failed")
+		return rec, nil
+	}
+	d.metrics.Sent.Add(1)
+	return rec, nil
+}
+
+// TestEventType — event_type пробного уведомления Dispatcher.Test
+const TestEventType = "NotificationTest"
+
+// Test отправляет в канал пробное уведомление одной попыткой — проверить адрес и секреты
+// канала, не дожидаясь события. Попытка пишется в журнал; её неуспех, как у Redeliver,
+// не ошибка Test, а Delivery.Error.
+func (d *Dispatcher) Test(ctx context.Context, channel string) (Delivery, error) {
+	if err := d.logEnabled(channel); err != nil {
+		return Delivery{}, err
+	}
+	now := d.clock()
+	msg := Message{
+		Subject: "Test notification",
+		Body:    fmt.Sprintf("Test notification to %s at %s", channel, now.UTC().Format("2006-01-02 15:04:05Z")),
+		Event: Notification{
+			EventID:    uuid.NewString(),
+			EventType:  TestEventType,
+			OccurredAt: now,
+			Payload:    map[string]any{},
+		},
+	}
+	rec, err := d.attempt(ctx, channel, msg, 1, nil)
+	if err != nil {
+		d.logger.Warn().Err(err).Str("channel", channel).Msg("test notification failed")
+	}
+	return rec, nil
+}
+
+// ErrDeliveryLogDisabled — Dispatcher создан без DispatcherConfig.Deliveries
+var ErrDeliveryLogDisabled = errors.New("delivery log is disabled")
+
+func (d *Dispatcher) logEnabled(channel string) error {
+	if d.log == nil {
+		return ErrDeliveryLogDisabled
+	}
+	if _, ok := d.channels[channel]; !ok {
+		return fmt.Errorf("%w: channel %q", models.ErrNotFound, channel)
+	}
+	return nil
+}
+
+func (s subscription) render(n Notification) (Message, error) {
+	var subject, body bytes.Buffer
+	if err := s.subject.Execute(&subject, n); err != nil {
+		return Message{}, fmt.Errorf("subscription %q: render subject: %w", s.Name, err)
+	}
+	if err := s.body.Execute(&body, n); err != nil {
+		return Message{}, fmt.Errorf("subscription %q: render template: %w", s.Name, err)
+	}
+	return Message{Subject: subject.String(), Body: body.String(), Event: n}, nil
+}
+
+// notification собирает данные шаблонов из конверта события
+func notification(env events.Envelope) (Notification, error) {
+	n := Notification{
+		EventID:    env.EventID,
+		EventType:  env.EventType,
+		OccurredAt: env.OccurredAt,
+		MediaID:    env.AggregateID,
+	}
+	if err := json.Unmarshal(env.Payload, &n.Payload); err != nil {
+		return Notification{}, fmt.Errorf("decode %s payload: %w", env.EventType, err)
+	}
+	str := func(key string) string {
+		s, _ := n.Payload[key].(string)
+		return s
+	}
+	if id := str("media_id"); id != "" {
+		n.MediaID = id
+	}
+	n.OwnerID = str("owner_id")
+	n.Status = str("to")
+	n.Reason = str("reason")
+	return n, nil
+}
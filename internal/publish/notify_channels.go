@@ -0,0 +1,429 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/romariotrain/media-platform/pkg/webhooksig"
+)
+
+// notifyTimeout — timeout HTTP клиента и SMTP сессии каналов по умолчанию
+const notifyTimeout = 10 * time.Second
+
+// postJSON отправляет body в url; sign (может быть nil) подписывает запрос по готовому телу.
+// Любой ответ кроме 2xx — *APIError провайдера.
+func postJSON(ctx context.Context, client *http.Client, provider, url string, body any, sign func(*http.Request, []byte)) (Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return Response{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return Response{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sign != nil {
+		sign(req, data)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("%s notify: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	out := Response{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	if resp.StatusCode/100 != 2 {
+		return out, &APIError{Provider: provider, StatusCode: resp.StatusCode, Code: out.Body}
+	}
+	return out, nil
+}
+
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("invalid url")
+	}
+	return nil
+}
+
+// SlackChannel — Slack incoming webhook
+type SlackChannel struct {
+	url    string
+	client *http.Client
+}
+
+func newSlackChannel(settings json.RawMessage) (Notifier, error) {
+	var s struct {
+		Type          string `json:"type"`
+		WebhookURL    string `json:"webhook_url"`
+		WebhookURLEnv string `json:"webhook_url_env"`
+	}
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+	return NewSlackChannel(secret(s.WebhookURL, s.WebhookURLEnv), nil)
+}
+
+// NewSlackChannel создаёт канал по адресу incoming webhook; client может быть nil —
+// тогда используется клиент с timeout 10s
+func NewSlackChannel(webhookURL string, client *http.Client) (*SlackChannel, error) {
+	if err := validURL(webhookURL); err != nil {
+		return nil, fmt.Errorf("slack webhook url: %w", err)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: notifyTimeout}
+	}
+	return &SlackChannel{url: webhookURL, client: client}, nil
+}
+
+// slackEscape экранирует управляющие символы разметки Slack
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (c *SlackChannel) Notify(ctx context.Context, msg Message) error {
+	_, err := c.Deliver(ctx, msg)
+	return err
+}
+
+// Deliver отправляет тему жирным и тело следующей строкой
+func (c *SlackChannel) Deliver(ctx context.Context, msg Message) (Response, error) {
+	text := slackEscape.Replace(msg.Body)
+	if msg.Subject != "" {
+		text = "*" + slackEscape.Replace(msg.Subject) + "*\n" + text
+	}
+	return postJSON(ctx, c.client, "slack", c.url, map[string]string{"text": text}, nil)
+}
+
+// WebhookChannel — POST JSON {subject, body, event} на произвольный адрес. С ключами
+// доставка подписывается заголовком webhooksig.Header: получатель проверяет её пакетом
+// pkg/webhooksig.
+type WebhookChannel struct {
+	url     string
+	secrets []string
+	client  *http.Client
+	clock   func() time.Time
+}
+
+func newWebhookChannel(settings json.RawMessage) (Notifier, error) {
+	var s struct {
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		URLEnv    string `json:"url_env"`
+		Secret    string `json:"secret"`
+		SecretEnv string `json:"secret_env"`
+	}
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+	// Ключи через запятую: на время ротации доставка подписывается каждым
+	var secrets []string
+	for key := range strings.SplitSeq(secret(s.Secret, s.SecretEnv), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			secrets = append(secrets, key)
+		}
+	}
+	return NewWebhookChannel(secret(s.URL, s.URLEnv), secrets, nil)
+}
+
+// NewWebhookChannel создаёт канал; secrets — ключи подписи (пустой — без подписи, первый —
+// текущий), client может быть nil — тогда клиент с timeout 10s
+func NewWebhookChannel(target string, secrets []string, client *http.Client) (*WebhookChannel, error) {
+	if err := validURL(target); err != nil {
+		return nil, fmt.Errorf("webhook url: %w", err)
+	}
+	for i, key := range secrets {
+		if len(key) < webhooksig.MinSecretLength {
+			return nil, fmt.Errorf("webhook secret %d must be at least %d bytes, got: %d", i, webhooksig.MinSecretLength, len(key))
+		}
+	}
+	if client == nil {
+		client = &http.Client{Timeout: notifyTimeout}
+	}
+	return &WebhookChannel{url: target, secrets: secrets, client: client, clock: time.Now}, nil
+}
+
+func (c *WebhookChannel) Notify(ctx context.Context, msg Message) error {
+	_, err := c.Deliver(ctx, msg)
+	return err
+}
+
+// Deliver отправляет сообщение; повтор (redeliver) подписывается заново с текущим временем
+func (c *WebhookChannel) Deliver(ctx context.Context, msg Message) (Response, error) {
+	var sign func(*http.Request, []byte)
+	if len(c.secrets) > 0 {
+		sign = func(req *http.Request, body []byte) {
+			webhooksig.SignRequest(req, body, c.clock(), c.secrets...)
+		}
+	}
+	return postJSON(ctx, c.client, "webhook", c.url, struct {
+		Subject string       `json:"subject"`
+		Body    string       `json:"body"`
+		Event   Notification `json:"event"`
+	}{msg.Subject, msg.Body, msg.Event}, sign)
+}
+
+// snsSubjectLimit — SNS принимает тему не длиннее 100 символов
+const snsSubjectLimit = 100
+
+// SNSConfig содержит конфигурацию SNSChannel
+type SNSConfig struct {
+	TopicARN        string // arn:aws:sns:{region}:{account}:{topic}; регион берётся из него
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string       // временные credentials (STS); пустой — не передаётся
+	Endpoint        string       // default: https://sns.{region}.amazonaws.com
+	HTTPClient      *http.Client // default: timeout 10s
+}
+
+// SNSChannel — публикация в топик AWS SNS (Publish, подпись AWS Signature V4)
+type SNSChannel struct {
+	config SNSConfig
+	region string
+	client *http.Client
+	clock  func() time.Time
+}
+
+func newSNSChannel(settings json.RawMessage) (Notifier, error) {
+	var s struct {
+		Type     string `json:"type"`
+		TopicARN string `json:"topic_arn"`
+		Endpoint string `json:"endpoint"`
+	}
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+	// Credentials — стандартные переменные AWS, как у S3 и CloudFront
+	return NewSNSChannel(SNSConfig{
+		TopicARN:        s.TopicARN,
+		AccessKeyID:     secret("", "AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: secret("", "AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    secret("", "AWS_SESSION_TOKEN"),
+		Endpoint:        s.Endpoint,
+	})
+}
+
+func NewSNSChannel(cfg SNSConfig) (*SNSChannel, error) {
+	parts := strings.Split(cfg.TopicARN, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return nil, fmt.Errorf("invalid sns topic arn %q", cfg.TopicARN)
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("sns credentials are required")
+	}
+	region := parts[3]
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://sns." + region + ".amazonaws.com"
+	}
+	if err := validURL(cfg.Endpoint); err != nil {
+		return nil, fmt.Errorf("sns endpoint: %w", err)
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/") + "/"
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: notifyTimeout}
+	}
+	return &SNSChannel{config: cfg, region: region, client: client, clock: time.Now}, nil
+}
+
+func (c *SNSChannel) Notify(ctx context.Context, msg Message) error {
+	_, err := c.Deliver(ctx, msg)
+	return err
+}
+
+// Deliver публикует сообщение; ответ SNS — XML с MessageId
+func (c *SNSChannel) Deliver(ctx context.Context, msg Message) (Response, error) {
+	form := url.Values{
+		"Action":   {"Publish"},
+		"Version":  {"2010-03-31"},
+		"TopicArn": {c.config.TopicARN},
+		"Message":  {msg.Body},
+	}
+	if subject := snsSubject(msg.Subject); subject != "" {
+		form.Set("Subject", subject)
+	}
+	body := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := awsCredentials{AccessKeyID: c.config.AccessKeyID, SecretAccessKey: c.config.SecretAccessKey, SessionToken: c.config.SessionToken}
+	signV4(req, body, creds, c.region, "sns", c.clock())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("sns notify: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	out := Response{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code string `xml:"Error>Code"`
+		}
+		_ = xml.Unmarshal(data, &e)
+		return out, &APIError{Provider: "sns", StatusCode: resp.StatusCode, Code: e.Code}
+	}
+	return out, nil
+}
+
+// snsSubject — тема в ограничениях SNS: одна строка, не длиннее snsSubjectLimit символов
+func snsSubject(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= snsSubjectLimit {
+		return s
+	}
+	return string([]rune(s)[:snsSubjectLimit-1]) + "…"
+}
+
+// EmailConfig содержит конфигурацию EmailChannel
+type EmailConfig struct {
+	Addr     string // host:port SMTP сервера; STARTTLS — если сервер его поддерживает
+	Username string // пустой — без аутентификации
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailChannel — письмо через SMTP
+type EmailChannel struct {
+	config EmailConfig
+	host   string
+	clock  func() time.Time
+}
+
+func newEmailChannel(settings json.RawMessage) (Notifier, error) {
+	var s struct {
+		Type        string   `json:"type"`
+		Addr        string   `json:"addr"`
+		Username    string   `json:"username"`
+		Password    string   `json:"password"`
+		PasswordEnv string   `json:"password_env"`
+		From        string   `json:"from"`
+		To          []string `json:"to"`
+	}
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+	return NewEmailChannel(EmailConfig{
+		Addr:     s.Addr,
+		Username: s.Username,
+		Password: secret(s.Password, s.PasswordEnv),
+		From:     s.From,
+		To:       s.To,
+	})
+}
+
+func NewEmailChannel(cfg EmailConfig) (*EmailChannel, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("invalid smtp addr %q", cfg.Addr)
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid from address %q", cfg.From)
+	}
+	if len(cfg.To) == 0 {
+		return nil, errors.New("email recipients are required")
+	}
+	for _, to := range cfg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q", to)
+		}
+	}
+	return &EmailChannel{config: cfg, host: host, clock: time.Now}, nil
+}
+
+// Notify отправляет письмо всем получателям одной SMTP сессией. Ошибки 4xx сервера временные
+// и повторяются, 5xx — нет.
+func (c *EmailChannel) Notify(ctx context.Context, msg Message) error {
+	data, err := c.message(msg)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, notifyTimeout)
+		defer cancel()
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if c.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.config.Username, c.config.Password, c.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(c.config.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, to := range c.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return client.Quit()
+}
+
+// message собирает письмо: text/plain в UTF-8, quoted-printable
+func (c *EmailChannel) message(msg Message) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(k, v string) { b.WriteString(k + ": " + v + "\r\n") }
+	header("From", c.config.From)
+	header("To", strings.Join(c.config.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(msg.Subject), " ")))
+	header("Date", c.clock().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,265 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/pkg/webhooksig"
+)
+
+// recordingNotifier записывает уведомления; errs — ошибки следующих вызовов по порядку
+type recordingNotifier struct {
+	mu   sync.Mutex
+	msgs []Message
+	errs []error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.errs) > 0 {
+		err := n.errs[0]
+		n.errs = n.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	n.msgs = append(n.msgs, msg)
+	return nil
+}
+
+func statusChanged(t *testing.T, id uuid.UUID, to models.Status, reason string) events.Envelope {
+	t.Helper()
+	payload, err := json.Marshal(map[string]string{"media_id": id.String(), "from": "processing", "to": string(to), "reason": reason})
+	require.NoError(t, err)
+	return events.Envelope{
+		EventID:       uuid.NewString(),
+		EventType:     "MediaStatusChanged",
+		SchemaVersion: 1,
+		AggregateID:   id.String(),
+		OccurredAt:    time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Payload:       payload,
+	}
+}
+
+func TestDispatcher_Subscriptions(t *testing.T) {
+	ops, audit := &recordingNotifier{}, &recordingNotifier{}
+	d, err := NewDispatcher(DispatcherConfig{
+		Channels: map[string]Notifier{"ops": ops, "audit": audit},
+		Subscriptions: []Subscription{
+			{
+				Name: "processing-failed", Events: []string{"MediaStatusChanged"}, Statuses: []models.Status{models.FailedStatus},
+				Channels: []string{"ops"}, Subject: "[{{.Subscription}}] {{.MediaID}}", Template: "Media {{.MediaID}} failed: {{.Reason}}",
+			},
+			{Name: "all", Events: []string{"MediaStatusChanged", "MediaDeleted"}, Channels: []string{"audit"}},
+		},
+		Logger: zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	id := uuid.New()
+
+	require.NoError(t, d.Handle(ctx, statusChanged(t, id, models.ReadyStatus, "")))
+	require.Empty(t, ops.msgs)
+	require.Len(t, audit.msgs, 1)
+	require.Equal(t, "MediaStatusChanged: media "+id.String()+" is ready at 2026-03-01 10:00:00Z", audit.msgs[0].Body)
+
+	require.NoError(t, d.Handle(ctx, statusChanged(t, id, models.FailedStatus, "transcoder crashed")))
+	require.Len(t, ops.msgs, 1)
+	require.Equal(t, "[processing-failed] "+id.String(), ops.msgs[0].Subject)
+	require.Equal(t, "Media "+id.String()+" failed: transcoder crashed", ops.msgs[0].Body)
+	require.Equal(t, "failed", ops.msgs[0].Event.Status)
+	require.Equal(t, int64(3), d.Metrics().Sent.Load())
+
+	// Неподписанные события не разбираются вовсе
+	require.NoError(t, d.Handle(ctx, events.Envelope{EventType: "MediaCreated", Payload: json.RawMessage(`not json`)}))
+
+	_, err = NewDispatcher(DispatcherConfig{
+		Channels:      map[string]Notifier{"ops": ops},
+		Subscriptions: []Subscription{{Name: "s", Events: []string{"MediaDeleted"}, Channels: []string{"pager"}}},
+	})
+	require.ErrorContains(t, err, `unknown channel "pager"`)
+	_, err = NewDispatcher(DispatcherConfig{
+		Channels:      map[string]Notifier{"ops": ops},
+		Subscriptions: []Subscription{{Name: "s", Events: []string{"MediaDeleted"}, Channels: []string{"ops"}, Template: "{{.Broken"}},
+	})
+	require.Error(t, err)
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	flaky := &recordingNotifier{errs: []error{
+		&APIError{Provider: "slack", StatusCode: http.StatusServiceUnavailable}, nil,
+		&APIError{Provider: "slack", StatusCode: http.StatusNotFound},
+	}}
+	healthy := &recordingNotifier{}
+	d, err := NewDispatcher(DispatcherConfig{
+		Channels:      map[string]Notifier{"flaky": flaky, "healthy": healthy},
+		Subscriptions: []Subscription{{Name: "deleted", Events: []string{"MediaStatusChanged"}, Channels: []string{"flaky", "healthy"}}},
+		Logger:        zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	d.sleep = func(context.Context, time.Duration) error { return nil }
+	ctx := context.Background()
+
+	require.NoError(t, d.Handle(ctx, statusChanged(t, uuid.New(), models.ReadyStatus, "")))
+	require.Len(t, flaky.msgs, 1)
+
+	// Постоянная ошибка канала не повторяется и не мешает другим каналам
+	err = d.Handle(ctx, statusChanged(t, uuid.New(), models.ReadyStatus, ""))
+	require.ErrorContains(t, err, `channel "flaky"`)
+	require.Len(t, flaky.msgs, 1)
+	require.Len(t, healthy.msgs, 2)
+	require.Equal(t, int64(1), d.Metrics().Failed.Load())
+}
+
+func TestLoadNotifyConfig(t *testing.T) {
+	t.Setenv("TEST_SLACK_URL", "https://hooks.slack.test/services/x")
+	channels, subs, err := LoadNotifyConfig(strings.NewReader(`{
+		"channels": {
+			"ops-slack": {"type": "slack", "webhook_url_env": "TEST_SLACK_URL"},
+			"ops-email": {"type": "email", "addr": "smtp.example.com:587", "from": "media@example.com", "to": ["ops@example.com"]},
+			"hook": {"type": "webhook", "url": "https://example.com/notify"}
+		},
+		"subscriptions": [{"name": "failed", "events": ["MediaStatusChanged"], "statuses": ["failed"], "channels": ["ops-slack"]}]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, channels, 3)
+	require.Equal(t, "https://hooks.slack.test/services/x", channels["ops-slack"].(*SlackChannel).url)
+	require.Equal(t, []models.Status{models.FailedStatus}, subs[0].Statuses)
+
+	_, _, err = LoadNotifyConfig(strings.NewReader(`{"channels": {"x": {"type": "pager"}}}`))
+	require.ErrorContains(t, err, `unknown type "pager"`)
+	_, _, err = LoadNotifyConfig(strings.NewReader(`{"channels": {"x": {"type": "slack", "webhook": "https://a.b"}}}`))
+	require.Error(t, err, "unknown settings field")
+
+	// Плагин: тип канала, зарегистрированный снаружи
+	custom := &recordingNotifier{}
+	RegisterChannel("test-custom", func(json.RawMessage) (Notifier, error) { return custom, nil })
+	require.Contains(t, ChannelTypes(), "test-custom")
+	channels, _, err = LoadNotifyConfig(strings.NewReader(`{"channels": {"x": {"type": "test-custom"}}}`))
+	require.NoError(t, err)
+	require.Same(t, custom, channels["x"])
+}
+
+func TestSlackAndWebhookChannels(t *testing.T) {
+	var (
+		bodies     []map[string]any
+		signatures []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(raw, &body))
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(webhooksig.Header))
+		if r.URL.Path == "/signed" {
+			verifier, err := webhooksig.NewVerifier(webhooksig.VerifierConfig{Secrets: []string{strings.Repeat("o", 32)}})
+			require.NoError(t, err)
+			require.NoError(t, verifier.Verify(raw, r.Header.Get(webhooksig.Header)))
+		}
+		if r.URL.Path == "/gone" {
+			http.Error(w, "no_service", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+	msg := Message{Subject: "Failed <1>", Body: "a & b", Event: Notification{EventType: "MediaStatusChanged", MediaID: "m1"}}
+
+	slack, err := NewSlackChannel(srv.URL+"/hook", nil)
+	require.NoError(t, err)
+	require.NoError(t, slack.Notify(ctx, msg))
+	require.Equal(t, "*Failed &lt;1&gt;*\na &amp; b", bodies[0]["text"])
+
+	hook, err := NewWebhookChannel(srv.URL+"/hook", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, hook.Notify(ctx, msg))
+	require.Equal(t, "a & b", bodies[1]["body"])
+	require.Equal(t, "m1", bodies[1]["event"].(map[string]any)["media_id"])
+	require.Empty(t, signatures[1])
+
+	// Ротация: подписано новым и старым ключом, получатель со старым ключом принимает
+	signed, err := NewWebhookChannel(srv.URL+"/signed", []string{strings.Repeat("n", 32), strings.Repeat("o", 32)}, nil)
+	require.NoError(t, err)
+	require.NoError(t, signed.Notify(ctx, msg))
+	require.Equal(t, 2, strings.Count(signatures[2], "v1="))
+
+	_, err = NewWebhookChannel(srv.URL, []string{"short"}, nil)
+	require.Error(t, err)
+
+	gone, err := NewSlackChannel(srv.URL+"/gone", nil)
+	require.NoError(t, err)
+	err = gone.Notify(ctx, msg)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "no_service", apiErr.Code)
+	require.False(t, retryable(err))
+}
+
+func TestSNSChannel(t *testing.T) {
+	var (
+		form url.Values
+		auth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		_, _ = io.WriteString(w, `<PublishResponse><PublishResult><MessageId>1</MessageId></PublishResult></PublishResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewSNSChannel(SNSConfig{TopicARN: "alerts", AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	require.Error(t, err)
+
+	sns, err := NewSNSChannel(SNSConfig{
+		TopicARN: "arn:aws:sns:eu-central-1:123456789012:media-alerts", AccessKeyID: "AKID", SecretAccessKey: "secret",
+		Endpoint: srv.URL,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sns.Notify(context.Background(), Message{Subject: strings.Repeat("x", 150), Body: "media failed"}))
+	require.Equal(t, "Publish", form.Get("Action"))
+	require.Equal(t, "arn:aws:sns:eu-central-1:123456789012:media-alerts", form.Get("TopicArn"))
+	require.Equal(t, "media failed", form.Get("Message"))
+	require.Equal(t, snsSubjectLimit, len([]rune(form.Get("Subject"))))
+	require.Contains(t, auth, "/eu-central-1/sns/aws4_request")
+}
+
+func TestEmailChannel_Message(t *testing.T) {
+	_, err := NewEmailChannel(EmailConfig{Addr: "smtp.example.com", From: "media@example.com", To: []string{"ops@example.com"}})
+	require.Error(t, err)
+
+	email, err := NewEmailChannel(EmailConfig{Addr: "smtp.example.com:587", From: "media@example.com", To: []string{"ops@example.com", "dev@example.com"}})
+	require.NoError(t, err)
+	email.clock = func() time.Time { return time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC) }
+
+	data, err := email.message(Message{Subject: "Обработка не удалась", Body: "line 1\nline 2"})
+	require.NoError(t, err)
+	s := string(data)
+	require.Contains(t, s, "To: ops@example.com, dev@example.com\r\n")
+	require.Contains(t, s, "Subject: =?utf-8?q?")
+	require.Contains(t, s, "Date: Sun, 01 Mar 2026 10:00:00 +0000\r\n")
+	require.True(t, strings.HasSuffix(s, "\r\n\r\nline 1\r\nline 2"), s)
+
+	// Без SMTP сервера — ошибка соединения, она временная
+	email.config.Addr = "127.0.0.1:1"
+	err = email.Notify(context.Background(), Message{Body: "x"})
+	require.Error(t, err)
+	require.True(t, retryable(err))
+	require.False(t, errors.Is(err, context.DeadlineExceeded))
+}
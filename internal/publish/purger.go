@@ -0,0 +1,248 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// PurgeMetrics содержит метрики Purger
+type PurgeMetrics struct {
+	Purged   atomic.Int64 // Медиа, кэш которых сброшен
+	Failures atomic.Int64 // Неудачные запросы к CDN API
+	Dropped  atomic.Int64 // Медиа, кэш которых не удалось сбросить за все попытки
+	Pending  atomic.Int64 // Медиа, ждущие сброса
+
+	// Latency — длительность запросов к CDN API, успешных и нет
+	Latency prometheus.Histogram
+}
+
+func newPurgeMetrics(provider string) *PurgeMetrics {
+	return &PurgeMetrics{
+		Latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "publish_cdn_purge_duration_seconds",
+			Help:        "Длительность запросов сброса кэша к CDN API",
+			ConstLabels: prometheus.Labels{"provider": provider},
+			Buckets:     []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+	}
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *PurgeMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "publish_cdn_purged_media_total",
+			Help: "Медиа, кэш которых сброшен в CDN",
+		}, func() float64 { return float64(m.Purged.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "publish_cdn_purge_errors_total",
+			Help: "Неудачные запросы сброса кэша к CDN API",
+		}, func() float64 { return float64(m.Failures.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "publish_cdn_purge_dropped_media_total",
+			Help: "Медиа, кэш которых не удалось сбросить за все попытки",
+		}, func() float64 { return float64(m.Dropped.Load()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "publish_cdn_purge_pending_media",
+			Help: "Медиа, ждущие сброса кэша",
+		}, func() float64 { return float64(m.Pending.Load()) }),
+		m.Latency,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgerConfig содержит конфигурацию Purger
+type PurgerConfig struct {
+	CDN      CDNInvalidator
+	Provider string // метка provider у метрик: cloudfront, fastly
+	// FlushInterval — сколько копятся медиа до сброса; полный батч (CDN.MaxBatch)
+	// сбрасывается сразу. Default: 5s
+	FlushInterval time.Duration
+	MaxAttempts   int           // попыток на батч; default: 5
+	RetryBackoff  time.Duration // пауза перед повтором, удваивается; default: 1s
+	MaxBackoff    time.Duration // default: 30s
+	Logger        zerolog.Logger
+}
+
+// Purger копит медиа, кэш которых надо сбросить, и сбрасывает их батчами с повторами.
+// Одно медиа в батче сбрасывается один раз, сколько бы событий о нём ни пришло.
+type Purger struct {
+	cdn        CDNInvalidator
+	interval   time.Duration
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	pending []uuid.UUID
+	queued  map[uuid.UUID]struct{}
+	full    chan struct{} // сигнал Start: набрался полный батч
+
+	flushMu sync.Mutex // сбросы не пересекаются: Start и Flush при остановке
+	sleep   func(ctx context.Context, d time.Duration) error
+	metrics *PurgeMetrics
+	logger  zerolog.Logger
+}
+
+func NewPurger(cfg PurgerConfig) (*Purger, error) {
+	if cfg.CDN == nil {
+		return nil, errors.New("cdn invalidator is required")
+	}
+	if cfg.CDN.MaxBatch() <= 0 {
+		return nil, fmt.Errorf("cdn max batch must be positive, got: %d", cfg.CDN.MaxBatch())
+	}
+	if cfg.FlushInterval < 0 || cfg.MaxAttempts < 0 || cfg.RetryBackoff < 0 || cfg.MaxBackoff < 0 {
+		return nil, errors.New("purger intervals and attempts cannot be negative")
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "cdn"
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	return &Purger{
+		cdn:        cfg.CDN,
+		interval:   cfg.FlushInterval,
+		attempts:   cfg.MaxAttempts,
+		backoff:    cfg.RetryBackoff,
+		maxBackoff: max(cfg.MaxBackoff, cfg.RetryBackoff),
+		queued:     make(map[uuid.UUID]struct{}),
+		full:       make(chan struct{}, 1),
+		sleep:      sleepContext,
+		metrics:    newPurgeMetrics(cfg.Provider),
+		logger:     cfg.Logger.With().Str("component", "cdn_purger").Str("provider", cfg.Provider).Logger(),
+	}, nil
+}
+
+// Metrics возвращает метрики Purger
+func (p *Purger) Metrics() *PurgeMetrics { return p.metrics }
+
+// Enqueue ставит медиа в очередь на сброс кэша; не блокируется
+func (p *Purger) Enqueue(ids ...uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := p.queued[id]; ok {
+			continue
+		}
+		p.queued[id] = struct{}{}
+		p.pending = append(p.pending, id)
+	}
+	p.metrics.Pending.Store(int64(len(p.pending)))
+	if len(p.pending) >= p.cdn.MaxBatch() {
+		select {
+		case p.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start сбрасывает накопленное раз в FlushInterval и сразу, как набирается полный батч.
+// Возвращается при отмене ctx; то, что осталось в очереди, сбрасывает Flush.
+func (p *Purger) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-p.full:
+		}
+		if err := p.Flush(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error().Err(err).Msg("cdn purge failed")
+		}
+	}
+}
+
+// Flush сбрасывает всё, что в очереди, батчами по CDN.MaxBatch. Медиа, которые не удалось
+// сбросить за MaxAttempts, отбрасываются с ошибкой; при отмене ctx несброшенные возвращаются
+// в очередь. Вызывается и при остановке сервиса, после остановки consumer'а.
+func (p *Purger) Flush(ctx context.Context) error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	ids := p.pending
+	p.pending = nil
+	p.queued = make(map[uuid.UUID]struct{})
+	p.mu.Unlock()
+
+	var errs []error
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), p.cdn.MaxBatch())]
+		if err := p.purge(ctx, batch); err != nil {
+			if ctx.Err() != nil {
+				p.Enqueue(ids...)
+				return err
+			}
+			errs = append(errs, err)
+		}
+		ids = ids[len(batch):]
+	}
+	p.mu.Lock()
+	p.metrics.Pending.Store(int64(len(p.pending)))
+	p.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// purge сбрасывает батч с повторами временных ошибок
+func (p *Purger) purge(ctx context.Context, batch []uuid.UUID) error {
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := p.cdn.Invalidate(ctx, batch)
+		p.metrics.Latency.Observe(time.Since(start).Seconds())
+		if err == nil {
+			p.metrics.Purged.Add(int64(len(batch)))
+			p.logger.Debug().Int("media", len(batch)).Int("attempt", attempt).Msg("cdn cache purged")
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		p.metrics.Failures.Add(1)
+
+		if !retryable(err) || attempt >= p.attempts {
+			p.metrics.Dropped.Add(int64(len(batch)))
+			return fmt.Errorf("purge %d media after %d attempts: %w", len(batch), attempt, err)
+		}
+		p.logger.Warn().Err(err).Int("media", len(batch)).Int("attempt", attempt).Dur("backoff", backoff).Msg("cdn purge failed, retrying")
+		if err := p.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, p.maxBackoff)
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
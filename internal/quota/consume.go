@@ -0,0 +1,65 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// UsageHandlerConfig содержит конфигурацию UsageHandler
+type UsageHandlerConfig struct {
+	Store UsageStore
+	// Events получает QuotaUsageChanged по каждому учтённому событию; nil — не публиковать.
+	// Публикуется и повторная доставка: в цикле read-process-write (kafka.Consumer.RunTransactional)
+	// её видно только после отката, когда запись прошлой попытки откачена вместе с offset'ом
+	Events EnvelopePublisher
+	Logger zerolog.Logger
+}
+
+// UsageHandler применяет к usage события media
+type UsageHandler struct {
+	store  UsageStore
+	events EnvelopePublisher
+	clock  func() time.Time
+	logger zerolog.Logger
+}
+
+func NewUsageHandler(cfg UsageHandlerConfig) (*UsageHandler, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("usage store is required")
+	}
+	return &UsageHandler{
+		store:  cfg.Store,
+		events: cfg.Events,
+		clock:  time.Now,
+		logger: cfg.Logger.With().Str("component", "quota_usage").Logger(),
+	}, nil
+}
+
+// Handle учитывает событие media. События, не влияющие на usage, пропускаются.
+func (h *UsageHandler) Handle(ctx context.Context, env events.Envelope) error {
+	adj, ok, err := AdjustmentFromEvent(env.EventID, env.EventType, env.Payload)
+	if err != nil || !ok {
+		return err
+	}
+	applied, err := h.store.Apply(ctx, adj)
+	if err != nil {
+		return fmt.Errorf("apply %s: %w", env.EventType, err)
+	}
+	if !applied {
+		h.logger.Debug().Str("event_id", env.EventID).Msg("usage: event already counted")
+	}
+	if h.events == nil {
+		return nil
+	}
+	out, err := events.Quota.Wrap(NewQuotaUsageChanged(adj, h.clock()))
+	if err != nil {
+		return err
+	}
+	return h.events.PublishEnvelope(ctx, out, time.Time{})
+}
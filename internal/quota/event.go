@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QuotaReconciled — сверка исправила usage владельца: Previous — usage по событиям,
+// Actual — фактический по таблице media, он теперь и записан
+type QuotaReconciled struct {
+	eventID    uuid.UUID
+	owner      string
+	previous   Usage
+	actual     Usage
+	occurredAt time.Time
+}
+
+func NewQuotaReconciled(d Drift, at time.Time) *QuotaReconciled {
+	return &QuotaReconciled{
+		eventID:    uuid.New(),
+		owner:      d.Owner,
+		previous:   d.Recorded,
+		actual:     d.Actual,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent; агрегат — владелец (uuid.Nil — общий пул)
+func (e *QuotaReconciled) EventID() uuid.UUID { return e.eventID }
+func (e *QuotaReconciled) EventType() string  { return "QuotaReconciled" }
+func (e *QuotaReconciled) AggregateID() uuid.UUID {
+	id, _ := uuid.Parse(e.owner)
+	return id
+}
+func (e *QuotaReconciled) OccurredAt() time.Time { return e.occurredAt }
+
+func (e *QuotaReconciled) MarshalJSON() ([]byte, error) {
+	type usage struct {
+		Objects int64 `json:"objects"`
+		Bytes   int64 `json:"bytes"`
+	}
+	return json.Marshal(struct {
+		EventID    uuid.UUID `json:"event_id"`
+		OwnerID    string    `json:"owner_id,omitempty"`
+		Previous   usage     `json:"previous"`
+		Actual     usage     `json:"actual"`
+		OccurredAt time.Time `json:"occurred_at"`
+	}{
+		EventID:    e.eventID,
+		OwnerID:    e.owner,
+		Previous:   usage(e.previous),
+		Actual:     usage(e.actual),
+		OccurredAt: e.occurredAt,
+	})
+}
+
+// usageChangedNamespace — пространство имён id событий QuotaUsageChanged
+var usageChangedNamespace = uuid.MustParse("6f1c2a52-3f0e-4f7b-9a61-8d2f0c6e4b17")
+
+// QuotaUsageChanged — событие media учтено в usage. EventID выводится из id события media:
+// повторная запись после отката транзакции получает тот же id.
+type QuotaUsageChanged struct {
+	eventID    uuid.UUID
+	adj        Adjustment
+	occurredAt time.Time
+}
+
+func NewQuotaUsageChanged(adj Adjustment, at time.Time) *QuotaUsageChanged {
+	return &QuotaUsageChanged{
+		eventID:    uuid.NewSHA1(usageChangedNamespace, []byte(adj.EventID)),
+		adj:        adj,
+		occurredAt: at,
+	}
+}
+
+// Реализация интерфейса DomainEvent; агрегат — владелец (uuid.Nil — общий пул)
+func (e *QuotaUsageChanged) EventID() uuid.UUID { return e.eventID }
+func (e *QuotaUsageChanged) EventType() string  { return "QuotaUsageChanged" }
+func (e *QuotaUsageChanged) AggregateID() uuid.UUID {
+	id, _ := uuid.Parse(e.adj.Owner)
+	return id
+}
+func (e *QuotaUsageChanged) OccurredAt() time.Time { return e.occurredAt }
+
+func (e *QuotaUsageChanged) MarshalJSON() ([]byte, error) {
+	type usage struct {
+		Objects int64 `json:"objects"`
+		Bytes   int64 `json:"bytes"`
+	}
+	return json.Marshal(struct {
+		EventID       uuid.UUID `json:"event_id"`
+		SourceEventID string    `json:"source_event_id"`
+		OwnerID       string    `json:"owner_id,omitempty"`
+		Delta         usage     `json:"delta"`
+		OccurredAt    time.Time `json:"occurred_at"`
+	}{
+		EventID:       e.eventID,
+		SourceEventID: e.adj.EventID,
+		OwnerID:       e.adj.Owner,
+		Delta:         usage{Objects: e.adj.Objects, Bytes: e.adj.Bytes},
+		OccurredAt:    e.occurredAt,
+	})
+}
@@ -0,0 +1,477 @@
+package quota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/apierr"
+)
+
+// Заголовки лимита в ответах quota и ingest
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset" // unix-время в секундах
+)
+
+// Админские ручки доступны со scope admin в X-Scopes — как и /admin/ media API,
+// заголовок проставляет gateway после аутентификации
+const (
+	ScopesHeader = "X-Scopes"
+	AdminScope   = "admin"
+)
+
+// SetRateLimitHeaders пишет лимит в заголовки ответа; при отказе по лимиту загрузок — ещё и
+// Retry-After (отказ по лимиту хранения ожиданием не лечится)
+func SetRateLimitHeaders(h http.Header, d Decision, now time.Time) {
+	h.Set(HeaderRateLimitLimit, strconv.Itoa(d.Limit))
+	h.Set(HeaderRateLimitRemaining, strconv.Itoa(d.Remaining))
+	h.Set(HeaderRateLimitReset, strconv.FormatInt(d.ResetAt.Unix(), 10))
+	if !d.Allowed && d.Exceeded == ExceededUploads {
+		h.Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(d.ResetAt.Sub(now).Seconds())))))
+	}
+}
+
+// ConsumeRequest — тело POST /limits/uploads
+type ConsumeRequest struct {
+	OwnerID string `json:"owner_id"` // пустой — общий пул медиа без владельца
+	Count   int    `json:"count"`    // default: 1
+	Bytes   int64  `json:"bytes"`    // сколько байт добавят загрузки, для лимита хранения
+}
+
+// DecisionResponse — ответ POST /limits/uploads; при 429 заполнены code, message и exceeded
+type DecisionResponse struct {
+	Allowed       bool          `json:"allowed"`
+	Exceeded      string        `json:"exceeded,omitempty"`
+	Limit         int           `json:"limit"`
+	WindowSeconds int64         `json:"window_seconds"`
+	Remaining     int           `json:"remaining"`
+	ResetAt       time.Time     `json:"reset_at"`
+	Plan          *PlanResponse `json:"plan,omitempty"`
+	Code          string        `json:"code,omitempty"`
+	Message       string        `json:"message,omitempty"`
+}
+
+// PlanResponse — тариф владельца с действующими лимитами; 0 — без ограничения.
+// upgrade_available — есть тариф выше: клиент может предложить его при отказе.
+type PlanResponse struct {
+	Name             string        `json:"name"`
+	MaxObjects       int64         `json:"max_objects"`
+	MaxBytes         int64         `json:"max_bytes"`
+	UploadLimit      int           `json:"upload_limit"`
+	UploadWindowSecs int64         `json:"upload_window_seconds"`
+	UpgradeAvailable bool          `json:"upgrade_available"`
+	Usage            UsageResponse `json:"usage"`
+}
+
+func planResponse(p *PlanStatus) *PlanResponse {
+	if p == nil {
+		return nil
+	}
+	return &PlanResponse{
+		Name:             p.Name,
+		MaxObjects:       p.Limits.MaxObjects,
+		MaxBytes:         p.Limits.MaxBytes,
+		UploadLimit:      p.Limits.Uploads.Uploads,
+		UploadWindowSecs: int64(p.Limits.Uploads.Window / time.Second),
+		UpgradeAvailable: p.Upgradable,
+		Usage:            UsageResponse(p.Usage),
+	}
+}
+
+func (p *PlanResponse) status() *PlanStatus {
+	if p == nil {
+		return nil
+	}
+	return &PlanStatus{
+		Name: p.Name,
+		Limits: Limits{
+			MaxObjects: p.MaxObjects,
+			MaxBytes:   p.MaxBytes,
+			Uploads:    Limit{Uploads: p.UploadLimit, Window: time.Duration(p.UploadWindowSecs) * time.Second},
+		},
+		Usage:      Usage(p.Usage),
+		Upgradable: p.UpgradeAvailable,
+	}
+}
+
+func decisionResponse(d Decision) DecisionResponse {
+	resp := DecisionResponse{
+		Allowed:       d.Allowed,
+		Exceeded:      d.Exceeded,
+		Limit:         d.Limit,
+		WindowSeconds: int64(d.Window / time.Second),
+		Remaining:     d.Remaining,
+		ResetAt:       d.ResetAt,
+		Plan:          planResponse(d.Plan),
+	}
+	if err := d.Err(); err != nil {
+		m := apierr.Lookup(err)
+		resp.Code, resp.Message = m.Code, err.Error()
+	}
+	return resp
+}
+
+// UsageResponse — usage владельца
+type UsageResponse struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// UploadLimitResponse — лимит загрузок владельца без списания
+type UploadLimitResponse struct {
+	Limit         int       `json:"limit"`
+	WindowSeconds int64     `json:"window_seconds"`
+	Remaining     int       `json:"remaining"`
+	ResetAt       time.Time `json:"reset_at"` // когда станет доступна следующая загрузка
+}
+
+// LimitsResponse — лимиты владельца
+type LimitsResponse struct {
+	Uploads UploadLimitResponse `json:"uploads"`
+}
+
+// OverridesRequest — тело PUT /quota/{owner}/overrides и overrides в ответах; null — как в тарифе
+type OverridesRequest struct {
+	MaxObjects  *int64 `json:"max_objects"`
+	MaxBytes    *int64 `json:"max_bytes"`
+	UploadLimit *int   `json:"upload_limit"`
+}
+
+// OwnerQuotaResponse — ответ GET /quota/{owner}; plan и overrides — если настроены тарифы
+type OwnerQuotaResponse struct {
+	OwnerID   string            `json:"owner_id"`
+	Usage     UsageResponse     `json:"usage"`
+	Limits    LimitsResponse    `json:"limits"`
+	Plan      *PlanResponse     `json:"plan,omitempty"`
+	Overrides *OverridesRequest `json:"overrides,omitempty"`
+}
+
+// AssignPlanRequest — тело PUT /quota/{owner}/plan
+type AssignPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// PlansResponse — ответ GET /plans
+type PlansResponse struct {
+	Plans []PlanResponse `json:"plans"` // по возрастанию тарифа; usage пустой
+}
+
+// HandlerConfig содержит конфигурацию Handler
+type HandlerConfig struct {
+	Limiter *Limiter
+	Usage   UsageStore
+	Plans   PlanStore // nil — без тарифов и админских ручек
+	Logger  zerolog.Logger
+}
+
+// Handler — HTTP API quota: GET /quota/{owner} отдаёт usage и лимиты владельца,
+// POST /limits/uploads засчитывает его загрузки. С тарифами есть админские ручки (scope admin):
+// GET /plans, PUT /quota/{owner}/plan, PUT и DELETE /quota/{owner}/overrides.
+type Handler struct {
+	limiter *Limiter
+	usage   UsageStore
+	plans   PlanStore
+	logger  zerolog.Logger
+}
+
+func NewHandler(cfg HandlerConfig) (*Handler, error) {
+	if cfg.Limiter == nil {
+		return nil, errors.New("limiter is required")
+	}
+	if cfg.Usage == nil {
+		return nil, errors.New("usage store is required")
+	}
+	return &Handler{
+		limiter: cfg.Limiter,
+		usage:   cfg.Usage,
+		plans:   cfg.Plans,
+		logger:  cfg.Logger.With().Str("component", "quota_http").Logger(),
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/health":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case r.URL.Path == "/limits/uploads":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		h.ConsumeUploads(w, r)
+	case r.URL.Path == "/plans" && h.plans != nil:
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		h.admin(w, r, h.Plans)
+	case strings.HasPrefix(r.URL.Path, "/quota/"):
+		h.serveOwner(w, r)
+	default:
+		writeError(w, http.StatusNotFound, apierr.CodeNotFound, "not found")
+	}
+}
+
+// serveOwner разбирает /quota/{owner}[/plan|/overrides]
+func (h *Handler) serveOwner(w http.ResponseWriter, r *http.Request) {
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/quota/"), "/")
+	owner, err := uuid.Parse(id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, apierr.CodeInvalidArgument, "invalid owner id")
+		return
+	}
+
+	var handle func(http.ResponseWriter, *http.Request, string)
+	method := http.MethodGet
+	switch {
+	case sub == "":
+		handle = h.OwnerQuota
+	case sub == "plan" && h.plans != nil:
+		handle, method = h.AssignPlan, http.MethodPut
+	case sub == "overrides" && h.plans != nil:
+		handle, method = h.SetOverrides, http.MethodPut
+		if r.Method == http.MethodDelete {
+			handle, method = h.DeleteOverrides, http.MethodDelete
+		}
+	default:
+		writeError(w, http.StatusNotFound, apierr.CodeNotFound, "not found")
+		return
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		return
+	}
+	if sub != "" {
+		h.admin(w, r, func(w http.ResponseWriter, r *http.Request) { handle(w, r, owner.String()) })
+		return
+	}
+	handle(w, r, owner.String())
+}
+
+// admin пропускает запрос, только если в X-Scopes есть AdminScope; иначе 403
+func (h *Handler) admin(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !slices.Contains(strings.Fields(r.Header.Get(ScopesHeader)), AdminScope) {
+		writeError(w, http.StatusForbidden, "forbidden", "missing scope "+AdminScope)
+		return
+	}
+	next(w, r)
+}
+
+// OwnerQuota — GET /quota/{owner}: usage владельца (по событиям media, исправляется сверкой),
+// текущее состояние его лимита загрузок и тариф. Загрузка при этом не засчитывается.
+func (h *Handler) OwnerQuota(w http.ResponseWriter, r *http.Request, owner string) {
+	ctx := r.Context()
+	usage, err := h.usage.OwnerUsage(ctx, owner)
+	if err != nil {
+		h.writeInternal(w, owner, err)
+		return
+	}
+	d, err := h.limiter.Status(ctx, owner)
+	if err != nil {
+		h.writeInternal(w, owner, err)
+		return
+	}
+	resp := OwnerQuotaResponse{
+		OwnerID: owner,
+		Usage:   UsageResponse(usage),
+		Limits: LimitsResponse{Uploads: UploadLimitResponse{
+			Limit:         d.Limit,
+			WindowSeconds: int64(d.Window / time.Second),
+			Remaining:     d.Remaining,
+			ResetAt:       d.ResetAt,
+		}},
+		Plan: planResponse(d.Plan),
+	}
+	if h.plans != nil {
+		op, err := h.plans.OwnerPlan(ctx, owner)
+		if err != nil {
+			h.writeInternal(w, owner, err)
+			return
+		}
+		resp.Overrides = &OverridesRequest{MaxObjects: op.Override.MaxObjects, MaxBytes: op.Override.MaxBytes, UploadLimit: op.Override.UploadLimit}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Plans — GET /plans: все тарифы с лимитами по умолчанию
+func (h *Handler) Plans(w http.ResponseWriter, r *http.Request) {
+	plans, err := h.plans.Plans(r.Context())
+	if err != nil {
+		h.writeInternal(w, "", err)
+		return
+	}
+	resp := PlansResponse{Plans: make([]PlanResponse, 0, len(plans))}
+	for _, p := range plans {
+		resp.Plans = append(resp.Plans, *planResponse(&PlanStatus{Name: p.Name, Limits: p.Limits, Upgradable: upgradable(plans, p)}))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AssignPlan — PUT /quota/{owner}/plan: назначает владельцу тариф; overrides сохраняются
+func (h *Handler) AssignPlan(w http.ResponseWriter, r *http.Request, owner string) {
+	var req AssignPlanRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json body")
+		return
+	}
+	if req.Plan == "" {
+		writeError(w, http.StatusBadRequest, apierr.CodeInvalidArgument, "plan is required")
+		return
+	}
+	if err := h.plans.AssignPlan(r.Context(), owner, req.Plan); err != nil {
+		h.writeServiceError(w, owner, err)
+		return
+	}
+	h.logger.Info().Str("owner_id", owner).Str("plan", req.Plan).Msg("plan assigned")
+	h.OwnerQuota(w, r, owner)
+}
+
+// SetOverrides — PUT /quota/{owner}/overrides: заменяет лимиты владельца поверх тарифа
+func (h *Handler) SetOverrides(w http.ResponseWriter, r *http.Request, owner string) {
+	var req OverridesRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json body")
+		return
+	}
+	o := Override(req)
+	if err := h.plans.SetOverride(r.Context(), owner, o); err != nil {
+		h.writeServiceError(w, owner, err)
+		return
+	}
+	h.logger.Info().Str("owner_id", owner).Bool("cleared", o.IsZero()).Msg("limit overrides set")
+	h.OwnerQuota(w, r, owner)
+}
+
+// DeleteOverrides — DELETE /quota/{owner}/overrides: возвращает владельцу лимиты тарифа
+func (h *Handler) DeleteOverrides(w http.ResponseWriter, r *http.Request, owner string) {
+	if err := h.plans.SetOverride(r.Context(), owner, Override{}); err != nil {
+		h.writeServiceError(w, owner, err)
+		return
+	}
+	h.logger.Info().Str("owner_id", owner).Msg("limit overrides cleared")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeServiceError отвечает ошибкой по реестру apierr: клиентские — с текстом ошибки
+func (h *Handler) writeServiceError(w http.ResponseWriter, owner string, err error) {
+	m := apierr.Lookup(err)
+	message := m.Message
+	if m.HTTPStatus >= http.StatusInternalServerError {
+		h.logger.Error().Err(err).Str("owner_id", owner).Msg("quota request failed")
+	} else {
+		message = err.Error()
+	}
+	writeError(w, m.HTTPStatus, m.Code, message)
+}
+
+func (h *Handler) writeInternal(w http.ResponseWriter, owner string, err error) {
+	h.logger.Error().Err(err).Str("owner_id", owner).Msg("owner quota failed")
+	writeError(w, http.StatusInternalServerError, apierr.CodeInternal, "internal error")
+}
+
+// ConsumeUploads — POST /limits/uploads: CheckAndConsume для owner_id. 200 — загрузки засчитаны,
+// 429 rate_limited — лимит загрузок исчерпан до reset_at (он же в Retry-After и X-RateLimit-Reset),
+// 429 quota_exceeded — загрузка превысит лимит хранения тарифа (exceeded: objects | bytes).
+func (h *Handler) ConsumeUploads(w http.ResponseWriter, r *http.Request) {
+	var req ConsumeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json body")
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+
+	d, err := h.limiter.CheckAndConsume(r.Context(), req.OwnerID, req.Count, req.Bytes)
+	if err != nil {
+		h.writeServiceError(w, req.OwnerID, err)
+		return
+	}
+
+	SetRateLimitHeaders(w.Header(), d, time.Now())
+	status := http.StatusOK
+	if !d.Allowed {
+		status = http.StatusTooManyRequests
+	}
+	writeJSON(w, status, decisionResponse(d))
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Client — HTTP клиент quota API
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient создаёт клиент quota API по адресу baseURL (http://quota:8083);
+// client может быть nil — тогда используется клиент с timeout 2s
+func NewClient(baseURL string, client *http.Client) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid quota url %q", baseURL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}, nil
+}
+
+// CheckAndConsume — POST /limits/uploads. Отказ по лимиту — Decision.Allowed=false без ошибки.
+func (c *Client) CheckAndConsume(ctx context.Context, owner string, n int, size int64) (Decision, error) {
+	body, err := json.Marshal(ConsumeRequest{OwnerID: owner, Count: n, Bytes: size})
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/limits/uploads", bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("quota consume uploads: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusTooManyRequests {
+		return Decision{}, errors.New("quota consume uploads: " + resp.Status)
+	}
+	var out DecisionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Decision{}, fmt.Errorf("quota consume uploads: decode response: %w", err)
+	}
+	return Decision{
+		Allowed:   out.Allowed,
+		Exceeded:  out.Exceeded,
+		Limit:     out.Limit,
+		Window:    time.Duration(out.WindowSeconds) * time.Second,
+		Remaining: out.Remaining,
+		ResetAt:   out.ResetAt,
+		Plan:      out.Plan.status(),
+	}, nil
+}
@@ -0,0 +1,196 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DefaultPlan — тариф владельцев, которым тариф не назначен, и общего пула
+const DefaultPlan = "free"
+
+// Limits — лимиты владельца; 0 — без ограничения
+type Limits struct {
+	MaxObjects int64
+	MaxBytes   int64
+	Uploads    Limit // Uploads.Uploads == 0 — лимит загрузок сервиса по умолчанию
+}
+
+// Plan — тариф: лимиты по умолчанию для всех его владельцев. Rank упорядочивает тарифы:
+// у владельца есть куда расти, если есть тариф с большим Rank.
+type Plan struct {
+	Name   string
+	Rank   int
+	Limits Limits
+}
+
+func (p Plan) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: plan name is required", models.ErrInvalidArgument)
+	}
+	return p.Limits.validate()
+}
+
+func (l Limits) validate() error {
+	if l.MaxObjects < 0 || l.MaxBytes < 0 {
+		return fmt.Errorf("%w: limits cannot be negative", models.ErrInvalidArgument)
+	}
+	if l.Uploads.Uploads < 0 {
+		return fmt.Errorf("%w: upload limit cannot be negative", models.ErrInvalidArgument)
+	}
+	if l.Uploads.Uploads > 0 {
+		if err := l.Uploads.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", models.ErrInvalidArgument, err)
+		}
+	}
+	return nil
+}
+
+// DefaultPlans — тарифы, которые заводятся вместе со схемой (sql/script.sql) и в памяти
+var DefaultPlans = []Plan{
+	{Name: "free", Rank: 0, Limits: Limits{MaxObjects: 100, MaxBytes: 1 << 30, Uploads: Limit{Uploads: 20, Window: time.Hour}}},
+	{Name: "pro", Rank: 10, Limits: Limits{MaxObjects: 10_000, MaxBytes: 100 << 30, Uploads: Limit{Uploads: 500, Window: time.Hour}}},
+	{Name: "enterprise", Rank: 20, Limits: Limits{Uploads: Limit{Uploads: 5000, Window: time.Hour}}},
+}
+
+// Override — лимиты, выставленные владельцу администратором поверх тарифа; nil — как в тарифе
+type Override struct {
+	MaxObjects  *int64
+	MaxBytes    *int64
+	UploadLimit *int // загрузок за окно тарифа
+}
+
+func (o Override) IsZero() bool {
+	return o.MaxObjects == nil && o.MaxBytes == nil && o.UploadLimit == nil
+}
+
+func (o Override) Validate() error {
+	if (o.MaxObjects != nil && *o.MaxObjects < 0) || (o.MaxBytes != nil && *o.MaxBytes < 0) ||
+		(o.UploadLimit != nil && *o.UploadLimit < 0) {
+		return fmt.Errorf("%w: limits cannot be negative", models.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// OwnerPlan — тариф владельца с его overrides
+type OwnerPlan struct {
+	Owner      string
+	Plan       Plan
+	Override   Override
+	Upgradable bool // есть тариф выше текущего
+}
+
+// Limits — действующие лимиты: тариф с применёнными overrides
+func (p OwnerPlan) Limits() Limits {
+	l := p.Plan.Limits
+	if p.Override.MaxObjects != nil {
+		l.MaxObjects = *p.Override.MaxObjects
+	}
+	if p.Override.MaxBytes != nil {
+		l.MaxBytes = *p.Override.MaxBytes
+	}
+	if p.Override.UploadLimit != nil {
+		l.Uploads.Uploads = *p.Override.UploadLimit
+		if l.Uploads.Window == 0 {
+			l.Uploads.Window = time.Hour
+		}
+	}
+	return l
+}
+
+// PlanStore хранит тарифы и их назначение владельцам
+type PlanStore interface {
+	// Plans возвращает тарифы по возрастанию Rank
+	Plans(ctx context.Context) ([]Plan, error)
+	// OwnerPlan возвращает тариф владельца; без назначения — DefaultPlan без overrides
+	OwnerPlan(ctx context.Context, owner string) (OwnerPlan, error)
+	// AssignPlan назначает владельцу тариф, overrides сохраняются; неизвестный тариф — models.ErrNotFound
+	AssignPlan(ctx context.Context, owner, plan string) error
+	// SetOverride заменяет overrides владельца; нулевой Override их снимает
+	SetOverride(ctx context.Context, owner string, o Override) error
+}
+
+// upgradable — есть ли среди plans тариф выше p
+func upgradable(plans []Plan, p Plan) bool {
+	return slices.ContainsFunc(plans, func(other Plan) bool { return other.Rank > p.Rank })
+}
+
+// MemoryPlanStore — PlanStore в памяти процесса с тарифами DefaultPlans
+type MemoryPlanStore struct {
+	mu     sync.Mutex
+	plans  []Plan
+	owners map[string]memoryOwnerPlan
+}
+
+type memoryOwnerPlan struct {
+	plan     string
+	override Override
+}
+
+func NewMemoryPlanStore() *MemoryPlanStore {
+	return &MemoryPlanStore{plans: slices.Clone(DefaultPlans), owners: make(map[string]memoryOwnerPlan)}
+}
+
+func (s *MemoryPlanStore) Plans(ctx context.Context) ([]Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.plans), nil
+}
+
+func (s *MemoryPlanStore) OwnerPlan(ctx context.Context, owner string) (OwnerPlan, error) {
+	if err := ctx.Err(); err != nil {
+		return OwnerPlan{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := s.owners[owner]
+	name := op.plan
+	if name == "" {
+		name = DefaultPlan
+	}
+	i := slices.IndexFunc(s.plans, func(p Plan) bool { return p.Name == name })
+	if i < 0 {
+		return OwnerPlan{}, fmt.Errorf("%w: plan %q", models.ErrNotFound, name)
+	}
+	return OwnerPlan{Owner: owner, Plan: s.plans[i], Override: op.override, Upgradable: upgradable(s.plans, s.plans[i])}, nil
+}
+
+func (s *MemoryPlanStore) AssignPlan(ctx context.Context, owner, plan string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.plans, func(p Plan) bool { return p.Name == plan }) {
+		return fmt.Errorf("%w: plan %q", models.ErrNotFound, plan)
+	}
+	op := s.owners[owner]
+	op.plan = plan
+	s.owners[owner] = op
+	return nil
+}
+
+func (s *MemoryPlanStore) SetOverride(ctx context.Context, owner string, o Override) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := s.owners[owner]
+	op.override = o
+	s.owners[owner] = op
+	return nil
+}
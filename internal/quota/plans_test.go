@@ -0,0 +1,164 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func TestMemoryPlanStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryPlanStore()
+
+	op, err := s.OwnerPlan(ctx, "o1")
+	require.NoError(t, err)
+	require.Equal(t, DefaultPlan, op.Plan.Name)
+	require.True(t, op.Upgradable)
+
+	require.ErrorIs(t, s.AssignPlan(ctx, "o1", "platinum"), models.ErrNotFound)
+	require.NoError(t, s.AssignPlan(ctx, "o1", "enterprise"))
+
+	objects := int64(5)
+	require.NoError(t, s.SetOverride(ctx, "o1", Override{MaxObjects: &objects}))
+	op, err = s.OwnerPlan(ctx, "o1")
+	require.NoError(t, err)
+	require.Equal(t, "enterprise", op.Plan.Name)
+	require.False(t, op.Upgradable)
+	require.Equal(t, int64(5), op.Limits().MaxObjects)
+	require.Zero(t, op.Limits().MaxBytes)
+
+	// Смена тарифа не снимает overrides
+	require.NoError(t, s.AssignPlan(ctx, "o1", "pro"))
+	op, err = s.OwnerPlan(ctx, "o1")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), op.Limits().MaxObjects)
+
+	negative := int64(-1)
+	require.ErrorIs(t, s.SetOverride(ctx, "o1", Override{MaxBytes: &negative}), models.ErrInvalidArgument)
+}
+
+func TestLimiter_PlanLimits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	plans := NewMemoryPlanStore()
+	usage := NewMemoryUsageStore()
+	l := newTestLimiter(t, LimiterConfig{Limit: Limit{Uploads: 1000, Window: time.Hour}, Plans: plans, Usage: usage}, &now)
+
+	// Лимит загрузок берётся из тарифа
+	d, err := l.CheckAndConsume(ctx, "o1", 1, 100)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+	require.Equal(t, 20, d.Limit)
+	require.Equal(t, DefaultPlan, d.Plan.Name)
+	require.True(t, d.Plan.Upgradable)
+
+	// Объём хранения: отказ без списания загрузки
+	require.NoError(t, usage.Set(ctx, "o1", Usage{Objects: 1, Bytes: 1<<30 - 10}))
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 11)
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+	require.Equal(t, ExceededBytes, d.Exceeded)
+	require.Equal(t, 19, d.Remaining)
+	require.ErrorIs(t, d.Err(), domain.ErrQuotaExceeded)
+	require.Contains(t, d.Err().Error(), "upgrade the plan")
+
+	// Override администратора поднимает лимит поверх тарифа
+	maxBytes := int64(2 << 30)
+	require.NoError(t, plans.SetOverride(ctx, "o1", Override{MaxBytes: &maxBytes}))
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 11)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+
+	require.NoError(t, usage.Set(ctx, "o1", Usage{Objects: 101}))
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, ExceededObjects, d.Exceeded)
+
+	// Лимит загрузок — отдельная ошибка
+	require.NoError(t, plans.AssignPlan(ctx, "o1", "enterprise"))
+	limit := 3 // две загрузки уже засчитаны
+	require.NoError(t, plans.SetOverride(ctx, "o1", Override{UploadLimit: &limit}))
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 0)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, ExceededUploads, d.Exceeded)
+	var limitErr *LimitError
+	require.True(t, errors.As(d.Err(), &limitErr))
+	require.ErrorIs(t, d.Err(), domain.ErrRateLimited)
+	require.NotContains(t, d.Err().Error(), "upgrade")
+}
+
+func TestHandler_PlansAdmin(t *testing.T) {
+	now := time.Now()
+	plans := NewMemoryPlanStore()
+	usage := NewMemoryUsageStore()
+	l := newTestLimiter(t, LimiterConfig{Limit: Limit{Uploads: 10, Window: time.Hour}, Plans: plans, Usage: usage}, &now)
+	h, err := NewHandler(HandlerConfig{Limiter: l, Usage: usage, Plans: plans, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	owner := uuid.NewString()
+
+	do := func(method, path, body string, admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if admin {
+			req.Header.Set(ScopesHeader, "media:read "+AdminScope)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/plans", "", false).Code)
+	require.Equal(t, http.StatusForbidden, do(http.MethodPut, "/quota/"+owner+"/plan", `{"plan":"pro"}`, false).Code)
+
+	rec := do(http.MethodGet, "/plans", "", true)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list PlansResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Plans, len(DefaultPlans))
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodPut, "/quota/"+owner+"/plan", `{"plan":"platinum"}`, true).Code)
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/quota/"+owner+"/plan", `{"plan":"pro"}`, true).Code)
+
+	rec = do(http.MethodPut, "/quota/"+owner+"/overrides", `{"max_objects":3,"upload_limit":1}`, true)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got OwnerQuotaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, "pro", got.Plan.Name)
+	require.Equal(t, int64(3), got.Plan.MaxObjects)
+	require.Equal(t, int64(100<<30), got.Plan.MaxBytes)
+	require.Equal(t, 1, got.Plan.UploadLimit)
+	require.Equal(t, 1, got.Limits.Uploads.Limit)
+	require.Equal(t, int64(3), *got.Overrides.MaxObjects)
+	require.Nil(t, got.Overrides.MaxBytes)
+
+	// Отказ по тарифу отдаётся с данными тарифа
+	require.NoError(t, usage.Set(context.Background(), owner, Usage{Objects: 4}))
+	rec = do(http.MethodPost, "/limits/uploads", `{"owner_id":"`+owner+`","bytes":10}`, false)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+	var resp DecisionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "quota_exceeded", resp.Code)
+	require.Equal(t, ExceededObjects, resp.Exceeded)
+	require.True(t, resp.Plan.UpgradeAvailable)
+	require.Equal(t, int64(4), resp.Plan.Usage.Objects)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/quota/"+owner+"/overrides", "", true).Code)
+	op, err := plans.OwnerPlan(context.Background(), owner)
+	require.NoError(t, err)
+	require.True(t, op.Override.IsZero())
+}
@@ -0,0 +1,264 @@
+package quota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+type staticCounter map[string]Usage
+
+func (c staticCounter) UsageByOwner(context.Context) (map[string]Usage, error) {
+	return c, nil
+}
+
+// recordingPublisher запоминает опубликованные конверты
+type recordingPublisher struct {
+	envelopes []events.Envelope
+}
+
+func (p *recordingPublisher) PublishEnvelope(_ context.Context, env events.Envelope, _ time.Time) error {
+	p.envelopes = append(p.envelopes, env)
+	return nil
+}
+
+func TestAdjustmentFromEvent(t *testing.T) {
+	adj, ok, err := AdjustmentFromEvent("e1", "MediaCreated", json.RawMessage(`{"media_id":"m"}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Adjustment{EventID: "e1", Owner: "", Objects: 1}, adj)
+
+	adj, ok, err = AdjustmentFromEvent("e2", "MediaDeleted", json.RawMessage(`{"owner_id":"o1","reason":"expired"}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Adjustment{EventID: "e2", Owner: "o1", Objects: -1}, adj)
+
+	adj, ok, err = AdjustmentFromEvent("e5", "MediaContentRecorded", json.RawMessage(`{"owner_id":"o1","size_bytes":300,"previous_size_bytes":100}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Adjustment{EventID: "e5", Owner: "o1", Bytes: 200}, adj)
+
+	adj, ok, err = AdjustmentFromEvent("e6", "MediaDeleted", json.RawMessage(`{"owner_id":"o1","size_bytes":300}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Adjustment{EventID: "e6", Owner: "o1", Objects: -1, Bytes: -300}, adj)
+
+	_, ok, err = AdjustmentFromEvent("e3", "MediaStatusChanged", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = AdjustmentFromEvent("e4", "MediaCreated", json.RawMessage(`{broken`))
+	require.Error(t, err)
+}
+
+func TestMemoryUsageStore_ApplyIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryUsageStore()
+
+	applied, err := s.Apply(ctx, Adjustment{EventID: "e1", Objects: 1})
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	// Повторная доставка того же события не меняет usage
+	applied, err = s.Apply(ctx, Adjustment{EventID: "e1", Objects: 1})
+	require.NoError(t, err)
+	require.False(t, applied)
+
+	_, err = s.Apply(ctx, Adjustment{EventID: "e2", Objects: -1})
+	require.NoError(t, err)
+
+	usage, err := s.Usage(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Usage{}, usage[""])
+}
+
+func TestMemoryUsageStore_ForgetsOldEvents(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryUsageStore()
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	s.clock = func() time.Time { return now }
+
+	_, err := s.Apply(ctx, Adjustment{EventID: "e1", Objects: 1})
+	require.NoError(t, err)
+	now = now.Add(DefaultDedupTTL + time.Minute)
+	_, err = s.Apply(ctx, Adjustment{EventID: "e2", Objects: 1})
+	require.NoError(t, err)
+
+	require.NotContains(t, s.applied, "e1")
+	require.Contains(t, s.applied, "e2")
+}
+
+func TestUsageHandler_PublishesUsageChanged(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryUsageStore()
+	publisher := &recordingPublisher{}
+	h, err := NewUsageHandler(UsageHandlerConfig{Store: store, Events: publisher, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	created := events.Envelope{EventID: "e1", EventType: "MediaCreated", Payload: json.RawMessage(`{"owner_id":"a"}`)}
+	require.NoError(t, h.Handle(ctx, created))
+	require.NoError(t, h.Handle(ctx, events.Envelope{EventID: "e2", EventType: "MediaStatusChanged"}))
+
+	usage, err := store.OwnerUsage(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, Usage{Objects: 1}, usage)
+
+	require.Len(t, publisher.envelopes, 1)
+	decoded, err := events.Quota.Decode(publisher.envelopes[0])
+	require.NoError(t, err)
+	dec := json.NewDecoder(bytes.NewReader(publisher.envelopes[0].Payload))
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(decoded))
+	changed := decoded.(*events.QuotaUsageChangedV1)
+	require.Equal(t, "e1", changed.SourceEventID)
+	require.Equal(t, "a", changed.OwnerID)
+	require.Equal(t, events.QuotaUsageV1{Objects: 1}, changed.Delta)
+
+	// Повтор после отката транзакции: usage не меняется, событие пишется заново с тем же id
+	require.NoError(t, h.Handle(ctx, created))
+	usage, err = store.OwnerUsage(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, Usage{Objects: 1}, usage)
+	require.Len(t, publisher.envelopes, 2)
+	require.Equal(t, publisher.envelopes[0].EventID, publisher.envelopes[1].EventID)
+}
+
+func TestReconciler_ReportsDrift(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryUsageStore()
+	require.NoError(t, store.Set(ctx, "a", Usage{Objects: 5, Bytes: 500}))
+	require.NoError(t, store.Set(ctx, "b", Usage{Objects: 2, Bytes: 200}))
+
+	r, err := NewReconciler(ReconcilerConfig{
+		Store:  store,
+		Actual: staticCounter{"a": {Objects: 3, Bytes: 300}, "b": {Objects: 2, Bytes: 250}, "c": {Objects: 1}},
+		Logger: zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	report, err := r.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, report.Owners)
+	require.Equal(t, []Drift{
+		{Owner: "a", Recorded: Usage{Objects: 5, Bytes: 500}, Actual: Usage{Objects: 3, Bytes: 300}},
+		{Owner: "b", Recorded: Usage{Objects: 2, Bytes: 200}, Actual: Usage{Objects: 2, Bytes: 250}},
+		{Owner: "c", Recorded: Usage{}, Actual: Usage{Objects: 1}},
+	}, report.Drifts)
+
+	require.Equal(t, int64(3), r.Metrics().OwnersDrifted.Load())
+	require.Equal(t, int64(3), r.Metrics().DriftObjects.Load())
+	require.Equal(t, int64(250), r.Metrics().DriftBytes.Load())
+
+	// Без AutoCorrect usage не меняется
+	usage, err := store.Usage(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), usage["a"].Objects)
+}
+
+func TestReconciler_AutoCorrect(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryUsageStore()
+	require.NoError(t, store.Set(ctx, "a", Usage{Objects: 5, Bytes: 500}))
+	publisher := &recordingPublisher{}
+
+	r, err := NewReconciler(ReconcilerConfig{
+		Store:       store,
+		Actual:      staticCounter{"a": {Objects: 3, Bytes: 300}},
+		AutoCorrect: true,
+		Events:      publisher,
+		Logger:      zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	_, err = r.RunOnce(ctx)
+	require.NoError(t, err)
+
+	usage, err := store.Usage(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Usage{Objects: 3, Bytes: 300}, usage["a"])
+	require.Equal(t, int64(1), r.Metrics().Corrections.Load())
+
+	// Исправление публикуется как QuotaReconciled, payload совпадает со схемой реестра
+	require.Len(t, publisher.envelopes, 1)
+	env := publisher.envelopes[0]
+	require.Equal(t, "QuotaReconciled", env.EventType)
+	decoded, err := events.Quota.Decode(env)
+	require.NoError(t, err)
+	dec := json.NewDecoder(bytes.NewReader(env.Payload))
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(decoded))
+	require.Equal(t, &events.QuotaReconciledV1{
+		EventID:    decoded.(*events.QuotaReconciledV1).EventID,
+		OwnerID:    "a",
+		Previous:   events.QuotaUsageV1{Objects: 5, Bytes: 500},
+		Actual:     events.QuotaUsageV1{Objects: 3, Bytes: 300},
+		OccurredAt: decoded.(*events.QuotaReconciledV1).OccurredAt,
+	}, decoded)
+
+	// Повторная сверка drift не находит
+	report, err := r.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Drifts)
+	require.Len(t, publisher.envelopes, 1)
+}
+
+func TestReconciler_NightlySchedule(t *testing.T) {
+	r, err := NewReconciler(ReconcilerConfig{
+		Store:  NewMemoryUsageStore(),
+		Actual: staticCounter{},
+		At:     3 * time.Hour,
+		Logger: zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, 2*time.Hour, r.untilNext(day.Add(time.Hour)))
+	require.Equal(t, 24*time.Hour, r.untilNext(day.Add(3*time.Hour)))
+	require.Equal(t, 17*time.Hour, r.untilNext(day.Add(10*time.Hour)))
+
+	_, err = NewReconciler(ReconcilerConfig{Store: NewMemoryUsageStore(), Actual: staticCounter{}, At: 25 * time.Hour})
+	require.Error(t, err)
+}
+
+func TestHandler_OwnerQuota(t *testing.T) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+	usage := NewMemoryUsageStore()
+	require.NoError(t, usage.Set(ctx, owner, Usage{Objects: 2, Bytes: 2048}))
+	limiter, err := NewLimiter(LimiterConfig{Store: NewMemoryWindowStore(), Limit: Limit{Uploads: 10, Window: time.Hour}, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	_, err = limiter.CheckAndConsume(ctx, owner, 3, 0)
+	require.NoError(t, err)
+	h, err := NewHandler(HandlerConfig{Limiter: limiter, Usage: usage, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	rec := get("/quota/" + owner)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp OwnerQuotaResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, UsageResponse{Objects: 2, Bytes: 2048}, resp.Usage)
+	require.Equal(t, 10, resp.Limits.Uploads.Limit)
+	require.Equal(t, int64(3600), resp.Limits.Uploads.WindowSeconds)
+
+	// Чтение лимит не тратит
+	require.Equal(t, 7, resp.Limits.Uploads.Remaining)
+	require.NoError(t, json.Unmarshal(get("/quota/"+owner).Body.Bytes(), &resp))
+	require.Equal(t, 7, resp.Limits.Uploads.Remaining)
+
+	require.Equal(t, http.StatusBadRequest, get("/quota/not-an-owner").Code)
+}
@@ -0,0 +1,411 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/domain"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// Limit — не больше Uploads загрузок за любые Window подряд
+type Limit struct {
+	Uploads int
+	Window  time.Duration
+}
+
+func (l Limit) Validate() error {
+	if l.Uploads <= 0 {
+		return fmt.Errorf("uploads limit must be positive, got: %d", l.Uploads)
+	}
+	if l.Window < time.Second {
+		return fmt.Errorf("limit window must be at least 1s, got: %v", l.Window)
+	}
+	return nil
+}
+
+// Что исчерпано, когда Decision.Allowed=false
+const (
+	ExceededUploads = "uploads" // лимит загрузок за окно: пройдёт после ResetAt
+	ExceededObjects = "objects" // число медиа по тарифу
+	ExceededBytes   = "bytes"   // объём исходников по тарифу
+)
+
+// Decision — результат CheckAndConsume
+type Decision struct {
+	Allowed   bool
+	Exceeded  string // при отказе: ExceededUploads | ExceededObjects | ExceededBytes
+	Limit     int
+	Window    time.Duration
+	Remaining int       // загрузок, которые ещё можно сделать сейчас
+	ResetAt   time.Time // когда окно снова пропустит столько загрузок, сколько запрошено
+	Plan      *PlanStatus
+}
+
+// Err возвращает *LimitError при отказе и nil, если загрузка разрешена
+func (d Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	return &LimitError{Decision: d}
+}
+
+// PlanStatus — тариф владельца в ответе проверки: по нему клиент предлагает сменить тариф
+type PlanStatus struct {
+	Name       string
+	Limits     Limits // действующие, с overrides администратора
+	Usage      Usage
+	Upgradable bool // есть тариф выше текущего
+}
+
+// LimitError — отказ в загрузке. errors.Is(err, domain.ErrRateLimited) для лимита загрузок
+// за окно и errors.Is(err, domain.ErrQuotaExceeded) для лимитов хранения по тарифу.
+type LimitError struct {
+	Decision Decision
+}
+
+func (e *LimitError) Error() string {
+	d := e.Decision
+	var msg string
+	switch d.Exceeded {
+	case ExceededObjects:
+		msg = fmt.Sprintf("media count limit of %d exceeded", d.Plan.Limits.MaxObjects)
+	case ExceededBytes:
+		msg = fmt.Sprintf("storage limit of %d bytes exceeded", d.Plan.Limits.MaxBytes)
+	default:
+		msg = fmt.Sprintf("upload rate limit of %d per %v exceeded, retry at %s", d.Limit, d.Window, d.ResetAt.UTC().Format(time.RFC3339))
+	}
+	if d.Plan != nil {
+		msg += " on plan " + d.Plan.Name
+		if d.Plan.Upgradable {
+			msg += ", upgrade the plan for higher limits"
+		}
+	}
+	return msg
+}
+
+func (e *LimitError) Unwrap() error {
+	if e.Decision.Exceeded == ExceededObjects || e.Decision.Exceeded == ExceededBytes {
+		return domain.ErrQuotaExceeded
+	}
+	return domain.ErrRateLimited
+}
+
+// WindowStore — счётчики загрузок по окнам фиксированной длины. Скользящее окно оценивается
+// как prev*weight + curr, где weight — доля предыдущего окна, ещё попадающая в скользящее.
+type WindowStore interface {
+	// ConsumeWindow атомарно засчитывает n загрузок key в окне start, если оценка с ними
+	// не превышает limit. Возвращает счётчики предыдущего и текущего окна после операции.
+	ConsumeWindow(ctx context.Context, key string, start time.Time, window time.Duration, weight float64, limit, n int) (prev, curr int64, allowed bool, err error)
+}
+
+// LimiterConfig содержит конфигурацию Limiter
+type LimiterConfig struct {
+	Store  WindowStore
+	Limit  Limit            // лимит по умолчанию
+	Owners map[string]Limit // лимиты отдельных владельцев вместо Limit и тарифа
+	// Plans — тарифы владельцев: лимит загрузок тарифа заменяет Limit, а с Usage проверяются
+	// ещё и лимиты хранения. nil — тарифов нет
+	Plans  PlanStore
+	Usage  UsageStore
+	Logger zerolog.Logger
+}
+
+// Limiter ограничивает число загрузок владельца за скользящее окно и, с тарифами, объём хранения
+type Limiter struct {
+	store  WindowStore
+	mu     sync.Mutex
+	limit  Limit // под mu, меняется SetLimit
+	owners map[string]Limit
+	plans  PlanStore
+	usage  UsageStore
+	clock  func() time.Time
+	logger zerolog.Logger
+}
+
+func NewLimiter(cfg LimiterConfig) (*Limiter, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("window store is required")
+	}
+	if err := cfg.Limit.Validate(); err != nil {
+		return nil, err
+	}
+	for owner, l := range cfg.Owners {
+		if err := l.Validate(); err != nil {
+			return nil, fmt.Errorf("owner %q: %w", owner, err)
+		}
+	}
+	return &Limiter{
+		store:  cfg.Store,
+		limit:  cfg.Limit,
+		owners: cfg.Owners,
+		plans:  cfg.Plans,
+		usage:  cfg.Usage,
+		clock:  time.Now,
+		logger: cfg.Logger.With().Str("component", "upload_limiter").Logger(),
+	}, nil
+}
+
+// Limit возвращает лимит загрузок по умолчанию
+func (l *Limiter) Limit() Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// SetLimit меняет лимит загрузок по умолчанию на лету; лимиты владельцев и тарифов не трогает
+func (l *Limiter) SetLimit(lim Limit) error {
+	if err := lim.Validate(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = lim
+	return nil
+}
+
+// CheckAndConsume засчитывает n загрузок владельца общим размером bytes, если лимиты позволяют.
+// Отказ — не ошибка: Decision.Allowed=false, Exceeded и, для лимита загрузок, ResetAt, когда
+// стоит повторить. Пустой owner — общий пул медиа без владельца.
+func (l *Limiter) CheckAndConsume(ctx context.Context, owner string, n int, bytes int64) (Decision, error) {
+	if n <= 0 {
+		return Decision{}, fmt.Errorf("%w: uploads to consume must be positive, got: %d", models.ErrInvalidArgument, n)
+	}
+	if bytes < 0 {
+		return Decision{}, fmt.Errorf("%w: upload bytes cannot be negative, got: %d", models.ErrInvalidArgument, bytes)
+	}
+	lim, plan, err := l.limits(ctx, owner)
+	if err != nil {
+		return Decision{}, err
+	}
+	if n > lim.Uploads {
+		return Decision{}, fmt.Errorf("%w: %d uploads exceed the limit of %d", models.ErrInvalidArgument, n, lim.Uploads)
+	}
+
+	// Лимит хранения проверяется до списания: отказ по нему не тратит лимит загрузок
+	exceeded := ""
+	if plan != nil {
+		exceeded = storageExceeded(plan.Usage, plan.Limits, bytes)
+	}
+	consume := n
+	if exceeded != "" {
+		consume = 0
+	}
+	d, err := l.check(ctx, owner, lim, consume)
+	if err != nil {
+		return Decision{}, err
+	}
+	d.Plan = plan
+	switch {
+	case exceeded != "":
+		d.Allowed, d.Exceeded = false, exceeded
+	case !d.Allowed:
+		d.Exceeded = ExceededUploads
+	}
+	if !d.Allowed {
+		l.logger.Info().Str("owner_id", owner).Str("exceeded", d.Exceeded).Int("limit", d.Limit).Time("reset_at", d.ResetAt).Msg("upload rejected by quota")
+	}
+	return d, nil
+}
+
+// Status — лимиты владельца без списания: Allowed и ResetAt относятся к следующей одной загрузке
+func (l *Limiter) Status(ctx context.Context, owner string) (Decision, error) {
+	lim, plan, err := l.limits(ctx, owner)
+	if err != nil {
+		return Decision{}, err
+	}
+	d, err := l.check(ctx, owner, lim, 0)
+	if err != nil {
+		return Decision{}, err
+	}
+	d.Plan = plan
+	d.Allowed = d.Remaining > 0
+	if !d.Allowed {
+		d.Exceeded = ExceededUploads
+	}
+	if plan != nil {
+		if exceeded := storageExceeded(plan.Usage, plan.Limits, 0); exceeded != "" {
+			d.Allowed, d.Exceeded = false, exceeded
+		}
+	}
+	return d, nil
+}
+
+// limits — лимит загрузок владельца и, если настроены тарифы, его тариф с usage
+func (l *Limiter) limits(ctx context.Context, owner string) (Limit, *PlanStatus, error) {
+	if l.plans == nil {
+		if lim, ok := l.owners[owner]; ok {
+			return lim, nil, nil
+		}
+		return l.Limit(), nil, nil
+	}
+
+	op, err := l.plans.OwnerPlan(ctx, owner)
+	if err != nil {
+		return Limit{}, nil, fmt.Errorf("owner plan: %w", err)
+	}
+	status := &PlanStatus{Name: op.Plan.Name, Limits: op.Limits(), Upgradable: op.Upgradable}
+	if l.usage != nil {
+		if status.Usage, err = l.usage.OwnerUsage(ctx, owner); err != nil {
+			return Limit{}, nil, fmt.Errorf("owner usage: %w", err)
+		}
+	}
+
+	lim := l.Limit()
+	if status.Limits.Uploads.Uploads > 0 {
+		lim = status.Limits.Uploads
+	}
+	if o, ok := l.owners[owner]; ok {
+		lim = o
+	}
+	status.Limits.Uploads = lim
+	return lim, status, nil
+}
+
+// storageExceeded — какой лимит хранения нарушит загрузка bytes. Загружаемое медиа уже
+// учтено в usage.Objects (MediaCreated), поэтому превышением считается только «больше».
+func storageExceeded(u Usage, l Limits, bytes int64) string {
+	switch {
+	case l.MaxObjects > 0 && u.Objects > l.MaxObjects:
+		return ExceededObjects
+	case l.MaxBytes > 0 && u.Bytes+bytes > l.MaxBytes:
+		return ExceededBytes
+	}
+	return ""
+}
+
+// check засчитывает n загрузок (0 — только читает окно) и собирает Decision
+func (l *Limiter) check(ctx context.Context, owner string, lim Limit, n int) (Decision, error) {
+	now := l.clock()
+	start := now.Truncate(lim.Window)
+	weight := 1 - float64(now.Sub(start))/float64(lim.Window)
+	prev, curr, allowed, err := l.store.ConsumeWindow(ctx, "uploads:"+owner, start, lim.Window, weight, lim.Uploads, n)
+	if err != nil {
+		return Decision{}, fmt.Errorf("consume upload window: %w", err)
+	}
+
+	d := Decision{Allowed: allowed, Limit: lim.Uploads, Window: lim.Window}
+	estimate := float64(prev)*weight + float64(curr)
+	d.Remaining = max(0, int(math.Floor(float64(lim.Uploads)-estimate+1e-9)))
+	d.ResetAt = resetAt(now, start, lim, prev, curr, max(n, 1))
+	return d, nil
+}
+
+// resetAt — ближайший момент, когда оценка окна опустится до limit-n: сначала «выветривается»
+// предыдущее окно, а если не хватает и этого — текущее после его окончания
+func resetAt(now, start time.Time, lim Limit, prev, curr int64, n int) time.Time {
+	room := float64(lim.Uploads - n)
+	if float64(curr) <= room {
+		if prev == 0 {
+			return now
+		}
+		// prev*(1-f) + curr <= room  =>  f >= 1 - (room-curr)/prev
+		f := 1 - (room-float64(curr))/float64(prev)
+		return ceilTime(start.Add(time.Duration(f * float64(lim.Window))))
+	}
+	// Следующее окно: curr*(1-g) <= room  =>  g >= 1 - room/curr
+	g := 1 - room/float64(curr)
+	return ceilTime(start.Add(lim.Window).Add(time.Duration(g * float64(lim.Window))))
+}
+
+// ceilTime округляет до секунды вверх: клиенты получают reset в секундах
+func ceilTime(t time.Time) time.Time {
+	if r := t.Truncate(time.Second); !r.Equal(t) {
+		return r.Add(time.Second)
+	}
+	return t
+}
+
+// MemoryWindowStore — WindowStore в памяти процесса: для одного инстанса и тестов
+type MemoryWindowStore struct {
+	mu     sync.Mutex
+	counts map[string]map[int64]int64 // key -> начало окна (unix ns) -> загрузок
+}
+
+func NewMemoryWindowStore() *MemoryWindowStore {
+	return &MemoryWindowStore{counts: make(map[string]map[int64]int64)}
+}
+
+func (s *MemoryWindowStore) ConsumeWindow(ctx context.Context, key string, start time.Time, window time.Duration, weight float64, limit, n int) (int64, int64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windows := s.counts[key]
+	if windows == nil {
+		windows = make(map[int64]int64)
+		s.counts[key] = windows
+	}
+	cur, prevStart := start.UnixNano(), start.Add(-window).UnixNano()
+	for w := range windows {
+		if w < prevStart {
+			delete(windows, w)
+		}
+	}
+	prev, curr := windows[prevStart], windows[cur]
+	if float64(prev)*weight+float64(curr+int64(n)) > float64(limit) {
+		return prev, curr, false, nil
+	}
+	windows[cur] += int64(n)
+	return prev, windows[cur], true, nil
+}
+
+// consumeScript — ConsumeWindow одной командой: оценка и инкремент атомарны для всех инстансов
+var consumeScript = redis.NewScript(`
+local prev = tonumber(redis.call('GET', KEYS[1]) or '0')
+local curr = tonumber(redis.call('GET', KEYS[2]) or '0')
+local weight = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+if prev * weight + curr + n > limit then
+  return {prev, curr, 0}
+end
+curr = redis.call('INCRBY', KEYS[2], n)
+redis.call('PEXPIRE', KEYS[2], ARGV[4])
+return {prev, curr, 1}
+`)
+
+// RedisWindowStore — WindowStore в Redis, общий для всех инстансов quota
+type RedisWindowStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisWindowStore создаёт store; prefix по умолчанию "quota:"
+func NewRedisWindowStore(client redis.UniversalClient, prefix string) (*RedisWindowStore, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if prefix == "" {
+		prefix = "quota:"
+	}
+	return &RedisWindowStore{client: client, prefix: prefix}, nil
+}
+
+func (s *RedisWindowStore) ConsumeWindow(ctx context.Context, key string, start time.Time, window time.Duration, weight float64, limit, n int) (int64, int64, bool, error) {
+	// Hash tag {key}: оба окна в одном слоте Redis Cluster, скрипт может читать их вместе
+	windowKey := func(t time.Time) string {
+		return fmt.Sprintf("%s{%s}:%d", s.prefix, key, t.Unix())
+	}
+	keys := []string{windowKey(start.Add(-window)), windowKey(start)}
+	// Текущее окно нужно ещё одно окно спустя — как предыдущее
+	ttl := (2 * window).Milliseconds()
+
+	res, err := consumeScript.Run(ctx, s.client, keys, weight, limit, n, ttl).Int64Slice()
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("redis consume window: %w", err)
+	}
+	if len(res) != 3 {
+		return 0, 0, false, fmt.Errorf("redis consume window: unexpected reply %v", res)
+	}
+	return res[0], res[1], res[2] == 1, nil
+}
@@ -0,0 +1,137 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(t *testing.T, cfg LimiterConfig, now *time.Time) *Limiter {
+	t.Helper()
+	cfg.Store = NewMemoryWindowStore()
+	cfg.Logger = zerolog.Nop()
+	l, err := NewLimiter(cfg)
+	require.NoError(t, err)
+	l.clock = func() time.Time { return *now }
+	return l
+}
+
+func TestLimiter_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(30 * time.Second)
+	l := newTestLimiter(t, LimiterConfig{Limit: Limit{Uploads: 3, Window: time.Minute}}, &now)
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		d, err := l.CheckAndConsume(ctx, "o1", 1, 0)
+		require.NoError(t, err)
+		require.True(t, d.Allowed)
+		require.Equal(t, remaining, d.Remaining)
+	}
+
+	// Окно исчерпано: 3 загрузки текущего окна должны «выветриться» на треть — через 20s после его конца
+	d, err := l.CheckAndConsume(ctx, "o1", 1, 0)
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+	require.Equal(t, 3, d.Limit)
+	require.Zero(t, d.Remaining)
+	require.Equal(t, start.Add(80*time.Second), d.ResetAt)
+
+	// Другой владелец считается отдельно
+	d, err = l.CheckAndConsume(ctx, "o2", 1, 0)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+
+	now = start.Add(79 * time.Second)
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 0)
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+
+	now = start.Add(80 * time.Second)
+	d, err = l.CheckAndConsume(ctx, "o1", 1, 0)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+	require.Zero(t, d.Remaining)
+
+	_, err = l.CheckAndConsume(ctx, "o1", 4, 0)
+	require.Error(t, err)
+}
+
+func TestLimiter_OwnerOverride(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(t, LimiterConfig{
+		Limit:  Limit{Uploads: 1, Window: time.Hour},
+		Owners: map[string]Limit{"vip": {Uploads: 2, Window: time.Hour}},
+	}, &now)
+
+	for i := 0; i < 2; i++ {
+		d, err := l.CheckAndConsume(context.Background(), "vip", 1, 0)
+		require.NoError(t, err)
+		require.True(t, d.Allowed)
+	}
+	d, err := l.CheckAndConsume(context.Background(), "vip", 1, 0)
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+
+	_, err = NewLimiter(LimiterConfig{Store: NewMemoryWindowStore(), Limit: Limit{Uploads: 1, Window: time.Hour},
+		Owners: map[string]Limit{"bad": {}}})
+	require.Error(t, err)
+}
+
+func TestLimiter_SetLimit(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(t, LimiterConfig{Limit: Limit{Uploads: 1, Window: time.Hour}}, &now)
+
+	d, err := l.CheckAndConsume(context.Background(), "o1", 1, 0)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+
+	require.Error(t, l.SetLimit(Limit{Uploads: 0, Window: time.Hour}))
+	require.NoError(t, l.SetLimit(Limit{Uploads: 2, Window: time.Hour}))
+	require.Equal(t, 2, l.Limit().Uploads)
+
+	d, err = l.CheckAndConsume(context.Background(), "o1", 1, 0)
+	require.NoError(t, err)
+	require.True(t, d.Allowed, "raised limit applies to the current window")
+}
+
+func TestClient_CheckAndConsume(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(t, LimiterConfig{Limit: Limit{Uploads: 1, Window: time.Hour}}, &now)
+	h, err := NewHandler(HandlerConfig{Limiter: l, Usage: NewMemoryUsageStore(), Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(srv.URL, nil)
+	require.NoError(t, err)
+
+	d, err := client.CheckAndConsume(context.Background(), "o1", 1, 0)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+
+	// Отказ — 429 с заголовками лимита, клиент отдаёт его как Decision без ошибки
+	resp, err := srv.Client().Post(srv.URL+"/limits/uploads", "application/json", strings.NewReader(`{"owner_id":"o1"}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "1", resp.Header.Get(HeaderRateLimitLimit))
+	require.Equal(t, "0", resp.Header.Get(HeaderRateLimitRemaining))
+	retry, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Positive(t, retry)
+
+	d, err = client.CheckAndConsume(context.Background(), "o1", 1, 0)
+	require.NoError(t, err)
+	require.False(t, d.Allowed)
+	require.True(t, d.ResetAt.After(now))
+
+	_, err = client.CheckAndConsume(context.Background(), "o1", -1, 0)
+	require.Error(t, err)
+}
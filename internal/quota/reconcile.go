@@ -0,0 +1,235 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/events"
+)
+
+// ActualCounter возвращает фактический usage по владельцам (источник истины — таблица media)
+type ActualCounter interface {
+	UsageByOwner(ctx context.Context) (map[string]Usage, error)
+}
+
+// EnvelopePublisher публикует события quota; реализуется *kafka.Producer
+type EnvelopePublisher interface {
+	PublishEnvelope(ctx context.Context, env events.Envelope, ts time.Time) error
+}
+
+// Drift — расхождение usage с фактом для одного владельца
+type Drift struct {
+	Owner    string
+	Recorded Usage
+	Actual   Usage
+}
+
+func (d Drift) Delta() Usage {
+	return Usage{Objects: d.Actual.Objects - d.Recorded.Objects, Bytes: d.Actual.Bytes - d.Recorded.Bytes}
+}
+
+// Report — результат одного прогона сверки
+type Report struct {
+	CheckedAt time.Time
+	Owners    int
+	Drifts    []Drift
+	Corrected bool
+}
+
+// ReconcilerConfig содержит конфигурацию Reconciler
+type ReconcilerConfig struct {
+	Store    UsageStore
+	Actual   ActualCounter
+	Interval time.Duration // Период сверки (default: 24h — "ночной" job)
+	// At — время суток (UTC), от которого отсчитываются запуски: 3h при Interval 24h — каждую
+	// ночь в 03:00. 0 — каждые Interval от старта
+	At          time.Duration
+	AutoCorrect bool // Исправлять usage по факту, а не только репортить drift
+	// Events получает QuotaReconciled по каждому исправленному владельцу; nil — не публиковать
+	Events EnvelopePublisher
+	Logger zerolog.Logger
+}
+
+// ReconcilerMetrics содержит метрики сверки
+type ReconcilerMetrics struct {
+	Runs          atomic.Int64
+	Failures      atomic.Int64
+	OwnersDrifted atomic.Int64 // Владельцев с расхождением в последнем прогоне
+	DriftObjects  atomic.Int64 // Сумма |drift| объектов в последнем прогоне
+	DriftBytes    atomic.Int64 // Сумма |drift| байт в последнем прогоне
+	Corrections   atomic.Int64 // Всего исправленных записей usage
+}
+
+// Reconciler сверяет usage из UsageStore с фактическим количеством media
+type Reconciler struct {
+	store       UsageStore
+	actual      ActualCounter
+	interval    time.Duration
+	at          time.Duration
+	autoCorrect bool
+	events      EnvelopePublisher
+	metrics     *ReconcilerMetrics
+	clock       func() time.Time
+	logger      zerolog.Logger
+}
+
+func NewReconciler(cfg ReconcilerConfig) (*Reconciler, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("usage store is required")
+	}
+	if cfg.Actual == nil {
+		return nil, fmt.Errorf("actual counter is required")
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative, got: %v", cfg.Interval)
+	}
+	if cfg.At < 0 || cfg.At >= 24*time.Hour {
+		return nil, fmt.Errorf("at must be a time of day in [0, 24h), got: %v", cfg.At)
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+
+	return &Reconciler{
+		store:       cfg.Store,
+		actual:      cfg.Actual,
+		interval:    cfg.Interval,
+		at:          cfg.At,
+		autoCorrect: cfg.AutoCorrect,
+		events:      cfg.Events,
+		metrics:     &ReconcilerMetrics{},
+		clock:       time.Now,
+		logger:      cfg.Logger.With().Str("component", "quota_reconciler").Logger(),
+	}, nil
+}
+
+// Metrics возвращает метрики сверки
+func (r *Reconciler) Metrics() *ReconcilerMetrics { return r.metrics }
+
+// RunOnce выполняет одну сверку
+func (r *Reconciler) RunOnce(ctx context.Context) (Report, error) {
+	r.metrics.Runs.Add(1)
+
+	recorded, err := r.store.Usage(ctx)
+	if err != nil {
+		r.metrics.Failures.Add(1)
+		return Report{}, fmt.Errorf("load usage: %w", err)
+	}
+	actual, err := r.actual.UsageByOwner(ctx)
+	if err != nil {
+		r.metrics.Failures.Add(1)
+		return Report{}, fmt.Errorf("count actual: %w", err)
+	}
+
+	owners := make(map[string]struct{}, len(recorded)+len(actual))
+	for o := range recorded {
+		owners[o] = struct{}{}
+	}
+	for o := range actual {
+		owners[o] = struct{}{}
+	}
+
+	report := Report{CheckedAt: r.clock(), Owners: len(owners), Corrected: r.autoCorrect}
+	var driftObjects, driftBytes int64
+	for o := range owners {
+		d := Drift{Owner: o, Recorded: recorded[o], Actual: actual[o]}
+		delta := d.Delta()
+		if delta == (Usage{}) {
+			continue
+		}
+		report.Drifts = append(report.Drifts, d)
+		driftObjects += abs(delta.Objects)
+		driftBytes += abs(delta.Bytes)
+	}
+	sort.Slice(report.Drifts, func(i, j int) bool { return report.Drifts[i].Owner < report.Drifts[j].Owner })
+
+	r.metrics.OwnersDrifted.Store(int64(len(report.Drifts)))
+	r.metrics.DriftObjects.Store(driftObjects)
+	r.metrics.DriftBytes.Store(driftBytes)
+
+	for _, d := range report.Drifts {
+		r.logger.Warn().
+			Str("owner", d.Owner).
+			Int64("recorded", d.Recorded.Objects).
+			Int64("actual", d.Actual.Objects).
+			Int64("recorded_bytes", d.Recorded.Bytes).
+			Int64("actual_bytes", d.Actual.Bytes).
+			Msg("quota usage drift detected")
+
+		if !r.autoCorrect {
+			continue
+		}
+		if err := r.store.Set(ctx, d.Owner, d.Actual); err != nil {
+			r.metrics.Failures.Add(1)
+			return report, fmt.Errorf("correct usage for %q: %w", d.Owner, err)
+		}
+		r.metrics.Corrections.Add(1)
+		// Исправление уже применено: без события потребители узнают о нём только из usage API
+		if err := r.publish(ctx, d, report.CheckedAt); err != nil {
+			r.metrics.Failures.Add(1)
+			r.logger.Error().Err(err).Str("owner", d.Owner).Msg("publish QuotaReconciled failed")
+		}
+	}
+
+	r.logger.Info().
+		Int("owners", report.Owners).
+		Int("drifted", len(report.Drifts)).
+		Int64("drift_objects", driftObjects).
+		Int64("drift_bytes", driftBytes).
+		Msg("quota reconciliation completed")
+
+	return report, nil
+}
+
+func (r *Reconciler) publish(ctx context.Context, d Drift, at time.Time) error {
+	if r.events == nil {
+		return nil
+	}
+	env, err := events.Quota.Wrap(NewQuotaReconciled(d, at))
+	if err != nil {
+		return err
+	}
+	return r.events.PublishEnvelope(ctx, env, time.Time{})
+}
+
+// Start запускает сверку по расписанию (Interval, выровненный по At) до отмены контекста
+func (r *Reconciler) Start(ctx context.Context) error {
+	timer := time.NewTimer(r.untilNext(r.clock()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if _, err := r.RunOnce(ctx); err != nil {
+				r.logger.Error().Err(err).Msg("quota reconciliation failed")
+			}
+			timer.Reset(r.untilNext(r.clock()))
+		}
+	}
+}
+
+// untilNext — время до следующего запуска: ближайший момент полночь+At+k*Interval после now
+func (r *Reconciler) untilNext(now time.Time) time.Duration {
+	if r.at == 0 {
+		return r.interval
+	}
+	next := now.UTC().Truncate(24 * time.Hour).Add(r.at)
+	for !next.After(now) {
+		next = next.Add(r.interval)
+	}
+	return next.Sub(now)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,165 @@
+// Package quota — учёт usage медиа-объектов по владельцам.
+// Usage меняется только по событиям из events.media (MediaCreated / MediaContentRecorded /
+// MediaDeleted), а Reconciler периодически сверяет его с фактическим состоянием media.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Usage — занятое владельцем: число медиа и суммарный размер исходников
+type Usage struct {
+	Objects int64
+	Bytes   int64
+}
+
+// Adjustment — изменение usage, вычисленное из одного события
+type Adjustment struct {
+	EventID string
+	Owner   string // пустая строка — общий пул (медиа без владельца)
+	Objects int64
+	Bytes   int64
+}
+
+// AdjustmentFromEvent переводит событие media в корректировку usage.
+// ok=false для событий, не влияющих на usage (например MediaStatusChanged).
+func AdjustmentFromEvent(eventID, eventType string, payload json.RawMessage) (adj Adjustment, ok bool, err error) {
+	switch eventType {
+	case "MediaCreated", "MediaContentRecorded", "MediaDeleted":
+	default:
+		return Adjustment{}, false, nil
+	}
+
+	var body struct {
+		OwnerID      string `json:"owner_id"`
+		Size         int64  `json:"size_bytes"`
+		PreviousSize int64  `json:"previous_size_bytes"`
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &body); err != nil {
+			return Adjustment{}, false, fmt.Errorf("decode %s payload: %w", eventType, err)
+		}
+	}
+
+	adj = Adjustment{EventID: eventID, Owner: body.OwnerID}
+	switch eventType {
+	case "MediaCreated":
+		adj.Objects = 1
+	case "MediaContentRecorded":
+		adj.Bytes = body.Size - body.PreviousSize
+	case "MediaDeleted":
+		adj.Objects, adj.Bytes = -1, -body.Size
+	}
+	return adj, true, nil
+}
+
+// UsageStore хранит usage по владельцам
+type UsageStore interface {
+	// Apply применяет корректировку ровно один раз для EventID.
+	// applied=false означает, что событие уже было учтено (повторная доставка).
+	Apply(ctx context.Context, adj Adjustment) (applied bool, err error)
+	// Usage возвращает текущий usage по всем владельцам
+	Usage(ctx context.Context) (map[string]Usage, error)
+	// OwnerUsage возвращает usage одного владельца; нулевой, если о нём ничего не известно
+	OwnerUsage(ctx context.Context, owner string) (Usage, error)
+	// Set выставляет usage владельца (используется reconciliation)
+	Set(ctx context.Context, owner string, usage Usage) error
+}
+
+// DefaultDedupTTL — сколько MemoryUsageStore помнит учтённые event_id; как и у inbox,
+// должно превышать retention топика событий media
+const DefaultDedupTTL = 7 * 24 * time.Hour
+
+// dedupPruneInterval — как часто Apply удаляет устаревшие event_id
+const dedupPruneInterval = time.Minute
+
+// MemoryUsageStore — потокобезопасная in-memory реализация UsageStore (один инстанс, до рестарта).
+// Учтённые event_id хранятся DefaultDedupTTL.
+type MemoryUsageStore struct {
+	mu      sync.Mutex
+	usage   map[string]Usage
+	applied map[string]time.Time // event_id → когда учтено
+	pruned  time.Time
+	clock   func() time.Time
+}
+
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{
+		usage:   make(map[string]Usage),
+		applied: make(map[string]time.Time),
+		clock:   time.Now,
+	}
+}
+
+func (s *MemoryUsageStore) Apply(ctx context.Context, adj Adjustment) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if adj.EventID == "" {
+		return false, fmt.Errorf("adjustment without event id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	if now.Sub(s.pruned) >= dedupPruneInterval {
+		for id, at := range s.applied {
+			if now.Sub(at) > DefaultDedupTTL {
+				delete(s.applied, id)
+			}
+		}
+		s.pruned = now
+	}
+	if _, seen := s.applied[adj.EventID]; seen {
+		return false, nil
+	}
+	s.applied[adj.EventID] = now
+	u := s.usage[adj.Owner]
+	u.Objects += adj.Objects
+	u.Bytes += adj.Bytes
+	s.usage[adj.Owner] = u
+	return true, nil
+}
+
+func (s *MemoryUsageStore) Usage(ctx context.Context) (map[string]Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]Usage, len(s.usage))
+	for owner, u := range s.usage {
+		out[owner] = u
+	}
+	return out, nil
+}
+
+func (s *MemoryUsageStore) OwnerUsage(ctx context.Context, owner string) (Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return Usage{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.usage[owner], nil
+}
+
+func (s *MemoryUsageStore) Set(ctx context.Context, owner string, usage Usage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usage[owner] = usage
+	return nil
+}
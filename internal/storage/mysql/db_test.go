@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	require.True(t, IsURL("mysql://media@db/media"))
+	require.False(t, IsURL("postgres://media@db/media"))
+
+	dsn, err := ParseURL("mysql://media:p%40ss@db/media?timeout=5s")
+	require.NoError(t, err)
+	cfg, err := driver.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.Equal(t, "media", cfg.User)
+	require.Equal(t, "p@ss", cfg.Passwd)
+	require.Equal(t, "db:3306", cfg.Addr)
+	require.Equal(t, "media", cfg.DBName)
+	require.True(t, cfg.ParseTime)
+	require.True(t, cfg.InterpolateParams)
+	require.Equal(t, "UTC", cfg.Loc.String())
+	require.Equal(t, "'+00:00'", cfg.Params["time_zone"])
+	require.Equal(t, "5s", cfg.Timeout.String())
+
+	// Параметры URL важнее значений по умолчанию
+	dsn, err = ParseURL("mysql://media@db:3307/media?interpolateParams=false")
+	require.NoError(t, err)
+	cfg, err = driver.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.Equal(t, "db:3307", cfg.Addr)
+	require.False(t, cfg.InterpolateParams)
+
+	for _, bad := range []string{"postgres://media@db/media", "mysql://db", "mysql:///media"} {
+		_, err := ParseURL(bad)
+		require.Error(t, err, bad)
+	}
+}
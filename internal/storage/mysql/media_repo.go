@@ -0,0 +1,541 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// mediaColumns — колонки media в порядке полей models.Media
+const mediaColumns = `id, status, type, source, created_at, updated_at, title, tags, metadata, processing_attempts, last_error, owner_id, checksum_sha256, size_bytes, content_type, publish_at, expires_at, visibility`
+
+const selectMediaByID = `SELECT ` + mediaColumns + ` FROM media WHERE id = ?`
+
+// MediaRepo — repository.MediaRepository поверх MySQL. RETURNING в MySQL нет: изменения
+// перечитываются той же транзакцией (своей, если вызывающий её не открыл).
+type MediaRepo struct {
+	db *sqlx.DB
+	tx *TxManager
+}
+
+func NewMediaRepo(db *sqlx.DB) *MediaRepo {
+	return &MediaRepo{db: db, tx: NewTxManager(db)}
+}
+
+var _ repository.MediaRepository = (*MediaRepo)(nil)
+
+// WithinTransaction — см. TxManager.WithinTransaction
+func (r *MediaRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.tx.WithinTransaction(ctx, fn)
+}
+
+// Create вставляет медиа. Нарушение уникальности в MySQL откатывает только команду, не
+// транзакцию, поэтому конфликт одной записи batch'а не мешает остальным.
+func (r *MediaRepo) Create(ctx context.Context, m *models.Media) error {
+	const q = `
+		INSERT INTO media (id, status, type, source, created_at, updated_at, title, tags, metadata, last_error, owner_id, visibility)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CAST(? AS JSON), CAST(? AS JSON), '', ?, COALESCE(NULLIF(?, ''), 'draft'))
+	`
+	tags, err := jsonString(m.Tags)
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	metadata, err := jsonString(m.Metadata)
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, q,
+		m.ID, m.Status, m.Type, m.Source, m.CreatedAt, m.UpdatedAt, m.Title, tags, metadata, nullUUID(m.OwnerID), string(m.Visibility),
+	)
+	if isDuplicate(err) {
+		return models.ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	return nil
+}
+
+func (r *MediaRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	m, err := r.get(ctx, selectMediaByID, id)
+	if err != nil {
+		return nil, fmt.Errorf("media get by id: %w", err)
+	}
+	return m, nil
+}
+
+// GetForUpdate — см. repository.MediaRepository; без транзакции в ctx блокировка снимается сразу
+func (r *MediaRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	m, err := r.get(ctx, selectMediaByID+` FOR UPDATE`, id)
+	if err != nil {
+		return nil, fmt.Errorf("media get for update: %w", err)
+	}
+	return m, nil
+}
+
+// get читает одно медиа; нет строки — models.ErrNotFound
+func (r *MediaRepo) get(ctx context.Context, q string, args ...any) (*models.Media, error) {
+	var m models.Media
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &m, q, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UpdateStatus меняет статус: вход в processing считается попыткой обработки, успешное
+// завершение (ready или scheduled под эмбарго) обнуляет счётчик и последнюю ошибку
+func (r *MediaRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	const q = `
+		UPDATE media
+		SET status = ?,
+		    updated_at = NOW(6),
+		    processing_attempts = CASE
+		        WHEN ? = 'processing' THEN processing_attempts + 1
+		        WHEN ? IN ('ready', 'scheduled') THEN 0
+		        ELSE processing_attempts
+		    END,
+		    last_error = CASE WHEN ? IN ('ready', 'scheduled') THEN '' ELSE last_error END
+		WHERE id = ?`
+
+	m, err := r.update(ctx, id, q, status, status, status, status, id)
+	if err != nil {
+		return nil, fmt.Errorf("media update status: %w", err)
+	}
+	return m, nil
+}
+
+// Update частично обновляет медиа: NULL параметр в COALESCE оставляет колонку как есть.
+// Возвращает обновлённую запись; пустой патч ничего не пишет и возвращает текущую.
+func (r *MediaRepo) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	if patch.IsEmpty() {
+		return r.GetByID(ctx, id)
+	}
+
+	const q = `
+		UPDATE media
+		SET source = COALESCE(?, source),
+		    title = COALESCE(?, title),
+		    tags = COALESCE(CAST(? AS JSON), tags),
+		    metadata = COALESCE(CAST(? AS JSON), metadata),
+		    checksum_sha256 = COALESCE(?, checksum_sha256),
+		    size_bytes = COALESCE(?, size_bytes),
+		    content_type = COALESCE(?, content_type),
+		    publish_at = CASE WHEN ? THEN ? ELSE publish_at END,
+		    expires_at = CASE WHEN ? THEN ? ELSE expires_at END,
+		    visibility = COALESCE(?, visibility),
+		    updated_at = NOW(6)
+		WHERE id = ?`
+
+	var tags, metadata any
+	if patch.Tags != nil {
+		s, err := jsonString(*patch.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("media update: %w", err)
+		}
+		tags = s
+	}
+	if patch.Metadata != nil {
+		s, err := jsonString(*patch.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("media update: %w", err)
+		}
+		metadata = s
+	}
+	var (
+		checksum, contentType *string
+		size                  *int64
+	)
+	if c := patch.Content; c != nil {
+		checksum, size, contentType = &c.Checksum, &c.Size, &c.ContentType
+	}
+	// Расписание заменяется целиком, в том числе на NULL — COALESCE тут не подходит
+	var publishAt, expiresAt sql.NullTime
+	if sch := patch.Schedule; sch != nil {
+		publishAt, expiresAt = nullTime(sch.PublishAt), nullTime(sch.ExpiresAt)
+	}
+
+	m, err := r.update(ctx, id, q, patch.Source, patch.Title, tags, metadata, checksum, size, contentType,
+		patch.Schedule != nil, publishAt, patch.Schedule != nil, expiresAt, patch.Visibility, id)
+	if err != nil {
+		return nil, fmt.Errorf("media update: %w", err)
+	}
+	return m, nil
+}
+
+// update выполняет UPDATE медиа id и перечитывает его в той же транзакции
+func (r *MediaRepo) update(ctx context.Context, id uuid.UUID, q string, args ...any) (*models.Media, error) {
+	var m *models.Media
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+		var err error
+		m, err = r.get(ctx, selectMediaByID, id)
+		return err
+	})
+	return m, err
+}
+
+// List возвращает страницу медиа в порядке filter.Sort, при равных значениях — по id
+func (r *MediaRepo) List(ctx context.Context, filter repository.ListFilter) ([]*models.Media, error) {
+	filter = filter.WithDefaults()
+	if !filter.Sort.Valid() {
+		return nil, fmt.Errorf("%w: unknown sort %q", models.ErrInvalidArgument, filter.Sort)
+	}
+
+	// Колонка сортировки — из закрытого списка ListSort, в запрос подставляется как есть
+	column, direction, after := string(filter.Sort), "DESC", "<"
+	if filter.Ascending {
+		direction, after = "ASC", ">"
+	}
+	var cursorAt sql.NullTime
+	var cursorID string
+	if filter.After != nil {
+		cursorAt = sql.NullTime{Time: filter.After.At, Valid: true}
+		cursorID = filter.After.ID.String()
+	}
+
+	q := `
+		SELECT ` + mediaColumns + `
+		FROM media` + listWhere + `
+		  AND (? IS NULL OR ` + column + ` ` + after + ` ? OR (` + column + ` = ? AND id > ?))
+		ORDER BY ` + column + ` ` + direction + `, id
+		LIMIT ? OFFSET ?
+	`
+	args := append(listArgs(filter), cursorAt, cursorAt, cursorAt, cursorID, filter.Limit, filter.Offset)
+
+	var out []*models.Media
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, args...); err != nil {
+		return nil, fmt.Errorf("media list: %w", err)
+	}
+	return out, nil
+}
+
+// listWhere — условия ListFilter без пагинации, параметры — listArgs
+const listWhere = `
+		WHERE (? = '' OR status = ?)
+		  AND (? IS NULL OR owner_id = ?)
+		  AND (? = '' OR checksum_sha256 = ?)
+		  AND (? = '' OR type = ?)
+		  AND (? = '' OR visibility = ?)`
+
+func listArgs(f repository.ListFilter) []any {
+	owner := nullUUID(f.OwnerID)
+	return []any{f.Status, f.Status, owner, owner, f.Checksum, f.Checksum, f.Type, f.Type, f.Visibility, f.Visibility}
+}
+
+// Totals — см. repository.TotalsQuery. Фасеты считаются одним GROUP BY status, type, и итог
+// с ними — их сумма. CountEstimated на таблице от repository.EstimateThreshold строк (по
+// information_schema.TABLES.TABLE_ROWS — аналогу pg_class.reltuples) берёт оценку из EXPLAIN.
+func (r *MediaRepo) Totals(ctx context.Context, filter repository.ListFilter, tq repository.TotalsQuery) (repository.ListTotals, error) {
+	if !tq.Count.Valid() {
+		return repository.ListTotals{}, fmt.Errorf("%w: unknown count mode %q", models.ErrInvalidArgument, tq.Count)
+	}
+	db, args := conn(ctx, r.db), listArgs(filter)
+
+	var (
+		t   repository.ListTotals
+		err error
+	)
+	switch {
+	case tq.Facets:
+		err = facets(ctx, db, args, &t, tq.Count != repository.CountNone)
+	case tq.Count == repository.CountEstimated:
+		err = estimateCount(ctx, db, args, &t)
+	case tq.Count == repository.CountExact:
+		err = sqlx.GetContext(ctx, db, &t.Total, `SELECT COUNT(*) FROM media`+listWhere, args...)
+	}
+	if err != nil {
+		return repository.ListTotals{}, fmt.Errorf("media totals: %w", err)
+	}
+	return t, nil
+}
+
+// facets заполняет t.ByStatus и t.ByType, а с withTotal — и t.Total
+func facets(ctx context.Context, db sqlx.QueryerContext, args []any, t *repository.ListTotals, withTotal bool) error {
+	var groups []struct {
+		Status models.Status    `db:"status"`
+		Type   models.MediaType `db:"type"`
+		Count  int64            `db:"count"`
+	}
+	q := `SELECT status, type, COUNT(*) AS count FROM media` + listWhere + ` GROUP BY status, type`
+	if err := sqlx.SelectContext(ctx, db, &groups, q, args...); err != nil {
+		return err
+	}
+	t.ByStatus = make(map[models.Status]int64)
+	t.ByType = make(map[models.MediaType]int64)
+	for _, g := range groups {
+		t.ByStatus[g.Status] += g.Count
+		t.ByType[g.Type] += g.Count
+		if withTotal {
+			t.Total += g.Count
+		}
+	}
+	return nil
+}
+
+// estimateCount — точный COUNT(*) на малой таблице, иначе строки плана с долей, прошедшей
+// фильтр (rows_examined_per_scan × filtered из EXPLAIN FORMAT=JSON). План без таблицы
+// (оптимизатор ответил сам) — считается точно.
+func estimateCount(ctx context.Context, db sqlx.QueryerContext, args []any, t *repository.ListTotals) error {
+	var rows sql.NullInt64
+	const size = `SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'media'`
+	if err := sqlx.GetContext(ctx, db, &rows, size); err != nil {
+		return err
+	}
+	exact := func() error {
+		return sqlx.GetContext(ctx, db, &t.Total, `SELECT COUNT(*) FROM media`+listWhere, args...)
+	}
+	if rows.Int64 < repository.EstimateThreshold {
+		return exact()
+	}
+
+	var raw string
+	if err := sqlx.GetContext(ctx, db, &raw, `EXPLAIN FORMAT=JSON SELECT 1 FROM media`+listWhere, args...); err != nil {
+		return err
+	}
+	var plan struct {
+		QueryBlock struct {
+			Table *struct {
+				Rows     float64 `json:"rows_examined_per_scan"`
+				Filtered string  `json:"filtered"`
+			} `json:"table"`
+		} `json:"query_block"`
+	}
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil {
+		return fmt.Errorf("parse explain: %w", err)
+	}
+	table := plan.QueryBlock.Table
+	if table == nil {
+		return exact()
+	}
+	filtered := 100.0
+	if _, err := fmt.Sscan(table.Filtered, &filtered); err != nil && table.Filtered != "" {
+		return fmt.Errorf("parse explain: filtered %q: %w", table.Filtered, err)
+	}
+	t.Total, t.Estimated = int64(table.Rows*filtered/100), true
+	return nil
+}
+
+// Search — полнотекстовый поиск FULLTEXT индексом по title и metadata.description: каждое
+// слово запроса обязательно (BOOLEAN MODE с +слово). Слова короче innodb_ft_min_token_size
+// (3 по умолчанию) и стоп-слова InnoDB не ищутся. Без текста сортирует по дате, как List.
+func (r *MediaRepo) Search(ctx context.Context, sq repository.SearchQuery) ([]repository.SearchHit, error) {
+	sq = sq.WithDefaults()
+
+	tags, err := jsonString(models.Tags(sq.Tags))
+	if err != nil {
+		return nil, fmt.Errorf("media search: %w", err)
+	}
+	owner := nullUUID(sq.OwnerID)
+	// rank — зарезервированное слово MySQL 8, псевдоним в обратных кавычках
+	rank, match, args := "0", "TRUE", []any{}
+	if text := booleanQuery(sq.Text); text != "" {
+		rank = "MATCH(title, search_description) AGAINST (? IN BOOLEAN MODE)"
+		match = rank
+		args = append(args, text, text)
+	}
+	q := `
+		SELECT ` + mediaColumns + `, ` + rank + " AS `rank`" + `
+		FROM media
+		WHERE ` + match + `
+		  AND JSON_CONTAINS(tags, CAST(? AS JSON))
+		  AND (? = '' OR status = ?)
+		  AND (? IS NULL OR owner_id = ?)
+		ORDER BY ` + "`rank`" + ` DESC, created_at DESC, id
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, tags, sq.Status, sq.Status, owner, owner, sq.Limit, sq.Offset)
+
+	var out []repository.SearchHit
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, args...); err != nil {
+		return nil, fmt.Errorf("media search: %w", err)
+	}
+	return out, nil
+}
+
+// booleanQuery — строка поиска в синтаксисе BOOLEAN MODE: каждое слово с "+"; операторы
+// и пунктуация пользователя отбрасываются
+func booleanQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, w := range words {
+		words[i] = "+" + w
+	}
+	return strings.Join(words, " ")
+}
+
+// Dashboard — см. repository.DashboardQuery. История статусов переживает удаление media,
+// но с фильтром по владельцу учитывается только история существующих media.
+func (r *MediaRepo) Dashboard(ctx context.Context, dq repository.DashboardQuery) (repository.Dashboard, error) {
+	const countsQuery = `
+		SELECT status, type, COUNT(*) AS count, COALESCE(SUM(created_at >= ?), 0) AS created
+		FROM media
+		WHERE ? IS NULL OR owner_id = ?
+		GROUP BY status, type`
+
+	// Длительность — от последнего входа в processing до перехода в ready/failed;
+	// scheduled — та же успешная обработка, опубликованная позже
+	const processingQuery = `
+		SELECT COALESCE(SUM(h.to_status IN ('ready', 'scheduled')), 0) AS completed,
+		       COALESCE(SUM(h.to_status = 'failed'), 0) AS failed,
+		       COALESCE(AVG(TIMESTAMPDIFF(MICROSECOND, p.changed_at, h.changed_at)), 0) / 1000000 AS avg_seconds
+		FROM media_status_history h
+		LEFT JOIN media m ON m.id = h.media_id
+		LEFT JOIN LATERAL (
+			SELECT changed_at FROM media_status_history
+			WHERE media_id = h.media_id AND to_status = 'processing' AND changed_at <= h.changed_at
+			ORDER BY changed_at DESC
+			LIMIT 1
+		) p ON TRUE
+		WHERE h.from_status = 'processing' AND h.to_status IN ('ready', 'scheduled', 'failed')
+		  AND h.changed_at >= ?
+		  AND (? IS NULL OR m.owner_id = ?)`
+
+	var (
+		counts []struct {
+			Status  models.Status    `db:"status"`
+			Type    models.MediaType `db:"type"`
+			Count   int64            `db:"count"`
+			Created int64            `db:"created"`
+		}
+		processing struct {
+			Completed  int64   `db:"completed"`
+			Failed     int64   `db:"failed"`
+			AvgSeconds float64 `db:"avg_seconds"`
+		}
+	)
+	db, owner := conn(ctx, r.db), nullUUID(dq.OwnerID)
+	if err := sqlx.SelectContext(ctx, db, &counts, countsQuery, dq.Since, owner, owner); err != nil {
+		return repository.Dashboard{}, fmt.Errorf("media dashboard: %w", err)
+	}
+	if err := sqlx.GetContext(ctx, db, &processing, processingQuery, dq.Since, owner, owner); err != nil {
+		return repository.Dashboard{}, fmt.Errorf("media dashboard: %w", err)
+	}
+
+	d := repository.Dashboard{
+		ByStatus:      make(map[models.Status]int64),
+		ByType:        make(map[models.MediaType]int64),
+		Completed:     processing.Completed,
+		Failed:        processing.Failed,
+		AvgProcessing: time.Duration(processing.AvgSeconds * float64(time.Second)),
+	}
+	for _, c := range counts {
+		d.ByStatus[c.Status] += c.Count
+		d.ByType[c.Type] += c.Count
+		d.Total += c.Count
+		d.Created += c.Created
+	}
+	return d, nil
+}
+
+// Delete удаляет медиа и возвращает удалённую запись,
+// чтобы вызывающий мог положить в outbox событие с её данными.
+func (r *MediaRepo) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	var m *models.Media
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if m, err = r.get(ctx, selectMediaByID+` FOR UPDATE`, id); err != nil {
+			return err
+		}
+		_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM media WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("media delete: %w", err)
+	}
+	return m, nil
+}
+
+// SetLastError сохраняет ошибку последней неудачной обработки
+func (r *MediaRepo) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	const q = `UPDATE media SET last_error = ?, updated_at = NOW(6) WHERE id = ?`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, lastError, id)
+	if err != nil {
+		return fmt.Errorf("media set last error: %w", err)
+	}
+	// updated_at меняется всегда, поэтому 0 изменённых строк — медиа нет
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("media set last error: %w", err)
+	}
+	if n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// AddStatusChange пишет запись в историю статусов; вызывается в той же транзакции, что и смена статуса
+func (r *MediaRepo) AddStatusChange(ctx context.Context, c *models.StatusChange) error {
+	const q = `
+		INSERT INTO media_status_history (media_id, from_status, to_status, actor, reason, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, c.MediaID, c.From, c.To, c.Actor, c.Reason, c.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("media add status change: %w", err)
+	}
+	if c.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("media add status change: %w", err)
+	}
+	return nil
+}
+
+// ListStatusChanges возвращает историю статусов медиа в хронологическом порядке
+func (r *MediaRepo) ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error) {
+	const q = `
+		SELECT id, media_id, from_status, to_status, actor, reason, changed_at
+		FROM media_status_history
+		WHERE media_id = ?
+		ORDER BY changed_at ASC, id ASC
+	`
+	var out []models.StatusChange
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, mediaID); err != nil {
+		return nil, fmt.Errorf("media list status changes: %w", err)
+	}
+	return out, nil
+}
+
+// nullUUID — uuid параметр запроса: uuid.Nil пишется как NULL (owner_id общего пула, нет фильтра)
+func nullUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.String()
+}
+
+// nullTime — необязательное время параметром запроса: nil пишется как NULL
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// jsonString — значение JSON колонки параметром запроса. Строкой, а не []byte: байты драйвер
+// передаёт как binary, а из binary строки MySQL JSON не создаёт.
+func jsonString(v sqldriver.Valuer) (string, error) {
+	value, err := v.Value()
+	if err != nil {
+		return "", err
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return "", fmt.Errorf("json column value %T", value)
+	}
+	return string(b), nil
+}
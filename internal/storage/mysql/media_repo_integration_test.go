@@ -0,0 +1,28 @@
+//go:build integration
+
+package mysql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/repository/repotest"
+	"github.com/romariotrain/media-platform/internal/storage/mysql"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestMediaRepo_Contract(t *testing.T) {
+	db := testutil.StartMySQL(t)
+
+	// один контейнер на весь контракт, каждый подтест — с пустыми таблицами
+	repotest.RunRepositoryTests(t, func(t *testing.T) repository.MediaRepository {
+		for _, table := range []string{"media", "media_status_history"} {
+			_, err := db.DB.ExecContext(context.Background(), `TRUNCATE TABLE `+table)
+			require.NoError(t, err)
+		}
+		return mysql.NewMediaRepo(db.DB)
+	})
+}
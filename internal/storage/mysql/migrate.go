@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	schema "github.com/romariotrain/media-platform/sql"
+)
+
+// MigrateUp применяет схему sql/mysql/script.sql. Скрипт идемпотентен, повторный вызов безопасен.
+func MigrateUp(ctx context.Context, db *sqlx.DB) error {
+	if err := execScript(ctx, db, schema.MySQLUp); err != nil {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown удаляет таблицы схемы вместе с данными (sql/mysql/down.sql)
+func MigrateDown(ctx context.Context, db *sqlx.DB) error {
+	if err := execScript(ctx, db, schema.MySQLDown); err != nil {
+		return fmt.Errorf("migrate down: %w", err)
+	}
+	return nil
+}
+
+// execScript выполняет скрипт по одной команде: без multiStatements драйвер принимает только
+// одну. Строки-комментарии выбрасываются, команды разделены ";".
+func execScript(ctx context.Context, db *sqlx.DB, script string) error {
+	var b strings.Builder
+	for line := range strings.Lines(script) {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			b.WriteString(line)
+		}
+	}
+	for stmt := range strings.SplitSeq(b.String(), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// DefaultClaimLease — на сколько GetPending откладывает захваченные события: за это время
+// publisher должен их опубликовать и отметить, иначе их возьмёт следующий вызов
+const DefaultClaimLease = time.Minute
+
+// OutboxRepo — outbox в MySQL. Записи читаются в postgres.OutboxRecord: publisher и админка
+// работают с одним типом независимо от хранилища.
+type OutboxRepo struct {
+	db       *sqlx.DB
+	tx       *TxManager
+	registry *events.Registry
+	cipher   *encryption.Cipher
+	lease    time.Duration
+	shard    postgres.OutboxShard // см. Shard
+}
+
+// outboxColumns — колонки outbox в порядке полей postgres.OutboxRecord
+const outboxColumns = `id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at,
+        attempts, last_error, next_retry_at, dead_lettered_at, processed_at`
+
+func NewOutboxRepo(db *sqlx.DB) *OutboxRepo {
+	return &OutboxRepo{db: db, tx: NewTxManager(db), registry: events.Default, lease: DefaultClaimLease}
+}
+
+// WithEncryption шифрует payload новых событий; прочитанные payload расшифровываются,
+// записанные до включения шифрования читаются как есть
+func (r *OutboxRepo) WithEncryption(c *encryption.Cipher) *OutboxRepo {
+	r.cipher = c
+	return r
+}
+
+// WithClaimLease задаёт аренду захваченных событий (по умолчанию DefaultClaimLease)
+func (r *OutboxRepo) WithClaimLease(d time.Duration) *OutboxRepo {
+	r.lease = d
+	return r
+}
+
+// Shard возвращает outbox, в котором GetPending и CountPending видят только события шарда s
+// (см. postgres.OutboxShard; хэш — CRC32 aggregate_id). Исходный репозиторий не меняется.
+func (r *OutboxRepo) Shard(s postgres.OutboxShard) *OutboxRepo {
+	c := *r
+	c.shard = s
+	return &c
+}
+
+// shardCondition — условие шарда для WHERE; без шардирования пустое
+func (r *OutboxRepo) shardCondition() (string, []any) {
+	if !r.shard.Sharded() {
+		return "", nil
+	}
+	return " AND CRC32(aggregate_id) % ? = ?", []any{r.shard.Count, r.shard.Index}
+}
+
+// openPending расшифровывает payload прочитанных записей; event_id — aad шифротекста.
+// Запись, которую не удалось расшифровать (ключ неизвестен или выведен из ротации),
+// паркуется в dead letter с ошибкой, остальные возвращаются: иначе одна такая запись
+// роняла бы каждый poll и publisher стоял бы на ней.
+func (r *OutboxRepo) openPending(ctx context.Context, records []postgres.OutboxRecord) ([]postgres.OutboxRecord, error) {
+	opened := records[:0]
+	for _, rec := range records {
+		payload, err := r.cipher.OpenJSON(ctx, rec.Payload, []byte(rec.EventID))
+		if err != nil {
+			if err := r.MarkDeadLetter(ctx, rec.ID, "decrypt payload: "+err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rec.Payload = payload
+		opened = append(opened, rec)
+	}
+	return opened, nil
+}
+
+// Add кладёт событие в outbox в рамках транзакции из контекста, без неё — ErrNoTx.
+// См. AddBatch: одиночное событие — batch из одного.
+func (r *OutboxRepo) Add(ctx context.Context, event models.DomainEvent) error {
+	return r.AddBatch(ctx, []models.DomainEvent{event})
+}
+
+// AddBatch — Add для пачки событий (service.BatchOutbox). Номера в потоках агрегатов
+// резервируются в aggregate_sequences: строки счётчиков заблокированы до конца транзакции,
+// так что номера идут в порядке коммитов. События одного агрегата получают номера в порядке batch.
+func (r *OutboxRepo) AddBatch(ctx context.Context, batch []models.DomainEvent) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		return ErrNoTx
+	}
+
+	// Агрегаты по возрастанию id, чтобы конкурентные пачки блокировали счётчики в одном порядке
+	counts := make(map[string]int64, len(batch))
+	for _, event := range batch {
+		counts[event.AggregateID().String()]++
+	}
+	aggregates := slices.Sorted(maps.Keys(counts))
+
+	var (
+		values []string
+		args   []any
+	)
+	for _, id := range aggregates {
+		values = append(values, "(?, ?)")
+		args = append(args, id, counts[id])
+	}
+	reserve := `
+    INSERT INTO aggregate_sequences (aggregate_id, sequence) VALUES ` + strings.Join(values, ", ") + ` AS reserved
+    ON DUPLICATE KEY UPDATE sequence = aggregate_sequences.sequence + reserved.sequence
+`
+	if _, err := tx.ExecContext(ctx, reserve, args...); err != nil {
+		return fmt.Errorf("reserve aggregate sequences: %w", err)
+	}
+	query, args, err := sqlx.In(`SELECT aggregate_id, sequence FROM aggregate_sequences WHERE aggregate_id IN (?)`, aggregates)
+	if err != nil {
+		return fmt.Errorf("reserve aggregate sequences: %w", err)
+	}
+	var reserved []struct {
+		AggregateID string `db:"aggregate_id"`
+		Sequence    int64  `db:"sequence"`
+	}
+	if err := tx.SelectContext(ctx, &reserved, query, args...); err != nil {
+		return fmt.Errorf("reserve aggregate sequences: %w", err)
+	}
+	// next — номер следующего события агрегата: счётчик хранит последний зарезервированный
+	next := make(map[string]int64, len(reserved))
+	for _, row := range reserved {
+		next[row.AggregateID] = row.Sequence - counts[row.AggregateID] + 1
+	}
+
+	values, args = values[:0], args[:0]
+	for _, event := range batch {
+		aggregate := event.AggregateID().String()
+		seq := next[aggregate]
+		next[aggregate]++
+		if s, ok := event.(models.Sequenced); ok {
+			s.SetSequence(seq)
+		}
+
+		env, err := r.registry.Wrap(event)
+		if err != nil {
+			return fmt.Errorf("wrap event: %w", err)
+		}
+		env.Sequence = seq
+		payload, err := r.cipher.SealJSON(ctx, env.Payload, []byte(env.EventID))
+		if err != nil {
+			return fmt.Errorf("encrypt payload: %w", err)
+		}
+
+		// payload строкой: из binary параметра MySQL JSON не создаёт
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, '')")
+		args = append(args, env.EventID, env.EventType, env.SchemaVersion, env.AggregateID, env.Sequence, string(payload), env.OccurredAt)
+	}
+	insert := `
+    INSERT INTO outbox (event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at, last_error)
+    VALUES ` + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, insert, args...); err != nil {
+		return fmt.Errorf("insert outbox: %w", err)
+	}
+	return nil
+}
+
+// GetPending захватывает события к публикации: не обработанные, не припаркованные и без
+// отложенного повтора в будущем. Строки, заблокированные другим publisher'ом, пропускаются
+// (FOR UPDATE SKIP LOCKED), а захваченные откладываются на время аренды — так конкурентные
+// publisher'ы не получают одни и те же события. MarkProcessed, MarkFailed и MarkDeadLetter
+// снимают аренду. У шарда (Shard) — только события его агрегатов. Пока более раннее событие
+// агрегата ждёт повтора или захвачено, его следующие события не берутся.
+func (r *OutboxRepo) GetPending(ctx context.Context, limit int) ([]postgres.OutboxRecord, error) {
+	shard, args := r.shardCondition()
+	q := `
+        SELECT ` + outboxColumns + `
+        FROM outbox
+        WHERE processed_at IS NULL
+          AND dead_lettered_at IS NULL
+          AND (next_retry_at IS NULL OR next_retry_at <= NOW(6))
+          AND NOT EXISTS (
+              SELECT 1 FROM outbox prev
+              WHERE prev.aggregate_id = outbox.aggregate_id
+                AND prev.id < outbox.id
+                AND prev.processed_at IS NULL
+                AND prev.dead_lettered_at IS NULL
+                AND prev.next_retry_at > NOW(6)
+          )` + shard + `
+        ORDER BY id ASC
+        LIMIT ?
+        FOR UPDATE SKIP LOCKED
+    `
+
+	var records []postgres.OutboxRecord
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if err := sqlx.SelectContext(ctx, db, &records, q, append(args, limit)...); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		ids := make([]int64, len(records))
+		for i, rec := range records {
+			ids[i] = rec.ID
+		}
+		claim, args, err := sqlx.In(`UPDATE outbox SET next_retry_at = NOW(6) + INTERVAL ? MICROSECOND WHERE id IN (?)`,
+			r.lease.Microseconds(), ids)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, claim, args...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get pending: %w", err)
+	}
+	records, err = r.openPending(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CountPending возвращает число необработанных событий (backlog publisher'а); у шарда — его событий
+func (r *OutboxRepo) CountPending(ctx context.Context) (int64, error) {
+	shard, args := r.shardCondition()
+	q := `SELECT COUNT(*) FROM outbox WHERE processed_at IS NULL AND dead_lettered_at IS NULL` + shard
+
+	var n int64
+	if err := r.db.GetContext(ctx, &n, q, args...); err != nil {
+		return 0, fmt.Errorf("count pending: %w", err)
+	}
+	return n, nil
+}
+
+func (r *OutboxRepo) MarkProcessed(ctx context.Context, id int64) error {
+	const q = `UPDATE outbox SET processed_at = NOW(6), next_retry_at = NULL WHERE id = ?`
+
+	if _, err := r.db.ExecContext(ctx, q, id); err != nil {
+		return fmt.Errorf("mark processed: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed фиксирует неудачную попытку публикации и откладывает следующую на retryIn
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error {
+	const q = `
+        UPDATE outbox
+        SET attempts = attempts + 1,
+            last_error = ?,
+            next_retry_at = NOW(6) + INTERVAL ? MICROSECOND
+        WHERE id = ?
+    `
+
+	if _, err := r.db.ExecContext(ctx, q, lastError, retryIn.Microseconds(), id); err != nil {
+		return fmt.Errorf("mark failed: %w", err)
+	}
+	return nil
+}
+
+// MarkDeadLetter паркует событие, исчерпавшее попытки: publisher его больше не берёт
+func (r *OutboxRepo) MarkDeadLetter(ctx context.Context, id int64, lastError string) error {
+	const q = `
+        UPDATE outbox
+        SET attempts = attempts + 1,
+            last_error = ?,
+            next_retry_at = NULL,
+            dead_lettered_at = NOW(6)
+        WHERE id = ?
+    `
+
+	if _, err := r.db.ExecContext(ctx, q, lastError, id); err != nil {
+		return fmt.Errorf("mark dead letter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+//go:build integration
+
+package mysql_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/storage/mysql"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestOutboxRepo_AggregateSequence(t *testing.T) {
+	db := testutil.StartMySQL(t)
+	ctx := context.Background()
+	outbox := mysql.NewOutboxRepo(db.DB)
+	svc := service.New(mysql.NewMediaRepo(db.DB), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+
+	records, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	sequences := map[string][]int64{}
+	for _, rec := range records {
+		sequences[rec.AggregateID] = append(sequences[rec.AggregateID], rec.Sequence)
+	}
+	require.Equal(t, []int64{1, 2}, sequences[m.ID.String()])
+
+	n, err := outbox.CountPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+}
+
+func TestOutboxRepo_ConcurrentClaims(t *testing.T) {
+	db := testutil.StartMySQL(t)
+	ctx := context.Background()
+	outbox := mysql.NewOutboxRepo(db.DB)
+	svc := service.New(mysql.NewMediaRepo(db.DB), outbox)
+
+	const total = 40
+	for range total {
+		_, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+		require.NoError(t, err)
+	}
+
+	// Конкурентные publisher'ы не получают одно событие дважды
+	var (
+		mu      sync.Mutex
+		claimed = map[int64]int{}
+		wg      sync.WaitGroup
+	)
+	for range 4 {
+		wg.Go(func() {
+			for {
+				records, err := outbox.GetPending(ctx, 3)
+				if !assertNoError(t, err) || len(records) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, rec := range records {
+					claimed[rec.ID]++
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	require.Len(t, claimed, total)
+	for id, n := range claimed {
+		require.Equal(t, 1, n, "event %d", id)
+	}
+
+	// Захваченное, но не отмеченное событие возвращается после аренды
+	short := mysql.NewOutboxRepo(db.DB).WithClaimLease(time.Millisecond)
+	_, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+	first, err := short.GetPending(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	time.Sleep(10 * time.Millisecond)
+	again, err := short.GetPending(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, first[0].ID, again[0].ID)
+
+	require.NoError(t, short.MarkProcessed(ctx, first[0].ID))
+	records, err := short.GetPending(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+// assertNoError — require.NoError для горутин: отмечает ошибку, не останавливая тест
+func assertNoError(t *testing.T, err error) bool {
+	t.Helper()
+	if err != nil {
+		t.Errorf("get pending: %v", err)
+		return false
+	}
+	return true
+}
+
+func TestOutboxRepo_Shard(t *testing.T) {
+	db := testutil.StartMySQL(t)
+	ctx := context.Background()
+	outbox := mysql.NewOutboxRepo(db.DB)
+	svc := service.New(mysql.NewMediaRepo(db.DB), outbox)
+
+	const aggregates = 12
+	for range aggregates {
+		m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+		require.NoError(t, err)
+		_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+		require.NoError(t, err)
+	}
+	total, err := outbox.CountPending(ctx)
+	require.NoError(t, err)
+
+	// Шарды делят outbox без пересечений, все события агрегата — в одном шарде
+	owner := map[string]int{}
+	var claimed int64
+	for i := range 3 {
+		shard := outbox.Shard(postgres.OutboxShard{Index: i, Count: 3})
+		n, err := shard.CountPending(ctx)
+		require.NoError(t, err)
+		records, err := shard.GetPending(ctx, 100)
+		require.NoError(t, err)
+		require.Len(t, records, int(n))
+		for _, rec := range records {
+			if prev, ok := owner[rec.AggregateID]; ok {
+				require.Equal(t, prev, i, "aggregate %s split between shards", rec.AggregateID)
+			}
+			owner[rec.AggregateID] = i
+		}
+		claimed += n
+	}
+	require.Equal(t, total, claimed)
+	require.Len(t, owner, aggregates)
+}
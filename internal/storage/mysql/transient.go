@@ -0,0 +1,33 @@
+package mysql
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// Коды ошибок сервера MySQL
+const (
+	errDuplicateEntry  = 1062
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+// IsTransient — ошибка, после которой операцию можно повторить целиком: взаимоблокировка
+// (1213) и истёкшее ожидание блокировки (1205), а также соединение, отвергнутое до отправки
+// запроса (driver.ErrBadConn). Обрыв посреди запроса (ErrInvalidConn) сюда не входит:
+// неизвестно, применился ли он.
+func IsTransient(err error) bool {
+	var myErr *driver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == errDeadlock || myErr.Number == errLockWaitTimeout
+	}
+	return errors.Is(err, sqldriver.ErrBadConn)
+}
+
+// isDuplicate — нарушение уникальности
+func isDuplicate(err error) bool {
+	var myErr *driver.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == errDuplicateEntry
+}
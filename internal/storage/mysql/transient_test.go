@@ -0,0 +1,20 @@
+package mysql
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransient(t *testing.T) {
+	require.False(t, IsTransient(nil))
+	require.True(t, IsTransient(&driver.MySQLError{Number: errDeadlock}))
+	require.True(t, IsTransient(fmt.Errorf("media update status: %w", &driver.MySQLError{Number: errLockWaitTimeout})))
+	require.True(t, IsTransient(sqldriver.ErrBadConn))
+	require.False(t, IsTransient(&driver.MySQLError{Number: errDuplicateEntry}))
+	require.False(t, IsTransient(errors.New("unexpected EOF")))
+}
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoTx — операция должна выполняться внутри WithinTransaction
+var ErrNoTx = errors.New("mysql: operation requires a transaction")
+
+type txKey struct{}
+
+// TxManager открывает транзакции MySQL и передаёт их репозиториям через контекст
+type TxManager struct {
+	db *sqlx.DB
+}
+
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTransaction выполняет fn в транзакции: commit, если fn вернула nil, иначе rollback.
+// Репозитории, получившие ctx из fn, работают в этой транзакции.
+// Вложенный вызов присоединяется к внешней транзакции.
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func txFromContext(ctx context.Context) (*sqlx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sqlx.Tx)
+	return tx, ok
+}
+
+// conn возвращает транзакцию из контекста, если она есть, иначе пул соединений
+func conn(ctx context.Context, db *sqlx.DB) sqlx.ExtContext {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/audit"
+)
+
+// AuditRepo — журнал аудита (audit_log), audit.Store
+type AuditRepo struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepo(db *sqlx.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+var _ audit.Store = (*AuditRepo)(nil)
+
+// auditRow — строка audit_log; diff хранится в jsonb
+type auditRow struct {
+	ID           int64          `db:"id"`
+	At           time.Time      `db:"at"`
+	RequestID    string         `db:"request_id"`
+	Actor        string         `db:"actor"`
+	OwnerID      uuid.NullUUID  `db:"owner_id"`
+	Admin        bool           `db:"admin"`
+	Method       string         `db:"method"`
+	Endpoint     string         `db:"endpoint"`
+	ResourceType string         `db:"resource_type"`
+	ResourceID   string         `db:"resource_id"`
+	Status       int            `db:"status"`
+	Diff         sql.NullString `db:"diff"`
+}
+
+func (r auditRow) entry() (audit.Entry, error) {
+	e := audit.Entry{
+		ID:           r.ID,
+		At:           r.At,
+		RequestID:    r.RequestID,
+		Actor:        r.Actor,
+		OwnerID:      r.OwnerID.UUID,
+		Admin:        r.Admin,
+		Method:       r.Method,
+		Endpoint:     r.Endpoint,
+		ResourceType: r.ResourceType,
+		ResourceID:   r.ResourceID,
+		Status:       r.Status,
+	}
+	if r.Diff.Valid {
+		if err := json.Unmarshal([]byte(r.Diff.String), &e.Diff); err != nil {
+			return audit.Entry{}, fmt.Errorf("audit entry %d: decode diff: %w", r.ID, err)
+		}
+	}
+	return e, nil
+}
+
+// Record пишет записи одной вставкой
+func (r *AuditRepo) Record(ctx context.Context, entries []audit.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var (
+		values []string
+		args   []any
+	)
+	for _, e := range entries {
+		var diff any
+		if len(e.Diff) > 0 {
+			raw, err := json.Marshal(e.Diff)
+			if err != nil {
+				return fmt.Errorf("audit record: encode diff: %w", err)
+			}
+			diff = string(raw)
+		}
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d::jsonb)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11))
+		args = append(args,
+			e.At, e.RequestID, e.Actor, nullUUID(e.OwnerID), e.Admin,
+			e.Method, e.Endpoint, e.ResourceType, e.ResourceID, e.Status, diff,
+		)
+	}
+	q := `
+		INSERT INTO audit_log (at, request_id, actor, owner_id, admin, method, endpoint, resource_type, resource_id, status, diff)
+		VALUES ` + strings.Join(values, ", ")
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, args...); err != nil {
+		return fmt.Errorf("audit record: %w", err)
+	}
+	return nil
+}
+
+// List — см. audit.Store; фильтр по ресурсу идёт по idx_audit_log_resource, по actor — по idx_audit_log_actor
+func (r *AuditRepo) List(ctx context.Context, f audit.Filter) ([]audit.Entry, error) {
+	if f.Limit <= 0 {
+		f.Limit = audit.DefaultListLimit
+	}
+	var from, to sql.NullTime
+	if !f.From.IsZero() {
+		from = sql.NullTime{Time: f.From, Valid: true}
+	}
+	if !f.To.IsZero() {
+		to = sql.NullTime{Time: f.To, Valid: true}
+	}
+
+	const q = `
+		SELECT id, at, request_id, actor, owner_id, admin, method, endpoint, resource_type, resource_id, status, diff
+		FROM audit_log
+		WHERE ($1 = '' OR actor = $1)
+		  AND ($2::uuid IS NULL OR owner_id = $2)
+		  AND ($3 = '' OR resource_type = $3)
+		  AND ($4 = '' OR resource_id = $4)
+		  AND ($5::timestamptz IS NULL OR at >= $5)
+		  AND ($6::timestamptz IS NULL OR at < $6)
+		ORDER BY id DESC
+		LIMIT $7 OFFSET $8
+	`
+	var rows []auditRow
+	err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q,
+		f.Actor, nullUUID(f.OwnerID), f.ResourceType, f.ResourceID, from, to, f.Limit, f.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("audit list: %w", err)
+	}
+	out := make([]audit.Entry, 0, len(rows))
+	for _, row := range rows {
+		e, err := row.entry()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
@@ -0,0 +1,56 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/audit"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestAuditRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewAuditRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	owner, mediaID := uuid.New(), uuid.NewString()
+	diff := map[string]audit.FieldChange{
+		"status": {Before: json.RawMessage(`"uploaded"`), After: json.RawMessage(`"ready"`)},
+	}
+	require.NoError(t, repo.Record(ctx, []audit.Entry{
+		{At: now, RequestID: "r1", Actor: "alice", OwnerID: owner, Method: "PATCH", Endpoint: "/media/{id}/status", ResourceType: audit.ResourceMedia, ResourceID: mediaID, Status: 200, Diff: diff},
+		{At: now.Add(time.Second), RequestID: "r2", Actor: "ops", Admin: true, Method: "POST", Endpoint: "/admin/outbox/{id}/requeue", ResourceType: "outbox", ResourceID: "7", Status: 204},
+	}))
+	require.NoError(t, repo.Record(ctx, nil))
+
+	list, err := repo.List(ctx, audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "r2", list[0].RequestID)
+	require.Nil(t, list[0].Diff)
+	require.Equal(t, uuid.Nil, list[0].OwnerID)
+
+	list, err = repo.List(ctx, audit.Filter{ResourceType: audit.ResourceMedia, ResourceID: mediaID})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, owner, list[0].OwnerID)
+	require.Equal(t, now, list[0].At)
+	require.JSONEq(t, `"ready"`, string(list[0].Diff["status"].After))
+
+	list, err = repo.List(ctx, audit.Filter{Actor: "ops", From: now, To: now.Add(time.Second)})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	list, err = repo.List(ctx, audit.Filter{OwnerID: owner, Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// CollectionRepo хранит коллекции (collections) и их состав (collection_items).
+// Транзакцию берёт из ctx — её открывает MediaRepo.WithinTransaction.
+type CollectionRepo struct {
+	db *sqlx.DB
+}
+
+func NewCollectionRepo(db *sqlx.DB) *CollectionRepo {
+	return &CollectionRepo{db: db}
+}
+
+var _ repository.CollectionRepository = (*CollectionRepo)(nil)
+
+const collectionColumns = `id, owner_id, title, description, created_at, updated_at,
+	(SELECT count(*) FROM collection_items i WHERE i.collection_id = collections.id)::int AS item_count`
+
+// itemPlace — место элемента в коллекции: номер по position. Position — только ключ порядка:
+// пропуски после каскадного удаления медиа на место не влияют.
+const itemPlace = `(row_number() OVER (PARTITION BY collection_id ORDER BY position) - 1)::int`
+
+func (r *CollectionRepo) Create(ctx context.Context, c *models.Collection) error {
+	const q = `
+		INSERT INTO collections (id, owner_id, title, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO NOTHING
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q,
+		c.ID, nullUUID(c.OwnerID), c.Title, c.Description, c.CreatedAt, c.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("collection create: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("collection create: %w", err)
+	}
+	if n == 0 {
+		return models.ErrConflict
+	}
+	return nil
+}
+
+func (r *CollectionRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	return r.get(ctx, "collection get by id", `SELECT `+collectionColumns+` FROM collections WHERE id = $1`, id)
+}
+
+// GetForUpdate — см. repository.CollectionRepository
+func (r *CollectionRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	return r.get(ctx, "collection get for update", `SELECT `+collectionColumns+` FROM collections WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *CollectionRepo) get(ctx context.Context, op, q string, args ...any) (*models.Collection, error) {
+	var c models.Collection
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &c, q, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &c, nil
+}
+
+// List — страница коллекций в порядке created_at; идёт по idx_collections_owner
+func (r *CollectionRepo) List(ctx context.Context, filter repository.CollectionFilter) ([]*models.Collection, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = repository.DefaultListLimit
+	}
+	direction, after := "DESC", "<"
+	if filter.Ascending {
+		direction, after = "ASC", ">"
+	}
+	var cursorAt sql.NullTime
+	var cursorID uuid.UUID
+	if filter.After != nil {
+		cursorAt = sql.NullTime{Time: filter.After.At, Valid: true}
+		cursorID = filter.After.ID
+	}
+
+	q := `
+		SELECT ` + collectionColumns + `
+		FROM collections
+		WHERE ($1::uuid IS NULL OR owner_id = $1)
+		  AND ($2::timestamptz IS NULL OR created_at ` + after + ` $2 OR (created_at = $2 AND id > $3))
+		ORDER BY created_at ` + direction + `, id
+		LIMIT $4
+	`
+	var out []*models.Collection
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, nullUUID(filter.OwnerID), cursorAt, cursorID, filter.Limit); err != nil {
+		return nil, fmt.Errorf("collection list: %w", err)
+	}
+	return out, nil
+}
+
+func (r *CollectionRepo) Update(ctx context.Context, id uuid.UUID, patch models.CollectionPatch) (*models.Collection, error) {
+	if patch.IsEmpty() {
+		return r.GetByID(ctx, id)
+	}
+	const q = `
+		UPDATE collections
+		SET title = COALESCE($2, title),
+		    description = COALESCE($3, description),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + collectionColumns
+	return r.get(ctx, "collection update", q, id, patch.Title, patch.Description)
+}
+
+// Delete — см. repository.CollectionRepository; элементы удаляются каскадом
+func (r *CollectionRepo) Delete(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	const q = `DELETE FROM collections WHERE id = $1 RETURNING ` + collectionColumns
+	return r.get(ctx, "collection delete", q, id)
+}
+
+func (r *CollectionRepo) Items(ctx context.Context, collectionID uuid.UUID) ([]models.CollectionItem, error) {
+	const q = `
+		SELECT collection_id, media_id, ` + itemPlace + ` AS position, added_at
+		FROM collection_items
+		WHERE collection_id = $1
+		ORDER BY position
+	`
+	var out []models.CollectionItem
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, collectionID); err != nil {
+		return nil, fmt.Errorf("collection items: %w", err)
+	}
+	return out, nil
+}
+
+// AddItem — см. repository.CollectionRepository. Вызывается под GetForUpdate коллекции:
+// проверка места и дубликата и вставка не пересекаются с другими изменениями состава.
+func (r *CollectionRepo) AddItem(ctx context.Context, item models.CollectionItem) error {
+	const check = `
+		SELECT count(*)::int AS count, coalesce(bool_or(media_id = $2), false) AS present
+		FROM collection_items
+		WHERE collection_id = $1
+	`
+	var state struct {
+		Count   int  `db:"count"`
+		Present bool `db:"present"`
+	}
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &state, check, item.CollectionID, item.MediaID); err != nil {
+		return fmt.Errorf("collection add item: %w", err)
+	}
+	if item.Position < 0 || item.Position > state.Count {
+		return fmt.Errorf("%w: position must be between 0 and %d", models.ErrInvalidArgument, state.Count)
+	}
+	if state.Present {
+		return fmt.Errorf("%w: media %s is already in the collection", models.ErrConflict, item.MediaID)
+	}
+
+	// anchor — ключ элемента, который сейчас на месте вставки; он и следующие сдвигаются.
+	// Вставка в конец (anchor нет) ставит ключ после последнего.
+	const q = `
+		WITH anchor AS (
+			SELECT position FROM collection_items
+			WHERE collection_id = $1
+			ORDER BY position
+			OFFSET $3 LIMIT 1
+		), shifted AS (
+			UPDATE collection_items SET position = position + 1
+			WHERE collection_id = $1 AND position >= (SELECT position FROM anchor)
+		), touched AS (
+			UPDATE collections SET updated_at = NOW() WHERE id = $1
+		)
+		INSERT INTO collection_items (collection_id, media_id, position, added_at)
+		VALUES ($1, $2, COALESCE(
+			(SELECT position FROM anchor),
+			(SELECT max(position) + 1 FROM collection_items WHERE collection_id = $1),
+			0
+		), $4)
+	`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, item.CollectionID, item.MediaID, item.Position, item.AddedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // коллекцию или медиа успели удалить
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("collection add item: %w", err)
+	}
+	return nil
+}
+
+func (r *CollectionRepo) RemoveItem(ctx context.Context, collectionID, mediaID uuid.UUID) (models.CollectionItem, error) {
+	const q = `
+		WITH ranked AS (
+			SELECT collection_id, media_id, ` + itemPlace + ` AS place
+			FROM collection_items
+			WHERE collection_id = $1
+		), removed AS (
+			DELETE FROM collection_items i
+			USING ranked r
+			WHERE i.collection_id = $1 AND i.media_id = $2
+			  AND r.collection_id = i.collection_id AND r.media_id = i.media_id
+			RETURNING i.collection_id, i.media_id, r.place AS position, i.added_at
+		), touched AS (
+			UPDATE collections SET updated_at = NOW()
+			WHERE id = $1 AND EXISTS (SELECT 1 FROM removed)
+		)
+		SELECT collection_id, media_id, position, added_at FROM removed
+	`
+	var item models.CollectionItem
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &item, q, collectionID, mediaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CollectionItem{}, models.ErrNotFound
+		}
+		return models.CollectionItem{}, fmt.Errorf("collection remove item: %w", err)
+	}
+	return item, nil
+}
+
+// RemoveMedia — см. repository.CollectionRepository; идёт по idx_collection_items_media
+func (r *CollectionRepo) RemoveMedia(ctx context.Context, mediaID uuid.UUID) ([]models.CollectionItem, error) {
+	const q = `
+		WITH ranked AS (
+			SELECT collection_id, media_id, ` + itemPlace + ` AS place
+			FROM collection_items
+			WHERE collection_id IN (SELECT collection_id FROM collection_items WHERE media_id = $1)
+		), removed AS (
+			DELETE FROM collection_items i
+			USING ranked r
+			WHERE i.media_id = $1
+			  AND r.collection_id = i.collection_id AND r.media_id = i.media_id
+			RETURNING i.collection_id, i.media_id, r.place AS position, i.added_at
+		), touched AS (
+			UPDATE collections SET updated_at = NOW()
+			WHERE id IN (SELECT collection_id FROM removed)
+		)
+		SELECT collection_id, media_id, position, added_at FROM removed ORDER BY collection_id
+	`
+	var out []models.CollectionItem
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, mediaID); err != nil {
+		return nil, fmt.Errorf("collection remove media: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestCollectionRepo_Items(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	media := postgres.NewMediaRepo(db.DB)
+	repo := postgres.NewCollectionRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	owner := uuid.New()
+	c := &models.Collection{ID: uuid.New(), OwnerID: owner, Title: "Trip", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, c))
+	require.ErrorIs(t, repo.Create(ctx, c), models.ErrConflict)
+
+	ids := make([]uuid.UUID, 4)
+	for i := range ids {
+		m := &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Type: models.Video, Source: "s3://media/" + uuid.NewString(), CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, media.Create(ctx, m))
+		ids[i] = m.ID
+	}
+	add := func(mediaID uuid.UUID, position int) error {
+		return repo.AddItem(ctx, models.CollectionItem{CollectionID: c.ID, MediaID: mediaID, Position: position, AddedAt: now})
+	}
+	order := func() []uuid.UUID {
+		items, err := repo.Items(ctx, c.ID)
+		require.NoError(t, err)
+		out := make([]uuid.UUID, len(items))
+		for i, it := range items {
+			require.Equal(t, i, it.Position)
+			out[i] = it.MediaID
+		}
+		return out
+	}
+
+	require.NoError(t, add(ids[0], 0))
+	require.NoError(t, add(ids[1], 1))
+	require.NoError(t, add(ids[2], 0)) // в начало
+	require.NoError(t, add(ids[3], 2)) // в середину
+	require.Equal(t, []uuid.UUID{ids[2], ids[0], ids[3], ids[1]}, order())
+	require.ErrorIs(t, add(ids[0], 0), models.ErrConflict)
+	require.ErrorIs(t, add(uuid.New(), 9), models.ErrInvalidArgument)
+	require.ErrorIs(t, add(uuid.New(), 0), models.ErrNotFound) // медиа нет
+
+	got, err := repo.GetByID(ctx, c.ID)
+	require.NoError(t, err)
+	require.Equal(t, 4, got.ItemCount)
+	require.Equal(t, owner, got.OwnerID)
+
+	removed, err := repo.RemoveItem(ctx, c.ID, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, 1, removed.Position)
+	require.Equal(t, []uuid.UUID{ids[2], ids[3], ids[1]}, order())
+	_, err = repo.RemoveItem(ctx, c.ID, ids[0])
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	// Удаление медиа в обход сервиса: каскад оставляет пропуск в ключах, но не в местах
+	_, err = media.Delete(ctx, ids[3])
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{ids[2], ids[1]}, order())
+	require.NoError(t, add(ids[0], 1))
+	require.Equal(t, []uuid.UUID{ids[2], ids[0], ids[1]}, order())
+
+	gone, err := repo.RemoveMedia(ctx, ids[1])
+	require.NoError(t, err)
+	require.Len(t, gone, 1)
+	require.Equal(t, 2, gone[0].Position)
+
+	title := "Trip 2026"
+	updated, err := repo.Update(ctx, c.ID, models.CollectionPatch{Title: &title})
+	require.NoError(t, err)
+	require.Equal(t, title, updated.Title)
+	require.Equal(t, 2, updated.ItemCount)
+
+	deleted, err := repo.Delete(ctx, c.ID)
+	require.NoError(t, err)
+	require.Equal(t, c.ID, deleted.ID)
+	items, err := repo.Items(ctx, c.ID)
+	require.NoError(t, err)
+	require.Empty(t, items)
+}
+
+func TestCollectionRepo_List(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewCollectionRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	owner := uuid.New()
+	var created []*models.Collection
+	for i := range 3 {
+		c := &models.Collection{ID: uuid.New(), OwnerID: owner, Title: "c", CreatedAt: now.Add(time.Duration(i) * time.Second), UpdatedAt: now}
+		require.NoError(t, repo.Create(ctx, c))
+		created = append(created, c)
+	}
+	require.NoError(t, repo.Create(ctx, &models.Collection{ID: uuid.New(), OwnerID: uuid.New(), Title: "other", CreatedAt: now, UpdatedAt: now}))
+
+	page, err := repo.List(ctx, repository.CollectionFilter{OwnerID: owner, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, created[2].ID, page[0].ID)
+
+	cursor := repository.CollectionCursor(page[1])
+	page, err = repo.List(ctx, repository.CollectionFilter{OwnerID: owner, After: &cursor})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, created[0].ID, page[0].ID)
+
+	page, err = repo.List(ctx, repository.CollectionFilter{OwnerID: owner, Ascending: true, Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, created[0].ID, page[0].ID)
+}
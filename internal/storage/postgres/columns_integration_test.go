@@ -0,0 +1,48 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+// Колонки таблиц после миграций совпадают с полями моделей: новая колонка без поля
+// или поле без колонки ловятся здесь, а не на первом запросе в проде.
+func TestSchema_MatchesModels(t *testing.T) {
+	db := testutil.StartPostgres(t)
+
+	tables := []struct {
+		table string
+		model any
+		extra []string // колонки, которые модель не читает
+	}{
+		{"media", models.Media{}, []string{"search_vector"}},
+		{"outbox", postgres.OutboxRecord{}, []string{"created_at"}},
+	}
+	for _, tt := range tables {
+		t.Run(tt.table, func(t *testing.T) {
+			var columns []string
+			err := db.DB.SelectContext(context.Background(), &columns, `
+				SELECT column_name FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = $1`, tt.table)
+			require.NoError(t, err)
+
+			want := tt.extra
+			typ := reflect.TypeOf(tt.model)
+			for i := range typ.NumField() {
+				if tag := typ.Field(i).Tag.Get("db"); tag != "" && tag != "-" {
+					want = append(want, tag)
+				}
+			}
+			require.ElementsMatch(t, want, columns)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// splitColumns разбирает список колонок из SELECT
+func splitColumns(list string) []string {
+	var out []string
+	for _, c := range strings.Split(list, ",") {
+		out = append(out, strings.TrimSpace(c))
+	}
+	return out
+}
+
+// dbTags — db теги полей структуры по порядку
+func dbTags(v any) []string {
+	var out []string
+	t := reflect.TypeOf(v)
+	for i := range t.NumField() {
+		if tag := t.Field(i).Tag.Get("db"); tag != "" && tag != "-" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// Списки колонок в запросах повторяют поля структур, в которые сканируются строки
+func TestColumnLists_MatchStructs(t *testing.T) {
+	require.Equal(t, dbTags(models.Media{}), splitColumns(mediaColumns))
+	require.Equal(t, dbTags(OutboxRecord{}), splitColumns(outboxColumns))
+	require.Equal(t, dbTags(purgeRow{}), splitColumns(purgeColumns))
+}
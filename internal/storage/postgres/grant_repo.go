@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// GrantRepo хранит ACL медиа (media_grants). Записи удаляются вместе с медиа каскадом.
+// Транзакцию берёт из ctx — её открывает MediaRepo.WithinTransaction.
+type GrantRepo struct {
+	db *sqlx.DB
+}
+
+func NewGrantRepo(db *sqlx.DB) *GrantRepo {
+	return &GrantRepo{db: db}
+}
+
+var _ repository.GrantRepository = (*GrantRepo)(nil)
+
+const grantColumns = `media_id, principal, permission, granted_by, created_at, updated_at`
+
+func (r *GrantRepo) Get(ctx context.Context, mediaID, principal uuid.UUID) (models.Grant, error) {
+	const q = `SELECT ` + grantColumns + ` FROM media_grants WHERE media_id = $1 AND principal = $2`
+	var g models.Grant
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &g, q, mediaID, principal); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Grant{}, models.ErrNotFound
+		}
+		return models.Grant{}, fmt.Errorf("grant get: %w", err)
+	}
+	return g, nil
+}
+
+func (r *GrantRepo) Put(ctx context.Context, g models.Grant) (models.Grant, error) {
+	const q = `
+		INSERT INTO media_grants (media_id, principal, permission, granted_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (media_id, principal) DO UPDATE
+		SET permission = EXCLUDED.permission,
+		    granted_by = EXCLUDED.granted_by,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING ` + grantColumns
+	var out models.Grant
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &out, q,
+		g.MediaID, g.Principal, g.Permission, g.GrantedBy, g.CreatedAt, g.UpdatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // медиа успели удалить
+			return models.Grant{}, models.ErrNotFound
+		}
+		return models.Grant{}, fmt.Errorf("grant put: %w", err)
+	}
+	return out, nil
+}
+
+func (r *GrantRepo) Revoke(ctx context.Context, mediaID, principal uuid.UUID) (models.Grant, error) {
+	const q = `DELETE FROM media_grants WHERE media_id = $1 AND principal = $2 RETURNING ` + grantColumns
+	var g models.Grant
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &g, q, mediaID, principal); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Grant{}, models.ErrNotFound
+		}
+		return models.Grant{}, fmt.Errorf("grant revoke: %w", err)
+	}
+	return g, nil
+}
+
+func (r *GrantRepo) List(ctx context.Context, mediaID uuid.UUID) ([]models.Grant, error) {
+	const q = `SELECT ` + grantColumns + ` FROM media_grants WHERE media_id = $1 ORDER BY created_at, principal::text`
+	var out []models.Grant
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, mediaID); err != nil {
+		return nil, fmt.Errorf("grant list: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestGrantRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	media := postgres.NewMediaRepo(db.DB)
+	repo := postgres.NewGrantRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	m := &models.Media{ID: uuid.New(), OwnerID: uuid.New(), Status: models.ReadyStatus, Type: models.Video, Source: "s3://media/a.mp4", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, media.Create(ctx, m))
+	alice, bob := uuid.New(), uuid.New()
+
+	_, err := repo.Get(ctx, m.ID, alice)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	g, err := repo.Put(ctx, models.Grant{MediaID: m.ID, Principal: alice, Permission: models.ReadPermission, GrantedBy: "owner", CreatedAt: now, UpdatedAt: now})
+	require.NoError(t, err)
+	require.Equal(t, now, g.CreatedAt)
+	_, err = repo.Put(ctx, models.Grant{MediaID: m.ID, Principal: bob, Permission: models.ReadPermission, CreatedAt: now.Add(time.Second), UpdatedAt: now.Add(time.Second)})
+	require.NoError(t, err)
+
+	// Повторная выдача меняет право, но не время выдачи
+	later := now.Add(time.Minute)
+	g, err = repo.Put(ctx, models.Grant{MediaID: m.ID, Principal: alice, Permission: models.DownloadPermission, CreatedAt: later, UpdatedAt: later})
+	require.NoError(t, err)
+	require.Equal(t, now, g.CreatedAt)
+	require.Equal(t, later, g.UpdatedAt)
+	got, err := repo.Get(ctx, m.ID, alice)
+	require.NoError(t, err)
+	require.Equal(t, models.DownloadPermission, got.Permission)
+
+	list, err := repo.List(ctx, m.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, alice, list[0].Principal)
+
+	revoked, err := repo.Revoke(ctx, m.ID, bob)
+	require.NoError(t, err)
+	require.Equal(t, models.ReadPermission, revoked.Permission)
+	_, err = repo.Revoke(ctx, m.ID, bob)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	_, err = repo.Put(ctx, models.Grant{MediaID: uuid.New(), Principal: alice, Permission: models.ReadPermission, CreatedAt: now, UpdatedAt: now})
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	// Записи уходят вместе с медиа
+	_, err = media.Delete(ctx, m.ID)
+	require.NoError(t, err)
+	list, err = repo.List(ctx, m.ID)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
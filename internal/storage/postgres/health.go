@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Pinger — проверка соединения с базой; реализуется *pgxpool.Pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthPool — пул, за которым следит Health; реализуется *pgxpool.Pool
+type HealthPool interface {
+	Pinger
+	Reset()
+}
+
+// HealthConfig содержит настройки фоновой проверки базы
+type HealthConfig struct {
+	Pool     HealthPool
+	Name     string        // имя пула в логах: primary, replica
+	Interval time.Duration // Период ping (default: 5s)
+	Timeout  time.Duration // Лимит одного ping (default: 2s)
+	Logger   zerolog.Logger
+}
+
+// Health периодически пингует пул и помнит результат последней проверки.
+// После сбоя пул сбрасывается: соединения, пережившие рестарт базы, закрываются,
+// новые открываются при следующем запросе. /readyz читает состояние без похода в базу.
+type Health struct {
+	pool     HealthPool
+	interval time.Duration
+	timeout  time.Duration
+	logger   zerolog.Logger
+
+	mu  sync.RWMutex
+	err error
+}
+
+func NewHealth(cfg HealthConfig) (*Health, error) {
+	if cfg.Pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if cfg.Interval < 0 || cfg.Timeout < 0 {
+		return nil, fmt.Errorf("health check durations cannot be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+
+	return &Health{
+		pool:     cfg.Pool,
+		interval: cfg.Interval,
+		timeout:  cfg.Timeout,
+		logger:   cfg.Logger.With().Str("component", "pg_health").Str("pool", cfg.Name).Logger(),
+	}, nil
+}
+
+// Run проверяет пул каждые Interval до отмены ctx
+func (h *Health) Run(ctx context.Context) error {
+	t := time.NewTicker(h.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			h.probe(ctx)
+		}
+	}
+}
+
+func (h *Health) probe(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	err := h.pool.Ping(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	h.mu.Lock()
+	prev := h.err
+	h.err = err
+	h.mu.Unlock()
+
+	switch {
+	case err != nil:
+		h.pool.Reset()
+		if prev == nil {
+			h.logger.Error().Err(err).Msg("database unavailable")
+		}
+	case prev != nil:
+		h.logger.Info().Msg("database reconnected")
+	}
+}
+
+// Check — ReadinessCheck: ошибка последнего ping, nil — база отвечала
+func (h *Health) Check(context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.err != nil {
+		return fmt.Errorf("database unavailable: %w", h.err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePool struct {
+	err    error
+	pings  int
+	resets int
+}
+
+func (p *fakePool) Ping(context.Context) error {
+	p.pings++
+	return p.err
+}
+
+func (p *fakePool) Reset() { p.resets++ }
+
+func TestHealth_TracksLastPing(t *testing.T) {
+	ctx := context.Background()
+	pool := &fakePool{}
+	h, err := NewHealth(HealthConfig{Pool: pool, Name: "primary"})
+	require.NoError(t, err)
+
+	require.NoError(t, h.Check(ctx))
+
+	pool.err = errors.New("connection refused")
+	h.probe(ctx)
+	require.ErrorContains(t, h.Check(ctx), "connection refused")
+	require.Equal(t, 1, pool.resets, "stale connections are dropped after a failure")
+
+	pool.err = nil
+	h.probe(ctx)
+	require.NoError(t, h.Check(ctx))
+	require.Equal(t, 1, pool.resets)
+}
+
+func TestNewHealth_Validation(t *testing.T) {
+	_, err := NewHealth(HealthConfig{})
+	require.Error(t, err)
+	_, err = NewHealth(HealthConfig{Pool: &fakePool{}, Interval: -time.Second})
+	require.Error(t, err)
+
+	h, err := NewHealth(HealthConfig{Pool: &fakePool{}})
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, h.interval)
+	require.Equal(t, 2*time.Second, h.timeout)
+}
+
+func TestWaitPing(t *testing.T) {
+	ctx := context.Background()
+	var slept []time.Duration
+	sleep := func(d time.Duration) { slept = append(slept, d) }
+
+	// Без ожидания — сразу ошибка
+	pool := &fakePool{err: errors.New("connection refused")}
+	require.Error(t, waitPing(ctx, pool, 0, zerolog.Nop(), sleep))
+	require.Equal(t, 1, pool.pings)
+	require.Empty(t, slept)
+
+	// Ожидание ограничено wait; паузы удваиваются
+	pool = &fakePool{err: errors.New("connection refused")}
+	require.Error(t, waitPing(ctx, pool, 2*time.Second, zerolog.Nop(), sleep))
+	require.Equal(t, []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second}, slept)
+	require.Equal(t, 4, pool.pings)
+
+	// База поднялась в пределах ожидания
+	slept = nil
+	pool = &fakePool{err: errors.New("connection refused")}
+	require.NoError(t, waitPing(ctx, pool, time.Minute, zerolog.Nop(), func(d time.Duration) {
+		slept = append(slept, d)
+		if len(slept) == 2 {
+			pool.err = nil
+		}
+	}))
+	require.Equal(t, 3, pool.pings)
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
+)
+
+// IdempotencyRepo — ключи Idempotency-Key (idempotency_keys), idempotency.Store
+type IdempotencyRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepo(db *sqlx.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+var _ idempotency.Store = (*IdempotencyRepo)(nil)
+
+// claimAttempts — сколько раз Claim перечитывает ключ, освобождённый между вставкой и чтением
+const claimAttempts = 3
+
+type idempotencyRow struct {
+	Scope       string         `db:"scope"`
+	Key         string         `db:"key"`
+	Route       string         `db:"route"`
+	Fingerprint string         `db:"fingerprint"`
+	Token       uuid.UUID      `db:"token"`
+	Status      sql.NullInt32  `db:"status"`
+	Header      sql.NullString `db:"header"` // http.Header в jsonb
+	Body        []byte         `db:"body"`
+	CreatedAt   time.Time      `db:"created_at"`
+	LockedUntil time.Time      `db:"locked_until"`
+	ExpiresAt   time.Time      `db:"expires_at"`
+}
+
+func (r idempotencyRow) record() (idempotency.Record, error) {
+	rec := idempotency.Record{
+		Scope:       r.Scope,
+		Key:         r.Key,
+		Route:       r.Route,
+		Fingerprint: r.Fingerprint,
+		Token:       r.Token,
+		CreatedAt:   r.CreatedAt,
+		LockedUntil: r.LockedUntil,
+		ExpiresAt:   r.ExpiresAt,
+	}
+	if r.Status.Valid {
+		resp := &idempotency.Response{Status: int(r.Status.Int32), Body: r.Body}
+		if r.Header.Valid {
+			if err := json.Unmarshal([]byte(r.Header.String), &resp.Header); err != nil {
+				return idempotency.Record{}, fmt.Errorf("idempotency key %q: decode header: %w", r.Key, err)
+			}
+		}
+		rec.Response = resp
+	}
+	return rec, nil
+}
+
+// Claim — см. idempotency.Store. Занять свободный, истёкший или брошенный ключ — один upsert:
+// из двух одновременных запросов ключ получает один, второй читает его запись.
+func (r *IdempotencyRepo) Claim(ctx context.Context, rec idempotency.Record) (idempotency.Record, bool, error) {
+	const claim = `
+		INSERT INTO idempotency_keys (scope, key, route, fingerprint, token, created_at, locked_until, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			route = EXCLUDED.route,
+			fingerprint = EXCLUDED.fingerprint,
+			token = EXCLUDED.token,
+			status = NULL,
+			header = NULL,
+			body = NULL,
+			created_at = EXCLUDED.created_at,
+			locked_until = EXCLUDED.locked_until,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		   OR (idempotency_keys.status IS NULL AND idempotency_keys.locked_until <= EXCLUDED.created_at)
+	`
+	const existing = `
+		SELECT scope, key, route, fingerprint, token, status, header, body, created_at, locked_until, expires_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`
+
+	for range claimAttempts {
+		res, err := conn(ctx, r.db).ExecContext(ctx, claim,
+			rec.Scope, rec.Key, rec.Route, rec.Fingerprint, rec.Token, rec.CreatedAt, rec.LockedUntil, rec.ExpiresAt,
+		)
+		if err != nil {
+			return idempotency.Record{}, false, fmt.Errorf("idempotency claim: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return idempotency.Record{}, false, fmt.Errorf("idempotency claim: %w", err)
+		}
+		if n == 1 {
+			rec.Response = nil
+			return rec, true, nil
+		}
+
+		var row idempotencyRow
+		err = sqlx.GetContext(ctx, conn(ctx, r.db), &row, existing, rec.Scope, rec.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // ключ освободили после вставки — пробуем занять снова
+		}
+		if err != nil {
+			return idempotency.Record{}, false, fmt.Errorf("idempotency claim: %w", err)
+		}
+		cur, err := row.record()
+		return cur, false, err
+	}
+	return idempotency.Record{}, false, fmt.Errorf("idempotency claim: key %q keeps being released", rec.Key)
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, scope, key string, token uuid.UUID, resp idempotency.Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("idempotency complete: encode header: %w", err)
+	}
+	const q = `
+		UPDATE idempotency_keys
+		SET status = $4, header = $5::jsonb, body = $6
+		WHERE scope = $1 AND key = $2 AND token = $3
+	`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, scope, key, token, resp.Status, string(header), resp.Body); err != nil {
+		return fmt.Errorf("idempotency complete: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) Release(ctx context.Context, scope, key string, token uuid.UUID) error {
+	const q = `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND token = $3 AND status IS NULL`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, scope, key, token); err != nil {
+		return fmt.Errorf("idempotency release: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired — см. idempotency.Store; идёт по idx_idempotency_keys_expires_at
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("idempotency delete expired: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,78 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestIdempotencyRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewIdempotencyRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	newRecord := func(scope, fingerprint string, at time.Time) idempotency.Record {
+		return idempotency.Record{
+			Scope: scope, Key: "k1", Route: "POST /media", Fingerprint: fingerprint, Token: uuid.New(),
+			CreatedAt: at, LockedUntil: at.Add(time.Minute), ExpiresAt: at.Add(time.Hour),
+		}
+	}
+
+	first := newRecord("owner", "f1", now)
+	_, claimed, err := repo.Claim(ctx, first)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// Ключ занят и ещё без ответа
+	cur, claimed, err := repo.Claim(ctx, newRecord("owner", "f1", now))
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.Nil(t, cur.Response)
+	require.Equal(t, first.Token, cur.Token)
+
+	// Ответ чужого запроса не сохраняется
+	require.NoError(t, repo.Complete(ctx, "owner", "k1", uuid.New(), idempotency.Response{Status: 500}))
+	resp := idempotency.Response{
+		Status: http.StatusCreated,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`{"id":"1"}`),
+	}
+	require.NoError(t, repo.Complete(ctx, "owner", "k1", first.Token, resp))
+	// Ключ с ответом Release не освобождает
+	require.NoError(t, repo.Release(ctx, "owner", "k1", first.Token))
+
+	cur, claimed, err = repo.Claim(ctx, newRecord("owner", "f2", now.Add(2*time.Minute)))
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.Equal(t, "f1", cur.Fingerprint)
+	require.Equal(t, &resp, cur.Response)
+
+	// Тот же ключ другого владельца — другой ключ
+	_, claimed, err = repo.Claim(ctx, newRecord("other", "f1", now))
+	require.NoError(t, err)
+	require.True(t, claimed)
+	// Брошенный без ответа ключ занимается снова после LockedUntil
+	_, claimed, err = repo.Claim(ctx, newRecord("other", "f2", now.Add(2*time.Minute)))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// Истёкший ключ с ответом занимается заново
+	_, claimed, err = repo.Claim(ctx, newRecord("owner", "f3", now.Add(2*time.Hour)))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	n, err := repo.DeleteExpired(ctx, now.Add(10*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+}
@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/quota"
+)
+
+// QueryMetrics — метрики методов репозиториев с метками repo и method
+type QueryMetrics struct {
+	Duration *prometheus.HistogramVec
+	Errors   *prometheus.CounterVec
+	Rows     *prometheus.CounterVec
+}
+
+func NewQueryMetrics() *QueryMetrics {
+	labels := []string{"repo", "method"}
+	return &QueryMetrics{
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "media_db_query_duration_seconds",
+			Help:    "Длительность методов репозиториев Postgres, успешных и нет",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, labels),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "media_db_query_errors_total",
+			Help: "Ошибки методов репозиториев Postgres, кроме not found и conflict",
+		}, labels),
+		Rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "media_db_query_rows_total",
+			Help: "Строки, прочитанные или изменённые методами репозиториев Postgres",
+		}, labels),
+	}
+}
+
+// Register регистрирует метрики в Prometheus
+func (m *QueryMetrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.Duration, m.Errors, m.Rows} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstrumentConfig содержит настройки инструментирования репозиториев
+type InstrumentConfig struct {
+	Metrics   *QueryMetrics
+	SlowQuery time.Duration // вызовы дольше порога пишутся в лог (default: 500ms)
+	Logger    zerolog.Logger
+}
+
+// instrument замеряет вызовы методов одного репозитория
+type instrument struct {
+	repo      string
+	metrics   *QueryMetrics
+	slowQuery time.Duration
+	now       func() time.Time
+	logger    zerolog.Logger
+}
+
+func newInstrument(repo string, cfg InstrumentConfig) (*instrument, error) {
+	if cfg.Metrics == nil {
+		return nil, fmt.Errorf("metrics are required")
+	}
+	if cfg.SlowQuery < 0 {
+		return nil, fmt.Errorf("slow query threshold cannot be negative, got: %v", cfg.SlowQuery)
+	}
+	if cfg.SlowQuery == 0 {
+		cfg.SlowQuery = 500 * time.Millisecond
+	}
+
+	return &instrument{
+		repo:      repo,
+		metrics:   cfg.Metrics,
+		slowQuery: cfg.SlowQuery,
+		now:       time.Now,
+		logger:    cfg.Logger.With().Str("component", "pg_queries").Str("repo", repo).Logger(),
+	}, nil
+}
+
+// observe начинает замер метода; возвращённая функция записывает метрики и возвращает err как есть
+func (in *instrument) observe(method string) func(rows int, err error) error {
+	start := in.now()
+	return func(rows int, err error) error {
+		elapsed := in.now().Sub(start)
+		in.metrics.Duration.WithLabelValues(in.repo, method).Observe(elapsed.Seconds())
+		in.metrics.Rows.WithLabelValues(in.repo, method).Add(float64(rows))
+		// Отсутствующая запись и конфликт — ответ, а не сбой запроса
+		failed := err != nil && !errors.Is(err, models.ErrNotFound) && !errors.Is(err, models.ErrConflict)
+		if failed {
+			in.metrics.Errors.WithLabelValues(in.repo, method).Inc()
+		}
+		if elapsed >= in.slowQuery {
+			ev := in.logger.Warn()
+			if failed {
+				ev = ev.Err(err)
+			}
+			ev.Str("method", method).Dur("elapsed", elapsed).Int("rows", rows).Msg("slow query")
+		}
+		return err
+	}
+}
+
+// one — число строк результата из одной записи
+func one[T any](v *T) int {
+	if v == nil {
+		return 0
+	}
+	return 1
+}
+
+// affected — одна строка для операций, результат которых не считается построчно
+func affected(err error) int {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+// InstrumentedMediaRepo — декоратор MediaRepo с метриками и логом медленных вызовов.
+// Методы, не переопределённые здесь (WithinTransaction, настройка), вызываются напрямую.
+type InstrumentedMediaRepo struct {
+	*MediaRepo
+	in *instrument
+}
+
+func InstrumentMediaRepo(repo *MediaRepo, cfg InstrumentConfig) (*InstrumentedMediaRepo, error) {
+	if repo == nil {
+		return nil, fmt.Errorf("media repo is required")
+	}
+	in, err := newInstrument("media", cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &InstrumentedMediaRepo{MediaRepo: repo, in: in}, nil
+}
+
+var _ repository.MediaRepository = (*InstrumentedMediaRepo)(nil)
+
+func (r *InstrumentedMediaRepo) Create(ctx context.Context, m *models.Media) error {
+	done := r.in.observe("Create")
+	err := r.MediaRepo.Create(ctx, m)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedMediaRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	done := r.in.observe("GetByID")
+	m, err := r.MediaRepo.GetByID(ctx, id)
+	return m, done(one(m), err)
+}
+
+func (r *InstrumentedMediaRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	done := r.in.observe("GetForUpdate")
+	m, err := r.MediaRepo.GetForUpdate(ctx, id)
+	return m, done(one(m), err)
+}
+
+func (r *InstrumentedMediaRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	done := r.in.observe("UpdateStatus")
+	m, err := r.MediaRepo.UpdateStatus(ctx, id, status)
+	return m, done(one(m), err)
+}
+
+func (r *InstrumentedMediaRepo) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	done := r.in.observe("Update")
+	m, err := r.MediaRepo.Update(ctx, id, patch)
+	return m, done(one(m), err)
+}
+
+func (r *InstrumentedMediaRepo) List(ctx context.Context, filter repository.ListFilter) ([]*models.Media, error) {
+	done := r.in.observe("List")
+	out, err := r.MediaRepo.List(ctx, filter)
+	return out, done(len(out), err)
+}
+
+func (r *InstrumentedMediaRepo) Totals(ctx context.Context, filter repository.ListFilter, q repository.TotalsQuery) (repository.ListTotals, error) {
+	done := r.in.observe("Totals")
+	t, err := r.MediaRepo.Totals(ctx, filter, q)
+	return t, done(affected(err), err)
+}
+
+func (r *InstrumentedMediaRepo) Search(ctx context.Context, q repository.SearchQuery) ([]repository.SearchHit, error) {
+	done := r.in.observe("Search")
+	out, err := r.MediaRepo.Search(ctx, q)
+	return out, done(len(out), err)
+}
+
+func (r *InstrumentedMediaRepo) Dashboard(ctx context.Context, q repository.DashboardQuery) (repository.Dashboard, error) {
+	done := r.in.observe("Dashboard")
+	d, err := r.MediaRepo.Dashboard(ctx, q)
+	return d, done(affected(err), err)
+}
+
+func (r *InstrumentedMediaRepo) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	done := r.in.observe("Delete")
+	m, err := r.MediaRepo.Delete(ctx, id)
+	return m, done(one(m), err)
+}
+
+func (r *InstrumentedMediaRepo) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	done := r.in.observe("SetLastError")
+	err := r.MediaRepo.SetLastError(ctx, id, lastError)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedMediaRepo) AddStatusChange(ctx context.Context, c *models.StatusChange) error {
+	done := r.in.observe("AddStatusChange")
+	err := r.MediaRepo.AddStatusChange(ctx, c)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedMediaRepo) ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error) {
+	done := r.in.observe("ListStatusChanges")
+	out, err := r.MediaRepo.ListStatusChanges(ctx, mediaID)
+	return out, done(len(out), err)
+}
+
+func (r *InstrumentedMediaRepo) UsageByOwner(ctx context.Context) (map[string]quota.Usage, error) {
+	done := r.in.observe("UsageByOwner")
+	out, err := r.MediaRepo.UsageByOwner(ctx)
+	return out, done(len(out), err)
+}
+
+// InstrumentedOutboxRepo — декоратор OutboxRepo с метриками и логом медленных вызовов
+type InstrumentedOutboxRepo struct {
+	*OutboxRepo
+	in *instrument
+}
+
+func InstrumentOutboxRepo(repo *OutboxRepo, cfg InstrumentConfig) (*InstrumentedOutboxRepo, error) {
+	if repo == nil {
+		return nil, fmt.Errorf("outbox repo is required")
+	}
+	in, err := newInstrument("outbox", cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &InstrumentedOutboxRepo{OutboxRepo: repo, in: in}, nil
+}
+
+func (r *InstrumentedOutboxRepo) Add(ctx context.Context, event models.DomainEvent) error {
+	done := r.in.observe("Add")
+	err := r.OutboxRepo.Add(ctx, event)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) AddBatch(ctx context.Context, batch []models.DomainEvent) error {
+	done := r.in.observe("AddBatch")
+	err := r.OutboxRepo.AddBatch(ctx, batch)
+	return done(len(batch)*affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) GetPending(ctx context.Context, limit int) ([]OutboxRecord, error) {
+	done := r.in.observe("GetPending")
+	out, err := r.OutboxRepo.GetPending(ctx, limit)
+	return out, done(len(out), err)
+}
+
+func (r *InstrumentedOutboxRepo) CountPending(ctx context.Context) (int64, error) {
+	done := r.in.observe("CountPending")
+	n, err := r.OutboxRepo.CountPending(ctx)
+	return n, done(affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) MarkProcessed(ctx context.Context, id int64) error {
+	done := r.in.observe("MarkProcessed")
+	err := r.OutboxRepo.MarkProcessed(ctx, id)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error {
+	done := r.in.observe("MarkFailed")
+	err := r.OutboxRepo.MarkFailed(ctx, id, lastError, retryIn)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) MarkDeadLetter(ctx context.Context, id int64, lastError string) error {
+	done := r.in.observe("MarkDeadLetter")
+	err := r.OutboxRepo.MarkDeadLetter(ctx, id, lastError)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) ListOutbox(ctx context.Context, f OutboxFilter) ([]OutboxRecord, error) {
+	done := r.in.observe("ListOutbox")
+	out, err := r.OutboxRepo.ListOutbox(ctx, f)
+	return out, done(len(out), err)
+}
+
+func (r *InstrumentedOutboxRepo) ListDeadLetters(ctx context.Context, limit, offset int) ([]OutboxRecord, error) {
+	return r.ListOutbox(ctx, OutboxFilter{State: OutboxStateDeadLetter, Limit: limit, Offset: offset})
+}
+
+func (r *InstrumentedOutboxRepo) GetOutbox(ctx context.Context, id int64) (*OutboxRecord, error) {
+	done := r.in.observe("GetOutbox")
+	rec, err := r.OutboxRepo.GetOutbox(ctx, id)
+	return rec, done(one(rec), err)
+}
+
+func (r *InstrumentedOutboxRepo) Requeue(ctx context.Context, id int64) error {
+	done := r.in.observe("Requeue")
+	err := r.OutboxRepo.Requeue(ctx, id)
+	return done(affected(err), err)
+}
+
+func (r *InstrumentedOutboxRepo) ListProcessed(ctx context.Context, f OutboxReplayFilter, afterID int64, limit int) ([]OutboxRecord, error) {
+	done := r.in.observe("ListProcessed")
+	out, err := r.OutboxRepo.ListProcessed(ctx, f, afterID, limit)
+	return out, done(len(out), err)
+}
+
+func (r *InstrumentedOutboxRepo) RequeueProcessed(ctx context.Context, f OutboxReplayFilter, limit int) (int64, error) {
+	done := r.in.observe("RequeueProcessed")
+	n, err := r.OutboxRepo.RequeueProcessed(ctx, f, limit)
+	return n, done(int(n), err)
+}
+
+func (r *InstrumentedOutboxRepo) DeleteOutbox(ctx context.Context, id int64) error {
+	done := r.in.observe("DeleteOutbox")
+	err := r.OutboxRepo.DeleteOutbox(ctx, id)
+	return done(affected(err), err)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+func TestInstrument_Observe(t *testing.T) {
+	var buf bytes.Buffer
+	metrics := NewQueryMetrics()
+	in, err := newInstrument("media", InstrumentConfig{Metrics: metrics, SlowQuery: time.Second, Logger: zerolog.New(&buf)})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	in.now = func() time.Time { return now }
+
+	done := in.observe("List")
+	now = now.Add(10 * time.Millisecond)
+	require.NoError(t, done(3, nil))
+
+	// Not found — не ошибка запроса
+	done = in.observe("GetByID")
+	require.ErrorIs(t, done(0, models.ErrNotFound), models.ErrNotFound)
+	require.Empty(t, buf.String())
+
+	boom := errors.New("connection reset")
+	done = in.observe("List")
+	now = now.Add(2 * time.Second)
+	require.ErrorIs(t, done(0, boom), boom)
+
+	require.Equal(t, 2, testutil.CollectAndCount(metrics.Duration))
+	require.Equal(t, float64(3), testutil.ToFloat64(metrics.Rows.WithLabelValues("media", "List")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.Errors.WithLabelValues("media", "List")))
+	require.Equal(t, float64(0), testutil.ToFloat64(metrics.Errors.WithLabelValues("media", "GetByID")))
+
+	require.Contains(t, buf.String(), `"method":"List"`)
+	require.Contains(t, buf.String(), `"repo":"media"`)
+	require.Contains(t, buf.String(), `"error":"connection reset"`)
+}
+
+func TestInstrumentRepos_Validation(t *testing.T) {
+	_, err := InstrumentMediaRepo(nil, InstrumentConfig{Metrics: NewQueryMetrics()})
+	require.Error(t, err)
+	_, err = InstrumentOutboxRepo(&OutboxRepo{}, InstrumentConfig{})
+	require.Error(t, err)
+	_, err = InstrumentOutboxRepo(&OutboxRepo{}, InstrumentConfig{Metrics: NewQueryMetrics(), SlowQuery: -time.Second})
+	require.Error(t, err)
+
+	r, err := InstrumentMediaRepo(&MediaRepo{}, InstrumentConfig{Metrics: NewQueryMetrics()})
+	require.NoError(t, err)
+	require.Equal(t, 500*time.Millisecond, r.in.slowQuery)
+}
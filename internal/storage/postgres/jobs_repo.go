@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/processing/jobs"
+)
+
+// JobsRepo — очередь задач processing (jobs.Store) в таблице jobs. Готовые задачи захватываются
+// FOR UPDATE SKIP LOCKED: параллельные worker'ы не ждут друг друга и не получают одну задачу дважды.
+type JobsRepo struct {
+	db *sqlx.DB
+}
+
+func NewJobsRepo(db *sqlx.DB) *JobsRepo {
+	return &JobsRepo{db: db}
+}
+
+// jobColumns — колонки jobs в порядке полей jobs.Job
+const jobColumns = `id, queue, kind, payload, priority, attempts, max_attempts, coalesce(unique_key, '') AS unique_key,
+        scheduled_at, locked_until, last_error, created_at, completed_at, failed_at`
+
+// activeJob — условие незавершённой задачи; совпадает с условием частичных индексов jobs
+const activeJob = `completed_at IS NULL AND failed_at IS NULL`
+
+// Enqueue ставит задачу. Задача с UniqueKey, пока не завершена предыдущая с тем же ключом,
+// не создаётся: возвращается существующая.
+func (r *JobsRepo) Enqueue(ctx context.Context, job jobs.Job) (jobs.Job, error) {
+	if err := job.Validate(); err != nil {
+		return jobs.Job{}, err
+	}
+	const insert = `
+        INSERT INTO jobs (queue, kind, payload, priority, max_attempts, unique_key, scheduled_at)
+        VALUES ($1, $2, coalesce($3::jsonb, '{}'), $4, $5, NULLIF($6, ''), coalesce($7, now()))
+        ON CONFLICT (queue, unique_key) WHERE unique_key IS NOT NULL AND ` + activeJob + ` DO NOTHING
+        RETURNING ` + jobColumns
+	const existing = `SELECT ` + jobColumns + ` FROM jobs WHERE queue = $1 AND unique_key = $2 AND ` + activeJob
+
+	maxAttempts := job.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = jobs.DefaultMaxAttempts
+	}
+	var scheduledAt *time.Time
+	if !job.ScheduledAt.IsZero() {
+		scheduledAt = &job.ScheduledAt
+	}
+
+	// Существующая задача может завершиться между INSERT и SELECT — тогда ставим заново
+	for range 2 {
+		var out jobs.Job
+		err := sqlx.GetContext(ctx, conn(ctx, r.db), &out, insert,
+			job.Queue, job.Kind, []byte(job.Payload), job.Priority, maxAttempts, job.UniqueKey, scheduledAt,
+		)
+		if err == nil {
+			return out, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return jobs.Job{}, fmt.Errorf("enqueue job: %w", err)
+		}
+		err = sqlx.GetContext(ctx, conn(ctx, r.db), &out, existing, job.Queue, job.UniqueKey)
+		if err == nil {
+			return out, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return jobs.Job{}, fmt.Errorf("get queued job: %w", err)
+		}
+	}
+	return jobs.Job{}, fmt.Errorf("enqueue job: unique key %q contended", job.UniqueKey)
+}
+
+// Claim захватывает готовые задачи: по приоритету, затем по времени запуска
+func (r *JobsRepo) Claim(ctx context.Context, queue string, limit int, visibility time.Duration) ([]jobs.Job, error) {
+	const q = `
+        WITH ready AS (
+            SELECT id FROM jobs
+            WHERE queue = $1
+              AND ` + activeJob + `
+              AND scheduled_at <= now()
+              AND (locked_until IS NULL OR locked_until <= now())
+            ORDER BY priority DESC, scheduled_at, id
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        ), claimed AS (
+            UPDATE jobs
+            SET attempts = attempts + 1,
+                locked_until = now() + make_interval(secs => $3)
+            FROM ready
+            WHERE jobs.id = ready.id
+            RETURNING jobs.*
+        )
+        SELECT ` + jobColumns + ` FROM claimed
+        ORDER BY priority DESC, scheduled_at, id
+    `
+
+	var out []jobs.Job
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, queue, limit, visibility.Seconds()); err != nil {
+		return nil, fmt.Errorf("claim jobs: %w", err)
+	}
+	return out, nil
+}
+
+// Extend продлевает аренду попытки attempt
+func (r *JobsRepo) Extend(ctx context.Context, id int64, attempt int, visibility time.Duration) error {
+	return r.leased(ctx, "extend job", id, attempt,
+		`locked_until = now() + make_interval(secs => $3)`, visibility.Seconds())
+}
+
+// Complete завершает задачу
+func (r *JobsRepo) Complete(ctx context.Context, id int64, attempt int) error {
+	return r.leased(ctx, "complete job", id, attempt,
+		`completed_at = now(), locked_until = NULL`)
+}
+
+// Retry откладывает задачу после неудачной попытки
+func (r *JobsRepo) Retry(ctx context.Context, id int64, attempt int, runAt time.Time, lastError string) error {
+	return r.leased(ctx, "retry job", id, attempt,
+		`scheduled_at = $3, last_error = $4, locked_until = NULL`, runAt, lastError)
+}
+
+// Release возвращает задачу в очередь, не засчитывая попытку
+func (r *JobsRepo) Release(ctx context.Context, id int64, attempt int, runAt time.Time) error {
+	return r.leased(ctx, "release job", id, attempt,
+		`scheduled_at = $3, attempts = attempts - 1, locked_until = NULL`, runAt)
+}
+
+// Fail помечает задачу проваленной
+func (r *JobsRepo) Fail(ctx context.Context, id int64, attempt int, lastError string) error {
+	return r.leased(ctx, "fail job", id, attempt,
+		`failed_at = now(), last_error = $3, locked_until = NULL`, lastError)
+}
+
+// leased обновляет задачу, только если она всё ещё захвачена попыткой attempt и аренда не истекла.
+// Параметры set начинаются с $3.
+func (r *JobsRepo) leased(ctx context.Context, op string, id int64, attempt int, set string, args ...any) error {
+	q := `
+        UPDATE jobs SET ` + set + `
+        WHERE id = $1 AND attempts = $2 AND locked_until > now() AND ` + activeJob
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, append([]any{id, attempt}, args...)...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &exists, `SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1)`, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return jobs.ErrNotFound
+	}
+	return jobs.ErrLeaseLost
+}
+
+// Get возвращает задачу по id
+func (r *JobsRepo) Get(ctx context.Context, id int64) (jobs.Job, error) {
+	var out jobs.Job
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &out, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	if err != nil {
+		return jobs.Job{}, fmt.Errorf("get job: %w", err)
+	}
+	return out, nil
+}
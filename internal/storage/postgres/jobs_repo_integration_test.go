@@ -0,0 +1,142 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/processing/jobs"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestJobsRepo_ClaimOrderAndLease(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewJobsRepo(db.DB)
+
+	low, err := repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode", Payload: json.RawMessage(`{"media_id":"a"}`)})
+	require.NoError(t, err)
+	require.Equal(t, jobs.DefaultMaxAttempts, low.MaxAttempts)
+	high, err := repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode", Priority: 10})
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(high.Payload))
+	_, err = repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode", Priority: 100, ScheduledAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	claimed, err := repo.Claim(ctx, "processing", 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.Equal(t, high.ID, claimed[0].ID)
+	require.Equal(t, low.ID, claimed[1].ID)
+	require.Equal(t, 1, claimed[1].Attempts)
+	require.JSONEq(t, `{"media_id":"a"}`, string(claimed[1].Payload))
+
+	again, err := repo.Claim(ctx, "processing", 10, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	require.NoError(t, repo.Extend(ctx, high.ID, 1, time.Minute))
+	require.ErrorIs(t, repo.Complete(ctx, high.ID, 2), jobs.ErrLeaseLost)
+	require.NoError(t, repo.Complete(ctx, high.ID, 1))
+	require.ErrorIs(t, repo.Complete(ctx, high.ID, 1), jobs.ErrLeaseLost)
+	require.ErrorIs(t, repo.Complete(ctx, 1_000_000, 1), jobs.ErrNotFound)
+
+	// Release не тратит попытку, Retry откладывает задачу
+	require.NoError(t, repo.Release(ctx, low.ID, 1, time.Now()))
+	claimed, err = repo.Claim(ctx, "processing", 1, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, claimed[0].Attempts)
+	require.NoError(t, repo.Retry(ctx, low.ID, 1, time.Now().Add(time.Hour), "encoder crashed"))
+	claimed, err = repo.Claim(ctx, "processing", 1, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	got, err := repo.Get(ctx, low.ID)
+	require.NoError(t, err)
+	require.Equal(t, "encoder crashed", got.LastError)
+	require.Nil(t, got.LockedUntil)
+
+	// Истёкшая аренда: задачу забирает следующий захват
+	expiring, err := repo.Enqueue(ctx, jobs.Job{Queue: "expiring", Kind: "transcode"})
+	require.NoError(t, err)
+	_, err = repo.Claim(ctx, "expiring", 1, 10*time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	claimed, err = repo.Claim(ctx, "expiring", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, 2, claimed[0].Attempts)
+	require.ErrorIs(t, repo.Fail(ctx, expiring.ID, 1, "stale"), jobs.ErrLeaseLost)
+	require.NoError(t, repo.Fail(ctx, expiring.ID, 2, "corrupt source"))
+	got, err = repo.Get(ctx, expiring.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.FailedAt)
+}
+
+func TestJobsRepo_EnqueueUnique(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewJobsRepo(db.DB)
+
+	first, err := repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode", UniqueKey: "media-1"})
+	require.NoError(t, err)
+	dup, err := repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode", UniqueKey: "media-1"})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, dup.ID)
+	require.Equal(t, "media-1", dup.UniqueKey)
+
+	claimed, err := repo.Claim(ctx, "processing", 1, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.Complete(ctx, first.ID, claimed[0].Attempts))
+	next, err := repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode", UniqueKey: "media-1"})
+	require.NoError(t, err)
+	require.NotEqual(t, first.ID, next.ID)
+}
+
+func TestJobsRepo_ConcurrentClaimsSkipLocked(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewJobsRepo(db.DB)
+
+	const total = 50
+	for range total {
+		_, err := repo.Enqueue(ctx, jobs.Job{Queue: "processing", Kind: "transcode"})
+		require.NoError(t, err)
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[int64]int)
+		wg   sync.WaitGroup
+	)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := repo.Claim(ctx, "processing", 3, time.Minute)
+				require.NoError(t, err)
+				if len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, j := range claimed {
+					seen[j.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, seen, total)
+	for id, n := range seen {
+		require.Equal(t, 1, n, "job %d claimed twice", id)
+	}
+}
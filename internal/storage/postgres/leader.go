@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLeaderLockNotHeld — Renew или Release без взятой блокировки
+var ErrLeaderLockNotHeld = errors.New("leader lock is not held")
+
+// LeaderLock — блокировка лидерства (cli.LeaderLock) на session advisory lock Postgres с ключом
+// из имени. Блокировку держит выделенное соединение пула: оборвалась сессия — Postgres снимает
+// блокировку сам, и её берёт другой инстанс. Продление — ping этого соединения: пока сессия жива,
+// блокировка её. Через pgbouncer в режиме transaction не работает: сессия не закреплена за клиентом.
+type LeaderLock struct {
+	db   *sqlx.DB
+	name string
+
+	mu   sync.Mutex
+	conn *sqlx.Conn // соединение с блокировкой; nil — не взята
+}
+
+func NewLeaderLock(db *sqlx.DB, name string) *LeaderLock {
+	return &LeaderLock{db: db, name: name}
+}
+
+// TryAcquire — см. cli.LeaderLock. Повторный вызов при взятой блокировке возвращает true.
+func (l *LeaderLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		return true, nil
+	}
+
+	conn, err := l.db.Connx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("leader lock %s: %w", l.name, err)
+	}
+	var acquired bool
+	err = conn.GetContext(ctx, &acquired, `SELECT pg_try_advisory_lock(hashtext('leader'), hashtext($1))`, l.name)
+	if err != nil {
+		discardConn(conn)
+		return false, fmt.Errorf("leader lock %s: %w", l.name, err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// Renew — см. cli.LeaderLock. Сессия не ответила — соединение закрывается, блокировка считается
+// потерянной: если сессия ещё жива на сервере, она снимется вместе с соединением.
+func (l *LeaderLock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return ErrLeaderLockNotHeld
+	}
+	if err := l.conn.PingContext(ctx); err != nil {
+		discardConn(l.conn)
+		l.conn = nil
+		return fmt.Errorf("leader lock %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// Release — см. cli.LeaderLock. Если снять блокировку не удалось, соединение закрывается, а не
+// возвращается в пул: иначе блокировка осталась бы за соединением пула.
+func (l *LeaderLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+	conn := l.conn
+	l.conn = nil
+
+	var released bool
+	err := conn.GetContext(ctx, &released, `SELECT pg_advisory_unlock(hashtext('leader'), hashtext($1))`, l.name)
+	if err != nil || !released {
+		discardConn(conn)
+		if err == nil {
+			err = ErrLeaderLockNotHeld
+		}
+		return fmt.Errorf("leader lock %s: %w", l.name, err)
+	}
+	return conn.Close()
+}
+
+// discardConn закрывает соединение вместе с сессией вместо возврата в пул
+func discardConn(conn *sqlx.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
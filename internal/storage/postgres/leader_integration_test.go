@@ -0,0 +1,52 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestLeaderLock(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	a := postgres.NewLeaderLock(db.DB, "schedule_job")
+	b := postgres.NewLeaderLock(db.DB, "schedule_job")
+	other := postgres.NewLeaderLock(db.DB, "outbox_archiver")
+
+	require.ErrorIs(t, a.Renew(ctx), postgres.ErrLeaderLockNotHeld)
+
+	ok, err := a.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = b.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+	// У блокировок с разными именами разные ключи
+	ok, err = other.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, a.Renew(ctx))
+	require.NoError(t, a.Release(ctx))
+	ok, err = b.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Сессия лидера оборвалась: Postgres снимает блокировку, лидер узнаёт о потере при продлении
+	_, err = db.DB.ExecContext(ctx, `
+        SELECT pg_terminate_backend(pid) FROM pg_locks
+        WHERE locktype = 'advisory' AND pid <> pg_backend_pid()`)
+	require.NoError(t, err)
+	require.Error(t, b.Renew(ctx))
+	ok, err = a.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, a.Release(ctx))
+}
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+)
+
+// DefaultFeedSettle — сколько ReadAll ждёт, прежде чем отдать событие проекциям
+const DefaultFeedSettle = 5 * time.Second
+
+// MediaEventsRepo — журнал событий media в таблице media_events (eventstore.Store,
+// eventstore.Feed) и снапшоты потоков в media_snapshots (eventstore.SnapshotStore).
+// Внутри транзакции из ctx пишет в неё — так события фиксируются вместе с изменением состояния.
+type MediaEventsRepo struct {
+	db     *sqlx.DB
+	settle time.Duration
+}
+
+func NewMediaEventsRepo(db *sqlx.DB) *MediaEventsRepo {
+	return &MediaEventsRepo{db: db, settle: DefaultFeedSettle}
+}
+
+// WithFeedSettle задаёт задержку ReadAll (по умолчанию DefaultFeedSettle)
+func (r *MediaEventsRepo) WithFeedSettle(d time.Duration) *MediaEventsRepo {
+	r.settle = d
+	return r
+}
+
+type mediaEventRow struct {
+	AggregateID   uuid.UUID       `db:"aggregate_id"`
+	Sequence      int64           `db:"sequence"`
+	Position      int64           `db:"position"`
+	EventID       string          `db:"event_id"`
+	EventType     string          `db:"event_type"`
+	SchemaVersion int             `db:"schema_version"`
+	Payload       json.RawMessage `db:"payload"`
+	OccurredAt    time.Time       `db:"occurred_at"`
+	RecordedAt    time.Time       `db:"recorded_at"`
+}
+
+func (row mediaEventRow) event() eventstore.Event {
+	return eventstore.Event{
+		Sequence:   row.Sequence,
+		Position:   row.Position,
+		RecordedAt: row.RecordedAt.UTC(),
+		Envelope: events.Envelope{
+			EventID:       row.EventID,
+			EventType:     row.EventType,
+			SchemaVersion: row.SchemaVersion,
+			AggregateID:   row.AggregateID.String(),
+			OccurredAt:    row.OccurredAt.UTC(),
+			Payload:       row.Payload,
+		},
+	}
+}
+
+const mediaEventColumns = `aggregate_id, sequence, position, event_id, event_type, schema_version, payload, occurred_at, recorded_at`
+
+// Append — см. eventstore.Store. Конкурентная запись в поток упирается в первичный ключ
+// (aggregate_id, sequence) и тоже возвращает eventstore.ErrVersionConflict.
+func (r *MediaEventsRepo) Append(ctx context.Context, aggregateID uuid.UUID, expected int64, envs ...events.Envelope) (int64, error) {
+	q := conn(ctx, r.db)
+
+	var version int64
+	const versionQuery = `SELECT COALESCE(MAX(sequence), 0) FROM media_events WHERE aggregate_id = $1`
+	if err := sqlx.GetContext(ctx, q, &version, versionQuery, aggregateID); err != nil {
+		return 0, fmt.Errorf("get stream version: %w", err)
+	}
+	if expected != eventstore.AnyVersion && expected != version {
+		return version, fmt.Errorf("%w: stream %s is at version %d, expected %d", eventstore.ErrVersionConflict, aggregateID, version, expected)
+	}
+	if len(envs) == 0 {
+		return version, nil
+	}
+
+	// Одним INSERT: без транзакции в ctx пачка всё равно пишется атомарно
+	var (
+		values []string
+		args   []any
+	)
+	for i, env := range envs {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+		args = append(args, aggregateID, version+int64(i)+1, env.EventID, env.EventType, env.SchemaVersion, []byte(env.Payload), env.OccurredAt)
+	}
+	query := `
+		INSERT INTO media_events (aggregate_id, sequence, event_id, event_type, schema_version, payload, occurred_at)
+		VALUES ` + strings.Join(values, ", ")
+
+	if _, err := q.ExecContext(ctx, query, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			if pgErr.ConstraintName == "media_events_pkey" {
+				return version, fmt.Errorf("%w: stream %s changed concurrently", eventstore.ErrVersionConflict, aggregateID)
+			}
+			return version, fmt.Errorf("%w: event already recorded: %s", models.ErrConflict, pgErr.Detail)
+		}
+		return version, fmt.Errorf("insert media events: %w", err)
+	}
+	return version + int64(len(envs)), nil
+}
+
+// Load — см. eventstore.Store
+func (r *MediaEventsRepo) Load(ctx context.Context, aggregateID uuid.UUID, after int64) ([]eventstore.Event, error) {
+	const q = `
+		SELECT ` + mediaEventColumns + ` FROM media_events
+		WHERE aggregate_id = $1 AND sequence > $2
+		ORDER BY sequence`
+
+	var rows []mediaEventRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q, aggregateID, after); err != nil {
+		return nil, fmt.Errorf("load media events: %w", err)
+	}
+	out := make([]eventstore.Event, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.event())
+	}
+	return out, nil
+}
+
+// ReadAll — см. eventstore.Feed. position выдаётся при вставке, а видна строка после commit,
+// поэтому событие с меньшим position может появиться позже соседних; чтобы проекция его
+// не проскочила, отдаются только события транзакций, начатых раньше settle назад.
+// Транзакции сервиса короче settle.
+func (r *MediaEventsRepo) ReadAll(ctx context.Context, after int64, limit int) ([]eventstore.Event, error) {
+	const q = `
+		SELECT ` + mediaEventColumns + ` FROM media_events
+		WHERE position > $1 AND recorded_at < NOW() - make_interval(secs => $2)
+		ORDER BY position
+		LIMIT $3`
+
+	var rows []mediaEventRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q, after, r.settle.Seconds(), limit); err != nil {
+		return nil, fmt.Errorf("read media events: %w", err)
+	}
+	out := make([]eventstore.Event, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.event())
+	}
+	return out, nil
+}
+
+// SaveSnapshot — см. eventstore.SnapshotStore
+func (r *MediaEventsRepo) SaveSnapshot(ctx context.Context, s eventstore.Snapshot) error {
+	state, err := json.Marshal(s.State)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	const q = `
+		INSERT INTO media_snapshots (aggregate_id, version, state, taken_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (aggregate_id, version) DO NOTHING`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, s.AggregateID, s.Version, state, s.TakenAt); err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LatestSnapshot — см. eventstore.SnapshotStore
+func (r *MediaEventsRepo) LatestSnapshot(ctx context.Context, aggregateID uuid.UUID) (eventstore.Snapshot, bool, error) {
+	const q = `
+		SELECT version, state, taken_at FROM media_snapshots
+		WHERE aggregate_id = $1
+		ORDER BY version DESC
+		LIMIT 1`
+
+	var row struct {
+		Version int64     `db:"version"`
+		State   []byte    `db:"state"`
+		TakenAt time.Time `db:"taken_at"`
+	}
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, q, aggregateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return eventstore.Snapshot{}, false, nil
+	}
+	if err != nil {
+		return eventstore.Snapshot{}, false, fmt.Errorf("get snapshot: %w", err)
+	}
+	s := eventstore.Snapshot{AggregateID: aggregateID, Version: row.Version, TakenAt: row.TakenAt.UTC()}
+	if err := json.Unmarshal(row.State, &s.State); err != nil {
+		return eventstore.Snapshot{}, false, fmt.Errorf("decode snapshot %s@%d: %w", aggregateID, row.Version, err)
+	}
+	return s, true, nil
+}
@@ -0,0 +1,59 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestMediaEventsRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	store := postgres.NewMediaEventsRepo(db.DB)
+
+	// События пишутся в поток в транзакции сервиса вместе с outbox
+	svc := service.New(postgres.NewMediaRepo(db.DB), eventstore.NewRecorder(postgres.NewOutboxRepo(db.DB), store))
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	want, err := svc.ChangeStatus(ctx, m.ID, models.FailedStatus, service.ChangeMeta{Reason: "transcoder crashed"})
+	require.NoError(t, err)
+
+	stream, err := store.Load(ctx, m.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, stream, 3)
+	require.Equal(t, []int64{1, 2, 3}, []int64{stream[0].Sequence, stream[1].Sequence, stream[2].Sequence})
+	require.Equal(t, "MediaCreated", stream[0].EventType)
+
+	rebuilt, err := eventstore.Rebuild(stream)
+	require.NoError(t, err)
+	require.Equal(t, want.Status, rebuilt.Status)
+	require.Equal(t, want.ProcessingAttempts, rebuilt.ProcessingAttempts)
+	require.Equal(t, want.Source, rebuilt.Source)
+
+	tail, err := store.Load(ctx, m.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, tail, 1)
+
+	// Оптимистичная блокировка: запись с устаревшей версией отклоняется
+	env := events.Envelope{EventID: uuid.NewString(), EventType: "MediaDeleted", SchemaVersion: 1, AggregateID: m.ID.String(), Payload: []byte(`{}`)}
+	_, err = store.Append(ctx, m.ID, 2, env)
+	require.ErrorIs(t, err, eventstore.ErrVersionConflict)
+	version, err := store.Append(ctx, m.ID, 3, env)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), version)
+	_, err = store.Append(ctx, m.ID, eventstore.AnyVersion, env)
+	require.ErrorIs(t, err, models.ErrConflict, "event id is unique")
+}
@@ -3,45 +3,105 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 
 	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/quota"
 )
 
+// mediaColumns — колонки media в порядке полей models.Media
+const mediaColumns = `id, status, type, source, created_at, updated_at, title, tags, metadata, processing_attempts, last_error, owner_id, checksum_sha256, size_bytes, content_type, publish_at, expires_at, visibility`
+
+// setStatusSQL — SET для смены статуса: вход в processing считается попыткой обработки,
+// успешное завершение (ready или scheduled под эмбарго) обнуляет счётчик и последнюю ошибку.
+const setStatusSQL = `
+		status = $2,
+		updated_at = NOW(),
+		processing_attempts = CASE
+			WHEN $2 = 'processing' THEN processing_attempts + 1
+			WHEN $2 IN ('ready', 'scheduled') THEN 0
+			ELSE processing_attempts
+		END,
+		last_error = CASE WHEN $2 IN ('ready', 'scheduled') THEN '' ELSE last_error END`
+
 type MediaRepo struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	tx       *TxManager
+	replica  *Replica
+	timeouts *Timeouts
 }
 
 func NewMediaRepo(db *sqlx.DB) *MediaRepo {
-	return &MediaRepo{db: db}
+	return &MediaRepo{db: db, tx: NewTxManager(db)}
+}
+
+// WithReplica направляет GetByID и List в реплику; записи и транзакции остаются на primary
+func (r *MediaRepo) WithReplica(replica *Replica) *MediaRepo {
+	r.replica = replica
+	return r
+}
+
+// WithTimeouts ограничивает время операций и логирует медленные запросы
+func (r *MediaRepo) WithTimeouts(t *Timeouts) *MediaRepo {
+	r.timeouts = t
+	return r
+}
+
+// WithinTransaction — см. TxManager.WithinTransaction
+func (r *MediaRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.tx.WithinTransaction(ctx, fn)
 }
 
+// Create вставляет медиа.
+// ON CONFLICT DO NOTHING вместо ошибки уникальности — чтобы конфликт одной записи
+// не переводил транзакцию в aborted и остальные вставки batch'а продолжались.
 func (r *MediaRepo) Create(ctx context.Context, m *models.Media) error {
+	ctx, done := r.timeouts.writing(ctx, "media create")
+	defer done()
+
 	const q = `
-		INSERT INTO media (id, status, type, source, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO media (id, status, type, source, created_at, updated_at, owner_id, visibility, title, tags, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), 'draft'), $9, $10::jsonb, $11::jsonb)
+		ON CONFLICT (id) DO NOTHING
 	`
-	_, err := r.db.ExecContext(ctx, q,
-		m.ID, m.Status, m.Type, m.Source, m.CreatedAt, m.UpdatedAt,
+	res, err := conn(ctx, r.db).ExecContext(ctx, q,
+		m.ID, m.Status, m.Type, m.Source, m.CreatedAt, m.UpdatedAt, nullUUID(m.OwnerID), string(m.Visibility),
+		m.Title, m.Tags, m.Metadata,
 	)
 	if err != nil {
 		return fmt.Errorf("media create: %w", err)
 	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	if n == 0 {
+		return models.ErrConflict
+	}
 	return nil
 }
 
 func (r *MediaRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	ctx, done := r.timeouts.reading(ctx, "media get by id")
+	defer done()
+
 	const q = `
-		SELECT id, status, type, source, created_at, updated_at
+		SELECT ` + mediaColumns + `
 		FROM media
 		WHERE id = $1
 	`
 
 	var m models.Media
-	if err := r.db.GetContext(ctx, &m, q, id); err != nil {
+	err := read(ctx, r.db, r.replica, func(db sqlx.QueryerContext) error {
+		return sqlx.GetContext(ctx, db, &m, q, id)
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, models.ErrNotFound
 		}
@@ -51,16 +111,41 @@ func (r *MediaRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, e
 	return &m, nil
 }
 
+// GetForUpdate — см. repository.MediaRepository; без транзакции в ctx блокировка снимается сразу
+func (r *MediaRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	ctx, done := r.timeouts.writing(ctx, "media get for update")
+	defer done()
+
+	const q = `
+		SELECT ` + mediaColumns + `
+		FROM media
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	var m models.Media
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &m, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("media get for update: %w", err)
+	}
+
+	return &m, nil
+}
+
 func (r *MediaRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	ctx, done := r.timeouts.writing(ctx, "media update status")
+	defer done()
+
 	const q = `
 		UPDATE media
-		SET status = $2, updated_at = NOW()
+		SET ` + setStatusSQL + `
 		WHERE id = $1
-		RETURNING id, status, type, source, created_at, updated_at
-	`
+		RETURNING ` + mediaColumns
 
 	var m models.Media
-	if err := r.db.GetContext(ctx, &m, q, id, status); err != nil {
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &m, q, id, status); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, models.ErrNotFound
 		}
@@ -70,26 +155,423 @@ func (r *MediaRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status model
 	return &m, nil
 }
 
-func (r *MediaRepo) BeginTx(ctx context.Context) (*sqlx.Tx, error) {
-	return r.db.BeginTxx(ctx, nil)
+// Update частично обновляет медиа: NULL параметр в COALESCE оставляет колонку как есть.
+// Возвращает обновлённую запись; пустой патч ничего не пишет и возвращает текущую.
+func (r *MediaRepo) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	if patch.IsEmpty() {
+		return r.GetByID(repository.WithReadPrimary(ctx), id)
+	}
+
+	ctx, done := r.timeouts.writing(ctx, "media update")
+	defer done()
+
+	const q = `
+		UPDATE media
+		SET source = COALESCE($2, source),
+		    title = COALESCE($3, title),
+		    tags = COALESCE($4::jsonb, tags),
+		    metadata = COALESCE($5::jsonb, metadata),
+		    checksum_sha256 = COALESCE($6, checksum_sha256),
+		    size_bytes = COALESCE($7, size_bytes),
+		    content_type = COALESCE($8, content_type),
+		    publish_at = CASE WHEN $9 THEN $10 ELSE publish_at END,
+		    expires_at = CASE WHEN $9 THEN $11 ELSE expires_at END,
+		    visibility = COALESCE($12, visibility),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + mediaColumns
+
+	var (
+		checksum, contentType *string
+		size                  *int64
+	)
+	if c := patch.Content; c != nil {
+		checksum, size, contentType = &c.Checksum, &c.Size, &c.ContentType
+	}
+
+	// Расписание заменяется целиком, в том числе на NULL — COALESCE тут не подходит
+	var publishAt, expiresAt sql.NullTime
+	if sch := patch.Schedule; sch != nil {
+		publishAt, expiresAt = nullTime(sch.PublishAt), nullTime(sch.ExpiresAt)
+	}
+
+	var m models.Media
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &m, q, id, patch.Source, patch.Title, patch.Tags, patch.Metadata,
+		checksum, size, contentType, patch.Schedule != nil, publishAt, expiresAt, patch.Visibility)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("media update: %w", err)
+	}
+
+	return &m, nil
+}
+
+// List возвращает страницу медиа в порядке filter.Sort, при равных значениях — по id
+func (r *MediaRepo) List(ctx context.Context, filter repository.ListFilter) ([]*models.Media, error) {
+	ctx, done := r.timeouts.reading(ctx, "media list")
+	defer done()
+
+	filter = filter.WithDefaults()
+	if !filter.Sort.Valid() {
+		return nil, fmt.Errorf("%w: unknown sort %q", models.ErrInvalidArgument, filter.Sort)
+	}
+
+	// Колонка сортировки — из закрытого списка ListSort, в запрос подставляется как есть
+	column, direction, after := string(filter.Sort), "DESC", "<"
+	if filter.Ascending {
+		direction, after = "ASC", ">"
+	}
+	var cursorAt sql.NullTime
+	var cursorID uuid.UUID
+	if filter.After != nil {
+		cursorAt = sql.NullTime{Time: filter.After.At, Valid: true}
+		cursorID = filter.After.ID
+	}
+
+	q := `
+		SELECT ` + mediaColumns + `
+		FROM media
+		WHERE ($1 = '' OR status = $1)
+		  AND ($4::uuid IS NULL OR owner_id = $4)
+		  AND ($5 = '' OR checksum_sha256 = $5)
+		  AND ($6 = '' OR type = $6)
+		  AND ($7::timestamptz IS NULL OR ` + column + ` ` + after + ` $7 OR (` + column + ` = $7 AND id > $8))
+		  AND ($9 = '' OR visibility = $9)
+		ORDER BY ` + column + ` ` + direction + `, id
+		LIMIT $2 OFFSET $3
+	`
+
+	var out []*models.Media
+	err := read(ctx, r.db, r.replica, func(db sqlx.QueryerContext) error {
+		out = nil // Select дописывает в срез, при повторе на primary начинаем заново
+		return sqlx.SelectContext(ctx, db, &out, q, filter.Status, filter.Limit, filter.Offset,
+			nullUUID(filter.OwnerID), filter.Checksum, filter.Type, cursorAt, cursorID, filter.Visibility)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("media list: %w", err)
+	}
+	return out, nil
+}
+
+// totalsWhere — условия ListFilter без пагинации: $1 status, $2 owner_id, $3 checksum, $4 type, $5 visibility
+const totalsWhere = `
+		WHERE ($1 = '' OR status = $1)
+		  AND ($2::uuid IS NULL OR owner_id = $2)
+		  AND ($3 = '' OR checksum_sha256 = $3)
+		  AND ($4 = '' OR type = $4)
+		  AND ($5 = '' OR visibility = $5)`
+
+// Totals — см. repository.TotalsQuery. Фасеты считаются одним GROUP BY status, type, и итог
+// с ними — их сумма, точная в любом режиме. CountEstimated на таблице от
+// repository.EstimateThreshold строк (по pg_class.reltuples) берёт оценку планировщика
+// для фильтра вместо COUNT(*), который прошёл бы по всем подходящим строкам.
+func (r *MediaRepo) Totals(ctx context.Context, filter repository.ListFilter, tq repository.TotalsQuery) (repository.ListTotals, error) {
+	ctx, done := r.timeouts.reading(ctx, "media totals")
+	defer done()
+
+	if !tq.Count.Valid() {
+		return repository.ListTotals{}, fmt.Errorf("%w: unknown count mode %q", models.ErrInvalidArgument, tq.Count)
+	}
+	args := []any{filter.Status, nullUUID(filter.OwnerID), filter.Checksum, filter.Type, filter.Visibility}
+
+	var t repository.ListTotals
+	err := read(ctx, r.db, r.replica, func(db sqlx.QueryerContext) error {
+		t = repository.ListTotals{}
+		switch {
+		case tq.Facets:
+			return facets(ctx, db, args, &t, tq.Count != repository.CountNone)
+		case tq.Count == repository.CountEstimated:
+			return estimateCount(ctx, db, args, &t)
+		case tq.Count == repository.CountExact:
+			return sqlx.GetContext(ctx, db, &t.Total, `SELECT COUNT(*) FROM media`+totalsWhere, args...)
+		}
+		return nil
+	})
+	if err != nil {
+		return repository.ListTotals{}, fmt.Errorf("media totals: %w", err)
+	}
+	return t, nil
+}
+
+// facets заполняет t.ByStatus и t.ByType, а с withTotal — и t.Total
+func facets(ctx context.Context, db sqlx.QueryerContext, args []any, t *repository.ListTotals, withTotal bool) error {
+	var groups []struct {
+		Status models.Status    `db:"status"`
+		Type   models.MediaType `db:"type"`
+		Count  int64            `db:"count"`
+	}
+	q := `SELECT status, type, COUNT(*) AS count FROM media` + totalsWhere + ` GROUP BY status, type`
+	if err := sqlx.SelectContext(ctx, db, &groups, q, args...); err != nil {
+		return err
+	}
+	t.ByStatus = make(map[models.Status]int64)
+	t.ByType = make(map[models.MediaType]int64)
+	for _, g := range groups {
+		t.ByStatus[g.Status] += g.Count
+		t.ByType[g.Type] += g.Count
+		if withTotal {
+			t.Total += g.Count
+		}
+	}
+	return nil
+}
+
+// estimateCount — точный COUNT(*) на малой таблице, иначе Plan Rows из EXPLAIN запроса с фильтром.
+// reltuples -1 (таблицу ещё не анализировали) — размер неизвестен, считается точно.
+func estimateCount(ctx context.Context, db sqlx.QueryerContext, args []any, t *repository.ListTotals) error {
+	var reltuples float64
+	if err := sqlx.GetContext(ctx, db, &reltuples, `SELECT reltuples FROM pg_class WHERE oid = 'media'::regclass`); err != nil {
+		return err
+	}
+	if reltuples < repository.EstimateThreshold {
+		return sqlx.GetContext(ctx, db, &t.Total, `SELECT COUNT(*) FROM media`+totalsWhere, args...)
+	}
+
+	var raw []byte
+	if err := sqlx.GetContext(ctx, db, &raw, `EXPLAIN (FORMAT JSON) SELECT 1 FROM media`+totalsWhere, args...); err != nil {
+		return err
+	}
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return fmt.Errorf("parse explain: %w", err)
+	}
+	if len(plan) == 0 {
+		return fmt.Errorf("parse explain: empty plan")
+	}
+	t.Total, t.Estimated = int64(plan[0].Plan.Rows), true
+	return nil
+}
+
+// Search — полнотекстовый поиск по search_vector (title с весом A, metadata.description с весом B)
+// с фильтрами по меткам и статусу. Без текста сортирует по дате, как List.
+func (r *MediaRepo) Search(ctx context.Context, sq repository.SearchQuery) ([]repository.SearchHit, error) {
+	ctx, done := r.timeouts.reading(ctx, "media search")
+	defer done()
+
+	sq = sq.WithDefaults()
+
+	const q = `
+		SELECT ` + mediaColumns + `,
+		       CASE WHEN $1 = '' THEN 0
+		            ELSE ts_rank_cd(search_vector, websearch_to_tsquery('simple', $1))
+		       END AS rank
+		FROM media
+		WHERE ($1 = '' OR search_vector @@ websearch_to_tsquery('simple', $1))
+		  AND ($2::jsonb = '[]'::jsonb OR tags @> $2::jsonb)
+		  AND ($3 = '' OR status = $3)
+		  AND ($6::uuid IS NULL OR owner_id = $6)
+		ORDER BY rank DESC, created_at DESC, id
+		LIMIT $4 OFFSET $5
+	`
+
+	var out []repository.SearchHit
+	err := read(ctx, r.db, r.replica, func(db sqlx.QueryerContext) error {
+		out = nil
+		return sqlx.SelectContext(ctx, db, &out, q, sq.Text, models.Tags(sq.Tags), sq.Status, sq.Limit, sq.Offset, nullUUID(sq.OwnerID))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("media search: %w", err)
+	}
+	return out, nil
+}
+
+// Dashboard — см. repository.DashboardQuery. История статусов переживает удаление media,
+// но с фильтром по владельцу учитывается только история существующих media.
+func (r *MediaRepo) Dashboard(ctx context.Context, dq repository.DashboardQuery) (repository.Dashboard, error) {
+	ctx, done := r.timeouts.reading(ctx, "media dashboard")
+	defer done()
+
+	const countsQuery = `
+		SELECT status, type, COUNT(*) AS count,
+		       COUNT(*) FILTER (WHERE created_at >= $2) AS created
+		FROM media
+		WHERE $1::uuid IS NULL OR owner_id = $1
+		GROUP BY status, type`
+
+	// Длительность — от последнего входа в processing до перехода в ready/failed;
+	// scheduled — та же успешная обработка, опубликованная позже
+	const processingQuery = `
+		SELECT COUNT(*) FILTER (WHERE h.to_status IN ('ready', 'scheduled')) AS completed,
+		       COUNT(*) FILTER (WHERE h.to_status = 'failed') AS failed,
+		       COALESCE(AVG(EXTRACT(EPOCH FROM h.changed_at - p.changed_at)), 0) AS avg_seconds
+		FROM media_status_history h
+		LEFT JOIN media m ON m.id = h.media_id
+		LEFT JOIN LATERAL (
+			SELECT changed_at FROM media_status_history
+			WHERE media_id = h.media_id AND to_status = 'processing' AND changed_at <= h.changed_at
+			ORDER BY changed_at DESC
+			LIMIT 1
+		) p ON true
+		WHERE h.from_status = 'processing' AND h.to_status IN ('ready', 'scheduled', 'failed')
+		  AND h.changed_at >= $2
+		  AND ($1::uuid IS NULL OR m.owner_id = $1)`
+
+	var (
+		counts []struct {
+			Status  models.Status    `db:"status"`
+			Type    models.MediaType `db:"type"`
+			Count   int64            `db:"count"`
+			Created int64            `db:"created"`
+		}
+		processing struct {
+			Completed  int64   `db:"completed"`
+			Failed     int64   `db:"failed"`
+			AvgSeconds float64 `db:"avg_seconds"`
+		}
+	)
+	owner := nullUUID(dq.OwnerID)
+	err := read(ctx, r.db, r.replica, func(db sqlx.QueryerContext) error {
+		counts = nil
+		if err := sqlx.SelectContext(ctx, db, &counts, countsQuery, owner, dq.Since); err != nil {
+			return err
+		}
+		return sqlx.GetContext(ctx, db, &processing, processingQuery, owner, dq.Since)
+	})
+	if err != nil {
+		return repository.Dashboard{}, fmt.Errorf("media dashboard: %w", err)
+	}
+
+	d := repository.Dashboard{
+		ByStatus:      make(map[models.Status]int64),
+		ByType:        make(map[models.MediaType]int64),
+		Completed:     processing.Completed,
+		Failed:        processing.Failed,
+		AvgProcessing: time.Duration(processing.AvgSeconds * float64(time.Second)),
+	}
+	for _, c := range counts {
+		d.ByStatus[c.Status] += c.Count
+		d.ByType[c.Type] += c.Count
+		d.Total += c.Count
+		d.Created += c.Created
+	}
+	return d, nil
 }
 
-func (r *MediaRepo) UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status models.Status) (*models.Media, error) {
+// Delete удаляет медиа и возвращает удалённую запись,
+// чтобы вызывающий мог положить в outbox событие с её данными.
+func (r *MediaRepo) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	ctx, done := r.timeouts.writing(ctx, "media delete")
+	defer done()
+
 	const q = `
-        UPDATE media
-        SET status = $2, updated_at = NOW()
-        WHERE id = $1
-        RETURNING id, status, type, source, created_at, updated_at
-    `
+		DELETE FROM media
+		WHERE id = $1
+		RETURNING ` + mediaColumns
 
 	var m models.Media
-	// Вместо r.db используем tx!
-	if err := tx.GetContext(ctx, &m, q, id, status); err != nil {
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &m, q, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, models.ErrNotFound
 		}
-		return nil, fmt.Errorf("media update status tx: %w", err)
+		return nil, fmt.Errorf("media delete: %w", err)
 	}
 
 	return &m, nil
 }
+
+// SetLastError сохраняет ошибку последней неудачной обработки
+func (r *MediaRepo) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	ctx, done := r.timeouts.writing(ctx, "media set last error")
+	defer done()
+
+	const q = `UPDATE media SET last_error = $2, updated_at = NOW() WHERE id = $1`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, id, lastError)
+	if err != nil {
+		return fmt.Errorf("media set last error: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("media set last error: %w", err)
+	}
+	if n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// AddStatusChange пишет запись в историю статусов; вызывается в той же транзакции, что и смена статуса
+func (r *MediaRepo) AddStatusChange(ctx context.Context, c *models.StatusChange) error {
+	ctx, done := r.timeouts.writing(ctx, "media add status change")
+	defer done()
+
+	const q = `
+		INSERT INTO media_status_history (media_id, from_status, to_status, actor, reason, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &c.ID, q,
+		c.MediaID, c.From, c.To, c.Actor, c.Reason, c.ChangedAt,
+	); err != nil {
+		return fmt.Errorf("media add status change: %w", err)
+	}
+	return nil
+}
+
+// ListStatusChanges возвращает историю статусов медиа в хронологическом порядке
+func (r *MediaRepo) ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error) {
+	ctx, done := r.timeouts.reading(ctx, "media list status changes")
+	defer done()
+
+	const q = `
+		SELECT id, media_id, from_status, to_status, actor, reason, changed_at
+		FROM media_status_history
+		WHERE media_id = $1
+		ORDER BY changed_at ASC, id ASC
+	`
+
+	var out []models.StatusChange
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, mediaID); err != nil {
+		return nil, fmt.Errorf("media list status changes: %w", err)
+	}
+	return out, nil
+}
+
+// UsageByOwner возвращает число media и суммарный размер исходников по владельцам для сверки квот.
+// Медиа без владельца считаются в общем пуле "" — так же, как их учитывает quota по событиям.
+func (r *MediaRepo) UsageByOwner(ctx context.Context) (map[string]quota.Usage, error) {
+	ctx, done := r.timeouts.reading(ctx, "media usage by owner")
+	defer done()
+
+	const q = `
+		SELECT coalesce(owner_id::text, '') AS owner, count(*) AS n, coalesce(sum(size_bytes), 0)::bigint AS bytes
+		FROM media
+		GROUP BY owner_id`
+
+	var rows []struct {
+		Owner string `db:"owner"`
+		N     int64  `db:"n"`
+		Bytes int64  `db:"bytes"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, q); err != nil {
+		return nil, fmt.Errorf("media usage by owner: %w", err)
+	}
+
+	out := make(map[string]quota.Usage, len(rows))
+	for _, row := range rows {
+		out[row.Owner] = quota.Usage{Objects: row.N, Bytes: row.Bytes}
+	}
+	return out, nil
+}
+
+// nullUUID — uuid параметр запроса: uuid.Nil пишется как NULL (owner_id общего пула, нет фильтра)
+func nullUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
+
+// nullTime — необязательное время параметром запроса: nil пишется как NULL
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,62 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/repository/repotest"
+	"github.com/romariotrain/media-platform/internal/quota"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestMediaRepo_Contract(t *testing.T) {
+	db := testutil.StartPostgres(t)
+
+	// один контейнер на весь контракт, каждый подтест — с пустыми таблицами
+	repotest.RunRepositoryTests(t, func(t *testing.T) repository.MediaRepository {
+		_, err := db.DB.ExecContext(context.Background(), `TRUNCATE media, media_status_history, retention_policies, jobs`)
+		require.NoError(t, err)
+		return postgres.NewMediaRepo(db.DB)
+	})
+}
+
+func TestMediaRepo_UsageByOwner(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewMediaRepo(db.DB)
+
+	owner := uuid.New()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	create := func(owner uuid.UUID, size int64) {
+		m := &models.Media{
+			ID: uuid.New(), Status: models.UploadedStatus, Type: models.Video,
+			Source: "s3://media/" + uuid.NewString(), CreatedAt: now, UpdatedAt: now, OwnerID: owner,
+		}
+		require.NoError(t, repo.Create(ctx, m))
+		if size > 0 {
+			_, err := repo.Update(ctx, m.ID, models.MediaPatch{Content: &models.Content{
+				Checksum: "00", Size: size, ContentType: "video/mp4",
+			}})
+			require.NoError(t, err)
+		}
+	}
+	create(owner, 100)
+	create(owner, 250)
+	create(uuid.Nil, 0) // ещё не загружено
+
+	usage, err := repo.UsageByOwner(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]quota.Usage{
+		owner.String(): {Objects: 2, Bytes: 350},
+		"":             {Objects: 1},
+	}, usage)
+}
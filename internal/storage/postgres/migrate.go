@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	schema "github.com/romariotrain/media-platform/sql"
+)
+
+// MigrateUp применяет схему sql/script.sql. Скрипт идемпотентен, повторный вызов безопасен.
+func MigrateUp(ctx context.Context, pool *pgxpool.Pool) error {
+	// Exec без аргументов идёт simple protocol'ом — скрипт из нескольких команд выполняется целиком
+	if _, err := pool.Exec(ctx, schema.Up); err != nil {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown удаляет таблицы схемы вместе с данными (sql/down.sql)
+func MigrateDown(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(ctx, schema.Down); err != nil {
+		return fmt.Errorf("migrate down: %w", err)
+	}
+	return nil
+}
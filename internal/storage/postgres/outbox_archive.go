@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// archivePartitionPrefix — партиции outbox_archive называются по дню публикации: outbox_archive_p20260102
+const (
+	archivePartitionPrefix = "outbox_archive_p"
+	archivePartitionLayout = "20060102"
+)
+
+// ArchiveProcessed переносит в outbox_archive не больше limit событий, опубликованных раньше
+// before, и возвращает, сколько строк ушло из outbox. События, опубликованные раньше dropBefore,
+// удаляются без архива: их партиции уже удалены или вот-вот будут. Недостающие дневные партиции
+// [dropBefore, before] создаются в той же транзакции. Запуски archiver'ов разных инстансов
+// сериализуются advisory lock'ом.
+func (r *OutboxRepo) ArchiveProcessed(ctx context.Context, before, dropBefore time.Time, limit int) (int64, error) {
+	ctx, done := r.timeouts.writing(ctx, "outbox archive")
+	defer done()
+
+	const q = `
+        WITH moved AS (
+            DELETE FROM outbox
+            WHERE id IN (
+                SELECT id FROM outbox
+                WHERE processed_at < $1
+                ORDER BY processed_at
+                LIMIT $3
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at,
+                      attempts, processed_at
+        ), archived AS (
+            INSERT INTO outbox_archive (id, event_id, event_type, schema_version, aggregate_id, sequence, payload,
+                                        occurred_at, attempts, processed_at)
+            SELECT id, event_id, event_type, schema_version, aggregate_id, sequence, payload,
+                   occurred_at, attempts, processed_at
+            FROM moved
+            WHERE processed_at >= $2
+        )
+        SELECT count(*) FROM moved
+    `
+
+	var n int64
+	err := NewTxManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if err := lockArchive(ctx, db); err != nil {
+			return err
+		}
+		if err := ensureArchivePartitions(ctx, db, dropBefore, before); err != nil {
+			return err
+		}
+		return sqlx.GetContext(ctx, db, &n, q, before.UTC(), dropBefore.UTC(), limit)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("archive outbox: %w", err)
+	}
+	return n, nil
+}
+
+// DropArchive удаляет партиции outbox_archive, целиком лежащие раньше before, и возвращает их число
+func (r *OutboxRepo) DropArchive(ctx context.Context, before time.Time) (int, error) {
+	ctx, done := r.timeouts.writing(ctx, "outbox drop archive")
+	defer done()
+
+	dropped := 0
+	err := NewTxManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if err := lockArchive(ctx, db); err != nil {
+			return err
+		}
+		days, err := archivePartitions(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, day := range days {
+			if day.AddDate(0, 0, 1).After(before.UTC()) {
+				continue
+			}
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS `+archivePartition(day)); err != nil {
+				return fmt.Errorf("drop partition %s: %w", archivePartition(day), err)
+			}
+			dropped++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("drop outbox archive: %w", err)
+	}
+	return dropped, nil
+}
+
+// lockArchive берёт advisory lock архива до конца транзакции
+func lockArchive(ctx context.Context, db sqlx.ExtContext) error {
+	if _, err := db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('outbox_archive'))`); err != nil {
+		return fmt.Errorf("lock outbox archive: %w", err)
+	}
+	return nil
+}
+
+// ensureArchivePartitions создаёт недостающие дневные партиции с from по to включительно
+func ensureArchivePartitions(ctx context.Context, db sqlx.ExtContext, from, to time.Time) error {
+	days, err := archivePartitions(ctx, db)
+	if err != nil {
+		return err
+	}
+	existing := make(map[time.Time]bool, len(days))
+	for _, day := range days {
+		existing[day] = true
+	}
+	for day := archiveDay(from); !day.After(to.UTC()); day = day.AddDate(0, 0, 1) {
+		if existing[day] {
+			continue
+		}
+		q := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF outbox_archive FOR VALUES FROM ('%s') TO ('%s')`,
+			archivePartition(day), day.Format(time.DateOnly), day.AddDate(0, 0, 1).Format(time.DateOnly))
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("create partition %s: %w", archivePartition(day), err)
+		}
+	}
+	return nil
+}
+
+// archivePartitions возвращает дни существующих партиций outbox_archive; партиции с чужими
+// именами (созданные вручную) не учитываются и не удаляются
+func archivePartitions(ctx context.Context, db sqlx.ExtContext) ([]time.Time, error) {
+	const q = `
+        SELECT c.relname
+        FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'outbox_archive'::regclass
+    `
+
+	var names []string
+	if err := sqlx.SelectContext(ctx, db, &names, q); err != nil {
+		return nil, fmt.Errorf("list archive partitions: %w", err)
+	}
+	days := make([]time.Time, 0, len(names))
+	for _, name := range names {
+		suffix, ok := strings.CutPrefix(name, archivePartitionPrefix)
+		if !ok {
+			continue
+		}
+		day, err := time.Parse(archivePartitionLayout, suffix)
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// archiveDay — начало дня t по UTC: processed_at хранится без зоны, в UTC
+func archiveDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func archivePartition(day time.Time) string {
+	return archivePartitionPrefix + day.Format(archivePartitionLayout)
+}
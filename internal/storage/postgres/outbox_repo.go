@@ -2,47 +2,147 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
 	"github.com/romariotrain/media-platform/internal/media/models"
 )
 
 type OutboxRepo struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	registry *events.Registry
+	timeouts *Timeouts
+	cipher   *encryption.Cipher
+	shard    OutboxShard // см. Shard
 }
 
 type OutboxRecord struct {
-	ID          int64           `db:"id"`
-	EventID     string          `db:"event_id"`
-	EventType   string          `db:"event_type"`
-	AggregateID string          `db:"aggregate_id"`
-	Payload     json.RawMessage `db:"payload"`
-	OccurredAt  time.Time       `db:"occurred_at"`
+	ID            int64           `db:"id"`
+	EventID       string          `db:"event_id"`
+	EventType     string          `db:"event_type"`
+	SchemaVersion int             `db:"schema_version"`
+	AggregateID   string          `db:"aggregate_id"`
+	Sequence      int64           `db:"sequence"` // номер события в потоке агрегата; 0 — записано до появления номеров
+	Payload       json.RawMessage `db:"payload"`
+	OccurredAt    time.Time       `db:"occurred_at"`
+
+	Attempts       int        `db:"attempts"`         // неудачные попытки публикации
+	LastError      string     `db:"last_error"`       // ошибка последней попытки
+	NextRetryAt    *time.Time `db:"next_retry_at"`    // раньше этого времени событие не берётся
+	DeadLetteredAt *time.Time `db:"dead_lettered_at"` // событие исчерпало попытки и припарковано
+	ProcessedAt    *time.Time `db:"processed_at"`     // событие опубликовано
 }
 
+// outboxColumns — колонки outbox в порядке полей OutboxRecord
+const outboxColumns = `id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at,
+        attempts, last_error, next_retry_at, dead_lettered_at, processed_at`
+
 func NewOutboxRepo(db *sqlx.DB) *OutboxRepo {
-	return &OutboxRepo{db: db}
+	return &OutboxRepo{db: db, registry: events.Default}
+}
+
+// WithTimeouts ограничивает время операций и логирует медленные запросы
+func (r *OutboxRepo) WithTimeouts(t *Timeouts) *OutboxRepo {
+	r.timeouts = t
+	return r
+}
+
+// WithEncryption шифрует payload новых событий; прочитанные payload расшифровываются,
+// записанные до включения шифрования читаются как есть
+func (r *OutboxRepo) WithEncryption(c *encryption.Cipher) *OutboxRepo {
+	r.cipher = c
+	return r
+}
+
+// open расшифровывает payload прочитанных записей; event_id — aad шифротекста
+func (r *OutboxRepo) open(ctx context.Context, records []OutboxRecord) error {
+	for i := range records {
+		payload, err := r.cipher.OpenJSON(ctx, records[i].Payload, []byte(records[i].EventID))
+		if err != nil {
+			return fmt.Errorf("outbox %d payload: %w", records[i].ID, err)
+		}
+		records[i].Payload = payload
+	}
+	return nil
 }
 
-func (r *OutboxRepo) Add(ctx context.Context, tx *sqlx.Tx, event models.DomainEvent) error {
+// openPending — open для GetPending: запись, которую не удалось расшифровать (ключ неизвестен
+// или выведен из ротации), паркуется в dead letter с ошибкой, остальные возвращаются —
+// иначе одна такая запись роняла бы каждый poll и publisher стоял бы на ней.
+func (r *OutboxRepo) openPending(ctx context.Context, records []OutboxRecord) ([]OutboxRecord, error) {
+	opened := records[:0]
+	for _, rec := range records {
+		payload, err := r.cipher.OpenJSON(ctx, rec.Payload, []byte(rec.EventID))
+		if err != nil {
+			if err := r.MarkDeadLetter(ctx, rec.ID, "decrypt payload: "+err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rec.Payload = payload
+		opened = append(opened, rec)
+	}
+	return opened, nil
+}
+
+// Add кладёт событие в outbox в рамках транзакции из контекста (TxManager.WithinTransaction).
+// Без транзакции возвращает ErrNoTx: событие без атомарной записи с изменением состояния теряет смысл.
+// Событие заворачивается через реестр events, поэтому незарегистрированный тип
+// не попадёт в outbox, а версия схемы фиксируется в момент записи.
+// Номер события в потоке агрегата берётся из счётчика aggregate_sequences: строка счётчика
+// заблокирована до конца транзакции, так что номера идут в порядке коммитов.
+func (r *OutboxRepo) Add(ctx context.Context, event models.DomainEvent) error {
+	ctx, done := r.timeouts.writing(ctx, "outbox add")
+	defer done()
+
+	const nextSequence = `
+    INSERT INTO aggregate_sequences (aggregate_id, sequence) VALUES ($1, 1)
+    ON CONFLICT (aggregate_id) DO UPDATE SET sequence = aggregate_sequences.sequence + 1
+    RETURNING sequence
+`
 	const query = `
-    INSERT INTO outbox (event_id, event_type, aggregate_id, payload, occurred_at)
-    VALUES ($1, $2, $3, $4, $5)
+    INSERT INTO outbox (event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
 `
-	payload, err := json.Marshal(event)
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		return ErrNoTx
+	}
+
+	var seq int64
+	if err := tx.GetContext(ctx, &seq, nextSequence, event.AggregateID().String()); err != nil {
+		return fmt.Errorf("next aggregate sequence: %w", err)
+	}
+	if s, ok := event.(models.Sequenced); ok {
+		s.SetSequence(seq)
+	}
+
+	env, err := r.registry.Wrap(event)
+	if err != nil {
+		return fmt.Errorf("wrap event: %w", err)
+	}
+	env.Sequence = seq
+	payload, err := r.cipher.SealJSON(ctx, env.Payload, []byte(env.EventID))
 	if err != nil {
-		return fmt.Errorf("marshal event: %w", err)
+		return fmt.Errorf("encrypt payload: %w", err)
 	}
 
 	_, err = tx.ExecContext(ctx, query,
-		event.EventID(),
-		event.EventType(),
-		event.AggregateID(),
-		payload,
-		event.OccurredAt(),
+		env.EventID,
+		env.EventType,
+		env.SchemaVersion,
+		env.AggregateID,
+		env.Sequence,
+		[]byte(payload),
+		env.OccurredAt,
 	)
 	if err != nil {
 		return fmt.Errorf("insert outbox: %w", err)
@@ -52,24 +152,146 @@ func (r *OutboxRepo) Add(ctx context.Context, tx *sqlx.Tx, event models.DomainEv
 
 }
 
+// AddBatch — Add для пачки событий (service.BatchOutbox): номера в потоках агрегатов
+// резервируются одним запросом к aggregate_sequences, события пишутся одним INSERT.
+// События одного агрегата получают номера в порядке batch.
+func (r *OutboxRepo) AddBatch(ctx context.Context, batch []models.DomainEvent) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	ctx, done := r.timeouts.writing(ctx, "outbox add batch")
+	defer done()
+
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		return ErrNoTx
+	}
+
+	// Сколько номеров нужно каждому агрегату; агрегаты по возрастанию id, чтобы конкурентные
+	// пачки блокировали строки счётчиков в одном порядке
+	counts := make(map[string]int64, len(batch))
+	for _, event := range batch {
+		counts[event.AggregateID().String()]++
+	}
+	aggregates := slices.Sorted(maps.Keys(counts))
+
+	var (
+		values []string
+		args   []any
+	)
+	for _, id := range aggregates {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d::bigint)", n+1, n+2))
+		args = append(args, id, counts[id])
+	}
+	reserve := `
+    INSERT INTO aggregate_sequences (aggregate_id, sequence) VALUES ` + strings.Join(values, ", ") + `
+    ON CONFLICT (aggregate_id) DO UPDATE SET sequence = aggregate_sequences.sequence + EXCLUDED.sequence
+    RETURNING aggregate_id, sequence
+`
+	var reserved []struct {
+		AggregateID string `db:"aggregate_id"`
+		Sequence    int64  `db:"sequence"`
+	}
+	if err := tx.SelectContext(ctx, &reserved, reserve, args...); err != nil {
+		return fmt.Errorf("reserve aggregate sequences: %w", err)
+	}
+	// next — номер следующего события агрегата: RETURNING отдаёт последний зарезервированный
+	next := make(map[string]int64, len(reserved))
+	for _, row := range reserved {
+		next[row.AggregateID] = row.Sequence - counts[row.AggregateID] + 1
+	}
+
+	values, args = values[:0], args[:0]
+	for _, event := range batch {
+		aggregate := event.AggregateID().String()
+		seq := next[aggregate]
+		next[aggregate]++
+		if s, ok := event.(models.Sequenced); ok {
+			s.SetSequence(seq)
+		}
+
+		env, err := r.registry.Wrap(event)
+		if err != nil {
+			return fmt.Errorf("wrap event: %w", err)
+		}
+		env.Sequence = seq
+		payload, err := r.cipher.SealJSON(ctx, env.Payload, []byte(env.EventID))
+		if err != nil {
+			return fmt.Errorf("encrypt payload: %w", err)
+		}
+
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+		args = append(args, env.EventID, env.EventType, env.SchemaVersion, env.AggregateID, env.Sequence, []byte(payload), env.OccurredAt)
+	}
+	insert := `
+    INSERT INTO outbox (event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at)
+    VALUES ` + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, insert, args...); err != nil {
+		return fmt.Errorf("insert outbox: %w", err)
+	}
+	return nil
+}
+
+// GetPending возвращает события к публикации: не обработанные, не припаркованные
+// и без отложенного повтора в будущем; у шарда (Shard) — только события его агрегатов.
+// Пока более раннее событие агрегата ждёт повтора, его следующие события не берутся:
+// consumer получает события агрегата в порядке sequence.
 func (r *OutboxRepo) GetPending(ctx context.Context, limit int) ([]OutboxRecord, error) {
-	const q = `
-        SELECT id, event_id, event_type, aggregate_id, payload, occurred_at
+	ctx, done := r.timeouts.reading(ctx, "outbox get pending")
+	defer done()
+
+	shard, args := r.shard.condition(2)
+	q := `
+        SELECT ` + outboxColumns + `
         FROM outbox
         WHERE processed_at IS NULL
+          AND dead_lettered_at IS NULL
+          AND (next_retry_at IS NULL OR next_retry_at <= NOW())
+          AND NOT EXISTS (
+              SELECT 1 FROM outbox prev
+              WHERE prev.aggregate_id = outbox.aggregate_id
+                AND prev.id < outbox.id
+                AND prev.processed_at IS NULL
+                AND prev.dead_lettered_at IS NULL
+                AND prev.next_retry_at > NOW()
+          )` + shard + `
         ORDER BY id ASC
         LIMIT $1
     `
 
 	var records []OutboxRecord
-	if err := r.db.SelectContext(ctx, &records, q, limit); err != nil {
+	if err := r.db.SelectContext(ctx, &records, q, append([]any{limit}, args...)...); err != nil {
 		return nil, fmt.Errorf("get pending: %w", err)
 	}
+	records, err := r.openPending(ctx, records)
+	if err != nil {
+		return nil, err
+	}
 
 	return records, nil
 }
 
+// CountPending возвращает число необработанных событий (backlog publisher'а); у шарда — его событий
+func (r *OutboxRepo) CountPending(ctx context.Context) (int64, error) {
+	ctx, done := r.timeouts.reading(ctx, "outbox count pending")
+	defer done()
+
+	shard, args := r.shard.condition(1)
+	q := `SELECT count(*) FROM outbox WHERE processed_at IS NULL AND dead_lettered_at IS NULL` + shard
+
+	var n int64
+	if err := r.db.GetContext(ctx, &n, q, args...); err != nil {
+		return 0, fmt.Errorf("count pending: %w", err)
+	}
+	return n, nil
+}
+
 func (r *OutboxRepo) MarkProcessed(ctx context.Context, id int64) error {
+	ctx, done := r.timeouts.writing(ctx, "outbox mark processed")
+	defer done()
+
 	const q = `
         UPDATE outbox
         SET processed_at = NOW()
@@ -83,3 +305,301 @@ func (r *OutboxRepo) MarkProcessed(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// MarkFailed фиксирует неудачную попытку публикации и откладывает следующую на retryIn
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error {
+	ctx, done := r.timeouts.writing(ctx, "outbox mark failed")
+	defer done()
+
+	const q = `
+        UPDATE outbox
+        SET attempts = attempts + 1,
+            last_error = $2,
+            next_retry_at = NOW() + make_interval(secs => $3)
+        WHERE id = $1
+    `
+
+	if _, err := r.db.ExecContext(ctx, q, id, lastError, retryIn.Seconds()); err != nil {
+		return fmt.Errorf("mark failed: %w", err)
+	}
+	return nil
+}
+
+// MarkDeadLetter паркует событие, исчерпавшее попытки: publisher его больше не берёт
+func (r *OutboxRepo) MarkDeadLetter(ctx context.Context, id int64, lastError string) error {
+	ctx, done := r.timeouts.writing(ctx, "outbox mark dead letter")
+	defer done()
+
+	const q = `
+        UPDATE outbox
+        SET attempts = attempts + 1,
+            last_error = $2,
+            next_retry_at = NULL,
+            dead_lettered_at = NOW()
+        WHERE id = $1
+    `
+
+	if _, err := r.db.ExecContext(ctx, q, id, lastError); err != nil {
+		return fmt.Errorf("mark dead letter: %w", err)
+	}
+	return nil
+}
+
+// Состояния необработанных событий для OutboxFilter.State
+const (
+	OutboxStatePending    = "pending"     // ждёт публикации или повтора
+	OutboxStateDeadLetter = "dead_letter" // исчерпало попытки
+)
+
+// OutboxFilter — фильтр необработанных событий для операторов. Пустые поля не фильтруют.
+type OutboxFilter struct {
+	State       string
+	EventType   string
+	AggregateID string
+	Limit       int
+	Offset      int
+}
+
+// ListOutbox возвращает необработанные события по фильтру: припаркованные — последние первыми,
+// остальные — в порядке публикации.
+func (r *OutboxRepo) ListOutbox(ctx context.Context, f OutboxFilter) ([]OutboxRecord, error) {
+	ctx, done := r.timeouts.reading(ctx, "outbox list")
+	defer done()
+
+	where := []string{"processed_at IS NULL"}
+	order := "id ASC"
+	var args []any
+
+	switch f.State {
+	case "":
+	case OutboxStatePending:
+		where = append(where, "dead_lettered_at IS NULL")
+	case OutboxStateDeadLetter:
+		where = append(where, "dead_lettered_at IS NOT NULL")
+		order = "dead_lettered_at DESC, id DESC"
+	default:
+		return nil, fmt.Errorf("unknown outbox state %q: %w", f.State, models.ErrInvalidArgument)
+	}
+	if f.EventType != "" {
+		args = append(args, f.EventType)
+		where = append(where, fmt.Sprintf("event_type = $%d", len(args)))
+	}
+	if f.AggregateID != "" {
+		args = append(args, f.AggregateID)
+		where = append(where, fmt.Sprintf("aggregate_id = $%d", len(args)))
+	}
+	args = append(args, f.Limit, f.Offset)
+
+	q := fmt.Sprintf(`
+        SELECT %s
+        FROM outbox
+        WHERE %s
+        ORDER BY %s
+        LIMIT $%d OFFSET $%d
+    `, outboxColumns, strings.Join(where, " AND "), order, len(args)-1, len(args))
+
+	var records []OutboxRecord
+	if err := r.db.SelectContext(ctx, &records, q, args...); err != nil {
+		return nil, fmt.Errorf("list outbox: %w", err)
+	}
+	if err := r.open(ctx, records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ListDeadLetters возвращает припаркованные события, последние первыми
+func (r *OutboxRepo) ListDeadLetters(ctx context.Context, limit, offset int) ([]OutboxRecord, error) {
+	return r.ListOutbox(ctx, OutboxFilter{State: OutboxStateDeadLetter, Limit: limit, Offset: offset})
+}
+
+// GetOutbox возвращает событие по id в любом состоянии, включая обработанные
+func (r *OutboxRepo) GetOutbox(ctx context.Context, id int64) (*OutboxRecord, error) {
+	ctx, done := r.timeouts.reading(ctx, "outbox get")
+	defer done()
+
+	const q = `SELECT ` + outboxColumns + ` FROM outbox WHERE id = $1`
+
+	var rec OutboxRecord
+	if err := r.db.GetContext(ctx, &rec, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("get outbox: %w", err)
+	}
+	records := []OutboxRecord{rec}
+	if err := r.open(ctx, records); err != nil {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// Requeue возвращает припаркованное событие в очередь публикации со сброшенным счётчиком попыток.
+// Событие не в dead letter — ErrConflict: повтор и так запланирован или событие уже опубликовано.
+func (r *OutboxRepo) Requeue(ctx context.Context, id int64) error {
+	ctx, done := r.timeouts.writing(ctx, "outbox requeue")
+	defer done()
+
+	const q = `
+        UPDATE outbox
+        SET attempts = 0,
+            last_error = '',
+            next_retry_at = NULL,
+            dead_lettered_at = NULL
+        WHERE id = $1 AND dead_lettered_at IS NOT NULL AND processed_at IS NULL
+    `
+
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("requeue outbox: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("requeue outbox: %w", err)
+	}
+	if n == 0 {
+		if _, err := r.GetOutbox(ctx, id); err != nil {
+			return err
+		}
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// OutboxReplayFilter — опубликованные события для переигрывания: события агрегата, типа
+// и/или интервала occurred_at [From, To). Пустые поля не фильтруют.
+type OutboxReplayFilter struct {
+	AggregateID string
+	EventType   string
+	From        time.Time
+	To          time.Time
+}
+
+// where собирает условие выборки; occurred_at хранится без зоны, в UTC
+func (f OutboxReplayFilter) where() (string, []any) {
+	where := []string{"processed_at IS NOT NULL"}
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.AggregateID != "" {
+		add("aggregate_id = $%d", f.AggregateID)
+	}
+	if f.EventType != "" {
+		add("event_type = $%d", f.EventType)
+	}
+	if !f.From.IsZero() {
+		add("occurred_at >= $%d", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("occurred_at < $%d", f.To.UTC())
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// archiveReplayColumns — колонки outbox_archive в порядке полей OutboxRecord; полей повторов
+// в архиве нет, опубликованное событие их и не несёт
+const archiveReplayColumns = `id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at,
+        attempts, '' AS last_error, NULL::timestamp AS next_retry_at, NULL::timestamp AS dead_lettered_at, processed_at`
+
+// ListProcessed возвращает опубликованные события по фильтру с id больше afterID — в порядке
+// публикации, страницами по limit. Читаются и outbox, и outbox_archive: событие, ушедшее в архив,
+// переигрывается до удаления его партиции.
+func (r *OutboxRepo) ListProcessed(ctx context.Context, f OutboxReplayFilter, afterID int64, limit int) ([]OutboxRecord, error) {
+	ctx, done := r.timeouts.reading(ctx, "outbox list processed")
+	defer done()
+
+	where, args := f.where()
+	args = append(args, afterID, limit)
+	q := fmt.Sprintf(`
+        SELECT %[1]s FROM outbox WHERE %[3]s AND id > $%[4]d
+        UNION ALL
+        SELECT %[2]s FROM outbox_archive WHERE %[3]s AND id > $%[4]d
+        ORDER BY id ASC
+        LIMIT $%[5]d
+    `, outboxColumns, archiveReplayColumns, where, len(args)-1, len(args))
+
+	var records []OutboxRecord
+	if err := r.db.SelectContext(ctx, &records, q, args...); err != nil {
+		return nil, fmt.Errorf("list processed outbox: %w", err)
+	}
+	if err := r.open(ctx, records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RequeueProcessed снова ставит в очередь публикации опубликованные события по фильтру —
+// не больше limit первых (0 — все). event_id сохраняются: consumer'ы с inbox пропустят
+// уже обработанные события, новый consumer получит их впервые. События из outbox_archive
+// возвращаются в outbox с прежними id и sequence; advisory lock архива не даёт archiver'у
+// перенести выбранные события посреди запроса.
+func (r *OutboxRepo) RequeueProcessed(ctx context.Context, f OutboxReplayFilter, limit int) (int64, error) {
+	ctx, done := r.timeouts.writing(ctx, "outbox requeue processed")
+	defer done()
+
+	where, args := f.where()
+	selection := `
+            SELECT id, false AS archived FROM outbox WHERE ` + where + `
+            UNION ALL
+            SELECT id, true FROM outbox_archive WHERE ` + where + `
+            ORDER BY id`
+	if limit > 0 {
+		args = append(args, limit)
+		selection += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	q := `
+        WITH picked AS (` + selection + `
+        ), restored AS (
+            DELETE FROM outbox_archive
+            WHERE id IN (SELECT id FROM picked WHERE archived)
+            RETURNING id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at
+        ), inserted AS (
+            INSERT INTO outbox (id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at)
+            SELECT id, event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at
+            FROM restored
+            RETURNING id
+        ), updated AS (
+            UPDATE outbox
+            SET processed_at = NULL,
+                attempts = 0,
+                last_error = '',
+                next_retry_at = NULL
+            WHERE id IN (SELECT id FROM picked WHERE NOT archived)
+            RETURNING id
+        )
+        SELECT (SELECT count(*) FROM inserted) + (SELECT count(*) FROM updated)`
+
+	var n int64
+	err := NewTxManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if err := lockArchive(ctx, db); err != nil {
+			return err
+		}
+		return sqlx.GetContext(ctx, db, &n, q, args...)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("requeue processed outbox: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteOutbox удаляет событие безвозвратно: оно не будет опубликовано
+func (r *OutboxRepo) DeleteOutbox(ctx context.Context, id int64) error {
+	ctx, done := r.timeouts.writing(ctx, "outbox delete")
+	defer done()
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete outbox: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete outbox: %w", err)
+	}
+	if n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestOutboxRepo_AggregateSequence(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	outbox := postgres.NewOutboxRepo(db.DB)
+	svc := service.New(postgres.NewMediaRepo(db.DB), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	other, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ReadyStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+
+	records, err := outbox.ListOutbox(ctx, postgres.OutboxFilter{AggregateID: m.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	for i, rec := range records {
+		require.Equal(t, int64(i+1), rec.Sequence)
+	}
+
+	// Номер есть и в payload MediaStatusChanged
+	payload, err := events.Default.DecodeLatest(events.Envelope{
+		EventType:     records[2].EventType,
+		SchemaVersion: records[2].SchemaVersion,
+		Payload:       records[2].Payload,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), payload.(*events.MediaStatusChangedV1).Sequence)
+
+	// У каждого агрегата свой счётчик
+	records, err = outbox.ListOutbox(ctx, postgres.OutboxFilter{AggregateID: other.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, int64(1), records[0].Sequence)
+}
+
+func TestOutboxRepo_AddBatch(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	outbox := postgres.NewOutboxRepo(db.DB)
+	repo := postgres.NewMediaRepo(db.DB)
+	svc := service.New(repo, outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	other, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+
+	batch := []models.DomainEvent{
+		models.NewMediaStatusChanged(m.ID, m.OwnerID, models.UploadedStatus, models.ProcessingStatus, "", ""),
+		models.NewMediaStatusChanged(other.ID, other.OwnerID, models.UploadedStatus, models.ProcessingStatus, "", ""),
+		models.NewMediaStatusChanged(m.ID, m.OwnerID, models.ProcessingStatus, models.ReadyStatus, "", ""),
+	}
+	require.ErrorIs(t, outbox.AddBatch(ctx, batch), postgres.ErrNoTx)
+	require.NoError(t, repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		return outbox.AddBatch(ctx, batch)
+	}))
+
+	// Номера продолжают потоки агрегатов, события одного агрегата — в порядке пачки
+	records, err := outbox.ListOutbox(ctx, postgres.OutboxFilter{AggregateID: m.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	for i, rec := range records {
+		require.Equal(t, int64(i+1), rec.Sequence)
+	}
+	require.Equal(t, batch[2].EventID().String(), records[2].EventID)
+	require.Equal(t, int64(3), batch[2].(*models.MediaStatusChanged).Sequence())
+
+	records, err = outbox.ListOutbox(ctx, postgres.OutboxFilter{AggregateID: other.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, int64(2), records[1].Sequence)
+}
+
+func TestOutboxRepo_Encryption(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	keys, err := encryption.ParseKeyring("k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	cipher, err := encryption.NewCipher(keys)
+	require.NoError(t, err)
+	outbox := postgres.NewOutboxRepo(db.DB).WithEncryption(cipher)
+	svc := service.New(postgres.NewMediaRepo(db.DB), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/private.mp4")
+	require.NoError(t, err)
+
+	// В таблице payload зашифрован
+	var raw string
+	require.NoError(t, db.DB.GetContext(ctx, &raw, `SELECT payload::text FROM outbox WHERE aggregate_id = $1`, m.ID.String()))
+	require.Contains(t, raw, `"$encrypted"`)
+	require.NotContains(t, raw, "private.mp4")
+
+	records, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Contains(t, string(records[0].Payload), "s3://bucket/private.mp4")
+
+	// Без ключей зашифрованное событие не читается
+	_, err = postgres.NewOutboxRepo(db.DB).GetOutbox(ctx, records[0].ID)
+	require.ErrorIs(t, err, encryption.ErrNoCipher)
+}
+
+func TestOutboxRepo_Archive(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	outbox := postgres.NewOutboxRepo(db.DB)
+	svc := service.New(postgres.NewMediaRepo(db.DB), outbox)
+
+	for i := range 4 {
+		_, err := svc.CreateMedia(ctx, models.Video, fmt.Sprintf("s3://bucket/%d.mp4", i))
+		require.NoError(t, err)
+	}
+	records, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	// Полдень: границы дней не зависят от времени запуска теста
+	now := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	ages := []time.Duration{time.Hour, 30 * time.Hour, 50 * time.Hour, 10 * 24 * time.Hour}
+	for i, rec := range records {
+		_, err := db.DB.ExecContext(ctx, `UPDATE outbox SET processed_at = $2 WHERE id = $1`, rec.ID, now.Add(-ages[i]))
+		require.NoError(t, err)
+	}
+
+	// Опубликованные больше суток назад уходят из outbox, старше недели — без архива
+	n, err := outbox.ArchiveProcessed(ctx, now.Add(-24*time.Hour), now.Add(-7*24*time.Hour), 100)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	var left, archived []int64
+	require.NoError(t, db.DB.SelectContext(ctx, &left, `SELECT id FROM outbox ORDER BY id`))
+	require.Equal(t, []int64{records[0].ID}, left)
+	require.NoError(t, db.DB.SelectContext(ctx, &archived, `SELECT id FROM outbox_archive ORDER BY id`))
+	require.Equal(t, []int64{records[1].ID, records[2].ID}, archived)
+
+	// Повторный запуск ничего не переносит: партиции уже есть, событий к переносу нет
+	n, err = outbox.ArchiveProcessed(ctx, now.Add(-24*time.Hour), now.Add(-7*24*time.Hour), 100)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// Партиции — по дням с now-7d по now-1d; удаляются только целиком лежащие раньше границы:
+	// до now-40h — пять дней, включая день события, опубликованного 50 часов назад
+	dropped, err := outbox.DropArchive(ctx, now.Add(-40*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 5, dropped)
+	require.NoError(t, db.DB.SelectContext(ctx, &archived, `SELECT id FROM outbox_archive ORDER BY id`))
+	require.Equal(t, []int64{records[1].ID}, archived)
+
+	dropped, err = outbox.DropArchive(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, dropped)
+}
+
+func TestOutboxRepo_ReplayReadsArchive(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	outbox := postgres.NewOutboxRepo(db.DB)
+	svc := service.New(postgres.NewMediaRepo(db.DB), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	records, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for _, rec := range records {
+		require.NoError(t, outbox.MarkProcessed(ctx, rec.ID))
+	}
+
+	// Первое событие уходит в архив, второе остаётся в outbox
+	now := time.Now().UTC()
+	_, err = db.DB.ExecContext(ctx, `UPDATE outbox SET processed_at = $2 WHERE id = $1`, records[0].ID, now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	n, err := outbox.ArchiveProcessed(ctx, now.Add(-time.Hour), now.Add(-24*time.Hour), 100)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	filter := postgres.OutboxReplayFilter{AggregateID: m.ID.String()}
+	listed, err := outbox.ListProcessed(ctx, filter, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	require.Equal(t, records[0].EventID, listed[0].EventID)
+	require.Equal(t, records[1].EventID, listed[1].EventID)
+
+	// Переигрывание возвращает архивное событие в outbox с прежними id и sequence
+	requeued, err := outbox.RequeueProcessed(ctx, filter, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), requeued)
+	pending, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, records[0].ID, pending[0].ID)
+	require.Equal(t, records[0].Sequence, pending[0].Sequence)
+
+	var archived int
+	require.NoError(t, db.DB.GetContext(ctx, &archived, `SELECT count(*) FROM outbox_archive`))
+	require.Zero(t, archived)
+}
+
+func TestOutboxRepo_Shard(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	outbox := postgres.NewOutboxRepo(db.DB)
+	svc := service.New(postgres.NewMediaRepo(db.DB), outbox)
+
+	const aggregates = 12
+	for range aggregates {
+		m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+		require.NoError(t, err)
+		_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+		require.NoError(t, err)
+	}
+	total, err := outbox.CountPending(ctx)
+	require.NoError(t, err)
+
+	// Шарды делят outbox без пересечений, все события агрегата — в одном шарде
+	owner := map[string]int{}
+	var claimed int64
+	for i := range 3 {
+		shard := outbox.Shard(postgres.OutboxShard{Index: i, Count: 3})
+		n, err := shard.CountPending(ctx)
+		require.NoError(t, err)
+		records, err := shard.GetPending(ctx, 100)
+		require.NoError(t, err)
+		require.Len(t, records, int(n))
+		for _, rec := range records {
+			if prev, ok := owner[rec.AggregateID]; ok {
+				require.Equal(t, prev, i, "aggregate %s split between shards", rec.AggregateID)
+			}
+			owner[rec.AggregateID] = i
+		}
+		claimed += n
+	}
+	require.Equal(t, total, claimed)
+	require.Len(t, owner, aggregates)
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import "fmt"
+
+// OutboxShard — доля outbox одного publisher'а: события агрегатов, чей хэш aggregate_id по
+// модулю Count равен Index. Все события агрегата попадают в один шард, поэтому N publisher'ов
+// с индексами 0..N-1 делят outbox без пересечений и сохраняют порядок событий агрегата.
+// Хэш считает база, так что разбиение одинаково у всех инстансов. Нулевое значение — весь outbox.
+type OutboxShard struct {
+	Index int
+	Count int
+}
+
+// Validate проверяет, что Index попадает в [0, Count)
+func (s OutboxShard) Validate() error {
+	if s.Count < 0 {
+		return fmt.Errorf("shard count cannot be negative, got: %d", s.Count)
+	}
+	if s.Index < 0 || s.Index >= max(s.Count, 1) {
+		return fmt.Errorf("shard index %d out of range [0, %d)", s.Index, max(s.Count, 1))
+	}
+	return nil
+}
+
+// Sharded сообщает, делится ли outbox между несколькими publisher'ами
+func (s OutboxShard) Sharded() bool { return s.Count > 1 }
+
+func (s OutboxShard) String() string { return fmt.Sprintf("%d/%d", s.Index, max(s.Count, 1)) }
+
+// Shard возвращает outbox, в котором GetPending и CountPending видят только события шарда s;
+// остальные методы работают со всей таблицей. Исходный репозиторий не меняется.
+func (r *OutboxRepo) Shard(s OutboxShard) *OutboxRepo {
+	c := *r
+	c.shard = s
+	return &c
+}
+
+// condition — условие шарда для WHERE с параметрами с номера n; без шардирования пустое.
+// hashtext отдаёт int4, знаковый бит снимается до взятия остатка.
+func (s OutboxShard) condition(n int) (string, []any) {
+	if !s.Sharded() {
+		return "", nil
+	}
+	return fmt.Sprintf(" AND (hashtext(aggregate_id) & 2147483647) %% $%d = $%d", n, n+1), []any{s.Count, s.Index}
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxShard_Validate(t *testing.T) {
+	require.NoError(t, OutboxShard{}.Validate())
+	require.NoError(t, OutboxShard{Index: 2, Count: 3}.Validate())
+	require.Error(t, OutboxShard{Index: 3, Count: 3}.Validate())
+	require.Error(t, OutboxShard{Index: -1, Count: 3}.Validate())
+	require.Error(t, OutboxShard{Index: 1}.Validate())
+	require.Error(t, OutboxShard{Count: -2}.Validate())
+}
+
+func TestOutboxShard_Condition(t *testing.T) {
+	cond, args := OutboxShard{Count: 1}.condition(2)
+	require.Empty(t, cond)
+	require.Empty(t, args)
+
+	cond, args = OutboxShard{Index: 1, Count: 4}.condition(2)
+	require.Equal(t, " AND (hashtext(aggregate_id) & 2147483647) % $2 = $3", cond)
+	require.Equal(t, []any{4, 1}, args)
+}
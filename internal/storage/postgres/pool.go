@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// PoolConfig содержит настройки нативного пула pgxpool
+type PoolConfig struct {
+	DSN               string
+	MaxConns          int32         // Максимум соединений (default: 25)
+	MinConns          int32         // Соединения, которые пул держит открытыми (default: 0)
+	MaxConnLifetime   time.Duration // Время жизни соединения (default: 1h)
+	MaxConnIdleTime   time.Duration // Простой, после которого соединение закрывается (default: 5m)
+	HealthCheckPeriod time.Duration // Период проверки простаивающих соединений (default: 1m)
+
+	// StatementCacheCapacity — размер кэша prepared statements на соединение (default: 512).
+	// Отрицательное значение отключает кэш: запросы идут через extended protocol без подготовки.
+	StatementCacheCapacity int
+
+	// QueryTimeout — statement_timeout на стороне сервера для каждого запроса (0 = без ограничения)
+	QueryTimeout time.Duration
+
+	// StartupWait — сколько NewPool ждёт недоступную базу, повторяя ping с backoff (0 = не ждать).
+	// Нужно, когда сервис стартует раньше Postgres (docker-compose).
+	StartupWait time.Duration
+	Logger      zerolog.Logger // попытки подключения в пределах StartupWait
+}
+
+func (c PoolConfig) validate() error {
+	if c.DSN == "" {
+		return fmt.Errorf("dsn is required")
+	}
+	if c.MaxConns < 0 {
+		return fmt.Errorf("max conns cannot be negative, got: %d", c.MaxConns)
+	}
+	if c.MinConns < 0 {
+		return fmt.Errorf("min conns cannot be negative, got: %d", c.MinConns)
+	}
+	if c.MaxConns > 0 && c.MinConns > c.MaxConns {
+		return fmt.Errorf("min conns (%d) cannot exceed max conns (%d)", c.MinConns, c.MaxConns)
+	}
+	if c.MaxConnLifetime < 0 || c.MaxConnIdleTime < 0 || c.HealthCheckPeriod < 0 {
+		return fmt.Errorf("pool durations cannot be negative")
+	}
+	if c.QueryTimeout < 0 {
+		return fmt.Errorf("query timeout cannot be negative, got: %v", c.QueryTimeout)
+	}
+	if c.StartupWait < 0 {
+		return fmt.Errorf("startup wait cannot be negative, got: %v", c.StartupWait)
+	}
+	return nil
+}
+
+func (c *PoolConfig) setDefaults() {
+	if c.MaxConns == 0 {
+		c.MaxConns = 25
+	}
+	if c.MaxConnLifetime == 0 {
+		c.MaxConnLifetime = time.Hour
+	}
+	if c.MaxConnIdleTime == 0 {
+		c.MaxConnIdleTime = 5 * time.Minute
+	}
+	if c.HealthCheckPeriod == 0 {
+		c.HealthCheckPeriod = time.Minute
+	}
+	if c.StatementCacheCapacity == 0 {
+		c.StatementCacheCapacity = 512
+	}
+}
+
+// parsePoolConfig превращает PoolConfig в конфиг pgxpool без подключения к базе
+func parsePoolConfig(cfg PoolConfig) (*pgxpool.Config, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	cfg.setDefaults()
+
+	pc, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("parse dsn: %w", err)
+	}
+
+	pc.MaxConns = cfg.MaxConns
+	pc.MinConns = cfg.MinConns
+	pc.MaxConnLifetime = cfg.MaxConnLifetime
+	pc.MaxConnIdleTime = cfg.MaxConnIdleTime
+	pc.HealthCheckPeriod = cfg.HealthCheckPeriod
+
+	if cfg.StatementCacheCapacity > 0 {
+		pc.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		pc.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	} else {
+		pc.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		pc.ConnConfig.StatementCacheCapacity = 0
+	}
+
+	if cfg.QueryTimeout > 0 {
+		pc.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.QueryTimeout.Milliseconds(), 10)
+	}
+
+	return pc, nil
+}
+
+// Пауза между ping при ожидании базы на старте: удваивается до потолка
+const (
+	startupBackoff    = 250 * time.Millisecond
+	maxStartupBackoff = 5 * time.Second
+)
+
+// NewPool открывает pgxpool и проверяет соединение; с StartupWait ждёт, пока база поднимется
+func NewPool(ctx context.Context, cfg PoolConfig) (*pgxpool.Pool, error) {
+	pc, err := parsePoolConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("postgres pool config: %w", err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, pc)
+	if err != nil {
+		return nil, fmt.Errorf("postgres pool: %w", err)
+	}
+	if err := waitPing(ctx, pool, cfg.StartupWait, cfg.Logger, time.Sleep); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// waitPing пингует базу, пока она не ответит или не выйдет wait
+func waitPing(ctx context.Context, p Pinger, wait time.Duration, logger zerolog.Logger, sleep func(time.Duration)) error {
+	var waited time.Duration
+	delay := startupBackoff
+	for {
+		err := p.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if waited+delay > wait || ctx.Err() != nil {
+			return fmt.Errorf("postgres ping: %w", err)
+		}
+		logger.Warn().Err(err).Dur("retry_in", delay).Msg("postgres unavailable, waiting")
+		sleep(delay)
+		waited += delay
+		delay = min(delay*2, maxStartupBackoff)
+	}
+}
+
+// OpenDB оборачивает пул в *sqlx.DB для репозиториев.
+// Соединениями управляет pgxpool; Close у результата пул не закрывает.
+func OpenDB(pool *pgxpool.Pool) *sqlx.DB {
+	return sqlx.NewDb(stdlib.OpenDBFromPool(pool), "pgx")
+}
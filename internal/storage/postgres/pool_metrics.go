@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStater — источник статистики пула; *pgxpool.Pool удовлетворяет интерфейсу
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// PoolCollector экспортирует pgxpool.Stat как метрики Prometheus.
+// Статистика снимается в момент scrape, фоновых горутин нет.
+type PoolCollector struct {
+	pool PoolStater
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	lifetimeDestroyCount *prometheus.Desc
+	idleDestroyCount     *prometheus.Desc
+}
+
+// NewPoolCollector создаёт коллектор; name попадает в label pool (например "primary")
+func NewPoolCollector(pool PoolStater, name string) *PoolCollector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("media_db_pool_"+metric, help, nil, labels)
+	}
+
+	return &PoolCollector{
+		pool:                 pool,
+		acquiredConns:        desc("acquired_conns", "Соединения, выданные из пула в данный момент"),
+		idleConns:            desc("idle_conns", "Простаивающие соединения"),
+		constructingConns:    desc("constructing_conns", "Соединения в процессе установки"),
+		totalConns:           desc("total_conns", "Все соединения пула"),
+		maxConns:             desc("max_conns", "Максимальный размер пула"),
+		acquireCount:         desc("acquire_total", "Успешные получения соединения"),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Суммарное время ожидания соединения"),
+		canceledAcquireCount: desc("canceled_acquire_total", "Получения соединения, отменённые контекстом"),
+		emptyAcquireCount:    desc("empty_acquire_total", "Получения, которым пришлось ждать свободное соединение"),
+		newConnsCount:        desc("new_conns_total", "Открытые новые соединения"),
+		lifetimeDestroyCount: desc("max_lifetime_destroy_total", "Соединения, закрытые по MaxConnLifetime"),
+		idleDestroyCount:     desc("max_idle_destroy_total", "Соединения, закрытые по MaxConnIdleTime"),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.canceledAcquireCount
+	ch <- c.emptyAcquireCount
+	ch <- c.newConnsCount
+	ch <- c.lifetimeDestroyCount
+	ch <- c.idleDestroyCount
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.acquiredConns, float64(s.AcquiredConns()))
+	gauge(c.idleConns, float64(s.IdleConns()))
+	gauge(c.constructingConns, float64(s.ConstructingConns()))
+	gauge(c.totalConns, float64(s.TotalConns()))
+	gauge(c.maxConns, float64(s.MaxConns()))
+	counter(c.acquireCount, float64(s.AcquireCount()))
+	counter(c.acquireDuration, s.AcquireDuration().Seconds())
+	counter(c.canceledAcquireCount, float64(s.CanceledAcquireCount()))
+	counter(c.emptyAcquireCount, float64(s.EmptyAcquireCount()))
+	counter(c.newConnsCount, float64(s.NewConnsCount()))
+	counter(c.lifetimeDestroyCount, float64(s.MaxLifetimeDestroyCount()))
+	counter(c.idleDestroyCount, float64(s.MaxIdleDestroyCount()))
+}
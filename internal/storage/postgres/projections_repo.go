@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/projection"
+)
+
+// ProjectionsRepo — checkpoint'ы проекций (projection.Checkpoints) и состояние встроенных
+// проекций media_counts_by_status и owner_storage_usage. Вызывается projection.Runner
+// внутри транзакции TxManager, поэтому все методы пишут в транзакцию из ctx.
+type ProjectionsRepo struct {
+	db *sqlx.DB
+}
+
+func NewProjectionsRepo(db *sqlx.DB) *ProjectionsRepo {
+	return &ProjectionsRepo{db: db}
+}
+
+// Checkpoint — см. projection.Checkpoints; в транзакции берёт строку checkpoint'а FOR UPDATE
+func (r *ProjectionsRepo) Checkpoint(ctx context.Context, name string) (int64, error) {
+	q := conn(ctx, r.db)
+	const insert = `INSERT INTO projection_checkpoints (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`
+	if _, err := q.ExecContext(ctx, insert, name); err != nil {
+		return 0, fmt.Errorf("init checkpoint: %w", err)
+	}
+	var position int64
+	const query = `SELECT position FROM projection_checkpoints WHERE name = $1 FOR UPDATE`
+	if err := sqlx.GetContext(ctx, q, &position, query, name); err != nil {
+		return 0, fmt.Errorf("get checkpoint: %w", err)
+	}
+	return position, nil
+}
+
+// SaveCheckpoint — см. projection.Checkpoints
+func (r *ProjectionsRepo) SaveCheckpoint(ctx context.Context, name string, position int64) error {
+	const q = `
+		INSERT INTO projection_checkpoints (name, position, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (name) DO UPDATE SET position = EXCLUDED.position, updated_at = NOW()`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, name, position); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// AddMedia — см. projection.MediaStatusStore
+func (r *ProjectionsRepo) AddMedia(ctx context.Context, id, owner uuid.UUID, status models.Status) error {
+	const q = `
+		INSERT INTO projection_media_status (media_id, owner_id, status) VALUES ($1, $2, $3)
+		ON CONFLICT (media_id) DO UPDATE SET owner_id = EXCLUDED.owner_id, status = EXCLUDED.status`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, id, owner, status); err != nil {
+		return fmt.Errorf("projection add media: %w", err)
+	}
+	return nil
+}
+
+// SetMediaStatus — см. projection.MediaStatusStore
+func (r *ProjectionsRepo) SetMediaStatus(ctx context.Context, id uuid.UUID, status models.Status) error {
+	const q = `UPDATE projection_media_status SET status = $2 WHERE media_id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, id, status); err != nil {
+		return fmt.Errorf("projection set media status: %w", err)
+	}
+	return nil
+}
+
+// RemoveMedia — см. projection.MediaStatusStore
+func (r *ProjectionsRepo) RemoveMedia(ctx context.Context, id uuid.UUID) error {
+	const q = `DELETE FROM projection_media_status WHERE media_id = $1`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, id); err != nil {
+		return fmt.Errorf("projection remove media: %w", err)
+	}
+	return nil
+}
+
+// ResetMediaStatuses — см. projection.MediaStatusStore
+func (r *ProjectionsRepo) ResetMediaStatuses(ctx context.Context) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM projection_media_status`); err != nil {
+		return fmt.Errorf("reset projection media status: %w", err)
+	}
+	return nil
+}
+
+// AddOwnerUsage — см. projection.OwnerUsageStore
+func (r *ProjectionsRepo) AddOwnerUsage(ctx context.Context, owner uuid.UUID, objects, bytes int64) error {
+	const q = `
+		INSERT INTO projection_owner_usage (owner_id, objects, bytes) VALUES ($1, $2, $3)
+		ON CONFLICT (owner_id) DO UPDATE SET
+			objects = projection_owner_usage.objects + EXCLUDED.objects,
+			bytes = projection_owner_usage.bytes + EXCLUDED.bytes`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, owner, objects, bytes); err != nil {
+		return fmt.Errorf("projection add owner usage: %w", err)
+	}
+	return nil
+}
+
+// ResetOwnerUsage — см. projection.OwnerUsageStore
+func (r *ProjectionsRepo) ResetOwnerUsage(ctx context.Context) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM projection_owner_usage`); err != nil {
+		return fmt.Errorf("reset projection owner usage: %w", err)
+	}
+	return nil
+}
+
+// Stats читает сводку проекций для GET /stats
+func (r *ProjectionsRepo) Stats(ctx context.Context, f projection.StatsFilter) (projection.Stats, error) {
+	var owner uuid.NullUUID
+	if f.Owner != nil {
+		owner = uuid.NullUUID{UUID: *f.Owner, Valid: true}
+	}
+	q := conn(ctx, r.db)
+
+	var counts []struct {
+		Status models.Status `db:"status"`
+		Count  int64         `db:"count"`
+	}
+	const countsQuery = `
+		SELECT status, COUNT(*) AS count FROM projection_media_status
+		WHERE $1::uuid IS NULL OR owner_id = $1
+		GROUP BY status`
+	if err := sqlx.SelectContext(ctx, q, &counts, countsQuery, owner); err != nil {
+		return projection.Stats{}, fmt.Errorf("media counts: %w", err)
+	}
+	st := projection.Stats{ByStatus: make(map[models.Status]int64, len(counts))}
+	for _, c := range counts {
+		st.ByStatus[c.Status] = c.Count
+		st.Total += c.Count
+	}
+
+	const usageQuery = `
+		SELECT owner_id, objects, bytes FROM projection_owner_usage
+		WHERE $1::uuid IS NULL OR owner_id = $1
+		ORDER BY bytes DESC, owner_id
+		LIMIT $2`
+	var usage []struct {
+		OwnerID uuid.UUID `db:"owner_id"`
+		Objects int64     `db:"objects"`
+		Bytes   int64     `db:"bytes"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &usage, usageQuery, owner, f.OwnersLimit()); err != nil {
+		return projection.Stats{}, fmt.Errorf("owner usage: %w", err)
+	}
+	for _, u := range usage {
+		st.Owners = append(st.Owners, projection.OwnerStats{OwnerID: u.OwnerID, Objects: u.Objects, Bytes: u.Bytes})
+	}
+	return st, nil
+}
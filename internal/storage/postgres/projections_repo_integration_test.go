@@ -0,0 +1,68 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/eventstore"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/projection"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestProjectionsRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	log := postgres.NewMediaEventsRepo(db.DB).WithFeedSettle(0)
+	state := postgres.NewProjectionsRepo(db.DB)
+
+	svc := service.New(postgres.NewMediaRepo(db.DB), eventstore.NewRecorder(postgres.NewOutboxRepo(db.DB), log))
+	a, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	_, err = svc.RecordContent(ctx, a.ID, models.Content{Checksum: strings.Repeat("ab", 32), Size: 100, ContentType: "video/mp4"})
+	require.NoError(t, err)
+	b, err := svc.CreateMedia(ctx, models.Audio, "s3://bucket/b.mp3")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, b.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+
+	runner, err := projection.NewRunner(projection.Config{
+		Feed:        log,
+		Checkpoints: state,
+		Tx:          postgres.NewTxManager(db.DB),
+		Projections: []projection.Projection{projection.NewMediaCounts(state), projection.NewOwnerUsage(state)},
+		BatchSize:   2,
+	})
+	require.NoError(t, err)
+	for _, name := range runner.Names() {
+		_, err := runner.CatchUp(ctx, name)
+		require.NoError(t, err)
+	}
+
+	st, err := state.Stats(ctx, projection.StatsFilter{})
+	require.NoError(t, err)
+	require.Equal(t, map[models.Status]int64{models.UploadedStatus: 1, models.ProcessingStatus: 1}, st.ByStatus)
+	require.Equal(t, []projection.OwnerStats{{Objects: 2, Bytes: 100}}, st.Owners)
+
+	require.NoError(t, svc.DeleteMedia(ctx, a.ID, models.DeleteReasonDeleted))
+	n, err := runner.Rebuild(ctx, projection.OwnerUsageName)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	_, err = runner.CatchUp(ctx, projection.MediaCountsName)
+	require.NoError(t, err)
+
+	st, err = state.Stats(ctx, projection.StatsFilter{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), st.Total)
+	require.Equal(t, []projection.OwnerStats{{Objects: 1, Bytes: 0}}, st.Owners)
+	pos, err := state.Checkpoint(ctx, projection.MediaCountsName)
+	require.NoError(t, err)
+	require.Positive(t, pos)
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/publish"
+)
+
+// PublishDeliveriesRepo — журнал доставок уведомлений publish (publish.DeliveryStore)
+type PublishDeliveriesRepo struct {
+	db *sqlx.DB
+}
+
+func NewPublishDeliveriesRepo(db *sqlx.DB) *PublishDeliveriesRepo {
+	return &PublishDeliveriesRepo{db: db}
+}
+
+// deliveryRow — строка publish_deliveries; сообщение хранится в jsonb, latency — в миллисекундах
+type deliveryRow struct {
+	ID           uuid.UUID     `db:"id"`
+	Channel      string        `db:"channel"`
+	Subscription string        `db:"subscription"`
+	EventID      string        `db:"event_id"`
+	EventType    string        `db:"event_type"`
+	Attempt      int           `db:"attempt"`
+	RedeliveryOf uuid.NullUUID `db:"redelivery_of"`
+	Message      []byte        `db:"message"`
+	StatusCode   int           `db:"status_code"`
+	Response     string        `db:"response"`
+	Error        string        `db:"error"`
+	LatencyMS    int64         `db:"latency_ms"`
+	DeliveredAt  time.Time     `db:"delivered_at"`
+}
+
+func (r deliveryRow) delivery() (publish.Delivery, error) {
+	d := publish.Delivery{
+		ID:           r.ID,
+		Channel:      r.Channel,
+		Subscription: r.Subscription,
+		EventID:      r.EventID,
+		EventType:    r.EventType,
+		Attempt:      r.Attempt,
+		StatusCode:   r.StatusCode,
+		Response:     r.Response,
+		Error:        r.Error,
+		Latency:      time.Duration(r.LatencyMS) * time.Millisecond,
+		DeliveredAt:  r.DeliveredAt,
+	}
+	if r.RedeliveryOf.Valid {
+		d.RedeliveryOf = &r.RedeliveryOf.UUID
+	}
+	if err := json.Unmarshal(r.Message, &d.Message); err != nil {
+		return publish.Delivery{}, fmt.Errorf("decode delivery %s message: %w", r.ID, err)
+	}
+	return d, nil
+}
+
+const deliveryColumns = `id, channel, subscription, event_id, event_type, attempt, redelivery_of, message,
+	status_code, response, error, latency_ms, delivered_at`
+
+// Record — см. publish.DeliveryStore
+func (r *PublishDeliveriesRepo) Record(ctx context.Context, d publish.Delivery) error {
+	msg, err := json.Marshal(d.Message)
+	if err != nil {
+		return err
+	}
+	var redeliveryOf uuid.NullUUID
+	if d.RedeliveryOf != nil {
+		redeliveryOf = uuid.NullUUID{UUID: *d.RedeliveryOf, Valid: true}
+	}
+	const q = `
+		INSERT INTO publish_deliveries (` + deliveryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, q,
+		d.ID, d.Channel, d.Subscription, d.EventID, d.EventType, d.Attempt, redeliveryOf, msg,
+		d.StatusCode, d.Response, d.Error, d.Latency.Milliseconds(), d.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("record delivery: %w", err)
+	}
+	return nil
+}
+
+// Delivery — см. publish.DeliveryStore
+func (r *PublishDeliveriesRepo) Delivery(ctx context.Context, id uuid.UUID) (publish.Delivery, error) {
+	const q = `SELECT ` + deliveryColumns + ` FROM publish_deliveries WHERE id = $1`
+
+	var row deliveryRow
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, q, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return publish.Delivery{}, fmt.Errorf("%w: delivery %s", models.ErrNotFound, id)
+	}
+	if err != nil {
+		return publish.Delivery{}, fmt.Errorf("get delivery: %w", err)
+	}
+	return row.delivery()
+}
+
+// Deliveries — см. publish.DeliveryStore
+func (r *PublishDeliveriesRepo) Deliveries(ctx context.Context, channel string, f publish.DeliveryFilter) ([]publish.Delivery, error) {
+	if f.Limit <= 0 {
+		f.Limit = publish.DefaultDeliveriesLimit
+	}
+	const q = `
+		SELECT ` + deliveryColumns + ` FROM publish_deliveries
+		WHERE channel = $1 AND (NOT $2 OR error <> '')
+		ORDER BY delivered_at DESC, id
+		LIMIT $3`
+
+	var rows []deliveryRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q, channel, f.Failed, f.Limit); err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+	out := make([]publish.Delivery, 0, len(rows))
+	for _, row := range rows {
+		d, err := row.delivery()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
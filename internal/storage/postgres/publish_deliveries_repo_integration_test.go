@@ -0,0 +1,59 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/publish"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestPublishDeliveriesRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewPublishDeliveriesRepo(db.DB)
+
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	failed := publish.Delivery{
+		ID: uuid.New(), Channel: "ops", Subscription: "processing-failed", EventID: "e1", EventType: "MediaStatusChanged",
+		Attempt: 1, Message: publish.Message{Subject: "s", Body: "b", Event: publish.Notification{MediaID: "m1", Status: "failed"}},
+		StatusCode: 503, Response: "unavailable", Error: "slack api: 503", Latency: 120 * time.Millisecond, DeliveredAt: at,
+	}
+	redelivered := failed
+	redelivered.ID, redelivered.RedeliveryOf = uuid.New(), &failed.ID
+	redelivered.StatusCode, redelivered.Response, redelivered.Error = 200, "ok", ""
+	redelivered.DeliveredAt = at.Add(time.Minute)
+	other := publish.Delivery{ID: uuid.New(), Channel: "audit", Attempt: 1, DeliveredAt: at}
+	for _, d := range []publish.Delivery{failed, redelivered, other} {
+		require.NoError(t, repo.Record(ctx, d))
+	}
+
+	got, err := repo.Delivery(ctx, failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, failed.Message, got.Message)
+	require.Equal(t, 120*time.Millisecond, got.Latency)
+	require.Nil(t, got.RedeliveryOf)
+	require.True(t, failed.DeliveredAt.Equal(got.DeliveredAt))
+
+	list, err := repo.Deliveries(ctx, "ops", publish.DeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, redelivered.ID, list[0].ID, "newest first")
+	require.Equal(t, failed.ID, *list[0].RedeliveryOf)
+
+	list, err = repo.Deliveries(ctx, "ops", publish.DeliveryFilter{Failed: true})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, failed.ID, list[0].ID)
+
+	_, err = repo.Delivery(ctx, uuid.New())
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
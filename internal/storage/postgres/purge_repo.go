@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/purge"
+)
+
+// PurgeRepo — запросы на удаление данных владельца (owner_purges) и само удаление (purge.Store)
+type PurgeRepo struct {
+	db *sqlx.DB
+	tx *TxManager
+}
+
+func NewPurgeRepo(db *sqlx.DB) *PurgeRepo {
+	return &PurgeRepo{db: db, tx: NewTxManager(db)}
+}
+
+// purgeRow — строка owner_purges; отчёт хранится в jsonb
+type purgeRow struct {
+	ID          int64        `db:"id"`
+	OwnerID     uuid.UUID    `db:"owner_id"`
+	RequestedBy string       `db:"requested_by"`
+	Status      purge.Status `db:"status"`
+	Report      []byte       `db:"report"`
+	LastError   string       `db:"last_error"`
+	RequestedAt time.Time    `db:"requested_at"`
+	UpdatedAt   time.Time    `db:"updated_at"`
+	CompletedAt *time.Time   `db:"completed_at"`
+}
+
+func (r purgeRow) purge() (purge.Purge, error) {
+	p := purge.Purge{
+		ID:          r.ID,
+		OwnerID:     r.OwnerID,
+		RequestedBy: r.RequestedBy,
+		Status:      r.Status,
+		LastError:   r.LastError,
+		RequestedAt: r.RequestedAt,
+		UpdatedAt:   r.UpdatedAt,
+		CompletedAt: r.CompletedAt,
+	}
+	if err := json.Unmarshal(r.Report, &p.Report); err != nil {
+		return purge.Purge{}, fmt.Errorf("purge %d: decode report: %w", r.ID, err)
+	}
+	return p, nil
+}
+
+const purgeColumns = `id, owner_id, requested_by, status, report, last_error, requested_at, updated_at, completed_at`
+
+// CreatePurge — см. purge.Store
+func (r *PurgeRepo) CreatePurge(ctx context.Context, ownerID uuid.UUID, requestedBy string) (purge.Purge, error) {
+	const insert = `
+		INSERT INTO owner_purges (owner_id, requested_by, status)
+		VALUES ($1, $2, '` + string(purge.StatusPending) + `')
+		ON CONFLICT (owner_id) WHERE completed_at IS NULL DO NOTHING
+		RETURNING ` + purgeColumns
+	const existing = `SELECT ` + purgeColumns + ` FROM owner_purges WHERE owner_id = $1 AND completed_at IS NULL`
+
+	// Незавершённый запрос может завершиться между INSERT и SELECT — тогда создаём заново
+	for range 2 {
+		var row purgeRow
+		err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, insert, ownerID, requestedBy)
+		if err == nil {
+			return row.purge()
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return purge.Purge{}, fmt.Errorf("create purge: %w", err)
+		}
+		err = sqlx.GetContext(ctx, conn(ctx, r.db), &row, existing, ownerID)
+		if err == nil {
+			return row.purge()
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return purge.Purge{}, fmt.Errorf("get active purge: %w", err)
+		}
+	}
+	return purge.Purge{}, fmt.Errorf("create purge: owner %s contended", ownerID)
+}
+
+// GetPurge — см. purge.Store
+func (r *PurgeRepo) GetPurge(ctx context.Context, id int64) (purge.Purge, error) {
+	var row purgeRow
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, `SELECT `+purgeColumns+` FROM owner_purges WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return purge.Purge{}, purge.ErrNotFound
+	}
+	if err != nil {
+		return purge.Purge{}, fmt.Errorf("get purge: %w", err)
+	}
+	return row.purge()
+}
+
+// UpdatePurge — см. purge.Store
+func (r *PurgeRepo) UpdatePurge(ctx context.Context, p purge.Purge) error {
+	report, err := json.Marshal(p.Report)
+	if err != nil {
+		return fmt.Errorf("update purge: %w", err)
+	}
+	const q = `
+		UPDATE owner_purges
+		SET status = $2, report = $3, last_error = $4, completed_at = $5, updated_at = now()
+		WHERE id = $1`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, p.ID, p.Status, report, p.LastError, p.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("update purge: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update purge: %w", err)
+	}
+	if n == 0 {
+		return purge.ErrNotFound
+	}
+	return nil
+}
+
+// OwnerMedia — см. purge.Store
+func (r *PurgeRepo) OwnerMedia(ctx context.Context, ownerID uuid.UUID, limit int) ([]models.Media, error) {
+	const q = `SELECT ` + mediaColumns + ` FROM media WHERE owner_id = $1 ORDER BY created_at, id LIMIT $2`
+
+	var out []models.Media
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, ownerID, limit); err != nil {
+		return nil, fmt.Errorf("owner media: %w", err)
+	}
+	return out, nil
+}
+
+// purgeStatement — запрос удаления и счётчик отчёта, в который идёт число удалённых строк (nil — не учитывается)
+type purgeStatement struct {
+	counter func(*purge.Report) *int64
+	query   string
+}
+
+// purgeMediaStatements — что удаляется вместе с медиа; $1 — text[] id медиа.
+// Строка media — последней: пока она есть, медиа остаётся в выборке OwnerMedia.
+var purgeMediaStatements = []purgeStatement{
+	{func(r *purge.Report) *int64 { return &r.Deliveries }, `DELETE FROM publish_deliveries WHERE message->'event'->>'media_id' = ANY($1::text[])`},
+	{func(r *purge.Report) *int64 { return &r.Outbox }, `DELETE FROM outbox WHERE aggregate_id = ANY($1::text[])`},
+	{func(r *purge.Report) *int64 { return &r.Outbox }, `DELETE FROM outbox_archive WHERE aggregate_id = ANY($1::text[])`},
+	{nil, `DELETE FROM aggregate_sequences WHERE aggregate_id = ANY($1::text[])`},
+	{func(r *purge.Report) *int64 { return &r.Events }, `DELETE FROM media_events WHERE aggregate_id = ANY($1::uuid[])`},
+	{func(r *purge.Report) *int64 { return &r.Events }, `DELETE FROM media_snapshots WHERE aggregate_id = ANY($1::uuid[])`},
+	{func(r *purge.Report) *int64 { return &r.StatusHistory }, `DELETE FROM media_status_history WHERE media_id = ANY($1::uuid[])`},
+	{nil, `DELETE FROM projection_media_status WHERE media_id = ANY($1::uuid[])`},
+	{func(r *purge.Report) *int64 { return &r.Media }, `DELETE FROM media WHERE id = ANY($1::uuid[])`}, // retention_policies, media_grants — ON DELETE CASCADE
+}
+
+// purgeOwnerStatements — записи самого владельца; $1 — owner_id. Доставки уведомлений о владельце
+// удаляются и те, что не привязаны к оставшимся медиа (например, о медиа, удалённых раньше).
+// Доступ, выданный владельцу к чужим медиа (media_grants), и сохранённые ответы на его запросы
+// с Idempotency-Key (в них данные медиа) тоже удаляются.
+// Коллекции — последними: по ним находятся события их потоков.
+var purgeOwnerStatements = []purgeStatement{
+	{func(r *purge.Report) *int64 { return &r.Deliveries }, `DELETE FROM publish_deliveries WHERE message->'event'->>'owner_id' = $1::text`},
+	{func(r *purge.Report) *int64 { return &r.OwnerRecords }, `DELETE FROM quota_owner_plans WHERE owner_id = $1::uuid`},
+	{func(r *purge.Report) *int64 { return &r.OwnerRecords }, `DELETE FROM quota_usage WHERE owner_id = $1::text`},
+	{func(r *purge.Report) *int64 { return &r.OwnerRecords }, `DELETE FROM projection_owner_usage WHERE owner_id = $1::uuid`},
+	{func(r *purge.Report) *int64 { return &r.OwnerRecords }, `DELETE FROM media_grants WHERE principal = $1::uuid`},
+	{func(r *purge.Report) *int64 { return &r.OwnerRecords }, `DELETE FROM idempotency_keys WHERE scope = $1::text`},
+	{func(r *purge.Report) *int64 { return &r.Outbox }, `DELETE FROM outbox WHERE aggregate_id IN (SELECT id::text FROM collections WHERE owner_id = $1::uuid)`},
+	{func(r *purge.Report) *int64 { return &r.Outbox }, `DELETE FROM outbox_archive WHERE aggregate_id IN (SELECT id::text FROM collections WHERE owner_id = $1::uuid)`},
+	{nil, `DELETE FROM aggregate_sequences WHERE aggregate_id IN (SELECT id::text FROM collections WHERE owner_id = $1::uuid)`},
+	{func(r *purge.Report) *int64 { return &r.Events }, `DELETE FROM media_events WHERE aggregate_id IN (SELECT id FROM collections WHERE owner_id = $1::uuid)`},
+	{func(r *purge.Report) *int64 { return &r.OwnerRecords }, `DELETE FROM collections WHERE owner_id = $1::uuid`}, // collection_items — ON DELETE CASCADE
+}
+
+// DeleteMedia — см. purge.Store
+func (r *PurgeRepo) DeleteMedia(ctx context.Context, ids []uuid.UUID) (purge.Report, error) {
+	args := make([]string, len(ids))
+	for i, id := range ids {
+		args[i] = id.String()
+	}
+	return r.exec(ctx, "purge media", purgeMediaStatements, args)
+}
+
+// DeleteOwner — см. purge.Store
+func (r *PurgeRepo) DeleteOwner(ctx context.Context, ownerID uuid.UUID) (purge.Report, error) {
+	return r.exec(ctx, "purge owner", purgeOwnerStatements, ownerID.String())
+}
+
+// exec выполняет statements в одной транзакции и собирает отчёт
+func (r *PurgeRepo) exec(ctx context.Context, op string, statements []purgeStatement, arg any) (purge.Report, error) {
+	var report purge.Report
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, st := range statements {
+			res, err := conn(ctx, r.db).ExecContext(ctx, st.query, arg)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if st.counter == nil {
+				continue
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			*st.counter(&report) += n
+		}
+		return nil
+	})
+	if err != nil {
+		return purge.Report{}, err
+	}
+	return report, nil
+}
@@ -0,0 +1,114 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/purge"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestPurgeRepo_Requests(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewPurgeRepo(db.DB)
+	owner := uuid.New()
+
+	first, err := repo.CreatePurge(ctx, owner, "admin@ops")
+	require.NoError(t, err)
+	require.Equal(t, purge.StatusPending, first.Status)
+	// Пока запрос не завершён, повторный возвращает его же
+	again, err := repo.CreatePurge(ctx, owner, "someone else")
+	require.NoError(t, err)
+	require.Equal(t, first.ID, again.ID)
+	require.Equal(t, "admin@ops", again.RequestedBy)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	first.Status, first.Report, first.CompletedAt = purge.StatusCompleted, purge.Report{Media: 3, Blobs: 3}, &now
+	require.NoError(t, repo.UpdatePurge(ctx, first))
+	got, err := repo.GetPurge(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, purge.StatusCompleted, got.Status)
+	require.Equal(t, purge.Report{Media: 3, Blobs: 3}, got.Report)
+	require.WithinDuration(t, now, *got.CompletedAt, time.Millisecond)
+
+	// После завершения создаётся новый запрос
+	next, err := repo.CreatePurge(ctx, owner, "admin@ops")
+	require.NoError(t, err)
+	require.NotEqual(t, first.ID, next.ID)
+
+	_, err = repo.GetPurge(ctx, 1_000_000)
+	require.ErrorIs(t, err, purge.ErrNotFound)
+	require.ErrorIs(t, repo.UpdatePurge(ctx, purge.Purge{ID: 1_000_000}), purge.ErrNotFound)
+}
+
+func TestPurgeRepo_DeletesOwnerData(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	media := postgres.NewMediaRepo(db.DB)
+	repo := postgres.NewPurgeRepo(db.DB)
+	owner, other := uuid.New(), uuid.New()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	create := func(owner uuid.UUID) *models.Media {
+		m := &models.Media{
+			ID: uuid.New(), Status: models.ReadyStatus, Type: models.Video, OwnerID: owner,
+			Source: "s3://media/" + uuid.NewString(), CreatedAt: now, UpdatedAt: now,
+		}
+		require.NoError(t, media.Create(ctx, m))
+		exec := func(q string, args ...any) {
+			_, err := db.DB.ExecContext(ctx, q, args...)
+			require.NoError(t, err)
+		}
+		exec(`INSERT INTO media_status_history (media_id, from_status, to_status, actor, changed_at) VALUES ($1, 'processing', 'ready', 'alice', now())`, m.ID)
+		exec(`INSERT INTO media_events (aggregate_id, sequence, event_id, event_type, schema_version, payload, occurred_at)
+			VALUES ($1, 1, $2, 'MediaCreated', 1, '{}', now())`, m.ID, uuid.NewString())
+		exec(`INSERT INTO outbox (event_id, event_type, aggregate_id, payload, occurred_at) VALUES ($1, 'MediaCreated', $2, '{}', now())`,
+			uuid.NewString(), m.ID.String())
+		exec(`INSERT INTO publish_deliveries (id, channel, subscription, event_id, event_type, attempt, message)
+			VALUES ($1, 'ops', 'all', $2, 'MediaCreated', 1, jsonb_build_object('event', jsonb_build_object('media_id', $3::text, 'owner_id', $4::text)))`,
+			uuid.New(), uuid.NewString(), m.ID.String(), owner.String())
+		return m
+	}
+	create(owner)
+	create(owner)
+	kept := create(other)
+	_, err := db.DB.ExecContext(ctx, `INSERT INTO quota_owner_plans (owner_id, plan) VALUES ($1, 'pro'), ($2, 'pro')`, owner, other)
+	require.NoError(t, err)
+	_, err = db.DB.ExecContext(ctx, `INSERT INTO quota_usage (owner_id, objects) VALUES ($1, 2), ($2, 1)`, owner.String(), other.String())
+	require.NoError(t, err)
+
+	batch, err := repo.OwnerMedia(ctx, owner, 10)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	report, err := repo.DeleteMedia(ctx, []uuid.UUID{batch[0].ID, batch[1].ID})
+	require.NoError(t, err)
+	require.Equal(t, purge.Report{Media: 2, Events: 2, Outbox: 2, StatusHistory: 2, Deliveries: 2}, report)
+	batch, err = repo.OwnerMedia(ctx, owner, 10)
+	require.NoError(t, err)
+	require.Empty(t, batch)
+
+	report, err = repo.DeleteOwner(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, purge.Report{OwnerRecords: 2}, report)
+
+	// Данные другого владельца не тронуты
+	_, err = media.GetByID(ctx, kept.ID)
+	require.NoError(t, err)
+	var left int
+	require.NoError(t, db.DB.GetContext(ctx, &left, `SELECT count(*) FROM publish_deliveries`))
+	require.Equal(t, 1, left)
+	require.NoError(t, db.DB.GetContext(ctx, &left, `SELECT count(*) FROM quota_owner_plans`))
+	require.Equal(t, 1, left)
+	require.NoError(t, db.DB.GetContext(ctx, &left, `SELECT count(*) FROM quota_usage`))
+	require.Equal(t, 1, left)
+}
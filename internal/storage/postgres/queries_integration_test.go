@@ -0,0 +1,32 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+// Каждый запрос репозиториев готовится на схеме после миграций: опечатка в колонке, колонка,
+// которой нет в схеме, или несовместимые типы параметров ловятся здесь, а не на первом вызове
+// метода в проде — в том числе у запросов, которые не проходят интеграционные тесты репозиториев.
+func TestQueries_PrepareAgainstSchema(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	queries := repositoryQueries(t)
+
+	conn, err := db.Pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	for _, q := range queries {
+		t.Run(q.fn, func(t *testing.T) {
+			_, err := conn.Conn().PgConn().Prepare(ctx, "", q.sql, nil)
+			require.NoError(t, err, "%s\n%s", q.pos, q.sql)
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package postgres_test
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/purge"
+)
+
+// query — текст запроса репозитория, собранный из исходников пакета
+type query struct {
+	fn  string // Тип.Метод или функция
+	pos string // файл:строка
+	sql string
+}
+
+// queryHints — значения частей запросов, которые из исходников не вычислить: параметры функций
+// и результаты вызовов (ключ — Тип.Метод.имя), константы других пакетов (ключ — пакет.Имя),
+// результаты функций пакета (ключ — имя функции).
+var queryHints = map[string]string{
+	"purge.StatusPending":               string(purge.StatusPending),
+	"statusList":                        "'" + string(models.ReadyStatus) + "'",
+	"JobsRepo.leased.set":               `scheduled_at = $3, last_error = $4, locked_until = NULL`,
+	"MediaRepo.List.column":             `created_at`,
+	"OutboxRepo.GetPending.shard":       ` AND (hashtext(aggregate_id) & 2147483647) % $2 = $3`,
+	"OutboxRepo.CountPending.shard":     ` AND (hashtext(aggregate_id) & 2147483647) % $1 = $2`,
+	"OutboxRepo.ListProcessed.where":    replayWhere,
+	"OutboxRepo.RequeueProcessed.where": replayWhere,
+}
+
+// queryFuncs — функции пакета со строковыми аргументами, которые строят части запросов
+var queryFuncs = map[string]func(args ...string) string{
+	// qualified(alias, columns)
+	"qualified": func(args ...string) string {
+		cols := strings.Split(args[1], ", ")
+		for i, c := range cols {
+			cols[i] = args[0] + "." + c
+		}
+		return strings.Join(cols, ", ")
+	},
+}
+
+// replayWhere — OutboxReplayFilter.where со всеми фильтрами
+const replayWhere = `processed_at IS NOT NULL AND aggregate_id = $1 AND event_type = $2 AND occurred_at >= $3 AND occurred_at < $4`
+
+// sqlStatement — начало строки, с которой начинается запрос
+var sqlStatement = regexp.MustCompile(`^(SELECT|INSERT|UPDATE|DELETE|WITH|EXPLAIN)\b`)
+
+// repositoryQueries собирает запросы пакета: строки, начинающиеся с SELECT, INSERT, UPDATE,
+// DELETE, WITH или EXPLAIN, вместе с тем, что к ним дописывается конкатенацией и fmt.Sprintf.
+// Константы и переменные подставляются по последнему присваиванию до места использования,
+// срезы для strings.Join — всеми append после него; %d в fmt.Sprintf становится следующим
+// номером параметра. DDL (CREATE, DROP) не собирается.
+func repositoryQueries(t *testing.T) []query {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+	p, ok := pkgs["postgres"]
+	require.True(t, ok, "package postgres not found")
+
+	// Константы и переменные пакета: запросы собираются и из них
+	globals := map[string]ast.Expr{}
+	var specs []*ast.ValueSpec
+	for _, f := range p.Files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || (gen.Tok != token.CONST && gen.Tok != token.VAR) {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				specs = append(specs, vs)
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						globals[name.Name] = vs.Values[i]
+					}
+				}
+			}
+		}
+	}
+
+	var (
+		out  []query
+		errs []error
+	)
+	collect := func(e *evaluator, n ast.Node) {
+		for _, root := range queryRoots(n) {
+			sql, err := e.query(root)
+			pos := fset.Position(root.Pos())
+			where := fmt.Sprintf("%s:%d", filepath.Base(pos.Filename), pos.Line)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", where, e.fn, err))
+				continue
+			}
+			out = append(out, query{fn: e.fn, pos: where, sql: sql})
+		}
+	}
+	for _, vs := range specs {
+		collect(&evaluator{fn: vs.Names[0].Name, globals: globals}, vs)
+	}
+	for _, f := range p.Files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				collect(&evaluator{fn: funcName(fn), globals: globals, assigns: assignments(fn.Body)}, fn.Body)
+			}
+		}
+	}
+	require.NoError(t, errors.Join(errs...), "add the missing parts to queryHints")
+	return out
+}
+
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// queryRoots — выражения, в которые входят строки запросов: строка вместе с конкатенацией
+// вокруг неё или вызов fmt.Sprintf, для которого она — формат
+func queryRoots(n ast.Node) []ast.Expr {
+	var (
+		stack []ast.Node
+		roots []ast.Expr
+		seen  = map[ast.Expr]bool{}
+	)
+	ast.Inspect(n, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if s, err := strconv.Unquote(lit.Value); err == nil && sqlStatement.MatchString(strings.TrimSpace(s)) {
+				root := ast.Expr(lit)
+			climb:
+				for i := len(stack) - 1; i >= 0; i-- {
+					switch parent := stack[i].(type) {
+					case *ast.BinaryExpr:
+						if parent.Op != token.ADD {
+							break climb
+						}
+						root = parent
+					case *ast.ParenExpr:
+						root = parent
+					case *ast.CallExpr:
+						if !isCall(parent, "fmt", "Sprintf") || parent.Args[0] != root {
+							break climb
+						}
+						root = parent
+					default:
+						break climb
+					}
+				}
+				if !seen[root] {
+					seen[root] = true
+					roots = append(roots, root)
+				}
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return roots
+}
+
+// assignment — значение переменной с позиции pos; append — добавленные в срез элементы
+type assignment struct {
+	pos    token.Pos
+	value  ast.Expr // nil — значение не вычисляется (кортеж из вызова, var без значения)
+	append []ast.Expr
+}
+
+// assignments — присваивания переменных функции в порядке исходника
+func assignments(body *ast.BlockStmt) map[string][]assignment {
+	out := map[string][]assignment{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				a := assignment{pos: name.Pos()}
+				if i < len(n.Values) {
+					a.value = n.Values[i]
+				}
+				out[name.Name] = append(out[name.Name], a)
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE && n.Tok != token.ASSIGN {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok || id.Name == "_" {
+					continue
+				}
+				a := assignment{pos: n.Pos()}
+				if len(n.Rhs) == len(n.Lhs) {
+					a.value = n.Rhs[i]
+					if call, ok := a.value.(*ast.CallExpr); ok && isBuiltin(call, "append") {
+						if base, ok := call.Args[0].(*ast.Ident); ok && base.Name == id.Name {
+							a.value, a.append = nil, call.Args[1:]
+						}
+					}
+				}
+				out[id.Name] = append(out[id.Name], a)
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// evaluator вычисляет строки запросов одной функции
+type evaluator struct {
+	fn      string
+	globals map[string]ast.Expr
+	assigns map[string][]assignment
+
+	params map[paramKey]int // номера параметров, выданные %d
+}
+
+type paramKey struct {
+	call *ast.CallExpr
+	arg  int
+}
+
+// placeholder — номер параметра из %d до перенумерации
+var placeholder = regexp.MustCompile(`\$\x00(\d+)\x00`)
+
+// literalParam — параметр, записанный в запросе
+var literalParam = regexp.MustCompile(`\$(\d+)`)
+
+// query вычисляет запрос; параметры из %d нумеруются после записанных в нём
+func (e *evaluator) query(root ast.Expr) (string, error) {
+	e.params = map[paramKey]int{}
+	s, err := e.str(root, root.Pos())
+	if err != nil {
+		return "", err
+	}
+	last := 0
+	for _, m := range literalParam.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(m[1])
+		last = max(last, n)
+	}
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		n, _ := strconv.Atoi(placeholder.FindStringSubmatch(m)[1])
+		return "$" + strconv.Itoa(last+n)
+	}), nil
+}
+
+func (e *evaluator) str(x ast.Expr, at token.Pos) (string, error) {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		if x.Kind != token.STRING {
+			return "", fmt.Errorf("%s is not a string", x.Value)
+		}
+		return strconv.Unquote(x.Value)
+	case *ast.ParenExpr:
+		return e.str(x.X, at)
+	case *ast.BinaryExpr:
+		if x.Op != token.ADD {
+			return "", fmt.Errorf("unsupported operator %s", x.Op)
+		}
+		l, err := e.str(x.X, at)
+		if err != nil {
+			return "", err
+		}
+		r, err := e.str(x.Y, at)
+		return l + r, err
+	case *ast.Ident:
+		if hint, ok := queryHints[e.fn+"."+x.Name]; ok {
+			return hint, nil
+		}
+		if a, ok := e.lastAssignment(x.Name, at); ok {
+			if a.value == nil {
+				return "", fmt.Errorf("value of %s is not known", x.Name)
+			}
+			return e.str(a.value, a.pos)
+		}
+		if g, ok := e.globals[x.Name]; ok {
+			return e.str(g, token.NoPos)
+		}
+		return "", fmt.Errorf("value of %s is not known", x.Name)
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); ok {
+			if hint, ok := queryHints[pkg.Name+"."+x.Sel.Name]; ok {
+				return hint, nil
+			}
+			return "", fmt.Errorf("value of %s.%s is not known", pkg.Name, x.Sel.Name)
+		}
+	case *ast.CallExpr:
+		switch {
+		case isBuiltin(x, "string") && len(x.Args) == 1:
+			return e.str(x.Args[0], at)
+		case isFunc(x):
+			name := x.Fun.(*ast.Ident).Name
+			if hint, ok := queryHints[name]; ok {
+				return hint, nil
+			}
+			if f, ok := queryFuncs[name]; ok {
+				args := make([]string, len(x.Args))
+				for i, arg := range x.Args {
+					s, err := e.str(arg, at)
+					if err != nil {
+						return "", err
+					}
+					args[i] = s
+				}
+				return f(args...), nil
+			}
+		case isCall(x, "fmt", "Sprintf"):
+			return e.sprintf(x, at)
+		case isCall(x, "strings", "Join"):
+			items, err := e.slice(x.Args[0], at)
+			if err != nil {
+				return "", err
+			}
+			sep, err := e.str(x.Args[1], at)
+			return strings.Join(items, sep), err
+		}
+	}
+	return "", fmt.Errorf("cannot evaluate %T", x)
+}
+
+// lastAssignment — последнее присваивание name до позиции at
+func (e *evaluator) lastAssignment(name string, at token.Pos) (assignment, bool) {
+	var (
+		last  assignment
+		found bool
+	)
+	for _, a := range e.assigns[name] {
+		if a.pos < at && a.append == nil {
+			last, found = a, true
+		}
+	}
+	return last, found
+}
+
+// slice — элементы среза строк: значение последнего присваивания и все append после него до at
+func (e *evaluator) slice(x ast.Expr, at token.Pos) ([]string, error) {
+	id, ok := x.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("cannot evaluate slice %T", x)
+	}
+	base, ok := e.lastAssignment(id.Name, at)
+	if !ok {
+		return nil, fmt.Errorf("slice %s is not declared", id.Name)
+	}
+	var items []string
+	if lit, ok := base.value.(*ast.CompositeLit); ok {
+		for _, el := range lit.Elts {
+			s, err := e.str(el, base.pos)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, s)
+		}
+	}
+	for _, a := range e.assigns[id.Name] {
+		if a.append == nil || a.pos < base.pos || a.pos > at {
+			continue
+		}
+		for _, el := range a.append {
+			s, err := e.str(el, a.pos)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, s)
+		}
+	}
+	return items, nil
+}
+
+// sprintf подставляет в формат строки (%s) и номера параметров (%d); %[n] — аргумент n
+func (e *evaluator) sprintf(call *ast.CallExpr, at token.Pos) (string, error) {
+	format, err := e.str(call.Args[0], at)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	arg := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if i < len(format) && format[i] == '[' {
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("bad format %q", format)
+			}
+			n, err := strconv.Atoi(format[i+1 : i+end])
+			if err != nil {
+				return "", fmt.Errorf("bad format %q", format)
+			}
+			arg = n - 1
+			i += end + 1
+		}
+		if i >= len(format) || arg+1 >= len(call.Args) {
+			return "", fmt.Errorf("bad format %q", format)
+		}
+		switch format[i] {
+		case 's':
+			s, err := e.str(call.Args[arg+1], at)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case 'd':
+			key := paramKey{call: call, arg: arg}
+			if _, ok := e.params[key]; !ok {
+				e.params[key] = len(e.params) + 1
+			}
+			fmt.Fprintf(&b, "\x00%d\x00", e.params[key])
+		default:
+			return "", fmt.Errorf("unsupported verb %%%c in %q", format[i], format)
+		}
+		arg++
+	}
+	return b.String(), nil
+}
+
+func isCall(call *ast.CallExpr, pkg, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == pkg && len(call.Args) > 0
+}
+
+// isFunc — вызов функции пакета
+func isFunc(call *ast.CallExpr) bool {
+	_, ok := call.Fun.(*ast.Ident)
+	return ok
+}
+
+func isBuiltin(call *ast.CallExpr, name string) bool {
+	id, ok := call.Fun.(*ast.Ident)
+	return ok && id.Name == name && len(call.Args) > 0
+}
+
+// Запросы собираются из каждого репозитория; сами запросы готовятся на схеме
+// в TestQueries_PrepareAgainstSchema
+func TestRepositoryQueries(t *testing.T) {
+	queries := repositoryQueries(t)
+
+	files, err := filepath.Glob("*_repo.go")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		found := false
+		for _, q := range queries {
+			found = found || strings.HasPrefix(q.pos, file+":")
+		}
+		require.True(t, found, "no queries found in %s", file)
+	}
+
+	byFn := map[string][]string{}
+	for _, q := range queries {
+		byFn[q.fn] = append(byFn[q.fn], q.sql)
+	}
+	// Пачка из fmt.Sprintf: параметры по порядку
+	require.Contains(t, byFn["AuditRepo.Record"][0], "($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11::jsonb)")
+	// Параметры из %d — после записанных в запросе, %[n] — один параметр
+	require.Contains(t, byFn["OutboxRepo.ListProcessed"][0], "id > $5")
+	require.Contains(t, byFn["OutboxRepo.ListProcessed"][0], "LIMIT $6")
+	// Срез пересобирается после values[:0]: у вставки outbox свои параметры
+	require.Len(t, byFn["OutboxRepo.AddBatch"], 2)
+	require.Contains(t, byFn["OutboxRepo.AddBatch"][1], "VALUES ($1, $2, $3, $4, $5, $6, $7)")
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/quota"
+)
+
+// QuotaPlansRepo хранит тарифы quota и их назначение владельцам (quota.PlanStore)
+type QuotaPlansRepo struct {
+	db *sqlx.DB
+}
+
+func NewQuotaPlansRepo(db *sqlx.DB) *QuotaPlansRepo {
+	return &QuotaPlansRepo{db: db}
+}
+
+// planRow — строка quota_plans; окно лимита загрузок хранится в секундах
+type planRow struct {
+	Name         string `db:"name"`
+	Rank         int    `db:"rank"`
+	MaxObjects   int64  `db:"max_objects"`
+	MaxBytes     int64  `db:"max_bytes"`
+	UploadLimit  int    `db:"upload_limit"`
+	UploadWindow int64  `db:"upload_window_seconds"`
+}
+
+func (r planRow) plan() quota.Plan {
+	return quota.Plan{
+		Name: r.Name,
+		Rank: r.Rank,
+		Limits: quota.Limits{
+			MaxObjects: r.MaxObjects,
+			MaxBytes:   r.MaxBytes,
+			Uploads:    quota.Limit{Uploads: r.UploadLimit, Window: time.Duration(r.UploadWindow) * time.Second},
+		},
+	}
+}
+
+const planColumns = `p.name, p.rank, p.max_objects, p.max_bytes, p.upload_limit, p.upload_window_seconds`
+
+// Plans — см. quota.PlanStore
+func (r *QuotaPlansRepo) Plans(ctx context.Context) ([]quota.Plan, error) {
+	const q = `SELECT ` + planColumns + ` FROM quota_plans p ORDER BY p.rank, p.name`
+
+	var rows []planRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q); err != nil {
+		return nil, fmt.Errorf("quota plans: %w", err)
+	}
+	out := make([]quota.Plan, len(rows))
+	for i, row := range rows {
+		out[i] = row.plan()
+	}
+	return out, nil
+}
+
+// OwnerPlan — см. quota.PlanStore. Владелец не uuid (общий пул) — всегда quota.DefaultPlan.
+func (r *QuotaPlansRepo) OwnerPlan(ctx context.Context, owner string) (quota.OwnerPlan, error) {
+	const q = `
+		WITH o AS (SELECT * FROM quota_owner_plans WHERE owner_id = $1)
+		SELECT ` + planColumns + `,
+		       o.max_objects AS override_max_objects, o.max_bytes AS override_max_bytes,
+		       o.upload_limit AS override_upload_limit,
+		       EXISTS (SELECT 1 FROM quota_plans h WHERE h.rank > p.rank) AS upgradable
+		FROM quota_plans p
+		LEFT JOIN o ON true
+		WHERE p.name = coalesce(o.plan, $2)`
+
+	id, _ := uuid.Parse(owner)
+	var row struct {
+		planRow
+		MaxObjects  sql.NullInt64 `db:"override_max_objects"`
+		MaxBytes    sql.NullInt64 `db:"override_max_bytes"`
+		UploadLimit sql.NullInt32 `db:"override_upload_limit"`
+		Upgradable  bool          `db:"upgradable"`
+	}
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, q, nullUUID(id), quota.DefaultPlan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return quota.OwnerPlan{}, fmt.Errorf("%w: plan %q", models.ErrNotFound, quota.DefaultPlan)
+	}
+	if err != nil {
+		return quota.OwnerPlan{}, fmt.Errorf("quota owner plan: %w", err)
+	}
+
+	op := quota.OwnerPlan{Owner: owner, Plan: row.plan(), Upgradable: row.Upgradable}
+	if row.MaxObjects.Valid {
+		op.Override.MaxObjects = &row.MaxObjects.Int64
+	}
+	if row.MaxBytes.Valid {
+		op.Override.MaxBytes = &row.MaxBytes.Int64
+	}
+	if row.UploadLimit.Valid {
+		limit := int(row.UploadLimit.Int32)
+		op.Override.UploadLimit = &limit
+	}
+	return op, nil
+}
+
+// AssignPlan — см. quota.PlanStore
+func (r *QuotaPlansRepo) AssignPlan(ctx context.Context, owner, plan string) error {
+	id, err := parseOwner(owner)
+	if err != nil {
+		return err
+	}
+	// INSERT ... SELECT ничего не вставит, если тарифа нет: это отличает его от ошибки FK
+	const q = `
+		INSERT INTO quota_owner_plans (owner_id, plan)
+		SELECT $1, name FROM quota_plans WHERE name = $2
+		ON CONFLICT (owner_id) DO UPDATE SET plan = EXCLUDED.plan, updated_at = now()`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, id, plan)
+	if err != nil {
+		return fmt.Errorf("quota assign plan: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("quota assign plan: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: plan %q", models.ErrNotFound, plan)
+	}
+	return nil
+}
+
+// SetOverride — см. quota.PlanStore. Владелец без тарифа получает строку с quota.DefaultPlan.
+func (r *QuotaPlansRepo) SetOverride(ctx context.Context, owner string, o quota.Override) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	id, err := parseOwner(owner)
+	if err != nil {
+		return err
+	}
+	const q = `
+		INSERT INTO quota_owner_plans (owner_id, plan, max_objects, max_bytes, upload_limit)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (owner_id) DO UPDATE SET
+			max_objects = EXCLUDED.max_objects,
+			max_bytes = EXCLUDED.max_bytes,
+			upload_limit = EXCLUDED.upload_limit,
+			updated_at = now()`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, id, quota.DefaultPlan, o.MaxObjects, o.MaxBytes, o.UploadLimit); err != nil {
+		return fmt.Errorf("quota set override: %w", err)
+	}
+	return nil
+}
+
+func parseOwner(owner string) (uuid.UUID, error) {
+	id, err := uuid.Parse(owner)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid owner id %q", models.ErrInvalidArgument, owner)
+	}
+	return id, nil
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/quota"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestQuotaPlansRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewQuotaPlansRepo(db.DB)
+
+	// Схема заводит те же тарифы, что и quota в памяти
+	plans, err := repo.Plans(ctx)
+	require.NoError(t, err)
+	require.Equal(t, quota.DefaultPlans, plans)
+
+	owner := uuid.NewString()
+	op, err := repo.OwnerPlan(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, quota.DefaultPlan, op.Plan.Name)
+	require.True(t, op.Upgradable)
+	require.True(t, op.Override.IsZero())
+
+	// Общий пул без владельца — тариф по умолчанию
+	op, err = repo.OwnerPlan(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, quota.DefaultPlan, op.Plan.Name)
+
+	// Overrides до назначения тарифа: владелец остаётся на тарифе по умолчанию
+	objects, limit := int64(7), 3
+	require.NoError(t, repo.SetOverride(ctx, owner, quota.Override{MaxObjects: &objects, UploadLimit: &limit}))
+	op, err = repo.OwnerPlan(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, quota.DefaultPlan, op.Plan.Name)
+	require.Equal(t, int64(7), op.Limits().MaxObjects)
+	require.Equal(t, 3, op.Limits().Uploads.Uploads)
+	require.Nil(t, op.Override.MaxBytes)
+
+	require.ErrorIs(t, repo.AssignPlan(ctx, owner, "platinum"), models.ErrNotFound)
+	require.NoError(t, repo.AssignPlan(ctx, owner, "enterprise"))
+	op, err = repo.OwnerPlan(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, "enterprise", op.Plan.Name)
+	require.False(t, op.Upgradable)
+	require.Equal(t, int64(7), op.Limits().MaxObjects, "plan change keeps overrides")
+
+	require.NoError(t, repo.SetOverride(ctx, owner, quota.Override{}))
+	op, err = repo.OwnerPlan(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, "enterprise", op.Plan.Name)
+	require.True(t, op.Override.IsZero())
+
+	require.ErrorIs(t, repo.AssignPlan(ctx, "not-a-uuid", "pro"), models.ErrInvalidArgument)
+}
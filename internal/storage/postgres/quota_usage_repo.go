@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/events/inbox"
+	"github.com/romariotrain/media-platform/internal/quota"
+)
+
+// QuotaUsageConsumer — consumer, под которым QuotaUsageRepo отмечает учтённые события в processed_events
+const QuotaUsageConsumer = "quota-usage"
+
+// QuotaUsageRepo хранит usage владельцев в quota_usage (quota.UsageStore). Учтённые события
+// отмечаются в processed_events (inbox) той же транзакцией, что и корректировка: повторная
+// доставка не меняет usage, а отметки чистит inbox.Cleaner.
+type QuotaUsageRepo struct {
+	db    *sqlx.DB
+	inbox *inbox.Store
+}
+
+func NewQuotaUsageRepo(db *sqlx.DB) *QuotaUsageRepo {
+	return &QuotaUsageRepo{db: db, inbox: inbox.NewStore(db)}
+}
+
+// Apply — см. quota.UsageStore
+func (r *QuotaUsageRepo) Apply(ctx context.Context, adj quota.Adjustment) (applied bool, err error) {
+	if adj.EventID == "" {
+		return false, errors.New("adjustment without event id")
+	}
+	tx, err := r.inbox.BeginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil || !applied {
+			_ = tx.Rollback()
+		}
+	}()
+
+	first, err := r.inbox.MarkProcessedTx(ctx, tx, QuotaUsageConsumer, adj.EventID)
+	if err != nil || !first {
+		return false, err
+	}
+	const q = `
+		INSERT INTO quota_usage (owner_id, objects, bytes, updated_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (owner_id) DO UPDATE
+		SET objects = quota_usage.objects + EXCLUDED.objects,
+		    bytes = quota_usage.bytes + EXCLUDED.bytes,
+		    updated_at = now()`
+	if _, err = tx.ExecContext(ctx, q, adj.Owner, adj.Objects, adj.Bytes); err != nil {
+		return false, fmt.Errorf("apply usage: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	return true, nil
+}
+
+type usageRow struct {
+	Owner   string `db:"owner_id"`
+	Objects int64  `db:"objects"`
+	Bytes   int64  `db:"bytes"`
+}
+
+// Usage — см. quota.UsageStore
+func (r *QuotaUsageRepo) Usage(ctx context.Context) (map[string]quota.Usage, error) {
+	const q = `SELECT owner_id, objects, bytes FROM quota_usage`
+
+	var rows []usageRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q); err != nil {
+		return nil, fmt.Errorf("quota usage: %w", err)
+	}
+	out := make(map[string]quota.Usage, len(rows))
+	for _, row := range rows {
+		out[row.Owner] = quota.Usage{Objects: row.Objects, Bytes: row.Bytes}
+	}
+	return out, nil
+}
+
+// OwnerUsage — см. quota.UsageStore
+func (r *QuotaUsageRepo) OwnerUsage(ctx context.Context, owner string) (quota.Usage, error) {
+	const q = `SELECT owner_id, objects, bytes FROM quota_usage WHERE owner_id = $1`
+
+	var rows []usageRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q, owner); err != nil {
+		return quota.Usage{}, fmt.Errorf("quota owner usage: %w", err)
+	}
+	if len(rows) == 0 {
+		return quota.Usage{}, nil
+	}
+	return quota.Usage{Objects: rows[0].Objects, Bytes: rows[0].Bytes}, nil
+}
+
+// Set — см. quota.UsageStore
+func (r *QuotaUsageRepo) Set(ctx context.Context, owner string, usage quota.Usage) error {
+	const q = `
+		INSERT INTO quota_usage (owner_id, objects, bytes, updated_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (owner_id) DO UPDATE
+		SET objects = EXCLUDED.objects, bytes = EXCLUDED.bytes, updated_at = now()`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, q, owner, usage.Objects, usage.Bytes); err != nil {
+		return fmt.Errorf("set quota usage: %w", err)
+	}
+	return nil
+}
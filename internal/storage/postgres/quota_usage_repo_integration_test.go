@@ -0,0 +1,63 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events/inbox"
+	"github.com/romariotrain/media-platform/internal/quota"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestQuotaUsageRepo(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	repo := postgres.NewQuotaUsageRepo(db.DB)
+	owner := uuid.NewString()
+
+	u, err := repo.OwnerUsage(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, quota.Usage{}, u)
+
+	applied, err := repo.Apply(ctx, quota.Adjustment{EventID: "e1", Owner: owner, Objects: 1})
+	require.NoError(t, err)
+	require.True(t, applied)
+	applied, err = repo.Apply(ctx, quota.Adjustment{EventID: "e2", Owner: owner, Bytes: 500})
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	// Повторная доставка того же события не меняет usage
+	applied, err = repo.Apply(ctx, quota.Adjustment{EventID: "e1", Owner: owner, Objects: 1})
+	require.NoError(t, err)
+	require.False(t, applied)
+
+	u, err = repo.OwnerUsage(ctx, owner)
+	require.NoError(t, err)
+	require.Equal(t, quota.Usage{Objects: 1, Bytes: 500}, u)
+
+	// Сверка выставляет usage целиком, общий пул — под пустым владельцем
+	require.NoError(t, repo.Set(ctx, owner, quota.Usage{Objects: 3, Bytes: 900}))
+	_, err = repo.Apply(ctx, quota.Adjustment{EventID: "e3", Objects: 1})
+	require.NoError(t, err)
+	all, err := repo.Usage(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]quota.Usage{owner: {Objects: 3, Bytes: 900}, "": {Objects: 1}}, all)
+
+	// Отметки старше TTL удаляет inbox.Cleaner — дальше событие снова учитывается
+	deleted, err := inbox.NewStore(db.DB).DeleteProcessedBefore(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), deleted)
+	applied, err = repo.Apply(ctx, quota.Adjustment{EventID: "e1", Owner: owner, Objects: 1})
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	_, err = repo.Apply(ctx, quota.Adjustment{Owner: owner, Objects: 1})
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/processing/packaging"
+)
+
+// RenditionsRepo — renditions упакованных медиа (packaging.Registry) в таблице media_renditions
+type RenditionsRepo struct {
+	db *sqlx.DB
+}
+
+func NewRenditionsRepo(db *sqlx.DB) *RenditionsRepo {
+	return &RenditionsRepo{db: db}
+}
+
+// ReplaceRenditions в одной транзакции удаляет renditions медиа и записывает новые
+func (r *RenditionsRepo) ReplaceRenditions(ctx context.Context, mediaID uuid.UUID, renditions []packaging.Stored) error {
+	const del = `DELETE FROM media_renditions WHERE media_id = $1`
+	const insert = `
+        INSERT INTO media_renditions (media_id, name, uri, bandwidth, width, height, codecs)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	err := NewTxManager(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if _, err := db.ExecContext(ctx, del, mediaID); err != nil {
+			return err
+		}
+		for _, s := range renditions {
+			if _, err := db.ExecContext(ctx, insert, mediaID, s.Name, s.URI, s.Bandwidth, s.Width, s.Height, s.Codecs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("replace renditions: %w", err)
+	}
+	return nil
+}
+
+// ListRenditions возвращает renditions медиа по имени
+func (r *RenditionsRepo) ListRenditions(ctx context.Context, mediaID uuid.UUID) ([]packaging.Stored, error) {
+	const q = `
+        SELECT media_id, name, uri, bandwidth, width, height, codecs
+        FROM media_renditions
+        WHERE media_id = $1
+        ORDER BY name`
+
+	var out []packaging.Stored
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, mediaID); err != nil {
+		return nil, fmt.Errorf("list renditions: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/processing/packaging"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestRenditionsRepo_Replace(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	media := postgres.NewMediaRepo(db.DB)
+	repo := postgres.NewRenditionsRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	m := &models.Media{ID: uuid.New(), Status: models.ReadyStatus, Type: models.Video, Source: "s3://media/in/1.mp4", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, media.Create(ctx, m))
+
+	root := "s3://media/packaged/" + m.ID.String()
+	first := []packaging.Stored{
+		{MediaID: m.ID, Name: packaging.MasterRendition, URI: root + "/master.m3u8", Bandwidth: 2_500_000},
+		{MediaID: m.ID, Name: "360p", URI: root + "/360p/index.m3u8", Bandwidth: 800_000, Width: 640, Height: 360},
+		{MediaID: m.ID, Name: "720p", URI: root + "/720p/index.m3u8", Bandwidth: 2_500_000, Width: 1280, Height: 720, Codecs: "avc1.64001f,mp4a.40.2"},
+	}
+	require.NoError(t, repo.ReplaceRenditions(ctx, m.ID, first))
+	got, err := repo.ListRenditions(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, []packaging.Stored{first[1], first[2], first[0]}, got)
+
+	// Повторная упаковка не оставляет старых renditions
+	second := []packaging.Stored{first[0], first[2]}
+	require.NoError(t, repo.ReplaceRenditions(ctx, m.ID, second))
+	got, err = repo.ListRenditions(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, []packaging.Stored{first[2], first[0]}, got)
+
+	// Renditions несуществующего медиа не регистрируются
+	require.Error(t, repo.ReplaceRenditions(ctx, uuid.New(), first))
+
+	got, err = repo.ListRenditions(ctx, uuid.New())
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// ReplicaConfig содержит настройки реплики для чтения
+type ReplicaConfig struct {
+	DB       *sqlx.DB
+	Cooldown time.Duration // Сколько не ходить в реплику после сбоя (default: 30s)
+	Logger   zerolog.Logger
+}
+
+// Replica — реплика для чтения с автоматическим откатом на primary.
+// После ошибки соединения реплика считается недоступной Cooldown, затем пробуется снова.
+type Replica struct {
+	db        *sqlx.DB
+	cooldown  time.Duration
+	downUntil atomic.Int64 // unix nano; 0 — реплика доступна
+	fallbacks atomic.Int64
+	now       func() time.Time
+	logger    zerolog.Logger
+}
+
+func NewReplica(cfg ReplicaConfig) (*Replica, error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("replica db is required")
+	}
+	if cfg.Cooldown < 0 {
+		return nil, fmt.Errorf("cooldown cannot be negative, got: %v", cfg.Cooldown)
+	}
+	if cfg.Cooldown == 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+
+	return &Replica{
+		db:       cfg.DB,
+		cooldown: cfg.Cooldown,
+		now:      time.Now,
+		logger:   cfg.Logger.With().Str("component", "pg_replica").Logger(),
+	}, nil
+}
+
+// Fallbacks возвращает число чтений, ушедших в primary из-за сбоя реплики
+func (r *Replica) Fallbacks() int64 { return r.fallbacks.Load() }
+
+func (r *Replica) available() bool {
+	until := r.downUntil.Load()
+	return until == 0 || r.now().UnixNano() >= until
+}
+
+func (r *Replica) markDown(err error) {
+	r.downUntil.Store(r.now().Add(r.cooldown).UnixNano())
+	r.fallbacks.Add(1)
+	r.logger.Warn().Err(err).Dur("cooldown", r.cooldown).Msg("replica unavailable, reading from primary")
+}
+
+// replicaUnavailable отличает недоступность реплики от ошибок самого запроса.
+// Ответ сервера (PgError) означает, что реплика жива, кроме классов 08 (connection exception)
+// и 57 (operator intervention: shutdown, cannot_connect_now).
+func replicaUnavailable(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57")
+	}
+	return true
+}
+
+// read выполняет чтение на реплике, если это допустимо, иначе — на primary или в текущей транзакции.
+// Без реплики, внутри транзакции и с repository.WithReadPrimary чтение идёт в primary.
+func read(ctx context.Context, primary *sqlx.DB, replica *Replica, fn func(q sqlx.QueryerContext) error) error {
+	if _, inTx := txFromContext(ctx); inTx || replica == nil || repository.ReadPrimary(ctx) || !replica.available() {
+		return fn(conn(ctx, primary))
+	}
+
+	err := fn(replica.db)
+	if !replicaUnavailable(ctx, err) {
+		return err
+	}
+	replica.markDown(err)
+	return fn(primary)
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	require.False(t, replicaUnavailable(ctx, nil))
+	require.False(t, replicaUnavailable(ctx, sql.ErrNoRows))
+	require.False(t, replicaUnavailable(ctx, &pgconn.PgError{Code: "42P01"}))
+	require.True(t, replicaUnavailable(ctx, &pgconn.PgError{Code: "57P03"}))
+	require.True(t, replicaUnavailable(ctx, &pgconn.PgError{Code: "08006"}))
+	require.True(t, replicaUnavailable(ctx, errors.New("dial tcp: connection refused")))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.False(t, replicaUnavailable(cancelled, context.Canceled))
+}
+
+func TestReplica_CooldownAfterFailure(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r, err := NewReplica(ReplicaConfig{DB: &sqlx.DB{}, Cooldown: 10 * time.Second})
+	require.NoError(t, err)
+	r.now = func() time.Time { return now }
+
+	require.True(t, r.available())
+
+	r.markDown(errors.New("connection refused"))
+	require.False(t, r.available())
+	require.Equal(t, int64(1), r.Fallbacks())
+
+	now = now.Add(10 * time.Second)
+	require.True(t, r.available())
+}
+
+func TestNewReplica_Validation(t *testing.T) {
+	_, err := NewReplica(ReplicaConfig{})
+	require.Error(t, err)
+
+	_, err = NewReplica(ReplicaConfig{DB: &sqlx.DB{}, Cooldown: -time.Second})
+	require.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/retention"
+)
+
+// RetentionRepo хранит политики retention и находит медиа с истёкшим сроком
+type RetentionRepo struct {
+	db *sqlx.DB
+}
+
+func NewRetentionRepo(db *sqlx.DB) *RetentionRepo {
+	return &RetentionRepo{db: db}
+}
+
+// policyRow — строка retention_policies; срок хранится в секундах
+type policyRow struct {
+	ID        int64            `db:"id"`
+	MediaID   uuid.UUID        `db:"media_id"`
+	MediaType models.MediaType `db:"media_type"`
+	RetainFor int64            `db:"retain_for_seconds"`
+	Action    retention.Action `db:"action"`
+	CreatedAt time.Time        `db:"created_at"`
+}
+
+func (r policyRow) policy() retention.Policy {
+	return retention.Policy{
+		ID:        r.ID,
+		MediaID:   r.MediaID,
+		MediaType: r.MediaType,
+		RetainFor: time.Duration(r.RetainFor) * time.Second,
+		Action:    r.Action,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+const policyColumns = `id, media_id, coalesce(media_type, '') AS media_type, retain_for_seconds, action, created_at`
+
+// SetPolicy создаёт политику или заменяет политику того же медиа/типа
+func (r *RetentionRepo) SetPolicy(ctx context.Context, p retention.Policy) (retention.Policy, error) {
+	if err := p.Validate(); err != nil {
+		return retention.Policy{}, err
+	}
+
+	// Частичные уникальные индексы: ON CONFLICT должен назвать тот, который проверяется
+	target := `(media_type) WHERE media_type IS NOT NULL`
+	if p.MediaID != uuid.Nil {
+		target = `(media_id) WHERE media_id IS NOT NULL`
+	}
+	q := `
+		INSERT INTO retention_policies (media_id, media_type, retain_for_seconds, action)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		ON CONFLICT ` + target + `
+		DO UPDATE SET retain_for_seconds = EXCLUDED.retain_for_seconds, action = EXCLUDED.action
+		RETURNING ` + policyColumns
+
+	var row policyRow
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, q,
+		nullUUID(p.MediaID), string(p.MediaType), int64(p.RetainFor.Round(time.Second)/time.Second), p.Action,
+	)
+	if err != nil {
+		return retention.Policy{}, fmt.Errorf("retention set policy: %w", err)
+	}
+	return row.policy(), nil
+}
+
+// ListPolicies возвращает все политики: сначала политики типов, потом отдельных медиа
+func (r *RetentionRepo) ListPolicies(ctx context.Context) ([]retention.Policy, error) {
+	const q = `SELECT ` + policyColumns + ` FROM retention_policies ORDER BY media_id NULLS FIRST, media_type, id`
+
+	var rows []policyRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, q); err != nil {
+		return nil, fmt.Errorf("retention list policies: %w", err)
+	}
+	out := make([]retention.Policy, len(rows))
+	for i, row := range rows {
+		out[i] = row.policy()
+	}
+	return out, nil
+}
+
+// DeletePolicy удаляет политику; retention.ErrPolicyNotFound, если её нет
+func (r *RetentionRepo) DeletePolicy(ctx context.Context, id int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM retention_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("retention delete policy: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("retention delete policy: %w", err)
+	}
+	if n == 0 {
+		return retention.ErrPolicyNotFound
+	}
+	return nil
+}
+
+// dueQuery выбирает для каждого медиа самую конкретную политику (медиа важнее типа)
+// и оставляет медиа, у которых срок истёк и статус подходит для действия
+var dueQuery = `
+	SELECT ` + qualified("m", mediaColumns) + `,
+	       p.id AS policy_id, p.media_id AS policy_media_id, coalesce(p.media_type, '') AS policy_media_type,
+	       p.retain_for_seconds AS policy_retain_for_seconds, p.action AS policy_action, p.created_at AS policy_created_at
+	FROM media m
+	JOIN LATERAL (
+		SELECT * FROM retention_policies rp
+		WHERE rp.media_id = m.id OR (rp.media_id IS NULL AND rp.media_type = m.type)
+		ORDER BY rp.media_id IS NULL
+		LIMIT 1
+	) p ON true
+	WHERE m.created_at + make_interval(secs => p.retain_for_seconds) <= $1
+	  AND ((p.action = 'archive' AND m.status IN (` + statusList(retention.ActionArchive.EligibleStatuses()) + `))
+	    OR (p.action = 'delete' AND m.status IN (` + statusList(retention.ActionDelete.EligibleStatuses()) + `)))
+	ORDER BY m.created_at, m.id
+	LIMIT $2
+`
+
+// Due — см. retention.DueStore
+func (r *RetentionRepo) Due(ctx context.Context, now time.Time, limit int) ([]retention.Candidate, error) {
+	var rows []struct {
+		models.Media
+		PolicyID        int64            `db:"policy_id"`
+		PolicyMediaID   uuid.UUID        `db:"policy_media_id"`
+		PolicyMediaType models.MediaType `db:"policy_media_type"`
+		PolicyRetainFor int64            `db:"policy_retain_for_seconds"`
+		PolicyAction    retention.Action `db:"policy_action"`
+		PolicyCreatedAt time.Time        `db:"policy_created_at"`
+	}
+	if err := sqlx.SelectContext(ctx, r.db, &rows, dueQuery, now, limit); err != nil {
+		return nil, fmt.Errorf("retention due: %w", err)
+	}
+
+	out := make([]retention.Candidate, len(rows))
+	for i, row := range rows {
+		out[i] = retention.Candidate{
+			Media: row.Media,
+			Policy: policyRow{
+				ID:        row.PolicyID,
+				MediaID:   row.PolicyMediaID,
+				MediaType: row.PolicyMediaType,
+				RetainFor: row.PolicyRetainFor,
+				Action:    row.PolicyAction,
+				CreatedAt: row.PolicyCreatedAt,
+			}.policy(),
+		}
+	}
+	return out, nil
+}
+
+// SourceShared — см. retention.DueStore
+func (r *RetentionRepo) SourceShared(ctx context.Context, m models.Media) (bool, error) {
+	var shared bool
+	const q = `SELECT EXISTS (SELECT 1 FROM media WHERE source = $1 AND id <> $2)`
+	if err := sqlx.GetContext(ctx, r.db, &shared, q, m.Source, m.ID); err != nil {
+		return false, fmt.Errorf("retention shared source: %w", err)
+	}
+	return shared, nil
+}
+
+// qualified добавляет к каждой колонке списка алиас таблицы: "id, status" → "m.id, m.status"
+func qualified(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+// statusList — SQL литерал списка статусов для IN (...); статусы — константы models
+func statusList(statuses []models.Status) string {
+	quoted := make([]string, len(statuses))
+	for i, s := range statuses {
+		quoted[i] = "'" + string(s) + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
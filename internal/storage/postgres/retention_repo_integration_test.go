@@ -0,0 +1,84 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/retention"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestRetentionRepo_Due(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	media := postgres.NewMediaRepo(db.DB)
+	repo := postgres.NewRetentionRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	create := func(typ models.MediaType, status models.Status, age time.Duration) *models.Media {
+		m := &models.Media{
+			ID:        uuid.New(),
+			Status:    status,
+			Type:      typ,
+			Source:    "s3://media/" + uuid.NewString(),
+			CreatedAt: now.Add(-age),
+			UpdatedAt: now.Add(-age),
+		}
+		require.NoError(t, media.Create(ctx, m))
+		return m
+	}
+
+	oldVideo := create(models.Video, models.ReadyStatus, 48*time.Hour)
+	pinned := create(models.Video, models.ReadyStatus, 48*time.Hour)
+	create(models.Video, models.ReadyStatus, time.Hour)         // срок не истёк
+	create(models.Video, models.ProcessingStatus, 48*time.Hour) // в обработке не архивируется
+	create(models.Audio, models.UploadedStatus, 48*time.Hour)   // для аудио политики нет
+
+	_, err := repo.SetPolicy(ctx, retention.Policy{MediaType: models.Video, RetainFor: time.Hour, Action: retention.ActionDelete})
+	require.NoError(t, err)
+	// Повторная установка заменяет политику типа, а не добавляет вторую
+	videoPolicy, err := repo.SetPolicy(ctx, retention.Policy{MediaType: models.Video, RetainFor: 24 * time.Hour, Action: retention.ActionArchive})
+	require.NoError(t, err)
+	// Политика медиа важнее политики типа
+	_, err = repo.SetPolicy(ctx, retention.Policy{MediaID: pinned.ID, RetainFor: 365 * 24 * time.Hour, Action: retention.ActionDelete})
+	require.NoError(t, err)
+
+	policies, err := repo.ListPolicies(ctx)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+	require.Equal(t, models.Video, policies[0].MediaType)
+	require.Equal(t, 24*time.Hour, policies[0].RetainFor)
+	require.Equal(t, pinned.ID, policies[1].MediaID)
+
+	due, err := repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "only the old ready video without its own policy")
+	require.Equal(t, oldVideo.ID, due[0].Media.ID)
+	require.Equal(t, videoPolicy.ID, due[0].Policy.ID)
+	require.Equal(t, retention.ActionArchive, due[0].Policy.Action)
+
+	require.NoError(t, repo.DeletePolicy(ctx, videoPolicy.ID))
+	require.ErrorIs(t, repo.DeletePolicy(ctx, videoPolicy.ID), retention.ErrPolicyNotFound)
+	due, err = repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Empty(t, due)
+
+	// Медиа, сославшееся на исходник oldVideo при дедупликации загрузки
+	shared, err := repo.SourceShared(ctx, *oldVideo)
+	require.NoError(t, err)
+	require.False(t, shared)
+	copied := create(models.Video, models.UploadedStatus, time.Minute)
+	_, err = media.Update(ctx, copied.ID, models.MediaPatch{Source: &oldVideo.Source})
+	require.NoError(t, err)
+	shared, err = repo.SourceShared(ctx, *oldVideo)
+	require.NoError(t, err)
+	require.True(t, shared)
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/schedule"
+)
+
+// ScheduleRepo находит медиа, расписание публикации которых наступило
+type ScheduleRepo struct {
+	db *sqlx.DB
+}
+
+func NewScheduleRepo(db *sqlx.DB) *ScheduleRepo {
+	return &ScheduleRepo{db: db}
+}
+
+var _ schedule.DueStore = (*ScheduleRepo)(nil)
+
+// DuePublish — см. schedule.DueStore; идёт по idx_media_publish_due
+func (r *ScheduleRepo) DuePublish(ctx context.Context, now time.Time, limit int) ([]models.Media, error) {
+	const q = `
+		SELECT ` + mediaColumns + `
+		FROM media
+		WHERE status = 'scheduled'
+		  AND (publish_at IS NULL OR publish_at <= $1)
+		  AND (expires_at IS NULL OR expires_at > $1)
+		ORDER BY publish_at NULLS FIRST, id
+		LIMIT $2
+	`
+	var out []models.Media
+	if err := sqlx.SelectContext(ctx, r.db, &out, q, now, limit); err != nil {
+		return nil, fmt.Errorf("schedule due publish: %w", err)
+	}
+	return out, nil
+}
+
+// dueExpiryQuery — медиа, которые ExpireMedia может архивировать; идёт по idx_media_expires_at
+var dueExpiryQuery = `
+	SELECT ` + mediaColumns + `
+	FROM media
+	WHERE expires_at <= $1
+	  AND status IN (` + statusList([]models.Status{models.ReadyStatus, models.ScheduledStatus, models.FailedStatus}) + `)
+	ORDER BY expires_at, id
+	LIMIT $2
+`
+
+// DueExpiry — см. schedule.DueStore
+func (r *ScheduleRepo) DueExpiry(ctx context.Context, now time.Time, limit int) ([]models.Media, error) {
+	var out []models.Media
+	if err := sqlx.SelectContext(ctx, r.db, &out, dueExpiryQuery, now, limit); err != nil {
+		return nil, fmt.Errorf("schedule due expiry: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,62 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+	"github.com/romariotrain/media-platform/internal/testutil"
+)
+
+func TestScheduleRepo_Due(t *testing.T) {
+	db := testutil.StartPostgres(t)
+	ctx := context.Background()
+	media := postgres.NewMediaRepo(db.DB)
+	repo := postgres.NewScheduleRepo(db.DB)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	create := func(status models.Status, sched models.Schedule) *models.Media {
+		m := &models.Media{ID: uuid.New(), Status: status, Type: models.Video, Source: "s3://media/" + uuid.NewString(), CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, media.Create(ctx, m))
+		updated, err := media.Update(ctx, m.ID, models.MediaPatch{Schedule: &sched})
+		require.NoError(t, err)
+		return updated
+	}
+
+	publish := create(models.ScheduledStatus, models.Schedule{PublishAt: at(-time.Minute)})
+	create(models.ScheduledStatus, models.Schedule{PublishAt: at(time.Hour)})                                          // эмбарго ещё действует
+	bothDue := create(models.ScheduledStatus, models.Schedule{PublishAt: at(-time.Hour), ExpiresAt: at(-time.Minute)}) // сначала архивируется
+	expire := create(models.ReadyStatus, models.Schedule{ExpiresAt: at(-2 * time.Minute)})
+	create(models.ReadyStatus, models.Schedule{ExpiresAt: at(time.Hour)})
+	create(models.ProcessingStatus, models.Schedule{ExpiresAt: at(-time.Minute)}) // в обработке не архивируется
+
+	// publish_at и expires_at читаются обратно
+	require.Equal(t, now.Add(-time.Minute), publish.PublishAt.UTC())
+	require.Nil(t, publish.ExpiresAt)
+
+	due, err := repo.DuePublish(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, publish.ID, due[0].ID)
+
+	due, err = repo.DueExpiry(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	require.Equal(t, expire.ID, due[0].ID)
+	require.Equal(t, bothDue.ID, due[1].ID)
+
+	due, err = repo.DueExpiry(ctx, now, 1)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TimeoutsConfig содержит таймауты запросов репозиториев по классу операции
+type TimeoutsConfig struct {
+	Read   time.Duration // чтения (default: 5s)
+	Write  time.Duration // записи и чтения с блокировкой (default: 10s)
+	Logger zerolog.Logger
+}
+
+// Timeouts ограничивает время операций репозитория, если вызывающий не задал дедлайн короче.
+// Таймаут охватывает всю операцию — запрос, чтение строк и повтор на primary после сбоя реплики.
+// Операции, упёршиеся в таймаут, пишутся в лог; медленные — см. InstrumentMediaRepo.
+// nil *Timeouts — без таймаутов.
+type Timeouts struct {
+	read   time.Duration
+	write  time.Duration
+	logger zerolog.Logger
+}
+
+func NewTimeouts(cfg TimeoutsConfig) (*Timeouts, error) {
+	if cfg.Read < 0 {
+		return nil, fmt.Errorf("read timeout cannot be negative, got: %v", cfg.Read)
+	}
+	if cfg.Write < 0 {
+		return nil, fmt.Errorf("write timeout cannot be negative, got: %v", cfg.Write)
+	}
+	if cfg.Read == 0 {
+		cfg.Read = 5 * time.Second
+	}
+	if cfg.Write == 0 {
+		cfg.Write = 10 * time.Second
+	}
+
+	return &Timeouts{
+		read:   cfg.Read,
+		write:  cfg.Write,
+		logger: cfg.Logger.With().Str("component", "pg_queries").Logger(),
+	}, nil
+}
+
+// reading оборачивает ctx таймаутом чтения; done вызывается по завершении операции op
+func (t *Timeouts) reading(ctx context.Context, op string) (context.Context, func()) {
+	if t == nil {
+		return ctx, func() {}
+	}
+	return t.wrap(ctx, op, t.read)
+}
+
+// writing оборачивает ctx таймаутом записи; done вызывается по завершении операции op
+func (t *Timeouts) writing(ctx context.Context, op string) (context.Context, func()) {
+	if t == nil {
+		return ctx, func() {}
+	}
+	return t.wrap(ctx, op, t.write)
+}
+
+func (t *Timeouts) wrap(ctx context.Context, op string, timeout time.Duration) (context.Context, func()) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+		if timedOut {
+			t.logger.Warn().Str("op", op).Dur("timeout", timeout).Msg("query timed out")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeouts_Deadlines(t *testing.T) {
+	tm, err := NewTimeouts(TimeoutsConfig{Read: time.Second, Write: time.Minute})
+	require.NoError(t, err)
+
+	ctx, done := tm.reading(context.Background(), "media get by id")
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+	done()
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	ctx, done = tm.writing(context.Background(), "media create")
+	deadline, _ = ctx.Deadline()
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, 100*time.Millisecond)
+	done()
+
+	// Дедлайн вызывающего короче — остаётся его
+	parent, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	want, _ := parent.Deadline()
+	ctx, done = tm.writing(parent, "media create")
+	defer done()
+	deadline, _ = ctx.Deadline()
+	require.Equal(t, want, deadline)
+
+	// nil — без таймаутов
+	var none *Timeouts
+	ctx, done = none.reading(context.Background(), "media list")
+	defer done()
+	_, ok = ctx.Deadline()
+	require.False(t, ok)
+}
+
+func TestTimeouts_LogsTimedOut(t *testing.T) {
+	var buf bytes.Buffer
+	tm, err := NewTimeouts(TimeoutsConfig{Logger: zerolog.New(&buf)})
+	require.NoError(t, err)
+
+	_, done := tm.reading(context.Background(), "media list")
+	done()
+	require.Empty(t, buf.String())
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	_, done = tm.writing(expired, "outbox add")
+	done()
+	require.Contains(t, buf.String(), `"op":"outbox add"`)
+	require.Contains(t, buf.String(), "query timed out")
+}
+
+func TestNewTimeouts_Validation(t *testing.T) {
+	for _, cfg := range []TimeoutsConfig{{Read: -time.Second}, {Write: -time.Second}} {
+		_, err := NewTimeouts(cfg)
+		require.Error(t, err)
+	}
+
+	tm, err := NewTimeouts(TimeoutsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, tm.read)
+	require.Equal(t, 10*time.Second, tm.write)
+}
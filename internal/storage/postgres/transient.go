@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsTransient — ошибка, после которой операцию можно повторить целиком: конфликт
+// сериализации (40001), взаимоблокировка (40P01), обрыв соединения (класс 08) и сбои,
+// при которых запрос заведомо не ушёл на сервер. Обрыв посреди коммита сюда не входит:
+// неизвестно, применилась ли транзакция.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01" || strings.HasPrefix(pgErr.Code, "08")
+	}
+	return pgconn.SafeToRetry(err)
+}
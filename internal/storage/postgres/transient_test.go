@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransient(t *testing.T) {
+	require.False(t, IsTransient(nil))
+	require.True(t, IsTransient(&pgconn.PgError{Code: "40001"}))
+	require.True(t, IsTransient(fmt.Errorf("media update status: %w", &pgconn.PgError{Code: "40P01"})))
+	require.True(t, IsTransient(&pgconn.PgError{Code: "08006"}))
+	require.False(t, IsTransient(&pgconn.PgError{Code: "23505"}))
+	require.False(t, IsTransient(errors.New("unexpected EOF")))
+}
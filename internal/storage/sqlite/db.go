@@ -0,0 +1,60 @@
+// Package sqlite — хранилище media сервиса в одном файле SQLite: MediaRepo
+// (repository.MediaRepository) и OutboxRepo (outbox.Store, service.Outbox). Для демо,
+// edge-инсталляций и быстрых тестов: сервис работает одним бинарём без внешних зависимостей.
+// Возможности, завязанные на Postgres (реплики, журнал событий, аудит, коллекции, админка
+// outbox), с ним не работают.
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" // драйвер "sqlite3"
+)
+
+// MemoryPath — база в памяти процесса: пропадает при выходе
+const MemoryPath = ":memory:"
+
+// DSN — строка подключения к файлу path: журнал WAL, ожидание блокировки до 5s вместо
+// немедленного SQLITE_BUSY, транзакции берут блокировку записи сразу (BEGIN IMMEDIATE),
+// а не при первой записи, когда её уже может держать другой процесс.
+func DSN(path string) string {
+	params := url.Values{
+		"_busy_timeout": {"5000"},
+		"_txlock":       {"immediate"},
+	}
+	if path != MemoryPath {
+		params.Set("_journal_mode", "WAL")
+	}
+	return "file:" + path + "?" + params.Encode()
+}
+
+// Open открывает базу path (MemoryPath — в памяти) и применяет схему. Соединение одно:
+// SQLite всё равно пишет по одному, а база в памяти живёт, пока открыто её соединение.
+func Open(ctx context.Context, path string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("sqlite3", DSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("sqlite open: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	if err := MigrateUp(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// timeLayout — время в базе: UTC с микросекундами, фиксированной ширины
+const timeLayout = "2006-01-02 15:04:05.000000"
+
+// ts — время параметром запроса
+func ts(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
@@ -0,0 +1,490 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+)
+
+// mediaColumns — колонки media в порядке полей models.Media
+const mediaColumns = `id, status, type, source, created_at, updated_at, title, tags, metadata, processing_attempts, last_error, owner_id, checksum_sha256, size_bytes, content_type, publish_at, expires_at, visibility`
+
+const selectMediaByID = `SELECT ` + mediaColumns + ` FROM media WHERE id = ?1`
+
+// MediaRepo — repository.MediaRepository поверх SQLite. Изменения перечитываются той же
+// транзакцией: время RETURNING драйвер отдаёт строкой, а не time.Time.
+type MediaRepo struct {
+	db  *sqlx.DB
+	tx  *TxManager
+	now func() time.Time
+}
+
+func NewMediaRepo(db *sqlx.DB) *MediaRepo {
+	return &MediaRepo{db: db, tx: NewTxManager(db), now: time.Now}
+}
+
+var _ repository.MediaRepository = (*MediaRepo)(nil)
+
+// WithinTransaction — см. TxManager.WithinTransaction
+func (r *MediaRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.tx.WithinTransaction(ctx, fn)
+}
+
+// Create вставляет медиа. Нарушение уникальности откатывает только команду, не транзакцию.
+func (r *MediaRepo) Create(ctx context.Context, m *models.Media) error {
+	const q = `
+		INSERT INTO media (id, status, type, source, created_at, updated_at, owner_id, visibility, title, tags, metadata)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, COALESCE(NULLIF(?8, ''), 'draft'), ?9, ?10, ?11)
+	`
+	tags, err := jsonString(m.Tags)
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	metadata, err := jsonString(m.Metadata)
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, q,
+		m.ID.String(), m.Status, m.Type, m.Source, ts(m.CreatedAt), ts(m.UpdatedAt), nullUUID(m.OwnerID), string(m.Visibility),
+		m.Title, tags, metadata,
+	)
+	if isDuplicate(err) {
+		return models.ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("media create: %w", err)
+	}
+	return nil
+}
+
+func (r *MediaRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	m, err := r.get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("media get by id: %w", err)
+	}
+	return m, nil
+}
+
+// GetForUpdate — GetByID: транзакции SQLite берут блокировку записи на всю базу
+// при BEGIN IMMEDIATE, отдельная блокировка строки не нужна
+func (r *MediaRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	m, err := r.get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("media get for update: %w", err)
+	}
+	return m, nil
+}
+
+// get читает одно медиа; нет строки — models.ErrNotFound
+func (r *MediaRepo) get(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	var m models.Media
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &m, selectMediaByID, id.String()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UpdateStatus меняет статус: вход в processing считается попыткой обработки, успешное
+// завершение (ready или scheduled под эмбарго) обнуляет счётчик и последнюю ошибку
+func (r *MediaRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.Status) (*models.Media, error) {
+	const q = `
+		UPDATE media
+		SET status = ?2,
+		    updated_at = ?3,
+		    processing_attempts = CASE
+		        WHEN ?2 = 'processing' THEN processing_attempts + 1
+		        WHEN ?2 IN ('ready', 'scheduled') THEN 0
+		        ELSE processing_attempts
+		    END,
+		    last_error = CASE WHEN ?2 IN ('ready', 'scheduled') THEN '' ELSE last_error END
+		WHERE id = ?1`
+
+	m, err := r.update(ctx, id, q, id.String(), status, ts(r.now()))
+	if err != nil {
+		return nil, fmt.Errorf("media update status: %w", err)
+	}
+	return m, nil
+}
+
+// Update частично обновляет медиа: NULL параметр в COALESCE оставляет колонку как есть.
+// Возвращает обновлённую запись; пустой патч ничего не пишет и возвращает текущую.
+func (r *MediaRepo) Update(ctx context.Context, id uuid.UUID, patch models.MediaPatch) (*models.Media, error) {
+	if patch.IsEmpty() {
+		return r.GetByID(ctx, id)
+	}
+
+	const q = `
+		UPDATE media
+		SET source = COALESCE(?2, source),
+		    title = COALESCE(?3, title),
+		    tags = COALESCE(?4, tags),
+		    metadata = COALESCE(?5, metadata),
+		    checksum_sha256 = COALESCE(?6, checksum_sha256),
+		    size_bytes = COALESCE(?7, size_bytes),
+		    content_type = COALESCE(?8, content_type),
+		    publish_at = CASE WHEN ?9 THEN ?10 ELSE publish_at END,
+		    expires_at = CASE WHEN ?9 THEN ?11 ELSE expires_at END,
+		    visibility = COALESCE(?12, visibility),
+		    updated_at = ?13
+		WHERE id = ?1`
+
+	var tags, metadata any
+	if patch.Tags != nil {
+		s, err := jsonString(*patch.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("media update: %w", err)
+		}
+		tags = s
+	}
+	if patch.Metadata != nil {
+		s, err := jsonString(*patch.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("media update: %w", err)
+		}
+		metadata = s
+	}
+	var (
+		checksum, contentType *string
+		size                  *int64
+	)
+	if c := patch.Content; c != nil {
+		checksum, size, contentType = &c.Checksum, &c.Size, &c.ContentType
+	}
+	// Расписание заменяется целиком, в том числе на NULL — COALESCE тут не подходит
+	var publishAt, expiresAt any
+	if sch := patch.Schedule; sch != nil {
+		publishAt, expiresAt = nullTime(sch.PublishAt), nullTime(sch.ExpiresAt)
+	}
+
+	m, err := r.update(ctx, id, q, id.String(), patch.Source, patch.Title, tags, metadata, checksum, size, contentType,
+		patch.Schedule != nil, publishAt, expiresAt, patch.Visibility, ts(r.now()))
+	if err != nil {
+		return nil, fmt.Errorf("media update: %w", err)
+	}
+	return m, nil
+}
+
+// update выполняет UPDATE медиа id и перечитывает его в той же транзакции
+func (r *MediaRepo) update(ctx context.Context, id uuid.UUID, q string, args ...any) (*models.Media, error) {
+	var m *models.Media
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+		var err error
+		m, err = r.get(ctx, id)
+		return err
+	})
+	return m, err
+}
+
+// listWhere — условия ListFilter без пагинации, параметры ?1..?5 — listArgs
+const listWhere = `
+		WHERE (?1 = '' OR status = ?1)
+		  AND (?2 IS NULL OR owner_id = ?2)
+		  AND (?3 = '' OR checksum_sha256 = ?3)
+		  AND (?4 = '' OR type = ?4)
+		  AND (?5 = '' OR visibility = ?5)`
+
+func listArgs(f repository.ListFilter) []any {
+	return []any{string(f.Status), nullUUID(f.OwnerID), f.Checksum, string(f.Type), string(f.Visibility)}
+}
+
+// List возвращает страницу медиа в порядке filter.Sort, при равных значениях — по id
+func (r *MediaRepo) List(ctx context.Context, filter repository.ListFilter) ([]*models.Media, error) {
+	filter = filter.WithDefaults()
+	if !filter.Sort.Valid() {
+		return nil, fmt.Errorf("%w: unknown sort %q", models.ErrInvalidArgument, filter.Sort)
+	}
+
+	// Колонка сортировки — из закрытого списка ListSort, в запрос подставляется как есть
+	column, direction, after := string(filter.Sort), "DESC", "<"
+	if filter.Ascending {
+		direction, after = "ASC", ">"
+	}
+	var cursorAt, cursorID any
+	if filter.After != nil {
+		cursorAt, cursorID = ts(filter.After.At), filter.After.ID.String()
+	}
+
+	q := `
+		SELECT ` + mediaColumns + `
+		FROM media` + listWhere + `
+		  AND (?6 IS NULL OR ` + column + ` ` + after + ` ?6 OR (` + column + ` = ?6 AND id > ?7))
+		ORDER BY ` + column + ` ` + direction + `, id
+		LIMIT ?8 OFFSET ?9
+	`
+	args := append(listArgs(filter), cursorAt, cursorID, filter.Limit, filter.Offset)
+
+	var out []*models.Media
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, args...); err != nil {
+		return nil, fmt.Errorf("media list: %w", err)
+	}
+	return out, nil
+}
+
+// Totals — см. repository.TotalsQuery. Фасеты считаются одним GROUP BY status, type, и итог
+// с ними — их сумма. Дешёвой оценки числа строк у SQLite нет, и CountEstimated считает точно.
+func (r *MediaRepo) Totals(ctx context.Context, filter repository.ListFilter, tq repository.TotalsQuery) (repository.ListTotals, error) {
+	if !tq.Count.Valid() {
+		return repository.ListTotals{}, fmt.Errorf("%w: unknown count mode %q", models.ErrInvalidArgument, tq.Count)
+	}
+	db, args := conn(ctx, r.db), listArgs(filter)
+
+	var t repository.ListTotals
+	switch {
+	case tq.Facets:
+		var groups []struct {
+			Status models.Status    `db:"status"`
+			Type   models.MediaType `db:"type"`
+			Count  int64            `db:"count"`
+		}
+		q := `SELECT status, type, COUNT(*) AS count FROM media` + listWhere + ` GROUP BY status, type`
+		if err := sqlx.SelectContext(ctx, db, &groups, q, args...); err != nil {
+			return repository.ListTotals{}, fmt.Errorf("media totals: %w", err)
+		}
+		t.ByStatus = make(map[models.Status]int64)
+		t.ByType = make(map[models.MediaType]int64)
+		for _, g := range groups {
+			t.ByStatus[g.Status] += g.Count
+			t.ByType[g.Type] += g.Count
+			if tq.Count != repository.CountNone {
+				t.Total += g.Count
+			}
+		}
+	case tq.Count != repository.CountNone:
+		if err := sqlx.GetContext(ctx, db, &t.Total, `SELECT COUNT(*) FROM media`+listWhere, args...); err != nil {
+			return repository.ListTotals{}, fmt.Errorf("media totals: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// Search — полнотекстовый поиск индексом FTS4 media_fts по title и metadata.description:
+// каждое слово запроса обязательно. Релевантность — число совпадений слов запроса.
+// Без текста сортирует по дате, как List.
+func (r *MediaRepo) Search(ctx context.Context, sq repository.SearchQuery) ([]repository.SearchHit, error) {
+	sq = sq.WithDefaults()
+
+	tags, err := jsonString(models.Tags(sq.Tags))
+	if err != nil {
+		return nil, fmt.Errorf("media search: %w", err)
+	}
+	args := []any{tags, string(sq.Status), nullUUID(sq.OwnerID), sq.Limit, sq.Offset}
+	rank, from := "0", "media"
+	if text := matchQuery(sq.Text); text != "" {
+		// offsets() — четвёрки чисел через пробел, по одной на совпадение
+		rank = "fts.rank"
+		from = `media JOIN (
+			SELECT docid, (length(offsets(media_fts)) - length(replace(offsets(media_fts), ' ', '')) + 1) / 4.0 AS rank
+			FROM media_fts
+			WHERE media_fts MATCH ?6
+		) AS fts ON fts.docid = media.rowid`
+		args = append(args, text)
+	}
+	q := `
+		SELECT ` + mediaColumns + `, ` + rank + ` AS rank
+		FROM ` + from + `
+		WHERE NOT EXISTS (
+		        SELECT 1 FROM json_each(?1) AS want
+		        WHERE want.value NOT IN (SELECT value FROM json_each(media.tags))
+		    )
+		  AND (?2 = '' OR status = ?2)
+		  AND (?3 IS NULL OR owner_id = ?3)
+		ORDER BY rank DESC, created_at DESC, id
+		LIMIT ?4 OFFSET ?5
+	`
+
+	var out []repository.SearchHit
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, args...); err != nil {
+		return nil, fmt.Errorf("media search: %w", err)
+	}
+	return out, nil
+}
+
+// matchQuery — строка поиска в синтаксисе MATCH: слова в кавычках через пробел (все
+// обязательны); операторы и пунктуация пользователя отбрасываются
+func matchQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for i, w := range words {
+		words[i] = `"` + w + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// Dashboard — см. repository.DashboardQuery. История статусов переживает удаление media,
+// но с фильтром по владельцу учитывается только история существующих media.
+func (r *MediaRepo) Dashboard(ctx context.Context, dq repository.DashboardQuery) (repository.Dashboard, error) {
+	const countsQuery = `
+		SELECT status, type, COUNT(*) AS count, COALESCE(SUM(created_at >= ?1), 0) AS created
+		FROM media
+		WHERE ?2 IS NULL OR owner_id = ?2
+		GROUP BY status, type`
+
+	// Длительность — от последнего входа в processing до перехода в ready/failed;
+	// scheduled — та же успешная обработка, опубликованная позже
+	const processingQuery = `
+		SELECT COALESCE(SUM(to_status IN ('ready', 'scheduled')), 0) AS completed,
+		       COALESCE(SUM(to_status = 'failed'), 0) AS failed,
+		       COALESCE(AVG(unixepoch(changed_at, 'subsec') - unixepoch(started_at, 'subsec')), 0) AS avg_seconds
+		FROM (
+			SELECT h.to_status, h.changed_at,
+			       (SELECT p.changed_at FROM media_status_history p
+			        WHERE p.media_id = h.media_id AND p.to_status = 'processing' AND p.changed_at <= h.changed_at
+			        ORDER BY p.changed_at DESC
+			        LIMIT 1) AS started_at
+			FROM media_status_history h
+			LEFT JOIN media m ON m.id = h.media_id
+			WHERE h.from_status = 'processing' AND h.to_status IN ('ready', 'scheduled', 'failed')
+			  AND h.changed_at >= ?1
+			  AND (?2 IS NULL OR m.owner_id = ?2)
+		)`
+
+	var (
+		counts []struct {
+			Status  models.Status    `db:"status"`
+			Type    models.MediaType `db:"type"`
+			Count   int64            `db:"count"`
+			Created int64            `db:"created"`
+		}
+		processing struct {
+			Completed  int64   `db:"completed"`
+			Failed     int64   `db:"failed"`
+			AvgSeconds float64 `db:"avg_seconds"`
+		}
+	)
+	db, since, owner := conn(ctx, r.db), ts(dq.Since), nullUUID(dq.OwnerID)
+	if err := sqlx.SelectContext(ctx, db, &counts, countsQuery, since, owner); err != nil {
+		return repository.Dashboard{}, fmt.Errorf("media dashboard: %w", err)
+	}
+	if err := sqlx.GetContext(ctx, db, &processing, processingQuery, since, owner); err != nil {
+		return repository.Dashboard{}, fmt.Errorf("media dashboard: %w", err)
+	}
+
+	d := repository.Dashboard{
+		ByStatus:  make(map[models.Status]int64),
+		ByType:    make(map[models.MediaType]int64),
+		Completed: processing.Completed,
+		Failed:    processing.Failed,
+		// unixepoch считает с точностью до миллисекунд, остальное — погрешность float
+		AvgProcessing: time.Duration(math.Round(processing.AvgSeconds*1000)) * time.Millisecond,
+	}
+	for _, c := range counts {
+		d.ByStatus[c.Status] += c.Count
+		d.ByType[c.Type] += c.Count
+		d.Total += c.Count
+		d.Created += c.Created
+	}
+	return d, nil
+}
+
+// Delete удаляет медиа и возвращает удалённую запись,
+// чтобы вызывающий мог положить в outbox событие с её данными.
+func (r *MediaRepo) Delete(ctx context.Context, id uuid.UUID) (*models.Media, error) {
+	var m *models.Media
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if m, err = r.get(ctx, id); err != nil {
+			return err
+		}
+		_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM media WHERE id = ?1`, id.String())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("media delete: %w", err)
+	}
+	return m, nil
+}
+
+// SetLastError сохраняет ошибку последней неудачной обработки
+func (r *MediaRepo) SetLastError(ctx context.Context, id uuid.UUID, lastError string) error {
+	const q = `UPDATE media SET last_error = ?2, updated_at = ?3 WHERE id = ?1`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, id.String(), lastError, ts(r.now()))
+	if err != nil {
+		return fmt.Errorf("media set last error: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("media set last error: %w", err)
+	}
+	if n == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// AddStatusChange пишет запись в историю статусов; вызывается в той же транзакции, что и смена статуса
+func (r *MediaRepo) AddStatusChange(ctx context.Context, c *models.StatusChange) error {
+	const q = `
+		INSERT INTO media_status_history (media_id, from_status, to_status, actor, reason, changed_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, c.MediaID.String(), c.From, c.To, c.Actor, c.Reason, ts(c.ChangedAt))
+	if err != nil {
+		return fmt.Errorf("media add status change: %w", err)
+	}
+	if c.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("media add status change: %w", err)
+	}
+	return nil
+}
+
+// ListStatusChanges возвращает историю статусов медиа в хронологическом порядке
+func (r *MediaRepo) ListStatusChanges(ctx context.Context, mediaID uuid.UUID) ([]models.StatusChange, error) {
+	const q = `
+		SELECT id, media_id, from_status, to_status, actor, reason, changed_at
+		FROM media_status_history
+		WHERE media_id = ?1
+		ORDER BY changed_at ASC, id ASC
+	`
+	var out []models.StatusChange
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &out, q, mediaID.String()); err != nil {
+		return nil, fmt.Errorf("media list status changes: %w", err)
+	}
+	return out, nil
+}
+
+// nullUUID — uuid параметр запроса: uuid.Nil пишется как NULL (owner_id общего пула, нет фильтра)
+func nullUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.String()
+}
+
+// nullTime — необязательное время параметром запроса: nil пишется как NULL
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return ts(*t)
+}
+
+// jsonString — значение JSON колонки параметром запроса. Строкой, а не []byte: BLOB
+// JSON функции SQLite читают как JSONB.
+func jsonString(v sqldriver.Valuer) (string, error) {
+	value, err := v.Value()
+	if err != nil {
+		return "", err
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return "", fmt.Errorf("json column value %T", value)
+	}
+	return string(b), nil
+}
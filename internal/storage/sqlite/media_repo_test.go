@@ -0,0 +1,71 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/repository/repotest"
+	"github.com/romariotrain/media-platform/internal/storage/sqlite"
+)
+
+// SQLite встраивается в бинарь, поэтому контракт гоняется в обычных тестах, без контейнеров
+func TestMediaRepo_Contract(t *testing.T) {
+	repotest.RunRepositoryTests(t, func(t *testing.T) repository.MediaRepository {
+		db, err := sqlite.Open(context.Background(), sqlite.MemoryPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		return sqlite.NewMediaRepo(db)
+	})
+}
+
+func TestMediaRepo_Search(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, sqlite.MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+	repo := sqlite.NewMediaRepo(db)
+
+	now := time.Now()
+	create := func(title, description string, tags ...string) *models.Media {
+		m := &models.Media{
+			ID: uuid.New(), Status: models.UploadedStatus, Type: models.Video,
+			Source: "s3://media/" + title, CreatedAt: now, UpdatedAt: now,
+		}
+		require.NoError(t, repo.Create(ctx, m))
+		metadata, tagSet := models.Metadata{"description": description}, models.Tags(tags)
+		_, err := repo.Update(ctx, m.ID, models.MediaPatch{Title: &title, Metadata: &metadata, Tags: &tagSet})
+		require.NoError(t, err)
+		return m
+	}
+	cats := create("Кошки дома", "про кошек и котят", "pets")
+	dogs := create("Собаки", "кошки не упоминаются дважды: кошки", "pets", "dogs")
+	create("Погода", "прогноз")
+
+	hits, err := repo.Search(ctx, repository.SearchQuery{Text: "кошки"})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	require.Equal(t, dogs.ID, hits[0].ID, "more matches rank higher")
+	require.Equal(t, cats.ID, hits[1].ID)
+
+	hits, err = repo.Search(ctx, repository.SearchQuery{Text: "кошки", Tags: []string{"dogs"}})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Equal(t, dogs.ID, hits[0].ID)
+
+	hits, err = repo.Search(ctx, repository.SearchQuery{Tags: []string{"pets"}})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+
+	// Удалённое медиа пропадает из индекса
+	_, err = repo.Delete(ctx, dogs.ID)
+	require.NoError(t, err)
+	hits, err = repo.Search(ctx, repository.SearchQuery{Text: "кошки"})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	schema "github.com/romariotrain/media-platform/sql"
+)
+
+// MigrateUp применяет схему sql/sqlite/script.sql. Скрипт идемпотентен, повторный вызов безопасен.
+// Драйвер выполняет скрипт из нескольких команд одним вызовом.
+func MigrateUp(ctx context.Context, db *sqlx.DB) error {
+	if _, err := db.ExecContext(ctx, schema.SQLiteUp); err != nil {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown удаляет таблицы схемы вместе с данными (sql/sqlite/down.sql)
+func MigrateDown(ctx context.Context, db *sqlx.DB) error {
+	if _, err := db.ExecContext(ctx, schema.SQLiteDown); err != nil {
+		return fmt.Errorf("migrate down: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// DefaultClaimLease — на сколько GetPending откладывает захваченные события: за это время
+// publisher должен их опубликовать и отметить, иначе их возьмёт следующий вызов
+const DefaultClaimLease = time.Minute
+
+// OutboxRepo — outbox в SQLite. Записи читаются в postgres.OutboxRecord: publisher работает
+// с одним типом независимо от хранилища.
+type OutboxRepo struct {
+	db       *sqlx.DB
+	tx       *TxManager
+	registry *events.Registry
+	cipher   *encryption.Cipher
+	lease    time.Duration
+	now      func() time.Time
+}
+
+// outboxColumns — колонки outbox в порядке полей postgres.OutboxRecord; payload — байтами:
+// TEXT драйвер отдаёт строкой, а json.RawMessage строку не сканирует
+const outboxColumns = `id, event_id, event_type, schema_version, aggregate_id, sequence, CAST(payload AS BLOB) AS payload, occurred_at,
+        attempts, last_error, next_retry_at, dead_lettered_at, processed_at`
+
+func NewOutboxRepo(db *sqlx.DB) *OutboxRepo {
+	return &OutboxRepo{db: db, tx: NewTxManager(db), registry: events.Default, lease: DefaultClaimLease, now: time.Now}
+}
+
+// WithEncryption шифрует payload новых событий; прочитанные payload расшифровываются,
+// записанные до включения шифрования читаются как есть
+func (r *OutboxRepo) WithEncryption(c *encryption.Cipher) *OutboxRepo {
+	r.cipher = c
+	return r
+}
+
+// WithClaimLease задаёт аренду захваченных событий (по умолчанию DefaultClaimLease)
+func (r *OutboxRepo) WithClaimLease(d time.Duration) *OutboxRepo {
+	r.lease = d
+	return r
+}
+
+// openPending расшифровывает payload прочитанных записей; event_id — aad шифротекста.
+// Запись, которую не удалось расшифровать (ключ неизвестен или выведен из ротации),
+// паркуется в dead letter с ошибкой, остальные возвращаются: иначе одна такая запись
+// роняла бы каждый poll и publisher стоял бы на ней.
+func (r *OutboxRepo) openPending(ctx context.Context, records []postgres.OutboxRecord) ([]postgres.OutboxRecord, error) {
+	opened := records[:0]
+	for _, rec := range records {
+		payload, err := r.cipher.OpenJSON(ctx, rec.Payload, []byte(rec.EventID))
+		if err != nil {
+			if err := r.MarkDeadLetter(ctx, rec.ID, "decrypt payload: "+err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rec.Payload = payload
+		opened = append(opened, rec)
+	}
+	return opened, nil
+}
+
+// Add кладёт событие в outbox в рамках транзакции из контекста, без неё — ErrNoTx.
+// См. AddBatch: одиночное событие — batch из одного.
+func (r *OutboxRepo) Add(ctx context.Context, event models.DomainEvent) error {
+	return r.AddBatch(ctx, []models.DomainEvent{event})
+}
+
+// AddBatch — Add для пачки событий (service.BatchOutbox). Номера в потоках агрегатов
+// резервируются в aggregate_sequences одним upsert; события одного агрегата получают
+// номера в порядке batch.
+func (r *OutboxRepo) AddBatch(ctx context.Context, batch []models.DomainEvent) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		return ErrNoTx
+	}
+
+	counts := make(map[string]int64, len(batch))
+	for _, event := range batch {
+		counts[event.AggregateID().String()]++
+	}
+	var (
+		values []string
+		args   []any
+	)
+	for _, id := range slices.Sorted(maps.Keys(counts)) {
+		values = append(values, "(?, ?)")
+		args = append(args, id, counts[id])
+	}
+	reserve := `
+    INSERT INTO aggregate_sequences (aggregate_id, sequence) VALUES ` + strings.Join(values, ", ") + `
+    ON CONFLICT (aggregate_id) DO UPDATE SET sequence = aggregate_sequences.sequence + excluded.sequence
+    RETURNING aggregate_id, sequence
+`
+	var reserved []struct {
+		AggregateID string `db:"aggregate_id"`
+		Sequence    int64  `db:"sequence"`
+	}
+	if err := tx.SelectContext(ctx, &reserved, reserve, args...); err != nil {
+		return fmt.Errorf("reserve aggregate sequences: %w", err)
+	}
+	// next — номер следующего события агрегата: RETURNING отдаёт последний зарезервированный
+	next := make(map[string]int64, len(reserved))
+	for _, row := range reserved {
+		next[row.AggregateID] = row.Sequence - counts[row.AggregateID] + 1
+	}
+
+	values, args = values[:0], args[:0]
+	for _, event := range batch {
+		aggregate := event.AggregateID().String()
+		seq := next[aggregate]
+		next[aggregate]++
+		if s, ok := event.(models.Sequenced); ok {
+			s.SetSequence(seq)
+		}
+
+		env, err := r.registry.Wrap(event)
+		if err != nil {
+			return fmt.Errorf("wrap event: %w", err)
+		}
+		env.Sequence = seq
+		payload, err := r.cipher.SealJSON(ctx, env.Payload, []byte(env.EventID))
+		if err != nil {
+			return fmt.Errorf("encrypt payload: %w", err)
+		}
+
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, env.EventID, env.EventType, env.SchemaVersion, env.AggregateID, env.Sequence, string(payload), ts(env.OccurredAt))
+	}
+	insert := `
+    INSERT INTO outbox (event_id, event_type, schema_version, aggregate_id, sequence, payload, occurred_at)
+    VALUES ` + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, insert, args...); err != nil {
+		return fmt.Errorf("insert outbox: %w", err)
+	}
+	return nil
+}
+
+// GetPending захватывает события к публикации: не обработанные, не припаркованные и без
+// отложенного повтора в будущем. Захваченные откладываются на время аренды в той же
+// транзакции, которая держит блокировку записи базы, — так publisher'ы нескольких процессов
+// над одним файлом не получают одни и те же события. MarkProcessed, MarkFailed
+// и MarkDeadLetter снимают аренду. Пока более раннее событие агрегата ждёт повтора
+// или захвачено, его следующие события не берутся.
+func (r *OutboxRepo) GetPending(ctx context.Context, limit int) ([]postgres.OutboxRecord, error) {
+	const q = `
+        SELECT ` + outboxColumns + `
+        FROM outbox
+        WHERE processed_at IS NULL
+          AND dead_lettered_at IS NULL
+          AND (next_retry_at IS NULL OR next_retry_at <= ?1)
+          AND NOT EXISTS (
+              SELECT 1 FROM outbox prev
+              WHERE prev.aggregate_id = outbox.aggregate_id
+                AND prev.id < outbox.id
+                AND prev.processed_at IS NULL
+                AND prev.dead_lettered_at IS NULL
+                AND prev.next_retry_at > ?1
+          )
+        ORDER BY id ASC
+        LIMIT ?2
+    `
+
+	var records []postgres.OutboxRecord
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := r.now()
+		db := conn(ctx, r.db)
+		if err := sqlx.SelectContext(ctx, db, &records, q, ts(now), limit); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		ids := make([]int64, len(records))
+		for i, rec := range records {
+			ids[i] = rec.ID
+		}
+		claim, args, err := sqlx.In(`UPDATE outbox SET next_retry_at = ? WHERE id IN (?)`, ts(now.Add(r.lease)), ids)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, claim, args...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get pending: %w", err)
+	}
+	records, err = r.openPending(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CountPending возвращает число необработанных событий (backlog publisher'а)
+func (r *OutboxRepo) CountPending(ctx context.Context) (int64, error) {
+	const q = `SELECT COUNT(*) FROM outbox WHERE processed_at IS NULL AND dead_lettered_at IS NULL`
+
+	var n int64
+	if err := r.db.GetContext(ctx, &n, q); err != nil {
+		return 0, fmt.Errorf("count pending: %w", err)
+	}
+	return n, nil
+}
+
+func (r *OutboxRepo) MarkProcessed(ctx context.Context, id int64) error {
+	const q = `UPDATE outbox SET processed_at = ?2, next_retry_at = NULL WHERE id = ?1`
+
+	if _, err := r.db.ExecContext(ctx, q, id, ts(r.now())); err != nil {
+		return fmt.Errorf("mark processed: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed фиксирует неудачную попытку публикации и откладывает следующую на retryIn
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error {
+	const q = `
+        UPDATE outbox
+        SET attempts = attempts + 1,
+            last_error = ?2,
+            next_retry_at = ?3
+        WHERE id = ?1
+    `
+
+	if _, err := r.db.ExecContext(ctx, q, id, lastError, ts(r.now().Add(retryIn))); err != nil {
+		return fmt.Errorf("mark failed: %w", err)
+	}
+	return nil
+}
+
+// MarkDeadLetter паркует событие, исчерпавшее попытки: publisher его больше не берёт
+func (r *OutboxRepo) MarkDeadLetter(ctx context.Context, id int64, lastError string) error {
+	const q = `
+        UPDATE outbox
+        SET attempts = attempts + 1,
+            last_error = ?2,
+            next_retry_at = NULL,
+            dead_lettered_at = ?3
+        WHERE id = ?1
+    `
+
+	if _, err := r.db.ExecContext(ctx, q, id, lastError, ts(r.now())); err != nil {
+		return fmt.Errorf("mark dead letter: %w", err)
+	}
+	return nil
+}
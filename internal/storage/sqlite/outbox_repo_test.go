@@ -0,0 +1,127 @@
+package sqlite_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/events"
+	"github.com/romariotrain/media-platform/internal/events/encryption"
+	"github.com/romariotrain/media-platform/internal/media/models"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/storage/sqlite"
+)
+
+func TestOutboxRepo(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, sqlite.MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+	outbox := sqlite.NewOutboxRepo(db)
+	svc := service.New(sqlite.NewMediaRepo(db), outbox)
+
+	m, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	other, err := svc.CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+	_, err = svc.ChangeStatus(ctx, m.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+
+	n, err := outbox.CountPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	records, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, m.ID.String(), records[2].AggregateID)
+	require.Equal(t, int64(2), records[2].Sequence)
+	payload, err := events.Default.DecodeLatest(events.Envelope{
+		EventType:     records[2].EventType,
+		SchemaVersion: records[2].SchemaVersion,
+		Payload:       records[2].Payload,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), payload.(*events.MediaStatusChangedV1).Sequence)
+
+	// Захваченные события не отдаются повторно до конца аренды
+	again, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	require.NoError(t, outbox.MarkProcessed(ctx, records[0].ID))
+	require.NoError(t, outbox.MarkFailed(ctx, records[1].ID, "kafka down", 0))
+	require.NoError(t, outbox.MarkDeadLetter(ctx, records[2].ID, "too large"))
+	again, err = outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, records[1].ID, again[0].ID)
+	require.Equal(t, 1, again[0].Attempts)
+	require.Equal(t, "kafka down", again[0].LastError)
+
+	n, err = outbox.CountPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// Пока более раннее событие агрегата ждёт повтора, следующие его события не отдаются
+	require.NoError(t, outbox.MarkFailed(ctx, again[0].ID, "kafka down", time.Hour))
+	_, err = svc.ChangeStatus(ctx, other.ID, models.ProcessingStatus, service.ChangeMeta{})
+	require.NoError(t, err)
+	held, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, held)
+	require.NoError(t, outbox.MarkProcessed(ctx, again[0].ID))
+
+	// Захваченное, но не отмеченное событие возвращается после аренды
+	short := sqlite.NewOutboxRepo(db).WithClaimLease(time.Millisecond)
+	first, err := short.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	time.Sleep(5 * time.Millisecond)
+	again, err = short.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	require.Equal(t, first[0].ID, again[0].ID)
+}
+
+func TestOutboxRepo_ParksUndecryptable(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, sqlite.MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	cipherWith := func(spec string) *encryption.Cipher {
+		keys, err := encryption.ParseKeyring(spec)
+		require.NoError(t, err)
+		c, err := encryption.NewCipher(keys)
+		require.NoError(t, err)
+		return c
+	}
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	// Событие зашифровано ключом, которого у читателя уже нет
+	lost, err := service.New(sqlite.NewMediaRepo(db), sqlite.NewOutboxRepo(db).WithEncryption(cipherWith("old:"+key))).
+		CreateMedia(ctx, models.Video, "s3://bucket/a.mp4")
+	require.NoError(t, err)
+	plain, err := service.New(sqlite.NewMediaRepo(db), sqlite.NewOutboxRepo(db)).
+		CreateMedia(ctx, models.Video, "s3://bucket/b.mp4")
+	require.NoError(t, err)
+
+	outbox := sqlite.NewOutboxRepo(db).WithEncryption(cipherWith("new:" + key))
+	records, err := outbox.GetPending(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, plain.ID.String(), records[0].AggregateID)
+
+	// Нерасшифрованное событие припарковано с ошибкой и больше не отдаётся
+	var lastError string
+	require.NoError(t, db.GetContext(ctx, &lastError,
+		`SELECT last_error FROM outbox WHERE aggregate_id = ? AND dead_lettered_at IS NOT NULL`, lost.ID.String()))
+	require.Contains(t, lastError, "decrypt payload")
+	n, err := outbox.CountPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
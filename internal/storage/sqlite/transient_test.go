@@ -0,0 +1,18 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransient(t *testing.T) {
+	require.False(t, IsTransient(nil))
+	require.True(t, IsTransient(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	require.True(t, IsTransient(fmt.Errorf("media update status: %w", sqlite3.Error{Code: sqlite3.ErrLocked})))
+	require.False(t, IsTransient(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintPrimaryKey}))
+	require.False(t, IsTransient(errors.New("unexpected EOF")))
+}
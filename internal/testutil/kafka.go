@@ -0,0 +1,76 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	tckafka "github.com/testcontainers/testcontainers-go/modules/kafka"
+
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+)
+
+// KafkaImage — одноузловой KRaft брокер без zookeeper
+const KafkaImage = "confluentinc/confluent-local:7.6.1"
+
+// Kafka — брокер в контейнере
+type Kafka struct {
+	Brokers []string
+}
+
+// StartKafka запускает Kafka и создаёт topics (по одной партиции — порядок чтения в тестах детерминирован)
+func StartKafka(t *testing.T, topics ...string) *Kafka {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	container, err := tckafka.Run(ctx, KafkaImage, tckafka.WithClusterID("media-test"))
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "start kafka")
+
+	brokers, err := container.Brokers(ctx)
+	require.NoError(t, err)
+
+	k := &Kafka{Brokers: brokers}
+	if len(topics) > 0 {
+		admin, err := kafka.NewTopicAdmin(kafka.TopicAdminConfig{Brokers: brokers})
+		require.NoError(t, err)
+
+		specs := make([]kafka.TopicSpec, len(topics))
+		for i, topic := range topics {
+			specs[i] = kafka.TopicSpec{Name: topic}
+		}
+		require.NoError(t, admin.EnsureTopics(ctx, specs...))
+	}
+	return k
+}
+
+// ReadMessages читает n сообщений из начала единственной партиции топика. Тест падает,
+// если за timeout сообщений меньше.
+func (k *Kafka) ReadMessages(t *testing.T, topic string, n int, timeout time.Duration) []kafkago.Message {
+	t.Helper()
+
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:   k.Brokers,
+		Topic:     topic,
+		Partition: 0,
+		MaxWait:   100 * time.Millisecond,
+	})
+	defer reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msgs := make([]kafkago.Message, 0, n)
+	for len(msgs) < n {
+		msg, err := reader.ReadMessage(ctx)
+		require.NoError(t, err, "read %s: got %d of %d messages", topic, len(msgs), n)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
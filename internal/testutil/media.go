@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/media/httpapi"
+	"github.com/romariotrain/media-platform/internal/media/kafka"
+	"github.com/romariotrain/media-platform/internal/media/outbox"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// MediaTopic — топик событий media, как в cmd/media
+const MediaTopic = "events.media"
+
+// MediaConfig описывает окружение in-process media сервиса
+type MediaConfig struct {
+	Postgres *Postgres
+	Kafka    *Kafka
+	Topic    string         // default: MediaTopic
+	Logger   zerolog.Logger // default: без логов
+}
+
+// Media — media сервис, собранный как в cmd/media: postgres репозитории, outbox publisher
+// в Kafka и HTTP API на httptest сервере (публичный API и /admin/)
+type Media struct {
+	URL       string
+	Service   *service.Service
+	Outbox    *pg.OutboxRepo
+	Producer  *kafka.Producer
+	Publisher *outbox.Publisher
+}
+
+// StartMedia собирает и запускает сервис; publisher дренируется и сервер закрывается после теста
+func StartMedia(t *testing.T, cfg MediaConfig) *Media {
+	t.Helper()
+	if cfg.Topic == "" {
+		cfg.Topic = MediaTopic
+	}
+
+	mediaRepo := pg.NewMediaRepo(cfg.Postgres.DB)
+	outboxRepo := pg.NewOutboxRepo(cfg.Postgres.DB)
+	svc := service.New(mediaRepo, outboxRepo).WithLogger(cfg.Logger)
+
+	producer, err := kafka.NewProducer(kafka.ProducerConfig{
+		Brokers: cfg.Kafka.Brokers,
+		Topic:   cfg.Topic,
+		Logger:  cfg.Logger,
+	})
+	require.NoError(t, err)
+
+	publisher, err := outbox.NewPublisher(outbox.PublisherConfig{
+		OutboxRepo:  outboxRepo,
+		Producer:    producer,
+		Interval:    50 * time.Millisecond,
+		MaxInterval: 200 * time.Millisecond,
+		Logger:      cfg.Logger,
+	})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- publisher.Start(context.Background()) }()
+
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", httpapi.NewAdminRouter(httpapi.NewAdmin(outboxRepo)))
+	mux.Handle("/", httpapi.NewRouter(httpapi.New(svc).WithLogger(cfg.Logger).WithReadinessCheck("outbox_backlog", publisher.CheckBacklog)))
+	srv := httptest.NewServer(mux)
+
+	t.Cleanup(func() {
+		srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, publisher.Stop(ctx))
+		require.NoError(t, <-done)
+	})
+
+	return &Media{
+		URL:       srv.URL,
+		Service:   svc,
+		Outbox:    outboxRepo,
+		Producer:  producer,
+		Publisher: publisher,
+	}
+}
@@ -0,0 +1,58 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/romariotrain/media-platform/internal/storage/mysql"
+)
+
+// MySQLImage — MySQL 8 с LATERAL и SKIP LOCKED
+const MySQLImage = "mysql:8.0.36"
+
+// MySQL — база в контейнере с применённой схемой
+type MySQL struct {
+	URL string // mysql://... — как DATABASE_URL сервиса
+	DB  *sqlx.DB
+}
+
+// StartMySQL запускает MySQL, применяет sql/mysql/script.sql и останавливает контейнер после теста
+func StartMySQL(t *testing.T) *MySQL {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	container, err := testcontainers.Run(ctx, MySQLImage,
+		testcontainers.WithExposedPorts("3306/tcp"),
+		testcontainers.WithEnv(map[string]string{
+			"MYSQL_DATABASE":      "media",
+			"MYSQL_USER":          "media",
+			"MYSQL_PASSWORD":      "media",
+			"MYSQL_ROOT_PASSWORD": "media",
+		}),
+		// Первый запуск поднимает временный сервер без сети; готов — когда слушает порт
+		testcontainers.WithWaitStrategy(wait.ForLog("port: 3306  MySQL Community Server - GPL").WithStartupTimeout(2*time.Minute)),
+	)
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "start mysql")
+
+	endpoint, err := container.PortEndpoint(ctx, "3306/tcp", "")
+	require.NoError(t, err)
+	url := fmt.Sprintf("mysql://media:media@%s/media", endpoint)
+
+	db, err := mysql.Connect(ctx, url)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	require.NoError(t, mysql.MigrateUp(ctx, db))
+	return &MySQL{URL: url, DB: db}
+}
@@ -0,0 +1,76 @@
+// Package testutil поднимает зависимости сервисов в контейнерах (testcontainers-go)
+// для интеграционных тестов. Тесты с ним помечаются тегом integration:
+//
+//	go test -tags=integration ./...
+//
+// Без доступного Docker тесты пропускаются.
+package testutil
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	pg "github.com/romariotrain/media-platform/internal/storage/postgres"
+)
+
+// PostgresImage — тот же образ, что в deploy/docker-compose.yml
+const PostgresImage = "postgres:16-alpine"
+
+// Postgres — база в контейнере с применённой схемой
+type Postgres struct {
+	DSN  string
+	Pool *pgxpool.Pool
+	DB   *sqlx.DB
+}
+
+// StartPostgres запускает Postgres, применяет sql/script.sql и останавливает контейнер после теста
+func StartPostgres(t *testing.T) *Postgres {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	container, err := tcpostgres.Run(ctx, PostgresImage,
+		tcpostgres.WithDatabase("media"),
+		tcpostgres.WithUsername("media"),
+		tcpostgres.WithPassword("media"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "start postgres")
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	pool, err := pg.NewPool(ctx, pg.PoolConfig{DSN: dsn})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	require.NoError(t, Migrate(ctx, pool))
+
+	db := pg.OpenDB(pool)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return &Postgres{DSN: dsn, Pool: pool, DB: db}
+}
+
+// Migrate применяет схему из sql/script.sql. Скрипт идемпотентен, повторный вызов безопасен.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	return pg.MigrateUp(ctx, pool)
+}
+
+// RepoRoot — корень репозитория, чтобы тесты из любого пакета находили sql/ и deploy/
+func RepoRoot() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..")
+}
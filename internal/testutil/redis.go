@@ -0,0 +1,39 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// RedisImage — тот же образ, что в deploy/docker-compose.yml
+const RedisImage = "redis:7-alpine"
+
+// StartRedis запускает Redis и останавливает контейнер после теста
+func StartRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	container, err := testcontainers.Run(ctx, RedisImage,
+		testcontainers.WithExposedPorts("6379/tcp"),
+		testcontainers.WithWaitStrategy(wait.ForLog("Ready to accept connections")),
+	)
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "start redis")
+
+	endpoint, err := container.PortEndpoint(ctx, "6379/tcp", "")
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: endpoint})
+	t.Cleanup(func() { _ = client.Close() })
+	require.NoError(t, client.Ping(ctx).Err())
+	return client
+}
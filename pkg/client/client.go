@@ -0,0 +1,399 @@
+// Package client — Go клиент HTTP API media платформы: медиа (media сервис) и загрузка
+// исходников (ingest). Запросы принимают context; ответы 429 и 5xx повторяются с backoff
+// с учётом Retry-After. Изменяющие запросы media API уходят с Idempotency-Key, общим для всех
+// попыток, поэтому повтор не выполнит их дважды. Ошибка API возвращается как *APIError
+// с кодом из тела ответа.
+//
+//	c, err := client.New(client.Config{
+//		BaseURL:   "https://api.example.com",
+//		IngestURL: "https://upload.example.com",
+//		Auth:      client.BearerToken(os.Getenv("MEDIA_TOKEN")),
+//	})
+//	m, err := c.CreateMedia(ctx, client.CreateMediaRequest{Type: client.Video, Source: "s3://media/in/1.mp4", Title: "Intro"})
+//	_, err = c.Upload(ctx, m.ID, client.UploadRequest{Body: f, Size: size})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMaxAttempts — попыток одного запроса вместе с первой
+	DefaultMaxAttempts = 4
+	// DefaultRetryBackoff — пауза перед первым повтором, дальше удваивается
+	DefaultRetryBackoff = 200 * time.Millisecond
+	// DefaultMaxBackoff — предел паузы, в том числе заданной Retry-After
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// maxResponseBytes — предел тела ответа, которое читает клиент
+const maxResponseBytes = 16 << 20
+
+// Config содержит конфигурацию Client
+type Config struct {
+	// BaseURL — адрес media API (через gateway или напрямую: http://media:8081)
+	BaseURL string
+	// IngestURL — адрес ingest API для Upload; пустой — Upload недоступен
+	IngestURL string
+	// Auth добавляет к запросу данные аутентификации; nil — запросы без них
+	Auth Auth
+	// HTTPClient — default: timeout 30s. Для загрузки больших исходников нужен клиент
+	// без общего timeout: предел задаёт context.
+	HTTPClient *http.Client
+	// UserAgent — default: media-platform-go-client
+	UserAgent string
+
+	// MaxAttempts — попыток запроса вместе с первой (default: DefaultMaxAttempts; 1 — без повторов)
+	MaxAttempts int
+	// RetryBackoff — пауза перед повтором, удваивается (default: DefaultRetryBackoff)
+	RetryBackoff time.Duration
+	// MaxBackoff — предел паузы; Retry-After больше него повтор не ждёт (default: DefaultMaxBackoff)
+	MaxBackoff time.Duration
+}
+
+// Client — клиент media API; безопасен для одновременного использования
+type Client struct {
+	baseURL   string
+	ingestURL string
+	auth      Auth
+	http      *http.Client
+	userAgent string
+
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error // тесты
+}
+
+func New(cfg Config) (*Client, error) {
+	if err := validURL(cfg.BaseURL); err != nil {
+		return nil, fmt.Errorf("base url: %w", err)
+	}
+	if cfg.IngestURL != "" {
+		if err := validURL(cfg.IngestURL); err != nil {
+			return nil, fmt.Errorf("ingest url: %w", err)
+		}
+	}
+	if cfg.MaxAttempts < 0 || cfg.RetryBackoff < 0 || cfg.MaxBackoff < 0 {
+		return nil, errors.New("client attempts and backoff cannot be negative")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "media-platform-go-client"
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		ingestURL:  strings.TrimSuffix(cfg.IngestURL, "/"),
+		auth:       cfg.Auth,
+		http:       cfg.HTTPClient,
+		userAgent:  cfg.UserAgent,
+		attempts:   cfg.MaxAttempts,
+		backoff:    cfg.RetryBackoff,
+		maxBackoff: max(cfg.MaxBackoff, cfg.RetryBackoff),
+		sleep:      sleep,
+	}, nil
+}
+
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid url %q", raw)
+	}
+	return nil
+}
+
+// Auth добавляет к запросу данные аутентификации
+type Auth interface {
+	Apply(req *http.Request) error
+}
+
+// AuthFunc — Auth из функции: подпись запроса, токен из своего источника и т.п.
+type AuthFunc func(req *http.Request) error
+
+func (f AuthFunc) Apply(req *http.Request) error { return f(req) }
+
+// BearerToken — токен gateway в Authorization: Bearer
+func BearerToken(token string) Auth {
+	return AuthFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// Principal — заголовки, которые gateway проставляет после аутентификации; для сервисов,
+// которые вызывают media напрямую во внутренней сети
+type Principal struct {
+	OwnerID string   // X-Owner-ID; пустой — не передаётся
+	Actor   string   // X-Actor — автор изменений в истории статусов
+	Scopes  []string // X-Scopes, например admin
+}
+
+func (p Principal) Apply(req *http.Request) error {
+	if p.OwnerID != "" {
+		req.Header.Set("X-Owner-ID", p.OwnerID)
+	}
+	if p.Actor != "" {
+		req.Header.Set("X-Actor", p.Actor)
+	}
+	if len(p.Scopes) > 0 {
+		req.Header.Set("X-Scopes", strings.Join(p.Scopes, " "))
+	}
+	return nil
+}
+
+// APIError — ответ API не 2xx (формат ошибки: code, message, details, request_id)
+type APIError struct {
+	StatusCode int            `json:"-"`
+	Code       string         `json:"code"`
+	Message    string         `json:"message"`
+	Details    map[string]any `json:"details,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	// RetryAfter — из заголовка Retry-After (429, 503); 0 — не передан
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("media api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.RequestID != "" {
+		msg += " (request_id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsNotFound сообщает, что err — ответ 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request — один вызов API; body повторяется при каждой попытке
+type request struct {
+	method  string
+	url     string
+	header  http.Header
+	body    []byte     // JSON тело; nil — без тела
+	stream  io.Reader  // тело загрузки вместо body; повторяется, только если это io.Seeker
+	size    int64      // длина stream
+	retries retryScope // какие ответы повторять
+}
+
+// retryScope — когда запрос безопасно повторить
+type retryScope int
+
+const (
+	// retryAll — идемпотентный запрос: повторяются 429, 5xx и сетевые ошибки
+	retryAll retryScope = iota
+	// retryKeyed — неидемпотентный запрос media API: уходит с Idempotency-Key, общим для всех
+	// попыток, и повторяется как идемпотентный; 409 request_in_progress тоже повторяется
+	retryKeyed
+)
+
+// IdempotencyKeyHeader — заголовок ключа повтора изменяющих запросов
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// codeRequestInProgress — запрос с тем же Idempotency-Key ещё выполняется
+const codeRequestInProgress = "request_in_progress"
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey задаёт Idempotency-Key изменяющего запроса вместо случайного: с одним
+// ключом повтор вызова после ошибки (например, после рестарта вызывающего) получит ответ
+// первого выполнения. Ключ — до 255 символов; сервис хранит его сутки.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// do выполняет запрос с повторами и раскладывает JSON ответ в out (nil — ответ не нужен).
+// Возвращает заголовки ответа.
+func (c *Client) do(ctx context.Context, req request, out any) (http.Header, error) {
+	if req.retries == retryKeyed {
+		key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+		if key == "" {
+			key = uuid.NewString()
+		}
+		header := req.header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set(IdempotencyKeyHeader, key)
+		req.header = header
+	}
+	seeker, _ := req.stream.(io.Seeker)
+	var start int64
+	if seeker != nil {
+		// Тело может начинаться не с начала файла: повтор читает с той же позиции
+		pos, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			seeker = nil
+		}
+		start = pos
+	}
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && seeker != nil {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("rewind body: %w", err)
+			}
+		}
+		header, err := c.once(ctx, req, out)
+		if err == nil {
+			return header, nil
+		}
+		if attempt >= c.attempts || ctx.Err() != nil || !c.retryable(req, seeker != nil, err) {
+			return nil, err
+		}
+		wait := jitter(backoff)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > c.maxBackoff {
+				// Ждать дольше, чем разрешено, бессмысленно: решает вызывающий
+				return nil, err
+			}
+			wait = apiErr.RetryAfter
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		backoff = min(backoff*2, c.maxBackoff)
+	}
+}
+
+// retryable сообщает, можно ли повторить запрос после ошибки err
+func (c *Client) retryable(req request, rewindable bool, err error) bool {
+	if req.stream != nil && !rewindable {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Сетевая ошибка: неизвестно, дошёл ли запрос, но повтор не выполнит его дважды
+		return true
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests, apiErr.StatusCode >= 500:
+		return true
+	case apiErr.StatusCode == http.StatusConflict && apiErr.Code == codeRequestInProgress:
+		return req.retries == retryKeyed
+	}
+	return false
+}
+
+func (c *Client) once(ctx context.Context, req request, out any) (http.Header, error) {
+	var body io.Reader
+	switch {
+	case req.stream != nil:
+		// Без обёртки http.Client закрыл бы файл вызывающего после первой попытки
+		body = io.NopCloser(req.stream)
+	case req.body != nil:
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.stream != nil {
+		httpReq.ContentLength = req.size
+		if req.size == 0 {
+			httpReq.Body = http.NoBody
+		}
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if c.auth != nil {
+		if err := c.auth.Apply(httpReq); err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("media api %s %s: %w", req.method, httpReq.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("media api %s %s: read response: %w", req.method, httpReq.URL.Path, err)
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, responseError(resp, raw)
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return nil, fmt.Errorf("media api %s %s: decode response: %w", req.method, httpReq.URL.Path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// responseError разбирает ошибку из тела; тело не в формате API — код по статусу ответа
+func responseError(resp *http.Response, raw []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Code == "" {
+		apiErr.Code, apiErr.Message = http.StatusText(resp.StatusCode), strings.TrimSpace(string(raw))
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	apiErr.RetryAfter = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return apiErr
+}
+
+// retryAfter разбирает Retry-After: секунды или HTTP-дата
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// jitter разбрасывает паузу на ±20%, чтобы клиенты после общего сбоя не повторяли разом
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()*0.4-0.2)*float64(d))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,556 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/romariotrain/media-platform/internal/ingest"
+	"github.com/romariotrain/media-platform/internal/media/httpapi"
+	"github.com/romariotrain/media-platform/internal/media/idempotency"
+	"github.com/romariotrain/media-platform/internal/media/repository"
+	"github.com/romariotrain/media-platform/internal/media/service"
+	"github.com/romariotrain/media-platform/internal/media/share"
+)
+
+// memorySink — хранилище исходников ingest в памяти
+type memorySink struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memorySink) Put(_ context.Context, source string, body io.Reader, size int64, _ string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return io.ErrUnexpectedEOF
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[source] = data
+	return nil
+}
+
+func (s *memorySink) Open(_ context.Context, source string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(s.objects[source])), nil
+}
+
+// failure — ответ flaky вместо сервиса
+type failure func(w http.ResponseWriter, r *http.Request)
+
+// flaky отвечает failures[i] на i-й запрос (nil — пропускает), остальные пропускает в next
+type flaky struct {
+	next     http.Handler
+	failures []failure
+	mu       sync.Mutex
+	requests []string // метод и путь каждого запроса
+	bodies   []string
+	keys     []string // Idempotency-Key каждого запроса
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	f.mu.Lock()
+	n := len(f.requests)
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.bodies = append(f.bodies, string(body))
+	f.keys = append(f.keys, r.Header.Get(IdempotencyKeyHeader))
+	f.mu.Unlock()
+	if n < len(f.failures) && f.failures[n] != nil {
+		f.failures[n](w, r)
+		return
+	}
+	f.next.ServeHTTP(w, r)
+}
+
+func status(code int, retryAfter string) failure {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_, _ = io.WriteString(w, `{"code":"unavailable","message":"try later"}`)
+	}
+}
+
+// lost выполняет запрос, но отвечает code: ответ сервиса потерян по дороге к клиенту
+func lost(next http.Handler, code int) failure {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(httptest.NewRecorder(), r)
+		status(code, "")(w, r)
+	}
+}
+
+// platform — media API и ingest на memory репозитории; клиент ходит к ним через flaky,
+// ingest к media — напрямую
+type platform struct {
+	media     *flaky
+	ingest    *flaky
+	mediaURL  string
+	ingestURL string
+	sink      *memorySink
+	sleeps    []time.Duration
+}
+
+func newPlatform(t *testing.T) *platform {
+	t.Helper()
+	svc := service.New(repository.NewMemoryRepository(), nil).
+		WithCollections(repository.NewMemoryCollectionRepository()).
+		WithGrants(repository.NewMemoryGrantRepository())
+	shares, err := share.New(share.Config{Secret: []byte("0123456789abcdef0123456789abcdef")})
+	require.NoError(t, err)
+	router := httpapi.NewRouter(httpapi.New(svc).
+		WithShareLinks(shares).
+		WithIdempotency(idempotency.NewMemoryStore(), time.Hour))
+	internal := httptest.NewServer(router)
+	t.Cleanup(internal.Close)
+	mediaClient, err := ingest.NewMediaClient(internal.URL, nil)
+	require.NoError(t, err)
+	p := &platform{sink: &memorySink{objects: map[string][]byte{}}}
+	h, err := ingest.NewHandler(ingest.HandlerConfig{Media: mediaClient, Sink: p.sink, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+
+	p.media, p.ingest = &flaky{next: router}, &flaky{next: h}
+	for _, s := range []struct {
+		handler http.Handler
+		url     *string
+	}{{p.media, &p.mediaURL}, {p.ingest, &p.ingestURL}} {
+		srv := httptest.NewServer(s.handler)
+		t.Cleanup(srv.Close)
+		*s.url = srv.URL
+	}
+	return p
+}
+
+// newClient — клиент платформы p; паузы между повторами записываются, а не выжидаются
+func newClient(t *testing.T, p *platform, auth Auth) *Client {
+	t.Helper()
+	c, err := New(Config{BaseURL: p.mediaURL, IngestURL: p.ingestURL, Auth: auth})
+	require.NoError(t, err)
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		p.sleeps = append(p.sleeps, d)
+		return nil
+	}
+	return c
+}
+
+func TestClient_MediaLifecycle(t *testing.T) {
+	ctx := context.Background()
+	p := newPlatform(t)
+	owner := uuid.New()
+	c := newClient(t, p, Principal{OwnerID: owner.String(), Actor: "tester"})
+
+	created, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	require.Equal(t, StatusUploaded, created.Status)
+	require.Equal(t, owner, created.OwnerID)
+
+	got, err := c.GetMedia(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, got.ID)
+	require.NotEmpty(t, got.ETag)
+
+	changed, err := c.ChangeStatus(ctx, created.ID, ChangeStatusRequest{Status: StatusProcessing, IfMatch: got.ETag})
+	require.NoError(t, err)
+	require.Equal(t, StatusProcessing, changed.Status)
+	require.NotEqual(t, got.ETag, changed.ETag)
+
+	// Медиа изменилось после чтения: условная смена статуса отклоняется
+	_, err = c.ChangeStatus(ctx, created.ID, ChangeStatusRequest{Status: StatusFailed, Reason: "x", IfMatch: got.ETag})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusPreconditionFailed, apiErr.StatusCode)
+
+	_, err = c.CreateMedia(ctx, CreateMediaRequest{Type: Audio, Source: "s3://media/in/2.mp3", Title: "clip"})
+	require.NoError(t, err)
+	list, err := c.ListMedia(ctx, ListOptions{Status: StatusProcessing})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, created.ID, list.Items[0].ID)
+	require.Empty(t, list.NextCursor)
+	require.Nil(t, list.Total)
+	list, err = c.ListMedia(ctx, ListOptions{Limit: 1, Count: CountExact, Facets: true})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, int64(2), *list.Total)
+	require.Equal(t, map[Status]int64{StatusProcessing: 1, StatusUploaded: 1}, list.Facets.Status)
+	require.Equal(t, map[MediaType]int64{Video: 1, Audio: 1}, list.Facets.Type)
+	found, err := c.SearchMedia(ctx, SearchOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, found.Items, 1)
+	require.Equal(t, 1, found.Limit)
+
+	results, err := c.ChangeStatusBatch(ctx, []StatusBatchItem{
+		{ID: created.ID, Status: StatusReady},
+		{ID: uuid.New(), Status: StatusReady},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "changed", results[0].Status)
+	require.Equal(t, StatusReady, results[0].Media.Status)
+	require.Equal(t, "not_found", results[1].Code)
+
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheduled, err := c.SetSchedule(ctx, created.ID, Schedule{PublishAt: &publishAt})
+	require.NoError(t, err)
+	require.Equal(t, StatusScheduled, scheduled.Status)
+	require.True(t, publishAt.Equal(*scheduled.PublishAt))
+	require.Nil(t, scheduled.ExpiresAt)
+
+	require.Equal(t, VisibilityDraft, scheduled.Visibility)
+	public, err := c.SetVisibility(ctx, created.ID, SetVisibilityRequest{Visibility: VisibilityPublic})
+	require.NoError(t, err)
+	require.Equal(t, VisibilityPublic, public.Visibility)
+	// Под эмбарго медиа не опубликовано и видно только владельцу
+	require.Equal(t, VisibilityPrivate, public.EffectiveVisibility)
+	require.NotEmpty(t, public.ETag)
+	list, err = c.ListMedia(ctx, ListOptions{Visibility: VisibilityPublic})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+
+	require.NoError(t, c.DeleteMedia(ctx, created.ID))
+	_, err = c.GetMedia(ctx, uuid.New())
+	require.True(t, IsNotFound(err))
+}
+
+func TestClient_ReportFailure(t *testing.T) {
+	ctx := context.Background()
+	p := newPlatform(t)
+	c := newClient(t, p, Principal{Actor: "processing", Scopes: []string{"internal"}})
+
+	owner := newClient(t, p, Principal{OwnerID: uuid.NewString()})
+	created, err := owner.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	_, err = owner.ChangeStatus(ctx, created.ID, ChangeStatusRequest{Status: StatusProcessing})
+	require.NoError(t, err)
+
+	// Пока попытки есть, media запрашивает повтор
+	for attempt := 1; attempt < 3; attempt++ {
+		m, err := c.ReportFailure(ctx, created.ID, "decoder crashed")
+		require.NoError(t, err)
+		require.Equal(t, StatusProcessing, m.Status)
+		require.Equal(t, attempt+1, m.ProcessingAttempts)
+	}
+	m, err := c.ReportFailure(ctx, created.ID, "decoder crashed")
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, m.Status)
+
+	// Не в processing — отказ, повторять нечего
+	_, err = c.ReportFailure(ctx, created.ID, "decoder crashed")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+}
+
+func TestClient_ListMediaPager(t *testing.T) {
+	p := newPlatform(t)
+	c := newClient(t, p, Principal{OwnerID: uuid.NewString()})
+	ctx := context.Background()
+
+	var created []uuid.UUID
+	for i := range 5 {
+		m, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: fmt.Sprintf("s3://media/in/%d.mp4", i), Title: "clip"})
+		require.NoError(t, err)
+		created = append(created, m.ID)
+	}
+
+	first, err := c.ListMedia(ctx, ListOptions{Limit: 2, Sort: SortOldest})
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	pager := c.ListMediaPager(ListOptions{Limit: 2, Sort: SortOldest})
+	var walked []uuid.UUID
+	for pager.Next(ctx) {
+		walked = append(walked, pager.Media().ID)
+	}
+	require.NoError(t, pager.Err())
+	require.Equal(t, created, walked)
+	require.Empty(t, pager.Cursor())
+	require.False(t, pager.Next(ctx))
+
+	// Курсор другой сортировки отклоняется
+	_, err = c.ListMedia(ctx, ListOptions{Limit: 2, Sort: SortNewest, Cursor: first.NextCursor})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+
+	pager = c.ListMediaPager(ListOptions{Type: Audio})
+	require.False(t, pager.Next(ctx))
+	require.NoError(t, pager.Err())
+}
+
+func TestClient_Collections(t *testing.T) {
+	ctx := context.Background()
+	p := newPlatform(t)
+	c := newClient(t, p, Principal{OwnerID: uuid.NewString()})
+
+	first, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	second, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Audio, Source: "s3://media/in/2.mp3", Title: "clip"})
+	require.NoError(t, err)
+
+	coll, err := c.CreateCollection(ctx, CreateCollectionRequest{Title: "Trip"})
+	require.NoError(t, err)
+	require.Zero(t, coll.ItemCount)
+
+	_, err = c.AddCollectionItem(ctx, coll.ID, first.ID, nil)
+	require.NoError(t, err)
+	head := 0
+	item, err := c.AddCollectionItem(ctx, coll.ID, second.ID, &head)
+	require.NoError(t, err)
+	require.Equal(t, 0, item.Position)
+	_, err = c.AddCollectionItem(ctx, coll.ID, second.ID, nil)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+
+	title := "Trip 2026"
+	updated, err := c.UpdateCollection(ctx, coll.ID, UpdateCollectionRequest{Title: &title})
+	require.NoError(t, err)
+	require.Equal(t, title, updated.Title)
+	require.Equal(t, 2, updated.ItemCount)
+
+	got, err := c.GetCollection(ctx, coll.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Items, 2)
+	require.Equal(t, []uuid.UUID{second.ID, first.ID}, []uuid.UUID{got.Items[0].MediaID, got.Items[1].MediaID})
+
+	// Удалённое медиа уходит из коллекции
+	require.NoError(t, c.DeleteMedia(ctx, second.ID))
+	require.NoError(t, c.RemoveCollectionItem(ctx, coll.ID, first.ID))
+	list, err := c.ListCollections(ctx, CollectionListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Zero(t, list.Items[0].ItemCount)
+
+	require.NoError(t, c.DeleteCollection(ctx, coll.ID))
+	_, err = c.GetCollection(ctx, coll.ID)
+	require.True(t, IsNotFound(err))
+}
+
+func TestClient_Grants(t *testing.T) {
+	ctx := context.Background()
+	p := newPlatform(t)
+	owner, reader := uuid.New(), uuid.New()
+	c := newClient(t, p, Principal{OwnerID: owner.String()})
+	other := newClient(t, p, Principal{OwnerID: reader.String()})
+
+	m, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	_, err = other.GetMedia(ctx, m.ID)
+	require.True(t, IsNotFound(err))
+
+	g, err := c.GrantAccess(ctx, m.ID, reader, PermissionRead)
+	require.NoError(t, err)
+	require.Equal(t, PermissionRead, g.Permission)
+	got, err := other.GetMedia(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, got.ID)
+
+	grants, err := c.ListGrants(ctx, m.ID)
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	require.Equal(t, reader, grants[0].Principal)
+
+	link, err := c.CreateShareLink(ctx, m.ID, ShareLinkRequest{TTL: time.Hour})
+	require.NoError(t, err)
+	require.Equal(t, PermissionRead, link.Permission)
+	shared, err := newClient(t, p, nil).GetSharedMedia(ctx, link.Token)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, shared.ID)
+	require.Equal(t, Video, shared.Type)
+	_, err = other.GetSharedMedia(ctx, link.Token+"x")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+
+	require.NoError(t, c.RevokeAccess(ctx, m.ID, reader))
+	_, err = other.GetMedia(ctx, m.ID)
+	require.True(t, IsNotFound(err))
+	require.True(t, IsNotFound(c.RevokeAccess(ctx, m.ID, reader)))
+}
+
+func TestClient_ValidationError(t *testing.T) {
+	p := newPlatform(t)
+	c := newClient(t, p, nil)
+
+	_, err := c.CreateMedia(context.Background(), CreateMediaRequest{Type: "image"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	require.Equal(t, "validation_failed", apiErr.Code)
+	require.Contains(t, apiErr.Details, "fields")
+	require.NotEmpty(t, apiErr.RequestID)
+	require.Len(t, p.media.requests, 1)
+}
+
+func TestClient_Upload(t *testing.T) {
+	ctx := context.Background()
+	p := newPlatform(t)
+	c := newClient(t, p, Principal{OwnerID: uuid.NewString()})
+
+	m, err := c.CreateMedia(ctx, CreateMediaRequest{Type: File, Source: "s3://media/in/notes.txt", Title: "clip"})
+	require.NoError(t, err)
+
+	content := []byte("meeting notes\n")
+	sum := sha256.Sum256(content)
+	// Первая попытка обрывается 502: тело отправляется заново целиком
+	p.ingest.failures = []failure{status(http.StatusBadGateway, "")}
+	result, err := c.Upload(ctx, m.ID, UploadRequest{
+		Body:           bytes.NewReader(content),
+		Size:           int64(len(content)),
+		ChecksumSHA256: hex.EncodeToString(sum[:]),
+	})
+	require.NoError(t, err)
+	require.Equal(t, m.ID, result.MediaID)
+	require.Equal(t, int64(len(content)), result.Size)
+	require.Equal(t, []string{string(content), string(content)}, p.ingest.bodies)
+	require.Equal(t, content, p.sink.objects[m.Source])
+
+	got, err := c.GetMedia(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(sum[:]), got.Checksum)
+
+	// Тело без Seek повторить нельзя
+	p.ingest.failures = append(p.ingest.failures, nil, status(http.StatusBadGateway, ""))
+	_, err = c.Upload(ctx, m.ID, UploadRequest{Body: io.LimitReader(bytes.NewReader(content), 100), Size: int64(len(content))})
+	require.Error(t, err)
+	require.Len(t, p.ingest.requests, 3)
+
+	_, err = c.Upload(ctx, m.ID, UploadRequest{Body: bytes.NewReader(content), Size: int64(len(content)), ChecksumSHA256: strings.Repeat("0", 64)})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+}
+
+func TestClient_Retries(t *testing.T) {
+	ctx := context.Background()
+	p := newPlatform(t)
+	c := newClient(t, p, Principal{OwnerID: uuid.NewString()})
+
+	// 429 и 503 повторяются и для POST; Retry-After важнее backoff
+	p.media.failures = []failure{
+		status(http.StatusTooManyRequests, "2"),
+		status(http.StatusServiceUnavailable, ""),
+	}
+	m, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	require.Len(t, p.media.requests, 3)
+	require.Equal(t, 2*time.Second, p.sleeps[0])
+	require.InDelta(t, float64(DefaultRetryBackoff*2), float64(p.sleeps[1]), float64(DefaultRetryBackoff*2)/5)
+
+	// 500 на POST повторяется с тем же Idempotency-Key: выполненный запрос не выполняется
+	// снова, повтор получает его ответ
+	require.NotEmpty(t, p.media.keys[0])
+	require.Equal(t, p.media.keys[0], p.media.keys[2])
+	p.media.failures = append(p.media.failures, nil, lost(p.media.next, http.StatusInternalServerError))
+	second, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/2.mp4", Title: "clip"})
+	require.NoError(t, err)
+	require.Len(t, p.media.requests, 5)
+	require.Equal(t, p.media.keys[3], p.media.keys[4])
+	require.NotEqual(t, p.media.keys[0], p.media.keys[3])
+	list, err := c.ListMedia(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 2)
+	require.NotEqual(t, m.ID, second.ID)
+
+	// GET повторяется и на 500, но не больше MaxAttempts
+	p.media.failures = append(p.media.failures, nil, nil, status(500, ""), status(502, ""), status(504, ""), status(500, ""))
+	_, err = c.GetMedia(ctx, m.ID)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	require.Empty(t, p.media.keys[6])
+	require.Len(t, p.media.requests, 6+DefaultMaxAttempts)
+
+	// Retry-After дольше MaxBackoff не ждётся
+	p.media.failures = append(p.media.failures, status(http.StatusServiceUnavailable, "3600"))
+	_, err = c.GetMedia(ctx, m.ID)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, time.Hour, apiErr.RetryAfter)
+	require.Len(t, p.media.requests, 7+DefaultMaxAttempts)
+
+	got, err := c.GetMedia(ctx, m.ID)
+	require.NoError(t, err)
+	require.Equal(t, m.ID, got.ID)
+}
+
+func TestClient_IdempotencyKey(t *testing.T) {
+	ctx := WithIdempotencyKey(context.Background(), "import-42")
+	p := newPlatform(t)
+	c := newClient(t, p, Principal{OwnerID: uuid.NewString()})
+
+	// Повтор вызова с тем же ключом получает ответ первого; 409 request_in_progress повторяется
+	p.media.failures = []failure{nil, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, `{"code":"request_in_progress","message":"in progress"}`)
+	}}
+	first, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	again, err := c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/1.mp4", Title: "clip"})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, again.ID)
+	require.Equal(t, []string{"import-42", "import-42", "import-42"}, p.media.keys)
+
+	// Тот же ключ с другим запросом — ошибка клиента
+	_, err = c.CreateMedia(ctx, CreateMediaRequest{Type: Video, Source: "s3://media/in/2.mp4", Title: "clip"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	require.Equal(t, "idempotency_key_reused", apiErr.Code)
+}
+
+func TestClient_ContextCancel(t *testing.T) {
+	p := newPlatform(t)
+	c, err := New(Config{BaseURL: p.mediaURL, RetryBackoff: time.Hour, MaxBackoff: time.Hour})
+	require.NoError(t, err)
+
+	p.media.failures = []failure{status(http.StatusServiceUnavailable, "")}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.GetMedia(ctx, uuid.New())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, 5*time.Second, retryAfter("5", now))
+	require.Equal(t, 30*time.Second, retryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	require.Zero(t, retryAfter("soon", now))
+	require.Zero(t, retryAfter("", now))
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Config{})
+	require.Error(t, err)
+	_, err = New(Config{BaseURL: "http://media", IngestURL: "ftp://ingest"})
+	require.Error(t, err)
+	_, err = New(Config{BaseURL: "http://media", MaxAttempts: -1})
+	require.Error(t, err)
+
+	c, err := New(Config{BaseURL: "http://media"})
+	require.NoError(t, err)
+	_, err = c.Upload(context.Background(), uuid.New(), UploadRequest{Body: strings.NewReader("x"), Size: 1})
+	require.ErrorContains(t, err, "ingest url")
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Collection — коллекция (упорядоченная подборка медиа) в ответе API
+type Collection struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id,omitzero"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Items — медиа коллекции по порядку; заполняется только GetCollection
+	Items []CollectionItem `json:"items,omitempty"`
+}
+
+// CollectionItem — медиа в коллекции
+type CollectionItem struct {
+	MediaID  uuid.UUID `json:"media_id"`
+	Position int       `json:"position"` // место с нуля
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// CreateCollectionRequest — тело POST /collections
+type CreateCollectionRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// CreateCollection — POST /collections. Создание не идемпотентно: повторяются только ответы 429 и 503.
+func (c *Client) CreateCollection(ctx context.Context, req CreateCollectionRequest) (*Collection, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var coll Collection
+	if _, err := c.do(ctx, request{method: http.MethodPost, url: c.baseURL + "/collections", body: body, retries: retryKeyed}, &coll); err != nil {
+		return nil, err
+	}
+	return &coll, nil
+}
+
+// GetCollection — GET /collections/{id}: коллекция вместе с Items. Чужая или отсутствующая
+// коллекция — *APIError 404 (IsNotFound).
+func (c *Client) GetCollection(ctx context.Context, id uuid.UUID) (*Collection, error) {
+	var coll Collection
+	if _, err := c.do(ctx, request{method: http.MethodGet, url: c.collectionURL(id, "")}, &coll); err != nil {
+		return nil, err
+	}
+	return &coll, nil
+}
+
+// CollectionListOptions — параметры ListCollections; пустые не применяются
+type CollectionListOptions struct {
+	Limit  int    // 0 — по умолчанию сервиса (20), не больше 100
+	Cursor string // CollectionList.NextCursor предыдущей страницы
+	Sort   Sort   // SortNewest или SortOldest
+	// OwnerID — коллекции другого владельца; только со scope admin
+	OwnerID string
+}
+
+// CollectionList — страница ListCollections
+type CollectionList struct {
+	Items []Collection `json:"items"`
+	// NextCursor — Cursor следующей страницы; пустой — страница последняя
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListCollections — GET /collections: одна страница коллекций вызывающего
+func (c *Client) ListCollections(ctx context.Context, opts CollectionListOptions) (*CollectionList, error) {
+	query := url.Values{}
+	if opts.Limit != 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.Sort != "" {
+		query.Set("sort", string(opts.Sort))
+	}
+	if opts.OwnerID != "" {
+		query.Set("filter[owner_id]", opts.OwnerID)
+	}
+	u := c.baseURL + "/collections"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var list CollectionList
+	header, err := c.do(ctx, request{method: http.MethodGet, url: u}, &list)
+	if err != nil {
+		return nil, err
+	}
+	if list.NextCursor == "" {
+		list.NextCursor = header.Get("X-Next-Cursor")
+	}
+	return &list, nil
+}
+
+// UpdateCollectionRequest — изменение коллекции; nil поле не меняется
+type UpdateCollectionRequest struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// UpdateCollection — PATCH /collections/{id}. Повтор тех же значений ничего не меняет,
+// поэтому запрос повторяется как идемпотентный.
+func (c *Client) UpdateCollection(ctx context.Context, id uuid.UUID, req UpdateCollectionRequest) (*Collection, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var coll Collection
+	if _, err := c.do(ctx, request{method: http.MethodPatch, url: c.collectionURL(id, ""), body: body}, &coll); err != nil {
+		return nil, err
+	}
+	return &coll, nil
+}
+
+// DeleteCollection — DELETE /collections/{id}; медиа коллекции не удаляются
+func (c *Client) DeleteCollection(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, url: c.collectionURL(id, "")}, nil)
+	return err
+}
+
+// AddCollectionItem — POST /collections/{id}/items: медиа на место position (с нуля;
+// nil — в конец). Медиа уже в коллекции — *APIError 409, поэтому повторяются только
+// ответы 429 и 503.
+func (c *Client) AddCollectionItem(ctx context.Context, id, mediaID uuid.UUID, position *int) (*CollectionItem, error) {
+	body, err := json.Marshal(struct {
+		MediaID  uuid.UUID `json:"media_id"`
+		Position *int      `json:"position,omitempty"`
+	}{mediaID, position})
+	if err != nil {
+		return nil, err
+	}
+	var item CollectionItem
+	if _, err := c.do(ctx, request{method: http.MethodPost, url: c.collectionURL(id, "/items"), body: body, retries: retryKeyed}, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// RemoveCollectionItem — DELETE /collections/{id}/items/{media_id}; медиа нет в коллекции —
+// *APIError 404
+func (c *Client) RemoveCollectionItem(ctx context.Context, id, mediaID uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, url: c.collectionURL(id, "/items/"+mediaID.String())}, nil)
+	return err
+}
+
+func (c *Client) collectionURL(id uuid.UUID, suffix string) string {
+	return c.baseURL + "/collections/" + id.String() + suffix
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission — право на чужое медиа
+type Permission string
+
+const (
+	PermissionRead     Permission = "read"     // медиа как по GetMedia
+	PermissionDownload Permission = "download" // ещё и ссылка на исходник
+)
+
+// Grant — запись ACL медиа
+type Grant struct {
+	Principal  uuid.UUID  `json:"principal"`
+	Permission Permission `json:"permission"`
+	GrantedBy  string     `json:"granted_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// GrantAccess — PUT /media/{id}/grants/{principal}: открывает медиа пользователю principal
+// или меняет его право. Повтор того же права ничего не меняет, поэтому запрос повторяется
+// как идемпотентный.
+func (c *Client) GrantAccess(ctx context.Context, id, principal uuid.UUID, perm Permission) (*Grant, error) {
+	body, err := json.Marshal(struct {
+		Permission Permission `json:"permission"`
+	}{perm})
+	if err != nil {
+		return nil, err
+	}
+	var g Grant
+	if _, err := c.do(ctx, request{method: http.MethodPut, url: c.mediaURL(id, "/grants/"+principal.String()), body: body}, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// RevokeAccess — DELETE /media/{id}/grants/{principal}; права нет — *APIError 404
+func (c *Client) RevokeAccess(ctx context.Context, id, principal uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, url: c.mediaURL(id, "/grants/"+principal.String())}, nil)
+	return err
+}
+
+// ListGrants — GET /media/{id}/grants: кому открыто медиа, в порядке выдачи
+func (c *Client) ListGrants(ctx context.Context, id uuid.UUID) ([]Grant, error) {
+	var out struct {
+		Items []Grant `json:"items"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, url: c.mediaURL(id, "/grants")}, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// ShareLinkRequest — параметры анонимной ссылки; пустые — по умолчанию сервиса
+type ShareLinkRequest struct {
+	Permission Permission    `json:"permission,omitempty"` // пустое — PermissionRead
+	TTL        time.Duration `json:"-"`                    // 0 — срок по умолчанию (24h)
+}
+
+// ShareLink — анонимная ссылка на медиа; отозвать её до ExpiresAt нельзя
+type ShareLink struct {
+	URL        string     `json:"url"`
+	Token      string     `json:"token"`
+	Permission Permission `json:"permission"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// CreateShareLink — POST /media/{id}/share. Каждый вызов выдаёт новую ссылку, поэтому
+// повторяются только ответы 429 и 503.
+func (c *Client) CreateShareLink(ctx context.Context, id uuid.UUID, req ShareLinkRequest) (*ShareLink, error) {
+	payload := struct {
+		Permission Permission `json:"permission,omitempty"`
+		TTL        string     `json:"ttl,omitempty"`
+	}{Permission: req.Permission}
+	if req.TTL > 0 {
+		payload.TTL = req.TTL.String()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var link ShareLink
+	if _, err := c.do(ctx, request{method: http.MethodPost, url: c.mediaURL(id, "/share"), body: body, retries: retryKeyed}, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// SharedMedia — медиа по анонимной ссылке: без Source и владельца
+type SharedMedia struct {
+	ID          uuid.UUID  `json:"id"`
+	Type        MediaType  `json:"type"`
+	Status      Status     `json:"status"`
+	Title       string     `json:"title,omitempty"`
+	ContentType string     `json:"content_type,omitempty"`
+	Size        int64      `json:"size_bytes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Permission  Permission `json:"permission"`
+	ExpiresAt   time.Time  `json:"expires_at"` // срок ссылки
+}
+
+// GetSharedMedia — GET /shared/{token}. Недействительная или истёкшая ссылка — *APIError 403.
+func (c *Client) GetSharedMedia(ctx context.Context, token string) (*SharedMedia, error) {
+	var m SharedMedia
+	if _, err := c.do(ctx, request{method: http.MethodGet, url: c.baseURL + "/shared/" + token}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,530 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MediaType — тип медиа
+type MediaType string
+
+const (
+	Video MediaType = "video"
+	Audio MediaType = "audio"
+	File  MediaType = "file"
+)
+
+// Status — статус медиа
+type Status string
+
+const (
+	StatusUploaded    Status = "uploaded"
+	StatusProcessing  Status = "processing"
+	StatusReady       Status = "ready"
+	StatusScheduled   Status = "scheduled" // обработано, но под эмбарго до PublishAt
+	StatusFailed      Status = "failed"
+	StatusDeleted     Status = "deleted"
+	StatusArchived    Status = "archived"
+	StatusQuarantined Status = "quarantined"
+)
+
+// Visibility — кому видно медиа
+type Visibility string
+
+const (
+	VisibilityDraft    Visibility = "draft"    // только владельцу, ещё не решено
+	VisibilityPrivate  Visibility = "private"  // только владельцу
+	VisibilityUnlisted Visibility = "unlisted" // всем, у кого есть id, но не в чужих списках
+	VisibilityPublic   Visibility = "public"   // всем
+)
+
+// Media — медиа в ответе API
+type Media struct {
+	ID        uuid.UUID `json:"id"`
+	Status    Status    `json:"status"`
+	Type      MediaType `json:"type"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Title    string            `json:"title,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	ProcessingAttempts int    `json:"processing_attempts"`
+	LastError          string `json:"last_error,omitempty"`
+
+	OwnerID uuid.UUID `json:"owner_id,omitzero"`
+
+	Checksum    string `json:"checksum_sha256,omitempty"`
+	Size        int64  `json:"size_bytes,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Visibility — заданная владельцем видимость; EffectiveVisibility — действующая сейчас:
+	// unlisted и public действуют только для медиа в статусе ready, иначе это private
+	Visibility          Visibility `json:"visibility"`
+	EffectiveVisibility Visibility `json:"effective_visibility"`
+
+	// ETag — версия медиа из ответа GetMedia и ChangeStatus: передаётся в
+	// ChangeStatusRequest.IfMatch, чтобы не перезаписать чужое изменение
+	ETag string `json:"-"`
+}
+
+// CreateMediaRequest — тело POST /media
+type CreateMediaRequest struct {
+	Type   MediaType `json:"type"`
+	Source string    `json:"source"`
+
+	Title    string            `json:"title"` // обязателен, до 200 символов
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// CreateMedia — POST /media. Создание не идемпотентно: повторяются только ответы 429 и 503.
+func (c *Client) CreateMedia(ctx context.Context, req CreateMediaRequest) (*Media, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var m Media
+	if _, err := c.do(ctx, request{method: http.MethodPost, url: c.baseURL + "/media", body: body, retries: retryKeyed}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMedia — GET /media/{id}; отсутствующее медиа — *APIError 404 (IsNotFound)
+func (c *Client) GetMedia(ctx context.Context, id uuid.UUID) (*Media, error) {
+	var m Media
+	header, err := c.do(ctx, request{method: http.MethodGet, url: c.mediaURL(id, "")}, &m)
+	if err != nil {
+		return nil, err
+	}
+	m.ETag = header.Get("ETag")
+	return &m, nil
+}
+
+// Sort — порядок ListMedia: поле, "-" впереди — по убыванию
+type Sort string
+
+const (
+	SortNewest          Sort = "-created_at" // по умолчанию
+	SortOldest          Sort = "created_at"
+	SortRecentlyUpdated Sort = "-updated_at"
+	SortLeastUpdated    Sort = "updated_at"
+)
+
+// ListOptions — параметры ListMedia; пустые не применяются
+type ListOptions struct {
+	Limit      int    // 0 — по умолчанию сервиса (20), не больше 100
+	Cursor     string // MediaList.NextCursor предыдущей страницы
+	Sort       Sort   // курсор действует только с той же сортировкой
+	Status     Status
+	Type       MediaType
+	Visibility Visibility
+	// OwnerID — только медиа владельца; без scope admin чужой владелец отдаёт только
+	// его опубликованное public медиа
+	OwnerID string
+
+	Count  Count // заполняет MediaList.Total
+	Facets bool  // заполняет MediaList.Facets
+}
+
+// Count — как ListMedia считает MediaList.Total
+type Count string
+
+const (
+	CountExact Count = "exact"
+	// CountEstimated — на больших таблицах оценка вместо точного подсчёта
+	CountEstimated Count = "estimated"
+)
+
+// MediaList — страница ListMedia
+type MediaList struct {
+	Items []Media `json:"items"`
+	// NextCursor — Cursor следующей страницы; пустой — страница последняя
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Total — с ListOptions.Count: медиа под фильтром на всех страницах; TotalEstimated — это оценка
+	Total          *int64 `json:"total,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	// Facets — с ListOptions.Facets
+	Facets *Facets `json:"facets,omitempty"`
+}
+
+// Facets — медиа под фильтром на всех страницах по статусам и типам
+type Facets struct {
+	Status map[Status]int64    `json:"status"`
+	Type   map[MediaType]int64 `json:"type"`
+}
+
+// ListMedia — GET /media: одна страница медиа, доступных вызывающему. Все страницы
+// обходит ListMediaPager.
+func (c *Client) ListMedia(ctx context.Context, opts ListOptions) (*MediaList, error) {
+	query := url.Values{}
+	if opts.Limit != 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.Sort != "" {
+		query.Set("sort", string(opts.Sort))
+	}
+	if opts.Count != "" {
+		query.Set("count", string(opts.Count))
+	}
+	if opts.Facets {
+		query.Set("facets", "true")
+	}
+	for field, value := range map[string]string{
+		"status": string(opts.Status), "type": string(opts.Type), "visibility": string(opts.Visibility), "owner_id": opts.OwnerID,
+	} {
+		if value != "" {
+			query.Set("filter["+field+"]", value)
+		}
+	}
+	u := c.baseURL + "/media"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var list MediaList
+	header, err := c.do(ctx, request{method: http.MethodGet, url: u}, &list)
+	if err != nil {
+		return nil, err
+	}
+	if list.NextCursor == "" {
+		list.NextCursor = header.Get("X-Next-Cursor")
+	}
+	return &list, nil
+}
+
+// ListMediaPager обходит все страницы ListMedia
+//
+//	pager := c.ListMediaPager(client.ListOptions{Status: client.StatusReady})
+//	for pager.Next(ctx) {
+//		handle(pager.Media())
+//	}
+//	if err := pager.Err(); err != nil { ... }
+type ListMediaPager struct {
+	client *Client
+	opts   ListOptions
+	page   []Media
+	pos    int
+	media  Media
+	done   bool
+	err    error
+}
+
+// ListMediaPager — обход медиа по opts начиная с opts.Cursor (пустой — с начала)
+func (c *Client) ListMediaPager(opts ListOptions) *ListMediaPager {
+	return &ListMediaPager{client: c, opts: opts}
+}
+
+// Next переходит к следующему медиа, запрашивая страницы по мере надобности; false — медиа
+// кончились или запрос не удался (см. Err)
+func (p *ListMediaPager) Next(ctx context.Context) bool {
+	for p.pos >= len(p.page) {
+		if p.done || p.err != nil {
+			return false
+		}
+		list, err := p.client.ListMedia(ctx, p.opts)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.page, p.pos = list.Items, 0
+		p.opts.Cursor = list.NextCursor
+		p.done = list.NextCursor == ""
+	}
+	p.media = p.page[p.pos]
+	p.pos++
+	return true
+}
+
+// Media — текущее медиа после успешного Next
+func (p *ListMediaPager) Media() Media { return p.media }
+
+// Cursor — курсор страницы, следующей за уже запрошенными: с ним обход продолжается
+// новым ListMediaPager (например, после перезапуска). Пустой — страниц больше нет.
+func (p *ListMediaPager) Cursor() string { return p.opts.Cursor }
+
+// Err — ошибка запроса страницы, прервавшая обход
+func (p *ListMediaPager) Err() error { return p.err }
+
+// SearchOptions — параметры SearchMedia; пустые не применяются
+type SearchOptions struct {
+	Query  string   // полнотекстовый поиск по title и описанию
+	Tags   []string // медиа со всеми тегами
+	Status Status
+	Limit  int // 0 — по умолчанию сервиса (20)
+	Offset int
+}
+
+// SearchHit — медиа в выдаче поиска и его релевантность
+type SearchHit struct {
+	Media Media   `json:"media"`
+	Rank  float64 `json:"rank"`
+}
+
+// SearchResult — страница SearchMedia
+type SearchResult struct {
+	Items  []SearchHit `json:"items"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// SearchMedia — GET /media/search: медиа по тексту, отсортированные по релевантности
+func (c *Client) SearchMedia(ctx context.Context, opts SearchOptions) (*SearchResult, error) {
+	query := url.Values{}
+	if opts.Query != "" {
+		query.Set("q", opts.Query)
+	}
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+	if opts.Status != "" {
+		query.Set("status", string(opts.Status))
+	}
+	if opts.Limit != 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset != 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	u := c.baseURL + "/media/search"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var result SearchResult
+	if _, err := c.do(ctx, request{method: http.MethodGet, url: u}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ChangeStatusRequest — смена статуса медиа
+type ChangeStatusRequest struct {
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"` // обязателен для failed
+	// IfMatch — Media.ETag: статус меняется, только если медиа не изменилось с чтения,
+	// иначе *APIError 412. Пустой — без условия.
+	IfMatch string `json:"-"`
+}
+
+// ChangeStatus — PATCH /media/{id}/status. Повтор перехода уже выполненного запроса
+// ответил бы конфликтом, поэтому повторяются только ответы 429 и 503.
+func (c *Client) ChangeStatus(ctx context.Context, id uuid.UUID, req ChangeStatusRequest) (*Media, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	r := request{method: http.MethodPatch, url: c.mediaURL(id, "/status"), body: body, retries: retryKeyed}
+	if req.IfMatch != "" {
+		r.header = http.Header{"If-Match": {req.IfMatch}}
+	}
+	var m Media
+	header, err := c.do(ctx, r, &m)
+	if err != nil {
+		return nil, err
+	}
+	m.ETag = header.Get("ETag")
+	return &m, nil
+}
+
+// StatusBatchItem — переход одного медиа в ChangeStatusBatch
+type StatusBatchItem struct {
+	ID     uuid.UUID `json:"id"`
+	Status Status    `json:"status"`
+	Reason string    `json:"reason,omitempty"` // обязателен для failed
+}
+
+// StatusBatchResult — результат элемента ChangeStatusBatch
+type StatusBatchResult struct {
+	Index int       `json:"index"`
+	ID    uuid.UUID `json:"id"`
+	// Status — changed, unchanged (медиа уже в этом статусе), invalid (ошибки полей
+	// в Errors) или rejected (причина в Code: not_found, invalid_transition, invalid_argument)
+	Status  string       `json:"status"`
+	Media   *Media       `json:"media,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
+
+// FieldError — ошибка валидации поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ChangeStatusBatch — PATCH /media/status/batch: переходы пачки медиа (до 500) одной
+// транзакцией. Отклонённые элементы не прерывают пачку — см. StatusBatchResult. Повтор
+// уже применённой пачки отвечает unchanged, поэтому запрос повторяется как GET.
+func (c *Client) ChangeStatusBatch(ctx context.Context, items []StatusBatchItem) ([]StatusBatchResult, error) {
+	body, err := json.Marshal(map[string]any{"items": items})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Results []StatusBatchResult `json:"results"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPatch, url: c.baseURL + "/media/status/batch", body: body}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Schedule — расписание публикации; nil поле снимает ограничение
+type Schedule struct {
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// SetSchedule — PUT /media/{id}/schedule: заменяет расписание целиком. Обработанное медиа
+// сразу переходит в scheduled или ready по новому PublishAt; в ExpiresAt его архивирует планировщик.
+func (c *Client) SetSchedule(ctx context.Context, id uuid.UUID, s Schedule) (*Media, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var m Media
+	if _, err := c.do(ctx, request{method: http.MethodPut, url: c.mediaURL(id, "/schedule"), body: body}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SetVisibilityRequest — смена видимости медиа
+type SetVisibilityRequest struct {
+	Visibility Visibility `json:"visibility"`
+	// IfMatch — Media.ETag: видимость меняется, только если медиа не изменилось с чтения,
+	// иначе *APIError 412. Пустой — без условия.
+	IfMatch string `json:"-"`
+}
+
+// SetVisibility — PATCH /media/{id}/visibility. Повтор той же видимости ничего не меняет,
+// поэтому запрос без IfMatch повторяется как идемпотентный.
+func (c *Client) SetVisibility(ctx context.Context, id uuid.UUID, req SetVisibilityRequest) (*Media, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	r := request{method: http.MethodPatch, url: c.mediaURL(id, "/visibility"), body: body}
+	if req.IfMatch != "" {
+		// После применённого запроса версия другая: повтор без Idempotency-Key ответил бы 412
+		r.header = http.Header{"If-Match": {req.IfMatch}}
+		r.retries = retryKeyed
+	}
+	var m Media
+	header, err := c.do(ctx, r, &m)
+	if err != nil {
+		return nil, err
+	}
+	m.ETag = header.Get("ETag")
+	return &m, nil
+}
+
+// ReportFailure — POST /media/{id}/failures: неудачная попытка обработки медиа в processing.
+// Повтор решает media по своему счётчику попыток: в ответе processing — повтор запрошен,
+// failed — попытки исчерпаны. Запрос уходит с Idempotency-Key: повтор не потратит попытку дважды.
+func (c *Client) ReportFailure(ctx context.Context, id uuid.UUID, message string) (*Media, error) {
+	body, err := json.Marshal(struct {
+		Error string `json:"error"`
+	}{message})
+	if err != nil {
+		return nil, err
+	}
+	var m Media
+	if _, err := c.do(ctx, request{method: http.MethodPost, url: c.mediaURL(id, "/failures"), body: body, retries: retryKeyed}, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// DeleteMedia — DELETE /media/{id}
+func (c *Client) DeleteMedia(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, url: c.mediaURL(id, "")}, nil)
+	return err
+}
+
+// DedupMode — поведение загрузки, если у владельца уже есть медиа с тем же содержимым
+type DedupMode string
+
+const (
+	DedupOff       DedupMode = "off"
+	DedupReject    DedupMode = "reject"    // *APIError 409 с id существующего медиа в Details
+	DedupReference DedupMode = "reference" // медиа ссылается на уже сохранённый исходник
+)
+
+// UploadRequest — исходник медиа для Upload
+type UploadRequest struct {
+	// Body — содержимое; при ошибке загрузка повторяется, только если Body — io.Seeker
+	// (*os.File, *bytes.Reader): повтор читает его с той же позиции
+	Body io.Reader
+	Size int64 // точная длина Body
+	// ChecksumSHA256 — sha256 содержимого в hex: ingest сверяет его с полученным,
+	// и без него не работает дедупликация
+	ChecksumSHA256 string
+	// Dedup — режим дедупликации этой загрузки; пустой — по умолчанию ingest
+	Dedup DedupMode
+}
+
+// UploadResult — характеристики загруженного исходника
+type UploadResult struct {
+	MediaID     uuid.UUID `json:"media_id"`
+	Checksum    string    `json:"checksum_sha256"`
+	Size        int64     `json:"size_bytes"`
+	ContentType string    `json:"content_type"`
+	// Scan — антивирусная проверка: clean, pending (идёт в фоне); пусто — выключена
+	Scan string `json:"scan,omitempty"`
+	// DuplicateOf — медиа, на исходник которого сослалась загрузка (DedupReference)
+	DuplicateOf uuid.UUID `json:"duplicate_of,omitzero"`
+}
+
+// Upload — PUT /uploads/{media_id} в ingest (Config.IngestURL): загружает исходник
+// медиа со статусом uploaded или failed
+func (c *Client) Upload(ctx context.Context, id uuid.UUID, req UploadRequest) (*UploadResult, error) {
+	if c.ingestURL == "" {
+		return nil, errors.New("ingest url is not configured")
+	}
+	if req.Body == nil || req.Size < 0 {
+		return nil, errors.New("upload body and size are required")
+	}
+	header := http.Header{}
+	if req.ChecksumSHA256 != "" {
+		header.Set("X-Checksum-SHA256", req.ChecksumSHA256)
+	}
+	if req.Dedup != "" {
+		header.Set("X-Dedup", string(req.Dedup))
+	}
+	var result UploadResult
+	r := request{
+		method: http.MethodPut,
+		url:    c.ingestURL + "/uploads/" + id.String(),
+		header: header,
+		stream: req.Body,
+		size:   req.Size,
+	}
+	if _, err := c.do(ctx, r, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) mediaURL(id uuid.UUID, suffix string) string {
+	return c.baseURL + "/media/" + id.String() + suffix
+}
@@ -0,0 +1,226 @@
+// Package webhooksig подписывает и проверяет доставки webhook канала publish сервиса.
+//
+// Подпись передаётся в заголовке Webhook-Signature:
+//
+//	Webhook-Signature: t=1700000000,v1=5257a869...,v1=9f86d081...
+//
+// t — время отправки (unix секунды), v1 — hex HMAC-SHA256 от "{t}.{тело запроса}" по одному
+// ключу. На время ротации отправитель подписывает доставку и новым, и старым ключом: получатель
+// принимает её, если сошлась хотя бы одна подпись с любым из его ключей. Время отправки
+// ограничивает повтор перехваченной доставки.
+//
+// Получатель на Go:
+//
+//	verifier, err := webhooksig.NewVerifier(webhooksig.VerifierConfig{Secrets: []string{os.Getenv("WEBHOOK_SECRET")}})
+//	...
+//	body, err := verifier.VerifyRequest(r)
+//	if err != nil {
+//		http.Error(w, "invalid signature", http.StatusUnauthorized)
+//		return
+//	}
+package webhooksig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header — заголовок с подписью доставки
+const Header = "Webhook-Signature"
+
+// DefaultTolerance — насколько время отправки может разойтись с часами получателя
+const DefaultTolerance = 5 * time.Minute
+
+// DefaultMaxBodyBytes — предел тела, которое читает VerifyRequest
+const DefaultMaxBodyBytes = 1 << 20
+
+// MinSecretLength — ключ короче 256 бит подбирается слишком легко
+const MinSecretLength = 32
+
+// version — схема подписи; новая схема получит свой ключ в заголовке
+const version = "v1"
+
+var (
+	// ErrNoSignature — заголовка подписи нет или в нём нет подписи v1
+	ErrNoSignature = errors.New("webhook signature is missing")
+	// ErrInvalidHeader — заголовок подписи не разбирается
+	ErrInvalidHeader = errors.New("invalid webhook signature header")
+	// ErrTimestamp — время отправки вне допустимого окна
+	ErrTimestamp = errors.New("webhook timestamp is outside the tolerance")
+	// ErrMismatch — ни одна подпись не сошлась ни с одним ключом
+	ErrMismatch = errors.New("webhook signature mismatch")
+)
+
+// Sign возвращает значение заголовка Header для тела payload, отправленного в at:
+// по подписи на каждый ключ из secrets (первый — текущий, остальные — на время ротации)
+func Sign(payload []byte, at time.Time, secrets ...string) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	var b strings.Builder
+	b.WriteString("t=" + ts)
+	for _, secret := range secrets {
+		b.WriteString("," + version + "=" + hex.EncodeToString(mac(secret, ts, payload)))
+	}
+	return b.String()
+}
+
+// SignRequest подписывает запрос с телом payload: выставляет заголовок Header
+func SignRequest(req *http.Request, payload []byte, at time.Time, secrets ...string) {
+	req.Header.Set(Header, Sign(payload, at, secrets...))
+}
+
+// VerifierConfig содержит конфигурацию Verifier
+type VerifierConfig struct {
+	// Secrets — принимаемые ключы; на время ротации — новый и старый
+	Secrets []string
+	// Tolerance — допустимое расхождение времени отправки с часами получателя в обе стороны
+	// (default: DefaultTolerance)
+	Tolerance time.Duration
+	// MaxBodyBytes — предел тела в VerifyRequest (default: DefaultMaxBodyBytes)
+	MaxBodyBytes int64
+	// Clock — текущее время (default: time.Now)
+	Clock func() time.Time
+}
+
+// Verifier проверяет подписи доставок
+type Verifier struct {
+	secrets   []string
+	tolerance time.Duration
+	maxBody   int64
+	clock     func() time.Time
+}
+
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	if len(cfg.Secrets) == 0 {
+		return nil, errors.New("webhook secret is required")
+	}
+	for i, secret := range cfg.Secrets {
+		if len(secret) < MinSecretLength {
+			return nil, fmt.Errorf("webhook secret %d must be at least %d bytes, got: %d", i, MinSecretLength, len(secret))
+		}
+	}
+	if cfg.Tolerance < 0 {
+		return nil, fmt.Errorf("webhook tolerance cannot be negative, got: %v", cfg.Tolerance)
+	}
+	if cfg.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("webhook max body bytes cannot be negative, got: %d", cfg.MaxBodyBytes)
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = DefaultTolerance
+	}
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return &Verifier{
+		secrets:   append([]string(nil), cfg.Secrets...),
+		tolerance: cfg.Tolerance,
+		maxBody:   cfg.MaxBodyBytes,
+		clock:     cfg.Clock,
+	}, nil
+}
+
+// Verify проверяет заголовок header для тела payload. Подписи сравниваются за постоянное
+// время; ошибка — одна из ErrNoSignature, ErrInvalidHeader, ErrTimestamp, ErrMismatch.
+func (v *Verifier) Verify(payload []byte, header string) error {
+	ts, signatures, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp %q", ErrInvalidHeader, ts)
+	}
+	if skew := v.clock().Sub(time.Unix(sent, 0)); skew > v.tolerance || skew < -v.tolerance {
+		return fmt.Errorf("%w: sent at %s", ErrTimestamp, time.Unix(sent, 0).UTC().Format(time.RFC3339))
+	}
+
+	matched := false
+	for _, secret := range v.secrets {
+		want := mac(secret, ts, payload)
+		for _, sig := range signatures {
+			// Перебор без раннего выхода: время ответа не выдаёт, какой ключ сошёлся
+			if hmac.Equal(sig, want) {
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// VerifyRequest читает тело запроса (не больше MaxBodyBytes) и проверяет его подпись.
+// Возвращает прочитанное тело; r.Body заменяется его копией, чтобы обработчик мог
+// прочитать тело ещё раз.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	header := r.Header.Get(Header)
+	if header == "" {
+		return nil, ErrNoSignature
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, v.maxBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("read webhook body: %w", err)
+	}
+	if int64(len(body)) > v.maxBody {
+		return nil, fmt.Errorf("webhook body is larger than %d bytes", v.maxBody)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := v.Verify(body, header); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseHeader разбирает "t=...,v1=...,v1=..."; подписи неизвестных версий пропускаются
+func parseHeader(header string) (string, [][]byte, error) {
+	if strings.TrimSpace(header) == "" {
+		return "", nil, ErrNoSignature
+	}
+	var (
+		ts         string
+		signatures [][]byte
+	)
+	for item := range strings.SplitSeq(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %q", ErrInvalidHeader, item)
+		}
+		switch key {
+		case "t":
+			ts = value
+		case version:
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: signature is not hex", ErrInvalidHeader)
+			}
+			signatures = append(signatures, sig)
+		}
+	}
+	if ts == "" {
+		return "", nil, fmt.Errorf("%w: no timestamp", ErrInvalidHeader)
+	}
+	if len(signatures) == 0 {
+		return "", nil, ErrNoSignature
+	}
+	return ts, signatures, nil
+}
+
+func mac(secret, ts string, payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return h.Sum(nil)
+}
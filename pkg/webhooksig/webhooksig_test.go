@@ -0,0 +1,97 @@
+package webhooksig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	current  = strings.Repeat("c", 32)
+	previous = strings.Repeat("p", 32)
+)
+
+func newVerifier(t *testing.T, now time.Time, secrets ...string) *Verifier {
+	t.Helper()
+	v, err := NewVerifier(VerifierConfig{Secrets: secrets, Clock: func() time.Time { return now }})
+	require.NoError(t, err)
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte(`{"event":{"media_id":"m1"}}`)
+	header := Sign(payload, now, current)
+	require.Equal(t, "t=1700000000,v1=", header[:len("t=1700000000,v1=")])
+
+	v := newVerifier(t, now.Add(time.Minute), current)
+	require.NoError(t, v.Verify(payload, header))
+	require.ErrorIs(t, v.Verify([]byte(`{"event":{"media_id":"m2"}}`), header), ErrMismatch)
+	require.ErrorIs(t, newVerifier(t, now, previous).Verify(payload, header), ErrMismatch)
+
+	// Время отправки подписано: подмена t ломает подпись
+	forged := strings.Replace(header, "t=1700000000", "t=1700000030", 1)
+	require.ErrorIs(t, v.Verify(payload, forged), ErrMismatch)
+
+	require.ErrorIs(t, newVerifier(t, now.Add(DefaultTolerance+time.Second), current).Verify(payload, header), ErrTimestamp)
+	require.ErrorIs(t, newVerifier(t, now.Add(-DefaultTolerance-time.Second), current).Verify(payload, header), ErrTimestamp)
+
+	for _, bad := range []string{"v1=abcd", "t=x,v1=abcd", "t=1,v1=zz", "garbage"} {
+		require.ErrorIs(t, v.Verify(payload, bad), ErrInvalidHeader, bad)
+	}
+	require.ErrorIs(t, v.Verify(payload, ""), ErrNoSignature)
+	require.ErrorIs(t, v.Verify(payload, "t=1700000000,v0=abcd"), ErrNoSignature)
+}
+
+func TestVerify_KeyRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	payload := []byte("body")
+
+	// Отправитель уже на новом ключе, но ещё подписывает и старым
+	header := Sign(payload, now, current, previous)
+	require.Equal(t, 2, strings.Count(header, "v1="))
+	require.NoError(t, newVerifier(t, now, previous).Verify(payload, header))
+	require.NoError(t, newVerifier(t, now, current).Verify(payload, header))
+
+	// Получатель уже принимает оба ключа, отправитель ещё на старом
+	require.NoError(t, newVerifier(t, now, current, previous).Verify(payload, Sign(payload, now, previous)))
+}
+
+func TestVerifyRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v, err := NewVerifier(VerifierConfig{Secrets: []string{current}, MaxBodyBytes: 16, Clock: func() time.Time { return now }})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("hello"))
+	SignRequest(req, []byte("hello"), now, current)
+	body, err := v.VerifyRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+	again := make([]byte, 5)
+	_, err = req.Body.Read(again)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(again))
+
+	req = httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("hello"))
+	_, err = v.VerifyRequest(req)
+	require.ErrorIs(t, err, ErrNoSignature)
+
+	large := strings.Repeat("x", 17)
+	req = httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(large))
+	SignRequest(req, []byte(large), now, current)
+	_, err = v.VerifyRequest(req)
+	require.ErrorContains(t, err, "larger than 16 bytes")
+}
+
+func TestNewVerifier_Validation(t *testing.T) {
+	_, err := NewVerifier(VerifierConfig{})
+	require.Error(t, err)
+	_, err = NewVerifier(VerifierConfig{Secrets: []string{"short"}})
+	require.Error(t, err)
+	_, err = NewVerifier(VerifierConfig{Secrets: []string{current}, Tolerance: -time.Second})
+	require.Error(t, err)
+}
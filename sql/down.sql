@@ -0,0 +1,25 @@
+-- откат схемы sql/script.sql: удаляет все таблицы сервиса вместе с данными
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS media_grants;
+DROP TABLE IF EXISTS collection_items;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS owner_purges;
+DROP TABLE IF EXISTS aggregate_sequences;
+DROP TABLE IF EXISTS projection_owner_usage;
+DROP TABLE IF EXISTS projection_media_status;
+DROP TABLE IF EXISTS projection_checkpoints;
+DROP TABLE IF EXISTS media_snapshots;
+DROP TABLE IF EXISTS media_events;
+DROP TABLE IF EXISTS publish_deliveries;
+DROP TABLE IF EXISTS quota_usage;
+DROP TABLE IF EXISTS quota_owner_plans;
+DROP TABLE IF EXISTS quota_plans;
+DROP TABLE IF EXISTS media_renditions;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS retention_policies;
+DROP TABLE IF EXISTS media_status_history;
+DROP TABLE IF EXISTS processed_events;
+DROP TABLE IF EXISTS outbox_archive;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS aggregate_sequences;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS media_status_history;
+DROP TABLE IF EXISTS media;
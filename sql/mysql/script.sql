@@ -0,0 +1,69 @@
+-- Схема MySQL 8 для media сервиса с -storage sql и DATABASE_URL mysql://: media, история
+-- статусов и outbox. Время — DATETIME(6) в UTC (сессия с time_zone '+00:00'), uuid — CHAR(36).
+CREATE TABLE IF NOT EXISTS media (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    status VARCHAR(32) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    source TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    title TEXT NOT NULL,
+    tags JSON NOT NULL,
+    metadata JSON NOT NULL,
+    processing_attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    owner_id CHAR(36) NULL,
+    checksum_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    publish_at DATETIME(6) NULL,
+    expires_at DATETIME(6) NULL,
+    visibility VARCHAR(16) NOT NULL DEFAULT 'draft',
+    -- полнотекстовый поиск (GET /media/search) по title и metadata.description
+    search_description TEXT GENERATED ALWAYS AS (COALESCE(metadata->>'$.description', '')) STORED,
+    INDEX idx_media_status (status),
+    INDEX idx_media_owner (owner_id, created_at),
+    INDEX idx_media_owner_checksum (owner_id, checksum_sha256),
+    INDEX idx_media_type_created (type, created_at),
+    INDEX idx_media_created (created_at, id),
+    INDEX idx_media_updated (updated_at, id),
+    FULLTEXT INDEX idx_media_search (title, search_description)
+);
+
+-- аудит переходов статуса; без FK на media, чтобы история переживала удаление
+CREATE TABLE IF NOT EXISTS media_status_history (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    media_id CHAR(36) NOT NULL,
+    from_status VARCHAR(32) NOT NULL,
+    to_status VARCHAR(32) NOT NULL,
+    actor TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    changed_at DATETIME(6) NOT NULL,
+    INDEX idx_media_status_history_media (media_id, changed_at)
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL UNIQUE,
+    event_type VARCHAR(255) NOT NULL,
+    schema_version INT NOT NULL DEFAULT 1,
+    aggregate_id VARCHAR(255) NOT NULL,
+    sequence BIGINT NOT NULL DEFAULT 0,
+    payload JSON NOT NULL,
+    occurred_at DATETIME(6) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    -- раньше этого времени событие не берётся: отложенный повтор или аренда захватившего publisher'а
+    next_retry_at DATETIME(6) NULL,
+    dead_lettered_at DATETIME(6) NULL,
+    processed_at DATETIME(6) NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_outbox_publishable (processed_at, dead_lettered_at, id),
+    INDEX idx_outbox_aggregate (aggregate_id, id)
+);
+
+-- счётчики номеров событий по агрегатам; строка блокируется транзакцией, пишущей событие
+CREATE TABLE IF NOT EXISTS aggregate_sequences (
+    aggregate_id VARCHAR(255) NOT NULL PRIMARY KEY,
+    sequence BIGINT NOT NULL
+);
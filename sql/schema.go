@@ -0,0 +1,35 @@
+// Package schema встраивает SQL схему сервисов в бинарь, чтобы миграции
+// (media migrate up/down, интеграционные тесты) не зависели от рабочей директории.
+package schema
+
+import _ "embed"
+
+// Up — схема целиком; скрипт идемпотентен, повторное применение безопасно
+//
+//go:embed script.sql
+var Up string
+
+// Down — удаление всех таблиц схемы вместе с данными
+//
+//go:embed down.sql
+var Down string
+
+// MySQLUp — схема MySQL (media, история статусов, outbox); идемпотентна, как Up
+//
+//go:embed mysql/script.sql
+var MySQLUp string
+
+// MySQLDown — удаление таблиц схемы MySQL вместе с данными
+//
+//go:embed mysql/down.sql
+var MySQLDown string
+
+// SQLiteUp — схема SQLite (media, история статусов, outbox); идемпотентна, как Up
+//
+//go:embed sqlite/script.sql
+var SQLiteUp string
+
+// SQLiteDown — удаление таблиц схемы SQLite вместе с данными
+//
+//go:embed sqlite/down.sql
+var SQLiteDown string
@@ -8,3 +8,406 @@ CREATE TABLE IF NOT EXISTS media (
 );
 
 CREATE INDEX IF NOT EXISTS idx_media_status ON media(status);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL UNIQUE,
+    event_type VARCHAR(255) NOT NULL,
+    schema_version INT NOT NULL DEFAULT 1,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    processed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- для баз, где outbox создан до появления версий схем
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS schema_version INT NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(processed_at)
+    WHERE processed_at IS NULL;
+
+-- inbox: event_id, уже обработанные consumer'ом (идемпотентность при at-least-once)
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer VARCHAR(255) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (consumer, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
+
+-- аудит переходов статуса; без FK на media, чтобы история переживала удаление
+CREATE TABLE IF NOT EXISTS media_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    media_id uuid NOT NULL,
+    from_status text NOT NULL,
+    to_status text NOT NULL,
+    actor text NOT NULL DEFAULT '',
+    reason text NOT NULL DEFAULT '',
+    changed_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_status_history_media ON media_status_history(media_id, changed_at);
+
+-- попытки обработки и последняя ошибка (retry политика)
+ALTER TABLE media ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS last_error text NOT NULL DEFAULT '';
+
+-- редактируемые атрибуты медиа (MediaRepo.Update)
+ALTER TABLE media ADD COLUMN IF NOT EXISTS title text NOT NULL DEFAULT '';
+ALTER TABLE media ADD COLUMN IF NOT EXISTS tags jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE media ADD COLUMN IF NOT EXISTS metadata jsonb NOT NULL DEFAULT '{}';
+
+-- полнотекстовый поиск (GET /media/search): title + metadata.description, фильтр по меткам
+ALTER TABLE media ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', coalesce(metadata->>'description', '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_media_search ON media USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_media_tags ON media USING GIN (tags jsonb_path_ops);
+
+-- учёт попыток публикации outbox и парковка poison-событий
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS last_error text NOT NULL DEFAULT '';
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMP NULL;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_publishable ON outbox(id)
+    WHERE processed_at IS NULL AND dead_lettered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_dead_letter ON outbox(dead_lettered_at)
+    WHERE dead_lettered_at IS NOT NULL;
+-- более раннее событие агрегата, ждущее повтора, задерживает его следующие события
+CREATE INDEX IF NOT EXISTS idx_outbox_pending_aggregate ON outbox(aggregate_id, id)
+    WHERE processed_at IS NULL AND dead_lettered_at IS NULL;
+
+-- владелец медиа (пользователь/тенант); NULL — общий пул, созданный до появления владельцев
+ALTER TABLE media ADD COLUMN IF NOT EXISTS owner_id uuid NULL;
+CREATE INDEX IF NOT EXISTS idx_media_owner ON media(owner_id, created_at DESC);
+
+-- сроки хранения (retention): политика типа медиа или конкретного медиа, которое важнее типа
+CREATE TABLE IF NOT EXISTS retention_policies (
+    id BIGSERIAL PRIMARY KEY,
+    media_id uuid NULL REFERENCES media(id) ON DELETE CASCADE,
+    media_type text NULL,
+    retain_for_seconds BIGINT NOT NULL CHECK (retain_for_seconds > 0),
+    action text NOT NULL CHECK (action IN ('archive', 'delete')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((media_id IS NULL) <> (media_type IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_retention_policies_media ON retention_policies(media_id)
+    WHERE media_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_retention_policies_type ON retention_policies(media_type)
+    WHERE media_type IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_media_type_created ON media(type, created_at);
+
+-- характеристики исходника, проверенные ingest'ом; пустые, пока исходник не загружен
+ALTER TABLE media ADD COLUMN IF NOT EXISTS checksum_sha256 text NOT NULL DEFAULT '';
+ALTER TABLE media ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS content_type text NOT NULL DEFAULT '';
+
+-- дедупликация загрузок: поиск медиа владельца с тем же исходником по checksum; медиа,
+-- сославшиеся на один объект, делят source — retention не удаляет объект, пока он нужен другим
+CREATE INDEX IF NOT EXISTS idx_media_owner_checksum ON media(owner_id, checksum_sha256)
+    WHERE checksum_sha256 <> '';
+CREATE INDEX IF NOT EXISTS idx_media_source ON media(source);
+
+-- очередь задач processing: приоритет, отложенный запуск и аренда захваченной задачи (locked_until);
+-- attempts — число захватов, он же токен аренды текущей попытки
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGSERIAL PRIMARY KEY,
+    queue text NOT NULL,
+    kind text NOT NULL,
+    payload jsonb NOT NULL DEFAULT '{}',
+    priority INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL CHECK (max_attempts > 0),
+    unique_key text NULL,
+    scheduled_at timestamptz NOT NULL DEFAULT now(),
+    locked_until timestamptz NULL,
+    last_error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz NULL,
+    failed_at timestamptz NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_ready ON jobs(queue, priority DESC, scheduled_at, id)
+    WHERE completed_at IS NULL AND failed_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_jobs_unique_key ON jobs(queue, unique_key)
+    WHERE unique_key IS NOT NULL AND completed_at IS NULL AND failed_at IS NULL;
+
+-- результат упаковки processing (packaging.Packager): master-плейлист, DASH-манифест и
+-- варианты медиа; повторная упаковка заменяет набор целиком
+CREATE TABLE IF NOT EXISTS media_renditions (
+    media_id uuid NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    uri text NOT NULL,
+    bandwidth INT NOT NULL DEFAULT 0,
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    codecs text NOT NULL DEFAULT '',
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (media_id, name)
+);
+
+-- тарифы quota: лимиты по умолчанию для владельцев тарифа, 0 — без ограничения;
+-- rank упорядочивает тарифы для предложения перейти на тариф выше
+CREATE TABLE IF NOT EXISTS quota_plans (
+    name text PRIMARY KEY,
+    rank INT NOT NULL,
+    max_objects BIGINT NOT NULL DEFAULT 0 CHECK (max_objects >= 0),
+    max_bytes BIGINT NOT NULL DEFAULT 0 CHECK (max_bytes >= 0),
+    upload_limit INT NOT NULL DEFAULT 0 CHECK (upload_limit >= 0),
+    upload_window_seconds BIGINT NOT NULL DEFAULT 3600 CHECK (upload_window_seconds > 0)
+);
+
+-- совпадает с quota.DefaultPlans; существующие тарифы не перезаписываются
+INSERT INTO quota_plans (name, rank, max_objects, max_bytes, upload_limit, upload_window_seconds) VALUES
+    ('free', 0, 100, 1073741824, 20, 3600),
+    ('pro', 10, 10000, 107374182400, 500, 3600),
+    ('enterprise', 20, 0, 0, 5000, 3600)
+ON CONFLICT (name) DO NOTHING;
+
+-- тариф владельца и лимиты, выставленные администратором поверх тарифа (NULL — как в тарифе);
+-- владельцы без строки — на тарифе free
+CREATE TABLE IF NOT EXISTS quota_owner_plans (
+    owner_id uuid PRIMARY KEY,
+    plan text NOT NULL REFERENCES quota_plans(name),
+    max_objects BIGINT NULL CHECK (max_objects >= 0),
+    max_bytes BIGINT NULL CHECK (max_bytes >= 0),
+    upload_limit INT NULL CHECK (upload_limit >= 0),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- usage владельцев quota по событиям media; owner_id '' — общий пул медиа без владельца.
+-- Учтённые события отмечаются в processed_events (consumer quota-usage)
+CREATE TABLE IF NOT EXISTS quota_usage (
+    owner_id text PRIMARY KEY,
+    objects BIGINT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- журнал попыток доставки уведомлений publish: ответ канала и отправленное сообщение,
+-- по которому доставку можно повторить; redelivery_of — повторённая попытка
+CREATE TABLE IF NOT EXISTS publish_deliveries (
+    id uuid PRIMARY KEY,
+    channel text NOT NULL,
+    subscription text NOT NULL,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    attempt INT NOT NULL,
+    redelivery_of uuid NULL,
+    message jsonb NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    response text NOT NULL DEFAULT '',
+    error text NOT NULL DEFAULT '',
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    delivered_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_publish_deliveries_channel ON publish_deliveries(channel, delivered_at DESC);
+
+-- журнал событий media (event sourcing): полная история каждого агрегата, в отличие от
+-- outbox не чистится; sequence — номер события в потоке агрегата, с 1 без пропусков
+CREATE TABLE IF NOT EXISTS media_events (
+    aggregate_id uuid NOT NULL,
+    sequence BIGINT NOT NULL CHECK (sequence > 0),
+    event_id text NOT NULL UNIQUE,
+    event_type text NOT NULL,
+    schema_version INT NOT NULL,
+    payload jsonb NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    recorded_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (aggregate_id, sequence)
+);
+
+-- position — сквозной порядок журнала событий для проекций
+ALTER TABLE media_events ADD COLUMN IF NOT EXISTS position BIGSERIAL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_events_position ON media_events(position);
+
+-- снапшоты потоков media_events: состояние media на версии потока
+CREATE TABLE IF NOT EXISTS media_snapshots (
+    aggregate_id uuid NOT NULL,
+    version BIGINT NOT NULL,
+    state jsonb NOT NULL,
+    taken_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (aggregate_id, version)
+);
+
+-- проекции журнала событий: checkpoint — position последнего применённого события
+CREATE TABLE IF NOT EXISTS projection_checkpoints (
+    name text PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- проекция media_counts_by_status: текущий статус каждого media
+CREATE TABLE IF NOT EXISTS projection_media_status (
+    media_id uuid PRIMARY KEY,
+    owner_id uuid NOT NULL,
+    status text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_projection_media_status_owner ON projection_media_status(owner_id, status);
+
+-- проекция owner_storage_usage: объекты и байты исходников владельца (uuid.Nil — общий пул)
+CREATE TABLE IF NOT EXISTS projection_owner_usage (
+    owner_id uuid PRIMARY KEY,
+    objects BIGINT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0
+);
+
+-- GET /stats: приём и завершённые обработки за окно
+CREATE INDEX IF NOT EXISTS idx_media_created ON media(created_at);
+CREATE INDEX IF NOT EXISTS idx_media_status_history_changed ON media_status_history(changed_at) WHERE from_status = 'processing';
+
+-- GET /media?sort=updated_at: keyset страницы по (updated_at, id)
+CREATE INDEX IF NOT EXISTS idx_media_updated ON media(updated_at, id);
+
+-- номер события в потоке агрегата (events.Envelope.sequence); 0 — событие записано до появления номеров
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS sequence BIGINT NOT NULL DEFAULT 0;
+
+-- счётчики номеров событий по агрегатам; строка блокируется транзакцией, пишущей событие
+CREATE TABLE IF NOT EXISTS aggregate_sequences (
+    aggregate_id VARCHAR(255) PRIMARY KEY,
+    sequence BIGINT NOT NULL
+);
+
+-- запросы на удаление всех данных владельца (GDPR) и отчёты о них (purge.Report в report);
+-- у владельца не больше одного незавершённого запроса
+CREATE TABLE IF NOT EXISTS owner_purges (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id uuid NOT NULL,
+    requested_by text NOT NULL DEFAULT '',
+    status text NOT NULL,
+    report jsonb NOT NULL DEFAULT '{}',
+    last_error text NOT NULL DEFAULT '',
+    requested_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    completed_at timestamptz NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_owner_purges_active ON owner_purges(owner_id)
+    WHERE completed_at IS NULL;
+
+-- расписание публикации: до publish_at обработанное медиа в scheduled, после expires_at архивируется
+ALTER TABLE media ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ NULL;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+CREATE INDEX IF NOT EXISTS idx_media_publish_due ON media(publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_media_expires_at ON media(expires_at) WHERE expires_at IS NOT NULL;
+
+-- видимость медиа, задаётся владельцем; существующее медиа видел только владелец — draft
+ALTER TABLE media ADD COLUMN IF NOT EXISTS visibility text NOT NULL DEFAULT 'draft';
+CREATE INDEX IF NOT EXISTS idx_media_owner_public ON media(owner_id, created_at) WHERE visibility = 'public' AND status = 'ready';
+
+-- коллекции (плейлисты, альбомы): упорядоченные подборки медиа владельца
+CREATE TABLE IF NOT EXISTS collections (
+    id uuid PRIMARY KEY,
+    owner_id uuid NULL,
+    title text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_owner ON collections(owner_id, created_at DESC);
+
+-- состав коллекций; position — ключ порядка, место элемента — его номер по position.
+-- Сервис убирает медиа из коллекций до удаления, каскад — для удалений в обход сервиса (purge)
+CREATE TABLE IF NOT EXISTS collection_items (
+    collection_id uuid NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    media_id uuid NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    position int NOT NULL,
+    added_at timestamptz NOT NULL,
+    PRIMARY KEY (collection_id, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_items_order ON collection_items(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_collection_items_media ON collection_items(media_id);
+
+-- ACL медиа: кому из не-владельцев медиа открыто независимо от видимости
+CREATE TABLE IF NOT EXISTS media_grants (
+    media_id uuid NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    principal uuid NOT NULL,
+    permission text NOT NULL CHECK (permission IN ('read', 'download')),
+    granted_by text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    PRIMARY KEY (media_id, principal)
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_grants_principal ON media_grants(principal);
+
+-- журнал аудита изменяющих запросов API; diff — изменённые поля ресурса: {поле: {before, after}}.
+-- resource_id — текст: у служебных ресурсов (outbox, purges) id числовые
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    at timestamptz NOT NULL,
+    request_id text NOT NULL DEFAULT '',
+    actor text NOT NULL DEFAULT '',
+    owner_id uuid,
+    admin boolean NOT NULL DEFAULT false,
+    method text NOT NULL,
+    endpoint text NOT NULL,
+    resource_type text NOT NULL DEFAULT '',
+    resource_id text NOT NULL DEFAULT '',
+    status int NOT NULL,
+    diff jsonb
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_at ON audit_log(at);
+
+-- ключи Idempotency-Key и сохранённые ответы; scope — владелец вызывающего ('' — внутренние вызовы).
+-- status IS NULL — запрос ещё выполняется, ключ занят до locked_until
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope text NOT NULL,
+    key text NOT NULL,
+    route text NOT NULL,
+    fingerprint text NOT NULL,
+    token uuid NOT NULL,
+    status int,
+    header jsonb,
+    body bytea,
+    created_at timestamptz NOT NULL,
+    locked_until timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- архив опубликованных событий (outbox.Archiver): опубликованное событие переезжает сюда через
+-- -outbox-archive-after, так что в outbox остаются только необработанные и недавние события.
+-- Партиции — по дню публикации (outbox_archive_pYYYYMMDD), archiver создаёт их перед переносом
+-- и удаляет целиком по истечении -outbox-archive-retention, без DELETE и vacuum больших объёмов.
+CREATE TABLE IF NOT EXISTS outbox_archive (
+    id BIGINT NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    schema_version INT NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    sequence BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL,
+    processed_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+) PARTITION BY RANGE (processed_at);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_archive_aggregate ON outbox_archive(aggregate_id, sequence);
+
+-- Индексы outbox: GetPending и CountPending читают только idx_outbox_publishable (частичный,
+-- размер — backlog, а не таблица), поэтому выборка publisher'а — O(batch) при любом числе
+-- опубликованных событий. Archiver выбирает опубликованные по idx_outbox_processed. Новые
+-- индексы на outbox — только частичные: каждый полный индекс удорожает вставку события и
+-- MarkProcessed. После переноса пачек в архив остаются мёртвые строки, поэтому autovacuum
+-- outbox запускается раньше, чем по умолчанию (20% таблицы).
+CREATE INDEX IF NOT EXISTS idx_outbox_processed ON outbox(processed_at)
+    WHERE processed_at IS NOT NULL;
+ALTER TABLE outbox SET (autovacuum_vacuum_scale_factor = 0.01, autovacuum_analyze_scale_factor = 0.02);
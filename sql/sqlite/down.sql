@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS aggregate_sequences;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS media_status_history;
+DROP TRIGGER IF EXISTS media_fts_delete;
+DROP TRIGGER IF EXISTS media_fts_update;
+DROP TRIGGER IF EXISTS media_fts_insert;
+DROP TABLE IF EXISTS media_fts;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,89 @@
+-- Схема SQLite для media сервиса с -storage sqlite: media, история статусов и outbox.
+-- Время — TEXT "YYYY-MM-DD HH:MM:SS.ffffff" в UTC: фиксированная ширина, поэтому строки
+-- сравниваются и сортируются как время. uuid — TEXT, tags и metadata — JSON в TEXT.
+CREATE TABLE IF NOT EXISTS media (
+    id TEXT NOT NULL PRIMARY KEY,
+    status TEXT NOT NULL,
+    type TEXT NOT NULL,
+    source TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '[]',
+    metadata TEXT NOT NULL DEFAULT '{}',
+    processing_attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    owner_id TEXT NULL,
+    checksum_sha256 TEXT NOT NULL DEFAULT '',
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    publish_at DATETIME NULL,
+    expires_at DATETIME NULL,
+    visibility TEXT NOT NULL DEFAULT 'draft'
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_status ON media(status);
+CREATE INDEX IF NOT EXISTS idx_media_owner ON media(owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_media_owner_checksum ON media(owner_id, checksum_sha256);
+CREATE INDEX IF NOT EXISTS idx_media_created ON media(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_media_updated ON media(updated_at, id);
+
+-- полнотекстовый поиск (GET /media/search) по title и metadata.description;
+-- docid — rowid строки media, индекс поддерживают триггеры
+CREATE VIRTUAL TABLE IF NOT EXISTS media_fts USING fts4(title, description, tokenize=unicode61);
+
+CREATE TRIGGER IF NOT EXISTS media_fts_insert AFTER INSERT ON media BEGIN
+    INSERT INTO media_fts (docid, title, description)
+    VALUES (new.rowid, new.title, COALESCE(json_extract(new.metadata, '$.description'), ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS media_fts_update AFTER UPDATE OF title, metadata ON media BEGIN
+    UPDATE media_fts
+    SET title = new.title, description = COALESCE(json_extract(new.metadata, '$.description'), '')
+    WHERE docid = new.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS media_fts_delete AFTER DELETE ON media BEGIN
+    DELETE FROM media_fts WHERE docid = old.rowid;
+END;
+
+-- аудит переходов статуса; без FK на media, чтобы история переживала удаление
+CREATE TABLE IF NOT EXISTS media_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    media_id TEXT NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_status_history_media ON media_status_history(media_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    schema_version INTEGER NOT NULL DEFAULT 1,
+    aggregate_id TEXT NOT NULL,
+    sequence INTEGER NOT NULL DEFAULT 0,
+    payload TEXT NOT NULL,
+    occurred_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    -- раньше этого времени событие не берётся: отложенный повтор или аренда захватившего publisher'а
+    next_retry_at DATETIME NULL,
+    dead_lettered_at DATETIME NULL,
+    processed_at DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id)
+    WHERE processed_at IS NULL AND dead_lettered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending_aggregate ON outbox(aggregate_id, id)
+    WHERE processed_at IS NULL AND dead_lettered_at IS NULL;
+
+-- счётчики номеров событий по агрегатам
+CREATE TABLE IF NOT EXISTS aggregate_sequences (
+    aggregate_id TEXT NOT NULL PRIMARY KEY,
+    sequence INTEGER NOT NULL
+);